@@ -0,0 +1,60 @@
+package approvals
+
+import (
+	"strings"
+
+	"github.com/keel-hq/keel/pkg/auth"
+	"github.com/keel-hq/keel/types"
+)
+
+// StatusArchived - status of archived approvals, whether they were approved or not
+const StatusArchived = "archived"
+
+// Status - pending, approved, rejected or archived
+func Status(a *types.Approval) string {
+	if a.Archived {
+		return StatusArchived
+	}
+	return a.Status().String()
+}
+
+// Namespace - namespace of the approval identifier, ie:
+// deployment/<namespace>/<name>:<version> or <namespace>/<release>:<version>
+func Namespace(identifier string) string {
+	parts := strings.Split(identifier, "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[len(parts)-2]
+}
+
+// Allowed - whether the user can see and vote on the approval, everything is
+// allowed without authentication
+func Allowed(user *auth.User, a *types.Approval) bool {
+	return user == nil || user.NamespaceAllowed(Namespace(a.Identifier))
+}
+
+// Vote - approval vote, votes are counted once per voter
+type Vote struct {
+	// Voter - identity the vote is counted for
+	Voter string
+	// OnBehalfOf - optional user the voter acts for, ie: user of a deployment
+	// portal, it's only recorded in the audit log
+	OnBehalfOf string
+}
+
+// UserVote - vote of the authenticated user, voter given by the caller is
+// recorded as the user the vote was cast on behalf of so one user can't vote
+// more than once. Without authentication the given voter is counted.
+func UserVote(user *auth.User, voter string) *Vote {
+	if user == nil {
+		if voter == "" {
+			voter = "api"
+		}
+		return &Vote{Voter: voter}
+	}
+	if voter == user.Username {
+		voter = ""
+	}
+	return &Vote{Voter: user.Username, OnBehalfOf: voter}
+}
@@ -0,0 +1,55 @@
+package approvals
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/pkg/auth"
+	"github.com/keel-hq/keel/types"
+)
+
+func TestNamespace(t *testing.T) {
+	tests := map[string]string{
+		"deployment/staging/app:1.1.0":         "staging",
+		"staging/release:1.1.0":                "staging",
+		"cluster/deployment/staging/app:1.1.0": "staging",
+		"app:1.1.0":                            "",
+	}
+	for identifier, expected := range tests {
+		if got := Namespace(identifier); got != expected {
+			t.Errorf("%s: expected namespace '%s', got '%s'", identifier, expected, got)
+		}
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	a := &types.Approval{Identifier: "deployment/staging/app:1.1.0"}
+	if !Allowed(nil, a) {
+		t.Errorf("expected approval to be allowed without authentication")
+	}
+	if !Allowed(&auth.User{Username: "ci", Namespaces: []string{"staging"}}, a) {
+		t.Errorf("expected approval of staging to be allowed")
+	}
+	if Allowed(&auth.User{Username: "ci", Namespaces: []string{"production"}}, a) {
+		t.Errorf("didn't expect approval of staging to be allowed")
+	}
+}
+
+func TestUserVote(t *testing.T) {
+	user := &auth.User{Username: "portal"}
+	tests := []struct {
+		user     *auth.User
+		voter    string
+		expected Vote
+	}{
+		{nil, "", Vote{Voter: "api"}},
+		{nil, "alice", Vote{Voter: "alice"}},
+		{user, "", Vote{Voter: "portal"}},
+		{user, "portal", Vote{Voter: "portal"}},
+		{user, "alice", Vote{Voter: "portal", OnBehalfOf: "alice"}},
+	}
+	for _, tt := range tests {
+		if got := UserVote(tt.user, tt.voter); *got != tt.expected {
+			t.Errorf("voter '%s': expected %+v, got %+v", tt.voter, tt.expected, *got)
+		}
+	}
+}
@@ -6,10 +6,10 @@ import (
 	"fmt"
 	"strconv"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/keel-hq/keel/internal/bus"
 	"github.com/keel-hq/keel/pkg/store"
 	"github.com/keel-hq/keel/types"
 
@@ -33,8 +33,12 @@ type Manager interface {
 
 	// Increases Approval votes by 1
 	Approve(identifier, voter string) (*types.Approval, error)
-	// Rejects Approval
-	Reject(identifier string) (*types.Approval, error)
+	// Rejects Approval, voter is recorded in the audit log
+	Reject(identifier, voter string) (*types.Approval, error)
+	// ApproveVote - same as Approve, vote is counted once per vote.Voter
+	ApproveVote(identifier string, vote *Vote) (*types.Approval, error)
+	// RejectVote - same as Reject, vote.OnBehalfOf is recorded in the audit log
+	RejectVote(identifier string, vote *Vote) (*types.Approval, error)
 
 	Get(identifier string) (*types.Approval, error)
 	List() ([]*types.Approval, error)
@@ -49,6 +53,14 @@ var (
 	ErrApprovalAlreadyExists = errors.New("approval already exists")
 )
 
+// Approvals topics
+var (
+	// TopicRequested - new approval requests
+	TopicRequested = bus.NewTopic("approval.requested", (*types.Approval)(nil))
+	// TopicApproved - approvals that received all votes
+	TopicApproved = bus.NewTopic("approval.approved", (*types.Approval)(nil))
+)
+
 // Approvals cache prefix
 const (
 	ApprovalsPrefix = "approvals"
@@ -62,19 +74,16 @@ type DefaultManager struct {
 
 	store store.Store
 
-	// subscriber channels
-	channels map[uint32]chan *types.Approval
-	index    uint32
+	// bus - approval requests and approved updates are published to it
+	bus *bus.Bus
 
-	// approved channels
-	approvedCh map[uint32]chan *types.Approval
-
-	mu    *sync.Mutex
-	subMu *sync.RWMutex
+	mu *sync.Mutex
 }
 
 type Opts struct {
 	Store store.Store
+	// Bus - event bus shared with other modules, manager creates its own when not set
+	Bus *bus.Bus
 	// Cache cache.Cache
 }
 
@@ -82,12 +91,12 @@ type Opts struct {
 func New(opts *Opts) *DefaultManager {
 	man := &DefaultManager{
 		// cache:      opts.Cache,
-		store:      opts.Store,
-		channels:   make(map[uint32]chan *types.Approval),
-		approvedCh: make(map[uint32]chan *types.Approval),
-		index:      0,
-		mu:         &sync.Mutex{},
-		subMu:      &sync.RWMutex{},
+		store: opts.Store,
+		bus:   opts.Bus,
+		mu:    &sync.Mutex{},
+	}
+	if man.bus == nil {
+		man.bus = bus.New()
 	}
 
 	return man
@@ -139,7 +148,7 @@ func (m *DefaultManager) expireEntries() error {
 				continue
 			}
 
-			m.addAuditEntry(approval, types.AuditActionApprovalExpired, "")
+			m.addAuditEntry(approval, types.AuditActionApprovalExpired, &Vote{})
 		}
 	}
 
@@ -148,72 +157,42 @@ func (m *DefaultManager) expireEntries() error {
 
 // Subscribe - subscribe for approval events
 func (m *DefaultManager) Subscribe(ctx context.Context) (<-chan *types.Approval, error) {
-	m.subMu.Lock()
-	index := atomic.AddUint32(&m.index, 1)
-	approvalsCh := make(chan *types.Approval, 10)
-	m.channels[index] = approvalsCh
-	m.subMu.Unlock()
-
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				m.subMu.Lock()
-
-				delete(m.channels, index)
-
-				m.subMu.Unlock()
-				return
-			}
-		}
-	}()
-
-	return approvalsCh, nil
+	return m.subscribe(ctx, TopicRequested), nil
 }
 
 // SubscribeApproved - subscribe for approved update requests
 func (m *DefaultManager) SubscribeApproved(ctx context.Context) (<-chan *types.Approval, error) {
-	m.subMu.Lock()
-	index := atomic.AddUint32(&m.index, 1)
-	approvedCh := make(chan *types.Approval, 10)
-	m.approvedCh[index] = approvedCh
-	m.subMu.Unlock()
+	return m.subscribe(ctx, TopicApproved), nil
+}
 
+// subscribe - approvals aren't lost, publishers wait for subscribers that are behind
+func (m *DefaultManager) subscribe(ctx context.Context, topic bus.Topic) <-chan *types.Approval {
+	sub := m.bus.Subscribe(ctx, topic, bus.SubscribeOpts{
+		Name:   "approvals",
+		Buffer: 10,
+		Policy: bus.Block,
+	})
+	approvalsCh := make(chan *types.Approval)
 	go func() {
-		for {
+		for msg := range sub.C {
 			select {
+			case approvalsCh <- msg.(*types.Approval):
 			case <-ctx.Done():
-				m.subMu.Lock()
-
-				delete(m.approvedCh, index)
-
-				m.subMu.Unlock()
 				return
 			}
 		}
 	}()
-
-	return approvedCh, nil
+	return approvalsCh
 }
 
 func (m *DefaultManager) publishRequest(approval *types.Approval) error {
-	m.subMu.RLock()
-	defer m.subMu.RUnlock()
-
-	for _, subscriber := range m.channels {
-		subscriber <- approval
-	}
-	return nil
+	return m.bus.Publish(TopicRequested, approval)
 }
 
+// publishApproved - subscribers get a copy, the approval is still returned to
+// the caller and stored after it's published
 func (m *DefaultManager) publishApproved(approval *types.Approval) error {
-	m.subMu.RLock()
-	defer m.subMu.RUnlock()
-
-	for _, subscriber := range m.approvedCh {
-		subscriber <- approval
-	}
-	return nil
+	return m.bus.Publish(TopicApproved, approval.Copy())
 }
 
 // Update - update approval
@@ -239,6 +218,12 @@ func (m *DefaultManager) Update(r *types.Approval) error {
 
 // Approve - increase VotesReceived by 1 and returns updated version
 func (m *DefaultManager) Approve(identifier, voter string) (*types.Approval, error) {
+	return m.ApproveVote(identifier, &Vote{Voter: voter})
+}
+
+// ApproveVote - increases approval votes by 1 unless vote.Voter has already
+// voted, the user the vote was cast on behalf of doesn't count as a voter
+func (m *DefaultManager) ApproveVote(identifier string, vote *Vote) (*types.Approval, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -252,13 +237,13 @@ func (m *DefaultManager) Approve(identifier, voter string) (*types.Approval, err
 	}
 
 	for _, v := range existing.GetVoters() {
-		if v == voter {
+		if v == vote.Voter {
 			// nothing to do, same voter
 			return existing, nil
 		}
 	}
 
-	existing.AddVoter(voter)
+	existing.AddVoter(vote.Voter)
 	existing.VotesReceived++
 
 	err = m.Update(existing)
@@ -270,7 +255,7 @@ func (m *DefaultManager) Approve(identifier, voter string) (*types.Approval, err
 		return nil, err
 	}
 
-	m.addAuditEntry(existing, types.AuditActionApprovalApproved, voter)
+	m.addAuditEntry(existing, types.AuditActionApprovalApproved, vote)
 
 	log.WithFields(log.Fields{
 		"identifier": identifier,
@@ -279,25 +264,29 @@ func (m *DefaultManager) Approve(identifier, voter string) (*types.Approval, err
 	return existing, nil
 }
 
-func (m *DefaultManager) addAuditEntry(approval *types.Approval, action string, voter string) {
+func (m *DefaultManager) addAuditEntry(approval *types.Approval, action string, vote *Vote) {
 
 	entry := &types.AuditLog{
 		ID:           uuid.New().String(),
-		AccountID:    voter,
-		Username:     voter,
+		AccountID:    vote.Voter,
+		Username:     vote.Voter,
 		Action:       action,
 		ResourceKind: types.AuditResourceKindApproval,
 		Identifier:   approval.Identifier,
 	}
 
-	entry.SetMetadata(map[string]string{
+	metadata := map[string]string{
 		"provider":        approval.Provider.String(),
 		"approval_id":     approval.ID,
 		"new_version":     approval.NewVersion,
 		"current_version": approval.CurrentVersion,
 		"votes_required":  strconv.Itoa(approval.VotesReceived),
 		"votes_received":  strconv.Itoa(approval.VotesReceived),
-	})
+	}
+	if vote.OnBehalfOf != "" {
+		metadata["on_behalf_of"] = vote.OnBehalfOf
+	}
+	entry.SetMetadata(metadata)
 
 	_, err := m.store.CreateAuditLog(entry)
 	if err != nil {
@@ -310,7 +299,12 @@ func (m *DefaultManager) addAuditEntry(approval *types.Approval, action string,
 
 // Reject - rejects approval (marks rejected=true), approval will not be valid even if it
 // collects required votes
-func (m *DefaultManager) Reject(identifier string) (*types.Approval, error) {
+func (m *DefaultManager) Reject(identifier, voter string) (*types.Approval, error) {
+	return m.RejectVote(identifier, &Vote{Voter: voter})
+}
+
+// RejectVote - rejects approval, vote.OnBehalfOf is recorded in the audit log
+func (m *DefaultManager) RejectVote(identifier string, vote *Vote) (*types.Approval, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -326,7 +320,7 @@ func (m *DefaultManager) Reject(identifier string) (*types.Approval, error) {
 		return nil, err
 	}
 
-	m.addAuditEntry(existing, types.AuditActionApprovalRejected, "")
+	m.addAuditEntry(existing, types.AuditActionApprovalRejected, vote)
 
 	return existing, nil
 }
@@ -367,7 +361,7 @@ func (m *DefaultManager) Delete(approval *types.Approval) error {
 		return err
 	}
 
-	m.addAuditEntry(existing, types.AuditActionDeleted, "")
+	m.addAuditEntry(existing, types.AuditActionDeleted, &Vote{})
 
 	return m.store.DeleteApproval(existing)
 }
@@ -379,15 +373,18 @@ func (m *DefaultManager) Archive(identifier string) error {
 	}
 	existing.Archived = true
 
-	m.addAuditEntry(existing, types.AuditActionApprovalArchived, "")
+	m.addAuditEntry(existing, types.AuditActionApprovalArchived, &Vote{})
 
 	return m.store.UpdateApproval(existing)
 }
 
 // Create - creates new approval request and publishes to all subscribers
 func (m *DefaultManager) Create(r *types.Approval) error {
+	// events of the same change can request the approval at the same time
+	m.mu.Lock()
 	_, err := m.Get(r.Identifier)
 	if err == nil {
+		m.mu.Unlock()
 		return ErrApprovalAlreadyExists
 	}
 
@@ -395,6 +392,7 @@ func (m *DefaultManager) Create(r *types.Approval) error {
 	r.UpdatedAt = time.Now()
 
 	created, err := m.store.CreateApproval(r)
+	m.mu.Unlock()
 	if err != nil {
 		return fmt.Errorf("failed to create approval: %s", err)
 	}
@@ -380,7 +380,7 @@ func TestReject(t *testing.T) {
 		t.Fatalf("failed to create approval: %s", err)
 	}
 
-	am.Reject("xxx/app-1")
+	am.Reject("xxx/app-1", "user-1")
 
 	stored, err := am.Get("xxx/app-1")
 	if err != nil {
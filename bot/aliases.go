@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	aliasesM sync.RWMutex
+	// aliases - alias -> command, ie: ship -> approve
+	aliases map[string]string
+	// prefixes - additional prefixes that address the bot, ie: !keel
+	prefixes []string
+)
+
+// ParseAliases - parses comma separated aliases, ie: ship=approve,lgtm=approve,deploys=get deployments.
+// Aliases and commands are lowercased as bots match lowercased messages.
+func ParseAliases(s string) (map[string]string, error) {
+	parsed := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid alias '%s', expected <alias>=<command>", pair)
+		}
+		alias := strings.Join(strings.Fields(strings.ToLower(parts[0])), " ")
+		command := strings.Join(strings.Fields(strings.ToLower(parts[1])), " ")
+		if alias == "" || command == "" {
+			return nil, fmt.Errorf("invalid alias '%s', expected <alias>=<command>", pair)
+		}
+		if alias == command {
+			return nil, fmt.Errorf("alias '%s' points to itself", alias)
+		}
+		parsed[alias] = command
+	}
+	return parsed, nil
+}
+
+// SetCommandAliases - sets command aliases, they can be changed while bots are running
+func SetCommandAliases(a map[string]string) {
+	aliasesM.Lock()
+	aliases = a
+	aliasesM.Unlock()
+}
+
+// SetPrefixes - sets additional prefixes bots respond to besides their name
+// and mentions, they can be changed while bots are running
+func SetPrefixes(p []string) {
+	var lowered []string
+	for _, prefix := range p {
+		if prefix = strings.ToLower(strings.TrimSpace(prefix)); prefix != "" {
+			lowered = append(lowered, prefix)
+		}
+	}
+	aliasesM.Lock()
+	prefixes = lowered
+	aliasesM.Unlock()
+}
+
+// Prefixes - additional prefixes that address the bot
+func Prefixes() []string {
+	aliasesM.RLock()
+	defer aliasesM.RUnlock()
+	return prefixes
+}
+
+// TrimPrefix - removes additional bot prefix from the message, ok is false
+// when message doesn't start with any of them
+func TrimPrefix(msg string) (trimmed string, ok bool) {
+	for _, p := range Prefixes() {
+		if strings.HasPrefix(msg, p) {
+			return strings.Trim(strings.TrimPrefix(msg, p), " :\n"), true
+		}
+	}
+	return msg, false
+}
+
+// ExpandAlias - replaces the longest alias the message starts with by its command,
+// ie: "lgtm deployment/default/wd:1.2.3" becomes "approve deployment/default/wd:1.2.3"
+func ExpandAlias(msg string) string {
+	aliasesM.RLock()
+	defer aliasesM.RUnlock()
+
+	fields := strings.Fields(msg)
+	var command string
+	var aliasLen int
+	for alias, cmd := range aliases {
+		aliasFields := strings.Fields(alias)
+		if len(aliasFields) > len(fields) || len(aliasFields) <= aliasLen {
+			continue
+		}
+		if strings.Join(fields[:len(aliasFields)], " ") == alias {
+			command = cmd
+			aliasLen = len(aliasFields)
+		}
+	}
+	if command == "" {
+		return msg
+	}
+	return strings.Join(append([]string{command}, fields[aliasLen:]...), " ")
+}
+
+// aliasesHelp - help line with configured aliases
+func aliasesHelp() string {
+	aliasesM.RLock()
+	defer aliasesM.RUnlock()
+
+	var lines []string
+	for alias, cmd := range aliases {
+		lines = append(lines, fmt.Sprintf(`"%s" -> "%s"`, alias, cmd))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, ", ")
+}
@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestParseAliases(t *testing.T) {
+	aliases, err := ParseAliases("ship=approve, LGTM=approve,deploys = get  deployments")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(aliases) != 3 || aliases["lgtm"] != "approve" || aliases["deploys"] != "get deployments" {
+		t.Errorf("unexpected aliases: %v", aliases)
+	}
+
+	for _, s := range []string{"ship", "=approve", "ship=", "approve=approve"} {
+		if _, err := ParseAliases(s); err == nil {
+			t.Errorf("expected error for '%s'", s)
+		}
+	}
+}
+
+func TestExpandAlias(t *testing.T) {
+	SetCommandAliases(map[string]string{"ship": "approve", "ship it": "approve", "deploys": "get deployments"})
+	defer SetCommandAliases(nil)
+
+	for input, expected := range map[string]string{
+		"ship deployment/default/wd:1.2.3":    "approve deployment/default/wd:1.2.3",
+		"ship it deployment/default/wd:1.2.3": "approve deployment/default/wd:1.2.3",
+		"deploys":                             "get deployments",
+		"shipping":                            "shipping",
+		"get approvals":                       "get approvals",
+	} {
+		if got := ExpandAlias(input); got != expected {
+			t.Errorf("%s: expected '%s', got '%s'", input, expected, got)
+		}
+	}
+
+	cmd, _ := findCommand("deploys")
+	if cmd == nil || cmd.Name != "get deployments" {
+		t.Errorf("expected alias to find command, got: %v", cmd)
+	}
+
+	resp, ok := IsApproval("karolis", "ship deployment/default/wd:1.2.3")
+	if !ok || resp.Status != types.ApprovalStatusApproved || resp.Text != "approve deployment/default/wd:1.2.3" {
+		t.Errorf("unexpected approval: %v, %v", resp, ok)
+	}
+
+	if !strings.Contains(HelpResponse(), `"ship" -> "approve"`) {
+		t.Errorf("expected aliases in help")
+	}
+}
+
+func TestTrimPrefix(t *testing.T) {
+	SetPrefixes([]string{" !Keel", "", "kb"})
+	defer SetPrefixes(nil)
+
+	if msg, ok := TrimPrefix("!keel: get approvals"); !ok || msg != "get approvals" {
+		t.Errorf("unexpected message: %s, %v", msg, ok)
+	}
+	if msg, ok := TrimPrefix("get approvals"); ok || msg != "get approvals" {
+		t.Errorf("unexpected message: %s, %v", msg, ok)
+	}
+}
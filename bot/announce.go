@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/i18n"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterCommand(&Command{
+		Name:        "ping",
+		Description: "post a test message through every bot and notification sender",
+		Handler: func(bm *BotManager, req *CommandRequest) string {
+			return PingHandler(req)
+		},
+	})
+}
+
+// Announcer - optional bot interface, bots implementing it post test
+// messages to their approvals channel
+type Announcer interface {
+	Announce(text string) error
+}
+
+// Endpoint kinds
+const (
+	EndpointKindBot          = "bot"
+	EndpointKindNotification = "notification"
+)
+
+// EndpointResult - whether a bot or notification sender delivered the test message
+type EndpointResult struct {
+	Name  string `json:"name"`
+	Kind  string `json:"kind"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// errAnnounceNotSupported - bot can't post messages on its own
+var errAnnounceNotSupported = errors.New("test messages aren't supported")
+
+// DefaultAnnouncement - test message, used when the message is empty
+const DefaultAnnouncement = "This is a test message from keel, bots and notifications are set up."
+
+// Announce - posts the message through every running bot and configured
+// notification sender, ie: to verify channel IDs, tokens and permissions
+func Announce(message string) []EndpointResult {
+	if message == "" {
+		message = i18n.T(DefaultAnnouncement)
+	}
+
+	var results []EndpointResult
+	add := func(name, kind string, err error) {
+		result := EndpointResult{Name: name, Kind: kind, OK: err == nil}
+		if err != nil {
+			result.Error = err.Error()
+			log.WithFields(log.Fields{
+				"name":  name,
+				"kind":  kind,
+				"error": err,
+			}).Warn("bot.Announce: failed to post test message")
+		}
+		results = append(results, result)
+	}
+
+	botsM.RLock()
+	running := make(map[string]Bot)
+	for name := range teardowns {
+		running[name] = bots[name]
+	}
+	botsM.RUnlock()
+	for name, b := range running {
+		announcer, ok := b.(Announcer)
+		if !ok {
+			add(name, EndpointKindBot, errAnnounceNotSupported)
+			continue
+		}
+		add(name, EndpointKindBot, announcer.Announce(message))
+	}
+
+	_, sender := connectionSettings()
+	if tester, ok := sender.(notification.Tester); ok {
+		tested := tester.Test(types.EventNotification{
+			Name:      "test notification",
+			Message:   message,
+			CreatedAt: time.Now(),
+			Type:      types.NotificationSystemEvent,
+			Level:     types.LevelInfo,
+		})
+		for name, err := range tested {
+			add(name, EndpointKindNotification, err)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Kind != results[j].Kind {
+			return results[i].Kind < results[j].Kind
+		}
+		return results[i].Name < results[j].Name
+	})
+	return results
+}
+
+// PingHandler - posts test messages and replies with the result of every endpoint
+func PingHandler(req *CommandRequest) string {
+	results := Announce("")
+	if len(results) == 0 {
+		return i18n.T("no bots or notification senders are configured.")
+	}
+
+	log.WithFields(log.Fields{
+		"user": req.Message.User,
+	}).Info("bot: test messages posted")
+
+	return req.ReplyStructured(PingResponse(results))
+}
+
+// PingResponse - result of every endpoint, failed ones have the error
+func PingResponse(results []EndpointResult) *Response {
+	resp := &Response{Title: i18n.T("Test messages")}
+	for _, result := range results {
+		item := ResponseItem{
+			Title: result.Kind + " " + result.Name,
+			Color: types.LevelSuccess.Color(),
+			Fields: []ResponseField{
+				{Title: i18n.T("Status"), Value: i18n.T("delivered"), Short: true},
+			},
+		}
+		if !result.OK {
+			item.Color = types.LevelError.Color()
+			item.Fields = []ResponseField{
+				{Title: i18n.T("Status"), Value: i18n.T("failed"), Short: true},
+				{Title: i18n.T("Error"), Value: result.Error},
+			}
+		}
+		resp.Items = append(resp.Items, item)
+	}
+	return resp
+}
@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+)
+
+type testerStub struct {
+	connectionSenderStub
+}
+
+func (s *testerStub) Test(event types.EventNotification) map[string]error {
+	s.events = append(s.events, event)
+	return map[string]error{
+		"webhook": nil,
+		"teams":   errors.New("got status 404"),
+	}
+}
+
+func TestAnnounce(t *testing.T) {
+	sender := &testerStub{}
+	SetNotificationSender(sender)
+	defer SetNotificationSender(nil)
+
+	results := Announce("")
+	if len(sender.events) != 1 || sender.events[0].Message != DefaultAnnouncement {
+		t.Fatalf("expected test notification, got: %+v", sender.events)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got: %+v", results)
+	}
+	if results[0].Name != "teams" || results[0].OK || results[0].Error != "got status 404" {
+		t.Errorf("unexpected teams result: %+v", results[0])
+	}
+	if results[1].Name != "webhook" || !results[1].OK {
+		t.Errorf("unexpected webhook result: %+v", results[1])
+	}
+
+	resp := PingResponse(results).String()
+	if !strings.Contains(resp, "notification teams") || !strings.Contains(resp, "got status 404") {
+		t.Errorf("unexpected response: %s", resp)
+	}
+}
@@ -1,18 +1,153 @@
 package bot
 
 import (
-	"bytes"
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/keel-hq/keel/approvals"
-	"github.com/keel-hq/keel/bot/formatter"
+	"github.com/keel-hq/keel/internal/bus"
 	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/i18n"
 
 	log "github.com/sirupsen/logrus"
 )
 
+func init() {
+	RegisterCommand(&Command{
+		Name: "get approvals",
+		Flags: []Flag{
+			{Name: "ns", Description: "namespace"},
+			{Name: "image", Description: "image"},
+			{Name: "page", Description: "page"},
+		},
+		Description: "get a list of approvals",
+		Handler:     getApprovalsHandler,
+	})
+	RegisterCommand(&Command{
+		Name:        RemoveApprovalPrefix,
+		Args:        []Arg{{Name: "approval identifier"}},
+		Description: "remove approval",
+		Handler: func(bm *BotManager, req *CommandRequest) string {
+			return RemoveApprovalHandler(req.Arg("approval identifier"), bm.approvalsManager)
+		},
+	})
+	// approvals are usually intercepted by the bots (ie: to check the approvals channel),
+	// registering them here so they show up in help and still work when a bot doesn't
+	RegisterCommand(&Command{
+		Name:        ApprovalResponseKeyword,
+		Args:        []Arg{{Name: "approval identifier"}},
+		Description: "approve update request",
+		Handler:     approvalCommandHandler,
+	})
+	RegisterCommand(&Command{
+		Name:        RejectResponseKeyword,
+		Args:        []Arg{{Name: "approval identifier"}},
+		Description: "reject update request",
+		Handler:     approvalCommandHandler,
+	})
+}
+
+// ApprovalsPageSize - number of approvals shown per page
+const ApprovalsPageSize = 10
+
+// ApprovalsFilter - filters approvals by namespace and image
+type ApprovalsFilter struct {
+	Namespace string
+	Image     string
+}
+
+func getApprovalsHandler(bm *BotManager, req *CommandRequest) string {
+	log.Info("HandleCommand: getting approvals")
+
+	page := 1
+	if req.Flag("page") != "" {
+		p, err := strconv.Atoi(req.Flag("page"))
+		if err != nil || p < 1 {
+			return i18n.T("invalid page '%s', expected a positive number", req.Flag("page"))
+		}
+		page = p
+	}
+
+	approvals, err := bm.approvalsManager.List()
+	if err != nil {
+		return i18n.T("got error while fetching approvals: %s", err)
+	}
+
+	filtered := FilterApprovals(approvals, &ApprovalsFilter{
+		Namespace: req.Flag("ns"),
+		Image:     req.Flag("image"),
+	})
+	if len(filtered) == 0 {
+		return i18n.T("there are currently no request waiting to be approved.")
+	}
+
+	return req.ReplyStructured(ApprovalsPage(filtered, page, ApprovalsPageSize))
+}
+
+// FilterApprovals - returns approvals that match the filter
+func FilterApprovals(list []*types.Approval, filter *ApprovalsFilter) []*types.Approval {
+	var filtered []*types.Approval
+	for _, a := range list {
+		if filter.Namespace != "" && approvals.Namespace(a.Identifier) != filter.Namespace {
+			continue
+		}
+		if filter.Image != "" && (a.Event == nil || !strings.Contains(a.Event.Repository.Name, filter.Image)) {
+			continue
+		}
+		filtered = append(filtered, a)
+	}
+	return filtered
+}
+
+// ApprovalsPage - structured response with a single page of approvals
+func ApprovalsPage(approvals []*types.Approval, page, pageSize int) *Response {
+	pages := (len(approvals) + pageSize - 1) / pageSize
+	if page > pages {
+		page = pages
+	}
+
+	start := (page - 1) * pageSize
+	end := start + pageSize
+	if end > len(approvals) {
+		end = len(approvals)
+	}
+
+	resp := &Response{
+		Title: i18n.T("Approvals waiting for votes: %d", len(approvals)),
+	}
+	for _, a := range approvals[start:end] {
+		resp.Items = append(resp.Items, ResponseItem{
+			Title: a.Identifier,
+			Color: types.LevelInfo.Color(),
+			Fields: []ResponseField{
+				{Title: i18n.T("Delta"), Value: a.Delta(), Short: true},
+				{Title: i18n.T("Votes"), Value: fmt.Sprintf("%d/%d", a.VotesReceived, a.VotesRequired), Short: true},
+				{Title: i18n.T("Status"), Value: a.Status().String(), Short: true},
+				{Title: i18n.T("Provider"), Value: a.Provider.String(), Short: true},
+				{Title: i18n.T("Deadline"), Value: a.Deadline.Format(time.RFC3339), Short: true},
+			},
+		})
+	}
+
+	resp.Footer = i18n.T("page %d/%d", page, pages)
+	if page < pages {
+		resp.Footer += i18n.T(", use 'get approvals page=%d' to see more", page+1)
+	}
+
+	return resp
+}
+
+func approvalCommandHandler(bm *BotManager, req *CommandRequest) string {
+	approval, ok := IsApproval(req.Message.User, req.Message.Message)
+	if ok {
+		bm.approvalsRespCh <- approval
+	}
+	return ""
+}
+
 type BotRequestApproval func(req *types.Approval) error
 type BotReplyApproval func(approval *types.Approval) error
 
@@ -26,43 +161,53 @@ func (bm *BotManager) SubscribeForApprovals(ctx context.Context, approval BotReq
 	for {
 		select {
 		case <-ctx.Done():
-			return nil
-		case a := <-approvalsCh:
-			err = approval(a)
-			if err != nil {
-				log.WithFields(log.Fields{
-					"error":    err,
-					"approval": a.Identifier,
-				}).Error("bot.subscribeForApprovals: approval request failed")
+			// requests published before the bot stopped are still sent
+			for {
+				select {
+				case a := <-approvalsCh:
+					requestApproval(approval, a)
+				default:
+					return nil
+				}
 			}
+		case a := <-approvalsCh:
+			requestApproval(approval, a)
 		}
 	}
 }
 
-func (bm *BotManager) ProcessApprovalResponses(ctx context.Context, reply BotReplyApproval) error {
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		case resp := <-bm.approvalsRespCh:
-			switch resp.Status {
-			case types.ApprovalStatusApproved:
-				err := bm.processApprovedResponse(resp, reply)
-				if err != nil {
-					log.WithFields(log.Fields{
-						"error": err,
-					}).Error("bot.processApprovalResponses: failed to process approval response message")
-				}
-			case types.ApprovalStatusRejected:
-				err := bm.processRejectedResponse(resp, reply)
-				if err != nil {
-					log.WithFields(log.Fields{
-						"error": err,
-					}).Error("bot.processApprovalResponses: failed to process approval reject response message")
-				}
+func requestApproval(approval BotRequestApproval, a *types.Approval) {
+	err := approval(a)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"approval": a.Identifier,
+		}).Error("bot.subscribeForApprovals: approval request failed")
+	}
+}
+
+// ProcessApprovalResponses - handles approval votes until the subscription ends
+func (bm *BotManager) ProcessApprovalResponses(responses *bus.Subscription, reply BotReplyApproval) error {
+	for msg := range responses.C {
+		resp := msg.(*ApprovalResponse)
+		switch resp.Status {
+		case types.ApprovalStatusApproved:
+			err := bm.processApprovedResponse(resp, reply)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+				}).Error("bot.processApprovalResponses: failed to process approval response message")
+			}
+		case types.ApprovalStatusRejected:
+			err := bm.processRejectedResponse(resp, reply)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+				}).Error("bot.processApprovalResponses: failed to process approval reject response message")
 			}
 		}
 	}
+	return nil
 }
 
 func (bm *BotManager) processApprovedResponse(approvalResponse *ApprovalResponse, reply BotReplyApproval) error {
@@ -104,7 +249,7 @@ func (bm *BotManager) processRejectedResponse(approvalResponse *ApprovalResponse
 	}
 
 	for _, identifier := range identifiers {
-		approval, err := bm.approvalsManager.Reject(identifier)
+		approval, err := bm.approvalsManager.Reject(identifier, approvalResponse.User)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"error":      err,
@@ -124,32 +269,10 @@ func (bm *BotManager) processRejectedResponse(approvalResponse *ApprovalResponse
 	return nil
 }
 
-func ApprovalsResponse(approvalsManager approvals.Manager) string {
-	approvals, err := approvalsManager.List()
-	if err != nil {
-		return fmt.Sprintf("got error while fetching approvals: %s", err)
-	}
-
-	if len(approvals) == 0 {
-		return fmt.Sprintf("there are currently no request waiting to be approved.")
-	}
-
-	buf := &bytes.Buffer{}
-
-	approvalCtx := formatter.Context{
-		Output: buf,
-		Format: formatter.NewApprovalsFormat(formatter.TableFormatKey, false),
-	}
-	err = formatter.ApprovalWrite(approvalCtx, approvals)
-
-	if err != nil {
-		return fmt.Sprintf("got error while formatting approvals: %s", err)
-	}
-
-	return buf.String()
-}
-
+// IsApproval - checks whether text is an approve or reject command, aliases are
+// expanded so the response text always starts with the command keyword
 func IsApproval(eventUser string, eventText string) (resp *ApprovalResponse, ok bool) {
+	eventText = ExpandAlias(eventText)
 	if strings.HasPrefix(strings.ToLower(eventText), ApprovalResponseKeyword) {
 		return &ApprovalResponse{
 			User:   eventUser,
@@ -172,11 +295,25 @@ func IsApproval(eventUser string, eventText string) (resp *ApprovalResponse, ok
 func RemoveApprovalHandler(identifier string, approvalsManager approvals.Manager) string {
 	approval, err := approvalsManager.Get(identifier)
 	if err != nil {
-		return fmt.Sprintf("approval with identifier '%s' was not found", identifier)
+		return i18n.T("approval with identifier '%s' was not found", identifier)
 	}
 	err = approvalsManager.Delete(approval)
 	if err != nil {
-		return fmt.Sprintf("failed to remove '%s' approval: %s.", identifier, err)
+		return i18n.T("failed to remove '%s' approval: %s.", identifier, err)
+	}
+	return i18n.T("approval '%s' removed.", identifier)
+}
+
+// ApprovalDiffLines - lines of the approval diff shown in approval messages,
+// bots that support files attach the full diff
+const ApprovalDiffLines = 30
+
+// TruncateDiff - first lines of the diff, truncated is true when lines were cut
+func TruncateDiff(diff string, lines int) (string, bool) {
+	diff = strings.TrimRight(diff, "\n")
+	all := strings.Split(diff, "\n")
+	if len(all) <= lines {
+		return diff, false
 	}
-	return fmt.Sprintf("approval '%s' removed.", identifier)
+	return strings.Join(all[:lines], "\n") + "\n" + i18n.T("... %d more lines", len(all)-lines), true
 }
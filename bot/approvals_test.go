@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func testApprovals() []*types.Approval {
+	var approvals []*types.Approval
+	for i := 0; i < 15; i++ {
+		approvals = append(approvals, &types.Approval{
+			Provider:       types.ProviderTypeKubernetes,
+			Identifier:     fmt.Sprintf("deployment/payments/app-%d:1.0.%d", i, i),
+			CurrentVersion: "0.9.0",
+			NewVersion:     fmt.Sprintf("1.0.%d", i),
+			VotesRequired:  1,
+			Deadline:       time.Now().Add(time.Hour),
+			Event: &types.Event{
+				Repository: types.Repository{Name: "karolisr/app"},
+			},
+		})
+	}
+	approvals = append(approvals, &types.Approval{
+		Provider:   types.ProviderTypeHelm,
+		Identifier: "default/release:1.0.0",
+		Event: &types.Event{
+			Repository: types.Repository{Name: "karolisr/other"},
+		},
+	})
+	return approvals
+}
+
+func TestFilterApprovals(t *testing.T) {
+	approvals := testApprovals()
+
+	filtered := FilterApprovals(approvals, &ApprovalsFilter{Namespace: "payments"})
+	if len(filtered) != 15 {
+		t.Errorf("expected 15 approvals, got: %d", len(filtered))
+	}
+
+	filtered = FilterApprovals(approvals, &ApprovalsFilter{Namespace: "default"})
+	if len(filtered) != 1 {
+		t.Errorf("expected 1 approval, got: %d", len(filtered))
+	}
+
+	filtered = FilterApprovals(approvals, &ApprovalsFilter{Image: "karolisr/other"})
+	if len(filtered) != 1 || filtered[0].Identifier != "default/release:1.0.0" {
+		t.Errorf("unexpected approvals: %v", filtered)
+	}
+}
+
+func TestApprovalsPage(t *testing.T) {
+	approvals := testApprovals()
+
+	resp := ApprovalsPage(approvals, 1, 10)
+	if len(resp.Items) != 10 {
+		t.Errorf("expected 10 items, got: %d", len(resp.Items))
+	}
+	if !strings.Contains(resp.Footer, "page 1/2") || !strings.Contains(resp.Footer, "page=2") {
+		t.Errorf("unexpected footer: %s", resp.Footer)
+	}
+
+	resp = ApprovalsPage(approvals, 2, 10)
+	if len(resp.Items) != 6 {
+		t.Errorf("expected 6 items, got: %d", len(resp.Items))
+	}
+
+	// out of range page returns the last one
+	resp = ApprovalsPage(approvals, 5, 10)
+	if resp.Footer != "page 2/2" {
+		t.Errorf("unexpected footer: %s", resp.Footer)
+	}
+
+	if !strings.Contains(resp.String(), "default/release:1.0.0") {
+		t.Errorf("expected plain text to contain approval: %s", resp.String())
+	}
+
+	approvals[0].Rejected = true
+	resp = ApprovalsPage(approvals, 1, 10)
+	if !strings.Contains(resp.String(), "Status: rejected") {
+		t.Errorf("expected plain text to contain rejected status: %s", resp.String())
+	}
+}
+
+func TestTruncateDiff(t *testing.T) {
+	diff, truncated := TruncateDiff("a\nb\nc\n", 3)
+	if truncated || diff != "a\nb\nc" {
+		t.Errorf("unexpected diff %q, truncated: %t", diff, truncated)
+	}
+
+	diff, truncated = TruncateDiff("a\nb\nc\nd\ne", 2)
+	if !truncated || diff != "a\nb\n... 3 more lines" {
+		t.Errorf("unexpected diff %q, truncated: %t", diff, truncated)
+	}
+}
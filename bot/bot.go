@@ -2,13 +2,16 @@ package bot
 
 import (
 	"context"
-	"fmt"
-	"strings"
 	"sync"
 
 	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/internal/bus"
+	"github.com/keel-hq/keel/internal/ratelimit"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/provider"
 	"github.com/keel-hq/keel/provider/kubernetes"
 	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/i18n"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -18,28 +21,6 @@ const (
 )
 
 var (
-	BotEventTextToResponse = map[string][]string{
-		"help": {
-			`Here's a list of supported commands`,
-			`- "get deployments" -> get a list of all deployments`,
-			`- "get approvals" -> get a list of approvals`,
-			`- "rm approval <approval identifier>" -> remove approval`,
-			`- "approve <approval identifier>" -> approve update request`,
-			`- "reject <approval identifier>" -> reject update request`,
-			// `- "get deployments all" -> get a list of all deployments`,
-			// `- "describe deployment <deployment>" -> get details for specified deployment`,
-		},
-	}
-
-	// static bot commands can be used straight away
-	staticBotCommands = map[string]bool{
-		"get deployments": true,
-		"get approvals":   true,
-	}
-
-	// dynamic bot command prefixes have to be matched
-	dynamicBotCommandPrefixes = []string{RemoveApprovalPrefix}
-
 	ApprovalResponseKeyword = "approve"
 	RejectResponseKeyword   = "reject"
 )
@@ -59,8 +40,30 @@ var (
 	botsM     sync.RWMutex
 	bots      = make(map[string]Bot)
 	teardowns = make(map[string]teardown)
+
+	// workers - message and approval processing of running bots, Drain waits
+	// for them to finish messages that are being handled
+	workers sync.WaitGroup
+	// stopManager - stops processing of bot messages and approval responses
+	stopManager context.CancelFunc
+
+	// eventBus - bot messages and approval responses are published to it, see SetEventBus
+	eventBus *bus.Bus
 )
 
+// Bot topics, messages received by bots and approval votes are published to
+// them so other modules (ie: audit) can subscribe too
+var (
+	TopicBotMessage       = bus.NewTopic("bot.message", (*BotMessage)(nil))
+	TopicApprovalResponse = bus.NewTopic("bot.approvalResponse", (*ApprovalResponse)(nil))
+)
+
+// SetEventBus - sets event bus shared with other modules, bots use their own
+// bus when not set
+func SetEventBus(b *bus.Bus) {
+	eventBus = b
+}
+
 // BotMessage represents abstract container for any bot Message
 // add here more fields if you needed for a new bot implementation
 type BotMessage struct {
@@ -68,6 +71,12 @@ type BotMessage struct {
 	User    string
 	Name    string
 	Channel string
+	// Thread - message that responses are threaded under, ie: Slack message
+	// timestamp, empty when the bot doesn't support threads
+	Thread string
+
+	// receiver - bot that received the message, set by the manager
+	receiver Bot
 }
 
 // ApprovalResponse - used to track approvals once vote begins
@@ -77,12 +86,19 @@ type ApprovalResponse struct {
 	Text   string
 }
 
-// BotManager holds approvalsManager and k8sImplementer for every bot
+// BotManager holds approvalsManager, k8sImplementer, providers and store for every bot
 type BotManager struct {
-	approvalsManager   approvals.Manager
-	k8sImplementer     kubernetes.Implementer
-	botMessagesChannel chan *BotMessage
-	approvalsRespCh    chan *ApprovalResponse
+	approvalsManager approvals.Manager
+	k8sImplementer   kubernetes.Implementer
+	providers        provider.Providers
+	store            store.Store
+	rateLimiter      *ratelimit.Limiter
+	approvalsRespCh  chan *ApprovalResponse
+	bus              *bus.Bus
+
+	// running bots, keyed by bot name
+	runningM sync.RWMutex
+	running  map[string]Bot
 }
 
 // RegisterBot makes a bot implementation available by the provided name.
@@ -110,24 +126,73 @@ func RegisterBot(name string, b Bot) {
 }
 
 // Run all implemented bots
-func Run(k8sImplementer kubernetes.Implementer, approvalsManager approvals.Manager) {
+func Run(k8sImplementer kubernetes.Implementer, approvalsManager approvals.Manager, providers provider.Providers, store store.Store) {
 	bm := &BotManager{
-		approvalsManager:   approvalsManager,
-		k8sImplementer:     k8sImplementer,
-		approvalsRespCh:    make(chan *ApprovalResponse), // don't add buffer to make it blocking
-		botMessagesChannel: make(chan *BotMessage),
+		approvalsManager: approvalsManager,
+		k8sImplementer:   k8sImplementer,
+		providers:        providers,
+		store:            store,
+		rateLimiter:      rateLimiter,
+		approvalsRespCh:  make(chan *ApprovalResponse), // don't add buffer to make it blocking
+		bus:              eventBus,
+		running:          make(map[string]Bot),
+	}
+	if bm.bus == nil {
+		bm.bus = bus.New()
 	}
+	rollouts.setManager(bm)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	botsM.Lock()
+	stopManager = cancel
+	botsM.Unlock()
+
+	// blocking subscriptions keep bots waiting until messages are handled
+	messages := bm.bus.Subscribe(ctx, TopicBotMessage, bus.SubscribeOpts{Name: "bot.manager", Policy: bus.Block})
+	responses := bm.bus.Subscribe(ctx, TopicApprovalResponse, bus.SubscribeOpts{Name: "bot.manager", Policy: bus.Block})
+	workers.Add(3)
+	go func() {
+		defer workers.Done()
+		bm.ProcessBotMessages(messages)
+	}()
+	go func() {
+		defer workers.Done()
+		bm.ProcessApprovalResponses(responses, bm.replyToApproval)
+	}()
+	// approvals channel is shared by all bots, separate publisher so that
+	// commands handled by the manager can vote
+	go func() {
+		defer workers.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case resp := <-bm.approvalsRespCh:
+				bm.bus.Publish(TopicApprovalResponse, resp)
+			}
+		}
+	}()
+
 	for botName, bot := range bots {
-		configured := bot.Configure(bm.approvalsRespCh, bm.botMessagesChannel)
+		// every bot has its own messages channel so responses go through
+		// the bot that received the message
+		botMessages := make(chan *BotMessage)
+		configured := bot.Configure(bm.approvalsRespCh, botMessages)
 		if configured {
 			bm.SetupBot(botName, bot)
+			bm.publishMessages(ctx, bot, botMessages)
 		} else {
 			log.Errorf("bot.Run(): can not get configuration for bot [%s]", botName)
 		}
 	}
+	bm.startReminders()
 }
 
 func (bm *BotManager) SetupBot(botName string, bot Bot) {
+	if keeper, ok := bot.(ConversationKeeper); ok && bm.store != nil {
+		keeper.SetConversationStore(bm.store)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	err := bot.Start(ctx)
 	if err != nil {
@@ -137,71 +202,123 @@ func (bm *BotManager) SetupBot(botName string, bot Bot) {
 		}).Fatalf("main: failed to setup %s bot\n", botName)
 	} else {
 		// store cancelling context for each bot
+		botsM.Lock()
 		teardowns[botName] = func() { cancel() }
+		botsM.Unlock()
 
-		go bm.ProcessBotMessages(ctx, bot.Respond)
-		go bm.ProcessApprovalResponses(ctx, bot.ReplyToApproval)
-		go bm.SubscribeForApprovals(ctx, bot.RequestApproval)
+		bm.runningM.Lock()
+		bm.running[botName] = bot
+		bm.runningM.Unlock()
+
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			bm.SubscribeForApprovals(ctx, bot.RequestApproval)
+		}()
 	}
 }
 
-func (bm *BotManager) ProcessBotMessages(ctx context.Context, respond BotMessageResponder) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case message := <-bm.botMessagesChannel:
-			response := bm.handleBotMessage(message)
-			if response != "" {
-				respond(response, message.Channel)
+// publishMessages - publishes messages received by the bot to the bus
+func (bm *BotManager) publishMessages(ctx context.Context, b Bot, messages chan *BotMessage) {
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message := <-messages:
+				message.receiver = b
+				bm.bus.Publish(TopicBotMessage, message)
 			}
 		}
-	}
+	}()
 }
 
-func Stop() {
-	for botName, teardown := range teardowns {
-		log.Infof("Teardown %s bot", botName)
-		teardown()
-		UnregisterBot(botName)
+// ProcessBotMessages - handles messages of all bots until the subscription ends
+func (bm *BotManager) ProcessBotMessages(messages *bus.Subscription) {
+	for msg := range messages.C {
+		message := msg.(*BotMessage)
+		// responding through the bot that received the message, bots
+		// can be registered under a different name than they report
+		origin := bm.getRunning(message.Name, message.receiver)
+		if origin == nil {
+			log.WithFields(log.Fields{
+				"bot":  message.Name,
+				"user": message.User,
+			}).Warn("bot.ProcessBotMessages: bot isn't running, ignoring message")
+			continue
+		}
+		response := bm.handleBotMessage(message, origin)
+		if response != "" {
+			responder(origin, message)(response, message.Channel)
+		}
 	}
 }
 
-func IsBotCommand(eventText string) bool {
-	if staticBotCommands[eventText] {
-		return true
+// getRunning - returns running bot by name, fallback is returned
+// when bot is not found
+func (bm *BotManager) getRunning(name string, fallback Bot) Bot {
+	bm.runningM.RLock()
+	defer bm.runningM.RUnlock()
+	if b, ok := bm.running[name]; ok {
+		return b
 	}
+	return fallback
+}
 
-	for _, prefix := range dynamicBotCommandPrefixes {
-		if strings.HasPrefix(eventText, prefix) {
-			return true
+// replyToApproval - approval updates are sent to all running bots,
+// bots decide whether approval is relevant to them
+func (bm *BotManager) replyToApproval(approval *types.Approval) error {
+	bm.runningM.RLock()
+	defer bm.runningM.RUnlock()
+
+	for name, b := range bm.running {
+		err := b.ReplyToApproval(approval)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"bot":      name,
+				"approval": approval.Identifier,
+			}).Error("bot.replyToApproval: failed to reply")
 		}
 	}
-
-	return false
+	return nil
 }
 
-func (bm *BotManager) handleCommand(eventText string) string {
-	switch eventText {
-	case "get deployments":
-		log.Info("HandleCommand: getting deployments")
-		return DeploymentsResponse(Filter{}, bm.k8sImplementer)
-	case "get approvals":
-		log.Info("HandleCommand: getting approvals")
-		return ApprovalsResponse(bm.approvalsManager)
+func Stop() {
+	stopReminders()
+	botsM.Lock()
+	if stopManager != nil {
+		stopManager()
+		stopManager = nil
 	}
-
-	// handle dynamic commands
-	if strings.HasPrefix(eventText, RemoveApprovalPrefix) {
-		id := strings.TrimSpace(strings.TrimPrefix(eventText, RemoveApprovalPrefix))
-		return RemoveApprovalHandler(id, bm.approvalsManager)
+	botsM.Unlock()
+	for botName, teardown := range teardowns {
+		log.Infof("Teardown %s bot", botName)
+		teardown()
+		UnregisterBot(botName)
 	}
+}
 
-	log.Infof("bot.HandleCommand(): command [%s] not found", eventText)
-	return ""
+// Drain - stops bots and waits until messages being handled are answered and
+// queued approval requests are sent, or ctx is done
+func Drain(ctx context.Context) {
+	Stop()
+
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Warn("bot.Drain: bots didn't finish pending messages")
+	}
 }
 
-func (bm *BotManager) handleBotMessage(m *BotMessage) string {
+func (bm *BotManager) handleBotMessage(m *BotMessage, b Bot) string {
 	command := m.Message
 
 	switch command {
@@ -209,12 +326,8 @@ func (bm *BotManager) handleBotMessage(m *BotMessage) string {
 		return ""
 	}
 
-	if responseLines, ok := BotEventTextToResponse[command]; ok {
-		return strings.Join(responseLines, "\n")
-	}
-
 	if IsBotCommand(command) {
-		return bm.handleCommand(command)
+		return bm.handleCommand(m, b)
 	}
 
 	log.WithFields(log.Fields{
@@ -223,7 +336,7 @@ func (bm *BotManager) handleBotMessage(m *BotMessage) string {
 		"command": command,
 	}).Debug("handleMessage: bot couldn't recognize command")
 
-	return fmt.Sprintf("unknown command '%s'", command)
+	return i18n.T("unknown command '%s'", command)
 }
 
 // UnregisterBot removes a Sender with a particular name from the list.
@@ -0,0 +1,68 @@
+package chatops
+
+import (
+	"fmt"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/types"
+)
+
+// RequestApproval - sends approval request to the webhook
+func (b *Bot) RequestApproval(req *types.Approval) error {
+	return b.post(&Message{
+		Type: MessageTypeApprovalRequest,
+		Text: fmt.Sprintf("Approval required: %s. To vote for change type 'approve %s', to reject it: 'reject %s'. Votes: %d/%d, delta: %s",
+			req.Message, req.Identifier, req.Identifier, req.VotesReceived, req.VotesRequired, req.Delta()),
+		Status:   req.Status().String(),
+		Approval: req,
+		Mention:  req.Oncall,
+	})
+}
+
+// ReplyToApproval - sends approval status update to the webhook
+func (b *Bot) ReplyToApproval(approval *types.Approval) error {
+	var text string
+	switch approval.Status() {
+	case types.ApprovalStatusPending:
+		text = fmt.Sprintf("Vote received: %d/%d, %s (%s)",
+			approval.VotesReceived, approval.VotesRequired, approval.Delta(), approval.Identifier)
+	case types.ApprovalStatusRejected:
+		text = fmt.Sprintf("Change rejected: %d/%d, %s (%s)",
+			approval.VotesReceived, approval.VotesRequired, approval.Delta(), approval.Identifier)
+	case types.ApprovalStatusApproved:
+		text = fmt.Sprintf("Update approved: %d/%d, %s (%s)",
+			approval.VotesReceived, approval.VotesRequired, approval.Delta(), approval.Identifier)
+	default:
+		return nil
+	}
+
+	return b.post(&Message{
+		Type:     MessageTypeApprovalUpdate,
+		Text:     text,
+		Status:   approval.Status().String(),
+		Approval: approval,
+	})
+}
+
+// ReplyToRollout - sends rollout progress of approved update to the webhook,
+// status is the notification level, ie: "info" or "error"
+func (b *Bot) ReplyToRollout(approvalIdentifier string, event types.EventNotification) error {
+	return b.post(&Message{
+		Type:               MessageTypeRolloutProgress,
+		Text:               event.Message,
+		Status:             event.Level.String(),
+		ApprovalIdentifier: approvalIdentifier,
+	})
+}
+
+// RemindApproval - sends reminder of the pending approval to the webhook, mention
+// is also passed separately so bridges can translate it to the platform mention
+func (b *Bot) RemindApproval(approval *types.Approval, mention string) error {
+	return b.post(&Message{
+		Type:     MessageTypeApprovalReminder,
+		Text:     bot.ReminderMessage(approval, mention),
+		Status:   approval.Status().String(),
+		Approval: approval,
+		Mention:  mention,
+	})
+}
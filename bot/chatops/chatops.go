@@ -0,0 +1,382 @@
+package chatops
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultPort    = 9301
+	defaultChannel = "general"
+
+	timeout = 5 * time.Second
+
+	// SignatureHeader - header that holds HMAC-SHA256 signature of the timestamp
+	// and the request body, both outgoing requests and incoming callbacks are signed
+	SignatureHeader = "X-Keel-Signature"
+	// TimestampHeader - unix time (seconds) when the request was signed
+	TimestampHeader = "X-Keel-Timestamp"
+
+	// signatureMaxAge - callbacks signed earlier (or later) are rejected,
+	// accepted signatures are remembered for that long to reject replays
+	signatureMaxAge = 5 * time.Minute
+
+	// CallbackPath - path on which approve/reject callbacks are accepted
+	CallbackPath = "/v1/chatops/callback"
+)
+
+// Message types that are sent to the webhook
+const (
+	MessageTypeApprovalRequest  = "approval_request"
+	MessageTypeApprovalUpdate   = "approval_update"
+	MessageTypeRolloutProgress  = "rollout_progress"
+	MessageTypeApprovalReminder = "approval_reminder"
+	MessageTypeResponse         = "response"
+	MessageTypeTest             = "test"
+)
+
+// Callback actions
+const (
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+	ActionCommand = "command"
+)
+
+// Message - payload that is POSTed to the configured webhook
+type Message struct {
+	Type     string          `json:"type"`
+	Channel  string          `json:"channel"`
+	Text     string          `json:"text"`
+	Status   string          `json:"status,omitempty"`
+	Approval *types.Approval `json:"approval,omitempty"`
+	// ApprovalIdentifier - approval request of the rolling out update, set for
+	// rollout progress so bridges can reply to (or update) the request message
+	ApprovalIdentifier string `json:"approvalIdentifier,omitempty"`
+	// Mention - approver group or on-call handle mentioned by the approval reminder,
+	// comma separated emails of on-call users for approval requests
+	Mention string `json:"mention,omitempty"`
+}
+
+// Callback - payload that chat bridges send back to keel
+type Callback struct {
+	Action     string `json:"action"`
+	Identifier string `json:"identifier,omitempty"`
+	Text       string `json:"text,omitempty"`
+	User       string `json:"user"`
+	Channel    string `json:"channel,omitempty"`
+}
+
+// Bot - generic chatops bot, bridges keel to any chat platform through webhooks
+type Bot struct {
+	endpoint string
+	secret   []byte
+	port     int
+	channel  string
+
+	client *http.Client
+	server *http.Server
+
+	botMessagesChannel chan *bot.BotMessage
+	approvalsRespCh    chan *bot.ApprovalResponse
+
+	// seen - signatures of accepted callbacks and when they were accepted
+	seen   map[string]time.Time
+	seenMu sync.Mutex
+}
+
+func init() {
+	bot.RegisterBot("chatops", &Bot{})
+}
+
+// Configure - reads configuration from environment, bot is only enabled
+// when both webhook URL and secret are set
+func (b *Bot) Configure(approvalsRespCh chan *bot.ApprovalResponse, botMessagesChannel chan *bot.BotMessage) bool {
+	endpoint := os.Getenv(constants.EnvChatopsWebhookURL)
+	if endpoint == "" {
+		log.Info("bot.chatops.Configure(): chatops bot is not configured")
+		return false
+	}
+
+	if _, err := url.ParseRequestURI(endpoint); err != nil {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"endpoint": endpoint,
+		}).Error("bot.chatops.Configure(): invalid webhook URL")
+		return false
+	}
+
+	secret := os.Getenv(constants.EnvChatopsSecret)
+	if secret == "" {
+		log.Errorf("bot.chatops.Configure(): %s is required to verify callbacks", constants.EnvChatopsSecret)
+		return false
+	}
+
+	b.endpoint = endpoint
+	b.secret = []byte(secret)
+
+	b.port = defaultPort
+	if os.Getenv(constants.EnvChatopsPort) != "" {
+		var port int
+		_, err := fmt.Sscanf(os.Getenv(constants.EnvChatopsPort), "%d", &port)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"port":  os.Getenv(constants.EnvChatopsPort),
+			}).Errorf("bot.chatops.Configure(): failed to parse port, using default: %d", defaultPort)
+		} else {
+			b.port = port
+		}
+	}
+
+	b.channel = defaultChannel
+	if os.Getenv(constants.EnvChatopsChannel) != "" {
+		b.channel = os.Getenv(constants.EnvChatopsChannel)
+	}
+
+	b.client = &http.Client{
+		Transport: http.DefaultTransport,
+		Timeout:   timeout,
+	}
+
+	b.botMessagesChannel = botMessagesChannel
+	b.approvalsRespCh = approvalsRespCh
+	b.seen = make(map[string]time.Time)
+
+	return true
+}
+
+// Start - starts callback server
+func (b *Bot) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(CallbackPath, b.callbackHandler)
+
+	b.server = &http.Server{
+		Addr:    fmt.Sprintf(":%d", b.port),
+		Handler: mux,
+	}
+
+	go func() {
+		log.WithFields(log.Fields{
+			"port": b.port,
+		}).Info("bot.chatops: callback server starting...")
+		err := b.server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("bot.chatops: callback server stopped")
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		b.server.Shutdown(context.Background())
+	}()
+
+	return nil
+}
+
+// Respond - sends command response back to the webhook
+func (b *Bot) Respond(text string, channel string) {
+	err := b.post(&Message{
+		Type:    MessageTypeResponse,
+		Channel: channel,
+		Text:    text,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"channel": channel,
+		}).Error("bot.chatops.Respond(): failed to send response")
+	}
+}
+
+// Announce - sends test message to the webhook
+func (b *Bot) Announce(text string) error {
+	return b.post(&Message{
+		Type: MessageTypeTest,
+		Text: text,
+	})
+}
+
+func (b *Bot) post(msg *Message) error {
+	if msg.Channel == "" {
+		msg.Channel = b.channel
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, b.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(b.secret, timestamp, body))
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("got status %d, expected 2xx", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (b *Bot) callbackHandler(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "failed to read request body: %s", err)
+		return
+	}
+
+	err = b.verifyCallback(req.Header.Get(TimestampHeader), body, req.Header.Get(SignatureHeader), time.Now())
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Warn("bot.chatops: callback signature verification failed")
+		resp.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintf(resp, "%s", err)
+		return
+	}
+
+	var cb Callback
+	err = json.Unmarshal(body, &cb)
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "failed to decode request: %s", err)
+		return
+	}
+
+	err = b.handleCallback(&cb)
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "%s", err)
+		return
+	}
+
+	resp.WriteHeader(http.StatusAccepted)
+}
+
+func (b *Bot) handleCallback(cb *Callback) error {
+	if cb.User == "" {
+		return errors.New("user is required")
+	}
+
+	switch cb.Action {
+	case ActionApprove, ActionReject:
+		if cb.Identifier == "" {
+			return errors.New("identifier is required")
+		}
+		approval, _ := bot.IsApproval(cb.User, cb.Action+" "+cb.Identifier)
+		b.approvalsRespCh <- approval
+	case ActionCommand:
+		text := strings.ToLower(strings.TrimSpace(cb.Text))
+		if text == "" {
+			return errors.New("text is required")
+		}
+		channel := cb.Channel
+		if channel == "" {
+			channel = b.channel
+		}
+
+		// commands can also be approvals, ie: "approve <identifier>"
+		approval, ok := bot.IsApproval(cb.User, text)
+		if ok {
+			b.approvalsRespCh <- approval
+			return nil
+		}
+
+		b.botMessagesChannel <- &bot.BotMessage{
+			Message: text,
+			User:    cb.User,
+			Channel: channel,
+			Name:    "chatops",
+		}
+	default:
+		return fmt.Errorf("unknown action '%s'", cb.Action)
+	}
+
+	return nil
+}
+
+// verifyCallback - checks callback signature, rejects callbacks signed outside
+// of the allowed window and signatures that were already accepted
+func (b *Bot) verifyCallback(timestamp string, body []byte, signature string, now time.Time) error {
+	if !Verify(b.secret, timestamp, body, signature) {
+		return errors.New("invalid signature")
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %s", err)
+	}
+	signed := time.Unix(unix, 0)
+	if now.Sub(signed) > signatureMaxAge || signed.Sub(now) > signatureMaxAge {
+		return fmt.Errorf("signature timestamp %s is outside of the allowed window", signed.UTC().Format(time.RFC3339))
+	}
+
+	b.seenMu.Lock()
+	defer b.seenMu.Unlock()
+	for sig, accepted := range b.seen {
+		if now.Sub(accepted) > 2*signatureMaxAge {
+			delete(b.seen, sig)
+		}
+	}
+	if _, ok := b.seen[signature]; ok {
+		return errors.New("signature was already used")
+	}
+	b.seen[signature] = now
+
+	return nil
+}
+
+// Sign - computes signature of the timestamp and payload, HMAC covers
+// <timestamp>.<payload>, format: sha256=<hex encoded HMAC>
+func Sign(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify - checks whether signature matches the timestamp and payload
+func Verify(secret []byte, timestamp string, payload []byte, signature string) bool {
+	if signature == "" || timestamp == "" {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, timestamp, payload)), []byte(signature))
+}
@@ -0,0 +1,286 @@
+package chatops
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/types"
+)
+
+func newTestBot(t *testing.T, endpoint string) (*Bot, chan *bot.ApprovalResponse, chan *bot.BotMessage) {
+	os.Setenv(constants.EnvChatopsWebhookURL, endpoint)
+	os.Setenv(constants.EnvChatopsSecret, "very-secret")
+	defer os.Unsetenv(constants.EnvChatopsWebhookURL)
+	defer os.Unsetenv(constants.EnvChatopsSecret)
+
+	approvalsRespCh := make(chan *bot.ApprovalResponse, 1)
+	botMessagesChannel := make(chan *bot.BotMessage, 1)
+
+	b := &Bot{}
+	if !b.Configure(approvalsRespCh, botMessagesChannel) {
+		t.Fatalf("expected bot to be configured")
+	}
+	return b, approvalsRespCh, botMessagesChannel
+}
+
+// callbackRequest - callback request signed with the given secret and time
+func callbackRequest(secret string, signed time.Time, body []byte) *http.Request {
+	timestamp := strconv.FormatInt(signed.Unix(), 10)
+	req, _ := http.NewRequest("POST", CallbackPath, bytes.NewReader(body))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign([]byte(secret), timestamp, body))
+	return req
+}
+
+func TestConfigureNotEnabled(t *testing.T) {
+	b := &Bot{}
+	if b.Configure(nil, nil) {
+		t.Errorf("bot shouldn't be configured without webhook URL")
+	}
+
+	os.Setenv(constants.EnvChatopsWebhookURL, "http://localhost:12345")
+	defer os.Unsetenv(constants.EnvChatopsWebhookURL)
+	if b.Configure(nil, nil) {
+		t.Errorf("bot shouldn't be configured without secret")
+	}
+}
+
+func TestRequestApproval(t *testing.T) {
+	var received Message
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		if !Verify([]byte("very-secret"), r.Header.Get(TimestampHeader), body, signature) {
+			t.Errorf("invalid signature: %s", signature)
+		}
+		json.Unmarshal(body, &received)
+	}))
+	defer srv.Close()
+
+	b, _, _ := newTestBot(t, srv.URL)
+
+	err := b.RequestApproval(&types.Approval{
+		Identifier:     "deployment/default/wd:1.2.3",
+		CurrentVersion: "1.2.2",
+		NewVersion:     "1.2.3",
+		VotesRequired:  1,
+		Deadline:       time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if received.Type != MessageTypeApprovalRequest {
+		t.Errorf("unexpected message type: %s", received.Type)
+	}
+	if received.Channel != defaultChannel {
+		t.Errorf("unexpected channel: %s", received.Channel)
+	}
+	if received.Approval == nil || received.Approval.Identifier != "deployment/default/wd:1.2.3" {
+		t.Errorf("unexpected approval: %#v", received.Approval)
+	}
+}
+
+func TestCallbackApprove(t *testing.T) {
+	b, approvalsRespCh, _ := newTestBot(t, "http://localhost:12345")
+
+	body := []byte(`{"action": "approve", "identifier": "deployment/default/wd:1.2.3", "user": "karolis"}`)
+
+	rec := httptest.NewRecorder()
+	b.callbackHandler(rec, callbackRequest("very-secret", time.Now(), body))
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+
+	resp := <-approvalsRespCh
+	if resp.Status != types.ApprovalStatusApproved {
+		t.Errorf("unexpected status: %s", resp.Status)
+	}
+	if resp.User != "karolis" {
+		t.Errorf("unexpected user: %s", resp.User)
+	}
+	if resp.Text != "approve deployment/default/wd:1.2.3" {
+		t.Errorf("unexpected text: %s", resp.Text)
+	}
+}
+
+func TestCallbackCommand(t *testing.T) {
+	b, _, botMessagesChannel := newTestBot(t, "http://localhost:12345")
+
+	body := []byte(`{"action": "command", "text": "get approvals", "user": "karolis", "channel": "ops"}`)
+
+	rec := httptest.NewRecorder()
+	b.callbackHandler(rec, callbackRequest("very-secret", time.Now(), body))
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+
+	msg := <-botMessagesChannel
+	if msg.Message != "get approvals" {
+		t.Errorf("unexpected message: %s", msg.Message)
+	}
+	if msg.Channel != "ops" {
+		t.Errorf("unexpected channel: %s", msg.Channel)
+	}
+}
+
+func TestCallbackInvalidSignature(t *testing.T) {
+	b, _, _ := newTestBot(t, "http://localhost:12345")
+
+	body := []byte(`{"action": "approve", "identifier": "deployment/default/wd:1.2.3", "user": "karolis"}`)
+
+	rec := httptest.NewRecorder()
+	b.callbackHandler(rec, callbackRequest("wrong-secret", time.Now(), body))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("unexpected status code: %d", rec.Code)
+	}
+
+	// signature of the body alone is not accepted
+	req, _ := http.NewRequest("POST", CallbackPath, bytes.NewReader(body))
+	req.Header.Set(SignatureHeader, Sign([]byte("very-secret"), "", body))
+	rec = httptest.NewRecorder()
+	b.callbackHandler(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("unexpected status code without timestamp: %d", rec.Code)
+	}
+}
+
+func TestCallbackStaleSignature(t *testing.T) {
+	b, _, _ := newTestBot(t, "http://localhost:12345")
+
+	body := []byte(`{"action": "approve", "identifier": "deployment/default/wd:1.2.3", "user": "karolis"}`)
+
+	for _, signed := range []time.Time{time.Now().Add(-10 * time.Minute), time.Now().Add(10 * time.Minute)} {
+		rec := httptest.NewRecorder()
+		b.callbackHandler(rec, callbackRequest("very-secret", signed, body))
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected callback signed at %s to be rejected, got: %d", signed, rec.Code)
+		}
+	}
+}
+
+func TestCallbackReplayedSignature(t *testing.T) {
+	b, approvalsRespCh, _ := newTestBot(t, "http://localhost:12345")
+
+	body := []byte(`{"action": "approve", "identifier": "deployment/default/wd:1.2.3", "user": "karolis"}`)
+	req := callbackRequest("very-secret", time.Now(), body)
+
+	rec := httptest.NewRecorder()
+	b.callbackHandler(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+	<-approvalsRespCh
+
+	// same request sent again
+	replayed, _ := http.NewRequest("POST", CallbackPath, bytes.NewReader(body))
+	replayed.Header = req.Header
+	rec = httptest.NewRecorder()
+	b.callbackHandler(rec, replayed)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected replayed callback to be rejected, got: %d", rec.Code)
+	}
+
+	// captured signature with a fresh timestamp
+	replayed, _ = http.NewRequest("POST", CallbackPath, bytes.NewReader(body))
+	replayed.Header.Set(TimestampHeader, strconv.FormatInt(time.Now().Add(time.Second).Unix(), 10))
+	replayed.Header.Set(SignatureHeader, req.Header.Get(SignatureHeader))
+	rec = httptest.NewRecorder()
+	b.callbackHandler(rec, replayed)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected signature with a different timestamp to be rejected, got: %d", rec.Code)
+	}
+}
+
+func TestReplyToRollout(t *testing.T) {
+	var received Message
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+	}))
+	defer srv.Close()
+
+	b, _, _ := newTestBot(t, srv.URL)
+
+	err := b.ReplyToRollout("deployment/default/wd:1.2.3", types.EventNotification{
+		Message: "deployment default/wd update 1.2.2->1.2.3 rolling out: 1 out of 3 new replicas have been updated",
+		Level:   types.LevelDebug,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if received.Type != MessageTypeRolloutProgress {
+		t.Errorf("unexpected message type: %s", received.Type)
+	}
+	if received.ApprovalIdentifier != "deployment/default/wd:1.2.3" || received.Status != "debug" {
+		t.Errorf("unexpected message: %#v", received)
+	}
+}
+
+func TestRemindApproval(t *testing.T) {
+	var received Message
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+	}))
+	defer srv.Close()
+
+	b, _, _ := newTestBot(t, srv.URL)
+
+	err := b.RemindApproval(&types.Approval{
+		Identifier:    "deployment/default/wd:1.2.3",
+		VotesRequired: 2,
+		VotesReceived: 1,
+	}, "@deploy-approvers")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if received.Type != MessageTypeApprovalReminder || received.Mention != "@deploy-approvers" {
+		t.Errorf("unexpected message: %#v", received)
+	}
+	if !strings.HasPrefix(received.Text, "@deploy-approvers Reminder: approval deployment/default/wd:1.2.3") {
+		t.Errorf("unexpected text: %s", received.Text)
+	}
+}
+
+func TestAnnounce(t *testing.T) {
+	status := http.StatusOK
+	var received Message
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	b, _, _ := newTestBot(t, srv.URL)
+	if err := b.Announce("test"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if received.Type != MessageTypeTest || received.Text != "test" || received.Channel != defaultChannel {
+		t.Errorf("unexpected message: %+v", received)
+	}
+
+	status = http.StatusForbidden
+	if err := b.Announce("test"); err == nil {
+		t.Errorf("expected error when the webhook rejects the message")
+	}
+}
@@ -0,0 +1,278 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/keel-hq/keel/util/i18n"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Arg - command argument definition, used to parse command input
+// and to generate help
+type Arg struct {
+	Name     string
+	Optional bool
+}
+
+// Flag - optional command flag, passed as <name>=<value>, ie: page=2
+type Flag struct {
+	Name        string
+	Description string
+}
+
+// CommandRequest - parsed command that is passed to the handler
+type CommandRequest struct {
+	// Args - positional arguments, keyed by argument name
+	Args map[string]string
+	// Flags - flag values, keyed by flag name
+	Flags map[string]string
+	// Message - original bot message
+	Message *BotMessage
+	// Respond - can be used by long running commands
+	// to send additional responses later on
+	Respond BotMessageResponder
+	// RespondStructured - set when bot can render structured responses
+	RespondStructured func(resp *Response, channel string)
+}
+
+// Flag - returns flag value by name
+func (r *CommandRequest) Flag(name string) string {
+	return r.Flags[name]
+}
+
+// ReplyStructured - sends structured response if bot supports it, otherwise
+// returns plain text version that should be returned by the handler
+func (r *CommandRequest) ReplyStructured(resp *Response) string {
+	if r.RespondStructured != nil {
+		r.RespondStructured(resp, r.Message.Channel)
+		return ""
+	}
+	return resp.String()
+}
+
+// Reply - sends additional response to the channel command was received from
+func (r *CommandRequest) Reply(text string) {
+	if r.Respond != nil {
+		r.Respond(text, r.Message.Channel)
+	}
+}
+
+// Arg - returns argument value by name
+func (r *CommandRequest) Arg(name string) string {
+	return r.Args[name]
+}
+
+// CommandHandler - handles parsed command and returns response text,
+// empty response is not sent back
+type CommandHandler func(bm *BotManager, req *CommandRequest) string
+
+// Command - bot command, once registered it becomes available
+// in every bot implementation
+type Command struct {
+	// Name - one or more words, ie: "get deployments"
+	Name        string
+	Args        []Arg
+	Flags       []Flag
+	Description string
+	Handler     CommandHandler
+}
+
+// Usage - command usage string, ie: rm approval <identifier>
+func (c *Command) Usage() string {
+	usage := c.Name
+	for _, arg := range c.Args {
+		if arg.Optional {
+			usage += " [" + arg.Name + "]"
+		} else {
+			usage += " <" + arg.Name + ">"
+		}
+	}
+	for _, flag := range c.Flags {
+		usage += " [" + flag.Name + "=<" + flag.Description + ">]"
+	}
+	return usage
+}
+
+func (c *Command) isFlag(field string) (name, value string, ok bool) {
+	parts := strings.SplitN(field, "=", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	for _, flag := range c.Flags {
+		if flag.Name == parts[0] {
+			return parts[0], parts[1], true
+		}
+	}
+	return "", "", false
+}
+
+func (c *Command) parse(input []string) (map[string]string, map[string]string, error) {
+	flags := make(map[string]string)
+	var fields []string
+	for _, field := range input {
+		if name, value, ok := c.isFlag(field); ok {
+			flags[name] = value
+			continue
+		}
+		fields = append(fields, field)
+	}
+
+	if len(c.Args) == 0 && len(fields) > 0 {
+		return nil, nil, errors.New(i18n.T("unexpected arguments '%s', usage: %s", strings.Join(fields, " "), c.Usage()))
+	}
+
+	args := make(map[string]string)
+	for idx, arg := range c.Args {
+		if idx >= len(fields) {
+			if !arg.Optional {
+				return nil, nil, errors.New(i18n.T("missing argument '%s', usage: %s", arg.Name, c.Usage()))
+			}
+			continue
+		}
+		// last argument gets the rest of the input
+		if idx == len(c.Args)-1 {
+			args[arg.Name] = strings.Join(fields[idx:], " ")
+			continue
+		}
+		args[arg.Name] = fields[idx]
+	}
+	return args, flags, nil
+}
+
+var (
+	commandsM sync.RWMutex
+	commands  []*Command
+)
+
+// RegisterCommand makes a command available to all bots
+func RegisterCommand(cmd *Command) {
+	if cmd == nil || cmd.Name == "" {
+		panic("bot: could not register a command with an empty name")
+	}
+
+	if cmd.Handler == nil {
+		panic("bot: could not register a command without handler: " + cmd.Name)
+	}
+
+	commandsM.Lock()
+	defer commandsM.Unlock()
+
+	for _, c := range commands {
+		if c.Name == cmd.Name {
+			panic("bot: RegisterCommand called twice for " + cmd.Name)
+		}
+	}
+
+	commands = append(commands, cmd)
+}
+
+// UnregisterCommand removes a command with a particular name from the registry.
+func UnregisterCommand(name string) {
+	commandsM.Lock()
+	defer commandsM.Unlock()
+
+	for idx, c := range commands {
+		if c.Name == name {
+			commands = append(commands[:idx], commands[idx+1:]...)
+			return
+		}
+	}
+}
+
+// Commands - returns all registered commands
+func Commands() []*Command {
+	commandsM.RLock()
+	defer commandsM.RUnlock()
+
+	registered := make([]*Command, len(commands))
+	copy(registered, commands)
+	return registered
+}
+
+// findCommand - finds command with the longest name matching the input,
+// returns remaining input fields as arguments. Aliases are expanded first.
+func findCommand(text string) (*Command, []string) {
+	fields := strings.Fields(ExpandAlias(text))
+
+	var found *Command
+	var foundLen int
+	for _, c := range Commands() {
+		nameFields := strings.Fields(c.Name)
+		if len(nameFields) > len(fields) || len(nameFields) <= foundLen {
+			continue
+		}
+		if strings.Join(fields[:len(nameFields)], " ") == c.Name {
+			found = c
+			foundLen = len(nameFields)
+		}
+	}
+
+	if found == nil {
+		return nil, nil
+	}
+
+	return found, fields[foundLen:]
+}
+
+// IsBotCommand - checks whether text matches any registered command
+func IsBotCommand(eventText string) bool {
+	cmd, _ := findCommand(eventText)
+	return cmd != nil
+}
+
+func (bm *BotManager) handleCommand(m *BotMessage, b Bot) string {
+	cmd, fields := findCommand(m.Message)
+	if cmd == nil {
+		log.Infof("bot.HandleCommand(): command [%s] not found", m.Message)
+		return ""
+	}
+
+	args, flags, err := cmd.parse(fields)
+	if err != nil {
+		return err.Error()
+	}
+
+	log.WithFields(log.Fields{
+		"command": cmd.Name,
+		"user":    m.User,
+		"bot":     m.Name,
+	}).Info("bot.HandleCommand(): handling command")
+
+	req := &CommandRequest{
+		Args:    args,
+		Flags:   flags,
+		Message: m,
+	}
+	if b != nil {
+		req.Respond = responder(b, m)
+		req.RespondStructured = structuredResponder(b, m)
+	}
+
+	return cmd.Handler(bm, req)
+}
+
+// HelpResponse - generates help from registered commands
+func HelpResponse() string {
+	lines := []string{i18n.T("Here's a list of supported commands")}
+	for _, c := range Commands() {
+		lines = append(lines, fmt.Sprintf(`- "%s" -> %s`, c.Usage(), i18n.T(c.Description)))
+	}
+	if help := aliasesHelp(); help != "" {
+		lines = append(lines, i18n.T("Aliases: %s", help))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func init() {
+	RegisterCommand(&Command{
+		Name:        "help",
+		Description: "get a list of supported commands",
+		Handler: func(bm *BotManager, req *CommandRequest) string {
+			return HelpResponse()
+		},
+	})
+}
@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindCommand(t *testing.T) {
+	cmd, args := findCommand("rm approval deployment/default/wd:1.2.3")
+	if cmd == nil {
+		t.Fatalf("expected to find command")
+	}
+	if cmd.Name != RemoveApprovalPrefix {
+		t.Errorf("unexpected command: %s", cmd.Name)
+	}
+	if len(args) != 1 || args[0] != "deployment/default/wd:1.2.3" {
+		t.Errorf("unexpected args: %v", args)
+	}
+
+	cmd, _ = findCommand("get deploymentsss")
+	if cmd != nil {
+		t.Errorf("didn't expect to find command, got: %s", cmd.Name)
+	}
+
+	if !IsBotCommand("get approvals") {
+		t.Errorf("expected 'get approvals' to be a command")
+	}
+}
+
+func TestRegisterCommand(t *testing.T) {
+	RegisterCommand(&Command{
+		Name:        "say hello",
+		Args:        []Arg{{Name: "name"}, {Name: "greeting", Optional: true}},
+		Description: "say hello to someone",
+		Handler: func(bm *BotManager, req *CommandRequest) string {
+			return "hello " + req.Arg("name") + " " + req.Arg("greeting")
+		},
+	})
+	defer UnregisterCommand("say hello")
+
+	bm := &BotManager{}
+
+	resp := bm.handleBotMessage(&BotMessage{Message: "say hello world how are you"}, nil)
+	if resp != "hello world how are you" {
+		t.Errorf("unexpected response: %s", resp)
+	}
+
+	resp = bm.handleBotMessage(&BotMessage{Message: "say hello"}, nil)
+	if !strings.Contains(resp, "usage: say hello <name> [greeting]") {
+		t.Errorf("unexpected response: %s", resp)
+	}
+
+	help := bm.handleBotMessage(&BotMessage{Message: "help"}, nil)
+	if !strings.Contains(help, `- "say hello <name> [greeting]" -> say hello to someone`) {
+		t.Errorf("command not found in help: %s", help)
+	}
+	if !strings.Contains(help, `- "get deployments" -> get a list of all deployments`) {
+		t.Errorf("command not found in help: %s", help)
+	}
+}
+
+func TestCommandFlags(t *testing.T) {
+	RegisterCommand(&Command{
+		Name:        "list things",
+		Flags:       []Flag{{Name: "page", Description: "page"}},
+		Description: "list things",
+		Handler: func(bm *BotManager, req *CommandRequest) string {
+			return "page " + req.Flag("page")
+		},
+	})
+	defer UnregisterCommand("list things")
+
+	bm := &BotManager{}
+
+	resp := bm.handleBotMessage(&BotMessage{Message: "list things page=2"}, nil)
+	if resp != "page 2" {
+		t.Errorf("unexpected response: %s", resp)
+	}
+
+	resp = bm.handleBotMessage(&BotMessage{Message: "list things foo"}, nil)
+	if !strings.Contains(resp, "usage: list things [page=<page>]") {
+		t.Errorf("unexpected response: %s", resp)
+	}
+}
+
+type fakeThreadBot struct {
+	fakeRolloutBot
+	replies    []string
+	structured []string
+}
+
+func (b *fakeThreadBot) Respond(text string, channel string) {
+	b.replies = append(b.replies, channel+": "+text)
+}
+func (b *fakeThreadBot) RespondInThread(text, channel, thread string) {
+	b.replies = append(b.replies, channel+"/"+thread+": "+text)
+}
+func (b *fakeThreadBot) RespondStructuredInThread(resp *Response, channel, thread string) {
+	b.structured = append(b.structured, channel+"/"+thread+": "+resp.Title)
+}
+
+func TestCommandRepliesInThread(t *testing.T) {
+	RegisterCommand(&Command{
+		Name:        "show things",
+		Description: "show things",
+		Handler: func(bm *BotManager, req *CommandRequest) string {
+			req.Reply("working on it")
+			return req.ReplyStructured(&Response{Title: "things"})
+		},
+	})
+	defer UnregisterCommand("show things")
+
+	b := &fakeThreadBot{}
+	bm := &BotManager{}
+
+	bm.handleBotMessage(&BotMessage{Message: "show things", Channel: "C1", Thread: "1503435956.000100"}, b)
+	if len(b.replies) != 1 || b.replies[0] != "C1/1503435956.000100: working on it" {
+		t.Errorf("unexpected replies: %v", b.replies)
+	}
+	if len(b.structured) != 1 || b.structured[0] != "C1/1503435956.000100: things" {
+		t.Errorf("unexpected structured replies: %v", b.structured)
+	}
+
+	// messages without thread are answered in the channel
+	resp := bm.handleBotMessage(&BotMessage{Message: "show things", Channel: "D1"}, b)
+	if len(b.replies) != 2 || b.replies[1] != "D1: working on it" {
+		t.Errorf("unexpected replies: %v", b.replies)
+	}
+	if resp != "things\n" {
+		t.Errorf("expected plain text response, got: %q", resp)
+	}
+}
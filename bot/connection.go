@@ -0,0 +1,186 @@
+package bot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var botConnectedGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "bot_connected",
+		Help: "Whether the bot is connected to the chat service (1) or not (0), partitioned by bot.",
+	},
+	[]string{"bot"},
+)
+
+func init() {
+	prometheus.MustRegister(botConnectedGauge)
+}
+
+// DefaultDisconnectThreshold - how long bots can be disconnected, ie: while
+// they reconnect, before it's reported
+const DefaultDisconnectThreshold = 5 * time.Minute
+
+var (
+	connectionM         sync.RWMutex
+	disconnectThreshold = DefaultDisconnectThreshold
+	connectionSender    notification.Sender
+)
+
+// SetDisconnectThreshold - sets how long bots can be disconnected before they
+// are reported unhealthy
+func SetDisconnectThreshold(d time.Duration) {
+	connectionM.Lock()
+	disconnectThreshold = d
+	connectionM.Unlock()
+}
+
+// SetNotificationSender - sender that is notified when bots lose connection
+// for longer than the threshold and once they reconnect, ping command tests
+// its senders too
+func SetNotificationSender(sender notification.Sender) {
+	connectionM.Lock()
+	connectionSender = sender
+	connectionM.Unlock()
+}
+
+func connectionSettings() (time.Duration, notification.Sender) {
+	connectionM.RLock()
+	defer connectionM.RUnlock()
+	return disconnectThreshold, connectionSender
+}
+
+// ConnectionMonitor - tracks connection of a bot to the chat service. Short
+// disconnects (ie: while reconnecting) aren't reported, bots become unhealthy
+// once they are disconnected longer than the threshold.
+type ConnectionMonitor struct {
+	name string
+
+	mu        sync.Mutex
+	connected bool
+	lastErr   error
+	// since - when the bot was disconnected, or started connecting
+	since    time.Time
+	reported bool
+
+	now func() time.Time
+}
+
+// NewConnectionMonitor - creates monitor of the bot, it's disconnected until
+// Connected is called
+func NewConnectionMonitor(name string) *ConnectionMonitor {
+	m := &ConnectionMonitor{
+		name:    name,
+		lastErr: fmt.Errorf("connecting"),
+		now:     time.Now,
+	}
+	m.since = m.now()
+	botConnectedGauge.WithLabelValues(name).Set(0)
+	return m
+}
+
+// Connected - bot is connected, reconnects after a reported disconnect are
+// notified
+func (m *ConnectionMonitor) Connected() {
+	m.mu.Lock()
+	wasReported := m.reported
+	disconnectedFor := m.now().Sub(m.since)
+	m.connected = true
+	m.lastErr = nil
+	m.reported = false
+	m.mu.Unlock()
+
+	botConnectedGauge.WithLabelValues(m.name).Set(1)
+	if wasReported {
+		log.WithFields(log.Fields{
+			"bot":              m.name,
+			"disconnected_for": disconnectedFor,
+		}).Info("bot.ConnectionMonitor: bot reconnected")
+		m.notify(types.LevelInfo, "bot reconnected",
+			fmt.Sprintf("Bot %s reconnected after %s", m.name, disconnectedFor.Round(time.Second)))
+	}
+}
+
+// Disconnected - bot lost connection, err is the reason
+func (m *ConnectionMonitor) Disconnected(err error) {
+	m.mu.Lock()
+	if m.connected {
+		m.since = m.now()
+	}
+	m.connected = false
+	m.lastErr = err
+	m.mu.Unlock()
+
+	botConnectedGauge.WithLabelValues(m.name).Set(0)
+}
+
+// Healthy - error when the bot is disconnected longer than the threshold
+func (m *ConnectionMonitor) Healthy() error {
+	threshold, _ := connectionSettings()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.connected {
+		return nil
+	}
+	disconnectedFor := m.now().Sub(m.since)
+	if disconnectedFor < threshold {
+		return nil
+	}
+	return fmt.Errorf("disconnected for %s: %s", disconnectedFor.Round(time.Second), m.lastErr)
+}
+
+// Check - notifies once the bot is disconnected longer than the threshold,
+// bots call it periodically
+func (m *ConnectionMonitor) Check() {
+	err := m.Healthy()
+	if err == nil {
+		return
+	}
+
+	m.mu.Lock()
+	report := !m.reported
+	m.reported = true
+	m.mu.Unlock()
+	if !report {
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"bot":   m.name,
+		"error": err,
+	}).Error("bot.ConnectionMonitor: bot is disconnected, approvals and commands aren't received")
+	m.notify(types.LevelError, "bot disconnected",
+		fmt.Sprintf("Bot %s is %s, approvals and commands aren't received until it reconnects", m.name, err))
+}
+
+func (m *ConnectionMonitor) notify(level types.Level, name, message string) {
+	_, sender := connectionSettings()
+	if sender == nil {
+		return
+	}
+	err := sender.Send(types.EventNotification{
+		Name:      name,
+		Message:   message,
+		CreatedAt: m.now(),
+		Type:      types.NotificationSystemEvent,
+		Level:     level,
+		Metadata: map[string]string{
+			"bot": m.name,
+		},
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"bot":   m.name,
+			"error": err,
+		}).Warn("bot.ConnectionMonitor: failed to send notification")
+	}
+}
@@ -0,0 +1,65 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+)
+
+type connectionSenderStub struct {
+	events []types.EventNotification
+}
+
+func (s *connectionSenderStub) Configure(*notification.Config) (bool, error) { return true, nil }
+
+func (s *connectionSenderStub) Send(event types.EventNotification) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func TestConnectionMonitor(t *testing.T) {
+	sender := &connectionSenderStub{}
+	SetNotificationSender(sender)
+	SetDisconnectThreshold(time.Minute)
+	defer SetNotificationSender(nil)
+	defer SetDisconnectThreshold(DefaultDisconnectThreshold)
+
+	now := time.Now()
+	m := NewConnectionMonitor("slack")
+	m.now = func() time.Time { return now }
+	m.since = now
+
+	m.Connected()
+	m.Disconnected(errors.New("connection reset"))
+
+	// reconnecting, not reported yet
+	now = now.Add(30 * time.Second)
+	m.Check()
+	if err := m.Healthy(); err != nil {
+		t.Errorf("expected bot to be healthy while reconnecting, got: %s", err)
+	}
+	if len(sender.events) != 0 {
+		t.Fatalf("expected no notifications, got: %+v", sender.events)
+	}
+
+	now = now.Add(time.Minute)
+	m.Check()
+	m.Check()
+	if err := m.Healthy(); err == nil {
+		t.Errorf("expected bot to be unhealthy")
+	}
+	if len(sender.events) != 1 || sender.events[0].Level != types.LevelError {
+		t.Fatalf("expected a disconnect notification, got: %+v", sender.events)
+	}
+
+	m.Connected()
+	if err := m.Healthy(); err != nil {
+		t.Errorf("expected bot to be healthy, got: %s", err)
+	}
+	if len(sender.events) != 2 || sender.events[1].Name != "bot reconnected" {
+		t.Errorf("expected a reconnect notification, got: %+v", sender.events)
+	}
+}
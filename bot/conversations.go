@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+)
+
+// ConversationStore - storage of bot approval conversations, the keel store
+// implements it so that conversations are shared by replicas and survive restarts
+type ConversationStore interface {
+	SaveBotConversation(conversation *types.BotConversation) error
+	// GetBotConversation - store.ErrRecordNotFound is returned when bot
+	// doesn't have a conversation of the approval
+	GetBotConversation(bot, identifier string) (*types.BotConversation, error)
+	ListBotConversations(bot string) ([]*types.BotConversation, error)
+	DeleteBotConversations(expiredBefore time.Time) error
+}
+
+// ConversationKeeper - bots that keep approval conversations, they get the
+// conversation store of the manager before they are started
+type ConversationKeeper interface {
+	SetConversationStore(conversations ConversationStore)
+}
+
+// MemoryConversationStore - conversations kept in memory, used by bots that
+// aren't started with a store
+type MemoryConversationStore struct {
+	mu            sync.Mutex
+	conversations map[string]types.BotConversation
+}
+
+// NewMemoryConversationStore - creates empty in-memory conversation store
+func NewMemoryConversationStore() *MemoryConversationStore {
+	return &MemoryConversationStore{
+		conversations: make(map[string]types.BotConversation),
+	}
+}
+
+// SaveBotConversation - stores copy of the conversation
+func (s *MemoryConversationStore) SaveBotConversation(conversation *types.BotConversation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conversation.ID = types.BotConversationID(conversation.Bot, conversation.Identifier)
+	conversation.UpdatedAt = time.Now()
+	if existing, ok := s.conversations[conversation.ID]; ok {
+		conversation.CreatedAt = existing.CreatedAt
+	} else if conversation.CreatedAt.IsZero() {
+		conversation.CreatedAt = conversation.UpdatedAt
+	}
+	s.conversations[conversation.ID] = *conversation
+	return nil
+}
+
+func (s *MemoryConversationStore) GetBotConversation(bot, identifier string) (*types.BotConversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conversation, ok := s.conversations[types.BotConversationID(bot, identifier)]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+	return &conversation, nil
+}
+
+func (s *MemoryConversationStore) ListBotConversations(bot string) ([]*types.BotConversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var conversations []*types.BotConversation
+	for _, c := range s.conversations {
+		if c.Bot == bot {
+			conversation := c
+			conversations = append(conversations, &conversation)
+		}
+	}
+	return conversations, nil
+}
+
+func (s *MemoryConversationStore) DeleteBotConversations(expiredBefore time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, c := range s.conversations {
+		if c.Expires.Before(expiredBefore) {
+			delete(s.conversations, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+)
+
+func TestMemoryConversationStore(t *testing.T) {
+	s := NewMemoryConversationStore()
+
+	conversation := &types.BotConversation{
+		Bot:        "slack",
+		Identifier: "deployment/default/wd:1.1.0",
+		Channel:    "C1",
+		TS:         "1.1",
+		Expires:    time.Now().Add(time.Hour),
+	}
+	conversation.SetApproval(&types.Approval{Identifier: "deployment/default/wd:1.1.0", VotesRequired: 2})
+	if err := s.SaveBotConversation(conversation); err != nil {
+		t.Fatalf("failed to save conversation: %s", err)
+	}
+	err := s.SaveBotConversation(&types.BotConversation{
+		Bot:        "slack-eu",
+		Identifier: "deployment/default/wd:1.1.0",
+		Expires:    time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("failed to save conversation: %s", err)
+	}
+
+	stored, err := s.GetBotConversation("slack", "deployment/default/wd:1.1.0")
+	if err != nil {
+		t.Fatalf("failed to get conversation: %s", err)
+	}
+	if stored.TS != "1.1" || stored.GetApproval().VotesRequired != 2 {
+		t.Errorf("unexpected conversation: %+v", stored)
+	}
+
+	// stored conversations are copies
+	stored.Final = true
+	again, _ := s.GetBotConversation("slack", "deployment/default/wd:1.1.0")
+	if again.Final {
+		t.Errorf("conversation changed without saving")
+	}
+
+	list, _ := s.ListBotConversations("slack")
+	if len(list) != 1 {
+		t.Errorf("expected 1 conversation of the bot, got %d", len(list))
+	}
+
+	if err := s.DeleteBotConversations(time.Now()); err != nil {
+		t.Fatalf("failed to delete conversations: %s", err)
+	}
+	if _, err := s.GetBotConversation("slack-eu", "deployment/default/wd:1.1.0"); err != store.ErrRecordNotFound {
+		t.Errorf("expected expired conversation to be deleted, got: %v", err)
+	}
+	if _, err := s.GetBotConversation("slack", "deployment/default/wd:1.1.0"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
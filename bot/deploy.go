@@ -0,0 +1,194 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/provider/kubernetes"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/i18n"
+	"github.com/keel-hq/keel/util/image"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DeployTriggerName - trigger name of the events that are created by the deploy command
+const DeployTriggerName = "manual"
+
+// rollout status is checked until it's either complete or timeout is reached,
+// timeout also covers the time when update is waiting for approvals
+var (
+	deployRolloutTimeout  = 10 * time.Minute
+	deployRolloutInterval = 10 * time.Second
+)
+
+func init() {
+	RegisterCommand(&Command{
+		Name:        "deploy",
+		Args:        []Arg{{Name: "namespace/name"}, {Name: "tag"}, {Name: "image", Optional: true}},
+		Description: "deploy specific tag, bypasses update policy but still requires approvals",
+		Handler:     deployCommandHandler,
+	})
+}
+
+func deployCommandHandler(bm *BotManager, req *CommandRequest) string {
+	if bm.providers == nil {
+		return i18n.T("deploy is not available")
+	}
+
+	identifier := req.Arg("namespace/name")
+	namespace, name, ok := parseWorkloadIdentifier(identifier)
+	if !ok {
+		return i18n.T("invalid workload '%s', expected format: <namespace>/<name>", identifier)
+	}
+
+	tag := req.Arg("tag")
+
+	tracked, err := bm.providers.TrackedImages()
+	if err != nil {
+		return i18n.T("got error while fetching tracked images: %s", err)
+	}
+
+	ref, err := FindDeployImage(tracked, namespace, name, req.Arg("image"))
+	if err != nil {
+		return err.Error()
+	}
+
+	err = bm.providers.Submit(types.Event{
+		Repository: types.Repository{
+			Name: ref.Repository(),
+			Tag:  tag,
+		},
+		CreatedAt:   time.Now(),
+		TriggerName: DeployTriggerName,
+		Target:      identifier,
+	})
+	if err != nil {
+		return i18n.T("failed to deploy '%s': %s", identifier, err)
+	}
+
+	log.WithFields(log.Fields{
+		"identifier": identifier,
+		"image":      ref.Repository(),
+		"previous":   ref.Tag(),
+		"new":        tag,
+		"user":       req.Message.User,
+	}).Info("bot: manual deploy submitted")
+
+	if bm.k8sImplementer != nil && req.Respond != nil {
+		go waitForRollout(bm.k8sImplementer, namespace, name, ref.Repository(), tag, req.Reply)
+	}
+
+	return i18n.T("deploying %s: %s %s -> %s (approvals still apply)", identifier, ref.Repository(), ref.Tag(), tag)
+}
+
+// FindDeployImage - finds tracked image of the workload, image name is only required
+// when workload has more than one tracked image
+func FindDeployImage(tracked []*types.TrackedImage, namespace, name, imageName string) (*image.Reference, error) {
+	img, err := findTrackedImage(tracked, namespace, name, imageName)
+	if err != nil {
+		return nil, err
+	}
+	return img.Image, nil
+}
+
+func findTrackedImage(tracked []*types.TrackedImage, namespace, name, imageName string) (*types.TrackedImage, error) {
+	var candidates []*types.TrackedImage
+	for _, img := range tracked {
+		if img.Provider != kubernetes.ProviderName || img.Namespace != namespace || img.Meta["name"] != name {
+			continue
+		}
+		candidates = append(candidates, img)
+	}
+
+	if len(candidates) == 0 {
+		return nil, errors.New(i18n.T("workload '%s/%s' not found or is not tracked by keel", namespace, name))
+	}
+
+	if imageName == "" {
+		if len(candidates) > 1 {
+			var names []string
+			for _, c := range candidates {
+				names = append(names, c.Image.Repository())
+			}
+			return nil, errors.New(i18n.T("workload '%s/%s' has multiple images, specify one of: %s", namespace, name, strings.Join(names, ", ")))
+		}
+		return candidates[0], nil
+	}
+
+	want, err := image.Parse(imageName)
+	if err != nil {
+		return nil, errors.New(i18n.T("invalid image '%s': %s", imageName, err))
+	}
+
+	for _, c := range candidates {
+		if c.Image.Repository() == want.Repository() {
+			return c, nil
+		}
+	}
+
+	return nil, errors.New(i18n.T("image '%s' not found in workload '%s/%s'", imageName, namespace, name))
+}
+
+// waitForRollout - reports deployment rollout result back to the chat
+func waitForRollout(implementer kubernetes.Implementer, namespace, name, repository, tag string, reply func(string)) {
+	deadline := time.Now().Add(deployRolloutTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(deployRolloutInterval)
+
+		done, status, err := deploymentRolloutStatus(implementer, namespace, name, repository, tag)
+		if err != nil {
+			reply(i18n.T("failed to check rollout status of %s/%s: %s", namespace, name, err))
+			return
+		}
+		if done {
+			reply(i18n.T("%s/%s rolled out %s:%s, %s", namespace, name, repository, tag, status))
+			return
+		}
+	}
+
+	reply(i18n.T("%s/%s rollout of %s:%s is not complete yet (it might be waiting for approvals)", namespace, name, repository, tag))
+}
+
+func deploymentRolloutStatus(implementer kubernetes.Implementer, namespace, name, repository, tag string) (done bool, status string, err error) {
+	deps, err := implementer.Deployments(namespace)
+	if err != nil {
+		return false, "", err
+	}
+
+	for _, d := range deps.Items {
+		if d.Name != name {
+			continue
+		}
+
+		updated := false
+		for _, c := range d.Spec.Template.Spec.Containers {
+			ref, err := image.Parse(c.Image)
+			if err != nil {
+				continue
+			}
+			if ref.Repository() == repository && ref.Tag() == tag {
+				updated = true
+			}
+		}
+		if !updated {
+			return false, "", nil
+		}
+
+		var replicas int32 = 1
+		if d.Spec.Replicas != nil {
+			replicas = *d.Spec.Replicas
+		}
+
+		status = fmt.Sprintf("ready: %d/%d", d.Status.AvailableReplicas, replicas)
+		done = d.Status.ObservedGeneration >= d.Generation &&
+			d.Status.UpdatedReplicas == replicas &&
+			d.Status.AvailableReplicas == replicas
+		return done, status, nil
+	}
+
+	// not a deployment, can't check rollout status
+	return true, "resource is not a deployment, rollout status not available", nil
+}
@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+)
+
+type fakeProviders struct {
+	submitted []types.Event
+	images    []*types.TrackedImage
+}
+
+func (p *fakeProviders) Submit(event types.Event) error {
+	p.submitted = append(p.submitted, event)
+	return nil
+}
+
+func (p *fakeProviders) TrackedImages() ([]*types.TrackedImage, error) {
+	return p.images, nil
+}
+
+func (p *fakeProviders) List() []string {
+	return []string{"kubernetes"}
+}
+
+func (p *fakeProviders) Stop() {}
+
+func trackedImage(namespace, name, img string) *types.TrackedImage {
+	ref, _ := image.Parse(img)
+	return &types.TrackedImage{
+		Image:     ref,
+		Provider:  "kubernetes",
+		Namespace: namespace,
+		Meta:      map[string]string{"name": name},
+	}
+}
+
+func TestDeployCommand(t *testing.T) {
+	fp := &fakeProviders{
+		images: []*types.TrackedImage{
+			trackedImage("default", "wd", "karolisr/webhook-demo:0.0.10"),
+			trackedImage("default", "other", "karolisr/other:1.0.0"),
+		},
+	}
+	bm := &BotManager{providers: fp}
+
+	resp := bm.handleBotMessage(&BotMessage{Message: "deploy default/wd 0.0.15", User: "karolis"}, nil)
+	if !strings.Contains(resp, "0.0.10 -> 0.0.15") {
+		t.Errorf("unexpected response: %s", resp)
+	}
+
+	if len(fp.submitted) != 1 {
+		t.Fatalf("expected 1 submitted event, got: %d", len(fp.submitted))
+	}
+
+	event := fp.submitted[0]
+	if event.Target != "default/wd" {
+		t.Errorf("unexpected target: %s", event.Target)
+	}
+	if event.Repository.Name != "index.docker.io/karolisr/webhook-demo" {
+		t.Errorf("unexpected repository: %s", event.Repository.Name)
+	}
+	if event.Repository.Tag != "0.0.15" {
+		t.Errorf("unexpected tag: %s", event.Repository.Tag)
+	}
+}
+
+func TestDeployCommandNotTracked(t *testing.T) {
+	fp := &fakeProviders{}
+	bm := &BotManager{providers: fp}
+
+	resp := bm.handleBotMessage(&BotMessage{Message: "deploy default/wd 0.0.15", User: "karolis"}, nil)
+	if !strings.Contains(resp, "not tracked") {
+		t.Errorf("unexpected response: %s", resp)
+	}
+	if len(fp.submitted) != 0 {
+		t.Errorf("didn't expect submitted events")
+	}
+}
+
+func TestFindDeployImageMultiple(t *testing.T) {
+	tracked := []*types.TrackedImage{
+		trackedImage("default", "wd", "karolisr/webhook-demo:0.0.10"),
+		trackedImage("default", "wd", "karolisr/sidecar:1.0.0"),
+	}
+
+	_, err := FindDeployImage(tracked, "default", "wd", "")
+	if err == nil || !strings.Contains(err.Error(), "multiple images") {
+		t.Errorf("expected multiple images error, got: %v", err)
+	}
+
+	ref, err := FindDeployImage(tracked, "default", "wd", "karolisr/sidecar")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ref.Tag() != "1.0.0" {
+		t.Errorf("unexpected image: %s", ref.Remote())
+	}
+}
@@ -2,10 +2,10 @@ package bot
 
 import (
 	"bytes"
-	"fmt"
 
 	"github.com/keel-hq/keel/bot/formatter"
 	"github.com/keel-hq/keel/provider/kubernetes"
+	"github.com/keel-hq/keel/util/i18n"
 
 	apps_v1 "k8s.io/api/apps/v1"
 
@@ -18,6 +18,17 @@ type Filter struct {
 	All       bool // keel or not
 }
 
+func init() {
+	RegisterCommand(&Command{
+		Name:        "get deployments",
+		Description: "get a list of all deployments",
+		Handler: func(bm *BotManager, req *CommandRequest) string {
+			log.Info("HandleCommand: getting deployments")
+			return DeploymentsResponse(Filter{}, bm.k8sImplementer)
+		},
+	})
+}
+
 // deployments - gets all deployments
 func deployments(k8sImplementer kubernetes.Implementer) ([]apps_v1.Deployment, error) {
 	deploymentLists := []*apps_v1.DeploymentList{}
@@ -53,7 +64,7 @@ func deployments(k8sImplementer kubernetes.Implementer) ([]apps_v1.Deployment, e
 func DeploymentsResponse(filter Filter, k8sImplementer kubernetes.Implementer) string {
 	deps, err := deployments(k8sImplementer)
 	if err != nil {
-		return fmt.Sprintf("got error while fetching deployments: %s", err)
+		return i18n.T("got error while fetching deployments: %s", err)
 	}
 	log.Debugf("%d deployments fetched, formatting", len(deps))
 	buf := &bytes.Buffer{}
@@ -65,7 +76,7 @@ func DeploymentsResponse(filter Filter, k8sImplementer kubernetes.Implementer) s
 	err = formatter.DeploymentWrite(DeploymentCtx, convertToInternal(deps))
 
 	if err != nil {
-		return fmt.Sprintf(" got error while formatting deployments: %s", err)
+		return i18n.T(" got error while formatting deployments: %s", err)
 	}
 
 	return buf.String()
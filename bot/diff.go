@@ -0,0 +1,208 @@
+package bot
+
+import (
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/i18n"
+	"github.com/keel-hq/keel/util/image"
+)
+
+// DiffRegistry - registry client used by the diff command to look up digests
+// and image labels
+type DiffRegistry interface {
+	Digest(opts registry.Opts) (string, error)
+	Config(opts registry.Opts) (*registry.ImageConfig, error)
+}
+
+var (
+	diffRegistryM sync.RWMutex
+	diffRegistry  DiffRegistry
+)
+
+// SetRegistryClient - sets registry client of the diff command, without it
+// diff only compares tags and the policy decision
+func SetRegistryClient(client DiffRegistry) {
+	diffRegistryM.Lock()
+	diffRegistry = client
+	diffRegistryM.Unlock()
+}
+
+func getDiffRegistry() DiffRegistry {
+	diffRegistryM.RLock()
+	defer diffRegistryM.RUnlock()
+	return diffRegistry
+}
+
+// diffLabel - image label shown by the diff command, the first label of keys
+// that is set on the image is used
+type diffLabel struct {
+	title string
+	keys  []string
+}
+
+var diffLabels = []diffLabel{
+	{title: "Git SHA", keys: []string{"org.opencontainers.image.revision", "org.label-schema.vcs-ref"}},
+	{title: "Source", keys: []string{"org.opencontainers.image.source", "org.label-schema.vcs-url"}},
+	{title: "Build URL", keys: []string{"build-url", "org.label-schema.build-url"}},
+}
+
+func init() {
+	RegisterCommand(&Command{
+		Name:        "diff",
+		Args:        []Arg{{Name: "namespace/name"}, {Name: "tag"}, {Name: "image", Optional: true}},
+		Description: "show what updating workload to the tag would change",
+		Handler:     diffCommandHandler,
+	})
+}
+
+func diffCommandHandler(bm *BotManager, req *CommandRequest) string {
+	if bm.providers == nil {
+		return i18n.T("diff is not available")
+	}
+
+	identifier := req.Arg("namespace/name")
+	namespace, name, ok := parseWorkloadIdentifier(identifier)
+	if !ok {
+		return i18n.T("invalid workload '%s', expected format: <namespace>/<name>", identifier)
+	}
+
+	tracked, err := bm.providers.TrackedImages()
+	if err != nil {
+		return i18n.T("got error while fetching tracked images: %s", err)
+	}
+
+	img, err := findTrackedImage(tracked, namespace, name, req.Arg("image"))
+	if err != nil {
+		return err.Error()
+	}
+
+	return req.ReplyStructured(DiffResponse(getDiffRegistry(), identifier, img, req.Arg("tag")))
+}
+
+// DiffResponse - compares current image of the tracked workload with the
+// candidate tag, registry is optional
+func DiffResponse(reg DiffRegistry, identifier string, img *types.TrackedImage, tag string) *Response {
+	current := img.Image
+	candidate, err := image.Parse(current.Repository() + ":" + tag)
+	if err != nil {
+		return &Response{Title: i18n.T("invalid tag '%s': %s", tag, err)}
+	}
+
+	currentItem := ResponseItem{
+		Title:  i18n.T("Current: %s", current.Remote()),
+		Color:  types.LevelInfo.Color(),
+		Fields: []ResponseField{{Title: i18n.T("Tag"), Value: current.Tag(), Short: true}},
+	}
+	candidateItem := ResponseItem{
+		Title:  i18n.T("Candidate: %s", candidate.Remote()),
+		Color:  types.LevelSuccess.Color(),
+		Fields: []ResponseField{{Title: i18n.T("Tag"), Value: candidate.Tag(), Short: true}},
+	}
+
+	var candidateDigest string
+	if reg != nil {
+		currentFields, _ := imageDiffFields(reg, img, current)
+		currentItem.Fields = append(currentItem.Fields, currentFields...)
+
+		candidateFields, digest := imageDiffFields(reg, img, candidate)
+		candidateItem.Fields = append(candidateItem.Fields, candidateFields...)
+		candidateDigest = digest
+	}
+
+	return &Response{
+		Title:  i18n.T("Diff of %s: %s %s -> %s", identifier, current.Repository(), current.Tag(), candidate.Tag()),
+		Items:  []ResponseItem{currentItem, candidateItem},
+		Footer: policyDecision(img, current, candidate, candidateDigest),
+	}
+}
+
+// imageDiffFields - digest and labels of the image, registry errors are shown
+// in place of the values
+func imageDiffFields(reg DiffRegistry, img *types.TrackedImage, ref *image.Reference) ([]ResponseField, string) {
+	creds := credentialshelper.GetCredentials(&types.TrackedImage{
+		Image:     ref,
+		Namespace: img.Namespace,
+		Secrets:   img.Secrets,
+		Provider:  img.Provider,
+	})
+	opts := registry.Opts{
+		Registry: ref.Scheme() + "://" + ref.Registry(),
+		Name:     ref.ShortName(),
+		Tag:      ref.Tag(),
+		Username: creds.Username,
+		Password: creds.Password,
+	}
+	if ref.Digest() != "" {
+		opts.Tag = ref.Digest()
+	}
+
+	digest := ref.Digest()
+	if digest == "" {
+		var err error
+		digest, err = reg.Digest(opts)
+		if err != nil {
+			return []ResponseField{{Title: i18n.T("Digest"), Value: i18n.T("not available: %s", err)}}, ""
+		}
+	}
+	fields := []ResponseField{{Title: i18n.T("Digest"), Value: digest}}
+
+	config, err := reg.Config(opts)
+	if err != nil {
+		return append(fields, ResponseField{Title: i18n.T("Labels"), Value: i18n.T("not available: %s", err)}), digest
+	}
+	if !config.Created.IsZero() {
+		fields = append(fields, ResponseField{Title: i18n.T("Created"), Value: config.Created.Format(time.RFC3339), Short: true})
+	}
+	for _, l := range diffLabels {
+		for _, key := range l.keys {
+			if v := config.Labels[key]; v != "" {
+				fields = append(fields, ResponseField{Title: i18n.T(l.title), Value: v, Short: true})
+				break
+			}
+		}
+	}
+	return fields, digest
+}
+
+// policyDecision - whether keel would update the workload to the candidate on its own
+func policyDecision(img *types.TrackedImage, current, candidate *image.Reference, candidateDigest string) string {
+	var decision string
+	plc, ok := img.Policy.(policy.Policy)
+	switch {
+	case img.Policy == nil || (ok && plc.Type() == policy.PolicyTypeNone):
+		decision = i18n.T("Workload has no update policy, keel wouldn't update it automatically.")
+	default:
+		var update bool
+		var err error
+		if ok {
+			update, err = policy.ShouldUpdate(plc, current.Tag(), candidate.Tag(), policy.Metadata{
+				Registry:   candidate.Registry(),
+				Repository: candidate.Repository(),
+				Digest:     candidateDigest,
+			})
+		} else {
+			update, err = img.Policy.ShouldUpdate(current.Tag(), candidate.Tag())
+		}
+		switch {
+		case err != nil:
+			decision = i18n.T("Policy %s can't compare tags: %s", img.Policy.Name(), err)
+		case update:
+			decision = i18n.T("Policy %s allows the update.", img.Policy.Name())
+		default:
+			decision = i18n.T("Policy %s wouldn't update to the candidate, it can still be deployed with the deploy command.", img.Policy.Name())
+		}
+	}
+
+	switch {
+	case img.FreezeReason != "":
+		decision += " " + i18n.T("Updates are frozen: %s.", img.FreezeReason)
+	case img.Paused:
+		decision += " " + i18n.T("Automatic updates are paused.")
+	}
+	return decision
+}
@@ -0,0 +1,101 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+)
+
+type fakeDiffRegistry struct {
+	digests map[string]string
+	configs map[string]*registry.ImageConfig
+}
+
+func (r *fakeDiffRegistry) Digest(opts registry.Opts) (string, error) {
+	if d, ok := r.digests[opts.Tag]; ok {
+		return d, nil
+	}
+	return "", errors.New("manifest unknown")
+}
+
+func (r *fakeDiffRegistry) Config(opts registry.Opts) (*registry.ImageConfig, error) {
+	if c, ok := r.configs[opts.Tag]; ok {
+		return c, nil
+	}
+	return nil, errors.New("manifest unknown")
+}
+
+func TestDiffResponse(t *testing.T) {
+	img := trackedImage("default", "wd", "karolisr/webhook-demo:0.0.10")
+	img.Policy = policy.NewSemverPolicy(policy.SemverPolicyTypeMinor)
+
+	reg := &fakeDiffRegistry{
+		digests: map[string]string{
+			"0.0.10": "sha256:aaa",
+			"0.0.15": "sha256:bbb",
+		},
+		configs: map[string]*registry.ImageConfig{
+			"0.0.15": {
+				Created: time.Date(2018, 10, 1, 10, 20, 30, 0, time.UTC),
+				Labels: map[string]string{
+					"org.opencontainers.image.revision": "abc123",
+					"build-url":                         "https://ci.example.com/builds/42",
+				},
+			},
+		},
+	}
+
+	resp := DiffResponse(reg, "default/wd", img, "0.0.15").String()
+	for _, expected := range []string{
+		"0.0.10 -> 0.0.15",
+		"sha256:aaa",
+		"sha256:bbb",
+		"Git SHA: abc123",
+		"Build URL: https://ci.example.com/builds/42",
+		"Created: 2018-10-01T10:20:30Z",
+		"allows the update",
+	} {
+		if !strings.Contains(resp, expected) {
+			t.Errorf("expected response to contain '%s', got: %s", expected, resp)
+		}
+	}
+	if !strings.Contains(resp, "Labels: not available") {
+		t.Errorf("expected missing labels of the current image, got: %s", resp)
+	}
+}
+
+func TestDiffResponsePolicyRejects(t *testing.T) {
+	img := trackedImage("default", "wd", "karolisr/webhook-demo:0.0.10")
+	img.Policy = policy.NewSemverPolicy(policy.SemverPolicyTypePatch)
+	img.Paused = true
+
+	resp := DiffResponse(nil, "default/wd", img, "0.1.0").String()
+	if !strings.Contains(resp, "wouldn't update to the candidate") {
+		t.Errorf("expected policy to reject the candidate, got: %s", resp)
+	}
+	if !strings.Contains(resp, "paused") {
+		t.Errorf("expected paused note, got: %s", resp)
+	}
+	if strings.Contains(resp, "Digest") {
+		t.Errorf("didn't expect digests without registry client, got: %s", resp)
+	}
+}
+
+func TestDiffCommandNotTracked(t *testing.T) {
+	fp := &fakeProviders{
+		images: []*types.TrackedImage{
+			trackedImage("default", "other", "karolisr/other:1.0.0"),
+		},
+	}
+	bm := &BotManager{providers: fp}
+
+	resp := bm.handleBotMessage(&BotMessage{Message: "diff default/wd 0.0.15", User: "karolis"}, nil)
+	if !strings.Contains(resp, "not tracked") {
+		t.Errorf("unexpected response: %s", resp)
+	}
+}
@@ -0,0 +1,110 @@
+package formatter
+
+import (
+	"strings"
+	"time"
+)
+
+// UpdateRecord - internal update history entry representation
+type UpdateRecord struct {
+	CreatedAt   time.Time
+	Resource    string
+	Image       string
+	PreviousTag string
+	NewTag      string
+	Approvers   []string
+	Status      string
+}
+
+// Formatter headers
+const (
+	defaultHistoryQuietFormat = "{{.Resource}} {{.Delta}}"
+	defaultHistoryTableFormat = "table {{.Created}}\t{{.Resource}}\t{{.Image}}\t{{.Delta}}\t{{.Approvers}}\t{{.Status}}"
+
+	HistoryCreatedHeader   = "CREATED"
+	HistoryResourceHeader  = "RESOURCE"
+	HistoryImageHeader     = "IMAGE"
+	HistoryDeltaHeader     = "DELTA"
+	HistoryApproversHeader = "APPROVED BY"
+	HistoryStatusHeader    = "STATUS"
+)
+
+// NewHistoryFormat returns a format for use with an update history Context
+func NewHistoryFormat(source string, quiet bool) Format {
+	switch source {
+	case TableFormatKey:
+		if quiet {
+			return defaultHistoryQuietFormat
+		}
+		return defaultHistoryTableFormat
+	case RawFormatKey:
+		if quiet {
+			return `resource: {{.Resource}}`
+		}
+		return `resource: {{.Resource}}\n`
+	}
+	return Format(source)
+}
+
+// HistoryWrite writes formatted update history using the Context
+func HistoryWrite(ctx Context, records []UpdateRecord) error {
+	render := func(format func(subContext subContext) error) error {
+		for _, record := range records {
+			if err := format(&HistoryContext{v: record}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return ctx.Write(&HistoryContext{}, render)
+}
+
+// HistoryContext - update history context is a container for each line
+type HistoryContext struct {
+	HeaderContext
+	v UpdateRecord
+}
+
+// MarshalJSON - marshal to json (inspect)
+func (c *HistoryContext) MarshalJSON() ([]byte, error) {
+	return marshalJSON(c)
+}
+
+// Created - print update time
+func (c *HistoryContext) Created() string {
+	c.AddHeader(HistoryCreatedHeader)
+	return c.v.CreatedAt.Format(time.RFC3339)
+}
+
+// Resource - print resource identifier
+func (c *HistoryContext) Resource() string {
+	c.AddHeader(HistoryResourceHeader)
+	return c.v.Resource
+}
+
+// Image - print updated image
+func (c *HistoryContext) Image() string {
+	c.AddHeader(HistoryImageHeader)
+	return c.v.Image
+}
+
+// Delta - print previous and new tags
+func (c *HistoryContext) Delta() string {
+	c.AddHeader(HistoryDeltaHeader)
+	return c.v.PreviousTag + " -> " + c.v.NewTag
+}
+
+// Approvers - print approvers
+func (c *HistoryContext) Approvers() string {
+	c.AddHeader(HistoryApproversHeader)
+	if len(c.v.Approvers) == 0 {
+		return "-"
+	}
+	return strings.Join(c.v.Approvers, ", ")
+}
+
+// Status - print update status
+func (c *HistoryContext) Status() string {
+	c.AddHeader(HistoryStatusHeader)
+	return c.v.Status
+}
@@ -0,0 +1,118 @@
+package formatter
+
+import (
+	"strconv"
+)
+
+// TrackedImage - internal tracked image representation
+type TrackedImage struct {
+	Namespace string
+	Name      string
+	Image     string
+	Policy    string
+	Trigger   string
+	Provider  string
+	Paused    bool
+	// Frozen - change freeze reason
+	Frozen string
+}
+
+// Formatter headers
+const (
+	defaultTrackedQuietFormat = "{{.Image}}"
+	defaultTrackedTableFormat = "table {{.Namespace}}\t{{.Name}}\t{{.Image}}\t{{.Policy}}\t{{.Trigger}}\t{{.Provider}}\t{{.Paused}}"
+
+	TrackedNamespaceHeader = "NAMESPACE"
+	TrackedNameHeader      = "NAME"
+	TrackedImageHeader     = "IMAGE"
+	TrackedPolicyHeader    = "POLICY"
+	TrackedTriggerHeader   = "TRIGGER"
+	TrackedProviderHeader  = "PROVIDER"
+	TrackedPausedHeader    = "PAUSED"
+)
+
+// NewTrackedFormat returns a format for use with a tracked image Context
+func NewTrackedFormat(source string, quiet bool) Format {
+	switch source {
+	case TableFormatKey:
+		if quiet {
+			return defaultTrackedQuietFormat
+		}
+		return defaultTrackedTableFormat
+	case RawFormatKey:
+		if quiet {
+			return `image: {{.Image}}`
+		}
+		return `image: {{.Image}}\n`
+	}
+	return Format(source)
+}
+
+// TrackedWrite writes formatted tracked images using the Context
+func TrackedWrite(ctx Context, images []TrackedImage) error {
+	render := func(format func(subContext subContext) error) error {
+		for _, img := range images {
+			if err := format(&TrackedContext{v: img}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return ctx.Write(&TrackedContext{}, render)
+}
+
+// TrackedContext - tracked image context is a container for each line
+type TrackedContext struct {
+	HeaderContext
+	v TrackedImage
+}
+
+// MarshalJSON - marshal to json (inspect)
+func (c *TrackedContext) MarshalJSON() ([]byte, error) {
+	return marshalJSON(c)
+}
+
+// Namespace - print namespace
+func (c *TrackedContext) Namespace() string {
+	c.AddHeader(TrackedNamespaceHeader)
+	return c.v.Namespace
+}
+
+// Name - print resource name
+func (c *TrackedContext) Name() string {
+	c.AddHeader(TrackedNameHeader)
+	return c.v.Name
+}
+
+// Image - print image
+func (c *TrackedContext) Image() string {
+	c.AddHeader(TrackedImageHeader)
+	return c.v.Image
+}
+
+// Policy - print policy
+func (c *TrackedContext) Policy() string {
+	c.AddHeader(TrackedPolicyHeader)
+	return c.v.Policy
+}
+
+// Trigger - print trigger
+func (c *TrackedContext) Trigger() string {
+	c.AddHeader(TrackedTriggerHeader)
+	return c.v.Trigger
+}
+
+// Provider - print provider
+func (c *TrackedContext) Provider() string {
+	c.AddHeader(TrackedProviderHeader)
+	return c.v.Provider
+}
+
+// Paused - print whether updates are paused, frozen updates include the reason
+func (c *TrackedContext) Paused() string {
+	c.AddHeader(TrackedPausedHeader)
+	if c.v.Frozen != "" {
+		return "frozen (" + c.v.Frozen + ")"
+	}
+	return strconv.FormatBool(c.v.Paused)
+}
@@ -0,0 +1,22 @@
+package bot
+
+// HealthChecker - bots that can report whether they are connected to the
+// chat service
+type HealthChecker interface {
+	Healthy() error
+}
+
+// Health - connection status of the started bots that report it, keyed by
+// bot name
+func Health() map[string]error {
+	botsM.RLock()
+	defer botsM.RUnlock()
+
+	status := make(map[string]error)
+	for name := range teardowns {
+		if hc, ok := bots[name].(HealthChecker); ok {
+			status[name] = hc.Healthy()
+		}
+	}
+	return status
+}
@@ -4,9 +4,13 @@ import (
 	"fmt"
 
 	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/templates"
 )
 
 func (b *Bot) RequestApproval(req *types.Approval) error {
+	if msg, ok := templates.RenderMessage(templates.MessageApprovalRequest, req); ok {
+		return b.postMessage(formatAsSnippet(msg.String()))
+	}
 	msg := fmt.Sprintf(ApprovalRequiredTempl,
 		req.Message, req.Identifier, req.Identifier,
 		req.VotesReceived, req.VotesRequired, req.Delta(), req.Identifier,
@@ -163,7 +163,8 @@ func (b *Bot) trimBot(msg string) string {
 	msg = re.ReplaceAllString(msg, "")
 	msg = strings.Trim(msg, "\n")
 	msg = strings.TrimSpace(msg)
-	return strings.ToLower(msg)
+	msg, _ = bot.TrimPrefix(strings.ToLower(msg))
+	return msg
 }
 
 func (b *Bot) isBotMessage(message *h.Message) bool {
@@ -1,9 +1,11 @@
 package hipchat
 
 import (
+	"fmt"
 	"os"
 	"regexp"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -11,7 +13,7 @@ import (
 
 	"github.com/keel-hq/keel/approvals"
 	b "github.com/keel-hq/keel/bot"
-	"github.com/keel-hq/keel/cache/memory"
+	"github.com/keel-hq/keel/pkg/store/sql"
 	"github.com/keel-hq/keel/provider/kubernetes"
 	"github.com/keel-hq/keel/types"
 
@@ -96,7 +98,7 @@ func NewBot(k8sImplementer kubernetes.Implementer,
 	os.Setenv("HIPCHAT_CONNECTION_ATTEMPTS", "0")
 
 	b.RegisterBot("fakechat", fakeBot)
-	b.Run(k8sImplementer, approvalsManager)
+	b.Run(k8sImplementer, approvalsManager, nil, nil)
 	return fakeBot
 }
 
@@ -108,8 +110,7 @@ func TestHelpCommand(t *testing.T) {
 	f8s := &testutil.FakeK8sImplementer{}
 	fi := &fakeXmppImplementer{}
 	fi.messages = make(chan *h.Message)
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 
 	NewBot(f8s, am, fi)
 	defer b.Stop()
@@ -138,8 +139,7 @@ func TestBotAproval(t *testing.T) {
 	f8s := &testutil.FakeK8sImplementer{}
 	fi := &fakeXmppImplementer{}
 	fi.messages = make(chan *h.Message)
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 
 	NewBot(f8s, am, fi)
 	defer b.Stop()
@@ -191,7 +191,7 @@ func TestBotAproval(t *testing.T) {
 	}
 	resp := trimSpaces(fi.postedMessages[3].text)
 
-	if !strings.Contains(resp, "k8s/project/repo:1.2.3 2.3.4 -> 3.4.5 1/1 false") {
+	if !strings.Contains(resp, "k8s/project/repo:1.2.3 Delta: 2.3.4 -> 3.4.5 Votes: 1/1 Status: approved") {
 		t.Errorf("expected to find message, but got: %s", resp)
 	}
 }
@@ -200,8 +200,7 @@ func TestBotReject(t *testing.T) {
 	f8s := &testutil.FakeK8sImplementer{}
 	fi := &fakeXmppImplementer{}
 	fi.messages = make(chan *h.Message)
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 
 	NewBot(f8s, am, fi)
 	defer b.Stop()
@@ -253,7 +252,7 @@ func TestBotReject(t *testing.T) {
 	}
 	resp := trimSpaces(fi.postedMessages[3].text)
 
-	if !strings.Contains(resp, "k8s/project/repo:1.2.3 2.3.4 -> 3.4.5 0/1 true") {
+	if !strings.Contains(resp, "k8s/project/repo:1.2.3 Delta: 2.3.4 -> 3.4.5 Votes: 0/1 Status: rejected") {
 		t.Errorf("expected to find message, but got: %s", resp)
 	}
 }
@@ -265,3 +264,15 @@ func trimSpaces(input string) string {
 	final = reInsideWhtsp.ReplaceAllString(final, " ")
 	return final
 }
+
+var approverDatabases int32
+
+// approver - approvals manager with its own in-memory sqlite store
+func approver() *approvals.DefaultManager {
+	uri := fmt.Sprintf("file:approver%d?mode=memory&cache=shared", atomic.AddInt32(&approverDatabases, 1))
+	store, err := sql.New(sql.Opts{DatabaseType: sql.DatabaseTypeSQLite, URI: uri})
+	if err != nil {
+		panic(err)
+	}
+	return approvals.New(&approvals.Opts{Store: store})
+}
@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+
+	"github.com/keel-hq/keel/bot/formatter"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/i18n"
+	"github.com/keel-hq/keel/util/image"
+)
+
+// HistoryDefaultLimit - default number of update history entries returned
+const HistoryDefaultLimit = 10
+
+func init() {
+	RegisterCommand(&Command{
+		Name:        "history",
+		Args:        []Arg{{Name: "image or namespace/name"}, {Name: "limit", Optional: true}},
+		Description: "get recent updates of an image or a workload",
+		Handler: func(bm *BotManager, req *CommandRequest) string {
+			return HistoryResponse(bm, req.Arg("image or namespace/name"), req.Arg("limit"))
+		},
+	})
+}
+
+// HistoryResponse - formats update history, target is either workload (<namespace>/<name>)
+// or an image, workloads are checked first
+func HistoryResponse(bm *BotManager, target, limitStr string) string {
+	if bm.store == nil {
+		return i18n.T("update history is not available")
+	}
+
+	limit := HistoryDefaultLimit
+	if limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 {
+			return i18n.T("invalid limit '%s', expected a positive number", limitStr)
+		}
+		limit = l
+	}
+
+	var records []*types.UpdateRecord
+	var err error
+
+	namespace, name, ok := parseWorkloadIdentifier(target)
+	if ok {
+		records, err = bm.store.ListUpdateRecords(&types.UpdateRecordQuery{
+			Namespace: namespace,
+			Name:      name,
+			Limit:     limit,
+		})
+		if err != nil {
+			return i18n.T("got error while fetching update history: %s", err)
+		}
+	}
+
+	if len(records) == 0 {
+		ref, parseErr := image.Parse(target)
+		if parseErr == nil {
+			records, err = bm.store.ListUpdateRecords(&types.UpdateRecordQuery{
+				Image: ref.Repository(),
+				Limit: limit,
+			})
+			if err != nil {
+				return i18n.T("got error while fetching update history: %s", err)
+			}
+		}
+	}
+
+	if len(records) == 0 {
+		return i18n.T("no updates found for '%s'.", target)
+	}
+
+	var formatted []formatter.UpdateRecord
+	for _, r := range records {
+		approvers := r.GetApprovers()
+		sort.Strings(approvers)
+		formatted = append(formatted, formatter.UpdateRecord{
+			CreatedAt:   r.CreatedAt,
+			Resource:    r.Identifier,
+			Image:       r.Image,
+			PreviousTag: r.PreviousTag,
+			NewTag:      r.NewTag,
+			Approvers:   approvers,
+			Status:      r.Status,
+		})
+	}
+
+	buf := &bytes.Buffer{}
+	ctx := formatter.Context{
+		Output: buf,
+		Format: formatter.NewHistoryFormat(formatter.TableFormatKey, false),
+	}
+	err = formatter.HistoryWrite(ctx, formatted)
+	if err != nil {
+		return i18n.T("got error while formatting update history: %s", err)
+	}
+
+	return buf.String()
+}
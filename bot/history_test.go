@@ -0,0 +1,53 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestHistoryCommand(t *testing.T) {
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	for _, tag := range []string{"0.0.11", "0.0.12", "0.0.13"} {
+		_, err := store.CreateUpdateRecord(&types.UpdateRecord{
+			CreatedAt:    time.Now(),
+			Provider:     "kubernetes",
+			ResourceKind: "deployment",
+			Identifier:   "deployment/default/wd",
+			Namespace:    "default",
+			Name:         "wd",
+			Image:        "index.docker.io/karolisr/webhook-demo",
+			PreviousTag:  "0.0.10",
+			NewTag:       tag,
+			Approvers:    types.JSONB{"karolis": time.Now()},
+			Status:       types.UpdateStatusSuccess,
+		})
+		if err != nil {
+			t.Fatalf("failed to create record: %s", err)
+		}
+	}
+
+	bm := &BotManager{store: store}
+
+	resp := bm.handleBotMessage(&BotMessage{Message: "history default/wd"}, nil)
+	if strings.Count(resp, "deployment/default/wd") != 3 {
+		t.Errorf("expected 3 records, got: %s", resp)
+	}
+	if !strings.Contains(resp, "karolis") {
+		t.Errorf("expected approver in response: %s", resp)
+	}
+
+	resp = bm.handleBotMessage(&BotMessage{Message: "history karolisr/webhook-demo 2"}, nil)
+	if strings.Count(resp, "deployment/default/wd") != 2 {
+		t.Errorf("expected 2 records, got: %s", resp)
+	}
+
+	resp = bm.handleBotMessage(&BotMessage{Message: "history default/other"}, nil)
+	if !strings.Contains(resp, "no updates found") {
+		t.Errorf("unexpected response: %s", resp)
+	}
+}
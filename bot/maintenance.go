@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"strconv"
+
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/i18n"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterCommand(&Command{
+		Name:        "pause all",
+		Description: "enable maintenance mode, suspends automatic updates of all workloads",
+		Handler: func(bm *BotManager, req *CommandRequest) string {
+			return MaintenanceHandler(bm, true, req.Message.User)
+		},
+	})
+	RegisterCommand(&Command{
+		Name:        "resume all",
+		Description: "disable maintenance mode",
+		Handler: func(bm *BotManager, req *CommandRequest) string {
+			return MaintenanceHandler(bm, false, req.Message.User)
+		},
+	})
+}
+
+// MaintenanceHandler - enables or disables maintenance mode
+func MaintenanceHandler(bm *BotManager, enabled bool, user string) string {
+	if bm.store == nil {
+		return i18n.T("pausing updates is not available")
+	}
+
+	changed, err := store.SetMaintenance(bm.store, enabled, user)
+	if err != nil {
+		return i18n.T("failed to change maintenance mode: %s", err)
+	}
+
+	if !changed {
+		if enabled {
+			return i18n.T("maintenance mode is already enabled.")
+		}
+		return i18n.T("maintenance mode is not enabled.")
+	}
+
+	log.WithFields(log.Fields{
+		"enabled": enabled,
+		"user":    user,
+	}).Info("bot: maintenance mode changed")
+	bm.audit(types.AuditActionUpdated, types.AuditResourceKindMaintenance, types.PausedAllIdentifier, user, map[string]string{
+		"enabled": strconv.FormatBool(enabled),
+	})
+
+	if enabled {
+		return i18n.T("maintenance mode enabled, automatic updates of all workloads are suspended. Use 'resume all' to enable them again.")
+	}
+	return i18n.T("maintenance mode disabled, automatic updates resumed.")
+}
@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/keel-hq/keel/internal/ratelimit"
+	"github.com/keel-hq/keel/util/i18n"
+)
+
+// rateLimiter - update budgets of the providers, set before bots are started
+var rateLimiter *ratelimit.Limiter
+
+// SetRateLimiter - makes queued updates available to the 'queue' command
+func SetRateLimiter(l *ratelimit.Limiter) {
+	rateLimiter = l
+}
+
+func init() {
+	RegisterCommand(&Command{
+		Name:        "queue",
+		Description: "get update budgets and updates queued by rate limiting",
+		Handler: func(bm *BotManager, req *CommandRequest) string {
+			return QueueResponse(bm)
+		},
+	})
+}
+
+// QueueResponse - formats budgets usage and queued updates
+func QueueResponse(bm *BotManager) string {
+	if bm.rateLimiter == nil {
+		return i18n.T("rate limiting of automatic updates is not enabled.")
+	}
+
+	status := bm.rateLimiter.Status()
+
+	buf := &bytes.Buffer{}
+	if status.Global.Max > 0 {
+		fmt.Fprintf(buf, "%s\n", i18n.T("global budget: %d/%d updates per %s", status.Global.Used, status.Global.Max, status.Global.Period))
+	}
+
+	namespaces := make([]string, 0, len(status.Namespaces))
+	for ns, usage := range status.Namespaces {
+		if usage.Max > 0 {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	sort.Strings(namespaces)
+	for _, ns := range namespaces {
+		usage := status.Namespaces[ns]
+		fmt.Fprintf(buf, "%s\n", i18n.T("%s budget: %d/%d updates per %s", ns, usage.Used, usage.Max, usage.Period))
+	}
+
+	if len(status.Queued) == 0 {
+		fmt.Fprintf(buf, "%s", i18n.T("no updates are queued."))
+		return buf.String()
+	}
+
+	fmt.Fprintf(buf, "%s\n", i18n.T("queued updates (%d):", len(status.Queued)))
+	for _, u := range status.Queued {
+		fmt.Fprintf(buf, "- %s %s -> %s (%s, queued %s ago)\n", u.Identifier, u.CurrentVersion, u.NewVersion, u.Provider, time.Since(u.QueuedAt).Round(time.Second))
+	}
+	return buf.String()
+}
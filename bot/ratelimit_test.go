@@ -0,0 +1,35 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/internal/ratelimit"
+)
+
+func TestQueueResponse(t *testing.T) {
+	bm := &BotManager{}
+	if resp := bm.handleBotMessage(&BotMessage{Message: "queue", User: "karolis"}, nil); !strings.Contains(resp, "not enabled") {
+		t.Errorf("unexpected response: %s", resp)
+	}
+
+	limiter := ratelimit.New(ratelimit.Opts{Namespace: ratelimit.Budget{Max: 1, Period: time.Hour}})
+	limiter.Allow("default")
+	limiter.Enqueue(ratelimit.Update{
+		Provider:       "kubernetes",
+		Namespace:      "default",
+		Identifier:     "deployment/default/wd",
+		CurrentVersion: "0.0.1",
+		NewVersion:     "0.0.2",
+	}, func() {})
+
+	bm.rateLimiter = limiter
+	resp := bm.handleBotMessage(&BotMessage{Message: "queue", User: "karolis"}, nil)
+	if !strings.Contains(resp, "default budget: 1/1 updates per 1h0m0s") {
+		t.Errorf("expected namespace budget in response: %s", resp)
+	}
+	if !strings.Contains(resp, "deployment/default/wd 0.0.1 -> 0.0.2") {
+		t.Errorf("expected queued update in response: %s", resp)
+	}
+}
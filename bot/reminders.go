@@ -0,0 +1,209 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/i18n"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ApprovalReminder - optional bot interface, bots implementing it re-post pending
+// approval requests, mention is empty for the first reminder
+type ApprovalReminder interface {
+	RemindApproval(approval *types.Approval, mention string) error
+}
+
+// ReminderConfig - reminders of pending approvals
+type ReminderConfig struct {
+	// Schedule - default reminder intervals since approval was requested,
+	// approvals can override it with keel.sh/approvalReminders annotation
+	Schedule string
+	// Group - approver group mentioned in the second reminder
+	Group string
+	// Oncall - handle mentioned in the third and later reminders
+	Oncall string
+}
+
+// RemindersDisabled - schedule value that disables reminders of the approval
+const RemindersDisabled = "off"
+
+// reminderCheckInterval - how often pending approvals are checked for due reminders
+var reminderCheckInterval = time.Minute
+
+var (
+	remindersM      sync.Mutex
+	reminderConfig  ReminderConfig
+	cancelReminders context.CancelFunc
+)
+
+// SetApprovalReminders - configures reminders of pending approvals, set before bots are started
+func SetApprovalReminders(cfg ReminderConfig) {
+	remindersM.Lock()
+	reminderConfig = cfg
+	remindersM.Unlock()
+}
+
+// ParseReminderSchedule - parses comma separated reminder intervals, ie: 1h,4h,12h.
+// Intervals are measured from the approval request and have to increase.
+func ParseReminderSchedule(schedule string) ([]time.Duration, error) {
+	schedule = strings.TrimSpace(schedule)
+	if schedule == "" || schedule == RemindersDisabled {
+		return nil, nil
+	}
+
+	var intervals []time.Duration
+	for _, s := range strings.Split(schedule, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid reminder interval '%s': %s", s, err)
+		}
+		if d <= 0 || (len(intervals) > 0 && d <= intervals[len(intervals)-1]) {
+			return nil, fmt.Errorf("reminder intervals have to be positive and increasing, got: %s", schedule)
+		}
+		intervals = append(intervals, d)
+	}
+	return intervals, nil
+}
+
+// dueReminders - number of reminders of the approval that are due, reminders
+// that were missed (ie: keel was restarted) are collapsed into the latest one
+func dueReminders(approval *types.Approval, schedule []time.Duration, now time.Time) int {
+	due := 0
+	for _, d := range schedule {
+		if now.Sub(approval.CreatedAt) >= d {
+			due++
+		}
+	}
+	return due
+}
+
+// reminderMention - the first reminder doesn't mention anyone, the second one
+// mentions approver group, later reminders go to on-call
+func reminderMention(cfg ReminderConfig, reminder int) string {
+	switch {
+	case reminder <= 1:
+		return ""
+	case reminder == 2 || cfg.Oncall == "":
+		return cfg.Group
+	}
+	return cfg.Oncall
+}
+
+// ReminderMessage - text of the approval reminder
+func ReminderMessage(approval *types.Approval, mention string) string {
+	msg := i18n.T("Reminder: approval %s (%s) is still waiting for votes (%d/%d), deadline %s.",
+		approval.Identifier, approval.Delta(), approval.VotesReceived, approval.VotesRequired, approval.Deadline.Format(time.RFC3339))
+	if mention != "" {
+		msg = mention + " " + msg
+	}
+	return msg
+}
+
+// startReminders - periodically reminds about pending approvals until stopped
+func (bm *BotManager) startReminders() {
+	ctx, cancel := context.WithCancel(context.Background())
+	remindersM.Lock()
+	cancelReminders = cancel
+	remindersM.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(reminderCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				bm.sendReminders(time.Now())
+			}
+		}
+	}()
+}
+
+func stopReminders() {
+	remindersM.Lock()
+	defer remindersM.Unlock()
+	if cancelReminders != nil {
+		cancelReminders()
+		cancelReminders = nil
+	}
+}
+
+// sendReminders - sends due reminders of pending approvals to running bots,
+// number of sent reminders is stored with the approval
+func (bm *BotManager) sendReminders(now time.Time) {
+	remindersM.Lock()
+	cfg := reminderConfig
+	remindersM.Unlock()
+
+	approvals, err := bm.approvalsManager.List()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("bot.sendReminders: failed to list approvals")
+		return
+	}
+
+	for _, approval := range approvals {
+		if approval.Archived || approval.Status() != types.ApprovalStatusPending || approval.Expired() {
+			continue
+		}
+
+		scheduleStr := approval.Reminders
+		if scheduleStr == "" {
+			scheduleStr = cfg.Schedule
+		}
+		schedule, err := ParseReminderSchedule(scheduleStr)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"approval": approval.Identifier,
+			}).Warn("bot.sendReminders: invalid reminder schedule")
+			continue
+		}
+
+		due := dueReminders(approval, schedule, now)
+		if due <= approval.RemindersSent {
+			continue
+		}
+
+		bm.remindApproval(approval, reminderMention(cfg, due))
+
+		approval.RemindersSent = due
+		err = bm.approvalsManager.Update(approval)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"approval": approval.Identifier,
+			}).Error("bot.sendReminders: failed to update approval")
+		}
+	}
+}
+
+// remindApproval - reminder is sent to all running bots that can remind,
+// bots decide whether approval is relevant to them
+func (bm *BotManager) remindApproval(approval *types.Approval, mention string) {
+	bm.runningM.RLock()
+	defer bm.runningM.RUnlock()
+
+	for name, b := range bm.running {
+		reminder, ok := b.(ApprovalReminder)
+		if !ok {
+			continue
+		}
+		err := reminder.RemindApproval(approval, mention)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"bot":      name,
+				"approval": approval.Identifier,
+			}).Error("bot.remindApproval: failed to send reminder")
+		}
+	}
+}
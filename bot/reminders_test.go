@@ -0,0 +1,98 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/types"
+)
+
+type fakeReminderBot struct {
+	fakeRolloutBot
+	mentions []string
+}
+
+func (b *fakeReminderBot) RemindApproval(approval *types.Approval, mention string) error {
+	b.mentions = append(b.mentions, mention)
+	return nil
+}
+
+func TestParseReminderSchedule(t *testing.T) {
+	schedule, err := ParseReminderSchedule("30m, 2h,4h")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(schedule) != 3 || schedule[0] != 30*time.Minute || schedule[2] != 4*time.Hour {
+		t.Errorf("unexpected schedule: %v", schedule)
+	}
+
+	for _, s := range []string{"", RemindersDisabled} {
+		if schedule, err := ParseReminderSchedule(s); err != nil || schedule != nil {
+			t.Errorf("expected no reminders for '%s', got: %v, %v", s, schedule, err)
+		}
+	}
+	for _, s := range []string{"2h,1h", "1h,1h", "soon", "-1h"} {
+		if _, err := ParseReminderSchedule(s); err == nil {
+			t.Errorf("expected error for '%s'", s)
+		}
+	}
+}
+
+func TestSendReminders(t *testing.T) {
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	am := approvals.New(&approvals.Opts{Store: store})
+	b := &fakeReminderBot{}
+	bm := &BotManager{approvalsManager: am, running: map[string]Bot{"fake": b}}
+
+	SetApprovalReminders(ReminderConfig{Schedule: "1h,2h,3h", Group: "@approvers", Oncall: "@oncall"})
+	defer SetApprovalReminders(ReminderConfig{})
+
+	for _, a := range []*types.Approval{
+		{Identifier: "deployment/default/wd:1.2.3", VotesRequired: 1},
+		{Identifier: "deployment/default/quiet:1.2.3", VotesRequired: 1, Reminders: RemindersDisabled},
+		{Identifier: "deployment/default/approved:1.2.3", VotesRequired: 1, VotesReceived: 1},
+	} {
+		a.Deadline = time.Now().Add(24 * time.Hour)
+		if err := am.Create(a); err != nil {
+			t.Fatalf("failed to create approval: %s", err)
+		}
+	}
+
+	now := time.Now()
+	bm.sendReminders(now)
+	if len(b.mentions) != 0 {
+		t.Fatalf("expected no reminders yet, got: %v", b.mentions)
+	}
+
+	bm.sendReminders(now.Add(61 * time.Minute))
+	bm.sendReminders(now.Add(62 * time.Minute))
+	bm.sendReminders(now.Add(121 * time.Minute))
+	bm.sendReminders(now.Add(181 * time.Minute))
+	bm.sendReminders(now.Add(10 * time.Hour))
+
+	if strings.Join(b.mentions, ",") != ",@approvers,@oncall" {
+		t.Errorf("unexpected reminders: %q", b.mentions)
+	}
+
+	a, err := am.Get("deployment/default/wd:1.2.3")
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+	if a.RemindersSent != 3 {
+		t.Errorf("unexpected reminders sent: %d", a.RemindersSent)
+	}
+}
+
+func TestReminderMention(t *testing.T) {
+	cfg := ReminderConfig{Group: "@approvers"}
+	if m := reminderMention(cfg, 3); m != "@approvers" {
+		t.Errorf("expected approver group without on-call, got: %s", m)
+	}
+	if msg := ReminderMessage(&types.Approval{Identifier: "default/release:1.0.0", VotesRequired: 2}, "@approvers"); !strings.HasPrefix(msg, "@approvers Reminder: approval default/release:1.0.0") {
+		t.Errorf("unexpected message: %s", msg)
+	}
+}
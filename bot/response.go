@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// StructuredResponder - optional interface for bots that can render
+// structured responses natively (ie: Slack attachments), bots that don't
+// implement it get plain text version of the response
+type StructuredResponder interface {
+	RespondStructured(resp *Response, channel string)
+}
+
+// ThreadResponder - optional interface for bots that can reply in the thread
+// of the command message (ie: Slack threads), thread is BotMessage.Thread
+type ThreadResponder interface {
+	RespondInThread(text, channel, thread string)
+	RespondStructuredInThread(resp *Response, channel, thread string)
+}
+
+// responder - responds through the bot, in the thread of the message when
+// the bot supports threads
+func responder(b Bot, m *BotMessage) BotMessageResponder {
+	if tr, ok := b.(ThreadResponder); ok && m.Thread != "" {
+		return func(text, channel string) {
+			tr.RespondInThread(text, channel, m.Thread)
+		}
+	}
+	return b.Respond
+}
+
+// structuredResponder - renders structured responses through the bot, nil
+// when the bot only supports plain text
+func structuredResponder(b Bot, m *BotMessage) func(resp *Response, channel string) {
+	if tr, ok := b.(ThreadResponder); ok && m.Thread != "" {
+		return func(resp *Response, channel string) {
+			tr.RespondStructuredInThread(resp, channel, m.Thread)
+		}
+	}
+	if sr, ok := b.(StructuredResponder); ok {
+		return sr.RespondStructured
+	}
+	return nil
+}
+
+// Response - structured bot command response
+type Response struct {
+	Title  string
+	Items  []ResponseItem
+	Footer string
+}
+
+// ResponseItem - single item of the response, ie: approval
+type ResponseItem struct {
+	Title  string
+	Color  string
+	Fields []ResponseField
+}
+
+// ResponseField - item field
+type ResponseField struct {
+	Title string
+	Value string
+	Short bool
+}
+
+// String - plain text representation of the response
+func (r *Response) String() string {
+	buf := &bytes.Buffer{}
+	if r.Title != "" {
+		fmt.Fprintln(buf, r.Title)
+	}
+	for _, item := range r.Items {
+		fmt.Fprintf(buf, "\n%s\n", item.Title)
+		for _, f := range item.Fields {
+			fmt.Fprintf(buf, "  %s: %s\n", f.Title, f.Value)
+		}
+	}
+	if r.Footer != "" {
+		fmt.Fprintf(buf, "\n%s\n", r.Footer)
+	}
+	return buf.String()
+}
@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"sync"
+
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RolloutReporter - optional bot interface, bots implementing it reply to their
+// approval requests (ie: Slack thread) with rollout progress of the approved update
+type RolloutReporter interface {
+	ReplyToRollout(approvalIdentifier string, event types.EventNotification) error
+}
+
+// rolloutSender - notification sender that passes rollout notifications of
+// approved updates to the running bots
+type rolloutSender struct {
+	mu sync.RWMutex
+	bm *BotManager
+}
+
+var rollouts = &rolloutSender{}
+
+// RolloutSender - notification sender that has to be registered for bots to
+// receive rollout progress of approved updates
+func RolloutSender() notification.Sender {
+	return rollouts
+}
+
+func (s *rolloutSender) setManager(bm *BotManager) {
+	s.mu.Lock()
+	s.bm = bm
+	s.mu.Unlock()
+}
+
+func (s *rolloutSender) Configure(config *notification.Config) (bool, error) {
+	return true, nil
+}
+
+// Level - rollout progress reports are debug notifications so they don't
+// reach regular notification channels
+func (s *rolloutSender) Level() types.Level {
+	return types.LevelDebug
+}
+
+// Internal - rollout progress is posted by the bots themselves
+func (s *rolloutSender) Internal() bool {
+	return true
+}
+
+func (s *rolloutSender) Send(event types.EventNotification) error {
+	identifier := event.Metadata["approvalIdentifier"]
+	if identifier == "" {
+		return nil
+	}
+	switch event.Type {
+	case types.NotificationRolloutProgress, types.NotificationDeploymentUpdate:
+	default:
+		return nil
+	}
+
+	s.mu.RLock()
+	bm := s.bm
+	s.mu.RUnlock()
+	if bm == nil {
+		return nil
+	}
+	bm.replyToRollout(identifier, event)
+	return nil
+}
+
+// replyToRollout - rollout progress is sent to all running bots that can
+// report it, bots decide whether the approval request is theirs
+func (bm *BotManager) replyToRollout(approvalIdentifier string, event types.EventNotification) {
+	bm.runningM.RLock()
+	defer bm.runningM.RUnlock()
+
+	for name, b := range bm.running {
+		reporter, ok := b.(RolloutReporter)
+		if !ok {
+			continue
+		}
+		err := reporter.ReplyToRollout(approvalIdentifier, event)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"bot":      name,
+				"approval": approvalIdentifier,
+			}).Error("bot.replyToRollout: failed to reply")
+		}
+	}
+}
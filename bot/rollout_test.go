@@ -0,0 +1,55 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+)
+
+type fakeRolloutBot struct {
+	replies map[string][]string
+}
+
+func (b *fakeRolloutBot) Configure(approvalsRespCh chan *ApprovalResponse, botMessagesChannel chan *BotMessage) bool {
+	return true
+}
+func (b *fakeRolloutBot) Start(ctx context.Context) error           { return nil }
+func (b *fakeRolloutBot) Respond(text string, channel string)       {}
+func (b *fakeRolloutBot) RequestApproval(req *types.Approval) error { return nil }
+func (b *fakeRolloutBot) ReplyToApproval(approval *types.Approval) error {
+	return nil
+}
+func (b *fakeRolloutBot) ReplyToRollout(approvalIdentifier string, event types.EventNotification) error {
+	b.replies[approvalIdentifier] = append(b.replies[approvalIdentifier], event.Message)
+	return nil
+}
+
+func TestRolloutSender(t *testing.T) {
+	b := &fakeRolloutBot{replies: make(map[string][]string)}
+	bm := &BotManager{running: map[string]Bot{"fake": b}}
+
+	s := &rolloutSender{}
+	// bots aren't running yet
+	s.Send(types.EventNotification{Type: types.NotificationRolloutProgress, Metadata: map[string]string{"approvalIdentifier": "deployment/default/wd:1.2.3"}})
+
+	s.setManager(bm)
+	s.Send(types.EventNotification{
+		Type:     types.NotificationRolloutProgress,
+		Message:  "1 out of 3 new replicas have been updated",
+		Metadata: map[string]string{"approvalIdentifier": "deployment/default/wd:1.2.3"},
+	})
+	s.Send(types.EventNotification{
+		Type:     types.NotificationDeploymentUpdate,
+		Message:  "successfully rolled out",
+		Metadata: map[string]string{"approvalIdentifier": "deployment/default/wd:1.2.3"},
+	})
+	// updates that didn't require approvals and other notifications aren't reported
+	s.Send(types.EventNotification{Type: types.NotificationDeploymentUpdate, Message: "updated", Metadata: map[string]string{}})
+	s.Send(types.EventNotification{Type: types.NotificationUpdateApproved, Message: "approved", Metadata: map[string]string{"approvalIdentifier": "deployment/default/wd:1.2.3"}})
+
+	replies := b.replies["deployment/default/wd:1.2.3"]
+	if len(replies) != 2 || replies[0] != "1 out of 3 new replicas have been updated" || replies[1] != "successfully rolled out" {
+		t.Errorf("unexpected replies: %v", b.replies)
+	}
+}
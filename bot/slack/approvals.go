@@ -1,131 +1,303 @@
 package slack
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/keel-hq/keel/bot"
 	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/i18n"
+	"github.com/keel-hq/keel/util/templates"
 	"github.com/nlopes/slack"
+
+	log "github.com/sirupsen/logrus"
 )
 
+// approvalExpiryCheckInterval - how often request messages of expired approvals are updated
+var approvalExpiryCheckInterval = time.Minute
+
 // Request - request approval
 func (b *Bot) RequestApproval(req *types.Approval) error {
-	return b.postMessage(
-		"Approval required",
+	if !b.ownsApproval(req) {
+		return nil
+	}
+
+	title, text, fields := b.approvalRequest(req)
+
+	var fullDiff bool
+	if req.Diff != "" {
+		var diff string
+		diff, fullDiff = bot.TruncateDiff(req.Diff, bot.ApprovalDiffLines)
+		fields = append(fields, slack.AttachmentField{
+			Title: i18n.T("Changes"),
+			Value: "```" + diff + "```",
+			Short: false,
+		})
+	}
+
+	sent := func(channel, ts string) {
+		b.rememberThread(req, channel, ts)
+		if fullDiff {
+			b.uploadDiff(req, channel, ts)
+		}
+	}
+
+	var opts []slack.MsgOption
+	if mentions := b.oncallMentions(req); mentions != "" {
+		// mentions only notify when they are in the message text
+		opts = append(opts, slack.MsgOptionText(mentions, false))
+	}
+	if len(req.GetApprovers()) > 0 {
+		err := b.requestDirectApproval(req, title, text, fields, opts...)
+		if req.Private {
+			return err
+		}
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"approval": req.Identifier,
+			}).Error("bot.slack: failed to send approval request to approvers")
+		}
+	}
+	return b.postTrackedMessage(title, text, types.LevelSuccess.Color(), fields, sent, opts...)
+}
+
+// uploadDiff - uploads full diff of the approval into the request thread
+func (b *Bot) uploadDiff(req *types.Approval, channel, ts string) {
+	_, err := b.slackHTTPClient.UploadFile(slack.FileUploadParameters{
+		Content:         req.Diff,
+		Filetype:        "diff",
+		Filename:        strings.NewReplacer("/", "-", ":", "-").Replace(req.Identifier) + ".diff",
+		Title:           i18n.T("Changes of %s", req.Identifier),
+		Channels:        []string{channel},
+		ThreadTimestamp: ts,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"approval": req.Identifier,
+		}).Error("bot.slack: failed to upload approval diff")
+	}
+}
+
+// oncallMentions - mentions users that were on call when approval was requested,
+// users that aren't members of the workspace are mentioned by email
+func (b *Bot) oncallMentions(approval *types.Approval) string {
+	var mentions []string
+	for _, email := range approval.GetOncall() {
+		if id, ok := b.emails[strings.ToLower(email)]; ok {
+			mentions = append(mentions, "<@"+id+">")
+		} else {
+			mentions = append(mentions, email)
+		}
+	}
+	if len(mentions) == 0 {
+		return ""
+	}
+	return i18n.T("On call: %s", strings.Join(mentions, " "))
+}
+
+// approvalRequest - title, text and fields of the approval request message
+func (b *Bot) approvalRequest(req *types.Approval) (string, string, []slack.AttachmentField) {
+	if msg, ok := templates.RenderMessage(templates.MessageApprovalRequest, req); ok {
+		return msg.Title, msg.Text, messageFields(msg)
+	}
+
+	return i18n.T("Approval required"),
 		req.Message,
-		types.LevelSuccess.Color(),
 		[]slack.AttachmentField{
 			slack.AttachmentField{
-				Title: "Approval required!",
-				Value: req.Message + "\n" + fmt.Sprintf("To vote for change type '%s approve %s' to reject it: '%s reject %s'.", b.name, req.Identifier, b.name, req.Identifier),
+				Title: i18n.T("Approval required!"),
+				Value: req.Message + "\n" + i18n.T("To vote for change type '%s approve %s' to reject it: '%s reject %s'.", b.name, req.Identifier, b.name, req.Identifier),
 				Short: false,
 			},
 			slack.AttachmentField{
-				Title: "Votes",
+				Title: i18n.T("Votes"),
 				Value: fmt.Sprintf("%d/%d", req.VotesReceived, req.VotesRequired),
 				Short: true,
 			},
 			slack.AttachmentField{
-				Title: "Delta",
+				Title: i18n.T("Delta"),
 				Value: req.Delta(),
 				Short: true,
 			},
 			slack.AttachmentField{
-				Title: "Identifier",
+				Title: i18n.T("Identifier"),
 				Value: req.Identifier,
 				Short: true,
 			},
 			slack.AttachmentField{
-				Title: "Provider",
+				Title: i18n.T("Provider"),
 				Value: req.Provider.String(),
 				Short: true,
 			},
-		})
+		}
+}
+
+// updateApprovalMessage - edits the approval request message so it shows current
+// votes and status, false when the request message isn't known (ie: it was
+// requested by another bot or before conversations were stored)
+func (b *Bot) updateApprovalMessage(approval *types.Approval) bool {
+	thread := b.updateThread(approval, approval.Status() != types.ApprovalStatusPending)
+	if thread == nil {
+		return false
+	}
+
+	var status, color string
+	switch approval.Status() {
+	case types.ApprovalStatusPending:
+		status = i18n.T("Vote received, waiting for remaining votes (%d/%d).", approval.VotesReceived, approval.VotesRequired)
+		color = types.LevelSuccess.Color()
+	case types.ApprovalStatusRejected:
+		status = i18n.T("Change was rejected.")
+		color = types.LevelWarn.Color()
+	case types.ApprovalStatusApproved:
+		status = i18n.T("Update approved (%d/%d), thanks for voting!", approval.VotesReceived, approval.VotesRequired)
+		color = types.LevelInfo.Color()
+	default:
+		return false
+	}
+
+	b.editApprovalMessage(thread, status, color)
+	return true
+}
+
+// editApprovalMessage - replaces the request message with the latest approval state
+func (b *Bot) editApprovalMessage(thread *types.BotConversation, status, color string) {
+	approval := thread.GetApproval()
+	_, text, fields := b.approvalRequest(approval)
+	fields = append(fields, slack.AttachmentField{
+		Title: i18n.T("Status"),
+		Value: status,
+		Short: false,
+	})
+
+	err := b.update(thread.Channel, thread.TS, slack.MsgOptionAttachments(attachment(text, color, fields)))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"approval": approval.Identifier,
+		}).Error("bot.slack: failed to update approval request message")
+	}
+}
+
+// expireApprovalMessages - periodically marks request messages of approvals past
+// their deadline as expired
+func (b *Bot) expireApprovalMessages(ctx context.Context) {
+	ticker := time.NewTicker(approvalExpiryCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, thread := range b.expiredThreads(now) {
+				approval := thread.GetApproval()
+				b.editApprovalMessage(thread,
+					i18n.T("Approval expired without enough votes (%d/%d).", approval.VotesReceived, approval.VotesRequired),
+					types.LevelWarn.Color())
+			}
+		}
+	}
 }
 
+// ReplyToApproval - updates the approval request message, new message is posted
+// when the request message isn't known
 func (b *Bot) ReplyToApproval(approval *types.Approval) error {
+	if !b.ownsApproval(approval) {
+		return nil
+	}
+	if approval.Status() != types.ApprovalStatusPending {
+		defer b.forgetDirectApproval(approval.Identifier)
+	}
+	if b.updateApprovalMessage(approval) {
+		return nil
+	}
 	switch approval.Status() {
 	case types.ApprovalStatusPending:
-		b.postMessage(
-			"Vote received",
-			"All approvals received, thanks for voting!",
+		b.postApprovalReply(approval,
+			i18n.T("Vote received"),
+			i18n.T("All approvals received, thanks for voting!"),
 			types.LevelInfo.Color(),
 			[]slack.AttachmentField{
 				slack.AttachmentField{
-					Title: "vote received!",
-					Value: "Waiting for remaining votes.",
+					Title: i18n.T("vote received!"),
+					Value: i18n.T("Waiting for remaining votes."),
 					Short: false,
 				},
 				slack.AttachmentField{
-					Title: "Votes",
+					Title: i18n.T("Votes"),
 					Value: fmt.Sprintf("%d/%d", approval.VotesReceived, approval.VotesRequired),
 					Short: true,
 				},
 				slack.AttachmentField{
-					Title: "Delta",
+					Title: i18n.T("Delta"),
 					Value: approval.Delta(),
 					Short: true,
 				},
 				slack.AttachmentField{
-					Title: "Identifier",
+					Title: i18n.T("Identifier"),
 					Value: approval.Identifier,
 					Short: true,
 				},
 			})
 	case types.ApprovalStatusRejected:
-		b.postMessage(
-			"Change rejected",
-			"Change was rejected",
+		b.postApprovalReply(approval,
+			i18n.T("Change rejected"),
+			i18n.T("Change was rejected"),
 			types.LevelWarn.Color(),
 			[]slack.AttachmentField{
 				slack.AttachmentField{
-					Title: "change rejected",
-					Value: "Change was rejected.",
+					Title: i18n.T("change rejected"),
+					Value: i18n.T("Change was rejected."),
 					Short: false,
 				},
 				slack.AttachmentField{
-					Title: "Status",
+					Title: i18n.T("Status"),
 					Value: approval.Status().String(),
 					Short: true,
 				},
 				slack.AttachmentField{
-					Title: "Votes",
+					Title: i18n.T("Votes"),
 					Value: fmt.Sprintf("%d/%d", approval.VotesReceived, approval.VotesRequired),
 					Short: true,
 				},
 				slack.AttachmentField{
-					Title: "Delta",
+					Title: i18n.T("Delta"),
 					Value: approval.Delta(),
 					Short: true,
 				},
 				slack.AttachmentField{
-					Title: "Identifier",
+					Title: i18n.T("Identifier"),
 					Value: approval.Identifier,
 					Short: true,
 				},
 			})
 	case types.ApprovalStatusApproved:
-		b.postMessage(
-			"approval received",
-			"All approvals received, thanks for voting!",
+		b.postApprovalReply(approval,
+			i18n.T("approval received"),
+			i18n.T("All approvals received, thanks for voting!"),
 			types.LevelSuccess.Color(),
 			[]slack.AttachmentField{
 				slack.AttachmentField{
-					Title: "update approved!",
-					Value: "All approvals received, thanks for voting!",
+					Title: i18n.T("update approved!"),
+					Value: i18n.T("All approvals received, thanks for voting!"),
 					Short: false,
 				},
 				slack.AttachmentField{
-					Title: "Votes",
+					Title: i18n.T("Votes"),
 					Value: fmt.Sprintf("%d/%d", approval.VotesReceived, approval.VotesRequired),
 					Short: true,
 				},
 				slack.AttachmentField{
-					Title: "Delta",
+					Title: i18n.T("Delta"),
 					Value: approval.Delta(),
 					Short: true,
 				},
 				slack.AttachmentField{
-					Title: "Identifier",
+					Title: i18n.T("Identifier"),
 					Value: approval.Identifier,
 					Short: true,
 				},
@@ -133,3 +305,69 @@ func (b *Bot) ReplyToApproval(approval *types.Approval) error {
 	}
 	return nil
 }
+
+// RemindApproval - posts a reminder of the pending approval, reminders are replies
+// to the approval request (also sent to the channel) when its message is known
+func (b *Bot) RemindApproval(approval *types.Approval, mention string) error {
+	if !b.ownsApproval(approval) {
+		return nil
+	}
+
+	params := slack.NewPostMessageParameters()
+	params.Username = b.name
+
+	// mentions only notify when they are in the message text
+	opts := []slack.MsgOption{
+		slack.MsgOptionPostMessageParameters(params),
+		slack.MsgOptionText(bot.ReminderMessage(approval, mention), false),
+		slack.MsgOptionAttachments(slack.Attachment{
+			Fallback: approval.Message,
+			Color:    types.LevelWarn.Color(),
+			Fields: []slack.AttachmentField{
+				{Title: i18n.T("Approval reminder"), Value: i18n.T("To vote for change type '%s approve %s' to reject it: '%s reject %s'.", b.name, approval.Identifier, b.name, approval.Identifier), Short: false},
+				{Title: i18n.T("Votes"), Value: fmt.Sprintf("%d/%d", approval.VotesReceived, approval.VotesRequired), Short: true},
+				{Title: i18n.T("Delta"), Value: approval.Delta(), Short: true},
+			},
+		}),
+	}
+
+	if thread := b.approvalThread(approval.Identifier); thread != nil {
+		opts = append(opts, slack.MsgOptionTS(thread.TS), slack.MsgOptionBroadcast())
+		return b.send(thread.Channel, opts...)
+	}
+
+	var lastErr error
+	for _, channel := range b.approvalChannels(approval) {
+		if err := b.send(channel, opts...); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// postApprovalReply - posts reply to the channels of the approval
+func (b *Bot) postApprovalReply(approval *types.Approval, title, message, color string, fields []slack.AttachmentField) {
+	for _, channel := range b.approvalChannels(approval) {
+		b.postMessageTo(channel, title, message, color, fields, nil)
+	}
+}
+
+// messageFields - converts templated message into attachment fields, message without
+// fields is sent as a single field
+func messageFields(msg *templates.Message) []slack.AttachmentField {
+	if len(msg.Fields) == 0 {
+		return []slack.AttachmentField{
+			{Title: msg.Title, Value: msg.Text, Short: false},
+		}
+	}
+
+	var fields []slack.AttachmentField
+	for _, f := range msg.Fields {
+		fields = append(fields, slack.AttachmentField{
+			Title: f.Title,
+			Value: f.Value,
+			Short: f.Short,
+		})
+	}
+	return fields
+}
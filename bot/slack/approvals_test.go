@@ -0,0 +1,48 @@
+package slack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/types"
+)
+
+func TestRequestApprovalUploadsDiff(t *testing.T) {
+	fi := &rateLimitedImplementer{}
+	b := &Bot{
+		slackHTTPClient:  fi,
+		approvalsChannel: "general",
+	}
+
+	short := &types.Approval{
+		Provider:   types.ProviderTypeHelm,
+		Identifier: "default/wd:1.2.3",
+		Diff:       "-image.tag: 1.2.2\n+image.tag: 1.2.3",
+	}
+	if err := b.RequestApproval(short); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(fi.uploaded) != 0 {
+		t.Fatalf("diff that fits into the message shouldn't be uploaded")
+	}
+
+	long := &types.Approval{
+		Provider:   types.ProviderTypeHelm,
+		Identifier: "default/wd:1.2.4",
+		Diff:       strings.Repeat("+replicas: 2\n", bot.ApprovalDiffLines+1),
+	}
+	if err := b.RequestApproval(long); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(fi.uploaded) != 1 {
+		t.Fatalf("expected full diff to be uploaded, got %d uploads", len(fi.uploaded))
+	}
+	upload := fi.uploaded[0]
+	if upload.Content != long.Diff || upload.Filename != "default-wd-1.2.4.diff" {
+		t.Errorf("unexpected upload: %s %q", upload.Filename, upload.Content)
+	}
+	if upload.Channels[0] != "general" || upload.ThreadTimestamp != "ts" {
+		t.Errorf("expected diff in the approval thread, got: %v %s", upload.Channels, upload.ThreadTimestamp)
+	}
+}
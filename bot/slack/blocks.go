@@ -0,0 +1,170 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nlopes/slack"
+
+	"github.com/keel-hq/keel/bot"
+)
+
+// Block Kit limits, responses that don't fit are uploaded as snippets
+const (
+	maxBlocks        = 50
+	maxSectionFields = 10
+	maxSectionText   = 3000
+	maxFieldText     = 2000
+)
+
+// block - Block Kit layout block, vendored Slack client predates Block Kit
+// so blocks are posted by blocksClient
+type block struct {
+	Type     string       `json:"type"`
+	Text     *blockText   `json:"text,omitempty"`
+	Fields   []*blockText `json:"fields,omitempty"`
+	Elements []*blockText `json:"elements,omitempty"`
+}
+
+type blockText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func mrkdwn(text string) *blockText {
+	return &blockText{Type: "mrkdwn", Text: text}
+}
+
+// blocksMessage - queued Block Kit message, text is shown in notifications
+type blocksMessage struct {
+	text   string
+	thread string
+	blocks []block
+}
+
+// blocksPoster - posts Block Kit messages, returns channel ID and timestamp
+// of the message
+type blocksPoster interface {
+	PostBlocks(channel string, msg *blocksMessage) (string, string, error)
+}
+
+// blocksClient - posts Block Kit messages through chat.postMessage
+type blocksClient struct {
+	token    string
+	username string
+	client   *http.Client
+}
+
+func newBlocksClient(token, username string) *blocksClient {
+	return &blocksClient{
+		token:    token,
+		username: username,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *blocksClient) PostBlocks(channel string, msg *blocksMessage) (string, string, error) {
+	blocks, err := json.Marshal(msg.blocks)
+	if err != nil {
+		return "", "", err
+	}
+	values := url.Values{
+		"token":    {c.token},
+		"channel":  {channel},
+		"text":     {msg.text},
+		"blocks":   {string(blocks)},
+		"username": {c.username},
+	}
+	if msg.thread != "" {
+		values.Set("thread_ts", msg.thread)
+	}
+
+	resp, err := c.client.PostForm(slack.APIURL+"chat.postMessage", values)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retry, err := strconv.ParseInt(resp.Header.Get("Retry-After"), 10, 64)
+		if err != nil {
+			return "", "", err
+		}
+		return "", "", &slack.RateLimitedError{RetryAfter: time.Duration(retry) * time.Second}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("slack responded with %s", resp.Status)
+	}
+
+	var result struct {
+		slack.SlackResponse
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", "", err
+	}
+	if !result.Ok {
+		return "", "", fmt.Errorf("slack error: %s", result.Error)
+	}
+	return result.Channel, result.TS, nil
+}
+
+// responseBlocks - renders structured response as a table: every item is a
+// section with its fields in two columns. False is returned when the
+// response doesn't fit into a message.
+func responseBlocks(resp *bot.Response) ([]block, bool) {
+	var blocks []block
+	if resp.Title != "" {
+		blocks = append(blocks, block{Type: "section", Text: mrkdwn(resp.Title)})
+	}
+
+	for _, item := range resp.Items {
+		blocks = append(blocks, block{Type: "divider"})
+
+		section := block{Type: "section", Text: mrkdwn("*" + item.Title + "*")}
+		for _, f := range item.Fields {
+			if len(section.Fields) == maxSectionFields {
+				blocks = append(blocks, section)
+				section = block{Type: "section"}
+			}
+			text := fmt.Sprintf("*%s*\n%s", f.Title, f.Value)
+			if len(text) > maxFieldText {
+				return nil, false
+			}
+			section.Fields = append(section.Fields, mrkdwn(text))
+		}
+		blocks = append(blocks, section)
+	}
+
+	if resp.Footer != "" {
+		blocks = append(blocks, block{Type: "context", Elements: []*blockText{mrkdwn(resp.Footer)}})
+	}
+
+	if len(blocks) > maxBlocks {
+		return nil, false
+	}
+	for _, b := range blocks {
+		if b.Text != nil && len(b.Text.Text) > maxSectionText {
+			return nil, false
+		}
+	}
+	return blocks, true
+}
+
+// responseText - notification text of the structured response
+func responseText(resp *bot.Response) string {
+	if resp.Title != "" {
+		return resp.Title
+	}
+	titles := make([]string, 0, len(resp.Items))
+	for _, item := range resp.Items {
+		titles = append(titles, item.Title)
+	}
+	return strings.Join(titles, ", ")
+}
@@ -0,0 +1,119 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nlopes/slack"
+
+	"github.com/keel-hq/keel/bot"
+)
+
+func TestResponseBlocks(t *testing.T) {
+	resp := &bot.Response{
+		Title: "Approvals waiting for votes: 1",
+		Items: []bot.ResponseItem{
+			{
+				Title: "deployment/default/wd:1.2.3",
+				Fields: []bot.ResponseField{
+					{Title: "Votes", Value: "0/1", Short: true},
+					{Title: "Delta", Value: "1.2.2 -> 1.2.3", Short: true},
+				},
+			},
+		},
+		Footer: "page 1/1",
+	}
+
+	blocks, ok := responseBlocks(resp)
+	if !ok {
+		t.Fatalf("expected response to fit into a message")
+	}
+	if len(blocks) != 4 {
+		t.Fatalf("expected 4 blocks, got: %+v", blocks)
+	}
+	if blocks[0].Text.Text != "Approvals waiting for votes: 1" || blocks[1].Type != "divider" {
+		t.Errorf("unexpected header blocks: %+v", blocks[:2])
+	}
+	item := blocks[2]
+	if item.Text.Text != "*deployment/default/wd:1.2.3*" || len(item.Fields) != 2 || item.Fields[0].Text != "*Votes*\n0/1" {
+		t.Errorf("unexpected item block: %+v", item)
+	}
+	if blocks[3].Type != "context" || blocks[3].Elements[0].Text != "page 1/1" {
+		t.Errorf("unexpected footer block: %+v", blocks[3])
+	}
+}
+
+func TestResponseBlocksTooLarge(t *testing.T) {
+	resp := &bot.Response{}
+	for i := 0; i < maxBlocks; i++ {
+		resp.Items = append(resp.Items, bot.ResponseItem{Title: "item"})
+	}
+	if _, ok := responseBlocks(resp); ok {
+		t.Errorf("expected response with too many items not to fit")
+	}
+
+	resp = &bot.Response{Items: []bot.ResponseItem{{
+		Title:  "item",
+		Fields: []bot.ResponseField{{Title: "Diff", Value: strings.Repeat("x", maxFieldText)}},
+	}}}
+	if _, ok := responseBlocks(resp); ok {
+		t.Errorf("expected response with too long field not to fit")
+	}
+}
+
+func TestPostBlocks(t *testing.T) {
+	var form map[string][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat.postMessage" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		r.ParseForm()
+		form = r.PostForm
+		w.Write([]byte(`{"ok": true, "channel": "C1", "ts": "1503435956.000247"}`))
+	}))
+	defer srv.Close()
+
+	apiURL := slack.APIURL
+	slack.APIURL = srv.URL + "/"
+	defer func() { slack.APIURL = apiURL }()
+
+	c := newBlocksClient("token", "keel")
+	channel, ts, err := c.PostBlocks("general", &blocksMessage{
+		text:   "approvals",
+		thread: "1503435956.000100",
+		blocks: []block{{Type: "divider"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if channel != "C1" || ts != "1503435956.000247" {
+		t.Errorf("unexpected message: %s %s", channel, ts)
+	}
+	if form["thread_ts"][0] != "1503435956.000100" || form["username"][0] != "keel" {
+		t.Errorf("unexpected form: %v", form)
+	}
+	var blocks []block
+	if err := json.Unmarshal([]byte(form["blocks"][0]), &blocks); err != nil || len(blocks) != 1 {
+		t.Errorf("unexpected blocks: %s", form["blocks"])
+	}
+}
+
+func TestPostBlocksRateLimited(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	apiURL := slack.APIURL
+	slack.APIURL = srv.URL + "/"
+	defer func() { slack.APIURL = apiURL }()
+
+	_, _, err := newBlocksClient("token", "keel").PostBlocks("general", &blocksMessage{})
+	if rl, ok := err.(*slack.RateLimitedError); !ok || rl.RetryAfter.Seconds() != 3 {
+		t.Errorf("expected rate limited error, got: %v", err)
+	}
+}
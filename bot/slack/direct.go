@@ -0,0 +1,121 @@
+package slack
+
+import (
+	"strings"
+
+	"github.com/nlopes/slack"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// approverID - Slack user ID of the approver, approvers are user names or IDs
+func (b *Bot) approverID(approver string) (string, bool) {
+	if id, ok := b.users[strings.ToLower(approver)]; ok {
+		return id, true
+	}
+	if (strings.HasPrefix(approver, "U") || strings.HasPrefix(approver, "W")) && strings.ToUpper(approver) == approver {
+		return approver, true
+	}
+	return "", false
+}
+
+// requestDirectApproval - sends approval request to each approver as a direct message
+func (b *Bot) requestDirectApproval(req *types.Approval, title, text string, fields []slack.AttachmentField, opts ...slack.MsgOption) error {
+	var channels []string
+	var lastErr error
+	for _, approver := range req.GetApprovers() {
+		id, ok := b.approverID(approver)
+		if !ok {
+			log.WithFields(log.Fields{
+				"approver": approver,
+				"approval": req.Identifier,
+			}).Warn("bot.slack: approver not found")
+			continue
+		}
+		_, _, channel, err := b.slackClient.OpenIMChannel(id)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"approver": approver,
+				"approval": req.Identifier,
+			}).Error("bot.slack: failed to open direct message channel")
+			lastErr = err
+			continue
+		}
+		channels = append(channels, channel)
+
+		if err := b.postMessageTo(channel, title, text, types.LevelSuccess.Color(), fields, nil, opts...); err != nil {
+			lastErr = err
+		}
+	}
+
+	// votes of the approval are accepted in the direct message channels
+	b.conversationsM.Lock()
+	conversation := b.conversationOf(req)
+	conversation.DirectChannels = strings.Join(channels, ",")
+	conversation.Private = req.Private
+	b.saveConversation(conversation)
+	b.conversationsM.Unlock()
+
+	return lastErr
+}
+
+// approvalChannels - channels that get replies and reminders of the approval,
+// private approvals are only discussed in direct messages
+func (b *Bot) approvalChannels(approval *types.Approval) []string {
+	b.conversationsM.Lock()
+	defer b.conversationsM.Unlock()
+
+	conversation := b.getConversation(approval.Identifier)
+	if conversation == nil || !conversation.Private {
+		return []string{b.approvalsChannel}
+	}
+	return conversation.GetDirectChannels()
+}
+
+// forgetDirectApproval - stops accepting votes in the direct message channels
+// once the approval got approved or rejected
+func (b *Bot) forgetDirectApproval(identifier string) {
+	b.conversationsM.Lock()
+	defer b.conversationsM.Unlock()
+
+	conversation := b.getConversation(identifier)
+	if conversation == nil || conversation.DirectChannels == "" {
+		return
+	}
+	conversation.DirectChannels = ""
+	conversation.Private = false
+	b.saveConversation(conversation)
+}
+
+// directVote - checks votes received in the direct message channel, direct is true
+// when all voted approvals were sent to the channel, private is true when any of
+// them is private
+func (b *Bot) directVote(channel string, resp *bot.ApprovalResponse) (direct, private bool) {
+	b.conversationsM.Lock()
+	defer b.conversationsM.Unlock()
+
+	fields := strings.Fields(resp.Text)
+	if len(fields) < 2 {
+		return false, false
+	}
+
+	direct = true
+	for _, identifier := range fields[1:] {
+		conversation := b.getConversation(identifier)
+		if conversation == nil || conversation.DirectChannels == "" {
+			direct = false
+			continue
+		}
+		if conversation.Private {
+			private = true
+		}
+		if !conversation.HasDirectChannel(channel) {
+			direct = false
+		}
+	}
+	return direct, private
+}
@@ -0,0 +1,175 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/keel-hq/keel/util/timeutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var slackRateLimitedCounter = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "slack_bot_rate_limited_total",
+		Help: "How many times Slack API responded with rate limit error.",
+	},
+)
+
+var slackFailedMessagesCounter = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "slack_bot_failed_messages_total",
+		Help: "How many Slack bot messages were dropped after all send attempts failed.",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(slackRateLimitedCounter)
+	prometheus.MustRegister(slackFailedMessagesCounter)
+}
+
+const (
+	sendQueueSize   = 100
+	maxSendAttempts = 5
+	maxSendBackoff  = time.Minute
+)
+
+// ErrSendQueueFull - returned when message can't be queued
+var ErrSendQueueFull = errors.New("slack send queue is full")
+
+type outgoingMessage struct {
+	channel string
+	options []slack.MsgOption
+	// sent - optional, called with the channel ID and timestamp of the delivered message
+	sent func(channel, ts string)
+	// ts - timestamp of the message that is updated instead of posting a new one
+	ts string
+	// blocks - Block Kit message that is posted instead of options
+	blocks *blocksMessage
+}
+
+// startSendQueue - starts sending queued messages, messages are sent one by one
+// so bursts of updates don't hit Slack rate limits all at once
+func (b *Bot) startSendQueue(ctx context.Context) {
+	b.sendQueue = make(chan *outgoingMessage, sendQueueSize)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-b.sendQueue:
+				b.deliver(ctx, msg)
+			}
+		}
+	}()
+}
+
+// send - queues message, if queue is not started (bot wasn't started)
+// message is delivered straight away
+func (b *Bot) send(channel string, options ...slack.MsgOption) error {
+	return b.sendTracked(channel, nil, options...)
+}
+
+// sendTracked - queues message, sent is called once the message is delivered
+func (b *Bot) sendTracked(channel string, sent func(channel, ts string), options ...slack.MsgOption) error {
+	return b.enqueue(&outgoingMessage{
+		channel: channel,
+		options: options,
+		sent:    sent,
+	})
+}
+
+// sendBlocks - queues Block Kit message
+func (b *Bot) sendBlocks(channel string, msg *blocksMessage) error {
+	return b.enqueue(&outgoingMessage{
+		channel: channel,
+		blocks:  msg,
+	})
+}
+
+// update - queues update of the message, updates go through the same queue so
+// they are delivered after the message itself
+func (b *Bot) update(channel, ts string, options ...slack.MsgOption) error {
+	return b.enqueue(&outgoingMessage{
+		channel: channel,
+		options: options,
+		ts:      ts,
+	})
+}
+
+func (b *Bot) enqueue(msg *outgoingMessage) error {
+	if b.sendQueue == nil {
+		return b.deliver(context.Background(), msg)
+	}
+
+	select {
+	case b.sendQueue <- msg:
+		return nil
+	default:
+		slackFailedMessagesCounter.Inc()
+		log.WithFields(log.Fields{
+			"channel": msg.channel,
+		}).Error("bot.slack: send queue is full, dropping message")
+		return ErrSendQueueFull
+	}
+}
+
+// deliver - sends message, retries when rate limited (honoring Retry-After)
+// or on other errors with exponential backoff
+func (b *Bot) deliver(ctx context.Context, msg *outgoingMessage) error {
+	var backoff time.Duration
+	for attempt := 1; ; attempt++ {
+		var channel, ts string
+		var err error
+		switch {
+		case msg.blocks != nil:
+			channel, ts, err = b.slackBlocksClient.PostBlocks(msg.channel, msg.blocks)
+		case msg.ts != "":
+			channel, ts, _, err = b.slackHTTPClient.UpdateMessage(msg.channel, msg.ts, msg.options...)
+		default:
+			channel, ts, err = b.slackHTTPClient.PostMessage(msg.channel, msg.options...)
+		}
+		if err == nil {
+			if msg.sent != nil {
+				msg.sent(channel, ts)
+			}
+			return nil
+		}
+
+		if attempt >= maxSendAttempts {
+			slackFailedMessagesCounter.Inc()
+			log.WithFields(log.Fields{
+				"error":    err,
+				"channel":  msg.channel,
+				"attempts": attempt,
+			}).Error("bot.slack: failed to send message, giving up")
+			return err
+		}
+
+		var wait time.Duration
+		if rateLimitErr, ok := err.(*slack.RateLimitedError); ok {
+			slackRateLimitedCounter.Inc()
+			wait = rateLimitErr.RetryAfter
+		} else {
+			backoff = timeutil.ExpBackoff(backoff, maxSendBackoff)
+			wait = backoff
+		}
+
+		log.WithFields(log.Fields{
+			"error":   err,
+			"channel": msg.channel,
+			"attempt": attempt,
+			"retry":   wait,
+		}).Warn("bot.slack: failed to send message, retrying")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package slack
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+type rateLimitedImplementer struct {
+	mu        sync.Mutex
+	calls     int
+	failTimes int
+	uploaded  []slack.FileUploadParameters
+}
+
+func (i *rateLimitedImplementer) getCalls() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.calls
+}
+
+func (i *rateLimitedImplementer) PostMessage(channelID string, options ...slack.MsgOption) (string, string, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.calls++
+	if i.calls <= i.failTimes {
+		return "", "", &slack.RateLimitedError{RetryAfter: 10 * time.Millisecond}
+	}
+	return channelID, "ts", nil
+}
+
+func (i *rateLimitedImplementer) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+	channel, ts, err := i.PostMessage(channelID, options...)
+	return channel, ts, "", err
+}
+
+func (i *rateLimitedImplementer) UploadFile(params slack.FileUploadParameters) (*slack.File, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.uploaded = append(i.uploaded, params)
+	return &slack.File{}, nil
+}
+
+func TestDeliverRateLimited(t *testing.T) {
+	fi := &rateLimitedImplementer{failTimes: 2}
+	b := &Bot{slackHTTPClient: fi}
+
+	err := b.send("approvals", slack.MsgOptionText("hi", false))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if fi.calls != 3 {
+		t.Errorf("expected 3 calls, got: %d", fi.calls)
+	}
+}
+
+func TestDeliverGivesUp(t *testing.T) {
+	fi := &rateLimitedImplementer{failTimes: 100}
+	b := &Bot{slackHTTPClient: fi}
+
+	err := b.send("approvals", slack.MsgOptionText("hi", false))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	if fi.calls != maxSendAttempts {
+		t.Errorf("expected %d calls, got: %d", maxSendAttempts, fi.calls)
+	}
+}
+
+func TestSendQueue(t *testing.T) {
+	fi := &rateLimitedImplementer{failTimes: 1}
+	b := &Bot{slackHTTPClient: fi}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b.startSendQueue(ctx)
+
+	err := b.send("approvals", slack.MsgOptionText("hi", false))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for fi.getCalls() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if fi.getCalls() != 2 {
+		t.Errorf("expected 2 calls, got: %d", fi.getCalls())
+	}
+}
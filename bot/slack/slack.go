@@ -5,15 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nlopes/slack"
 
 	"github.com/keel-hq/keel/bot"
-	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/i18n"
 	"github.com/keel-hq/keel/version"
 
 	log "github.com/sirupsen/logrus"
@@ -23,6 +24,8 @@ import (
 // send messages with attachments
 type SlackImplementer interface {
 	PostMessage(channelID string, options ...slack.MsgOption) (string, string, error)
+	UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error)
+	UploadFile(params slack.FileUploadParameters) (*slack.File, error)
 }
 
 // Bot - main slack bot container
@@ -31,6 +34,8 @@ type Bot struct {
 	name string // bot name
 
 	users map[string]string
+	// emails - user IDs by email, used to mention on-call users
+	emails map[string]string
 
 	msgPrefix string
 
@@ -38,45 +43,79 @@ type Bot struct {
 	slackRTM    *slack.RTM
 
 	slackHTTPClient SlackImplementer
+	// slackBlocksClient - posts Block Kit messages, ie: structured command responses
+	slackBlocksClient blocksPoster
 
 	approvalsChannel string // slack approvals channel name
 
+	workspace  *Workspace   // workspace this bot is connected to
+	workspaces []*Workspace // all configured workspaces, used to route approvals
+
+	sendQueue chan *outgoingMessage
+
+	// conversations - approval request messages and approval requests sent
+	// to the approvers, rollout progress is posted in threads of the requests
+	conversationsM sync.Mutex
+	conversations  bot.ConversationStore
+
+	// rtmM - RTM connection is replaced when it's reconnected
+	rtmM    sync.RWMutex
+	monitor *bot.ConnectionMonitor
+
 	ctx                context.Context
 	botMessagesChannel chan *bot.BotMessage
 	approvalsRespCh    chan *bot.ApprovalResponse
 }
 
 func init() {
-	bot.RegisterBot("slack", &Bot{})
+	workspaces := getWorkspaces()
+	for _, ws := range workspaces {
+		bot.RegisterBot(ws.botName(), &Bot{workspace: ws, workspaces: workspaces})
+	}
 }
 
 func (b *Bot) Configure(approvalsRespCh chan *bot.ApprovalResponse, botMessagesChannel chan *bot.BotMessage) bool {
-	if os.Getenv(constants.EnvSlackToken) != "" {
+	if b.workspace == nil {
+		b.workspaces = getWorkspaces()
+		b.workspace = b.workspaces[0]
+	}
+
+	if b.workspace.Token != "" {
 
 		b.name = "keel"
-		if bootName := os.Getenv(constants.EnvSlackBotName); bootName != "" {
+		if bootName := b.workspace.BotName; bootName != "" {
 			b.name = bootName
 		}
 
-		token := os.Getenv(constants.EnvSlackToken)
-		client := slack.New(token)
+		client := slack.New(b.workspace.Token)
 
 		b.approvalsChannel = "general"
-		if channel := os.Getenv(constants.EnvSlackApprovalsChannel); channel != "" {
+		if channel := b.workspace.ApprovalsChannel; channel != "" {
 			b.approvalsChannel = strings.TrimPrefix(channel, "#")
 		}
 
 		b.slackClient = client
 		b.slackHTTPClient = client
+		b.slackBlocksClient = newBlocksClient(b.workspace.Token, b.name)
 		b.approvalsRespCh = approvalsRespCh
 		b.botMessagesChannel = botMessagesChannel
 
 		return true
 	}
-	log.Info("bot.slack.Configure(): Slack approval bot is not configured")
+	log.WithFields(log.Fields{
+		"workspace": b.workspace.Name,
+	}).Info("bot.slack.Configure(): Slack approval bot is not configured")
 	return false
 }
 
+// ownsApproval - checks whether approval should be handled by this bot's workspace
+func (b *Bot) ownsApproval(approval *types.Approval) bool {
+	if b.workspace == nil {
+		return true
+	}
+	return approvalWorkspace(approval, b.workspaces) == b.workspace.Name
+}
+
 // Start - start bot
 func (b *Bot) Start(ctx context.Context) error {
 	// setting root context
@@ -88,8 +127,13 @@ func (b *Bot) Start(ctx context.Context) error {
 	}
 
 	b.users = map[string]string{}
+	b.emails = map[string]string{}
 
 	for _, user := range users {
+		b.users[strings.ToLower(user.Name)] = user.ID
+		if user.Profile.Email != "" {
+			b.emails[strings.ToLower(user.Profile.Email)] = user.ID
+		}
 		switch user.Name {
 		case b.name:
 			if user.IsBot {
@@ -105,35 +149,141 @@ func (b *Bot) Start(ctx context.Context) error {
 
 	b.msgPrefix = strings.ToLower("<@" + b.id + ">")
 
-	go b.startInternal()
+	b.startSendQueue(ctx)
+	go b.expireApprovalMessages(ctx)
+
+	b.monitor = bot.NewConnectionMonitor(b.workspace.botName())
+	go b.supervise()
 
 	return nil
 }
 
-func (b *Bot) startInternal() error {
-	b.slackRTM = b.slackClient.NewRTM()
+// Healthy - error when RTM connection to the workspace is down longer than
+// the disconnect threshold
+func (b *Bot) Healthy() error {
+	if b.monitor == nil {
+		return errors.New("not started")
+	}
+	return b.monitor.Healthy()
+}
+
+const (
+	// staleAfter - RTM pings every 30s, connections without any events for
+	// longer are considered dead and recreated
+	staleAfter = 2 * time.Minute
+	// checkInterval - how often the connection is checked
+	checkInterval = 10 * time.Second
+
+	minReconnectBackOff = time.Second
+	maxReconnectBackOff = 5 * time.Minute
+)
+
+func (b *Bot) rtm() *slack.RTM {
+	b.rtmM.RLock()
+	defer b.rtmM.RUnlock()
+	return b.slackRTM
+}
+
+// supervise - keeps RTM connection up, it's recreated with backoff when it
+// ends (ie: invalid credentials or dead connection) until the bot is stopped
+func (b *Bot) supervise() {
+	var backOff time.Duration
+	for {
+		connected, err := b.startInternal()
+		if b.ctx.Err() != nil {
+			return
+		}
+		b.monitor.Disconnected(err)
+
+		if connected {
+			backOff = 0
+		}
+		backOff = nextBackOff(backOff)
+		log.WithFields(log.Fields{
+			"error":     err,
+			"workspace": b.workspace.Name,
+			"retry_in":  backOff,
+		}).Warn("bot.slack: RTM connection ended, reconnecting")
+
+		retry := time.After(backOff)
+		ticker := time.NewTicker(checkInterval)
+	wait:
+		for {
+			select {
+			case <-b.ctx.Done():
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				b.monitor.Check()
+			case <-retry:
+				break wait
+			}
+		}
+		ticker.Stop()
+	}
+}
+
+func nextBackOff(current time.Duration) time.Duration {
+	if current == 0 {
+		return minReconnectBackOff
+	}
+	current *= 2
+	if current > maxReconnectBackOff {
+		return maxReconnectBackOff
+	}
+	return current
+}
+
+// startInternal - handles events of a new RTM connection until the bot is
+// stopped or the connection ends, connected is set when the connection was
+// established
+func (b *Bot) startInternal() (connected bool, err error) {
+	rtm := b.slackClient.NewRTM()
+	b.rtmM.Lock()
+	b.slackRTM = rtm
+	b.rtmM.Unlock()
 
-	go b.slackRTM.ManageConnection()
+	go rtm.ManageConnection()
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	lastEvent := time.Now()
 	for {
 		select {
 		case <-b.ctx.Done():
-			return nil
+			rtm.Disconnect()
+			return connected, nil
+
+		case <-ticker.C:
+			b.monitor.Check()
+			if time.Since(lastEvent) > staleAfter {
+				abandon(rtm)
+				return connected, fmt.Errorf("no events received for %s", staleAfter)
+			}
 
-		case msg := <-b.slackRTM.IncomingEvents:
+		case msg := <-rtm.IncomingEvents:
+			lastEvent = time.Now()
 			switch ev := msg.Data.(type) {
 			case *slack.HelloEvent:
 				// Ignore hello
 			case *slack.ConnectedEvent:
-				// nothing to do
+				connected = true
+				b.monitor.Connected()
+			case *slack.DisconnectedEvent:
+				b.monitor.Disconnected(errors.New("disconnected"))
+			case *slack.ConnectionErrorEvent:
+				b.monitor.Disconnected(ev)
 			case *slack.MessageEvent:
 				b.handleMessage(ev)
 			case *slack.PresenceChangeEvent:
 				// nothing to do
 			case *slack.RTMError:
-				log.Error("Error: %s", ev.Error())
+				log.Errorf("bot.slack: RTM error: %s", ev.Error())
 			case *slack.InvalidAuthEvent:
-				log.Error("Invalid credentials")
-				return fmt.Errorf("invalid credentials")
+				// connection isn't retried by RTM, token could be fixed though
+				log.Error("bot.slack: invalid credentials")
+				abandon(rtm)
+				return connected, errors.New("invalid credentials")
 
 			default:
 
@@ -144,42 +294,80 @@ func (b *Bot) startInternal() error {
 	}
 }
 
-func (b *Bot) postMessage(title, message, color string, fields []slack.AttachmentField) error {
+// abandon - disconnects RTM connection that is no longer used, its remaining
+// events are drained so that RTM goroutines can exit
+func abandon(rtm *slack.RTM) {
+	rtm.Disconnect()
+	go func() {
+		for {
+			select {
+			case <-rtm.IncomingEvents:
+			case <-time.After(time.Minute):
+				return
+			}
+		}
+	}()
+}
+
+// postTrackedMessage - posts message to the approvals channel, sent is called
+// with the message channel ID and timestamp once it's delivered
+func (b *Bot) postTrackedMessage(title, message, color string, fields []slack.AttachmentField, sent func(channel, ts string), opts ...slack.MsgOption) error {
+	return b.postMessageTo(b.approvalsChannel, title, message, color, fields, sent, opts...)
+}
+
+// postMessageTo - posts message to the channel, sent is optional
+func (b *Bot) postMessageTo(channel, title, message, color string, fields []slack.AttachmentField, sent func(channel, ts string), opts ...slack.MsgOption) error {
 	params := slack.NewPostMessageParameters()
 	params.Username = b.name
 
-	attachements := []slack.Attachment{
-		slack.Attachment{
-			Fallback: message,
-			Color:    color,
-			Fields:   fields,
-			Footer:   fmt.Sprintf("https://keel.sh %s", version.GetKeelVersion().Version),
-			Ts:       json.Number(strconv.Itoa(int(time.Now().Unix()))),
-		},
-	}
-
 	var mgsOpts []slack.MsgOption
 
 	mgsOpts = append(mgsOpts, slack.MsgOptionPostMessageParameters(params))
-	mgsOpts = append(mgsOpts, slack.MsgOptionAttachments(attachements...))
+	mgsOpts = append(mgsOpts, slack.MsgOptionAttachments(attachment(message, color, fields)))
+	mgsOpts = append(mgsOpts, opts...)
 
-	_, _, err := b.slackHTTPClient.PostMessage(b.approvalsChannel, mgsOpts...)
+	err := b.sendTracked(channel, sent, mgsOpts...)
 	if err != nil {
 		log.WithFields(log.Fields{
-			"error":             err,
-			"approvals_channel": b.approvalsChannel,
+			"error":   err,
+			"channel": channel,
 		}).Error("bot.postMessage: failed to send message")
 	}
 	return err
 }
 
+// Announce - posts test message to the approvals channel, it skips the send
+// queue so channel and permission errors are returned
+func (b *Bot) Announce(text string) error {
+	params := slack.NewPostMessageParameters()
+	params.Username = b.name
+	_, _, err := b.slackHTTPClient.PostMessage(b.approvalsChannel,
+		slack.MsgOptionPostMessageParameters(params),
+		slack.MsgOptionAttachments(attachment(text, types.LevelSuccess.Color(), nil)),
+	)
+	if err != nil {
+		return fmt.Errorf("channel %s: %s", b.approvalsChannel, err)
+	}
+	return nil
+}
+
+func attachment(message, color string, fields []slack.AttachmentField) slack.Attachment {
+	return slack.Attachment{
+		Fallback: message,
+		Color:    color,
+		Fields:   fields,
+		Footer:   fmt.Sprintf("https://keel.sh %s", version.GetKeelVersion().Version),
+		Ts:       json.Number(strconv.Itoa(int(time.Now().Unix()))),
+	}
+}
+
 // checking if message was received in approvals channel
 func (b *Bot) isApprovalsChannel(event *slack.MessageEvent) bool {
 
 	channel, err := b.slackClient.GetChannelInfo(event.Channel)
 	if err != nil {
 		// looking for private channel
-		conv, err := b.slackRTM.GetConversationInfo(event.Channel, true)
+		conv, err := b.rtm().GetConversationInfo(event.Channel, true)
 		if err != nil {
 			log.Errorf("couldn't find amongst private conversations: %s", err)
 		} else if conv.Name == b.approvalsChannel {
@@ -220,6 +408,17 @@ func (b *Bot) handleMessage(event *slack.MessageEvent) {
 	eventText = b.trimBot(eventText)
 
 	approval, ok := bot.IsApproval(event.User, eventText)
+	if ok {
+		direct, private := b.directVote(event.Channel, approval)
+		switch {
+		case direct:
+			b.approvalsRespCh <- approval
+			return
+		case private:
+			b.RespondInThread(i18n.T("votes for private approvals are only accepted in direct messages from the approvers"), event.Channel, messageThread(event))
+			return
+		}
+	}
 	// only accepting approvals from approvals channel
 	if ok && b.isApprovalsChannel(event) {
 		b.approvalsRespCh <- approval
@@ -229,7 +428,7 @@ func (b *Bot) handleMessage(event *slack.MessageEvent) {
 			"received_on":    event.Channel,
 			"approvals_chan": b.approvalsChannel,
 		}).Warnf("message was received not in approvals channel: %s", event.Channel)
-		b.Respond(fmt.Sprintf("please use approvals channel '%s'", b.approvalsChannel), event.Channel)
+		b.RespondInThread(i18n.T("please use approvals channel '%s'", b.approvalsChannel), event.Channel, messageThread(event))
 		return
 	}
 
@@ -237,28 +436,65 @@ func (b *Bot) handleMessage(event *slack.MessageEvent) {
 		Message: eventText,
 		User:    event.User,
 		Channel: event.Channel,
-		Name:    "slack",
+		Thread:  messageThread(event),
+		Name:    b.botName(),
+	}
+}
+
+// messageThread - thread that responses to the message are posted in, replies
+// in direct messages aren't threaded
+func messageThread(event *slack.MessageEvent) string {
+	if strings.HasPrefix(event.Channel, "D") {
+		return ""
+	}
+	if event.ThreadTimestamp != "" {
+		return event.ThreadTimestamp
+	}
+	return event.Timestamp
+}
+
+// botName - name that this bot is registered with
+func (b *Bot) botName() string {
+	if b.workspace == nil {
+		return "slack"
 	}
+	return b.workspace.botName()
 }
 
 func (b *Bot) Respond(text string, channel string) {
+	b.RespondInThread(text, channel, "")
+}
+
+// RespondInThread - replies in the thread of the command message, empty
+// thread replies in the channel
+func (b *Bot) RespondInThread(text, channel, thread string) {
 
 	// if message is short, replying directly via slack RTM
 	if len(text) < 3000 {
-		b.slackRTM.SendMessage(b.slackRTM.NewOutgoingMessage(formatAsSnippet(text), channel))
+		rtm := b.rtm()
+		var opts []slack.RTMsgOption
+		if thread != "" {
+			opts = append(opts, slack.RTMsgOptionTS(thread))
+		}
+		rtm.SendMessage(rtm.NewOutgoingMessage(formatAsSnippet(text), channel, opts...))
 		return
 	}
 
 	// longer messages are getting uploaded as files
+	b.upload(text, channel, thread)
+}
 
+// upload - uploads response as a snippet into the thread
+func (b *Bot) upload(text, channel, thread string) {
 	f := slack.FileUploadParameters{
-		Filename: "keel response",
-		Content:  text,
-		Filetype: "text",
-		Channels: []string{channel},
+		Filename:        "keel response",
+		Content:         text,
+		Filetype:        "text",
+		Channels:        []string{channel},
+		ThreadTimestamp: thread,
 	}
 
-	_, err := b.slackClient.UploadFile(f)
+	_, err := b.slackHTTPClient.UploadFile(f)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err,
@@ -266,6 +502,34 @@ func (b *Bot) Respond(text string, channel string) {
 	}
 }
 
+// RespondStructured - renders structured response as a Block Kit table
+func (b *Bot) RespondStructured(resp *bot.Response, channel string) {
+	b.RespondStructuredInThread(resp, channel, "")
+}
+
+// RespondStructuredInThread - renders structured response as a Block Kit table
+// in the thread of the command message, responses that don't fit into a
+// message are uploaded as snippets
+func (b *Bot) RespondStructuredInThread(resp *bot.Response, channel, thread string) {
+	blocks, ok := responseBlocks(resp)
+	if !ok {
+		b.upload(resp.String(), channel, thread)
+		return
+	}
+
+	err := b.sendBlocks(channel, &blocksMessage{
+		text:   responseText(resp),
+		thread: thread,
+		blocks: blocks,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"channel": channel,
+		}).Error("RespondStructured: failed to send message")
+	}
+}
+
 func (b *Bot) isBotMessage(event *slack.MessageEvent, eventText string) bool {
 	prefixes := []string{
 		b.msgPrefix,
@@ -273,7 +537,7 @@ func (b *Bot) isBotMessage(event *slack.MessageEvent, eventText string) bool {
 		// "kel",
 	}
 
-	for _, p := range prefixes {
+	for _, p := range append(prefixes, bot.Prefixes()...) {
 		if strings.HasPrefix(eventText, p) {
 			return true
 		}
@@ -284,6 +548,9 @@ func (b *Bot) isBotMessage(event *slack.MessageEvent, eventText string) bool {
 }
 
 func (b *Bot) trimBot(msg string) string {
+	if trimmed, ok := bot.TrimPrefix(msg); ok {
+		return trimmed
+	}
 	msg = strings.Replace(msg, strings.ToLower(b.msgPrefix), "", 1)
 	msg = strings.TrimPrefix(msg, b.name)
 	msg = strings.Trim(msg, " :\n")
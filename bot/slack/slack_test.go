@@ -3,6 +3,7 @@ package slack
 import (
 	"fmt"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/nlopes/slack"
@@ -12,8 +13,8 @@ import (
 
 	"github.com/keel-hq/keel/approvals"
 	b "github.com/keel-hq/keel/bot"
-	"github.com/keel-hq/keel/cache/memory"
 	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/pkg/store/sql"
 	"github.com/keel-hq/keel/types"
 
 	"testing"
@@ -33,7 +34,7 @@ func New(name, token, channel string,
 
 	slack := &Bot{}
 	b.RegisterBot(name, slack)
-	b.Run(k8sImplementer, approvalsManager)
+	b.Run(k8sImplementer, approvalsManager, nil, nil)
 	slack.slackHTTPClient = fi
 	return slack
 }
@@ -69,7 +70,9 @@ type postedMessage struct {
 }
 
 type fakeSlackImplementer struct {
-	postedMessages []postedMessage
+	postedMessages  []postedMessage
+	updatedMessages []postedMessage
+	uploadedFiles   []slack.FileUploadParameters
 }
 
 // func (i *fakeSlackImplementer) PostMessage(channel, text string, params slack.PostMessageParameters) (string, string, error) {
@@ -83,20 +86,32 @@ func (i *fakeSlackImplementer) PostMessage(channelID string, options ...slack.Ms
 	return "", "", nil
 }
 
+func (i *fakeSlackImplementer) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+	i.updatedMessages = append(i.updatedMessages, postedMessage{
+		channel: channelID,
+		msg:     options,
+	})
+	return channelID, timestamp, "", nil
+}
+
+func (i *fakeSlackImplementer) UploadFile(params slack.FileUploadParameters) (*slack.File, error) {
+	i.uploadedFiles = append(i.uploadedFiles, params)
+	return &slack.File{}, nil
+}
+
 func TestBotRequest(t *testing.T) {
 
 	os.Setenv(constants.EnvSlackToken, "")
 
 	f8s := &testutil.FakeK8sImplementer{}
 	fi := &fakeSlackImplementer{}
-	mem := memory.NewMemoryCache()
 
 	token := os.Getenv(constants.EnvSlackToken)
 	if token == "" {
 		t.Skip()
 	}
 
-	am := approvals.New(mem)
+	am := approver()
 
 	New("keel", token, "approvals", f8s, am, fi)
 	defer b.Stop()
@@ -133,14 +148,13 @@ func TestProcessApprovedResponse(t *testing.T) {
 
 	f8s := &testutil.FakeK8sImplementer{}
 	fi := &fakeSlackImplementer{}
-	mem := memory.NewMemoryCache()
 
 	token := os.Getenv(constants.EnvSlackToken)
 	if token == "" {
 		t.Skip()
 	}
 
-	am := approvals.New(mem)
+	am := approver()
 
 	New("keel", token, "approvals", f8s, am, fi)
 	defer b.Stop()
@@ -177,14 +191,13 @@ func TestProcessApprovalReply(t *testing.T) {
 
 	f8s := &testutil.FakeK8sImplementer{}
 	fi := &fakeSlackImplementer{}
-	mem := memory.NewMemoryCache()
 
 	token := os.Getenv(constants.EnvSlackToken)
 	if token == "" {
 		t.Skip()
 	}
 
-	am := approvals.New(mem)
+	am := approver()
 
 	identifier := "k8s/project/repo:1.2.3"
 
@@ -245,7 +258,6 @@ func TestProcessRejectedReply(t *testing.T) {
 
 	f8s := &testutil.FakeK8sImplementer{}
 	fi := &fakeSlackImplementer{}
-	mem := memory.NewMemoryCache()
 
 	token := os.Getenv(constants.EnvSlackToken)
 	if token == "" {
@@ -254,7 +266,7 @@ func TestProcessRejectedReply(t *testing.T) {
 
 	identifier := "k8s/project/repo:1.2.3"
 
-	am := approvals.New(mem)
+	am := approver()
 	// creating initial approve request
 	err := am.Create(&types.Approval{
 		Identifier:     identifier,
@@ -350,3 +362,15 @@ func TestIsApproval(t *testing.T) {
 		t.Errorf("event expected to be an approval")
 	}
 }
+
+var approverDatabases int32
+
+// approver - approvals manager with its own in-memory sqlite store
+func approver() *approvals.DefaultManager {
+	uri := fmt.Sprintf("file:approver%d?mode=memory&cache=shared", atomic.AddInt32(&approverDatabases, 1))
+	store, err := sql.New(sql.Opts{DatabaseType: sql.DatabaseTypeSQLite, URI: uri})
+	if err != nil {
+		panic(err)
+	}
+	return approvals.New(&approvals.Opts{Store: store})
+}
@@ -0,0 +1,195 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nlopes/slack"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/version"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// approvalThreadTTL - how long approval conversations are remembered after
+// the approval deadline, rollouts of updates approved later aren't reported
+const approvalThreadTTL = 24 * time.Hour
+
+// SetConversationStore - sets store of approval conversations, conversations
+// are only kept in memory when it's not set
+func (b *Bot) SetConversationStore(conversations bot.ConversationStore) {
+	b.conversationsM.Lock()
+	b.conversations = conversations
+	b.conversationsM.Unlock()
+}
+
+// conversationBot - name the conversations of this bot are stored under
+func (b *Bot) conversationBot() string {
+	if b.workspace == nil {
+		return "slack"
+	}
+	return b.workspace.botName()
+}
+
+// conversationStore - must be called with conversationsM locked
+func (b *Bot) conversationStore() bot.ConversationStore {
+	if b.conversations == nil {
+		b.conversations = bot.NewMemoryConversationStore()
+	}
+	return b.conversations
+}
+
+// getConversation - stored conversation of the approval, nil when there isn't
+// one. Must be called with conversationsM locked.
+func (b *Bot) getConversation(identifier string) *types.BotConversation {
+	conversation, err := b.conversationStore().GetBotConversation(b.conversationBot(), identifier)
+	if err != nil {
+		if err != store.ErrRecordNotFound {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"approval": identifier,
+			}).Error("bot.slack: failed to get approval conversation")
+		}
+		return nil
+	}
+	return conversation
+}
+
+// conversationOf - stored conversation of the approval or a new one. Must be
+// called with conversationsM locked.
+func (b *Bot) conversationOf(approval *types.Approval) *types.BotConversation {
+	if conversation := b.getConversation(approval.Identifier); conversation != nil {
+		return conversation
+	}
+
+	expires := time.Now().Add(approvalThreadTTL)
+	if deadline := approval.Deadline.Add(approvalThreadTTL); deadline.After(expires) {
+		expires = deadline
+	}
+	return &types.BotConversation{
+		Bot:        b.conversationBot(),
+		Identifier: approval.Identifier,
+		Expires:    expires,
+	}
+}
+
+// saveConversation - must be called with conversationsM locked
+func (b *Bot) saveConversation(conversation *types.BotConversation) {
+	err := b.conversationStore().SaveBotConversation(conversation)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"approval": conversation.Identifier,
+		}).Error("bot.slack: failed to save approval conversation")
+	}
+}
+
+// rememberThread - stores approval request message
+func (b *Bot) rememberThread(approval *types.Approval, channel, ts string) {
+	if ts == "" {
+		return
+	}
+
+	b.conversationsM.Lock()
+	defer b.conversationsM.Unlock()
+
+	conversation := b.conversationOf(approval)
+	conversation.Channel = channel
+	conversation.TS = ts
+	conversation.SetApproval(approval)
+	b.saveConversation(conversation)
+}
+
+// approvalThread - conversation of the approval request message, nil when the
+// message isn't known
+func (b *Bot) approvalThread(identifier string) *types.BotConversation {
+	b.conversationsM.Lock()
+	defer b.conversationsM.Unlock()
+
+	conversation := b.getConversation(identifier)
+	if conversation == nil || conversation.TS == "" {
+		return nil
+	}
+	return conversation
+}
+
+// updateThread - stores latest approval state, returns the conversation when
+// its request message should be updated
+func (b *Bot) updateThread(approval *types.Approval, final bool) *types.BotConversation {
+	b.conversationsM.Lock()
+	defer b.conversationsM.Unlock()
+
+	conversation := b.getConversation(approval.Identifier)
+	if conversation == nil || conversation.TS == "" || conversation.Final {
+		return nil
+	}
+	conversation.SetApproval(approval)
+	conversation.Final = final
+	b.saveConversation(conversation)
+	return conversation
+}
+
+// expiredThreads - conversations of approvals past their deadline, they are
+// marked as final. Conversations past their TTL are deleted.
+func (b *Bot) expiredThreads(now time.Time) []*types.BotConversation {
+	b.conversationsM.Lock()
+	defer b.conversationsM.Unlock()
+
+	conversations, err := b.conversationStore().ListBotConversations(b.conversationBot())
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("bot.slack: failed to list approval conversations")
+		return nil
+	}
+
+	var expired []*types.BotConversation
+	for _, conversation := range conversations {
+		approval := conversation.GetApproval()
+		if conversation.TS == "" || conversation.Final || approval == nil || approval.Deadline.IsZero() || approval.Deadline.After(now) {
+			continue
+		}
+		conversation.Final = true
+		b.saveConversation(conversation)
+		expired = append(expired, conversation)
+	}
+
+	err = b.conversationStore().DeleteBotConversations(now)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("bot.slack: failed to delete expired approval conversations")
+	}
+	return expired
+}
+
+// ReplyToRollout - posts rollout progress of approved update in the thread of
+// its approval request, rollouts of approvals requested by other bots are ignored
+func (b *Bot) ReplyToRollout(approvalIdentifier string, event types.EventNotification) error {
+	thread := b.approvalThread(approvalIdentifier)
+	if thread == nil {
+		return nil
+	}
+
+	params := slack.NewPostMessageParameters()
+	params.Username = b.name
+
+	attachment := slack.Attachment{
+		Fallback: event.Message,
+		Color:    event.Level.Color(),
+		Text:     event.Message,
+		Footer:   fmt.Sprintf("https://keel.sh %s", version.GetKeelVersion().Version),
+		Ts:       json.Number(strconv.Itoa(int(event.CreatedAt.Unix()))),
+	}
+
+	return b.send(thread.Channel,
+		slack.MsgOptionPostMessageParameters(params),
+		slack.MsgOptionTS(thread.TS),
+		slack.MsgOptionAttachments(attachment),
+	)
+}
@@ -0,0 +1,93 @@
+package slack
+
+import (
+	"os"
+	"strings"
+
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/types"
+)
+
+// DefaultWorkspace - name of the workspace configured with SLACK_TOKEN
+const DefaultWorkspace = "default"
+
+// Workspace - Slack workspace configuration
+type Workspace struct {
+	Name             string
+	Token            string
+	BotName          string
+	ApprovalsChannel string
+	// Namespaces - approvals for resources in these namespaces
+	// are routed to this workspace
+	Namespaces []string
+}
+
+// workspaceEnv - SLACK_<NAME>_<KEY>, ie: SLACK_OPS_TOKEN
+func workspaceEnv(name, key string) string {
+	name = strings.ToUpper(strings.Replace(name, "-", "_", -1))
+	return os.Getenv("SLACK_" + name + "_" + key)
+}
+
+// getWorkspaces - reads default and additional workspace configuration
+// from environment variables
+func getWorkspaces() []*Workspace {
+	workspaces := []*Workspace{
+		{
+			Name:             DefaultWorkspace,
+			Token:            os.Getenv(constants.EnvSlackToken),
+			BotName:          os.Getenv(constants.EnvSlackBotName),
+			ApprovalsChannel: os.Getenv(constants.EnvSlackApprovalsChannel),
+		},
+	}
+
+	for _, name := range strings.Split(os.Getenv(constants.EnvSlackWorkspaces), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || name == DefaultWorkspace {
+			continue
+		}
+
+		ws := &Workspace{
+			Name:             name,
+			Token:            workspaceEnv(name, "TOKEN"),
+			BotName:          workspaceEnv(name, "BOT_NAME"),
+			ApprovalsChannel: workspaceEnv(name, "APPROVALS_CHANNEL"),
+		}
+		for _, ns := range strings.Split(workspaceEnv(name, "NAMESPACES"), ",") {
+			if ns = strings.TrimSpace(ns); ns != "" {
+				ws.Namespaces = append(ws.Namespaces, ns)
+			}
+		}
+		workspaces = append(workspaces, ws)
+	}
+
+	return workspaces
+}
+
+// botName - name that workspace bot is registered with
+func (w *Workspace) botName() string {
+	if w.Name == DefaultWorkspace {
+		return "slack"
+	}
+	return "slack-" + w.Name
+}
+
+// approvalWorkspace - finds workspace that should handle the approval. Workspace set
+// through the approval (keel.sh/approvalsWorkspace annotation) takes priority, then
+// namespace mapping is checked, otherwise approval goes to the default workspace.
+func approvalWorkspace(approval *types.Approval, workspaces []*Workspace) string {
+	if approval.Workspace != "" {
+		return strings.ToLower(approval.Workspace)
+	}
+
+	namespace := approvals.Namespace(approval.Identifier)
+	for _, ws := range workspaces {
+		for _, ns := range ws.Namespaces {
+			if ns == namespace {
+				return ws.Name
+			}
+		}
+	}
+
+	return DefaultWorkspace
+}
@@ -0,0 +1,129 @@
+package slack
+
+import (
+	"os"
+	"testing"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/types"
+)
+
+func TestGetWorkspaces(t *testing.T) {
+	os.Setenv(constants.EnvSlackToken, "default-token")
+	os.Setenv(constants.EnvSlackWorkspaces, "ops, data-eng")
+	os.Setenv("SLACK_OPS_TOKEN", "ops-token")
+	os.Setenv("SLACK_OPS_APPROVALS_CHANNEL", "#ops-approvals")
+	os.Setenv("SLACK_DATA_ENG_TOKEN", "data-token")
+	os.Setenv("SLACK_DATA_ENG_NAMESPACES", "etl, warehouse")
+	defer func() {
+		for _, env := range []string{constants.EnvSlackToken, constants.EnvSlackWorkspaces, "SLACK_OPS_TOKEN",
+			"SLACK_OPS_APPROVALS_CHANNEL", "SLACK_DATA_ENG_TOKEN", "SLACK_DATA_ENG_NAMESPACES"} {
+			os.Unsetenv(env)
+		}
+	}()
+
+	workspaces := getWorkspaces()
+	if len(workspaces) != 3 {
+		t.Fatalf("expected 3 workspaces, got: %d", len(workspaces))
+	}
+
+	if workspaces[0].Name != DefaultWorkspace || workspaces[0].Token != "default-token" || workspaces[0].botName() != "slack" {
+		t.Errorf("unexpected default workspace: %#v", workspaces[0])
+	}
+
+	if workspaces[1].Name != "ops" || workspaces[1].Token != "ops-token" || workspaces[1].ApprovalsChannel != "#ops-approvals" {
+		t.Errorf("unexpected ops workspace: %#v", workspaces[1])
+	}
+	if workspaces[1].botName() != "slack-ops" {
+		t.Errorf("unexpected bot name: %s", workspaces[1].botName())
+	}
+
+	if workspaces[2].Name != "data-eng" || workspaces[2].Token != "data-token" {
+		t.Errorf("unexpected data-eng workspace: %#v", workspaces[2])
+	}
+	if len(workspaces[2].Namespaces) != 2 || workspaces[2].Namespaces[0] != "etl" || workspaces[2].Namespaces[1] != "warehouse" {
+		t.Errorf("unexpected namespaces: %v", workspaces[2].Namespaces)
+	}
+}
+
+func TestApprovalWorkspace(t *testing.T) {
+	workspaces := []*Workspace{
+		{Name: DefaultWorkspace},
+		{Name: "ops", Namespaces: []string{"infra"}},
+		{Name: "data", Namespaces: []string{"etl"}},
+	}
+
+	tests := []struct {
+		name     string
+		approval *types.Approval
+		want     string
+	}{
+		{
+			name:     "by namespace",
+			approval: &types.Approval{Provider: types.ProviderTypeKubernetes, Identifier: "deployment/etl/worker:1.2.3"},
+			want:     "data",
+		},
+		{
+			name:     "helm by namespace",
+			approval: &types.Approval{Provider: types.ProviderTypeHelm, Identifier: "infra/ingress:1.2.3"},
+			want:     "ops",
+		},
+		{
+			name:     "annotation overrides namespace",
+			approval: &types.Approval{Provider: types.ProviderTypeKubernetes, Identifier: "deployment/etl/worker:1.2.3", Workspace: "Ops"},
+			want:     "ops",
+		},
+		{
+			name:     "default",
+			approval: &types.Approval{Provider: types.ProviderTypeKubernetes, Identifier: "deployment/default/wd:1.2.3"},
+			want:     DefaultWorkspace,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := approvalWorkspace(tt.approval, workspaces); got != tt.want {
+				t.Errorf("approvalWorkspace() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestApprovalOtherWorkspace(t *testing.T) {
+	fi := &rateLimitedImplementer{}
+	workspaces := []*Workspace{
+		{Name: DefaultWorkspace},
+		{Name: "ops", Namespaces: []string{"infra"}},
+	}
+
+	b := &Bot{
+		slackHTTPClient:  fi,
+		approvalsChannel: "general",
+		workspace:        workspaces[0],
+		workspaces:       workspaces,
+	}
+
+	err := b.RequestApproval(&types.Approval{
+		Provider:   types.ProviderTypeKubernetes,
+		Identifier: "deployment/infra/ingress:1.2.3",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if fi.getCalls() != 0 {
+		t.Errorf("approval for ops workspace shouldn't be sent to default workspace")
+	}
+
+	err = b.RequestApproval(&types.Approval{
+		Provider:   types.ProviderTypeKubernetes,
+		Identifier: "deployment/default/wd:1.2.3",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if fi.getCalls() != 1 {
+		t.Errorf("expected approval to be sent to default workspace, calls: %d", fi.getCalls())
+	}
+}
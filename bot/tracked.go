@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/keel-hq/keel/bot/formatter"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/i18n"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterCommand(&Command{
+		Name:        "get tracked",
+		Description: "get a list of tracked images",
+		Handler: func(bm *BotManager, req *CommandRequest) string {
+			log.Info("HandleCommand: getting tracked images")
+			return TrackedResponse(bm)
+		},
+	})
+	RegisterCommand(&Command{
+		Name:        "pause",
+		Args:        []Arg{{Name: "namespace/name"}},
+		Description: "pause automatic updates for a workload",
+		Handler: func(bm *BotManager, req *CommandRequest) string {
+			return PauseHandler(bm, req.Arg("namespace/name"), req.Message.User)
+		},
+	})
+	RegisterCommand(&Command{
+		Name:        "resume",
+		Args:        []Arg{{Name: "namespace/name"}},
+		Description: "resume automatic updates for a workload",
+		Handler: func(bm *BotManager, req *CommandRequest) string {
+			return ResumeHandler(bm, req.Arg("namespace/name"), req.Message.User)
+		},
+	})
+}
+
+// TrackedResponse - formats all tracked images
+func TrackedResponse(bm *BotManager) string {
+	if bm.providers == nil {
+		return i18n.T("tracked images are not available")
+	}
+
+	trackedImages, err := bm.providers.TrackedImages()
+	if err != nil {
+		return i18n.T("got error while fetching tracked images: %s", err)
+	}
+
+	if len(trackedImages) == 0 {
+		return i18n.T("there are currently no tracked images.")
+	}
+
+	var imgs []formatter.TrackedImage
+	for _, img := range trackedImages {
+		var plc string
+		if img.Policy != nil {
+			plc = img.Policy.Name()
+		}
+		imgs = append(imgs, formatter.TrackedImage{
+			Namespace: img.Namespace,
+			Name:      img.Meta["name"],
+			Image:     img.Image.Remote(),
+			Policy:    plc,
+			Trigger:   img.Trigger.String(),
+			Provider:  img.Provider,
+			Paused:    img.Paused,
+			Frozen:    img.FreezeReason,
+		})
+	}
+
+	buf := &bytes.Buffer{}
+	ctx := formatter.Context{
+		Output: buf,
+		Format: formatter.NewTrackedFormat(formatter.TableFormatKey, false),
+	}
+	err = formatter.TrackedWrite(ctx, imgs)
+	if err != nil {
+		return i18n.T("got error while formatting tracked images: %s", err)
+	}
+
+	return buf.String()
+}
+
+// PauseHandler - pauses automatic updates for a workload, identifier format: <namespace>/<name>
+func PauseHandler(bm *BotManager, identifier, user string) string {
+	if bm.store == nil {
+		return i18n.T("pausing updates is not available")
+	}
+
+	namespace, name, ok := parseWorkloadIdentifier(identifier)
+	if !ok {
+		return i18n.T("invalid workload '%s', expected format: <namespace>/<name>", identifier)
+	}
+
+	_, err := bm.store.GetPausedResource(types.PausedIdentifier(namespace, name))
+	if err == nil {
+		return i18n.T("updates for '%s' are already paused.", identifier)
+	}
+
+	_, err = bm.store.CreatePausedResource(&types.PausedResource{
+		Identifier: types.PausedIdentifier(namespace, name),
+		User:       user,
+	})
+	if err != nil {
+		return i18n.T("failed to pause '%s': %s", identifier, err)
+	}
+
+	log.WithFields(log.Fields{
+		"identifier": identifier,
+		"user":       user,
+	}).Info("bot: automatic updates paused")
+	bm.audit(types.AuditActionCreated, types.AuditResourceKindPaused, identifier, user, nil)
+
+	return i18n.T("automatic updates for '%s' paused, use 'resume %s' to enable them again.", identifier, identifier)
+}
+
+// ResumeHandler - resumes automatic updates for a workload
+func ResumeHandler(bm *BotManager, identifier, user string) string {
+	if bm.store == nil {
+		return i18n.T("pausing updates is not available")
+	}
+
+	namespace, name, ok := parseWorkloadIdentifier(identifier)
+	if !ok {
+		return i18n.T("invalid workload '%s', expected format: <namespace>/<name>", identifier)
+	}
+
+	_, err := bm.store.GetPausedResource(types.PausedIdentifier(namespace, name))
+	if err == store.ErrRecordNotFound {
+		return i18n.T("updates for '%s' are not paused.", identifier)
+	}
+
+	err = bm.store.DeletePausedResource(types.PausedIdentifier(namespace, name))
+	if err != nil {
+		return i18n.T("failed to resume '%s': %s", identifier, err)
+	}
+
+	log.WithFields(log.Fields{
+		"identifier": identifier,
+		"user":       user,
+	}).Info("bot: automatic updates resumed")
+	bm.audit(types.AuditActionDeleted, types.AuditResourceKindPaused, identifier, user, nil)
+
+	return i18n.T("automatic updates for '%s' resumed.", identifier)
+}
+
+func parseWorkloadIdentifier(identifier string) (namespace, name string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(identifier), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// audit - records change made through a bot command
+func (bm *BotManager) audit(action, kind, identifier, user string, meta map[string]string) {
+	entry := &types.AuditLog{
+		AccountID:    user,
+		Username:     user,
+		Action:       action,
+		ResourceKind: kind,
+		Identifier:   identifier,
+	}
+	if meta == nil {
+		meta = make(map[string]string)
+	}
+	meta["source"] = "bot"
+	entry.SetMetadata(meta)
+	if _, err := bm.store.CreateAuditLog(entry); err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"identifier": identifier,
+		}).Error("bot: failed to create audit log")
+	}
+}
@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/pkg/store/sql"
+	"github.com/keel-hq/keel/types"
+)
+
+func NewTestingUtils() (*sql.SQLStore, func()) {
+	dir, err := ioutil.TempDir("", "botstoretest")
+	if err != nil {
+		log.Fatal(err)
+	}
+	tmpfn := filepath.Join(dir, "gorm.db")
+	store, err := sql.New(sql.Opts{DatabaseType: "sqlite3", URI: tmpfn})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	teardown := func() {
+		os.RemoveAll(dir) // clean up
+	}
+
+	return store, teardown
+}
+
+func TestPauseResume(t *testing.T) {
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	bm := &BotManager{store: store}
+
+	resp := bm.handleBotMessage(&BotMessage{Message: "pause default/wd", User: "karolis"}, nil)
+	if !strings.Contains(resp, "paused") {
+		t.Errorf("unexpected response: %s", resp)
+	}
+
+	paused, err := store.GetPausedResource(types.PausedIdentifier("default", "wd"))
+	if err != nil {
+		t.Fatalf("expected resource to be paused: %s", err)
+	}
+	if paused.User != "karolis" {
+		t.Errorf("unexpected user: %s", paused.User)
+	}
+
+	resp = bm.handleBotMessage(&BotMessage{Message: "pause default/wd", User: "karolis"}, nil)
+	if !strings.Contains(resp, "already paused") {
+		t.Errorf("unexpected response: %s", resp)
+	}
+
+	resp = bm.handleBotMessage(&BotMessage{Message: "resume default/wd", User: "karolis"}, nil)
+	if !strings.Contains(resp, "resumed") {
+		t.Errorf("unexpected response: %s", resp)
+	}
+
+	_, err = store.GetPausedResource(types.PausedIdentifier("default", "wd"))
+	if err == nil {
+		t.Errorf("expected resource to be resumed")
+	}
+
+	resp = bm.handleBotMessage(&BotMessage{Message: "pause wd", User: "karolis"}, nil)
+	if !strings.Contains(resp, "invalid workload") {
+		t.Errorf("unexpected response: %s", resp)
+	}
+}
+
+func TestPauseResumeAll(t *testing.T) {
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	bm := &BotManager{store: store}
+
+	resp := bm.handleBotMessage(&BotMessage{Message: "pause all", User: "karolis"}, nil)
+	if !strings.Contains(resp, "maintenance mode enabled") {
+		t.Errorf("unexpected response: %s", resp)
+	}
+
+	paused, err := store.GetPausedResource(types.PausedAllIdentifier)
+	if err != nil {
+		t.Fatalf("expected maintenance mode to be enabled: %s", err)
+	}
+	if paused.User != "karolis" {
+		t.Errorf("unexpected user: %s", paused.User)
+	}
+
+	resp = bm.handleBotMessage(&BotMessage{Message: "pause all", User: "karolis"}, nil)
+	if !strings.Contains(resp, "already enabled") {
+		t.Errorf("unexpected response: %s", resp)
+	}
+
+	resp = bm.handleBotMessage(&BotMessage{Message: "resume all", User: "karolis"}, nil)
+	if !strings.Contains(resp, "maintenance mode disabled") {
+		t.Errorf("unexpected response: %s", resp)
+	}
+
+	_, err = store.GetPausedResource(types.PausedAllIdentifier)
+	if err == nil {
+		t.Errorf("expected maintenance mode to be disabled")
+	}
+}
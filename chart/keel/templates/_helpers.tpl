@@ -30,3 +30,99 @@ Create chart name and version as used by the chart label.
 {{- define "keel.chart" -}}
 {{- printf "%s-%s" .Chart.Name .Chart.Version | replace "+" "_" | trunc 63 | trimSuffix "-" -}}
 {{- end -}}
+
+{{/*
+Rules keel needs in every watched namespace, cluster-wide unless rbac.namespaces is set.
+*/}}
+{{- define "keel.rules" -}}
+  - apiGroups:
+      - ""
+    resources:
+      - secrets
+    verbs:
+      - get
+      - watch
+      - list
+  - apiGroups:
+      - ""
+      - extensions
+      - apps
+      - batch
+    resources:
+      - pods
+      - replicasets
+      - replicationcontrollers
+      - statefulsets
+      - deployments
+      - daemonsets
+      - jobs
+      - cronjobs
+    verbs:
+      - get
+      - delete # required to delete pods during force upgrade of the same tag
+      - watch
+      - list
+      - update
+  - apiGroups:
+      - apps
+    resources:
+      - deployments
+    verbs:
+      - create # canary and blue/green deployments are created next to the updated ones
+  - apiGroups:
+      - ""
+    resources:
+      - services
+    verbs:
+      - get
+      - update # blue/green updates switch service selectors
+  - apiGroups:
+      - argoproj.io
+    resources:
+      - rollouts # only used when ARGO_ROLLOUTS is enabled
+    verbs:
+      - get
+      - watch
+      - list
+      - update
+  - apiGroups:
+      - keel.sh
+    resources:
+      - imageupdatepolicies # only used when IMAGE_UPDATE_POLICIES is enabled
+    verbs:
+      - get
+      - watch
+      - list
+  - apiGroups:
+      - keel.sh
+    resources:
+      - imageupdatepolicies/status
+    verbs:
+      - update
+  - apiGroups:
+      - serving.knative.dev
+    resources:
+      - services # only used when KNATIVE is enabled
+    verbs:
+      - get
+      - watch
+      - list
+      - update
+  - apiGroups:
+      - ""
+    resources:
+      - configmaps
+      - pods/portforward
+    verbs:
+      - get
+      - create
+      - update
+  - apiGroups:
+      - coordination.k8s.io
+    resources:
+      - leases # only used when leader election is enabled
+    verbs:
+      - get
+      - create
+      - update
+{{- end -}}
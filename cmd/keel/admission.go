@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/keel-hq/keel/pkg/admission"
+	"github.com/keel-hq/keel/pkg/store"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// setupAdmission - starts admission webhook server when its certificate is set,
+// all replicas serve the webhook
+func setupAdmission(sqlStore store.Store) (teardown func()) {
+	if os.Getenv(EnvAdmissionTLSCert) == "" {
+		return func() {}
+	}
+
+	port := admission.DefaultPort
+	if os.Getenv(EnvAdmissionPort) != "" {
+		var err error
+		port, err = strconv.Atoi(os.Getenv(EnvAdmissionPort))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"port":  os.Getenv(EnvAdmissionPort),
+			}).Fatal("main.setupAdmission: invalid admission webhook port")
+		}
+	}
+
+	serviceAccount := os.Getenv(EnvAdmissionServiceAccount)
+	if serviceAccount == "" {
+		namespace := os.Getenv(EnvNamespace)
+		if namespace == "" {
+			namespace = "keel"
+		}
+		serviceAccount = fmt.Sprintf("system:serviceaccount:%s:keel", namespace)
+	}
+
+	srv := admission.New(&admission.Opts{
+		Port:         port,
+		CertFile:     os.Getenv(EnvAdmissionTLSCert),
+		KeyFile:      os.Getenv(EnvAdmissionTLSKey),
+		Store:        sqlStore,
+		IgnoredUsers: append(splitList(os.Getenv(EnvAdmissionIgnoreUsers)), serviceAccount),
+		Repin:        os.Getenv(EnvAdmissionRepin) == "true",
+	})
+
+	go func() {
+		err := srv.Start()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"port":  port,
+			}).Fatal("admission webhook server stopped")
+		}
+	}()
+
+	return srv.Stop
+}
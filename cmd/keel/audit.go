@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/keel-hq/keel/internal/audit"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// setupAuditSink - audit webhook, nil when it isn't configured
+func setupAuditSink(dataDir string) *audit.Sink {
+	if os.Getenv(EnvAuditWebhookURL) == "" {
+		return nil
+	}
+	dir := os.Getenv(EnvAuditWebhookBufferDir)
+	if dir == "" {
+		dir = filepath.Join(dataDir, "audit")
+	}
+	sink, err := audit.New(&audit.Opts{
+		Endpoint: os.Getenv(EnvAuditWebhookURL),
+		Secret:   os.Getenv(EnvAuditWebhookSecret),
+		Dir:      dir,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("main: failed to configure audit webhook")
+	}
+	log.WithFields(log.Fields{
+		"endpoint":   os.Getenv(EnvAuditWebhookURL),
+		"buffer_dir": dir,
+		"buffered":   sink.Pending(),
+	}).Info("main: audit webhook configured")
+	return sink
+}
+
+// auditConfigReload - records config file change, it's forwarded to the
+// audit webhook with other audit entries
+func auditConfigReload(s store.Store, path string) {
+	entry := &types.AuditLog{
+		AccountID:    "system",
+		Username:     "system",
+		Action:       types.AuditActionUpdated,
+		ResourceKind: types.AuditResourceKindConfig,
+		Identifier:   path,
+	}
+	entry.SetMetadata(map[string]string{"source": "config_file"})
+	if _, err := s.CreateAuditLog(entry); err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"path":  path,
+		}).Error("main: failed to create audit log")
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/pkg/auth"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// setupOIDC - OIDC login of the UI and API, nil when the issuer isn't set
+func setupOIDC() *auth.OIDC {
+	if os.Getenv(constants.EnvOIDCIssuerURL) == "" {
+		return nil
+	}
+
+	roles, err := auth.ParseRoleMapping(os.Getenv(constants.EnvOIDCRoles))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("main.setupOIDC: invalid OIDC role mapping")
+	}
+	oidc, err := auth.NewOIDC(auth.OIDCOpts{
+		IssuerURL:     os.Getenv(constants.EnvOIDCIssuerURL),
+		ClientID:      os.Getenv(constants.EnvOIDCClientID),
+		ClientSecret:  os.Getenv(constants.EnvOIDCClientSecret),
+		RedirectURL:   os.Getenv(constants.EnvOIDCRedirectURL),
+		UsernameClaim: os.Getenv(constants.EnvOIDCUsernameClaim),
+		GroupsClaim:   os.Getenv(constants.EnvOIDCGroupsClaim),
+		Roles:         roles,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("main.setupOIDC: failed to configure OIDC")
+	}
+	log.WithFields(log.Fields{
+		"issuer": os.Getenv(constants.EnvOIDCIssuerURL),
+	}).Info("main.setupOIDC: OIDC authentication enabled")
+
+	return oidc
+}
+
+// setupAPITokens - API tokens loaded from the tokens file, nil when it isn't
+// set. Tokens created through the API are kept in the store.
+func setupAPITokens() auth.APITokens {
+	if os.Getenv(constants.EnvAPITokens) == "" {
+		return nil
+	}
+
+	apiTokens, err := auth.LoadAPITokens(os.Getenv(constants.EnvAPITokens))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"path":  os.Getenv(constants.EnvAPITokens),
+		}).Fatal("main.setupAPITokens: failed to load API tokens")
+	}
+	return apiTokens
+}
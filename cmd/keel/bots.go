@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/internal/bus"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/util/i18n"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// setupBots - configures bots before they are started, config file bot
+// settings are applied on top of these
+func setupBots(sender notification.Sender, eventBus *bus.Bus) {
+	if os.Getenv(constants.EnvBotLocale) != "" {
+		err := i18n.SetLocale(os.Getenv(constants.EnvBotLocale))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"locale":  os.Getenv(constants.EnvBotLocale),
+				"locales": i18n.Locales(),
+			}).Errorf("main: failed to set locale, defaulting to: %s", i18n.DefaultLocale)
+		}
+	}
+
+	aliases, err := bot.ParseAliases(os.Getenv(constants.EnvBotCommandAliases))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("main: invalid bot command aliases")
+	}
+	bot.SetCommandAliases(aliases)
+	bot.SetPrefixes(strings.Split(os.Getenv(constants.EnvBotPrefixes), ","))
+
+	// bots report connection loss longer than the threshold
+	bot.SetNotificationSender(sender)
+	if os.Getenv(constants.EnvBotDisconnectThreshold) != "" {
+		threshold, err := time.ParseDuration(os.Getenv(constants.EnvBotDisconnectThreshold))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"threshold": os.Getenv(constants.EnvBotDisconnectThreshold),
+			}).Fatal("main: invalid bot disconnect threshold")
+		}
+		bot.SetDisconnectThreshold(threshold)
+	}
+
+	if _, err := bot.ParseReminderSchedule(os.Getenv(EnvApprovalReminders)); err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("main: invalid approval reminders")
+	}
+	bot.SetApprovalReminders(bot.ReminderConfig{
+		Schedule: os.Getenv(EnvApprovalReminders),
+		Group:    os.Getenv(EnvApprovalRemindersGroup),
+		Oncall:   os.Getenv(EnvApprovalRemindersOncall),
+	})
+	bot.SetRegistryClient(registry.New())
+	bot.SetEventBus(eventBus)
+}
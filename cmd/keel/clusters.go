@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/internal/workgroup"
+	"github.com/keel-hq/keel/provider/kubernetes"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// cluster - additional cluster managed by keel, it has its own resource cache
+// and kubernetes provider
+type cluster struct {
+	name        string
+	implementer *kubernetes.KubernetesImplementer
+	translator  *k8s.Translator
+}
+
+// kubernetesContexts - kubeconfig contexts from KUBERNETES_CONTEXTS, context name
+// is used as a cluster name
+func kubernetesContexts() []string {
+	var contexts []string
+	for _, c := range strings.Split(os.Getenv(EnvKubernetesContexts), ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			contexts = append(contexts, c)
+		}
+	}
+	return contexts
+}
+
+// watchResources - starts watching workloads of the cluster
+func watchResources(g *workgroup.Group, implementer *kubernetes.KubernetesImplementer, t *k8s.Translator, cluster string) {
+	buf := k8s.NewBuffer(g, t, log.StandardLogger(), 128)
+	wl := log.WithField("context", "watch")
+	if cluster != "" {
+		wl = wl.WithField("cluster", cluster)
+	}
+	k8s.WatchNamespaces(g, implementer.Client(), wl, buf)
+	k8s.WatchDeployments(g, implementer.Client(), wl, buf)
+	k8s.WatchStatefulSets(g, implementer.Client(), wl, buf)
+	k8s.WatchDaemonSets(g, implementer.Client(), wl, buf)
+	k8s.WatchCronJobs(g, implementer.Client(), wl, buf)
+	if os.Getenv(EnvArgoRollouts) == "1" || os.Getenv(EnvArgoRollouts) == "true" {
+		k8s.WatchArgoRollouts(g, implementer.Dynamic(), wl, buf)
+	}
+	if os.Getenv(EnvKnative) == "1" || os.Getenv(EnvKnative) == "true" {
+		k8s.WatchKnativeServices(g, implementer.Dynamic(), wl, buf)
+	}
+	if os.Getenv(EnvImageUpdatePolicies) == "1" || os.Getenv(EnvImageUpdatePolicies) == "true" {
+		k8s.WatchImageUpdatePolicies(g, implementer.Dynamic(), wl, buf)
+		k8s.ReconcileImageUpdatePolicies(g, implementer.Dynamic(), &t.GenericResourceCache, wl)
+	}
+	for _, resource := range strings.Split(os.Getenv(EnvCustomResources), ",") {
+		if strings.TrimSpace(resource) == "" {
+			continue
+		}
+		gvr, err := k8s.ParseCustomResource(resource)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("main: invalid custom resource")
+		}
+		k8s.WatchCustomResources(g, implementer.Dynamic(), wl, gvr, buf)
+	}
+}
+
+// setupClusters - connects to additional clusters and starts watching their workloads
+func setupClusters(g *workgroup.Group, configPath string, contexts []string) []*cluster {
+	var clusters []*cluster
+	for _, context := range contexts {
+		implementer, err := kubernetes.NewKubernetesImplementer(&kubernetes.Opts{
+			ConfigPath: configPath,
+			Context:    context,
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"context": context,
+			}).Fatal("main: failed to create kubernetes implementer for cluster")
+		}
+
+		t := &k8s.Translator{
+			FieldLogger: log.WithField("context", "translator").WithField("cluster", context),
+		}
+		watchResources(g, implementer, t, context)
+
+		clusters = append(clusters, &cluster{
+			name:        context,
+			implementer: implementer,
+			translator:  t,
+		})
+	}
+	return clusters
+}
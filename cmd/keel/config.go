@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/internal/config"
+	"github.com/keel-hq/keel/internal/logging"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// loadConfig - loads config file and exports its static settings as
+// environment variables, has to run before anything reads them
+func loadConfig(path string) *config.Config {
+	if path == "" {
+		return nil
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"path":  path,
+		}).Fatal("main: invalid config file")
+	}
+	log.WithFields(log.Fields{
+		"path":     path,
+		"settings": cfg.Apply(),
+	}).Info("main: config file loaded")
+	return cfg
+}
+
+// applyConfig - applies reloadable sections of the config file. Sections
+// whose environment variables are set are skipped, the variables take
+// precedence over the file.
+func applyConfig(cfg *config.Config, sender *notification.DefaultNotificationSender) error {
+	if !envSet(EnvDebug, EnvLogLevel, EnvLogLevels) {
+		if err := logging.Configure(cfg.Logging); err != nil {
+			return err
+		}
+	}
+
+	if !envSet(constants.EnvBotCommandAliases) {
+		aliases, err := bot.ParseAliases(cfg.Bots.AliasesString())
+		if err != nil {
+			return err
+		}
+		bot.SetCommandAliases(aliases)
+	}
+	if !envSet(constants.EnvBotPrefixes) {
+		bot.SetPrefixes(cfg.Bots.Prefixes)
+	}
+
+	if !envSet(constants.EnvNotificationLevel) {
+		level := types.LevelInfo
+		if cfg.Notifications.Level != "" {
+			level, _ = types.ParseLevel(cfg.Notifications.Level)
+		}
+		sender.SetLevel(level)
+	}
+	if !envSet(constants.EnvNotificationFilters) {
+		filters, err := notification.NewFilters(cfg.Notifications.Filters)
+		if err != nil {
+			return err
+		}
+		notification.SetFilters(filters)
+	}
+
+	if !envSet(registry.EnvConfig) {
+		if err := registry.SetConfig(cfg.Registries); err != nil {
+			return err
+		}
+	}
+	if !envSet(credentialshelper.EnvRegistryCredentials) {
+		if err := credentialshelper.SetRegistryCredentials(cfg.Credentials); err != nil {
+			return err
+		}
+	}
+
+	types.SetDefaults(types.Defaults{
+		PollSchedule:     cfg.Policies.PollSchedule,
+		ApprovalDeadline: cfg.Policies.ApprovalDeadline,
+	})
+	return nil
+}
+
+func envSet(names ...string) bool {
+	for _, name := range names {
+		if strings.TrimSpace(os.Getenv(name)) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// watchConfig - applies reloadable sections of the config file, again whenever
+// the file changes
+func watchConfig(ctx context.Context, cfg *config.Config, path string, sender *notification.DefaultNotificationSender, s store.Store) {
+	if err := applyConfig(cfg, sender); err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"path":  path,
+		}).Fatal("main: invalid config file")
+	}
+	watcher := config.NewWatcher(path, cfg, func(cfg *config.Config) {
+		if err := applyConfig(cfg, sender); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"path":  path,
+			}).Error("main: failed to apply reloaded config")
+			return
+		}
+		auditConfigReload(s, path)
+	})
+	go watcher.Start(ctx)
+}
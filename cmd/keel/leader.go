@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	kube "k8s.io/client-go/kubernetes"
+
+	"github.com/keel-hq/keel/internal/leader"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// setupLeaderElection - starts competing for the lease, keel exits once it
+// loses leadership so the new leader doesn't duplicate its work
+func setupLeaderElection(ctx context.Context, client kube.Interface) *leader.Elector {
+	namespace := os.Getenv(EnvNamespace)
+	if namespace == "" {
+		namespace = "keel"
+	}
+	name := os.Getenv(EnvLeaderElectionLease)
+	if name == "" {
+		name = "keel"
+	}
+	identity := os.Getenv(EnvPodName)
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("main.setupLeaderElection: failed to get hostname")
+		}
+		identity = hostname
+	}
+
+	elector, err := leader.New(leader.Opts{
+		Client:   client.CoordinationV1().Leases(namespace),
+		Name:     name,
+		Identity: identity,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("main.setupLeaderElection: failed to create leader elector")
+	}
+
+	go elector.Run(ctx)
+	go func() {
+		select {
+		case <-elector.Lost():
+			log.WithFields(log.Fields{
+				"identity": identity,
+			}).Fatal("main.setupLeaderElection: leadership lost, exiting")
+		case <-ctx.Done():
+		}
+	}()
+
+	log.WithFields(log.Fields{
+		"namespace": namespace,
+		"lease":     name,
+		"identity":  identity,
+	}).Info("main.setupLeaderElection: leader election enabled, waiting for leadership")
+
+	return elector
+}
+
+// whenLeading - runs fn in the background, with leader election only once this
+// replica becomes the leader
+func whenLeading(ctx context.Context, elector *leader.Elector, fn func()) {
+	if elector == nil {
+		go fn()
+		return
+	}
+	go func() {
+		select {
+		case <-elector.Leading():
+			fn()
+		case <-ctx.Done():
+		}
+	}()
+}
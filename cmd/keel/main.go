@@ -1,56 +1,61 @@
 package main
 
 import (
-	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
 	"context"
 
 	netContext "golang.org/x/net/context"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
-	kube "k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
-	"k8s.io/helm/pkg/helm/portforwarder"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/keel-hq/keel/approvals"
 	"github.com/keel-hq/keel/bot"
 
 	// "github.com/keel-hq/keel/cache/memory"
-	"github.com/keel-hq/keel/pkg/auth"
 	"github.com/keel-hq/keel/pkg/http"
 	"github.com/keel-hq/keel/pkg/store"
-	"github.com/keel-hq/keel/pkg/store/sql"
 
-	"github.com/keel-hq/keel/constants"
-	"github.com/keel-hq/keel/extension/credentialshelper"
-	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/extension/approval"
+	"github.com/keel-hq/keel/internal/audit"
+	"github.com/keel-hq/keel/internal/bus"
 	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/internal/leader"
+	"github.com/keel-hq/keel/internal/logging"
 	"github.com/keel-hq/keel/internal/workgroup"
-	"github.com/keel-hq/keel/provider"
-	"github.com/keel-hq/keel/provider/helm"
 	"github.com/keel-hq/keel/provider/kubernetes"
-	"github.com/keel-hq/keel/registry"
-	"github.com/keel-hq/keel/secrets"
-	"github.com/keel-hq/keel/trigger/poll"
-	"github.com/keel-hq/keel/trigger/pubsub"
-	"github.com/keel-hq/keel/types"
 	"github.com/keel-hq/keel/version"
 
 	// notification extensions
-	"github.com/keel-hq/keel/extension/notification/auditor"
+	_ "github.com/keel-hq/keel/extension/notification/aws"
+	_ "github.com/keel-hq/keel/extension/notification/datadog"
+	_ "github.com/keel-hq/keel/extension/notification/email"
+	_ "github.com/keel-hq/keel/extension/notification/github"
+	_ "github.com/keel-hq/keel/extension/notification/grafana"
 	_ "github.com/keel-hq/keel/extension/notification/hipchat"
+	_ "github.com/keel-hq/keel/extension/notification/jira"
+	_ "github.com/keel-hq/keel/extension/notification/kafka"
 	_ "github.com/keel-hq/keel/extension/notification/mattermost"
+	_ "github.com/keel-hq/keel/extension/notification/pagerduty"
 	_ "github.com/keel-hq/keel/extension/notification/slack"
+	_ "github.com/keel-hq/keel/extension/notification/teams"
 	_ "github.com/keel-hq/keel/extension/notification/webhook"
 
+	// approval collectors
+	_ "github.com/keel-hq/keel/extension/approval/servicenow"
+
 	// credentials helpers
-	_ "github.com/keel-hq/keel/extension/credentialshelper/aws"
-	secretsCredentialsHelper "github.com/keel-hq/keel/extension/credentialshelper/secrets"
+	awsCredentialsHelper "github.com/keel-hq/keel/extension/credentialshelper/aws"
+	_ "github.com/keel-hq/keel/extension/credentialshelper/azure"
+	_ "github.com/keel-hq/keel/extension/credentialshelper/gcr"
 
 	// bots
+	_ "github.com/keel-hq/keel/bot/chatops"
 	_ "github.com/keel-hq/keel/bot/hipchat"
 	_ "github.com/keel-hq/keel/bot/slack"
 
@@ -69,6 +74,80 @@ const (
 	EnvHelmTillerNamespace = "TILLER_NAMESPACE" // helm provider
 	EnvUIDir               = "UI_DIR"
 
+	// EnvTriggerECRQueueURL - SQS queue that receives ECR push events from EventBridge,
+	// setting it enables the ECR trigger
+	EnvTriggerECRQueueURL = "ECR_SQS_QUEUE_URL"
+
+	// EnvGitOpsConfig - path to GitOps provider configuration (repositories and files
+	// that reference images), setting it enables the provider that opens pull requests
+	EnvGitOpsConfig = "GITOPS_CONFIG"
+
+	// EnvKustomizeConfig - path to kustomize provider configuration (kustomization.yaml
+	// files in Git or ConfigMaps), setting it enables updates of images: overrides
+	EnvKustomizeConfig = "KUSTOMIZE_CONFIG"
+
+	// EnvHelmChartPoll - how often (ie: 10m) chart repositories of Helm releases are
+	// checked for new chart versions, setting it enables the chart trigger
+	EnvHelmChartPoll = "HELM_CHART_POLL"
+
+	// EnvHelmRegistryConfig - registry config.json (as written by 'helm registry login')
+	// with credentials of OCI registries that store charts
+	EnvHelmRegistryConfig = "HELM_REGISTRY_CONFIG"
+
+	// EnvHelmKeyring - public keyring used to verify chart provenance signatures,
+	// requires gpgv
+	EnvHelmKeyring = "HELM_KEYRING"
+
+	// EnvTriggerNATSURL - NATS server(s), setting it together with NATS_SUBJECT
+	// enables the NATS trigger
+	EnvTriggerNATSURL     = "NATS_URL"
+	EnvTriggerNATSSubject = "NATS_SUBJECT"
+	EnvTriggerNATSQueue   = "NATS_QUEUE"
+	// EnvTriggerNATSStream - JetStream stream, enables durable JetStream consumer
+	EnvTriggerNATSStream  = "NATS_STREAM"
+	EnvTriggerNATSDurable = "NATS_DURABLE"
+
+	// EnvTriggerDedupWindow - duration (ie: 1m) during which repeated events with the
+	// same image, tag and digest are ignored, deduplication is disabled when not set
+	EnvTriggerDedupWindow = "TRIGGER_DEDUP_WINDOW"
+
+	// EnvTriggerPollJitter - maximum random delay (ie: 30s) added to scheduled
+	// poll checks so images with the same schedule don't hit registries at once
+	EnvTriggerPollJitter = "POLL_JITTER"
+
+	// EnvTriggerPollPlatform - platform (ie: linux/arm64) that is watched for
+	// multi-arch tags, defaults to the platform keel is running on
+	EnvTriggerPollPlatform = "POLL_PLATFORM"
+
+	// EnvTriggerPollCacheTTL - how long (ie: 24h) watched digests are stored in the
+	// database and reused after restarts, set to 0 to disable
+	EnvTriggerPollCacheTTL = "POLL_CACHE_TTL"
+
+	// EnvTriggerPollWorkers - how many registry checks run concurrently, defaults
+	// to 10, set to 0 to check images sequentially
+	EnvTriggerPollWorkers = "POLL_WORKERS"
+	// EnvTriggerPollRegistryConcurrency - maximum concurrent checks of a single
+	// registry, not limited when not set
+	EnvTriggerPollRegistryConcurrency = "POLL_REGISTRY_CONCURRENCY"
+
+	// EnvTriggerPollDiscovery - comma separated registry namespaces that are watched
+	// for new repositories, ie: registry.example.com/team/*
+	EnvTriggerPollDiscovery = "POLL_DISCOVERY"
+	// EnvTriggerPollDiscoveryInterval - how often registry catalogs are checked, defaults to 5m
+	EnvTriggerPollDiscoveryInterval = "POLL_DISCOVERY_INTERVAL"
+
+	// EnvShutdownTimeout - how long (ie: 25s) keel waits for in-flight updates
+	// and pending bot messages on SIGTERM, defaults to 25s
+	EnvShutdownTimeout = "SHUTDOWN_TIMEOUT"
+
+	// EnvConfigFile - path to keel config file (usually mounted from a ConfigMap),
+	// environment variables take precedence over its settings
+	EnvConfigFile = "CONFIG_FILE"
+
+	// EnvPluginsConfig - path to gRPC plugins configuration, plugins are registered
+	// as triggers or as gates that check events before updates
+	EnvPluginsConfig = "PLUGINS_CONFIG"
+
 	// EnvDefaultDockerRegistryCfg - default registry configuration that can be passed into
 	// keel for polling trigger
 	EnvDefaultDockerRegistryCfg = "DOCKER_REGISTRY_CFG"
@@ -77,17 +156,241 @@ const (
 // kubernetes config, if empty - will default to InCluster
 const (
 	EnvKubernetesConfig = "KUBERNETES_CONFIG"
+
+	// EnvKubernetesContexts - comma separated kubeconfig contexts of the clusters keel
+	// manages, the first one is the main cluster (bot, UI) and context names are used
+	// as cluster names in approvals and notifications
+	EnvKubernetesContexts = "KUBERNETES_CONTEXTS"
+
+	// EnvArgoRollouts - set to 1 or true to watch and update argoproj.io Rollouts,
+	// Argo Rollouts CRDs have to be installed in the cluster
+	EnvArgoRollouts = "ARGO_ROLLOUTS"
+
+	// EnvKnative - set to 1 or true to watch and update serving.knative.dev Services
+	EnvKnative = "KNATIVE"
+
+	// EnvImageUpdatePolicies - set to 1 or true to apply keel.sh ImageUpdatePolicies to
+	// the workloads they select, the CRD has to be installed in the cluster
+	EnvImageUpdatePolicies = "IMAGE_UPDATE_POLICIES"
+
+	// EnvTagAllowlists - comma separated name=URL allowlists of deployable tags (ie:
+	// payments=https://releases.example.com/api/payments/tags), resources with
+	// allowlist:<name> policy are only updated to tags the endpoint returns
+	EnvTagAllowlists = "TAG_ALLOWLISTS"
+	// EnvTagAllowlistToken - optional bearer token sent to allowlist endpoints
+	EnvTagAllowlistToken = "TAG_ALLOWLIST_TOKEN"
+	// EnvTagAllowlistInterval - how often allowlists are refreshed, defaults to 5m
+	EnvTagAllowlistInterval = "TAG_ALLOWLIST_INTERVAL"
+
+	// EnvCustomResources - comma separated custom resources (group/version/resource, ie:
+	// apps.example.com/v1/applications) that are watched, their images are set in
+	// fields listed in keel.sh/image-paths annotation
+	EnvCustomResources = "CUSTOM_RESOURCES"
+
+	// EnvCanaryPrometheusURL - Prometheus address (ie: http://prometheus.monitoring:9090),
+	// enables canaries of deployments with keel.sh/canaryReplicas annotation
+	EnvCanaryPrometheusURL = "CANARY_PROMETHEUS_URL"
+
+	// EnvCosignPublicKeys - comma separated PEM files of cosign public keys, images of
+	// resources with keel.sh/verifySignature annotation have to be signed with one of them
+	EnvCosignPublicKeys = "COSIGN_PUBLIC_KEYS"
+	// EnvCosignIdentities - comma separated keyless signers in issuer=subject format,
+	// requires COSIGN_FULCIO_ROOTS and COSIGN_REKOR_PUBLIC_KEY
+	EnvCosignIdentities     = "COSIGN_IDENTITIES"
+	EnvCosignFulcioRoots    = "COSIGN_FULCIO_ROOTS"
+	EnvCosignRekorPublicKey = "COSIGN_REKOR_PUBLIC_KEY"
+
+	// EnvVulnerabilityScanner - scanner of new images of resources with
+	// keel.sh/vulnerabilitySeverity annotation, harbor or webhook
+	EnvVulnerabilityScanner = "VULNERABILITY_SCANNER"
+	// EnvVulnerabilityScannerURL - Harbor base URL or webhook scanner endpoint
+	EnvVulnerabilityScannerURL      = "VULNERABILITY_SCANNER_URL"
+	EnvVulnerabilityScannerUsername = "VULNERABILITY_SCANNER_USERNAME"
+	EnvVulnerabilityScannerPassword = "VULNERABILITY_SCANNER_PASSWORD"
+
+	// EnvProvenanceBuilders - comma separated SLSA builder IDs (can contain wildcards),
+	// new images of resources with keel.sh/verifyProvenance annotation need provenance from one of them
+	EnvProvenanceBuilders = "PROVENANCE_BUILDERS"
+
+	// EnvPullPreflight - set to true to check that updated images can be pulled with
+	// resource image pull secrets before resources are updated
+	EnvPullPreflight = "PULL_PREFLIGHT"
+
+	// EnvDigestRestart - set to true to restart pods (like kubectl rollout restart) when
+	// digest of the tag changes but the tag stays the same, resources can opt out
+	// with keel.sh/digestRestart annotation
+	EnvDigestRestart = "DIGEST_RESTART"
+
+	// EnvPinDeletedTags - set to true to pin images of resources to the digest of their
+	// tag when registry webhooks report that the tag was deleted, deletions are
+	// reported either way
+	EnvPinDeletedTags = "PIN_DELETED_TAGS"
+
+	// EnvUpdateAttempts - attempts of Kubernetes updates and Helm upgrades failing with
+	// transient errors (conflicts, timeouts, throttling), retried with exponential backoff.
+	// Failures are reported once attempts run out, defaults to 5, set to 1 to disable retries
+	EnvUpdateAttempts = "UPDATE_ATTEMPTS"
+
+	// EnvReleaseNotes - set to true to add release notes of new images to approval requests
+	// and update notifications, GitHub release of the tag (keel.sh/githubRepository or the
+	// org.opencontainers.image.source label) or the image description. GITHUB_TOKEN and
+	// GITHUB_API_URL are used for GitHub requests.
+	EnvReleaseNotes = "RELEASE_NOTES"
+	// EnvReleaseNotesMaxLength - release notes are truncated to it, defaults to 500 characters
+	EnvReleaseNotesMaxLength = "RELEASE_NOTES_MAX_LENGTH"
+
+	// EnvHygieneReportInterval - how often deployed images are compared with registry
+	// contents (ie: 24h), the report is sent as a notification and served on
+	// /v1/reports/hygiene. Images are checked for signatures and scan reports when
+	// cosign or the vulnerability scanner are configured.
+	EnvHygieneReportInterval = "HYGIENE_REPORT_INTERVAL"
+	// EnvHygieneBehindThreshold - images this many versions behind their policy are
+	// reported, defaults to 3
+	EnvHygieneBehindThreshold = "HYGIENE_BEHIND_THRESHOLD"
+
+	// EnvOncallProvider - pagerduty or opsgenie, users on call for the keel.sh/oncallSchedule
+	// schedule of the resource are mentioned in its approval requests
+	EnvOncallProvider = "ONCALL_PROVIDER"
+	// EnvOncallAPIToken - PagerDuty REST API or Opsgenie API key
+	EnvOncallAPIToken = "ONCALL_API_TOKEN"
+	// EnvOncallAPIURL - optional API address, ie: https://api.eu.opsgenie.com
+	EnvOncallAPIURL = "ONCALL_API_URL"
+
+	// EnvFreezeSelector - label selector of frozen workloads or namespaces (ie: change-freeze=true),
+	// frozen workloads aren't updated, reason can be set with keel.sh/freezeReason annotation
+	EnvFreezeSelector = "FREEZE_SELECTOR"
+
+	// EnvTrackNamespaces - comma separated namespaces (can be patterns, ie: team-*),
+	// workloads in other namespaces are never tracked or updated
+	EnvTrackNamespaces = "TRACK_NAMESPACES"
+	// EnvIgnoreNamespaces - comma separated namespaces keel never touches, ie: kube-system
+	EnvIgnoreNamespaces = "IGNORE_NAMESPACES"
+	// EnvTrackNamespaceSelector - label selector of namespaces that can be tracked, ie: keel.sh/enabled=true
+	EnvTrackNamespaceSelector = "TRACK_NAMESPACE_SELECTOR"
+	// EnvTrackSelector - label selector of workloads that can be tracked
+	EnvTrackSelector = "TRACK_SELECTOR"
+	// EnvWatchSelector - label selector of workloads watched by keel (ie: keel.sh/policy),
+	// other workloads aren't sent by the API server. Workloads with the policy in
+	// annotations or ImageUpdatePolicies have to match it too.
+	EnvWatchSelector = "WATCH_SELECTOR"
+	// EnvWatchNamespaces - comma separated namespaces watched by keel, it then
+	// only needs namespaced roles in them instead of cluster-wide ones. Workloads
+	// are tracked in these namespaces when TRACK_NAMESPACES isn't set.
+	EnvWatchNamespaces = "WATCH_NAMESPACES"
+)
+
+// database, defaults to sqlite stored in the data dir
+const (
+	// EnvDatabaseType - sqlite3, postgres or mysql (keel has to be built with mysql tag)
+	EnvDatabaseType = "DATABASE_TYPE"
+	// EnvDatabaseDSN - connection string, required for postgres and mysql
+	EnvDatabaseDSN = "DATABASE_DSN"
 )
 
 // EnvDebug - set to 1 or anything else to enable debug logging
 const EnvDebug = "DEBUG"
 
+// log levels, can be changed at runtime through /v1/log-levels
+const (
+	// EnvLogLevel - default log level, defaults to info (debug when DEBUG is set)
+	EnvLogLevel = "LOG_LEVEL"
+	// EnvLogLevels - module log levels, ie: trigger.poll=debug,bot.slack=info
+	EnvLogLevels = "LOG_LEVELS"
+)
+
+// OpenTelemetry tracing, spans are exported with OTLP/HTTP when the endpoint
+// (ie: http://otel-collector:4318) is set
+const (
+	EnvOTLPEndpoint = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	// EnvOTLPHeaders - additional exporter headers, ie: authorization=Bearer xx
+	EnvOTLPHeaders     = "OTEL_EXPORTER_OTLP_HEADERS"
+	EnvOTELServiceName = "OTEL_SERVICE_NAME"
+)
+
+// audit webhook, audit entries (approval votes, pauses, API and config
+// changes) and applied updates are sent as signed JSON, ie: to a SIEM
+const (
+	// EnvAuditWebhookURL - HTTPS endpoint of the audit webhook
+	EnvAuditWebhookURL = "AUDIT_WEBHOOK_URL"
+	// EnvAuditWebhookSecret - required, events are signed with HMAC-SHA256 in
+	// the X-Keel-Signature header
+	EnvAuditWebhookSecret = "AUDIT_WEBHOOK_SECRET"
+	// EnvAuditWebhookBufferDir - events are buffered there until the endpoint
+	// accepts them, defaults to audit directory in the data dir
+	EnvAuditWebhookBufferDir = "AUDIT_WEBHOOK_BUFFER_DIR"
+)
+
+// leader election, replicas compete for a Lease in the keel namespace and only
+// the leader polls registries and applies updates
+const (
+	// EnvLeaderElection - set to 1 or true when running several replicas
+	EnvLeaderElection = "LEADER_ELECTION"
+	// EnvLeaderElectionLease - lease name, defaults to keel
+	EnvLeaderElectionLease = "LEADER_ELECTION_LEASE"
+	// EnvNamespace - namespace keel runs in, defaults to keel
+	EnvNamespace = "NAMESPACE"
+	// EnvPodName - replica identity, defaults to the hostname
+	EnvPodName = "POD_NAME"
+)
+
+// admission webhook
+const (
+	// EnvAdmissionTLSCert, EnvAdmissionTLSKey - serving certificate of the admission
+	// webhook, setting them enables the webhook that records manual image changes
+	EnvAdmissionTLSCert = "ADMISSION_TLS_CERT"
+	EnvAdmissionTLSKey  = "ADMISSION_TLS_KEY"
+	// EnvAdmissionPort - admission webhook port, defaults to 9443
+	EnvAdmissionPort = "ADMISSION_PORT"
+	// EnvAdmissionRepin - set to true to pause keel updates of manually changed
+	// workloads until they are resumed
+	EnvAdmissionRepin = "ADMISSION_REPIN"
+	// EnvAdmissionServiceAccount - service account keel updates workloads with,
+	// its changes aren't recorded as manual. Defaults to keel in the keel namespace.
+	EnvAdmissionServiceAccount = "ADMISSION_SERVICE_ACCOUNT"
+	// EnvAdmissionIgnoreUsers - additional comma separated users whose changes
+	// aren't manual, ie: CD pipelines or tiller when keel updates Helm releases
+	EnvAdmissionIgnoreUsers = "ADMISSION_IGNORE_USERS"
+)
+
+// rate limiting of automatic updates, budgets are <max>/<period> (ie: 5/10m).
+// Updates over a budget are queued and applied in order once the budget allows.
+const (
+	// EnvRateLimit - budget of all automatic updates
+	EnvRateLimit = "RATE_LIMIT"
+	// EnvRateLimitNamespace - default budget of each namespace
+	EnvRateLimitNamespace = "RATE_LIMIT_NAMESPACE"
+	// EnvRateLimitNamespaces - budgets of specific namespaces, ie: production=2/10m,staging=10/10m
+	EnvRateLimitNamespaces = "RATE_LIMIT_NAMESPACES"
+)
+
+// reminders of pending approvals, the first reminder is posted without mentions,
+// the second one mentions the approver group and later ones the on-call handle
+const (
+	// EnvApprovalReminders - default reminder intervals since the request, ie: 1h,4h,12h,
+	// resources can override them with keel.sh/approvalReminders annotation
+	EnvApprovalReminders = "APPROVAL_REMINDERS"
+	// EnvApprovalRemindersGroup - approver group mention, ie: <!subteam^S0123ABC> on Slack
+	EnvApprovalRemindersGroup = "APPROVAL_REMINDERS_GROUP"
+	// EnvApprovalRemindersOncall - on-call mention, defaults to the approver group
+	EnvApprovalRemindersOncall = "APPROVAL_REMINDERS_ONCALL"
+)
+
+// rateLimitDrainInterval - how often queued updates are checked against the budgets
+const rateLimitDrainInterval = 5 * time.Second
+
+// accessCheckInterval - permissions are checked again to report roles that
+// were granted or revoked after keel started
+const accessCheckInterval = 5 * time.Minute
+
 func main() {
 	ver := version.GetKeelVersion()
 
 	inCluster := kingpin.Flag("incluster", "use in cluster configuration (defaults to 'true'), use '--no-incluster' if running outside of the cluster").Default("true").Bool()
 	kubeconfig := kingpin.Flag("kubeconfig", "path to kubeconfig (if not in running inside a cluster)").Default(filepath.Join(os.Getenv("HOME"), ".kube", "config")).String()
 	uiDir := kingpin.Flag("ui-dir", "path to web UI static files").Default("www").Envar(EnvUIDir).String()
+	exportStatePath := kingpin.Flag("export-state", "export state (approvals, audit logs, paused resources, update history, poll digests) to a .json or .tar.gz file and exit").String()
+	importStatePath := kingpin.Flag("import-state", "import state from a file created with --export-state or the state API and exit").String()
+	configPath := kingpin.Flag("config", "path to keel config file, reloadable sections are applied again when it changes").Envar(EnvConfigFile).String()
 
 	kingpin.UsageTemplate(kingpin.CompactUsageTemplate).Version(ver.Version)
 	kingpin.CommandLine.Help = "Automated Kubernetes deployment updates. Learn more on https://keel.sh."
@@ -102,63 +405,80 @@ func main() {
 		"arch":       ver.Arch,
 	}).Info("keel starting...")
 
+	cfg := loadConfig(*configPath)
+
+	logLevels := logging.Levels{Default: os.Getenv(EnvLogLevel)}
 	if os.Getenv(EnvDebug) == "true" {
-		log.SetLevel(log.DebugLevel)
+		logLevels.Default = log.DebugLevel.String()
 	}
-
-	dataDir := "/data"
-	if os.Getenv(EnvDataDir) != "" {
-		dataDir = os.Getenv(EnvDataDir)
+	modules, err := logging.ParseModules(os.Getenv(EnvLogLevels))
+	if err == nil {
+		logLevels.Modules = modules
+		err = logging.Configure(logLevels)
 	}
-
-	sqlStore, err := sql.New(sql.Opts{
-		DatabaseType: "sqlite3",
-		URI:          filepath.Join(dataDir, "keel.db"),
-	})
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err,
-		}).Error("failed to initialize database")
-		os.Exit(1)
+		}).Fatal("main: invalid log levels")
 	}
-	log.WithFields(log.Fields{
-		"database_path": filepath.Join(dataDir, "keel.db"),
-		"type":          "sqlite3",
-	}).Info("initializing database")
 
-	// registering auditor to log events
-	auditLogger := auditor.New(sqlStore)
-	notification.RegisterSender("auditor", auditLogger)
+	dataDir := "/data"
+	if os.Getenv(EnvDataDir) != "" {
+		dataDir = os.Getenv(EnvDataDir)
+	}
 
-	// setting up triggers
-	ctx, cancel := netContext.WithCancel(context.Background())
-	defer cancel()
+	db := setupStore(dataDir)
 
-	notificationLevel := types.LevelInfo
-	if os.Getenv(constants.EnvNotificationLevel) != "" {
-		parsedLevel, err := types.ParseLevel(os.Getenv(constants.EnvNotificationLevel))
+	if *exportStatePath != "" || *importStatePath != "" {
+		if *importStatePath != "" {
+			err = importState(db, *importStatePath)
+		}
+		if err == nil && *exportStatePath != "" {
+			err = exportState(db, *exportStatePath)
+		}
+		db.Close()
 		if err != nil {
 			log.WithFields(log.Fields{
 				"error": err,
-			}).Errorf("main: got error while parsing notification level, defaulting to: %s", notificationLevel)
-		} else {
-			notificationLevel = parsedLevel
+			}).Fatal("main: state export/import failed")
 		}
+		return
 	}
 
-	notifCfg := &notification.Config{
-		Attempts: 10,
-		Level:    notificationLevel,
+	traceExporter := setupTracing()
+
+	// audit entries and update records are forwarded to the audit webhook
+	var sqlStore store.Store = db
+	auditSink := setupAuditSink(dataDir)
+	if auditSink != nil {
+		sqlStore = audit.Store(db, auditSink)
 	}
-	sender := notification.New(ctx)
 
-	_, err = sender.Configure(notifCfg)
-	if err != nil {
-		log.WithFields(log.Fields{
-			"error": err,
-		}).Fatal("main: failed to configure notification sender manager")
+	// setting up triggers
+	ctx, cancel := netContext.WithCancel(context.Background())
+	defer cancel()
+
+	if auditSink != nil {
+		go auditSink.Start(ctx)
 	}
 
+	setupRegistries()
+
+	// trigger events, approval requests, bot messages and notifications are
+	// published to the event bus, providers, bots, senders and extensions
+	// subscribe to it
+	eventBus := bus.New()
+
+	sender := setupNotifications(ctx, eventBus, sqlStore)
+	setupBots(sender, eventBus)
+
+	if cfg != nil {
+		watchConfig(ctx, cfg, *configPath, sender, sqlStore)
+	}
+
+	// ECR token refresh failures are sent as system events
+	awsCredentialsHelper.DefaultHelper.SetSender(sender)
+
 	// getting k8s provider
 	k8sCfg := &kubernetes.Opts{
 		ConfigPath: *kubeconfig,
@@ -170,6 +490,15 @@ func main() {
 
 	k8sCfg.InCluster = *inCluster
 
+	contexts := kubernetesContexts()
+	var clusterName string
+	if len(contexts) > 0 {
+		// contexts are always taken from kubeconfig
+		k8sCfg.InCluster = false
+		k8sCfg.Context = contexts[0]
+		clusterName = contexts[0]
+	}
+
 	implementer, err := kubernetes.NewKubernetesImplementer(k8sCfg)
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -178,29 +507,78 @@ func main() {
 		}).Fatal("main: failed to create kubernetes implementer")
 	}
 
+	// lease is released once in-flight updates are done, not when triggers stop
+	leaseCtx, releaseLease := context.WithCancel(context.Background())
+	defer releaseLease()
+
+	var elector *leader.Elector
+	if os.Getenv(EnvLeaderElection) == "1" || os.Getenv(EnvLeaderElection) == "true" {
+		elector = setupLeaderElection(leaseCtx, implementer.Client())
+	}
+
 	var g workgroup.Group
 
 	t := &k8s.Translator{
 		FieldLogger: log.WithField("context", "translator"),
 	}
 
-	buf := k8s.NewBuffer(&g, t, log.StandardLogger(), 128)
-	wl := log.WithField("context", "watch")
-	k8s.WatchDeployments(&g, implementer.Client(), wl, buf)
-	k8s.WatchStatefulSets(&g, implementer.Client(), wl, buf)
-	k8s.WatchDaemonSets(&g, implementer.Client(), wl, buf)
-	k8s.WatchCronJobs(&g, implementer.Client(), wl, buf)
+	if os.Getenv(EnvWatchSelector) != "" {
+		selector, err := labels.Parse(os.Getenv(EnvWatchSelector))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"selector": os.Getenv(EnvWatchSelector),
+			}).Fatal("main: invalid watch selector")
+		}
+		k8s.SetWorkloadSelector(selector.String())
+	}
+
+	// watches are skipped where keel lacks permissions, ie: with namespaced roles
+	accessChecker := k8s.NewAccessChecker(implementer.Client(), splitList(os.Getenv(EnvWatchNamespaces)), log.WithField("context", "access"))
+	accessChecker.Check()
+	k8s.SetWatchNamespaces(splitList(os.Getenv(EnvWatchNamespaces)))
+	k8s.SetAccessChecker(accessChecker)
+	go accessChecker.Start(ctx, accessCheckInterval)
+
+	// loaded before triggers start, allowlist policies block updates until then
+	if allowlists := tagAllowlists(); allowlists != nil {
+		if err := allowlists.Refresh(); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("main: failed to load tag allowlist, updates of its resources are blocked until it's refreshed")
+		}
+		go allowlists.Start(ctx)
+	}
+
+	watchResources(&g, implementer, t, clusterName)
+
+	var clusters []*cluster
+	if len(contexts) > 1 {
+		clusters = setupClusters(&g, k8sCfg.ConfigPath, contexts[1:])
+	}
 
 	// approvalsCache := memory.NewMemoryCache()
 	approvalsManager := approvals.New(&approvals.Opts{
 		// Cache: approvalsCache,
 		Store: sqlStore,
+		Bus:   eventBus,
 	})
 
-	go approvalsManager.StartExpiryService(ctx)
+	whenLeading(ctx, elector, func() { approvalsManager.StartExpiryService(ctx) })
+
+	approvalCollector := approval.New()
+	approvalCollector.Configure(approvalsManager)
 
 	// setting up providers
-	providers := setupProviders(&ProviderOpts{
+	charts := chartRepositories()
+
+	limiter := rateLimiter()
+	whenLeading(ctx, elector, func() { limiter.Run(rateLimitDrainInterval, ctx.Done()) })
+
+	scope := trackingScope()
+	plugins := pluginsConfig()
+
+	providers, helmProvider := setupProviders(&ProviderOpts{
 		k8sImplementer:   implementer,
 		sender:           sender,
 		approvalsManager: approvalsManager,
@@ -208,223 +586,98 @@ func main() {
 		store:            sqlStore,
 		k8sClient:        implementer.Client(),
 		config:           implementer.Config(),
+		charts:           charts,
+		cluster:          clusterName,
+		clusters:         clusters,
+		elector:          elector,
+		rateLimiter:      limiter,
+		scope:            scope,
+		plugins:          plugins,
+		bus:              eventBus,
 	})
 
-	// registering secrets based credentials helper
-	dockerConfig := make(secrets.DockerCfg)
-	if os.Getenv(EnvDefaultDockerRegistryCfg) != "" {
-		dockerConfigStr := os.Getenv(EnvDefaultDockerRegistryCfg)
-		dockerConfig, err = secrets.DecodeDockerCfgJson([]byte(dockerConfigStr))
-		if err != nil {
-			log.WithFields(log.Fields{
-				"error": err,
-			}).Fatalf("failed to decode secret provided in %s env variable", EnvDefaultDockerRegistryCfg)
-		}
+	reporter, reportInterval := hygieneReporter(providers, sender)
+	if reporter != nil {
+		whenLeading(ctx, elector, func() { reporter.Run(reportInterval, ctx.Done()) })
 	}
-	secretsGetter := secrets.NewGetter(implementer, dockerConfig)
 
-	ch := secretsCredentialsHelper.New(secretsGetter)
-	credentialshelper.RegisterCredentialsHelper("secrets", ch)
+	// registering secrets based credentials helper
+	setupSecretsCredentials(&g, implementer, clusterName, clusters)
 
 	// trigger setup
 	// teardownTriggers := setupTriggers(ctx, providers, approvalsManager, &t.GenericResourceCache, implementer)
 	teardownTriggers := setupTriggers(ctx, &TriggerOpts{
-		providers:        providers,
-		approvalsManager: approvalsManager,
-		grc:              &t.GenericResourceCache,
-		k8sClient:        implementer,
-		store:            sqlStore,
-		uiDir:            *uiDir,
+		providers:         providers,
+		approvalsManager:  approvalsManager,
+		approvalCollector: approvalCollector,
+		grc:               &t.GenericResourceCache,
+		k8sClient:         implementer,
+		store:             sqlStore,
+		sender:            sender,
+		helmProvider:      helmProvider,
+		charts:            charts,
+		uiDir:             *uiDir,
+		elector:           elector,
+		rateLimiter:       limiter,
+		scope:             scope,
+		plugins:           plugins,
+		accessChecker:     accessChecker,
+		hygieneReporter:   reporter,
+		healthChecks: []http.HealthCheck{{
+			Name:     "kubernetes",
+			Critical: true,
+			Check: func() error {
+				_, err := implementer.Client().Discovery().ServerVersion()
+				return err
+			},
+		}},
 	})
 
-	bot.Run(implementer, approvalsManager)
+	teardownAdmission := setupAdmission(sqlStore)
+
+	// bots answer chat commands and approvals, a single replica has to run them
+	bot.SetRateLimiter(limiter)
+	whenLeading(ctx, elector, func() { bot.Run(implementer, approvalsManager, providers, sqlStore) })
+
+	shutdownOpts := &shutdownOpts{
+		timeout:       shutdownTimeout(),
+		stopTriggers:  []func(){teardownTriggers, teardownAdmission, cancel},
+		providers:     providers,
+		sender:        sender,
+		traceExporter: traceExporter,
+		store:         db,
+		releaseLease:  releaseLease,
+	}
 
 	signalChan := make(chan os.Signal, 1)
 	cleanupDone := make(chan bool)
-	signal.Notify(signalChan, os.Interrupt)
+	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
 	g.Add(func(stop <-chan struct{}) {
 		go func() {
-			for range signalChan {
-				log.Info("received an interrupt, shutting down...")
-				go func() {
-					select {
-					case <-time.After(10 * time.Second):
-						log.Info("connection shutdown took too long, exiting... ")
-						close(cleanupDone)
-						return
-					case <-cleanupDone:
-						return
-					}
-				}()
-				providers.Stop()
-				teardownTriggers()
-				bot.Stop()
-
-				cleanupDone <- true
-			}
+			<-signalChan
+			log.WithFields(log.Fields{
+				"timeout": shutdownOpts.timeout,
+			}).Info("received an interrupt, shutting down...")
+			go func() {
+				<-signalChan
+				log.Info("received another interrupt, exiting...")
+				os.Exit(1)
+			}()
+			shutdown(shutdownOpts)
+			close(cleanupDone)
 		}()
 		<-cleanupDone
 	})
 	g.Run()
 }
 
-type ProviderOpts struct {
-	k8sImplementer   kubernetes.Implementer
-	sender           notification.Sender
-	approvalsManager approvals.Manager
-	grc              *k8s.GenericResourceCache
-	store            store.Store
-
-	k8sClient kube.Interface
-	config    *rest.Config
-}
-
-// setupProviders - setting up available providers. New providers should be initialised here and added to
-// provider map
-func setupProviders(opts *ProviderOpts) (providers provider.Providers) {
-	var enabledProviders []provider.Provider
-
-	k8sProvider, err := kubernetes.NewProvider(opts.k8sImplementer, opts.sender, opts.approvalsManager, opts.grc)
-	if err != nil {
-		log.WithFields(log.Fields{
-			"error": err,
-		}).Fatal("main.setupProviders: failed to create kubernetes provider")
-	}
-	go func() {
-		err := k8sProvider.Start()
-		if err != nil {
-			log.WithFields(log.Fields{
-				"error": err,
-			}).Fatal("kubernetes provider stopped with an error")
-		}
-	}()
-
-	enabledProviders = append(enabledProviders, k8sProvider)
-
-	if os.Getenv(EnvHelmProvider) == "1" || os.Getenv(EnvHelmProvider) == "true" {
-
-		var tillerAddr string
-
-		if os.Getenv(EnvHelmTillerAddress) != "" {
-			tillerAddr = os.Getenv(EnvHelmTillerAddress)
-			log.Infof("Tiller address specified: %s", tillerAddr)
-		} else {
-			tillerNamespace := "kube-system"
-			if os.Getenv(EnvHelmTillerNamespace) != "" {
-				tillerNamespace = os.Getenv(EnvHelmTillerNamespace)
-			}
-
-			tillerTunnel, err := portforwarder.New(tillerNamespace, opts.k8sClient, opts.config)
-			if err != nil {
-				log.WithFields(log.Fields{
-					"error": err,
-				}).Fatal("failed to setup Tiller tunnel")
-			}
-
-			tillerAddr = fmt.Sprintf("127.0.0.1:%d", tillerTunnel.Local)
-			log.Infof("created local tunnel using local port: '%d'", tillerTunnel.Local)
-		}
-
-		helmImplementer := helm.NewHelmImplementer(tillerAddr)
-		helmProvider := helm.NewProvider(helmImplementer, opts.sender, opts.approvalsManager)
-
-		go func() {
-			err := helmProvider.Start()
-			if err != nil {
-				log.WithFields(log.Fields{
-					"error": err,
-				}).Fatal("helm provider stopped with an error")
-			}
-		}()
-
-		enabledProviders = append(enabledProviders, helmProvider)
-	}
-
-	providers = provider.New(enabledProviders, opts.approvalsManager)
-
-	return providers
-}
-
-type TriggerOpts struct {
-	providers        provider.Providers
-	approvalsManager approvals.Manager
-	grc              *k8s.GenericResourceCache
-	k8sClient        kubernetes.Implementer
-	store            store.Store
-	uiDir            string
-}
-
-// setupTriggers - setting up triggers. New triggers should be added to this function. Each trigger
-// should go through all providers (or not if there is a reason) and submit events)
-// func setupTriggers(ctx context.Context, providers provider.Providers, approvalsManager approvals.Manager, grc *k8s.GenericResourceCache, k8sClient kubernetes.Implementer) (teardown func()) {
-func setupTriggers(ctx context.Context, opts *TriggerOpts) (teardown func()) {
-
-	authenticator := auth.New(&auth.Opts{
-		Username: os.Getenv(constants.EnvBasicAuthUser),
-		Password: os.Getenv(constants.EnvBasicAuthPassword),
-		Secret:   []byte(os.Getenv(constants.EnvTokenSecret)),
-	})
-
-	// setting up generic http webhook server
-	whs := http.NewTriggerServer(&http.Opts{
-		Port:                  types.KeelDefaultPort,
-		GRC:                   opts.grc,
-		KubernetesClient:      opts.k8sClient,
-		Providers:             opts.providers,
-		ApprovalManager:       opts.approvalsManager,
-		Store:                 opts.store,
-		Authenticator:         authenticator,
-		UIDir:                 opts.uiDir,
-		AuthenticatedWebhooks: os.Getenv(constants.EnvAuthenticatedWebhooks) == "true",
-	})
-
-	go func() {
-		err := whs.Start()
-		if err != nil {
-			log.WithFields(log.Fields{
-				"error": err,
-				"port":  types.KeelDefaultPort,
-			}).Fatal("trigger server stopped")
-		}
-	}()
-
-	// checking whether pubsub (GCR) trigger is enabled
-	if os.Getenv(EnvTriggerPubSub) != "" {
-		projectID := os.Getenv(EnvProjectID)
-		if projectID == "" {
-			log.Fatalf("main.setupTriggers: project ID env variable not set")
-			return
-		}
-
-		ps, err := pubsub.NewPubsubSubscriber(&pubsub.Opts{
-			ProjectID: projectID,
-			Providers: opts.providers,
-		})
-		if err != nil {
-			log.WithFields(log.Fields{
-				"error": err,
-			}).Fatal("main.setupTriggers: failed to create gcloud pubsub subscriber")
-			return
+// splitList - comma separated values without empty ones
+func splitList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
 		}
-
-		subManager := pubsub.NewDefaultManager(os.Getenv(EnvClusterName), projectID, opts.providers, ps)
-		go subManager.Start(ctx)
 	}
-
-	if os.Getenv(EnvTriggerPoll) != "0" {
-
-		registryClient := registry.New()
-		watcher := poll.NewRepositoryWatcher(opts.providers, registryClient)
-		pollManager := poll.NewPollManager(opts.providers, watcher)
-
-		// start poll manager, will finish with ctx
-		go watcher.Start(ctx)
-		go pollManager.Start(ctx)
-	}
-
-	teardown = func() {
-		whs.Stop()
-	}
-
-	return teardown
+	return values
 }
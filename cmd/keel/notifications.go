@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/extension/notification/auditor"
+	"github.com/keel-hq/keel/internal/bus"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/templates"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// setupNotifications - configures registered senders, notifications are
+// published to the event bus so extensions can subscribe to them
+func setupNotifications(ctx context.Context, eventBus *bus.Bus, sqlStore store.Store) *notification.DefaultNotificationSender {
+	// registering auditor to log events
+	auditLogger := auditor.New(sqlStore)
+	notification.RegisterSender("auditor", auditLogger)
+	// bots reply to approval requests with rollout progress of approved updates
+	notification.RegisterSender("approvals", bot.RolloutSender())
+
+	notificationLevel := types.LevelInfo
+	if os.Getenv(constants.EnvNotificationLevel) != "" {
+		parsedLevel, err := types.ParseLevel(os.Getenv(constants.EnvNotificationLevel))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Errorf("main: got error while parsing notification level, defaulting to: %s", notificationLevel)
+		} else {
+			notificationLevel = parsedLevel
+		}
+	}
+
+	if os.Getenv(constants.EnvMessageTemplates) != "" {
+		err := templates.LoadMessages(os.Getenv(constants.EnvMessageTemplates))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"path":  os.Getenv(constants.EnvMessageTemplates),
+			}).Fatal("main: failed to load message templates")
+		}
+	}
+
+	if os.Getenv(constants.EnvNotificationFilters) != "" {
+		err := notification.LoadFilters(os.Getenv(constants.EnvNotificationFilters))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"path":  os.Getenv(constants.EnvNotificationFilters),
+			}).Fatal("main: failed to load notification filters")
+		}
+	}
+
+	notifCfg := &notification.Config{
+		Attempts: 10,
+		Level:    notificationLevel,
+	}
+	if os.Getenv(constants.EnvNotificationDigestInterval) != "" {
+		interval, err := time.ParseDuration(os.Getenv(constants.EnvNotificationDigestInterval))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"interval": os.Getenv(constants.EnvNotificationDigestInterval),
+			}).Fatal("main: invalid notification digest interval")
+		}
+		notifCfg.DigestInterval = interval
+		if os.Getenv(constants.EnvNotificationDigestSenders) != "" {
+			notifCfg.DigestSenders = strings.Split(os.Getenv(constants.EnvNotificationDigestSenders), ",")
+		}
+	}
+
+	sender := notification.New(ctx)
+	sender.SetEventBus(eventBus)
+
+	_, err := sender.Configure(notifCfg)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("main: failed to configure notification sender manager")
+	}
+
+	return sender
+}
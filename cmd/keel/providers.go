@@ -0,0 +1,634 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	kube "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/helm/pkg/helm/portforwarder"
+
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/extension/plugin"
+	"github.com/keel-hq/keel/internal/allowlist"
+	"github.com/keel-hq/keel/internal/bluegreen"
+	"github.com/keel-hq/keel/internal/bus"
+	"github.com/keel-hq/keel/internal/canary"
+	"github.com/keel-hq/keel/internal/cosign"
+	"github.com/keel-hq/keel/internal/hygiene"
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/internal/leader"
+	"github.com/keel-hq/keel/internal/oncall"
+	"github.com/keel-hq/keel/internal/provenance"
+	"github.com/keel-hq/keel/internal/ratelimit"
+	"github.com/keel-hq/keel/internal/releasenotes"
+	"github.com/keel-hq/keel/internal/vulnscan"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/provider"
+	"github.com/keel-hq/keel/provider/gitops"
+	"github.com/keel-hq/keel/provider/helm"
+	"github.com/keel-hq/keel/provider/kubernetes"
+	"github.com/keel-hq/keel/provider/kustomize"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/util/chartrepo"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type ProviderOpts struct {
+	k8sImplementer   kubernetes.Implementer
+	sender           notification.Sender
+	approvalsManager approvals.Manager
+	grc              *k8s.GenericResourceCache
+	store            store.Store
+
+	k8sClient kube.Interface
+	config    *rest.Config
+
+	charts *chartrepo.Client
+
+	// cluster - main cluster name, set when keel manages several clusters
+	cluster  string
+	clusters []*cluster
+
+	elector *leader.Elector
+
+	// rateLimiter - update budgets shared by kubernetes and helm providers, nil
+	// when rate limiting is disabled
+	rateLimiter *ratelimit.Limiter
+
+	// scope - namespaces and workloads kubernetes providers can track, nil
+	// when they aren't restricted
+	scope *kubernetes.Scope
+
+	// plugins - gRPC plugins, gates are consulted before events reach providers
+	plugins []plugin.Config
+
+	// bus - event bus, providers receive trigger events through it
+	bus *bus.Bus
+}
+
+// approvalsChannels - chat channels configured for approval requests
+func approvalsChannels() []string {
+	var channels []string
+	for _, env := range []string{constants.EnvSlackApprovalsChannel, constants.EnvHipchatApprovalsChannel} {
+		if channel := strings.TrimPrefix(os.Getenv(env), "#"); channel != "" {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// updateAttempts - attempts of updates failing with transient errors, 0 when
+// providers should use their default
+func updateAttempts() int {
+	v := os.Getenv(EnvUpdateAttempts)
+	if v == "" {
+		return 0
+	}
+	attempts, err := strconv.Atoi(v)
+	if err != nil || attempts <= 0 {
+		log.WithFields(log.Fields{
+			"value": v,
+		}).Fatal("main: invalid update attempts, expected a positive number")
+	}
+	return attempts
+}
+
+// rateLimiter - budgets of automatic updates, nil when none is configured
+func rateLimiter() *ratelimit.Limiter {
+	var opts ratelimit.Opts
+	var err error
+	if os.Getenv(EnvRateLimit) != "" {
+		opts.Global, err = ratelimit.ParseBudget(os.Getenv(EnvRateLimit))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatalf("main: invalid %s", EnvRateLimit)
+		}
+	}
+	if os.Getenv(EnvRateLimitNamespace) != "" {
+		opts.Namespace, err = ratelimit.ParseBudget(os.Getenv(EnvRateLimitNamespace))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatalf("main: invalid %s", EnvRateLimitNamespace)
+		}
+	}
+	opts.Namespaces, err = ratelimit.ParseNamespaceBudgets(os.Getenv(EnvRateLimitNamespaces))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatalf("main: invalid %s", EnvRateLimitNamespaces)
+	}
+
+	limiter := ratelimit.New(opts)
+	if limiter != nil {
+		log.WithFields(log.Fields{
+			"global":     opts.Global.String(),
+			"namespace":  opts.Namespace.String(),
+			"namespaces": os.Getenv(EnvRateLimitNamespaces),
+		}).Info("main: rate limiting of automatic updates enabled")
+	}
+	return limiter
+}
+
+// canaryPrometheus - Prometheus used for canary analysis, nil when canaries are disabled
+func canaryPrometheus() *canary.Prometheus {
+	if os.Getenv(EnvCanaryPrometheusURL) == "" {
+		return nil
+	}
+	return canary.NewPrometheus(os.Getenv(EnvCanaryPrometheusURL))
+}
+
+// signatureVerifier - cosign verifier of image signatures, nil when no keys or
+// identities are configured
+func signatureVerifier() *cosign.Verifier {
+	opts := cosign.Options{
+		FulcioRoots:    os.Getenv(EnvCosignFulcioRoots),
+		RekorPublicKey: os.Getenv(EnvCosignRekorPublicKey),
+	}
+	for _, key := range strings.Split(os.Getenv(EnvCosignPublicKeys), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			opts.PublicKeys = append(opts.PublicKeys, key)
+		}
+	}
+	for _, value := range strings.Split(os.Getenv(EnvCosignIdentities), ",") {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		identity, err := cosign.ParseIdentity(value)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("main: invalid cosign identity")
+		}
+		opts.Identities = append(opts.Identities, identity)
+	}
+	if len(opts.PublicKeys) == 0 && len(opts.Identities) == 0 {
+		return nil
+	}
+
+	verifier, err := cosign.New(registry.New(), opts)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("main: failed to configure image signature verification")
+	}
+	return verifier
+}
+
+// provenanceVerifier - verifier of image provenance attestations, nil when no
+// builders are configured
+func provenanceVerifier() *provenance.Verifier {
+	var builders []string
+	for _, builder := range strings.Split(os.Getenv(EnvProvenanceBuilders), ",") {
+		if builder = strings.TrimSpace(builder); builder != "" {
+			builders = append(builders, builder)
+		}
+	}
+	if len(builders) == 0 {
+		return nil
+	}
+
+	verifier, err := provenance.New(registry.New(), builders)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("main: failed to configure image provenance verification")
+	}
+	return verifier
+}
+
+// freezeSelector - selector of frozen workloads, nil when it's not configured
+func freezeSelector() labels.Selector {
+	if os.Getenv(EnvFreezeSelector) == "" {
+		return nil
+	}
+	selector, err := labels.Parse(os.Getenv(EnvFreezeSelector))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"selector": os.Getenv(EnvFreezeSelector),
+		}).Fatal("main: invalid freeze selector")
+	}
+	return selector
+}
+
+// trackingScope - namespaces and workloads keel can track, nil when it's not restricted
+func trackingScope() *kubernetes.Scope {
+	scope := &kubernetes.Scope{
+		Namespaces:        splitList(os.Getenv(EnvTrackNamespaces)),
+		IgnoredNamespaces: splitList(os.Getenv(EnvIgnoreNamespaces)),
+	}
+	if len(scope.Namespaces) == 0 {
+		scope.Namespaces = splitList(os.Getenv(EnvWatchNamespaces))
+	}
+	for env, selector := range map[string]*labels.Selector{
+		EnvTrackNamespaceSelector: &scope.NamespaceSelector,
+		EnvTrackSelector:          &scope.Selector,
+	} {
+		if os.Getenv(env) == "" {
+			continue
+		}
+		parsed, err := labels.Parse(os.Getenv(env))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"selector": os.Getenv(env),
+			}).Fatalf("main: invalid %s", env)
+		}
+		*selector = parsed
+	}
+
+	if len(scope.Namespaces) == 0 && len(scope.IgnoredNamespaces) == 0 && scope.NamespaceSelector == nil && scope.Selector == nil {
+		return nil
+	}
+	return scope
+}
+
+// pluginsConfig - configured gRPC plugins
+func pluginsConfig() []plugin.Config {
+	if os.Getenv(EnvPluginsConfig) == "" {
+		return nil
+	}
+	plugins, err := plugin.LoadConfig(os.Getenv(EnvPluginsConfig))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"path":  os.Getenv(EnvPluginsConfig),
+		}).Fatal("main: failed to load plugins")
+	}
+	return plugins
+}
+
+// oncallResolver - resolver of on-call schedules, nil when it's not configured
+func oncallResolver() oncall.Resolver {
+	if os.Getenv(EnvOncallProvider) == "" {
+		return nil
+	}
+	resolver, err := oncall.New(os.Getenv(EnvOncallProvider), os.Getenv(EnvOncallAPIURL), os.Getenv(EnvOncallAPIToken))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("main: failed to configure on-call schedules")
+	}
+	return resolver
+}
+
+// tagAllowlists - fetcher of allowlists of deployable tags, nil when none are configured
+func tagAllowlists() *allowlist.Fetcher {
+	if os.Getenv(EnvTagAllowlists) == "" {
+		return nil
+	}
+	sources, err := allowlist.ParseSources(os.Getenv(EnvTagAllowlists))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("main: invalid tag allowlists")
+	}
+	var interval time.Duration
+	if os.Getenv(EnvTagAllowlistInterval) != "" {
+		interval, err = time.ParseDuration(os.Getenv(EnvTagAllowlistInterval))
+		if err != nil || interval <= 0 {
+			log.WithFields(log.Fields{
+				"interval": os.Getenv(EnvTagAllowlistInterval),
+			}).Fatal("main: invalid tag allowlist interval")
+		}
+	}
+	return allowlist.New(&allowlist.Opts{
+		Sources:  sources,
+		Token:    os.Getenv(EnvTagAllowlistToken),
+		Interval: interval,
+	})
+}
+
+// hygieneReporter - reporter of deployed images drifting from registry contents,
+// nil when it's not enabled
+func hygieneReporter(providers provider.Providers, sender notification.Sender) (*hygiene.Reporter, time.Duration) {
+	if os.Getenv(EnvHygieneReportInterval) == "" {
+		return nil, 0
+	}
+	interval, err := time.ParseDuration(os.Getenv(EnvHygieneReportInterval))
+	if err != nil || interval <= 0 {
+		log.WithFields(log.Fields{
+			"interval": os.Getenv(EnvHygieneReportInterval),
+		}).Fatal("main: invalid hygiene report interval")
+	}
+
+	opts := hygiene.Opts{
+		Images:   providers,
+		Registry: registry.New(),
+		Scanner:  vulnerabilityScanner(),
+		Sender:   sender,
+	}
+	if verifier := signatureVerifier(); verifier != nil {
+		opts.Verifier = verifier
+	}
+	if os.Getenv(EnvHygieneBehindThreshold) != "" {
+		opts.BehindThreshold, err = strconv.Atoi(os.Getenv(EnvHygieneBehindThreshold))
+		if err != nil || opts.BehindThreshold <= 0 {
+			log.WithFields(log.Fields{
+				"threshold": os.Getenv(EnvHygieneBehindThreshold),
+			}).Fatal("main: invalid hygiene behind threshold")
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"interval": interval,
+	}).Info("main: hygiene reports of deployed images enabled")
+	return hygiene.New(opts), interval
+}
+
+// releaseNotesFetcher - fetcher of release notes of new images, nil when it's not enabled
+func releaseNotesFetcher() *releasenotes.Fetcher {
+	if os.Getenv(EnvReleaseNotes) != "true" {
+		return nil
+	}
+	maxLength := 0
+	if v := os.Getenv(EnvReleaseNotesMaxLength); v != "" {
+		var err error
+		maxLength, err = strconv.Atoi(v)
+		if err != nil || maxLength <= 0 {
+			log.WithFields(log.Fields{
+				"value": v,
+			}).Fatal("main: invalid release notes max length, expected a positive number")
+		}
+	}
+	return releasenotes.New(&releasenotes.Opts{
+		Registry:     registry.New(),
+		GithubAPIURL: os.Getenv(constants.EnvGithubAPIURL),
+		GithubToken:  os.Getenv(constants.EnvGithubToken),
+		MaxLength:    maxLength,
+	})
+}
+
+// vulnerabilityScanner - scanner of new images, nil when it's not configured
+func vulnerabilityScanner() vulnscan.Scanner {
+	address := os.Getenv(EnvVulnerabilityScannerURL)
+	switch os.Getenv(EnvVulnerabilityScanner) {
+	case "":
+		return nil
+	case "harbor":
+		return vulnscan.NewHarbor(address, os.Getenv(EnvVulnerabilityScannerUsername), os.Getenv(EnvVulnerabilityScannerPassword))
+	case "webhook":
+		return vulnscan.NewWebhook(address)
+	}
+	log.WithFields(log.Fields{
+		"scanner": os.Getenv(EnvVulnerabilityScanner),
+	}).Fatal("main: unknown vulnerability scanner, expected harbor or webhook")
+	return nil
+}
+
+// setupProviders - setting up available providers. New providers should be initialised here and added to
+// provider map
+func setupProviders(opts *ProviderOpts) (providers provider.Providers, helmProvider *helm.Provider) {
+	var enabledProviders []provider.Provider
+
+	verifier := signatureVerifier()
+	scanner := vulnerabilityScanner()
+	attestationVerifier := provenanceVerifier()
+	frozen := freezeSelector()
+	oncallSchedules := oncallResolver()
+	notesFetcher := releaseNotesFetcher()
+
+	k8sProvider, err := kubernetes.NewProvider(opts.k8sImplementer, opts.sender, opts.approvalsManager, opts.grc, opts.store)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("main.setupProviders: failed to create kubernetes provider")
+	}
+	k8sProvider.SetCluster(opts.cluster)
+	k8sProvider.SetApprovalsChannels(approvalsChannels())
+	k8sProvider.SetRegistryClient(registry.New())
+	k8sProvider.SetPullPreflight(os.Getenv(EnvPullPreflight) == "true")
+	k8sProvider.SetDigestRestart(os.Getenv(EnvDigestRestart) == "true")
+	k8sProvider.SetPinDeletedTags(os.Getenv(EnvPinDeletedTags) == "true")
+	k8sProvider.SetUpdateAttempts(updateAttempts())
+	if prometheus := canaryPrometheus(); prometheus != nil {
+		k8sProvider.SetCanaryController(canary.New(opts.k8sClient.AppsV1(), prometheus))
+	}
+	k8sProvider.SetBlueGreenController(bluegreen.New(opts.k8sClient.AppsV1(), opts.k8sClient.CoreV1()))
+	if verifier != nil {
+		k8sProvider.SetSignatureVerifier(verifier)
+	}
+	if scanner != nil {
+		k8sProvider.SetScanner(scanner)
+	}
+	if attestationVerifier != nil {
+		k8sProvider.SetProvenanceVerifier(attestationVerifier)
+	}
+	if frozen != nil {
+		k8sProvider.SetFreezeSelector(frozen)
+	}
+	if opts.scope != nil {
+		k8sProvider.SetScope(opts.scope)
+	}
+	if oncallSchedules != nil {
+		k8sProvider.SetOncallResolver(oncallSchedules)
+	}
+	if notesFetcher != nil {
+		k8sProvider.SetReleaseNotesFetcher(notesFetcher)
+	}
+	if opts.rateLimiter != nil {
+		k8sProvider.SetRateLimiter(opts.rateLimiter)
+	}
+	go func() {
+		err := k8sProvider.Start()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("kubernetes provider stopped with an error")
+		}
+	}()
+
+	enabledProviders = append(enabledProviders, k8sProvider)
+
+	for _, c := range opts.clusters {
+		clusterProvider, err := kubernetes.NewProvider(c.implementer, opts.sender, opts.approvalsManager, &c.translator.GenericResourceCache, opts.store)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"cluster": c.name,
+			}).Fatal("main.setupProviders: failed to create kubernetes provider for cluster")
+		}
+		clusterProvider.SetCluster(c.name)
+		clusterProvider.SetApprovalsChannels(approvalsChannels())
+		clusterProvider.SetRegistryClient(registry.New())
+		clusterProvider.SetPullPreflight(os.Getenv(EnvPullPreflight) == "true")
+		clusterProvider.SetDigestRestart(os.Getenv(EnvDigestRestart) == "true")
+		clusterProvider.SetPinDeletedTags(os.Getenv(EnvPinDeletedTags) == "true")
+		clusterProvider.SetUpdateAttempts(updateAttempts())
+		if prometheus := canaryPrometheus(); prometheus != nil {
+			clusterProvider.SetCanaryController(canary.New(c.implementer.Client().AppsV1(), prometheus))
+		}
+		clusterProvider.SetBlueGreenController(bluegreen.New(c.implementer.Client().AppsV1(), c.implementer.Client().CoreV1()))
+		if verifier != nil {
+			clusterProvider.SetSignatureVerifier(verifier)
+		}
+		if scanner != nil {
+			clusterProvider.SetScanner(scanner)
+		}
+		if attestationVerifier != nil {
+			clusterProvider.SetProvenanceVerifier(attestationVerifier)
+		}
+		if frozen != nil {
+			clusterProvider.SetFreezeSelector(frozen)
+		}
+		if opts.scope != nil {
+			clusterProvider.SetScope(opts.scope)
+		}
+		if oncallSchedules != nil {
+			clusterProvider.SetOncallResolver(oncallSchedules)
+		}
+		if notesFetcher != nil {
+			clusterProvider.SetReleaseNotesFetcher(notesFetcher)
+		}
+		if opts.rateLimiter != nil {
+			clusterProvider.SetRateLimiter(opts.rateLimiter)
+		}
+		go func(name string) {
+			err := clusterProvider.Start()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":   err,
+					"cluster": name,
+				}).Fatal("kubernetes provider stopped with an error")
+			}
+		}(c.name)
+
+		enabledProviders = append(enabledProviders, clusterProvider)
+	}
+
+	if os.Getenv(EnvHelmProvider) == "1" || os.Getenv(EnvHelmProvider) == "true" {
+
+		var tillerAddr string
+
+		if os.Getenv(EnvHelmTillerAddress) != "" {
+			tillerAddr = os.Getenv(EnvHelmTillerAddress)
+			log.Infof("Tiller address specified: %s", tillerAddr)
+		} else {
+			tillerNamespace := "kube-system"
+			if os.Getenv(EnvHelmTillerNamespace) != "" {
+				tillerNamespace = os.Getenv(EnvHelmTillerNamespace)
+			}
+
+			tillerTunnel, err := portforwarder.New(tillerNamespace, opts.k8sClient, opts.config)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+				}).Fatal("failed to setup Tiller tunnel")
+			}
+
+			tillerAddr = fmt.Sprintf("127.0.0.1:%d", tillerTunnel.Local)
+			log.Infof("created local tunnel using local port: '%d'", tillerTunnel.Local)
+		}
+
+		helmImplementer := helm.NewHelmImplementer(tillerAddr)
+		helmProvider = helm.NewProvider(helmImplementer, opts.sender, opts.approvalsManager, opts.store)
+		helmProvider.SetChartFetcher(opts.charts)
+		helmProvider.SetUpdateAttempts(updateAttempts())
+		if opts.rateLimiter != nil {
+			helmProvider.SetRateLimiter(opts.rateLimiter)
+		}
+
+		go func() {
+			err := helmProvider.Start()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+				}).Fatal("helm provider stopped with an error")
+			}
+		}()
+
+		enabledProviders = append(enabledProviders, helmProvider)
+	}
+
+	if os.Getenv(EnvGitOpsConfig) != "" {
+		gitopsCfg, err := gitops.LoadConfig(os.Getenv(EnvGitOpsConfig))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"path":  os.Getenv(EnvGitOpsConfig),
+			}).Fatal("main.setupProviders: failed to load gitops config")
+		}
+		gitopsProvider, err := gitops.NewProvider(gitopsCfg, opts.sender, opts.approvalsManager)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("main.setupProviders: failed to create gitops provider")
+		}
+		go func() {
+			err := gitopsProvider.Start()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+				}).Fatal("gitops provider stopped with an error")
+			}
+		}()
+
+		enabledProviders = append(enabledProviders, gitopsProvider)
+	}
+
+	if os.Getenv(EnvKustomizeConfig) != "" {
+		kustomizeCfg, err := kustomize.LoadConfig(os.Getenv(EnvKustomizeConfig))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"path":  os.Getenv(EnvKustomizeConfig),
+			}).Fatal("main.setupProviders: failed to load kustomize config")
+		}
+		kustomizeProvider, err := kustomize.NewProvider(kustomizeCfg, opts.k8sImplementer, opts.sender, opts.approvalsManager)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("main.setupProviders: failed to create kustomize provider")
+		}
+		go func() {
+			err := kustomizeProvider.Start()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+				}).Fatal("kustomize provider stopped with an error")
+			}
+		}()
+
+		enabledProviders = append(enabledProviders, kustomizeProvider)
+	}
+
+	defaultProviders := provider.New(enabledProviders, opts.approvalsManager)
+	if opts.elector != nil {
+		defaultProviders.SetLeaderElection(opts.elector.IsLeader)
+	}
+
+	var gates []provider.Gate
+	for _, cfg := range opts.plugins {
+		if cfg.Role != plugin.RoleGate {
+			continue
+		}
+		gate, err := plugin.NewGate(cfg)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"plugin": cfg.Name,
+			}).Fatal("main.setupProviders: failed to create gate plugin")
+		}
+		log.WithFields(log.Fields{
+			"plugin":  cfg.Name,
+			"address": cfg.Address,
+		}).Info("main.setupProviders: gate plugin registered")
+		gates = append(gates, gate)
+	}
+	defaultProviders.SetGates(gates)
+	if opts.bus != nil {
+		defaultProviders.SetEventBus(opts.bus)
+	}
+
+	return defaultProviders, helmProvider
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	secretsCredentialsHelper "github.com/keel-hq/keel/extension/credentialshelper/secrets"
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/internal/workgroup"
+	"github.com/keel-hq/keel/provider/kubernetes"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/secrets"
+	"github.com/keel-hq/keel/util/chartrepo"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// setupRegistries - loads registry configuration and credentials files
+func setupRegistries() {
+	if path := registry.ConfigPath(); path != "" {
+		err := registry.LoadConfig(path)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"path":  path,
+			}).Fatal("main: failed to load registry config")
+		}
+	}
+
+	if os.Getenv(credentialshelper.EnvRegistryCredentials) != "" {
+		err := credentialshelper.LoadRegistryCredentials(os.Getenv(credentialshelper.EnvRegistryCredentials))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"path":  os.Getenv(credentialshelper.EnvRegistryCredentials),
+			}).Fatal("main: failed to load registry credentials")
+		}
+	}
+}
+
+// chartRepositories - client of Helm chart repositories and OCI registries
+func chartRepositories() *chartrepo.Client {
+	charts := chartrepo.New(registry.New())
+	if os.Getenv(EnvHelmRegistryConfig) != "" {
+		err := charts.LoadRegistryConfig(os.Getenv(EnvHelmRegistryConfig))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"path":  os.Getenv(EnvHelmRegistryConfig),
+			}).Fatal("main: failed to load helm registry config")
+		}
+	}
+	if os.Getenv(EnvHelmKeyring) != "" {
+		charts.SetKeyring(os.Getenv(EnvHelmKeyring))
+	}
+	return charts
+}
+
+// setupSecretsCredentials - registers credentials helper that uses image pull
+// secrets of the workloads, secrets of all clusters are watched
+func setupSecretsCredentials(g *workgroup.Group, implementer *kubernetes.KubernetesImplementer, clusterName string, clusters []*cluster) {
+	dockerConfig := make(secrets.DockerCfg)
+	if os.Getenv(EnvDefaultDockerRegistryCfg) != "" {
+		var err error
+		dockerConfig, err = secrets.DecodeDockerCfgJson([]byte(os.Getenv(EnvDefaultDockerRegistryCfg)))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatalf("failed to decode secret provided in %s env variable", EnvDefaultDockerRegistryCfg)
+		}
+	}
+	secretsGetter := secrets.NewGetter(implementer, dockerConfig)
+	k8s.WatchSecrets(g, implementer.Client(), log.WithField("context", "watch"), secretsGetter.EventHandler(clusterName))
+	for _, c := range clusters {
+		secretsGetter.AddCluster(c.name, c.implementer)
+		k8s.WatchSecrets(g, c.implementer.Client(), log.WithFields(log.Fields{"context": "watch", "cluster": c.name}), secretsGetter.EventHandler(c.name))
+	}
+
+	ch := secretsCredentialsHelper.New(secretsGetter)
+	credentialshelper.RegisterCredentialsHelper("secrets", ch)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/pkg/http"
+	"github.com/keel-hq/keel/pkg/rpc"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// setupRPC - starts gRPC API when its port is set, calls are authenticated by
+// the trigger server so authentication or API tokens have to be configured
+func setupRPC(opts *TriggerOpts, whs *http.TriggerServer, authenticated bool) *rpc.Server {
+	if os.Getenv(constants.EnvGRPCPort) == "" {
+		return nil
+	}
+	port, err := strconv.Atoi(os.Getenv(constants.EnvGRPCPort))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"port":  os.Getenv(constants.EnvGRPCPort),
+		}).Fatal("main.setupRPC: invalid gRPC port")
+	}
+	if !authenticated {
+		log.Warn("main.setupRPC: gRPC API requires authentication, set basic auth credentials or API tokens")
+		return nil
+	}
+
+	rpcServer := rpc.New(&rpc.Opts{
+		Port:            port,
+		Providers:       opts.providers,
+		ApprovalManager: opts.approvalsManager,
+		Store:           opts.store,
+		Authenticator:   whs,
+	})
+	go func() {
+		err := rpcServer.Start()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"port":  port,
+			}).Fatal("gRPC API server stopped")
+		}
+	}()
+	return rpcServer
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/internal/tracing"
+	"github.com/keel-hq/keel/pkg/store/sql"
+	"github.com/keel-hq/keel/provider"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultShutdownTimeout - how long keel waits for in-flight updates and
+// pending messages on shutdown, pod termination grace period has to be longer
+const DefaultShutdownTimeout = 25 * time.Second
+
+func shutdownTimeout() time.Duration {
+	if os.Getenv(EnvShutdownTimeout) == "" {
+		return DefaultShutdownTimeout
+	}
+	timeout, err := time.ParseDuration(os.Getenv(EnvShutdownTimeout))
+	if err != nil || timeout <= 0 {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"timeout": os.Getenv(EnvShutdownTimeout),
+		}).Fatal("main: invalid shutdown timeout")
+	}
+	return timeout
+}
+
+type shutdownOpts struct {
+	timeout time.Duration
+
+	// stopTriggers - stops webhook servers, admission webhook and background
+	// loops (poll, pubsub, plugins, approvals expiry)
+	stopTriggers []func()
+
+	providers     provider.Providers
+	sender        *notification.DefaultNotificationSender
+	traceExporter *tracing.Exporter
+	store         *sql.SQLStore
+
+	// releaseLease - another replica takes over once in-flight updates are done
+	releaseLease func()
+}
+
+// shutdown - stops accepting new events, lets providers finish in-flight
+// updates, flushes notifications and bot messages and closes the database.
+// Steps share the timeout, whatever isn't done by then is abandoned.
+func shutdown(opts *shutdownOpts) {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+
+	log.Info("main.shutdown: stopping triggers")
+	for _, stop := range opts.stopTriggers {
+		stop()
+	}
+
+	if drainer, ok := opts.providers.(interface{ Drain(context.Context) }); ok {
+		log.Info("main.shutdown: waiting for in-flight updates")
+		drainer.Drain(ctx)
+	}
+	opts.providers.Stop()
+
+	opts.sender.Flush()
+
+	log.Info("main.shutdown: sending pending bot messages")
+	bot.Drain(ctx)
+
+	if opts.traceExporter != nil {
+		opts.traceExporter.Stop()
+	}
+
+	opts.releaseLease()
+
+	if err := opts.store.Close(); err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("main.shutdown: failed to close database")
+	}
+
+	if ctx.Err() != nil {
+		log.WithFields(log.Fields{
+			"timeout": opts.timeout,
+		}).Warn("main.shutdown: shutdown timed out")
+		return
+	}
+	log.Info("main.shutdown: done")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+
+	"github.com/keel-hq/keel/internal/backup"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/version"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// exportState - writes keel state to path, format is picked by the file
+// extension (.json, .tar, .tar.gz or .tgz)
+func exportState(s store.Store, path string) error {
+	state, err := s.ExportState()
+	if err != nil {
+		return err
+	}
+	state.KeelVersion = version.GetKeelVersion().Version
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = backup.Write(f, state, backup.FormatFromPath(path))
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"path":      path,
+		"approvals": len(state.Approvals),
+		"audit":     len(state.AuditLogs),
+		"paused":    len(state.PausedResources),
+		"history":   len(state.UpdateRecords),
+		"digests":   len(state.PollDigests),
+	}).Info("main.exportState: state exported")
+	return f.Sync()
+}
+
+// importState - imports state exported with exportState or the API
+func importState(s store.Store, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	state, err := backup.Read(f)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.ImportState(state)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"path":         path,
+		"keel_version": state.KeelVersion,
+		"imported":     result.Imported,
+		"skipped":      result.Skipped,
+	}).Info("main.importState: state imported")
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/keel-hq/keel/pkg/store/sql"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// setupStore - opens the database and applies pending migrations, sqlite
+// database in the data dir is used by default
+func setupStore(dataDir string) *sql.SQLStore {
+	dbType, err := sql.ParseDatabaseType(os.Getenv(EnvDatabaseType))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("main: invalid database type")
+	}
+	dbURI := os.Getenv(EnvDatabaseDSN)
+	if dbType == sql.DatabaseTypeSQLite && dbURI == "" {
+		dbURI = filepath.Join(dataDir, "keel.db")
+	}
+	if dbURI == "" {
+		log.WithFields(log.Fields{
+			"type": dbType,
+		}).Fatalf("main: %s is required for %s database", EnvDatabaseDSN, dbType)
+	}
+
+	db, err := sql.New(sql.Opts{
+		DatabaseType: dbType,
+		URI:          dbURI,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("failed to initialize database")
+		os.Exit(1)
+	}
+	dbFields := log.Fields{"type": dbType}
+	if dbType == sql.DatabaseTypeSQLite {
+		dbFields["database_path"] = dbURI
+	}
+	log.WithFields(dbFields).Info("initializing database")
+
+	return db
+}
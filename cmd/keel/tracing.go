@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+
+	"github.com/keel-hq/keel/internal/tracing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// setupTracing - starts exporting spans to the OTLP collector, nil when its
+// endpoint isn't set
+func setupTracing() *tracing.Exporter {
+	if os.Getenv(EnvOTLPEndpoint) == "" {
+		return nil
+	}
+
+	exporter := tracing.NewExporter(tracing.ExporterOpts{
+		Endpoint:    os.Getenv(EnvOTLPEndpoint),
+		Headers:     tracing.ParseHeaders(os.Getenv(EnvOTLPHeaders)),
+		ServiceName: os.Getenv(EnvOTELServiceName),
+	})
+	tracing.SetExporter(exporter)
+	log.WithFields(log.Fields{
+		"endpoint": os.Getenv(EnvOTLPEndpoint),
+	}).Info("main: tracing enabled")
+
+	return exporter
+}
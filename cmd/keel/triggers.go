@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/approval"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/extension/plugin"
+	"github.com/keel-hq/keel/internal/hygiene"
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/internal/leader"
+	"github.com/keel-hq/keel/internal/ratelimit"
+	"github.com/keel-hq/keel/pkg/auth"
+	"github.com/keel-hq/keel/pkg/http"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/provider"
+	"github.com/keel-hq/keel/provider/helm"
+	"github.com/keel-hq/keel/provider/kubernetes"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/trigger/chart"
+	"github.com/keel-hq/keel/trigger/dedup"
+	"github.com/keel-hq/keel/trigger/ecr"
+	"github.com/keel-hq/keel/trigger/nats"
+	"github.com/keel-hq/keel/trigger/poll"
+	"github.com/keel-hq/keel/trigger/pubsub"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/chartrepo"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type TriggerOpts struct {
+	providers         provider.Providers
+	approvalsManager  approvals.Manager
+	approvalCollector *approval.MainCollector
+	grc               *k8s.GenericResourceCache
+	k8sClient         kubernetes.Implementer
+	store             store.Store
+	sender            notification.Sender
+	helmProvider      *helm.Provider
+	charts            *chartrepo.Client
+	uiDir             string
+	elector           *leader.Elector
+	rateLimiter       *ratelimit.Limiter
+	scope             *kubernetes.Scope
+	plugins           []plugin.Config
+	healthChecks      []http.HealthCheck
+	accessChecker     *k8s.AccessChecker
+	hygieneReporter   *hygiene.Reporter
+}
+
+// setupTriggers - setting up triggers. New triggers should be added to this function. Each trigger
+// should go through all providers (or not if there is a reason) and submit events)
+// func setupTriggers(ctx context.Context, providers provider.Providers, approvalsManager approvals.Manager, grc *k8s.GenericResourceCache, k8sClient kubernetes.Implementer) (teardown func()) {
+func setupTriggers(ctx context.Context, opts *TriggerOpts) (teardown func()) {
+
+	if os.Getenv(EnvTriggerDedupWindow) != "" {
+		window, err := time.ParseDuration(os.Getenv(EnvTriggerDedupWindow))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"window": os.Getenv(EnvTriggerDedupWindow),
+			}).Fatal("main.setupTriggers: invalid trigger deduplication window")
+		}
+		opts.providers = dedup.New(opts.providers, window)
+	}
+
+	oidc := setupOIDC()
+	authenticator := auth.New(&auth.Opts{
+		Username: os.Getenv(constants.EnvBasicAuthUser),
+		Password: os.Getenv(constants.EnvBasicAuthPassword),
+		Secret:   []byte(os.Getenv(constants.EnvTokenSecret)),
+		OIDC:     oidc,
+	})
+	apiTokens := setupAPITokens()
+
+	var customWebhooks *http.CustomWebhooks
+	if os.Getenv(constants.EnvCustomWebhooks) != "" {
+		var err error
+		customWebhooks, err = http.LoadCustomWebhooks(os.Getenv(constants.EnvCustomWebhooks))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"path":  os.Getenv(constants.EnvCustomWebhooks),
+			}).Fatal("main.setupTriggers: failed to load custom webhooks")
+		}
+	}
+
+	webhookHistorySize := http.DefaultWebhookHistorySize
+	if os.Getenv(constants.EnvWebhookHistorySize) != "" {
+		size, err := strconv.Atoi(os.Getenv(constants.EnvWebhookHistorySize))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"size":  os.Getenv(constants.EnvWebhookHistorySize),
+			}).Fatal("main.setupTriggers: invalid webhook history size")
+		}
+		webhookHistorySize = size
+	}
+
+	// setting up generic http webhook server
+	var isLeader func() bool
+	if opts.elector != nil {
+		isLeader = opts.elector.IsLeader
+	}
+
+	whs := http.NewTriggerServer(&http.Opts{
+		Port:                  types.KeelDefaultPort,
+		GRC:                   opts.grc,
+		KubernetesClient:      opts.k8sClient,
+		HealthChecks:          opts.healthChecks,
+		IsLeader:              isLeader,
+		RateLimiter:           opts.rateLimiter,
+		Scope:                 opts.scope,
+		Providers:             opts.providers,
+		ApprovalManager:       opts.approvalsManager,
+		ApprovalCollector:     opts.approvalCollector,
+		Store:                 opts.store,
+		Authenticator:         authenticator,
+		APITokens:             apiTokens,
+		OIDC:                  oidc,
+		OIDCUIRedirect:        os.Getenv(constants.EnvOIDCUIRedirect),
+		UIDir:                 opts.uiDir,
+		AuthenticatedWebhooks: os.Getenv(constants.EnvAuthenticatedWebhooks) == "true",
+		GithubWebhookSecret:   os.Getenv(constants.EnvGithubWebhookSecret),
+
+		NativeWebhookSecret:             os.Getenv(constants.EnvNativeWebhookSecret),
+		NativeWebhookSignatureHeader:    os.Getenv(constants.EnvNativeWebhookSignatureHeader),
+		NativeWebhookSignatureAlgorithm: os.Getenv(constants.EnvNativeWebhookSignatureAlgorithm),
+		CustomWebhooks:                  customWebhooks,
+		WebhookHistorySize:              webhookHistorySize,
+		AccessChecker:                   opts.accessChecker,
+		HygieneReporter:                 opts.hygieneReporter,
+	})
+
+	go func() {
+		err := whs.Start()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"port":  types.KeelDefaultPort,
+			}).Fatal("trigger server stopped")
+		}
+	}()
+
+	rpcServer := setupRPC(opts, whs, authenticator.Enabled() || len(apiTokens) > 0)
+
+	// checking whether pubsub (GCR) trigger is enabled
+	if os.Getenv(EnvTriggerPubSub) != "" {
+		projectID := os.Getenv(EnvProjectID)
+		if projectID == "" {
+			log.Fatalf("main.setupTriggers: project ID env variable not set")
+			return
+		}
+
+		ps, err := pubsub.NewPubsubSubscriber(&pubsub.Opts{
+			ProjectID: projectID,
+			Providers: opts.providers,
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("main.setupTriggers: failed to create gcloud pubsub subscriber")
+			return
+		}
+
+		subManager := pubsub.NewDefaultManager(os.Getenv(EnvClusterName), projectID, opts.providers, ps)
+		whenLeading(ctx, opts.elector, func() { subManager.Start(ctx) })
+	}
+
+	// checking whether ECR (EventBridge/SQS) trigger is enabled
+	if os.Getenv(EnvTriggerECRQueueURL) != "" {
+		ecrTrigger, err := ecr.New(&ecr.Opts{
+			QueueURL:  os.Getenv(EnvTriggerECRQueueURL),
+			Providers: opts.providers,
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("main.setupTriggers: failed to create ECR SQS trigger")
+			return
+		}
+
+		whenLeading(ctx, opts.elector, func() { ecrTrigger.Start(ctx) })
+	}
+
+	// checking whether NATS trigger is enabled
+	if os.Getenv(EnvTriggerNATSURL) != "" && os.Getenv(EnvTriggerNATSSubject) != "" {
+		natsTrigger, err := nats.New(&nats.Opts{
+			URL:       os.Getenv(EnvTriggerNATSURL),
+			Subject:   os.Getenv(EnvTriggerNATSSubject),
+			Queue:     os.Getenv(EnvTriggerNATSQueue),
+			Stream:    os.Getenv(EnvTriggerNATSStream),
+			Durable:   os.Getenv(EnvTriggerNATSDurable),
+			Providers: opts.providers,
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("main.setupTriggers: failed to create NATS trigger")
+			return
+		}
+
+		whenLeading(ctx, opts.elector, func() { natsTrigger.Start(ctx) })
+	}
+
+	for _, cfg := range opts.plugins {
+		if cfg.Role != plugin.RoleTrigger {
+			continue
+		}
+		pluginTrigger, err := plugin.NewTrigger(cfg, opts.providers)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"plugin": cfg.Name,
+			}).Fatal("main.setupTriggers: failed to create trigger plugin")
+		}
+		whenLeading(ctx, opts.elector, func() { pluginTrigger.Start(ctx) })
+	}
+
+	if os.Getenv(EnvTriggerPoll) != "0" {
+
+		registryClient := registry.New()
+		watcher := poll.NewRepositoryWatcher(opts.providers, registryClient)
+		if os.Getenv(EnvTriggerPollJitter) != "" {
+			jitter, err := time.ParseDuration(os.Getenv(EnvTriggerPollJitter))
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":  err,
+					"jitter": os.Getenv(EnvTriggerPollJitter),
+				}).Fatal("main.setupTriggers: invalid poll jitter")
+			}
+			watcher.SetJitter(jitter)
+		}
+		if os.Getenv(EnvTriggerPollPlatform) != "" {
+			watcher.SetPlatform(os.Getenv(EnvTriggerPollPlatform))
+		}
+		cacheTTL := 24 * time.Hour
+		if os.Getenv(EnvTriggerPollCacheTTL) != "" {
+			var err error
+			cacheTTL, err = time.ParseDuration(os.Getenv(EnvTriggerPollCacheTTL))
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"ttl":   os.Getenv(EnvTriggerPollCacheTTL),
+				}).Fatal("main.setupTriggers: invalid poll cache TTL")
+			}
+		}
+		if cacheTTL > 0 {
+			err := watcher.SetDigestCache(opts.store, cacheTTL)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+				}).Error("main.setupTriggers: failed to load poll digest cache, checking all images on startup")
+			}
+		}
+		workers := poll.DefaultPollWorkers
+		if os.Getenv(EnvTriggerPollWorkers) != "" {
+			var err error
+			workers, err = strconv.Atoi(os.Getenv(EnvTriggerPollWorkers))
+			if err != nil || workers < 0 {
+				log.WithFields(log.Fields{
+					"error":   err,
+					"workers": os.Getenv(EnvTriggerPollWorkers),
+				}).Fatal("main.setupTriggers: invalid poll workers")
+			}
+		}
+		if workers > 0 {
+			var perRegistry int
+			if os.Getenv(EnvTriggerPollRegistryConcurrency) != "" {
+				var err error
+				perRegistry, err = strconv.Atoi(os.Getenv(EnvTriggerPollRegistryConcurrency))
+				if err != nil || perRegistry < 0 {
+					log.WithFields(log.Fields{
+						"error":       err,
+						"concurrency": os.Getenv(EnvTriggerPollRegistryConcurrency),
+					}).Fatal("main.setupTriggers: invalid poll registry concurrency")
+				}
+			}
+			watcher.SetWorkerPool(workers, perRegistry)
+		}
+		pollManager := poll.NewPollManager(opts.providers, watcher)
+
+		// start poll manager, will finish with ctx
+		whenLeading(ctx, opts.elector, func() { watcher.Start(ctx) })
+		whenLeading(ctx, opts.elector, func() { pollManager.Start(ctx) })
+
+		if os.Getenv(EnvTriggerPollDiscovery) != "" {
+			var interval time.Duration
+			if os.Getenv(EnvTriggerPollDiscoveryInterval) != "" {
+				var err error
+				interval, err = time.ParseDuration(os.Getenv(EnvTriggerPollDiscoveryInterval))
+				if err != nil {
+					log.WithFields(log.Fields{
+						"error":    err,
+						"interval": os.Getenv(EnvTriggerPollDiscoveryInterval),
+					}).Fatal("main.setupTriggers: invalid registry discovery interval")
+				}
+			}
+			discovery, err := poll.NewRegistryDiscovery(registryClient, opts.sender, strings.Split(os.Getenv(EnvTriggerPollDiscovery), ","), interval)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+				}).Fatal("main.setupTriggers: failed to setup registry discovery")
+			}
+			whenLeading(ctx, opts.elector, func() { discovery.Start(ctx) })
+		}
+	}
+
+	if os.Getenv(EnvHelmChartPoll) != "" && opts.helmProvider != nil {
+		interval, err := time.ParseDuration(os.Getenv(EnvHelmChartPoll))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"interval": os.Getenv(EnvHelmChartPoll),
+			}).Fatal("main.setupTriggers: invalid chart poll interval")
+		}
+		chartTrigger := chart.New(opts.providers, opts.helmProvider, opts.charts, interval)
+		whenLeading(ctx, opts.elector, func() { chartTrigger.Start(ctx) })
+	}
+
+	teardown = func() {
+		if rpcServer != nil {
+			rpcServer.Stop()
+		}
+		whs.Stop()
+	}
+
+	return teardown
+}
@@ -0,0 +1,327 @@
+// keelctl - command-line client of the keel API
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/keel-hq/keel/internal/ratelimit"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/version"
+
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// environment variables of the global flags
+const (
+	EnvAddr     = "KEEL_ADDR"
+	EnvToken    = "KEEL_TOKEN"
+	EnvUser     = "KEEL_USER"
+	EnvPassword = "KEEL_PASSWORD"
+)
+
+// output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+type trackedImage struct {
+	Image        string `json:"image"`
+	Trigger      string `json:"trigger"`
+	PollSchedule string `json:"pollSchedule"`
+	Provider     string `json:"provider"`
+	Namespace    string `json:"namespace"`
+	Policy       string `json:"policy"`
+	Paused       bool   `json:"paused"`
+	FreezeReason string `json:"freezeReason"`
+}
+
+type deployResponse struct {
+	Identifier string `json:"identifier"`
+	Image      string `json:"image"`
+	Previous   string `json:"previous"`
+	Tag        string `json:"tag"`
+}
+
+type rateLimitStatus struct {
+	Enabled bool `json:"enabled"`
+	ratelimit.Status
+}
+
+var (
+	app = kingpin.New("keelctl", "Command-line client of the keel API. Learn more on https://keel.sh.")
+
+	addr     = app.Flag("addr", "keel API address").Default("http://localhost:9300").Envar(EnvAddr).String()
+	token    = app.Flag("token", "API token, used instead of basic auth when set").Envar(EnvToken).String()
+	user     = app.Flag("user", "basic auth username").Envar(EnvUser).String()
+	password = app.Flag("password", "basic auth password").Envar(EnvPassword).String()
+	timeout  = app.Flag("timeout", "request timeout, doesn't apply to the event stream").Default("30s").Duration()
+	output   = app.Flag("output", "output format, table or json").Short('o').Default(outputTable).Enum(outputTable, outputJSON)
+
+	trackedCmd       = app.Command("tracked", "list tracked images")
+	trackedNamespace = trackedCmd.Flag("namespace", "only images of the namespace").Short('n').String()
+
+	approvalsCmd           = app.Command("approvals", "list, approve and reject approvals")
+	approvalsListCmd       = approvalsCmd.Command("list", "list approvals").Default()
+	approvalsListStatus    = approvalsListCmd.Flag("status", "only approvals with the status: pending, approved, rejected or archived").String()
+	approvalsListNamespace = approvalsListCmd.Flag("namespace", "only approvals of the namespace").Short('n').String()
+	approvalsApproveCmd    = approvalsCmd.Command("approve", "approve update")
+	approvalsApproveID     = approvalsApproveCmd.Arg("id", "approval ID").Required().String()
+	approvalsApproveVoter  = approvalsApproveCmd.Flag("voter", "user on whose behalf the vote is cast").String()
+	approvalsRejectCmd     = approvalsCmd.Command("reject", "reject update")
+	approvalsRejectID      = approvalsRejectCmd.Arg("id", "approval ID").Required().String()
+	approvalsRejectVoter   = approvalsRejectCmd.Flag("voter", "user on whose behalf the vote is cast").String()
+
+	pausedCmd = app.Command("paused", "list workloads with paused automatic updates")
+
+	pauseCmd        = app.Command("pause", "pause automatic updates of a workload")
+	pauseIdentifier = pauseCmd.Arg("namespace/name", "workload").Required().String()
+
+	resumeCmd        = app.Command("resume", "resume automatic updates of a workload")
+	resumeIdentifier = resumeCmd.Arg("namespace/name", "workload").Required().String()
+
+	deployCmd        = app.Command("deploy", "deploy specific tag, bypasses update policy but still requires approvals")
+	deployIdentifier = deployCmd.Arg("namespace/name", "workload").Required().String()
+	deployTag        = deployCmd.Arg("tag", "image tag").Required().String()
+	deployImage      = deployCmd.Flag("image", "image to update, required when the workload has more than one tracked image").String()
+
+	queueCmd = app.Command("queue", "get update budgets and updates queued by rate limiting")
+
+	eventsCmd    = app.Command("events", "stream keel events (audit log) until interrupted")
+	eventsFilter = eventsCmd.Flag("filter", "comma separated resource kinds, ie: approval,deployment").String()
+	eventsSince  = eventsCmd.Flag("since", "also stream events of the given duration before now, ie: 1h").Duration()
+)
+
+func main() {
+	app.UsageTemplate(kingpin.CompactUsageTemplate).Version(version.GetKeelVersion().Version)
+	command := kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	c := newClient(*addr, *token, *user, *password, *timeout)
+
+	var err error
+	switch command {
+	case trackedCmd.FullCommand():
+		err = listTracked(c, os.Stdout)
+	case approvalsListCmd.FullCommand():
+		err = listApprovals(c, os.Stdout)
+	case approvalsApproveCmd.FullCommand():
+		err = vote(c, os.Stdout, *approvalsApproveID, "approve", *approvalsApproveVoter)
+	case approvalsRejectCmd.FullCommand():
+		err = vote(c, os.Stdout, *approvalsRejectID, "reject", *approvalsRejectVoter)
+	case pausedCmd.FullCommand():
+		err = listPaused(c, os.Stdout)
+	case pauseCmd.FullCommand():
+		err = pause(c, os.Stdout, *pauseIdentifier)
+	case resumeCmd.FullCommand():
+		err = resume(c, os.Stdout, *resumeIdentifier)
+	case deployCmd.FullCommand():
+		err = deploy(c, os.Stdout, *deployIdentifier, *deployTag, *deployImage)
+	case queueCmd.FullCommand():
+		err = listQueue(c, os.Stdout)
+	case eventsCmd.FullCommand():
+		err = streamEvents(c, os.Stdout, *eventsFilter, *eventsSince)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "keelctl: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func listTracked(c *client, w io.Writer) error {
+	query := url.Values{}
+	if *trackedNamespace != "" {
+		query.Set("namespace", *trackedNamespace)
+	}
+
+	var images []trackedImage
+	err := c.do("GET", "/v1/tracked", query, nil, &images)
+	if err != nil {
+		return err
+	}
+
+	return render(w, images, []string{"NAMESPACE", "IMAGE", "PROVIDER", "TRIGGER", "SCHEDULE", "POLICY", "PAUSED"}, func(row func(...interface{})) {
+		for _, img := range images {
+			var paused interface{} = img.Paused
+			if img.FreezeReason != "" {
+				paused = "frozen (" + img.FreezeReason + ")"
+			}
+			row(img.Namespace, img.Image, img.Provider, img.Trigger, img.PollSchedule, img.Policy, paused)
+		}
+	})
+}
+
+func listApprovals(c *client, w io.Writer) error {
+	query := url.Values{}
+	if *approvalsListStatus != "" {
+		query.Set("status", *approvalsListStatus)
+	}
+	if *approvalsListNamespace != "" {
+		query.Set("namespace", *approvalsListNamespace)
+	}
+
+	var approvals []*types.Approval
+	err := c.do("GET", "/v1/approvals", query, nil, &approvals)
+	if err != nil {
+		return err
+	}
+
+	return render(w, approvals, []string{"ID", "IDENTIFIER", "CURRENT", "NEW", "VOTES", "STATUS", "DEADLINE"}, func(row func(...interface{})) {
+		for _, a := range approvals {
+			row(a.ID, a.Identifier, a.CurrentVersion, a.NewVersion, fmt.Sprintf("%d/%d", a.VotesReceived, a.VotesRequired), a.Status(), a.Deadline.Format(time.RFC3339))
+		}
+	})
+}
+
+func vote(c *client, w io.Writer, id, action, voter string) error {
+	var body interface{}
+	if voter != "" {
+		body = map[string]string{"voter": voter}
+	}
+
+	var approval types.Approval
+	err := c.do("POST", "/v1/approvals/"+url.PathEscape(id)+"/"+action, nil, body, &approval)
+	if err != nil {
+		return err
+	}
+
+	return render(w, &approval, []string{"ID", "IDENTIFIER", "VOTES", "STATUS"}, func(row func(...interface{})) {
+		row(approval.ID, approval.Identifier, fmt.Sprintf("%d/%d", approval.VotesReceived, approval.VotesRequired), approval.Status())
+	})
+}
+
+func listPaused(c *client, w io.Writer) error {
+	var paused []*types.PausedResource
+	err := c.do("GET", "/v1/paused", nil, nil, &paused)
+	if err != nil {
+		return err
+	}
+
+	return render(w, paused, []string{"WORKLOAD", "USER", "SINCE"}, func(row func(...interface{})) {
+		for _, p := range paused {
+			row(p.Identifier, p.User, p.CreatedAt.Format(time.RFC3339))
+		}
+	})
+}
+
+func pause(c *client, w io.Writer, identifier string) error {
+	var paused types.PausedResource
+	err := c.do("POST", "/v1/paused", nil, map[string]string{"identifier": identifier}, &paused)
+	if err != nil {
+		return err
+	}
+
+	return render(w, &paused, nil, func(row func(...interface{})) {
+		row(fmt.Sprintf("automatic updates of %s paused", paused.Identifier))
+	})
+}
+
+func resume(c *client, w io.Writer, identifier string) error {
+	parts := strings.Split(identifier, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("invalid workload '%s', expected format: <namespace>/<name>", identifier)
+	}
+
+	var status map[string]interface{}
+	err := c.do("DELETE", "/v1/paused/"+url.PathEscape(parts[0])+"/"+url.PathEscape(parts[1]), nil, nil, &status)
+	if err != nil {
+		return err
+	}
+
+	return render(w, status, nil, func(row func(...interface{})) {
+		row(fmt.Sprintf("automatic updates of %s resumed", identifier))
+	})
+}
+
+func deploy(c *client, w io.Writer, identifier, tag, image string) error {
+	var dr deployResponse
+	err := c.do("POST", "/v1/deploy", nil, map[string]string{
+		"identifier": identifier,
+		"tag":        tag,
+		"image":      image,
+	}, &dr)
+	if err != nil {
+		return err
+	}
+
+	return render(w, &dr, nil, func(row func(...interface{})) {
+		row(fmt.Sprintf("deploying %s: %s %s -> %s (approvals still apply)", dr.Identifier, dr.Image, dr.Previous, dr.Tag))
+	})
+}
+
+func listQueue(c *client, w io.Writer) error {
+	var status rateLimitStatus
+	err := c.do("GET", "/v1/ratelimit", nil, nil, &status)
+	if err != nil {
+		return err
+	}
+
+	if *output == outputTable && !status.Enabled {
+		fmt.Fprintln(w, "rate limiting of automatic updates is not enabled")
+		return nil
+	}
+
+	return render(w, &status, []string{"IDENTIFIER", "NAMESPACE", "CURRENT", "NEW", "PROVIDER", "QUEUED"}, func(row func(...interface{})) {
+		for _, u := range status.Queued {
+			row(u.Identifier, u.Namespace, u.CurrentVersion, u.NewVersion, u.Provider, u.QueuedAt.Format(time.RFC3339))
+		}
+	})
+}
+
+func streamEvents(c *client, w io.Writer, filter string, since time.Duration) error {
+	query := url.Values{}
+	if filter != "" {
+		query.Set("filter", filter)
+	}
+	if since > 0 {
+		query.Set("since", time.Now().Add(-since).Format(time.RFC3339))
+	}
+
+	return c.stream("/v1/events", query, func(event, data string) error {
+		if *output == outputJSON {
+			_, err := fmt.Fprintln(w, data)
+			return err
+		}
+
+		var entry types.AuditLog
+		err := json.Unmarshal([]byte(data), &entry)
+		if err != nil {
+			return err
+		}
+		user := entry.Username
+		if user == "" {
+			user = "-"
+		}
+		_, err = fmt.Fprintf(w, "%s  %-16s %-10s %s  %s %s\n", entry.CreatedAt.Format(time.RFC3339), entry.ResourceKind, entry.Action, entry.Identifier, user, entry.Message)
+		return err
+	})
+}
+
+// render - writes v as JSON or rows of a table, tables without header are
+// plain messages
+func render(w io.Writer, v interface{}, header []string, rows func(row func(...interface{}))) error {
+	if *output == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if len(header) > 0 {
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+	}
+	rows(func(values ...interface{}) {
+		cells := make([]string, len(values))
+		for i, v := range values {
+			cells[i] = fmt.Sprint(v)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	})
+	return tw.Flush()
+}
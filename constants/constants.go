@@ -9,6 +9,34 @@ const DefaultNamespace = "kube-system"
 // WebhookEndpointEnv if set - enables webhook notifications
 const WebhookEndpointEnv = "WEBHOOK_ENDPOINT"
 
+// EnvWebhookConfig - optional path to templated webhook endpoints file, request
+// headers and bodies are Go templates rendered with the event
+const EnvWebhookConfig = "WEBHOOK_CONFIG"
+
+// EnvWebhookRouteHosts - optional comma separated hosts that workloads can route
+// webhook notifications to with keel.sh/notify (ie: webhook:https://hooks.example.com/x),
+// enables webhook sender on its own. When not set, any host is allowed.
+const EnvWebhookRouteHosts = "WEBHOOK_ROUTE_HOSTS"
+
+// EnvMessageTemplates - optional path to message templates file (usually mounted
+// from a ConfigMap) that customizes approval and notification messages
+const EnvMessageTemplates = "MESSAGE_TEMPLATES"
+
+// EnvBotLocale - locale of the bot replies and approval messages, ie: "de"
+const EnvBotLocale = "BOT_LOCALE"
+
+// EnvBotCommandAliases - optional comma separated command aliases of all bots,
+// ie: ship=approve,lgtm=approve,deploys=get deployments
+const EnvBotCommandAliases = "BOT_COMMAND_ALIASES"
+
+// EnvBotPrefixes - optional comma separated prefixes bots respond to in addition
+// to their name and mentions, ie: !keel,kb
+const EnvBotPrefixes = "BOT_PREFIXES"
+
+// EnvBotDisconnectThreshold - how long (ie: 5m) a bot can be disconnected from
+// the chat service before keel sends a notification and fails health checks
+const EnvBotDisconnectThreshold = "BOT_DISCONNECT_THRESHOLD"
+
 // slack bot/token
 const (
 	EnvSlackToken            = "SLACK_TOKEN"
@@ -16,6 +44,11 @@ const (
 	EnvSlackChannels         = "SLACK_CHANNELS"
 	EnvSlackApprovalsChannel = "SLACK_APPROVALS_CHANNEL"
 
+	// Comma separated list of additional Slack workspaces, each workspace is
+	// configured with SLACK_<NAME>_TOKEN, SLACK_<NAME>_BOT_NAME,
+	// SLACK_<NAME>_APPROVALS_CHANNEL and SLACK_<NAME>_NAMESPACES
+	EnvSlackWorkspaces = "SLACK_WORKSPACES"
+
 	EnvHipchatToken    = "HIPCHAT_TOKEN"
 	EnvHipchatBotName  = "HIPCHAT_BOT_NAME"
 	EnvHipchatChannels = "HIPCHAT_CHANNELS"
@@ -30,15 +63,202 @@ const (
 	// for documentation on setting it up
 	EnvMattermostEndpoint = "MATTERMOST_ENDPOINT"
 	EnvMattermostName     = "MATTERMOST_USERNAME"
+
+	// Microsoft Teams incoming webhook URL, notifications are posted as Adaptive Cards
+	EnvTeamsWebhookURL = "TEAMS_WEBHOOK_URL"
+
+	// Generic chatops bot, approval requests and responses are POSTed to
+	// the webhook URL, approve/reject callbacks are accepted on the chatops port.
+	// Both are signed with the secret over X-Keel-Timestamp and the body,
+	// callbacks signed more than 5 minutes ago are rejected
+	EnvChatopsWebhookURL = "CHATOPS_WEBHOOK_URL"
+	EnvChatopsSecret     = "CHATOPS_SECRET"
+	EnvChatopsPort       = "CHATOPS_PORT"
+	EnvChatopsChannel    = "CHATOPS_CHANNEL"
+)
+
+// EnvECRAssumeRoles - roles assumed to get tokens of ECR registries in other
+// accounts, ie: 123456789012=arn:aws:iam::123456789012:role/keel-ecr,...
+const EnvECRAssumeRoles = "AWS_ECR_ASSUME_ROLES"
+
+// Azure ACR credentials, workload identity federation is used when the token
+// file is set (injected by the workload identity webhook), otherwise managed
+// identity is used when enabled. Client ID selects user-assigned identities.
+const (
+	EnvAzureClientID           = "AZURE_CLIENT_ID"
+	EnvAzureTenantID           = "AZURE_TENANT_ID"
+	EnvAzureFederatedTokenFile = "AZURE_FEDERATED_TOKEN_FILE"
+	EnvAzureAuthorityHost      = "AZURE_AUTHORITY_HOST"
+	EnvACRManagedIdentity      = "ACR_MANAGED_IDENTITY"
+)
+
+// AWS notifications, prepared, submitted, approved and failed update events are
+// published to the SNS topic and/or sent to the SQS queue
+const (
+	EnvSNSTopicARN = "NOTIFICATION_SNS_TOPIC_ARN"
+	EnvSQSQueueURL = "NOTIFICATION_SQS_QUEUE_URL"
+)
+
+// Datadog notifications, update and approval events are posted to the Events API
+// when the API key is set, DogStatsD counters are emitted for all events when the
+// agent address (ie: localhost:8125) is set
+const (
+	EnvDatadogAPIKey     = "DATADOG_API_KEY"
+	EnvDatadogSite       = "DATADOG_SITE"
+	EnvDatadogStatsdAddr = "DATADOG_STATSD_ADDR"
+	EnvDatadogTags       = "DATADOG_TAGS"
+)
+
+// Grafana annotations of successful updates, the API key is a service account
+// token. Annotations without dashboard UID are organization wide.
+const (
+	EnvGrafanaURL          = "GRAFANA_URL"
+	EnvGrafanaAPIKey       = "GRAFANA_API_KEY"
+	EnvGrafanaOrgID        = "GRAFANA_ORG_ID"
+	EnvGrafanaDashboardUID = "GRAFANA_DASHBOARD_UID"
+	EnvGrafanaPanelID      = "GRAFANA_PANEL_ID"
+	EnvGrafanaTags         = "GRAFANA_TAGS"
+)
+
+// GitHub deployments of resources with keel.sh/githubRepository annotation, API
+// URL defaults to https://api.github.com (set it for GitHub Enterprise)
+const (
+	EnvGithubToken  = "GITHUB_TOKEN"
+	EnvGithubAPIURL = "GITHUB_API_URL"
+)
+
+// Jira issues of successful updates, the API token is used with the username
+// (Jira Cloud) or as a personal access token (Jira Server). Summary and description
+// are Go templates rendered with the event, JIRA_NAMESPACES limits issues to the
+// namespaces (ie: production).
+const (
+	EnvJiraURL                 = "JIRA_URL"
+	EnvJiraUsername            = "JIRA_USERNAME"
+	EnvJiraAPIToken            = "JIRA_API_TOKEN"
+	EnvJiraProject             = "JIRA_PROJECT"
+	EnvJiraIssueType           = "JIRA_ISSUE_TYPE"
+	EnvJiraLabels              = "JIRA_LABELS"
+	EnvJiraNamespaces          = "JIRA_NAMESPACES"
+	EnvJiraSummaryTemplate     = "JIRA_SUMMARY_TEMPLATE"
+	EnvJiraDescriptionTemplate = "JIRA_DESCRIPTION_TEMPLATE"
+)
+
+// ServiceNow change requests of approvals, the required mode needs the change
+// request approval in addition to chat votes, the sufficient mode approves updates
+// once the change request is approved
+const (
+	EnvServiceNowURL             = "SERVICENOW_URL"
+	EnvServiceNowUsername        = "SERVICENOW_USERNAME"
+	EnvServiceNowPassword        = "SERVICENOW_PASSWORD"
+	EnvServiceNowChangeType      = "SERVICENOW_CHANGE_TYPE"
+	EnvServiceNowAssignmentGroup = "SERVICENOW_ASSIGNMENT_GROUP"
+	EnvServiceNowApprovalMode    = "SERVICENOW_APPROVAL_MODE"
+	EnvServiceNowPollInterval    = "SERVICENOW_POLL_INTERVAL"
+)
+
+// Kafka notifications, events are produced as JSON records to the topic, keyed by
+// namespace/name of the resource unless KAFKA_KEY_TEMPLATE is set. SASL uses the
+// PLAIN mechanism.
+const (
+	EnvKafkaBrokers      = "KAFKA_BROKERS"
+	EnvKafkaTopic        = "KAFKA_TOPIC"
+	EnvKafkaKeyTemplate  = "KAFKA_KEY_TEMPLATE"
+	EnvKafkaTLS          = "KAFKA_TLS"
+	EnvKafkaTLSCA        = "KAFKA_TLS_CA"
+	EnvKafkaTLSCert      = "KAFKA_TLS_CERT"
+	EnvKafkaTLSKey       = "KAFKA_TLS_KEY"
+	EnvKafkaSASLUsername = "KAFKA_SASL_USERNAME"
+	EnvKafkaSASLPassword = "KAFKA_SASL_PASSWORD"
+)
+
+// Email notifications, enabled when SMTP host is set. SMTP_TO recipients get all
+// notifications, SMTP_TO_<LEVEL> (ie: SMTP_TO_ERROR) recipients only notifications
+// of the level. TLS is starttls (default), tls or none, SMTP_TEMPLATE is an optional
+// path to the HTML body template.
+const (
+	EnvSMTPHost     = "SMTP_HOST"
+	EnvSMTPPort     = "SMTP_PORT"
+	EnvSMTPUsername = "SMTP_USERNAME"
+	EnvSMTPPassword = "SMTP_PASSWORD"
+	EnvSMTPTLS      = "SMTP_TLS"
+	EnvSMTPFrom     = "SMTP_FROM"
+	EnvSMTPTo       = "SMTP_TO"
+	EnvSMTPTemplate = "SMTP_TEMPLATE"
+)
+
+// PagerDuty alerts of failed updates and rollouts (Events API v2), enabled when the
+// routing key is set. PAGERDUTY_LEVEL is the minimum level of alerts (defaults to
+// error), set PAGERDUTY_RESOLVE to true to resolve alerts once the resource is
+// updated successfully.
+const (
+	EnvPagerDutyRoutingKey = "PAGERDUTY_ROUTING_KEY"
+	EnvPagerDutyEndpoint   = "PAGERDUTY_ENDPOINT"
+	EnvPagerDutyLevel      = "PAGERDUTY_LEVEL"
+	EnvPagerDutyResolve    = "PAGERDUTY_RESOLVE"
 )
 
 // EnvNotificationLevel - minimum level for notifications, defaults to info
 const EnvNotificationLevel = "NOTIFICATION_LEVEL"
 
+// EnvNotificationFilters - optional path to per sender and per endpoint level
+// and event type filters file
+const EnvNotificationFilters = "NOTIFICATION_FILTERS"
+
+// Digest mode, notifications below warn level are batched and sent as a single
+// digest per channel every interval (ie: 10m). Senders default to chat senders.
+const (
+	EnvNotificationDigestInterval = "NOTIFICATION_DIGEST_INTERVAL"
+	EnvNotificationDigestSenders  = "NOTIFICATION_DIGEST_SENDERS"
+)
+
 // Basic Auth - User / Password
 const EnvBasicAuthUser = "BASIC_AUTH_USER"
 const EnvBasicAuthPassword = "BASIC_AUTH_PASSWORD"
 const EnvAuthenticatedWebhooks = "AUTHENTICATED_WEBHOOKS"
+
+// EnvAPITokens - optional path to static API tokens file, tokens can be
+// restricted to namespaces, see auth.LoadAPITokens. Tokens can list, approve
+// and reject approvals.
+const EnvAPITokens = "API_TOKENS"
+
+// EnvGRPCPort - optional port of the gRPC API (approvals, tracked images and
+// events stream), calls are authenticated like REST API requests
+const EnvGRPCPort = "GRPC_PORT"
+
+// OIDC - OpenID Connect sign in, ID tokens of the provider are accepted as
+// bearer tokens and UI users can sign in with the authorization code flow
+const (
+	EnvOIDCIssuerURL    = "OIDC_ISSUER_URL"
+	EnvOIDCClientID     = "OIDC_CLIENT_ID"
+	EnvOIDCClientSecret = "OIDC_CLIENT_SECRET"
+	// EnvOIDCRedirectURL - callback URL, ie: https://keel.example.com/v1/auth/oidc/callback
+	EnvOIDCRedirectURL = "OIDC_REDIRECT_URL"
+	// EnvOIDCUsernameClaim - defaults to email
+	EnvOIDCUsernameClaim = "OIDC_USERNAME_CLAIM"
+	// EnvOIDCGroupsClaim - defaults to groups
+	EnvOIDCGroupsClaim = "OIDC_GROUPS_CLAIM"
+	// EnvOIDCRoles - keel roles of the groups, ie: admin=keel-admins,approver=sre|release,viewer=*
+	EnvOIDCRoles = "OIDC_ROLES"
+	// EnvOIDCUIRedirect - where UI users are sent after signing in, defaults to /
+	EnvOIDCUIRedirect = "OIDC_UI_REDIRECT"
+)
+
+// EnvGithubWebhookSecret - GitHub webhook secret, used to validate
+// package webhook signatures (X-Hub-Signature-256)
+const EnvGithubWebhookSecret = "GITHUB_WEBHOOK_SECRET"
+
+// Native webhook HMAC signature verification, enabled when secret is set
+const EnvNativeWebhookSecret = "NATIVE_WEBHOOK_SECRET"
+const EnvNativeWebhookSignatureHeader = "NATIVE_WEBHOOK_SIGNATURE_HEADER"
+const EnvNativeWebhookSignatureAlgorithm = "NATIVE_WEBHOOK_SIGNATURE_ALGORITHM"
+
+// EnvCustomWebhooks - optional path to custom webhook payload mappings file
+// (usually mounted ConfigMap), see http.CustomWebhookMapping
+const EnvCustomWebhooks = "CUSTOM_WEBHOOKS"
+
+// EnvWebhookHistorySize - how many received webhooks are kept for the admin
+// API (list and replay), defaults to 50, 0 disables recording
+const EnvWebhookHistorySize = "WEBHOOK_HISTORY_SIZE"
 const EnvTokenSecret = "TOKEN_SECRET"
 
 // KeelLogoURL - is a logo URL for bot icon
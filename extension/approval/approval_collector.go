@@ -1,6 +1,7 @@
 package approval
 
 import (
+	"net/http"
 	"sync"
 
 	"github.com/keel-hq/keel/approvals"
@@ -18,6 +19,13 @@ type Collector interface {
 	Configure(approvalsManager approvals.Manager) (bool, error)
 }
 
+// WebhookCollector - collectors that receive callbacks on
+// /v1/webhooks/approvals/<collector name>
+type WebhookCollector interface {
+	Collector
+	ServeWebhook(resp http.ResponseWriter, req *http.Request)
+}
+
 func RegisterCollector(name string, c Collector) {
 	if name == "" {
 		panic("approval collector:: could not register a Sender with an empty name")
@@ -87,6 +95,15 @@ func (m *MainCollector) Collectors() map[string]Collector {
 	return ret
 }
 
+// Webhook returns configured collector that receives callbacks
+func (m *MainCollector) Webhook(name string) (WebhookCollector, bool) {
+	collectorsM.RLock()
+	defer collectorsM.RUnlock()
+
+	c, ok := collectors[name].(WebhookCollector)
+	return c, ok
+}
+
 // UnregisterCollector removes a Collector with a particular name from the list.
 func (m *MainCollector) UnregisterCollector(name string) {
 	collectorsM.Lock()
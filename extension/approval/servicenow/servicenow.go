@@ -0,0 +1,331 @@
+// Package servicenow opens a ServiceNow change request for every approval
+// request and approves or rejects the update once the change request is
+// approved or rejected. Change requests are correlated with approvals by the
+// correlation_id field, so there is no state to lose on restarts.
+package servicenow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/approval"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 10 * time.Second
+
+const defaultPollInterval = time.Minute
+
+// approval modes
+const (
+	// modeRequired - change request approval is needed in addition to the
+	// chat votes, approvals require one more vote which only ServiceNow gives
+	modeRequired = "required"
+	// modeSufficient - change request approval approves the update instead
+	// of chat votes
+	modeSufficient = "sufficient"
+)
+
+// change request approval values and the canceled state
+const (
+	approvalApproved = "approved"
+	approvalRejected = "rejected"
+	stateCanceled    = "4"
+)
+
+const correlationDisplay = "keel"
+
+type changeRequest struct {
+	SysID    string `json:"sys_id"`
+	Number   string `json:"number"`
+	Approval string `json:"approval"`
+	State    string `json:"state"`
+}
+
+type collector struct {
+	endpoint        string
+	username        string
+	password        string
+	changeType      string
+	assignmentGroup string
+	mode            string
+	interval        time.Duration
+	client          *http.Client
+
+	approvalsManager approvals.Manager
+
+	// mu serializes approval checks of the poller, subscription and callbacks
+	mu sync.Mutex
+}
+
+func init() {
+	approval.RegisterCollector("servicenow", &collector{})
+}
+
+func (c *collector) Configure(approvalsManager approvals.Manager) (bool, error) {
+	instance := os.Getenv(constants.EnvServiceNowURL)
+	if instance == "" {
+		return false, nil
+	}
+	if _, err := url.ParseRequestURI(instance); err != nil {
+		return false, fmt.Errorf("could not parse ServiceNow URL: %s", err)
+	}
+	c.endpoint = strings.TrimSuffix(instance, "/") + "/api/now/table/change_request"
+	c.username = os.Getenv(constants.EnvServiceNowUsername)
+	c.password = os.Getenv(constants.EnvServiceNowPassword)
+	c.changeType = os.Getenv(constants.EnvServiceNowChangeType)
+	if c.changeType == "" {
+		c.changeType = "normal"
+	}
+	c.assignmentGroup = os.Getenv(constants.EnvServiceNowAssignmentGroup)
+
+	c.mode = strings.ToLower(os.Getenv(constants.EnvServiceNowApprovalMode))
+	switch c.mode {
+	case "":
+		c.mode = modeRequired
+	case modeRequired, modeSufficient:
+	default:
+		return false, fmt.Errorf("invalid ServiceNow approval mode %q, expected required or sufficient", c.mode)
+	}
+
+	c.interval = defaultPollInterval
+	if v := os.Getenv(constants.EnvServiceNowPollInterval); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			return false, fmt.Errorf("invalid ServiceNow poll interval %q", v)
+		}
+		c.interval = interval
+	}
+
+	c.client = &http.Client{
+		Transport: http.DefaultTransport,
+		Timeout:   timeout,
+	}
+	c.approvalsManager = approvalsManager
+
+	go c.run(context.Background())
+
+	log.WithFields(log.Fields{
+		"url":      instance,
+		"mode":     c.mode,
+		"interval": c.interval,
+	}).Info("extension.approval.servicenow: collector configured")
+
+	return true, nil
+}
+
+// run - opens change requests for new approvals and polls pending ones
+func (c *collector) run(ctx context.Context) {
+	requests, err := c.approvalsManager.Subscribe(ctx)
+	if err != nil {
+		log.WithError(err).Error("extension.approval.servicenow: failed to subscribe for approvals")
+		return
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case a := <-requests:
+			if err := c.check(a.Identifier); err != nil {
+				log.WithFields(log.Fields{
+					"error":    err,
+					"approval": a.Identifier,
+				}).Error("extension.approval.servicenow: failed to open change request")
+			}
+		case <-ticker.C:
+			c.poll()
+		}
+	}
+}
+
+// poll - checks change requests of all pending approvals, approvals that were
+// requested while keel wasn't running get their change requests here
+func (c *collector) poll() {
+	pending, err := c.approvalsManager.List()
+	if err != nil {
+		log.WithError(err).Error("extension.approval.servicenow: failed to list approvals")
+		return
+	}
+	for _, a := range pending {
+		if a.Status() != types.ApprovalStatusPending {
+			continue
+		}
+		if err := c.check(a.Identifier); err != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"approval": a.Identifier,
+			}).Error("extension.approval.servicenow: failed to check change request")
+		}
+	}
+}
+
+// check - opens the change request of the approval or applies its decision
+func (c *collector) check(identifier string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	a, err := c.approvalsManager.Get(identifier)
+	if err != nil {
+		return err
+	}
+	if a.Status() != types.ApprovalStatusPending {
+		return nil
+	}
+
+	cr, err := c.find(identifier)
+	if err != nil {
+		return err
+	}
+	if cr == nil {
+		return c.open(a)
+	}
+
+	voter := "servicenow:" + cr.Number
+	switch {
+	case cr.Approval == approvalApproved:
+		if c.mode == modeSufficient {
+			a.AddVoter(voter)
+			a.VotesReceived = a.VotesRequired
+			return c.approvalsManager.Update(a)
+		}
+		_, err = c.approvalsManager.Approve(identifier, voter)
+		return err
+	case cr.Approval == approvalRejected || cr.State == stateCanceled:
+		log.WithFields(log.Fields{
+			"approval":       identifier,
+			"change_request": cr.Number,
+		}).Info("extension.approval.servicenow: change request rejected, rejecting update")
+		_, err = c.approvalsManager.Reject(identifier, voter)
+		return err
+	}
+	return nil
+}
+
+func (c *collector) open(a *types.Approval) error {
+	fields := map[string]string{
+		"type":                c.changeType,
+		"short_description":   fmt.Sprintf("Update %s %s", a.Identifier, a.Delta()),
+		"description":         a.Message,
+		"correlation_id":      a.Identifier,
+		"correlation_display": correlationDisplay,
+		"end_date":            a.Deadline.UTC().Format("2006-01-02 15:04:05"),
+	}
+	if c.assignmentGroup != "" {
+		fields["assignment_group"] = c.assignmentGroup
+	}
+
+	var cr changeRequest
+	if err := c.do(http.MethodPost, c.endpoint, fields, &cr); err != nil {
+		return fmt.Errorf("failed to create change request: %s", err)
+	}
+
+	// ServiceNow has to be asked as well, chat votes alone can't approve
+	if c.mode == modeRequired {
+		a.VotesRequired++
+		if err := c.approvalsManager.Update(a); err != nil {
+			return err
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"approval":       a.Identifier,
+		"change_request": cr.Number,
+	}).Info("extension.approval.servicenow: change request created")
+	return nil
+}
+
+// find - latest change request of the approval, nil if there isn't one
+func (c *collector) find(identifier string) (*changeRequest, error) {
+	q := url.Values{}
+	q.Set("sysparm_query", fmt.Sprintf("correlation_display=%s^correlation_id=%s^ORDERBYDESCsys_created_on", correlationDisplay, identifier))
+	q.Set("sysparm_fields", "sys_id,number,approval,state")
+	q.Set("sysparm_limit", "1")
+
+	var found []changeRequest
+	if err := c.do(http.MethodGet, c.endpoint+"?"+q.Encode(), nil, &found); err != nil {
+		return nil, fmt.Errorf("failed to query change requests: %s", err)
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+	return &found[0], nil
+}
+
+// do - calls the Table API, results are unwrapped into result
+func (c *collector) do(method, endpoint string, body, result interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.SetBasicAuth(c.username, c.password)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("got status %d", resp.StatusCode)
+	}
+
+	envelope := struct {
+		Result interface{} `json:"result"`
+	}{Result: result}
+	return json.NewDecoder(resp.Body).Decode(&envelope)
+}
+
+// ServeWebhook - change request callbacks (ie: from a business rule) with
+// {"correlation_id": "<approval identifier>"} body, the change request is
+// checked with ServiceNow so the callback doesn't have to be trusted
+func (c *collector) ServeWebhook(resp http.ResponseWriter, req *http.Request) {
+	var callback struct {
+		CorrelationID string `json:"correlation_id"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&callback); err != nil || callback.CorrelationID == "" {
+		http.Error(resp, "correlation_id is required", http.StatusBadRequest)
+		return
+	}
+	err := c.check(callback.CorrelationID)
+	if err == store.ErrRecordNotFound {
+		http.Error(resp, fmt.Sprintf("approval '%s' not found", callback.CorrelationID), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"approval": callback.CorrelationID,
+		}).Error("extension.approval.servicenow: failed to process callback")
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp.WriteHeader(http.StatusOK)
+}
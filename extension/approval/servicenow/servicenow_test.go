@@ -0,0 +1,207 @@
+package servicenow
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/pkg/store/sql"
+	"github.com/keel-hq/keel/types"
+)
+
+// fakeServiceNow - Table API with change requests by correlation id
+type fakeServiceNow struct {
+	mu      sync.Mutex
+	changes map[string]*changeRequest
+	created []map[string]string
+}
+
+func (f *fakeServiceNow) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if user, _, _ := req.BasicAuth(); user != "keel" {
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var result interface{}
+	switch req.Method {
+	case http.MethodPost:
+		var fields map[string]string
+		json.NewDecoder(req.Body).Decode(&fields)
+		f.created = append(f.created, fields)
+		cr := &changeRequest{SysID: "1", Number: "CHG0000001", Approval: "requested", State: "-5"}
+		f.changes[fields["correlation_id"]] = cr
+		result = cr
+		resp.WriteHeader(http.StatusCreated)
+	case http.MethodGet:
+		found := []*changeRequest{}
+		for id, cr := range f.changes {
+			if strings.Contains(req.URL.Query().Get("sysparm_query"), "correlation_id="+id+"^") {
+				found = append(found, cr)
+			}
+		}
+		result = found
+	}
+	json.NewEncoder(resp).Encode(map[string]interface{}{"result": result})
+}
+
+func (f *fakeServiceNow) set(identifier, approval, state string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.changes[identifier].Approval = approval
+	f.changes[identifier].State = state
+}
+
+func newTestCollector(t *testing.T, mode string) (*collector, *fakeServiceNow, func()) {
+	dir, err := ioutil.TempDir("", "servicenowtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := sql.New(sql.Opts{DatabaseType: "sqlite3", URI: filepath.Join(dir, "gorm.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeServiceNow{changes: make(map[string]*changeRequest)}
+	ts := httptest.NewServer(fake)
+
+	c := &collector{
+		endpoint:         ts.URL + "/api/now/table/change_request",
+		username:         "keel",
+		password:         "secret",
+		changeType:       "normal",
+		mode:             mode,
+		client:           &http.Client{},
+		approvalsManager: approvals.New(&approvals.Opts{Store: store}),
+	}
+
+	err = c.approvalsManager.Create(&types.Approval{
+		Provider:       types.ProviderTypeKubernetes,
+		Identifier:     "deployment/production/wd:1.2.0",
+		CurrentVersion: "1.1.0",
+		NewVersion:     "1.2.0",
+		VotesRequired:  1,
+		Message:        "New image is available for resource production/wd",
+		Deadline:       time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c, fake, func() {
+		ts.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+const identifier = "deployment/production/wd:1.2.0"
+
+func status(t *testing.T, c *collector) *types.Approval {
+	a, err := c.approvalsManager.Get(identifier)
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+	return a
+}
+
+func TestRequiredMode(t *testing.T) {
+	c, fake, teardown := newTestCollector(t, modeRequired)
+	defer teardown()
+
+	if err := c.check(identifier); err != nil {
+		t.Fatalf("failed to check: %s", err)
+	}
+	if len(fake.created) != 1 {
+		t.Fatalf("expected change request, got: %v", fake.created)
+	}
+	if fake.created[0]["short_description"] != "Update deployment/production/wd:1.2.0 1.1.0 -> 1.2.0" {
+		t.Errorf("unexpected short description: %s", fake.created[0]["short_description"])
+	}
+	if a := status(t, c); a.VotesRequired != 2 {
+		t.Errorf("expected ServiceNow vote to be required, got %d required votes", a.VotesRequired)
+	}
+
+	// chat vote isn't enough
+	c.approvalsManager.Approve(identifier, "alice")
+	if err := c.check(identifier); err != nil {
+		t.Fatalf("failed to check: %s", err)
+	}
+	if a := status(t, c); a.Status() != types.ApprovalStatusPending {
+		t.Errorf("expected pending approval, got %s", a.Status())
+	}
+	if len(fake.created) != 1 {
+		t.Errorf("expected change request to be reused, got: %v", fake.created)
+	}
+
+	fake.set(identifier, approvalApproved, "-1")
+	if err := c.check(identifier); err != nil {
+		t.Fatalf("failed to check: %s", err)
+	}
+	if a := status(t, c); a.Status() != types.ApprovalStatusApproved {
+		t.Errorf("expected approved approval, got %s (%d/%d)", a.Status(), a.VotesReceived, a.VotesRequired)
+	}
+}
+
+func TestSufficientMode(t *testing.T) {
+	c, fake, teardown := newTestCollector(t, modeSufficient)
+	defer teardown()
+
+	if err := c.check(identifier); err != nil {
+		t.Fatalf("failed to check: %s", err)
+	}
+	if a := status(t, c); a.VotesRequired != 1 {
+		t.Errorf("expected required votes to be unchanged, got %d", a.VotesRequired)
+	}
+
+	fake.set(identifier, approvalApproved, "-1")
+	if err := c.check(identifier); err != nil {
+		t.Fatalf("failed to check: %s", err)
+	}
+	a := status(t, c)
+	if a.Status() != types.ApprovalStatusApproved {
+		t.Errorf("expected approved approval, got %s", a.Status())
+	}
+	if voters := a.GetVoters(); len(voters) != 1 || voters[0] != "servicenow:CHG0000001" {
+		t.Errorf("unexpected voters: %v", voters)
+	}
+}
+
+func TestCallbackRejected(t *testing.T) {
+	c, fake, teardown := newTestCollector(t, modeRequired)
+	defer teardown()
+
+	if err := c.check(identifier); err != nil {
+		t.Fatalf("failed to check: %s", err)
+	}
+	fake.set(identifier, "requested", stateCanceled)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/v1/webhooks/approvals/servicenow", bytes.NewBufferString(`{"correlation_id": "`+identifier+`"}`))
+	c.ServeWebhook(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d (%s)", rec.Code, rec.Body.String())
+	}
+	if a := status(t, c); a.Status() != types.ApprovalStatusRejected {
+		t.Errorf("expected rejected approval, got %s", a.Status())
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/v1/webhooks/approvals/servicenow", bytes.NewBufferString(`{"correlation_id": "deployment/default/missing:1.0.0"}`))
+	c.ServeWebhook(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected not found, got %d", rec.Code)
+	}
+}
@@ -4,18 +4,22 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/url"
+	"os"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
-	// "github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/service/ecr"
 
+	"github.com/keel-hq/keel/constants"
 	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/extension/notification"
 	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/awsauth"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -25,22 +29,49 @@ import (
 // more info here: https://docs.aws.amazon.com/AmazonECR/latest/userguide/service_limits.html
 const AWSCredentialsExpiry = 2 * time.Hour
 
-var registryRegxp *regexp.Regexp
+var registryRegxp = regexp.MustCompile(`(?P<registryID>\d+)\.dkr\.ecr\.(?P<region>\S+)\.amazonaws\.com`)
+
+// DefaultHelper - registered helper
+var DefaultHelper = New()
 
 func init() {
-	credentialshelper.RegisterCredentialsHelper("aws", New())
-	registryRegxp = regexp.MustCompile(`(?P<registryID>\d+)\.dkr\.ecr\.(?P<region>\S+)\.amazonaws\.com`)
+	credentialshelper.RegisterCredentialsHelper("aws", DefaultHelper)
 }
 
+// tokenExpiryMargin - tokens are renewed before they expire so in-flight
+// registry requests don't fail
+const tokenExpiryMargin = 5 * time.Minute
+
 // CredentialsHelper provides authorization to ECR.
 // Authentication details: https://docs.aws.amazon.com/sdk-for-go/api/aws/session/
 // # Access Key ID
 // AWS_ACCESS_KEY_ID=AKID
 // AWS_ACCESS_KEY=AKID # only read if AWS_ACCESS_KEY_ID is not set.
 // more on auth: https://stackoverflow.com/questions/41544554/how-to-run-aws-sdk-with-credentials-from-variables
+// IAM roles for service accounts (IRSA) are used when AWS_ROLE_ARN and
+// AWS_WEB_IDENTITY_TOKEN_FILE are set, registries of other accounts can use
+// roles from AWS_ECR_ASSUME_ROLES (<registry ID>=<role ARN>,...).
 type CredentialsHelper struct {
 	enabled bool
 	cache   *Cache
+
+	// roles - role ARNs assumed for registries of other accounts
+	roles map[string]string
+
+	mu      sync.Mutex
+	clients map[string]tokenGetter
+	// failing - registries which token refresh failed, notifications are
+	// only sent once until the refresh succeeds again
+	failing map[string]bool
+
+	sender notification.Sender
+
+	// newClient - creates ECR client, replaced in tests
+	newClient func(region, roleARN string) (tokenGetter, error)
+}
+
+type tokenGetter interface {
+	GetAuthorizationToken(input *ecr.GetAuthorizationTokenInput) (*ecr.GetAuthorizationTokenOutput, error)
 }
 
 // New creates a new instance of aws credentials helper
@@ -48,9 +79,73 @@ func New() *CredentialsHelper {
 	ch := &CredentialsHelper{}
 	ch.enabled = true
 	ch.cache = NewCache(AWSCredentialsExpiry)
+	ch.clients = make(map[string]tokenGetter)
+	ch.failing = make(map[string]bool)
+	ch.newClient = newECRClient
+
+	roles, err := parseRoles(os.Getenv(constants.EnvECRAssumeRoles))
+	if err != nil {
+		log.WithError(err).Error("credentialshelper.aws: invalid ECR roles, registries of other accounts use default credentials")
+	}
+	ch.roles = roles
 	return ch
 }
 
+// SetSender - sets sender that is notified about token refresh failures
+func (h *CredentialsHelper) SetSender(sender notification.Sender) {
+	h.mu.Lock()
+	h.sender = sender
+	h.mu.Unlock()
+}
+
+// parseRoles - parses <registry ID>=<role ARN> pairs separated by commas
+func parseRoles(value string) (map[string]string, error) {
+	roles := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || !strings.HasPrefix(strings.TrimSpace(parts[1]), "arn:") {
+			return nil, fmt.Errorf("invalid role %q, expected <registry ID>=<role ARN>", pair)
+		}
+		roles[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return roles, nil
+}
+
+func newECRClient(region, roleARN string) (tokenGetter, error) {
+	sess, err := awsauth.NewSession(region)
+	if err != nil {
+		return nil, err
+	}
+	if roleARN == "" {
+		return ecr.New(sess), nil
+	}
+	creds := stscreds.NewCredentials(sess, roleARN, func(p *stscreds.AssumeRoleProvider) {
+		p.RoleSessionName = "keel-ecr"
+	})
+	return ecr.New(sess, &aws.Config{Credentials: creds}), nil
+}
+
+// client - ECR client of the region and role, clients are reused so
+// assumed role credentials are cached by the SDK
+func (h *CredentialsHelper) client(region, roleARN string) (tokenGetter, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := region + "/" + roleARN
+	if c, ok := h.clients[key]; ok {
+		return c, nil
+	}
+	c, err := h.newClient(region, roleARN)
+	if err != nil {
+		return nil, err
+	}
+	h.clients[key] = c
+	return c, nil
+}
+
 // IsEnabled returns a bool whether this credentials helper is initialised or not
 func (h *CredentialsHelper) IsEnabled() bool {
 	return h.enabled
@@ -65,7 +160,7 @@ func (h *CredentialsHelper) GetCredentials(image *types.TrackedImage) (*types.Cr
 
 	registry := image.Image.Registry()
 
-	_, region, err := parseRegistry(registry)
+	registryID, region, err := parseRegistry(registry)
 	if err != nil {
 		return nil, err
 	}
@@ -74,29 +169,39 @@ func (h *CredentialsHelper) GetCredentials(image *types.TrackedImage) (*types.Cr
 	if err == nil {
 		return cached, nil
 	}
-	// fetch region from registry instead of env
-	svc := ecr.New(session.New(), &aws.Config{
-		Region: aws.String(region),
-	})
 
-	input := &ecr.GetAuthorizationTokenInput{}
+	creds, err := h.fetch(registry, registryID, region)
+	if err != nil {
+		h.failed(registry, err)
+		return nil, err
+	}
+	h.succeeded(registry)
+	return creds, nil
+}
 
-	result, err := svc.GetAuthorizationToken(input)
+func (h *CredentialsHelper) fetch(registry, registryID, region string) (*types.Credentials, error) {
+	roleARN := h.roles[registryID]
+	svc, err := h.client(region, roleARN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ECR client: %s", err)
+	}
+
+	result, err := svc.GetAuthorizationToken(&ecr.GetAuthorizationTokenInput{
+		RegistryIds: []*string{aws.String(registryID)},
+	})
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case ecr.ErrCodeServerException:
-				fmt.Println(ecr.ErrCodeServerException, aerr.Error())
-			case ecr.ErrCodeInvalidParameterException:
-				fmt.Println(ecr.ErrCodeInvalidParameterException, aerr.Error())
-			default:
-				fmt.Println(aerr.Error())
-			}
+			log.WithFields(log.Fields{
+				"error":    aerr.Message(),
+				"code":     aerr.Code(),
+				"registry": registry,
+				"role":     roleARN,
+			}).Error("credentialshelper.aws: failed to get authorization token")
 		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
 			log.WithFields(log.Fields{
-				"error": err,
+				"error":    err,
+				"registry": registry,
+				"role":     roleARN,
 			}).Error("credentialshelper.aws: failed to get authorization token")
 		}
 		return nil, err
@@ -104,21 +209,20 @@ func (h *CredentialsHelper) GetCredentials(image *types.TrackedImage) (*types.Cr
 
 	for _, ad := range result.AuthorizationData {
 
-		u, err := url.Parse(*ad.ProxyEndpoint)
+		u, err := url.Parse(aws.StringValue(ad.ProxyEndpoint))
 		if err != nil {
-			log.WithError(err).Errorf("credentialshelper.aws: failed to parse registry endpoint: %s", *ad.ProxyEndpoint)
+			log.WithError(err).Errorf("credentialshelper.aws: failed to parse registry endpoint: %s", aws.StringValue(ad.ProxyEndpoint))
 			continue
 		}
 
 		log.WithFields(log.Fields{
 			"current_registry": u.Host,
-			"token":            *ad.AuthorizationToken,
 			"registry":         registry,
 		}).Debug("checking registry")
 		if u.Host == registry {
-			username, password, err := decodeBase64Secret(*ad.AuthorizationToken)
+			username, password, err := decodeBase64Secret(aws.StringValue(ad.AuthorizationToken))
 			if err != nil {
-				return nil, fmt.Errorf("failed to decode authentication token: %s, error: %s", *ad.AuthorizationToken, err)
+				return nil, fmt.Errorf("failed to decode authentication token of %s: %s", registry, err)
 			}
 
 			creds := &types.Credentials{
@@ -126,7 +230,12 @@ func (h *CredentialsHelper) GetCredentials(image *types.TrackedImage) (*types.Cr
 				Password: password,
 			}
 
-			h.cache.Put(registry, creds)
+			// tokens are valid for 12 hours
+			if ad.ExpiresAt != nil {
+				h.cache.PutUntil(registry, creds, ad.ExpiresAt.Add(-tokenExpiryMargin))
+			} else {
+				h.cache.Put(registry, creds)
+			}
 
 			return creds, nil
 		}
@@ -135,6 +244,35 @@ func (h *CredentialsHelper) GetCredentials(image *types.TrackedImage) (*types.Cr
 	return nil, fmt.Errorf("not found")
 }
 
+func (h *CredentialsHelper) failed(registry string, err error) {
+	h.mu.Lock()
+	notify := !h.failing[registry] && h.sender != nil
+	h.failing[registry] = true
+	sender := h.sender
+	h.mu.Unlock()
+
+	if !notify {
+		return
+	}
+	sender.Send(types.EventNotification{
+		Name:      "credentials refresh failed",
+		Message:   fmt.Sprintf("Failed to refresh ECR credentials of %s: %s", registry, err),
+		CreatedAt: time.Now(),
+		Type:      types.NotificationSystemEvent,
+		Level:     types.LevelError,
+		Metadata: map[string]string{
+			"registry": registry,
+			"helper":   "aws",
+		},
+	})
+}
+
+func (h *CredentialsHelper) succeeded(registry string) {
+	h.mu.Lock()
+	delete(h.failing, registry)
+	h.mu.Unlock()
+}
+
 func decodeBase64Secret(authSecret string) (username, password string, err error) {
 	decoded, err := base64.StdEncoding.DecodeString(authSecret)
 	if err != nil {
@@ -1,9 +1,16 @@
 package aws
 
 import (
+	"encoding/base64"
+	"errors"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecr"
+
+	"github.com/keel-hq/keel/extension/notification"
 	"github.com/keel-hq/keel/registry"
 	"github.com/keel-hq/keel/types"
 	"github.com/keel-hq/keel/util/image"
@@ -76,3 +83,139 @@ func TestAWSRegistryParse(t *testing.T) {
 		t.Fatalf("parseRegistry parse region(us-east-2) not as expected: %s", region)
 	}
 }
+
+type fakeECR struct {
+	calls   int
+	err     error
+	expires time.Time
+	ids     []string
+}
+
+func (f *fakeECR) GetAuthorizationToken(input *ecr.GetAuthorizationTokenInput) (*ecr.GetAuthorizationTokenOutput, error) {
+	f.calls++
+	f.ids = append(f.ids, aws.StringValue(input.RegistryIds[0]))
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ecr.GetAuthorizationTokenOutput{
+		AuthorizationData: []*ecr.AuthorizationData{
+			{
+				AuthorizationToken: aws.String(base64.StdEncoding.EncodeToString([]byte("AWS:token"))),
+				ProxyEndpoint:      aws.String("https://" + aws.StringValue(input.RegistryIds[0]) + ".dkr.ecr.eu-west-1.amazonaws.com"),
+				ExpiresAt:          aws.Time(f.expires),
+			},
+		},
+	}, nil
+}
+
+type fakeSender struct {
+	events []types.EventNotification
+}
+
+func (s *fakeSender) Configure(*notification.Config) (bool, error) { return true, nil }
+
+func (s *fakeSender) Send(event types.EventNotification) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func newTestHelper(fake *fakeECR, roles map[string]string) (*CredentialsHelper, map[string]string) {
+	h := New()
+	h.roles = roles
+	assumed := make(map[string]string)
+	h.newClient = func(region, roleARN string) (tokenGetter, error) {
+		assumed[region] = roleARN
+		return fake, nil
+	}
+	return h, assumed
+}
+
+func TestParseRoles(t *testing.T) {
+	roles, err := parseRoles("111111111111=arn:aws:iam::111111111111:role/keel, 222222222222=arn:aws:iam::222222222222:role/keel")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if roles["222222222222"] != "arn:aws:iam::222222222222:role/keel" || len(roles) != 2 {
+		t.Errorf("unexpected roles: %v", roles)
+	}
+
+	for _, value := range []string{"111111111111", "=arn:aws:iam::1:role/keel", "111111111111=keel"} {
+		if _, err := parseRoles(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+func TestGetCredentialsAssumeRole(t *testing.T) {
+	fake := &fakeECR{expires: time.Now().Add(12 * time.Hour)}
+	h, assumed := newTestHelper(fake, map[string]string{"222222222222": "arn:aws:iam::222222222222:role/keel"})
+
+	imgRef, _ := image.Parse("222222222222.dkr.ecr.eu-west-1.amazonaws.com/app:1.0.0")
+	for i := 0; i < 3; i++ {
+		creds, err := h.GetCredentials(&types.TrackedImage{Image: imgRef})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if creds.Username != "AWS" || creds.Password != "token" {
+			t.Errorf("unexpected credentials: %+v", creds)
+		}
+	}
+
+	if fake.calls != 1 {
+		t.Errorf("expected token to be cached, got %d calls", fake.calls)
+	}
+	if assumed["eu-west-1"] != "arn:aws:iam::222222222222:role/keel" {
+		t.Errorf("expected role to be assumed, got %v", assumed)
+	}
+	if fake.ids[0] != "222222222222" {
+		t.Errorf("unexpected registry ID: %s", fake.ids[0])
+	}
+}
+
+func TestGetCredentialsTokenExpiry(t *testing.T) {
+	// token expires within the margin, it has to be refreshed
+	fake := &fakeECR{expires: time.Now().Add(tokenExpiryMargin / 2)}
+	h, _ := newTestHelper(fake, nil)
+
+	imgRef, _ := image.Parse("111111111111.dkr.ecr.eu-west-1.amazonaws.com/app:1.0.0")
+	for i := 0; i < 2; i++ {
+		if _, err := h.GetCredentials(&types.TrackedImage{Image: imgRef}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if fake.calls != 2 {
+		t.Errorf("expected token to be refreshed, got %d calls", fake.calls)
+	}
+}
+
+func TestGetCredentialsRefreshFailureNotification(t *testing.T) {
+	fake := &fakeECR{err: errors.New("AccessDenied"), expires: time.Now().Add(12 * time.Hour)}
+	h, _ := newTestHelper(fake, nil)
+	sender := &fakeSender{}
+	h.SetSender(sender)
+
+	imgRef, _ := image.Parse("111111111111.dkr.ecr.eu-west-1.amazonaws.com/app:1.0.0")
+	for i := 0; i < 3; i++ {
+		if _, err := h.GetCredentials(&types.TrackedImage{Image: imgRef}); err == nil {
+			t.Fatalf("expected error")
+		}
+	}
+	if len(sender.events) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(sender.events))
+	}
+	if sender.events[0].Level != types.LevelError || sender.events[0].Metadata["registry"] != "111111111111.dkr.ecr.eu-west-1.amazonaws.com" {
+		t.Errorf("unexpected notification: %+v", sender.events[0])
+	}
+
+	// recovered, next failure is notified again
+	fake.err = nil
+	if _, err := h.GetCredentials(&types.TrackedImage{Image: imgRef}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	h.cache = NewCache(AWSCredentialsExpiry)
+	fake.err = errors.New("AccessDenied")
+	h.GetCredentials(&types.TrackedImage{Image: imgRef})
+	if len(sender.events) != 2 {
+		t.Errorf("expected 2 notifications, got %d", len(sender.events))
+	}
+}
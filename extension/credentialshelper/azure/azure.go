@@ -0,0 +1,248 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ACR accepts refresh tokens as passwords of this user
+const refreshTokenUsername = "00000000-0000-0000-0000-000000000000"
+
+// refresh tokens are valid for 3 hours, they are renewed earlier
+const refreshTokenTTL = time.Hour
+
+const (
+	defaultAuthorityHost = "https://login.microsoftonline.com/"
+	imdsTokenURL         = "http://169.254.169.254/metadata/identity/oauth2/token"
+	managementResource   = "https://management.azure.com/"
+)
+
+func init() {
+	credentialshelper.RegisterCredentialsHelper("azure", New())
+}
+
+// CredentialsHelper provides authorization to ACR (*.azurecr.io) with AAD
+// tokens of the pod identity, AAD tokens are exchanged for ACR refresh tokens
+// so admin credentials don't have to be stored in imagePullSecrets.
+type CredentialsHelper struct {
+	enabled bool
+
+	clientID      string
+	tenantID      string
+	tokenFile     string
+	authorityHost string
+	imdsURL       string
+
+	client *http.Client
+
+	mu     sync.Mutex
+	aad    *token
+	tokens map[string]*token
+
+	// exchangeURL - ACR token exchange endpoint, replaced in tests
+	exchangeURL func(registry string) string
+}
+
+type token struct {
+	value   string
+	expires time.Time
+}
+
+func (t *token) valid(now time.Time) bool {
+	return t != nil && now.Before(t.expires)
+}
+
+// New creates a new instance of the ACR credentials helper
+func New() *CredentialsHelper {
+	h := &CredentialsHelper{
+		clientID:      os.Getenv(constants.EnvAzureClientID),
+		tenantID:      os.Getenv(constants.EnvAzureTenantID),
+		tokenFile:     os.Getenv(constants.EnvAzureFederatedTokenFile),
+		authorityHost: os.Getenv(constants.EnvAzureAuthorityHost),
+		imdsURL:       imdsTokenURL,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		tokens:        make(map[string]*token),
+		exchangeURL: func(registry string) string {
+			return "https://" + registry + "/oauth2/exchange"
+		},
+	}
+	if h.authorityHost == "" {
+		h.authorityHost = defaultAuthorityHost
+	}
+
+	managedIdentity, _ := strconv.ParseBool(os.Getenv(constants.EnvACRManagedIdentity))
+	h.enabled = h.workloadIdentity() || managedIdentity
+	return h
+}
+
+// workloadIdentity - whether federated token of the workload identity is
+// available
+func (h *CredentialsHelper) workloadIdentity() bool {
+	return h.tokenFile != "" && h.clientID != "" && h.tenantID != ""
+}
+
+// IsEnabled returns whether workload identity or managed identity is configured
+func (h *CredentialsHelper) IsEnabled() bool {
+	return h.enabled
+}
+
+// GetCredentials - ACR refresh token
+func (h *CredentialsHelper) GetCredentials(image *types.TrackedImage) (*types.Credentials, error) {
+	if !h.enabled {
+		return nil, fmt.Errorf("not initialised")
+	}
+
+	registry := image.Image.Registry()
+	if !strings.HasSuffix(strings.SplitN(registry, ":", 2)[0], ".azurecr.io") {
+		return nil, credentialshelper.ErrUnsupportedRegistry
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	if t := h.tokens[registry]; t.valid(now) {
+		return &types.Credentials{Username: refreshTokenUsername, Password: t.value}, nil
+	}
+
+	aad, err := h.aadToken(now)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"registry": registry,
+		}).Error("credentialshelper.azure: failed to get AAD token")
+		return nil, err
+	}
+
+	refreshToken, err := h.exchange(registry, aad)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"registry": registry,
+		}).Error("credentialshelper.azure: failed to exchange AAD token")
+		return nil, err
+	}
+	h.tokens[registry] = &token{value: refreshToken, expires: now.Add(refreshTokenTTL)}
+
+	return &types.Credentials{Username: refreshTokenUsername, Password: refreshToken}, nil
+}
+
+type aadResponse struct {
+	AccessToken string `json:"access_token"`
+	// IMDS returns numbers as strings
+	ExpiresIn json.Number `json:"expires_in"`
+}
+
+// aadToken - AAD access token of the workload or managed identity
+func (h *CredentialsHelper) aadToken(now time.Time) (string, error) {
+	if h.aad.valid(now) {
+		return h.aad.value, nil
+	}
+
+	var req *http.Request
+	var err error
+	if h.workloadIdentity() {
+		assertion, err := ioutil.ReadFile(h.tokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read federated token: %s", err)
+		}
+		form := url.Values{
+			"grant_type":            {"client_credentials"},
+			"client_id":             {h.clientID},
+			"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+			"client_assertion":      {strings.TrimSpace(string(assertion))},
+			"scope":                 {managementResource + ".default"},
+		}
+		endpoint := strings.TrimSuffix(h.authorityHost, "/") + "/" + h.tenantID + "/oauth2/v2.0/token"
+		req, err = http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		query := url.Values{
+			"api-version": {"2018-02-01"},
+			"resource":    {managementResource},
+		}
+		if h.clientID != "" {
+			query.Set("client_id", h.clientID)
+		}
+		req, err = http.NewRequest(http.MethodGet, h.imdsURL+"?"+query.Encode(), nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata", "true")
+	}
+
+	var resp aadResponse
+	if err := h.do(req, &resp); err != nil {
+		return "", err
+	}
+	if resp.AccessToken == "" {
+		return "", fmt.Errorf("no access token in AAD response")
+	}
+
+	expiresIn, _ := resp.ExpiresIn.Int64()
+	// renew a minute before it expires
+	h.aad = &token{value: resp.AccessToken, expires: now.Add(time.Duration(expiresIn)*time.Second - time.Minute)}
+	return resp.AccessToken, nil
+}
+
+// exchange - exchanges AAD access token for ACR refresh token
+func (h *CredentialsHelper) exchange(registry, aad string) (string, error) {
+	form := url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {registry},
+		"access_token": {aad},
+	}
+	if h.tenantID != "" {
+		form.Set("tenant", h.tenantID)
+	}
+	req, err := http.NewRequest(http.MethodPost, h.exchangeURL(registry), strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var resp struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := h.do(req, &resp); err != nil {
+		return "", err
+	}
+	if resp.RefreshToken == "" {
+		return "", fmt.Errorf("no refresh token in ACR response")
+	}
+	return resp.RefreshToken, nil
+}
+
+func (h *CredentialsHelper) do(req *http.Request, out interface{}) error {
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: unexpected status code %d: %s", req.Method, req.URL.Host, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
@@ -0,0 +1,117 @@
+package azure
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+)
+
+type fakeAzure struct {
+	*httptest.Server
+	aadRequests      int
+	exchangeRequests int
+}
+
+func newFakeAzure(t *testing.T) *fakeAzure {
+	f := &fakeAzure{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.URL.Path {
+		case "/tenant/oauth2/v2.0/token":
+			f.aadRequests++
+			if r.Form.Get("client_assertion") != "federated" || r.Form.Get("client_id") != "client" {
+				t.Errorf("unexpected AAD request: %v", r.Form)
+			}
+			w.Write([]byte(`{"access_token": "aad", "expires_in": 3600}`))
+		case "/metadata/identity/oauth2/token":
+			f.aadRequests++
+			if r.Header.Get("Metadata") != "true" || r.Form.Get("resource") != managementResource {
+				t.Errorf("unexpected IMDS request: %v", r.Form)
+			}
+			w.Write([]byte(`{"access_token": "aad", "expires_in": "3600"}`))
+		case "/oauth2/exchange":
+			f.exchangeRequests++
+			if r.Form.Get("access_token") != "aad" || r.Form.Get("service") != "keel.azurecr.io" {
+				t.Errorf("unexpected exchange request: %v", r.Form)
+			}
+			w.Write([]byte(`{"refresh_token": "refresh"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return f
+}
+
+func newTestHelper(f *fakeAzure) *CredentialsHelper {
+	h := New()
+	h.enabled = true
+	h.authorityHost = f.URL
+	h.imdsURL = f.URL + "/metadata/identity/oauth2/token"
+	h.exchangeURL = func(registry string) string { return f.URL + "/oauth2/exchange" }
+	return h
+}
+
+func TestGetCredentialsWorkloadIdentity(t *testing.T) {
+	f := newFakeAzure(t)
+	defer f.Close()
+
+	dir, err := ioutil.TempDir("", "keel-azure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	ioutil.WriteFile(tokenFile, []byte("federated\n"), 0600)
+
+	h := newTestHelper(f)
+	h.clientID = "client"
+	h.tenantID = "tenant"
+	h.tokenFile = tokenFile
+
+	imgRef, _ := image.Parse("keel.azurecr.io/app:1.0.0")
+	for i := 0; i < 2; i++ {
+		creds, err := h.GetCredentials(&types.TrackedImage{Image: imgRef})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if creds.Username != refreshTokenUsername || creds.Password != "refresh" {
+			t.Errorf("unexpected credentials: %+v", creds)
+		}
+	}
+	if f.aadRequests != 1 || f.exchangeRequests != 1 {
+		t.Errorf("expected tokens to be cached, got %d AAD and %d exchange requests", f.aadRequests, f.exchangeRequests)
+	}
+}
+
+func TestGetCredentialsManagedIdentity(t *testing.T) {
+	f := newFakeAzure(t)
+	defer f.Close()
+
+	h := newTestHelper(f)
+	imgRef, _ := image.Parse("keel.azurecr.io/app:1.0.0")
+	creds, err := h.GetCredentials(&types.TrackedImage{Image: imgRef})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if creds.Password != "refresh" {
+		t.Errorf("unexpected credentials: %+v", creds)
+	}
+}
+
+func TestGetCredentialsUnsupportedRegistry(t *testing.T) {
+	f := newFakeAzure(t)
+	defer f.Close()
+
+	h := newTestHelper(f)
+	imgRef, _ := image.Parse("gcr.io/project/app:1.0.0")
+	if _, err := h.GetCredentials(&types.TrackedImage{Image: imgRef}); err != credentialshelper.ErrUnsupportedRegistry {
+		t.Errorf("expected unsupported registry, got %v", err)
+	}
+}
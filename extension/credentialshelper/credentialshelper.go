@@ -69,6 +69,11 @@ func GetCredentials(image *types.TrackedImage) (creds *types.Credentials) {
 	credHelpersM.RLock()
 	defer credHelpersM.RUnlock()
 
+	// registries with configured credentials don't fall back to other helpers
+	if creds, ok := fromConfigured(image); ok {
+		return creds
+	}
+
 	creds = &types.Credentials{}
 
 	for name, credHelper := range credHelpers {
@@ -0,0 +1,99 @@
+package gcr
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/compute/metadata"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// username used by registries for OAuth2 access tokens
+const tokenUsername = "oauth2accesstoken"
+
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+func init() {
+	credentialshelper.RegisterCredentialsHelper("gcr", New())
+}
+
+// CredentialsHelper provides authorization to Container Registry (gcr.io) and
+// Artifact Registry (*.pkg.dev) with access tokens of the metadata server, on
+// GKE with Workload Identity these are tokens of the Google service account
+// bound to keel's Kubernetes service account. No JSON key is required.
+type CredentialsHelper struct {
+	once    sync.Once
+	enabled bool
+
+	// tokens are refreshed by the token source once they expire
+	tokens oauth2.TokenSource
+
+	// onGCE and newTokenSource are replaced in tests
+	onGCE          func() bool
+	newTokenSource func() oauth2.TokenSource
+}
+
+// New creates a new instance of the metadata server credentials helper
+func New() *CredentialsHelper {
+	return &CredentialsHelper{
+		onGCE: metadata.OnGCE,
+		newTokenSource: func() oauth2.TokenSource {
+			return oauth2.ReuseTokenSource(nil, google.ComputeTokenSource("", cloudPlatformScope))
+		},
+	}
+}
+
+// IsEnabled returns whether the helper is enabled, metadata server is only
+// looked up once there's a Google registry image
+func (h *CredentialsHelper) IsEnabled() bool { return true }
+
+func (h *CredentialsHelper) init() {
+	h.once.Do(func() {
+		h.enabled = h.onGCE()
+		if !h.enabled {
+			log.Debug("credentialshelper.gcr: metadata server not available, helper disabled")
+			return
+		}
+		h.tokens = h.newTokenSource()
+		log.Info("credentialshelper.gcr: using metadata server tokens")
+	})
+}
+
+// GetCredentials - access token of the metadata server
+func (h *CredentialsHelper) GetCredentials(image *types.TrackedImage) (*types.Credentials, error) {
+	if !isGoogleRegistry(image.Image.Registry()) {
+		return nil, credentialshelper.ErrUnsupportedRegistry
+	}
+
+	h.init()
+	if !h.enabled {
+		return nil, credentialshelper.ErrCredentialsNotAvailable
+	}
+
+	token, err := h.tokens.Token()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"registry": image.Image.Registry(),
+		}).Error("credentialshelper.gcr: failed to get access token")
+		return nil, fmt.Errorf("failed to get access token: %s", err)
+	}
+
+	return &types.Credentials{
+		Username: tokenUsername,
+		Password: token.AccessToken,
+	}, nil
+}
+
+// isGoogleRegistry - gcr.io, <region>.gcr.io and <region>-docker.pkg.dev
+func isGoogleRegistry(registry string) bool {
+	host := strings.SplitN(registry, ":", 2)[0]
+	return host == "gcr.io" || strings.HasSuffix(host, ".gcr.io") || strings.HasSuffix(host, ".pkg.dev")
+}
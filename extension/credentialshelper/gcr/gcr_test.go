@@ -0,0 +1,67 @@
+package gcr
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+)
+
+type fakeTokenSource struct {
+	calls int
+}
+
+func (f *fakeTokenSource) Token() (*oauth2.Token, error) {
+	f.calls++
+	return &oauth2.Token{AccessToken: "token", Expiry: time.Now().Add(time.Hour)}, nil
+}
+
+func newTestHelper(onGCE bool) (*CredentialsHelper, *fakeTokenSource) {
+	fake := &fakeTokenSource{}
+	h := New()
+	h.onGCE = func() bool { return onGCE }
+	h.newTokenSource = func() oauth2.TokenSource { return oauth2.ReuseTokenSource(nil, fake) }
+	return h, fake
+}
+
+func TestGetCredentials(t *testing.T) {
+	h, fake := newTestHelper(true)
+
+	for _, img := range []string{"europe-west1-docker.pkg.dev/project/repo/app:1.0.0", "eu.gcr.io/project/app:1.0.0", "gcr.io/project/app:1.0.0"} {
+		imgRef, _ := image.Parse(img)
+		creds, err := h.GetCredentials(&types.TrackedImage{Image: imgRef})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", img, err)
+		}
+		if creds.Username != "oauth2accesstoken" || creds.Password != "token" {
+			t.Errorf("%s: unexpected credentials: %+v", img, creds)
+		}
+	}
+
+	if fake.calls != 1 {
+		t.Errorf("expected token to be reused, got %d calls", fake.calls)
+	}
+}
+
+func TestGetCredentialsUnsupportedRegistry(t *testing.T) {
+	h, _ := newTestHelper(true)
+	imgRef, _ := image.Parse("quay.io/project/app:1.0.0")
+	if _, err := h.GetCredentials(&types.TrackedImage{Image: imgRef}); err != credentialshelper.ErrUnsupportedRegistry {
+		t.Errorf("expected unsupported registry, got %v", err)
+	}
+}
+
+func TestGetCredentialsNotOnGCE(t *testing.T) {
+	h, fake := newTestHelper(false)
+	imgRef, _ := image.Parse("gcr.io/project/app:1.0.0")
+	if _, err := h.GetCredentials(&types.TrackedImage{Image: imgRef}); err != credentialshelper.ErrCredentialsNotAvailable {
+		t.Errorf("expected credentials not available, got %v", err)
+	}
+	if fake.calls != 0 {
+		t.Errorf("unexpected token requests: %d", fake.calls)
+	}
+}
@@ -0,0 +1,164 @@
+package credentialshelper
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// EnvRegistryCredentials - path to registry credentials file (usually mounted
+// from a Secret), see ParseRegistryCredentials
+const EnvRegistryCredentials = "REGISTRY_CREDENTIALS"
+
+// RegistryCredentials - credentials of a registry host, either username and
+// password or the helper (ie: aws, gcr, azure, secrets) that provides them.
+// Environment variables are expanded, ie: $CORP_REGISTRY_PASSWORD
+type RegistryCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Helper   string `json:"helper"`
+}
+
+// ParseRegistryCredentials - parses YAML (or JSON) credentials keyed by registry
+// host (with an optional port), ie:
+//
+//	registry.corp.example.com:
+//	  username: keel
+//	  password: $CORP_REGISTRY_PASSWORD
+//	123456789012.dkr.ecr.us-east-1.amazonaws.com:
+//	  helper: aws
+func ParseRegistryCredentials(data []byte) (map[string]RegistryCredentials, error) {
+	var raw map[string]RegistryCredentials
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode registry credentials: %s", err)
+	}
+	if err := ValidateRegistryCredentials(raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// ValidateRegistryCredentials - checks that every registry has either
+// username and password or a helper
+func ValidateRegistryCredentials(c map[string]RegistryCredentials) error {
+	for host, creds := range c {
+		if normalizeHost(host) == "" {
+			return fmt.Errorf("invalid registry credentials host '%s'", host)
+		}
+		hasPassword := creds.Username != "" || creds.Password != ""
+		switch {
+		case hasPassword && creds.Helper != "":
+			return fmt.Errorf("registry %s: set either username and password or helper", host)
+		case !hasPassword && creds.Helper == "":
+			return fmt.Errorf("registry %s: username and password or helper is required", host)
+		case hasPassword && (creds.Username == "" || creds.Password == ""):
+			return fmt.Errorf("registry %s: both username and password are required", host)
+		}
+	}
+	return nil
+}
+
+// LoadRegistryCredentials - loads registry credentials from a file
+func LoadRegistryCredentials(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	c, err := ParseRegistryCredentials(data)
+	if err != nil {
+		return err
+	}
+	return SetRegistryCredentials(c)
+}
+
+var registryCredentials map[string]RegistryCredentials
+
+// SetRegistryCredentials - sets credentials of registries, they are used
+// before workload pull secrets and cloud helpers
+func SetRegistryCredentials(c map[string]RegistryCredentials) error {
+	if err := ValidateRegistryCredentials(c); err != nil {
+		return err
+	}
+	parsed := make(map[string]RegistryCredentials, len(c))
+	for host, creds := range c {
+		parsed[normalizeHost(host)] = creds
+	}
+
+	credHelpersM.Lock()
+	registryCredentials = parsed
+	credHelpersM.Unlock()
+	return nil
+}
+
+// configuredCredentials - credentials of the registry, the ones with a port
+// take precedence over the ones of the whole host. Has to be called with
+// credHelpersM held.
+func configuredCredentials(registry string) (RegistryCredentials, bool) {
+	host := normalizeHost(registry)
+	if creds, ok := registryCredentials[host]; ok {
+		return creds, true
+	}
+	if i := strings.LastIndex(host, ":"); i > 0 {
+		creds, ok := registryCredentials[host[:i]]
+		return creds, ok
+	}
+	return RegistryCredentials{}, false
+}
+
+// fromConfigured - credentials of the image registry from the config, ok is
+// false when the registry isn't configured. Has to be called with credHelpersM held.
+func fromConfigured(image *types.TrackedImage) (creds *types.Credentials, ok bool) {
+	if image == nil || image.Image == nil {
+		return nil, false
+	}
+	configured, ok := configuredCredentials(image.Image.Registry())
+	if !ok {
+		return nil, false
+	}
+	if configured.Helper == "" {
+		return &types.Credentials{Username: configured.Username, Password: configured.Password}, true
+	}
+
+	credHelper, found := credHelpers[configured.Helper]
+	if !found || !credHelper.IsEnabled() {
+		log.WithFields(log.Fields{
+			"helper":   configured.Helper,
+			"registry": image.Image.Registry(),
+		}).Warn("extension.credentialshelper: configured helper isn't available")
+		return &types.Credentials{}, true
+	}
+	helperCreds, err := credHelper.GetCredentials(image)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"helper":        configured.Helper,
+			"error":         err,
+			"tracked_image": image,
+		}).Warn("extension.credentialshelper: configured helper failed to get credentials")
+		return &types.Credentials{}, true
+	}
+	return helperCreds, true
+}
+
+// Docker Hub has several names, credentials use any of them
+var dockerHubHosts = map[string]bool{
+	"docker.io":            true,
+	"index.docker.io":      true,
+	"registry-1.docker.io": true,
+}
+
+func normalizeHost(registry string) string {
+	registry = strings.TrimPrefix(registry, "https://")
+	registry = strings.TrimPrefix(registry, "http://")
+	host := strings.ToLower(strings.SplitN(registry, "/", 2)[0])
+	if dockerHubHosts[host] {
+		return "docker.io"
+	}
+	return host
+}
@@ -0,0 +1,87 @@
+package credentialshelper
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+)
+
+type fakeHelper struct {
+	creds *types.Credentials
+}
+
+func (h *fakeHelper) IsEnabled() bool { return true }
+
+func (h *fakeHelper) GetCredentials(image *types.TrackedImage) (*types.Credentials, error) {
+	return h.creds, nil
+}
+
+func trackedImage(t *testing.T, name string) *types.TrackedImage {
+	ref, err := image.Parse(name)
+	if err != nil {
+		t.Fatalf("failed to parse image: %s", err)
+	}
+	return &types.TrackedImage{Image: ref}
+}
+
+func TestParseRegistryCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "password", data: "registry.example.com:\n  username: keel\n  password: secret\n"},
+		{name: "helper", data: "123456789012.dkr.ecr.us-east-1.amazonaws.com:\n  helper: aws\n"},
+		{name: "both", data: "registry.example.com:\n  username: keel\n  password: secret\n  helper: aws\n", wantErr: true},
+		{name: "none", data: "registry.example.com: {}\n", wantErr: true},
+		{name: "no password", data: "registry.example.com:\n  username: keel\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRegistryCredentials([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRegistryCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfiguredCredentials(t *testing.T) {
+	RegisterCredentialsHelper("fake", &fakeHelper{creds: &types.Credentials{Username: "helper", Password: "token"}})
+	defer UnregisterCredentialsHelper("fake")
+
+	err := SetRegistryCredentials(map[string]RegistryCredentials{
+		"registry.example.com":      {Username: "keel", Password: "secret"},
+		"registry.example.com:5000": {Username: "ported", Password: "secret"},
+		"index.docker.io":           {Username: "hub", Password: "secret"},
+		"helper.example.com":        {Helper: "fake"},
+		"missing.example.com":       {Helper: "missing"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer SetRegistryCredentials(nil)
+
+	tests := []struct {
+		image    string
+		username string
+	}{
+		{image: "registry.example.com/team/app:1.0.0", username: "keel"},
+		{image: "registry.example.com:5000/team/app:1.0.0", username: "ported"},
+		{image: "registry.example.com:5001/team/app:1.0.0", username: "keel"},
+		{image: "karolisr/keel:0.1.0", username: "hub"},
+		{image: "helper.example.com/app:1.0.0", username: "helper"},
+		{image: "missing.example.com/app:1.0.0", username: ""},
+		// registries without credentials fall back to all helpers
+		{image: "other.example.com/app:1.0.0", username: "helper"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			creds := GetCredentials(trackedImage(t, tt.image))
+			if creds.Username != tt.username {
+				t.Errorf("expected username %q, got: %q", tt.username, creds.Username)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package credentialshelper
+
+import (
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+// AuthStatus - last registry authentication failure of an image
+type AuthStatus struct {
+	Error string    `json:"error"`
+	Since time.Time `json:"since"`
+}
+
+var (
+	statusM      sync.RWMutex
+	authFailures = make(map[string]AuthStatus)
+	// secretsChanged - when secrets were last changed, map[cluster/namespace/name]
+	secretsChanged = make(map[string]time.Time)
+)
+
+func secretKey(cluster, namespace, name string) string {
+	return cluster + "/" + namespace + "/" + name
+}
+
+// SecretChanged - records that image pull secret was changed (ie: rotated), images
+// using it don't have to wait for error backoffs to expire
+func SecretChanged(cluster, namespace, name string) {
+	statusM.Lock()
+	secretsChanged[secretKey(cluster, namespace, name)] = time.Now()
+	statusM.Unlock()
+}
+
+// SecretsChangedSince - whether any of the image pull secrets of the image
+// changed after t
+func SecretsChangedSince(image *types.TrackedImage, t time.Time) bool {
+	statusM.RLock()
+	defer statusM.RUnlock()
+
+	for _, secret := range image.Secrets {
+		if changed, ok := secretsChanged[secretKey(image.Meta["cluster"], image.Namespace, secret)]; ok && changed.After(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordAuthFailure - records that registry rejected credentials of the image
+func RecordAuthFailure(image *types.TrackedImage, err error) {
+	statusM.Lock()
+	defer statusM.Unlock()
+
+	key := image.Image.String()
+	status, ok := authFailures[key]
+	if !ok {
+		status.Since = time.Now()
+	}
+	status.Error = err.Error()
+	authFailures[key] = status
+}
+
+// ClearAuthFailure - clears failure once the registry accepted credentials
+func ClearAuthFailure(image *types.TrackedImage) {
+	statusM.Lock()
+	delete(authFailures, image.Image.String())
+	statusM.Unlock()
+}
+
+// AuthFailure - current authentication failure of the image, if there's one
+func AuthFailure(image *types.TrackedImage) (AuthStatus, bool) {
+	statusM.RLock()
+	defer statusM.RUnlock()
+	status, ok := authFailures[image.Image.String()]
+	return status, ok
+}
@@ -29,6 +29,11 @@ func (a *auditor) Configure(config *notification.Config) (bool, error) {
 	return true, nil
 }
 
+// Internal - notifications are only stored in the audit log
+func (a *auditor) Internal() bool {
+	return true
+}
+
 func (a *auditor) Send(event types.EventNotification) error {
 	al := &types.AuditLog{
 		ID:           uuid.New().String(),
@@ -0,0 +1,195 @@
+// Package aws publishes keel events to an SNS topic and/or an SQS queue so AWS
+// automation (Lambda, Step Functions) can react to updates. Credentials come from
+// the default chain or IAM roles for service accounts, see util/awsauth.
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/awsauth"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// published events
+const (
+	eventPrepared  = "prepared"
+	eventSubmitted = "submitted"
+	eventApproved  = "approved"
+	eventFailed    = "failed"
+)
+
+type publisher interface {
+	Publish(input *publishInput) (string, error)
+}
+
+type queue interface {
+	SendMessage(input *sendMessageInput) (string, error)
+}
+
+type sender struct {
+	topicARN string
+	queueURL string
+
+	sns publisher
+	sqs queue
+}
+
+func init() {
+	notification.RegisterSender("aws", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	s.topicARN = os.Getenv(constants.EnvSNSTopicARN)
+	s.queueURL = os.Getenv(constants.EnvSQSQueueURL)
+	if s.topicARN == "" && s.queueURL == "" {
+		return false, nil
+	}
+
+	if s.topicARN != "" {
+		region := topicRegion(s.topicARN)
+		if region == "" {
+			return false, fmt.Errorf("invalid SNS topic ARN %q", s.topicARN)
+		}
+		sess, err := awsauth.NewSession(region)
+		if err != nil {
+			return false, fmt.Errorf("failed to create AWS session: %s", err)
+		}
+		s.sns = newSNSClient(sess, aws.NewConfig().WithRegion(region))
+	}
+
+	if s.queueURL != "" {
+		region := queueRegion(s.queueURL)
+		if region == "" {
+			return false, fmt.Errorf("invalid SQS queue URL %q", s.queueURL)
+		}
+		sess, err := awsauth.NewSession(region)
+		if err != nil {
+			return false, fmt.Errorf("failed to create AWS session: %s", err)
+		}
+		s.sqs = newSQSClient(sess, aws.NewConfig().WithRegion(region))
+	}
+
+	log.WithFields(log.Fields{
+		"name":  "aws",
+		"topic": s.topicARN,
+		"queue": s.queueURL,
+	}).Info("extension.notification.aws: sender configured")
+
+	return true, nil
+}
+
+// topicRegion - arn:aws:sns:us-east-1:123456789012:keel -> us-east-1
+func topicRegion(topicARN string) string {
+	parts := strings.Split(topicARN, ":")
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "sns" {
+		return ""
+	}
+	return parts[3]
+}
+
+// queueRegion - https://sqs.us-west-2.amazonaws.com/123456789012/keel -> us-west-2
+func queueRegion(queueURL string) string {
+	u, err := url.Parse(queueURL)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(u.Host, ".")
+	if len(parts) > 2 && parts[0] == "sqs" {
+		return parts[1]
+	}
+	return ""
+}
+
+// eventName - published event of the notification, empty for notifications
+// that aren't published
+func eventName(event types.EventNotification) string {
+	switch event.Type {
+	case types.NotificationPreDeploymentUpdate, types.NotificationPreReleaseUpdate:
+		return eventPrepared
+	case types.NotificationDeploymentUpdate, types.NotificationReleaseUpdate:
+		if event.Level >= types.LevelError {
+			return eventFailed
+		}
+		return eventSubmitted
+	case types.NotificationUpdateApproved:
+		return eventApproved
+	}
+	return ""
+}
+
+// message - structured event, ie:
+// {"event": "failed", "level": "error", "identifier": "deployment/default/wd", ...}
+type message struct {
+	Event        string            `json:"event"`
+	Name         string            `json:"name"`
+	Message      string            `json:"message"`
+	Level        string            `json:"level"`
+	ResourceKind string            `json:"resourceKind"`
+	Identifier   string            `json:"identifier"`
+	CreatedAt    time.Time         `json:"createdAt"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+func (s *sender) Send(event types.EventNotification) error {
+	name := eventName(event)
+	if name == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(message{
+		Event:        name,
+		Name:         event.Name,
+		Message:      event.Message,
+		Level:        event.Level.String(),
+		ResourceKind: event.ResourceKind,
+		Identifier:   event.Identifier,
+		CreatedAt:    event.CreatedAt,
+		Metadata:     event.Metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+
+	// attributes allow subscription filter policies on the event and level
+	attributes := map[string]*messageAttributeValue{
+		"event": {DataType: aws.String("String"), StringValue: aws.String(name)},
+		"level": {DataType: aws.String("String"), StringValue: aws.String(event.Level.String())},
+	}
+
+	var errs []string
+	if s.sns != nil {
+		_, err := s.sns.Publish(&publishInput{
+			TopicArn:          aws.String(s.topicARN),
+			Message:           aws.String(string(body)),
+			MessageAttributes: attributes,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("SNS publish failed: %s", err))
+		}
+	}
+	if s.sqs != nil {
+		_, err := s.sqs.SendMessage(&sendMessageInput{
+			QueueUrl:          aws.String(s.queueURL),
+			MessageBody:       aws.String(string(body)),
+			MessageAttributes: attributes,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("SQS send failed: %s", err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
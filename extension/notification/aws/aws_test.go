@@ -0,0 +1,119 @@
+package aws
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestSend(t *testing.T) {
+	var requests []url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		req.ParseForm()
+		requests = append(requests, req.PostForm)
+		action := req.PostForm.Get("Action")
+		resp.Write([]byte(`<` + action + `Response><` + action + `Result><MessageId>id-1</MessageId></` + action + `Result></` + action + `Response>`))
+	}))
+	defer ts.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(ts.URL).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("failed to create session: %s", err)
+	}
+
+	s := &sender{
+		topicARN: "arn:aws:sns:us-east-1:123456789012:keel",
+		queueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/keel",
+		sns:      newSNSClient(sess),
+		sqs:      newSQSClient(sess),
+	}
+
+	err = s.Send(types.EventNotification{
+		Name:         "update resource",
+		ResourceKind: "deployment",
+		Identifier:   "deployment/default/wd",
+		Message:      "update failed",
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        types.LevelError,
+		Metadata:     map[string]string{"namespace": "default"},
+	})
+	if err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected SNS and SQS requests, got %d", len(requests))
+	}
+
+	publish := requests[0]
+	if publish.Get("Action") != "Publish" || publish.Get("TopicArn") != s.topicARN {
+		t.Errorf("unexpected publish request: %v", publish)
+	}
+	var msg message
+	if err := json.Unmarshal([]byte(publish.Get("Message")), &msg); err != nil {
+		t.Fatalf("failed to decode message: %s", err)
+	}
+	if msg.Event != eventFailed || msg.Identifier != "deployment/default/wd" || msg.Metadata["namespace"] != "default" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+	attributes := map[string]string{}
+	for i := 1; i <= 2; i++ {
+		prefix := "MessageAttributes.entry." + strconv.Itoa(i)
+		attributes[publish.Get(prefix+".Name")] = publish.Get(prefix + ".Value.StringValue")
+	}
+	if attributes["event"] != eventFailed || attributes["level"] != "error" {
+		t.Errorf("unexpected publish attributes: %v", publish)
+	}
+
+	send := requests[1]
+	if send.Get("Action") != "SendMessage" || send.Get("QueueUrl") != s.queueURL || send.Get("MessageBody") != publish.Get("Message") {
+		t.Errorf("unexpected send request: %v", send)
+	}
+	if send.Get("MessageAttribute.1.Name") == "" || send.Get("MessageAttribute.1.Value.DataType") != "String" {
+		t.Errorf("unexpected send attributes: %v", send)
+	}
+}
+
+func TestEventName(t *testing.T) {
+	tests := []struct {
+		event types.EventNotification
+		want  string
+	}{
+		{types.EventNotification{Type: types.NotificationPreDeploymentUpdate, Level: types.LevelDebug}, eventPrepared},
+		{types.EventNotification{Type: types.NotificationDeploymentUpdate, Level: types.LevelSuccess}, eventSubmitted},
+		{types.EventNotification{Type: types.NotificationReleaseUpdate, Level: types.LevelError}, eventFailed},
+		{types.EventNotification{Type: types.NotificationUpdateApproved, Level: types.LevelInfo}, eventApproved},
+		{types.EventNotification{Type: types.NotificationSystemEvent, Level: types.LevelError}, ""},
+	}
+	for _, tt := range tests {
+		if got := eventName(tt.event); got != tt.want {
+			t.Errorf("eventName(%s, %s) = %q, want %q", tt.event.Type, tt.event.Level, got, tt.want)
+		}
+	}
+}
+
+func TestRegions(t *testing.T) {
+	if got := topicRegion("arn:aws:sns:eu-west-1:123456789012:keel"); got != "eu-west-1" {
+		t.Errorf("unexpected topic region: %s", got)
+	}
+	if got := topicRegion("keel"); got != "" {
+		t.Errorf("expected no region, got: %s", got)
+	}
+	if got := queueRegion("https://sqs.us-west-2.amazonaws.com/123456789012/keel"); got != "us-west-2" {
+		t.Errorf("unexpected queue region: %s", got)
+	}
+}
@@ -0,0 +1,104 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/query"
+)
+
+type messageAttributeValue struct {
+	_ struct{} `type:"structure"`
+
+	DataType    *string `type:"string" required:"true"`
+	StringValue *string `type:"string"`
+}
+
+type publishInput struct {
+	_ struct{} `type:"structure"`
+
+	Message           *string                           `type:"string" required:"true"`
+	MessageAttributes map[string]*messageAttributeValue `locationNameKey:"Name" locationNameValue:"Value" type:"map"`
+	Subject           *string                           `type:"string"`
+	TopicArn          *string                           `type:"string"`
+}
+
+type sendMessageInput struct {
+	_ struct{} `type:"structure"`
+
+	MessageAttributes map[string]*messageAttributeValue `locationName:"MessageAttribute" locationNameKey:"Name" locationNameValue:"Value" type:"map" flattened:"true"`
+	MessageBody       *string                           `type:"string" required:"true"`
+	QueueUrl          *string                           `type:"string" required:"true"`
+}
+
+type messageOutput struct {
+	_ struct{} `type:"structure"`
+
+	MessageId *string `type:"string"`
+}
+
+// queryClient - minimal SNS/SQS query API client, only publishing messages is required
+type queryClient struct {
+	*client.Client
+}
+
+func newQueryClient(p client.ConfigProvider, service, serviceID, apiVersion string, cfgs ...*aws.Config) *queryClient {
+	c := p.ClientConfig(service, cfgs...)
+	svc := &queryClient{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   service,
+				ServiceID:     serviceID,
+				SigningName:   c.SigningName,
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    apiVersion,
+			},
+			c.Handlers,
+		),
+	}
+
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(query.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(query.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(query.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(query.UnmarshalErrorHandler)
+
+	return svc
+}
+
+// newSNSClient - creates SNS client from AWS session
+func newSNSClient(p client.ConfigProvider, cfgs ...*aws.Config) *queryClient {
+	return newQueryClient(p, "sns", "SNS", "2010-03-31", cfgs...)
+}
+
+// newSQSClient - creates SQS client from AWS session
+func newSQSClient(p client.ConfigProvider, cfgs ...*aws.Config) *queryClient {
+	return newQueryClient(p, "sqs", "SQS", "2012-11-05", cfgs...)
+}
+
+func (c *queryClient) send(name string, input interface{}) (string, error) {
+	op := &request.Operation{
+		Name:       name,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+	output := &messageOutput{}
+	if err := c.NewRequest(op, input, output).Send(); err != nil {
+		return "", err
+	}
+	return aws.StringValue(output.MessageId), nil
+}
+
+// Publish - publishes message to the SNS topic
+func (c *queryClient) Publish(input *publishInput) (string, error) {
+	return c.send("Publish", input)
+}
+
+// SendMessage - sends message to the SQS queue
+func (c *queryClient) SendMessage(input *sendMessageInput) (string, error) {
+	return c.send("SendMessage", input)
+}
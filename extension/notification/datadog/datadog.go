@@ -0,0 +1,185 @@
+package datadog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 5 * time.Second
+
+const defaultSite = "datadoghq.com"
+
+// metricName - DogStatsD counter of keel events
+const metricName = "keel.event"
+
+// events - event types posted to the Events API, metrics are emitted for all events
+var events = map[string]bool{
+	"deployment.updated": true,
+	"deployment.failed":  true,
+	"release.updated":    true,
+	"release.failed":     true,
+	"approval.approved":  true,
+	"approval.rejected":  true,
+}
+
+type sender struct {
+	apiKey   string
+	endpoint string
+	tags     []string
+	client   *http.Client
+
+	statsd net.Conn
+}
+
+func init() {
+	notification.RegisterSender("datadog", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	s.apiKey = os.Getenv(constants.EnvDatadogAPIKey)
+	statsdAddr := os.Getenv(constants.EnvDatadogStatsdAddr)
+	if s.apiKey == "" && statsdAddr == "" {
+		return false, nil
+	}
+
+	site := os.Getenv(constants.EnvDatadogSite)
+	if site == "" {
+		site = defaultSite
+	}
+	s.endpoint = "https://api." + site + "/api/v1/events"
+
+	for _, tag := range strings.Split(os.Getenv(constants.EnvDatadogTags), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			s.tags = append(s.tags, tag)
+		}
+	}
+
+	s.client = &http.Client{
+		Transport: http.DefaultTransport,
+		Timeout:   timeout,
+	}
+
+	if statsdAddr != "" {
+		conn, err := net.Dial("udp", statsdAddr)
+		if err != nil {
+			return false, fmt.Errorf("failed to connect to DogStatsD: %s", err)
+		}
+		s.statsd = conn
+	}
+
+	log.WithFields(log.Fields{
+		"name":   "datadog",
+		"events": s.apiKey != "",
+		"statsd": statsdAddr,
+	}).Info("extension.notification.datadog: sender configured")
+
+	return true, nil
+}
+
+// alertType - Datadog event alert type of the notification level
+func alertType(level types.Level) string {
+	switch level {
+	case types.LevelError, types.LevelFatal:
+		return "error"
+	case types.LevelWarn:
+		return "warning"
+	case types.LevelSuccess:
+		return "success"
+	default:
+		return "info"
+	}
+}
+
+// eventTags - namespace, name, images and result of the event and the configured tags
+func (s *sender) eventTags(event types.EventNotification) []string {
+	eventType := notification.EventType(event)
+	tags := append([]string{}, s.tags...)
+	tags = append(tags, "source:keel", "event_type:"+eventType)
+	if idx := strings.LastIndex(eventType, "."); idx >= 0 {
+		tags = append(tags, "result:"+eventType[idx+1:])
+	}
+	if event.ResourceKind != "" {
+		tags = append(tags, "kind:"+event.ResourceKind)
+	}
+	for _, key := range []string{"namespace", "name", "provider", "cluster"} {
+		if v := event.Metadata[key]; v != "" {
+			tags = append(tags, key+":"+v)
+		}
+	}
+	for _, image := range strings.Split(event.Metadata["images"], ",") {
+		if image != "" {
+			tags = append(tags, "image:"+image)
+		}
+	}
+	return tags
+}
+
+type ddEvent struct {
+	Title          string   `json:"title"`
+	Text           string   `json:"text"`
+	DateHappened   int64    `json:"date_happened"`
+	AlertType      string   `json:"alert_type"`
+	AggregationKey string   `json:"aggregation_key,omitempty"`
+	SourceTypeName string   `json:"source_type_name"`
+	Tags           []string `json:"tags"`
+}
+
+func (s *sender) Send(event types.EventNotification) error {
+	tags := s.eventTags(event)
+
+	if s.statsd != nil {
+		// metrics are best effort, UDP write errors are ignored
+		fmt.Fprintf(s.statsd, "%s:1|c|#%s", metricName, strings.Join(tags, ","))
+	}
+
+	if s.apiKey == "" || !events[notification.EventType(event)] {
+		return nil
+	}
+
+	title := fmt.Sprintf("keel: %s", event.Type.String())
+	if event.Identifier != "" {
+		title += " " + event.Identifier
+	}
+	body, err := json.Marshal(ddEvent{
+		Title:          title,
+		Text:           event.Message,
+		DateHappened:   event.CreatedAt.Unix(),
+		AlertType:      alertType(event.Level),
+		AggregationKey: event.Identifier,
+		SourceTypeName: "keel",
+		Tags:           tags,
+	})
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", s.apiKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("got status %d, expected 202", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package datadog
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestSend(t *testing.T) {
+	var (
+		got    ddEvent
+		apiKey string
+		posted int
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		apiKey = req.Header.Get("DD-API-KEY")
+		json.NewDecoder(req.Body).Decode(&got)
+		posted++
+		resp.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	defer pc.Close()
+	statsd, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %s", err)
+	}
+
+	s := &sender{
+		apiKey:   "key",
+		endpoint: ts.URL,
+		tags:     []string{"env:prod"},
+		client:   &http.Client{},
+		statsd:   statsd,
+	}
+
+	event := types.EventNotification{
+		Name:         "update resource",
+		ResourceKind: "deployment",
+		Identifier:   "deployment/default/wd",
+		Message:      "deployment default/wd update 0.0.14->0.0.15 failed",
+		CreatedAt:    time.Unix(1538395200, 0),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        types.LevelError,
+		Metadata:     map[string]string{"namespace": "default", "name": "wd", "images": "karolisr/webhook-demo:0.0.15"},
+	}
+	if err := s.Send(event); err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+
+	if apiKey != "key" || got.AlertType != "error" || got.DateHappened != 1538395200 || got.AggregationKey != "deployment/default/wd" {
+		t.Errorf("unexpected event: %+v", got)
+	}
+	tags := strings.Join(got.Tags, ",")
+	for _, tag := range []string{"env:prod", "result:failed", "namespace:default", "image:karolisr/webhook-demo:0.0.15"} {
+		if !strings.Contains(tags, tag) {
+			t.Errorf("missing tag %s in %s", tag, tags)
+		}
+	}
+
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("failed to read metric: %s", err)
+	}
+	if metric := string(buf[:n]); !strings.HasPrefix(metric, "keel.event:1|c|#env:prod,") || !strings.Contains(metric, "event_type:deployment.failed") {
+		t.Errorf("unexpected metric: %s", metric)
+	}
+
+	// progress events are only counted
+	event.Level = types.LevelInfo
+	s.Send(event)
+	if posted != 1 {
+		t.Errorf("expected progress event not to be posted")
+	}
+}
@@ -0,0 +1,106 @@
+package notification
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+// DefaultDigestSenders - chat senders notifications are batched for in digest
+// mode, machine consumers (webhook, kafka, ...) still get every event
+var DefaultDigestSenders = []string{"slack", "mattermost", "teams", "hipchat"}
+
+// digestLevel - notifications of this level and above are never batched
+const digestLevel = types.LevelWarn
+
+// digestMaxLines - maximum number of messages listed in a digest
+const digestMaxLines = 50
+
+// digest - buffered notifications keyed by sender and channels
+type digest struct {
+	mu      sync.Mutex
+	senders map[string]bool
+	events  map[string]map[string][]types.EventNotification
+}
+
+func newDigest(senders []string) *digest {
+	d := &digest{
+		senders: make(map[string]bool, len(senders)),
+		events:  make(map[string]map[string][]types.EventNotification),
+	}
+	for _, s := range senders {
+		d.senders[strings.TrimSpace(s)] = true
+	}
+	return d
+}
+
+// add - buffers the event, returns false if it has to be sent immediately
+func (d *digest) add(sender string, event types.EventNotification) bool {
+	if d == nil || !d.senders[sender] || event.Level >= digestLevel || event.Type == types.NotificationDigest {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	channels := strings.Join(event.Channels, ",")
+	if d.events[sender] == nil {
+		d.events[sender] = make(map[string][]types.EventNotification)
+	}
+	d.events[sender][channels] = append(d.events[sender][channels], event)
+	return true
+}
+
+// flush - returns digest notifications of the buffered events per sender
+func (d *digest) flush(interval time.Duration) map[string][]types.EventNotification {
+	d.mu.Lock()
+	buffered := d.events
+	d.events = make(map[string]map[string][]types.EventNotification)
+	d.mu.Unlock()
+
+	digests := make(map[string][]types.EventNotification)
+	for sender, byChannels := range buffered {
+		var keys []string
+		for channels := range byChannels {
+			keys = append(keys, channels)
+		}
+		sort.Strings(keys)
+		for _, channels := range keys {
+			digests[sender] = append(digests[sender], summarize(byChannels[channels], interval))
+		}
+	}
+	return digests
+}
+
+// summarize - single notification listing buffered messages, level is the
+// highest level of the messages
+func summarize(events []types.EventNotification, interval time.Duration) types.EventNotification {
+	level := events[0].Level
+	lines := []string{fmt.Sprintf("%d notifications in the last %s:", len(events), interval)}
+	for i, e := range events {
+		if e.Level > level {
+			level = e.Level
+		}
+		if i < digestMaxLines {
+			lines = append(lines, "• "+e.Message)
+		}
+	}
+	if len(events) > digestMaxLines {
+		lines = append(lines, fmt.Sprintf("… and %d more", len(events)-digestMaxLines))
+	}
+
+	return types.EventNotification{
+		Name:      "notification digest",
+		Message:   strings.Join(lines, "\n"),
+		CreatedAt: time.Now(),
+		Type:      types.NotificationDigest,
+		Level:     level,
+		Channels:  events[0].Channels,
+		Metadata: map[string]string{
+			"count": fmt.Sprintf("%d", len(events)),
+		},
+	}
+}
@@ -0,0 +1,73 @@
+package notification
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+type recordingSender struct {
+	sent []types.EventNotification
+}
+
+func (s *recordingSender) Configure(*Config) (bool, error) { return true, nil }
+
+func (s *recordingSender) Send(event types.EventNotification) error {
+	s.sent = append(s.sent, event)
+	return nil
+}
+
+func TestDigest(t *testing.T) {
+	sndr := New(context.Background())
+	chat := &recordingSender{}
+	archive := &recordingSender{}
+	RegisterSender("digestchat", chat)
+	RegisterSender("digestarchive", archive)
+	defer sndr.UnregisterSender("digestchat")
+	defer sndr.UnregisterSender("digestarchive")
+
+	sndr.Configure(&Config{
+		Level:          types.LevelDebug,
+		Attempts:       1,
+		DigestInterval: time.Hour,
+		DigestSenders:  []string{"digestchat"},
+	})
+
+	for _, msg := range []string{"updated wd", "updated api"} {
+		sndr.Send(types.EventNotification{Message: msg, Type: types.NotificationDeploymentUpdate, Level: types.LevelSuccess})
+	}
+	sndr.Send(types.EventNotification{Message: "updated db", Type: types.NotificationDeploymentUpdate, Level: types.LevelInfo, Channels: []string{"db"}})
+	sndr.Send(types.EventNotification{Message: "update failed", Type: types.NotificationDeploymentUpdate, Level: types.LevelError})
+
+	if len(chat.sent) != 1 || chat.sent[0].Message != "update failed" {
+		t.Fatalf("expected only the error to be sent immediately, got: %v", chat.sent)
+	}
+	if len(archive.sent) != 4 {
+		t.Errorf("expected senders without digest to get all events, got %d", len(archive.sent))
+	}
+
+	sndr.flushDigests(time.Hour)
+
+	if len(chat.sent) != 3 {
+		t.Fatalf("expected 2 digests (per channel), got: %v", chat.sent[1:])
+	}
+	d := chat.sent[1]
+	if d.Type != types.NotificationDigest || d.Level != types.LevelSuccess || d.Metadata["count"] != "2" {
+		t.Errorf("unexpected digest: %+v", d)
+	}
+	if !strings.Contains(d.Message, "2 notifications in the last 1h0m0s") || !strings.Contains(d.Message, "• updated api") {
+		t.Errorf("unexpected digest message: %s", d.Message)
+	}
+	if len(chat.sent[2].Channels) != 1 || chat.sent[2].Channels[0] != "db" {
+		t.Errorf("expected db channel digest, got: %+v", chat.sent[2])
+	}
+
+	// nothing buffered
+	sndr.flushDigests(time.Hour)
+	if len(chat.sent) != 3 {
+		t.Errorf("unexpected digest without buffered events")
+	}
+}
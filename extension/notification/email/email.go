@@ -0,0 +1,273 @@
+package email
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/templates"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 10 * time.Second
+
+// TLS modes
+const (
+	tlsStartTLS = "starttls"
+	tlsImplicit = "tls"
+	tlsNone     = "none"
+)
+
+// defaultTemplate - HTML body, template data is bodyData
+const defaultTemplate = `<html>
+<body style="font-family: sans-serif">
+<h3 style="color: {{ .Color }}">{{ .Title }}</h3>
+<p>{{ .Text }}</p>
+{{- if .Fields }}
+<table>
+{{- range .Fields }}
+<tr><td><b>{{ .Title }}</b></td><td>{{ .Value }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+<p style="color: #888888">{{ .Event.ResourceKind }} {{ .Event.Identifier }}, {{ .Event.Level }}, {{ .Event.CreatedAt.Format "2006-01-02 15:04:05 MST" }}</p>
+</body>
+</html>
+`
+
+type sender struct {
+	addr     string
+	host     string
+	tlsMode  string
+	auth     smtp.Auth
+	from     string
+	to       []string
+	levelTo  map[types.Level][]string
+	template *template.Template
+
+	// send - delivers the message, replaced in tests
+	send func(to []string, msg []byte) error
+}
+
+// bodyData - data of the HTML body template
+type bodyData struct {
+	Title  string
+	Text   string
+	Color  string
+	Fields []templates.MessageField
+	Event  types.EventNotification
+}
+
+func init() {
+	notification.RegisterSender("email", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	s.host = os.Getenv(constants.EnvSMTPHost)
+	if s.host == "" {
+		return false, nil
+	}
+
+	port := 587
+	if p := os.Getenv(constants.EnvSMTPPort); p != "" {
+		var err error
+		if port, err = strconv.Atoi(p); err != nil {
+			return false, fmt.Errorf("invalid SMTP port %q: %s", p, err)
+		}
+	}
+	s.addr = net.JoinHostPort(s.host, strconv.Itoa(port))
+
+	s.tlsMode = strings.ToLower(os.Getenv(constants.EnvSMTPTLS))
+	switch s.tlsMode {
+	case "":
+		s.tlsMode = tlsStartTLS
+		if port == 465 {
+			s.tlsMode = tlsImplicit
+		}
+	case tlsStartTLS, tlsImplicit, tlsNone:
+	default:
+		return false, fmt.Errorf("invalid SMTP TLS mode %q, expected starttls, tls or none", s.tlsMode)
+	}
+
+	if username := os.Getenv(constants.EnvSMTPUsername); username != "" {
+		s.auth = smtp.PlainAuth("", username, os.Getenv(constants.EnvSMTPPassword), s.host)
+	}
+
+	s.from = os.Getenv(constants.EnvSMTPFrom)
+	if s.from == "" {
+		return false, fmt.Errorf("%s is required", constants.EnvSMTPFrom)
+	}
+
+	s.to = recipients(os.Getenv(constants.EnvSMTPTo))
+	s.levelTo = make(map[types.Level][]string)
+	for _, level := range []types.Level{types.LevelDebug, types.LevelInfo, types.LevelSuccess, types.LevelWarn, types.LevelError, types.LevelFatal} {
+		if to := recipients(os.Getenv(constants.EnvSMTPTo + "_" + strings.ToUpper(level.String()))); len(to) > 0 {
+			s.levelTo[level] = to
+		}
+	}
+	if len(s.to) == 0 && len(s.levelTo) == 0 {
+		return false, fmt.Errorf("no recipients, set %s or %s_<LEVEL>", constants.EnvSMTPTo, constants.EnvSMTPTo)
+	}
+
+	body := defaultTemplate
+	if path := os.Getenv(constants.EnvSMTPTemplate); path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return false, fmt.Errorf("failed to read email template: %s", err)
+		}
+		body = string(data)
+	}
+	tmpl, err := template.New("email").Parse(body)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse email template: %s", err)
+	}
+	s.template = tmpl
+	s.send = s.sendMail
+
+	log.WithFields(log.Fields{
+		"name":   "email",
+		"server": s.addr,
+		"tls":    s.tlsMode,
+	}).Info("extension.notification.email: sender configured")
+
+	return true, nil
+}
+
+func recipients(value string) []string {
+	var to []string
+	for _, r := range strings.Split(value, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			to = append(to, r)
+		}
+	}
+	return to
+}
+
+// recipientsFor - recipients of all levels and of the event level
+func (s *sender) recipientsFor(event types.EventNotification) []string {
+	seen := make(map[string]bool)
+	var to []string
+	for _, r := range append(append([]string{}, s.to...), s.levelTo[event.Level]...) {
+		if !seen[r] && notification.EndpointAllowed("email", r, event) {
+			seen[r] = true
+			to = append(to, r)
+		}
+	}
+	return to
+}
+
+func (s *sender) Send(event types.EventNotification) error {
+	to := s.recipientsFor(event)
+	if len(to) == 0 {
+		return nil
+	}
+
+	msg, err := s.message(event, to)
+	if err != nil {
+		return err
+	}
+	return s.send(to, msg)
+}
+
+// message - MIME message with HTML body
+func (s *sender) message(event types.EventNotification, to []string) ([]byte, error) {
+	data := bodyData{
+		Title: event.Type.String(),
+		Text:  event.Message,
+		Color: event.Level.Color(),
+		Event: event,
+	}
+	if msg, ok := templates.RenderMessage(templates.NotificationMessageName(event.Type), event); ok {
+		if msg.Title != "" {
+			data.Title = msg.Title
+		}
+		data.Text = msg.Text
+		data.Fields = msg.Fields
+	}
+
+	var body bytes.Buffer
+	if err := s.template.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("failed to render email: %s", err)
+	}
+
+	subject := fmt.Sprintf("[keel] %s: %s", strings.ToUpper(event.Level.String()), data.Title)
+	if event.Identifier != "" {
+		subject += " " + event.Identifier
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", event.CreatedAt.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=\"utf-8\"\r\n")
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+func (s *sender) sendMail(to []string, msg []byte) error {
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: timeout}
+	if s.tlsMode == tlsImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.addr, &tls.Config{ServerName: s.host})
+	} else {
+		conn, err = dialer.Dial("tcp", s.addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %s", err)
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to connect to SMTP server: %s", err)
+	}
+	defer c.Close()
+
+	if s.tlsMode == tlsStartTLS {
+		if err := c.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return fmt.Errorf("STARTTLS failed: %s", err)
+		}
+	}
+	if s.auth != nil {
+		if err := c.Auth(s.auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %s", err)
+		}
+	}
+	if err := c.Mail(s.from); err != nil {
+		return err
+	}
+	for _, r := range to {
+		if err := c.Rcpt(r); err != nil {
+			return fmt.Errorf("recipient %s rejected: %s", r, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
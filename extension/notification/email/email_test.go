@@ -0,0 +1,237 @@
+package email
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+)
+
+type sentMail struct {
+	to  []string
+	msg string
+}
+
+func configure(t *testing.T, env map[string]string) (*sender, *[]sentMail) {
+	for k, v := range env {
+		os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range env {
+			os.Unsetenv(k)
+		}
+	}()
+
+	s := &sender{}
+	configured, err := s.Configure(&notification.Config{})
+	if err != nil || !configured {
+		t.Fatalf("failed to configure sender: %t %v", configured, err)
+	}
+
+	var sent []sentMail
+	s.send = func(to []string, msg []byte) error {
+		sent = append(sent, sentMail{to: to, msg: string(msg)})
+		return nil
+	}
+	return s, &sent
+}
+
+func TestSend(t *testing.T) {
+	s, sent := configure(t, map[string]string{
+		constants.EnvSMTPHost:          "smtp.example.com",
+		constants.EnvSMTPFrom:          "keel@example.com",
+		constants.EnvSMTPTo:            "releases@example.com",
+		constants.EnvSMTPTo + "_ERROR": "oncall@example.com, releases@example.com",
+		constants.EnvSMTPUsername:      "keel",
+		constants.EnvSMTPPassword:      "secret",
+	})
+	if s.addr != "smtp.example.com:587" || s.tlsMode != tlsStartTLS || s.auth == nil {
+		t.Errorf("unexpected configuration: %s %s", s.addr, s.tlsMode)
+	}
+
+	event := types.EventNotification{
+		Name:         "update deployment",
+		Message:      "Successfully updated deployment default/wd <script>",
+		Identifier:   "default/wd",
+		ResourceKind: "deployment",
+		CreatedAt:    time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        types.LevelSuccess,
+	}
+	if err := s.Send(event); err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+
+	event.Level = types.LevelError
+	if err := s.Send(event); err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+
+	if len(*sent) != 2 {
+		t.Fatalf("expected 2 emails, got: %d", len(*sent))
+	}
+	if strings.Join((*sent)[0].to, ",") != "releases@example.com" {
+		t.Errorf("unexpected recipients: %v", (*sent)[0].to)
+	}
+	if strings.Join((*sent)[1].to, ",") != "releases@example.com,oncall@example.com" {
+		t.Errorf("unexpected error recipients: %v", (*sent)[1].to)
+	}
+
+	msg := (*sent)[0].msg
+	for _, expected := range []string{
+		"From: keel@example.com\r\n",
+		"Subject: [keel] SUCCESS: deployment update default/wd\r\n",
+		"Content-Type: text/html",
+		"Successfully updated deployment default/wd &lt;script&gt;",
+		"#00C853",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("expected %q in message:\n%s", expected, msg)
+		}
+	}
+}
+
+func TestSendLevelRecipientsOnly(t *testing.T) {
+	s, sent := configure(t, map[string]string{
+		constants.EnvSMTPHost:          "smtp.example.com",
+		constants.EnvSMTPPort:          "465",
+		constants.EnvSMTPFrom:          "keel@example.com",
+		constants.EnvSMTPTo + "_FATAL": "oncall@example.com",
+	})
+	if s.tlsMode != tlsImplicit {
+		t.Errorf("expected implicit TLS on port 465, got: %s", s.tlsMode)
+	}
+
+	s.Send(types.EventNotification{Type: types.NotificationDeploymentUpdate, Level: types.LevelInfo})
+	if len(*sent) != 0 {
+		t.Errorf("expected no email without recipients for the level")
+	}
+}
+
+func TestCustomTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "email.html")
+	ioutil.WriteFile(path, []byte(`<p>{{ .Event.Identifier }} - {{ .Text }}</p>`), 0600)
+
+	s, sent := configure(t, map[string]string{
+		constants.EnvSMTPHost:     "smtp.example.com",
+		constants.EnvSMTPFrom:     "keel@example.com",
+		constants.EnvSMTPTo:       "releases@example.com",
+		constants.EnvSMTPTemplate: path,
+	})
+	s.Send(types.EventNotification{Identifier: "default/wd", Message: "updated", Type: types.NotificationDeploymentUpdate})
+	if len(*sent) != 1 || !strings.HasSuffix((*sent)[0].msg, "<p>default/wd - updated</p>") {
+		t.Errorf("unexpected message: %v", *sent)
+	}
+}
+
+func TestConfigureErrors(t *testing.T) {
+	s := &sender{}
+	if configured, _ := s.Configure(&notification.Config{}); configured {
+		t.Errorf("sender shouldn't be configured without SMTP host")
+	}
+
+	os.Setenv(constants.EnvSMTPHost, "smtp.example.com")
+	defer os.Unsetenv(constants.EnvSMTPHost)
+	if _, err := s.Configure(&notification.Config{}); err == nil {
+		t.Errorf("expected error without sender address")
+	}
+
+	os.Setenv(constants.EnvSMTPFrom, "keel@example.com")
+	defer os.Unsetenv(constants.EnvSMTPFrom)
+	if _, err := s.Configure(&notification.Config{}); err == nil {
+		t.Errorf("expected error without recipients")
+	}
+}
+
+// fakeSMTPServer - accepts a single message and returns its data
+func fakeSMTPServer(t *testing.T) (string, chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make(chan string, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 localhost ESMTP\r\n")
+		var body strings.Builder
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					data <- body.String()
+					fmt.Fprint(conn, "250 OK\r\n")
+					continue
+				}
+				body.WriteString(line)
+				continue
+			}
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				fmt.Fprint(conn, "250-localhost\r\n250 AUTH PLAIN\r\n")
+			case strings.HasPrefix(line, "DATA"):
+				inData = true
+				fmt.Fprint(conn, "354 go ahead\r\n")
+			case strings.HasPrefix(line, "QUIT"):
+				fmt.Fprint(conn, "221 bye\r\n")
+				return
+			case strings.HasPrefix(line, "AUTH"):
+				fmt.Fprint(conn, "235 OK\r\n")
+			default:
+				fmt.Fprint(conn, "250 OK\r\n")
+			}
+		}
+	}()
+	return l.Addr().String(), data
+}
+
+func TestSendMail(t *testing.T) {
+	addr, data := fakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(addr)
+
+	s, _ := configure(t, map[string]string{
+		constants.EnvSMTPHost:     host,
+		constants.EnvSMTPPort:     port,
+		constants.EnvSMTPTLS:      "none",
+		constants.EnvSMTPFrom:     "keel@example.com",
+		constants.EnvSMTPTo:       "releases@example.com",
+		constants.EnvSMTPUsername: "keel",
+	})
+	s.send = s.sendMail
+
+	if err := s.Send(types.EventNotification{Identifier: "default/wd", Message: "updated", Type: types.NotificationDeploymentUpdate}); err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+	select {
+	case msg := <-data:
+		if !strings.Contains(msg, "To: releases@example.com") || !strings.Contains(msg, "updated") {
+			t.Errorf("unexpected message: %s", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("message wasn't delivered")
+	}
+}
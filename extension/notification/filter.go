@@ -0,0 +1,190 @@
+package notification
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sync"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/keel-hq/keel/types"
+)
+
+// FilterConfig - level threshold and event type allowlist of a sender or an
+// endpoint, event types can be patterns, ie: "deployment.*"
+type FilterConfig struct {
+	Level  string   `json:"level"`
+	Events []string `json:"events"`
+}
+
+// Filter - parsed filter
+type Filter struct {
+	level    types.Level
+	hasLevel bool
+	events   []string
+}
+
+// Filters - filters keyed by sender name (ie: "pagerduty") or by sender endpoint,
+// "<sender>:<endpoint>" (ie: "slack:#deployments", "webhook:n8n",
+// "email:ops@example.com"). Sender filters replace the global level, endpoint
+// filters are applied on top of sender filters.
+type Filters map[string]*Filter
+
+// ParseFilters - parses YAML (or JSON) filters, ie:
+//
+//	pagerduty:
+//	  level: error
+//	  events: ["deployment.failed", "release.failed"]
+//	slack:#deployments:
+//	  events: ["deployment.*"]
+func ParseFilters(data []byte) (Filters, error) {
+	var raw map[string]*FilterConfig
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode notification filters: %s", err)
+	}
+	return NewFilters(raw)
+}
+
+// NewFilters - parses filter configs keyed by sender or sender endpoint
+func NewFilters(raw map[string]*FilterConfig) (Filters, error) {
+	filters := make(Filters, len(raw))
+	for name, cfg := range raw {
+		if cfg == nil {
+			continue
+		}
+		f := &Filter{events: cfg.Events}
+		if cfg.Level != "" {
+			level, err := types.ParseLevel(cfg.Level)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s level: %s", name, err)
+			}
+			f.level = level
+			f.hasLevel = true
+		}
+		for _, pattern := range cfg.Events {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid %s event pattern %q: %s", name, pattern, err)
+			}
+		}
+		filters[name] = f
+	}
+	return filters, nil
+}
+
+// Allows - whether event passes the filter, level defaults to the given one
+func (f *Filter) Allows(event types.EventNotification, defaultLevel types.Level) bool {
+	level := defaultLevel
+	if f != nil && f.hasLevel {
+		level = f.level
+	}
+	if event.Level < level {
+		return false
+	}
+	if f == nil || len(f.events) == 0 {
+		return true
+	}
+	eventType := EventType(event)
+	for _, pattern := range f.events {
+		if ok, _ := path.Match(pattern, eventType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	filtersM sync.RWMutex
+	filters  Filters
+)
+
+// LoadFilters - loads filters from a file (usually mounted ConfigMap)
+func LoadFilters(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	f, err := ParseFilters(data)
+	if err != nil {
+		return err
+	}
+	SetFilters(f)
+	return nil
+}
+
+// SetFilters - sets filters used by senders and endpoints
+func SetFilters(f Filters) {
+	filtersM.Lock()
+	filters = f
+	filtersM.Unlock()
+}
+
+func getFilter(name string) *Filter {
+	filtersM.RLock()
+	defer filtersM.RUnlock()
+	return filters[name]
+}
+
+// EndpointAllowed - whether the event should be sent to the endpoint of the
+// sender, senders with several destinations call it for each of them
+func EndpointAllowed(sender, endpoint string, event types.EventNotification) bool {
+	f := getFilter(sender + ":" + endpoint)
+	if f == nil {
+		return true
+	}
+	return f.Allows(event, types.LevelDebug)
+}
+
+// EventType - event type used by filters, ie: "deployment.failed",
+// "release.updated" or "approval.approved"
+func EventType(event types.EventNotification) string {
+	update := func(kind string) string {
+		switch {
+		case event.Level >= types.LevelError:
+			return kind + ".failed"
+		case event.Level == types.LevelSuccess:
+			return kind + ".updated"
+		}
+		return kind + ".progress"
+	}
+
+	switch event.Type {
+	case types.NotificationPreDeploymentUpdate:
+		return "deployment.preparing"
+	case types.NotificationDeploymentUpdate:
+		return update("deployment")
+	case types.NotificationPreReleaseUpdate:
+		return "release.preparing"
+	case types.NotificationReleaseUpdate:
+		return update("release")
+	case types.NotificationDryRunUpdate:
+		return "deployment.dryrun"
+	case types.NotificationUpdateApproved:
+		return "approval.approved"
+	case types.NotificationUpdateRejected:
+		return "approval.rejected"
+	case types.NotificationRepositoryDiscovered:
+		return "repository.discovered"
+	case types.NotificationSignatureVerification:
+		return "signature.verification"
+	case types.NotificationVulnerabilityScan:
+		return "vulnerability.scan"
+	case types.NotificationSystemEvent:
+		return "system.event"
+	case types.NotificationDigest:
+		return "notification.digest"
+	case types.NotificationUpdateQueued:
+		return "update.queued"
+	case types.NotificationRolloutProgress:
+		return "rollout.progress"
+	case types.NotificationProvenanceVerification:
+		return "provenance.verification"
+	case types.NotificationTagDeleted:
+		return "tag.deleted"
+	case types.NotificationHygieneReport:
+		return "hygiene.report"
+	case types.PreProviderSubmitNotification, types.PostProviderSubmitNotification:
+		return "provider.submit"
+	}
+	return "unknown"
+}
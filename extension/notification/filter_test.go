@@ -0,0 +1,124 @@
+package notification
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestParseFilters(t *testing.T) {
+	filters, err := ParseFilters([]byte(`
+pagerduty:
+  level: error
+  events: ["deployment.failed", "release.*"]
+slack:#deployments:
+  events: ["deployment.*"]
+`))
+	if err != nil {
+		t.Fatalf("failed to parse filters: %s", err)
+	}
+
+	failed := types.EventNotification{Type: types.NotificationDeploymentUpdate, Level: types.LevelError}
+	updated := types.EventNotification{Type: types.NotificationDeploymentUpdate, Level: types.LevelSuccess}
+	releaseFailed := types.EventNotification{Type: types.NotificationReleaseUpdate, Level: types.LevelFatal}
+	approved := types.EventNotification{Type: types.NotificationUpdateApproved, Level: types.LevelInfo}
+
+	pd := filters["pagerduty"]
+	if !pd.Allows(failed, types.LevelInfo) || !pd.Allows(releaseFailed, types.LevelInfo) {
+		t.Errorf("expected pagerduty to allow failures")
+	}
+	if pd.Allows(updated, types.LevelDebug) {
+		t.Errorf("expected pagerduty to drop successful updates")
+	}
+
+	slack := filters["slack:#deployments"]
+	if !slack.Allows(updated, types.LevelInfo) || slack.Allows(approved, types.LevelInfo) {
+		t.Errorf("unexpected slack channel filtering")
+	}
+
+	// no filter, default level
+	var none *Filter
+	if none.Allows(types.EventNotification{Level: types.LevelDebug}, types.LevelInfo) || !none.Allows(approved, types.LevelInfo) {
+		t.Errorf("unexpected default filtering")
+	}
+
+	for _, invalid := range []string{"slack:\n  level: loud", "slack:\n  events: ['[']"} {
+		if _, err := ParseFilters([]byte(invalid)); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestSendFiltered(t *testing.T) {
+	SetFilters(Filters{
+		"filtered": &Filter{level: types.LevelError, hasLevel: true},
+		"verbose":  &Filter{level: types.LevelDebug, hasLevel: true},
+	})
+	defer SetFilters(nil)
+
+	sndr := New(context.Background())
+	sndr.Configure(&Config{
+		Level:    types.LevelInfo,
+		Attempts: 1,
+	})
+
+	filtered := &fakeSender{shouldConfigure: true}
+	verbose := &fakeSender{shouldConfigure: true}
+	RegisterSender("filtered", filtered)
+	RegisterSender("verbose", verbose)
+	defer sndr.UnregisterSender("filtered")
+	defer sndr.UnregisterSender("verbose")
+
+	sndr.Send(types.EventNotification{
+		Name:  "update",
+		Type:  types.NotificationPreDeploymentUpdate,
+		Level: types.LevelDebug,
+	})
+
+	if filtered.sent != nil {
+		t.Errorf("expected filtered sender to drop the event")
+	}
+	if verbose.sent == nil {
+		t.Errorf("expected verbose sender to get the event below global level")
+	}
+}
+
+func TestEndpointAllowed(t *testing.T) {
+	SetFilters(Filters{
+		"webhook:n8n": &Filter{events: []string{"approval.*"}},
+	})
+	defer SetFilters(nil)
+
+	approved := types.EventNotification{Type: types.NotificationUpdateApproved, Level: types.LevelInfo}
+	updated := types.EventNotification{Type: types.NotificationDeploymentUpdate, Level: types.LevelSuccess}
+	if !EndpointAllowed("webhook", "n8n", approved) || EndpointAllowed("webhook", "n8n", updated) {
+		t.Errorf("unexpected n8n endpoint filtering")
+	}
+	if !EndpointAllowed("webhook", "other", updated) {
+		t.Errorf("expected endpoint without filter to get all events")
+	}
+}
+
+type debugSender struct {
+	recordingSender
+}
+
+func (s *debugSender) Level() types.Level { return types.LevelDebug }
+
+func TestSendLevelSender(t *testing.T) {
+	sndr := New(context.Background())
+	sndr.Configure(&Config{
+		Level:    types.LevelInfo,
+		Attempts: 1,
+	})
+
+	ds := &debugSender{}
+	RegisterSender("debugsender", ds)
+	defer sndr.UnregisterSender("debugsender")
+
+	sndr.Send(types.EventNotification{Type: types.NotificationPreDeploymentUpdate, Level: types.LevelDebug})
+	if len(ds.sent) != 1 {
+		t.Errorf("expected sender level to be used instead of the global level")
+	}
+}
@@ -0,0 +1,239 @@
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 10 * time.Second
+
+const defaultAPIURL = "https://api.github.com"
+
+// descriptionLimit - maximum length of deployment status descriptions
+const descriptionLimit = 140
+
+// deployment statuses
+const (
+	stateInProgress = "in_progress"
+	stateSuccess    = "success"
+	stateFailure    = "failure"
+)
+
+type sender struct {
+	apiURL string
+	token  string
+	client *http.Client
+
+	mu sync.Mutex
+	// deployments - GitHub deployments of updates in progress
+	deployments map[string]*deployment
+}
+
+type deployment struct {
+	id    int64
+	state string
+}
+
+func init() {
+	notification.RegisterSender("github", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	s.token = os.Getenv(constants.EnvGithubToken)
+	if s.token == "" {
+		return false, nil
+	}
+
+	s.apiURL = defaultAPIURL
+	if apiURL := os.Getenv(constants.EnvGithubAPIURL); apiURL != "" {
+		if _, err := url.ParseRequestURI(apiURL); err != nil {
+			return false, fmt.Errorf("could not parse GitHub API URL: %s", err)
+		}
+		s.apiURL = strings.TrimSuffix(apiURL, "/")
+	}
+
+	s.deployments = make(map[string]*deployment)
+	s.client = &http.Client{
+		Transport: http.DefaultTransport,
+		Timeout:   timeout,
+	}
+
+	log.WithFields(log.Fields{
+		"name": "github",
+		"api":  s.apiURL,
+	}).Info("extension.notification.github: sender configured")
+
+	return true, nil
+}
+
+// Level - deployments are created when updates are being prepared, these
+// notifications are debug level
+func (s *sender) Level() types.Level {
+	return types.LevelDebug
+}
+
+func environment(event types.EventNotification) string {
+	env := event.Metadata["githubEnvironment"]
+	if env == "" {
+		env = event.Metadata["namespace"]
+		if cluster := event.Metadata["cluster"]; cluster != "" {
+			env = cluster + "/" + env
+		}
+	}
+	return env
+}
+
+// Send - creates deployment when the update is being prepared and sets its
+// status as the rollout proceeds. Deployments are created on the first
+// notification of the update, ie: when keel was restarted during the rollout.
+func (s *sender) Send(event types.EventNotification) error {
+	repo := event.Metadata["githubRepository"]
+	version := event.Metadata["newVersion"]
+	if repo == "" || version == "" {
+		return nil
+	}
+
+	var state string
+	switch notification.EventType(event) {
+	case "deployment.preparing", "deployment.progress":
+		state = stateInProgress
+	case "deployment.updated":
+		state = stateSuccess
+	case "deployment.failed":
+		state = stateFailure
+	default:
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := event.Identifier + "@" + version
+	d, ok := s.deployments[key]
+	if !ok {
+		id, err := s.createDeployment(repo, version, event)
+		if err != nil {
+			return err
+		}
+		d = &deployment{id: id}
+		s.deployments[key] = d
+	}
+
+	if d.state != state {
+		if err := s.createStatus(repo, d.id, state, event); err != nil {
+			return err
+		}
+		d.state = state
+	}
+
+	if state != stateInProgress {
+		delete(s.deployments, key)
+	}
+	return nil
+}
+
+type deploymentRequest struct {
+	Ref              string            `json:"ref"`
+	Environment      string            `json:"environment"`
+	Description      string            `json:"description"`
+	AutoMerge        bool              `json:"auto_merge"`
+	RequiredContexts []string          `json:"required_contexts"`
+	Payload          map[string]string `json:"payload"`
+}
+
+type deploymentResponse struct {
+	ID int64 `json:"id"`
+}
+
+type statusRequest struct {
+	State       string `json:"state"`
+	Description string `json:"description"`
+	Environment string `json:"environment"`
+}
+
+// createDeployment - deployment of the new version ref, commit statuses are
+// not required since the image is already built
+func (s *sender) createDeployment(repo, version string, event types.EventNotification) (int64, error) {
+	var resp deploymentResponse
+	err := s.post(fmt.Sprintf("/repos/%s/deployments", repo), deploymentRequest{
+		Ref:              version,
+		Environment:      environment(event),
+		Description:      truncate(fmt.Sprintf("keel update of %s", event.Identifier)),
+		RequiredContexts: []string{},
+		Payload: map[string]string{
+			"identifier": event.Identifier,
+			"namespace":  event.Metadata["namespace"],
+			"name":       event.Metadata["name"],
+			"images":     event.Metadata["images"],
+		},
+	}, &resp)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create GitHub deployment: %s", err)
+	}
+	return resp.ID, nil
+}
+
+func (s *sender) createStatus(repo string, id int64, state string, event types.EventNotification) error {
+	err := s.post(fmt.Sprintf("/repos/%s/deployments/%d/statuses", repo, id), statusRequest{
+		State:       state,
+		Description: truncate(event.Message),
+		Environment: environment(event),
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to set GitHub deployment status: %s", err)
+	}
+	return nil
+}
+
+func (s *sender) post(path string, body, result interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.apiURL+path, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		var ghErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&ghErr)
+		return fmt.Errorf("got status %d, expected 201: %s", resp.StatusCode, ghErr.Message)
+	}
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
+
+func truncate(s string) string {
+	r := []rune(s)
+	if len(r) > descriptionLimit {
+		return string(r[:descriptionLimit-1]) + "…"
+	}
+	return s
+}
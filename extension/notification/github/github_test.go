@@ -0,0 +1,85 @@
+package github
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestSend(t *testing.T) {
+	type call struct {
+		path string
+		body map[string]interface{}
+	}
+	var calls []call
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("missing token")
+		}
+		var body map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&body)
+		calls = append(calls, call{path: req.URL.Path, body: body})
+		resp.WriteHeader(http.StatusCreated)
+		resp.Write([]byte(`{"id": 42}`))
+	}))
+	defer ts.Close()
+
+	s := &sender{
+		apiURL:      ts.URL,
+		token:       "token",
+		client:      &http.Client{},
+		deployments: make(map[string]*deployment),
+	}
+
+	event := types.EventNotification{
+		Identifier: "deployment/default/wd",
+		Message:    "Preparing to update deployment default/wd 0.0.14->0.0.15",
+		Type:       types.NotificationPreDeploymentUpdate,
+		Level:      types.LevelDebug,
+		Metadata: map[string]string{
+			"namespace":        "default",
+			"name":             "wd",
+			"newVersion":       "0.0.15",
+			"githubRepository": "keel-hq/webhook-demo",
+		},
+	}
+	for _, e := range []struct {
+		typ   types.Notification
+		level types.Level
+	}{
+		{types.NotificationPreDeploymentUpdate, types.LevelDebug},
+		{types.NotificationDeploymentUpdate, types.LevelInfo},
+		{types.NotificationDeploymentUpdate, types.LevelSuccess},
+	} {
+		event.Type, event.Level = e.typ, e.level
+		if err := s.Send(event); err != nil {
+			t.Fatalf("failed to send: %s", err)
+		}
+	}
+
+	if len(calls) != 3 {
+		t.Fatalf("expected deployment and 2 statuses, got: %v", calls)
+	}
+	if calls[0].path != "/repos/keel-hq/webhook-demo/deployments" || calls[0].body["ref"] != "0.0.15" || calls[0].body["environment"] != "default" {
+		t.Errorf("unexpected deployment: %v", calls[0])
+	}
+	if calls[1].path != "/repos/keel-hq/webhook-demo/deployments/42/statuses" || calls[1].body["state"] != stateInProgress {
+		t.Errorf("unexpected status: %v", calls[1])
+	}
+	if calls[2].body["state"] != stateSuccess {
+		t.Errorf("unexpected status: %v", calls[2])
+	}
+	if len(s.deployments) != 0 {
+		t.Errorf("expected finished deployment to be forgotten")
+	}
+
+	// resources without repository are ignored
+	delete(event.Metadata, "githubRepository")
+	s.Send(event)
+	if len(calls) != 3 {
+		t.Errorf("unexpected call for resource without repository")
+	}
+}
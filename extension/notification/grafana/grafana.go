@@ -0,0 +1,143 @@
+package grafana
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 5 * time.Second
+
+type sender struct {
+	endpoint     string
+	apiKey       string
+	orgID        string
+	dashboardUID string
+	panelID      int
+	tags         []string
+	client       *http.Client
+}
+
+func init() {
+	notification.RegisterSender("grafana", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	grafanaURL := os.Getenv(constants.EnvGrafanaURL)
+	if grafanaURL == "" {
+		return false, nil
+	}
+	if _, err := url.ParseRequestURI(grafanaURL); err != nil {
+		return false, fmt.Errorf("could not parse Grafana URL: %s", err)
+	}
+	s.endpoint = strings.TrimSuffix(grafanaURL, "/") + "/api/annotations"
+
+	s.apiKey = os.Getenv(constants.EnvGrafanaAPIKey)
+	s.orgID = os.Getenv(constants.EnvGrafanaOrgID)
+	s.dashboardUID = os.Getenv(constants.EnvGrafanaDashboardUID)
+	if panel := os.Getenv(constants.EnvGrafanaPanelID); panel != "" {
+		id, err := strconv.Atoi(panel)
+		if err != nil {
+			return false, fmt.Errorf("invalid Grafana panel ID %q: %s", panel, err)
+		}
+		s.panelID = id
+	}
+
+	s.tags = []string{"keel"}
+	for _, tag := range strings.Split(os.Getenv(constants.EnvGrafanaTags), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			s.tags = append(s.tags, tag)
+		}
+	}
+
+	s.client = &http.Client{
+		Transport: http.DefaultTransport,
+		Timeout:   timeout,
+	}
+
+	log.WithFields(log.Fields{
+		"name":      "grafana",
+		"endpoint":  s.endpoint,
+		"dashboard": s.dashboardUID,
+	}).Info("extension.notification.grafana: sender configured")
+
+	return true, nil
+}
+
+type annotation struct {
+	DashboardUID string   `json:"dashboardUID,omitempty"`
+	PanelID      int      `json:"panelId,omitempty"`
+	Time         int64    `json:"time"`
+	Tags         []string `json:"tags"`
+	Text         string   `json:"text"`
+}
+
+// Send - annotates successful updates, annotations without dashboard are
+// organization wide and can be shown on any dashboard by tags
+func (s *sender) Send(event types.EventNotification) error {
+	switch notification.EventType(event) {
+	case "deployment.updated", "release.updated":
+	default:
+		return nil
+	}
+
+	tags := append([]string{}, s.tags...)
+	for _, key := range []string{"namespace", "name", "cluster"} {
+		if v := event.Metadata[key]; v != "" {
+			tags = append(tags, key+":"+v)
+		}
+	}
+	if v := event.Metadata["newVersion"]; v != "" {
+		tags = append(tags, "version:"+v)
+	}
+
+	createdAt := event.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	body, err := json.Marshal(annotation{
+		DashboardUID: s.dashboardUID,
+		PanelID:      s.panelID,
+		Time:         createdAt.UnixNano() / int64(time.Millisecond),
+		Tags:         tags,
+		Text:         event.Message,
+	})
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+	if s.orgID != "" {
+		req.Header.Set("X-Grafana-Org-Id", s.orgID)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("got status %d, expected 200", resp.StatusCode)
+	}
+	return nil
+}
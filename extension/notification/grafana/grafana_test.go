@@ -0,0 +1,70 @@
+package grafana
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestSend(t *testing.T) {
+	var (
+		got     []annotation
+		headers http.Header
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		var a annotation
+		json.NewDecoder(req.Body).Decode(&a)
+		got = append(got, a)
+		headers = req.Header
+	}))
+	defer ts.Close()
+
+	s := &sender{
+		endpoint:     ts.URL,
+		apiKey:       "token",
+		orgID:        "2",
+		dashboardUID: "abc",
+		tags:         []string{"keel", "prod"},
+		client:       &http.Client{},
+	}
+
+	event := types.EventNotification{
+		Message:   "Successfully updated deployment default/wd 0.0.14->0.0.15",
+		CreatedAt: time.Unix(1538395200, 0),
+		Type:      types.NotificationDeploymentUpdate,
+		Level:     types.LevelSuccess,
+		Metadata:  map[string]string{"namespace": "default", "name": "wd", "newVersion": "0.0.15"},
+	}
+	if err := s.Send(event); err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+
+	// only successful updates are annotated
+	event.Level = types.LevelError
+	s.Send(event)
+	s.Send(types.EventNotification{Type: types.NotificationPreDeploymentUpdate, Level: types.LevelSuccess})
+
+	if len(got) != 1 {
+		t.Fatalf("expected 1 annotation, got %d", len(got))
+	}
+	a := got[0]
+	if a.DashboardUID != "abc" || a.Time != 1538395200000 || a.Text != event.Message {
+		t.Errorf("unexpected annotation: %+v", a)
+	}
+	expectedTags := []string{"keel", "prod", "namespace:default", "name:wd", "version:0.0.15"}
+	if len(a.Tags) != len(expectedTags) {
+		t.Fatalf("unexpected tags: %v", a.Tags)
+	}
+	for i := range expectedTags {
+		if a.Tags[i] != expectedTags[i] {
+			t.Errorf("expected tag %s, got %s", expectedTags[i], a.Tags[i])
+		}
+	}
+	if headers.Get("Authorization") != "Bearer token" || headers.Get("X-Grafana-Org-Id") != "2" {
+		t.Errorf("unexpected headers: %v", headers)
+	}
+}
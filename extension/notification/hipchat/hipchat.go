@@ -12,6 +12,7 @@ import (
 	"github.com/keel-hq/keel/constants"
 	"github.com/keel-hq/keel/extension/notification"
 	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/templates"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -62,8 +63,16 @@ func (s *sender) Configure(config *notification.Config) (bool, error) {
 	return true, nil
 }
 
+// Routable - keel.sh/notify routes are rooms, ie: hipchat:payments
+func (s *sender) Routable() bool {
+	return true
+}
+
 func (s *sender) Send(event types.EventNotification) error {
 	msg := fmt.Sprintf("<b>%s</b><br>%s", event.Type.String(), event.Message)
+	if tmpl, ok := templates.RenderMessage(templates.NotificationMessageName(event.Type), event); ok {
+		msg = strings.Replace(tmpl.String(), "\n", "<br>", -1)
+	}
 
 	notification := &hipchat.NotificationRequest{
 		Color:   getHipchatColor(event.Level.String()),
@@ -0,0 +1,201 @@
+package jira
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/templates"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 10 * time.Second
+
+const defaultIssueType = "Task"
+
+// default summary and description templates, template data is types.EventNotification
+const (
+	defaultSummaryTemplate     = `Update {{ .Identifier }} {{ index .Metadata "previousVersion" }} -> {{ index .Metadata "newVersion" }}`
+	defaultDescriptionTemplate = `{{ .Message }}
+{{- with index .Metadata "images" }}
+
+Images: {{ . }}{{ end }}
+{{- with index .Metadata "approvers" }}
+Approved by: {{ . }}{{ end }}
+{{- with index .Metadata "trigger" }}
+Trigger: {{ . }}{{ end }}`
+)
+
+type sender struct {
+	endpoint    string
+	username    string
+	token       string
+	project     string
+	issueType   string
+	labels      []string
+	namespaces  map[string]bool
+	summary     *template.Template
+	description *template.Template
+	client      *http.Client
+}
+
+func init() {
+	notification.RegisterSender("jira", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	jiraURL := os.Getenv(constants.EnvJiraURL)
+	if jiraURL == "" {
+		return false, nil
+	}
+	if _, err := url.ParseRequestURI(jiraURL); err != nil {
+		return false, fmt.Errorf("could not parse Jira URL: %s", err)
+	}
+	s.endpoint = strings.TrimSuffix(jiraURL, "/") + "/rest/api/2"
+
+	s.username = os.Getenv(constants.EnvJiraUsername)
+	s.token = os.Getenv(constants.EnvJiraAPIToken)
+	s.project = os.Getenv(constants.EnvJiraProject)
+	if s.project == "" {
+		return false, fmt.Errorf("%s is required", constants.EnvJiraProject)
+	}
+	s.issueType = os.Getenv(constants.EnvJiraIssueType)
+	if s.issueType == "" {
+		s.issueType = defaultIssueType
+	}
+	s.labels = split(os.Getenv(constants.EnvJiraLabels))
+	if namespaces := split(os.Getenv(constants.EnvJiraNamespaces)); len(namespaces) > 0 {
+		s.namespaces = make(map[string]bool, len(namespaces))
+		for _, ns := range namespaces {
+			s.namespaces[ns] = true
+		}
+	}
+
+	var err error
+	if s.summary, err = parseTemplate("summary", os.Getenv(constants.EnvJiraSummaryTemplate), defaultSummaryTemplate); err != nil {
+		return false, err
+	}
+	if s.description, err = parseTemplate("description", os.Getenv(constants.EnvJiraDescriptionTemplate), defaultDescriptionTemplate); err != nil {
+		return false, err
+	}
+
+	s.client = &http.Client{
+		Transport: http.DefaultTransport,
+		Timeout:   timeout,
+	}
+
+	log.WithFields(log.Fields{
+		"name":    "jira",
+		"url":     jiraURL,
+		"project": s.project,
+	}).Info("extension.notification.jira: sender configured")
+
+	return true, nil
+}
+
+func split(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func parseTemplate(name, value, defaultValue string) (*template.Template, error) {
+	if value == "" {
+		value = defaultValue
+	}
+	tmpl, err := templates.NewParse("jira."+name, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Jira %s template: %s", name, err)
+	}
+	return tmpl, nil
+}
+
+func render(tmpl *template.Template, event types.EventNotification) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, event); err != nil {
+		return "", fmt.Errorf("failed to render Jira %s: %s", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+// Send - records successful updates of the configured namespaces (all when not
+// set), resources with keel.sh/jiraIssue annotation get a comment on the issue
+func (s *sender) Send(event types.EventNotification) error {
+	switch notification.EventType(event) {
+	case "deployment.updated", "release.updated":
+	default:
+		return nil
+	}
+	if s.namespaces != nil && !s.namespaces[event.Metadata["namespace"]] {
+		return nil
+	}
+
+	description, err := render(s.description, event)
+	if err != nil {
+		return err
+	}
+
+	if issue := event.Metadata["jiraIssue"]; issue != "" {
+		return s.post(fmt.Sprintf("/issue/%s/comment", url.PathEscape(issue)), map[string]string{
+			"body": description,
+		})
+	}
+
+	summary, err := render(s.summary, event)
+	if err != nil {
+		return err
+	}
+	fields := map[string]interface{}{
+		"project":     map[string]string{"key": s.project},
+		"issuetype":   map[string]string{"name": s.issueType},
+		"summary":     strings.TrimSpace(summary),
+		"description": description,
+	}
+	if len(s.labels) > 0 {
+		fields["labels"] = s.labels
+	}
+	return s.post("/issue", map[string]interface{}{"fields": fields})
+}
+
+func (s *sender) post(path string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint+path, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Jira Cloud uses email and API token, Jira Server/Data Center personal access tokens
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.token)
+	} else if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("got status %d, expected 201", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package jira
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+)
+
+type request struct {
+	path string
+	user string
+	body map[string]interface{}
+}
+
+func newTestSender(t *testing.T, endpoint string) *sender {
+	s := &sender{
+		endpoint:   endpoint,
+		username:   "ops@example.com",
+		token:      "token",
+		project:    "OPS",
+		issueType:  "Change",
+		labels:     []string{"keel"},
+		namespaces: map[string]bool{"production": true},
+		client:     &http.Client{},
+	}
+	var err error
+	if s.summary, err = parseTemplate("summary", "", defaultSummaryTemplate); err != nil {
+		t.Fatal(err)
+	}
+	if s.description, err = parseTemplate("description", "", defaultDescriptionTemplate); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSend(t *testing.T) {
+	var requests []request
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		user, _, _ := req.BasicAuth()
+		var body map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&body)
+		requests = append(requests, request{path: req.URL.Path, user: user, body: body})
+		resp.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	s := newTestSender(t, ts.URL)
+
+	event := types.EventNotification{
+		Identifier: "deployment/production/wd",
+		Message:    "Successfully updated deployment production/wd 0.0.14->0.0.15",
+		Type:       types.NotificationDeploymentUpdate,
+		Level:      types.LevelSuccess,
+		Metadata: map[string]string{
+			"namespace":       "production",
+			"previousVersion": "0.0.14",
+			"newVersion":      "0.0.15",
+			"approvers":       "alice,bob",
+		},
+	}
+	if err := s.Send(event); err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+
+	// other namespaces and failed updates are ignored
+	staging := event
+	staging.Metadata = map[string]string{"namespace": "staging"}
+	s.Send(staging)
+	failed := event
+	failed.Level = types.LevelError
+	s.Send(failed)
+
+	// annotated resources get comments
+	commented := event
+	commented.Metadata = map[string]string{"namespace": "production", "jiraIssue": "OPS-7"}
+	if err := s.Send(commented); err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected issue and comment, got: %v", requests)
+	}
+
+	issue := requests[0]
+	if issue.path != "/issue" || issue.user != "ops@example.com" {
+		t.Errorf("unexpected issue request: %+v", issue)
+	}
+	fields := issue.body["fields"].(map[string]interface{})
+	if fields["summary"] != "Update deployment/production/wd 0.0.14 -> 0.0.15" {
+		t.Errorf("unexpected summary: %v", fields["summary"])
+	}
+	if !strings.Contains(fields["description"].(string), "Approved by: alice,bob") {
+		t.Errorf("expected approvers in description: %v", fields["description"])
+	}
+	if fields["issuetype"].(map[string]interface{})["name"] != "Change" {
+		t.Errorf("unexpected issue type: %v", fields["issuetype"])
+	}
+
+	if requests[1].path != "/issue/OPS-7/comment" || requests[1].body["body"] == "" {
+		t.Errorf("unexpected comment request: %+v", requests[1])
+	}
+}
@@ -0,0 +1,182 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/templates"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 10 * time.Second
+
+// messageWriter - writes messages to the topic, implemented by kafka.Writer
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+type sender struct {
+	writer messageWriter
+	key    *template.Template
+}
+
+func init() {
+	notification.RegisterSender("kafka", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	brokers := os.Getenv(constants.EnvKafkaBrokers)
+	topic := os.Getenv(constants.EnvKafkaTopic)
+	if brokers == "" || topic == "" {
+		return false, nil
+	}
+
+	var addrs []string
+	for _, b := range strings.Split(brokers, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			if _, _, err := net.SplitHostPort(b); err != nil {
+				return false, fmt.Errorf("invalid Kafka broker %q, expected host:port", b)
+			}
+			addrs = append(addrs, b)
+		}
+	}
+	if len(addrs) == 0 {
+		return false, fmt.Errorf("no Kafka brokers in %s", constants.EnvKafkaBrokers)
+	}
+
+	dialer := &kafka.Dialer{
+		ClientID:  "keel",
+		Timeout:   timeout,
+		DualStack: true,
+	}
+
+	tlsEnabled := os.Getenv(constants.EnvKafkaTLS)
+	if tlsEnabled == "1" || tlsEnabled == "true" {
+		tlsConfig, err := newTLSConfig()
+		if err != nil {
+			return false, err
+		}
+		dialer.TLS = tlsConfig
+	}
+
+	if username := os.Getenv(constants.EnvKafkaSASLUsername); username != "" {
+		dialer.SASLMechanism = plain.Mechanism{
+			Username: username,
+			Password: os.Getenv(constants.EnvKafkaSASLPassword),
+		}
+	}
+
+	if keyTemplate := os.Getenv(constants.EnvKafkaKeyTemplate); keyTemplate != "" {
+		tmpl, err := templates.NewParse("kafka.key", keyTemplate)
+		if err != nil {
+			return false, fmt.Errorf("invalid Kafka key template: %s", err)
+		}
+		s.key = tmpl
+	}
+
+	s.writer = kafka.NewWriter(kafka.WriterConfig{
+		Brokers: addrs,
+		Topic:   topic,
+		Dialer:  dialer,
+		// same partitioning as the Java client, so events of a resource end up
+		// in the same partition as records keyed by other producers
+		Balancer: &kafka.Murmur2Balancer{Consistent: true},
+		// notifications are sent one by one, waiting for a batch would only
+		// delay them
+		BatchSize:    1,
+		RequiredAcks: -1,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+		MaxAttempts:  3,
+	})
+
+	log.WithFields(log.Fields{
+		"name":    "kafka",
+		"brokers": addrs,
+		"topic":   topic,
+		"tls":     dialer.TLS != nil,
+		"sasl":    dialer.SASLMechanism != nil,
+	}).Info("extension.notification.kafka: sender configured")
+
+	return true, nil
+}
+
+func newTLSConfig() (*tls.Config, error) {
+	cfg := &tls.Config{}
+	if path := os.Getenv(constants.EnvKafkaTLSCA); path != "" {
+		ca, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Kafka CA: %s", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in Kafka CA %s", path)
+		}
+	}
+	cert, key := os.Getenv(constants.EnvKafkaTLSCert), os.Getenv(constants.EnvKafkaTLSKey)
+	if cert != "" || key != "" {
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Kafka client certificate: %s", err)
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+	return cfg, nil
+}
+
+// eventKey - record key, namespace/name of the resource by default so events of
+// a resource are kept in order
+func (s *sender) eventKey(event types.EventNotification) (string, error) {
+	if s.key != nil {
+		var buf bytes.Buffer
+		if err := s.key.Execute(&buf, event); err != nil {
+			return "", fmt.Errorf("failed to render Kafka key: %s", err)
+		}
+		return buf.String(), nil
+	}
+	if event.Metadata["namespace"] != "" && event.Metadata["name"] != "" {
+		return event.Metadata["namespace"] + "/" + event.Metadata["name"], nil
+	}
+	return event.Identifier, nil
+}
+
+func (s *sender) Send(event types.EventNotification) error {
+	key, err := s.eventKey(event)
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return s.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(key),
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: "type", Value: []byte(event.Type.String())},
+			{Key: "level", Value: []byte(event.Level.String())},
+		},
+		Time: event.CreatedAt,
+	})
+}
@@ -0,0 +1,94 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/templates"
+	"github.com/segmentio/kafka-go"
+)
+
+type fakeWriter struct {
+	messages []kafka.Message
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func TestSend(t *testing.T) {
+	w := &fakeWriter{}
+	s := &sender{writer: w}
+
+	createdAt := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	err := s.Send(types.EventNotification{
+		Name:       "update resource",
+		Identifier: "deployment/default/wd",
+		Message:    "Successfully updated deployment default/wd 0.0.14->0.0.15",
+		CreatedAt:  createdAt,
+		Type:       types.NotificationDeploymentUpdate,
+		Level:      types.LevelSuccess,
+		Metadata:   map[string]string{"namespace": "default", "name": "wd"},
+	})
+	if err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+
+	if len(w.messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(w.messages))
+	}
+	m := w.messages[0]
+	if string(m.Key) != "default/wd" || !m.Time.Equal(createdAt) {
+		t.Errorf("unexpected message: %+v", m)
+	}
+	var event types.EventNotification
+	if err := json.Unmarshal(m.Value, &event); err != nil {
+		t.Fatalf("failed to decode event: %s", err)
+	}
+	if event.Identifier != "deployment/default/wd" || event.Level != types.LevelSuccess {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if len(m.Headers) != 2 || m.Headers[0].Key != "type" || string(m.Headers[1].Value) != "success" {
+		t.Errorf("unexpected headers: %+v", m.Headers)
+	}
+}
+
+func TestSendKeyTemplate(t *testing.T) {
+	tmpl, err := templates.NewParse("kafka.key", "{{ .Type }}:{{ .Identifier }}")
+	if err != nil {
+		t.Fatalf("failed to parse template: %s", err)
+	}
+	w := &fakeWriter{}
+	s := &sender{writer: w, key: tmpl}
+
+	err = s.Send(types.EventNotification{
+		Identifier: "deployment/default/wd",
+		Type:       types.NotificationDeploymentUpdate,
+	})
+	if err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+	if len(w.messages) != 1 || string(w.messages[0].Key) != "deployment update:deployment/default/wd" {
+		t.Errorf("unexpected messages: %+v", w.messages)
+	}
+}
+
+func TestConfigureInvalidBroker(t *testing.T) {
+	os.Setenv(constants.EnvKafkaBrokers, "localhost")
+	os.Setenv(constants.EnvKafkaTopic, "keel-events")
+	defer os.Unsetenv(constants.EnvKafkaBrokers)
+	defer os.Unsetenv(constants.EnvKafkaTopic)
+
+	s := &sender{}
+	enabled, err := s.Configure(&notification.Config{})
+	if err == nil || enabled {
+		t.Errorf("expected invalid broker to be rejected")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/keel-hq/keel/constants"
 	"github.com/keel-hq/keel/extension/notification"
 	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/templates"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -88,11 +89,16 @@ type notificationEnvelope struct {
 }
 
 func (s *sender) Send(event types.EventNotification) error {
+	text := fmt.Sprintf("#### %s \n %s", event.Type.String(), event.Message)
+	if msg, ok := templates.RenderMessage(templates.NotificationMessageName(event.Type), event); ok {
+		text = msg.String()
+	}
+
 	// Marshal notification.
 	jsonNotification, err := json.Marshal(notificationEnvelope{
 		IconURL:  constants.KeelLogoURL,
 		Username: s.name,
-		Text:     fmt.Sprintf("#### %s \n %s", event.Type.String(), event.Message),
+		Text:     text,
 	})
 	if err != nil {
 		return fmt.Errorf("could not marshal: %s", err)
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/keel-hq/keel/internal/bus"
 	"github.com/keel-hq/keel/types"
 	"github.com/keel-hq/keel/util/stopper"
 	"github.com/keel-hq/keel/util/timeutil"
@@ -23,9 +24,20 @@ const (
 	logNotiName   = "notification name"
 )
 
+// TopicNotification - notifications are published to it when the event bus is
+// set, see SetEventBus
+var TopicNotification = bus.NewTopic("notification", (*types.EventNotification)(nil))
+
+// notificationBuffer - notifications buffered for delivery, publishers wait
+// once senders fall this far behind
+const notificationBuffer = 100
+
 var (
 	sendersM sync.RWMutex
 	senders  = make(map[string]Sender)
+	// names - all registered sender names, unconfigured senders included, used
+	// to recognize sender routes
+	names = make(map[string]bool)
 )
 
 // Config is the configuration for the Notifier service and its registered
@@ -33,7 +45,12 @@ var (
 type Config struct {
 	Attempts int
 	Level    types.Level
-	Params   map[string]interface{} `yaml:",inline"`
+	// DigestInterval - when set, low priority notifications of digest senders
+	// are batched and sent as a single digest per channel every interval
+	DigestInterval time.Duration
+	// DigestSenders - senders batched in digest mode, defaults to DefaultDigestSenders
+	DigestSenders []string
+	Params        map[string]interface{} `yaml:",inline"`
 }
 
 // Sender represents anything that can transmit notifications.
@@ -46,6 +63,26 @@ type Sender interface {
 	Send(event types.EventNotification) error
 }
 
+// LevelSender - sender with its own minimum level which is used instead of the
+// global level, ie: senders tracking update progress need debug notifications.
+// Filters still take precedence.
+type LevelSender interface {
+	Sender
+	Level() types.Level
+}
+
+// InternalSender - sender that doesn't deliver notifications outside of keel
+// (ie: audit log), test notifications skip it
+type InternalSender interface {
+	Sender
+	Internal() bool
+}
+
+// Tester - sends test notifications, see DefaultNotificationSender.Test
+type Tester interface {
+	Test(event types.EventNotification) map[string]error
+}
+
 // RegisterSender makes a Sender available by the provided name.
 //
 // If called twice with the same name, the name is blank, or if the provided
@@ -71,22 +108,60 @@ func RegisterSender(name string, s Sender) {
 	}).Debug("extension.notification: sender registered")
 
 	senders[name] = s
+	names[name] = true
+}
+
+// knownSender - whether sender with the name was ever registered
+func knownSender(name string) bool {
+	sendersM.RLock()
+	defer sendersM.RUnlock()
+	return names[name]
 }
 
 // DefaultNotificationSender - default notification sender, manages configuration
 type DefaultNotificationSender struct {
 	config  *Config
 	stopper *stopper.Stopper
-	level   types.Level
+	digest  *digest
+
+	// levelM - level can be changed while notifications are sent
+	levelM sync.RWMutex
+
+	ctx context.Context
+	// bus is optional, notifications are delivered through it, see SetEventBus
+	bus *bus.Bus
+	// pending - published notifications that weren't delivered yet
+	pending sync.WaitGroup
 }
 
 // New - create new sender
 func New(ctx context.Context) *DefaultNotificationSender {
 	return &DefaultNotificationSender{
 		stopper: stopper.NewStopper(ctx),
+		ctx:     ctx,
 	}
 }
 
+// SetEventBus - notifications are published to the bus and delivered to senders
+// by its subscriber, other modules (ie: audit) can subscribe to them too. Without
+// the bus notifications are delivered by Send.
+func (m *DefaultNotificationSender) SetEventBus(b *bus.Bus) {
+	sub := b.Subscribe(m.ctx, TopicNotification, bus.SubscribeOpts{
+		Name:   "notification.sender",
+		Buffer: notificationBuffer,
+		Policy: bus.Block,
+	})
+	go func() {
+		for msg := range sub.C {
+			if err := m.deliver(*msg.(*types.EventNotification)); err != nil {
+				log.WithError(err).Error("notification: failed to deliver notification")
+			}
+			m.pending.Done()
+		}
+	}()
+	m.bus = b
+}
+
 // Configure - configure is used to register multiple notification senders
 func (m *DefaultNotificationSender) Configure(config *Config) (bool, error) {
 	m.config = config
@@ -102,9 +177,81 @@ func (m *DefaultNotificationSender) Configure(config *Config) (bool, error) {
 		}
 	}
 
+	if config.DigestInterval > 0 {
+		senders := config.DigestSenders
+		if len(senders) == 0 {
+			senders = DefaultDigestSenders
+		}
+		m.digest = newDigest(senders)
+		go m.sendDigests(config.DigestInterval)
+	}
+
 	return true, nil
 }
 
+// Test - sends the notification through every configured sender straight
+// away, ie: to verify channels and tokens. Levels, filters, digests and
+// retries are skipped, results are keyed by sender name.
+func (m *DefaultNotificationSender) Test(event types.EventNotification) map[string]error {
+	results := make(map[string]error)
+	for senderName, sender := range m.Senders() {
+		if is, ok := sender.(InternalSender); ok && is.Internal() {
+			continue
+		}
+		results[senderName] = sender.Send(event)
+	}
+	return results
+}
+
+// sendDigests - sends buffered notifications every interval until stopped
+func (m *DefaultNotificationSender) sendDigests(interval time.Duration) {
+	for m.stopper.Sleep(interval) {
+		m.flushDigests(interval)
+	}
+}
+
+// Flush - delivers published notifications and sends buffered digest
+// notifications right away, ie: before shutdown
+func (m *DefaultNotificationSender) Flush() {
+	if m.bus != nil {
+		m.pending.Wait()
+	}
+	if m.digest == nil {
+		return
+	}
+	m.flushDigests(m.config.DigestInterval)
+}
+
+func (m *DefaultNotificationSender) flushDigests(interval time.Duration) {
+	for senderName, events := range m.digest.flush(interval) {
+		sendersM.RLock()
+		sender, ok := senders[senderName]
+		sendersM.RUnlock()
+		if !ok {
+			continue
+		}
+		for _, event := range events {
+			if err := m.sendWith(senderName, sender, event); err != nil {
+				log.WithError(err).WithField(logSenderName, senderName).Error("could not send notification digest")
+			}
+		}
+	}
+}
+
+// SetLevel - changes minimum level of senders without their own level
+func (m *DefaultNotificationSender) SetLevel(level types.Level) {
+	m.levelM.Lock()
+	m.config.Level = level
+	m.levelM.Unlock()
+}
+
+// Level - minimum level of senders without their own level
+func (m *DefaultNotificationSender) Level() types.Level {
+	m.levelM.RLock()
+	defer m.levelM.RUnlock()
+	return m.config.Level
+}
+
 // Senders returns the list of the registered Senders.
 func (m *DefaultNotificationSender) Senders() map[string]Sender {
 	sendersM.RLock()
@@ -118,61 +265,90 @@ func (m *DefaultNotificationSender) Senders() map[string]Sender {
 	return ret
 }
 
-// Send - send notifications through all configured senders
+// Send - send notifications through all configured senders, notifications are
+// published to the event bus when it's set
 func (m *DefaultNotificationSender) Send(event types.EventNotification) error {
-	if event.Level < m.config.Level {
-		return nil
+	if m.bus == nil || m.ctx.Err() != nil {
+		return m.deliver(event)
 	}
+	m.pending.Add(1)
+	return m.bus.Publish(TopicNotification, &event)
+}
+
+// deliver - sends notification through all configured senders
+func (m *DefaultNotificationSender) deliver(event types.EventNotification) error {
+	routes := parseRoutes(event.Channels)
 
 	sendersM.RLock()
 	defer sendersM.RUnlock()
 
 	for senderName, sender := range m.Senders() {
-		// TODO: move this into goroutine if we have enough senders
-		var attempts int
-		var backOff time.Duration
-		for {
-			// Max attempts exceeded.
-			if attempts >= m.config.Attempts {
-				log.WithFields(log.Fields{
-					logNotiName:    event.Name,
-					logSenderName:  senderName,
-					"max attempts": m.config.Attempts,
-				}).Info("giving up on sending notification : max attempts exceeded")
-				return fmt.Errorf("failed to send notification, max attempts (%d) reached", m.config.Attempts)
-			}
-
-			// Backoff
-			if backOff > 0 {
-				log.WithFields(log.Fields{
-					"duration":     backOff,
-					logNotiName:    event.Name,
-					logSenderName:  senderName,
-					"attempts":     attempts + 1,
-					"max attempts": m.config.Attempts,
-				}).Info("waiting before retrying to send notification")
-				if !m.stopper.Sleep(backOff) {
-					return nil
-				}
-			}
+		level := m.Level()
+		if ls, ok := sender.(LevelSender); ok {
+			level = ls.Level()
+		}
+		if !getFilter(senderName).Allows(event, level) {
+			continue
+		}
 
-			// Send using the current notifier.
-			if err := sender.Send(event); err != nil {
-				// Send failed; increase attempts/backoff and retry.
-				log.WithError(err).WithFields(log.Fields{logSenderName: senderName, logNotiName: event.Name}).Error("could not send notification via notifier")
-				backOff = timeutil.ExpBackoff(backOff, notifierMaxBackOff)
-				attempts++
+		for _, e := range routes.events(senderName, sender, event) {
+			if m.digest.add(senderName, e) {
 				continue
 			}
 
-			// Send has been successful. Go to the next notifier.
-			break
+			// TODO: move this into goroutine if we have enough senders
+			if err := m.sendWith(senderName, sender, e); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
+// sendWith - sends notification with retries
+func (m *DefaultNotificationSender) sendWith(senderName string, sender Sender, event types.EventNotification) error {
+	var attempts int
+	var backOff time.Duration
+	for {
+		// Max attempts exceeded.
+		if attempts >= m.config.Attempts {
+			log.WithFields(log.Fields{
+				logNotiName:    event.Name,
+				logSenderName:  senderName,
+				"max attempts": m.config.Attempts,
+			}).Info("giving up on sending notification : max attempts exceeded")
+			return fmt.Errorf("failed to send notification, max attempts (%d) reached", m.config.Attempts)
+		}
+
+		// Backoff
+		if backOff > 0 {
+			log.WithFields(log.Fields{
+				"duration":     backOff,
+				logNotiName:    event.Name,
+				logSenderName:  senderName,
+				"attempts":     attempts + 1,
+				"max attempts": m.config.Attempts,
+			}).Info("waiting before retrying to send notification")
+			if !m.stopper.Sleep(backOff) {
+				return nil
+			}
+		}
+
+		// Send using the current notifier.
+		if err := sender.Send(event); err != nil {
+			// Send failed; increase attempts/backoff and retry.
+			log.WithError(err).WithFields(log.Fields{logSenderName: senderName, logNotiName: event.Name}).Error("could not send notification via notifier")
+			backOff = timeutil.ExpBackoff(backOff, notifierMaxBackOff)
+			attempts++
+			continue
+		}
+
+		// Send has been successful.
+		return nil
+	}
+}
+
 // UnregisterSender removes a Sender with a particular name from the list.
 func (m *DefaultNotificationSender) UnregisterSender(name string) {
 	sendersM.Lock()
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/keel-hq/keel/internal/bus"
 	"github.com/keel-hq/keel/types"
 )
 
@@ -60,6 +61,45 @@ func TestSend(t *testing.T) {
 	}
 }
 
+func TestSendThroughEventBus(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sndr := New(ctx)
+	sndr.Configure(&Config{
+		Level:    types.LevelDebug,
+		Attempts: 1,
+	})
+
+	fs := &fakeSender{shouldConfigure: true}
+	RegisterSender("fakeSender", fs)
+	defer sndr.UnregisterSender("fakeSender")
+
+	b := bus.New()
+	audit := b.Subscribe(ctx, TopicNotification, bus.SubscribeOpts{Name: "audit", Buffer: 1})
+	sndr.SetEventBus(b)
+
+	err := sndr.Send(types.EventNotification{
+		Level:   types.LevelInfo,
+		Type:    types.NotificationPreDeploymentUpdate,
+		Message: "foo",
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	// published notifications are delivered before flush returns
+	sndr.Flush()
+	if fs.sent == nil || fs.sent.Message != "foo" {
+		t.Fatalf("expected notification to be delivered, got: %v", fs.sent)
+	}
+
+	msg := <-audit.C
+	if msg.(*types.EventNotification).Message != "foo" {
+		t.Errorf("unexpected audited notification: %v", msg)
+	}
+}
+
 // test when configured level is higher than the event
 func TestSendLevelNotificationA(t *testing.T) {
 	sndr := New(context.Background())
@@ -162,3 +202,125 @@ func TestSendLevelNotificationC(t *testing.T) {
 		t.Errorf("unexpected level: %s", fs.sent.Level)
 	}
 }
+
+type fakeRoutingSender struct {
+	fakeSender
+	events []types.EventNotification
+}
+
+func (s *fakeRoutingSender) Send(event types.EventNotification) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *fakeRoutingSender) Routable() bool {
+	return true
+}
+
+func TestSendRoutes(t *testing.T) {
+	sndr := New(context.Background())
+	sndr.Configure(&Config{
+		Level:    types.LevelDebug,
+		Attempts: 1,
+	})
+
+	chat := &fakeRoutingSender{fakeSender: fakeSender{shouldConfigure: true}}
+	RegisterSender("fakeChat", chat)
+	defer sndr.UnregisterSender("fakeChat")
+	other := &fakeSender{shouldConfigure: true}
+	RegisterSender("fakeOther", other)
+	defer sndr.UnregisterSender("fakeOther")
+
+	event := types.EventNotification{
+		Level:    types.LevelInfo,
+		Type:     types.NotificationDeploymentUpdate,
+		Message:  "foo",
+		Channels: []string{"fakeChat:#team-payments"},
+	}
+
+	// routed targets are in addition to global channels
+	if err := sndr.Send(event); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(chat.events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(chat.events))
+	}
+	if len(chat.events[0].Channels) != 0 {
+		t.Errorf("expected global channels, got %v", chat.events[0].Channels)
+	}
+	if len(chat.events[1].Channels) != 1 || chat.events[1].Channels[0] != "#team-payments" {
+		t.Errorf("unexpected routed channels: %v", chat.events[1].Channels)
+	}
+	if other.sent == nil || len(other.sent.Channels) != 0 {
+		t.Errorf("expected other sender to get the event without channels, got %v", other.sent)
+	}
+
+	// replace mode only sends to routed senders
+	chat.events = nil
+	other.sent = nil
+	event.Channels = []string{"fakeChat:#team-payments", types.NotificationReplaceRoute}
+	if err := sndr.Send(event); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(chat.events) != 1 || chat.events[0].Channels[0] != "#team-payments" {
+		t.Errorf("expected only routed event, got %v", chat.events)
+	}
+	if other.sent != nil {
+		t.Errorf("expected other sender to be skipped")
+	}
+}
+
+func TestParseRoutesPlain(t *testing.T) {
+	RegisterSender("fakeRouted", &fakeSender{})
+	defer New(context.Background()).UnregisterSender("fakeRouted")
+
+	r := parseRoutes([]string{"#deployments", "unknown:x", "fakeRouted:y", types.NotificationReplaceRoute})
+	if len(r.plain) != 2 || r.plain[0] != "#deployments" || r.plain[1] != "unknown:x" {
+		t.Errorf("unexpected plain channels: %v", r.plain)
+	}
+	if len(r.targets["fakeRouted"]) != 1 || r.targets["fakeRouted"][0] != "y" {
+		t.Errorf("unexpected routes: %v", r.targets)
+	}
+	if !r.replace {
+		t.Errorf("expected replace mode")
+	}
+}
+
+type fakeInternalSender struct {
+	fakeSender
+}
+
+func (s *fakeInternalSender) Internal() bool { return true }
+
+func TestTestSkipsLevelsAndInternalSenders(t *testing.T) {
+	sndr := New(context.Background())
+
+	sndr.Configure(&Config{
+		Level:    types.LevelError,
+		Attempts: 1,
+	})
+
+	fs := &fakeSender{shouldConfigure: true, shouldError: fmt.Errorf("unauthorized")}
+	internal := &fakeInternalSender{fakeSender{shouldConfigure: true}}
+
+	RegisterSender("fakeSender", fs)
+	defer sndr.UnregisterSender("fakeSender")
+	RegisterSender("fakeInternalSender", internal)
+	defer sndr.UnregisterSender("fakeInternalSender")
+
+	results := sndr.Test(types.EventNotification{
+		Level:   types.LevelInfo,
+		Type:    types.NotificationSystemEvent,
+		Message: "test",
+	})
+
+	if len(results) != 1 || results["fakeSender"] == nil {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	if fs.sent == nil || fs.sent.Message != "test" {
+		t.Errorf("expected test notification to be sent below the configured level")
+	}
+	if internal.sent != nil {
+		t.Errorf("internal senders shouldn't get test notifications")
+	}
+}
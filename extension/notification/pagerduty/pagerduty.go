@@ -0,0 +1,183 @@
+package pagerduty
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 5 * time.Second
+
+// defaultEndpoint - PagerDuty Events API v2
+const defaultEndpoint = "https://events.pagerduty.com/v2/enqueue"
+
+// summaryLimit - maximum length of the alert summary
+const summaryLimit = 1024
+
+type sender struct {
+	endpoint   string
+	routingKey string
+	level      types.Level
+	resolve    bool
+	client     *http.Client
+}
+
+func init() {
+	notification.RegisterSender("pagerduty", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	s.routingKey = os.Getenv(constants.EnvPagerDutyRoutingKey)
+	if s.routingKey == "" {
+		return false, nil
+	}
+
+	s.endpoint = defaultEndpoint
+	if endpoint := os.Getenv(constants.EnvPagerDutyEndpoint); endpoint != "" {
+		if _, err := url.ParseRequestURI(endpoint); err != nil {
+			return false, fmt.Errorf("could not parse endpoint URL: %s", err)
+		}
+		s.endpoint = endpoint
+	}
+
+	s.level = types.LevelError
+	if lvl := os.Getenv(constants.EnvPagerDutyLevel); lvl != "" {
+		level, err := types.ParseLevel(lvl)
+		if err != nil {
+			return false, err
+		}
+		s.level = level
+	}
+
+	resolve := os.Getenv(constants.EnvPagerDutyResolve)
+	s.resolve = resolve == "1" || resolve == "true"
+
+	s.client = &http.Client{
+		Transport: http.DefaultTransport,
+		Timeout:   timeout,
+	}
+
+	log.WithFields(log.Fields{
+		"name":     "pagerduty",
+		"endpoint": s.endpoint,
+		"level":    s.level.String(),
+		"resolve":  s.resolve,
+	}).Info("extension.notification.pagerduty: sender configured")
+
+	return true, nil
+}
+
+type alert struct {
+	RoutingKey  string        `json:"routing_key"`
+	EventAction string        `json:"event_action"`
+	DedupKey    string        `json:"dedup_key"`
+	Client      string        `json:"client,omitempty"`
+	Payload     *alertPayload `json:"payload,omitempty"`
+}
+
+type alertPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Component     string            `json:"component,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// severity - PagerDuty severity of the notification level
+func severity(level types.Level) string {
+	switch level {
+	case types.LevelFatal:
+		return "critical"
+	case types.LevelError:
+		return "error"
+	case types.LevelWarn:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// dedupKey - alerts of the same resource are grouped into a single incident
+func dedupKey(event types.EventNotification) string {
+	key := "keel/" + event.Identifier
+	if provider := event.Metadata["provider"]; provider != "" {
+		key += "/" + provider
+	}
+	return key
+}
+
+// Send - triggers alerts for failed updates and rollouts, successful updates of
+// the resource resolve them when resolving is enabled
+func (s *sender) Send(event types.EventNotification) error {
+	switch event.Type {
+	case types.NotificationDeploymentUpdate, types.NotificationReleaseUpdate:
+	default:
+		return nil
+	}
+
+	var a alert
+	switch {
+	case event.Level >= s.level:
+		summary := event.Message
+		if len(summary) > summaryLimit {
+			summary = summary[:summaryLimit]
+		}
+		source := event.Metadata["provider"]
+		if source == "" {
+			source = "keel"
+		}
+		a = alert{
+			RoutingKey:  s.routingKey,
+			EventAction: "trigger",
+			DedupKey:    dedupKey(event),
+			Client:      "keel",
+			Payload: &alertPayload{
+				Summary:       summary,
+				Source:        source,
+				Severity:      severity(event.Level),
+				Timestamp:     event.CreatedAt.UTC().Format(time.RFC3339),
+				Component:     event.Identifier,
+				Group:         event.Metadata["namespace"],
+				Class:         event.ResourceKind,
+				CustomDetails: event.Metadata,
+			},
+		}
+	case s.resolve && event.Level == types.LevelSuccess:
+		a = alert{
+			RoutingKey:  s.routingKey,
+			EventAction: "resolve",
+			DedupKey:    dedupKey(event),
+		}
+	default:
+		return nil
+	}
+
+	body, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+
+	resp, err := s.client.Post(s.endpoint, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("got status %d, expected 202", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package pagerduty
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestSend(t *testing.T) {
+	var alerts []alert
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		var a alert
+		if err := json.NewDecoder(req.Body).Decode(&a); err != nil {
+			t.Errorf("failed to decode alert: %s", err)
+		}
+		alerts = append(alerts, a)
+		resp.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	s := &sender{
+		endpoint:   ts.URL,
+		routingKey: "routing-key",
+		level:      types.LevelError,
+		resolve:    true,
+		client:     &http.Client{},
+	}
+
+	failed := types.EventNotification{
+		Name:         "update resource",
+		ResourceKind: "deployment",
+		Identifier:   "deployment/default/wd",
+		Message:      "deployment default/wd update 0.0.14->0.0.15 rollout failed: progress deadline exceeded",
+		CreatedAt:    time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        types.LevelError,
+		Metadata:     map[string]string{"provider": "kubernetes", "namespace": "default", "name": "wd"},
+	}
+	if err := s.Send(failed); err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+
+	// not alerted
+	s.Send(types.EventNotification{Type: types.NotificationPreDeploymentUpdate, Level: types.LevelError})
+	s.Send(types.EventNotification{Type: types.NotificationDeploymentUpdate, Level: types.LevelWarn})
+
+	succeeded := failed
+	succeeded.Level = types.LevelSuccess
+	if err := s.Send(succeeded); err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+
+	if len(alerts) != 2 {
+		t.Fatalf("expected trigger and resolve alerts, got: %v", alerts)
+	}
+	trigger := alerts[0]
+	if trigger.EventAction != "trigger" || trigger.RoutingKey != "routing-key" || trigger.DedupKey != "keel/deployment/default/wd/kubernetes" {
+		t.Errorf("unexpected alert: %+v", trigger)
+	}
+	if trigger.Payload.Severity != "error" || trigger.Payload.Group != "default" || trigger.Payload.Timestamp != "2018-10-01T12:00:00Z" {
+		t.Errorf("unexpected payload: %+v", trigger.Payload)
+	}
+	if alerts[1].EventAction != "resolve" || alerts[1].DedupKey != trigger.DedupKey || alerts[1].Payload != nil {
+		t.Errorf("unexpected resolve alert: %+v", alerts[1])
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	for level, expected := range map[types.Level]string{
+		types.LevelFatal:   "critical",
+		types.LevelError:   "error",
+		types.LevelWarn:    "warning",
+		types.LevelSuccess: "info",
+	} {
+		if got := severity(level); got != expected {
+			t.Errorf("severity(%s) = %s, want %s", level, got, expected)
+		}
+	}
+}
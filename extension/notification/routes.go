@@ -0,0 +1,81 @@
+package notification
+
+import (
+	"strings"
+
+	"github.com/keel-hq/keel/types"
+)
+
+// RoutingSender - sender that delivers to targets routed to it with keel.sh/notify,
+// ie: "slack:#team-payments". Routed events have their Channels set to the targets.
+// Routes of other senders only select the sender, their targets are ignored.
+type RoutingSender interface {
+	Sender
+	Routable() bool
+}
+
+// routes - keel.sh/notify entries split by sender
+type routes struct {
+	// plain - entries without a sender, they override channels of every sender
+	plain []string
+	// targets - routed entries keyed by sender name
+	targets map[string][]string
+	// replace - only routed senders get the notification
+	replace bool
+}
+
+// parseRoutes - splits event channels into plain channels and <sender>:<target>
+// routes, prefixes that aren't sender names (ie: unconfigured senders) are
+// treated as a part of a plain channel
+func parseRoutes(channels []string) *routes {
+	r := &routes{targets: make(map[string][]string)}
+	for _, c := range channels {
+		if c == "" {
+			continue
+		}
+		if c == types.NotificationReplaceRoute {
+			r.replace = true
+			continue
+		}
+		parts := strings.SplitN(c, ":", 2)
+		if len(parts) == 2 && knownSender(parts[0]) {
+			r.targets[parts[0]] = append(r.targets[parts[0]], strings.TrimSpace(parts[1]))
+			continue
+		}
+		r.plain = append(r.plain, c)
+	}
+	if len(r.targets) == 0 {
+		// nothing to route to, resource keeps getting notifications
+		r.replace = false
+	}
+	return r
+}
+
+// events - events the sender should send, routing senders send the routed event
+// in addition to the one for global channels unless routes replace them
+func (r *routes) events(senderName string, sender Sender, event types.EventNotification) []types.EventNotification {
+	targets, routed := r.targets[senderName]
+	if r.replace && !routed {
+		return nil
+	}
+
+	rs, ok := sender.(RoutingSender)
+	if !routed || !ok || !rs.Routable() {
+		if r.replace {
+			// sender was routed, it sends to its own channels
+			event.Channels = nil
+		} else {
+			event.Channels = r.plain
+		}
+		return []types.EventNotification{event}
+	}
+
+	routedEvent := event
+	routedEvent.Channels = targets
+	if r.replace {
+		return []types.EventNotification{routedEvent}
+	}
+
+	event.Channels = r.plain
+	return []types.EventNotification{event, routedEvent}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/keel-hq/keel/constants"
 	"github.com/keel-hq/keel/extension/notification"
 	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/templates"
 	"github.com/keel-hq/keel/version"
 
 	log "github.com/sirupsen/logrus"
@@ -86,24 +87,34 @@ func (s *sender) Configure(config *notification.Config) (bool, error) {
 	return true, nil
 }
 
+// Routable - keel.sh/notify routes are channels, ie: slack:#team-payments
+func (s *sender) Routable() bool {
+	return true
+}
+
 func (s *sender) Send(event types.EventNotification) error {
 	params := slack.NewPostMessageParameters()
 	params.Username = s.botName
 	params.IconURL = constants.KeelLogoURL
 
+	fields := []slack.AttachmentField{
+		slack.AttachmentField{
+			Title: event.Type.String(),
+			Value: event.Message,
+			Short: false,
+		},
+	}
+	if msg, ok := templates.RenderMessage(templates.NotificationMessageName(event.Type), event); ok {
+		fields = messageFields(msg)
+	}
+
 	attachements := []slack.Attachment{
 		slack.Attachment{
 			Fallback: event.Message,
 			Color:    event.Level.Color(),
-			Fields: []slack.AttachmentField{
-				slack.AttachmentField{
-					Title: event.Type.String(),
-					Value: event.Message,
-					Short: false,
-				},
-			},
-			Footer: fmt.Sprintf("https://keel.sh %s", version.GetKeelVersion().Version),
-			Ts:     json.Number(strconv.Itoa(int(event.CreatedAt.Unix()))),
+			Fields:   fields,
+			Footer:   fmt.Sprintf("https://keel.sh %s", version.GetKeelVersion().Version),
+			Ts:       json.Number(strconv.Itoa(int(event.CreatedAt.Unix()))),
 		},
 	}
 
@@ -118,6 +129,9 @@ func (s *sender) Send(event types.EventNotification) error {
 	mgsOpts = append(mgsOpts, slack.MsgOptionAttachments(attachements...))
 
 	for _, channel := range chans {
+		if !notification.EndpointAllowed("slack", channel, event) {
+			continue
+		}
 		_, _, err := s.slackClient.PostMessage(channel, mgsOpts...)
 		if err != nil {
 			log.WithFields(log.Fields{
@@ -128,3 +142,26 @@ func (s *sender) Send(event types.EventNotification) error {
 	}
 	return nil
 }
+
+// messageFields - converts templated message into attachment fields
+func messageFields(msg *templates.Message) []slack.AttachmentField {
+	if len(msg.Fields) == 0 {
+		return []slack.AttachmentField{
+			slack.AttachmentField{
+				Title: msg.Title,
+				Value: msg.Text,
+				Short: false,
+			},
+		}
+	}
+
+	var fields []slack.AttachmentField
+	for _, f := range msg.Fields {
+		fields = append(fields, slack.AttachmentField{
+			Title: f.Title,
+			Value: f.Value,
+			Short: f.Short,
+		})
+	}
+	return fields
+}
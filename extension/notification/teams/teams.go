@@ -0,0 +1,176 @@
+package teams
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/templates"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 5 * time.Second
+
+const (
+	cardContentType = "application/vnd.microsoft.card.adaptive"
+	cardSchema      = "http://adaptivecards.io/schemas/adaptive-card.json"
+	cardVersion     = "1.4"
+)
+
+type sender struct {
+	endpoint string
+	client   *http.Client
+}
+
+func init() {
+	notification.RegisterSender("teams", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	endpoint := os.Getenv(constants.EnvTeamsWebhookURL)
+	if endpoint == "" {
+		return false, nil
+	}
+	if _, err := url.ParseRequestURI(endpoint); err != nil {
+		return false, fmt.Errorf("could not parse endpoint URL: %s", err)
+	}
+	s.endpoint = endpoint
+
+	s.client = &http.Client{
+		Transport: http.DefaultTransport,
+		Timeout:   timeout,
+	}
+
+	log.WithFields(log.Fields{
+		"name": "teams",
+	}).Info("extension.notification.teams: sender configured")
+
+	return true, nil
+}
+
+type message struct {
+	Type        string       `json:"type"`
+	Attachments []attachment `json:"attachments"`
+}
+
+type attachment struct {
+	ContentType string `json:"contentType"`
+	Content     card   `json:"content"`
+}
+
+type card struct {
+	Schema  string        `json:"$schema"`
+	Type    string        `json:"type"`
+	Version string        `json:"version"`
+	Body    []interface{} `json:"body"`
+}
+
+type textBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Size   string `json:"size,omitempty"`
+	Weight string `json:"weight,omitempty"`
+	Color  string `json:"color,omitempty"`
+	Wrap   bool   `json:"wrap"`
+}
+
+type factSet struct {
+	Type  string `json:"type"`
+	Facts []fact `json:"facts"`
+}
+
+type fact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// color - Adaptive Card text color of the notification level
+func color(level types.Level) string {
+	switch level {
+	case types.LevelError, types.LevelFatal:
+		return "attention"
+	case types.LevelWarn:
+		return "warning"
+	case types.LevelSuccess:
+		return "good"
+	default:
+		return "default"
+	}
+}
+
+// newCard - card with the title, message and facts of the update: versions,
+// namespace, trigger and approval status when they are known
+func newCard(event types.EventNotification) card {
+	title := event.Type.String()
+	text := event.Message
+	var facts []fact
+	if msg, ok := templates.RenderMessage(templates.NotificationMessageName(event.Type), event); ok {
+		if msg.Title != "" {
+			title = msg.Title
+		}
+		text = msg.Text
+		for _, f := range msg.Fields {
+			facts = append(facts, fact{Title: f.Title, Value: f.Value})
+		}
+	}
+
+	add := func(title, value string) {
+		if value != "" {
+			facts = append(facts, fact{Title: title, Value: value})
+		}
+	}
+	add("Resource", event.Identifier)
+	add("Namespace", event.Metadata["namespace"])
+	if event.Metadata["previousVersion"] != "" || event.Metadata["newVersion"] != "" {
+		add("Version", fmt.Sprintf("%s → %s", event.Metadata["previousVersion"], event.Metadata["newVersion"]))
+	}
+	add("Trigger", event.Metadata["trigger"])
+	add("Approval", event.Metadata["approval"])
+	add("Cluster", event.Metadata["cluster"])
+
+	body := []interface{}{
+		textBlock{Type: "TextBlock", Text: title, Size: "medium", Weight: "bolder", Color: color(event.Level), Wrap: true},
+		textBlock{Type: "TextBlock", Text: text, Wrap: true},
+	}
+	if len(facts) > 0 {
+		body = append(body, factSet{Type: "FactSet", Facts: facts})
+	}
+
+	return card{
+		Schema:  cardSchema,
+		Type:    "AdaptiveCard",
+		Version: cardVersion,
+		Body:    body,
+	}
+}
+
+func (s *sender) Send(event types.EventNotification) error {
+	body, err := json.Marshal(message{
+		Type: "message",
+		Attachments: []attachment{
+			{ContentType: cardContentType, Content: newCard(event)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+
+	resp, err := s.client.Post(s.endpoint, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("got status %d, expected 2xx", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package teams
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestSend(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		body, _ = ioutil.ReadAll(req.Body)
+		resp.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	s := &sender{endpoint: ts.URL, client: &http.Client{}}
+	err := s.Send(types.EventNotification{
+		Name:         "update resource",
+		ResourceKind: "deployment",
+		Identifier:   "deployment/default/wd",
+		Message:      "Successfully updated deployment default/wd 0.0.14->0.0.15",
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        types.LevelSuccess,
+		Metadata: map[string]string{
+			"provider":        "kubernetes",
+			"namespace":       "default",
+			"name":            "wd",
+			"previousVersion": "0.0.14",
+			"newVersion":      "0.0.15",
+			"trigger":         "poll",
+			"approval":        "approved (1/1)",
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+
+	var msg struct {
+		Attachments []struct {
+			ContentType string `json:"contentType"`
+			Content     struct {
+				Type string `json:"type"`
+				Body []struct {
+					Type  string `json:"type"`
+					Text  string `json:"text"`
+					Color string `json:"color"`
+					Facts []fact `json:"facts"`
+				} `json:"body"`
+			} `json:"content"`
+		} `json:"attachments"`
+	}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatalf("failed to decode message: %s", err)
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].ContentType != cardContentType {
+		t.Fatalf("unexpected attachments: %s", body)
+	}
+	content := msg.Attachments[0].Content
+	if content.Type != "AdaptiveCard" || len(content.Body) != 3 {
+		t.Fatalf("unexpected card: %s", body)
+	}
+	if content.Body[0].Color != "good" || !strings.Contains(content.Body[1].Text, "Successfully updated") {
+		t.Errorf("unexpected card text: %s", body)
+	}
+
+	facts := map[string]string{}
+	for _, f := range content.Body[2].Facts {
+		facts[f.Title] = f.Value
+	}
+	expected := map[string]string{
+		"Resource":  "deployment/default/wd",
+		"Namespace": "default",
+		"Version":   "0.0.14 → 0.0.15",
+		"Trigger":   "poll",
+		"Approval":  "approved (1/1)",
+	}
+	for title, value := range expected {
+		if facts[title] != value {
+			t.Errorf("expected fact %s to be %q, got %q", title, value, facts[title])
+		}
+	}
+	if _, ok := facts["Cluster"]; ok {
+		t.Errorf("unexpected empty cluster fact")
+	}
+}
+
+func TestSendError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.WriteHeader(http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	s := &sender{endpoint: ts.URL, client: &http.Client{}}
+	if err := s.Send(types.EventNotification{Type: types.NotificationDeploymentUpdate}); err == nil {
+		t.Errorf("expected error")
+	}
+}
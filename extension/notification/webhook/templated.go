@@ -0,0 +1,129 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/templates"
+)
+
+// EndpointConfig - templated webhook endpoint, header values and body are Go
+// templates rendered with the event notification. ${VAR} references in header
+// values are expanded from the environment when the config is loaded so
+// credentials can be kept in secrets.
+type EndpointConfig struct {
+	Name    string            `json:"name"`
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+}
+
+// EndpointsConfig - templated webhook endpoints file, ie:
+//
+//	endpoints:
+//	- name: n8n
+//	  url: https://n8n.example.com/webhook/keel
+//	  headers:
+//	    Authorization: Bearer ${N8N_TOKEN}
+//	  body: '{"text": {{ json .Message }}, "level": "{{ .Level }}"}'
+type EndpointsConfig struct {
+	Endpoints []EndpointConfig `json:"endpoints"`
+}
+
+type endpoint struct {
+	name    string
+	url     string
+	method  string
+	headers map[string]*template.Template
+	body    *template.Template
+}
+
+// loadEndpoints - loads templated endpoints from a YAML (or JSON) file
+func loadEndpoints(path string) ([]*endpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseEndpoints(data)
+}
+
+func parseEndpoints(data []byte) ([]*endpoint, error) {
+	var cfg EndpointsConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook endpoints: %s", err)
+	}
+
+	var endpoints []*endpoint
+	for idx, c := range cfg.Endpoints {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("endpoints[%d]", idx)
+		}
+		if _, err := url.ParseRequestURI(c.URL); err != nil {
+			return nil, fmt.Errorf("invalid %s URL: %s", name, err)
+		}
+
+		e := &endpoint{
+			name:    name,
+			url:     c.URL,
+			method:  strings.ToUpper(c.Method),
+			headers: make(map[string]*template.Template),
+		}
+		if e.method == "" {
+			e.method = http.MethodPost
+		}
+		for header, value := range c.Headers {
+			tmpl, err := templates.NewParse(name+".headers."+header, os.ExpandEnv(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s header %s template: %s", name, header, err)
+			}
+			e.headers[header] = tmpl
+		}
+		if c.Body != "" {
+			tmpl, err := templates.NewParse(name+".body", c.Body)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s body template: %s", name, err)
+			}
+			e.body = tmpl
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, nil
+}
+
+// request - renders the request of the event, body defaults to the event JSON
+func (e *endpoint) request(event types.EventNotification) (*http.Request, error) {
+	var body bytes.Buffer
+	if e.body != nil {
+		if err := e.body.Execute(&body, event); err != nil {
+			return nil, fmt.Errorf("failed to render %s body: %s", e.name, err)
+		}
+	} else if err := json.NewEncoder(&body).Encode(notificationEnvelope{event}); err != nil {
+		return nil, fmt.Errorf("could not marshal: %s", err)
+	}
+
+	req, err := http.NewRequest(e.method, e.url, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for header, tmpl := range e.headers {
+		var value bytes.Buffer
+		if err := tmpl.Execute(&value, event); err != nil {
+			return nil, fmt.Errorf("failed to render %s header %s: %s", e.name, header, err)
+		}
+		req.Header.Set(header, value.String())
+	}
+	return req, nil
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/keel-hq/keel/constants"
@@ -19,8 +20,12 @@ import (
 const timeout = 5 * time.Second
 
 type sender struct {
-	endpoint string
-	client   *http.Client
+	endpoint  string
+	endpoints []*endpoint
+	// routeHosts - hosts workloads are allowed to route notifications to,
+	// empty allows any host
+	routeHosts map[string]bool
+	client     *http.Client
 }
 
 // Config represents the configuration of a Webhook Sender.
@@ -38,18 +43,34 @@ func (s *sender) Configure(config *notification.Config) (bool, error) {
 
 	if os.Getenv(constants.WebhookEndpointEnv) != "" {
 		httpConfig.Endpoint = os.Getenv(constants.WebhookEndpointEnv)
-	} else {
-		return false, nil
 	}
 
-	// Validate endpoint URL.
-	if httpConfig.Endpoint == "" {
+	if path := os.Getenv(constants.EnvWebhookConfig); path != "" {
+		endpoints, err := loadEndpoints(path)
+		if err != nil {
+			return false, fmt.Errorf("failed to load webhook endpoints: %s", err)
+		}
+		s.endpoints = endpoints
+	}
+
+	s.routeHosts = make(map[string]bool)
+	for _, host := range strings.Split(os.Getenv(constants.EnvWebhookRouteHosts), ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			s.routeHosts[host] = true
+		}
+	}
+
+	if httpConfig.Endpoint == "" && len(s.endpoints) == 0 && len(s.routeHosts) == 0 {
 		return false, nil
 	}
-	if _, err := url.ParseRequestURI(httpConfig.Endpoint); err != nil {
-		return false, fmt.Errorf("could not parse endpoint URL: %s\n", err)
+
+	// Validate endpoint URL.
+	if httpConfig.Endpoint != "" {
+		if _, err := url.ParseRequestURI(httpConfig.Endpoint); err != nil {
+			return false, fmt.Errorf("could not parse endpoint URL: %s\n", err)
+		}
+		s.endpoint = httpConfig.Endpoint
 	}
-	s.endpoint = httpConfig.Endpoint
 
 	// Setup HTTP client.
 	s.client = &http.Client{
@@ -58,8 +79,10 @@ func (s *sender) Configure(config *notification.Config) (bool, error) {
 	}
 
 	log.WithFields(log.Fields{
-		"name":     "webhook",
-		"endpoint": s.endpoint,
+		"name":      "webhook",
+		"endpoint":  s.endpoint,
+		"templated": len(s.endpoints),
+		"routes":    len(s.routeHosts),
 	}).Info("extension.notification.webhook: sender configured")
 
 	return true, nil
@@ -69,7 +92,79 @@ type notificationEnvelope struct {
 	types.EventNotification
 }
 
+// Routable - keel.sh/notify routes are endpoint URLs, ie: webhook:https://example.com/hook
+func (s *sender) Routable() bool {
+	return true
+}
+
+// routeURL - validates URL the notification was routed to
+func (s *sender) routeURL(target string) (string, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	if len(s.routeHosts) > 0 && !s.routeHosts[strings.ToLower(u.Hostname())] {
+		return "", fmt.Errorf("host %s is not in %s", u.Hostname(), constants.EnvWebhookRouteHosts)
+	}
+	return u.String(), nil
+}
+
 func (s *sender) Send(event types.EventNotification) error {
+	// routed events only go to the routed endpoints, channels that aren't
+	// URLs belong to chat senders
+	var isRouted bool
+	var routed []string
+	for _, target := range event.Channels {
+		if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+			continue
+		}
+		isRouted = true
+		endpoint, err := s.routeURL(target)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":      err,
+				"endpoint":   target,
+				"identifier": event.Identifier,
+			}).Warn("extension.notification.webhook: ignoring routed endpoint")
+			continue
+		}
+		routed = append(routed, endpoint)
+	}
+	if isRouted {
+		var errs []string
+		for _, endpoint := range routed {
+			if err := s.send(endpoint, event); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %s", endpoint, err))
+			}
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("webhook notification failed: %s", strings.Join(errs, "; "))
+		}
+		return nil
+	}
+
+	var errs []string
+	if s.endpoint != "" {
+		if err := s.send(s.endpoint, event); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	for _, e := range s.endpoints {
+		if !notification.EndpointAllowed("webhook", e.name, event) {
+			continue
+		}
+		if err := s.sendTemplated(e, event); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", e.name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("webhook notification failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// send - posts the event JSON to the webhook endpoint
+func (s *sender) send(endpoint string, event types.EventNotification) error {
 	// Marshal notification.
 	jsonNotification, err := json.Marshal(notificationEnvelope{event})
 	if err != nil {
@@ -77,7 +172,7 @@ func (s *sender) Send(event types.EventNotification) error {
 	}
 
 	// Send notification via HTTP POST.
-	resp, err := s.client.Post(s.endpoint, "application/json", bytes.NewBuffer(jsonNotification))
+	resp, err := s.client.Post(endpoint, "application/json", bytes.NewBuffer(jsonNotification))
 	if err != nil || resp == nil || (resp.StatusCode != 200 && resp.StatusCode != 201) {
 		if resp != nil {
 			return fmt.Errorf("got status %d, expected 200/201", resp.StatusCode)
@@ -88,3 +183,22 @@ func (s *sender) Send(event types.EventNotification) error {
 
 	return nil
 }
+
+// sendTemplated - sends the rendered request to the templated endpoint
+func (s *sender) sendTemplated(e *endpoint, event types.EventNotification) error {
+	req, err := e.request(event)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("got status %d, expected 2xx", resp.StatusCode)
+	}
+	return nil
+}
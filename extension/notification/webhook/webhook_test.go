@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -57,3 +58,122 @@ func TestWebhookRequest(t *testing.T) {
 		Level:     types.LevelDebug,
 	})
 }
+
+func TestTemplatedWebhookRequest(t *testing.T) {
+	var (
+		body   string
+		header http.Header
+		method string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		data, _ := ioutil.ReadAll(req.Body)
+		body = string(data)
+		header = req.Header
+		method = req.Method
+	}))
+	defer ts.Close()
+
+	os.Setenv("TEST_WEBHOOK_TOKEN", "secret")
+	defer os.Unsetenv("TEST_WEBHOOK_TOKEN")
+
+	endpoints, err := parseEndpoints([]byte(`
+endpoints:
+- name: n8n
+  url: ` + ts.URL + `
+  method: put
+  headers:
+    Authorization: Bearer ${TEST_WEBHOOK_TOKEN}
+    X-Keel-Level: "{{ .Level }}"
+  body: '{"text": {{ json .Message }}, "namespace": "{{ .Metadata.namespace }}"}'
+`))
+	if err != nil {
+		t.Fatalf("failed to parse endpoints: %s", err)
+	}
+
+	s := &sender{
+		endpoints: endpoints,
+		client:    &http.Client{},
+	}
+	err = s.Send(types.EventNotification{
+		Name:      "update deployment",
+		Message:   `updated "wd"`,
+		CreatedAt: time.Now(),
+		Type:      types.NotificationDeploymentUpdate,
+		Level:     types.LevelSuccess,
+		Metadata:  map[string]string{"namespace": "default"},
+	})
+	if err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+
+	if method != http.MethodPut {
+		t.Errorf("expected PUT, got %s", method)
+	}
+	if body != `{"text": "updated \"wd\"", "namespace": "default"}` {
+		t.Errorf("unexpected body: %s", body)
+	}
+	if header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("unexpected authorization header: %s", header.Get("Authorization"))
+	}
+	if header.Get("X-Keel-Level") != "success" {
+		t.Errorf("unexpected level header: %s", header.Get("X-Keel-Level"))
+	}
+}
+
+func TestParseEndpointsInvalid(t *testing.T) {
+	for _, data := range []string{
+		"endpoints:\n- url: not a url",
+		"endpoints:\n- url: http://localhost\n  body: '{{ .Message'",
+	} {
+		if _, err := parseEndpoints([]byte(data)); err == nil {
+			t.Errorf("expected error for %q", data)
+		}
+	}
+}
+
+func TestRoutedWebhookRequest(t *testing.T) {
+	var defaultHits, routedHits int
+	defaultServer := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		defaultHits++
+	}))
+	defer defaultServer.Close()
+	routedServer := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		routedHits++
+	}))
+	defer routedServer.Close()
+
+	s := &sender{
+		endpoint: defaultServer.URL,
+		client:   &http.Client{},
+	}
+
+	event := types.EventNotification{
+		Name:     "update deployment",
+		Message:  "message here",
+		Type:     types.NotificationDeploymentUpdate,
+		Channels: []string{"#team-payments"},
+	}
+	if err := s.Send(event); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if defaultHits != 1 || routedHits != 0 {
+		t.Errorf("expected default endpoint only, got default %d, routed %d", defaultHits, routedHits)
+	}
+
+	event.Channels = []string{routedServer.URL}
+	if err := s.Send(event); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if defaultHits != 1 || routedHits != 1 {
+		t.Errorf("expected routed endpoint only, got default %d, routed %d", defaultHits, routedHits)
+	}
+
+	// routed hosts are restricted
+	s.routeHosts = map[string]bool{"hooks.example.com": true}
+	if err := s.Send(event); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if routedHits != 1 || defaultHits != 1 {
+		t.Errorf("expected routed host to be rejected, got default %d, routed %d", defaultHits, routedHits)
+	}
+}
@@ -0,0 +1,92 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/keel-hq/keel/provider"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Gate - gate plugin client, checks events before they are submitted to providers
+type Gate struct {
+	name     string
+	conn     *grpc.ClientConn
+	timeout  time.Duration
+	failOpen bool
+}
+
+var _ provider.Gate = &Gate{}
+
+// NewGate - creates gate plugin client
+func NewGate(cfg Config) (*Gate, error) {
+	timeout, err := cfg.timeout()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dial(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %s", cfg.Name, err)
+	}
+	return &Gate{
+		name:     cfg.Name,
+		conn:     conn,
+		timeout:  timeout,
+		failOpen: cfg.FailOpen,
+	}, nil
+}
+
+// Name - plugin name
+func (g *Gate) Name() string {
+	return g.name
+}
+
+// Check - asks the plugin about the event
+func (g *Gate) Check(event types.Event) (*provider.GateDecision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+
+	resp := new(CheckResponse)
+	err := g.conn.Invoke(ctx, gateCheckMethod, &CheckRequest{Event: fromEvent(event)}, resp)
+	if err != nil {
+		if g.failOpen {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"plugin": g.name,
+				"event":  event.Repository.Name,
+			}).Warn("plugin.gate: check failed, event allowed (fail open)")
+			return &provider.GateDecision{Allowed: true, Reason: "gate unavailable"}, nil
+		}
+		return nil, err
+	}
+
+	switch resp.Decision {
+	case DecisionAllow:
+		return &provider.GateDecision{Allowed: true, Reason: resp.Reason}, nil
+	case DecisionDeny:
+		return &provider.GateDecision{Allowed: false, Reason: resp.Reason}, nil
+	case DecisionModify:
+		if resp.Event == nil || resp.Event.Repository == "" {
+			return nil, fmt.Errorf("plugin %s modified event without returning it", g.name)
+		}
+		modified := toEvent(resp.Event)
+		if modified.TriggerName == "" {
+			modified.TriggerName = event.TriggerName
+		}
+		if resp.Event.CreatedAt == 0 {
+			modified.CreatedAt = event.CreatedAt
+		}
+		return &provider.GateDecision{Allowed: true, Reason: resp.Reason, Event: &modified}, nil
+	}
+	return nil, fmt.Errorf("plugin %s returned unknown decision %d", g.name, resp.Decision)
+}
+
+// Close - closes plugin connection
+func (g *Gate) Close() error {
+	return g.conn.Close()
+}
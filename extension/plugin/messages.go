@@ -0,0 +1,163 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+)
+
+// messages and services of plugin.proto, kept in sync by hand so the protocol
+// doesn't need protoc to build keel
+
+// Event - image event
+type Event struct {
+	Repository string `protobuf:"bytes,1,opt,name=repository,proto3" json:"repository,omitempty"`
+	Tag        string `protobuf:"bytes,2,opt,name=tag,proto3" json:"tag,omitempty"`
+	Digest     string `protobuf:"bytes,3,opt,name=digest,proto3" json:"digest,omitempty"`
+	Trigger    string `protobuf:"bytes,4,opt,name=trigger,proto3" json:"trigger,omitempty"`
+	Target     string `protobuf:"bytes,5,opt,name=target,proto3" json:"target,omitempty"`
+	CreatedAt  int64  `protobuf:"varint,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Chart      bool   `protobuf:"varint,7,opt,name=chart,proto3" json:"chart,omitempty"`
+}
+
+func (m *Event) Reset()         { *m = Event{} }
+func (m *Event) String() string { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()    {}
+
+// CheckRequest - gate check request
+type CheckRequest struct {
+	Event *Event `protobuf:"bytes,1,opt,name=event,proto3" json:"event,omitempty"`
+}
+
+func (m *CheckRequest) Reset()         { *m = CheckRequest{} }
+func (m *CheckRequest) String() string { return proto.CompactTextString(m) }
+func (*CheckRequest) ProtoMessage()    {}
+
+// Decision - gate decision
+type Decision int32
+
+// gate decisions
+const (
+	DecisionAllow  Decision = 0
+	DecisionDeny   Decision = 1
+	DecisionModify Decision = 2
+)
+
+func (d Decision) String() string {
+	switch d {
+	case DecisionAllow:
+		return "ALLOW"
+	case DecisionDeny:
+		return "DENY"
+	case DecisionModify:
+		return "MODIFY"
+	}
+	return "UNKNOWN"
+}
+
+// CheckResponse - gate decision about the event
+type CheckResponse struct {
+	Decision Decision `protobuf:"varint,1,opt,name=decision,proto3,enum=keel.plugin.v1.CheckResponse_Decision" json:"decision,omitempty"`
+	Reason   string   `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	Event    *Event   `protobuf:"bytes,3,opt,name=event,proto3" json:"event,omitempty"`
+}
+
+func (m *CheckResponse) Reset()         { *m = CheckResponse{} }
+func (m *CheckResponse) String() string { return proto.CompactTextString(m) }
+func (*CheckResponse) ProtoMessage()    {}
+
+// TrackedImage - image tracked by keel
+type TrackedImage struct {
+	Image     string `protobuf:"bytes,1,opt,name=image,proto3" json:"image,omitempty"`
+	Namespace string `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Provider  string `protobuf:"bytes,3,opt,name=provider,proto3" json:"provider,omitempty"`
+	Policy    string `protobuf:"bytes,4,opt,name=policy,proto3" json:"policy,omitempty"`
+}
+
+func (m *TrackedImage) Reset()         { *m = TrackedImage{} }
+func (m *TrackedImage) String() string { return proto.CompactTextString(m) }
+func (*TrackedImage) ProtoMessage()    {}
+
+// WatchRequest - trigger watch request with images tracked by keel
+type WatchRequest struct {
+	Images []*TrackedImage `protobuf:"bytes,1,rep,name=images,proto3" json:"images,omitempty"`
+}
+
+func (m *WatchRequest) Reset()         { *m = WatchRequest{} }
+func (m *WatchRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchRequest) ProtoMessage()    {}
+
+const (
+	gateCheckMethod    = "/keel.plugin.v1.Gate/Check"
+	triggerWatchMethod = "/keel.plugin.v1.Trigger/Watch"
+)
+
+// GateServer - server side of the Gate service, implemented by plugins
+type GateServer interface {
+	Check(context.Context, *CheckRequest) (*CheckResponse, error)
+}
+
+// TriggerServer - server side of the Trigger service, implemented by plugins
+type TriggerServer interface {
+	Watch(*WatchRequest, TriggerWatchServer) error
+}
+
+// TriggerWatchServer - event stream of the Watch call
+type TriggerWatchServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type triggerWatchServer struct {
+	grpc.ServerStream
+}
+
+func (s *triggerWatchServer) Send(event *Event) error {
+	return s.ServerStream.SendMsg(event)
+}
+
+// RegisterGateServer - registers Gate service of a Go plugin
+func RegisterGateServer(s *grpc.Server, srv GateServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "keel.plugin.v1.Gate",
+		HandlerType: (*GateServer)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Check",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := new(CheckRequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(GateServer).Check(ctx, req)
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: gateCheckMethod}
+				return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(GateServer).Check(ctx, req.(*CheckRequest))
+				})
+			},
+		}},
+		Metadata: "plugin.proto",
+	}, srv)
+}
+
+// RegisterTriggerServer - registers Trigger service of a Go plugin
+func RegisterTriggerServer(s *grpc.Server, srv TriggerServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "keel.plugin.v1.Trigger",
+		HandlerType: (*TriggerServer)(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Watch",
+			ServerStreams: true,
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				req := new(WatchRequest)
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				return srv.(TriggerServer).Watch(req, &triggerWatchServer{stream})
+			},
+		}},
+		Metadata: "plugin.proto",
+	}, srv)
+}
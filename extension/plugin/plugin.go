@@ -0,0 +1,173 @@
+// Package plugin - external gRPC plugins that are registered as triggers (stream
+// new versions of tracked images) or gates (allow, deny or modify events before
+// they are submitted to providers), see plugin.proto for the protocol.
+package plugin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/keel-hq/keel/types"
+)
+
+// plugin roles
+const (
+	RoleGate    = "gate"
+	RoleTrigger = "trigger"
+)
+
+const (
+	// DefaultTimeout - default timeout of gate checks
+	DefaultTimeout = 10 * time.Second
+	// DefaultResync - default interval after which trigger streams are reopened
+	// with the current tracked images
+	DefaultResync = 5 * time.Minute
+)
+
+// Config - plugin configuration
+type Config struct {
+	Name string `json:"name"`
+	// Address - plugin gRPC address, ie: keel-gate.tools:9000
+	Address string `json:"address"`
+	// Role - gate or trigger
+	Role string `json:"role"`
+	// Timeout - gate check timeout, ie: 5s
+	Timeout string `json:"timeout"`
+	// FailOpen - events are allowed when the gate can't be reached or fails,
+	// by default they are denied
+	FailOpen bool `json:"failOpen"`
+	// Resync - how often trigger streams are reopened with the current tracked images
+	Resync string `json:"resync"`
+	// TLS - connect to the plugin with TLS, CAFile is an optional CA bundle
+	TLS        bool   `json:"tls"`
+	CAFile     string `json:"caFile"`
+	ServerName string `json:"serverName"`
+}
+
+// PluginsConfig - plugins configuration file
+type PluginsConfig struct {
+	Plugins []Config `json:"plugins"`
+}
+
+// LoadConfig - loads plugins from a YAML (or JSON) file
+func LoadConfig(path string) ([]Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(data)
+}
+
+func parseConfig(data []byte) ([]Config, error) {
+	var cfg PluginsConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode plugins: %s", err)
+	}
+
+	names := make(map[string]bool)
+	for idx, c := range cfg.Plugins {
+		if c.Name == "" {
+			return nil, fmt.Errorf("plugins[%d]: name not set", idx)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("plugin %s: duplicate name", c.Name)
+		}
+		names[c.Name] = true
+		if c.Address == "" {
+			return nil, fmt.Errorf("plugin %s: address not set", c.Name)
+		}
+		if c.Role != RoleGate && c.Role != RoleTrigger {
+			return nil, fmt.Errorf("plugin %s: unknown role '%s', expected %s or %s", c.Name, c.Role, RoleGate, RoleTrigger)
+		}
+		if _, err := c.timeout(); err != nil {
+			return nil, fmt.Errorf("plugin %s: invalid timeout: %s", c.Name, err)
+		}
+		if _, err := c.resync(); err != nil {
+			return nil, fmt.Errorf("plugin %s: invalid resync: %s", c.Name, err)
+		}
+	}
+	return cfg.Plugins, nil
+}
+
+func parseDuration(value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive")
+	}
+	return d, nil
+}
+
+func (c Config) timeout() (time.Duration, error) {
+	return parseDuration(c.Timeout, DefaultTimeout)
+}
+
+func (c Config) resync() (time.Duration, error) {
+	return parseDuration(c.Resync, DefaultResync)
+}
+
+// dial - connection is established lazily, keel starts while plugins are unavailable
+func dial(cfg Config) (*grpc.ClientConn, error) {
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if cfg.TLS {
+		tlsCfg := &tls.Config{ServerName: cfg.ServerName}
+		if cfg.CAFile != "" {
+			ca, err := ioutil.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %s", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+			}
+			tlsCfg.RootCAs = pool
+		}
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg))}
+	}
+	return grpc.Dial(cfg.Address, opts...)
+}
+
+func fromEvent(event types.Event) *Event {
+	e := &Event{
+		Repository: event.Repository.Name,
+		Tag:        event.Repository.Tag,
+		Digest:     event.Repository.Digest,
+		Trigger:    event.TriggerName,
+		Target:     event.Target,
+		Chart:      event.Chart,
+	}
+	if !event.CreatedAt.IsZero() {
+		e.CreatedAt = event.CreatedAt.Unix()
+	}
+	return e
+}
+
+func toEvent(e *Event) types.Event {
+	event := types.Event{
+		Repository: types.Repository{
+			Name:   e.Repository,
+			Tag:    e.Tag,
+			Digest: e.Digest,
+		},
+		TriggerName: e.Trigger,
+		Target:      e.Target,
+		Chart:       e.Chart,
+		CreatedAt:   time.Now(),
+	}
+	if e.CreatedAt > 0 {
+		event.CreatedAt = time.Unix(e.CreatedAt, 0)
+	}
+	return event
+}
@@ -0,0 +1,246 @@
+package plugin
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+)
+
+type fakeProviders struct {
+	mu        sync.Mutex
+	submitted []types.Event
+	images    []*types.TrackedImage
+}
+
+func (p *fakeProviders) Submit(event types.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.submitted = append(p.submitted, event)
+	return nil
+}
+
+func (p *fakeProviders) events() []types.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]types.Event(nil), p.submitted...)
+}
+
+func (p *fakeProviders) TrackedImages() ([]*types.TrackedImage, error) {
+	return p.images, nil
+}
+
+func (p *fakeProviders) List() []string { return nil }
+
+func (p *fakeProviders) Stop() {}
+
+type fakeGate struct{}
+
+func (fakeGate) Check(ctx context.Context, req *CheckRequest) (*CheckResponse, error) {
+	switch req.Event.Tag {
+	case "1.0.0":
+		return &CheckResponse{Decision: DecisionAllow}, nil
+	case "1.1.0":
+		return &CheckResponse{
+			Decision: DecisionModify,
+			Reason:   "pinned digest",
+			Event:    &Event{Repository: req.Event.Repository, Tag: req.Event.Tag, Digest: "sha256:abc"},
+		}, nil
+	}
+	return &CheckResponse{Decision: DecisionDeny, Reason: "tag not scanned"}, nil
+}
+
+type fakeTrigger struct {
+	requests chan *WatchRequest
+}
+
+func (t *fakeTrigger) Watch(req *WatchRequest, stream TriggerWatchServer) error {
+	t.requests <- req
+	for _, img := range req.Images {
+		err := stream.Send(&Event{Repository: "karolisr/keel", Tag: "0.2.0", Trigger: img.Image})
+		if err != nil {
+			return err
+		}
+	}
+	<-stream.Context().Done()
+	return nil
+}
+
+func serve(t *testing.T, register func(s *grpc.Server)) (string, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	s := grpc.NewServer()
+	register(s)
+	go s.Serve(lis)
+	return lis.Addr().String(), s.Stop
+}
+
+func TestParseConfig(t *testing.T) {
+	cfgs, err := parseConfig([]byte(`
+plugins:
+  - name: scanner
+    address: scanner.tools:9000
+    role: gate
+    timeout: 2s
+  - name: artifactory
+    address: artifactory-trigger:9000
+    role: trigger
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(cfgs) != 2 {
+		t.Fatalf("expected 2 plugins, got %d", len(cfgs))
+	}
+	if timeout, _ := cfgs[0].timeout(); timeout != 2*time.Second {
+		t.Errorf("unexpected timeout: %s", timeout)
+	}
+	if resync, _ := cfgs[1].resync(); resync != DefaultResync {
+		t.Errorf("unexpected resync: %s", resync)
+	}
+
+	invalid := []string{
+		`plugins: [{name: x, address: a:1, role: hook}]`,
+		`plugins: [{name: x, role: gate}]`,
+		`plugins: [{address: a:1, role: gate}]`,
+		`plugins: [{name: x, address: a:1, role: gate, timeout: soon}]`,
+		`plugins: [{name: x, address: a:1, role: gate}, {name: x, address: b:1, role: gate}]`,
+	}
+	for _, data := range invalid {
+		if _, err := parseConfig([]byte(data)); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}
+
+func TestGateCheck(t *testing.T) {
+	addr, stop := serve(t, func(s *grpc.Server) { RegisterGateServer(s, fakeGate{}) })
+	defer stop()
+
+	gate, err := NewGate(Config{Name: "scanner", Address: addr, Role: RoleGate})
+	if err != nil {
+		t.Fatalf("failed to create gate: %s", err)
+	}
+	defer gate.Close()
+
+	event := types.Event{
+		Repository:  types.Repository{Name: "karolisr/keel", Tag: "1.0.0"},
+		TriggerName: "poll",
+		CreatedAt:   time.Unix(1500000000, 0),
+	}
+
+	decision, err := gate.Check(event)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !decision.Allowed || decision.Event != nil {
+		t.Errorf("expected event to be allowed unmodified: %+v", decision)
+	}
+
+	event.Repository.Tag = "1.1.0"
+	decision, err = gate.Check(event)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !decision.Allowed || decision.Event == nil {
+		t.Fatalf("expected modified event: %+v", decision)
+	}
+	if decision.Event.Repository.Digest != "sha256:abc" {
+		t.Errorf("unexpected digest: %s", decision.Event.Repository.Digest)
+	}
+	if decision.Event.TriggerName != "poll" || !decision.Event.CreatedAt.Equal(event.CreatedAt) {
+		t.Errorf("expected trigger and creation time to be kept: %+v", decision.Event)
+	}
+
+	event.Repository.Tag = "2.0.0"
+	decision, err = gate.Check(event)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if decision.Allowed || decision.Reason != "tag not scanned" {
+		t.Errorf("expected event to be denied: %+v", decision)
+	}
+}
+
+func TestGateUnavailable(t *testing.T) {
+	addr, stop := serve(t, func(s *grpc.Server) {})
+	stop()
+
+	event := types.Event{Repository: types.Repository{Name: "karolisr/keel", Tag: "1.0.0"}}
+
+	gate, err := NewGate(Config{Name: "scanner", Address: addr, Role: RoleGate, Timeout: "200ms"})
+	if err != nil {
+		t.Fatalf("failed to create gate: %s", err)
+	}
+	defer gate.Close()
+	if _, err := gate.Check(event); err == nil {
+		t.Errorf("expected error from unavailable gate")
+	}
+
+	failOpen, err := NewGate(Config{Name: "scanner", Address: addr, Role: RoleGate, Timeout: "200ms", FailOpen: true})
+	if err != nil {
+		t.Fatalf("failed to create gate: %s", err)
+	}
+	defer failOpen.Close()
+	decision, err := failOpen.Check(event)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !decision.Allowed {
+		t.Errorf("expected fail open gate to allow the event")
+	}
+}
+
+func TestTriggerWatch(t *testing.T) {
+	ft := &fakeTrigger{requests: make(chan *WatchRequest, 10)}
+	addr, stop := serve(t, func(s *grpc.Server) { RegisterTriggerServer(s, ft) })
+	defer stop()
+
+	ref, _ := image.Parse("karolisr/keel:0.1.0")
+	providers := &fakeProviders{
+		images: []*types.TrackedImage{{
+			Image:     ref,
+			Namespace: "default",
+			Provider:  "kubernetes",
+			Policy:    policy.NewSemverPolicy(policy.SemverPolicyTypeMinor),
+		}},
+	}
+
+	trigger, err := NewTrigger(Config{Name: "registry", Address: addr, Role: RoleTrigger, Resync: "200ms"}, providers)
+	if err != nil {
+		t.Fatalf("failed to create trigger: %s", err)
+	}
+	defer trigger.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go trigger.Start(ctx)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case req := <-ft.requests:
+			if len(req.Images) != 1 || req.Images[0].Namespace != "default" || req.Images[0].Policy != "minor" {
+				t.Fatalf("unexpected watch request: %v", req)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("watch %d not started, stream should be reopened on resync", i)
+		}
+	}
+
+	events := providers.events()
+	if len(events) == 0 {
+		t.Fatalf("expected events to be submitted")
+	}
+	if events[0].TriggerName != "plugin:registry" || events[0].Repository.Tag != "0.2.0" {
+		t.Errorf("unexpected event: %+v", events[0])
+	}
+}
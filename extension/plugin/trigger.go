@@ -0,0 +1,173 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/keel-hq/keel/provider"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const maxReconnectBackoff = 2 * time.Minute
+
+// Trigger - trigger plugin client, submits events streamed by the plugin
+type Trigger struct {
+	name      string
+	conn      *grpc.ClientConn
+	resync    time.Duration
+	providers provider.Providers
+}
+
+// NewTrigger - creates trigger plugin client
+func NewTrigger(cfg Config, providers provider.Providers) (*Trigger, error) {
+	resync, err := cfg.resync()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dial(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %s", cfg.Name, err)
+	}
+	return &Trigger{
+		name:      cfg.Name,
+		conn:      conn,
+		resync:    resync,
+		providers: providers,
+	}, nil
+}
+
+// TriggerName - trigger name of the plugin events
+func (t *Trigger) TriggerName() string {
+	return "plugin:" + t.name
+}
+
+// Start - watches for events until the context is cancelled, the stream is
+// reopened with the current tracked images every resync interval
+func (t *Trigger) Start(ctx context.Context) error {
+	log.WithFields(log.Fields{
+		"plugin": t.name,
+	}).Info("plugin.trigger: plugin trigger configured")
+
+	var backoff time.Duration
+	for {
+		err := t.watch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		wait := time.Duration(0)
+		if err != nil {
+			backoff = timeutil.ExpBackoff(backoff, maxReconnectBackoff)
+			wait = backoff
+			log.WithFields(log.Fields{
+				"error":  err,
+				"plugin": t.name,
+				"retry":  wait,
+			}).Error("plugin.trigger: watch failed")
+		} else {
+			backoff = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// watch - sends tracked images to the plugin and submits received events, returns
+// nil when the stream ends or the resync interval passes
+func (t *Trigger) watch(ctx context.Context) error {
+	trackedImages, err := t.providers.TrackedImages()
+	if err != nil {
+		return fmt.Errorf("failed to get tracked images: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.resync)
+	defer cancel()
+
+	stream, err := t.conn.NewStream(ctx, &grpc.StreamDesc{StreamName: "Watch", ServerStreams: true}, triggerWatchMethod)
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(watchRequest(trackedImages)); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	for {
+		e := new(Event)
+		err := stream.RecvMsg(e)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				// resync
+				return nil
+			}
+			return err
+		}
+		t.submit(e)
+	}
+}
+
+func (t *Trigger) submit(e *Event) {
+	if e.Repository == "" || e.Tag == "" {
+		log.WithFields(log.Fields{
+			"plugin":     t.name,
+			"repository": e.Repository,
+			"tag":        e.Tag,
+		}).Warn("plugin.trigger: event without repository or tag ignored")
+		return
+	}
+
+	event := toEvent(e)
+	event.TriggerName = t.TriggerName()
+
+	log.WithFields(log.Fields{
+		"plugin": t.name,
+		"image":  event.Repository.Name,
+		"tag":    event.Repository.Tag,
+	}).Debug("plugin.trigger: got event")
+
+	err := t.providers.Submit(event)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"plugin": t.name,
+			"image":  event.Repository.Name,
+		}).Error("plugin.trigger: failed to submit event")
+	}
+}
+
+func watchRequest(trackedImages []*types.TrackedImage) *WatchRequest {
+	req := &WatchRequest{}
+	for _, ti := range trackedImages {
+		image := &TrackedImage{
+			Image:     ti.Image.Remote(),
+			Namespace: ti.Namespace,
+			Provider:  ti.Provider,
+		}
+		if ti.Policy != nil {
+			image.Policy = ti.Policy.Name()
+		}
+		req.Images = append(req.Images, image)
+	}
+	return req
+}
+
+// Close - closes plugin connection
+func (t *Trigger) Close() error {
+	return t.conn.Close()
+}
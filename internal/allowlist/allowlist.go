@@ -0,0 +1,214 @@
+// Package allowlist fetches deployable tags from external endpoints (ie: a
+// release management system) and refreshes them periodically, resources with
+// allowlist:<name> policies are only updated to tags that were blessed upstream.
+package allowlist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/keel-hq/keel/internal/policy"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var allowlistTagsGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "tag_allowlist_tags",
+		Help: "How many tags are allowed, partitioned by allowlist.",
+	},
+	[]string{"allowlist"},
+)
+
+var allowlistFailuresCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "tag_allowlist_refresh_failures_total",
+		Help: "How many times allowlist couldn't be refreshed, partitioned by allowlist.",
+	},
+	[]string{"allowlist"},
+)
+
+func init() {
+	prometheus.MustRegister(allowlistTagsGauge)
+	prometheus.MustRegister(allowlistFailuresCounter)
+}
+
+const (
+	// DefaultInterval - how often allowlists are refreshed
+	DefaultInterval = 5 * time.Minute
+
+	timeout = 10 * time.Second
+	// maxBody - allowlists larger than it are rejected
+	maxBody = 5 << 20
+)
+
+// Source - named allowlist endpoint
+type Source struct {
+	Name string
+	URL  string
+}
+
+// ParseSources - parses comma separated name=URL pairs, ie:
+// payments=https://releases.example.com/api/payments/tags
+func ParseSources(s string) ([]Source, error) {
+	var sources []Source
+	seen := make(map[string]bool)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid allowlist %q, expected name=URL", pair)
+		}
+		u, err := url.Parse(parts[1])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid URL of allowlist %s: %q", parts[0], parts[1])
+		}
+		if seen[parts[0]] {
+			return nil, fmt.Errorf("allowlist %s is set more than once", parts[0])
+		}
+		seen[parts[0]] = true
+		sources = append(sources, Source{Name: parts[0], URL: parts[1]})
+	}
+	return sources, nil
+}
+
+// Opts - fetcher options
+type Opts struct {
+	Sources []Source
+	// Token - optional bearer token sent to the endpoints
+	Token string
+	// Interval - defaults to DefaultInterval
+	Interval time.Duration
+
+	Client *http.Client
+}
+
+// Fetcher - refreshes allowlists, the last fetched tags are kept when an
+// endpoint fails
+type Fetcher struct {
+	sources  []Source
+	token    string
+	interval time.Duration
+	client   *http.Client
+}
+
+// New - creates fetcher
+func New(opts *Opts) *Fetcher {
+	f := &Fetcher{
+		sources:  opts.Sources,
+		token:    opts.Token,
+		interval: opts.Interval,
+		client:   opts.Client,
+	}
+	if f.interval <= 0 {
+		f.interval = DefaultInterval
+	}
+	if f.client == nil {
+		f.client = &http.Client{Timeout: timeout}
+	}
+	return f
+}
+
+// Refresh - fetches every allowlist, returns the first error
+func (f *Fetcher) Refresh() error {
+	var firstErr error
+	for _, source := range f.sources {
+		tags, err := f.fetch(source)
+		if err != nil {
+			allowlistFailuresCounter.WithLabelValues(source.Name).Inc()
+			log.WithFields(log.Fields{
+				"error":     err,
+				"allowlist": source.Name,
+			}).Warn("allowlist.Fetcher: failed to refresh allowlist, keeping previous tags")
+			if firstErr == nil {
+				firstErr = fmt.Errorf("allowlist %s: %s", source.Name, err)
+			}
+			continue
+		}
+		policy.SetAllowedTags(source.Name, tags)
+		allowlistTagsGauge.WithLabelValues(source.Name).Set(float64(len(tags)))
+		log.WithFields(log.Fields{
+			"allowlist": source.Name,
+			"tags":      len(tags),
+		}).Debug("allowlist.Fetcher: allowlist refreshed")
+	}
+	return firstErr
+}
+
+// Start - refreshes allowlists every interval until ctx is done
+func (f *Fetcher) Start(ctx context.Context) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f.Refresh()
+		}
+	}
+}
+
+// fetch - endpoints respond with a JSON array of tags (oldest first), or an
+// object with the tags field, ie: {"tags": ["1.0.0", "1.1.0"]}
+func (f *Fetcher) fetch(source Source) ([]string, error) {
+	req, err := http.NewRequest("GET", source.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if f.token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.token)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got status %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxBody+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxBody {
+		return nil, fmt.Errorf("allowlist is larger than %d bytes", maxBody)
+	}
+	return parseTags(body)
+}
+
+func parseTags(body []byte) ([]string, error) {
+	var tags []string
+	if err := json.Unmarshal(body, &tags); err != nil {
+		var obj struct {
+			Tags []string `json:"tags"`
+		}
+		if err := json.Unmarshal(body, &obj); err != nil || obj.Tags == nil {
+			return nil, fmt.Errorf("expected JSON array of tags or an object with tags field")
+		}
+		tags = obj.Tags
+	}
+
+	allowed := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			allowed = append(allowed, tag)
+		}
+	}
+	return allowed, nil
+}
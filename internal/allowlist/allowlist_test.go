@@ -0,0 +1,69 @@
+package allowlist
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/keel-hq/keel/internal/policy"
+)
+
+func TestParseSources(t *testing.T) {
+	sources, err := ParseSources("payments=https://releases.example.com/api/payments/tags?env=prod, web=http://releases/web")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(sources) != 2 || sources[0].Name != "payments" || sources[0].URL != "https://releases.example.com/api/payments/tags?env=prod" || sources[1].Name != "web" {
+		t.Errorf("unexpected sources: %+v", sources)
+	}
+
+	for _, invalid := range []string{"payments", "=https://releases", "payments=releases", "a=http://x,a=http://y"} {
+		if _, err := ParseSources(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(status)
+		switch r.URL.Path {
+		case "/array":
+			fmt.Fprint(w, `["1.0.0", " 1.1.0 ", ""]`)
+		case "/object":
+			fmt.Fprint(w, `{"tags": ["2.0.0"]}`)
+		}
+	}))
+	defer srv.Close()
+	defer policy.RemoveAllowlist("array")
+	defer policy.RemoveAllowlist("object")
+
+	f := New(&Opts{
+		Sources: []Source{{Name: "array", URL: srv.URL + "/array"}, {Name: "object", URL: srv.URL + "/object"}},
+		Token:   "secret",
+	})
+	if err := f.Refresh(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if tags, ok := policy.AllowedTags("array"); !ok || len(tags) != 2 || tags[1] != "1.1.0" {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+	if tags, ok := policy.AllowedTags("object"); !ok || len(tags) != 1 || tags[0] != "2.0.0" {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+
+	// previous tags are kept when the endpoint fails
+	status = http.StatusInternalServerError
+	if err := f.Refresh(); err == nil {
+		t.Errorf("expected error")
+	}
+	if tags, ok := policy.AllowedTags("array"); !ok || len(tags) != 2 {
+		t.Errorf("expected previous tags to be kept, got: %v", tags)
+	}
+}
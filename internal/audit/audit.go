@@ -0,0 +1,378 @@
+// Package audit - forwards audit log entries and applied updates to an
+// external HTTPS endpoint (ie: SIEM) for security logging. It's separate from
+// notifications: events aren't filtered by level, they are buffered on disk
+// and retried until the endpoint accepts them.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var auditSentCounter = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "audit_webhook_sent_total",
+		Help: "How many audit events were accepted by the audit webhook.",
+	},
+)
+
+var auditDroppedCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "audit_webhook_dropped_total",
+		Help: "How many audit events were dropped, partitioned by reason (buffer_full, rejected, write_failed).",
+	},
+	[]string{"reason"},
+)
+
+var auditBufferedGauge = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "audit_webhook_buffered",
+		Help: "How many audit events are buffered on disk waiting to be sent.",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(auditSentCounter)
+	prometheus.MustRegister(auditDroppedCounter)
+	prometheus.MustRegister(auditBufferedGauge)
+}
+
+const (
+	// SignatureHeader - HMAC-SHA256 signature of the body, ie: sha256=<hex>
+	SignatureHeader = "X-Keel-Signature"
+	// DeliveryHeader - event ID, events are delivered at least once so
+	// receivers can use it to drop duplicates
+	DeliveryHeader = "X-Keel-Delivery"
+
+	// DefaultMaxBuffered - oldest events are dropped once the buffer is full
+	DefaultMaxBuffered = 10000
+
+	timeout    = 10 * time.Second
+	minBackOff = time.Second
+	maxBackOff = 5 * time.Minute
+)
+
+// event kinds
+const (
+	KindAuditLog = "audit_log"
+	KindUpdate   = "update"
+)
+
+// Event - JSON body sent to the endpoint
+type Event struct {
+	ID     string              `json:"id"`
+	Time   time.Time           `json:"time"`
+	Kind   string              `json:"kind"`
+	Audit  *types.AuditLog     `json:"audit,omitempty"`
+	Update *types.UpdateRecord `json:"update,omitempty"`
+}
+
+// Opts - audit sink options
+type Opts struct {
+	// Endpoint - HTTPS URL events are posted to
+	Endpoint string
+	// Secret - events are signed with it, see SignatureHeader
+	Secret string
+	// Dir - events are buffered in it until they are sent
+	Dir string
+	// MaxBuffered - defaults to DefaultMaxBuffered
+	MaxBuffered int
+
+	// Client - optional, ie: to trust custom CAs
+	Client *http.Client
+}
+
+// Sink - buffers events on disk and sends them in order
+type Sink struct {
+	endpoint    string
+	secret      []byte
+	dir         string
+	maxBuffered int
+	client      *http.Client
+
+	// mu - serializes buffer writes and trimming
+	mu     sync.Mutex
+	notify chan struct{}
+}
+
+// New - creates audit sink, buffered events of previous runs are sent once
+// it's started
+func New(opts *Opts) (*Sink, error) {
+	u, err := url.Parse(opts.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit webhook endpoint: %s", err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("audit webhook endpoint has to be an https URL, got: '%s'", opts.Endpoint)
+	}
+	if opts.Secret == "" {
+		return nil, errors.New("audit webhook secret is required")
+	}
+	if opts.Dir == "" {
+		return nil, errors.New("audit webhook buffer directory is required")
+	}
+	if err := os.MkdirAll(opts.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit webhook buffer directory: %s", err)
+	}
+
+	s := &Sink{
+		endpoint:    opts.Endpoint,
+		secret:      []byte(opts.Secret),
+		dir:         opts.Dir,
+		maxBuffered: opts.MaxBuffered,
+		client:      opts.Client,
+		notify:      make(chan struct{}, 1),
+	}
+	if s.maxBuffered <= 0 {
+		s.maxBuffered = DefaultMaxBuffered
+	}
+	if s.client == nil {
+		s.client = &http.Client{Timeout: timeout}
+	}
+	return s, nil
+}
+
+// RecordAuditLog - buffers audit log entry
+func (s *Sink) RecordAuditLog(entry *types.AuditLog) {
+	s.record(&Event{ID: entry.ID, Time: time.Now(), Kind: KindAuditLog, Audit: entry})
+}
+
+// RecordUpdate - buffers applied (or failed) update
+func (s *Sink) RecordUpdate(record *types.UpdateRecord) {
+	s.record(&Event{ID: record.ID, Time: time.Now(), Kind: KindUpdate, Update: record})
+}
+
+func (s *Sink) record(event *Event) {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if err := s.write(event); err != nil {
+		auditDroppedCounter.WithLabelValues("write_failed").Inc()
+		log.WithFields(log.Fields{
+			"error": err,
+			"id":    event.ID,
+			"kind":  event.Kind,
+		}).Error("audit.Sink: failed to buffer audit event")
+		return
+	}
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// write - stores event in the buffer, file names keep events in order
+func (s *Sink) write(event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := s.buffered()
+	if err != nil {
+		return err
+	}
+	for len(files) >= s.maxBuffered {
+		log.WithFields(log.Fields{
+			"file":         files[0],
+			"max_buffered": s.maxBuffered,
+		}).Warn("audit.Sink: buffer is full, dropping oldest audit event")
+		os.Remove(filepath.Join(s.dir, files[0]))
+		auditDroppedCounter.WithLabelValues("buffer_full").Inc()
+		files = files[1:]
+	}
+
+	name := fmt.Sprintf("%020d-%s.json", event.Time.UnixNano(), event.ID)
+	tmp := filepath.Join(s.dir, name+".tmp")
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	auditBufferedGauge.Set(float64(len(files) + 1))
+	return nil
+}
+
+// buffered - buffered event files, oldest first
+func (s *Sink) buffered() ([]string, error) {
+	infos, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".json") {
+			continue
+		}
+		files = append(files, info.Name())
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Pending - how many events wait to be sent
+func (s *Sink) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, _ := s.buffered()
+	return len(files)
+}
+
+// Start - sends buffered events until ctx is done, failed requests are
+// retried with backoff. Events that weren't sent stay on disk.
+func (s *Sink) Start(ctx context.Context) {
+	var backOff time.Duration
+	for {
+		sent, err := s.sendNext(ctx)
+		switch {
+		case err != nil:
+			// new events don't skip the backoff
+			backOff = nextBackOff(backOff)
+			log.WithFields(log.Fields{
+				"error":    err,
+				"endpoint": s.endpoint,
+				"retry_in": backOff,
+			}).Warn("audit.Sink: failed to send audit event")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backOff):
+			}
+		case !sent:
+			backOff = 0
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.notify:
+			}
+		default:
+			backOff = 0
+		}
+	}
+}
+
+func nextBackOff(current time.Duration) time.Duration {
+	if current == 0 {
+		return minBackOff
+	}
+	current *= 2
+	if current > maxBackOff {
+		return maxBackOff
+	}
+	return current
+}
+
+// sendNext - sends the oldest buffered event, sent is false when the buffer
+// is empty
+func (s *Sink) sendNext(ctx context.Context) (sent bool, err error) {
+	s.mu.Lock()
+	files, err := s.buffered()
+	s.mu.Unlock()
+	if err != nil {
+		return false, err
+	}
+	auditBufferedGauge.Set(float64(len(files)))
+	if len(files) == 0 {
+		return false, nil
+	}
+
+	path := filepath.Join(s.dir, files[0])
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		// dropped because the buffer was full
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = s.post(ctx, strings.TrimSuffix(files[0], ".json"), data)
+	if rejected, ok := err.(*rejectedError); ok {
+		// retrying won't help, ie: malformed event
+		auditDroppedCounter.WithLabelValues("rejected").Inc()
+		log.WithFields(log.Fields{
+			"error": rejected,
+			"file":  files[0],
+		}).Error("audit.Sink: audit event rejected by the endpoint, dropping it")
+	} else if err != nil {
+		return false, err
+	} else {
+		auditSentCounter.Inc()
+	}
+
+	s.mu.Lock()
+	os.Remove(path)
+	s.mu.Unlock()
+	return true, nil
+}
+
+type rejectedError struct {
+	status int
+}
+
+func (e *rejectedError) Error() string {
+	return fmt.Sprintf("got status %d", e.status)
+}
+
+func (s *Sink) post(ctx context.Context, name string, data []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(s.secret, data))
+	if i := strings.Index(name, "-"); i >= 0 {
+		req.Header.Set(DeliveryHeader, name[i+1:])
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("got status %d", resp.StatusCode)
+	default:
+		return &rejectedError{status: resp.StatusCode}
+	}
+}
+
+// Sign - HMAC-SHA256 signature of the body, ie: sha256=<hex>
+func Sign(secret, body []byte) string {
+	m := hmac.New(sha256.New, secret)
+	m.Write(body)
+	return "sha256=" + hex.EncodeToString(m.Sum(nil))
+}
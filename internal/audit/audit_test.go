@@ -0,0 +1,161 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	events   []Event
+	headers  []http.Header
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	if status == http.StatusOK {
+		body, _ := ioutil.ReadAll(req.Body)
+		var event Event
+		json.Unmarshal(body, &event)
+		if req.Header.Get(SignatureHeader) != Sign([]byte("secret"), body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		r.events = append(r.events, event)
+		r.headers = append(r.headers, req.Header)
+	}
+	w.WriteHeader(status)
+}
+
+func (r *receiver) received() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event{}, r.events...)
+}
+
+func newTestingSink(t *testing.T, srv *httptest.Server, dir string, maxBuffered int) *Sink {
+	sink, err := New(&Opts{
+		Endpoint:    srv.URL,
+		Secret:      "secret",
+		Dir:         dir,
+		MaxBuffered: maxBuffered,
+		Client:      srv.Client(),
+	})
+	if err != nil {
+		t.Fatalf("failed to create sink: %s", err)
+	}
+	return sink
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("failed to create dir: %s", err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestNewValidation(t *testing.T) {
+	dir, teardown := tempDir(t)
+	defer teardown()
+
+	for _, opts := range []*Opts{
+		{Endpoint: "http://siem.example.com", Secret: "secret", Dir: dir},
+		{Endpoint: "https://siem.example.com", Dir: dir},
+		{Endpoint: "https://siem.example.com", Secret: "secret"},
+	} {
+		if _, err := New(opts); err == nil {
+			t.Errorf("expected error for %+v", opts)
+		}
+	}
+}
+
+func TestSinkSendsInOrderWithRetries(t *testing.T) {
+	dir, teardown := tempDir(t)
+	defer teardown()
+
+	r := &receiver{statuses: []int{http.StatusServiceUnavailable, http.StatusOK, http.StatusBadRequest}}
+	srv := httptest.NewTLSServer(r)
+	defer srv.Close()
+
+	sink := newTestingSink(t, srv, dir, 0)
+	// first one is retried, second one is rejected and dropped
+	sink.RecordAuditLog(&types.AuditLog{ID: "1", Action: types.AuditActionApprovalApproved})
+	sink.RecordAuditLog(&types.AuditLog{ID: "2", Action: types.AuditActionApprovalRejected})
+	sink.RecordUpdate(&types.UpdateRecord{ID: "3", Identifier: "deployment/default/wd"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sink.Start(ctx)
+
+	waitFor(t, func() bool { return sink.Pending() == 0 })
+
+	events := r.received()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got: %+v", events)
+	}
+	if events[0].Kind != KindAuditLog || events[0].Audit.ID != "1" {
+		t.Errorf("unexpected first event: %+v", events[0])
+	}
+	if events[1].Kind != KindUpdate || events[1].Update.Identifier != "deployment/default/wd" {
+		t.Errorf("unexpected second event: %+v", events[1])
+	}
+	if r.headers[0].Get(DeliveryHeader) != "1" {
+		t.Errorf("unexpected delivery header: %s", r.headers[0].Get(DeliveryHeader))
+	}
+}
+
+func TestSinkBuffersOnDisk(t *testing.T) {
+	dir, teardown := tempDir(t)
+	defer teardown()
+
+	r := &receiver{}
+	srv := httptest.NewTLSServer(r)
+	defer srv.Close()
+
+	// buffered until it's started, oldest are dropped once the buffer is full
+	sink := newTestingSink(t, srv, dir, 2)
+	sink.RecordAuditLog(&types.AuditLog{ID: "1"})
+	sink.RecordAuditLog(&types.AuditLog{ID: "2"})
+	sink.RecordAuditLog(&types.AuditLog{ID: "3"})
+	if sink.Pending() != 2 {
+		t.Fatalf("expected 2 buffered events, got: %d", sink.Pending())
+	}
+
+	// events of the previous run are sent after restart
+	restarted := newTestingSink(t, srv, dir, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go restarted.Start(ctx)
+
+	waitFor(t, func() bool { return len(r.received()) == 2 })
+	events := r.received()
+	if events[0].ID != "2" || events[1].ID != "3" {
+		t.Errorf("unexpected events: %+v", events)
+	}
+}
@@ -0,0 +1,32 @@
+package audit
+
+import (
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+)
+
+// auditedStore - forwards audit log entries and update records to the sink
+// once they are created, everything else goes to the store
+type auditedStore struct {
+	store.Store
+	sink *Sink
+}
+
+// Store - wraps the store, approval votes, updates, pauses and changes made
+// through the API, bots and config file are all recorded through it
+func Store(s store.Store, sink *Sink) store.Store {
+	return &auditedStore{Store: s, sink: sink}
+}
+
+func (s *auditedStore) CreateAuditLog(entry *types.AuditLog) (string, error) {
+	id, err := s.Store.CreateAuditLog(entry)
+	// forwarded even when the database write failed
+	s.sink.RecordAuditLog(entry)
+	return id, err
+}
+
+func (s *auditedStore) CreateUpdateRecord(record *types.UpdateRecord) (*types.UpdateRecord, error) {
+	created, err := s.Store.CreateUpdateRecord(record)
+	s.sink.RecordUpdate(record)
+	return created, err
+}
@@ -0,0 +1,211 @@
+// Package backup - portable keel state archives. State is written either as a
+// single JSON document or as a gzipped tar with a manifest and one JSON file
+// per record kind, both formats are detected when reading.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+// archive formats
+const (
+	FormatJSON = "json"
+	FormatTar  = "tar"
+)
+
+const manifestFile = "manifest.json"
+
+// manifest - archive metadata
+type manifest struct {
+	Version     int       `json:"version"`
+	KeelVersion string    `json:"keelVersion"`
+	ExportedAt  time.Time `json:"exportedAt"`
+}
+
+// FormatFromPath - tar for .tar, .tar.gz and .tgz files, JSON otherwise
+func FormatFromPath(p string) string {
+	p = strings.ToLower(p)
+	if strings.HasSuffix(p, ".tar") || strings.HasSuffix(p, ".tar.gz") || strings.HasSuffix(p, ".tgz") {
+		return FormatTar
+	}
+	return FormatJSON
+}
+
+// Write - writes state in the given format
+func Write(w io.Writer, state *types.State, format string) error {
+	switch format {
+	case FormatJSON, "":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(state)
+	case FormatTar:
+		return writeTar(w, state)
+	}
+	return fmt.Errorf("unknown state format '%s', expected json or tar", format)
+}
+
+// Read - reads state written in any of the formats
+func Read(r io.Reader) (*types.State, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %s", err)
+	}
+
+	var state *types.State
+	if magic[0] == 0x1f && magic[1] == 0x8b {
+		state, err = readTar(br)
+	} else {
+		state = &types.State{}
+		err = json.NewDecoder(br).Decode(state)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Version > types.StateVersion {
+		return nil, fmt.Errorf("state version %d is not supported, keel supports version %d", state.Version, types.StateVersion)
+	}
+	return state, nil
+}
+
+// kinds - record kinds in the order they are written to archives
+var kinds = []string{
+	types.StateKindApprovals,
+	types.StateKindAuditLogs,
+	types.StateKindPausedResources,
+	types.StateKindUpdateRecords,
+	types.StateKindWebhookRecords,
+	types.StateKindAPITokens,
+	types.StateKindPollDigests,
+}
+
+// sections - records of each kind, stored in <kind>.json archive files
+func sections(state *types.State) map[string]interface{} {
+	return map[string]interface{}{
+		types.StateKindApprovals:       &state.Approvals,
+		types.StateKindAuditLogs:       &state.AuditLogs,
+		types.StateKindPausedResources: &state.PausedResources,
+		types.StateKindUpdateRecords:   &state.UpdateRecords,
+		types.StateKindWebhookRecords:  &state.WebhookRecords,
+		types.StateKindAPITokens:       &state.APITokens,
+		types.StateKindPollDigests:     &state.PollDigests,
+	}
+}
+
+func writeTar(w io.Writer, state *types.State) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	err := writeTarFile(tw, manifestFile, state.ExportedAt, &manifest{
+		Version:     state.Version,
+		KeelVersion: state.KeelVersion,
+		ExportedAt:  state.ExportedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	records := sections(state)
+	for _, kind := range kinds {
+		err = writeTarFile(tw, kind+".json", state.ExportedAt, records[kind])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func writeTarFile(tw *tar.Writer, name string, modTime time.Time, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+	return err
+}
+
+func readTar(r io.Reader) (*types.State, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	state := &types.State{}
+	targets := sections(state)
+	foundManifest := false
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+
+		name := path.Base(hdr.Name)
+		if name == manifestFile {
+			var m manifest
+			err = json.Unmarshal(data, &m)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %s", name, err)
+			}
+			state.Version = m.Version
+			state.KeelVersion = m.KeelVersion
+			state.ExportedAt = m.ExportedAt
+			foundManifest = true
+			continue
+		}
+
+		target, ok := targets[strings.TrimSuffix(name, ".json")]
+		if !ok {
+			// files of newer keel versions
+			continue
+		}
+		err = json.Unmarshal(data, target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", name, err)
+		}
+	}
+
+	if !foundManifest {
+		return nil, fmt.Errorf("invalid state archive, %s is missing", manifestFile)
+	}
+	return state, nil
+}
@@ -0,0 +1,77 @@
+package backup
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func testState() *types.State {
+	return &types.State{
+		Version:     types.StateVersion,
+		KeelVersion: "0.16.0",
+		ExportedAt:  time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Approvals: []*types.Approval{
+			{ID: "a-1", Identifier: "default/wd:1.1.0", VotesRequired: 2, VotesReceived: 1},
+		},
+		AuditLogs: []*types.AuditLog{
+			{ID: "l-1", Action: types.AuditActionApprovalApproved, Identifier: "default/wd:1.1.0"},
+		},
+		PausedResources: []*types.PausedResource{
+			{ID: "p-1", Identifier: "default/wd", User: "admin"},
+		},
+		APITokens: []*types.ExportedAPIToken{
+			{StoredAPIToken: types.StoredAPIToken{ID: "t-1", Name: "ci"}, Hash: "abc"},
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatTar} {
+		buf := &bytes.Buffer{}
+		err := Write(buf, testState(), format)
+		if err != nil {
+			t.Fatalf("%s: failed to write state: %s", format, err)
+		}
+
+		state, err := Read(buf)
+		if err != nil {
+			t.Fatalf("%s: failed to read state: %s", format, err)
+		}
+
+		if state.KeelVersion != "0.16.0" || !state.ExportedAt.Equal(testState().ExportedAt) {
+			t.Errorf("%s: unexpected metadata: %s %s", format, state.KeelVersion, state.ExportedAt)
+		}
+		if len(state.Approvals) != 1 || state.Approvals[0].VotesReceived != 1 {
+			t.Errorf("%s: unexpected approvals: %+v", format, state.Approvals)
+		}
+		if len(state.AuditLogs) != 1 || len(state.PausedResources) != 1 {
+			t.Errorf("%s: unexpected audit logs or paused resources", format)
+		}
+		if len(state.APITokens) != 1 || state.APITokens[0].Hash != "abc" {
+			t.Errorf("%s: expected token hash to be kept, got: %+v", format, state.APITokens)
+		}
+	}
+}
+
+func TestReadUnsupportedVersion(t *testing.T) {
+	_, err := Read(bytes.NewBufferString(`{"version": 99}`))
+	if err == nil {
+		t.Errorf("expected newer state version to be rejected")
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	for p, expected := range map[string]string{
+		"keel.json":         FormatJSON,
+		"keel.tar.gz":       FormatTar,
+		"/backups/keel.TGZ": FormatTar,
+		"keel":              FormatJSON,
+	} {
+		if got := FormatFromPath(p); got != expected {
+			t.Errorf("%s: expected %s, got %s", p, expected, got)
+		}
+	}
+}
@@ -0,0 +1,438 @@
+// Package bluegreen deploys new deployment versions as a clone (blue or green)
+// next to the version that serves traffic, and switches the Service selector to
+// it once it's ready and smoke tests pass. The previous color keeps running the
+// previous version so switching the selector back is a fast rollback.
+package bluegreen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typed_apps_v1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	typed_core_v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ColorLabel - label of blue and green pods, it's added to the Service selector
+// so only pods of the active color receive traffic
+const ColorLabel = "keel.sh/color"
+
+// colors
+const (
+	ColorBlue  = "blue"
+	ColorGreen = "green"
+)
+
+// phases reported while the new color is deployed
+const (
+	PhaseCreated   = "created"
+	PhaseSmokeTest = "smoke test"
+	PhaseSwitched  = "switched"
+	PhaseVerified  = "verified"
+)
+
+// defaults for blue/green annotations
+const (
+	DefaultReadyTimeout = 10 * time.Minute
+	DefaultVerify       = time.Minute
+
+	smokeTestTimeout = 5 * time.Minute
+)
+
+// checkInterval - how often deployments are checked while waiting for them
+var checkInterval = 5 * time.Second
+
+// Deployments - deployment operations used to manage colors
+type Deployments interface {
+	Get(name string, options meta_v1.GetOptions) (*apps_v1.Deployment, error)
+	Create(*apps_v1.Deployment) (*apps_v1.Deployment, error)
+	Update(*apps_v1.Deployment) (*apps_v1.Deployment, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+}
+
+// Services - service operations used to switch traffic
+type Services interface {
+	Get(name string, options meta_v1.GetOptions) (*core_v1.Service, error)
+	Update(*core_v1.Service) (*core_v1.Service, error)
+}
+
+// Controller - runs blue/green updates of deployments with keel.sh/blueGreenService annotation
+type Controller struct {
+	deployments func(namespace string) Deployments
+	services    func(namespace string) Services
+	client      *http.Client
+
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// New - creates blue/green controller
+func New(apps typed_apps_v1.DeploymentsGetter, core typed_core_v1.ServicesGetter) *Controller {
+	return newController(func(namespace string) Deployments {
+		return apps.Deployments(namespace)
+	}, func(namespace string) Services {
+		return core.Services(namespace)
+	})
+}
+
+func newController(deployments func(namespace string) Deployments, services func(namespace string) Services) *Controller {
+	return &Controller{
+		deployments: deployments,
+		services:    services,
+		client:      &http.Client{Timeout: smokeTestTimeout},
+		running:     make(map[string]bool),
+	}
+}
+
+// config - blue/green settings from resource annotations
+type config struct {
+	service   string
+	smokeTest string
+	verify    time.Duration
+}
+
+func parseConfig(annotations map[string]string) (*config, bool) {
+	service := annotations[types.KeelBlueGreenServiceAnnotation]
+	if service == "" {
+		return nil, false
+	}
+	cfg := &config{
+		service:   service,
+		smokeTest: annotations[types.KeelBlueGreenSmokeTestAnnotation],
+		verify:    DefaultVerify,
+	}
+	if value, ok := annotations[types.KeelBlueGreenVerifyAnnotation]; ok {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			log.WithFields(log.Fields{
+				"error": err,
+				"value": value,
+			}).Warn("bluegreen: invalid verify duration, using default")
+		} else {
+			cfg.verify = d
+		}
+	}
+	return cfg, true
+}
+
+// Enabled - blue/green updates are supported for deployments with keel.sh/blueGreenService annotation
+func (c *Controller) Enabled(resource *k8s.GenericResource) bool {
+	if _, ok := resource.GetResource().(*apps_v1.Deployment); !ok {
+		return false
+	}
+	_, ok := parseConfig(resource.GetAnnotations())
+	return ok
+}
+
+// Run - deploys updated resource as the inactive color, waits for it to become
+// ready, runs the smoke test and switches the Service to it. The new color has
+// to stay ready for the verify period, otherwise traffic is switched back. On
+// the first run current version is deployed as blue so there's a color to switch
+// back to. Returned error means that traffic stays on (or went back to) the
+// previous version.
+func (c *Controller) Run(current, updated *k8s.GenericResource, report func(phase, message string)) error {
+	currentDeployment, ok := current.GetResource().(*apps_v1.Deployment)
+	if !ok {
+		return fmt.Errorf("blue/green updates are not supported for %s", current.Kind())
+	}
+	updatedDeployment, ok := updated.GetResource().(*apps_v1.Deployment)
+	if !ok {
+		return fmt.Errorf("blue/green updates are not supported for %s", updated.Kind())
+	}
+	cfg, ok := parseConfig(updated.GetAnnotations())
+	if !ok {
+		return fmt.Errorf("blue/green service is not configured")
+	}
+
+	c.mu.Lock()
+	if c.running[updated.Identifier] {
+		c.mu.Unlock()
+		return fmt.Errorf("blue/green update of %s is already running", updated.Identifier)
+	}
+	c.running[updated.Identifier] = true
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.running, updated.Identifier)
+		c.mu.Unlock()
+	}()
+
+	deployments := c.deployments(updatedDeployment.Namespace)
+	services := c.services(updatedDeployment.Namespace)
+
+	svc, err := services.Get(cfg.service, meta_v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get service %s: %s", cfg.service, err)
+	}
+
+	active := svc.Spec.Selector[ColorLabel]
+	if active == "" {
+		blue := newColor(currentDeployment, ColorBlue)
+		if err := apply(deployments, blue); err != nil {
+			return fmt.Errorf("failed to create %s: %s", blue.Name, err)
+		}
+		if err := waitReady(deployments, blue.Name, DefaultReadyTimeout); err != nil {
+			return err
+		}
+		if err := switchService(services, cfg.service, ColorBlue); err != nil {
+			return fmt.Errorf("failed to switch service %s to %s: %s", cfg.service, ColorBlue, err)
+		}
+		active = ColorBlue
+		report(PhaseSwitched, fmt.Sprintf("current version deployed as %s, service %s switched to it", blue.Name, cfg.service))
+	}
+
+	target := ColorGreen
+	if active == ColorGreen {
+		target = ColorBlue
+	}
+	clone := newColor(updatedDeployment, target)
+
+	// target is switched back to the previous version when the update fails
+	previous, err := deployments.Get(clone.Name, meta_v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		previous, err = nil, nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %s", clone.Name, err)
+	}
+	fail := func(err error) error {
+		restore(deployments, clone.Name, previous)
+		return err
+	}
+
+	if err := apply(deployments, clone); err != nil {
+		return fail(fmt.Errorf("failed to create %s: %s", clone.Name, err))
+	}
+	report(PhaseCreated, fmt.Sprintf("%s deployment %s/%s created with the new version", target, clone.Namespace, clone.Name))
+
+	if err := waitReady(deployments, clone.Name, DefaultReadyTimeout); err != nil {
+		return fail(err)
+	}
+
+	if cfg.smokeTest != "" {
+		if err := c.smokeTest(cfg, clone, target); err != nil {
+			return fail(fmt.Errorf("smoke test failed: %s", err))
+		}
+		report(PhaseSmokeTest, "smoke test passed")
+	}
+
+	if err := switchService(services, cfg.service, target); err != nil {
+		return fail(fmt.Errorf("failed to switch service %s to %s: %s", cfg.service, target, err))
+	}
+	report(PhaseSwitched, fmt.Sprintf("service %s switched from %s to %s", cfg.service, active, target))
+
+	if err := verify(deployments, clone.Name, cfg.verify); err != nil {
+		if switchErr := switchService(services, cfg.service, active); switchErr != nil {
+			return fmt.Errorf("%s, failed to switch service %s back to %s: %s", err, cfg.service, active, switchErr)
+		}
+		return fail(fmt.Errorf("%s, service %s switched back to %s", err, cfg.service, active))
+	}
+	if cfg.verify > 0 {
+		report(PhaseVerified, fmt.Sprintf("%s stayed ready for %s, %s is kept for rollback", target, cfg.verify, active))
+	}
+	return nil
+}
+
+// apply - creates color deployment, existing one is updated to the new spec
+func apply(client Deployments, d *apps_v1.Deployment) error {
+	_, err := client.Create(d)
+	if err == nil || !errors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := client.Get(d.Name, meta_v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	existing.Labels = d.Labels
+	existing.Spec = d.Spec
+	_, err = client.Update(existing)
+	return err
+}
+
+// restore - color deployment goes back to the previous version, it's removed
+// when it didn't exist before the update
+func restore(client Deployments, name string, previous *apps_v1.Deployment) {
+	var err error
+	if previous == nil {
+		propagation := meta_v1.DeletePropagationBackground
+		err = client.Delete(name, &meta_v1.DeleteOptions{PropagationPolicy: &propagation})
+		if errors.IsNotFound(err) {
+			err = nil
+		}
+	} else {
+		var existing *apps_v1.Deployment
+		existing, err = client.Get(name, meta_v1.GetOptions{})
+		if err == nil {
+			existing.Spec = previous.Spec
+			_, err = client.Update(existing)
+		}
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  name,
+		}).Error("bluegreen: failed to restore deployment of the inactive color")
+	}
+}
+
+func switchService(client Services, name, color string) error {
+	svc, err := client.Get(name, meta_v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	selector := make(map[string]string, len(svc.Spec.Selector)+1)
+	for k, v := range svc.Spec.Selector {
+		selector[k] = v
+	}
+	selector[ColorLabel] = color
+	svc.Spec.Selector = selector
+	_, err = client.Update(svc)
+	return err
+}
+
+func desiredReplicas(d *apps_v1.Deployment) int32 {
+	if d.Spec.Replicas == nil {
+		return 1
+	}
+	return *d.Spec.Replicas
+}
+
+func ready(d *apps_v1.Deployment) bool {
+	replicas := desiredReplicas(d)
+	return d.Status.ObservedGeneration >= d.Generation && d.Status.UpdatedReplicas >= replicas && d.Status.ReadyReplicas >= replicas
+}
+
+func waitReady(client Deployments, name string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		d, err := client.Get(name, meta_v1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get %s: %s", name, err)
+		}
+		if ready(d) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s didn't become ready in %s, %d/%d replicas ready", name, timeout, d.Status.ReadyReplicas, desiredReplicas(d))
+		}
+		time.Sleep(checkInterval)
+	}
+}
+
+// verify - checks that replicas stay ready until the period ends
+func verify(client Deployments, name string, period time.Duration) error {
+	deadline := time.Now().Add(period)
+	for {
+		d, err := client.Get(name, meta_v1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get %s: %s", name, err)
+		}
+		if d.Status.ReadyReplicas < desiredReplicas(d) {
+			return fmt.Errorf("%s replicas became unavailable, %d/%d replicas ready", name, d.Status.ReadyReplicas, desiredReplicas(d))
+		}
+		if !time.Now().Add(checkInterval).Before(deadline) {
+			return nil
+		}
+		time.Sleep(checkInterval)
+	}
+}
+
+// smokeTestRequest - body of the smoke test request
+type smokeTestRequest struct {
+	Namespace  string   `json:"namespace"`
+	Deployment string   `json:"deployment"`
+	Service    string   `json:"service"`
+	Color      string   `json:"color"`
+	Images     []string `json:"images"`
+}
+
+// smokeTest - posts the new color to the hook, it has to respond with 2xx
+func (c *Controller) smokeTest(cfg *config, d *apps_v1.Deployment, color string) error {
+	var images []string
+	for _, container := range d.Spec.Template.Spec.Containers {
+		images = append(images, container.Image)
+	}
+	body, err := json.Marshal(&smokeTestRequest{
+		Namespace:  d.Namespace,
+		Deployment: d.Name,
+		Service:    cfg.service,
+		Color:      color,
+		Images:     images,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Post(cfg.smokeTest, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("got status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// newColor - copy of the deployment with the color label, keel annotations are
+// removed so colors themselves aren't updated by keel
+func newColor(deployment *apps_v1.Deployment, color string) *apps_v1.Deployment {
+	d := deployment.DeepCopy()
+
+	clone := &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        deployment.Name + "-" + color,
+			Namespace:   deployment.Namespace,
+			Labels:      withoutKeelKeys(d.Labels),
+			Annotations: withoutKeelKeys(d.Annotations),
+		},
+		Spec: d.Spec,
+	}
+	clone.Labels[ColorLabel] = color
+	if deployment.UID != "" {
+		clone.OwnerReferences = []meta_v1.OwnerReference{{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Name:       deployment.Name,
+			UID:        deployment.UID,
+		}}
+	}
+
+	if clone.Spec.Selector == nil {
+		clone.Spec.Selector = &meta_v1.LabelSelector{}
+	}
+	if clone.Spec.Selector.MatchLabels == nil {
+		clone.Spec.Selector.MatchLabels = make(map[string]string)
+	}
+	clone.Spec.Selector.MatchLabels[ColorLabel] = color
+	if clone.Spec.Template.Labels == nil {
+		clone.Spec.Template.Labels = make(map[string]string)
+	}
+	clone.Spec.Template.Labels[ColorLabel] = color
+
+	return clone
+}
+
+func withoutKeelKeys(meta map[string]string) map[string]string {
+	filtered := make(map[string]string, len(meta))
+	for k, v := range meta {
+		if strings.HasPrefix(k, "keel.sh/") || k == "kubernetes.io/change-cause" {
+			continue
+		}
+		filtered[k] = v
+	}
+	return filtered
+}
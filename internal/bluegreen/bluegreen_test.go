@@ -0,0 +1,290 @@
+package bluegreen
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fakeDeployments - deployments become ready as soon as they are created or updated
+type fakeDeployments struct {
+	mu          sync.Mutex
+	deployments map[string]*apps_v1.Deployment
+	// unavailable - deployments that lose their replicas
+	unavailable map[string]bool
+}
+
+func newFakeDeployments() *fakeDeployments {
+	return &fakeDeployments{
+		deployments: make(map[string]*apps_v1.Deployment),
+		unavailable: make(map[string]bool),
+	}
+}
+
+func (f *fakeDeployments) Get(name string, options meta_v1.GetOptions) (*apps_v1.Deployment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d, ok := f.deployments[name]
+	if !ok {
+		return nil, errors.NewNotFound(schema.GroupResource{Resource: "deployments"}, name)
+	}
+	d = d.DeepCopy()
+	if f.unavailable[name] {
+		d.Status.ReadyReplicas = 0
+	}
+	return d, nil
+}
+
+func (f *fakeDeployments) store(d *apps_v1.Deployment) *apps_v1.Deployment {
+	stored := d.DeepCopy()
+	stored.Status.UpdatedReplicas = desiredReplicas(d)
+	stored.Status.ReadyReplicas = desiredReplicas(d)
+	f.deployments[d.Name] = stored
+	return stored
+}
+
+func (f *fakeDeployments) Create(d *apps_v1.Deployment) (*apps_v1.Deployment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.deployments[d.Name]; ok {
+		return nil, errors.NewAlreadyExists(schema.GroupResource{Resource: "deployments"}, d.Name)
+	}
+	return f.store(d), nil
+}
+
+func (f *fakeDeployments) Update(d *apps_v1.Deployment) (*apps_v1.Deployment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.store(d), nil
+}
+
+func (f *fakeDeployments) Delete(name string, options *meta_v1.DeleteOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.deployments, name)
+	return nil
+}
+
+func (f *fakeDeployments) image(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d, ok := f.deployments[name]
+	if !ok {
+		return ""
+	}
+	return d.Spec.Template.Spec.Containers[0].Image
+}
+
+type fakeServices struct {
+	service  *core_v1.Service
+	switches []string
+	onSwitch func(color string)
+}
+
+func (f *fakeServices) Get(name string, options meta_v1.GetOptions) (*core_v1.Service, error) {
+	if f.service == nil || f.service.Name != name {
+		return nil, errors.NewNotFound(schema.GroupResource{Resource: "services"}, name)
+	}
+	return f.service.DeepCopy(), nil
+}
+
+func (f *fakeServices) Update(svc *core_v1.Service) (*core_v1.Service, error) {
+	f.service = svc.DeepCopy()
+	color := svc.Spec.Selector[ColorLabel]
+	f.switches = append(f.switches, color)
+	if f.onSwitch != nil {
+		f.onSwitch(color)
+	}
+	return svc, nil
+}
+
+func testDeployment(image string, annotations map[string]string) *k8s.GenericResource {
+	replicas := int32(3)
+	d := &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "default",
+			UID:         "123",
+			Labels:      map[string]string{"app": "web", types.KeelPolicyLabel: "all"},
+			Annotations: annotations,
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &meta_v1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: core_v1.PodTemplateSpec{
+				ObjectMeta: meta_v1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+				Spec: core_v1.PodSpec{
+					Containers: []core_v1.Container{{Image: image}},
+				},
+			},
+		},
+	}
+	gr, err := k8s.NewGenericResource(d)
+	if err != nil {
+		panic(err)
+	}
+	return gr
+}
+
+func testService(color string) *core_v1.Service {
+	selector := map[string]string{"app": "web"}
+	if color != "" {
+		selector[ColorLabel] = color
+	}
+	return &core_v1.Service{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       core_v1.ServiceSpec{Selector: selector},
+	}
+}
+
+func annotations(smokeTest string) map[string]string {
+	return map[string]string{
+		types.KeelBlueGreenServiceAnnotation:   "web",
+		types.KeelBlueGreenSmokeTestAnnotation: smokeTest,
+		types.KeelBlueGreenVerifyAnnotation:    "20ms",
+	}
+}
+
+func newTestController(deployments *fakeDeployments, services *fakeServices) *Controller {
+	checkInterval = time.Millisecond
+	return newController(func(string) Deployments { return deployments }, func(string) Services { return services })
+}
+
+func TestEnabled(t *testing.T) {
+	c := newController(nil, nil)
+	if c.Enabled(testDeployment("app:1", nil)) {
+		t.Errorf("expected blue/green to be disabled without annotation")
+	}
+	if !c.Enabled(testDeployment("app:1", annotations(""))) {
+		t.Errorf("expected blue/green to be enabled")
+	}
+}
+
+func TestRunFirstSwitch(t *testing.T) {
+	var smokeTests int
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		smokeTests++
+	}))
+	defer hook.Close()
+
+	deployments := newFakeDeployments()
+	services := &fakeServices{service: testService("")}
+	c := newTestController(deployments, services)
+
+	var phases []string
+	err := c.Run(testDeployment("app:1", annotations(hook.URL)), testDeployment("app:2", annotations(hook.URL)), func(phase, message string) {
+		phases = append(phases, phase)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if fmt.Sprint(phases) != fmt.Sprint([]string{PhaseSwitched, PhaseCreated, PhaseSmokeTest, PhaseSwitched, PhaseVerified}) {
+		t.Errorf("unexpected phases: %v", phases)
+	}
+	if deployments.image("web-blue") != "app:1" || deployments.image("web-green") != "app:2" {
+		t.Errorf("expected current version as blue and new version as green, got: %s, %s", deployments.image("web-blue"), deployments.image("web-green"))
+	}
+	if fmt.Sprint(services.switches) != fmt.Sprint([]string{ColorBlue, ColorGreen}) {
+		t.Errorf("unexpected switches: %v", services.switches)
+	}
+	if smokeTests != 1 {
+		t.Errorf("expected smoke test to be called once, got: %d", smokeTests)
+	}
+}
+
+func TestRunAlternatesColors(t *testing.T) {
+	deployments := newFakeDeployments()
+	deployments.store(newColor(testDeployment("app:1", nil).GetResource().(*apps_v1.Deployment), ColorBlue))
+	deployments.store(newColor(testDeployment("app:2", nil).GetResource().(*apps_v1.Deployment), ColorGreen))
+	services := &fakeServices{service: testService(ColorGreen)}
+	c := newTestController(deployments, services)
+
+	err := c.Run(testDeployment("app:2", annotations("")), testDeployment("app:3", annotations("")), func(phase, message string) {})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if deployments.image("web-blue") != "app:3" || deployments.image("web-green") != "app:2" {
+		t.Errorf("expected new version as blue, got: %s, %s", deployments.image("web-blue"), deployments.image("web-green"))
+	}
+	if fmt.Sprint(services.switches) != fmt.Sprint([]string{ColorBlue}) {
+		t.Errorf("unexpected switches: %v", services.switches)
+	}
+}
+
+func TestRunSmokeTestFailed(t *testing.T) {
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer hook.Close()
+
+	deployments := newFakeDeployments()
+	deployments.store(newColor(testDeployment("app:1", nil).GetResource().(*apps_v1.Deployment), ColorBlue))
+	services := &fakeServices{service: testService(ColorBlue)}
+	c := newTestController(deployments, services)
+
+	err := c.Run(testDeployment("app:1", annotations(hook.URL)), testDeployment("app:2", annotations(hook.URL)), func(phase, message string) {})
+	if err == nil {
+		t.Fatalf("expected smoke test to fail")
+	}
+	if len(services.switches) != 0 {
+		t.Errorf("expected traffic to stay on blue, got switches: %v", services.switches)
+	}
+	if deployments.image("web-green") != "" {
+		t.Errorf("expected green to be removed")
+	}
+}
+
+func TestRunVerifyFailed(t *testing.T) {
+	deployments := newFakeDeployments()
+	deployments.store(newColor(testDeployment("app:1", nil).GetResource().(*apps_v1.Deployment), ColorBlue))
+	deployments.store(newColor(testDeployment("app:0", nil).GetResource().(*apps_v1.Deployment), ColorGreen))
+	services := &fakeServices{service: testService(ColorBlue)}
+	services.onSwitch = func(color string) {
+		deployments.mu.Lock()
+		deployments.unavailable["web-green"] = color == ColorGreen
+		deployments.mu.Unlock()
+	}
+	c := newTestController(deployments, services)
+
+	err := c.Run(testDeployment("app:1", annotations("")), testDeployment("app:2", annotations("")), func(phase, message string) {})
+	if err == nil {
+		t.Fatalf("expected verification to fail")
+	}
+	if fmt.Sprint(services.switches) != fmt.Sprint([]string{ColorGreen, ColorBlue}) {
+		t.Errorf("expected traffic to be switched back to blue, got: %v", services.switches)
+	}
+	if deployments.image("web-green") != "app:0" {
+		t.Errorf("expected green to be restored to the previous version, got: %s", deployments.image("web-green"))
+	}
+}
+
+func TestNewColor(t *testing.T) {
+	resource := testDeployment("app:1", annotations(""))
+	clone := newColor(resource.GetResource().(*apps_v1.Deployment), ColorGreen)
+
+	if clone.Name != "web-green" {
+		t.Errorf("unexpected name: %s", clone.Name)
+	}
+	if _, ok := clone.Annotations[types.KeelBlueGreenServiceAnnotation]; ok {
+		t.Errorf("expected keel annotations to be removed")
+	}
+	if clone.Spec.Selector.MatchLabels[ColorLabel] != ColorGreen || clone.Spec.Template.Labels[ColorLabel] != ColorGreen {
+		t.Errorf("expected selector and pod labels to include color")
+	}
+	if _, ok := resource.GetResource().(*apps_v1.Deployment).Spec.Selector.MatchLabels[ColorLabel]; ok {
+		t.Errorf("original deployment selector was modified")
+	}
+}
@@ -0,0 +1,231 @@
+// Package bus - in-process event bus. Modules publish messages to topics
+// without knowing who consumes them, subscribers (bots, audit, metrics,
+// plugins) choose their buffer size and what happens when they fall behind.
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var busPublishedCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "event_bus_published_total",
+		Help: "How many messages were published, partitioned by topic.",
+	},
+	[]string{"topic"},
+)
+
+var busDroppedCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "event_bus_dropped_total",
+		Help: "How many messages were dropped because subscribers fell behind, partitioned by topic and subscriber.",
+	},
+	[]string{"topic", "subscriber"},
+)
+
+func init() {
+	prometheus.MustRegister(busPublishedCounter)
+	prometheus.MustRegister(busDroppedCounter)
+}
+
+// ErrUnexpectedType - message type doesn't match the topic type
+var ErrUnexpectedType = errors.New("unexpected message type")
+
+// Topic - named stream of messages of a single type
+type Topic struct {
+	Name string
+	typ  reflect.Type
+}
+
+// NewTopic - creates topic of the example message type, ie:
+// bus.NewTopic("approval.requested", (*types.Approval)(nil))
+func NewTopic(name string, example interface{}) Topic {
+	return Topic{Name: name, typ: reflect.TypeOf(example)}
+}
+
+func (t Topic) String() string {
+	return t.Name
+}
+
+// Policy - what happens with new messages when subscriber's buffer is full
+type Policy int
+
+// Available policies
+const (
+	// Block - publisher waits until the subscriber receives the message
+	// (backpressure), messages are never lost
+	Block Policy = iota
+	// DropNewest - new message is dropped
+	DropNewest
+	// DropOldest - oldest buffered message is dropped to make room for the new one
+	DropOldest
+)
+
+func (p Policy) String() string {
+	switch p {
+	case DropNewest:
+		return "drop-newest"
+	case DropOldest:
+		return "drop-oldest"
+	default:
+		return "block"
+	}
+}
+
+// SubscribeOpts - subscription options
+type SubscribeOpts struct {
+	// Name - subscriber name, used in logs and metrics
+	Name string
+	// Buffer - number of messages buffered for the subscriber
+	Buffer int
+	Policy Policy
+}
+
+// Subscription - messages of the topic, C is closed once the subscription
+// context is done
+type Subscription struct {
+	C <-chan interface{}
+
+	c     chan interface{}
+	topic Topic
+	opts  SubscribeOpts
+	done  chan struct{}
+	// mu - sends and closing of C
+	mu sync.Mutex
+}
+
+// Bus - in-process event bus
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[string]map[*Subscription]bool
+}
+
+// New - creates event bus
+func New() *Bus {
+	return &Bus{
+		subs: make(map[string]map[*Subscription]bool),
+	}
+}
+
+// Subscribe - subscribes to the topic until ctx is done
+func (b *Bus) Subscribe(ctx context.Context, topic Topic, opts SubscribeOpts) *Subscription {
+	if opts.Buffer < 0 {
+		opts.Buffer = 0
+	}
+	c := make(chan interface{}, opts.Buffer)
+	sub := &Subscription{
+		C:     c,
+		c:     c,
+		topic: topic,
+		opts:  opts,
+		done:  make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	if b.subs[topic.Name] == nil {
+		b.subs[topic.Name] = make(map[*Subscription]bool)
+	}
+	b.subs[topic.Name][sub] = true
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.unsubscribe(sub)
+	}()
+
+	log.WithFields(log.Fields{
+		"topic":      topic.Name,
+		"subscriber": opts.Name,
+		"buffer":     opts.Buffer,
+		"policy":     opts.Policy.String(),
+	}).Debug("bus: subscribed")
+
+	return sub
+}
+
+func (b *Bus) unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	delete(b.subs[sub.topic.Name], sub)
+	b.mu.Unlock()
+
+	// blocked publishers give up before the channel is closed
+	close(sub.done)
+	sub.mu.Lock()
+	close(sub.c)
+	sub.mu.Unlock()
+}
+
+// Publish - sends message to all subscribers of the topic, blocks while
+// subscribers with Block policy have full buffers
+func (b *Bus) Publish(topic Topic, msg interface{}) error {
+	if topic.typ != nil && reflect.TypeOf(msg) != topic.typ {
+		log.WithFields(log.Fields{
+			"topic":    topic.Name,
+			"expected": topic.typ.String(),
+			"type":     fmt.Sprintf("%T", msg),
+		}).Error("bus: unexpected message type")
+		return ErrUnexpectedType
+	}
+
+	b.mu.RLock()
+	subs := make([]*Subscription, 0, len(b.subs[topic.Name]))
+	for sub := range b.subs[topic.Name] {
+		subs = append(subs, sub)
+	}
+	b.mu.RUnlock()
+
+	busPublishedCounter.WithLabelValues(topic.Name).Inc()
+	for _, sub := range subs {
+		sub.send(msg)
+	}
+	return nil
+}
+
+func (s *Subscription) send(msg interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	if s.opts.Policy == Block {
+		select {
+		case s.c <- msg:
+		case <-s.done:
+		}
+		return
+	}
+
+	select {
+	case s.c <- msg:
+		return
+	default:
+	}
+
+	if s.opts.Policy == DropOldest {
+		select {
+		case <-s.c:
+		default:
+		}
+		select {
+		case s.c <- msg:
+		default:
+		}
+	}
+	busDroppedCounter.WithLabelValues(s.topic.Name, s.opts.Name).Inc()
+	log.WithFields(log.Fields{
+		"topic":      s.topic.Name,
+		"subscriber": s.opts.Name,
+		"policy":     s.opts.Policy.String(),
+	}).Warn("bus: subscriber is falling behind, message dropped")
+}
@@ -0,0 +1,135 @@
+package bus
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type testMessage struct {
+	ID int
+}
+
+var testTopic = NewTopic("test", (*testMessage)(nil))
+
+func TestPublishFanOut(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := New()
+	first := b.Subscribe(ctx, testTopic, SubscribeOpts{Name: "first", Buffer: 1})
+	second := b.Subscribe(ctx, testTopic, SubscribeOpts{Name: "second", Buffer: 1})
+
+	if err := b.Publish(testTopic, &testMessage{ID: 1}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, sub := range []*Subscription{first, second} {
+		msg := <-sub.C
+		if msg.(*testMessage).ID != 1 {
+			t.Errorf("unexpected message: %v", msg)
+		}
+	}
+}
+
+func TestPublishUnexpectedType(t *testing.T) {
+	b := New()
+	if err := b.Publish(testTopic, "foo"); err != ErrUnexpectedType {
+		t.Errorf("expected ErrUnexpectedType, got: %v", err)
+	}
+}
+
+func TestBlockPolicy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := New()
+	sub := b.Subscribe(ctx, testTopic, SubscribeOpts{Name: "slow", Policy: Block})
+
+	published := make(chan struct{})
+	go func() {
+		b.Publish(testTopic, &testMessage{ID: 1})
+		close(published)
+	}()
+
+	select {
+	case <-published:
+		t.Fatal("expected publisher to wait for the subscriber")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	<-sub.C
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("publisher is still blocked")
+	}
+}
+
+func TestDropPolicies(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		want   []int
+	}{
+		{policy: DropNewest, want: []int{1, 2}},
+		{policy: DropOldest, want: []int{2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			b := New()
+			sub := b.Subscribe(ctx, testTopic, SubscribeOpts{Name: "slow", Buffer: 2, Policy: tt.policy})
+			for i := 1; i <= 3; i++ {
+				b.Publish(testTopic, &testMessage{ID: i})
+			}
+
+			for _, id := range tt.want {
+				msg := <-sub.C
+				if msg.(*testMessage).ID != id {
+					t.Errorf("expected message %d, got: %d", id, msg.(*testMessage).ID)
+				}
+			}
+			if len(sub.C) != 0 {
+				t.Errorf("expected no more messages, got: %d", len(sub.C))
+			}
+		})
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b := New()
+	sub := b.Subscribe(ctx, testTopic, SubscribeOpts{Name: "gone", Policy: Block})
+
+	published := make(chan struct{})
+	go func() {
+		b.Publish(testTopic, &testMessage{ID: 1})
+		close(published)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("publisher is still blocked after subscriber went away")
+	}
+
+	select {
+	case _, ok := <-sub.C:
+		if ok {
+			t.Error("expected subscription to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscription wasn't closed")
+	}
+
+	// no subscribers left
+	if err := b.Publish(testTopic, &testMessage{ID: 2}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,318 @@
+// Package canary verifies new deployment versions on a subset of replicas
+// before the whole deployment is updated. Canary is a copy of the deployment
+// with the new version, its pods keep deployment labels so services route part
+// of the traffic to them while Prometheus queries are checked.
+package canary
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typed_apps_v1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TrackLabel - label added to canary pods, it's also part of canary selector so
+// canary pods aren't adopted by deployment replica sets
+const TrackLabel = "keel.sh/track"
+
+// canary phases reported while the canary is running
+const (
+	PhaseCreated  = "created"
+	PhaseAnalysis = "analysis"
+	PhasePassed   = "passed"
+)
+
+// defaults for canary annotations
+const (
+	DefaultAnalysis     = 5 * time.Minute
+	DefaultInterval     = time.Minute
+	DefaultReadyTimeout = 10 * time.Minute
+)
+
+// readyCheckInterval - how often canary deployment is checked while waiting for it to become ready
+var readyCheckInterval = 5 * time.Second
+
+// Querier - evaluates canary queries, ie: Prometheus
+type Querier interface {
+	Query(query string) (bool, error)
+}
+
+// Deployments - deployment operations used to manage canaries
+type Deployments interface {
+	Get(name string, options meta_v1.GetOptions) (*apps_v1.Deployment, error)
+	Create(*apps_v1.Deployment) (*apps_v1.Deployment, error)
+	Update(*apps_v1.Deployment) (*apps_v1.Deployment, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+}
+
+// Controller - runs canaries for deployments with keel.sh/canaryReplicas annotation
+type Controller struct {
+	deployments func(namespace string) Deployments
+	querier     Querier
+
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// New - creates canary controller
+func New(client typed_apps_v1.DeploymentsGetter, querier Querier) *Controller {
+	return newController(func(namespace string) Deployments {
+		return client.Deployments(namespace)
+	}, querier)
+}
+
+func newController(deployments func(namespace string) Deployments, querier Querier) *Controller {
+	return &Controller{
+		deployments: deployments,
+		querier:     querier,
+		running:     make(map[string]bool),
+	}
+}
+
+// config - canary settings from resource annotations
+type config struct {
+	replicas int32
+	queries  []string
+	analysis time.Duration
+	interval time.Duration
+}
+
+func parseConfig(annotations map[string]string) (*config, bool) {
+	value, ok := annotations[types.KeelCanaryReplicasAnnotation]
+	if !ok {
+		return nil, false
+	}
+	replicas, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || replicas <= 0 {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"replicas": value,
+		}).Warn("canary: invalid canary replicas, canary is disabled")
+		return nil, false
+	}
+
+	cfg := &config{
+		replicas: int32(replicas),
+		analysis: parseDuration(annotations, types.KeelCanaryAnalysisAnnotation, DefaultAnalysis),
+		interval: parseDuration(annotations, types.KeelCanaryIntervalAnnotation, DefaultInterval),
+	}
+	for _, q := range strings.Split(annotations[types.KeelCanaryQueriesAnnotation], "\n") {
+		if q = strings.TrimSpace(q); q != "" {
+			cfg.queries = append(cfg.queries, q)
+		}
+	}
+	return cfg, true
+}
+
+func parseDuration(annotations map[string]string, key string, defaultValue time.Duration) time.Duration {
+	value, ok := annotations[key]
+	if !ok {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"annotation": key,
+			"value":      value,
+		}).Warn("canary: invalid duration, using default")
+		return defaultValue
+	}
+	return d
+}
+
+// Enabled - canaries are supported for deployments with keel.sh/canaryReplicas annotation
+func (c *Controller) Enabled(resource *k8s.GenericResource) bool {
+	if _, ok := resource.GetResource().(*apps_v1.Deployment); !ok {
+		return false
+	}
+	_, ok := parseConfig(resource.GetAnnotations())
+	return ok
+}
+
+// Run - deploys updated resource as a canary, waits for it to become ready and
+// checks queries until analysis period ends. Canary is removed afterwards, returned
+// error means that the update should be rolled back (deployment stays on the
+// current version).
+func (c *Controller) Run(resource *k8s.GenericResource, report func(phase, message string)) error {
+	deployment, ok := resource.GetResource().(*apps_v1.Deployment)
+	if !ok {
+		return fmt.Errorf("canaries are not supported for %s", resource.Kind())
+	}
+	cfg, ok := parseConfig(resource.GetAnnotations())
+	if !ok {
+		return fmt.Errorf("canary is not configured")
+	}
+
+	c.mu.Lock()
+	if c.running[resource.Identifier] {
+		c.mu.Unlock()
+		return fmt.Errorf("canary of %s is already running", resource.Identifier)
+	}
+	c.running[resource.Identifier] = true
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.running, resource.Identifier)
+		c.mu.Unlock()
+	}()
+
+	client := c.deployments(deployment.Namespace)
+	canary := newCanary(deployment, cfg.replicas)
+
+	err := apply(client, canary)
+	if err != nil {
+		return fmt.Errorf("failed to create canary: %s", err)
+	}
+	defer func() {
+		propagation := meta_v1.DeletePropagationBackground
+		err := client.Delete(canary.Name, &meta_v1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil && !errors.IsNotFound(err) {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"namespace": canary.Namespace,
+				"name":      canary.Name,
+			}).Error("canary: failed to delete canary deployment")
+		}
+	}()
+	report(PhaseCreated, fmt.Sprintf("canary %s/%s created with %d replicas", canary.Namespace, canary.Name, cfg.replicas))
+
+	err = waitReady(client, canary.Name, cfg.replicas, DefaultReadyTimeout)
+	if err != nil {
+		return err
+	}
+	report(PhaseAnalysis, fmt.Sprintf("canary is ready, running %d queries every %s for %s", len(cfg.queries), cfg.interval, cfg.analysis))
+
+	err = c.analyse(client, canary, cfg)
+	if err != nil {
+		return err
+	}
+	report(PhasePassed, "canary analysis passed")
+	return nil
+}
+
+// apply - creates canary, canary left by previous run (ie: keel restarted) is replaced
+func apply(client Deployments, canary *apps_v1.Deployment) error {
+	_, err := client.Create(canary)
+	if err == nil || !errors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := client.Get(canary.Name, meta_v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	existing.Spec = canary.Spec
+	_, err = client.Update(existing)
+	return err
+}
+
+func waitReady(client Deployments, name string, replicas int32, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		d, err := client.Get(name, meta_v1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get canary: %s", err)
+		}
+		if d.Status.ObservedGeneration >= d.Generation && d.Status.UpdatedReplicas >= replicas && d.Status.ReadyReplicas >= replicas {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("canary didn't become ready in %s, %d/%d replicas ready", timeout, d.Status.ReadyReplicas, replicas)
+		}
+		time.Sleep(readyCheckInterval)
+	}
+}
+
+// analyse - checks that canary replicas stay ready and queries pass until analysis period ends
+func (c *Controller) analyse(client Deployments, canary *apps_v1.Deployment, cfg *config) error {
+	replacer := strings.NewReplacer("$canary", canary.Name, "$namespace", canary.Namespace)
+
+	deadline := time.Now().Add(cfg.analysis)
+	for {
+		d, err := client.Get(canary.Name, meta_v1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get canary: %s", err)
+		}
+		if d.Status.ReadyReplicas < cfg.replicas {
+			return fmt.Errorf("canary replicas became unavailable, %d/%d replicas ready", d.Status.ReadyReplicas, cfg.replicas)
+		}
+
+		for _, q := range cfg.queries {
+			query := replacer.Replace(q)
+			ok, err := c.querier.Query(query)
+			if err != nil {
+				return fmt.Errorf("canary query %q failed: %s", query, err)
+			}
+			if !ok {
+				return fmt.Errorf("canary check %q didn't pass", query)
+			}
+		}
+
+		if !time.Now().Add(cfg.interval).Before(deadline) {
+			return nil
+		}
+		time.Sleep(cfg.interval)
+	}
+}
+
+// newCanary - copy of the deployment with canary replicas, keel annotations are
+// removed so the canary itself isn't updated by keel
+func newCanary(deployment *apps_v1.Deployment, replicas int32) *apps_v1.Deployment {
+	d := deployment.DeepCopy()
+
+	canary := &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        deployment.Name + "-canary",
+			Namespace:   deployment.Namespace,
+			Labels:      withoutKeelKeys(d.Labels),
+			Annotations: withoutKeelKeys(d.Annotations),
+		},
+		Spec: d.Spec,
+	}
+	canary.Labels[TrackLabel] = "canary"
+	if deployment.UID != "" {
+		canary.OwnerReferences = []meta_v1.OwnerReference{{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Name:       deployment.Name,
+			UID:        deployment.UID,
+		}}
+	}
+
+	canary.Spec.Replicas = &replicas
+	if canary.Spec.Selector == nil {
+		canary.Spec.Selector = &meta_v1.LabelSelector{}
+	}
+	if canary.Spec.Selector.MatchLabels == nil {
+		canary.Spec.Selector.MatchLabels = make(map[string]string)
+	}
+	canary.Spec.Selector.MatchLabels[TrackLabel] = "canary"
+	if canary.Spec.Template.Labels == nil {
+		canary.Spec.Template.Labels = make(map[string]string)
+	}
+	canary.Spec.Template.Labels[TrackLabel] = "canary"
+
+	return canary
+}
+
+func withoutKeelKeys(meta map[string]string) map[string]string {
+	filtered := make(map[string]string, len(meta))
+	for k, v := range meta {
+		if strings.HasPrefix(k, "keel.sh/") || k == "kubernetes.io/change-cause" {
+			continue
+		}
+		filtered[k] = v
+	}
+	return filtered
+}
@@ -0,0 +1,207 @@
+package canary
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fakeDeployments - canaries become ready as soon as they are created
+type fakeDeployments struct {
+	mu          sync.Mutex
+	deployments map[string]*apps_v1.Deployment
+	deleted     []string
+}
+
+func (f *fakeDeployments) Get(name string, options meta_v1.GetOptions) (*apps_v1.Deployment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d, ok := f.deployments[name]
+	if !ok {
+		return nil, errors.NewNotFound(schema.GroupResource{Resource: "deployments"}, name)
+	}
+	return d.DeepCopy(), nil
+}
+
+func (f *fakeDeployments) Create(d *apps_v1.Deployment) (*apps_v1.Deployment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.deployments[d.Name]; ok {
+		return nil, errors.NewAlreadyExists(schema.GroupResource{Resource: "deployments"}, d.Name)
+	}
+	created := d.DeepCopy()
+	created.Status.UpdatedReplicas = *d.Spec.Replicas
+	created.Status.ReadyReplicas = *d.Spec.Replicas
+	f.deployments[d.Name] = created
+	return created, nil
+}
+
+func (f *fakeDeployments) Update(d *apps_v1.Deployment) (*apps_v1.Deployment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deployments[d.Name] = d.DeepCopy()
+	return d, nil
+}
+
+func (f *fakeDeployments) Delete(name string, options *meta_v1.DeleteOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.deployments, name)
+	f.deleted = append(f.deleted, name)
+	return nil
+}
+
+type fakeQuerier struct {
+	results map[string]bool
+	queries []string
+}
+
+func (q *fakeQuerier) Query(query string) (bool, error) {
+	q.queries = append(q.queries, query)
+	ok, found := q.results[query]
+	if !found {
+		return false, fmt.Errorf("unexpected query %q", query)
+	}
+	return ok, nil
+}
+
+func testDeployment(annotations map[string]string) *k8s.GenericResource {
+	replicas := int32(5)
+	d := &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "default",
+			UID:         "123",
+			Labels:      map[string]string{"app": "web", types.KeelPolicyLabel: "all"},
+			Annotations: annotations,
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &meta_v1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: core_v1.PodTemplateSpec{
+				ObjectMeta: meta_v1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+				Spec: core_v1.PodSpec{
+					Containers: []core_v1.Container{{Image: "karolisr/webhook-demo:0.0.2"}},
+				},
+			},
+		},
+	}
+	gr, err := k8s.NewGenericResource(d)
+	if err != nil {
+		panic(err)
+	}
+	return gr
+}
+
+func canaryAnnotations(queries string) map[string]string {
+	return map[string]string{
+		types.KeelCanaryReplicasAnnotation: "1",
+		types.KeelCanaryQueriesAnnotation:  queries,
+		types.KeelCanaryAnalysisAnnotation: "30ms",
+		types.KeelCanaryIntervalAnnotation: "10ms",
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	c := newController(nil, nil)
+	if c.Enabled(testDeployment(nil)) {
+		t.Errorf("expected canary to be disabled without annotation")
+	}
+	if c.Enabled(testDeployment(map[string]string{types.KeelCanaryReplicasAnnotation: "zero"})) {
+		t.Errorf("expected canary to be disabled with invalid replicas")
+	}
+	if !c.Enabled(testDeployment(canaryAnnotations(""))) {
+		t.Errorf("expected canary to be enabled")
+	}
+}
+
+func TestRunPassed(t *testing.T) {
+	readyCheckInterval = time.Millisecond
+	deployments := &fakeDeployments{deployments: make(map[string]*apps_v1.Deployment)}
+	querier := &fakeQuerier{results: map[string]bool{
+		`sum(rate(http_errors{pod=~"web-canary-.*",namespace="default"}[1m])) < 1`: true,
+	}}
+	c := newController(func(string) Deployments { return deployments }, querier)
+
+	var phases []string
+	err := c.Run(testDeployment(canaryAnnotations(`sum(rate(http_errors{pod=~"$canary-.*",namespace="$namespace"}[1m])) < 1`)), func(phase, message string) {
+		phases = append(phases, phase)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if fmt.Sprint(phases) != fmt.Sprint([]string{PhaseCreated, PhaseAnalysis, PhasePassed}) {
+		t.Errorf("unexpected phases: %v", phases)
+	}
+	if len(querier.queries) < 2 {
+		t.Errorf("expected queries to be checked several times, got: %d", len(querier.queries))
+	}
+	if len(deployments.deleted) != 1 || deployments.deleted[0] != "web-canary" {
+		t.Errorf("expected canary to be deleted, got: %v", deployments.deleted)
+	}
+}
+
+func TestRunFailed(t *testing.T) {
+	readyCheckInterval = time.Millisecond
+	deployments := &fakeDeployments{deployments: make(map[string]*apps_v1.Deployment)}
+	querier := &fakeQuerier{results: map[string]bool{"error_ratio < 0.01": false}}
+	c := newController(func(string) Deployments { return deployments }, querier)
+
+	var phases []string
+	err := c.Run(testDeployment(canaryAnnotations("error_ratio < 0.01")), func(phase, message string) {
+		phases = append(phases, phase)
+	})
+	if err == nil {
+		t.Fatalf("expected canary to fail")
+	}
+	if len(phases) != 2 || phases[1] != PhaseAnalysis {
+		t.Errorf("unexpected phases: %v", phases)
+	}
+	if len(deployments.deployments) != 0 {
+		t.Errorf("expected canary to be deleted")
+	}
+}
+
+func TestNewCanary(t *testing.T) {
+	resource := testDeployment(canaryAnnotations(""))
+	canary := newCanary(resource.GetResource().(*apps_v1.Deployment), 2)
+
+	if canary.Name != "web-canary" {
+		t.Errorf("unexpected name: %s", canary.Name)
+	}
+	if *canary.Spec.Replicas != 2 {
+		t.Errorf("unexpected replicas: %d", *canary.Spec.Replicas)
+	}
+	if _, ok := canary.Labels[types.KeelPolicyLabel]; ok {
+		t.Errorf("expected keel policy to be removed from canary")
+	}
+	if _, ok := canary.Annotations[types.KeelCanaryReplicasAnnotation]; ok {
+		t.Errorf("expected keel annotations to be removed from canary")
+	}
+	if canary.Spec.Selector.MatchLabels[TrackLabel] != "canary" || canary.Spec.Template.Labels[TrackLabel] != "canary" {
+		t.Errorf("expected canary selector and pod labels to include track label")
+	}
+	if canary.Spec.Template.Labels["app"] != "web" {
+		t.Errorf("expected pod labels to be kept")
+	}
+	if len(canary.OwnerReferences) != 1 || canary.OwnerReferences[0].UID != "123" {
+		t.Errorf("expected canary to be owned by deployment")
+	}
+
+	// original deployment is not modified
+	original := resource.GetResource().(*apps_v1.Deployment)
+	if _, ok := original.Spec.Selector.MatchLabels[TrackLabel]; ok {
+		t.Errorf("original deployment selector was modified")
+	}
+}
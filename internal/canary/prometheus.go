@@ -0,0 +1,79 @@
+package canary
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prometheus - evaluates canary queries with Prometheus HTTP API
+type Prometheus struct {
+	address string
+	client  *http.Client
+}
+
+// NewPrometheus - creates Prometheus client, address is Prometheus base URL,
+// ie: http://prometheus.monitoring:9090
+func NewPrometheus(address string) *Prometheus {
+	return &Prometheus{
+		address: strings.TrimSuffix(address, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type queryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// Query - evaluates instant query, check passes when vector or matrix result
+// isn't empty (comparison operators filter out samples that don't match) or
+// when scalar result isn't zero
+func (p *Prometheus) Query(query string) (bool, error) {
+	resp, err := p.client.Get(p.address + "/api/v1/query?" + url.Values{"query": {query}}.Encode())
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	var qr queryResponse
+	err = json.NewDecoder(resp.Body).Decode(&qr)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode prometheus response (status %d): %s", resp.StatusCode, err)
+	}
+	if qr.Status != "success" {
+		return false, fmt.Errorf("prometheus query failed: %s", qr.Error)
+	}
+
+	switch qr.Data.ResultType {
+	case "vector", "matrix":
+		var samples []json.RawMessage
+		err = json.Unmarshal(qr.Data.Result, &samples)
+		if err != nil {
+			return false, err
+		}
+		return len(samples) > 0, nil
+	case "scalar":
+		// [ <unix_time>, "<value>" ]
+		var sample []interface{}
+		err = json.Unmarshal(qr.Data.Result, &sample)
+		if err != nil || len(sample) != 2 {
+			return false, fmt.Errorf("unexpected scalar result: %s", string(qr.Data.Result))
+		}
+		value, _ := sample[1].(string)
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false, fmt.Errorf("unexpected scalar value %q", value)
+		}
+		return v != 0, nil
+	}
+	return false, fmt.Errorf("unsupported result type %q", qr.Data.ResultType)
+}
@@ -0,0 +1,47 @@
+package canary
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPrometheusQuery(t *testing.T) {
+	responses := map[string]string{
+		"passing": `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1,"0.001"]}]}}`,
+		"failing": `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+		"scalar":  `{"status":"success","data":{"resultType":"scalar","result":[1,"1"]}}`,
+		"invalid": `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Write([]byte(responses[r.URL.Query().Get("query")]))
+	}))
+	defer srv.Close()
+
+	p := NewPrometheus(srv.URL + "/")
+
+	tests := []struct {
+		query   string
+		want    bool
+		wantErr bool
+	}{
+		{query: "passing", want: true},
+		{query: "failing", want: false},
+		{query: "scalar", want: true},
+		{query: "invalid", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := p.Query(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Query() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Query() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
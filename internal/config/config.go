@@ -0,0 +1,389 @@
+// Package config - keel config file, usually mounted from a ConfigMap. Settings
+// are exported as environment variables so modules keep reading them as
+// before, variables set on the container take precedence over the file.
+// Reloadable sections (bot aliases and prefixes, notification level and
+// filters, policy defaults, registries, registry credentials and log levels)
+// are applied again when the file changes, other changes need a restart.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/rusenask/cron"
+	"github.com/sirupsen/logrus"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/internal/logging"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+)
+
+// Config - keel config file, ie:
+//
+//	triggers:
+//	  poll:
+//	    workers: 20
+//	bots:
+//	  aliases:
+//	    ship: approve
+//	  slack:
+//	    token: $SLACK_TOKEN
+//	    approvalsChannel: deployments
+//	notifications:
+//	  level: info
+//	  filters:
+//	    pagerduty:
+//	      level: error
+//	policies:
+//	  pollSchedule: "@every 5m"
+//	registries:
+//	  registry.corp.example.com:
+//	    ca: /etc/keel/tls/corp-ca.pem
+//	credentials:
+//	  registry.corp.example.com:
+//	    username: keel
+//	    password: $CORP_REGISTRY_PASSWORD
+//	env:
+//	  ARGO_ROLLOUTS: "true"
+//
+// Environment variables in string values are expanded.
+type Config struct {
+	Triggers      Triggers      `json:"triggers"`
+	Bots          Bots          `json:"bots"`
+	Notifications Notifications `json:"notifications"`
+	Policies      Policies      `json:"policies"`
+
+	// Registries - per-registry TLS, proxy and mirror settings, see
+	// registry.ParseConfig (reloadable)
+	Registries map[string]registry.HostConfig `json:"registries"`
+	// Credentials - registry credentials keyed by registry host, see
+	// credentialshelper.ParseRegistryCredentials (reloadable). Passwords
+	// should reference environment variables set from Secrets.
+	Credentials map[string]credentialshelper.RegistryCredentials `json:"credentials"`
+	// Logging - log levels (reloadable)
+	Logging logging.Levels `json:"logging"`
+
+	// Env - any other settings, keyed by their environment variable
+	Env map[string]string `json:"env"`
+}
+
+// Triggers - trigger settings
+type Triggers struct {
+	Poll   Poll   `json:"poll"`
+	PubSub PubSub `json:"pubsub"`
+	NATS   NATS   `json:"nats"`
+	ECR    ECR    `json:"ecr"`
+}
+
+// Poll - poll trigger, enabled by default
+type Poll struct {
+	Enabled             *bool  `json:"enabled"`
+	Workers             *int   `json:"workers"`
+	RegistryConcurrency *int   `json:"registryConcurrency"`
+	Jitter              string `json:"jitter"`
+	Platform            string `json:"platform"`
+	CacheTTL            string `json:"cacheTTL"`
+}
+
+// PubSub - Google Cloud Pub/Sub trigger
+type PubSub struct {
+	Enabled   bool   `json:"enabled"`
+	ProjectID string `json:"projectID"`
+}
+
+// NATS - NATS trigger
+type NATS struct {
+	URL     string `json:"url"`
+	Subject string `json:"subject"`
+	Queue   string `json:"queue"`
+	Stream  string `json:"stream"`
+	Durable string `json:"durable"`
+}
+
+// ECR - ECR events trigger
+type ECR struct {
+	QueueURL string `json:"queueURL"`
+}
+
+// Bots - bot settings
+type Bots struct {
+	Locale string `json:"locale"`
+	// Aliases - command aliases, ie: ship: approve (reloadable)
+	Aliases map[string]string `json:"aliases"`
+	// Prefixes - prefixes bots respond to, ie: !keel (reloadable)
+	Prefixes []string `json:"prefixes"`
+	// DisconnectThreshold - how long bots can be disconnected before keel
+	// reports them unhealthy, ie: 5m
+	DisconnectThreshold string `json:"disconnectThreshold"`
+
+	Slack   Slack   `json:"slack"`
+	Chatops Chatops `json:"chatops"`
+}
+
+// Slack - Slack bot, channels are also used by Slack notifications
+type Slack struct {
+	Token            string   `json:"token"`
+	BotName          string   `json:"botName"`
+	Channels         []string `json:"channels"`
+	ApprovalsChannel string   `json:"approvalsChannel"`
+}
+
+// Chatops - generic chatops bot
+type Chatops struct {
+	WebhookURL string `json:"webhookURL"`
+	Secret     string `json:"secret"`
+	Port       int    `json:"port"`
+	Channel    string `json:"channel"`
+}
+
+// Notifications - notification settings
+type Notifications struct {
+	// Level - minimum level, defaults to info (reloadable)
+	Level string `json:"level"`
+	// Filters - sender and endpoint filters, see notification.ParseFilters (reloadable)
+	Filters map[string]*notification.FilterConfig `json:"filters"`
+
+	DigestInterval string   `json:"digestInterval"`
+	DigestSenders  []string `json:"digestSenders"`
+
+	Webhook    Webhook    `json:"webhook"`
+	Teams      Teams      `json:"teams"`
+	Mattermost Mattermost `json:"mattermost"`
+}
+
+// Webhook - webhook notifications
+type Webhook struct {
+	Endpoint string `json:"endpoint"`
+}
+
+// Teams - Microsoft Teams notifications
+type Teams struct {
+	WebhookURL string `json:"webhookURL"`
+}
+
+// Mattermost - Mattermost notifications
+type Mattermost struct {
+	Endpoint string `json:"endpoint"`
+	Username string `json:"username"`
+}
+
+// Policies - defaults of workloads that don't set them with annotations (reloadable)
+type Policies struct {
+	PollSchedule string `json:"pollSchedule"`
+	// ApprovalDeadline - hours
+	ApprovalDeadline int `json:"approvalDeadline"`
+}
+
+// Load - reads and validates config file
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse - parses and validates YAML (or JSON) config, unknown fields are
+// rejected so typos don't go unnoticed
+func Parse(data []byte) (*Config, error) {
+	data, err := yaml.YAMLToJSON([]byte(os.ExpandEnv(string(data))))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode config: %s", err)
+	}
+
+	cfg := &Config{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %s", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate - checks values, registries are checked once they are applied as
+// their certificates are loaded
+func (c *Config) Validate() error {
+	durations := map[string]string{
+		"triggers.poll.jitter":         c.Triggers.Poll.Jitter,
+		"triggers.poll.cacheTTL":       c.Triggers.Poll.CacheTTL,
+		"notifications.digestInterval": c.Notifications.DigestInterval,
+		"bots.disconnectThreshold":     c.Bots.DisconnectThreshold,
+	}
+	for field, value := range durations {
+		if value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("invalid %s: %s", field, err)
+		}
+	}
+
+	if c.Triggers.Poll.Workers != nil && *c.Triggers.Poll.Workers < 0 {
+		return fmt.Errorf("invalid triggers.poll.workers: %d, expected 0 or more", *c.Triggers.Poll.Workers)
+	}
+	if c.Triggers.Poll.RegistryConcurrency != nil && *c.Triggers.Poll.RegistryConcurrency < 0 {
+		return fmt.Errorf("invalid triggers.poll.registryConcurrency: %d, expected 0 or more", *c.Triggers.Poll.RegistryConcurrency)
+	}
+	if c.Bots.Chatops.Port < 0 || c.Bots.Chatops.Port > 65535 {
+		return fmt.Errorf("invalid bots.chatops.port: %d", c.Bots.Chatops.Port)
+	}
+
+	for alias, command := range c.Bots.Aliases {
+		if strings.TrimSpace(alias) == "" || strings.TrimSpace(command) == "" || strings.ContainsAny(alias+command, "=,") {
+			return fmt.Errorf("invalid bots.aliases: '%s: %s'", alias, command)
+		}
+	}
+
+	if c.Notifications.Level != "" {
+		if _, err := types.ParseLevel(c.Notifications.Level); err != nil {
+			return fmt.Errorf("invalid notifications.level: %s", err)
+		}
+	}
+	if _, err := notification.NewFilters(c.Notifications.Filters); err != nil {
+		return fmt.Errorf("invalid notifications.filters: %s", err)
+	}
+
+	if c.Policies.PollSchedule != "" {
+		if _, err := cron.Parse(c.Policies.PollSchedule); err != nil {
+			return fmt.Errorf("invalid policies.pollSchedule: %s", err)
+		}
+	}
+	if c.Policies.ApprovalDeadline < 0 {
+		return fmt.Errorf("invalid policies.approvalDeadline: %d, expected hours", c.Policies.ApprovalDeadline)
+	}
+
+	if err := credentialshelper.ValidateRegistryCredentials(c.Credentials); err != nil {
+		return fmt.Errorf("invalid credentials: %s", err)
+	}
+
+	if c.Logging.Default != "" {
+		if _, err := logrus.ParseLevel(c.Logging.Default); err != nil {
+			return fmt.Errorf("invalid logging.default: %s", err)
+		}
+	}
+	for module, level := range c.Logging.Modules {
+		if _, err := logrus.ParseLevel(level); err != nil {
+			return fmt.Errorf("invalid logging.modules.%s: %s", module, err)
+		}
+	}
+
+	for name := range c.Env {
+		if name == "" || strings.ContainsAny(name, "= ") {
+			return fmt.Errorf("invalid env variable name '%s'", name)
+		}
+	}
+	return nil
+}
+
+// Environment - settings of static sections as environment variables
+func (c *Config) Environment() map[string]string {
+	env := make(map[string]string)
+	set := func(name, value string) {
+		if value != "" {
+			env[name] = value
+		}
+	}
+
+	for name, value := range c.Env {
+		env[name] = value
+	}
+
+	poll := c.Triggers.Poll
+	if poll.Enabled != nil {
+		env["POLL"] = "1"
+		if !*poll.Enabled {
+			env["POLL"] = "0"
+		}
+	}
+	if poll.Workers != nil {
+		env["POLL_WORKERS"] = strconv.Itoa(*poll.Workers)
+	}
+	if poll.RegistryConcurrency != nil {
+		env["POLL_REGISTRY_CONCURRENCY"] = strconv.Itoa(*poll.RegistryConcurrency)
+	}
+	set("POLL_JITTER", poll.Jitter)
+	set("POLL_PLATFORM", poll.Platform)
+	set("POLL_CACHE_TTL", poll.CacheTTL)
+
+	if c.Triggers.PubSub.Enabled {
+		env["PUBSUB"] = "1"
+	}
+	set("PROJECT_ID", c.Triggers.PubSub.ProjectID)
+
+	set("NATS_URL", c.Triggers.NATS.URL)
+	set("NATS_SUBJECT", c.Triggers.NATS.Subject)
+	set("NATS_QUEUE", c.Triggers.NATS.Queue)
+	set("NATS_STREAM", c.Triggers.NATS.Stream)
+	set("NATS_DURABLE", c.Triggers.NATS.Durable)
+	set("ECR_SQS_QUEUE_URL", c.Triggers.ECR.QueueURL)
+
+	set(constants.EnvBotLocale, c.Bots.Locale)
+	set(constants.EnvBotDisconnectThreshold, c.Bots.DisconnectThreshold)
+	set(constants.EnvSlackToken, c.Bots.Slack.Token)
+	set(constants.EnvSlackBotName, c.Bots.Slack.BotName)
+	set(constants.EnvSlackChannels, strings.Join(c.Bots.Slack.Channels, ","))
+	set(constants.EnvSlackApprovalsChannel, c.Bots.Slack.ApprovalsChannel)
+	set(constants.EnvChatopsWebhookURL, c.Bots.Chatops.WebhookURL)
+	set(constants.EnvChatopsSecret, c.Bots.Chatops.Secret)
+	if c.Bots.Chatops.Port != 0 {
+		env[constants.EnvChatopsPort] = strconv.Itoa(c.Bots.Chatops.Port)
+	}
+	set(constants.EnvChatopsChannel, c.Bots.Chatops.Channel)
+
+	set(constants.EnvNotificationDigestInterval, c.Notifications.DigestInterval)
+	set(constants.EnvNotificationDigestSenders, strings.Join(c.Notifications.DigestSenders, ","))
+	set(constants.WebhookEndpointEnv, c.Notifications.Webhook.Endpoint)
+	set(constants.EnvTeamsWebhookURL, c.Notifications.Teams.WebhookURL)
+	set(constants.EnvMattermostEndpoint, c.Notifications.Mattermost.Endpoint)
+	set(constants.EnvMattermostName, c.Notifications.Mattermost.Username)
+
+	return env
+}
+
+// Apply - exports settings of static sections as environment variables, the
+// ones already set are kept. Names of the exported variables are returned.
+func (c *Config) Apply() []string {
+	var exported []string
+	for name, value := range c.Environment() {
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+		os.Setenv(name, value)
+		exported = append(exported, name)
+	}
+	sort.Strings(exported)
+	return exported
+}
+
+// AliasesString - bot command aliases in BOT_COMMAND_ALIASES format
+func (b Bots) AliasesString() string {
+	var pairs []string
+	for alias, command := range b.Aliases {
+		pairs = append(pairs, alias+"="+command)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// RestartRequired - whether static sections differ, their changes are only
+// applied after a restart
+func RestartRequired(old, new *Config) bool {
+	o, _ := json.Marshal(old.Environment())
+	n, _ := json.Marshal(new.Environment())
+	return !bytes.Equal(o, n)
+}
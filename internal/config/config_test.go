@@ -0,0 +1,174 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testConfig = `
+triggers:
+  poll:
+    enabled: false
+    workers: 20
+    jitter: 30s
+  pubsub:
+    enabled: true
+bots:
+  aliases:
+    ship: approve
+  slack:
+    channels: [general, deployments]
+    approvalsChannel: deployments
+notifications:
+  level: warn
+  filters:
+    pagerduty:
+      level: error
+  webhook:
+    endpoint: https://hooks.example.com/keel
+policies:
+  pollSchedule: "@every 5m"
+  approvalDeadline: 12
+env:
+  ARGO_ROLLOUTS: "true"
+`
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := map[string]string{
+		"POLL":                    "0",
+		"POLL_WORKERS":            "20",
+		"POLL_JITTER":             "30s",
+		"PUBSUB":                  "1",
+		"SLACK_CHANNELS":          "general,deployments",
+		"SLACK_APPROVALS_CHANNEL": "deployments",
+		"WEBHOOK_ENDPOINT":        "https://hooks.example.com/keel",
+		"ARGO_ROLLOUTS":           "true",
+	}
+	if env := cfg.Environment(); !reflect.DeepEqual(env, expected) {
+		t.Errorf("unexpected environment: %v", env)
+	}
+	if cfg.Bots.AliasesString() != "ship=approve" {
+		t.Errorf("unexpected aliases: %s", cfg.Bots.AliasesString())
+	}
+	if cfg.Policies.ApprovalDeadline != 12 {
+		t.Errorf("unexpected approval deadline: %d", cfg.Policies.ApprovalDeadline)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{name: "unknown field", config: "triggers:\n  poll:\n    worker: 20\n"},
+		{name: "duration", config: "triggers:\n  poll:\n    jitter: 30\n"},
+		{name: "workers", config: "triggers:\n  poll:\n    workers: -1\n"},
+		{name: "level", config: "notifications:\n  level: loud\n"},
+		{name: "filter level", config: "notifications:\n  filters:\n    slack:\n      level: loud\n"},
+		{name: "schedule", config: "policies:\n  pollSchedule: sometimes\n"},
+		{name: "log level", config: "logging:\n  modules:\n    trigger.poll: verbose\n"},
+		{name: "alias", config: "bots:\n  aliases:\n    \"a=b\": approve\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.config)); err == nil {
+				t.Error("expected config to be rejected")
+			}
+		})
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	cfg, err := Parse([]byte(""))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(cfg.Environment()) != 0 {
+		t.Errorf("expected no settings, got: %v", cfg.Environment())
+	}
+}
+
+func TestApply(t *testing.T) {
+	os.Setenv("POLL_WORKERS", "5")
+	defer os.Unsetenv("POLL_WORKERS")
+	defer os.Unsetenv("POLL_JITTER")
+
+	cfg, err := Parse([]byte("triggers:\n  poll:\n    workers: 20\n    jitter: 30s\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	exported := cfg.Apply()
+	if !reflect.DeepEqual(exported, []string{"POLL_JITTER"}) {
+		t.Errorf("unexpected exported settings: %v", exported)
+	}
+	if os.Getenv("POLL_WORKERS") != "5" {
+		t.Errorf("expected environment to take precedence, got: %s", os.Getenv("POLL_WORKERS"))
+	}
+	if os.Getenv("POLL_JITTER") != "30s" {
+		t.Errorf("unexpected jitter: %s", os.Getenv("POLL_JITTER"))
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keel-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+
+	write := func(data string) {
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("notifications:\n  level: info\n")
+	current, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var reloaded []*Config
+	w := NewWatcher(path, current, func(cfg *Config) {
+		reloaded = append(reloaded, cfg)
+	})
+
+	w.check()
+	if len(reloaded) != 0 {
+		t.Fatalf("expected unchanged config to be skipped")
+	}
+
+	write("notifications:\n  level: loud\n")
+	w.check()
+	if len(reloaded) != 0 {
+		t.Fatalf("expected invalid config to be rejected")
+	}
+
+	write("notifications:\n  level: error\n")
+	w.check()
+	if len(reloaded) != 1 || reloaded[0].Notifications.Level != "error" {
+		t.Fatalf("expected config to be reloaded, got: %v", reloaded)
+	}
+}
+
+func TestRestartRequired(t *testing.T) {
+	old, _ := Parse([]byte("notifications:\n  level: info\n"))
+	reloadable, _ := Parse([]byte("notifications:\n  level: error\n"))
+	static, _ := Parse([]byte("notifications:\n  webhook:\n    endpoint: https://hooks.example.com\n"))
+
+	if RestartRequired(old, reloadable) {
+		t.Error("expected notification level to be reloadable")
+	}
+	if !RestartRequired(old, static) {
+		t.Error("expected webhook endpoint change to require restart")
+	}
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultReloadInterval - how often config file is checked for changes,
+// kubelet updates mounted ConfigMaps within a minute or so anyway
+const DefaultReloadInterval = 10 * time.Second
+
+// Watcher - reloads config file when its content changes, invalid configs are
+// rejected and the current one is kept
+type Watcher struct {
+	path     string
+	interval time.Duration
+	reload   func(*Config)
+
+	current *Config
+	data    []byte
+}
+
+// NewWatcher - creates watcher of the loaded config, reload is called with
+// every new valid config
+func NewWatcher(path string, current *Config, reload func(*Config)) *Watcher {
+	data, _ := ioutil.ReadFile(path)
+	return &Watcher{
+		path:     path,
+		interval: DefaultReloadInterval,
+		reload:   reload,
+		current:  current,
+		data:     data,
+	}
+}
+
+// Start - checks config file until ctx is done
+func (w *Watcher) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+func (w *Watcher) check() {
+	data, err := ioutil.ReadFile(w.path)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"path":  w.path,
+		}).Error("config.Watcher: failed to read config file")
+		return
+	}
+	if bytes.Equal(data, w.data) {
+		return
+	}
+	w.data = data
+
+	cfg, err := Parse(data)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"path":  w.path,
+		}).Error("config.Watcher: invalid config, keeping the current one")
+		return
+	}
+
+	if RestartRequired(w.current, cfg) {
+		log.WithFields(log.Fields{
+			"path": w.path,
+		}).Warn("config.Watcher: triggers, bots, notification endpoints or env changed, restart keel to apply them")
+	}
+	w.current = cfg
+	w.reload(cfg)
+	log.WithFields(log.Fields{
+		"path": w.path,
+	}).Info("config.Watcher: config reloaded")
+}
@@ -0,0 +1,392 @@
+// Package cosign verifies cosign image signatures. Signatures are stored in the
+// image repository as sha256-<digest>.sig tags, each layer of the signature
+// manifest is a simple signing payload with the signature in its annotations.
+// Signatures are verified either with public keys or keyless, with Fulcio
+// certificates whose signing time is proven by a Rekor bundle.
+package cosign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/registry"
+
+	"github.com/ryanuber/go-glob"
+)
+
+// signature manifest annotations
+const (
+	AnnotationSignature   = "dev.cosignproject.cosign/signature"
+	AnnotationCertificate = "dev.sigstore.cosign/certificate"
+	AnnotationChain       = "dev.sigstore.cosign/chain"
+	AnnotationBundle      = "dev.sigstore.cosign/bundle"
+)
+
+// Fulcio certificate extensions with the OIDC issuer
+var (
+	oidIssuer   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// ErrNoSignatures - image doesn't have signatures
+var ErrNoSignatures = errors.New("no signatures found")
+
+// Registry - registry operations used to fetch signatures
+type Registry interface {
+	Manifest(opts registry.Opts) (*registry.Manifest, error)
+	Blob(opts registry.Opts, digest string) ([]byte, error)
+}
+
+// Identity - keyless signer, subject can contain wildcards
+type Identity struct {
+	Issuer  string
+	Subject string
+}
+
+// ParseIdentity - parses identity in issuer=subject format, ie:
+// https://token.actions.githubusercontent.com=https://github.com/keel-hq/*
+func ParseIdentity(identity string) (Identity, error) {
+	parts := strings.SplitN(strings.TrimSpace(identity), "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Identity{}, fmt.Errorf("invalid identity %q, expected issuer=subject", identity)
+	}
+	return Identity{Issuer: parts[0], Subject: parts[1]}, nil
+}
+
+// Options - verifier configuration, PEM files of public keys, Fulcio roots and
+// Rekor public key
+type Options struct {
+	PublicKeys []string
+	Identities []Identity
+	// FulcioRoots - Fulcio root and intermediate certificates, required for identities
+	FulcioRoots string
+	// RekorPublicKey - used to verify signing time of keyless signatures
+	RekorPublicKey string
+}
+
+// Verifier - verifies image signatures
+type Verifier struct {
+	registry   Registry
+	keys       []crypto.PublicKey
+	identities []Identity
+	roots      *x509.CertPool
+	rekorKey   crypto.PublicKey
+}
+
+// New - creates verifier, at least one public key or identity is required
+func New(reg Registry, opts Options) (*Verifier, error) {
+	v := &Verifier{registry: reg, identities: opts.Identities}
+
+	for _, path := range opts.PublicKeys {
+		key, err := readPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = append(v.keys, key)
+	}
+
+	if len(opts.Identities) > 0 {
+		if opts.FulcioRoots == "" || opts.RekorPublicKey == "" {
+			return nil, fmt.Errorf("Fulcio roots and Rekor public key are required to verify keyless signatures")
+		}
+		data, err := ioutil.ReadFile(opts.FulcioRoots)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Fulcio roots: %s", err)
+		}
+		v.roots = x509.NewCertPool()
+		if !v.roots.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.FulcioRoots)
+		}
+		v.rekorKey, err = readPublicKey(opts.RekorPublicKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(v.keys) == 0 && len(v.identities) == 0 {
+		return nil, fmt.Errorf("public keys or identities have to be configured")
+	}
+	return v, nil
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %s", err)
+	}
+	return parsePublicKey(data)
+}
+
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode public key PEM")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// Result - successful verification
+type Result struct {
+	// Signer - key fingerprint or keyless identity
+	Signer string
+}
+
+// Verify - checks whether image digest has a valid signature, opts point to the
+// image repository (Tag is ignored)
+func (v *Verifier) Verify(opts registry.Opts, digest string) (*Result, error) {
+	tag, err := signatureTag(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	sigOpts := opts
+	sigOpts.Tag = tag
+	manifest, err := v.registry.Manifest(sigOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signatures: %s", err)
+	}
+	if len(manifest.Layers) == 0 {
+		return nil, ErrNoSignatures
+	}
+
+	var errs []string
+	for _, layer := range manifest.Layers {
+		payload, err := v.registry.Blob(opts, layer.Digest)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		result, err := v.verifyLayer(layer, payload, digest)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("no valid signatures: %s", strings.Join(errs, "; "))
+}
+
+// signatureTag - sha256:abc -> sha256-abc.sig
+func signatureTag(digest string) (string, error) {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("invalid digest %q", digest)
+	}
+	return parts[0] + "-" + parts[1] + ".sig", nil
+}
+
+type simpleSigning struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+func (v *Verifier) verifyLayer(layer registry.Descriptor, payload []byte, digest string) (*Result, error) {
+	var ss simpleSigning
+	if err := json.Unmarshal(payload, &ss); err != nil {
+		return nil, fmt.Errorf("failed to decode signature payload: %s", err)
+	}
+	if ss.Critical.Image.DockerManifestDigest != digest {
+		return nil, fmt.Errorf("signature is for digest %s", ss.Critical.Image.DockerManifestDigest)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(layer.Annotations[AnnotationSignature])
+	if err != nil || len(signature) == 0 {
+		return nil, fmt.Errorf("invalid signature annotation")
+	}
+
+	for _, key := range v.keys {
+		if verifySignature(key, payload, signature) == nil {
+			return &Result{Signer: "key " + fingerprint(key)}, nil
+		}
+	}
+
+	if cert := layer.Annotations[AnnotationCertificate]; cert != "" && len(v.identities) > 0 {
+		return v.verifyKeyless(layer, payload, signature)
+	}
+	return nil, fmt.Errorf("signature doesn't match configured keys")
+}
+
+func (v *Verifier) verifyKeyless(layer registry.Descriptor, payload, signature []byte) (*Result, error) {
+	cert, intermediates, err := parseCertificates(layer.Annotations[AnnotationCertificate], layer.Annotations[AnnotationChain])
+	if err != nil {
+		return nil, err
+	}
+
+	signedAt, err := v.verifyBundle(layer.Annotations[AnnotationBundle], signature, cert)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   signedAt,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing certificate: %s", err)
+	}
+
+	if err := verifySignature(cert.PublicKey, payload, signature); err != nil {
+		return nil, err
+	}
+
+	issuer, subject := certificateIdentity(cert)
+	for _, identity := range v.identities {
+		if identity.Issuer == issuer && glob.Glob(identity.Subject, subject) {
+			return &Result{Signer: fmt.Sprintf("%s (%s)", subject, issuer)}, nil
+		}
+	}
+	return nil, fmt.Errorf("signer %s (%s) isn't trusted", subject, issuer)
+}
+
+func parseCertificates(certPEM, chainPEM string) (*x509.Certificate, *x509.CertPool, error) {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return nil, nil, fmt.Errorf("failed to decode signing certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse signing certificate: %s", err)
+	}
+	intermediates := x509.NewCertPool()
+	intermediates.AppendCertsFromPEM([]byte(chainPEM))
+	return cert, intermediates, nil
+}
+
+// certificateIdentity - OIDC issuer and subject (email or URI) of Fulcio certificate
+func certificateIdentity(cert *x509.Certificate) (issuer, subject string) {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuer):
+			issuer = string(ext.Value)
+		case ext.Id.Equal(oidIssuerV2):
+			var value string
+			if _, err := asn1.Unmarshal(ext.Value, &value); err == nil {
+				issuer = value
+			}
+		}
+	}
+	switch {
+	case len(cert.EmailAddresses) > 0:
+		subject = cert.EmailAddresses[0]
+	case len(cert.URIs) > 0:
+		subject = cert.URIs[0].String()
+	}
+	return issuer, subject
+}
+
+// bundle - Rekor transparency log entry, signed entry timestamp proves that the
+// entry was logged at integrated time
+type bundle struct {
+	SignedEntryTimestamp []byte        `json:"SignedEntryTimestamp"`
+	Payload              bundlePayload `json:"Payload"`
+}
+
+// bundlePayload - fields are in canonical (sorted) order, the signed entry
+// timestamp is a signature of their JSON
+type bundlePayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+type rekorEntry struct {
+	Spec struct {
+		Signature struct {
+			Content   string `json:"content"`
+			PublicKey struct {
+				Content string `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+// verifyBundle - verifies Rekor bundle of the signature, returns signing time
+func (v *Verifier) verifyBundle(data string, signature []byte, cert *x509.Certificate) (time.Time, error) {
+	if data == "" {
+		return time.Time{}, fmt.Errorf("keyless signature has no Rekor bundle")
+	}
+	var b bundle
+	if err := json.Unmarshal([]byte(data), &b); err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode Rekor bundle: %s", err)
+	}
+
+	canonical, err := json.Marshal(b.Payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := verifySignature(v.rekorKey, canonical, b.SignedEntryTimestamp); err != nil {
+		return time.Time{}, fmt.Errorf("invalid Rekor bundle: %s", err)
+	}
+
+	// logged entry has to be of this signature and certificate
+	body, err := base64.StdEncoding.DecodeString(b.Payload.Body)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid Rekor entry: %s", err)
+	}
+	var entry rekorEntry
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return time.Time{}, fmt.Errorf("invalid Rekor entry: %s", err)
+	}
+	if entry.Spec.Signature.Content != base64.StdEncoding.EncodeToString(signature) {
+		return time.Time{}, fmt.Errorf("Rekor entry is for a different signature")
+	}
+	logged, err := base64.StdEncoding.DecodeString(entry.Spec.Signature.PublicKey.Content)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid Rekor entry: %s", err)
+	}
+	if block, _ := pem.Decode(logged); block == nil || string(block.Bytes) != string(cert.Raw) {
+		return time.Time{}, fmt.Errorf("Rekor entry is for a different certificate")
+	}
+
+	return time.Unix(b.Payload.IntegratedTime, 0), nil
+}
+
+func verifySignature(key crypto.PublicKey, payload, signature []byte) error {
+	hash := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if ecdsa.VerifyASN1(k, hash[:], signature) {
+			return nil
+		}
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], signature) == nil {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if ed25519.Verify(k, payload, signature) {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	return fmt.Errorf("invalid signature")
+}
+
+// fingerprint - shortened SHA-256 of the public key
+func fingerprint(key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "unknown"
+	}
+	sum := sha256.Sum256(der)
+	return fmt.Sprintf("%x", sum[:8])
+}
@@ -0,0 +1,284 @@
+package cosign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/registry"
+)
+
+const testDigest = "sha256:0a9c1e8c1d3e5a6b2c4d9f0b1e2a3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b"
+
+type fakeRegistry struct {
+	manifests map[string]*registry.Manifest
+	blobs     map[string][]byte
+}
+
+func (r *fakeRegistry) Manifest(opts registry.Opts) (*registry.Manifest, error) {
+	m, ok := r.manifests[opts.Tag]
+	if !ok {
+		return nil, fmt.Errorf("manifest %s not found", opts.Tag)
+	}
+	return m, nil
+}
+
+func (r *fakeRegistry) Blob(opts registry.Opts, digest string) ([]byte, error) {
+	b, ok := r.blobs[digest]
+	if !ok {
+		return nil, fmt.Errorf("blob %s not found", digest)
+	}
+	return b, nil
+}
+
+func payload(digest string) []byte {
+	return []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"index.docker.io/karolisr/webhook-demo"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, digest))
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, data []byte) []byte {
+	hash := sha256.Sum256(data)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatalf("failed to sign: %s", err)
+	}
+	return signature
+}
+
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+	return key
+}
+
+func writePublicKey(t *testing.T, dir, name string, key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %s", err)
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatalf("failed to write key: %s", err)
+	}
+	return path
+}
+
+// signedRegistry - registry with signature manifest of testDigest
+func signedRegistry(p []byte, annotations map[string]string) *fakeRegistry {
+	return &fakeRegistry{
+		manifests: map[string]*registry.Manifest{
+			"sha256-" + strings.TrimPrefix(testDigest, "sha256:") + ".sig": {
+				MediaType: registry.MediaTypeOCIManifest,
+				Layers: []registry.Descriptor{
+					{Digest: "sha256:payload", Annotations: annotations},
+				},
+			},
+		},
+		blobs: map[string][]byte{"sha256:payload": p},
+	}
+}
+
+func TestVerifyPublicKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cosign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key := generateKey(t)
+	other := generateKey(t)
+
+	p := payload(testDigest)
+	reg := signedRegistry(p, map[string]string{
+		AnnotationSignature: base64.StdEncoding.EncodeToString(sign(t, key, p)),
+	})
+
+	v, err := New(reg, Options{PublicKeys: []string{writePublicKey(t, dir, "other.pub", &other.PublicKey), writePublicKey(t, dir, "cosign.pub", &key.PublicKey)}})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %s", err)
+	}
+
+	result, err := v.Verify(registry.Opts{Name: "karolisr/webhook-demo"}, testDigest)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result.Signer != "key "+fingerprint(&key.PublicKey) {
+		t.Errorf("unexpected signer: %s", result.Signer)
+	}
+
+	// other key only
+	v, _ = New(reg, Options{PublicKeys: []string{writePublicKey(t, dir, "other.pub", &other.PublicKey)}})
+	if _, err := v.Verify(registry.Opts{Name: "karolisr/webhook-demo"}, testDigest); err == nil {
+		t.Errorf("expected signature of unknown key to be rejected")
+	}
+}
+
+func TestVerifyDifferentDigest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cosign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key := generateKey(t)
+	// signature of another image moved to the digest signature tag
+	p := payload("sha256:other")
+	reg := signedRegistry(p, map[string]string{
+		AnnotationSignature: base64.StdEncoding.EncodeToString(sign(t, key, p)),
+	})
+
+	v, err := New(reg, Options{PublicKeys: []string{writePublicKey(t, dir, "cosign.pub", &key.PublicKey)}})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %s", err)
+	}
+	if _, err := v.Verify(registry.Opts{}, testDigest); err == nil || !strings.Contains(err.Error(), "signature is for digest sha256:other") {
+		t.Errorf("expected digest mismatch, got: %v", err)
+	}
+
+	if _, err := v.Verify(registry.Opts{}, "sha256:unsigned"); err == nil {
+		t.Errorf("expected error for unsigned image")
+	}
+}
+
+func TestVerifyKeyless(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cosign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	signedAt := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+
+	// Fulcio root and short lived signing certificate
+	rootKey := generateKey(t)
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fulcio"},
+		NotBefore:             signedAt.Add(-time.Hour),
+		NotAfter:              signedAt.Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("failed to create root: %s", err)
+	}
+	root, _ := x509.ParseCertificate(rootDER)
+	rootsPath := filepath.Join(dir, "fulcio.pem")
+	ioutil.WriteFile(rootsPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootDER}), 0600)
+
+	signingKey := generateKey(t)
+	certDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       signedAt.Add(-time.Minute),
+		NotAfter:        signedAt.Add(10 * time.Minute),
+		EmailAddresses:  []string{"release@example.com"},
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuer, Value: []byte("https://accounts.example.com")}},
+	}, root, &signingKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+
+	p := payload(testDigest)
+	signature := sign(t, signingKey, p)
+
+	// Rekor bundle of the signature
+	rekorKey := generateKey(t)
+	body, _ := json.Marshal(map[string]interface{}{
+		"kind": "hashedrekord",
+		"spec": map[string]interface{}{
+			"signature": map[string]interface{}{
+				"content":   base64.StdEncoding.EncodeToString(signature),
+				"publicKey": map[string]string{"content": base64.StdEncoding.EncodeToString(certPEM)},
+			},
+		},
+	})
+	entry := bundlePayload{
+		Body:           base64.StdEncoding.EncodeToString(body),
+		IntegratedTime: signedAt.Unix(),
+		LogID:          "rekor",
+		LogIndex:       1,
+	}
+	canonical, _ := json.Marshal(entry)
+	b, _ := json.Marshal(bundle{SignedEntryTimestamp: sign(t, rekorKey, canonical), Payload: entry})
+
+	reg := signedRegistry(p, map[string]string{
+		AnnotationSignature:   base64.StdEncoding.EncodeToString(signature),
+		AnnotationCertificate: string(certPEM),
+		AnnotationBundle:      string(b),
+	})
+
+	opts := Options{
+		FulcioRoots:    rootsPath,
+		RekorPublicKey: writePublicKey(t, dir, "rekor.pub", &rekorKey.PublicKey),
+		Identities:     []Identity{{Issuer: "https://accounts.example.com", Subject: "*@example.com"}},
+	}
+	v, err := New(reg, opts)
+	if err != nil {
+		t.Fatalf("failed to create verifier: %s", err)
+	}
+	result, err := v.Verify(registry.Opts{}, testDigest)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result.Signer != "release@example.com (https://accounts.example.com)" {
+		t.Errorf("unexpected signer: %s", result.Signer)
+	}
+
+	// untrusted identity
+	opts.Identities = []Identity{{Issuer: "https://accounts.example.com", Subject: "ops@example.com"}}
+	v, _ = New(reg, opts)
+	if _, err := v.Verify(registry.Opts{}, testDigest); err == nil || !strings.Contains(err.Error(), "isn't trusted") {
+		t.Errorf("expected untrusted signer error, got: %v", err)
+	}
+
+	// bundle signed by another log
+	opts.Identities = []Identity{{Issuer: "https://accounts.example.com", Subject: "*@example.com"}}
+	opts.RekorPublicKey = writePublicKey(t, dir, "other.pub", &generateKey(t).PublicKey)
+	v, _ = New(reg, opts)
+	if _, err := v.Verify(registry.Opts{}, testDigest); err == nil || !strings.Contains(err.Error(), "Rekor bundle") {
+		t.Errorf("expected invalid bundle error, got: %v", err)
+	}
+}
+
+func TestNewRequiresKeylessConfiguration(t *testing.T) {
+	if _, err := New(&fakeRegistry{}, Options{}); err == nil {
+		t.Errorf("expected error without keys and identities")
+	}
+	if _, err := New(&fakeRegistry{}, Options{Identities: []Identity{{Issuer: "a", Subject: "b"}}}); err == nil {
+		t.Errorf("expected error for identities without Fulcio roots and Rekor key")
+	}
+}
+
+func TestParseIdentity(t *testing.T) {
+	identity, err := ParseIdentity("https://token.actions.githubusercontent.com=https://github.com/keel-hq/*")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if identity.Issuer != "https://token.actions.githubusercontent.com" || identity.Subject != "https://github.com/keel-hq/*" {
+		t.Errorf("unexpected identity: %v", identity)
+	}
+	if _, err := ParseIdentity("release@example.com"); err == nil {
+		t.Errorf("expected error for identity without issuer")
+	}
+}
@@ -0,0 +1,509 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrCostLimitExceeded - expression evaluation was stopped
+var ErrCostLimitExceeded = errors.New("cost limit exceeded")
+
+// Program - compiled expression
+type Program struct {
+	expr string
+	root node
+}
+
+// Compile - parses expression, only declared variables can be referenced
+func Compile(expr string, variables ...string) (*Program, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	declared := make(map[string]bool, len(variables))
+	for _, v := range variables {
+		declared[v] = true
+	}
+
+	p := &parser{tokens: tokens, variables: declared}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, unexpected(t, "end of expression")
+	}
+	return &Program{expr: expr, root: root}, nil
+}
+
+// Eval - evaluates the expression. Variables can be bool, int64, string,
+// []interface{}, map[string]interface{} and map[string]string values.
+func (p *Program) Eval(variables map[string]interface{}, costLimit int) (interface{}, error) {
+	e := &evaluator{variables: variables, budget: costLimit}
+	return e.eval(p.root)
+}
+
+// EvalBool - evaluates expression which has to result in a bool
+func (p *Program) EvalBool(variables map[string]interface{}, costLimit int) (bool, error) {
+	result, err := p.Eval(variables, costLimit)
+	if err != nil {
+		return false, err
+	}
+	b, ok := result.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q returned %s, expected bool", p.expr, typeName(result))
+	}
+	return b, nil
+}
+
+func (p *Program) String() string { return p.expr }
+
+type node interface{}
+
+type literalNode struct{ value interface{} }
+
+type variableNode struct{ name string }
+
+type listNode struct{ elements []node }
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+type conditionalNode struct {
+	cond, then, otherwise node
+}
+
+type selectNode struct {
+	operand node
+	field   string
+}
+
+type hasNode struct {
+	operand node
+	field   string
+}
+
+type indexNode struct {
+	operand, index node
+}
+
+type callNode struct {
+	function string
+	args     []node
+	regexp   *regexp.Regexp
+}
+
+type evaluator struct {
+	variables map[string]interface{}
+	budget    int
+}
+
+func (e *evaluator) charge(cost int) error {
+	e.budget -= cost
+	if e.budget < 0 {
+		return ErrCostLimitExceeded
+	}
+	return nil
+}
+
+func (e *evaluator) eval(n node) (interface{}, error) {
+	if err := e.charge(1); err != nil {
+		return nil, err
+	}
+
+	switch n := n.(type) {
+	case *literalNode:
+		return n.value, nil
+	case *variableNode:
+		v, ok := e.variables[n.name]
+		if !ok {
+			return nil, fmt.Errorf("no such attribute %q", n.name)
+		}
+		return normalize(v), nil
+	case *listNode:
+		list := make([]interface{}, 0, len(n.elements))
+		for _, element := range n.elements {
+			v, err := e.eval(element)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case *unaryNode:
+		return e.evalUnary(n)
+	case *binaryNode:
+		return e.evalBinary(n)
+	case *conditionalNode:
+		cond, err := e.evalBool(n.cond)
+		if err != nil {
+			return nil, err
+		}
+		if cond {
+			return e.eval(n.then)
+		}
+		return e.eval(n.otherwise)
+	case *selectNode:
+		m, err := e.evalMap(n.operand)
+		if err != nil {
+			return nil, err
+		}
+		v, ok := m[n.field]
+		if !ok {
+			return nil, fmt.Errorf("no such key %q", n.field)
+		}
+		return v, nil
+	case *hasNode:
+		m, err := e.evalMap(n.operand)
+		if err != nil {
+			return nil, err
+		}
+		_, ok := m[n.field]
+		return ok, nil
+	case *indexNode:
+		return e.evalIndex(n)
+	case *callNode:
+		return e.evalCall(n)
+	}
+	return nil, fmt.Errorf("unknown expression %T", n)
+}
+
+func (e *evaluator) evalBool(n node) (bool, error) {
+	v, err := e.eval(n)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected bool, got %s", typeName(v))
+	}
+	return b, nil
+}
+
+func (e *evaluator) evalMap(n node) (map[string]interface{}, error) {
+	v, err := e.eval(n)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected map, got %s", typeName(v))
+	}
+	return m, nil
+}
+
+func (e *evaluator) evalUnary(n *unaryNode) (interface{}, error) {
+	v, err := e.eval(n.operand)
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case bool:
+		if n.op == "!" {
+			return !v, nil
+		}
+	case int64:
+		if n.op == "-" {
+			return -v, nil
+		}
+	}
+	return nil, noOverload(n.op, v)
+}
+
+func (e *evaluator) evalBinary(n *binaryNode) (interface{}, error) {
+	// logical operators short circuit
+	switch n.op {
+	case "&&", "||":
+		left, err := e.evalBool(n.left)
+		if err != nil {
+			return nil, err
+		}
+		if (n.op == "&&") != left {
+			return left, nil
+		}
+		return e.evalBool(n.right)
+	}
+
+	left, err := e.eval(n.left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.eval(n.right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(left, right)
+	}
+
+	switch l := left.(type) {
+	case int64:
+		r, ok := right.(int64)
+		if !ok {
+			break
+		}
+		switch n.op {
+		case "<":
+			return l < r, nil
+		case "<=":
+			return l <= r, nil
+		case ">":
+			return l > r, nil
+		case ">=":
+			return l >= r, nil
+		case "+":
+			return l + r, nil
+		case "-":
+			return l - r, nil
+		case "*":
+			return l * r, nil
+		case "/", "%":
+			if r == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if n.op == "/" {
+				return l / r, nil
+			}
+			return l % r, nil
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			break
+		}
+		switch n.op {
+		case "<":
+			return l < r, nil
+		case "<=":
+			return l <= r, nil
+		case ">":
+			return l > r, nil
+		case ">=":
+			return l >= r, nil
+		case "+":
+			if err := e.charge(len(l) + len(r)); err != nil {
+				return nil, err
+			}
+			return l + r, nil
+		}
+	}
+	return nil, noOverload(n.op, left, right)
+}
+
+func (e *evaluator) evalIndex(n *indexNode) (interface{}, error) {
+	operand, err := e.eval(n.operand)
+	if err != nil {
+		return nil, err
+	}
+	index, err := e.eval(n.index)
+	if err != nil {
+		return nil, err
+	}
+	switch o := operand.(type) {
+	case map[string]interface{}:
+		key, ok := index.(string)
+		if !ok {
+			break
+		}
+		v, ok := o[key]
+		if !ok {
+			return nil, fmt.Errorf("no such key %q", key)
+		}
+		return v, nil
+	case []interface{}:
+		i, ok := index.(int64)
+		if !ok {
+			break
+		}
+		if i < 0 || i >= int64(len(o)) {
+			return nil, fmt.Errorf("index %d out of range", i)
+		}
+		return o[i], nil
+	}
+	return nil, noOverload("index", operand, index)
+}
+
+func (e *evaluator) evalCall(n *callNode) (interface{}, error) {
+	args := make([]interface{}, 0, len(n.args))
+	for _, arg := range n.args {
+		v, err := e.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	switch n.function {
+	case "size":
+		switch v := args[0].(type) {
+		case string:
+			return int64(len([]rune(v))), nil
+		case []interface{}:
+			return int64(len(v)), nil
+		case map[string]interface{}:
+			return int64(len(v)), nil
+		}
+	case "int":
+		switch v := args[0].(type) {
+		case int64:
+			return v, nil
+		case string:
+			i, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("string %q isn't an int", v)
+			}
+			return i, nil
+		}
+	case "string":
+		switch v := args[0].(type) {
+		case string:
+			return v, nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+	case "startsWith", "endsWith", "contains", "matches":
+		s, ok1 := args[0].(string)
+		arg, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			break
+		}
+		if err := e.charge(len(s) + len(arg)); err != nil {
+			return nil, err
+		}
+		switch n.function {
+		case "startsWith":
+			return strings.HasPrefix(s, arg), nil
+		case "endsWith":
+			return strings.HasSuffix(s, arg), nil
+		case "contains":
+			return strings.Contains(s, arg), nil
+		}
+		rx := n.regexp
+		if rx == nil {
+			var err error
+			rx, err = regexp.Compile(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %s", arg, err)
+			}
+		}
+		return rx.MatchString(s), nil
+	}
+	return nil, noOverload(n.function, args...)
+}
+
+// normalize - converts variables to expression values
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case map[string]string:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[k] = val
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[k] = normalize(val)
+		}
+		return m
+	case []string:
+		list := make([]interface{}, 0, len(v))
+		for _, val := range v {
+			list = append(list, val)
+		}
+		return list
+	}
+	return v
+}
+
+func equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if !equal(v, b[k]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func contains(element, collection interface{}) (interface{}, error) {
+	switch c := collection.(type) {
+	case []interface{}:
+		for _, v := range c {
+			if equal(element, v) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]interface{}:
+		key, ok := element.(string)
+		if !ok {
+			break
+		}
+		_, found := c[key]
+		return found, nil
+	}
+	return nil, noOverload("in", element, collection)
+}
+
+func noOverload(op string, args ...interface{}) error {
+	names := make([]string, 0, len(args))
+	for _, arg := range args {
+		names = append(names, typeName(arg))
+	}
+	return fmt.Errorf("no such overload: %s(%s)", op, strings.Join(names, ", "))
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,113 @@
+package expr
+
+import (
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	variables := map[string]interface{}{
+		"tag":    "v1.2.3-rc1",
+		"build":  42,
+		"labels": map[string]string{"tier": "backend", "team": "payments"},
+		"tags":   []string{"a", "b"},
+	}
+	tests := []struct {
+		expr string
+		want interface{}
+	}{
+		{`tag.startsWith("v1.") && !tag.endsWith("-debug")`, true},
+		{`tag.contains("rc") || false`, true},
+		{`tag.matches("^v\\d+\\.\\d+\\.\\d+$")`, false},
+		{`labels["tier"] == "backend"`, true},
+		{`labels.team != "payments"`, false},
+		{`has(labels.owner)`, false},
+		{`"tier" in labels && "b" in tags`, true},
+		{`build > 40 && build % 2 == 0`, true},
+		{`size(tag) == 10`, true},
+		{`int("12") + 1 == 13`, true},
+		{`string(build) + "-" + tags[1]`, "42-b"},
+		{`has(labels.owner) ? labels.owner : "none"`, "none"},
+		{`tag in ['v1.2.3-rc1', 'v1.2.3']`, true},
+		{`-build < 0`, true},
+		{`(1 + 2) * 3`, int64(9)},
+		// right side isn't evaluated
+		{`false && labels.missing == "x"`, false},
+		{`true || labels.missing == "x"`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := Compile(tt.expr, "tag", "build", "labels", "tags")
+			if err != nil {
+				t.Fatalf("failed to compile: %s", err)
+			}
+			got, err := p.Eval(variables, 1000)
+			if err != nil {
+				t.Fatalf("failed to evaluate: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, expr := range []string{
+		`unknown == "x"`,
+		`tag.startsWith("v") &&`,
+		`tag.lowerAscii()`,
+		`tag.startsWith()`,
+		`tag.matches("[")`,
+		`has(tag)`,
+		`"unterminated`,
+		`tag == "a" )`,
+		`tag # "a"`,
+	} {
+		if _, err := Compile(expr, "tag"); err == nil {
+			t.Errorf("expected error for %s", expr)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	variables := map[string]interface{}{"tag": "1.0", "labels": map[string]string{}}
+	for _, expr := range []string{
+		`tag > 1`,
+		`labels.missing == "x"`,
+		`int(tag) == 1`,
+		`1 / 0 == 1`,
+		`tag && true`,
+	} {
+		p, err := Compile(expr, "tag", "labels")
+		if err != nil {
+			t.Fatalf("failed to compile %s: %s", expr, err)
+		}
+		if _, err := p.Eval(variables, 1000); err == nil {
+			t.Errorf("expected error for %s", expr)
+		}
+	}
+
+	p, _ := Compile(`tag`, "tag")
+	if _, err := p.EvalBool(variables, 1000); err == nil {
+		t.Errorf("expected error for non bool result")
+	}
+}
+
+func TestEvalCostLimit(t *testing.T) {
+	p, err := Compile(`tag + tag + tag + tag == tag`, "tag")
+	if err != nil {
+		t.Fatalf("failed to compile: %s", err)
+	}
+
+	long := make([]byte, 100)
+	for i := range long {
+		long[i] = 'a'
+	}
+	variables := map[string]interface{}{"tag": string(long)}
+	if _, err := p.Eval(variables, 100); err != ErrCostLimitExceeded {
+		t.Errorf("expected cost limit to be exceeded, got: %v", err)
+	}
+	if _, err := p.Eval(variables, 10000); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
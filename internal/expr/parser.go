@@ -0,0 +1,412 @@
+// Package expr evaluates keel policy expressions. The syntax borrows from the
+// Common Expression Language but this is not a CEL implementation, only the
+// grammar below is supported:
+//
+//	expr    = or [ "?" expr ":" expr ]
+//	or      = and { "||" and }
+//	and     = rel { "&&" rel }
+//	rel     = add { ( "==" | "!=" | "<" | "<=" | ">" | ">=" | "in" ) add }
+//	add     = mul { ( "+" | "-" ) mul }
+//	mul     = unary { ( "*" | "/" | "%" ) unary }
+//	unary   = { "!" | "-" } member
+//	member  = primary { "." ident [ "(" args ")" ] | "[" expr "]" }
+//	primary = int | string | "true" | "false" | ident | ident "(" args ")"
+//	        | "(" expr ")" | "[" args "]"
+//
+// Values are bool, int, string, list and map, there are no floats, bytes,
+// durations, timestamps or message types. Strings are quoted with ' or " and
+// support \n, \t and escaped quotes. Comparisons work on ints and strings, +
+// concatenates strings. Functions: has(map.field), size(x), int(x), string(x)
+// and the s.startsWith(x), s.endsWith(x), s.contains(x) and s.matches(re)
+// methods, matches patterns have to be string literals. Only variables
+// declared at compile time can be referenced.
+//
+// Evaluation has a cost limit, every evaluated node costs 1 and string
+// functions additionally cost the length of their arguments.
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenInt
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "%", "?", ":", "(", ")", "[", "]", ".", ","}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(expr) && (expr[i] == '_' || unicode.IsLetter(rune(expr[i])) || unicode.IsDigit(rune(expr[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, value: expr[start:i], pos: start})
+		case unicode.IsDigit(c):
+			start := i
+			for i < len(expr) && unicode.IsDigit(rune(expr[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenInt, value: expr[start:i], pos: start})
+		case c == '"' || c == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			for {
+				if i >= len(expr) {
+					return nil, fmt.Errorf("unterminated string at %d", start)
+				}
+				if rune(expr[i]) == c {
+					i++
+					break
+				}
+				if expr[i] == '\\' && i+1 < len(expr) {
+					i++
+					switch expr[i] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					default:
+						sb.WriteByte(expr[i])
+					}
+					i++
+					continue
+				}
+				sb.WriteByte(expr[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenString, value: sb.String(), pos: start})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(expr[i:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, value: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(expr)}), nil
+}
+
+type parser struct {
+	tokens    []token
+	pos       int
+	variables map[string]bool
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOperator(values ...string) bool {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return false
+	}
+	for _, v := range values {
+		if t.value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	t := p.next()
+	if t.kind != tokenOperator || t.value != op {
+		return unexpected(t, op)
+	}
+	return nil
+}
+
+func unexpected(t token, expected string) error {
+	if t.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of expression, expected %s", expected)
+	}
+	return fmt.Errorf("unexpected %q at %d, expected %s", t.value, t.pos, expected)
+}
+
+func (p *parser) parseExpr() (node, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOperator("?") {
+		return cond, nil
+	}
+	p.next()
+	then, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &conditionalNode{cond: cond, then: then, otherwise: otherwise}, nil
+}
+
+// binary operators by precedence, lowest first
+var precedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) isBinaryOperator(level int) (string, bool) {
+	t := p.peek()
+	for _, op := range precedence[level] {
+		if op == "in" && t.kind == tokenIdent && t.value == "in" {
+			return op, true
+		}
+		if t.kind == tokenOperator && t.value == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseBinary(level int) (node, error) {
+	if level == len(precedence) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.isBinaryOperator(level)
+		if !ok {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOperator("!", "-") {
+		op := p.next().value
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, operand: operand}, nil
+	}
+	return p.parseMember()
+}
+
+func (p *parser) parseMember() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.isOperator("."):
+			p.next()
+			t := p.next()
+			if t.kind != tokenIdent {
+				return nil, unexpected(t, "field or function name")
+			}
+			if p.isOperator("(") {
+				args, err := p.parseArgs(")")
+				if err != nil {
+					return nil, err
+				}
+				n, err = newCall(t.value, n, args)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			n = &selectNode{operand: n, field: t.value}
+		case p.isOperator("["):
+			p.next()
+			index, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = &indexNode{operand: n, index: index}
+		default:
+			return n, nil
+		}
+	}
+}
+
+// parseArgs - parses comma separated expressions until the closing operator
+func (p *parser) parseArgs(closing string) ([]node, error) {
+	p.next()
+	var args []node
+	if p.isOperator(closing) {
+		p.next()
+		return args, nil
+	}
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.isOperator(",") {
+			p.next()
+			continue
+		}
+		if err := p.expect(closing); err != nil {
+			return nil, err
+		}
+		return args, nil
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenInt:
+		p.next()
+		v, err := strconv.ParseInt(t.value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid int %s at %d", t.value, t.pos)
+		}
+		return &literalNode{value: v}, nil
+	case tokenString:
+		p.next()
+		return &literalNode{value: t.value}, nil
+	case tokenIdent:
+		p.next()
+		switch t.value {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		}
+		if p.isOperator("(") {
+			args, err := p.parseArgs(")")
+			if err != nil {
+				return nil, err
+			}
+			if t.value == "has" {
+				if len(args) != 1 {
+					return nil, fmt.Errorf("has() expects a field selection")
+				}
+				sel, ok := args[0].(*selectNode)
+				if !ok {
+					return nil, fmt.Errorf("has() expects a field selection")
+				}
+				return &hasNode{operand: sel.operand, field: sel.field}, nil
+			}
+			return newCall(t.value, nil, args)
+		}
+		if !p.variables[t.value] {
+			return nil, fmt.Errorf("undeclared reference to %q", t.value)
+		}
+		return &variableNode{name: t.value}, nil
+	case tokenOperator:
+		switch t.value {
+		case "(":
+			p.next()
+			n, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		case "[":
+			elements, err := p.parseArgs("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{elements: elements}, nil
+		}
+	}
+	return nil, unexpected(t, "value")
+}
+
+// functions - number of arguments including the receiver of methods
+var functions = map[string]int{
+	"size":       1,
+	"int":        1,
+	"string":     1,
+	"startsWith": 2,
+	"endsWith":   2,
+	"contains":   2,
+	"matches":    2,
+}
+
+func newCall(name string, receiver node, args []node) (node, error) {
+	if receiver != nil {
+		args = append([]node{receiver}, args...)
+	}
+	count, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("undeclared reference to function %q", name)
+	}
+	if len(args) != count {
+		return nil, fmt.Errorf("function %s expects %d arguments, got %d", name, count, len(args))
+	}
+	call := &callNode{function: name, args: args}
+	// patterns are usually literals, compiling them once also reports errors early
+	if name == "matches" {
+		if lit, ok := args[1].(*literalNode); ok {
+			pattern, ok := lit.value.(string)
+			if !ok {
+				return nil, fmt.Errorf("matches expects a string pattern")
+			}
+			rx, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %s", pattern, err)
+			}
+			call.regexp = rx
+		}
+	}
+	return call, nil
+}
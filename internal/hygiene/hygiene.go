@@ -0,0 +1,359 @@
+// Package hygiene periodically compares deployed image tags with registry
+// contents and reports drift: workloads running tags that no longer exist,
+// tags many versions behind their policy and images that aren't scanned or
+// signed.
+package hygiene
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/internal/cosign"
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/internal/vulnscan"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// finding kinds
+const (
+	FindingMissingTag = "missing_tag"
+	FindingBehind     = "behind"
+	FindingUnscanned  = "unscanned"
+	FindingUnsigned   = "unsigned"
+)
+
+// DefaultBehindThreshold - tags behind the newest version allowed by the policy
+// that are reported
+const DefaultBehindThreshold = 3
+
+// reportMaxLines - maximum number of findings listed in the report notification
+const reportMaxLines = 50
+
+// Registry - registry operations used to check deployed images
+type Registry interface {
+	Get(opts registry.Opts) (*registry.Repository, error)
+	Digest(opts registry.Opts) (string, error)
+}
+
+// SignatureVerifier - verifies image signatures, ie: cosign
+type SignatureVerifier interface {
+	Verify(opts registry.Opts, digest string) (*cosign.Result, error)
+}
+
+// TrackedImages - source of deployed images, ie: providers
+type TrackedImages interface {
+	TrackedImages() ([]*types.TrackedImage, error)
+}
+
+// Sender - sends the scheduled report notification
+type Sender interface {
+	Send(event types.EventNotification) error
+}
+
+// Opts - reporter configuration, scanner and verifier are optional
+type Opts struct {
+	Images   TrackedImages
+	Registry Registry
+	Scanner  vulnscan.Scanner
+	Verifier SignatureVerifier
+	Sender   Sender
+
+	// BehindThreshold - DefaultBehindThreshold when not set
+	BehindThreshold int
+}
+
+// Finding - drift of a deployed image
+type Finding struct {
+	Kind      string `json:"kind"`
+	Image     string `json:"image"`
+	Namespace string `json:"namespace"`
+	Provider  string `json:"provider"`
+	// Resource - workload running the image, ie: deployment/wd
+	Resource string `json:"resource,omitempty"`
+	Message  string `json:"message"`
+	// Behind and Newest - versions behind the newest tag allowed by the policy
+	Behind int    `json:"behind,omitempty"`
+	Newest string `json:"newest,omitempty"`
+}
+
+// Report - findings of deployed images
+type Report struct {
+	GeneratedAt time.Time      `json:"generatedAt"`
+	Images      int            `json:"images"`
+	Findings    []Finding      `json:"findings"`
+	Counts      map[string]int `json:"counts"`
+	// Errors - images that couldn't be checked
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Reporter - generates hygiene reports
+type Reporter struct {
+	opts Opts
+
+	mu   sync.Mutex
+	last *Report
+}
+
+// New - creates reporter
+func New(opts Opts) *Reporter {
+	if opts.BehindThreshold <= 0 {
+		opts.BehindThreshold = DefaultBehindThreshold
+	}
+	return &Reporter{opts: opts}
+}
+
+// Last - latest generated report, nil before the first one
+func (r *Reporter) Last() *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last
+}
+
+// Run - generates report and sends it as a notification periodically until
+// stop is closed
+func (r *Reporter) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			report, err := r.Generate()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+				}).Error("hygiene.Run: failed to generate report")
+				continue
+			}
+			r.send(report, interval)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// repository - registry contents of a deployed image, shared by workloads that
+// run it
+type repository struct {
+	tags map[string]bool
+	list []string
+	err  error
+}
+
+// Generate - checks deployed images and stores the report
+func (r *Reporter) Generate() (*Report, error) {
+	images, err := r.opts.Images.TrackedImages()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		GeneratedAt: time.Now(),
+		Findings:    []Finding{},
+		Counts: map[string]int{
+			FindingMissingTag: 0,
+			FindingBehind:     0,
+			FindingUnscanned:  0,
+			FindingUnsigned:   0,
+		},
+	}
+
+	repositories := make(map[string]*repository)
+	checked := make(map[string]bool)
+	for _, img := range images {
+		key := img.Namespace + "/" + resourceName(img) + "/" + img.Image.Remote()
+		if checked[key] {
+			continue
+		}
+		checked[key] = true
+		report.Images++
+
+		findings, err := r.check(img, repositories)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %s", img.Image.Remote(), err))
+		}
+		for _, f := range findings {
+			report.Findings = append(report.Findings, f)
+			report.Counts[f.Kind]++
+		}
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		if report.Findings[i].Kind != report.Findings[j].Kind {
+			return report.Findings[i].Kind < report.Findings[j].Kind
+		}
+		return report.Findings[i].Image < report.Findings[j].Image
+	})
+
+	r.mu.Lock()
+	r.last = report
+	r.mu.Unlock()
+	return report, nil
+}
+
+// check - findings of the tracked image, error is returned when registry
+// contents couldn't be listed
+func (r *Reporter) check(img *types.TrackedImage, repositories map[string]*repository) ([]Finding, error) {
+	ref := img.Image
+	if ref.Digest() != "" {
+		// pinned images run by digest, their tags may be gone
+		return nil, nil
+	}
+
+	creds := credentialshelper.GetCredentials(img)
+	opts := registry.Opts{
+		Registry: ref.Scheme() + "://" + ref.Registry(),
+		Name:     ref.ShortName(),
+		Tag:      ref.Tag(),
+		Username: creds.Username,
+		Password: creds.Password,
+	}
+
+	finding := func(kind, message string) Finding {
+		return Finding{
+			Kind:      kind,
+			Image:     ref.Remote(),
+			Namespace: img.Namespace,
+			Provider:  img.Provider,
+			Resource:  resourceName(img),
+			Message:   message,
+		}
+	}
+
+	repo, ok := repositories[ref.Repository()]
+	if !ok {
+		repo = &repository{tags: make(map[string]bool)}
+		listed, err := r.opts.Registry.Get(opts)
+		if err != nil {
+			repo.err = err
+		} else {
+			repo.list = listed.Tags
+			for _, tag := range listed.Tags {
+				repo.tags[tag] = true
+			}
+		}
+		repositories[ref.Repository()] = repo
+	}
+	if repo.err != nil {
+		return nil, repo.err
+	}
+
+	var findings []Finding
+	if !repo.tags[ref.Tag()] {
+		return append(findings, finding(FindingMissingTag, fmt.Sprintf("tag %s no longer exists in the registry", ref.Tag()))), nil
+	}
+
+	if behind, newest := r.behind(img, repo.list); behind >= r.opts.BehindThreshold {
+		f := finding(FindingBehind, fmt.Sprintf("%d versions behind %s allowed by %s policy", behind, newest, img.Policy.Name()))
+		f.Behind = behind
+		f.Newest = newest
+		findings = append(findings, f)
+	}
+
+	if r.opts.Scanner == nil && r.opts.Verifier == nil {
+		return findings, nil
+	}
+
+	digest, err := r.opts.Registry.Digest(opts)
+	if err != nil {
+		return findings, err
+	}
+	if r.opts.Scanner != nil {
+		if _, err := r.opts.Scanner.Scan(ref.Remote(), digest); err != nil {
+			findings = append(findings, finding(FindingUnscanned, fmt.Sprintf("no scan report of %s: %s", digest, err)))
+		}
+	}
+	if r.opts.Verifier != nil {
+		if _, err := r.opts.Verifier.Verify(opts, digest); err != nil {
+			findings = append(findings, finding(FindingUnsigned, fmt.Sprintf("signature of %s not verified: %s", digest, err)))
+		}
+	}
+	return findings, nil
+}
+
+// behind - number of tags the policy would update the image to and the newest
+// of them, only checked for policies that order versions
+func (r *Reporter) behind(img *types.TrackedImage, tags []string) (int, string) {
+	plc, ok := img.Policy.(policy.Policy)
+	if !ok {
+		return 0, ""
+	}
+	switch plc.Type() {
+	case policy.PolicyTypeSemver, policy.PolicyTypeLexicographic, policy.PolicyTypeNumeric:
+	default:
+		return 0, ""
+	}
+
+	current := img.Image.Tag()
+	var behind int
+	var newest string
+	for _, tag := range tags {
+		update, err := plc.ShouldUpdate(current, tag)
+		if err != nil || !update {
+			continue
+		}
+		behind++
+		if newest == "" {
+			newest = tag
+		} else if newer, err := plc.ShouldUpdate(newest, tag); err == nil && newer {
+			newest = tag
+		}
+	}
+	return behind, newest
+}
+
+func resourceName(img *types.TrackedImage) string {
+	if img.Meta["kind"] != "" {
+		return strings.ToLower(img.Meta["kind"]) + "/" + img.Meta["name"]
+	}
+	return img.Meta["name"]
+}
+
+// send - report notification, reports without findings aren't sent
+func (r *Reporter) send(report *Report, interval time.Duration) {
+	if r.opts.Sender == nil || len(report.Findings) == 0 {
+		return
+	}
+
+	lines := []string{fmt.Sprintf("%d findings in %d deployed images:", len(report.Findings), report.Images)}
+	for i, f := range report.Findings {
+		if i == reportMaxLines {
+			lines = append(lines, fmt.Sprintf("… and %d more", len(report.Findings)-reportMaxLines))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("• %s/%s %s: %s", f.Namespace, f.Resource, f.Image, f.Message))
+	}
+
+	level := types.LevelInfo
+	if report.Counts[FindingMissingTag] > 0 {
+		level = types.LevelWarn
+	}
+
+	metadata := map[string]string{
+		"images":   fmt.Sprintf("%d", report.Images),
+		"interval": interval.String(),
+	}
+	for kind, count := range report.Counts {
+		metadata[kind] = fmt.Sprintf("%d", count)
+	}
+
+	err := r.opts.Sender.Send(types.EventNotification{
+		Name:      "hygiene report",
+		Message:   strings.Join(lines, "\n"),
+		CreatedAt: report.GeneratedAt,
+		Type:      types.NotificationHygieneReport,
+		Level:     level,
+		Metadata:  metadata,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("hygiene.send: failed to send report")
+	}
+}
@@ -0,0 +1,195 @@
+package hygiene
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/internal/cosign"
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/internal/vulnscan"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+)
+
+type fakeImages []*types.TrackedImage
+
+func (f fakeImages) TrackedImages() ([]*types.TrackedImage, error) {
+	return f, nil
+}
+
+type fakeRegistry struct {
+	tags map[string][]string
+	gets int
+}
+
+func (r *fakeRegistry) Get(opts registry.Opts) (*registry.Repository, error) {
+	r.gets++
+	tags, ok := r.tags[opts.Name]
+	if !ok {
+		return nil, fmt.Errorf("repository not found")
+	}
+	return &registry.Repository{Name: opts.Name, Tags: tags}, nil
+}
+
+func (r *fakeRegistry) Digest(opts registry.Opts) (string, error) {
+	return "sha256:" + strings.Replace(opts.Name, "/", "-", -1) + "-" + opts.Tag, nil
+}
+
+type fakeScanner map[string]bool
+
+func (s fakeScanner) Scan(image, digest string) (*vulnscan.Report, error) {
+	if !s[digest] {
+		return nil, fmt.Errorf("not scanned")
+	}
+	return &vulnscan.Report{}, nil
+}
+
+type fakeVerifier map[string]bool
+
+func (v fakeVerifier) Verify(opts registry.Opts, digest string) (*cosign.Result, error) {
+	if !v[digest] {
+		return nil, cosign.ErrNoSignatures
+	}
+	return &cosign.Result{Signer: "ci"}, nil
+}
+
+type fakeSender struct {
+	sent []types.EventNotification
+}
+
+func (s *fakeSender) Send(event types.EventNotification) error {
+	s.sent = append(s.sent, event)
+	return nil
+}
+
+func tracked(t *testing.T, img, name string, plc types.Policy) *types.TrackedImage {
+	ref, err := image.Parse(img)
+	if err != nil {
+		t.Fatalf("failed to parse image: %s", err)
+	}
+	return &types.TrackedImage{
+		Image:     ref,
+		Provider:  "kubernetes",
+		Namespace: "default",
+		Meta:      map[string]string{"name": name, "kind": "deployment"},
+		Policy:    plc,
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	semver := policy.NewSemverPolicy(policy.SemverPolicyTypeMajor)
+	reg := &fakeRegistry{tags: map[string][]string{
+		"karolisr/webhook-demo": {"1.0.0", "1.1.0", "1.2.0", "1.3.0", "2.0.0"},
+		"karolisr/keel":         {"0.9.0", "0.10.0"},
+	}}
+
+	r := New(Opts{
+		Images: fakeImages{
+			tracked(t, "karolisr/webhook-demo:1.0.0", "behind", semver),
+			tracked(t, "karolisr/webhook-demo:1.3.0", "current", semver),
+			tracked(t, "karolisr/keel:0.8.0", "missing", semver),
+			tracked(t, "karolisr/webhook-demo:1.0.0", "force", policy.NewForcePolicy(false)),
+			tracked(t, "karolisr/unknown:1.0.0", "unknown", semver),
+		},
+		Registry: reg,
+	})
+
+	report, err := r.Generate()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if r.Last() != report {
+		t.Errorf("expected report to be stored")
+	}
+	if report.Images != 5 {
+		t.Errorf("expected 5 checked images, got %d", report.Images)
+	}
+	if reg.gets != 3 {
+		t.Errorf("expected repositories to be listed once, got %d requests", reg.gets)
+	}
+	if len(report.Errors) != 1 {
+		t.Errorf("expected 1 error, got: %v", report.Errors)
+	}
+
+	if len(report.Findings) != 2 {
+		t.Fatalf("expected 2 findings, got: %+v", report.Findings)
+	}
+	behind := report.Findings[0]
+	if behind.Kind != FindingBehind || behind.Resource != "deployment/behind" || behind.Behind != 4 || behind.Newest != "2.0.0" {
+		t.Errorf("unexpected finding: %+v", behind)
+	}
+	missing := report.Findings[1]
+	if missing.Kind != FindingMissingTag || missing.Image != "index.docker.io/karolisr/keel:0.8.0" {
+		t.Errorf("unexpected finding: %+v", missing)
+	}
+	if report.Counts[FindingBehind] != 1 || report.Counts[FindingMissingTag] != 1 || report.Counts[FindingUnsigned] != 0 {
+		t.Errorf("unexpected counts: %v", report.Counts)
+	}
+}
+
+func TestGenerateScannedAndSigned(t *testing.T) {
+	semver := policy.NewSemverPolicy(policy.SemverPolicyTypeMajor)
+	reg := &fakeRegistry{tags: map[string][]string{
+		"karolisr/webhook-demo": {"1.0.0", "1.1.0"},
+	}}
+
+	r := New(Opts{
+		Images: fakeImages{
+			tracked(t, "karolisr/webhook-demo:1.0.0", "old", semver),
+			tracked(t, "karolisr/webhook-demo:1.1.0", "new", semver),
+		},
+		Registry: reg,
+		Scanner:  fakeScanner{"sha256:karolisr-webhook-demo-1.1.0": true},
+		Verifier: fakeVerifier{"sha256:karolisr-webhook-demo-1.0.0": true, "sha256:karolisr-webhook-demo-1.1.0": true},
+	})
+
+	report, err := r.Generate()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(report.Findings) != 1 {
+		t.Fatalf("expected 1 finding, got: %+v", report.Findings)
+	}
+	if f := report.Findings[0]; f.Kind != FindingUnscanned || f.Resource != "deployment/old" {
+		t.Errorf("unexpected finding: %+v", f)
+	}
+
+	r.opts.Verifier = fakeVerifier{}
+	report, _ = r.Generate()
+	if report.Counts[FindingUnsigned] != 2 {
+		t.Errorf("expected 2 unsigned images, got: %v", report.Counts)
+	}
+}
+
+func TestSend(t *testing.T) {
+	sender := &fakeSender{}
+	r := New(Opts{
+		Images:   fakeImages{tracked(t, "karolisr/keel:0.8.0", "wd", policy.NewSemverPolicy(policy.SemverPolicyTypeMajor))},
+		Registry: &fakeRegistry{tags: map[string][]string{"karolisr/keel": {"0.9.0"}}},
+		Sender:   sender,
+	})
+
+	report, _ := r.Generate()
+	r.send(report, 0)
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected report notification")
+	}
+	event := sender.sent[0]
+	if event.Type != types.NotificationHygieneReport || event.Level != types.LevelWarn {
+		t.Errorf("unexpected notification: %+v", event)
+	}
+	if !strings.Contains(event.Message, "default/deployment/wd index.docker.io/karolisr/keel:0.8.0: tag 0.8.0 no longer exists") {
+		t.Errorf("unexpected message: %s", event.Message)
+	}
+	if event.Metadata[FindingMissingTag] != "1" {
+		t.Errorf("unexpected metadata: %v", event.Metadata)
+	}
+
+	// reports without findings aren't sent
+	r.send(&Report{}, 0)
+	if len(sender.sent) != 1 {
+		t.Errorf("expected empty report not to be sent")
+	}
+}
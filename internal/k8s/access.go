@@ -0,0 +1,213 @@
+package k8s
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	authorization_v1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// AccessRule - permission keel uses, workloads can't be updated without
+// them, secrets are only needed for private registries
+type AccessRule struct {
+	Group    string
+	Resource string
+	Verb     string
+}
+
+func (r AccessRule) String() string {
+	if r.Group == "" {
+		return r.Verb + " " + r.Resource
+	}
+	return r.Verb + " " + r.Resource + "." + r.Group
+}
+
+// NamespacedAccess - permissions keel uses in watched namespaces
+var NamespacedAccess = []AccessRule{
+	{Group: "apps", Resource: "deployments", Verb: "list"},
+	{Group: "apps", Resource: "deployments", Verb: "watch"},
+	{Group: "apps", Resource: "deployments", Verb: "update"},
+	{Group: "apps", Resource: "statefulsets", Verb: "list"},
+	{Group: "apps", Resource: "statefulsets", Verb: "watch"},
+	{Group: "apps", Resource: "statefulsets", Verb: "update"},
+	{Group: "apps", Resource: "daemonsets", Verb: "list"},
+	{Group: "apps", Resource: "daemonsets", Verb: "watch"},
+	{Group: "apps", Resource: "daemonsets", Verb: "update"},
+	{Group: "batch", Resource: "cronjobs", Verb: "list"},
+	{Group: "batch", Resource: "cronjobs", Verb: "watch"},
+	{Group: "batch", Resource: "cronjobs", Verb: "update"},
+	{Resource: "pods", Verb: "list"},
+	{Resource: "secrets", Verb: "get"},
+	{Resource: "secrets", Verb: "list"},
+	{Resource: "secrets", Verb: "watch"},
+}
+
+// ClusterAccess - cluster scoped permissions, namespace annotations aren't
+// used as workload defaults without them
+var ClusterAccess = []AccessRule{
+	{Resource: "namespaces", Verb: "list"},
+	{Resource: "namespaces", Verb: "watch"},
+}
+
+// NamespaceAccess - permissions of keel in a namespace, namespace is empty
+// for cluster-wide permissions
+type NamespaceAccess struct {
+	Namespace string   `json:"namespace"`
+	Allowed   []string `json:"allowed"`
+	Denied    []string `json:"denied"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// AccessStatus - permissions of keel, per watched namespace when keel runs
+// with namespaced roles
+type AccessStatus struct {
+	Namespaced bool              `json:"namespaced"`
+	CheckedAt  time.Time         `json:"checkedAt"`
+	Namespaces []NamespaceAccess `json:"namespaces"`
+}
+
+// reviewer - checks whether keel is allowed to use the rule in the namespace
+type reviewer func(namespace string, rule AccessRule) (bool, error)
+
+// AccessChecker - checks permissions of keel with SelfSubjectAccessReviews,
+// watches are skipped where keel isn't allowed to list and watch resources
+// so it degrades instead of retrying forbidden requests
+type AccessChecker struct {
+	namespaces []string
+	review     reviewer
+	log        logrus.FieldLogger
+
+	mu     sync.RWMutex
+	status AccessStatus
+	// allowed - namespace -> rule -> allowed
+	allowed map[string]map[AccessRule]bool
+}
+
+// NewAccessChecker - creates access checker of the namespaces, cluster-wide
+// permissions are checked when namespaces are empty
+func NewAccessChecker(client kubernetes.Interface, namespaces []string, log logrus.FieldLogger) *AccessChecker {
+	return newAccessChecker(namespaces, func(namespace string, rule AccessRule) (bool, error) {
+		review, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(&authorization_v1.SelfSubjectAccessReview{
+			Spec: authorization_v1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorization_v1.ResourceAttributes{
+					Namespace: namespace,
+					Group:     rule.Group,
+					Resource:  rule.Resource,
+					Verb:      rule.Verb,
+				},
+			},
+		})
+		if err != nil {
+			return false, err
+		}
+		return review.Status.Allowed, nil
+	}, log)
+}
+
+func newAccessChecker(namespaces []string, review reviewer, log logrus.FieldLogger) *AccessChecker {
+	return &AccessChecker{
+		namespaces: namespaces,
+		review:     review,
+		log:        log,
+	}
+}
+
+// Check - checks permissions in all namespaces
+func (c *AccessChecker) Check() AccessStatus {
+	status := AccessStatus{
+		Namespaced: len(c.namespaces) > 0,
+		CheckedAt:  time.Now(),
+	}
+	allowed := make(map[string]map[AccessRule]bool)
+
+	check := func(namespace string, rules []AccessRule) {
+		access := NamespaceAccess{Namespace: namespace}
+		allowed[namespace] = make(map[AccessRule]bool)
+		for _, rule := range rules {
+			ok, err := c.review(namespace, rule)
+			if err != nil {
+				access.Error = err.Error()
+				access.Denied = append(access.Denied, rule.String())
+				continue
+			}
+			allowed[namespace][rule] = ok
+			if ok {
+				access.Allowed = append(access.Allowed, rule.String())
+			} else {
+				access.Denied = append(access.Denied, rule.String())
+			}
+		}
+		if len(access.Denied) > 0 {
+			c.log.WithFields(logrus.Fields{
+				"namespace": namespace,
+				"denied":    access.Denied,
+				"error":     access.Error,
+			}).Warn("k8s.AccessChecker: missing permissions, affected resources are skipped")
+		}
+		status.Namespaces = append(status.Namespaces, access)
+	}
+
+	if len(c.namespaces) == 0 {
+		// cluster-wide roles, namespaced rules are the same everywhere
+		check("", append(append([]AccessRule{}, ClusterAccess...), NamespacedAccess...))
+	} else {
+		check("", ClusterAccess)
+		for _, namespace := range c.namespaces {
+			check(namespace, NamespacedAccess)
+		}
+	}
+
+	sort.Slice(status.Namespaces, func(i, j int) bool {
+		return status.Namespaces[i].Namespace < status.Namespaces[j].Namespace
+	})
+
+	c.mu.Lock()
+	c.status = status
+	c.allowed = allowed
+	c.mu.Unlock()
+	return status
+}
+
+// Status - result of the last check
+func (c *AccessChecker) Status() AccessStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.status
+}
+
+// CanWatch - whether keel can list and watch the resource in the namespace,
+// resources that weren't checked are allowed
+func (c *AccessChecker) CanWatch(namespace, resource string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	rules, ok := c.allowed[namespace]
+	if !ok {
+		return true
+	}
+	for rule, allowed := range rules {
+		if rule.Resource == resource && (rule.Verb == "list" || rule.Verb == "watch") && !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// Start - checks permissions every interval until ctx is done, ie: to pick
+// up roles that were granted after keel started
+func (c *AccessChecker) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Check()
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package k8s
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestAccessCheckerClusterWide(t *testing.T) {
+	checker := newAccessChecker(nil, func(namespace string, rule AccessRule) (bool, error) {
+		if namespace != "" {
+			t.Errorf("unexpected namespace: %s", namespace)
+		}
+		return rule.Resource != "secrets", nil
+	}, logrus.New())
+
+	status := checker.Check()
+	if status.Namespaced {
+		t.Errorf("expected cluster-wide status")
+	}
+	if len(status.Namespaces) != 1 || len(status.Namespaces[0].Denied) != 3 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if checker.CanWatch("", "secrets") {
+		t.Errorf("expected secrets to be skipped")
+	}
+	if !checker.CanWatch("", "deployments") || !checker.CanWatch("", "namespaces") {
+		t.Errorf("expected deployments and namespaces to be watched")
+	}
+}
+
+func TestAccessCheckerNamespaced(t *testing.T) {
+	checker := newAccessChecker([]string{"team-b", "team-a"}, func(namespace string, rule AccessRule) (bool, error) {
+		switch namespace {
+		case "":
+			return false, nil
+		case "team-b":
+			return false, errors.New("forbidden")
+		}
+		return true, nil
+	}, logrus.New())
+
+	status := checker.Check()
+	if !status.Namespaced {
+		t.Errorf("expected namespaced status")
+	}
+	if len(status.Namespaces) != 3 {
+		t.Fatalf("expected 3 namespaces, got: %+v", status.Namespaces)
+	}
+	if status.Namespaces[1].Namespace != "team-a" || len(status.Namespaces[1].Denied) != 0 {
+		t.Errorf("unexpected team-a access: %+v", status.Namespaces[1])
+	}
+	if status.Namespaces[2].Error != "forbidden" || len(status.Namespaces[2].Allowed) != 0 {
+		t.Errorf("unexpected team-b access: %+v", status.Namespaces[2])
+	}
+
+	if checker.CanWatch("", "namespaces") {
+		t.Errorf("expected namespaces to be skipped")
+	}
+	if !checker.CanWatch("team-a", "deployments") {
+		t.Errorf("expected team-a deployments to be watched")
+	}
+	// reviews failed, watches are attempted
+	if !checker.CanWatch("team-b", "deployments") {
+		t.Errorf("expected team-b deployments to be watched")
+	}
+	if !checker.Status().CheckedAt.Equal(status.CheckedAt) {
+		t.Errorf("expected last status")
+	}
+}
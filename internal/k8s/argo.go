@@ -0,0 +1,106 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ArgoRolloutsResource - Argo Rollouts API resource (https://argoproj.github.io/argo-rollouts/)
+var ArgoRolloutsResource = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}
+
+// Argo Rollout phases
+const (
+	argoPhaseHealthy     = "Healthy"
+	argoPhaseProgressing = "Progressing"
+	argoPhasePaused      = "Paused"
+	argoPhaseDegraded    = "Degraded"
+)
+
+// isArgoRollout - whether unstructured object is an argoproj.io Rollout
+func isArgoRollout(obj *unstructured.Unstructured) bool {
+	return obj.GetKind() == "Rollout" && obj.GroupVersionKind().Group == ArgoRolloutsResource.Group
+}
+
+// argoObservedGeneration - rollout controller stores observed generation as
+// a string, older versions stored a hash of the spec instead
+func argoObservedGeneration(obj *unstructured.Unstructured) (int64, bool) {
+	v, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "status", "observedGeneration")
+	if !found {
+		return 0, false
+	}
+	switch g := v.(type) {
+	case int64:
+		return g, true
+	case string:
+		parsed, err := strconv.ParseInt(g, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return parsed, true
+	}
+	return 0, false
+}
+
+// argoCanaryStep - canary step progress, ie: "2/5 canary steps completed", empty for
+// blue-green rollouts
+func argoCanaryStep(obj *unstructured.Unstructured) string {
+	steps, found, _ := unstructured.NestedSlice(obj.Object, "spec", "strategy", "canary", "steps")
+	if !found || len(steps) == 0 {
+		return ""
+	}
+	index, _, _ := unstructured.NestedInt64(obj.Object, "status", "currentStepIndex")
+	return fmt.Sprintf("%d/%d canary steps completed", index, len(steps))
+}
+
+func argoRolloutStatus(obj *unstructured.Unstructured) RolloutStatus {
+	if observed, ok := argoObservedGeneration(obj); ok && obj.GetGeneration() > observed {
+		return RolloutStatus{Message: "waiting for rollout spec update to be observed"}
+	}
+
+	step := argoCanaryStep(obj)
+	message, _, _ := unstructured.NestedString(obj.Object, "status", "message")
+
+	if aborted, _, _ := unstructured.NestedBool(obj.Object, "status", "abort"); aborted {
+		return RolloutStatus{Failed: true, Progress: step, Message: withDetails("rollout was aborted", message)}
+	}
+
+	for _, analysis := range []string{"currentStepAnalysisRunStatus", "currentBackgroundAnalysisRunStatus"} {
+		run, found, _ := unstructured.NestedStringMap(obj.Object, "status", "canary", analysis)
+		if !found {
+			continue
+		}
+		switch run["status"] {
+		case "Failed", "Error", "Inconclusive":
+			return RolloutStatus{Failed: true, Progress: step, Message: withDetails(fmt.Sprintf("analysis run %s is %s", run["name"], run["status"]), run["message"])}
+		}
+	}
+
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	switch phase {
+	case argoPhaseHealthy:
+		return RolloutStatus{Done: true, Progress: step, Message: "successfully rolled out"}
+	case argoPhaseDegraded:
+		return RolloutStatus{Failed: true, Progress: step, Message: withDetails("rollout is degraded", message)}
+	case argoPhasePaused:
+		return RolloutStatus{Progress: step, Message: withDetails("rollout is paused", message)}
+	case argoPhaseProgressing:
+		return RolloutStatus{Progress: step, Message: withDetails("rollout is progressing", message)}
+	}
+
+	// rollouts controller that doesn't report phase, falling back to replica counts
+	status := unstructuredStatus(obj)
+	desired := int32(1)
+	if r, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas"); found {
+		desired = int32(r)
+	}
+	if status.UpdatedReplicas < desired {
+		return RolloutStatus{Progress: step, Message: fmt.Sprintf("%d out of %d new replicas have been updated", status.UpdatedReplicas, desired)}
+	}
+	if status.AvailableReplicas < status.UpdatedReplicas {
+		return RolloutStatus{Progress: step, Message: fmt.Sprintf("%d of %d updated replicas are available", status.AvailableReplicas, status.UpdatedReplicas)}
+	}
+	return RolloutStatus{Done: true, Progress: step, Message: "successfully rolled out"}
+}
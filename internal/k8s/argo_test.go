@@ -0,0 +1,171 @@
+package k8s
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func argoRollout() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Rollout",
+		"metadata": map[string]interface{}{
+			"name":       "app",
+			"namespace":  "default",
+			"generation": int64(2),
+			"labels":     map[string]interface{}{"keel.sh/policy": "major"},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"strategy": map[string]interface{}{
+				"canary": map[string]interface{}{
+					"steps": []interface{}{
+						map[string]interface{}{"setWeight": int64(20)},
+						map[string]interface{}{"pause": map[string]interface{}{}},
+						map[string]interface{}{"setWeight": int64(60)},
+					},
+				},
+			},
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"initContainers": []interface{}{
+						map[string]interface{}{"name": "migrate", "image": "karolisr/webhook-demo:0.0.14"},
+					},
+					"containers": []interface{}{
+						map[string]interface{}{"name": "app", "image": "karolisr/webhook-demo:0.0.14"},
+						map[string]interface{}{"name": "sidecar", "image": "envoyproxy/envoy:v1.10.0"},
+					},
+					"imagePullSecrets": []interface{}{
+						map[string]interface{}{"name": "registry-secret"},
+					},
+					"nodeSelector": map[string]interface{}{"kubernetes.io/os": "linux"},
+				},
+			},
+		},
+	}}
+}
+
+func TestArgoRolloutGenericResource(t *testing.T) {
+	gr := mustGenericResource(t, argoRollout())
+
+	if gr.Identifier != "rollout/default/app" {
+		t.Errorf("unexpected identifier: %s", gr.Identifier)
+	}
+	if gr.Kind() != "rollout" {
+		t.Errorf("unexpected kind: %s", gr.Kind())
+	}
+	if gr.GetLabels()["keel.sh/policy"] != "major" {
+		t.Errorf("unexpected labels: %v", gr.GetLabels())
+	}
+
+	images := gr.GetImages()
+	if len(images) != 2 || images[0] != "karolisr/webhook-demo:0.0.14" {
+		t.Fatalf("unexpected images: %v", images)
+	}
+	if secrets := gr.GetImagePullSecrets(); len(secrets) != 1 || secrets[0] != "registry-secret" {
+		t.Errorf("unexpected secrets: %v", secrets)
+	}
+	if selector := gr.GetNodeSelector(); selector["kubernetes.io/os"] != "linux" {
+		t.Errorf("unexpected node selector: %v", selector)
+	}
+
+	copied := gr.DeepCopy()
+	copied.UpdateContainer(0, "karolisr/webhook-demo:0.0.15")
+	copied.SetSpecAnnotations(map[string]string{"keel.sh/update-time": "now"})
+
+	if gr.Containers()[0].Image != "karolisr/webhook-demo:0.0.14" {
+		t.Errorf("original resource was modified: %s", gr.Containers()[0].Image)
+	}
+	if copied.Containers()[0].Image != "karolisr/webhook-demo:0.0.15" {
+		t.Errorf("container wasn't updated: %s", copied.Containers()[0].Image)
+	}
+	if copied.Containers()[1].Image != "envoyproxy/envoy:v1.10.0" {
+		t.Errorf("unexpected sidecar image: %s", copied.Containers()[1].Image)
+	}
+	copied.UpdateInitContainer(0, "karolisr/webhook-demo:0.0.15")
+	if init := copied.InitContainers(); len(init) != 1 || init[0].Image != "karolisr/webhook-demo:0.0.15" {
+		t.Errorf("init container wasn't updated: %v", init)
+	}
+	if copied.GetSpecAnnotations()["keel.sh/update-time"] != "now" {
+		t.Errorf("unexpected spec annotations: %v", copied.GetSpecAnnotations())
+	}
+}
+
+func TestUnsupportedUnstructuredResource(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Rollout",
+	}}
+	if _, err := NewGenericResource(obj); err == nil {
+		t.Errorf("expected error for unsupported kind")
+	}
+}
+
+func TestArgoRolloutStatus(t *testing.T) {
+	obj := argoRollout()
+	gr := mustGenericResource(t, obj)
+
+	unstructured.SetNestedField(obj.Object, "1", "status", "observedGeneration")
+	unstructured.SetNestedField(obj.Object, "Healthy", "status", "phase")
+	if status := gr.RolloutStatus(); status.Done || status.Failed {
+		t.Errorf("expected rollout in progress before spec is observed: %+v", status)
+	}
+
+	unstructured.SetNestedField(obj.Object, "2", "status", "observedGeneration")
+	unstructured.SetNestedField(obj.Object, "Paused", "status", "phase")
+	unstructured.SetNestedField(obj.Object, int64(1), "status", "currentStepIndex")
+	unstructured.SetNestedField(obj.Object, "CanaryPauseStep", "status", "message")
+	status := gr.RolloutStatus()
+	if status.Done || status.Failed {
+		t.Errorf("expected paused rollout in progress: %+v", status)
+	}
+	if status.Progress != "1/3 canary steps completed" {
+		t.Errorf("unexpected progress: %s", status.Progress)
+	}
+	if status.Message != "rollout is paused: CanaryPauseStep" {
+		t.Errorf("unexpected message: %s", status.Message)
+	}
+
+	unstructured.SetNestedField(obj.Object, "Progressing", "status", "phase")
+	unstructured.SetNestedStringMap(obj.Object, map[string]string{
+		"name":    "app-6d4f-2",
+		"status":  "Failed",
+		"message": "metric success-rate assessed Failed",
+	}, "status", "canary", "currentStepAnalysisRunStatus")
+	status = gr.RolloutStatus()
+	if !status.Failed {
+		t.Errorf("expected failed analysis to fail the rollout: %+v", status)
+	}
+	if status.Message != "analysis run app-6d4f-2 is Failed: metric success-rate assessed Failed" {
+		t.Errorf("unexpected message: %s", status.Message)
+	}
+
+	unstructured.RemoveNestedField(obj.Object, "status", "canary")
+	unstructured.SetNestedField(obj.Object, int64(3), "status", "currentStepIndex")
+	unstructured.SetNestedField(obj.Object, "Healthy", "status", "phase")
+	if status := gr.RolloutStatus(); !status.Done {
+		t.Errorf("expected rollout to be done: %+v", status)
+	}
+
+	unstructured.SetNestedField(obj.Object, true, "status", "abort")
+	if status := gr.RolloutStatus(); !status.Failed {
+		t.Errorf("expected aborted rollout to fail: %+v", status)
+	}
+}
+
+func TestArgoRolloutStatusWithoutPhase(t *testing.T) {
+	obj := argoRollout()
+	gr := mustGenericResource(t, obj)
+
+	unstructured.SetNestedField(obj.Object, int64(1), "status", "updatedReplicas")
+	if status := gr.RolloutStatus(); status.Done || status.Failed {
+		t.Errorf("expected rollout in progress: %+v", status)
+	}
+
+	unstructured.SetNestedField(obj.Object, int64(3), "status", "updatedReplicas")
+	unstructured.SetNestedField(obj.Object, int64(3), "status", "availableReplicas")
+	if status := gr.RolloutStatus(); !status.Done {
+		t.Errorf("expected rollout to be done: %+v", status)
+	}
+}
@@ -3,11 +3,27 @@ package k8s
 import (
 	"sort"
 	"sync"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
 )
 
 type genericResourceCache struct {
 	sync.Mutex
 	values []*GenericResource
+
+	// byIdentifier and byImage (repository -> identifiers) index values so that
+	// events resolve affected resources without going through the whole cache
+	byIdentifier map[string]*GenericResource
+	byImage      map[string]map[string]bool
+	// unindexed - resources with images outside of containers (env vars,
+	// ConfigMaps), they are checked for every image
+	unindexed map[string]bool
+
+	// namespace default annotations
+	defaults map[string]map[string]string
+	// ImageUpdatePolicies by namespace/name
+	policies map[string]*ImageUpdatePolicy
 }
 
 // GenericResourceCache - storage for generic resources with a rendezvous point for goroutines
@@ -22,12 +38,64 @@ func (cc *genericResourceCache) Values() []*GenericResource {
 	cc.Lock()
 	r := []*GenericResource{}
 	for _, v := range cc.values {
-		r = append(r, v.DeepCopy())
+		r = append(r, cc.copy(v))
 	}
 	cc.Unlock()
 	return r
 }
 
+// Get returns a copy of the resource, nil when it's not cached.
+func (cc *genericResourceCache) Get(identifier string) *GenericResource {
+	cc.Lock()
+	defer cc.Unlock()
+	gr, ok := cc.byIdentifier[identifier]
+	if !ok {
+		return nil
+	}
+	return cc.copy(gr)
+}
+
+// ValuesForImage returns copies of resources that may use the image repository
+// (ie: index.docker.io/karolisr/keel), sorted by identifier.
+func (cc *genericResourceCache) ValuesForImage(repository string) []*GenericResource {
+	cc.Lock()
+	defer cc.Unlock()
+
+	if cc.defaultsHaveImages() {
+		// image annotations of namespaces or policies can apply to any resource
+		r := make([]*GenericResource, 0, len(cc.values))
+		for _, v := range cc.values {
+			r = append(r, cc.copy(v))
+		}
+		return r
+	}
+
+	identifiers := make([]string, 0, len(cc.byImage[repository])+len(cc.unindexed))
+	for identifier := range cc.byImage[repository] {
+		identifiers = append(identifiers, identifier)
+	}
+	for identifier := range cc.unindexed {
+		if !cc.byImage[repository][identifier] {
+			identifiers = append(identifiers, identifier)
+		}
+	}
+	sort.Strings(identifiers)
+
+	r := make([]*GenericResource, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		r = append(r, cc.copy(cc.byIdentifier[identifier]))
+	}
+	return r
+}
+
+func (cc *genericResourceCache) copy(v *GenericResource) *GenericResource {
+	gr := v.DeepCopy()
+	if defaults := cc.resourceDefaults(gr); len(defaults) > 0 {
+		gr.SetDefaultAnnotations(defaults)
+	}
+	return gr
+}
+
 // Add adds an entry to the cache. If a GenericResource with the same
 // name exists, it is replaced.
 func (cc *genericResourceCache) Add(grs ...*GenericResource) {
@@ -48,8 +116,11 @@ func (cc *genericResourceCache) add(c *GenericResource) {
 	i := sort.Search(len(cc.values), func(i int) bool { return cc.values[i].Identifier >= c.Identifier })
 	if i < len(cc.values) && cc.values[i].Identifier == c.Identifier {
 		// c is already present, replace
+		cc.unindex(cc.values[i])
 		cc.values[i] = c
+		cc.index(c)
 	} else {
+		cc.index(c)
 		// c is not present, append
 		cc.values = append(cc.values, c)
 		// restort to convert append into insert
@@ -57,6 +128,39 @@ func (cc *genericResourceCache) add(c *GenericResource) {
 	}
 }
 
+// resourceDefaults - namespace defaults overridden by the ImageUpdatePolicy selecting the resource
+func (cc *genericResourceCache) resourceDefaults(gr *GenericResource) map[string]string {
+	namespaceDefaults := cc.defaults[gr.Namespace]
+	p := cc.imageUpdatePolicy(gr)
+	if p == nil {
+		return namespaceDefaults
+	}
+
+	defaults := make(map[string]string, len(namespaceDefaults)+len(p.Annotations))
+	for k, v := range namespaceDefaults {
+		defaults[k] = v
+	}
+	for k, v := range p.Annotations {
+		defaults[k] = v
+	}
+	return defaults
+}
+
+// SetNamespaceDefaults sets default annotations of resources in the namespace,
+// empty defaults remove them.
+func (cc *genericResourceCache) SetNamespaceDefaults(namespace string, defaults map[string]string) {
+	cc.Lock()
+	defer cc.Unlock()
+	if len(defaults) == 0 {
+		delete(cc.defaults, namespace)
+		return
+	}
+	if cc.defaults == nil {
+		cc.defaults = make(map[string]map[string]string)
+	}
+	cc.defaults[namespace] = defaults
+}
+
 // Remove removes the named entry from the cache. If the entry
 // is not present in the cache, the operation is a no-op.
 func (cc *genericResourceCache) Remove(identifiers ...string) {
@@ -77,10 +181,81 @@ func (cc *genericResourceCache) remove(identifier string) {
 	i := sort.Search(len(cc.values), func(i int) bool { return cc.values[i].Identifier >= identifier })
 	if i < len(cc.values) && cc.values[i].Identifier == identifier {
 		// c is present, remove
+		cc.unindex(cc.values[i])
 		cc.values = append(cc.values[:i], cc.values[i+1:]...)
 	}
 }
 
+// imageAnnotations - annotations that add images outside of containers
+var imageAnnotations = []string{types.KeelEnvImagesAnnotation, types.KeelConfigMapImagesAnnotation}
+
+func hasImageAnnotations(annotations map[string]string) bool {
+	for _, key := range imageAnnotations {
+		if _, ok := annotations[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultsHaveImages - whether namespace defaults or policies set image annotations
+func (cc *genericResourceCache) defaultsHaveImages() bool {
+	for _, defaults := range cc.defaults {
+		if hasImageAnnotations(defaults) {
+			return true
+		}
+	}
+	for _, p := range cc.policies {
+		if hasImageAnnotations(p.Annotations) {
+			return true
+		}
+	}
+	return false
+}
+
+// imageRepositories - repositories of container and init container images
+func imageRepositories(gr *GenericResource) []string {
+	var repositories []string
+	containers := gr.Containers()
+	for _, c := range append(containers[:len(containers):len(containers)], gr.InitContainers()...) {
+		ref, err := image.Parse(c.Image)
+		if err != nil {
+			continue
+		}
+		repositories = append(repositories, ref.Repository())
+	}
+	return repositories
+}
+
+func (cc *genericResourceCache) index(gr *GenericResource) {
+	if cc.byIdentifier == nil {
+		cc.byIdentifier = make(map[string]*GenericResource)
+		cc.byImage = make(map[string]map[string]bool)
+		cc.unindexed = make(map[string]bool)
+	}
+	cc.byIdentifier[gr.Identifier] = gr
+	if hasImageAnnotations(gr.objectAnnotations()) {
+		cc.unindexed[gr.Identifier] = true
+	}
+	for _, repository := range imageRepositories(gr) {
+		if cc.byImage[repository] == nil {
+			cc.byImage[repository] = make(map[string]bool)
+		}
+		cc.byImage[repository][gr.Identifier] = true
+	}
+}
+
+func (cc *genericResourceCache) unindex(gr *GenericResource) {
+	delete(cc.byIdentifier, gr.Identifier)
+	delete(cc.unindexed, gr.Identifier)
+	for _, repository := range imageRepositories(gr) {
+		delete(cc.byImage[repository], gr.Identifier)
+		if len(cc.byImage[repository]) == 0 {
+			delete(cc.byImage, repository)
+		}
+	}
+}
+
 // Cond implements a condition variable, a rendezvous point for goroutines
 // waiting for or announcing the occurence of an event.
 type Cond struct {
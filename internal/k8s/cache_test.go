@@ -1,8 +1,12 @@
 package k8s
 
 import (
+	"reflect"
 	"testing"
 
+	"github.com/keel-hq/keel/types"
+	"github.com/sirupsen/logrus"
+
 	apps_v1 "k8s.io/api/apps/v1"
 	core_v1 "k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -51,3 +55,136 @@ func TestAddGet(t *testing.T) {
 		t.Errorf("cached entry got modified: %s", stored2.Containers()[0].Image)
 	}
 }
+
+func TestNamespaceDefaults(t *testing.T) {
+	tr := &Translator{FieldLogger: logrus.New()}
+
+	tr.OnAdd(&core_v1.Namespace{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name: "xxxx",
+			Annotations: map[string]string{
+				"keel.sh/policy":    "minor",
+				"keel.sh/approvals": "1",
+				"keel.sh/trigger":   "poll",
+				"owner":             "platform",
+			},
+		},
+	})
+	tr.OnAdd(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "dep-1",
+			Namespace:   "xxxx",
+			Annotations: map[string]string{"keel.sh/approvals": "2"},
+			Labels:      map[string]string{"keel.sh/trigger": "default"},
+		},
+	})
+	tr.OnAdd(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "dep-2", Namespace: "other"},
+	})
+
+	// values are sorted by identifier
+	values := tr.Values()
+	annotations := values[1].GetAnnotations()
+	if annotations["keel.sh/policy"] != "minor" {
+		t.Errorf("expected namespace default policy, got: %v", annotations)
+	}
+	if annotations["keel.sh/approvals"] != "2" {
+		t.Errorf("expected workload annotation to override default, got: %v", annotations)
+	}
+	if _, ok := annotations["keel.sh/trigger"]; ok {
+		t.Errorf("expected workload label to override default, got: %v", annotations)
+	}
+	if _, ok := annotations["owner"]; ok {
+		t.Errorf("only keel annotations should be defaults, got: %v", annotations)
+	}
+	if len(values[0].GetAnnotations()) != 0 {
+		t.Errorf("unexpected annotations in other namespace: %v", values[0].GetAnnotations())
+	}
+
+	// defaults aren't written to the resource
+	annotations["kubernetes.io/change-cause"] = "update"
+	values[1].SetAnnotations(annotations)
+	own := values[1].GetResource().(*apps_v1.Deployment).Annotations
+	if len(own) != 2 || own["keel.sh/approvals"] != "2" || own["kubernetes.io/change-cause"] != "update" {
+		t.Errorf("unexpected resource annotations: %v", own)
+	}
+
+	tr.OnDelete(&core_v1.Namespace{ObjectMeta: meta_v1.ObjectMeta{Name: "xxxx"}})
+	if _, ok := tr.Values()[1].GetAnnotations()["keel.sh/policy"]; ok {
+		t.Errorf("expected defaults to be removed with namespace")
+	}
+}
+
+func testDeployment(name string, annotations map[string]string, images ...string) *GenericResource {
+	var containers []core_v1.Container
+	for _, img := range images {
+		containers = append(containers, core_v1.Container{Image: img})
+	}
+	gr, _ := NewGenericResource(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Annotations: annotations,
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: core_v1.PodTemplateSpec{
+				Spec: core_v1.PodSpec{Containers: containers},
+			},
+		},
+	})
+	return gr
+}
+
+func identifiers(grs []*GenericResource) []string {
+	var ids []string
+	for _, gr := range grs {
+		ids = append(ids, gr.Identifier)
+	}
+	return ids
+}
+
+func TestValuesForImage(t *testing.T) {
+	cc := &GenericResourceCache{}
+	cc.Add(
+		testDeployment("app", nil, "karolisr/keel:0.1.0", "redis:5"),
+		testDeployment("worker", nil, "index.docker.io/karolisr/keel:0.2.0"),
+		testDeployment("other", nil, "gcr.io/v2-namespace/hi-world:1.1.1"),
+		testDeployment("env", map[string]string{types.KeelEnvImagesAnnotation: "app=WORKER_IMAGE"}, "busybox"),
+	)
+
+	got := identifiers(cc.ValuesForImage("index.docker.io/karolisr/keel"))
+	expected := []string{"deployment/default/app", "deployment/default/env", "deployment/default/worker"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	// image changed, index is updated
+	cc.Add(testDeployment("worker", nil, "gcr.io/v2-namespace/hi-world:1.2.0"))
+	got = identifiers(cc.ValuesForImage("index.docker.io/karolisr/keel"))
+	expected = []string{"deployment/default/app", "deployment/default/env"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	got = identifiers(cc.ValuesForImage("gcr.io/v2-namespace/hi-world"))
+	expected = []string{"deployment/default/env", "deployment/default/other", "deployment/default/worker"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	cc.Remove("deployment/default/app", "deployment/default/env")
+	if got := cc.ValuesForImage("index.docker.io/karolisr/keel"); len(got) != 0 {
+		t.Errorf("expected removed resources to be unindexed, got %v", identifiers(got))
+	}
+	if cc.Get("deployment/default/app") != nil {
+		t.Errorf("expected removed resource to be gone")
+	}
+	if gr := cc.Get("deployment/default/other"); gr == nil || gr.Name != "other" {
+		t.Errorf("expected to get cached resource, got %v", gr)
+	}
+
+	// image annotations of namespace defaults apply to all resources
+	cc.SetNamespaceDefaults("default", map[string]string{types.KeelConfigMapImagesAnnotation: "config"})
+	if got := cc.ValuesForImage("index.docker.io/karolisr/keel"); len(got) != 2 {
+		t.Errorf("expected all resources, got %v", identifiers(got))
+	}
+}
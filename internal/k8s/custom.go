@@ -0,0 +1,209 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/keel-hq/keel/internal/workgroup"
+	"github.com/keel-hq/keel/types"
+
+	"github.com/sirupsen/logrus"
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+// custom resources of any CRD can be updated when their image fields are listed
+// in keel.sh/image-paths annotation, API resource of the watched kinds is
+// remembered so updated objects can be sent back through the dynamic client
+var customResources = struct {
+	sync.RWMutex
+	kinds map[schema.GroupVersionKind]schema.GroupVersionResource
+}{kinds: make(map[schema.GroupVersionKind]schema.GroupVersionResource)}
+
+// ParseCustomResource - parses API resource in group/version/resource format,
+// ie: apps.example.com/v1/applications
+func ParseCustomResource(resource string) (schema.GroupVersionResource, error) {
+	parts := strings.Split(strings.TrimSpace(resource), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return schema.GroupVersionResource{}, fmt.Errorf("invalid custom resource %q, expected group/version/resource", resource)
+	}
+	return schema.GroupVersionResource{Group: parts[0], Version: parts[1], Resource: parts[2]}, nil
+}
+
+// WatchCustomResources creates a SharedInformer for custom resources of the given API resource
+// and registers it with g. Only objects with keel.sh/image-paths annotation have images.
+func WatchCustomResources(g *workgroup.Group, client dynamic.Interface, log logrus.FieldLogger, gvr schema.GroupVersionResource, rs ...cache.ResourceEventHandler) {
+	handlers := make([]cache.ResourceEventHandler, 0, len(rs))
+	for _, r := range rs {
+		handlers = append(handlers, &customResourceHandler{gvr: gvr, next: r})
+	}
+	watchDynamic(g, client, log, gvr, workloadSelector, handlers...)
+}
+
+// customResourceHandler - registers kinds of watched objects
+type customResourceHandler struct {
+	gvr  schema.GroupVersionResource
+	next cache.ResourceEventHandler
+}
+
+func (h *customResourceHandler) register(obj interface{}) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	gvk := u.GroupVersionKind()
+
+	customResources.RLock()
+	_, found := customResources.kinds[gvk]
+	customResources.RUnlock()
+	if found {
+		return
+	}
+
+	customResources.Lock()
+	customResources.kinds[gvk] = h.gvr
+	customResources.Unlock()
+}
+
+func (h *customResourceHandler) OnAdd(obj interface{}) {
+	h.register(obj)
+	h.next.OnAdd(obj)
+}
+
+func (h *customResourceHandler) OnUpdate(oldObj, newObj interface{}) {
+	h.register(newObj)
+	h.next.OnUpdate(oldObj, newObj)
+}
+
+func (h *customResourceHandler) OnDelete(obj interface{}) {
+	h.next.OnDelete(obj)
+}
+
+// customResource - returns API resource of watched custom resource kind
+func customResource(obj *unstructured.Unstructured) (schema.GroupVersionResource, bool) {
+	customResources.RLock()
+	defer customResources.RUnlock()
+	gvr, ok := customResources.kinds[obj.GroupVersionKind()]
+	return gvr, ok
+}
+
+func isCustomResource(obj *unstructured.Unstructured) bool {
+	_, ok := customResource(obj)
+	return ok
+}
+
+// imagePaths - image fields from keel.sh/image-paths annotation, ie: ".spec.image, .spec.workers[0].image"
+func imagePaths(obj *unstructured.Unstructured) []string {
+	var paths []string
+	for _, p := range strings.Split(obj.GetAnnotations()[types.KeelImagePathsAnnotation], ",") {
+		p = strings.TrimSpace(p)
+		p = strings.TrimSuffix(strings.TrimPrefix(p, "{"), "}")
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// fieldSegment - field of the JSONPath, index is -1 when the field isn't a list item
+type fieldSegment struct {
+	field string
+	index int
+}
+
+// parseFieldPath - parses a subset of JSONPath, fields separated with dots
+// optionally followed by list index (.spec.workers[0].image)
+func parseFieldPath(path string) ([]fieldSegment, error) {
+	var segments []fieldSegment
+	for _, part := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		segment := fieldSegment{field: part, index: -1}
+		if i := strings.Index(part, "["); i >= 0 {
+			if !strings.HasSuffix(part, "]") {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+			index, err := strconv.Atoi(part[i+1 : len(part)-1])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid index in path %q", path)
+			}
+			segment.field, segment.index = part[:i], index
+		}
+		if segment.field == "" {
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// fieldParent - returns the map that holds the last field of the path
+func fieldParent(obj map[string]interface{}, segments []fieldSegment) (map[string]interface{}, bool) {
+	current := obj
+	for i, s := range segments {
+		if i == len(segments)-1 && s.index < 0 {
+			return current, true
+		}
+		v, ok := current[s.field]
+		if !ok {
+			return nil, false
+		}
+		if s.index >= 0 {
+			list, ok := v.([]interface{})
+			if !ok || s.index >= len(list) {
+				return nil, false
+			}
+			v = list[s.index]
+		}
+		if current, ok = v.(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// customContainers - image fields of custom resource as containers, container
+// name is the image path
+func customContainers(obj *unstructured.Unstructured) []core_v1.Container {
+	var containers []core_v1.Container
+	for _, path := range imagePaths(obj) {
+		segments, err := parseFieldPath(path)
+		if err != nil {
+			continue
+		}
+		parent, ok := fieldParent(obj.Object, segments)
+		if !ok {
+			continue
+		}
+		img, ok := parent[segments[len(segments)-1].field].(string)
+		if !ok || img == "" {
+			continue
+		}
+		containers = append(containers, core_v1.Container{Name: path, Image: img})
+	}
+	return containers
+}
+
+func updateCustomContainer(obj *unstructured.Unstructured, index int, image string) {
+	containers := customContainers(obj)
+	if index >= len(containers) {
+		return
+	}
+	segments, _ := parseFieldPath(containers[index].Name)
+	if parent, ok := fieldParent(obj.Object, segments); ok {
+		parent[segments[len(segments)-1].field] = image
+	}
+}
+
+// customResourceStatus - custom resources don't share status format, resources
+// that report observed generation are done once the update is observed
+func customResourceStatus(obj *unstructured.Unstructured) RolloutStatus {
+	observed, found, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if found && obj.GetGeneration() > observed {
+		return RolloutStatus{Message: "waiting for resource spec update to be observed"}
+	}
+	return RolloutStatus{Done: true, Message: "custom resource updated"}
+}
@@ -0,0 +1,101 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+func customApplication() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps.example.com/v1",
+		"kind":       "Application",
+		"metadata": map[string]interface{}{
+			"name":       "app",
+			"namespace":  "default",
+			"generation": int64(3),
+			"labels":     map[string]interface{}{"keel.sh/policy": "minor"},
+			"annotations": map[string]interface{}{
+				types.KeelImagePathsAnnotation: ".spec.image, {.spec.workers[1].image}, .spec.missing",
+			},
+		},
+		"spec": map[string]interface{}{
+			"image": "karolisr/webhook-demo:0.0.14",
+			"workers": []interface{}{
+				map[string]interface{}{"image": "redis:5.0.0"},
+				map[string]interface{}{"image": "karolisr/worker:1.0.0"},
+			},
+		},
+	}}
+}
+
+func TestParseCustomResource(t *testing.T) {
+	gvr, err := ParseCustomResource("apps.example.com/v1/applications")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if gvr != (schema.GroupVersionResource{Group: "apps.example.com", Version: "v1", Resource: "applications"}) {
+		t.Errorf("unexpected resource: %v", gvr)
+	}
+	if _, err := ParseCustomResource("applications"); err == nil {
+		t.Errorf("expected error for resource without group and version")
+	}
+}
+
+func TestCustomResourceGenericResource(t *testing.T) {
+	obj := customApplication()
+	if _, err := NewGenericResource(obj); err == nil {
+		t.Fatalf("expected error for kind that isn't watched")
+	}
+
+	gvr := schema.GroupVersionResource{Group: "apps.example.com", Version: "v1", Resource: "applications"}
+	h := &customResourceHandler{gvr: gvr, next: cache.ResourceEventHandlerFuncs{}}
+	h.OnAdd(obj)
+
+	if registered, ok := UnstructuredResource(obj); !ok || registered != gvr {
+		t.Fatalf("expected custom resource to be registered, got: %v", registered)
+	}
+
+	gr := mustGenericResource(t, obj)
+	if gr.Identifier != "application/default/app" {
+		t.Errorf("unexpected identifier: %s", gr.Identifier)
+	}
+
+	containers := gr.Containers()
+	if len(containers) != 2 {
+		t.Fatalf("unexpected containers: %v", containers)
+	}
+	if containers[0].Name != ".spec.image" || containers[0].Image != "karolisr/webhook-demo:0.0.14" {
+		t.Errorf("unexpected container: %v", containers[0])
+	}
+	if containers[1].Name != ".spec.workers[1].image" || containers[1].Image != "karolisr/worker:1.0.0" {
+		t.Errorf("unexpected container: %v", containers[1])
+	}
+
+	copied := gr.DeepCopy()
+	copied.UpdateContainer(1, "karolisr/worker:1.1.0")
+	copied.SetSpecAnnotations(map[string]string{"keel.sh/update-time": "now"})
+
+	if img := copied.Containers()[1].Image; img != "karolisr/worker:1.1.0" {
+		t.Errorf("image wasn't updated: %s", img)
+	}
+	if img := gr.Containers()[1].Image; img != "karolisr/worker:1.0.0" {
+		t.Errorf("original resource was modified: %s", img)
+	}
+	updated := copied.GetResource().(*unstructured.Unstructured)
+	if _, found, _ := unstructured.NestedMap(updated.Object, "spec", "template"); found {
+		t.Errorf("pod template shouldn't be added to custom resources")
+	}
+
+	if status := gr.RolloutStatus(); !status.Done {
+		t.Errorf("expected custom resource without status to be done, got: %s", status.Message)
+	}
+	unstructured.SetNestedField(obj.Object, int64(2), "status", "observedGeneration")
+	if status := mustGenericResource(t, obj).RolloutStatus(); status.Done {
+		t.Errorf("expected update not to be observed yet")
+	}
+}
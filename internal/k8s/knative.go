@@ -0,0 +1,113 @@
+package k8s
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// KnativeServiceResource - Knative Serving service API resource (https://knative.dev/docs/serving/)
+var KnativeServiceResource = schema.GroupVersionResource{Group: "serving.knative.dev", Version: "v1", Resource: "services"}
+
+// isKnativeService - whether unstructured object is a serving.knative.dev Service
+func isKnativeService(obj *unstructured.Unstructured) bool {
+	return obj.GetKind() == "Service" && obj.GroupVersionKind().Group == KnativeServiceResource.Group
+}
+
+// knativeCondition - returns status condition of the given type
+func knativeCondition(obj *unstructured.Unstructured, conditionType string) (map[string]interface{}, bool) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		m, ok := c.(map[string]interface{})
+		if ok && m["type"] == conditionType {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+func conditionMessage(condition map[string]interface{}) string {
+	reason, _ := condition["reason"].(string)
+	message, _ := condition["message"].(string)
+	if reason == "" {
+		return message
+	}
+	return withDetails(reason, message)
+}
+
+// knativeRevisionTraffic - percent of traffic routed to the revision
+func knativeRevisionTraffic(obj *unstructured.Unstructured, revision string) (int64, bool) {
+	targets, _, _ := unstructured.NestedSlice(obj.Object, "status", "traffic")
+	var percent int64
+	found := false
+	for _, t := range targets {
+		m, ok := t.(map[string]interface{})
+		if !ok || m["revisionName"] != revision {
+			continue
+		}
+		if p, ok := m["percent"].(int64); ok {
+			percent += p
+			found = true
+		}
+	}
+	return percent, found
+}
+
+func knativeServiceStatus(obj *unstructured.Unstructured) RolloutStatus {
+	observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if obj.GetGeneration() > observed {
+		return RolloutStatus{Message: "waiting for service spec update to be observed"}
+	}
+
+	created, _, _ := unstructured.NestedString(obj.Object, "status", "latestCreatedRevisionName")
+	ready, _, _ := unstructured.NestedString(obj.Object, "status", "latestReadyRevisionName")
+
+	if c, ok := knativeCondition(obj, "ConfigurationsReady"); ok && c["status"] == "False" {
+		return RolloutStatus{Failed: true, Message: withDetails(fmt.Sprintf("revision %s failed", created), conditionMessage(c))}
+	}
+	if created == "" || created != ready {
+		return RolloutStatus{Message: fmt.Sprintf("waiting for revision %s to become ready", created)}
+	}
+
+	c, ok := knativeCondition(obj, "Ready")
+	if !ok || c["status"] == "Unknown" {
+		return RolloutStatus{Message: fmt.Sprintf("waiting for service with revision %s to become ready", ready)}
+	}
+	if c["status"] == "False" {
+		return RolloutStatus{Failed: true, Message: withDetails("service is not ready", conditionMessage(c))}
+	}
+
+	if percent, ok := knativeRevisionTraffic(obj, ready); ok {
+		return RolloutStatus{Done: true, Message: fmt.Sprintf("revision %s is ready and receives %d%% of traffic", ready, percent)}
+	}
+	return RolloutStatus{Done: true, Message: fmt.Sprintf("revision %s is ready", ready)}
+}
+
+// ShiftTraffic - routes percent of traffic to the revision that will be created by
+// the update, the rest stays with the currently ready revision. Only Knative
+// services support traffic splitting.
+func (r *GenericResource) ShiftTraffic(percent int64) error {
+	obj, ok := r.obj.(*unstructured.Unstructured)
+	if !ok || !isKnativeService(obj) {
+		return fmt.Errorf("%s doesn't support traffic splitting", r.Kind())
+	}
+	if percent <= 0 || percent > 100 {
+		return fmt.Errorf("invalid traffic percent %d", percent)
+	}
+
+	latest := map[string]interface{}{"latestRevision": true, "percent": percent}
+	if percent == 100 {
+		return unstructured.SetNestedSlice(obj.Object, []interface{}{latest}, "spec", "traffic")
+	}
+
+	current, _, _ := unstructured.NestedString(obj.Object, "status", "latestReadyRevisionName")
+	if current == "" {
+		return fmt.Errorf("service doesn't have a ready revision to split traffic with")
+	}
+
+	return unstructured.SetNestedSlice(obj.Object, []interface{}{
+		map[string]interface{}{"revisionName": current, "percent": 100 - percent},
+		latest,
+	}, "spec", "traffic")
+}
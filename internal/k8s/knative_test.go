@@ -0,0 +1,125 @@
+package k8s
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func knativeService() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "serving.knative.dev/v1",
+		"kind":       "Service",
+		"metadata": map[string]interface{}{
+			"name":       "hello",
+			"namespace":  "default",
+			"generation": int64(2),
+		},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"image": "gcr.io/knative-samples/helloworld-go:1.0.0"},
+					},
+				},
+			},
+		},
+		"status": map[string]interface{}{
+			"observedGeneration":        int64(1),
+			"latestCreatedRevisionName": "hello-00001",
+			"latestReadyRevisionName":   "hello-00001",
+		},
+	}}
+}
+
+func TestKnativeServiceGenericResource(t *testing.T) {
+	gr := mustGenericResource(t, knativeService())
+
+	if gr.Identifier != "ksvc/default/hello" {
+		t.Errorf("unexpected identifier: %s", gr.Identifier)
+	}
+	gr.UpdateContainer(0, "gcr.io/knative-samples/helloworld-go:1.1.0")
+	if images := gr.GetImages(); len(images) != 1 || images[0] != "gcr.io/knative-samples/helloworld-go:1.1.0" {
+		t.Errorf("unexpected images: %v", images)
+	}
+}
+
+func TestKnativeServiceStatus(t *testing.T) {
+	obj := knativeService()
+	gr := mustGenericResource(t, obj)
+
+	if status := gr.RolloutStatus(); status.Done || status.Failed {
+		t.Errorf("expected rollout in progress before spec is observed: %+v", status)
+	}
+
+	unstructured.SetNestedField(obj.Object, int64(2), "status", "observedGeneration")
+	unstructured.SetNestedField(obj.Object, "hello-00002", "status", "latestCreatedRevisionName")
+	if status := gr.RolloutStatus(); status.Done || status.Failed {
+		t.Errorf("expected rollout in progress until revision is ready: %+v", status)
+	}
+
+	unstructured.SetNestedSlice(obj.Object, []interface{}{
+		map[string]interface{}{"type": "ConfigurationsReady", "status": "False", "reason": "RevisionFailed", "message": "image pull failed"},
+	}, "status", "conditions")
+	status := gr.RolloutStatus()
+	if !status.Failed {
+		t.Errorf("expected failed revision to fail the rollout: %+v", status)
+	}
+	if status.Message != "revision hello-00002 failed: RevisionFailed: image pull failed" {
+		t.Errorf("unexpected message: %s", status.Message)
+	}
+
+	unstructured.SetNestedField(obj.Object, "hello-00002", "status", "latestReadyRevisionName")
+	unstructured.SetNestedSlice(obj.Object, []interface{}{
+		map[string]interface{}{"type": "ConfigurationsReady", "status": "True"},
+		map[string]interface{}{"type": "Ready", "status": "True"},
+	}, "status", "conditions")
+	unstructured.SetNestedSlice(obj.Object, []interface{}{
+		map[string]interface{}{"revisionName": "hello-00001", "percent": int64(80)},
+		map[string]interface{}{"revisionName": "hello-00002", "percent": int64(20), "latestRevision": true},
+	}, "status", "traffic")
+	status = gr.RolloutStatus()
+	if !status.Done {
+		t.Errorf("expected rollout to be done: %+v", status)
+	}
+	if status.Message != "revision hello-00002 is ready and receives 20% of traffic" {
+		t.Errorf("unexpected message: %s", status.Message)
+	}
+}
+
+func TestKnativeShiftTraffic(t *testing.T) {
+	obj := knativeService()
+	gr := mustGenericResource(t, obj)
+
+	if err := gr.ShiftTraffic(20); err != nil {
+		t.Fatalf("failed to shift traffic: %s", err)
+	}
+
+	traffic, _, _ := unstructured.NestedSlice(obj.Object, "spec", "traffic")
+	if len(traffic) != 2 {
+		t.Fatalf("expected 2 traffic targets, got: %v", traffic)
+	}
+	previous := traffic[0].(map[string]interface{})
+	if previous["revisionName"] != "hello-00001" || previous["percent"] != int64(80) {
+		t.Errorf("unexpected previous revision target: %v", previous)
+	}
+	latest := traffic[1].(map[string]interface{})
+	if latest["latestRevision"] != true || latest["percent"] != int64(20) {
+		t.Errorf("unexpected latest revision target: %v", latest)
+	}
+
+	// copies must stay usable after traffic is set
+	gr.DeepCopy()
+
+	if err := gr.ShiftTraffic(100); err != nil {
+		t.Fatalf("failed to shift traffic: %s", err)
+	}
+	traffic, _, _ = unstructured.NestedSlice(obj.Object, "spec", "traffic")
+	if len(traffic) != 1 {
+		t.Errorf("expected all traffic on latest revision, got: %v", traffic)
+	}
+
+	if err := mustGenericResource(t, argoRollout()).ShiftTraffic(20); err == nil {
+		t.Errorf("expected error for resource without traffic splitting")
+	}
+}
@@ -0,0 +1,89 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxPodProblems - how many pod problems are reported at most, the rest is summarized
+const maxPodProblems = 5
+
+// podProblemReasons - container waiting reasons that won't resolve without intervention
+var podProblemReasons = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+}
+
+// PodSelector - label selector of the resource pods, empty for resources
+// that don't manage pods directly
+func (r *GenericResource) PodSelector() string {
+	var selector *meta_v1.LabelSelector
+	switch obj := r.obj.(type) {
+	case *apps_v1.Deployment:
+		selector = obj.Spec.Selector
+	case *apps_v1.StatefulSet:
+		selector = obj.Spec.Selector
+	case *apps_v1.DaemonSet:
+		selector = obj.Spec.Selector
+	}
+	if selector == nil {
+		return ""
+	}
+	return meta_v1.FormatLabelSelector(selector)
+}
+
+// ReadyReplicas - ready out of desired pods, empty for resources without
+// replica status
+func (r *GenericResource) ReadyReplicas() string {
+	switch obj := r.obj.(type) {
+	case *apps_v1.Deployment:
+		return fmt.Sprintf("%d/%d ready, %d updated", obj.Status.ReadyReplicas, replicas(obj.Spec.Replicas), obj.Status.UpdatedReplicas)
+	case *apps_v1.StatefulSet:
+		return fmt.Sprintf("%d/%d ready, %d updated", obj.Status.ReadyReplicas, replicas(obj.Spec.Replicas), obj.Status.UpdatedReplicas)
+	case *apps_v1.DaemonSet:
+		return fmt.Sprintf("%d/%d ready, %d updated", obj.Status.NumberReady, obj.Status.DesiredNumberScheduled, obj.Status.UpdatedNumberScheduled)
+	}
+	return ""
+}
+
+// PodProblems - describes pods that are stuck, ie: failing to pull the image,
+// crash looping or not schedulable
+func PodProblems(pods []core_v1.Pod) []string {
+	var problems []string
+	for _, pod := range pods {
+		for _, c := range pod.Status.Conditions {
+			if c.Type == core_v1.PodScheduled && c.Status == core_v1.ConditionFalse && c.Reason == core_v1.PodReasonUnschedulable {
+				problems = append(problems, fmt.Sprintf("pod %s: %s: %s", pod.Name, c.Reason, c.Message))
+			}
+		}
+
+		statuses := append([]core_v1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.ContainerStatuses...)
+		for _, s := range statuses {
+			if s.State.Waiting == nil || !podProblemReasons[s.State.Waiting.Reason] {
+				continue
+			}
+			problem := fmt.Sprintf("pod %s container %s: %s", pod.Name, s.Name, s.State.Waiting.Reason)
+			if s.State.Waiting.Message != "" {
+				problem += ": " + s.State.Waiting.Message
+			}
+			problems = append(problems, problem)
+		}
+	}
+
+	sort.Strings(problems)
+	if len(problems) > maxPodProblems {
+		more := len(problems) - maxPodProblems
+		problems = append(problems[:maxPodProblems], fmt.Sprintf("%d more problems", more))
+	}
+	return problems
+}
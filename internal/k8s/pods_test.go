@@ -0,0 +1,80 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodSelector(t *testing.T) {
+	d := &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "dep", Namespace: "default"},
+		Spec: apps_v1.DeploymentSpec{
+			Replicas: int32Ptr(3),
+			Selector: &meta_v1.LabelSelector{MatchLabels: map[string]string{"app": "dep"}},
+		},
+		Status: apps_v1.DeploymentStatus{ReadyReplicas: 2, UpdatedReplicas: 1},
+	}
+	gr := mustGenericResource(t, d)
+
+	if selector := gr.PodSelector(); selector != "app=dep" {
+		t.Errorf("unexpected selector: %s", selector)
+	}
+	if ready := gr.ReadyReplicas(); ready != "2/3 ready, 1 updated" {
+		t.Errorf("unexpected ready replicas: %s", ready)
+	}
+}
+
+func TestPodProblems(t *testing.T) {
+	pods := []core_v1.Pod{
+		{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "dep-1"},
+			Status: core_v1.PodStatus{
+				ContainerStatuses: []core_v1.ContainerStatus{
+					{Name: "app", State: core_v1.ContainerState{Waiting: &core_v1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image \"karolisr/keel:0.2.0\""}}},
+					{Name: "sidecar", State: core_v1.ContainerState{Running: &core_v1.ContainerStateRunning{}}},
+				},
+			},
+		},
+		{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "dep-2"},
+			Status: core_v1.PodStatus{
+				ContainerStatuses: []core_v1.ContainerStatus{
+					// containers are expected to wait while being created
+					{Name: "app", State: core_v1.ContainerState{Waiting: &core_v1.ContainerStateWaiting{Reason: "ContainerCreating"}}},
+				},
+			},
+		},
+		{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "dep-3"},
+			Status: core_v1.PodStatus{
+				Conditions: []core_v1.PodCondition{
+					{Type: core_v1.PodScheduled, Status: core_v1.ConditionFalse, Reason: core_v1.PodReasonUnschedulable, Message: "0/3 nodes are available"},
+				},
+			},
+		},
+	}
+
+	problems := PodProblems(pods)
+	if len(problems) != 2 {
+		t.Fatalf("expected 2 problems, got: %v", problems)
+	}
+	if !strings.HasPrefix(problems[0], "pod dep-1 container app: ImagePullBackOff") {
+		t.Errorf("unexpected problem: %s", problems[0])
+	}
+	if problems[1] != "pod dep-3: Unschedulable: 0/3 nodes are available" {
+		t.Errorf("unexpected problem: %s", problems[1])
+	}
+
+	var many []core_v1.Pod
+	for i := 0; i < 7; i++ {
+		many = append(many, pods[0])
+	}
+	problems = PodProblems(many)
+	if len(problems) != maxPodProblems+1 || problems[maxPodProblems] != "2 more problems" {
+		t.Errorf("expected problems to be summarized, got: %v", problems)
+	}
+}
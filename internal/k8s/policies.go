@@ -0,0 +1,310 @@
+package k8s
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/internal/workgroup"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	"github.com/rusenask/cron"
+	"github.com/sirupsen/logrus"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ImageUpdatePolicyResource - keel.sh ImageUpdatePolicy custom resources, they
+// select workloads in their namespace and replace keel annotations on them
+var ImageUpdatePolicyResource = schema.GroupVersionResource{Group: "keel.sh", Version: "v1alpha1", Resource: "imageupdatepolicies"}
+
+// ImageUpdatePolicyKind - kind of ImageUpdatePolicy custom resources
+const ImageUpdatePolicyKind = "ImageUpdatePolicy"
+
+// policyStatusInterval - how often matched workloads of policies are counted
+const policyStatusInterval = time.Minute
+
+// ImageUpdatePolicy - parsed ImageUpdatePolicy, its spec is translated to keel
+// annotations which are defaults of the selected workloads
+type ImageUpdatePolicy struct {
+	Namespace  string
+	Name       string
+	Generation int64
+
+	// Annotations - keel annotations of the spec
+	Annotations map[string]string
+	// Err - why the spec is invalid, invalid policies don't select workloads
+	Err error
+
+	selector labels.Selector
+	obj      *unstructured.Unstructured
+}
+
+// WatchImageUpdatePolicies creates a SharedInformer for keel.sh ImageUpdatePolicies and registers it with g.
+func WatchImageUpdatePolicies(g *workgroup.Group, client dynamic.Interface, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
+	watchDynamic(g, client, log, ImageUpdatePolicyResource, "", rs...)
+}
+
+func isImageUpdatePolicy(obj interface{}) (*unstructured.Unstructured, bool) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, false
+	}
+	gvk := u.GroupVersionKind()
+	return u, gvk.Group == ImageUpdatePolicyResource.Group && gvk.Kind == ImageUpdatePolicyKind
+}
+
+// ParseImageUpdatePolicy - translates policy spec (policy, matchTag, trigger,
+// pollSchedule, approvals, approvalDeadline, updateWindows, notificationChannels)
+// to annotations and validates it
+func ParseImageUpdatePolicy(u *unstructured.Unstructured) *ImageUpdatePolicy {
+	p := &ImageUpdatePolicy{
+		Namespace:   u.GetNamespace(),
+		Name:        u.GetName(),
+		Generation:  u.GetGeneration(),
+		Annotations: make(map[string]string),
+		obj:         u,
+	}
+	p.selector, p.Err = p.parse(u)
+	return p
+}
+
+func (p *ImageUpdatePolicy) parse(u *unstructured.Unstructured) (labels.Selector, error) {
+	selector := labels.Everything()
+	if s, found, err := unstructured.NestedMap(u.Object, "spec", "selector"); err != nil {
+		return nil, fmt.Errorf("invalid selector: %s", err)
+	} else if found {
+		var ls meta_v1.LabelSelector
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(s, &ls); err != nil {
+			return nil, fmt.Errorf("invalid selector: %s", err)
+		}
+		if selector, err = meta_v1.LabelSelectorAsSelector(&ls); err != nil {
+			return nil, fmt.Errorf("invalid selector: %s", err)
+		}
+	}
+
+	if value, found, err := unstructured.NestedString(u.Object, "spec", "policy"); err != nil {
+		return nil, fmt.Errorf("invalid policy: %s", err)
+	} else if found {
+		if plc := policy.GetPolicy(value, &policy.Options{}); plc.Type() == policy.PolicyTypeNone && value != "never" {
+			return nil, fmt.Errorf("invalid policy %q", value)
+		}
+		p.Annotations[types.KeelPolicyLabel] = value
+	}
+
+	if value, found, err := unstructured.NestedBool(u.Object, "spec", "matchTag"); err != nil {
+		return nil, fmt.Errorf("invalid matchTag: %s", err)
+	} else if found {
+		p.Annotations[types.KeelForceTagMatchLabel] = strconv.FormatBool(value)
+	}
+
+	if value, found, err := unstructured.NestedString(u.Object, "spec", "trigger"); err != nil {
+		return nil, fmt.Errorf("invalid trigger: %s", err)
+	} else if found {
+		if value != types.TriggerTypeDefault.String() && value != types.TriggerTypePoll.String() {
+			return nil, fmt.Errorf("invalid trigger %q, expected default or poll", value)
+		}
+		p.Annotations[types.KeelTriggerLabel] = value
+	}
+
+	if value, found, err := unstructured.NestedString(u.Object, "spec", "pollSchedule"); err != nil {
+		return nil, fmt.Errorf("invalid pollSchedule: %s", err)
+	} else if found {
+		if _, err := cron.Parse(value); err != nil {
+			return nil, fmt.Errorf("invalid pollSchedule %q: %s", value, err)
+		}
+		p.Annotations[types.KeelPollScheduleAnnotation] = value
+	}
+
+	for field, annotation := range map[string]string{
+		"approvals":        types.KeelMinimumApprovalsLabel,
+		"approvalDeadline": types.KeelApprovalDeadlineLabel,
+	} {
+		value, found, err := unstructured.NestedInt64(u.Object, "spec", field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", field, err)
+		}
+		if found {
+			if value < 0 {
+				return nil, fmt.Errorf("invalid %s %d", field, value)
+			}
+			p.Annotations[annotation] = strconv.FormatInt(value, 10)
+		}
+	}
+
+	if value, found, err := unstructured.NestedStringSlice(u.Object, "spec", "updateWindows"); err != nil {
+		return nil, fmt.Errorf("invalid updateWindows: %s", err)
+	} else if found && len(value) > 0 {
+		windows := strings.Join(value, "; ")
+		if _, err := timeutil.ParseWindows(windows); err != nil {
+			return nil, fmt.Errorf("invalid updateWindows: %s", err)
+		}
+		p.Annotations[types.KeelUpdateWindowAnnotation] = windows
+	}
+
+	if value, found, err := unstructured.NestedStringSlice(u.Object, "spec", "notificationChannels"); err != nil {
+		return nil, fmt.Errorf("invalid notificationChannels: %s", err)
+	} else if found && len(value) > 0 {
+		p.Annotations[types.KeelNotificationChanAnnotation] = strings.Join(value, ",")
+	}
+
+	return selector, nil
+}
+
+// matches - whether the policy selects the resource
+func (p *ImageUpdatePolicy) matches(gr *GenericResource) bool {
+	return p.Err == nil && p.Namespace == gr.Namespace && p.selector.Matches(labels.Set(gr.GetLabels()))
+}
+
+func (p *ImageUpdatePolicy) key() string {
+	return p.Namespace + "/" + p.Name
+}
+
+// ImageUpdatePolicyStatus - status of the policy reported in the custom resource
+type ImageUpdatePolicyStatus struct {
+	Policy           *ImageUpdatePolicy
+	MatchedWorkloads []string
+}
+
+// ReconcileImageUpdatePolicies - updates status of ImageUpdatePolicies with their
+// validation result and selected workloads, statuses are compared so objects are
+// only updated when they change
+func ReconcileImageUpdatePolicies(g *workgroup.Group, client dynamic.Interface, grc *GenericResourceCache, log logrus.FieldLogger) {
+	g.Add(func(stop <-chan struct{}) {
+		log := log.WithField("context", "imageupdatepolicies")
+		log.Println("started")
+		defer log.Println("stopped")
+
+		ticker := time.NewTicker(policyStatusInterval)
+		defer ticker.Stop()
+		for {
+			for _, status := range grc.ImageUpdatePolicyStatuses() {
+				if err := updatePolicyStatus(client, status); err != nil {
+					log.WithFields(logrus.Fields{
+						"error":     err,
+						"namespace": status.Policy.Namespace,
+						"name":      status.Policy.Name,
+					}).Error("failed to update policy status")
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+		}
+	})
+}
+
+func updatePolicyStatus(client dynamic.Interface, status ImageUpdatePolicyStatus) error {
+	obj := status.Policy.obj.DeepCopy()
+	current, _, _ := unstructured.NestedMap(obj.Object, "status")
+
+	desired := PolicyStatus(status, current, timeutil.Now())
+	if reflect.DeepEqual(current, desired) {
+		return nil
+	}
+	if err := unstructured.SetNestedMap(obj.Object, desired, "status"); err != nil {
+		return err
+	}
+	_, err := client.Resource(ImageUpdatePolicyResource).Namespace(obj.GetNamespace()).UpdateStatus(obj, meta_v1.UpdateOptions{})
+	return err
+}
+
+// PolicyStatus - status of the policy, transition time of the Ready condition is
+// kept while the condition doesn't change
+func PolicyStatus(status ImageUpdatePolicyStatus, current map[string]interface{}, now time.Time) map[string]interface{} {
+	condition := map[string]interface{}{
+		"type":    "Ready",
+		"status":  "True",
+		"reason":  "Valid",
+		"message": fmt.Sprintf("selects %d workloads", len(status.MatchedWorkloads)),
+	}
+	if status.Policy.Err != nil {
+		condition["status"] = "False"
+		condition["reason"] = "Invalid"
+		condition["message"] = status.Policy.Err.Error()
+	}
+
+	condition["lastTransitionTime"] = now.UTC().Format(time.RFC3339)
+	if conditions, ok := current["conditions"].([]interface{}); ok && len(conditions) == 1 {
+		if previous, ok := conditions[0].(map[string]interface{}); ok && previous["status"] == condition["status"] && previous["reason"] == condition["reason"] {
+			condition["lastTransitionTime"] = previous["lastTransitionTime"]
+		}
+	}
+
+	workloads := make([]interface{}, 0, len(status.MatchedWorkloads))
+	for _, w := range status.MatchedWorkloads {
+		workloads = append(workloads, w)
+	}
+	return map[string]interface{}{
+		"observedGeneration": status.Policy.Generation,
+		"matchedWorkloads":   workloads,
+		"conditions":         []interface{}{condition},
+	}
+}
+
+// imageUpdatePolicy - policy selecting the resource, when several policies select
+// it the first by name is used
+func (cc *genericResourceCache) imageUpdatePolicy(gr *GenericResource) *ImageUpdatePolicy {
+	var selected *ImageUpdatePolicy
+	for _, p := range cc.policies {
+		if p.matches(gr) && (selected == nil || p.Name < selected.Name) {
+			selected = p
+		}
+	}
+	return selected
+}
+
+// SetImageUpdatePolicy adds or replaces the policy.
+func (cc *genericResourceCache) SetImageUpdatePolicy(p *ImageUpdatePolicy) {
+	cc.Lock()
+	defer cc.Unlock()
+	if cc.policies == nil {
+		cc.policies = make(map[string]*ImageUpdatePolicy)
+	}
+	cc.policies[p.key()] = p
+}
+
+// RemoveImageUpdatePolicy removes the policy.
+func (cc *genericResourceCache) RemoveImageUpdatePolicy(namespace, name string) {
+	cc.Lock()
+	defer cc.Unlock()
+	delete(cc.policies, namespace+"/"+name)
+}
+
+// ImageUpdatePolicyStatuses returns policies with workloads they are used for.
+func (cc *genericResourceCache) ImageUpdatePolicyStatuses() []ImageUpdatePolicyStatus {
+	cc.Lock()
+	defer cc.Unlock()
+
+	statuses := make(map[string]*ImageUpdatePolicyStatus, len(cc.policies))
+	for key, p := range cc.policies {
+		statuses[key] = &ImageUpdatePolicyStatus{Policy: p, MatchedWorkloads: []string{}}
+	}
+	for _, gr := range cc.values {
+		if p := cc.imageUpdatePolicy(gr); p != nil {
+			status := statuses[p.key()]
+			status.MatchedWorkloads = append(status.MatchedWorkloads, strings.ToLower(gr.Kind())+"/"+gr.Name)
+		}
+	}
+
+	result := make([]ImageUpdatePolicyStatus, 0, len(statuses))
+	for _, status := range statuses {
+		sort.Strings(status.MatchedWorkloads)
+		result = append(result, *status)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Policy.key() < result[j].Policy.key() })
+	return result
+}
@@ -0,0 +1,167 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func imageUpdatePolicy(name string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "keel.sh/v1alpha1",
+		"kind":       "ImageUpdatePolicy",
+		"metadata": map[string]interface{}{
+			"name":       name,
+			"namespace":  "xxxx",
+			"generation": int64(2),
+		},
+		"spec": spec,
+	}}
+}
+
+func TestParseImageUpdatePolicy(t *testing.T) {
+	p := ParseImageUpdatePolicy(imageUpdatePolicy("api", map[string]interface{}{
+		"selector":             map[string]interface{}{"matchLabels": map[string]interface{}{"app": "api"}},
+		"policy":               "minor",
+		"matchTag":             true,
+		"trigger":              "poll",
+		"pollSchedule":         "@every 5m",
+		"approvals":            int64(2),
+		"approvalDeadline":     int64(12),
+		"updateWindows":        []interface{}{"Mon-Fri 09:00-17:00", "Sat 00:00-06:00"},
+		"notificationChannels": []interface{}{"releases", "ops"},
+	}))
+	if p.Err != nil {
+		t.Fatalf("unexpected error: %s", p.Err)
+	}
+
+	expected := map[string]string{
+		"keel.sh/policy":           "minor",
+		"keel.sh/matchTag":         "true",
+		"keel.sh/trigger":          "poll",
+		"keel.sh/pollSchedule":     "@every 5m",
+		"keel.sh/approvals":        "2",
+		"keel.sh/approvalDeadline": "12",
+		"keel.sh/updateWindow":     "Mon-Fri 09:00-17:00; Sat 00:00-06:00",
+		"keel.sh/notify":           "releases,ops",
+	}
+	if len(p.Annotations) != len(expected) {
+		t.Errorf("unexpected annotations: %v", p.Annotations)
+	}
+	for k, v := range expected {
+		if p.Annotations[k] != v {
+			t.Errorf("unexpected %s: %q", k, p.Annotations[k])
+		}
+	}
+}
+
+func TestParseImageUpdatePolicyInvalid(t *testing.T) {
+	for _, spec := range []map[string]interface{}{
+		{"policy": "sometimes"},
+		{"trigger": "approval"},
+		{"pollSchedule": "whenever"},
+		{"approvals": int64(-1)},
+		{"approvals": "two"},
+		{"updateWindows": []interface{}{"weekends"}},
+		{"selector": map[string]interface{}{"matchExpressions": []interface{}{map[string]interface{}{"key": "app", "operator": "Sometimes"}}}},
+	} {
+		if p := ParseImageUpdatePolicy(imageUpdatePolicy("invalid", spec)); p.Err == nil {
+			t.Errorf("expected error for spec %v", spec)
+		}
+	}
+}
+
+func policyDeployment(name string, labels, annotations map[string]string) *apps_v1.Deployment {
+	return &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        name,
+			Namespace:   "xxxx",
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: core_v1.PodTemplateSpec{
+				Spec: core_v1.PodSpec{
+					Containers: []core_v1.Container{{Image: "karolisr/webhook-demo:0.0.15"}},
+				},
+			},
+		},
+	}
+}
+
+func TestImageUpdatePolicyDefaults(t *testing.T) {
+	tr := &Translator{FieldLogger: logrus.New()}
+
+	tr.OnAdd(&core_v1.Namespace{ObjectMeta: meta_v1.ObjectMeta{
+		Name:        "xxxx",
+		Annotations: map[string]string{"keel.sh/policy": "patch", "keel.sh/notify": "team"},
+	}})
+	tr.OnAdd(imageUpdatePolicy("api", map[string]interface{}{
+		"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "api"}},
+		"policy":   "minor",
+	}))
+	// invalid policies don't select workloads
+	tr.OnAdd(imageUpdatePolicy("all", map[string]interface{}{"policy": "sometimes"}))
+
+	tr.OnAdd(policyDeployment("api", map[string]string{"app": "api"}, nil))
+	tr.OnAdd(policyDeployment("api-pinned", map[string]string{"app": "api"}, map[string]string{"keel.sh/policy": "major"}))
+	tr.OnAdd(policyDeployment("worker", map[string]string{"app": "worker"}, nil))
+
+	policies := make(map[string]string)
+	for _, gr := range tr.Values() {
+		annotations := gr.GetAnnotations()
+		policies[gr.Name] = annotations["keel.sh/policy"]
+		if annotations["keel.sh/notify"] != "team" {
+			t.Errorf("expected namespace default to be kept for %s, got: %v", gr.Name, annotations)
+		}
+	}
+	if policies["api"] != "minor" || policies["api-pinned"] != "major" || policies["worker"] != "patch" {
+		t.Errorf("unexpected policies: %v", policies)
+	}
+
+	statuses := tr.ImageUpdatePolicyStatuses()
+	if len(statuses) != 2 {
+		t.Fatalf("unexpected statuses: %v", statuses)
+	}
+	if statuses[1].Policy.Name != "api" || strings.Join(statuses[1].MatchedWorkloads, ",") != "deployment/api,deployment/api-pinned" {
+		t.Errorf("unexpected matched workloads: %v", statuses[1].MatchedWorkloads)
+	}
+
+	tr.OnDelete(imageUpdatePolicy("api", nil))
+	for _, gr := range tr.Values() {
+		if gr.Name == "api" && gr.GetAnnotations()["keel.sh/policy"] != "patch" {
+			t.Errorf("expected namespace default after policy was deleted")
+		}
+	}
+}
+
+func TestPolicyStatus(t *testing.T) {
+	now := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	valid := ParseImageUpdatePolicy(imageUpdatePolicy("api", map[string]interface{}{"policy": "minor"}))
+
+	status := PolicyStatus(ImageUpdatePolicyStatus{Policy: valid, MatchedWorkloads: []string{"deployment/api"}}, nil, now)
+	condition := status["conditions"].([]interface{})[0].(map[string]interface{})
+	if condition["status"] != "True" || condition["message"] != "selects 1 workloads" || status["observedGeneration"] != int64(2) {
+		t.Errorf("unexpected status: %v", status)
+	}
+
+	// transition time is kept while the condition doesn't change
+	again := PolicyStatus(ImageUpdatePolicyStatus{Policy: valid}, status, now.Add(time.Hour))
+	if again["conditions"].([]interface{})[0].(map[string]interface{})["lastTransitionTime"] != "2018-10-01T12:00:00Z" {
+		t.Errorf("unexpected transition time: %v", again)
+	}
+
+	invalid := ParseImageUpdatePolicy(imageUpdatePolicy("api", map[string]interface{}{"policy": "sometimes"}))
+	failed := PolicyStatus(ImageUpdatePolicyStatus{Policy: invalid}, status, now.Add(time.Hour))
+	condition = failed["conditions"].([]interface{})[0].(map[string]interface{})
+	if condition["status"] != "False" || condition["reason"] != "Invalid" || condition["lastTransitionTime"] != "2018-10-01T13:00:00Z" {
+		t.Errorf("unexpected status: %v", failed)
+	}
+}
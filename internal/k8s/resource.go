@@ -8,6 +8,7 @@ import (
 	apps_v1 "k8s.io/api/apps/v1"
 	v1beta1 "k8s.io/api/batch/v1beta1"
 	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 // GenericResource - generic resource,
@@ -19,6 +20,9 @@ type GenericResource struct {
 	Identifier string
 	Namespace  string
 	Name       string
+
+	// defaults - namespace default annotations, they are never written to the resource
+	defaults map[string]string
 }
 
 type genericResource []*GenericResource
@@ -43,6 +47,11 @@ func NewGenericResource(obj interface{}) (*GenericResource, error) {
 		// ok
 	case *v1beta1.CronJob:
 		// ok
+	case *unstructured.Unstructured:
+		u := obj.(*unstructured.Unstructured)
+		if _, ok := UnstructuredResource(u); !ok {
+			return nil, fmt.Errorf("unsupported resource kind: %s", u.GetKind())
+		}
 	default:
 		return nil, fmt.Errorf("unsupported resource type: %v", reflect.TypeOf(obj).Kind())
 	}
@@ -71,6 +80,7 @@ func (r *GenericResource) DeepCopy() *GenericResource {
 	gr.Identifier = r.Identifier
 	gr.Namespace = r.Namespace
 	gr.Name = r.Name
+	gr.defaults = r.defaults
 
 	switch obj := r.obj.(type) {
 	case *apps_v1.Deployment:
@@ -81,6 +91,8 @@ func (r *GenericResource) DeepCopy() *GenericResource {
 		gr.obj = obj.DeepCopy()
 	case *v1beta1.CronJob:
 		gr.obj = obj.DeepCopy()
+	case *unstructured.Unstructured:
+		gr.obj = obj.DeepCopy()
 	}
 
 	return gr
@@ -97,6 +109,8 @@ func (r *GenericResource) GetIdentifier() string {
 		return getDaemonsetSetIdentifier(obj)
 	case *v1beta1.CronJob:
 		return getCronJobIdentifier(obj)
+	case *unstructured.Unstructured:
+		return getUnstructuredIdentifier(obj)
 	}
 	return ""
 }
@@ -112,6 +126,8 @@ func (r *GenericResource) GetName() string {
 		return obj.GetName()
 	case *v1beta1.CronJob:
 		return obj.GetName()
+	case *unstructured.Unstructured:
+		return obj.GetName()
 	}
 	return ""
 }
@@ -127,13 +143,15 @@ func (r *GenericResource) GetNamespace() string {
 		return obj.GetNamespace()
 	case *v1beta1.CronJob:
 		return obj.GetNamespace()
+	case *unstructured.Unstructured:
+		return obj.GetNamespace()
 	}
 	return ""
 }
 
 // Kind returns a type of resource that this structure represents
 func (r *GenericResource) Kind() string {
-	switch r.obj.(type) {
+	switch obj := r.obj.(type) {
 	case *apps_v1.Deployment:
 		return "deployment"
 	case *apps_v1.StatefulSet:
@@ -142,6 +160,8 @@ func (r *GenericResource) Kind() string {
 		return "daemonset"
 	case *v1beta1.CronJob:
 		return "cronjob"
+	case *unstructured.Unstructured:
+		return unstructuredKind(obj)
 	}
 	return ""
 }
@@ -162,6 +182,8 @@ func (r *GenericResource) GetLabels() (labels map[string]string) {
 		return getOrInitialise(obj.GetLabels())
 	case *v1beta1.CronJob:
 		return getOrInitialise(obj.GetLabels())
+	case *unstructured.Unstructured:
+		return getOrInitialise(obj.GetLabels())
 	}
 	return
 }
@@ -177,6 +199,8 @@ func (r *GenericResource) SetLabels(labels map[string]string) {
 		obj.SetLabels(labels)
 	case *v1beta1.CronJob:
 		obj.SetLabels(labels)
+	case *unstructured.Unstructured:
+		obj.SetLabels(labels)
 	}
 }
 
@@ -191,6 +215,8 @@ func (r *GenericResource) GetSpecAnnotations() (annotations map[string]string) {
 		return getOrInitialise(obj.Spec.Template.GetAnnotations())
 	case *v1beta1.CronJob:
 		return getOrInitialise(obj.Spec.JobTemplate.GetAnnotations())
+	case *unstructured.Unstructured:
+		return unstructuredSpecAnnotations(obj)
 	}
 	return
 }
@@ -206,6 +232,8 @@ func (r *GenericResource) SetSpecAnnotations(annotations map[string]string) {
 		obj.Spec.Template.SetAnnotations(annotations)
 	case *v1beta1.CronJob:
 		obj.Spec.JobTemplate.SetAnnotations(annotations)
+	case *unstructured.Unstructured:
+		setUnstructuredSpecAnnotations(obj, annotations)
 	}
 }
 
@@ -216,8 +244,33 @@ func getOrInitialise(a map[string]string) map[string]string {
 	return a
 }
 
-// GetAnnotations - get resource annotations
+// SetDefaultAnnotations - sets namespace defaults, GetAnnotations returns them
+// unless the resource has the same annotation or label
+func (r *GenericResource) SetDefaultAnnotations(defaults map[string]string) {
+	r.defaults = defaults
+}
+
+// GetAnnotations - get resource annotations, including namespace defaults
 func (r *GenericResource) GetAnnotations() (annotations map[string]string) {
+	annotations = r.objectAnnotations()
+	if len(r.defaults) == 0 {
+		return annotations
+	}
+
+	labels := r.GetLabels()
+	merged := make(map[string]string, len(annotations)+len(r.defaults))
+	for k, v := range r.defaults {
+		if _, ok := labels[k]; !ok {
+			merged[k] = v
+		}
+	}
+	for k, v := range annotations {
+		merged[k] = v
+	}
+	return merged
+}
+
+func (r *GenericResource) objectAnnotations() (annotations map[string]string) {
 	switch obj := r.obj.(type) {
 	case *apps_v1.Deployment:
 		return getOrInitialise(obj.GetAnnotations())
@@ -227,12 +280,17 @@ func (r *GenericResource) GetAnnotations() (annotations map[string]string) {
 		return getOrInitialise(obj.GetAnnotations())
 	case *v1beta1.CronJob:
 		return getOrInitialise(obj.GetAnnotations())
+	case *unstructured.Unstructured:
+		return getOrInitialise(obj.GetAnnotations())
 	}
 	return
 }
 
 // SetAnnotations - set resource annotations
 func (r *GenericResource) SetAnnotations(annotations map[string]string) {
+	if len(r.defaults) > 0 {
+		annotations = r.withoutDefaults(annotations)
+	}
 	switch obj := r.obj.(type) {
 	case *apps_v1.Deployment:
 		obj.SetAnnotations(annotations)
@@ -242,7 +300,24 @@ func (r *GenericResource) SetAnnotations(annotations map[string]string) {
 		obj.SetAnnotations(annotations)
 	case *v1beta1.CronJob:
 		obj.SetAnnotations(annotations)
+	case *unstructured.Unstructured:
+		obj.SetAnnotations(annotations)
+	}
+}
+
+// withoutDefaults - removes unchanged namespace defaults from the annotations
+func (r *GenericResource) withoutDefaults(annotations map[string]string) map[string]string {
+	own := r.objectAnnotations()
+	result := make(map[string]string, len(annotations))
+	for k, v := range annotations {
+		if d, ok := r.defaults[k]; ok && d == v {
+			if _, ok := own[k]; !ok {
+				continue
+			}
+		}
+		result[k] = v
 	}
+	return result
 }
 
 // GetImagePullSecrets - returns secrets from pod spec
@@ -256,10 +331,23 @@ func (r *GenericResource) GetImagePullSecrets() (secrets []string) {
 		return getImagePullSecrets(obj.Spec.Template.Spec.ImagePullSecrets)
 	case *v1beta1.CronJob:
 		return getImagePullSecrets(obj.Spec.JobTemplate.Spec.Template.Spec.ImagePullSecrets)
+	case *unstructured.Unstructured:
+		return unstructuredImagePullSecrets(obj)
 	}
 	return
 }
 
+// GetNodeSelector - returns node selector of the pod spec
+func (r *GenericResource) GetNodeSelector() map[string]string {
+	if spec := r.podSpec(); spec != nil {
+		return spec.NodeSelector
+	}
+	if obj, ok := r.obj.(*unstructured.Unstructured); ok {
+		return unstructuredNodeSelector(obj)
+	}
+	return nil
+}
+
 // GetImages - returns images used by this resource
 func (r *GenericResource) GetImages() (images []string) {
 	switch obj := r.obj.(type) {
@@ -271,6 +359,8 @@ func (r *GenericResource) GetImages() (images []string) {
 		return getContainerImages(obj.Spec.Template.Spec.Containers)
 	case *v1beta1.CronJob:
 		return getContainerImages(obj.Spec.JobTemplate.Spec.Template.Spec.Containers)
+	case *unstructured.Unstructured:
+		return getContainerImages(unstructuredContainers(obj))
 	}
 	return
 }
@@ -286,10 +376,82 @@ func (r *GenericResource) Containers() (containers []core_v1.Container) {
 		return obj.Spec.Template.Spec.Containers
 	case *v1beta1.CronJob:
 		return obj.Spec.JobTemplate.Spec.Template.Spec.Containers
+	case *unstructured.Unstructured:
+		return unstructuredContainers(obj)
 	}
 	return
 }
 
+// InitContainers - returns init containers of the resource pod template
+func (r *GenericResource) InitContainers() (containers []core_v1.Container) {
+	if obj, ok := r.obj.(*unstructured.Unstructured); ok {
+		return unstructuredInitContainers(obj)
+	}
+	if spec := r.podSpec(); spec != nil {
+		return spec.InitContainers
+	}
+	return
+}
+
+// UpdateInitContainer - updates init container image
+func (r *GenericResource) UpdateInitContainer(index int, image string) {
+	if obj, ok := r.obj.(*unstructured.Unstructured); ok {
+		updateUnstructuredInitContainer(obj, index, image)
+		return
+	}
+	if spec := r.podSpec(); spec != nil && index < len(spec.InitContainers) {
+		spec.InitContainers[index].Image = image
+	}
+}
+
+// UpdateContainerEnv - sets value of the container env var, containers without
+// the env var are left as they are
+func (r *GenericResource) UpdateContainerEnv(index int, name, value string) {
+	if obj, ok := r.obj.(*unstructured.Unstructured); ok {
+		updateUnstructuredContainerEnv(obj, "containers", index, name, value)
+		return
+	}
+	if spec := r.podSpec(); spec != nil && index < len(spec.Containers) {
+		setEnv(spec.Containers[index].Env, name, value)
+	}
+}
+
+// UpdateInitContainerEnv - sets value of the init container env var
+func (r *GenericResource) UpdateInitContainerEnv(index int, name, value string) {
+	if obj, ok := r.obj.(*unstructured.Unstructured); ok {
+		updateUnstructuredContainerEnv(obj, "initContainers", index, name, value)
+		return
+	}
+	if spec := r.podSpec(); spec != nil && index < len(spec.InitContainers) {
+		setEnv(spec.InitContainers[index].Env, name, value)
+	}
+}
+
+// setEnv - only env vars with values are set, ones referencing secrets or
+// config maps (valueFrom) aren't changed
+func setEnv(env []core_v1.EnvVar, name, value string) {
+	for i := range env {
+		if env[i].Name == name && env[i].ValueFrom == nil {
+			env[i].Value = value
+		}
+	}
+}
+
+// podSpec - pod template spec of typed resources
+func (r *GenericResource) podSpec() *core_v1.PodSpec {
+	switch obj := r.obj.(type) {
+	case *apps_v1.Deployment:
+		return &obj.Spec.Template.Spec
+	case *apps_v1.StatefulSet:
+		return &obj.Spec.Template.Spec
+	case *apps_v1.DaemonSet:
+		return &obj.Spec.Template.Spec
+	case *v1beta1.CronJob:
+		return &obj.Spec.JobTemplate.Spec.Template.Spec
+	}
+	return nil
+}
+
 // UpdateContainer - updates container image
 func (r *GenericResource) UpdateContainer(index int, image string) {
 	switch obj := r.obj.(type) {
@@ -301,6 +463,8 @@ func (r *GenericResource) UpdateContainer(index int, image string) {
 		updateDaemonsetSetContainer(obj, index, image)
 	case *v1beta1.CronJob:
 		updateCronJobContainer(obj, index, image)
+	case *unstructured.Unstructured:
+		updateUnstructuredContainer(obj, index, image)
 	}
 }
 
@@ -362,6 +526,8 @@ func (r *GenericResource) GetStatus() Status {
 			AvailableReplicas:   0,
 			UnavailableReplicas: 0,
 		}
+	case *unstructured.Unstructured:
+		return unstructuredStatus(obj)
 	}
 	return Status{}
 }
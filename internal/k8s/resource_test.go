@@ -185,3 +185,82 @@ func TestDaemonsetlSetMultipleContainers(t *testing.T) {
 		t.Errorf("unexpected image: %s", updated.Spec.Template.Spec.Containers[0].Image)
 	}
 }
+
+func TestInitContainers(t *testing.T) {
+	d := &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "dep-1",
+			Namespace: "xxxx",
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: core_v1.PodTemplateSpec{
+				Spec: core_v1.PodSpec{
+					InitContainers: []core_v1.Container{
+						{Image: "gcr.io/v2-namespace/migrations:1.1.1"},
+					},
+					Containers: []core_v1.Container{
+						{Image: "gcr.io/v2-namespace/hello-world:1.1.1"},
+					},
+				},
+			},
+		},
+	}
+
+	gr, err := NewGenericResource(d)
+	if err != nil {
+		t.Fatalf("failed to create generic resource: %s", err)
+	}
+
+	copied := gr.DeepCopy()
+	copied.UpdateInitContainer(0, "gcr.io/v2-namespace/migrations:1.1.2")
+
+	if img := copied.InitContainers()[0].Image; img != "gcr.io/v2-namespace/migrations:1.1.2" {
+		t.Errorf("unexpected init container image: %s", img)
+	}
+	if img := gr.InitContainers()[0].Image; img != "gcr.io/v2-namespace/migrations:1.1.1" {
+		t.Errorf("original resource was modified: %s", img)
+	}
+	if img := copied.Containers()[0].Image; img != "gcr.io/v2-namespace/hello-world:1.1.1" {
+		t.Errorf("unexpected container image: %s", img)
+	}
+}
+
+func TestUpdateContainerEnv(t *testing.T) {
+	d := &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "dep-1",
+			Namespace: "xxxx",
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: core_v1.PodTemplateSpec{
+				Spec: core_v1.PodSpec{
+					Containers: []core_v1.Container{
+						{
+							Image: "gcr.io/v2-namespace/hello-world:1.1.1",
+							Env: []core_v1.EnvVar{
+								{Name: "JOB_IMAGE", Value: "gcr.io/v2-namespace/job:1.1.1"},
+								{Name: "SECRET_IMAGE", ValueFrom: &core_v1.EnvVarSource{}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	gr, err := NewGenericResource(d)
+	if err != nil {
+		t.Fatalf("failed to create generic resource: %s", err)
+	}
+
+	gr.UpdateContainerEnv(0, "JOB_IMAGE", "gcr.io/v2-namespace/job:1.1.2")
+	gr.UpdateContainerEnv(0, "SECRET_IMAGE", "gcr.io/v2-namespace/job:1.1.2")
+
+	env := gr.Containers()[0].Env
+	if env[0].Value != "gcr.io/v2-namespace/job:1.1.2" {
+		t.Errorf("unexpected env value: %s", env[0].Value)
+	}
+	if env[1].Value != "" {
+		t.Errorf("env var from a reference shouldn't be set: %s", env[1].Value)
+	}
+}
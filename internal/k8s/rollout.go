@@ -0,0 +1,130 @@
+package k8s
+
+import (
+	"fmt"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// RolloutStatus - rollout progress of an updated resource, mirrors
+// kubectl rollout status
+type RolloutStatus struct {
+	// Done - all pods are updated (or will only be updated when recreated for
+	// OnDelete strategies and CronJobs)
+	Done bool
+	// Failed - rollout can't progress, ie: deployment progress deadline exceeded
+	Failed bool
+	// Stopped - rollout wasn't tracked till the end as keel is stopping
+	Stopped bool
+	// Message - human readable status
+	Message string
+	// Progress - progressive delivery step, ie: "2/5 canary steps completed", only set for
+	// resources that roll out in steps
+	Progress string
+}
+
+// GetGeneration - resource generation, it's increased on every spec change
+func (r *GenericResource) GetGeneration() int64 {
+	switch obj := r.obj.(type) {
+	case *apps_v1.Deployment:
+		return obj.GetGeneration()
+	case *apps_v1.StatefulSet:
+		return obj.GetGeneration()
+	case *apps_v1.DaemonSet:
+		return obj.GetGeneration()
+	case *v1beta1.CronJob:
+		return obj.GetGeneration()
+	case *unstructured.Unstructured:
+		return obj.GetGeneration()
+	}
+	return 0
+}
+
+// RolloutStatus - returns rollout status of the resource
+func (r *GenericResource) RolloutStatus() RolloutStatus {
+	switch obj := r.obj.(type) {
+	case *apps_v1.Deployment:
+		return deploymentRolloutStatus(obj)
+	case *apps_v1.StatefulSet:
+		return statefulSetRolloutStatus(obj)
+	case *apps_v1.DaemonSet:
+		return daemonSetRolloutStatus(obj)
+	case *v1beta1.CronJob:
+		// there's nothing to roll out, next scheduled job uses the new images
+		return RolloutStatus{Done: true, Message: "next scheduled job will use updated images"}
+	case *unstructured.Unstructured:
+		return unstructuredRolloutStatus(obj)
+	}
+	return RolloutStatus{Failed: true, Message: "unsupported resource type"}
+}
+
+func replicas(r *int32) int32 {
+	if r == nil {
+		return 1
+	}
+	return *r
+}
+
+func deploymentRolloutStatus(d *apps_v1.Deployment) RolloutStatus {
+	if d.Generation > d.Status.ObservedGeneration {
+		return RolloutStatus{Message: "waiting for deployment spec update to be observed"}
+	}
+	for _, c := range d.Status.Conditions {
+		if c.Type == apps_v1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return RolloutStatus{Failed: true, Message: fmt.Sprintf("deployment exceeded its progress deadline: %s", c.Message)}
+		}
+	}
+	desired := replicas(d.Spec.Replicas)
+	if d.Status.UpdatedReplicas < desired {
+		return RolloutStatus{Message: fmt.Sprintf("%d out of %d new replicas have been updated", d.Status.UpdatedReplicas, desired)}
+	}
+	if d.Status.Replicas > d.Status.UpdatedReplicas {
+		return RolloutStatus{Message: fmt.Sprintf("%d old replicas are pending termination", d.Status.Replicas-d.Status.UpdatedReplicas)}
+	}
+	if d.Status.AvailableReplicas < d.Status.UpdatedReplicas {
+		return RolloutStatus{Message: fmt.Sprintf("%d of %d updated replicas are available", d.Status.AvailableReplicas, d.Status.UpdatedReplicas)}
+	}
+	return RolloutStatus{Done: true, Message: "successfully rolled out"}
+}
+
+func statefulSetRolloutStatus(s *apps_v1.StatefulSet) RolloutStatus {
+	if s.Spec.UpdateStrategy.Type == apps_v1.OnDeleteStatefulSetStrategyType {
+		return RolloutStatus{Done: true, Message: "OnDelete update strategy, pods are updated when they are deleted"}
+	}
+	if s.Status.ObservedGeneration == 0 || s.Generation > s.Status.ObservedGeneration {
+		return RolloutStatus{Message: "waiting for statefulset spec update to be observed"}
+	}
+	desired := replicas(s.Spec.Replicas)
+	if s.Status.ReadyReplicas < desired {
+		return RolloutStatus{Message: fmt.Sprintf("%d of %d pods are ready", s.Status.ReadyReplicas, desired)}
+	}
+	if ru := s.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.Partition != nil && *ru.Partition > 0 {
+		// only pods with ordinal >= partition are updated
+		if s.Status.UpdatedReplicas < desired-*ru.Partition {
+			return RolloutStatus{Message: fmt.Sprintf("%d of %d pods above partition %d are updated", s.Status.UpdatedReplicas, desired-*ru.Partition, *ru.Partition)}
+		}
+		return RolloutStatus{Done: true, Message: fmt.Sprintf("partitioned roll out complete: %d new pods have been updated", s.Status.UpdatedReplicas)}
+	}
+	if s.Status.UpdateRevision != s.Status.CurrentRevision {
+		return RolloutStatus{Message: fmt.Sprintf("%d of %d pods are updated to revision %s", s.Status.UpdatedReplicas, desired, s.Status.UpdateRevision)}
+	}
+	return RolloutStatus{Done: true, Message: fmt.Sprintf("successfully rolled out %d pods at revision %s", s.Status.CurrentReplicas, s.Status.CurrentRevision)}
+}
+
+func daemonSetRolloutStatus(d *apps_v1.DaemonSet) RolloutStatus {
+	if d.Spec.UpdateStrategy.Type == apps_v1.OnDeleteDaemonSetStrategyType {
+		return RolloutStatus{Done: true, Message: "OnDelete update strategy, pods are updated when they are deleted"}
+	}
+	if d.Generation > d.Status.ObservedGeneration {
+		return RolloutStatus{Message: "waiting for daemonset spec update to be observed"}
+	}
+	if d.Status.UpdatedNumberScheduled < d.Status.DesiredNumberScheduled {
+		return RolloutStatus{Message: fmt.Sprintf("%d out of %d new pods have been updated", d.Status.UpdatedNumberScheduled, d.Status.DesiredNumberScheduled)}
+	}
+	if d.Status.NumberAvailable < d.Status.DesiredNumberScheduled {
+		return RolloutStatus{Message: fmt.Sprintf("%d of %d updated pods are available", d.Status.NumberAvailable, d.Status.DesiredNumberScheduled)}
+	}
+	return RolloutStatus{Done: true, Message: "successfully rolled out"}
+}
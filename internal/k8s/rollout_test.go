@@ -0,0 +1,133 @@
+package k8s
+
+import (
+	"testing"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1beta1 "k8s.io/api/batch/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func mustGenericResource(t *testing.T, obj interface{}) *GenericResource {
+	gr, err := NewGenericResource(obj)
+	if err != nil {
+		t.Fatalf("failed to create generic resource: %s", err)
+	}
+	return gr
+}
+
+func TestDeploymentRolloutStatus(t *testing.T) {
+	d := &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "dep", Namespace: "default", Generation: 2},
+		Spec:       apps_v1.DeploymentSpec{Replicas: int32Ptr(3)},
+		Status: apps_v1.DeploymentStatus{
+			ObservedGeneration: 1,
+		},
+	}
+	gr := mustGenericResource(t, d)
+
+	if status := gr.RolloutStatus(); status.Done || status.Failed {
+		t.Errorf("expected rollout in progress before spec is observed: %+v", status)
+	}
+
+	d.Status = apps_v1.DeploymentStatus{ObservedGeneration: 2, Replicas: 4, UpdatedReplicas: 2, AvailableReplicas: 3}
+	if status := gr.RolloutStatus(); status.Done || status.Failed {
+		t.Errorf("expected rollout in progress: %+v", status)
+	}
+
+	d.Status = apps_v1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3}
+	if status := gr.RolloutStatus(); !status.Done {
+		t.Errorf("expected rollout to be done: %+v", status)
+	}
+
+	d.Status = apps_v1.DeploymentStatus{
+		ObservedGeneration: 2,
+		Replicas:           3,
+		UpdatedReplicas:    1,
+		Conditions: []apps_v1.DeploymentCondition{
+			{Type: apps_v1.DeploymentProgressing, Reason: "ProgressDeadlineExceeded"},
+		},
+	}
+	if status := gr.RolloutStatus(); !status.Failed {
+		t.Errorf("expected rollout to fail: %+v", status)
+	}
+}
+
+func TestStatefulSetRolloutStatus(t *testing.T) {
+	s := &apps_v1.StatefulSet{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "sts", Namespace: "default", Generation: 2},
+		Spec: apps_v1.StatefulSetSpec{
+			Replicas: int32Ptr(4),
+			UpdateStrategy: apps_v1.StatefulSetUpdateStrategy{
+				Type: apps_v1.RollingUpdateStatefulSetStrategyType,
+			},
+		},
+		Status: apps_v1.StatefulSetStatus{
+			ObservedGeneration: 2,
+			ReadyReplicas:      4,
+			UpdatedReplicas:    2,
+			CurrentRevision:    "sts-1",
+			UpdateRevision:     "sts-2",
+		},
+	}
+	gr := mustGenericResource(t, s)
+
+	if status := gr.RolloutStatus(); status.Done || status.Failed {
+		t.Errorf("expected rollout in progress: %+v", status)
+	}
+
+	// partition 2, only pods 2 and 3 are updated
+	s.Spec.UpdateStrategy.RollingUpdate = &apps_v1.RollingUpdateStatefulSetStrategy{Partition: int32Ptr(2)}
+	if status := gr.RolloutStatus(); !status.Done {
+		t.Errorf("expected partitioned rollout to be done: %+v", status)
+	}
+
+	s.Spec.UpdateStrategy.RollingUpdate = nil
+	s.Status.UpdatedReplicas = 4
+	s.Status.CurrentRevision = "sts-2"
+	if status := gr.RolloutStatus(); !status.Done {
+		t.Errorf("expected rollout to be done: %+v", status)
+	}
+
+	s.Spec.UpdateStrategy.Type = apps_v1.OnDeleteStatefulSetStrategyType
+	s.Status = apps_v1.StatefulSetStatus{}
+	if status := gr.RolloutStatus(); !status.Done {
+		t.Errorf("expected OnDelete rollout to be done: %+v", status)
+	}
+}
+
+func TestDaemonSetRolloutStatus(t *testing.T) {
+	d := &apps_v1.DaemonSet{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "ds", Namespace: "default", Generation: 3},
+		Status: apps_v1.DaemonSetStatus{
+			ObservedGeneration:     3,
+			DesiredNumberScheduled: 5,
+			UpdatedNumberScheduled: 5,
+			NumberAvailable:        4,
+		},
+	}
+	gr := mustGenericResource(t, d)
+
+	if status := gr.RolloutStatus(); status.Done || status.Failed {
+		t.Errorf("expected rollout in progress: %+v", status)
+	}
+
+	d.Status.NumberAvailable = 5
+	if status := gr.RolloutStatus(); !status.Done {
+		t.Errorf("expected rollout to be done: %+v", status)
+	}
+}
+
+func TestCronJobRolloutStatus(t *testing.T) {
+	gr := mustGenericResource(t, &v1beta1.CronJob{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "cron", Namespace: "default", Generation: 1},
+	})
+	if status := gr.RolloutStatus(); !status.Done {
+		t.Errorf("expected cronjob rollout to be done: %+v", status)
+	}
+	if gr.GetGeneration() != 1 {
+		t.Errorf("unexpected generation: %d", gr.GetGeneration())
+	}
+}
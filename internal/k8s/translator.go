@@ -1,9 +1,18 @@
 package k8s
 
 import (
+	"strings"
+
 	"github.com/sirupsen/logrus"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
+// namespaceDefaultsPrefix - keel annotations of namespaces are defaults for
+// their workloads, ie: keel.sh/policy, keel.sh/trigger, keel.sh/approvals
+const namespaceDefaultsPrefix = "keel.sh/"
+
 type Translator struct {
 	logrus.FieldLogger
 
@@ -13,6 +22,14 @@ type Translator struct {
 }
 
 func (t *Translator) OnAdd(obj interface{}) {
+	if u, ok := isImageUpdatePolicy(obj); ok {
+		t.setImageUpdatePolicy(u)
+		return
+	}
+	if ns, ok := obj.(*v1.Namespace); ok {
+		t.setNamespaceDefaults(ns)
+		return
+	}
 	gr, err := NewGenericResource(obj)
 	if err != nil {
 		t.Errorf("OnAdd failed to add resource %T: %#v", obj, obj)
@@ -23,6 +40,14 @@ func (t *Translator) OnAdd(obj interface{}) {
 }
 
 func (t *Translator) OnUpdate(oldObj, newObj interface{}) {
+	if u, ok := isImageUpdatePolicy(newObj); ok {
+		t.setImageUpdatePolicy(u)
+		return
+	}
+	if ns, ok := newObj.(*v1.Namespace); ok {
+		t.setNamespaceDefaults(ns)
+		return
+	}
 	gr, err := NewGenericResource(newObj)
 	if err != nil {
 		t.Errorf("OnUpdate failed to update resource %T: %#v", newObj, newObj)
@@ -33,6 +58,14 @@ func (t *Translator) OnUpdate(oldObj, newObj interface{}) {
 }
 
 func (t *Translator) OnDelete(obj interface{}) {
+	if u, ok := isImageUpdatePolicy(obj); ok {
+		t.GenericResourceCache.RemoveImageUpdatePolicy(u.GetNamespace(), u.GetName())
+		return
+	}
+	if ns, ok := obj.(*v1.Namespace); ok {
+		t.GenericResourceCache.SetNamespaceDefaults(ns.Name, nil)
+		return
+	}
 	gr, err := NewGenericResource(obj)
 	if err != nil {
 		t.Errorf("OnDelete failed to delete resource %T: %#v", obj, obj)
@@ -41,3 +74,22 @@ func (t *Translator) OnDelete(obj interface{}) {
 	t.Debugf("deleted %s %s", gr.Kind(), gr.Name)
 	t.GenericResourceCache.Remove(gr.GetIdentifier())
 }
+
+func (t *Translator) setNamespaceDefaults(ns *v1.Namespace) {
+	defaults := make(map[string]string)
+	for k, v := range ns.GetAnnotations() {
+		if strings.HasPrefix(k, namespaceDefaultsPrefix) {
+			defaults[k] = v
+		}
+	}
+	t.Debugf("namespace %s defaults: %v", ns.Name, defaults)
+	t.GenericResourceCache.SetNamespaceDefaults(ns.Name, defaults)
+}
+
+func (t *Translator) setImageUpdatePolicy(u *unstructured.Unstructured) {
+	p := ParseImageUpdatePolicy(u)
+	if p.Err != nil {
+		t.Errorf("invalid image update policy %s/%s: %s", p.Namespace, p.Name, p.Err)
+	}
+	t.GenericResourceCache.SetImageUpdatePolicy(p)
+}
@@ -0,0 +1,208 @@
+package k8s
+
+import (
+	"strings"
+
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// custom resources are handled as unstructured objects, all supported kinds
+// keep the pod template in the same place as deployments (spec.template)
+
+// UnstructuredResource - returns API resource of supported unstructured objects
+func UnstructuredResource(obj *unstructured.Unstructured) (schema.GroupVersionResource, bool) {
+	switch {
+	case isArgoRollout(obj):
+		return ArgoRolloutsResource, true
+	case isKnativeService(obj):
+		return KnativeServiceResource, true
+	}
+	return customResource(obj)
+}
+
+func getUnstructuredIdentifier(obj *unstructured.Unstructured) string {
+	return unstructuredKind(obj) + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+func unstructuredKind(obj *unstructured.Unstructured) string {
+	switch {
+	case isArgoRollout(obj):
+		return "rollout"
+	case isKnativeService(obj):
+		return "ksvc"
+	case isCustomResource(obj):
+		return strings.ToLower(obj.GetKind())
+	}
+	return ""
+}
+
+func unstructuredRolloutStatus(obj *unstructured.Unstructured) RolloutStatus {
+	switch {
+	case isArgoRollout(obj):
+		return argoRolloutStatus(obj)
+	case isKnativeService(obj):
+		return knativeServiceStatus(obj)
+	case isCustomResource(obj):
+		return customResourceStatus(obj)
+	}
+	return RolloutStatus{Failed: true, Message: "unsupported resource type"}
+}
+
+// podTemplatePath - fields of the pod template
+var podTemplatePath = []string{"spec", "template"}
+
+// hasPodTemplate - custom resources (other than supported kinds) only have images
+// listed in keel.sh/image-paths annotation
+func hasPodTemplate(obj *unstructured.Unstructured) bool {
+	return isArgoRollout(obj) || isKnativeService(obj)
+}
+
+func fieldPath(path []string, fields ...string) []string {
+	return append(append([]string{}, path...), fields...)
+}
+
+func unstructuredContainers(obj *unstructured.Unstructured) []core_v1.Container {
+	if !hasPodTemplate(obj) {
+		return customContainers(obj)
+	}
+	return unstructuredContainerList(obj, "containers")
+}
+
+func unstructuredInitContainers(obj *unstructured.Unstructured) []core_v1.Container {
+	return unstructuredContainerList(obj, "initContainers")
+}
+
+// unstructuredContainerList - containers from pod template spec field (containers or initContainers)
+func unstructuredContainerList(obj *unstructured.Unstructured, field string) []core_v1.Container {
+	if !hasPodTemplate(obj) {
+		return nil
+	}
+	items, _, _ := unstructured.NestedSlice(obj.Object, fieldPath(podTemplatePath, "spec", field)...)
+	containers := make([]core_v1.Container, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var c core_v1.Container
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &c); err != nil {
+			continue
+		}
+		containers = append(containers, c)
+	}
+	return containers
+}
+
+func updateUnstructuredContainer(obj *unstructured.Unstructured, index int, image string) {
+	if !hasPodTemplate(obj) {
+		updateCustomContainer(obj, index, image)
+		return
+	}
+	updateUnstructuredContainerList(obj, "containers", index, image)
+}
+
+func updateUnstructuredInitContainer(obj *unstructured.Unstructured, index int, image string) {
+	updateUnstructuredContainerList(obj, "initContainers", index, image)
+}
+
+func updateUnstructuredContainerList(obj *unstructured.Unstructured, field string, index int, image string) {
+	if !hasPodTemplate(obj) {
+		return
+	}
+	path := fieldPath(podTemplatePath, "spec", field)
+	items, found, err := unstructured.NestedSlice(obj.Object, path...)
+	if err != nil || !found || index >= len(items) {
+		return
+	}
+	m, ok := items[index].(map[string]interface{})
+	if !ok {
+		return
+	}
+	m["image"] = image
+	unstructured.SetNestedSlice(obj.Object, items, path...)
+}
+
+// updateUnstructuredContainerEnv - sets env var of pod template container, custom
+// resources without pod template don't have env vars
+func updateUnstructuredContainerEnv(obj *unstructured.Unstructured, field string, index int, name, value string) {
+	if !hasPodTemplate(obj) {
+		return
+	}
+	path := fieldPath(podTemplatePath, "spec", field)
+	items, found, err := unstructured.NestedSlice(obj.Object, path...)
+	if err != nil || !found || index >= len(items) {
+		return
+	}
+	m, ok := items[index].(map[string]interface{})
+	if !ok {
+		return
+	}
+	env, ok := m["env"].([]interface{})
+	if !ok {
+		return
+	}
+	for _, item := range env {
+		e, ok := item.(map[string]interface{})
+		if !ok || e["name"] != name || e["valueFrom"] != nil {
+			continue
+		}
+		e["value"] = value
+	}
+	unstructured.SetNestedSlice(obj.Object, items, path...)
+}
+
+func unstructuredImagePullSecrets(obj *unstructured.Unstructured) []string {
+	items, _, _ := unstructured.NestedSlice(obj.Object, fieldPath(podTemplatePath, "spec", "imagePullSecrets")...)
+	var secrets []string
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			if name, ok := m["name"].(string); ok {
+				secrets = append(secrets, name)
+			}
+		}
+	}
+	return secrets
+}
+
+func unstructuredNodeSelector(obj *unstructured.Unstructured) map[string]string {
+	selector, _, _ := unstructured.NestedStringMap(obj.Object, fieldPath(podTemplatePath, "spec", "nodeSelector")...)
+	return selector
+}
+
+func unstructuredSpecAnnotations(obj *unstructured.Unstructured) map[string]string {
+	annotations, _, _ := unstructured.NestedStringMap(obj.Object, fieldPath(podTemplatePath, "metadata", "annotations")...)
+	return getOrInitialise(annotations)
+}
+
+func setUnstructuredSpecAnnotations(obj *unstructured.Unstructured, annotations map[string]string) {
+	// pod template fields can't be added to custom resources
+	if !hasPodTemplate(obj) {
+		return
+	}
+	unstructured.SetNestedStringMap(obj.Object, annotations, fieldPath(podTemplatePath, "metadata", "annotations")...)
+}
+
+func nestedInt32(obj *unstructured.Unstructured, fields ...string) int32 {
+	v, _, _ := unstructured.NestedInt64(obj.Object, fields...)
+	return int32(v)
+}
+
+func unstructuredStatus(obj *unstructured.Unstructured) Status {
+	return Status{
+		Replicas:            nestedInt32(obj, "status", "replicas"),
+		UpdatedReplicas:     nestedInt32(obj, "status", "updatedReplicas"),
+		ReadyReplicas:       nestedInt32(obj, "status", "readyReplicas"),
+		AvailableReplicas:   nestedInt32(obj, "status", "availableReplicas"),
+		UnavailableReplicas: nestedInt32(obj, "status", "replicas") - nestedInt32(obj, "status", "availableReplicas"),
+	}
+}
+
+func withDetails(status, details string) string {
+	if details == "" {
+		return status
+	}
+	return status + ": " + details
+}
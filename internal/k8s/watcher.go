@@ -10,34 +10,149 @@ import (
 	v1beta1 "k8s.io/api/batch/v1beta1"
 	"k8s.io/api/core/v1"
 
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8s_watch "k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 )
 
+// workloadSelector - label selector of watched workloads, empty watches all
+var workloadSelector string
+
+var (
+	// watchNamespaces - namespaces of namespaced resources, empty watches all
+	watchNamespaces []string
+	// accessChecker - optional, watches keel isn't allowed to use are skipped
+	accessChecker *AccessChecker
+)
+
+// SetWatchNamespaces - watches namespaced resources (workloads, secrets,
+// policies) in the namespaces instead of cluster-wide, keel only needs roles
+// in them. Namespaces are watched only if keel is allowed to.
+func SetWatchNamespaces(namespaces []string) {
+	watchNamespaces = namespaces
+}
+
+// SetAccessChecker - watches are only started where keel has permissions
+func SetAccessChecker(c *AccessChecker) {
+	accessChecker = c
+}
+
+// namespaces - namespaces the resource is watched in
+func namespaces(resource string) []string {
+	all := watchNamespaces
+	if len(all) == 0 {
+		all = []string{v1.NamespaceAll}
+	}
+	var allowed []string
+	for _, namespace := range all {
+		if accessChecker != nil && !accessChecker.CanWatch(namespace, resource) {
+			logrus.WithFields(logrus.Fields{
+				"resource":  resource,
+				"namespace": namespace,
+			}).Warn("k8s: not allowed to watch resource, skipping")
+			continue
+		}
+		allowed = append(allowed, namespace)
+	}
+	return allowed
+}
+
+// SetWorkloadSelector - limits workload watches to objects matching the label
+// selector (ie: keel.sh/policy), so the API server only sends tracked workloads.
+// Namespaces, secrets and policies are always watched.
+func SetWorkloadSelector(selector string) {
+	workloadSelector = selector
+}
+
 // WatchDeployments creates a SharedInformer for apps/v1.Deployments and registers it with g.
 func WatchDeployments(g *workgroup.Group, client *kubernetes.Clientset, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
-	watch(g, client.AppsV1().RESTClient(), log, "deployments", new(apps_v1.Deployment), rs...)
+	watch(g, client.AppsV1().RESTClient(), log, "deployments", workloadSelector, new(apps_v1.Deployment), rs...)
+}
+
+// WatchNamespaces creates a SharedInformer for v1.Namespaces and registers it with g,
+// namespace annotations are defaults for workloads in the namespace.
+func WatchNamespaces(g *workgroup.Group, client *kubernetes.Clientset, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
+	if accessChecker != nil && !accessChecker.CanWatch(v1.NamespaceAll, "namespaces") {
+		log.Warn("k8s: not allowed to watch namespaces, namespace annotations aren't used as workload defaults")
+		return
+	}
+	lw := cache.NewListWatchFromClient(client.CoreV1().RESTClient(), "namespaces", v1.NamespaceAll, fields.Everything())
+	inform(g, lw, log, "namespaces", new(v1.Namespace), rs...)
+}
+
+// WatchSecrets creates a SharedInformer for v1.Secrets and registers it with g,
+// image pull secrets are reloaded once they are rotated.
+func WatchSecrets(g *workgroup.Group, client *kubernetes.Clientset, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
+	watch(g, client.CoreV1().RESTClient(), log, "secrets", "", new(v1.Secret), rs...)
 }
 
 // WatchStatefulSets creates a SharedInformer for apps/v1.StatefulSet and registers it with g.
 func WatchStatefulSets(g *workgroup.Group, client *kubernetes.Clientset, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
-	watch(g, client.AppsV1().RESTClient(), log, "statefulsets", new(apps_v1.StatefulSet), rs...)
+	watch(g, client.AppsV1().RESTClient(), log, "statefulsets", workloadSelector, new(apps_v1.StatefulSet), rs...)
 }
 
 // WatchDaemonSets creates a SharedInformer for apps/v1.DaemonSet and registers it with g.
 func WatchDaemonSets(g *workgroup.Group, client *kubernetes.Clientset, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
-	watch(g, client.AppsV1().RESTClient(), log, "daemonsets", new(apps_v1.DaemonSet), rs...)
+	watch(g, client.AppsV1().RESTClient(), log, "daemonsets", workloadSelector, new(apps_v1.DaemonSet), rs...)
 }
 
 // WatchCronJobs creates a SharedInformer for v1beta1.CronJob and registers it with g.
 func WatchCronJobs(g *workgroup.Group, client *kubernetes.Clientset, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
-	watch(g, client.BatchV1beta1().RESTClient(), log, "cronjobs", new(v1beta1.CronJob), rs...)
+	watch(g, client.BatchV1beta1().RESTClient(), log, "cronjobs", workloadSelector, new(v1beta1.CronJob), rs...)
+}
+
+// WatchArgoRollouts creates a SharedInformer for argoproj.io Rollouts and registers it with g.
+// Rollouts are custom resources so they are watched through the dynamic client.
+func WatchArgoRollouts(g *workgroup.Group, client dynamic.Interface, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
+	watchDynamic(g, client, log, ArgoRolloutsResource, workloadSelector, rs...)
+}
+
+// WatchKnativeServices creates a SharedInformer for serving.knative.dev Services and registers it with g.
+func WatchKnativeServices(g *workgroup.Group, client dynamic.Interface, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
+	watchDynamic(g, client, log, KnativeServiceResource, workloadSelector, rs...)
+}
+
+func watchDynamic(g *workgroup.Group, client dynamic.Interface, log logrus.FieldLogger, gvr schema.GroupVersionResource, selector string, rs ...cache.ResourceEventHandler) {
+	for _, namespace := range namespaces(gvr.Resource) {
+		ri := client.Resource(gvr).Namespace(namespace)
+		lw := &cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = selector
+				return ri.List(options)
+			},
+			WatchFunc: func(options meta_v1.ListOptions) (k8s_watch.Interface, error) {
+				options.LabelSelector = selector
+				return ri.Watch(options)
+			},
+		}
+		inform(g, lw, namespaced(log, namespace), gvr.Resource, new(unstructured.Unstructured), rs...)
+	}
+}
+
+func watch(g *workgroup.Group, c cache.Getter, log logrus.FieldLogger, resource, selector string, objType runtime.Object, rs ...cache.ResourceEventHandler) {
+	for _, namespace := range namespaces(resource) {
+		lw := cache.NewFilteredListWatchFromClient(c, resource, namespace, func(options *meta_v1.ListOptions) {
+			options.FieldSelector = fields.Everything().String()
+			options.LabelSelector = selector
+		})
+		inform(g, lw, namespaced(log, namespace), resource, objType, rs...)
+	}
+}
+
+func namespaced(log logrus.FieldLogger, namespace string) logrus.FieldLogger {
+	if namespace == v1.NamespaceAll {
+		return log
+	}
+	return log.WithField("namespace", namespace)
 }
 
-func watch(g *workgroup.Group, c cache.Getter, log logrus.FieldLogger, resource string, objType runtime.Object, rs ...cache.ResourceEventHandler) {
-	lw := cache.NewListWatchFromClient(c, resource, v1.NamespaceAll, fields.Everything())
+func inform(g *workgroup.Group, lw cache.ListerWatcher, log logrus.FieldLogger, resource string, objType runtime.Object, rs ...cache.ResourceEventHandler) {
 	sw := cache.NewSharedInformer(lw, objType, 30*time.Minute)
 	for _, r := range rs {
 		sw.AddEventHandler(r)
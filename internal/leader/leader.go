@@ -0,0 +1,341 @@
+// Package leader - leader election for keel replicas. Replicas compete for a
+// coordination.k8s.io Lease, the holder polls registries and applies updates
+// while all replicas keep serving the API. A holder that stops renewing is
+// replaced once its lease expires.
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaults, same as the ones used by kubernetes controllers
+const (
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRenewDeadline = 10 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+)
+
+// LeaseClient - leases of the namespace keel runs in, implemented by the
+// typed coordination/v1 client
+type LeaseClient interface {
+	Get(name string, options metav1.GetOptions) (*coordinationv1.Lease, error)
+	Create(lease *coordinationv1.Lease) (*coordinationv1.Lease, error)
+	Update(lease *coordinationv1.Lease) (*coordinationv1.Lease, error)
+}
+
+// Opts - elector options
+type Opts struct {
+	Client LeaseClient
+	// Name - lease name, shared by all replicas
+	Name string
+	// Identity - unique replica name, ie: pod name
+	Identity string
+
+	// LeaseDuration - how long followers wait before taking over the lease
+	// of a leader that stopped renewing it
+	LeaseDuration time.Duration
+	// RenewDeadline - leader gives up leadership when it fails to renew the
+	// lease for this long
+	RenewDeadline time.Duration
+	// RetryPeriod - how often the lease is acquired or renewed
+	RetryPeriod time.Duration
+}
+
+// Elector - competes for the lease and tracks whether this replica leads
+type Elector struct {
+	opts Opts
+
+	mu      sync.RWMutex
+	leading bool
+	holder  string
+
+	leadingCh chan struct{}
+	lostCh    chan struct{}
+}
+
+// New - new elector, call Run to start competing for the lease
+func New(opts Opts) (*Elector, error) {
+	if opts.Client == nil {
+		return nil, errors.New("lease client is required")
+	}
+	if opts.Name == "" || opts.Identity == "" {
+		return nil, errors.New("lease name and identity are required")
+	}
+	if opts.LeaseDuration == 0 {
+		opts.LeaseDuration = DefaultLeaseDuration
+	}
+	if opts.RenewDeadline == 0 {
+		opts.RenewDeadline = DefaultRenewDeadline
+	}
+	if opts.RetryPeriod == 0 {
+		opts.RetryPeriod = DefaultRetryPeriod
+	}
+	if opts.RenewDeadline >= opts.LeaseDuration {
+		return nil, errors.New("renew deadline has to be shorter than lease duration")
+	}
+
+	return &Elector{
+		opts:      opts,
+		leadingCh: make(chan struct{}),
+		lostCh:    make(chan struct{}),
+	}, nil
+}
+
+// IsLeader - whether this replica currently holds the lease
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leading
+}
+
+// Leader - identity of the last observed lease holder
+func (e *Elector) Leader() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.holder
+}
+
+// Identity - identity of this replica
+func (e *Elector) Identity() string {
+	return e.opts.Identity
+}
+
+// Leading - closed once this replica acquires the lease
+func (e *Elector) Leading() <-chan struct{} {
+	return e.leadingCh
+}
+
+// Lost - closed once the leader fails to renew the lease, replica is
+// expected to stop (and restart) so work of the new leader isn't duplicated
+func (e *Elector) Lost() <-chan struct{} {
+	return e.lostCh
+}
+
+// Run - acquires and then keeps renewing the lease until ctx is done or
+// leadership is lost, the lease is released when ctx is done
+func (e *Elector) Run(ctx context.Context) {
+	if !e.acquire(ctx) {
+		return
+	}
+
+	e.mu.Lock()
+	e.leading = true
+	e.mu.Unlock()
+	close(e.leadingCh)
+
+	log.WithFields(log.Fields{
+		"identity": e.opts.Identity,
+		"lease":    e.opts.Name,
+	}).Info("leader: acquired lease, this replica is the leader")
+
+	e.renew(ctx)
+
+	e.mu.Lock()
+	e.leading = false
+	e.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		e.release()
+	default:
+		log.WithFields(log.Fields{
+			"identity": e.opts.Identity,
+			"lease":    e.opts.Name,
+		}).Error("leader: failed to renew lease, leadership lost")
+		close(e.lostCh)
+	}
+}
+
+// acquire - retries until the lease is acquired, returns false when ctx is
+// done first
+func (e *Elector) acquire(ctx context.Context) bool {
+	ticker := time.NewTicker(e.opts.RetryPeriod)
+	defer ticker.Stop()
+
+	for {
+		ok, err := e.tryAcquireOrRenew()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"lease": e.opts.Name,
+			}).Warn("leader: failed to acquire lease")
+		}
+		if ok {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// renew - renews the lease until ctx is done or renewals keep failing for
+// longer than the renew deadline
+func (e *Elector) renew(ctx context.Context) {
+	ticker := time.NewTicker(e.opts.RetryPeriod)
+	defer ticker.Stop()
+
+	lastRenew := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ok, err := e.tryAcquireOrRenew()
+		if ok {
+			lastRenew = time.Now()
+			continue
+		}
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"lease": e.opts.Name,
+			}).Warn("leader: failed to renew lease")
+		}
+		if !ok && err == nil {
+			// somebody else holds the lease
+			return
+		}
+		if time.Since(lastRenew) > e.opts.RenewDeadline {
+			return
+		}
+	}
+}
+
+// tryAcquireOrRenew - takes the lease when it's free or expired, renews it
+// when it's already held by this replica
+func (e *Elector) tryAcquireOrRenew() (bool, error) {
+	now := metav1.NewMicroTime(time.Now())
+	durationSeconds := int32(e.opts.LeaseDuration / time.Second)
+
+	lease, err := e.opts.Client.Get(e.opts.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		transitions := int32(0)
+		_, err = e.opts.Client.Create(&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: e.opts.Name},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &e.opts.Identity,
+				LeaseDurationSeconds: &durationSeconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+				LeaseTransitions:     &transitions,
+			},
+		})
+		if err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				// another replica was faster
+				return false, nil
+			}
+			return false, err
+		}
+		e.observe(e.opts.Identity)
+		return true, nil
+	}
+
+	holder := ""
+	if lease.Spec.HolderIdentity != nil {
+		holder = *lease.Spec.HolderIdentity
+	}
+	e.observe(holder)
+
+	if holder != "" && holder != e.opts.Identity && !expired(lease, now.Time) {
+		return false, nil
+	}
+
+	updated := lease.DeepCopy()
+	updated.Spec.HolderIdentity = &e.opts.Identity
+	updated.Spec.LeaseDurationSeconds = &durationSeconds
+	updated.Spec.RenewTime = &now
+	if holder != e.opts.Identity {
+		transitions := int32(0)
+		if lease.Spec.LeaseTransitions != nil {
+			transitions = *lease.Spec.LeaseTransitions
+		}
+		if holder != "" {
+			transitions++
+		}
+		updated.Spec.LeaseTransitions = &transitions
+		updated.Spec.AcquireTime = &now
+	}
+
+	// update is rejected when the lease changed since it was read, ie:
+	// another replica took it over in the meantime
+	_, err = e.opts.Client.Update(updated)
+	if err != nil {
+		if apierrors.IsConflict(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	e.observe(e.opts.Identity)
+	return true, nil
+}
+
+// release - clears the holder so followers can take over without waiting for
+// the lease to expire
+func (e *Elector) release() {
+	lease, err := e.opts.Client.Get(e.opts.Name, metav1.GetOptions{})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"lease": e.opts.Name,
+		}).Warn("leader: failed to get lease for release")
+		return
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != e.opts.Identity {
+		return
+	}
+
+	updated := lease.DeepCopy()
+	updated.Spec.HolderIdentity = nil
+	updated.Spec.RenewTime = nil
+	_, err = e.opts.Client.Update(updated)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"lease": e.opts.Name,
+		}).Warn("leader: failed to release lease")
+		return
+	}
+	log.WithFields(log.Fields{
+		"identity": e.opts.Identity,
+		"lease":    e.opts.Name,
+	}).Info("leader: lease released")
+}
+
+func (e *Elector) observe(holder string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if holder != e.holder && holder != "" {
+		log.WithFields(log.Fields{
+			"leader": holder,
+			"lease":  e.opts.Name,
+		}).Info("leader: new leader observed")
+	}
+	e.holder = holder
+}
+
+func expired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	deadline := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+	return now.After(deadline)
+}
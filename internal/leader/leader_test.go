@@ -0,0 +1,192 @@
+package leader
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var leasesResource = schema.GroupResource{Group: "coordination.k8s.io", Resource: "leases"}
+
+// fakeLeases - in memory leases that reject stale updates like the API server
+type fakeLeases struct {
+	mu      sync.Mutex
+	leases  map[string]*coordinationv1.Lease
+	version int
+}
+
+func newFakeLeases() *fakeLeases {
+	return &fakeLeases{leases: make(map[string]*coordinationv1.Lease)}
+}
+
+func (f *fakeLeases) Get(name string, options metav1.GetOptions) (*coordinationv1.Lease, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lease, ok := f.leases[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(leasesResource, name)
+	}
+	return lease.DeepCopy(), nil
+}
+
+func (f *fakeLeases) Create(lease *coordinationv1.Lease) (*coordinationv1.Lease, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.leases[lease.Name]; ok {
+		return nil, apierrors.NewAlreadyExists(leasesResource, lease.Name)
+	}
+	f.version++
+	lease = lease.DeepCopy()
+	lease.ResourceVersion = strconv.Itoa(f.version)
+	f.leases[lease.Name] = lease
+	return lease.DeepCopy(), nil
+}
+
+func (f *fakeLeases) Update(lease *coordinationv1.Lease) (*coordinationv1.Lease, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	existing, ok := f.leases[lease.Name]
+	if !ok {
+		return nil, apierrors.NewNotFound(leasesResource, lease.Name)
+	}
+	if existing.ResourceVersion != lease.ResourceVersion {
+		return nil, apierrors.NewConflict(leasesResource, lease.Name, nil)
+	}
+	f.version++
+	lease = lease.DeepCopy()
+	lease.ResourceVersion = strconv.Itoa(f.version)
+	f.leases[lease.Name] = lease
+	return lease.DeepCopy(), nil
+}
+
+func (f *fakeLeases) holder(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lease, ok := f.leases[name]
+	if !ok || lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+func newTestElector(t *testing.T, client LeaseClient, identity string) *Elector {
+	e, err := New(Opts{
+		Client:        client,
+		Name:          "keel",
+		Identity:      identity,
+		LeaseDuration: 3 * time.Second,
+		RenewDeadline: 2 * time.Second,
+		RetryPeriod:   20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("failed to create elector: %s", err)
+	}
+	return e
+}
+
+func waitLeading(t *testing.T, e *Elector) {
+	select {
+	case <-e.Leading():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s didn't become the leader", e.Identity())
+	}
+}
+
+func TestSingleLeader(t *testing.T) {
+	client := newFakeLeases()
+	first := newTestElector(t, client, "keel-1")
+	second := newTestElector(t, client, "keel-2")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go first.Run(ctx)
+	waitLeading(t, first)
+	go second.Run(ctx)
+
+	// several renew periods, the leader has to keep the lease
+	time.Sleep(200 * time.Millisecond)
+
+	if !first.IsLeader() {
+		t.Errorf("expected keel-1 to lead")
+	}
+	if second.IsLeader() {
+		t.Errorf("expected keel-2 to follow")
+	}
+	if second.Leader() != "keel-1" {
+		t.Errorf("expected keel-2 to observe keel-1 as leader, got: %s", second.Leader())
+	}
+	if client.holder("keel") != "keel-1" {
+		t.Errorf("unexpected lease holder: %s", client.holder("keel"))
+	}
+}
+
+func TestFailoverAfterRelease(t *testing.T) {
+	client := newFakeLeases()
+	first := newTestElector(t, client, "keel-1")
+	second := newTestElector(t, client, "keel-2")
+
+	firstCtx, firstCancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		first.Run(firstCtx)
+		close(done)
+	}()
+	waitLeading(t, first)
+	go second.Run(ctx)
+
+	firstCancel()
+	<-done
+	if first.IsLeader() {
+		t.Errorf("expected keel-1 to give up leadership")
+	}
+
+	// released lease is taken over without waiting for it to expire
+	waitLeading(t, second)
+	if client.holder("keel") != "keel-2" {
+		t.Errorf("unexpected lease holder: %s", client.holder("keel"))
+	}
+
+	lease, _ := client.Get("keel", metav1.GetOptions{})
+	if lease.Spec.LeaseTransitions == nil || *lease.Spec.LeaseTransitions != 0 {
+		t.Errorf("released lease shouldn't count as transition, got: %v", lease.Spec.LeaseTransitions)
+	}
+}
+
+func TestTakeOverExpiredLease(t *testing.T) {
+	client := newFakeLeases()
+	stale := metav1.NewMicroTime(time.Now().Add(-time.Minute))
+	holder := "keel-1"
+	duration := int32(3)
+	transitions := int32(0)
+	client.Create(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: "keel"},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &duration,
+			RenewTime:            &stale,
+			LeaseTransitions:     &transitions,
+		},
+	})
+
+	e := newTestElector(t, client, "keel-2")
+	ok, err := e.tryAcquireOrRenew()
+	if err != nil || !ok {
+		t.Fatalf("expected expired lease to be acquired, ok: %t, err: %v", ok, err)
+	}
+
+	lease, _ := client.Get("keel", metav1.GetOptions{})
+	if *lease.Spec.HolderIdentity != "keel-2" || *lease.Spec.LeaseTransitions != 1 {
+		t.Errorf("unexpected lease: %+v", lease.Spec)
+	}
+}
@@ -0,0 +1,148 @@
+// Package logging - per module log levels. Keel log messages are prefixed with
+// the module that logs them (ie: "trigger.poll.manager: ..."), levels can be
+// set for module prefixes and changed at runtime. The most specific configured
+// module wins, other messages use the default level.
+package logging
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Levels - default and per module log levels, ie:
+// {"default": "info", "modules": {"trigger.poll": "debug"}}
+type Levels struct {
+	Default string            `json:"default"`
+	Modules map[string]string `json:"modules"`
+}
+
+type config struct {
+	defaultLevel logrus.Level
+	modules      map[string]logrus.Level
+	// prefixes - configured modules, longest (most specific) first
+	prefixes []string
+}
+
+var (
+	mu      sync.RWMutex
+	current = &config{defaultLevel: logrus.InfoLevel, modules: map[string]logrus.Level{}}
+)
+
+// ParseModules - parses module levels, ie: trigger.poll=debug,bot.slack=info
+func ParseModules(value string) (map[string]string, error) {
+	modules := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid module log level '%s', expected <module>=<level>", pair)
+		}
+		modules[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return modules, nil
+}
+
+// Configure - sets log levels of the standard logger
+func Configure(levels Levels) error {
+	return configure(logrus.StandardLogger(), levels)
+}
+
+func configure(logger *logrus.Logger, levels Levels) error {
+	cfg := &config{defaultLevel: logrus.InfoLevel, modules: make(map[string]logrus.Level)}
+
+	var err error
+	if levels.Default != "" {
+		cfg.defaultLevel, err = logrus.ParseLevel(levels.Default)
+		if err != nil {
+			return err
+		}
+	}
+	for module, level := range levels.Modules {
+		cfg.modules[module], err = logrus.ParseLevel(level)
+		if err != nil {
+			return fmt.Errorf("module %s: %s", module, err)
+		}
+	}
+
+	for module := range cfg.modules {
+		cfg.prefixes = append(cfg.prefixes, module)
+	}
+	sort.Slice(cfg.prefixes, func(i, j int) bool { return len(cfg.prefixes[i]) > len(cfg.prefixes[j]) })
+
+	// logger has to let through messages of the most verbose module, the rest
+	// is dropped by the formatter
+	verbose := cfg.defaultLevel
+	for _, level := range cfg.modules {
+		if level > verbose {
+			verbose = level
+		}
+	}
+
+	mu.Lock()
+	current = cfg
+	mu.Unlock()
+
+	logger.SetLevel(verbose)
+	if _, ok := logger.Formatter.(*moduleFormatter); !ok {
+		logger.SetFormatter(&moduleFormatter{Formatter: logger.Formatter})
+	}
+	return nil
+}
+
+// Current - currently configured log levels
+func Current() Levels {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	levels := Levels{
+		Default: current.defaultLevel.String(),
+		Modules: make(map[string]string, len(current.modules)),
+	}
+	for module, level := range current.modules {
+		levels.Modules[module] = level.String()
+	}
+	return levels
+}
+
+// moduleFormatter - drops messages that are more verbose than the level of
+// their module
+type moduleFormatter struct {
+	logrus.Formatter
+}
+
+func (f *moduleFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if entry.Level > levelOf(entry.Message) {
+		return nil, nil
+	}
+	return f.Formatter.Format(entry)
+}
+
+// levelOf - level of the message module, module is the message prefix before
+// the first colon
+func levelOf(message string) logrus.Level {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if len(current.modules) == 0 {
+		return current.defaultLevel
+	}
+
+	idx := strings.Index(message, ":")
+	if idx < 1 || strings.ContainsAny(message[:idx], " \t") {
+		return current.defaultLevel
+	}
+	module := message[:idx]
+
+	for _, prefix := range current.prefixes {
+		if module == prefix || strings.HasPrefix(module, prefix+".") {
+			return current.modules[prefix]
+		}
+	}
+	return current.defaultLevel
+}
@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestModuleLevels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.Out = buf
+	logger.Formatter = &logrus.TextFormatter{DisableTimestamp: true}
+
+	modules, err := ParseModules("trigger.poll=debug, trigger.poll.manager=warn")
+	if err != nil {
+		t.Fatalf("failed to parse modules: %s", err)
+	}
+	err = configure(logger, Levels{Default: "info", Modules: modules})
+	if err != nil {
+		t.Fatalf("failed to configure: %s", err)
+	}
+
+	logger.Debug("trigger.poll.WatchTagJob: checking tag")
+	logger.Info("trigger.poll.manager: watching")
+	logger.Debug("provider.kubernetes: no plans")
+	logger.Info("provider.kubernetes: updated")
+	logger.Debug("keel starting: poll")
+
+	out := buf.String()
+	for _, expected := range []string{"checking tag", "updated"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q to be logged, got: %s", expected, out)
+		}
+	}
+	for _, dropped := range []string{"watching", "no plans", "keel starting"} {
+		if strings.Contains(out, dropped) {
+			t.Errorf("expected %q to be dropped, got: %s", dropped, out)
+		}
+	}
+
+	levels := Current()
+	if levels.Default != "info" || levels.Modules["trigger.poll"] != "debug" {
+		t.Errorf("unexpected levels: %+v", levels)
+	}
+
+	if err := configure(logger, Levels{Modules: map[string]string{"bot": "loud"}}); err == nil {
+		t.Errorf("expected invalid level to be rejected")
+	}
+	if _, err := ParseModules("bot"); err == nil {
+		t.Errorf("expected module without level to be rejected")
+	}
+}
@@ -0,0 +1,173 @@
+// Package oncall resolves who is currently on call for a PagerDuty or Opsgenie
+// schedule, approval requests mention them so the owning team gets paged in chat.
+package oncall
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Resolver - returns emails of users currently on call for the schedule
+type Resolver interface {
+	OnCall(schedule string) ([]string, error)
+}
+
+// default API addresses
+const (
+	PagerDutyAPI = "https://api.pagerduty.com"
+	OpsgenieAPI  = "https://api.opsgenie.com"
+)
+
+// New - creates resolver by provider name, pagerduty or opsgenie. Address is
+// optional, ie: https://api.eu.opsgenie.com for Opsgenie EU accounts.
+func New(provider, address, token string) (Resolver, error) {
+	if token == "" {
+		return nil, fmt.Errorf("API token is required")
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	switch provider {
+	case "pagerduty":
+		if address == "" {
+			address = PagerDutyAPI
+		}
+		return &PagerDuty{address: strings.TrimSuffix(address, "/"), token: token, client: client}, nil
+	case "opsgenie":
+		if address == "" {
+			address = OpsgenieAPI
+		}
+		return &Opsgenie{address: strings.TrimSuffix(address, "/"), token: token, client: client}, nil
+	}
+	return nil, fmt.Errorf("unknown on-call provider '%s', expected pagerduty or opsgenie", provider)
+}
+
+// PagerDuty - resolves on-call users with the PagerDuty REST API
+type PagerDuty struct {
+	address string
+	token   string
+	client  *http.Client
+}
+
+type pagerDutyOncalls struct {
+	Oncalls []struct {
+		EscalationLevel int `json:"escalation_level"`
+		User            struct {
+			Email   string `json:"email"`
+			Summary string `json:"summary"`
+		} `json:"user"`
+	} `json:"oncalls"`
+}
+
+// OnCall - users on call for the schedule ID right now
+func (p *PagerDuty) OnCall(schedule string) ([]string, error) {
+	q := url.Values{}
+	q.Set("schedule_ids[]", schedule)
+	q.Set("include[]", "users")
+	q.Set("earliest", "true")
+
+	req, err := http.NewRequest("GET", p.address+"/oncalls?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+	req.Header.Set("Authorization", "Token token="+p.token)
+
+	var oncalls pagerDutyOncalls
+	if err := do(p.client, req, &oncalls); err != nil {
+		return nil, fmt.Errorf("pagerduty: %s", err)
+	}
+
+	var users []string
+	for _, o := range oncalls.Oncalls {
+		if o.User.Email != "" {
+			users = appendUnique(users, o.User.Email)
+		}
+	}
+	return users, nil
+}
+
+// Opsgenie - resolves on-call users with the Opsgenie schedule API
+type Opsgenie struct {
+	address string
+	token   string
+	client  *http.Client
+}
+
+type opsgenieOncalls struct {
+	Data struct {
+		OnCallRecipients []string `json:"onCallRecipients"`
+	} `json:"data"`
+}
+
+// OnCall - users on call for the schedule (ID or name) right now
+func (o *Opsgenie) OnCall(schedule string) ([]string, error) {
+	q := url.Values{}
+	q.Set("flat", "true")
+	q.Set("scheduleIdentifierType", "name")
+	if isUUID(schedule) {
+		q.Set("scheduleIdentifierType", "id")
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/v2/schedules/%s/on-calls?%s", o.address, url.PathEscape(schedule), q.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "GenieKey "+o.token)
+
+	var oncalls opsgenieOncalls
+	if err := do(o.client, req, &oncalls); err != nil {
+		return nil, fmt.Errorf("opsgenie: %s", err)
+	}
+
+	var users []string
+	for _, r := range oncalls.Data.OnCallRecipients {
+		users = appendUnique(users, r)
+	}
+	return users, nil
+}
+
+func do(client *http.Client, req *http.Request, v interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("schedule request returned status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode on-calls: %s", err)
+	}
+	return nil
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, existing := range list {
+		if existing == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// isUUID - Opsgenie schedule IDs are UUIDs, anything else is a schedule name
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case i == 8 || i == 13 || i == 18 || i == 23:
+			if c != '-' {
+				return false
+			}
+		case (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F'):
+		default:
+			return false
+		}
+	}
+	return true
+}
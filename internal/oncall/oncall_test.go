@@ -0,0 +1,73 @@
+package oncall
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestPagerDuty(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oncalls" || r.URL.Query().Get("schedule_ids[]") != "PI7DH85" {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		if r.Header.Get("Authorization") != "Token token=secret" {
+			t.Errorf("unexpected authorization: %s", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"oncalls":[{"escalation_level":1,"user":{"email":"karolis@example.com","summary":"Karolis"}},{"escalation_level":2,"user":{"email":"karolis@example.com"}}]}`))
+	}))
+	defer srv.Close()
+
+	r, err := New("pagerduty", srv.URL, "secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	users, err := r.OnCall("PI7DH85")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(users, []string{"karolis@example.com"}) {
+		t.Errorf("unexpected users: %v", users)
+	}
+}
+
+func TestOpsgenie(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/schedules/payments team/on-calls" || r.URL.Query().Get("scheduleIdentifierType") != "name" {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		if r.Header.Get("Authorization") != "GenieKey secret" {
+			t.Errorf("unexpected authorization: %s", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"data":{"onCallRecipients":["jane@example.com","john@example.com"]}}`))
+	}))
+	defer srv.Close()
+
+	r, _ := New("opsgenie", srv.URL, "secret")
+	users, err := r.OnCall("payments team")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(users, []string{"jane@example.com", "john@example.com"}) {
+		t.Errorf("unexpected users: %v", users)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	r, _ := New("opsgenie", srv.URL, "bad")
+	if _, err := r.OnCall("1d8e3f6a-0b2e-4c57-9d55-6a2f0f1e2b3c"); err == nil {
+		t.Errorf("expected error")
+	}
+	if _, err := New("victorops", "", "secret"); err == nil {
+		t.Errorf("expected unknown provider error")
+	}
+	if _, err := New("pagerduty", "", ""); err == nil {
+		t.Errorf("expected missing token error")
+	}
+}
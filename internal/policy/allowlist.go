@@ -0,0 +1,93 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver"
+)
+
+var (
+	allowlistsM sync.RWMutex
+	allowlists  = make(map[string][]string)
+)
+
+// SetAllowedTags - sets tags of the named allowlist, ie: after they are fetched
+// from the release management system. Tags are ordered oldest first.
+func SetAllowedTags(name string, tags []string) {
+	allowlistsM.Lock()
+	allowlists[name] = append([]string{}, tags...)
+	allowlistsM.Unlock()
+}
+
+// RemoveAllowlist - removes the named allowlist, policies using it block updates
+func RemoveAllowlist(name string) {
+	allowlistsM.Lock()
+	delete(allowlists, name)
+	allowlistsM.Unlock()
+}
+
+// AllowedTags - tags of the named allowlist, ok is false until it's loaded
+func AllowedTags(name string) (tags []string, ok bool) {
+	allowlistsM.RLock()
+	defer allowlistsM.RUnlock()
+	tags, ok = allowlists[name]
+	return tags, ok
+}
+
+// AllowlistPolicy - updates only to tags of an allowlist fetched from an external
+// endpoint, ie: allowlist:payments. Registry tags that weren't blessed upstream
+// are never deployed, updates are blocked until the allowlist is loaded.
+type AllowlistPolicy struct {
+	policy string
+	name   string
+}
+
+func NewAllowlistPolicy(policy string) (*AllowlistPolicy, error) {
+	name := strings.TrimPrefix(policy, "allowlist:")
+	if name == "" || name == policy {
+		return nil, fmt.Errorf("invalid allowlist policy: %s", policy)
+	}
+	return &AllowlistPolicy{policy: policy, name: name}, nil
+}
+
+// ShouldUpdate - new tag has to be allowed and newer than the current tag. Semver
+// tags are compared as versions, other tags by their position in the allowlist.
+// Current tags that aren't allowed can always be updated.
+func (p *AllowlistPolicy) ShouldUpdate(current, new string) (bool, error) {
+	tags, ok := AllowedTags(p.name)
+	if !ok {
+		return false, fmt.Errorf("allowlist %s isn't loaded", p.name)
+	}
+
+	currentIdx, newIdx := -1, -1
+	for i, tag := range tags {
+		if tag == current {
+			currentIdx = i
+		}
+		if tag == new {
+			newIdx = i
+		}
+	}
+	if newIdx < 0 || new == current {
+		return false, nil
+	}
+	if currentIdx < 0 {
+		return true, nil
+	}
+
+	currentVersion, err := semver.NewVersion(current)
+	if err == nil {
+		if newVersion, err := semver.NewVersion(new); err == nil {
+			return newVersion.GreaterThan(currentVersion), nil
+		}
+	}
+	return newIdx > currentIdx, nil
+}
+
+// Ordered - only the newest allowed tag is deployed
+func (p *AllowlistPolicy) Ordered() bool { return true }
+
+func (p *AllowlistPolicy) Name() string     { return p.policy }
+func (p *AllowlistPolicy) Type() PolicyType { return PolicyTypeAllowlist }
@@ -0,0 +1,64 @@
+package policy
+
+import "testing"
+
+func TestAllowlistPolicy_ShouldUpdate(t *testing.T) {
+	SetAllowedTags("test", []string{"1.0.0", "1.2.0", "build-10", "build-9"})
+	defer RemoveAllowlist("test")
+
+	tests := []struct {
+		name    string
+		current string
+		new     string
+		want    bool
+	}{
+		{name: "newer allowed version", current: "1.0.0", new: "1.2.0", want: true},
+		{name: "older allowed version", current: "1.2.0", new: "1.0.0", want: false},
+		{name: "same version", current: "1.2.0", new: "1.2.0", want: false},
+		{name: "tag isn't allowed", current: "1.0.0", new: "1.3.0", want: false},
+		{name: "current tag isn't allowed", current: "latest", new: "1.0.0", want: true},
+		{name: "non semver tags in allowlist order", current: "build-10", new: "build-9", want: true},
+	}
+	p := GetPolicy("allowlist:test", &Options{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.ShouldUpdate(tt.current, tt.new)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("ShouldUpdate(%s, %s) = %v, want %v", tt.current, tt.new, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowlistPolicyNotLoaded(t *testing.T) {
+	p, err := NewAllowlistPolicy("allowlist:missing")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if update, err := p.ShouldUpdate("1.0.0", "1.1.0"); err == nil || update {
+		t.Errorf("expected updates to be blocked until allowlist is loaded")
+	}
+
+	if _, err := NewAllowlistPolicy("allowlist:"); err == nil {
+		t.Errorf("expected error for allowlist policy without name")
+	}
+}
+
+func TestAllowlistCombinedPolicy(t *testing.T) {
+	SetAllowedTags("test", []string{"1.1.0", "2.0.0"})
+	defer RemoveAllowlist("test")
+
+	p := GetPolicy("semver:minor AND allowlist:test", &Options{})
+	if update, _ := p.ShouldUpdate("1.0.0", "1.1.0"); !update {
+		t.Errorf("expected update to allowed minor version")
+	}
+	if update, _ := p.ShouldUpdate("1.0.0", "2.0.0"); update {
+		t.Errorf("expected major version to be blocked by semver policy")
+	}
+	if update, _ := p.ShouldUpdate("1.0.0", "1.2.0"); update {
+		t.Errorf("expected update to be blocked by allowlist")
+	}
+}
@@ -0,0 +1,215 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// gate terms of combined policies, they can't be evaluated from tags and are
+// checked by providers before the update
+const (
+	gateMinimumAge = "minimumAge:"
+	gateSigned     = "signed"
+)
+
+// CombinedPolicy - policies combined with AND, OR, NOT operators and parentheses,
+// ie: "semver:minor AND NOT glob:*-debug AND minimumAge:1h AND signed". AND binds
+// tighter than OR, parentheses have to be separated by spaces. minimumAge and
+// signed gates can only be required with AND at the top level.
+type CombinedPolicy struct {
+	policy string
+	root   expression
+
+	minimumAge time.Duration
+	signed     bool
+	ordered    bool
+}
+
+type expression interface {
+	eval(current, new string) bool
+}
+
+type termExpr struct{ policy Policy }
+
+func (e *termExpr) eval(current, new string) bool {
+	update, err := e.policy.ShouldUpdate(current, new)
+	return err == nil && update
+}
+
+type andExpr struct{ left, right expression }
+
+func (e *andExpr) eval(current, new string) bool {
+	return e.left.eval(current, new) && e.right.eval(current, new)
+}
+
+type orExpr struct{ left, right expression }
+
+func (e *orExpr) eval(current, new string) bool {
+	return e.left.eval(current, new) || e.right.eval(current, new)
+}
+
+type notExpr struct{ expr expression }
+
+func (e *notExpr) eval(current, new string) bool { return !e.expr.eval(current, new) }
+
+// gateExpr - gates don't restrict tags
+type gateExpr struct{}
+
+func (e *gateExpr) eval(current, new string) bool { return true }
+
+// isCombinedPolicy - whether policy uses operators
+func isCombinedPolicy(policy string) bool {
+	for _, token := range strings.Fields(policy) {
+		switch strings.ToUpper(token) {
+		case "AND", "OR", "NOT", "(":
+			return true
+		}
+	}
+	return false
+}
+
+// NewCombinedPolicy - parses policy expression
+func NewCombinedPolicy(policy string) (*CombinedPolicy, error) {
+	p := &CombinedPolicy{policy: policy}
+	parser := &combinedParser{tokens: strings.Fields(policy), policy: p}
+
+	root, err := parser.parseOr(true)
+	if err != nil {
+		return nil, err
+	}
+	if parser.pos < len(parser.tokens) {
+		return nil, fmt.Errorf("unexpected %q in policy %q", parser.tokens[parser.pos], policy)
+	}
+	p.root = root
+	return p, nil
+}
+
+type combinedParser struct {
+	tokens []string
+	pos    int
+	policy *CombinedPolicy
+}
+
+func (c *combinedParser) peek() string {
+	if c.pos < len(c.tokens) {
+		return strings.ToUpper(c.tokens[c.pos])
+	}
+	return ""
+}
+
+// parseOr - top is set while the expression is a top level conjunction, gates are
+// only allowed there
+func (c *combinedParser) parseOr(top bool) (expression, error) {
+	left, err := c.parseAnd(top)
+	if err != nil {
+		return nil, err
+	}
+	for c.peek() == "OR" {
+		c.pos++
+		if c.policy.minimumAge > 0 || c.policy.signed {
+			return nil, fmt.Errorf("gates can't be combined with OR")
+		}
+		right, err := c.parseAnd(false)
+		if err != nil {
+			return nil, err
+		}
+		left = &orExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (c *combinedParser) parseAnd(top bool) (expression, error) {
+	left, err := c.parseUnary(top)
+	if err != nil {
+		return nil, err
+	}
+	for c.peek() == "AND" {
+		c.pos++
+		right, err := c.parseUnary(top)
+		if err != nil {
+			return nil, err
+		}
+		left = &andExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (c *combinedParser) parseUnary(top bool) (expression, error) {
+	switch c.peek() {
+	case "":
+		return nil, fmt.Errorf("unexpected end of policy %q", c.policy.policy)
+	case "NOT":
+		c.pos++
+		expr, err := c.parseUnary(false)
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{expr: expr}, nil
+	case "(":
+		c.pos++
+		expr, err := c.parseOr(false)
+		if err != nil {
+			return nil, err
+		}
+		if c.peek() != ")" {
+			return nil, fmt.Errorf("missing ) in policy %q", c.policy.policy)
+		}
+		c.pos++
+		return expr, nil
+	case "AND", "OR", ")":
+		return nil, fmt.Errorf("unexpected %q in policy %q", c.tokens[c.pos], c.policy.policy)
+	}
+
+	term := c.tokens[c.pos]
+	c.pos++
+	return c.parseTerm(term, top)
+}
+
+func (c *combinedParser) parseTerm(term string, top bool) (expression, error) {
+	switch {
+	case term == gateSigned, strings.HasPrefix(term, gateMinimumAge):
+		if !top {
+			return nil, fmt.Errorf("%s can only be required with AND", term)
+		}
+		if term == gateSigned {
+			c.policy.signed = true
+			return &gateExpr{}, nil
+		}
+		d, err := time.ParseDuration(strings.TrimPrefix(term, gateMinimumAge))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid minimum age %q", term)
+		}
+		c.policy.minimumAge = d
+		return &gateExpr{}, nil
+	}
+
+	plc := GetPolicy(term, &Options{})
+	if plc.Type() == PolicyTypeNone {
+		return nil, fmt.Errorf("invalid policy %q", term)
+	}
+	if op, ok := plc.(interface{ Ordered() bool }); ok && op.Ordered() {
+		c.policy.ordered = true
+	}
+	return &termExpr{policy: plc}, nil
+}
+
+// ShouldUpdate - evaluates the expression, errors of policies (ie: tag isn't
+// semver) evaluate to false
+func (p *CombinedPolicy) ShouldUpdate(current, new string) (bool, error) {
+	return p.root.eval(current, new), nil
+}
+
+// MinimumAge - minimum image age required by minimumAge gate
+func (p *CombinedPolicy) MinimumAge() (time.Duration, bool) {
+	return p.minimumAge, p.minimumAge > 0
+}
+
+// RequiresSignature - whether new images have to be signed
+func (p *CombinedPolicy) RequiresSignature() bool { return p.signed }
+
+// Ordered - combined policies with ordered policies only update to the highest tag
+func (p *CombinedPolicy) Ordered() bool { return p.ordered }
+
+func (p *CombinedPolicy) Name() string     { return p.policy }
+func (p *CombinedPolicy) Type() PolicyType { return PolicyTypeCombined }
@@ -0,0 +1,145 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCombinedPolicy_ShouldUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		current string
+		new     string
+		want    bool
+	}{
+		{
+			name:    "and both match",
+			policy:  "semver:minor AND NOT glob:*-debug",
+			current: "1.2.0",
+			new:     "1.3.0",
+			want:    true,
+		},
+		{
+			name:    "and excluded",
+			policy:  "semver:minor AND NOT glob:*-debug",
+			current: "1.2.0",
+			new:     "1.3.0-debug",
+			want:    false,
+		},
+		{
+			name:    "or second matches",
+			policy:  "patch OR glob:hotfix-*",
+			current: "1.2.0",
+			new:     "hotfix-1",
+			want:    true,
+		},
+		{
+			name:    "or none match",
+			policy:  "patch OR glob:hotfix-*",
+			current: "1.2.0",
+			new:     "1.3.0",
+			want:    false,
+		},
+		{
+			name:    "and binds tighter than or",
+			policy:  "glob:release-* OR patch AND NOT glob:*-debug",
+			current: "1.2.0",
+			new:     "release-1",
+			want:    true,
+		},
+		{
+			name:    "parentheses",
+			policy:  "( glob:release-* OR patch ) AND NOT glob:*-debug",
+			current: "1.2.0",
+			new:     "release-1-debug",
+			want:    false,
+		},
+		{
+			name:    "gates don't restrict tags",
+			policy:  "semver:minor AND minimumAge:1h AND signed",
+			current: "1.2.0",
+			new:     "1.3.0",
+			want:    true,
+		},
+		{
+			name:    "policy error evaluates to false",
+			policy:  "NOT major",
+			current: "1.2.0",
+			new:     "build-1",
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewCombinedPolicy(tt.policy)
+			if err != nil {
+				t.Fatalf("failed to parse policy: %s", err)
+			}
+			got, err := p.ShouldUpdate(tt.current, tt.new)
+			if err != nil {
+				t.Errorf("CombinedPolicy.ShouldUpdate() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("CombinedPolicy.ShouldUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCombinedPolicyGates(t *testing.T) {
+	p, err := NewCombinedPolicy("semver:minor AND minimumAge:1h AND signed")
+	if err != nil {
+		t.Fatalf("failed to parse policy: %s", err)
+	}
+	if age, ok := p.MinimumAge(); !ok || age != time.Hour {
+		t.Errorf("unexpected minimum age: %s", age)
+	}
+	if !p.RequiresSignature() {
+		t.Errorf("expected signature to be required")
+	}
+
+	p, _ = NewCombinedPolicy("minor AND NOT glob:*-debug")
+	if _, ok := p.MinimumAge(); ok || p.RequiresSignature() {
+		t.Errorf("unexpected gates")
+	}
+}
+
+func TestCombinedPolicyOrdered(t *testing.T) {
+	p, _ := NewCombinedPolicy("numeric:build-* AND NOT glob:*-debug")
+	if !p.Ordered() {
+		t.Errorf("expected policy with numeric term to be ordered")
+	}
+	p, _ = NewCombinedPolicy("minor AND NOT glob:*-debug")
+	if p.Ordered() {
+		t.Errorf("expected policy without ordered terms not to be ordered")
+	}
+}
+
+func TestNewCombinedPolicyInvalid(t *testing.T) {
+	for _, policy := range []string{
+		"minor AND",
+		"minor AND unknown",
+		"( minor OR patch",
+		"minor )",
+		"minor OR signed",
+		"signed AND minor OR patch",
+		"NOT signed",
+		"( minor AND minimumAge:1h )",
+		"minor AND minimumAge:soon",
+	} {
+		if _, err := NewCombinedPolicy(policy); err == nil {
+			t.Errorf("expected error for policy %q", policy)
+		}
+	}
+}
+
+func TestGetCombinedPolicy(t *testing.T) {
+	if p := GetPolicy("minor AND NOT glob:*-debug", &Options{}); p.Type() != PolicyTypeCombined {
+		t.Errorf("unexpected policy: %s", p.Name())
+	}
+	if p := GetPolicy("minor AND", &Options{}); p.Type() != PolicyTypeNone {
+		t.Errorf("expected invalid policy to be ignored, got: %s", p.Name())
+	}
+}
@@ -0,0 +1,61 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/keel-hq/keel/internal/expr"
+)
+
+// exprCostLimit - expressions are evaluated for every tag of polled repositories,
+// evaluation stops once the cost is exceeded
+const exprCostLimit = 1000
+
+// ExprPolicy - custom policy expression, ie:
+// expr:candidate.startsWith("v2.") && labels["tier"] != "critical". Expressions have
+// current and candidate tags, workload labels and registry metadata of the
+// candidate image (image.registry, image.repository, image.digest) available.
+// The syntax is a small CEL-like subset, see internal/expr for the grammar.
+type ExprPolicy struct {
+	policy  string
+	program *expr.Program
+	labels  map[string]string
+}
+
+func NewExprPolicy(policy string, labels map[string]string) (*ExprPolicy, error) {
+	source := strings.TrimSpace(strings.TrimPrefix(policy, "expr:"))
+	if source == "" {
+		return nil, fmt.Errorf("invalid expr policy: %s", policy)
+	}
+	program, err := expr.Compile(source, "current", "candidate", "labels", "image")
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile policy expression, error: %s", err)
+	}
+	return &ExprPolicy{policy: policy, program: program, labels: labels}, nil
+}
+
+// ShouldUpdate - evaluates expression without registry metadata, it's evaluated
+// again before the update when the metadata is known
+func (p *ExprPolicy) ShouldUpdate(current, new string) (bool, error) {
+	return p.ShouldUpdateWithMetadata(current, new, Metadata{})
+}
+
+func (p *ExprPolicy) ShouldUpdateWithMetadata(current, new string, metadata Metadata) (bool, error) {
+	labels := p.labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	return p.program.EvalBool(map[string]interface{}{
+		"current":   current,
+		"candidate": new,
+		"labels":    labels,
+		"image": map[string]string{
+			"registry":   metadata.Registry,
+			"repository": metadata.Repository,
+			"digest":     metadata.Digest,
+		},
+	}, exprCostLimit)
+}
+
+func (p *ExprPolicy) Name() string     { return p.policy }
+func (p *ExprPolicy) Type() PolicyType { return PolicyTypeExpr }
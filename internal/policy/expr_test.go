@@ -0,0 +1,89 @@
+package policy
+
+import "testing"
+
+func TestExprPolicy_ShouldUpdate(t *testing.T) {
+	labels := map[string]string{"tier": "backend"}
+	tests := []struct {
+		name     string
+		policy   string
+		current  string
+		new      string
+		metadata Metadata
+		want     bool
+	}{
+		{
+			name:    "candidate prefix",
+			policy:  `expr:candidate.startsWith("v2.") && candidate != current`,
+			current: "v2.0.1",
+			new:     "v2.1.0",
+			want:    true,
+		},
+		{
+			name:    "same tag",
+			policy:  `expr:candidate.startsWith("v2.") && candidate != current`,
+			current: "v2.1.0",
+			new:     "v2.1.0",
+			want:    false,
+		},
+		{
+			name:    "labels",
+			policy:  `expr:labels["tier"] == "frontend" || candidate.endsWith("-stable")`,
+			current: "1",
+			new:     "2",
+			want:    false,
+		},
+		{
+			name:     "registry metadata",
+			policy:   `expr:image.registry == "registry.example.com" && image.digest.startsWith("sha256:")`,
+			current:  "1",
+			new:      "2",
+			metadata: Metadata{Registry: "registry.example.com", Repository: "registry.example.com/app", Digest: "sha256:abc"},
+			want:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := GetPolicy(tt.policy, &Options{Labels: labels})
+			if p.Type() != PolicyTypeExpr {
+				t.Fatalf("unexpected policy: %s", p.Name())
+			}
+			got, err := ShouldUpdate(p, tt.current, tt.new, tt.metadata)
+			if err != nil {
+				t.Errorf("ExprPolicy.ShouldUpdate() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ExprPolicy.ShouldUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExprPolicyErrors(t *testing.T) {
+	if p := GetPolicy(`expr:candidate.startsWith(`, &Options{}); p.Type() != PolicyTypeNone {
+		t.Errorf("expected invalid expression to be ignored, got: %s", p.Name())
+	}
+	if p := GetPolicy(`expr:tag == "1"`, &Options{}); p.Type() != PolicyTypeNone {
+		t.Errorf("expected undeclared variable to be rejected, got: %s", p.Name())
+	}
+
+	p, err := NewExprPolicy(`expr:candidate`, nil)
+	if err != nil {
+		t.Fatalf("failed to parse policy: %s", err)
+	}
+	if _, err := p.ShouldUpdate("1", "2"); err == nil {
+		t.Errorf("expected error for non bool expression")
+	}
+}
+
+func TestGetPolicyFromAnnotationsExprLabels(t *testing.T) {
+	p := GetPolicyFromLabelsOrAnnotations(
+		map[string]string{"tier": "backend"},
+		map[string]string{"keel.sh/policy": `expr:labels.tier == "backend" && candidate > current`},
+	)
+	update, err := p.ShouldUpdate("20180601", "20180602")
+	if err != nil || !update {
+		t.Errorf("expected update with workload labels, got: %t %v", update, err)
+	}
+}
@@ -26,8 +26,35 @@ func NewGlobPolicy(policy string) (*GlobPolicy, error) {
 	return nil, fmt.Errorf("invalid glob policy: %s", policy)
 }
 
+// ShouldUpdate - pattern can be a comma separated list, tag has to match one of
+// the patterns and none of the exclusions prefixed with !, ie: release-*,!*-hotfix-*
 func (p *GlobPolicy) ShouldUpdate(current, new string) (bool, error) {
-	return glob.Glob(p.pattern, new), nil
+	return matchGlobs(p.pattern, new), nil
+}
+
+func matchGlobs(patterns, tag string) bool {
+	matched, included := false, false
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if strings.HasPrefix(pattern, "!") {
+			if glob.Glob(strings.TrimPrefix(pattern, "!"), tag) {
+				return false
+			}
+			continue
+		}
+		included = true
+		if glob.Glob(pattern, tag) {
+			matched = true
+		}
+	}
+	// only exclusions were given, everything else matches
+	if !included {
+		return true
+	}
+	return matched
 }
 
 func (p *GlobPolicy) Name() string     { return p.policy }
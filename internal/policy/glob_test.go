@@ -39,6 +39,41 @@ func TestGlobPolicy_ShouldUpdate(t *testing.T) {
 			want:    true,
 			wantErr: false,
 		},
+		{
+			name:    "test glob multiple patterns",
+			fields:  fields{pattern: "release-*, staging-*"},
+			args:    args{current: "release-1", new: "staging-2"},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "test glob excluded",
+			fields:  fields{pattern: "release-*,!*-hotfix-*"},
+			args:    args{current: "release-1", new: "release-1-hotfix-2"},
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "test glob not excluded",
+			fields:  fields{pattern: "release-*,!*-hotfix-*"},
+			args:    args{current: "release-1", new: "release-2"},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "test glob only exclusions",
+			fields:  fields{pattern: "!feature-*"},
+			args:    args{current: "latest", new: "master-3"},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "test glob no pattern matches",
+			fields:  fields{pattern: "release-*,!*-hotfix-*"},
+			args:    args{current: "release-1", new: "feature-1"},
+			want:    false,
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
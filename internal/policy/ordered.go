@@ -0,0 +1,88 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var numbersRegexp = regexp.MustCompile(`\d+`)
+
+// OrderedPolicy - updates only to tags that sort higher than the current tag,
+// for CI tags that aren't semver (20180601.3, build-1234). Tags are compared
+// lexicographically or by the numbers they contain, optional glob patterns
+// limit which tags are considered, ie: numeric:build-*
+type OrderedPolicy struct {
+	policy  string
+	pattern string
+	numeric bool
+}
+
+func NewOrderedPolicy(policy string) (*OrderedPolicy, error) {
+	parts := strings.SplitN(policy, ":", 2)
+	p := &OrderedPolicy{policy: policy}
+	switch parts[0] {
+	case "lexicographic":
+	case "numeric":
+		p.numeric = true
+	default:
+		return nil, fmt.Errorf("invalid ordered policy: %s", policy)
+	}
+	if len(parts) == 2 {
+		if parts[1] == "" {
+			return nil, fmt.Errorf("invalid ordered policy: %s", policy)
+		}
+		p.pattern = parts[1]
+	}
+	return p, nil
+}
+
+// ShouldUpdate - new tag has to match the pattern and sort higher than the current
+// tag, it prevents updates when older tag gets pushed again. Current tags that don't
+// match the pattern (or contain no numbers) can always be updated.
+func (p *OrderedPolicy) ShouldUpdate(current, new string) (bool, error) {
+	if !p.matches(new) {
+		return false, nil
+	}
+	if !p.matches(current) {
+		return true, nil
+	}
+
+	if !p.numeric {
+		return new > current, nil
+	}
+
+	newNumbers := numbersRegexp.FindAllString(new, -1)
+	if len(newNumbers) == 0 {
+		return false, nil
+	}
+	currentNumbers := numbersRegexp.FindAllString(current, -1)
+	if len(currentNumbers) == 0 {
+		return true, nil
+	}
+	for i := 0; i < len(newNumbers) && i < len(currentNumbers); i++ {
+		if c := compareTagParts(newNumbers[i], currentNumbers[i]); c != 0 {
+			return c > 0, nil
+		}
+	}
+	// 20180601.1 is higher than 20180601
+	return len(newNumbers) > len(currentNumbers), nil
+}
+
+func (p *OrderedPolicy) matches(tag string) bool {
+	if p.pattern == "" {
+		return true
+	}
+	return matchGlobs(p.pattern, tag)
+}
+
+// Ordered - poll trigger only updates to the highest tag
+func (p *OrderedPolicy) Ordered() bool { return true }
+
+func (p *OrderedPolicy) Name() string { return p.policy }
+func (p *OrderedPolicy) Type() PolicyType {
+	if p.numeric {
+		return PolicyTypeNumeric
+	}
+	return PolicyTypeLexicographic
+}
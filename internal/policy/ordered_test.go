@@ -0,0 +1,126 @@
+package policy
+
+import "testing"
+
+func TestOrderedPolicy_ShouldUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		current string
+		new     string
+		want    bool
+	}{
+		{
+			name:    "lexicographic higher",
+			policy:  "lexicographic",
+			current: "20180601.3",
+			new:     "20180602.1",
+			want:    true,
+		},
+		{
+			name:    "lexicographic older tag pushed again",
+			policy:  "lexicographic",
+			current: "20180602.1",
+			new:     "20180601.3",
+			want:    false,
+		},
+		{
+			name:    "lexicographic same tag",
+			policy:  "lexicographic",
+			current: "20180602.1",
+			new:     "20180602.1",
+			want:    false,
+		},
+		{
+			name:    "numeric compared by value",
+			policy:  "numeric",
+			current: "build-999",
+			new:     "build-1000",
+			want:    true,
+		},
+		{
+			name:    "numeric lower build",
+			policy:  "numeric",
+			current: "build-1000",
+			new:     "build-999",
+			want:    false,
+		},
+		{
+			name:    "numeric date with build number",
+			policy:  "numeric",
+			current: "20180601.9",
+			new:     "20180601.10",
+			want:    true,
+		},
+		{
+			name:    "numeric additional component",
+			policy:  "numeric",
+			current: "20180601",
+			new:     "20180601.1",
+			want:    true,
+		},
+		{
+			name:    "numeric without numbers",
+			policy:  "numeric",
+			current: "build-10",
+			new:     "latest",
+			want:    false,
+		},
+		{
+			name:    "numeric current without numbers",
+			policy:  "numeric",
+			current: "latest",
+			new:     "build-1",
+			want:    true,
+		},
+		{
+			name:    "pattern doesn't match",
+			policy:  "numeric:build-*",
+			current: "build-1",
+			new:     "pr-2",
+			want:    false,
+		},
+		{
+			name:    "pattern with exclusion",
+			policy:  "numeric:build-*,!*-debug",
+			current: "build-1",
+			new:     "build-2-debug",
+			want:    false,
+		},
+		{
+			name:    "current doesn't match pattern",
+			policy:  "numeric:build-*",
+			current: "pr-20",
+			new:     "build-2",
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewOrderedPolicy(tt.policy)
+			if err != nil {
+				t.Fatalf("failed to parse policy: %s", err)
+			}
+			got, err := p.ShouldUpdate(tt.current, tt.new)
+			if err != nil {
+				t.Errorf("OrderedPolicy.ShouldUpdate() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("OrderedPolicy.ShouldUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetOrderedPolicy(t *testing.T) {
+	if p := GetPolicy("numeric:build-*", &Options{}); p.Type() != PolicyTypeNumeric {
+		t.Errorf("unexpected policy: %s", p.Name())
+	}
+	if p := GetPolicy("lexicographic", &Options{}); p.Type() != PolicyTypeLexicographic {
+		t.Errorf("unexpected policy: %s", p.Name())
+	}
+	if p := GetPolicy("numeric:", &Options{}); p.Type() != PolicyTypeNone {
+		t.Errorf("expected invalid policy to be ignored, got: %s", p.Name())
+	}
+}
@@ -16,6 +16,11 @@ const (
 	PolicyTypeForce
 	PolicyTypeGlob
 	PolicyTypeRegexp
+	PolicyTypeLexicographic
+	PolicyTypeNumeric
+	PolicyTypeCombined
+	PolicyTypeExpr
+	PolicyTypeAllowlist
 )
 
 type Policy interface {
@@ -24,6 +29,26 @@ type Policy interface {
 	Type() PolicyType
 }
 
+// Metadata - registry metadata of the candidate image
+type Metadata struct {
+	Registry   string
+	Repository string
+	Digest     string
+}
+
+// MetadataPolicy - policies which also check registry metadata of the candidate image
+type MetadataPolicy interface {
+	ShouldUpdateWithMetadata(current, new string, metadata Metadata) (bool, error)
+}
+
+// ShouldUpdate - checks policy, with registry metadata when the policy uses it
+func ShouldUpdate(plc Policy, current, new string, metadata Metadata) (bool, error) {
+	if mp, ok := plc.(MetadataPolicy); ok {
+		return mp.ShouldUpdateWithMetadata(current, new, metadata)
+	}
+	return plc.ShouldUpdate(current, new)
+}
+
 type NilPolicy struct{}
 
 func (np *NilPolicy) ShouldUpdate(c, n string) (bool, error) { return false, nil }
@@ -35,7 +60,7 @@ func GetPolicyFromLabelsOrAnnotations(labels map[string]string, annotations map[
 
 	policyNameA, ok := getPolicyFromLabels(annotations)
 	if ok {
-		return GetPolicy(policyNameA, &Options{MatchTag: getMatchTag(annotations)})
+		return GetPolicy(policyNameA, &Options{MatchTag: getMatchTag(annotations), Labels: labels})
 	}
 
 	policyNameL, ok := getPolicyFromLabels(labels)
@@ -43,18 +68,51 @@ func GetPolicyFromLabelsOrAnnotations(labels map[string]string, annotations map[
 		return &NilPolicy{}
 	}
 
-	return GetPolicy(policyNameL, &Options{MatchTag: getMatchTag(labels)})
+	return GetPolicy(policyNameL, &Options{MatchTag: getMatchTag(labels), Labels: labels})
+}
+
+// GetContainerPolicy - container policy set through keel.sh/policy.<container name>
+// annotation, ok is false when container doesn't override resource policy
+func GetContainerPolicy(container string, labels map[string]string, annotations map[string]string) (plc Policy, ok bool) {
+	policyName, ok := annotations[types.KeelPolicyContainerAnnotationPrefix+container]
+	if !ok {
+		return nil, false
+	}
+	matchTag := getMatchTag(annotations) || getMatchTag(labels)
+	return GetPolicy(policyName, &Options{MatchTag: matchTag, Labels: labels}), true
 }
 
 // Options - additional options when parsing policy
 type Options struct {
 	MatchTag bool
+	// Labels - workload labels, available in expr policies
+	Labels map[string]string
 }
 
 // GetPolicy - policy getter used by Helm config
 func GetPolicy(policyName string, options *Options) Policy {
 
 	switch {
+	case strings.HasPrefix(policyName, "expr:"):
+		p, err := NewExprPolicy(policyName, options.Labels)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"policy": policyName,
+			}).Error("failed to parse expr policy, check your deployment configuration")
+			return &NilPolicy{}
+		}
+		return p
+	case isCombinedPolicy(policyName):
+		p, err := NewCombinedPolicy(policyName)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"policy": policyName,
+			}).Error("failed to parse combined policy, check your deployment configuration")
+			return &NilPolicy{}
+		}
+		return p
 	case strings.HasPrefix(policyName, "glob:"):
 		p, err := NewGlobPolicy(policyName)
 		if err != nil {
@@ -65,6 +123,26 @@ func GetPolicy(policyName string, options *Options) Policy {
 			return &NilPolicy{}
 		}
 		return p
+	case strings.HasPrefix(policyName, "semver:"):
+		p, err := NewSemverChannelPolicy(policyName)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"policy": policyName,
+			}).Error("failed to parse semver policy, check your deployment configuration")
+			return &NilPolicy{}
+		}
+		return p
+	case strings.HasPrefix(policyName, "allowlist:"):
+		p, err := NewAllowlistPolicy(policyName)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"policy": policyName,
+			}).Error("failed to parse allowlist policy, check your deployment configuration")
+			return &NilPolicy{}
+		}
+		return p
 	case strings.HasPrefix(policyName, "regexp:"):
 		p, err := NewRegexpPolicy(policyName)
 		if err != nil {
@@ -75,6 +153,17 @@ func GetPolicy(policyName string, options *Options) Policy {
 			return &NilPolicy{}
 		}
 		return p
+	case policyName == "lexicographic" || strings.HasPrefix(policyName, "lexicographic:"),
+		policyName == "numeric" || strings.HasPrefix(policyName, "numeric:"):
+		p, err := NewOrderedPolicy(policyName)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"policy": policyName,
+			}).Error("failed to parse ordered policy, check your deployment configuration")
+			return &NilPolicy{}
+		}
+		return p
 	}
 
 	switch policyName {
@@ -127,3 +127,22 @@ func TestGetPolicyFromLabelsOrAnnotations(t *testing.T) {
 		})
 	}
 }
+
+func TestGetContainerPolicy(t *testing.T) {
+	annotations := map[string]string{
+		types.KeelPolicyLabel: "minor",
+		types.KeelPolicyContainerAnnotationPrefix + "sidecar": "patch",
+	}
+
+	plc, ok := GetContainerPolicy("sidecar", nil, annotations)
+	if !ok {
+		t.Fatalf("expected sidecar policy override")
+	}
+	if !reflect.DeepEqual(plc, NewSemverPolicy(SemverPolicyTypePatch)) {
+		t.Errorf("unexpected sidecar policy: %v", plc)
+	}
+
+	if _, ok := GetContainerPolicy("app", nil, annotations); ok {
+		t.Errorf("app container shouldn't override resource policy")
+	}
+}
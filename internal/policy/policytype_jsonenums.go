@@ -9,19 +9,29 @@ import (
 
 var (
 	_PolicyTypeNameToValue = map[string]PolicyType{
-		"PolicyTypeNone":   PolicyTypeNone,
-		"PolicyTypeSemver": PolicyTypeSemver,
-		"PolicyTypeForce":  PolicyTypeForce,
-		"PolicyTypeGlob":   PolicyTypeGlob,
-		"PolicyTypeRegexp": PolicyTypeRegexp,
+		"PolicyTypeNone":          PolicyTypeNone,
+		"PolicyTypeSemver":        PolicyTypeSemver,
+		"PolicyTypeForce":         PolicyTypeForce,
+		"PolicyTypeGlob":          PolicyTypeGlob,
+		"PolicyTypeRegexp":        PolicyTypeRegexp,
+		"PolicyTypeLexicographic": PolicyTypeLexicographic,
+		"PolicyTypeNumeric":       PolicyTypeNumeric,
+		"PolicyTypeCombined":      PolicyTypeCombined,
+		"PolicyTypeExpr":          PolicyTypeExpr,
+		"PolicyTypeAllowlist":     PolicyTypeAllowlist,
 	}
 
 	_PolicyTypeValueToName = map[PolicyType]string{
-		PolicyTypeNone:   "PolicyTypeNone",
-		PolicyTypeSemver: "PolicyTypeSemver",
-		PolicyTypeForce:  "PolicyTypeForce",
-		PolicyTypeGlob:   "PolicyTypeGlob",
-		PolicyTypeRegexp: "PolicyTypeRegexp",
+		PolicyTypeNone:          "PolicyTypeNone",
+		PolicyTypeSemver:        "PolicyTypeSemver",
+		PolicyTypeForce:         "PolicyTypeForce",
+		PolicyTypeGlob:          "PolicyTypeGlob",
+		PolicyTypeRegexp:        "PolicyTypeRegexp",
+		PolicyTypeLexicographic: "PolicyTypeLexicographic",
+		PolicyTypeNumeric:       "PolicyTypeNumeric",
+		PolicyTypeCombined:      "PolicyTypeCombined",
+		PolicyTypeExpr:          "PolicyTypeExpr",
+		PolicyTypeAllowlist:     "PolicyTypeAllowlist",
 	}
 )
 
@@ -29,11 +39,16 @@ func init() {
 	var v PolicyType
 	if _, ok := interface{}(v).(fmt.Stringer); ok {
 		_PolicyTypeNameToValue = map[string]PolicyType{
-			interface{}(PolicyTypeNone).(fmt.Stringer).String():   PolicyTypeNone,
-			interface{}(PolicyTypeSemver).(fmt.Stringer).String(): PolicyTypeSemver,
-			interface{}(PolicyTypeForce).(fmt.Stringer).String():  PolicyTypeForce,
-			interface{}(PolicyTypeGlob).(fmt.Stringer).String():   PolicyTypeGlob,
-			interface{}(PolicyTypeRegexp).(fmt.Stringer).String(): PolicyTypeRegexp,
+			interface{}(PolicyTypeNone).(fmt.Stringer).String():          PolicyTypeNone,
+			interface{}(PolicyTypeSemver).(fmt.Stringer).String():        PolicyTypeSemver,
+			interface{}(PolicyTypeForce).(fmt.Stringer).String():         PolicyTypeForce,
+			interface{}(PolicyTypeGlob).(fmt.Stringer).String():          PolicyTypeGlob,
+			interface{}(PolicyTypeRegexp).(fmt.Stringer).String():        PolicyTypeRegexp,
+			interface{}(PolicyTypeLexicographic).(fmt.Stringer).String(): PolicyTypeLexicographic,
+			interface{}(PolicyTypeNumeric).(fmt.Stringer).String():       PolicyTypeNumeric,
+			interface{}(PolicyTypeCombined).(fmt.Stringer).String():      PolicyTypeCombined,
+			interface{}(PolicyTypeExpr).(fmt.Stringer).String():          PolicyTypeExpr,
+			interface{}(PolicyTypeAllowlist).(fmt.Stringer).String():     PolicyTypeAllowlist,
 		}
 	}
 }
@@ -6,18 +6,20 @@ import (
 	"strings"
 )
 
-// RegexpPolicy - regular expression based pattern
+// RegexpPolicy - regular expression based pattern, when the pattern has capture
+// groups tags are ordered by them, ie: regexp:^release-(\d{4})\.(\d{2})\.(\d{2})$
 type RegexpPolicy struct {
 	policy string
 	regexp *regexp.Regexp
 }
 
 func NewRegexpPolicy(policy string) (*RegexpPolicy, error) {
-	if strings.Contains(policy, ":") {
-		parts := strings.Split(policy, ":")
-		if len(parts) == 2 {
+	if strings.HasPrefix(policy, "regexp:") {
+		// pattern can contain colons, ie: (?:rc|beta)
+		pattern := strings.TrimPrefix(policy, "regexp:")
+		if pattern != "" {
 
-			rx, err := regexp.Compile(parts[1])
+			rx, err := regexp.Compile(pattern)
 			if err != nil {
 				return nil, fmt.Errorf("failed to parse regexp pattern, error: %s", err)
 			}
@@ -32,9 +34,64 @@ func NewRegexpPolicy(policy string) (*RegexpPolicy, error) {
 	return nil, fmt.Errorf("invalid regexp policy: %s", policy)
 }
 
+// ShouldUpdate - new tag has to match the pattern. With capture groups it also
+// has to be higher than the current tag, groups are compared in order, numbers
+// numerically. Current tags that don't match the pattern can always be updated.
 func (p *RegexpPolicy) ShouldUpdate(current, new string) (bool, error) {
-	return p.regexp.MatchString(new), nil
+	newParts := p.regexp.FindStringSubmatch(new)
+	if newParts == nil {
+		return false, nil
+	}
+	if !p.Ordered() {
+		return true, nil
+	}
+
+	currentParts := p.regexp.FindStringSubmatch(current)
+	if currentParts == nil {
+		return true, nil
+	}
+
+	for i := 1; i < len(newParts); i++ {
+		if c := compareTagParts(newParts[i], currentParts[i]); c != 0 {
+			return c > 0, nil
+		}
+	}
+	return false, nil
+}
+
+// Ordered - whether tags are ordered by capture groups, poll trigger then only
+// updates to the highest matching tag
+func (p *RegexpPolicy) Ordered() bool {
+	return p.regexp.NumSubexp() > 0
 }
 
 func (p *RegexpPolicy) Name() string     { return p.policy }
 func (p *RegexpPolicy) Type() PolicyType { return PolicyTypeRegexp }
+
+// compareTagParts - compares numbers by value (of any length) and other strings
+// lexicographically
+func compareTagParts(a, b string) int {
+	if isNumber(a) && isNumber(b) {
+		a = strings.TrimLeft(a, "0")
+		b = strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			if len(a) > len(b) {
+				return 1
+			}
+			return -1
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package policy
+
+import "testing"
+
+func TestRegexpPolicy_ShouldUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		current string
+		new     string
+		want    bool
+	}{
+		{
+			name:    "match without groups",
+			policy:  "regexp:^feature-.*",
+			current: "feature-b",
+			new:     "feature-a",
+			want:    true,
+		},
+		{
+			name:    "no match",
+			policy:  "regexp:^feature-.*",
+			current: "feature-a",
+			new:     "master-1",
+			want:    false,
+		},
+		{
+			name:    "newer date",
+			policy:  `regexp:^release-(\d{4})\.(\d{2})\.(\d{2})$`,
+			current: "release-2018.09.30",
+			new:     "release-2018.10.01",
+			want:    true,
+		},
+		{
+			name:    "older date",
+			policy:  `regexp:^release-(\d{4})\.(\d{2})\.(\d{2})$`,
+			current: "release-2018.10.01",
+			new:     "release-2018.09.30",
+			want:    false,
+		},
+		{
+			name:    "same tag",
+			policy:  `regexp:^release-(\d{4})\.(\d{2})\.(\d{2})$`,
+			current: "release-2018.10.01",
+			new:     "release-2018.10.01",
+			want:    false,
+		},
+		{
+			name:    "numbers compared by value",
+			policy:  `regexp:^build-(\d+)$`,
+			current: "build-99",
+			new:     "build-100",
+			want:    true,
+		},
+		{
+			name:    "current doesn't match",
+			policy:  `regexp:^build-(\d+)$`,
+			current: "latest",
+			new:     "build-1",
+			want:    true,
+		},
+		{
+			name:    "pattern with colons",
+			policy:  `regexp:^(?:rc|beta)-(\d+)$`,
+			current: "rc-1",
+			new:     "beta-2",
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewRegexpPolicy(tt.policy)
+			if err != nil {
+				t.Fatalf("failed to parse policy: %s", err)
+			}
+			got, err := p.ShouldUpdate(tt.current, tt.new)
+			if err != nil {
+				t.Errorf("RegexpPolicy.ShouldUpdate() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("RegexpPolicy.ShouldUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRegexpPolicyInvalid(t *testing.T) {
+	for _, policy := range []string{"regexp:", "regexp:[", "glob:*"} {
+		if _, err := NewRegexpPolicy(policy); err == nil {
+			t.Errorf("expected error for policy %q", policy)
+		}
+	}
+}
@@ -47,18 +47,51 @@ func NewSemverPolicy(spt SemverPolicyType) *SemverPolicy {
 	}
 }
 
+// NewSemverChannelPolicy - parses semver policy that follows a pre-release channel,
+// ie: semver:minor:rc tracks 1.4.0-rc.1, 1.4.0-rc.2 and releases but not 1.4.0-beta.1
+func NewSemverChannelPolicy(policy string) (*SemverPolicy, error) {
+	parts := strings.Split(policy, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "semver" {
+		return nil, fmt.Errorf("invalid semver policy: %s", policy)
+	}
+
+	sp, ok := ParseSemverPolicy(parts[1]).(*SemverPolicy)
+	if !ok {
+		return nil, fmt.Errorf("invalid semver policy type: %s", parts[1])
+	}
+	if len(parts) == 3 {
+		if parts[2] == "" || strings.Contains(parts[2], ".") {
+			return nil, fmt.Errorf("invalid semver pre-release channel: %s", parts[2])
+		}
+		sp.channel = parts[2]
+	}
+	return sp, nil
+}
+
 type SemverPolicy struct {
 	spt SemverPolicyType
+	// channel - pre-release channel, versions of other pre-releases are ignored
+	channel string
 }
 
 func (sp *SemverPolicy) ShouldUpdate(current, new string) (bool, error) {
+	if sp.channel != "" {
+		return shouldUpdateChannel(sp.spt, sp.channel, current, new)
+	}
 	return shouldUpdate(sp.spt, current, new)
 }
 
 func (sp *SemverPolicy) Name() string {
+	if sp.channel != "" {
+		return "semver:" + sp.spt.String() + ":" + sp.channel
+	}
 	return sp.spt.String()
 }
 
+// Ordered - versions of the channel have different pre-releases, poll trigger
+// only updates to the highest one
+func (sp *SemverPolicy) Ordered() bool { return sp.channel != "" }
+
 func (sp *SemverPolicy) Type() PolicyType { return PolicyTypeSemver }
 
 func shouldUpdate(spt SemverPolicyType, current, new string) (bool, error) {
@@ -100,3 +133,54 @@ func shouldUpdate(spt SemverPolicyType, current, new string) (bool, error) {
 	}
 	return false, nil
 }
+
+// inChannel - release versions and pre-releases of the channel, ie: rc, rc.1, rc1
+func inChannel(channel string, v *semver.Version) bool {
+	pre := v.Prerelease()
+	if pre == "" {
+		return true
+	}
+	first := strings.SplitN(pre, ".", 2)[0]
+	if first == channel {
+		return true
+	}
+	return strings.HasPrefix(first, channel) && isNumber(strings.TrimPrefix(first, channel))
+}
+
+func shouldUpdateChannel(spt SemverPolicyType, channel, current, new string) (bool, error) {
+	if len(strings.SplitN(new, ".", 3)) != 3 {
+		return false, ErrNoMajorMinorPatchElementsFound
+	}
+
+	newVersion, err := semver.NewVersion(new)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse new version: %s", err)
+	}
+	if !inChannel(channel, newVersion) {
+		return false, nil
+	}
+
+	if current == "latest" {
+		return true, nil
+	}
+
+	currentVersion, err := semver.NewVersion(current)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse current version: %s", err)
+	}
+
+	// pre-releases are ordered according to semver, 1.4.0-rc.2 < 1.4.0-rc.10 < 1.4.0
+	if !currentVersion.LessThan(newVersion) {
+		return false, nil
+	}
+
+	switch spt {
+	case SemverPolicyTypeAll, SemverPolicyTypeMajor:
+		return true, nil
+	case SemverPolicyTypeMinor:
+		return newVersion.Major() == currentVersion.Major(), nil
+	case SemverPolicyTypePatch:
+		return newVersion.Major() == currentVersion.Major() && newVersion.Minor() == currentVersion.Minor(), nil
+	}
+	return false, nil
+}
@@ -200,3 +200,125 @@ func Test_shouldUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestSemverChannelPolicy_ShouldUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		current string
+		new     string
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:    "next rc",
+			policy:  "semver:minor:rc",
+			current: "1.4.0-rc.1",
+			new:     "1.4.0-rc.2",
+			want:    true,
+		},
+		{
+			name:    "rc ordered numerically",
+			policy:  "semver:minor:rc",
+			current: "1.4.0-rc.9",
+			new:     "1.4.0-rc.10",
+			want:    true,
+		},
+		{
+			name:    "older rc",
+			policy:  "semver:minor:rc",
+			current: "1.4.0-rc.10",
+			new:     "1.4.0-rc.9",
+			want:    false,
+		},
+		{
+			name:    "beta ignored",
+			policy:  "semver:minor:rc",
+			current: "1.4.0-rc.1",
+			new:     "1.5.0-beta.1",
+			want:    false,
+		},
+		{
+			name:    "rc without separator",
+			policy:  "semver:minor:rc",
+			current: "1.4.0-rc1",
+			new:     "1.5.0-rc2",
+			want:    true,
+		},
+		{
+			name:    "release after rc",
+			policy:  "semver:minor:rc",
+			current: "1.4.0-rc.3",
+			new:     "1.4.0",
+			want:    true,
+		},
+		{
+			name:    "rc of the next minor from release",
+			policy:  "semver:minor:rc",
+			current: "1.4.0",
+			new:     "1.5.0-rc.1",
+			want:    true,
+		},
+		{
+			name:    "rc of the next major",
+			policy:  "semver:minor:rc",
+			current: "1.4.0-rc.1",
+			new:     "2.0.0-rc.1",
+			want:    false,
+		},
+		{
+			name:    "rc of the next major, policy major",
+			policy:  "semver:major:rc",
+			current: "1.4.0-rc.1",
+			new:     "2.0.0-rc.1",
+			want:    true,
+		},
+		{
+			name:    "not semver",
+			policy:  "semver:minor:rc",
+			current: "1.4.0-rc.1",
+			new:     "3050",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewSemverChannelPolicy(tt.policy)
+			if err != nil {
+				t.Fatalf("failed to parse policy: %s", err)
+			}
+			got, err := p.ShouldUpdate(tt.current, tt.new)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ShouldUpdate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ShouldUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSemverChannelPolicy(t *testing.T) {
+	p, err := NewSemverChannelPolicy("semver:patch")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p.Name() != "patch" || p.Ordered() {
+		t.Errorf("expected semver policy without channel, got: %s", p.Name())
+	}
+
+	p, err = NewSemverChannelPolicy("semver:minor:rc")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p.Name() != "semver:minor:rc" || !p.Ordered() {
+		t.Errorf("unexpected policy: %s", p.Name())
+	}
+
+	for _, policy := range []string{"semver:", "semver:force:rc", "semver:minor:", "semver:minor:rc.1"} {
+		if _, err := NewSemverChannelPolicy(policy); err == nil {
+			t.Errorf("expected error for policy %q", policy)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Package provenance checks that images carry an in-toto SLSA provenance
+// attestation from an allowed builder. Attestations are found with the OCI
+// referrers API, layers of the referrer manifests are either plain in-toto
+// statements, DSSE envelopes or sigstore bundles. Attestation signatures
+// aren't verified here, images should also require cosign signatures when
+// attestations can be pushed by anyone with write access to the repository.
+package provenance
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/keel-hq/keel/registry"
+
+	"github.com/ryanuber/go-glob"
+)
+
+// attestation layer media types
+const (
+	MediaTypeInToto         = "application/vnd.in-toto+json"
+	MediaTypeDSSE           = "application/vnd.dsse.envelope.v1+json"
+	MediaTypeSigstoreBundle = "application/vnd.dev.sigstore.bundle"
+)
+
+// SLSA provenance predicate types
+const (
+	PredicateSLSAv02 = "https://slsa.dev/provenance/v0.2"
+	PredicateSLSAv1  = "https://slsa.dev/provenance/v1"
+)
+
+// ErrNoAttestation - image doesn't have provenance attestations
+var ErrNoAttestation = errors.New("no provenance attestation found")
+
+// Registry - registry operations used to fetch attestations
+type Registry interface {
+	Referrers(opts registry.Opts, digest, artifactType string) ([]registry.Descriptor, error)
+	Manifest(opts registry.Opts) (*registry.Manifest, error)
+	Blob(opts registry.Opts, digest string) ([]byte, error)
+}
+
+// Verifier - checks provenance attestations of images
+type Verifier struct {
+	registry Registry
+	builders []string
+}
+
+// New - creates verifier, builders are builder IDs that can contain wildcards,
+// ie: https://github.com/actions/runner/*
+func New(reg Registry, builders []string) (*Verifier, error) {
+	if len(builders) == 0 {
+		return nil, fmt.Errorf("at least one allowed builder is required")
+	}
+	return &Verifier{registry: reg, builders: builders}, nil
+}
+
+// Attestation - provenance of the image
+type Attestation struct {
+	PredicateType string
+	Builder       string
+	BuildType     string
+	// Source - source repository of the build, when it's known
+	Source string
+}
+
+// Summary - short description used in notifications and approval messages
+func (a *Attestation) Summary() string {
+	version := strings.TrimPrefix(a.PredicateType, "https://slsa.dev/provenance/")
+	summary := fmt.Sprintf("SLSA provenance %s, built by %s", version, a.Builder)
+	if a.Source != "" {
+		summary += " from " + a.Source
+	}
+	return summary
+}
+
+// Verify - finds provenance attestation of the image digest from an allowed
+// builder, opts point to the image repository (Tag is ignored)
+func (v *Verifier) Verify(opts registry.Opts, digest string) (*Attestation, error) {
+	referrers, err := v.registry.Referrers(opts, digest, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get referrers: %s", err)
+	}
+
+	var found []string
+	for _, referrer := range referrers {
+		attestations, err := v.attestations(opts, referrer, digest)
+		if err != nil {
+			found = append(found, err.Error())
+			continue
+		}
+		for _, a := range attestations {
+			if v.allowed(a.Builder) {
+				return a, nil
+			}
+			found = append(found, fmt.Sprintf("builder %s is not allowed", a.Builder))
+		}
+	}
+	if len(found) == 0 {
+		return nil, ErrNoAttestation
+	}
+	return nil, fmt.Errorf("no provenance from allowed builders: %s", strings.Join(found, "; "))
+}
+
+func (v *Verifier) allowed(builder string) bool {
+	for _, pattern := range v.builders {
+		if glob.Glob(pattern, builder) {
+			return true
+		}
+	}
+	return false
+}
+
+// attestations - provenance attestations of the digest stored in the referrer manifest
+func (v *Verifier) attestations(opts registry.Opts, referrer registry.Descriptor, digest string) ([]*Attestation, error) {
+	manifestOpts := opts
+	manifestOpts.Tag = referrer.Digest
+	manifest, err := v.registry.Manifest(manifestOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get referrer %s: %s", referrer.Digest, err)
+	}
+
+	var attestations []*Attestation
+	for _, layer := range manifest.Layers {
+		if !isAttestation(layer.MediaType) {
+			continue
+		}
+		payload, err := v.registry.Blob(opts, layer.Digest)
+		if err != nil {
+			return nil, err
+		}
+		st, err := decodeStatement(layer.MediaType, payload)
+		if err != nil {
+			return nil, err
+		}
+		if a := st.provenance(digest); a != nil {
+			attestations = append(attestations, a)
+		}
+	}
+	return attestations, nil
+}
+
+func isAttestation(mediaType string) bool {
+	return mediaType == MediaTypeInToto || mediaType == MediaTypeDSSE || strings.HasPrefix(mediaType, MediaTypeSigstoreBundle)
+}
+
+type envelope struct {
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"`
+}
+
+type bundle struct {
+	DSSEEnvelope *envelope `json:"dsseEnvelope"`
+}
+
+type statement struct {
+	Subject []struct {
+		Name   string            `json:"name"`
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+// decodeStatement - unwraps in-toto statement from the attestation layer
+func decodeStatement(mediaType string, payload []byte) (*statement, error) {
+	if mediaType != MediaTypeInToto {
+		var env envelope
+		if mediaType == MediaTypeDSSE {
+			if err := json.Unmarshal(payload, &env); err != nil {
+				return nil, fmt.Errorf("failed to decode DSSE envelope: %s", err)
+			}
+		} else {
+			var b bundle
+			if err := json.Unmarshal(payload, &b); err != nil || b.DSSEEnvelope == nil {
+				return nil, fmt.Errorf("failed to decode sigstore bundle: %v", err)
+			}
+			env = *b.DSSEEnvelope
+		}
+		decoded, err := base64.StdEncoding.DecodeString(env.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode DSSE payload: %s", err)
+		}
+		payload = decoded
+	}
+
+	var st statement
+	if err := json.Unmarshal(payload, &st); err != nil {
+		return nil, fmt.Errorf("failed to decode in-toto statement: %s", err)
+	}
+	return &st, nil
+}
+
+type predicateV02 struct {
+	Builder struct {
+		ID string `json:"id"`
+	} `json:"builder"`
+	BuildType  string `json:"buildType"`
+	Invocation struct {
+		ConfigSource struct {
+			URI string `json:"uri"`
+		} `json:"configSource"`
+	} `json:"invocation"`
+}
+
+type predicateV1 struct {
+	BuildDefinition struct {
+		BuildType            string `json:"buildType"`
+		ResolvedDependencies []struct {
+			URI string `json:"uri"`
+		} `json:"resolvedDependencies"`
+	} `json:"buildDefinition"`
+	RunDetails struct {
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"`
+	} `json:"runDetails"`
+}
+
+// provenance - SLSA provenance of the statement, nil when it's a different
+// predicate or the digest isn't its subject
+func (st *statement) provenance(digest string) *Attestation {
+	if !st.hasSubject(digest) {
+		return nil
+	}
+
+	switch st.PredicateType {
+	case PredicateSLSAv02:
+		var p predicateV02
+		if err := json.Unmarshal(st.Predicate, &p); err != nil || p.Builder.ID == "" {
+			return nil
+		}
+		return &Attestation{
+			PredicateType: st.PredicateType,
+			Builder:       p.Builder.ID,
+			BuildType:     p.BuildType,
+			Source:        p.Invocation.ConfigSource.URI,
+		}
+	case PredicateSLSAv1:
+		var p predicateV1
+		if err := json.Unmarshal(st.Predicate, &p); err != nil || p.RunDetails.Builder.ID == "" {
+			return nil
+		}
+		a := &Attestation{
+			PredicateType: st.PredicateType,
+			Builder:       p.RunDetails.Builder.ID,
+			BuildType:     p.BuildDefinition.BuildType,
+		}
+		if len(p.BuildDefinition.ResolvedDependencies) > 0 {
+			a.Source = p.BuildDefinition.ResolvedDependencies[0].URI
+		}
+		return a
+	}
+	return nil
+}
+
+// hasSubject - sha256:abc matches subject with {"sha256": "abc"} digest
+func (st *statement) hasSubject(digest string) bool {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	for _, s := range st.Subject {
+		if s.Digest[parts[0]] == parts[1] {
+			return true
+		}
+	}
+	return false
+}
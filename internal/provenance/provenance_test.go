@@ -0,0 +1,127 @@
+package provenance
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/registry"
+)
+
+const (
+	testDigest  = "sha256:0a9c1e8c1d3e5a6b2c4d9f0b1e2a3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b"
+	testBuilder = "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_container_slsa3.yml@refs/tags/v1.9.0"
+)
+
+type fakeRegistry struct {
+	referrers map[string][]registry.Descriptor
+	manifests map[string]*registry.Manifest
+	blobs     map[string][]byte
+}
+
+func (r *fakeRegistry) Referrers(opts registry.Opts, digest, artifactType string) ([]registry.Descriptor, error) {
+	return r.referrers[digest], nil
+}
+
+func (r *fakeRegistry) Manifest(opts registry.Opts) (*registry.Manifest, error) {
+	m, ok := r.manifests[opts.Tag]
+	if !ok {
+		return nil, fmt.Errorf("manifest %s not found", opts.Tag)
+	}
+	return m, nil
+}
+
+func (r *fakeRegistry) Blob(opts registry.Opts, digest string) ([]byte, error) {
+	b, ok := r.blobs[digest]
+	if !ok {
+		return nil, fmt.Errorf("blob %s not found", digest)
+	}
+	return b, nil
+}
+
+func statementV1(digest, builder string) string {
+	return fmt.Sprintf(`{"_type":"https://in-toto.io/Statement/v1","subject":[{"name":"index.docker.io/karolisr/webhook-demo","digest":{"sha256":%q}}],"predicateType":"https://slsa.dev/provenance/v1","predicate":{"buildDefinition":{"buildType":"https://slsa-framework.github.io/github-actions-buildtypes/workflow/v1","resolvedDependencies":[{"uri":"git+https://github.com/keel-hq/keel@refs/heads/master"}]},"runDetails":{"builder":{"id":%q}}}}`,
+		strings.TrimPrefix(digest, "sha256:"), builder)
+}
+
+func dsse(statement string) []byte {
+	return []byte(fmt.Sprintf(`{"payloadType":"application/vnd.in-toto+json","payload":%q,"signatures":[{"sig":"c2ln"}]}`, base64.StdEncoding.EncodeToString([]byte(statement))))
+}
+
+// newRegistry - image with a single attestation referrer
+func newRegistry(mediaType string, layer []byte) *fakeRegistry {
+	return &fakeRegistry{
+		referrers: map[string][]registry.Descriptor{
+			testDigest: {{MediaType: registry.MediaTypeOCIManifest, ArtifactType: mediaType, Digest: "sha256:referrer"}},
+		},
+		manifests: map[string]*registry.Manifest{
+			"sha256:referrer": {Layers: []registry.Descriptor{{MediaType: mediaType, Digest: "sha256:layer"}}},
+		},
+		blobs: map[string][]byte{"sha256:layer": layer},
+	}
+}
+
+func TestVerify(t *testing.T) {
+	v, err := New(newRegistry(MediaTypeDSSE, dsse(statementV1(testDigest, testBuilder))), []string{"https://github.com/slsa-framework/slsa-github-generator/*"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	a, err := v.Verify(registry.Opts{Name: "karolisr/webhook-demo"}, testDigest)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if a.Builder != testBuilder || a.Source != "git+https://github.com/keel-hq/keel@refs/heads/master" {
+		t.Errorf("unexpected attestation: %+v", a)
+	}
+	if !strings.HasPrefix(a.Summary(), "SLSA provenance v1, built by https://github.com/slsa-framework/") {
+		t.Errorf("unexpected summary: %s", a.Summary())
+	}
+}
+
+func TestVerifyInTotoV02(t *testing.T) {
+	statement := fmt.Sprintf(`{"subject":[{"digest":{"sha256":%q}}],"predicateType":"https://slsa.dev/provenance/v0.2","predicate":{"builder":{"id":"https://cloudbuild.googleapis.com/GoogleHostedWorker"},"invocation":{"configSource":{"uri":"git+https://github.com/keel-hq/keel"}}}}`,
+		strings.TrimPrefix(testDigest, "sha256:"))
+	v, _ := New(newRegistry(MediaTypeInToto, []byte(statement)), []string{"https://cloudbuild.googleapis.com/*"})
+
+	a, err := v.Verify(registry.Opts{Name: "karolisr/webhook-demo"}, testDigest)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if a.Summary() != "SLSA provenance v0.2, built by https://cloudbuild.googleapis.com/GoogleHostedWorker from git+https://github.com/keel-hq/keel" {
+		t.Errorf("unexpected summary: %s", a.Summary())
+	}
+}
+
+func TestVerifyBuilderNotAllowed(t *testing.T) {
+	v, _ := New(newRegistry(MediaTypeDSSE, dsse(statementV1(testDigest, "https://evil.example.com/builder"))), []string{"https://github.com/slsa-framework/*"})
+
+	_, err := v.Verify(registry.Opts{Name: "karolisr/webhook-demo"}, testDigest)
+	if err == nil || !strings.Contains(err.Error(), "builder https://evil.example.com/builder is not allowed") {
+		t.Errorf("expected builder to be rejected, got: %v", err)
+	}
+}
+
+func TestVerifyOtherSubject(t *testing.T) {
+	other := "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	v, _ := New(newRegistry(MediaTypeDSSE, dsse(statementV1(other, testBuilder))), []string{"*"})
+
+	_, err := v.Verify(registry.Opts{Name: "karolisr/webhook-demo"}, testDigest)
+	if err != ErrNoAttestation {
+		t.Errorf("expected no attestation, got: %v", err)
+	}
+}
+
+func TestVerifyNoReferrers(t *testing.T) {
+	v, _ := New(&fakeRegistry{}, []string{"*"})
+
+	_, err := v.Verify(registry.Opts{Name: "karolisr/webhook-demo"}, testDigest)
+	if err != ErrNoAttestation {
+		t.Errorf("expected no attestation, got: %v", err)
+	}
+
+	if _, err := New(&fakeRegistry{}, nil); err == nil {
+		t.Errorf("expected error without builders")
+	}
+}
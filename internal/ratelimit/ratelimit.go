@@ -0,0 +1,346 @@
+// Package ratelimit - budgets of automatic updates. Updates over the global
+// or namespace budget are queued and applied in order once the budget allows,
+// protecting clusters from bursts of new tags.
+package ratelimit
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Budget - at most Max updates per Period, zero budget is unlimited
+type Budget struct {
+	Max    int
+	Period time.Duration
+}
+
+// Unlimited - whether budget doesn't limit updates
+func (b Budget) Unlimited() bool {
+	return b.Max <= 0 || b.Period <= 0
+}
+
+func (b Budget) String() string {
+	if b.Unlimited() {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d/%s", b.Max, b.Period)
+}
+
+// ParseBudget - parses <max>/<period> budget, ie: 5/10m
+func ParseBudget(s string) (Budget, error) {
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	if len(parts) != 2 {
+		return Budget{}, fmt.Errorf("invalid budget '%s', expected format: <max>/<period>, ie: 5/10m", s)
+	}
+	max, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || max < 1 {
+		return Budget{}, fmt.Errorf("invalid budget '%s', max has to be a positive number", s)
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || period <= 0 {
+		return Budget{}, fmt.Errorf("invalid budget '%s', period has to be a positive duration", s)
+	}
+	return Budget{Max: max, Period: period}, nil
+}
+
+// ParseNamespaceBudgets - parses comma separated <namespace>=<max>/<period>
+// budgets, ie: production=2/10m,staging=10/10m
+func ParseNamespaceBudgets(s string) (map[string]Budget, error) {
+	budgets := make(map[string]Budget)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid namespace budget '%s', expected format: <namespace>=<max>/<period>", entry)
+		}
+		b, err := ParseBudget(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		budgets[strings.TrimSpace(kv[0])] = b
+	}
+	return budgets, nil
+}
+
+// Opts - limiter budgets
+type Opts struct {
+	// Global - budget of all updates
+	Global Budget
+	// Namespace - default budget of each namespace
+	Namespace Budget
+	// Namespaces - budgets of specific namespaces, override the default
+	Namespaces map[string]Budget
+}
+
+// Update - queued update
+type Update struct {
+	Provider       string    `json:"provider"`
+	Namespace      string    `json:"namespace"`
+	Kind           string    `json:"kind"`
+	Identifier     string    `json:"identifier"`
+	CurrentVersion string    `json:"currentVersion"`
+	NewVersion     string    `json:"newVersion"`
+	QueuedAt       time.Time `json:"queuedAt"`
+}
+
+type queued struct {
+	update Update
+	apply  func()
+}
+
+// Usage - updates within the current period of a budget
+type Usage struct {
+	Max    int    `json:"max"`
+	Period string `json:"period"`
+	Used   int    `json:"used"`
+}
+
+func usage(b Budget, used int) Usage {
+	if b.Unlimited() {
+		return Usage{Used: used}
+	}
+	return Usage{Max: b.Max, Period: b.Period.String(), Used: used}
+}
+
+// Status - budgets usage and queued updates
+type Status struct {
+	Global     Usage            `json:"global"`
+	Namespaces map[string]Usage `json:"namespaces"`
+	Queued     []Update         `json:"queued"`
+}
+
+// Limiter - tracks updates of the budgets, safe for concurrent use
+type Limiter struct {
+	opts Opts
+
+	mu sync.Mutex
+	// applied - when updates were applied, all and by namespace
+	applied   []time.Time
+	namespace map[string][]time.Time
+	queue     []*queued
+
+	now func() time.Time
+}
+
+// New - creates limiter, nil when no budget is configured
+func New(opts Opts) *Limiter {
+	limited := !opts.Global.Unlimited() || !opts.Namespace.Unlimited()
+	for _, b := range opts.Namespaces {
+		limited = limited || !b.Unlimited()
+	}
+	if !limited {
+		return nil
+	}
+
+	return &Limiter{
+		opts:      opts,
+		namespace: make(map[string][]time.Time),
+		now:       time.Now,
+	}
+}
+
+func (l *Limiter) namespaceBudget(namespace string) Budget {
+	if b, ok := l.opts.Namespaces[namespace]; ok {
+		return b
+	}
+	return l.opts.Namespace
+}
+
+// used - prunes applied updates older than the budget period and returns
+// how many remain
+func (l *Limiter) used(applied []time.Time, budget Budget, now time.Time) ([]time.Time, int) {
+	if budget.Unlimited() {
+		return applied, len(applied)
+	}
+	i := sort.Search(len(applied), func(i int) bool {
+		return now.Sub(applied[i]) < budget.Period
+	})
+	applied = applied[i:]
+	return applied, len(applied)
+}
+
+// allowedLocked - whether update of the namespace fits both budgets
+func (l *Limiter) allowedLocked(namespace string, now time.Time) bool {
+	var used int
+	if !l.opts.Global.Unlimited() {
+		l.applied, used = l.used(l.applied, l.opts.Global, now)
+		if used >= l.opts.Global.Max {
+			return false
+		}
+	}
+
+	budget := l.namespaceBudget(namespace)
+	if !budget.Unlimited() {
+		l.namespace[namespace], used = l.used(l.namespace[namespace], budget, now)
+		if used >= budget.Max {
+			return false
+		}
+	}
+	return true
+}
+
+// recordLocked - records applied update, only limited budgets are tracked
+func (l *Limiter) recordLocked(namespace string, now time.Time) {
+	if !l.opts.Global.Unlimited() {
+		l.applied = append(l.applied, now)
+	}
+	if !l.namespaceBudget(namespace).Unlimited() {
+		l.namespace[namespace] = append(l.namespace[namespace], now)
+	}
+}
+
+// Allow - records update if it fits the budgets. Updates of namespaces with
+// queued updates are not allowed so that the queue is applied in order.
+func (l *Limiter) Allow(namespace string) bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.drainLocked(now)
+
+	for _, q := range l.queue {
+		if q.update.Namespace == namespace {
+			return false
+		}
+	}
+	if !l.allowedLocked(namespace, now) {
+		return false
+	}
+	l.recordLocked(namespace, now)
+	return true
+}
+
+// Enqueue - queues update, apply is called once the budgets allow it. Queued
+// update of the same resource is replaced, only the newest version is applied.
+func (l *Limiter) Enqueue(update Update, apply func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if update.QueuedAt.IsZero() {
+		update.QueuedAt = l.now()
+	}
+
+	for _, q := range l.queue {
+		if q.update.Provider == update.Provider && q.update.Identifier == update.Identifier {
+			update.QueuedAt = q.update.QueuedAt
+			q.update = update
+			q.apply = apply
+			return
+		}
+	}
+	l.queue = append(l.queue, &queued{update: update, apply: apply})
+}
+
+// Drain - applies queued updates that fit the budgets, oldest first
+func (l *Limiter) Drain() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.drainLocked(l.now())
+}
+
+func (l *Limiter) drainLocked(now time.Time) {
+	var remaining []*queued
+	// namespaces with waiting updates, later updates of the namespace wait too
+	blocked := make(map[string]bool)
+	for _, q := range l.queue {
+		ns := q.update.Namespace
+		if blocked[ns] || !l.allowedLocked(ns, now) {
+			blocked[ns] = true
+			remaining = append(remaining, q)
+			continue
+		}
+		l.recordLocked(ns, now)
+		go q.apply()
+	}
+	l.queue = remaining
+}
+
+// Run - drains the queue periodically until stop is closed
+func (l *Limiter) Run(interval time.Duration, stop <-chan struct{}) {
+	if l == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.Drain()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Queued - queued updates, oldest first
+func (l *Limiter) Queued() []Update {
+	if l == nil {
+		return []Update{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	updates := make([]Update, 0, len(l.queue))
+	for _, q := range l.queue {
+		updates = append(updates, q.update)
+	}
+	return updates
+}
+
+// Status - usage of the configured budgets and queued updates
+func (l *Limiter) Status() *Status {
+	if l == nil {
+		return &Status{Namespaces: map[string]Usage{}, Queued: []Update{}}
+	}
+
+	l.mu.Lock()
+	now := l.now()
+	status := &Status{
+		Namespaces: make(map[string]Usage),
+		Queued:     make([]Update, 0, len(l.queue)),
+	}
+
+	var used int
+	l.applied, used = l.used(l.applied, l.opts.Global, now)
+	status.Global = usage(l.opts.Global, used)
+
+	namespaces := make(map[string]bool)
+	for ns := range l.opts.Namespaces {
+		namespaces[ns] = true
+	}
+	for ns := range l.namespace {
+		namespaces[ns] = true
+	}
+	for _, q := range l.queue {
+		namespaces[q.update.Namespace] = true
+		status.Queued = append(status.Queued, q.update)
+	}
+	for ns := range namespaces {
+		budget := l.namespaceBudget(ns)
+		l.namespace[ns], used = l.used(l.namespace[ns], budget, now)
+		if used == 0 {
+			delete(l.namespace, ns)
+		}
+		status.Namespaces[ns] = usage(budget, used)
+	}
+	l.mu.Unlock()
+
+	return status
+}
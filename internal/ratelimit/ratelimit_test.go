@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestLimiter(opts Opts) (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	l := New(opts)
+	l.now = clock.Now
+	return l, clock
+}
+
+func TestParseBudget(t *testing.T) {
+	b, err := ParseBudget("5/10m")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if b.Max != 5 || b.Period != 10*time.Minute {
+		t.Errorf("unexpected budget: %+v", b)
+	}
+
+	for _, invalid := range []string{"5", "0/10m", "five/10m", "5/soon", "5/-1m"} {
+		if _, err := ParseBudget(invalid); err == nil {
+			t.Errorf("expected '%s' to be invalid", invalid)
+		}
+	}
+
+	budgets, err := ParseNamespaceBudgets("production=2/10m, staging=10/1h")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if budgets["production"].Max != 2 || budgets["staging"].Period != time.Hour {
+		t.Errorf("unexpected budgets: %+v", budgets)
+	}
+	if _, err := ParseNamespaceBudgets("production"); err == nil {
+		t.Errorf("expected namespace budget without budget to be invalid")
+	}
+}
+
+func TestNewUnlimited(t *testing.T) {
+	l := New(Opts{})
+	if l != nil {
+		t.Fatalf("expected no limiter without budgets")
+	}
+	// nil limiter allows everything
+	if !l.Allow("default") {
+		t.Errorf("expected nil limiter to allow updates")
+	}
+	if len(l.Queued()) != 0 {
+		t.Errorf("expected nil limiter to have no queue")
+	}
+}
+
+func TestGlobalBudget(t *testing.T) {
+	l, clock := newTestLimiter(Opts{Global: Budget{Max: 2, Period: 10 * time.Minute}})
+
+	if !l.Allow("default") || !l.Allow("staging") {
+		t.Fatalf("expected updates within budget to be allowed")
+	}
+	if l.Allow("production") {
+		t.Fatalf("expected update over budget to be rejected")
+	}
+
+	status := l.Status()
+	if status.Global.Used != 2 || status.Global.Max != 2 || status.Global.Period != "10m0s" {
+		t.Errorf("unexpected global usage: %+v", status.Global)
+	}
+
+	clock.Add(10 * time.Minute)
+	if !l.Allow("production") {
+		t.Errorf("expected update to be allowed once the period passed")
+	}
+}
+
+func TestQueueDrain(t *testing.T) {
+	l, clock := newTestLimiter(Opts{
+		Namespace:  Budget{Max: 1, Period: time.Minute},
+		Namespaces: map[string]Budget{"staging": {Max: 10, Period: time.Minute}},
+	})
+
+	applied := make(chan string, 10)
+	enqueue := func(identifier, version string) {
+		l.Enqueue(Update{Provider: "kubernetes", Namespace: "default", Identifier: identifier, NewVersion: version}, func() {
+			applied <- identifier + ":" + version
+		})
+	}
+
+	if !l.Allow("default") {
+		t.Fatalf("expected first update to be allowed")
+	}
+	if l.Allow("default") {
+		t.Fatalf("expected second update of the namespace to be rejected")
+	}
+	enqueue("deployment/default/first", "1.0.0")
+	enqueue("deployment/default/second", "1.0.0")
+	// newer version replaces the queued one and keeps its position
+	enqueue("deployment/default/first", "1.1.0")
+
+	// other namespaces aren't affected
+	if !l.Allow("staging") || !l.Allow("staging") {
+		t.Errorf("expected updates of namespace with its own budget to be allowed")
+	}
+
+	queued := l.Queued()
+	if len(queued) != 2 || queued[0].Identifier != "deployment/default/first" || queued[0].NewVersion != "1.1.0" {
+		t.Fatalf("unexpected queue: %+v", queued)
+	}
+
+	l.Drain()
+	select {
+	case u := <-applied:
+		t.Fatalf("expected queued updates to wait for the budget, applied: %s", u)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Add(time.Minute)
+	// queued updates go first, new update of the namespace has to wait
+	if l.Allow("default") {
+		t.Errorf("expected new update to wait for the queued updates")
+	}
+	if u := <-applied; u != "deployment/default/first:1.1.0" {
+		t.Errorf("unexpected update applied: %s", u)
+	}
+	if len(l.Queued()) != 1 {
+		t.Errorf("expected one queued update, got: %d", len(l.Queued()))
+	}
+
+	clock.Add(time.Minute)
+	l.Drain()
+	if u := <-applied; u != "deployment/default/second:1.0.0" {
+		t.Errorf("unexpected update applied: %s", u)
+	}
+	if len(l.Queued()) != 0 {
+		t.Errorf("expected empty queue, got: %d", len(l.Queued()))
+	}
+}
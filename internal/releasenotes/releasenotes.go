@@ -0,0 +1,246 @@
+// Package releasenotes fetches release notes of new image versions: the GitHub
+// release of the tag, or the image description when there's no release. A
+// truncated version is added to approval requests and update notifications.
+package releasenotes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/keel-hq/keel/registry"
+)
+
+// OCI image labels
+const (
+	// SourceLabel - URL of the image source, GitHub repository of the release
+	// when the resource doesn't set it
+	SourceLabel = "org.opencontainers.image.source"
+	// DescriptionLabel - used when the tag has no GitHub release
+	DescriptionLabel = "org.opencontainers.image.description"
+)
+
+const (
+	// DefaultMaxLength - release notes are truncated to it
+	DefaultMaxLength = 500
+
+	// GithubAPI - default API address
+	GithubAPI = "https://api.github.com"
+
+	timeout = 10 * time.Second
+	// cacheTTL - events of the same version (ie: several resources using the
+	// image) don't fetch release notes again
+	cacheTTL = time.Hour
+)
+
+// ConfigGetter - reads image configs, labels are used to find the source
+// repository and description
+type ConfigGetter interface {
+	Config(opts registry.Opts) (*registry.ImageConfig, error)
+}
+
+// Opts - fetcher options
+type Opts struct {
+	// Registry - optional, image labels aren't used without it
+	Registry ConfigGetter
+	// GithubAPIURL - defaults to GithubAPI, ie: https://github.example.com/api/v3
+	GithubAPIURL string
+	// GithubToken - optional, unauthenticated requests are rate limited and
+	// can't read private repositories
+	GithubToken string
+	// MaxLength - defaults to DefaultMaxLength
+	MaxLength int
+
+	Client *http.Client
+}
+
+// Fetcher - fetches and caches release notes
+type Fetcher struct {
+	registry  ConfigGetter
+	apiURL    string
+	token     string
+	maxLength int
+	client    *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cached
+}
+
+type cached struct {
+	notes   string
+	expires time.Time
+}
+
+// New - creates fetcher
+func New(opts *Opts) *Fetcher {
+	f := &Fetcher{
+		registry:  opts.Registry,
+		apiURL:    strings.TrimSuffix(opts.GithubAPIURL, "/"),
+		token:     opts.GithubToken,
+		maxLength: opts.MaxLength,
+		client:    opts.Client,
+		cache:     make(map[string]cached),
+	}
+	if f.apiURL == "" {
+		f.apiURL = GithubAPI
+	}
+	if f.maxLength <= 0 {
+		f.maxLength = DefaultMaxLength
+	}
+	if f.client == nil {
+		f.client = &http.Client{Timeout: timeout}
+	}
+	return f
+}
+
+// Fetch - truncated release notes of the image tag. Repository (owner/repo)
+// is optional, it defaults to the image source label. Empty when the tag has
+// neither a GitHub release nor an image description.
+func (f *Fetcher) Fetch(repository string, opts registry.Opts) (string, error) {
+	key := repository + " " + opts.Registry + "/" + opts.Name + ":" + opts.Tag
+
+	now := time.Now()
+	f.mu.Lock()
+	c, ok := f.cache[key]
+	f.mu.Unlock()
+	if ok && now.Before(c.expires) {
+		return c.notes, nil
+	}
+
+	notes, err := f.fetch(repository, opts)
+	if err != nil {
+		return "", err
+	}
+	notes = Truncate(notes, f.maxLength)
+
+	f.mu.Lock()
+	for k, c := range f.cache {
+		if now.After(c.expires) {
+			delete(f.cache, k)
+		}
+	}
+	f.cache[key] = cached{notes: notes, expires: now.Add(cacheTTL)}
+	f.mu.Unlock()
+	return notes, nil
+}
+
+func (f *Fetcher) fetch(repository string, opts registry.Opts) (string, error) {
+	var labels map[string]string
+	labelsLoaded := false
+	loadLabels := func() error {
+		labelsLoaded = true
+		if f.registry == nil {
+			return nil
+		}
+		config, err := f.registry.Config(opts)
+		if err != nil {
+			return fmt.Errorf("failed to read image config: %s", err)
+		}
+		labels = config.Labels
+		return nil
+	}
+
+	if repository == "" {
+		if err := loadLabels(); err != nil {
+			return "", err
+		}
+		repository = GithubRepository(labels[SourceLabel])
+	}
+
+	if repository != "" {
+		notes, err := f.githubRelease(repository, opts.Tag)
+		if err != nil {
+			return "", err
+		}
+		if notes != "" {
+			return notes, nil
+		}
+	}
+
+	if !labelsLoaded {
+		if err := loadLabels(); err != nil {
+			return "", err
+		}
+	}
+	return labels[DescriptionLabel], nil
+}
+
+type githubRelease struct {
+	Name string `json:"name"`
+	Body string `json:"body"`
+}
+
+// githubRelease - body of the release of the tag, tags without the v prefix
+// also match v-prefixed releases (ie: 1.2.3 and v1.2.3). Empty when there's
+// no release.
+func (f *Fetcher) githubRelease(repository, tag string) (string, error) {
+	tags := []string{tag}
+	if !strings.HasPrefix(tag, "v") {
+		tags = append(tags, "v"+tag)
+	}
+
+	for _, t := range tags {
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s/repos/%s/releases/tags/%s", f.apiURL, repository, url.PathEscape(t)), nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if f.token != "" {
+			req.Header.Set("Authorization", "token "+f.token)
+		}
+
+		resp, err := f.client.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("github: %s", err)
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return "", fmt.Errorf("github: got status %d for release %s of %s", resp.StatusCode, t, repository)
+		}
+
+		var release githubRelease
+		err = json.NewDecoder(resp.Body).Decode(&release)
+		resp.Body.Close()
+		if err != nil {
+			return "", fmt.Errorf("github: failed to decode release: %s", err)
+		}
+		if strings.TrimSpace(release.Body) != "" {
+			return release.Body, nil
+		}
+		return release.Name, nil
+	}
+	return "", nil
+}
+
+// GithubRepository - owner/repo of a GitHub source URL (ie:
+// https://github.com/keel-hq/keel.git), empty for other hosts
+func GithubRepository(source string) string {
+	u, err := url.Parse(source)
+	if err != nil || u.Host != "github.com" {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return ""
+	}
+	return parts[0] + "/" + strings.TrimSuffix(parts[1], ".git")
+}
+
+// Truncate - trims whitespace and cuts notes longer than max characters
+func Truncate(notes string, max int) string {
+	notes = strings.TrimSpace(strings.Replace(notes, "\r\n", "\n", -1))
+	if utf8.RuneCountInString(notes) <= max {
+		return notes
+	}
+	runes := []rune(notes)
+	return strings.TrimSpace(string(runes[:max])) + "…"
+}
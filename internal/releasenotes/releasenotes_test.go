@@ -0,0 +1,110 @@
+package releasenotes
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/registry"
+)
+
+type fakeRegistry struct {
+	labels map[string]string
+	calls  int
+}
+
+func (r *fakeRegistry) Config(opts registry.Opts) (*registry.ImageConfig, error) {
+	r.calls++
+	return &registry.ImageConfig{Labels: r.labels}, nil
+}
+
+func newGithub(t *testing.T, releases map[string]string) (*httptest.Server, *int) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, ok := releases[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"name": "release", "body": %q}`, body)
+	}))
+	return srv, &requests
+}
+
+func TestFetchGithubRelease(t *testing.T) {
+	srv, requests := newGithub(t, map[string]string{
+		"/repos/keel-hq/keel/releases/tags/v1.2.3": "## Changes\r\n\r\n* fixed approvals",
+	})
+	defer srv.Close()
+
+	reg := &fakeRegistry{labels: map[string]string{SourceLabel: "https://github.com/keel-hq/keel.git"}}
+	f := New(&Opts{Registry: reg, GithubAPIURL: srv.URL, GithubToken: "secret"})
+	opts := registry.Opts{Registry: "https://index.docker.io", Name: "keelhq/keel", Tag: "1.2.3"}
+
+	notes, err := f.Fetch("", opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if notes != "## Changes\n\n* fixed approvals" {
+		t.Errorf("unexpected notes: %q", notes)
+	}
+	// tag without and with the v prefix
+	if *requests != 2 {
+		t.Errorf("expected 2 requests, got: %d", *requests)
+	}
+
+	if _, err := f.Fetch("", opts); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if *requests != 2 || reg.calls != 1 {
+		t.Errorf("expected cached notes, got %d requests and %d config reads", *requests, reg.calls)
+	}
+}
+
+func TestFetchFallsBackToDescription(t *testing.T) {
+	srv, _ := newGithub(t, nil)
+	defer srv.Close()
+
+	reg := &fakeRegistry{labels: map[string]string{DescriptionLabel: strings.Repeat("a", 20)}}
+	f := New(&Opts{Registry: reg, GithubAPIURL: srv.URL, GithubToken: "secret", MaxLength: 10})
+
+	notes, err := f.Fetch("keel-hq/keel", registry.Opts{Name: "keelhq/keel", Tag: "1.2.3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if notes != strings.Repeat("a", 10)+"…" {
+		t.Errorf("unexpected notes: %q", notes)
+	}
+}
+
+func TestFetchGithubError(t *testing.T) {
+	srv, _ := newGithub(t, nil)
+	defer srv.Close()
+
+	f := New(&Opts{GithubAPIURL: srv.URL})
+	if _, err := f.Fetch("keel-hq/keel", registry.Opts{Tag: "1.2.3"}); err == nil {
+		t.Errorf("expected error when GitHub rejects the token")
+	}
+}
+
+func TestGithubRepository(t *testing.T) {
+	for source, want := range map[string]string{
+		"https://github.com/keel-hq/keel":          "keel-hq/keel",
+		"https://github.com/keel-hq/keel.git":      "keel-hq/keel",
+		"https://github.com/keel-hq/keel/tree/foo": "keel-hq/keel",
+		"https://gitlab.com/keel-hq/keel":          "",
+		"https://github.com/keel-hq":               "",
+		"":                                         "",
+	} {
+		if got := GithubRepository(source); got != want {
+			t.Errorf("GithubRepository(%q) = %q, want %q", source, got, want)
+		}
+	}
+}
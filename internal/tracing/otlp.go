@@ -0,0 +1,254 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultServiceName   = "keel"
+	defaultFlushInterval = 5 * time.Second
+	maxBatchSize         = 256
+	queueSize            = 2048
+)
+
+// OTLP span kind and status codes
+const (
+	spanKindInternal = 1
+	statusCodeOK     = 1
+	statusCodeError  = 2
+)
+
+// ExporterOpts - OTLP exporter configuration
+type ExporterOpts struct {
+	// Endpoint - OTLP/HTTP collector base URL, ie: http://otel-collector:4318,
+	// spans are sent to <endpoint>/v1/traces
+	Endpoint string
+	// Headers - additional request headers, ie: authentication for hosted collectors
+	Headers     map[string]string
+	ServiceName string
+
+	FlushInterval time.Duration
+	Client        *http.Client
+}
+
+// Exporter - batches ended spans and sends them to the collector
+type Exporter struct {
+	opts  ExporterOpts
+	queue chan *Span
+	stop  chan chan struct{}
+}
+
+// NewExporter - creates and starts OTLP/HTTP exporter
+func NewExporter(opts ExporterOpts) *Exporter {
+	opts.Endpoint = strings.TrimSuffix(opts.Endpoint, "/")
+	if opts.ServiceName == "" {
+		opts.ServiceName = defaultServiceName
+	}
+	if opts.FlushInterval == 0 {
+		opts.FlushInterval = defaultFlushInterval
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	e := &Exporter{
+		opts:  opts,
+		queue: make(chan *Span, queueSize),
+		stop:  make(chan chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// ParseHeaders - parses OTEL_EXPORTER_OTLP_HEADERS format (key1=value1,key2=value2)
+func ParseHeaders(value string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			continue
+		}
+		headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return headers
+}
+
+// Stop - exports queued spans and stops the exporter
+func (e *Exporter) Stop() {
+	done := make(chan struct{})
+	e.stop <- done
+	<-done
+}
+
+func (e *Exporter) enqueue(s *Span) {
+	select {
+	case e.queue <- s:
+	default:
+		log.Debug("tracing: export queue is full, dropping span")
+	}
+}
+
+func (e *Exporter) run() {
+	ticker := time.NewTicker(e.opts.FlushInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.export(batch); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"spans": len(batch),
+			}).Warn("tracing: failed to export spans")
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case s := <-e.queue:
+			batch = append(batch, s)
+			if len(batch) >= maxBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case done := <-e.stop:
+			for len(e.queue) > 0 {
+				batch = append(batch, <-e.queue)
+			}
+			flush()
+			close(done)
+			return
+		}
+	}
+}
+
+type otlpKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func keyValues(attributes map[string]string) []otlpKeyValue {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		kv := otlpKeyValue{Key: k}
+		kv.Value.StringValue = attributes[k]
+		kvs = append(kvs, kv)
+	}
+	return kvs
+}
+
+func toOTLP(s *Span) otlpSpan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	span := otlpSpan{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Attributes:        keyValues(s.attributes),
+		Status:            otlpStatus{Code: statusCodeOK},
+	}
+	if s.parentID != [8]byte{} {
+		span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	if s.err != nil {
+		span.Status = otlpStatus{Code: statusCodeError, Message: s.err.Error()}
+	}
+	return span
+}
+
+func (e *Exporter) export(spans []*Span) error {
+	scope := otlpScopeSpans{}
+	scope.Scope.Name = "github.com/keel-hq/keel"
+	for _, s := range spans {
+		scope.Spans = append(scope.Spans, toOTLP(s))
+	}
+
+	rs := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{scope}}
+	rs.Resource.Attributes = keyValues(map[string]string{"service.name": e.opts.ServiceName})
+
+	body, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{rs}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", e.opts.Endpoint+"/v1/traces", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.opts.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// Package tracing - lightweight tracing of the update pipeline (trigger, policy,
+// approvals, provider API calls). Spans are exported with the OTLP/HTTP JSON
+// protocol so any OpenTelemetry collector can receive them. Trace context is
+// passed between the stages in W3C traceparent format, events carry it in
+// types.Event.TraceParent so traces survive approval waits.
+//
+// The OpenTelemetry Go SDK and its OTLP exporters are not used because they
+// require google.golang.org/grpc 1.6x and google.golang.org/protobuf, while the
+// vendored pubsub, helm and keel's own gRPC API are pinned to grpc 1.21 and
+// github.com/golang/protobuf 1.3. Once those are upgraded the exporter in
+// otlp.go should be replaced with otlptracehttp.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	exporterMu sync.RWMutex
+	exporter   *Exporter
+)
+
+// SetExporter - enables tracing, spans are recorded only when exporter is set
+func SetExporter(e *Exporter) {
+	exporterMu.Lock()
+	exporter = e
+	exporterMu.Unlock()
+}
+
+func currentExporter() *Exporter {
+	exporterMu.RLock()
+	defer exporterMu.RUnlock()
+	return exporter
+}
+
+// Span - single traced operation. Start returns nil when tracing is disabled,
+// all span methods are safe to call on nil spans
+type Span struct {
+	mu sync.Mutex
+
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+
+	name       string
+	start      time.Time
+	end        time.Time
+	attributes map[string]string
+	err        error
+
+	exporter *Exporter
+}
+
+// Start - starts a span, parent is a traceparent of another span. New trace
+// is started when parent is empty or invalid
+func Start(parent, name string) *Span {
+	return StartAt(parent, name, time.Now())
+}
+
+// StartAt - starts a span at the given time, used for stages that are only
+// known once they are over, ie: waiting for approvals
+func StartAt(parent, name string, start time.Time) *Span {
+	e := currentExporter()
+	if e == nil {
+		return nil
+	}
+
+	s := &Span{
+		name:       name,
+		start:      start,
+		attributes: make(map[string]string),
+		exporter:   e,
+	}
+	if traceID, spanID, ok := ParseTraceParent(parent); ok {
+		s.traceID = traceID
+		s.parentID = spanID
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	rand.Read(s.spanID[:])
+	return s
+}
+
+// SetAttribute - sets span attribute, empty values are ignored
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil || value == "" {
+		return
+	}
+	s.mu.Lock()
+	s.attributes[key] = value
+	s.mu.Unlock()
+}
+
+// SetError - marks span as failed, nil errors are ignored
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// End - finishes the span and queues it for export, spans can only be
+// ended once
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	s.exporter.enqueue(s)
+}
+
+// TraceParent - W3C traceparent of the span, child spans are started with it
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:]))
+}
+
+// TraceID - hex encoded trace ID
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// ParseTraceParent - parses W3C traceparent (version-traceid-spanid-flags)
+func ParseTraceParent(value string) (traceID [16]byte, spanID [8]byte, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return traceID, spanID, false
+	}
+	if _, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil {
+		return traceID, spanID, false
+	}
+	if _, err := hex.Decode(spanID[:], []byte(parts[2])); err != nil {
+		return traceID, spanID, false
+	}
+	if traceID == [16]byte{} || spanID == [8]byte{} {
+		return traceID, spanID, false
+	}
+	return traceID, spanID, true
+}
@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		value string
+		ok    bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01", false},
+	}
+	for _, tt := range tests {
+		if _, _, ok := ParseTraceParent(tt.value); ok != tt.ok {
+			t.Errorf("ParseTraceParent(%q) = %v, want %v", tt.value, ok, tt.ok)
+		}
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	SetExporter(nil)
+
+	span := Start("", "event")
+	if span != nil {
+		t.Fatalf("expected no span when tracing is disabled")
+	}
+	// nil spans are no-ops
+	span.SetAttribute("image", "keel")
+	span.SetError(errors.New("failed"))
+	span.End()
+	if tp := span.TraceParent(); tp != "" {
+		t.Errorf("expected empty traceparent, got %s", tp)
+	}
+}
+
+func TestExport(t *testing.T) {
+	received := make(chan otlpRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Authorization") != "Bearer xx" {
+			t.Errorf("unexpected request: %s %v", r.URL.Path, r.Header)
+		}
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %s", err)
+		}
+		received <- req
+	}))
+	defer srv.Close()
+
+	e := NewExporter(ExporterOpts{
+		Endpoint:      srv.URL + "/",
+		Headers:       ParseHeaders("Authorization=Bearer xx"),
+		FlushInterval: time.Hour,
+	})
+	SetExporter(e)
+	defer SetExporter(nil)
+
+	root := Start("", "event")
+	root.SetAttribute("image", "karolisr/webhook-demo")
+	child := Start(root.TraceParent(), "kubernetes.update")
+	child.SetError(errors.New("forbidden"))
+	child.End()
+	root.End()
+	e.Stop()
+
+	var req otlpRequest
+	select {
+	case req = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("spans were not exported")
+	}
+
+	if len(req.ResourceSpans) != 1 || req.ResourceSpans[0].Resource.Attributes[0].Value.StringValue != "keel" {
+		t.Fatalf("unexpected resource spans: %+v", req.ResourceSpans)
+	}
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	c, r := spans[0], spans[1]
+	if c.TraceID != r.TraceID || c.ParentSpanID != r.SpanID || r.ParentSpanID != "" {
+		t.Errorf("child span is not linked to the root: %+v, %+v", c, r)
+	}
+	if c.Status.Code != statusCodeError || c.Status.Message != "forbidden" {
+		t.Errorf("unexpected child status: %+v", c.Status)
+	}
+	if len(r.Attributes) != 1 || r.Attributes[0].Key != "image" {
+		t.Errorf("unexpected root attributes: %+v", r.Attributes)
+	}
+}
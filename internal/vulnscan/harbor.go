@@ -0,0 +1,92 @@
+package vulnscan
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/util/image"
+)
+
+// harborReportTypes - vulnerability report mime types accepted from Harbor
+var harborReportTypes = []string{
+	"application/vnd.security.vulnerability.report; version=1.1",
+	"application/vnd.scanner.adapter.vuln.report.harbor+json; version=1.0",
+}
+
+// Harbor - reads reports of images scanned by Harbor (Trivy scanner)
+type Harbor struct {
+	address            string
+	username, password string
+	client             *http.Client
+}
+
+// NewHarbor - creates Harbor client, address is Harbor base URL (ie: https://harbor.example.com)
+func NewHarbor(address, username, password string) *Harbor {
+	return &Harbor{
+		address:  strings.TrimSuffix(address, "/"),
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type harborReport struct {
+	Vulnerabilities []struct {
+		ID       string `json:"id"`
+		Package  string `json:"package"`
+		Severity string `json:"severity"`
+	} `json:"vulnerabilities"`
+}
+
+// Scan - gets report of the artifact, images that weren't scanned yet return an error
+func (h *Harbor) Scan(img, digest string) (*Report, error) {
+	ref, err := image.Parse(img)
+	if err != nil {
+		return nil, err
+	}
+	// first path segment is the project, repository names with slashes are encoded twice
+	parts := strings.SplitN(ref.ShortName(), "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("image %s isn't in a Harbor project", img)
+	}
+	repository := url.PathEscape(url.PathEscape(parts[1]))
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v2.0/projects/%s/repositories/%s/artifacts/%s/additions/vulnerabilities", h.address, parts[0], repository, digest), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Accept-Vulnerabilities", strings.Join(harborReportTypes, ", "))
+	if h.username != "" {
+		req.SetBasicAuth(h.username, h.password)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("harbor returned status %d", resp.StatusCode)
+	}
+
+	var reports map[string]harborReport
+	if err := json.NewDecoder(resp.Body).Decode(&reports); err != nil {
+		return nil, fmt.Errorf("failed to decode Harbor report: %s", err)
+	}
+	if len(reports) == 0 {
+		return nil, fmt.Errorf("image %s wasn't scanned yet", img)
+	}
+
+	report := &Report{}
+	for _, r := range reports {
+		for _, v := range r.Vulnerabilities {
+			severity, _ := ParseSeverity(v.Severity)
+			report.Vulnerabilities = append(report.Vulnerabilities, Vulnerability{ID: v.ID, Package: v.Package, Severity: severity})
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,110 @@
+// Package vulnscan queries vulnerability scanners for reports of new images so
+// updates can be blocked or held for approval when the image has vulnerabilities
+// of configured severity.
+package vulnscan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity - vulnerability severity
+type Severity int
+
+// available severities, ordered
+const (
+	SeverityUnknown Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+// ParseSeverity - parses severity name (case insensitive)
+func ParseSeverity(severity string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case "unknown", "none", "negligible":
+		return SeverityUnknown, nil
+	case "low":
+		return SeverityLow, nil
+	case "medium", "moderate":
+		return SeverityMedium, nil
+	case "high":
+		return SeverityHigh, nil
+	case "critical":
+		return SeverityCritical, nil
+	}
+	return SeverityUnknown, fmt.Errorf("unknown severity %q", severity)
+}
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// Vulnerability - vulnerability found in the image
+type Vulnerability struct {
+	ID       string
+	Package  string
+	Severity Severity
+}
+
+// Report - scan report of the image
+type Report struct {
+	Vulnerabilities []Vulnerability
+}
+
+// Scanner - returns vulnerability report of the image digest, image is the
+// full image reference (ie: registry/project/app:1.0.0)
+type Scanner interface {
+	Scan(image, digest string) (*Report, error)
+}
+
+// Findings - vulnerabilities with severity at or above the threshold, most severe first
+func (r *Report) Findings(threshold Severity) []Vulnerability {
+	var found []Vulnerability
+	for _, v := range r.Vulnerabilities {
+		if v.Severity >= threshold {
+			found = append(found, v)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Severity > found[j].Severity })
+	return found
+}
+
+// maxSummaryIDs - vulnerability IDs listed in the summary
+const maxSummaryIDs = 5
+
+// Summary - findings summary, ie: "1 critical, 2 high (CVE-2019-1, CVE-2019-2, CVE-2019-3)"
+func Summary(findings []Vulnerability) string {
+	counts := make(map[Severity]int)
+	var ids []string
+	for _, v := range findings {
+		counts[v.Severity]++
+		if len(ids) < maxSummaryIDs {
+			ids = append(ids, v.ID)
+		}
+	}
+
+	var parts []string
+	for s := SeverityCritical; s >= SeverityUnknown; s-- {
+		if counts[s] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[s], s))
+		}
+	}
+	if len(findings) > maxSummaryIDs {
+		ids = append(ids, "...")
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(parts, ", "), strings.Join(ids, ", "))
+}
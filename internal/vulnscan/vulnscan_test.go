@@ -0,0 +1,105 @@
+package vulnscan
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSummary(t *testing.T) {
+	report := &Report{Vulnerabilities: []Vulnerability{
+		{ID: "CVE-2019-1", Severity: SeverityHigh},
+		{ID: "CVE-2019-2", Severity: SeverityLow},
+		{ID: "CVE-2019-3", Severity: SeverityCritical},
+		{ID: "CVE-2019-4", Severity: SeverityHigh},
+	}}
+
+	findings := report.Findings(SeverityHigh)
+	if len(findings) != 3 || findings[0].ID != "CVE-2019-3" {
+		t.Fatalf("unexpected findings: %v", findings)
+	}
+	if summary := Summary(findings); summary != "1 critical, 2 high (CVE-2019-3, CVE-2019-1, CVE-2019-4)" {
+		t.Errorf("unexpected summary: %s", summary)
+	}
+	if len(report.Findings(SeverityCritical)) != 1 {
+		t.Errorf("expected only critical vulnerabilities")
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	for value, expected := range map[string]Severity{"HIGH": SeverityHigh, "Critical": SeverityCritical, "moderate": SeverityMedium, "low": SeverityLow} {
+		severity, err := ParseSeverity(value)
+		if err != nil || severity != expected {
+			t.Errorf("%s: unexpected severity: %s (%v)", value, severity, err)
+		}
+	}
+	if _, err := ParseSeverity("urgent"); err == nil {
+		t.Errorf("expected error for unknown severity")
+	}
+}
+
+func TestHarborScan(t *testing.T) {
+	var path, user string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		user, _, _ = r.BasicAuth()
+		fmt.Fprint(w, `{"application/vnd.security.vulnerability.report; version=1.1": {
+			"severity": "High",
+			"vulnerabilities": [
+				{"id": "CVE-2019-1", "package": "openssl", "severity": "High"},
+				{"id": "CVE-2019-2", "package": "zlib", "severity": "Low"}
+			]
+		}}`)
+	}))
+	defer ts.Close()
+
+	h := NewHarbor(ts.URL, "robot", "secret")
+	report, err := h.Scan("harbor.example.com/library/team/app:1.0.0", "sha256:abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if path != "/api/v2.0/projects/library/repositories/team%252Fapp/artifacts/sha256:abc/additions/vulnerabilities" {
+		t.Errorf("unexpected path: %s", path)
+	}
+	if user != "robot" {
+		t.Errorf("expected basic auth, got user: %s", user)
+	}
+	if len(report.Vulnerabilities) != 2 || report.Vulnerabilities[0].Severity != SeverityHigh || report.Vulnerabilities[0].Package != "openssl" {
+		t.Errorf("unexpected report: %v", report.Vulnerabilities)
+	}
+}
+
+func TestHarborNotScanned(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	}))
+	defer ts.Close()
+
+	_, err := NewHarbor(ts.URL, "", "").Scan("harbor.example.com/library/app:1.0.0", "sha256:abc")
+	if err == nil || !strings.Contains(err.Error(), "wasn't scanned") {
+		t.Errorf("expected error for image that wasn't scanned, got: %v", err)
+	}
+}
+
+func TestWebhookScan(t *testing.T) {
+	var req webhookRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprint(w, `{"vulnerabilities": [{"id": "CVE-2019-1", "package": "openssl", "severity": "CRITICAL"}]}`)
+	}))
+	defer ts.Close()
+
+	report, err := NewWebhook(ts.URL).Scan("karolisr/webhook-demo:0.0.15", "sha256:abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if req.Image != "karolisr/webhook-demo:0.0.15" || req.Digest != "sha256:abc" {
+		t.Errorf("unexpected request: %v", req)
+	}
+	if len(report.Vulnerabilities) != 1 || report.Vulnerabilities[0].Severity != SeverityCritical {
+		t.Errorf("unexpected report: %v", report.Vulnerabilities)
+	}
+}
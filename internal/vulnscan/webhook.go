@@ -0,0 +1,68 @@
+package vulnscan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Webhook - generic scanner service, image and digest are POSTed to it and it
+// responds with the found vulnerabilities:
+// {"vulnerabilities": [{"id": "CVE-2019-1", "package": "openssl", "severity": "HIGH"}]}
+type Webhook struct {
+	address string
+	client  *http.Client
+}
+
+// NewWebhook - creates scanner client, scans can take a while so timeout is longer
+func NewWebhook(address string) *Webhook {
+	return &Webhook{
+		address: strings.TrimSuffix(address, "/"),
+		client:  &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+type webhookRequest struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest"`
+}
+
+type webhookResponse struct {
+	Vulnerabilities []struct {
+		ID       string `json:"id"`
+		Package  string `json:"package"`
+		Severity string `json:"severity"`
+	} `json:"vulnerabilities"`
+}
+
+// Scan - requests scan of the image
+func (w *Webhook) Scan(img, digest string) (*Report, error) {
+	body, err := json.Marshal(webhookRequest{Image: img, Digest: digest})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := w.client.Post(w.address, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scanner returned status %d", resp.StatusCode)
+	}
+
+	var r webhookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to decode scan report: %s", err)
+	}
+
+	report := &Report{}
+	for _, v := range r.Vulnerabilities {
+		severity, _ := ParseSeverity(v.Severity)
+		report.Vulnerabilities = append(report.Vulnerabilities, Vulnerability{ID: v.ID, Package: v.Package, Severity: severity})
+	}
+	return report, nil
+}
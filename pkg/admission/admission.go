@@ -0,0 +1,368 @@
+// Package admission - validating/mutating admission webhook that records manual
+// image changes of workloads tracked by keel (ie: kubectl set image during an
+// incident) in the update history. Requests are never rejected.
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	admission_v1beta1 "k8s.io/api/admission/v1beta1"
+	apps_v1 "k8s.io/api/apps/v1"
+	batch_v1beta1 "k8s.io/api/batch/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TriggerManual - trigger of update history entries created for manual changes
+const TriggerManual = "manual"
+
+// DefaultPort - default admission webhook port
+const DefaultPort = 9443
+
+// Opts - admission webhook server options
+type Opts struct {
+	Port     int
+	CertFile string
+	KeyFile  string
+
+	Store store.Store
+
+	// IgnoredUsers - users whose changes aren't manual, keel's own service
+	// account has to be listed here
+	IgnoredUsers []string
+
+	// Repin - pause keel updates of manually changed workloads, so the next
+	// poll doesn't revert the change. Updates are resumed with the bot or the UI.
+	Repin bool
+}
+
+// Server - admission webhook server
+type Server struct {
+	port     int
+	certFile string
+	keyFile  string
+
+	store        store.Store
+	ignoredUsers map[string]bool
+	repin        bool
+
+	server *http.Server
+}
+
+// New - creates admission webhook server
+func New(opts *Opts) *Server {
+	port := opts.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+	ignored := make(map[string]bool)
+	for _, user := range opts.IgnoredUsers {
+		ignored[user] = true
+	}
+	return &Server{
+		port:         port,
+		certFile:     opts.CertFile,
+		keyFile:      opts.KeyFile,
+		store:        opts.Store,
+		ignoredUsers: ignored,
+		repin:        opts.Repin,
+	}
+}
+
+// Handler - /validate never patches objects, /mutate annotates re-pinned workloads
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/validate", s.admissionHandler(false))
+	mux.HandleFunc("/mutate", s.admissionHandler(true))
+	mux.HandleFunc("/healthz", func(resp http.ResponseWriter, req *http.Request) {
+		resp.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+// Start - starts TLS server, API server only calls webhooks over HTTPS. Returns
+// nil once the server is stopped.
+func (s *Server) Start() error {
+	s.server = &http.Server{
+		Addr:    fmt.Sprintf(":%d", s.port),
+		Handler: s.Handler(),
+	}
+
+	log.WithFields(log.Fields{
+		"port":  s.port,
+		"repin": s.repin,
+	}).Info("admission webhook server starting...")
+	err := s.server.ListenAndServeTLS(s.certFile, s.keyFile)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Stop - stops admission webhook server
+func (s *Server) Stop() {
+	if s.server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	s.server.Shutdown(ctx)
+}
+
+func (s *Server) admissionHandler(mutate bool) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			http.Error(resp, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var review admission_v1beta1.AdmissionReview
+		if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+			http.Error(resp, "invalid admission review", http.StatusBadRequest)
+			return
+		}
+
+		response := &admission_v1beta1.AdmissionResponse{
+			UID:     review.Request.UID,
+			Allowed: true,
+		}
+		patch := s.review(review.Request, mutate)
+		if patch != nil {
+			patchType := admission_v1beta1.PatchTypeJSONPatch
+			response.Patch = patch
+			response.PatchType = &patchType
+		}
+
+		review.Response = response
+		review.Request = nil
+		resp.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(resp).Encode(&review)
+	}
+}
+
+// imageChange - container image changed outside of keel
+type imageChange struct {
+	container string
+	previous  *image.Reference
+	current   *image.Reference
+}
+
+// review - records manual changes of the request, returns optional JSON patch
+func (s *Server) review(req *admission_v1beta1.AdmissionRequest, mutate bool) []byte {
+	if req.Operation != admission_v1beta1.Update || (req.DryRun != nil && *req.DryRun) {
+		return nil
+	}
+	if s.ignoredUsers[req.UserInfo.Username] {
+		return nil
+	}
+
+	current, err := decodeResource(req.Kind, req.Object.Raw)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"kind":  req.Kind.Kind,
+		}).Debug("admission: skipping unsupported object")
+		return nil
+	}
+	previous, err := decodeResource(req.Kind, req.OldObject.Raw)
+	if err != nil {
+		return nil
+	}
+
+	changes := trackedChanges(current, imageChanges(previous, current))
+	if len(changes) == 0 {
+		return nil
+	}
+
+	user := req.UserInfo.Username
+	for _, change := range changes {
+		s.record(current, change, user)
+	}
+
+	if !s.repin {
+		return nil
+	}
+	s.pause(current, user)
+	if !mutate {
+		return nil
+	}
+	return manualChangePatch(current, fmt.Sprintf("%s %s", user, time.Now().UTC().Format(time.RFC3339)))
+}
+
+func decodeResource(kind meta_v1.GroupVersionKind, raw []byte) (*k8s.GenericResource, error) {
+	var obj interface{}
+	switch kind.Kind {
+	case "Deployment":
+		obj = &apps_v1.Deployment{}
+	case "StatefulSet":
+		obj = &apps_v1.StatefulSet{}
+	case "DaemonSet":
+		obj = &apps_v1.DaemonSet{}
+	case "CronJob":
+		obj = &batch_v1beta1.CronJob{}
+	default:
+		return nil, fmt.Errorf("unsupported kind %s", kind.Kind)
+	}
+	if err := json.Unmarshal(raw, obj); err != nil {
+		return nil, err
+	}
+	return k8s.NewGenericResource(obj)
+}
+
+// imageChanges - containers whose images differ between the objects
+func imageChanges(previous, current *k8s.GenericResource) []imageChange {
+	previousImages := make(map[string]string)
+	for _, c := range previous.Containers() {
+		previousImages[c.Name] = c.Image
+	}
+
+	var changes []imageChange
+	for _, c := range current.Containers() {
+		prev, ok := previousImages[c.Name]
+		if !ok || prev == c.Image {
+			continue
+		}
+		prevRef, err := image.Parse(prev)
+		if err != nil {
+			continue
+		}
+		ref, err := image.Parse(c.Image)
+		if err != nil {
+			continue
+		}
+		changes = append(changes, imageChange{container: c.Name, previous: prevRef, current: ref})
+	}
+	return changes
+}
+
+// trackedChanges - changes of containers keel tracks, containers can have their
+// own policies (keel.sh/policy.<container>)
+func trackedChanges(resource *k8s.GenericResource, changes []imageChange) []imageChange {
+	labels, annotations := resource.GetLabels(), resource.GetAnnotations()
+	plc := policy.GetPolicyFromLabelsOrAnnotations(labels, annotations)
+
+	var tracked []imageChange
+	for _, change := range changes {
+		containerPlc := plc
+		if override, ok := policy.GetContainerPolicy(change.container, labels, annotations); ok {
+			containerPlc = override
+		}
+		if containerPlc.Type() == policy.PolicyTypeNone {
+			continue
+		}
+		tracked = append(tracked, change)
+	}
+	return tracked
+}
+
+func (s *Server) record(resource *k8s.GenericResource, change imageChange, user string) {
+	log.WithFields(log.Fields{
+		"name":      resource.Name,
+		"namespace": resource.Namespace,
+		"container": change.container,
+		"previous":  change.previous.Remote(),
+		"current":   change.current.Remote(),
+		"user":      user,
+	}).Info("admission: manual image change recorded")
+
+	if s.store == nil {
+		return
+	}
+	record := &types.UpdateRecord{
+		Provider:       "kubernetes",
+		ResourceKind:   resource.Kind(),
+		Identifier:     resource.Identifier,
+		Namespace:      resource.Namespace,
+		Name:           resource.Name,
+		Containers:     change.container,
+		Image:          change.current.Repository(),
+		PreviousTag:    change.previous.Tag(),
+		NewTag:         change.current.Tag(),
+		PreviousDigest: change.previous.Digest(),
+		NewDigest:      change.current.Digest(),
+		Trigger:        TriggerManual,
+		Status:         types.UpdateStatusManual,
+		Message:        fmt.Sprintf("changed by %s", user),
+	}
+	if change.previous.Repository() != change.current.Repository() {
+		record.Message = fmt.Sprintf("changed by %s from %s", user, change.previous.Repository())
+	}
+	_, err := s.store.CreateUpdateRecord(record)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      resource.Name,
+			"namespace": resource.Namespace,
+		}).Error("admission: failed to save update history")
+	}
+}
+
+// pause - pauses keel updates of the workload, already paused workloads are kept
+func (s *Server) pause(resource *k8s.GenericResource, user string) {
+	if s.store == nil {
+		return
+	}
+	identifier := types.PausedIdentifier(resource.Namespace, resource.Name)
+	if _, err := s.store.GetPausedResource(identifier); err == nil {
+		return
+	}
+	_, err := s.store.CreatePausedResource(&types.PausedResource{
+		Identifier: identifier,
+		User:       user,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      resource.Name,
+			"namespace": resource.Namespace,
+		}).Error("admission: failed to pause updates of manually changed workload")
+		return
+	}
+	log.WithFields(log.Fields{
+		"name":      resource.Name,
+		"namespace": resource.Namespace,
+		"user":      user,
+	}).Info("admission: updates of manually changed workload paused")
+}
+
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// manualChangePatch - JSON patch that sets keel.sh/manualChange annotation
+func manualChangePatch(resource *k8s.GenericResource, value string) []byte {
+	var ops []patchOperation
+	if len(resource.GetAnnotations()) == 0 {
+		ops = append(ops, patchOperation{Op: "add", Path: "/metadata/annotations", Value: map[string]string{}})
+	}
+	ops = append(ops, patchOperation{
+		Op:    "add",
+		Path:  "/metadata/annotations/" + strings.Replace(types.KeelManualChangeAnnotation, "/", "~1", -1),
+		Value: value,
+	})
+	patch, err := json.Marshal(ops)
+	if err != nil {
+		return nil
+	}
+	return patch
+}
@@ -0,0 +1,190 @@
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	admission_v1beta1 "k8s.io/api/admission/v1beta1"
+	apps_v1 "k8s.io/api/apps/v1"
+	authentication_v1 "k8s.io/api/authentication/v1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/keel-hq/keel/pkg/store/sql"
+	"github.com/keel-hq/keel/types"
+
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+func newTestStore(t *testing.T) (*sql.SQLStore, func()) {
+	dir, err := ioutil.TempDir("", "admissiontest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := sql.New(sql.Opts{DatabaseType: "sqlite3", URI: filepath.Join(dir, "gorm.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func deployment(image string, annotations map[string]string) *apps_v1.Deployment {
+	return &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "wd",
+			Namespace:   "default",
+			Annotations: annotations,
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{{Name: "wd", Image: image}},
+				},
+			},
+		},
+	}
+}
+
+func admissionReview(t *testing.T, user string, previous, current *apps_v1.Deployment) []byte {
+	oldRaw, _ := json.Marshal(previous)
+	raw, _ := json.Marshal(current)
+	review := admission_v1beta1.AdmissionReview{
+		Request: &admission_v1beta1.AdmissionRequest{
+			UID:       "1234",
+			Kind:      meta_v1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			Operation: admission_v1beta1.Update,
+			UserInfo:  authentication_v1.UserInfo{Username: user},
+			Object:    runtime.RawExtension{Raw: raw},
+			OldObject: runtime.RawExtension{Raw: oldRaw},
+		},
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func send(t *testing.T, srv *Server, path string, body []byte) *admission_v1beta1.AdmissionResponse {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", rec.Code)
+	}
+	var review admission_v1beta1.AdmissionReview
+	if err := json.Unmarshal(rec.Body.Bytes(), &review); err != nil {
+		t.Fatalf("failed to decode response: %s", err)
+	}
+	if review.Response == nil || !review.Response.Allowed || review.Response.UID != "1234" {
+		t.Fatalf("expected request to be allowed: %+v", review.Response)
+	}
+	return review.Response
+}
+
+func TestManualChangeRecorded(t *testing.T) {
+	store, teardown := newTestStore(t)
+	defer teardown()
+
+	srv := New(&Opts{Store: store, IgnoredUsers: []string{"system:serviceaccount:keel:keel"}})
+
+	tracked := map[string]string{types.KeelPolicyLabel: "minor"}
+	previous := deployment("karolisr/webhook-demo:0.0.10", tracked)
+	current := deployment("karolisr/webhook-demo:0.0.9-hotfix", tracked)
+
+	resp := send(t, srv, "/validate", admissionReview(t, "jane@example.com", previous, current))
+	if resp.Patch != nil {
+		t.Errorf("validating webhook shouldn't patch objects")
+	}
+	// keel's own updates are ignored
+	send(t, srv, "/validate", admissionReview(t, "system:serviceaccount:keel:keel", previous, current))
+	// untracked workloads are ignored
+	send(t, srv, "/validate", admissionReview(t, "jane@example.com", deployment("karolisr/webhook-demo:0.0.10", nil), deployment("karolisr/webhook-demo:0.0.11", nil)))
+
+	records, err := store.ListUpdateRecords(&types.UpdateRecordQuery{})
+	if err != nil {
+		t.Fatalf("failed to list records: %s", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	r := records[0]
+	if r.Status != types.UpdateStatusManual || r.Trigger != TriggerManual {
+		t.Errorf("unexpected status or trigger: %s, %s", r.Status, r.Trigger)
+	}
+	if r.PreviousTag != "0.0.10" || r.NewTag != "0.0.9-hotfix" || r.Containers != "wd" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	if r.Identifier != "deployment/default/wd" || r.Message != "changed by jane@example.com" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+
+	if _, err := store.GetPausedResource(types.PausedIdentifier("default", "wd")); err == nil {
+		t.Errorf("workload shouldn't be paused without repin")
+	}
+}
+
+func TestManualChangeRepin(t *testing.T) {
+	store, teardown := newTestStore(t)
+	defer teardown()
+
+	srv := New(&Opts{Store: store, Repin: true})
+
+	previous := deployment("karolisr/webhook-demo:0.0.10", map[string]string{types.KeelPolicyLabel: "force"})
+	current := deployment("karolisr/webhook-demo:0.0.9-hotfix", map[string]string{types.KeelPolicyLabel: "force"})
+
+	resp := send(t, srv, "/mutate", admissionReview(t, "jane@example.com", previous, current))
+	if resp.PatchType == nil || *resp.PatchType != admission_v1beta1.PatchTypeJSONPatch {
+		t.Fatalf("expected JSON patch")
+	}
+	var ops []patchOperation
+	if err := json.Unmarshal(resp.Patch, &ops); err != nil {
+		t.Fatalf("failed to decode patch: %s", err)
+	}
+	if len(ops) != 1 || ops[0].Path != "/metadata/annotations/keel.sh~1manualChange" {
+		t.Errorf("unexpected patch: %s", string(resp.Patch))
+	}
+
+	paused, err := store.GetPausedResource(types.PausedIdentifier("default", "wd"))
+	if err != nil {
+		t.Fatalf("expected workload to be paused: %s", err)
+	}
+	if paused.User != "jane@example.com" {
+		t.Errorf("unexpected user: %s", paused.User)
+	}
+
+	// already paused workloads are kept paused
+	send(t, srv, "/mutate", admissionReview(t, "john@example.com", current, previous))
+	paused, err = store.GetPausedResource(types.PausedIdentifier("default", "wd"))
+	if err != nil || paused.User != "jane@example.com" {
+		t.Errorf("expected pause to be kept: %v %v", paused, err)
+	}
+}
+
+func TestManualChangePatchWithoutAnnotations(t *testing.T) {
+	srv := New(&Opts{Repin: true})
+
+	previous := deployment("karolisr/webhook-demo:0.0.10", nil)
+	current := deployment("karolisr/webhook-demo:0.0.11", nil)
+	previous.Labels = map[string]string{types.KeelPolicyLabel: "all"}
+	current.Labels = map[string]string{types.KeelPolicyLabel: "all"}
+
+	resp := send(t, srv, "/mutate", admissionReview(t, "jane@example.com", previous, current))
+	var ops []patchOperation
+	if err := json.Unmarshal(resp.Patch, &ops); err != nil {
+		t.Fatalf("failed to decode patch: %s", err)
+	}
+	if len(ops) != 2 || ops[0].Path != "/metadata/annotations" {
+		t.Errorf("expected annotations to be created: %s", string(resp.Patch))
+	}
+}
@@ -48,6 +48,7 @@ func New(opts *Opts) *DefaultAuthenticator {
 	return &DefaultAuthenticator{
 		opts:   opts,
 		secret: opts.Secret,
+		oidc:   opts.OIDC,
 	}
 }
 
@@ -58,12 +59,17 @@ type Opts struct {
 
 	// Secret used to sign JWT tokens
 	Secret []byte
+
+	// OIDC - optional OpenID Connect provider, its ID tokens are accepted
+	// as bearer tokens
+	OIDC *OIDC
 }
 
 type DefaultAuthenticator struct {
 	opts *Opts
 
 	secret []byte
+	oidc   *OIDC
 }
 
 var (
@@ -71,14 +77,26 @@ var (
 )
 
 func (a *DefaultAuthenticator) Enabled() bool {
+	return a.basicEnabled() || a.oidc != nil
+}
+
+func (a *DefaultAuthenticator) basicEnabled() bool {
 	return a.opts.Username != "" && a.opts.Password != ""
 }
 
+// OIDC - OpenID Connect provider, nil when it's not configured
+func (a *DefaultAuthenticator) OIDC() *OIDC {
+	return a.oidc
+}
+
 func (a *DefaultAuthenticator) Authenticate(req *AuthRequest) (*AuthResponse, error) {
 
 	switch req.AuthType {
 	case AuthTypeToken:
 		user, err := a.parseToken(req.Token)
+		if err != nil && a.oidc != nil {
+			user, err = a.oidc.Verify(req.Token)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -90,27 +108,53 @@ func (a *DefaultAuthenticator) Authenticate(req *AuthRequest) (*AuthResponse, er
 	}
 
 	if a.opts.Username == "" && a.opts.Password == "" {
+		if a.oidc != nil {
+			// users have to sign in with the OIDC provider
+			return nil, ErrUnauthorized
+		}
 		// if basic auth not set - authenticating as guest
-		return a.GenerateToken(User{Username: "guest"})
+		return a.GenerateToken(User{Username: "guest", Role: RoleAdmin})
 	}
 
 	if req.Username != a.opts.Username || req.Password != a.opts.Password {
 		return nil, ErrUnauthorized
 	}
 
-	return a.GenerateToken(User{Username: req.Username})
+	return a.GenerateToken(User{Username: req.Username, Role: RoleAdmin})
 }
 
 type User struct {
 	Username string
+	Email    string
+	Role     Role
+	// Namespaces - namespaces the user is restricted to, all when empty
+	Namespaces []string
+}
+
+// NamespaceAllowed - whether user can act on resources of the namespace
+func (u *User) NamespaceAllowed(namespace string) bool {
+	if len(u.Namespaces) == 0 {
+		return true
+	}
+	for _, ns := range u.Namespaces {
+		if ns == namespace || ns == "*" {
+			return true
+		}
+	}
+	return false
 }
 
 func (a *DefaultAuthenticator) GenerateToken(u User) (*AuthResponse, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{
-		"username": "admin",
+	claims := jwt.MapClaims{
+		"username": u.Username,
+		"role":     string(u.Role),
 		"exp":      time.Now().Add(expirationDelta).Unix(),
 		"iat":      time.Now().Unix(),
-	})
+	}
+	if u.Email != "" {
+		claims["email"] = u.Email
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
 
 	// Sign and get the complete encoded token as a string using the secret
 	tokenString, err := token.SignedString(a.secret)
@@ -120,6 +164,7 @@ func (a *DefaultAuthenticator) GenerateToken(u User) (*AuthResponse, error) {
 
 	return &AuthResponse{
 		Token: tokenString,
+		User:  u,
 	}, nil
 }
 
@@ -145,6 +190,14 @@ func (a *DefaultAuthenticator) parseToken(tokenString string) (*User, error) {
 			}).Warn("authenticator: malformed token")
 			return nil, fmt.Errorf("malformed token")
 		}
+		user.Email = parseString(claims, "email")
+		// tokens issued before roles were introduced belong to admins
+		user.Role = RoleAdmin
+		if role, ok := claims["role"].(string); ok && role != "" {
+			if user.Role, err = ParseRole(role); err != nil {
+				return nil, fmt.Errorf("malformed token")
+			}
+		}
 
 		// returning
 		return user, nil
@@ -11,8 +11,12 @@ const authenticationAccountObjectContextKey ctxKeyType = "authenticated-account-
 
 // SetAuthenticationDetails sets user details for this request
 func SetAuthenticationDetails(r *http.Request, u *User) *http.Request {
-	ctx := context.WithValue(r.Context(), authenticationAccountObjectContextKey, u)
-	return r.WithContext(ctx)
+	return r.WithContext(WithAccount(r.Context(), u))
+}
+
+// WithAccount - ctx with the authenticated user, ie: of gRPC calls
+func WithAccount(ctx context.Context, u *User) context.Context {
+	return context.WithValue(ctx, authenticationAccountObjectContextKey, u)
 }
 
 // GetAccountFromCtx - get current authenticated account info from ctx
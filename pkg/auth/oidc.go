@@ -0,0 +1,298 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// keys are refetched at most once per interval when tokens are signed
+// with unknown keys
+const jwksRefreshInterval = time.Minute
+
+// OIDCOpts - OpenID Connect provider settings
+type OIDCOpts struct {
+	IssuerURL string
+	// ClientID - expected audience of the tokens
+	ClientID     string
+	ClientSecret string
+	// RedirectURL - callback of the authorization code flow, ie:
+	// https://keel.example.com/v1/auth/oidc/callback
+	RedirectURL string
+
+	// UsernameClaim - defaults to email, sub is used when it's missing
+	UsernameClaim string
+	// GroupsClaim - defaults to groups
+	GroupsClaim string
+	// Roles - keel roles of the groups
+	Roles RoleMapping
+}
+
+// OIDC - validates ID tokens of the provider and runs the authorization
+// code flow for the UI
+type OIDC struct {
+	opts   OIDCOpts
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]*rsa.PublicKey
+	fetched   time.Time
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewOIDC - provider configuration is discovered on first use
+func NewOIDC(opts OIDCOpts) (*OIDC, error) {
+	if opts.IssuerURL == "" || opts.ClientID == "" {
+		return nil, fmt.Errorf("OIDC issuer URL and client ID are required")
+	}
+	if opts.UsernameClaim == "" {
+		opts.UsernameClaim = "email"
+	}
+	if opts.GroupsClaim == "" {
+		opts.GroupsClaim = "groups"
+	}
+	if len(opts.Roles) == 0 {
+		return nil, fmt.Errorf("OIDC role mapping is required")
+	}
+	opts.IssuerURL = strings.TrimSuffix(opts.IssuerURL, "/")
+	return &OIDC{
+		opts:   opts,
+		client: &http.Client{Timeout: 10 * time.Second},
+		keys:   make(map[string]*rsa.PublicKey),
+	}, nil
+}
+
+func (o *OIDC) get(endpoint string, out interface{}) error {
+	resp, err := o.client.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status code %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// provider - discovered provider configuration, o.mu has to be held
+func (o *OIDC) provider() (*oidcDiscovery, error) {
+	if o.discovery != nil {
+		return o.discovery, nil
+	}
+	var d oidcDiscovery
+	if err := o.get(o.opts.IssuerURL+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %s", err)
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != o.opts.IssuerURL {
+		return nil, fmt.Errorf("OIDC discovery returned issuer %q, expected %q", d.Issuer, o.opts.IssuerURL)
+	}
+	o.discovery = &d
+	return o.discovery, nil
+}
+
+type jwks struct {
+	Keys []struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// key - signing key, keys are refetched for unknown key IDs (rotation)
+func (o *OIDC) key(kid string) (*rsa.PublicKey, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if key, ok := o.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(o.fetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	d, err := o.provider()
+	if err != nil {
+		return nil, err
+	}
+	var set jwks
+	if err := o.get(d.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %s", err)
+	}
+	o.fetched = time.Now()
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	o.keys = keys
+
+	if key, ok := o.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// Verify - validates ID token and resolves keel user
+func (o *OIDC) Verify(rawToken string) (*User, error) {
+	token, err := jwt.Parse(rawToken, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return o.key(kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != o.opts.IssuerURL {
+		return nil, fmt.Errorf("unexpected issuer %q", iss)
+	}
+	if !containsString(stringsClaim(claims, "aud"), o.opts.ClientID) {
+		return nil, fmt.Errorf("token audience doesn't include %s", o.opts.ClientID)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("token doesn't expire")
+	}
+
+	user := &User{
+		Username: parseString(claims, o.opts.UsernameClaim),
+		Email:    parseString(claims, "email"),
+	}
+	if user.Username == "" {
+		user.Username = parseString(claims, "sub")
+	}
+	if user.Username == "" {
+		return nil, fmt.Errorf("token is missing %s and sub claims", o.opts.UsernameClaim)
+	}
+
+	user.Role = o.opts.Roles.Resolve(stringsClaim(claims, o.opts.GroupsClaim))
+	if user.Role == "" {
+		return nil, fmt.Errorf("user %s has no keel role", user.Username)
+	}
+	return user, nil
+}
+
+// AuthCodeURL - authorization endpoint URL the UI users are redirected to
+func (o *OIDC) AuthCodeURL(state string) (string, error) {
+	o.mu.Lock()
+	d, err := o.provider()
+	o.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	q := url.Values{
+		"response_type": {"code"},
+		"client_id":     {o.opts.ClientID},
+		"redirect_uri":  {o.opts.RedirectURL},
+		"scope":         {"openid email profile " + o.opts.GroupsClaim},
+		"state":         {state},
+	}
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange - exchanges authorization code for ID token and verifies it
+func (o *OIDC) Exchange(code string) (*User, error) {
+	o.mu.Lock()
+	d, err := o.provider()
+	o.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.opts.RedirectURL},
+		"client_id":     {o.opts.ClientID},
+		"client_secret": {o.opts.ClientSecret},
+	}
+	resp, err := o.client.PostForm(d.TokenEndpoint, form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("code exchange failed, status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, err
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("no ID token in code exchange response")
+	}
+	return o.Verify(tokens.IDToken)
+}
+
+// stringsClaim - claim that is either a string or a list of strings
+func stringsClaim(claims jwt.MapClaims, key string) []string {
+	switch v := claims[key].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
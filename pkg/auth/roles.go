@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Role - what the user can do, roles include permissions of the lower ones
+type Role string
+
+// Available roles
+const (
+	// RoleViewer - read only access
+	RoleViewer Role = "viewer"
+	// RoleApprover - can approve and reject updates
+	RoleApprover Role = "approver"
+	// RoleAdmin - full access
+	RoleAdmin Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer:   1,
+	RoleApprover: 2,
+	RoleAdmin:    3,
+}
+
+// ParseRole - parses role name
+func ParseRole(name string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(name)))
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("unknown role %q, expected viewer, approver or admin", name)
+	}
+	return role, nil
+}
+
+// Includes - whether role has permissions of the other role
+func (r Role) Includes(other Role) bool {
+	return roleRanks[r] >= roleRanks[other] && roleRanks[r] > 0
+}
+
+// HasRole - whether user has permissions of the role
+func (u *User) HasRole(role Role) bool {
+	return u.Role.Includes(role)
+}
+
+// RoleMapping - roles of groups, "*" matches all users
+type RoleMapping map[string]Role
+
+// ParseRoleMapping - parses <role>=<group>|<group>,... mappings, ie:
+// admin=keel-admins,approver=release-managers|sre,viewer=*
+func ParseRoleMapping(value string) (RoleMapping, error) {
+	mapping := make(RoleMapping)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid role mapping %q, expected <role>=<group>|<group>", pair)
+		}
+		role, err := ParseRole(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range strings.Split(parts[1], "|") {
+			if group = strings.TrimSpace(group); group != "" {
+				mapping[group] = role
+			}
+		}
+	}
+	return mapping, nil
+}
+
+// Resolve - highest role of the groups, empty if none of them is mapped
+func (m RoleMapping) Resolve(groups []string) Role {
+	role := m["*"]
+	for _, group := range groups {
+		if r, ok := m[group]; ok && roleRanks[r] > roleRanks[role] {
+			role = r
+		}
+	}
+	return role
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/keel-hq/keel/types"
+)
+
+// APIToken - static token of the approvals API, tokens restricted to
+// namespaces can only list and vote on approvals of those namespaces
+type APIToken struct {
+	Name       string   `json:"name"`
+	Token      string   `json:"token"`
+	Namespaces []string `json:"namespaces"`
+}
+
+// APITokens - configured API tokens
+type APITokens []APIToken
+
+// LoadAPITokens - loads YAML list of tokens, environment variables in
+// tokens are expanded, ie:
+//
+//   - name: portal
+//     token: $PORTAL_TOKEN
+//     namespaces: [staging, production]
+func LoadAPITokens(path string) (APITokens, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tokens APITokens
+	if err := yaml.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to decode API tokens: %s", err)
+	}
+	for i := range tokens {
+		tokens[i].Token = os.ExpandEnv(tokens[i].Token)
+		if tokens[i].Name == "" || tokens[i].Token == "" {
+			return nil, fmt.Errorf("API token %d: name and token are required", i)
+		}
+	}
+	return tokens, nil
+}
+
+// Authenticate - user of the token
+func (t APITokens) Authenticate(token string) (*User, bool) {
+	if token == "" {
+		return nil, false
+	}
+	for _, apiToken := range t {
+		if subtle.ConstantTimeCompare([]byte(apiToken.Token), []byte(token)) == 1 {
+			return &User{Username: apiToken.Name, Role: RoleApprover, Namespaces: apiToken.Namespaces}, true
+		}
+	}
+	return nil, false
+}
+
+// APITokenPrefix - prefix of the tokens created through the admin API
+const APITokenPrefix = "keel_"
+
+var scopeRoles = map[string]Role{
+	types.APITokenScopeReadOnly: RoleViewer,
+	types.APITokenScopeApprove:  RoleApprover,
+	types.APITokenScopeAdmin:    RoleAdmin,
+}
+
+// ScopeRole - role of the API token scope
+func ScopeRole(scope string) (Role, error) {
+	role, ok := scopeRoles[scope]
+	if !ok {
+		return "", fmt.Errorf("unknown scope %q, expected read-only, approve or admin", scope)
+	}
+	return role, nil
+}
+
+// NewAPIToken - generates random API token, only its hash should be stored
+func NewAPIToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = APITokenPrefix + hex.EncodeToString(b)
+	return token, HashAPIToken(token), nil
+}
+
+// HashAPIToken - hash of the token that is stored in the database
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// StoredTokenUser - user of the stored API token
+func StoredTokenUser(token *types.StoredAPIToken) (*User, error) {
+	role, err := ScopeRole(token.Scope)
+	if err != nil {
+		return nil, err
+	}
+	return &User{Username: "token:" + token.Name, Role: role, Namespaces: token.GetNamespaces()}, nil
+}
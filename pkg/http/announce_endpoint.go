@@ -0,0 +1,40 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/keel-hq/keel/bot"
+)
+
+type announceRequest struct {
+	// Message - optional, defaults to bot.DefaultAnnouncement
+	Message string `json:"message"`
+}
+
+type announceResponse struct {
+	Results []bot.EndpointResult `json:"results"`
+}
+
+// announceHandler - posts a test message through every bot and notification
+// sender, responds with the result of each one
+func (s *TriggerServer) announceHandler(resp http.ResponseWriter, req *http.Request) {
+	var ar announceRequest
+	dec := json.NewDecoder(req.Body)
+	defer req.Body.Close()
+
+	err := dec.Decode(&ar)
+	if err != nil && err != io.EOF {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "%s", err)
+		return
+	}
+
+	results := bot.Announce(ar.Message)
+	if results == nil {
+		results = []bot.EndpointResult{}
+	}
+	response(&announceResponse{Results: results}, http.StatusOK, nil, resp, req)
+}
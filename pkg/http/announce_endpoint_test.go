@@ -0,0 +1,42 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnnounceEndpoint(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	do := func(body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/v1/announce", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		req.SetBasicAuth("user-1", "secret")
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+	var ar announceResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &ar); err != nil {
+		t.Fatalf("failed to unmarshal response: %s", err)
+	}
+	if ar.Results == nil || len(ar.Results) != 0 {
+		t.Errorf("expected no results without bots and senders, got: %+v", ar.Results)
+	}
+
+	if rec := do(`{"message": `); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected bad request, got: %d", rec.Code)
+	}
+}
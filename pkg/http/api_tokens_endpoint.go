@@ -0,0 +1,135 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/keel-hq/keel/pkg/auth"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+)
+
+type apiTokenCreateRequest struct {
+	Name       string   `json:"name"`
+	Scope      string   `json:"scope"`
+	Namespaces []string `json:"namespaces"`
+	// ExpiresIn - optional token lifetime, ie: 720h
+	ExpiresIn string `json:"expiresIn"`
+}
+
+type apiTokenCreateResponse struct {
+	*types.StoredAPIToken
+	// Token - returned only once, keel stores just its hash
+	Token string `json:"token"`
+}
+
+// apiTokensHandler - lists API tokens, tokens themselves are not returned
+func (s *TriggerServer) apiTokensHandler(resp http.ResponseWriter, req *http.Request) {
+	tokens, err := s.store.ListAPITokens()
+	response(tokens, http.StatusOK, err, resp, req)
+}
+
+// apiTokenCreateHandler - creates API token
+func (s *TriggerServer) apiTokenCreateHandler(resp http.ResponseWriter, req *http.Request) {
+	var cr apiTokenCreateRequest
+	dec := json.NewDecoder(req.Body)
+	defer req.Body.Close()
+
+	err := dec.Decode(&cr)
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "%s", err)
+		return
+	}
+
+	if cr.Name == "" {
+		http.Error(resp, "name cannot be empty", http.StatusBadRequest)
+		return
+	}
+	if cr.Scope == "" {
+		cr.Scope = types.APITokenScopeReadOnly
+	}
+	if _, err := auth.ScopeRole(cr.Scope); err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var namespaces []string
+	for _, ns := range cr.Namespaces {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	token, hash, err := auth.NewAPIToken()
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	stored := &types.StoredAPIToken{
+		Name:       cr.Name,
+		Hash:       hash,
+		Prefix:     token[:len(auth.APITokenPrefix)+6],
+		Scope:      cr.Scope,
+		Namespaces: strings.Join(namespaces, ","),
+	}
+	if cr.ExpiresIn != "" {
+		expiresIn, err := time.ParseDuration(cr.ExpiresIn)
+		if err != nil || expiresIn <= 0 {
+			http.Error(resp, fmt.Sprintf("invalid expiresIn '%s'", cr.ExpiresIn), http.StatusBadRequest)
+			return
+		}
+		expiresAt := time.Now().Add(expiresIn)
+		stored.ExpiresAt = &expiresAt
+	}
+	if user := auth.GetAccountFromCtx(req.Context()); user != nil {
+		stored.CreatedBy = user.Username
+	}
+
+	stored, err = s.store.CreateAPIToken(stored)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.auditRequest(req, types.AuditActionCreated, types.AuditResourceKindAPIToken, stored.Name, map[string]string{
+		"token_id":   stored.ID,
+		"scope":      stored.Scope,
+		"namespaces": stored.Namespaces,
+	})
+
+	response(&apiTokenCreateResponse{StoredAPIToken: stored, Token: token}, http.StatusCreated, nil, resp, req)
+}
+
+// apiTokenRevokeHandler - deletes API token, it can't be used afterwards
+func (s *TriggerServer) apiTokenRevokeHandler(resp http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+
+	existing, err := s.store.GetAPIToken(&types.GetAPITokenQuery{ID: id})
+	if err != nil {
+		if err == store.ErrRecordNotFound {
+			http.Error(resp, fmt.Sprintf("API token '%s' not found", id), http.StatusNotFound)
+			return
+		}
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = s.store.DeleteAPIToken(existing.ID)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.auditRequest(req, types.AuditActionDeleted, types.AuditResourceKindAPIToken, existing.Name, map[string]string{
+		"token_id": existing.ID,
+	})
+
+	response(&APIResponse{Status: "revoked"}, http.StatusOK, nil, resp, req)
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/pkg/auth"
+	"github.com/keel-hq/keel/provider"
+	"github.com/keel-hq/keel/types"
+)
+
+func TestAPITokens(t *testing.T) {
+	fp := &fakeProvider{}
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	am := approvals.New(&approvals.Opts{
+		Store: store,
+	})
+
+	providers := provider.New([]provider.Provider{fp}, am)
+	srv := NewTriggerServer(&Opts{
+		Providers:       providers,
+		ApprovalManager: am,
+		Authenticator: auth.New(&auth.Opts{
+			Username: "admin",
+			Password: "pass",
+		}),
+		Store: store,
+	})
+	srv.registerRoutes(srv.router)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		if token == "" {
+			req.SetBasicAuth("admin", "pass")
+		} else {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	create := func(body string) *apiTokenCreateResponse {
+		rec := do("POST", "/v1/tokens", "", body)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+		}
+		var created apiTokenCreateResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+			t.Fatalf("failed to unmarshal token: %s", err)
+		}
+		return &created
+	}
+
+	readOnly := create(`{"name": "dashboard"}`)
+	if readOnly.Scope != types.APITokenScopeReadOnly {
+		t.Errorf("expected read-only scope by default, got %s", readOnly.Scope)
+	}
+
+	if rec := do("GET", "/v1/audit", readOnly.Token, ""); rec.Code != http.StatusOK {
+		t.Errorf("expected read-only token to read audit logs, got %d", rec.Code)
+	}
+	if rec := do("PUT", "/v1/approvals", readOnly.Token, `{}`); rec.Code != http.StatusForbidden {
+		t.Errorf("expected read-only token to be forbidden from changes, got %d", rec.Code)
+	}
+	if rec := do("GET", "/v1/tokens", readOnly.Token, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("expected read-only token to be forbidden from listing tokens, got %d", rec.Code)
+	}
+
+	rec := do("GET", "/v1/tokens", "", "")
+	if bytes.Contains(rec.Body.Bytes(), []byte(readOnly.Token)) {
+		t.Errorf("token listing contains the token itself")
+	}
+	stored, err := store.GetAPIToken(&types.GetAPITokenQuery{ID: readOnly.ID})
+	if err != nil {
+		t.Fatalf("failed to get stored token: %s", err)
+	}
+	if stored.Hash != auth.HashAPIToken(readOnly.Token) {
+		t.Errorf("expected token hash to be stored")
+	}
+
+	scoped := create(`{"name": "portal", "scope": "approve", "namespaces": ["staging"]}`)
+	if rec := do("GET", "/v1/approvals", scoped.Token, ""); rec.Code != http.StatusOK {
+		t.Errorf("expected namespaced token to list approvals, got %d", rec.Code)
+	}
+	if rec := do("GET", "/v1/audit", scoped.Token, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("expected namespaced token to be forbidden outside of approvals API, got %d", rec.Code)
+	}
+
+	expired := create(`{"name": "expired", "expiresIn": "1h"}`)
+	stored, err = store.GetAPIToken(&types.GetAPITokenQuery{ID: expired.ID})
+	if err != nil {
+		t.Fatalf("failed to get stored token: %s", err)
+	}
+	past := time.Now().Add(-time.Minute)
+	stored.ExpiresAt = &past
+	store.DeleteAPIToken(stored.ID)
+	store.CreateAPIToken(stored)
+	if rec := do("GET", "/v1/audit", expired.Token, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected expired token to be rejected, got %d", rec.Code)
+	}
+
+	if rec := do("DELETE", "/v1/tokens/"+readOnly.ID, "", ""); rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do("GET", "/v1/audit", readOnly.Token, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected revoked token to be rejected, got %d", rec.Code)
+	}
+	if rec := do("DELETE", "/v1/tokens/"+readOnly.ID, "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected not found, got %d", rec.Code)
+	}
+
+	if rec := do("POST", "/v1/tokens", "", `{"name": "x", "scope": "root"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected unknown scope to be rejected, got %d", rec.Code)
+	}
+}
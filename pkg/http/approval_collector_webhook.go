@@ -0,0 +1,25 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// approvalCollectorHandler - passes callbacks to the approval collector, ie:
+// change request updates of /v1/webhooks/approvals/servicenow
+func (s *TriggerServer) approvalCollectorHandler(resp http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["name"]
+	if s.approvalCollector == nil {
+		http.Error(resp, fmt.Sprintf("approval collector '%s' not found", name), http.StatusNotFound)
+		return
+	}
+	collector, ok := s.approvalCollector.Webhook(name)
+	if !ok {
+		http.Error(resp, fmt.Sprintf("approval collector '%s' not found", name), http.StatusNotFound)
+		return
+	}
+	defer req.Body.Close()
+	collector.ServeWebhook(resp, req)
+}
@@ -5,7 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/gorilla/mux"
+
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/pkg/auth"
 	"github.com/keel-hq/keel/pkg/store"
 	"github.com/keel-hq/keel/types"
 )
@@ -25,21 +30,67 @@ const (
 	actionArchive = "archive"
 )
 
+// approvalWorkload - <namespace>/<name> of the approval identifier
+func approvalWorkload(identifier string) string {
+	parts := strings.Split(strings.SplitN(identifier, ":", 2)[0], "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[len(parts)-2] + "/" + parts[len(parts)-1]
+}
+
+// frozenWorkloads - freeze reasons of tracked workloads, map[<namespace>/<name>]<reason>
+func (s *TriggerServer) frozenWorkloads() map[string]string {
+	trackedImages, err := s.providers.TrackedImages()
+	if err != nil {
+		return nil
+	}
+	frozen := make(map[string]string)
+	for _, img := range trackedImages {
+		if img.FreezeReason != "" {
+			frozen[img.Namespace+"/"+img.Meta["name"]] = img.FreezeReason
+		}
+	}
+	return frozen
+}
+
+// approvalAllowed - whether the authenticated user can see and vote on the approval
+func approvalAllowed(req *http.Request, a *types.Approval) bool {
+	return approvals.Allowed(auth.GetAccountFromCtx(req.Context()), a)
+}
+
+// approvalsHandler - lists approvals (both archived), optionally filtered by
+// status, namespace, provider and identifier (substring) query parameters
 func (s *TriggerServer) approvalsHandler(resp http.ResponseWriter, req *http.Request) {
 
-	// lists all (both archived)
-	approvals, err := s.store.ListApprovals(&types.GetApprovalQuery{})
+	list, err := s.store.ListApprovals(&types.GetApprovalQuery{})
 	if err != nil {
 		fmt.Fprintf(resp, "%s", err)
 		resp.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	if len(approvals) == 0 {
-		approvals = make([]*types.Approval, 0)
+	q := req.URL.Query()
+	filtered := make([]*types.Approval, 0, len(list))
+	for _, a := range list {
+		switch {
+		case !approvalAllowed(req, a):
+		case q.Get("status") != "" && q.Get("status") != approvals.Status(a):
+		case q.Get("namespace") != "" && q.Get("namespace") != approvals.Namespace(a.Identifier):
+		case q.Get("provider") != "" && q.Get("provider") != a.Provider.String():
+		case q.Get("identifier") != "" && !strings.Contains(a.Identifier, q.Get("identifier")):
+		default:
+			filtered = append(filtered, a)
+		}
+	}
+
+	if frozen := s.frozenWorkloads(); len(frozen) > 0 {
+		for _, a := range filtered {
+			a.FreezeReason = frozen[approvalWorkload(a.Identifier)]
+		}
 	}
 
-	bts, err := json.Marshal(&approvals)
+	bts, err := json.Marshal(&filtered)
 	if err != nil {
 		fmt.Fprintf(resp, "%s", err)
 		resp.WriteHeader(http.StatusInternalServerError)
@@ -100,6 +151,11 @@ func (s *TriggerServer) approvalSetHandler(resp http.ResponseWriter, req *http.R
 			v.SetAnnotations(ann)
 
 			err := s.kubernetesClient.Update(v)
+			if err == nil {
+				s.auditRequest(req, types.AuditActionUpdated, v.Kind(), v.Identifier, map[string]string{
+					"votes_required": strconv.Itoa(approvalUpdateRequest.VotesRequired),
+				})
+			}
 
 			response(&APIResponse{Status: "updated"}, 200, err, resp, req)
 			return
@@ -128,12 +184,25 @@ func (s *TriggerServer) approvalApproveHandler(resp http.ResponseWriter, req *ht
 		return
 	}
 
+	// admins can vote on behalf of other users, votes of other users are
+	// counted for their identity
+	vote := &approvals.Vote{Voter: ar.Voter}
+	if user := auth.GetAccountFromCtx(req.Context()); user != nil && !user.HasRole(auth.RoleAdmin) {
+		if ar.Action == actionDelete || ar.Action == actionArchive {
+			http.Error(resp, fmt.Sprintf("%s action requires admin role", ar.Action), http.StatusForbidden)
+			return
+		}
+		vote = approvals.UserVote(user, ar.Voter)
+	} else if ar.Voter == "" {
+		vote = approvals.UserVote(user, "")
+	}
+
 	var approval *types.Approval
 
 	// checking action
 	switch ar.Action {
 	case actionReject:
-		approval, err = s.approvalsManager.Reject(ar.Identifier)
+		approval, err = s.approvalsManager.RejectVote(ar.Identifier, vote)
 		if err != nil {
 			if err == store.ErrRecordNotFound {
 				http.Error(resp, fmt.Sprintf("approval '%s' not found", ar.Identifier), http.StatusNotFound)
@@ -159,6 +228,7 @@ func (s *TriggerServer) approvalApproveHandler(resp http.ResponseWriter, req *ht
 			resp.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.auditRequest(req, types.AuditActionDeleted, types.AuditResourceKindApproval, ar.Identifier, nil)
 	case actionArchive:
 		approval, err = s.approvalsManager.Get(ar.Identifier)
 		if err != nil {
@@ -180,10 +250,11 @@ func (s *TriggerServer) approvalApproveHandler(resp http.ResponseWriter, req *ht
 			resp.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.auditRequest(req, types.AuditActionApprovalArchived, types.AuditResourceKindApproval, ar.Identifier, nil)
 
 	default:
 		// "" or "approve"
-		approval, err = s.approvalsManager.Approve(ar.Identifier, ar.Voter)
+		approval, err = s.approvalsManager.ApproveVote(ar.Identifier, vote)
 		if err != nil {
 			if err == store.ErrRecordNotFound {
 				http.Error(resp, fmt.Sprintf("approval '%s' not found", ar.Identifier), http.StatusNotFound)
@@ -204,3 +275,53 @@ func (s *TriggerServer) approvalApproveHandler(resp http.ResponseWriter, req *ht
 
 	resp.Write(bts)
 }
+
+type voteRequest struct {
+	// Voter - optional user on whose behalf the caller votes, ie: user of
+	// a deployment portal
+	Voter string `json:"voter"`
+}
+
+// approvalVoteHandler - approves or rejects approval by ID, vote is counted for
+// the caller identity
+func (s *TriggerServer) approvalVoteHandler(action string) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		var vr voteRequest
+		if req.Body != nil && req.ContentLength != 0 {
+			defer req.Body.Close()
+			if err := json.NewDecoder(req.Body).Decode(&vr); err != nil {
+				http.Error(resp, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		id := mux.Vars(req)["id"]
+		existing, err := s.store.GetApproval(&types.GetApprovalQuery{ID: id})
+		if err != nil {
+			if err == store.ErrRecordNotFound {
+				http.Error(resp, fmt.Sprintf("approval '%s' not found", id), http.StatusNotFound)
+				return
+			}
+			http.Error(resp, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !approvalAllowed(req, existing) {
+			http.Error(resp, fmt.Sprintf("approvals of namespace '%s' are not allowed", approvals.Namespace(existing.Identifier)), http.StatusForbidden)
+			return
+		}
+		if existing.Archived {
+			http.Error(resp, fmt.Sprintf("approval '%s' is archived", id), http.StatusConflict)
+			return
+		}
+
+		vote := approvals.UserVote(auth.GetAccountFromCtx(req.Context()), vr.Voter)
+
+		var approval *types.Approval
+		if action == actionReject {
+			approval, err = s.approvalsManager.RejectVote(existing.Identifier, vote)
+		} else {
+			approval, err = s.approvalsManager.ApproveVote(existing.Identifier, vote)
+		}
+		response(approval, http.StatusOK, err, resp, req)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/keel-hq/keel/pkg/auth"
 	"github.com/keel-hq/keel/provider"
 	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
 )
 
 func TestListApprovals(t *testing.T) {
@@ -88,6 +89,56 @@ func TestListApprovals(t *testing.T) {
 	}
 }
 
+func TestListApprovalsFrozen(t *testing.T) {
+	ref, _ := image.Parse("karolisr/webhook-demo:0.0.1")
+	fp := &fakeProvider{images: []*types.TrackedImage{
+		{Image: ref, Namespace: "default", Meta: map[string]string{"name": "wd"}, Paused: true, FreezeReason: "release freeze until friday"},
+	}}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	for _, identifier := range []string{"deployment/default/wd:0.0.2", "deployment/default/other:0.0.2"} {
+		err := srv.approvalsManager.Create(&types.Approval{
+			Identifier:     identifier,
+			VotesRequired:  1,
+			NewVersion:     "0.0.2",
+			CurrentVersion: "0.0.1",
+		})
+		if err != nil {
+			t.Fatalf("failed to create approval: %s", err)
+		}
+	}
+
+	req, err := http.NewRequest("GET", "/v1/approvals", nil)
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	req.SetBasicAuth("user-1", "secret")
+
+	rec := httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != 200 {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+
+	var approvals []*types.Approval
+	if err := json.Unmarshal(rec.Body.Bytes(), &approvals); err != nil {
+		t.Fatalf("failed to unmarshal response into approvals: %s", err)
+	}
+	if len(approvals) != 2 {
+		t.Fatalf("expected to find 2 approvals but found: %d", len(approvals))
+	}
+	for _, a := range approvals {
+		expected := ""
+		if a.Identifier == "deployment/default/wd:0.0.2" {
+			expected = "release freeze until friday"
+		}
+		if a.FreezeReason != expected {
+			t.Errorf("unexpected freeze reason of %s: %q", a.Identifier, a.FreezeReason)
+		}
+	}
+}
+
 func TestDeleteApproval(t *testing.T) {
 	fp := &fakeProvider{}
 	store, teardown := NewTestingUtils()
@@ -609,3 +660,159 @@ func TestAuthListApprovalsB(t *testing.T) {
 		t.Errorf("unexpected current version: %s", approvals[0].CurrentVersion)
 	}
 }
+
+func TestApprovalsAPITokenScopes(t *testing.T) {
+	fp := &fakeProvider{}
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	am := approvals.New(&approvals.Opts{
+		Store: store,
+	})
+
+	providers := provider.New([]provider.Provider{fp}, am)
+	srv := NewTriggerServer(&Opts{
+		Providers:       providers,
+		ApprovalManager: am,
+		Authenticator:   auth.New(&auth.Opts{}),
+		APITokens: auth.APITokens{
+			{Name: "portal", Token: "portal-token", Namespaces: []string{"staging"}},
+		},
+		Store: store,
+	})
+	srv.registerRoutes(srv.router)
+
+	for _, identifier := range []string{"deployment/staging/app:1.1.0", "deployment/production/app:1.1.0"} {
+		err := am.Create(&types.Approval{
+			Provider:       types.ProviderTypeKubernetes,
+			Identifier:     identifier,
+			VotesRequired:  1,
+			NewVersion:     "1.1.0",
+			CurrentVersion: "1.0.0",
+			Deadline:       time.Now().Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("failed to create approval: %s", err)
+		}
+	}
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do("GET", "/v1/approvals", "wrong", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected unauthorized, got %d", rec.Code)
+	}
+
+	rec := do("GET", "/v1/approvals?status=pending", "portal-token", "")
+	var listed []*types.Approval
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("failed to unmarshal approvals: %s, body: %s", err, rec.Body.String())
+	}
+	if len(listed) != 1 || listed[0].Identifier != "deployment/staging/app:1.1.0" {
+		t.Fatalf("expected only staging approval, got %+v", listed)
+	}
+
+	production, err := am.Get("deployment/production/app:1.1.0")
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+	if rec := do("POST", "/v1/approvals/"+production.ID+"/approve", "portal-token", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("expected forbidden, got %d", rec.Code)
+	}
+
+	rec = do("POST", "/v1/approvals/"+listed[0].ID+"/approve", "portal-token", `{"voter": "alice"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+	approved, err := am.Get("deployment/staging/app:1.1.0")
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+	if approved.VotesReceived != 1 || len(approved.GetVoters()) != 1 || approved.GetVoters()[0] != "portal" {
+		t.Errorf("unexpected votes: %d, voters: %v", approved.VotesReceived, approved.GetVoters())
+	}
+
+	if rec := do("POST", "/v1/approvals/unknown/reject", "portal-token", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected not found, got %d", rec.Code)
+	}
+}
+
+func TestApprovalsAPITokenVotesOnce(t *testing.T) {
+	fp := &fakeProvider{}
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	am := approvals.New(&approvals.Opts{
+		Store: store,
+	})
+
+	providers := provider.New([]provider.Provider{fp}, am)
+	srv := NewTriggerServer(&Opts{
+		Providers:       providers,
+		ApprovalManager: am,
+		Authenticator:   auth.New(&auth.Opts{}),
+		APITokens: auth.APITokens{
+			{Name: "portal", Token: "portal-token"},
+		},
+		Store: store,
+	})
+	srv.registerRoutes(srv.router)
+
+	err := am.Create(&types.Approval{
+		Provider:       types.ProviderTypeKubernetes,
+		Identifier:     "deployment/staging/app:1.1.0",
+		VotesRequired:  3,
+		NewVersion:     "1.1.0",
+		CurrentVersion: "1.0.0",
+		Deadline:       time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to create approval: %s", err)
+	}
+	existing, err := am.Get("deployment/staging/app:1.1.0")
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+
+	for _, voter := range []string{"alice", "bob"} {
+		req, err := http.NewRequest("POST", "/v1/approvals/"+existing.ID+"/approve", bytes.NewBufferString(`{"voter": "`+voter+`"}`))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		req.Header.Set("Authorization", "Bearer portal-token")
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	approved, err := am.Get("deployment/staging/app:1.1.0")
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+	if approved.VotesReceived != 1 || len(approved.GetVoters()) != 1 || approved.GetVoters()[0] != "portal" {
+		t.Errorf("expected a single vote of the token, got votes: %d, voters: %v", approved.VotesReceived, approved.GetVoters())
+	}
+
+	logs, err := store.GetAuditLogs(&types.AuditLogQuery{Username: "portal", ResourceKindFilter: []string{types.AuditResourceKindApproval}})
+	if err != nil {
+		t.Fatalf("failed to get audit logs: %s", err)
+	}
+	if len(logs) != 1 {
+		t.Fatalf("expected 1 audit log entry, got %d", len(logs))
+	}
+	if logs[0].Metadata["on_behalf_of"] != "alice" {
+		t.Errorf("expected vote on behalf of alice, got metadata: %v", logs[0].Metadata)
+	}
+}
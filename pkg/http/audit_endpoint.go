@@ -5,9 +5,41 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/google/uuid"
+	"github.com/keel-hq/keel/pkg/auth"
 	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
 )
 
+// auditRequest - records change made through the API with the identity of
+// the authenticated user
+func (s *TriggerServer) auditRequest(req *http.Request, action, kind, identifier string, meta map[string]string) {
+	entry := &types.AuditLog{
+		ID:           uuid.New().String(),
+		Action:       action,
+		ResourceKind: kind,
+		Identifier:   identifier,
+	}
+	if user := auth.GetAccountFromCtx(req.Context()); user != nil {
+		entry.AccountID = user.Username
+		entry.Username = user.Username
+		entry.Email = user.Email
+	}
+	if meta == nil {
+		meta = make(map[string]string)
+	}
+	meta["source"] = "api"
+	entry.SetMetadata(meta)
+
+	if _, err := s.store.CreateAuditLog(entry); err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"identifier": identifier,
+		}).Error("http: failed to create audit log")
+	}
+}
+
 func (s *TriggerServer) adminAuditLogHandler(resp http.ResponseWriter, req *http.Request) {
 
 	query := &types.AuditLogQuery{}
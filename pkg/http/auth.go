@@ -1,12 +1,17 @@
 package http
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	request "github.com/dgrijalva/jwt-go/request"
 	"github.com/keel-hq/keel/pkg/auth"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -21,7 +26,24 @@ func authHeadersMiddleware(rw http.ResponseWriter, r *http.Request, next http.Ha
 	next(rw, r)
 }
 
+// requireAdminAuthorization - authenticated users can read, changes require
+// admin role
 func (s *TriggerServer) requireAdminAuthorization(next http.HandlerFunc) http.HandlerFunc {
+	return s.requireRoleAuthorization("", next)
+}
+
+// methodRole - role required by the request, viewers can read and admins
+// can change everything
+func methodRole(r *http.Request) auth.Role {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return auth.RoleViewer
+	}
+	return auth.RoleAdmin
+}
+
+// requireRoleAuthorization - authenticates the request and checks user role,
+// role required by the request method is used when role is empty
+func (s *TriggerServer) requireRoleAuthorization(role auth.Role, next http.HandlerFunc) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
 
 		// rw.Header().Set("Access-Control-Expose-Headers", "Authorization")
@@ -32,6 +54,16 @@ func (s *TriggerServer) requireAdminAuthorization(next http.HandlerFunc) http.Ha
 			return
 		}
 
+		if user, ok := s.storedTokenUser(extractToken(r)); ok {
+			if len(user.Namespaces) > 0 {
+				// namespaces are only checked by the approvals API
+				http.Error(rw, "API token is restricted to namespaces", http.StatusForbidden)
+				return
+			}
+			authorized(role, user, next)(rw, r)
+			return
+		}
+
 		username, password, ok := r.BasicAuth()
 		if ok {
 			resp, err := s.authenticator.Authenticate(&auth.AuthRequest{
@@ -44,16 +76,13 @@ func (s *TriggerServer) requireAdminAuthorization(next http.HandlerFunc) http.Ha
 				log.WithFields(log.Fields{
 					"error": err,
 					"user":  username,
-					"pas":   password,
 				}).Error("failed uath")
 				// rw.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
 				http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 				return
 			}
 
-			r = auth.SetAuthenticationDetails(r, &resp.User)
-
-			next(rw, r)
+			authorized(role, &resp.User, next)(rw, r)
 			return
 		}
 
@@ -67,14 +96,140 @@ func (s *TriggerServer) requireAdminAuthorization(next http.HandlerFunc) http.Ha
 		if err != nil {
 			// rw.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
 
-			log.Warnf("authentication by token failed, token: %s, err: %s", extractToken(r), err)
+			log.Warnf("authentication by token failed, err: %s", err)
+			http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		authorized(role, &resp.User, next)(rw, r)
+	}
+}
+
+// authorized - passes requests of users having the role
+func authorized(role auth.Role, user *auth.User, next http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		required := role
+		if required == "" {
+			required = methodRole(r)
+		}
+		if !user.HasRole(required) {
+			log.WithFields(log.Fields{
+				"user":     user.Username,
+				"role":     user.Role,
+				"required": required,
+				"path":     r.URL.Path,
+			}).Warn("http: user role doesn't allow the request")
+			http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		next(rw, auth.SetAuthenticationDetails(r, user))
+	}
+}
+
+// requireScopedAuthorization - API tokens are accepted in addition to admin
+// credentials, handlers check namespaces of the authenticated user
+func (s *TriggerServer) requireScopedAuthorization(role auth.Role, next http.HandlerFunc) http.HandlerFunc {
+	admin := s.requireRoleAuthorization(role, next)
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			rw.WriteHeader(200)
+			return
+		}
+
+		if user, ok := s.apiTokens.Authenticate(extractToken(r)); ok {
+			authorized(role, user, next)(rw, r)
+			return
+		}
+		if user, ok := s.storedTokenUser(extractToken(r)); ok {
+			authorized(role, user, next)(rw, r)
+			return
+		}
+
+		if !s.authenticator.Enabled() {
 			http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 			return
 		}
-		r = auth.SetAuthenticationDetails(r, &resp.User)
+		admin(rw, r)
+	}
+}
+
+// Authenticate - user of the Authorization header value (Bearer token or Basic
+// credentials), used by APIs served outside of the HTTP server (gRPC). API
+// tokens are accepted like by the approvals API, callers check namespaces.
+func (s *TriggerServer) Authenticate(authorization string) (*auth.User, error) {
+	if strings.HasPrefix(authorization, "Basic ") {
+		payload, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(authorization, "Basic "))
+		if err != nil {
+			return nil, fmt.Errorf("invalid basic credentials")
+		}
+		parts := strings.SplitN(string(payload), ":", 2)
+		if len(parts) != 2 || !s.authenticator.Enabled() {
+			return nil, fmt.Errorf("invalid basic credentials")
+		}
+		resp, err := s.authenticator.Authenticate(&auth.AuthRequest{
+			Username: parts[0],
+			Password: parts[1],
+			AuthType: auth.AuthTypeBasic,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &resp.User, nil
+	}
+
+	token := strings.TrimPrefix(authorization, "Bearer ")
+	if user, ok := s.apiTokens.Authenticate(token); ok {
+		return user, nil
+	}
+	if user, ok := s.storedTokenUser(token); ok {
+		return user, nil
+	}
+	if !s.authenticator.Enabled() || token == "" {
+		return nil, fmt.Errorf("invalid token")
+	}
+	resp, err := s.authenticator.Authenticate(&auth.AuthRequest{
+		Token:    token,
+		AuthType: auth.AuthTypeToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resp.User, nil
+}
+
+// storedTokenUser - user of the API token created through the admin API,
+// expired tokens are rejected
+func (s *TriggerServer) storedTokenUser(token string) (*auth.User, bool) {
+	if s.store == nil || !strings.HasPrefix(token, auth.APITokenPrefix) {
+		return nil, false
+	}
+
+	stored, err := s.store.GetAPIToken(&types.GetAPITokenQuery{Hash: auth.HashAPIToken(token)})
+	if err != nil {
+		if err != store.ErrRecordNotFound {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("http: failed to get API token")
+		}
+		return nil, false
+	}
+	if stored.Expired(time.Now()) {
+		log.WithFields(log.Fields{
+			"name": stored.Name,
+		}).Warn("http: API token expired")
+		return nil, false
+	}
 
-		next(rw, r)
+	user, err := auth.StoredTokenUser(stored)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  stored.Name,
+		}).Error("http: invalid API token")
+		return nil, false
 	}
+	return user, true
 }
 
 func extractToken(req *http.Request) string {
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/pkg/auth"
+	"github.com/keel-hq/keel/provider"
+)
+
+func TestAuthenticate(t *testing.T) {
+	fp := &fakeProvider{}
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	am := approvals.New(&approvals.Opts{
+		Store: store,
+	})
+
+	authenticator := auth.New(&auth.Opts{
+		Username: "admin",
+		Password: "pass",
+	})
+
+	srv := NewTriggerServer(&Opts{
+		Providers:       provider.New([]provider.Provider{fp}, am),
+		ApprovalManager: am,
+		Authenticator:   authenticator,
+		Store:           store,
+	})
+
+	basic := func(credentials string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
+
+	user, err := srv.Authenticate(basic("admin:pass"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if user.Username != "admin" {
+		t.Errorf("unexpected user: %s", user.Username)
+	}
+
+	for _, authorization := range []string{basic("admin:wrong"), basic("admin"), "Basic !!", "Bearer invalid", ""} {
+		if _, err := srv.Authenticate(authorization); err == nil {
+			t.Errorf("expected '%s' to be rejected", authorization)
+		}
+	}
+}
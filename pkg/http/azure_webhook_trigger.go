@@ -46,6 +46,8 @@ func init() {
 //}
 
 type azureWebhook struct {
+	// Action - push or delete
+	Action string `json:"action"`
 	Target struct {
 		Repository string `json:"repository"`
 		Tag        string `json:"tag"`
@@ -80,6 +82,7 @@ func (s *TriggerServer) azureHandler(resp http.ResponseWriter, req *http.Request
 	event.Repository.Name = DockerURL // need to build this url..
 	event.Repository.Tag = aw.Target.Tag
 	event.Repository.Digest = aw.Target.Digest
+	event.Deleted = aw.Action == "delete"
 	s.trigger(event)
 	newAzureWebhooksCounter.With(prometheus.Labels{"image": event.Repository.Name}).Inc()
 
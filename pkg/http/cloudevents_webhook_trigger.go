@@ -0,0 +1,222 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+	"github.com/prometheus/client_golang/prometheus"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var newCloudEventsWebhooksCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cloudevents_webhook_requests_total",
+		Help: "How many /v1/webhooks/cloudevents requests processed, partitioned by image.",
+	},
+	[]string{"image"},
+)
+
+func init() {
+	prometheus.MustRegister(newCloudEventsWebhooksCounter)
+}
+
+// CloudEvents HTTP protocol binding
+// https://github.com/cloudevents/spec/blob/v1.0/http-protocol-binding.md
+const (
+	cloudEventsContentType      = "application/cloudevents+json"
+	cloudEventsBatchContentType = "application/cloudevents-batch+json"
+	cloudEventsHeaderPrefix     = "Ce-"
+)
+
+// cloudEvent - structured mode event, in binary mode attributes are
+// passed as ce-* headers and body contains the data
+//
+//	{
+//	  "specversion": "1.0",
+//	  "type": "dev.example.image.pushed",
+//	  "source": "https://ci.example.com/pipelines/42",
+//	  "id": "A234-1234-1234",
+//	  "datacontenttype": "application/json",
+//	  "data": {
+//	    "name": "registry.example.com/team/app",
+//	    "tag": "1.2.3"
+//	  }
+//	}
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	Type            string          `json:"type"`
+	Source          string          `json:"source"`
+	ID              string          `json:"id"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+	DataBase64      string          `json:"data_base64"`
+}
+
+// cloudEventData - image push data, same as the native webhook payload,
+// "image" can be used instead of name and tag: "registry.example.com/team/app:1.2.3"
+type cloudEventData struct {
+	Name   string `json:"name"`
+	Tag    string `json:"tag"`
+	Digest string `json:"digest"`
+	Image  string `json:"image"`
+}
+
+func (d *cloudEventData) repository() (types.Repository, error) {
+	name, tag := d.Name, d.Tag
+	if name == "" {
+		name = d.Image
+	}
+	if name == "" {
+		return types.Repository{}, fmt.Errorf("repository name cannot be empty")
+	}
+
+	if tag == "" {
+		ref, err := image.Parse(name)
+		if err != nil {
+			return types.Repository{}, fmt.Errorf("invalid image '%s': %s", name, err)
+		}
+		if !strings.HasSuffix(name, ":"+ref.Tag()) {
+			return types.Repository{}, fmt.Errorf("repository tag cannot be empty")
+		}
+		name = strings.TrimSuffix(name, ":"+ref.Tag())
+		tag = ref.Tag()
+	}
+
+	return types.Repository{
+		Name:   name,
+		Tag:    tag,
+		Digest: d.Digest,
+	}, nil
+}
+
+// parseCloudEvents - decodes events from binary, structured or batched request
+func parseCloudEvents(req *http.Request, body []byte) ([]cloudEvent, error) {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case cloudEventsContentType:
+		var ev cloudEvent
+		if err := json.Unmarshal(body, &ev); err != nil {
+			return nil, err
+		}
+		return []cloudEvent{ev}, nil
+	case cloudEventsBatchContentType:
+		var evs []cloudEvent
+		if err := json.Unmarshal(body, &evs); err != nil {
+			return nil, err
+		}
+		return evs, nil
+	}
+
+	// binary mode
+	ev := cloudEvent{
+		SpecVersion:     req.Header.Get(cloudEventsHeaderPrefix + "Specversion"),
+		Type:            req.Header.Get(cloudEventsHeaderPrefix + "Type"),
+		Source:          req.Header.Get(cloudEventsHeaderPrefix + "Source"),
+		ID:              req.Header.Get(cloudEventsHeaderPrefix + "Id"),
+		DataContentType: req.Header.Get("Content-Type"),
+		Data:            body,
+	}
+	if ev.SpecVersion == "" {
+		return nil, fmt.Errorf("missing ce-specversion header")
+	}
+	return []cloudEvent{ev}, nil
+}
+
+func (ev *cloudEvent) validate() error {
+	if ev.SpecVersion == "" || ev.Type == "" || ev.Source == "" || ev.ID == "" {
+		return fmt.Errorf("specversion, type, source and id attributes are required")
+	}
+	if !strings.HasPrefix(ev.SpecVersion, "1.") {
+		return fmt.Errorf("unsupported specversion '%s'", ev.SpecVersion)
+	}
+	return nil
+}
+
+func (ev *cloudEvent) data() (*cloudEventData, error) {
+	raw := []byte(ev.Data)
+	if ev.DataBase64 != "" {
+		decoded, err := base64.StdEncoding.DecodeString(ev.DataBase64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data_base64: %s", err)
+		}
+		raw = decoded
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("event data cannot be empty")
+	}
+
+	var d cloudEventData
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return nil, fmt.Errorf("failed to decode event data: %s", err)
+	}
+	return &d, nil
+}
+
+// cloudEventsHandler - accepts CloudEvents carrying image push data (binary,
+// structured and batched HTTP content modes)
+func (s *TriggerServer) cloudEventsHandler(resp http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("trigger.cloudEventsHandler: failed to read request")
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	events, err := parseCloudEvents(req, body)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("trigger.cloudEventsHandler: failed to decode request")
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "invalid cloud event: %s", err)
+		return
+	}
+
+	var repos []types.Repository
+	for idx := range events {
+		ev := &events[idx]
+		if err := ev.validate(); err != nil {
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(resp, "invalid cloud event: %s", err)
+			return
+		}
+
+		data, err := ev.data()
+		if err != nil {
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(resp, "invalid cloud event '%s': %s", ev.ID, err)
+			return
+		}
+
+		repo, err := data.repository()
+		if err != nil {
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(resp, "invalid cloud event '%s': %s", ev.ID, err)
+			return
+		}
+		repos = append(repos, repo)
+	}
+
+	for _, repo := range repos {
+		s.trigger(types.Event{
+			Repository:  repo,
+			CreatedAt:   time.Now(),
+			TriggerName: "cloudevents",
+		})
+		newCloudEventsWebhooksCounter.With(prometheus.Labels{"image": repo.Name}).Inc()
+	}
+
+	resp.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,152 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var fakeStructuredCloudEvent = `{
+  "specversion": "1.0",
+  "type": "dev.example.image.pushed",
+  "source": "https://ci.example.com/pipelines/42",
+  "id": "A234-1234-1234",
+  "datacontenttype": "application/json",
+  "data": {
+    "name": "gcr.io/v2-namespace/hello-world",
+    "tag": "1.1.1"
+  }
+}`
+
+func TestCloudEventsHandlerBinary(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	req, err := http.NewRequest("POST", "/v1/webhooks/cloudevents", bytes.NewBuffer([]byte(`{"image": "gcr.io/v2-namespace/hello-world:1.1.1", "digest": "sha256:abc"}`)))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("ce-specversion", "1.0")
+	req.Header.Set("ce-type", "dev.example.image.pushed")
+	req.Header.Set("ce-source", "/ci")
+	req.Header.Set("ce-id", "1")
+
+	rec := httptest.NewRecorder()
+
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+
+	if len(fp.submitted) != 1 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+
+	if fp.submitted[0].Repository.Name != "gcr.io/v2-namespace/hello-world" {
+		t.Errorf("unexpected repo name: %s", fp.submitted[0].Repository.Name)
+	}
+	if fp.submitted[0].Repository.Tag != "1.1.1" {
+		t.Errorf("unexpected tag: %s", fp.submitted[0].Repository.Tag)
+	}
+	if fp.submitted[0].Repository.Digest != "sha256:abc" {
+		t.Errorf("unexpected digest: %s", fp.submitted[0].Repository.Digest)
+	}
+}
+
+func TestCloudEventsHandlerStructured(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	req, err := http.NewRequest("POST", "/v1/webhooks/cloudevents", bytes.NewBuffer([]byte(fakeStructuredCloudEvent)))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/cloudevents+json; charset=UTF-8")
+
+	rec := httptest.NewRecorder()
+
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+
+	if len(fp.submitted) != 1 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+
+	if fp.submitted[0].Repository.Name != "gcr.io/v2-namespace/hello-world" {
+		t.Errorf("unexpected repo name: %s", fp.submitted[0].Repository.Name)
+	}
+	if fp.submitted[0].TriggerName != "cloudevents" {
+		t.Errorf("unexpected trigger name: %s", fp.submitted[0].TriggerName)
+	}
+}
+
+func TestCloudEventsHandlerBatch(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	batch := `[` + fakeStructuredCloudEvent + `, {"specversion": "1.0", "type": "t", "source": "s", "id": "2", "data_base64": "eyJuYW1lIjogImFwcCIsICJ0YWciOiAiMi4wLjAifQ=="}]`
+	req, err := http.NewRequest("POST", "/v1/webhooks/cloudevents", bytes.NewBuffer([]byte(batch)))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/cloudevents-batch+json")
+
+	rec := httptest.NewRecorder()
+
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+
+	if len(fp.submitted) != 2 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+	if fp.submitted[1].Repository.Name != "app" || fp.submitted[1].Repository.Tag != "2.0.0" {
+		t.Errorf("unexpected repository: %#v", fp.submitted[1].Repository)
+	}
+}
+
+func TestCloudEventsHandlerInvalid(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	// missing ce-* headers
+	req, err := http.NewRequest("POST", "/v1/webhooks/cloudevents", bytes.NewBuffer([]byte(`{"name": "app", "tag": "1.0.0"}`)))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status code: %d", rec.Code)
+	}
+
+	// missing tag
+	req, err = http.NewRequest("POST", "/v1/webhooks/cloudevents", bytes.NewBuffer([]byte(`{"specversion": "1.0", "type": "t", "source": "s", "id": "1", "data": {"name": "app"}}`)))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/cloudevents+json")
+
+	rec = httptest.NewRecorder()
+
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status code: %d", rec.Code)
+	}
+
+	if len(fp.submitted) != 0 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+}
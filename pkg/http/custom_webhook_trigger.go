@@ -0,0 +1,264 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/gorilla/mux"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+	"github.com/keel-hq/keel/util/templates"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/client-go/util/jsonpath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var newCustomWebhooksCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "custom_webhook_requests_total",
+		Help: "How many /v1/webhooks/custom/{name} requests processed, partitioned by webhook and image.",
+	},
+	[]string{"webhook", "image"},
+)
+
+func init() {
+	prometheus.MustRegister(newCustomWebhooksCounter)
+}
+
+// CustomWebhookMapping - maps arbitrary JSON payload to image name, tag and digest.
+// Values are either JSONPath expressions ("{.repository.name}") or Go templates
+// ("{{ .repository.name }}"), "image" can be used instead of name and tag when payload
+// contains full image reference. Mappings are keyed by webhook name, ie:
+//
+//	gitea:
+//	  name: "{.package.repository.full_name}"
+//	  tag: "{.package.version}"
+//	ci:
+//	  image: "{{ .build.image }}:{{ .build.version }}"
+//	  digest: "{{ .build.digest }}"
+//
+// requests to /v1/webhooks/custom/gitea are then mapped with the gitea mapping.
+type CustomWebhookMapping struct {
+	Name   string `json:"name"`
+	Tag    string `json:"tag"`
+	Digest string `json:"digest"`
+	Image  string `json:"image"`
+}
+
+// CustomWebhooks - parsed custom webhook mappings
+type CustomWebhooks struct {
+	mappings map[string]*customMapping
+}
+
+type customMapping struct {
+	name   fieldExtractor
+	tag    fieldExtractor
+	digest fieldExtractor
+	image  fieldExtractor
+}
+
+// fieldExtractor - renders value from decoded JSON payload
+type fieldExtractor func(payload interface{}) (string, error)
+
+// ParseCustomWebhooks - parses YAML (or JSON) webhook mappings
+func ParseCustomWebhooks(data []byte) (*CustomWebhooks, error) {
+	var raw map[string]*CustomWebhookMapping
+	err := yaml.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode custom webhooks: %s", err)
+	}
+
+	webhooks := &CustomWebhooks{mappings: make(map[string]*customMapping)}
+	for name, m := range raw {
+		if m == nil {
+			continue
+		}
+		if m.Name == "" && m.Image == "" {
+			return nil, fmt.Errorf("custom webhook %s: name or image mapping is required", name)
+		}
+		if m.Image == "" && m.Tag == "" {
+			return nil, fmt.Errorf("custom webhook %s: tag mapping is required", name)
+		}
+
+		parsed := &customMapping{}
+		for _, f := range []struct {
+			field     string
+			expr      string
+			extractor *fieldExtractor
+		}{
+			{"name", m.Name, &parsed.name},
+			{"tag", m.Tag, &parsed.tag},
+			{"digest", m.Digest, &parsed.digest},
+			{"image", m.Image, &parsed.image},
+		} {
+			*f.extractor, err = newFieldExtractor(name+"."+f.field, f.expr)
+			if err != nil {
+				return nil, fmt.Errorf("custom webhook %s: invalid %s mapping: %s", name, f.field, err)
+			}
+		}
+		webhooks.mappings[name] = parsed
+	}
+
+	return webhooks, nil
+}
+
+// LoadCustomWebhooks - loads webhook mappings from a file (usually mounted ConfigMap)
+func LoadCustomWebhooks(path string) (*CustomWebhooks, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCustomWebhooks(data)
+}
+
+func newFieldExtractor(name, expr string) (fieldExtractor, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return func(interface{}) (string, error) { return "", nil }, nil
+	}
+
+	if strings.Contains(expr, "{{") {
+		tmpl, err := templates.NewParse(name, expr)
+		if err != nil {
+			return nil, err
+		}
+		tmpl = tmpl.Option("missingkey=zero")
+		return func(payload interface{}) (string, error) {
+			return executeTemplate(tmpl, payload)
+		}, nil
+	}
+
+	jp := jsonpath.New(name).AllowMissingKeys(true)
+	if err := jp.Parse(expr); err != nil {
+		return nil, err
+	}
+	return func(payload interface{}) (string, error) {
+		buf := &bytes.Buffer{}
+		if err := jp.Execute(buf, payload); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(buf.String()), nil
+	}, nil
+}
+
+func executeTemplate(tmpl *template.Template, payload interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, payload); err != nil {
+		return "", err
+	}
+	// missing keys of a map[string]interface{} are rendered as <no value>
+	return strings.TrimSpace(strings.Replace(buf.String(), "<no value>", "", -1)), nil
+}
+
+// Map - maps payload using named mapping, returns false if mapping is not defined
+func (w *CustomWebhooks) Map(name string, body []byte) (types.Repository, bool, error) {
+	if w == nil {
+		return types.Repository{}, false, nil
+	}
+	m, ok := w.mappings[name]
+	if !ok {
+		return types.Repository{}, false, nil
+	}
+
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return types.Repository{}, true, fmt.Errorf("failed to decode payload: %s", err)
+	}
+
+	repo, err := m.repository(payload)
+	return repo, true, err
+}
+
+func (m *customMapping) repository(payload interface{}) (types.Repository, error) {
+	var (
+		repo types.Repository
+		err  error
+	)
+	if repo.Name, err = m.name(payload); err != nil {
+		return repo, fmt.Errorf("failed to map name: %s", err)
+	}
+	if repo.Tag, err = m.tag(payload); err != nil {
+		return repo, fmt.Errorf("failed to map tag: %s", err)
+	}
+	if repo.Digest, err = m.digest(payload); err != nil {
+		return repo, fmt.Errorf("failed to map digest: %s", err)
+	}
+
+	if repo.Name == "" || repo.Tag == "" {
+		img, err := m.image(payload)
+		if err != nil {
+			return repo, fmt.Errorf("failed to map image: %s", err)
+		}
+		if img != "" {
+			ref, err := image.Parse(img)
+			if err != nil {
+				return repo, fmt.Errorf("invalid image '%s': %s", img, err)
+			}
+			if repo.Name == "" {
+				repo.Name = strings.TrimSuffix(img, ":"+ref.Tag())
+			}
+			if repo.Tag == "" && strings.HasSuffix(img, ":"+ref.Tag()) {
+				repo.Tag = ref.Tag()
+			}
+		}
+	}
+
+	if repo.Name == "" {
+		return repo, fmt.Errorf("repository name cannot be empty")
+	}
+	if repo.Tag == "" {
+		return repo, fmt.Errorf("repository tag cannot be empty")
+	}
+	return repo, nil
+}
+
+// customWebhookHandler - maps payload with configured mapping and triggers event
+func (s *TriggerServer) customWebhookHandler(resp http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["name"]
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("trigger.customWebhookHandler: failed to read request")
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	repo, ok, err := s.customWebhooks.Map(name, body)
+	if !ok {
+		resp.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(resp, "custom webhook '%s' is not configured", name)
+		return
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"webhook": name,
+		}).Error("trigger.customWebhookHandler: failed to map payload")
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "%s", err)
+		return
+	}
+
+	event := types.Event{
+		Repository:  repo,
+		CreatedAt:   time.Now(),
+		TriggerName: "custom/" + name,
+	}
+	s.trigger(event)
+
+	resp.WriteHeader(http.StatusOK)
+
+	newCustomWebhooksCounter.With(prometheus.Labels{"webhook": name, "image": event.Repository.Name}).Inc()
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var fakeCustomWebhooks = `
+gitea:
+  name: "{.package.repository.full_name}"
+  tag: "{.package.version}"
+ci:
+  image: "{{ .build.image }}:{{ .build.version }}"
+  digest: "{{ .build.digest }}"
+`
+
+func TestParseCustomWebhooksInvalid(t *testing.T) {
+	invalid := []string{
+		`ci: {tag: "{.tag}"}`,
+		`ci: {name: "{.name}"}`,
+		`ci: {name: "{{ .name ", tag: "{.tag}"}`,
+		`ci: {name: "{.name[}", tag: "{.tag}"}`,
+	}
+	for _, cfg := range invalid {
+		if _, err := ParseCustomWebhooks([]byte(cfg)); err == nil {
+			t.Errorf("expected error for config: %s", cfg)
+		}
+	}
+}
+
+func TestCustomWebhookHandler(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	webhooks, err := ParseCustomWebhooks([]byte(fakeCustomWebhooks))
+	if err != nil {
+		t.Fatalf("failed to parse webhooks: %s", err)
+	}
+	srv.customWebhooks = webhooks
+
+	tests := []struct {
+		name       string
+		webhook    string
+		body       string
+		wantCode   int
+		wantName   string
+		wantTag    string
+		wantDigest string
+	}{
+		{
+			name:     "jsonpath",
+			webhook:  "gitea",
+			body:     `{"package": {"repository": {"full_name": "registry.example.com/team/app"}, "version": "1.2.3"}}`,
+			wantCode: 200,
+			wantName: "registry.example.com/team/app",
+			wantTag:  "1.2.3",
+		},
+		{
+			name:       "template",
+			webhook:    "ci",
+			body:       `{"build": {"image": "registry.example.com/team/app", "version": "2.0.0", "digest": "sha256:abc"}}`,
+			wantCode:   200,
+			wantName:   "registry.example.com/team/app",
+			wantTag:    "2.0.0",
+			wantDigest: "sha256:abc",
+		},
+		{
+			name:     "missing tag",
+			webhook:  "gitea",
+			body:     `{"package": {"repository": {"full_name": "registry.example.com/team/app"}}}`,
+			wantCode: 400,
+		},
+		{
+			name:     "invalid payload",
+			webhook:  "gitea",
+			body:     `not json`,
+			wantCode: 400,
+		},
+		{
+			name:     "unknown webhook",
+			webhook:  "unknown",
+			body:     `{}`,
+			wantCode: 404,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fp.submitted = nil
+
+			req, err := http.NewRequest("POST", "/v1/webhooks/custom/"+tt.webhook, bytes.NewBuffer([]byte(tt.body)))
+			if err != nil {
+				t.Fatalf("failed to create req: %s", err)
+			}
+
+			rec := httptest.NewRecorder()
+
+			srv.router.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+			}
+
+			if tt.wantCode != 200 {
+				if len(fp.submitted) != 0 {
+					t.Errorf("unexpected number of events submitted: %d", len(fp.submitted))
+				}
+				return
+			}
+
+			if len(fp.submitted) != 1 {
+				t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+			}
+			repo := fp.submitted[0].Repository
+			if repo.Name != tt.wantName || repo.Tag != tt.wantTag || repo.Digest != tt.wantDigest {
+				t.Errorf("unexpected repository: %#v", repo)
+			}
+			if fp.submitted[0].TriggerName != "custom/"+tt.webhook {
+				t.Errorf("unexpected trigger name: %s", fp.submitted[0].TriggerName)
+			}
+		})
+	}
+}
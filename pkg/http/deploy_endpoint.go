@@ -0,0 +1,102 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type deployRequest struct {
+	// Identifier - <namespace>/<name> of the workload
+	Identifier string `json:"identifier"`
+	Tag        string `json:"tag"`
+	// Image - only required when the workload has more than one
+	// tracked image
+	Image string `json:"image,omitempty"`
+}
+
+type deployResponse struct {
+	Identifier string `json:"identifier"`
+	Image      string `json:"image"`
+	Previous   string `json:"previous"`
+	Tag        string `json:"tag"`
+}
+
+// deployHandler - forces update of a workload to the given tag, update
+// policy is bypassed but approvals still apply
+func (s *TriggerServer) deployHandler(resp http.ResponseWriter, req *http.Request) {
+	var dr deployRequest
+	dec := json.NewDecoder(req.Body)
+	defer req.Body.Close()
+
+	err := dec.Decode(&dr)
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "%s", err)
+		return
+	}
+
+	parts := strings.Split(strings.TrimSpace(dr.Identifier), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		http.Error(resp, fmt.Sprintf("invalid workload '%s', expected format: <namespace>/<name>", dr.Identifier), http.StatusBadRequest)
+		return
+	}
+	if dr.Tag == "" {
+		http.Error(resp, "tag is required", http.StatusBadRequest)
+		return
+	}
+
+	tracked, err := s.providers.TrackedImages()
+	if err != nil {
+		response(nil, http.StatusInternalServerError, err, resp, req)
+		return
+	}
+
+	ref, err := bot.FindDeployImage(tracked, parts[0], parts[1], dr.Image)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	identifier := types.PausedIdentifier(parts[0], parts[1])
+	err = s.providers.Submit(types.Event{
+		Repository: types.Repository{
+			Name: ref.Repository(),
+			Tag:  dr.Tag,
+		},
+		CreatedAt:   time.Now(),
+		TriggerName: bot.DeployTriggerName,
+		Target:      identifier,
+	})
+	if err != nil {
+		response(nil, http.StatusInternalServerError, err, resp, req)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"identifier": identifier,
+		"image":      ref.Repository(),
+		"previous":   ref.Tag(),
+		"new":        dr.Tag,
+	}).Info("http.deployHandler: manual deploy submitted")
+
+	s.auditRequest(req, types.AuditActionCreated, types.AuditResourceKindDeploy, identifier, map[string]string{
+		"image":    ref.Repository(),
+		"previous": ref.Tag(),
+		"tag":      dr.Tag,
+	})
+
+	response(&deployResponse{
+		Identifier: identifier,
+		Image:      ref.Repository(),
+		Previous:   ref.Tag(),
+		Tag:        dr.Tag,
+	}, http.StatusAccepted, nil, resp, req)
+}
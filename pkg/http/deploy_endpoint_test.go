@@ -0,0 +1,59 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+)
+
+func TestDeployHandler(t *testing.T) {
+	fp := &fakeProvider{}
+	ref, _ := image.Parse("karolisr/webhook-demo:0.0.1")
+	fp.images = append(fp.images, &types.TrackedImage{
+		Image:     ref,
+		Namespace: "default",
+		Provider:  "kubernetes",
+		Trigger:   types.TriggerTypeDefault,
+		Policy:    policy.NewSemverPolicy(policy.SemverPolicyTypeMajor),
+		Meta:      map[string]string{"name": "wd"},
+	})
+
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	deploy := func(body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/v1/deploy", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		req.SetBasicAuth("user-1", "secret")
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := deploy(`{"identifier": "default/other", "tag": "0.0.2"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("expected untracked workload to be rejected, got: %d", rec.Code)
+	}
+	if rec := deploy(`{"identifier": "default/wd"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected missing tag to be rejected, got: %d", rec.Code)
+	}
+
+	rec := deploy(`{"identifier": "default/wd", "tag": "0.0.2"}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+
+	if len(fp.submitted) != 1 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+	event := fp.submitted[0]
+	if event.Target != "default/wd" || event.Repository.Tag != "0.0.2" || event.Repository.Name != "index.docker.io/karolisr/webhook-demo" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+}
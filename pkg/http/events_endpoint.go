@@ -0,0 +1,89 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// audit log is polled for new entries, replicas share the database so
+// events of the leader are streamed by followers too
+var (
+	eventsPollInterval      = time.Second
+	eventsHeartbeatInterval = 15 * time.Second
+)
+
+// eventsHandler - streams new audit log entries as server-sent events until
+// the client disconnects, optionally filtered by comma separated resource
+// kinds (filter) and starting from an RFC3339 timestamp (since)
+func (s *TriggerServer) eventsHandler(resp http.ResponseWriter, req *http.Request) {
+	flusher, ok := resp.(http.Flusher)
+	if !ok {
+		http.Error(resp, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	query := &types.AuditLogQuery{
+		Order:              "created_at",
+		ResourceKindFilter: []string{"*"},
+	}
+	if filter := req.URL.Query().Get("filter"); filter != "" {
+		query.ResourceKindFilter = strings.Split(filter, ",")
+	}
+	start := time.Now()
+	if since := req.URL.Query().Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			http.Error(resp, fmt.Sprintf("invalid since '%s', expected RFC3339 timestamp", since), http.StatusBadRequest)
+			return
+		}
+		start = t
+	}
+
+	resp.Header().Set("Content-Type", "text/event-stream")
+	resp.Header().Set("Cache-Control", "no-cache")
+	resp.Header().Set("Connection", "keep-alive")
+	resp.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	poll := time.NewTicker(eventsPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(eventsHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	poller := store.NewAuditLogPoller(s.store, *query, start, eventsPollInterval)
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(resp, ": ping\n\n")
+			flusher.Flush()
+		case <-poll.C:
+			entries, err := poller.Poll()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+				}).Error("http.eventsHandler: failed to get audit logs")
+				continue
+			}
+
+			for _, entry := range entries {
+				data, err := json.Marshal(entry)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(resp, "id: %s\nevent: %s\ndata: %s\n\n", entry.ID, entry.Action, data)
+			}
+			flusher.Flush()
+		}
+	}
+}
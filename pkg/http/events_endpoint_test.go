@@ -0,0 +1,62 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestEventsStream(t *testing.T) {
+	interval := eventsPollInterval
+	eventsPollInterval = 10 * time.Millisecond
+	defer func() { eventsPollInterval = interval }()
+
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	// created before the stream is opened, not sent
+	srv.store.CreateAuditLog(&types.AuditLog{Action: types.AuditActionCreated, ResourceKind: types.AuditResourceKindPaused, Identifier: "default/old"})
+	time.Sleep(5 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequest("GET", "/v1/events?filter="+types.AuditResourceKindPaused, nil)
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	req = req.WithContext(ctx)
+	req.SetBasicAuth("user-1", "secret")
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		srv.router.ServeHTTP(rec, req)
+		close(done)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	srv.store.CreateAuditLog(&types.AuditLog{Action: types.AuditActionCreated, ResourceKind: types.AuditResourceKindPaused, Identifier: "default/wd"})
+	srv.store.CreateAuditLog(&types.AuditLog{Action: types.AuditActionCreated, ResourceKind: types.AuditResourceKindDeploy, Identifier: "default/wd"})
+	srv.store.CreateAuditLog(&types.AuditLog{Action: types.AuditActionDeleted, ResourceKind: types.AuditResourceKindPaused, Identifier: "default/wd"})
+	time.Sleep(100 * time.Millisecond)
+
+	cancel()
+	<-done
+
+	if rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("unexpected content type: %s", rec.Header().Get("Content-Type"))
+	}
+
+	body := rec.Body.String()
+	if strings.Contains(body, "default/old") {
+		t.Errorf("entries created before the stream was opened shouldn't be sent")
+	}
+	if strings.Count(body, "event: created\n") != 1 || strings.Count(body, "event: deleted\n") != 1 {
+		t.Errorf("unexpected events: %s", body)
+	}
+}
@@ -0,0 +1,211 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+	"github.com/prometheus/client_golang/prometheus"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var newGithubWebhooksCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "github_webhook_requests_total",
+		Help: "How many /v1/webhooks/github requests processed, partitioned by image.",
+	},
+	[]string{"image"},
+)
+
+func init() {
+	prometheus.MustRegister(newGithubWebhooksCounter)
+}
+
+// GitHub webhook headers
+const (
+	githubEventHeader     = "X-GitHub-Event"
+	githubSignatureHeader = "X-Hub-Signature-256"
+)
+
+// Example of GitHub package webhook (registry_package events have the same
+// structure with "registry_package" instead of "package")
+// {
+//   "action": "published",
+//   "package": {
+//     "name": "keel",
+//     "namespace": "keel-hq",
+//     "package_type": "CONTAINER",
+//     "owner": {
+//       "login": "keel-hq"
+//     },
+//     "package_version": {
+//       "version": "sha256:3c6bd0a...",
+//       "container_metadata": {
+//         "tag": {
+//           "name": "0.16.0",
+//           "digest": "sha256:3c6bd0a..."
+//         }
+//       },
+//       "package_url": "ghcr.io/keel-hq/keel:0.16.0"
+//     }
+//   }
+// }
+
+type githubPackageWebhook struct {
+	Action          string         `json:"action"`
+	Package         *githubPackage `json:"package"`
+	RegistryPackage *githubPackage `json:"registry_package"`
+}
+
+type githubPackage struct {
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace"`
+	PackageType string `json:"package_type"`
+	Owner       struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	PackageVersion struct {
+		Version           string `json:"version"`
+		PackageURL        string `json:"package_url"`
+		ContainerMetadata struct {
+			Tag struct {
+				Name   string `json:"name"`
+				Digest string `json:"digest"`
+			} `json:"tag"`
+		} `json:"container_metadata"`
+	} `json:"package_version"`
+}
+
+// repository - maps package version to image name and tag
+func (p *githubPackage) repository() (types.Repository, error) {
+	if !strings.EqualFold(p.PackageType, "container") && !strings.EqualFold(p.PackageType, "docker") {
+		return types.Repository{}, fmt.Errorf("unsupported package type '%s'", p.PackageType)
+	}
+
+	tag := p.PackageVersion.ContainerMetadata.Tag.Name
+	if tag == "" && !strings.HasPrefix(p.PackageVersion.Version, "sha256:") {
+		// docker.pkg.github.com packages use version as a tag
+		tag = p.PackageVersion.Version
+	}
+	if tag == "" {
+		return types.Repository{}, fmt.Errorf("package version is not tagged")
+	}
+
+	if p.PackageVersion.PackageURL != "" {
+		ref, err := image.Parse(p.PackageVersion.PackageURL)
+		if err == nil {
+			return types.Repository{
+				Name:   ref.Repository(),
+				Tag:    tag,
+				Digest: p.PackageVersion.ContainerMetadata.Tag.Digest,
+			}, nil
+		}
+	}
+
+	owner := p.Owner.Login
+	if owner == "" {
+		owner = p.Namespace
+	}
+	if owner == "" || p.Name == "" {
+		return types.Repository{}, fmt.Errorf("package name or owner is missing")
+	}
+
+	return types.Repository{
+		Name:   strings.ToLower("ghcr.io/" + owner + "/" + p.Name),
+		Tag:    tag,
+		Digest: p.PackageVersion.ContainerMetadata.Tag.Digest,
+	}, nil
+}
+
+// githubHandler - handles GitHub package and registry_package webhooks, when
+// webhook secret is configured requests have to be signed. GitHub can't send
+// credentials of authenticated webhooks so they require the secret.
+func (s *TriggerServer) githubHandler(resp http.ResponseWriter, req *http.Request) {
+	if s.authenticatedWebhooks && len(s.githubWebhookSecret) == 0 {
+		log.Warn("trigger.githubHandler: webhooks are authenticated but GitHub webhook secret is not set, request refused")
+		resp.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintf(resp, "webhook secret is not configured")
+		return
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("trigger.githubHandler: failed to read request")
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if len(s.githubWebhookSecret) > 0 && !validSignature(s.githubWebhookSecret, body, req.Header.Get(githubSignatureHeader)) {
+		log.Warn("trigger.githubHandler: invalid webhook signature")
+		resp.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintf(resp, "invalid signature")
+		return
+	}
+
+	switch req.Header.Get(githubEventHeader) {
+	case "ping":
+		resp.WriteHeader(http.StatusOK)
+		fmt.Fprintf(resp, "pong")
+		return
+	case "package", "registry_package":
+	default:
+		resp.WriteHeader(http.StatusOK)
+		fmt.Fprintf(resp, "event '%s' ignored", req.Header.Get(githubEventHeader))
+		return
+	}
+
+	gw := githubPackageWebhook{}
+	if err := json.Unmarshal(body, &gw); err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("trigger.githubHandler: failed to decode request")
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if gw.Action != "published" && gw.Action != "updated" {
+		resp.WriteHeader(http.StatusOK)
+		fmt.Fprintf(resp, "action '%s' ignored", gw.Action)
+		return
+	}
+
+	pkg := gw.Package
+	if pkg == nil {
+		pkg = gw.RegistryPackage
+	}
+	if pkg == nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "package cannot be empty")
+		return
+	}
+
+	repo, err := pkg.repository()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"package": pkg.Name,
+		}).Info("trigger.githubHandler: package ignored")
+		resp.WriteHeader(http.StatusOK)
+		fmt.Fprintf(resp, "package ignored: %s", err)
+		return
+	}
+
+	event := types.Event{
+		Repository:  repo,
+		CreatedAt:   time.Now(),
+		TriggerName: "github",
+	}
+	s.trigger(event)
+
+	resp.WriteHeader(http.StatusOK)
+
+	newGithubWebhooksCounter.With(prometheus.Labels{"image": event.Repository.Name}).Inc()
+}
@@ -0,0 +1,190 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var fakeGithubPackageWebhook = `{
+  "action": "published",
+  "package": {
+    "name": "Keel",
+    "namespace": "keel-hq",
+    "package_type": "CONTAINER",
+    "owner": {
+      "login": "keel-hq"
+    },
+    "package_version": {
+      "version": "sha256:3c6bd0a2c5e2bc1d6b1d2fb01b8ff1b1b0fcc2d6b0b7e0b8a4a3d2a1e0f9c8b7",
+      "container_metadata": {
+        "tag": {
+          "name": "0.16.0",
+          "digest": "sha256:3c6bd0a2c5e2bc1d6b1d2fb01b8ff1b1b0fcc2d6b0b7e0b8a4a3d2a1e0f9c8b7"
+        }
+      }
+    }
+  }
+}`
+
+var fakeGithubRegistryPackageWebhook = `{
+  "action": "published",
+  "registry_package": {
+    "name": "keel",
+    "namespace": "keel-hq",
+    "package_type": "CONTAINER",
+    "owner": {
+      "login": "keel-hq"
+    },
+    "package_version": {
+      "version": "sha256:3c6bd0a2c5e2bc1d6b1d2fb01b8ff1b1b0fcc2d6b0b7e0b8a4a3d2a1e0f9c8b7",
+      "container_metadata": {
+        "tag": {
+          "name": "0.17.0",
+          "digest": "sha256:3c6bd0a2c5e2bc1d6b1d2fb01b8ff1b1b0fcc2d6b0b7e0b8a4a3d2a1e0f9c8b7"
+        }
+      },
+      "package_url": "ghcr.io/keel-hq/keel:0.17.0"
+    }
+  }
+}`
+
+func TestGithubWebhookHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   string
+		payload string
+		tag     string
+	}{
+		{name: "package", event: "package", payload: fakeGithubPackageWebhook, tag: "0.16.0"},
+		{name: "registry_package", event: "registry_package", payload: fakeGithubRegistryPackageWebhook, tag: "0.17.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fp := &fakeProvider{}
+			srv, teardown := NewTestingServer(fp)
+			defer teardown()
+
+			req, err := http.NewRequest("POST", "/v1/webhooks/github", bytes.NewBuffer([]byte(tt.payload)))
+			if err != nil {
+				t.Fatalf("failed to create req: %s", err)
+			}
+			req.Header.Set(githubEventHeader, tt.event)
+
+			rec := httptest.NewRecorder()
+
+			srv.router.ServeHTTP(rec, req)
+			if rec.Code != 200 {
+				t.Errorf("unexpected status code: %d", rec.Code)
+				t.Log(rec.Body.String())
+			}
+
+			if len(fp.submitted) != 1 {
+				t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+			}
+
+			if fp.submitted[0].Repository.Name != "ghcr.io/keel-hq/keel" {
+				t.Errorf("expected ghcr.io/keel-hq/keel but got %s", fp.submitted[0].Repository.Name)
+			}
+
+			if fp.submitted[0].Repository.Tag != tt.tag {
+				t.Errorf("expected %s but got %s", tt.tag, fp.submitted[0].Repository.Tag)
+			}
+		})
+	}
+}
+
+func TestGithubWebhookHandlerSignature(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+	srv.githubWebhookSecret = []byte("very-secret")
+
+	// invalid signature
+	req, err := http.NewRequest("POST", "/v1/webhooks/github", bytes.NewBuffer([]byte(fakeGithubPackageWebhook)))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	req.Header.Set(githubEventHeader, "package")
+	req.Header.Set(githubSignatureHeader, sign([]byte("wrong-secret"), []byte(fakeGithubPackageWebhook)))
+
+	rec := httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("unexpected status code: %d", rec.Code)
+	}
+	if len(fp.submitted) != 0 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+
+	// valid signature
+	req, err = http.NewRequest("POST", "/v1/webhooks/github", bytes.NewBuffer([]byte(fakeGithubPackageWebhook)))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	req.Header.Set(githubEventHeader, "package")
+	req.Header.Set(githubSignatureHeader, sign([]byte("very-secret"), []byte(fakeGithubPackageWebhook)))
+
+	rec = httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("unexpected status code: %d", rec.Code)
+	}
+	if len(fp.submitted) != 1 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+}
+
+func TestGithubWebhookHandlerAuthenticatedWithoutSecret(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+	srv.authenticatedWebhooks = true
+
+	req, err := http.NewRequest("POST", "/v1/webhooks/github", bytes.NewBuffer([]byte(fakeGithubPackageWebhook)))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	req.Header.Set(githubEventHeader, "package")
+
+	rec := httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("unexpected status code: %d", rec.Code)
+	}
+	if len(fp.submitted) != 0 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+}
+
+func TestGithubWebhookHandlerPing(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	req, err := http.NewRequest("POST", "/v1/webhooks/github", bytes.NewBuffer([]byte(`{"zen": "Keep it logically awesome."}`)))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	req.Header.Set(githubEventHeader, "ping")
+
+	rec := httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("unexpected status code: %d", rec.Code)
+	}
+	if len(fp.submitted) != 0 {
+		t.Errorf("ping shouldn't submit events")
+	}
+}
+
+func TestGithubPackageNotContainer(t *testing.T) {
+	p := &githubPackage{Name: "keel", PackageType: "npm"}
+	p.PackageVersion.Version = "1.0.0"
+	_, err := p.repository()
+	if err == nil {
+		t.Errorf("expected error for npm package")
+	}
+}
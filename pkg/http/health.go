@@ -0,0 +1,157 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/extension/credentialshelper"
+)
+
+// healthCheckTimeout - checks that don't finish in time are failed
+const healthCheckTimeout = 5 * time.Second
+
+// HealthCheck - dependency check of the readiness probe
+type HealthCheck struct {
+	Name string
+	// Critical - keel isn't ready when the check fails, failures of other
+	// checks are only reported
+	Critical bool
+	Check    func() error
+}
+
+type healthCheckResult struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Critical   bool   `json:"critical"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+type healthResponse struct {
+	Status string              `json:"status"`
+	Checks []healthCheckResult `json:"checks,omitempty"`
+}
+
+// health check statuses
+const (
+	healthStatusOK      = "ok"
+	healthStatusFailed  = "failed"
+	healthStatusWarning = "warning"
+)
+
+// livenessHandler - keel process is serving requests, dependencies aren't
+// checked so their outages don't restart keel
+func (s *TriggerServer) livenessHandler(resp http.ResponseWriter, req *http.Request) {
+	response(&healthResponse{Status: healthStatusOK}, http.StatusOK, nil, resp, req)
+}
+
+// readinessHandler - runs dependency checks, responds with 503 when any of
+// the critical checks failed
+func (s *TriggerServer) readinessHandler(resp http.ResponseWriter, req *http.Request) {
+	checks := s.healthChecks()
+	results := make([]healthCheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			results[i] = runHealthCheck(check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	result := &healthResponse{Status: healthStatusOK, Checks: results}
+	code := http.StatusOK
+	for _, r := range results {
+		if r.Status == healthStatusFailed {
+			result.Status = healthStatusFailed
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	response(result, code, nil, resp, req)
+}
+
+func runHealthCheck(check HealthCheck) healthCheckResult {
+	result := healthCheckResult{Name: check.Name, Critical: check.Critical, Status: healthStatusOK}
+
+	started := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- check.Check()
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-time.After(healthCheckTimeout):
+		err = fmt.Errorf("check timed out after %s", healthCheckTimeout)
+	}
+	result.DurationMs = int64(time.Since(started) / time.Millisecond)
+
+	if err != nil {
+		result.Error = err.Error()
+		result.Status = healthStatusWarning
+		if check.Critical {
+			result.Status = healthStatusFailed
+		}
+	}
+	return result
+}
+
+// healthChecks - built-in checks followed by the ones configured in Opts
+func (s *TriggerServer) healthChecks() []HealthCheck {
+	checks := []HealthCheck{}
+	if s.store != nil {
+		checks = append(checks, HealthCheck{Name: "database", Critical: true, Check: s.store.Heartbeat})
+	}
+	checks = append(checks, s.extraHealthChecks...)
+
+	if s.providers != nil {
+		checks = append(checks, HealthCheck{Name: "registry_auth", Check: s.registryAuthHealth})
+	}
+
+	status := bot.Health()
+	names := make([]string, 0, len(status))
+	for name := range status {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		err := status[name]
+		checks = append(checks, HealthCheck{Name: "bot_" + name, Check: func() error { return err }})
+	}
+	return checks
+}
+
+// registryAuthHealth - fails when registries reject credentials of any of
+// the tracked images
+func (s *TriggerServer) registryAuthHealth() error {
+	images, err := s.providers.TrackedImages()
+	if err != nil {
+		return err
+	}
+
+	failing := make(map[string]bool)
+	for _, img := range images {
+		if _, ok := credentialshelper.AuthFailure(img); ok {
+			failing[img.Image.Remote()] = true
+		}
+	}
+	if len(failing) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(failing))
+	for name := range failing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("registry authentication fails for %d image(s): %s", len(names), strings.Join(names, ", "))
+}
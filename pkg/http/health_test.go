@@ -0,0 +1,69 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadiness(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	var kubernetesErr error
+	srv.extraHealthChecks = []HealthCheck{{
+		Name:     "kubernetes",
+		Critical: true,
+		Check:    func() error { return kubernetesErr },
+	}}
+
+	ready := func() (int, *healthResponse) {
+		req, err := http.NewRequest("GET", "/readyz", nil)
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+
+		var result healthResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("failed to unmarshal response: %s, body: %s", err, rec.Body.String())
+		}
+		return rec.Code, &result
+	}
+
+	code, result := ready()
+	if code != http.StatusOK || result.Status != healthStatusOK {
+		t.Fatalf("expected keel to be ready, got %d: %+v", code, result)
+	}
+	statuses := make(map[string]string)
+	for _, c := range result.Checks {
+		statuses[c.Name] = c.Status
+	}
+	for _, name := range []string{"database", "kubernetes", "registry_auth"} {
+		if statuses[name] != healthStatusOK {
+			t.Errorf("expected %s check to pass, got %q", name, statuses[name])
+		}
+	}
+
+	kubernetesErr = errors.New("connection refused")
+	code, result = ready()
+	if code != http.StatusServiceUnavailable || result.Status != healthStatusFailed {
+		t.Fatalf("expected keel not to be ready, got %d: %+v", code, result)
+	}
+	for _, c := range result.Checks {
+		if c.Name == "kubernetes" && c.Error != "connection refused" {
+			t.Errorf("unexpected kubernetes check: %+v", c)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/livez", nil)
+	rec := httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected keel to be live, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+var historyCSVHeader = []string{
+	"time", "provider", "kind", "identifier", "namespace", "name", "containers",
+	"image", "previousTag", "newTag", "previousDigest", "newDigest",
+	"trigger", "approvers", "durationMs", "status", "message",
+}
+
+// historyHandler - lists applied updates, newest first. Supports namespace,
+// name, image, provider and status filters, since/until time range (RFC3339),
+// limit/offset and format=csv for exporting
+func (s *TriggerServer) historyHandler(resp http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+
+	query := &types.UpdateRecordQuery{
+		Namespace: q.Get("namespace"),
+		Name:      q.Get("name"),
+		Image:     q.Get("image"),
+		Provider:  q.Get("provider"),
+		Status:    q.Get("status"),
+	}
+
+	var err error
+	for param, dst := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		v := q.Get(param)
+		if v == "" {
+			continue
+		}
+		*dst, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(resp, fmt.Sprintf("invalid %s '%s', expected RFC3339 time", param, v), http.StatusBadRequest)
+			return
+		}
+	}
+
+	opts, err := parseListOptions(req, nil)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query.Limit = opts.Limit
+	query.Offset = opts.Offset
+
+	records, err := s.store.ListUpdateRecords(query)
+	if err != nil {
+		response(nil, 500, err, resp, req)
+		return
+	}
+	if records == nil {
+		records = []*types.UpdateRecord{}
+	}
+
+	switch q.Get("format") {
+	case "", "json":
+		response(records, 200, nil, resp, req)
+	case "csv":
+		writeHistoryCSV(resp, records)
+	default:
+		http.Error(resp, fmt.Sprintf("unknown format '%s', supported: json, csv", q.Get("format")), http.StatusBadRequest)
+	}
+}
+
+func writeHistoryCSV(resp http.ResponseWriter, records []*types.UpdateRecord) {
+	resp.Header().Set("Content-Type", "text/csv")
+	resp.Header().Set("Content-Disposition", `attachment; filename="keel-history.csv"`)
+
+	w := csv.NewWriter(resp)
+	w.Write(historyCSVHeader)
+	for _, r := range records {
+		approvers := r.GetApprovers()
+		sort.Strings(approvers)
+		w.Write([]string{
+			r.CreatedAt.UTC().Format(time.RFC3339),
+			r.Provider,
+			r.ResourceKind,
+			r.Identifier,
+			r.Namespace,
+			r.Name,
+			r.Containers,
+			r.Image,
+			r.PreviousTag,
+			r.NewTag,
+			r.PreviousDigest,
+			r.NewDigest,
+			r.Trigger,
+			strings.Join(approvers, ","),
+			strconv.FormatInt(r.DurationMs, 10),
+			r.Status,
+			r.Message,
+		})
+	}
+	w.Flush()
+}
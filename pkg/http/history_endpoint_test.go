@@ -0,0 +1,78 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestUpdateHistory(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	for _, r := range []*types.UpdateRecord{
+		{Namespace: "default", Name: "wd", Image: "karolisr/webhook-demo", Containers: "wd", PreviousTag: "0.0.1", NewTag: "0.0.2", NewDigest: "sha256:abc", Trigger: "poll", DurationMs: 1500, Status: types.UpdateStatusSuccess},
+		{Namespace: "default", Name: "wd", Image: "karolisr/webhook-demo", PreviousTag: "0.0.2", NewTag: "0.0.3", Status: types.UpdateStatusFailed, Message: "quota exceeded"},
+		{Namespace: "staging", Name: "api", Image: "keel/api", PreviousTag: "1.0.0", NewTag: "1.1.0", Status: types.UpdateStatusSuccess},
+	} {
+		if _, err := srv.store.CreateUpdateRecord(r); err != nil {
+			t.Fatalf("failed to create update record: %s", err)
+		}
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", path, nil)
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		req.SetBasicAuth("user-1", "secret")
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/v1/history?namespace=default&status=success")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+	var records []*types.UpdateRecord
+	if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+		t.Fatalf("failed to unmarshal records: %s", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	if records[0].NewDigest != "sha256:abc" || records[0].Trigger != "poll" || records[0].DurationMs != 1500 {
+		t.Errorf("unexpected record: %+v", records[0])
+	}
+
+	rec = get("/v1/history?name=wd&format=csv")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("unexpected content type: %s", ct)
+	}
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read csv: %s", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected header and 2 rows, got %d", len(rows))
+	}
+	if rows[0][0] != "time" || len(rows[1]) != len(historyCSVHeader) {
+		t.Errorf("unexpected csv: %v", rows)
+	}
+
+	if rec := get("/v1/history?since=yesterday"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected invalid since to be rejected, got %d", rec.Code)
+	}
+	if rec := get("/v1/history?format=xml"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected unknown format to be rejected, got %d", rec.Code)
+	}
+}
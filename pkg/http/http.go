@@ -16,7 +16,10 @@ import (
 	"github.com/urfave/negroni"
 
 	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/extension/approval"
+	"github.com/keel-hq/keel/internal/hygiene"
 	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/internal/ratelimit"
 	"github.com/keel-hq/keel/pkg/auth"
 	"github.com/keel-hq/keel/pkg/store"
 	"github.com/keel-hq/keel/provider"
@@ -36,8 +39,21 @@ type Opts struct {
 
 	ApprovalManager approvals.Manager
 
+	// ApprovalCollector - configured approval collectors, collectors
+	// receiving callbacks are served on /v1/webhooks/approvals/{name}
+	ApprovalCollector *approval.MainCollector
+
 	Authenticator auth.Authenticator
 
+	// APITokens - static tokens of the approvals API
+	APITokens auth.APITokens
+
+	// OIDC - optional OpenID Connect provider of the UI sign in
+	OIDC *auth.OIDC
+	// OIDCUIRedirect - where users are sent with the keel token after
+	// signing in with the OIDC provider, defaults to /
+	OIDCUIRedirect string
+
 	GRC *k8s.GenericResourceCache
 
 	KubernetesClient kubernetes.Implementer
@@ -47,6 +63,47 @@ type Opts struct {
 	UIDir string
 
 	AuthenticatedWebhooks bool
+
+	// GithubWebhookSecret - optional secret that is used to validate
+	// GitHub webhook signatures
+	GithubWebhookSecret string
+
+	// NativeWebhookSecret - optional secret, when set native webhook requests
+	// have to be signed with HMAC of the request body
+	NativeWebhookSecret string
+	// NativeWebhookSignatureHeader - defaults to X-Keel-Signature
+	NativeWebhookSignatureHeader string
+	// NativeWebhookSignatureAlgorithm - sha1, sha256 (default) or sha512
+	NativeWebhookSignatureAlgorithm string
+
+	// CustomWebhooks - payload mappings for /v1/webhooks/custom/{name}
+	CustomWebhooks *CustomWebhooks
+
+	// WebhookHistorySize - how many received webhooks are stored for
+	// inspection and replays, 0 disables recording
+	WebhookHistorySize int
+
+	// HealthChecks - additional readiness checks, ie: Kubernetes API
+	HealthChecks []HealthCheck
+
+	// IsLeader - set when keel runs with leader election, webhooks that
+	// trigger updates are rejected by followers
+	IsLeader func() bool
+
+	// RateLimiter - optional update budgets, queued updates are listed on
+	// /v1/ratelimit
+	RateLimiter *ratelimit.Limiter
+
+	// Scope - optional namespaces and workloads keel can modify, resources out
+	// of scope can't be changed through the API either
+	Scope *kubernetes.Scope
+
+	// AccessChecker - optional, permissions of keel are listed on /v1/permissions
+	AccessChecker *k8s.AccessChecker
+
+	// HygieneReporter - optional, drift of deployed images is reported on
+	// /v1/reports/hygiene
+	HygieneReporter *hygiene.Reporter
 }
 
 // TriggerServer - webhook trigger & healthcheck server
@@ -54,33 +111,87 @@ type TriggerServer struct {
 	grc              *k8s.GenericResourceCache
 	kubernetesClient kubernetes.Implementer
 
-	providers        provider.Providers
-	approvalsManager approvals.Manager
-	port             int
-	server           *http.Server
-	router           *mux.Router
+	providers         provider.Providers
+	approvalsManager  approvals.Manager
+	approvalCollector *approval.MainCollector
+	port              int
+	server            *http.Server
+	router            *mux.Router
 
 	store         store.Store
 	authenticator auth.Authenticator
+	apiTokens     auth.APITokens
+
+	oidc           *auth.OIDC
+	oidcUIRedirect string
 
 	uiDir string
 
 	authenticatedWebhooks bool
+
+	githubWebhookSecret []byte
+
+	nativeWebhookVerifier *signatureVerifier
+
+	customWebhooks *CustomWebhooks
+
+	webhookHistorySize int
+	webhookHandlers    map[string]http.HandlerFunc
+
+	extraHealthChecks []HealthCheck
+
+	isLeader func() bool
+
+	rateLimiter *ratelimit.Limiter
+
+	scope *kubernetes.Scope
+
+	accessChecker *k8s.AccessChecker
+
+	hygieneReporter *hygiene.Reporter
 }
 
 // NewTriggerServer - create new HTTP trigger based server
 func NewTriggerServer(opts *Opts) *TriggerServer {
+	header := opts.NativeWebhookSignatureHeader
+	if header == "" {
+		header = DefaultNativeWebhookSignatureHeader
+	}
+	algorithm := opts.NativeWebhookSignatureAlgorithm
+	if algorithm == "" {
+		algorithm = "sha256"
+	}
+	if _, ok := signatureAlgorithms[strings.ToLower(algorithm)]; !ok && opts.NativeWebhookSecret != "" {
+		log.WithFields(log.Fields{
+			"algorithm": algorithm,
+		}).Error("http.NewTriggerServer: unsupported native webhook signature algorithm, native webhook requests will be rejected")
+	}
+
 	return &TriggerServer{
 		port:                  opts.Port,
 		grc:                   opts.GRC,
 		kubernetesClient:      opts.KubernetesClient,
 		providers:             opts.Providers,
 		approvalsManager:      opts.ApprovalManager,
+		approvalCollector:     opts.ApprovalCollector,
 		router:                mux.NewRouter(),
 		authenticator:         opts.Authenticator,
+		apiTokens:             opts.APITokens,
+		oidc:                  opts.OIDC,
+		oidcUIRedirect:        opts.OIDCUIRedirect,
 		store:                 opts.Store,
 		uiDir:                 opts.UIDir,
 		authenticatedWebhooks: opts.AuthenticatedWebhooks,
+		githubWebhookSecret:   []byte(opts.GithubWebhookSecret),
+		nativeWebhookVerifier: newSignatureVerifier(opts.NativeWebhookSecret, header, algorithm),
+		customWebhooks:        opts.CustomWebhooks,
+		webhookHistorySize:    opts.WebhookHistorySize,
+		extraHealthChecks:     opts.HealthChecks,
+		isLeader:              opts.IsLeader,
+		rateLimiter:           opts.RateLimiter,
+		scope:                 opts.Scope,
+		accessChecker:         opts.AccessChecker,
+		hygieneReporter:       opts.HygieneReporter,
 	}
 }
 
@@ -125,27 +236,47 @@ func (s *TriggerServer) registerRoutes(mux *mux.Router) {
 
 	// health endpoint for k8s to be happy
 	mux.HandleFunc("/healthz", s.healthHandler).Methods("GET", "OPTIONS")
+	mux.HandleFunc("/livez", s.livenessHandler).Methods("GET", "OPTIONS")
+	mux.HandleFunc("/readyz", s.readinessHandler).Methods("GET", "OPTIONS")
 	// version handler
 	mux.HandleFunc("/version", s.versionHandler).Methods("GET", "OPTIONS")
 
 	mux.Handle("/metrics", promhttp.Handler())
 
+	// approvals API, available to API tokens restricted to namespaces
+	if s.authenticator.Enabled() || len(s.apiTokens) > 0 {
+		mux.HandleFunc("/v1/approvals", s.requireScopedAuthorization(auth.RoleViewer, s.approvalsHandler)).Methods("GET", "OPTIONS")
+		mux.HandleFunc("/v1/approvals/{id}/approve", s.requireScopedAuthorization(auth.RoleApprover, s.approvalVoteHandler(actionApprove))).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/approvals/{id}/reject", s.requireScopedAuthorization(auth.RoleApprover, s.approvalVoteHandler(actionReject))).Methods("POST", "OPTIONS")
+	}
+
 	if s.authenticator.Enabled() {
 		log.Info("authentication enabled, setting up admin HTTP handlers")
 		// auth
 		mux.HandleFunc("/v1/auth/login", s.loginHandler).Methods("POST", "OPTIONS")
+		if s.oidc != nil {
+			mux.HandleFunc("/v1/auth/oidc/login", s.oidcLoginHandler).Methods("GET")
+			mux.HandleFunc("/v1/auth/oidc/callback", s.oidcCallbackHandler).Methods("GET")
+		}
 		mux.HandleFunc("/v1/auth/info", s.requireAdminAuthorization(s.userInfoHandler)).Methods("GET", "OPTIONS")
 		mux.HandleFunc("/v1/auth/user", s.requireAdminAuthorization(s.userInfoHandler)).Methods("GET", "OPTIONS")
-		mux.HandleFunc("/v1/auth/logout", s.requireAdminAuthorization(s.logoutHandler)).Methods("POST", "GET", "OPTIONS")
+		mux.HandleFunc("/v1/auth/logout", s.requireRoleAuthorization(auth.RoleViewer, s.logoutHandler)).Methods("POST", "GET", "OPTIONS")
 		mux.HandleFunc("/v1/auth/refresh", s.requireAdminAuthorization(s.refreshHandler)).Methods("GET", "OPTIONS")
 
-		// approvals
-		mux.HandleFunc("/v1/approvals", s.requireAdminAuthorization(s.approvalsHandler)).Methods("GET", "OPTIONS")
 		// approving/rejecting
-		mux.HandleFunc("/v1/approvals", s.requireAdminAuthorization(s.approvalApproveHandler)).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/approvals", s.requireRoleAuthorization(auth.RoleApprover, s.approvalApproveHandler)).Methods("POST", "OPTIONS")
 		// updating required approvals count
 		mux.HandleFunc("/v1/approvals", s.requireAdminAuthorization(s.approvalSetHandler)).Methods("PUT", "OPTIONS")
 
+		// maintenance mode
+		mux.HandleFunc("/v1/maintenance", s.requireAdminAuthorization(s.maintenanceHandler)).Methods("GET", "OPTIONS")
+		mux.HandleFunc("/v1/maintenance", s.requireAdminAuthorization(s.maintenanceSetHandler)).Methods("PUT", "OPTIONS")
+
+		// API tokens
+		mux.HandleFunc("/v1/tokens", s.requireRoleAuthorization(auth.RoleAdmin, s.apiTokensHandler)).Methods("GET", "OPTIONS")
+		mux.HandleFunc("/v1/tokens", s.requireRoleAuthorization(auth.RoleAdmin, s.apiTokenCreateHandler)).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/tokens/{id}", s.requireRoleAuthorization(auth.RoleAdmin, s.apiTokenRevokeHandler)).Methods("DELETE", "OPTIONS")
+
 		// available resources
 		mux.HandleFunc("/v1/resources", s.requireAdminAuthorization(s.resourcesHandler)).Methods("GET", "OPTIONS")
 
@@ -155,9 +286,38 @@ func (s *TriggerServer) registerRoutes(mux *mux.Router) {
 		mux.HandleFunc("/v1/tracked", s.requireAdminAuthorization(s.trackedHandler)).Methods("GET", "OPTIONS")
 		mux.HandleFunc("/v1/tracked", s.requireAdminAuthorization(s.trackSetHandler)).Methods("PUT", "OPTIONS")
 
+		// paused workloads
+		mux.HandleFunc("/v1/paused", s.requireAdminAuthorization(s.pausedHandler)).Methods("GET", "OPTIONS")
+		mux.HandleFunc("/v1/paused", s.requireAdminAuthorization(s.pauseHandler)).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/paused/{namespace}/{name}", s.requireAdminAuthorization(s.resumeHandler)).Methods("DELETE", "OPTIONS")
+
+		// manual deploys, bypass update policies
+		mux.HandleFunc("/v1/deploy", s.requireAdminAuthorization(s.requireLeader(s.deployHandler))).Methods("POST", "OPTIONS")
+
+		// test messages through bots and notification senders
+		mux.HandleFunc("/v1/announce", s.requireAdminAuthorization(s.announceHandler)).Methods("POST", "OPTIONS")
+
 		// status
 		mux.HandleFunc("/v1/audit", s.requireAdminAuthorization(s.adminAuditLogHandler)).Methods("GET", "OPTIONS")
+		mux.HandleFunc("/v1/events", s.requireAdminAuthorization(s.eventsHandler)).Methods("GET", "OPTIONS")
+		mux.HandleFunc("/v1/ratelimit", s.requireAdminAuthorization(s.rateLimitHandler)).Methods("GET", "OPTIONS")
+		mux.HandleFunc("/v1/permissions", s.requireAdminAuthorization(s.permissionsHandler)).Methods("GET", "OPTIONS")
+		mux.HandleFunc("/v1/reports/hygiene", s.requireAdminAuthorization(s.hygieneHandler)).Methods("GET", "OPTIONS")
 		mux.HandleFunc("/v1/stats", s.requireAdminAuthorization(s.statsHandler)).Methods("GET", "OPTIONS")
+		mux.HandleFunc("/v1/history", s.requireAdminAuthorization(s.historyHandler)).Methods("GET", "OPTIONS")
+
+		// runtime log levels
+		mux.HandleFunc("/v1/log-levels", s.requireRoleAuthorization(auth.RoleAdmin, s.logLevelsHandler)).Methods("GET", "OPTIONS")
+		mux.HandleFunc("/v1/log-levels", s.requireRoleAuthorization(auth.RoleAdmin, s.logLevelsSetHandler)).Methods("PUT", "OPTIONS")
+
+		// state export and import, for migrations and disaster recovery
+		mux.HandleFunc("/v1/state/export", s.requireRoleAuthorization(auth.RoleAdmin, s.stateExportHandler)).Methods("GET", "OPTIONS")
+		mux.HandleFunc("/v1/state/import", s.requireRoleAuthorization(auth.RoleAdmin, s.stateImportHandler)).Methods("POST", "OPTIONS")
+
+		// received webhooks
+		mux.HandleFunc("/v1/webhooks/received", s.requireAdminAuthorization(s.webhookRecordsHandler)).Methods("GET", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/received/{id}", s.requireAdminAuthorization(s.webhookRecordHandler)).Methods("GET", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/received/{id}/replay", s.requireAdminAuthorization(s.webhookReplayHandler)).Methods("POST", "OPTIONS")
 
 		if s.uiDir != "" {
 			// Serve static assets directly.
@@ -177,26 +337,61 @@ func (s *TriggerServer) registerRoutes(mux *mux.Router) {
 
 func (s *TriggerServer) registerWebhookRoutes(mux *mux.Router) {
 
+	// received webhooks are recorded, see webhook_records.go, followers
+	// reject the ones that trigger updates so senders retry
+	native := s.recordWebhook("native", s.requireLeader(s.nativeHandler))
+	dockerHub := s.recordWebhook("dockerhub", s.requireLeader(s.dockerHubHandler))
+	quay := s.recordWebhook("quay", s.requireLeader(s.quayHandler))
+	azure := s.recordWebhook("azure", s.requireLeader(s.azureHandler))
+	cloudEvents := s.recordWebhook("cloudevents", s.requireLeader(s.cloudEventsHandler))
+	custom := s.recordWebhook("custom", s.requireLeader(s.customWebhookHandler))
+	registry := s.recordWebhook("registry", s.requireLeader(s.registryNotificationHandler))
+	approvalCollector := s.recordWebhook("approvals", s.approvalCollectorHandler)
+	github := s.recordWebhook("github", s.requireLeader(s.githubHandler))
+
 	if s.authenticatedWebhooks {
-		mux.HandleFunc("/v1/webhooks/native", s.requireAdminAuthorization(s.nativeHandler)).Methods("POST", "OPTIONS")
-		mux.HandleFunc("/v1/webhooks/dockerhub", s.requireAdminAuthorization(s.dockerHubHandler)).Methods("POST", "OPTIONS")
-		mux.HandleFunc("/v1/webhooks/quay", s.requireAdminAuthorization(s.quayHandler)).Methods("POST", "OPTIONS")
-		mux.HandleFunc("/v1/webhooks/azure", s.requireAdminAuthorization(s.azureHandler)).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/native", s.requireAdminAuthorization(native)).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/dockerhub", s.requireAdminAuthorization(dockerHub)).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/quay", s.requireAdminAuthorization(quay)).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/azure", s.requireAdminAuthorization(azure)).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/cloudevents", s.requireAdminAuthorization(cloudEvents)).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/custom/{name}", s.requireAdminAuthorization(custom)).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/approvals/{name}", s.requireAdminAuthorization(approvalCollector)).Methods("POST", "OPTIONS")
 
 		// Docker registry notifications, used by Docker, Gitlab, Harbor
 		// https://docs.docker.com/registry/notifications/
 		//https://docs.gitlab.com/ee/administration/container_registry.html#configure-container-registry-notifications
-		mux.HandleFunc("/v1/webhooks/registry", s.registryNotificationHandler).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/registry", registry).Methods("POST", "OPTIONS")
 	} else {
-		mux.HandleFunc("/v1/webhooks/native", s.nativeHandler).Methods("POST", "OPTIONS")
-		mux.HandleFunc("/v1/webhooks/dockerhub", s.dockerHubHandler).Methods("POST", "OPTIONS")
-		mux.HandleFunc("/v1/webhooks/quay", s.quayHandler).Methods("POST", "OPTIONS")
-		mux.HandleFunc("/v1/webhooks/azure", s.azureHandler).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/native", native).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/dockerhub", dockerHub).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/quay", quay).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/azure", azure).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/cloudevents", cloudEvents).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/custom/{name}", custom).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/approvals/{name}", approvalCollector).Methods("POST", "OPTIONS")
 
 		// Docker registry notifications, used by Docker, Gitlab, Harbor
 		// https://docs.docker.com/registry/notifications/
 		//https://docs.gitlab.com/ee/administration/container_registry.html#configure-container-registry-notifications
-		mux.HandleFunc("/v1/webhooks/registry", s.registryNotificationHandler).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/registry", registry).Methods("POST", "OPTIONS")
+	}
+
+	// GitHub can't send basic auth credentials, requests are validated
+	// with webhook secret signature instead, authenticated webhooks refuse
+	// unsigned requests
+	mux.HandleFunc("/v1/webhooks/github", github).Methods("POST", "OPTIONS")
+}
+
+// requireLeader - responds with 503 when another replica leads, only the
+// leader submits events to providers
+func (s *TriggerServer) requireLeader(handler http.HandlerFunc) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		if s.isLeader != nil && !s.isLeader() && req.Method != http.MethodOptions {
+			http.Error(resp, "not the leader, retry later", http.StatusServiceUnavailable)
+			return
+		}
+		handler(resp, req)
 	}
 }
 
@@ -302,7 +497,7 @@ func (s *TriggerServer) userInfoHandler(resp http.ResponseWriter, req *http.Requ
 		Status:        1,
 		LastLoginIP:   "",
 		LastLoginTime: time.Now().Unix(),
-		RoleID:        "admin",
+		RoleID:        string(user.Role),
 	}
 
 	response(&ui, 200, nil, resp, req)
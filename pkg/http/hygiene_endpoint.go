@@ -0,0 +1,32 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/keel-hq/keel/internal/hygiene"
+)
+
+type hygieneResponse struct {
+	Enabled bool `json:"enabled"`
+	*hygiene.Report
+}
+
+// hygieneHandler - latest hygiene report of deployed images, report is
+// generated when there isn't one yet (ie: on followers) or refresh=true is set
+func (s *TriggerServer) hygieneHandler(resp http.ResponseWriter, req *http.Request) {
+	if s.hygieneReporter == nil {
+		response(&hygieneResponse{}, http.StatusOK, nil, resp, req)
+		return
+	}
+
+	report := s.hygieneReporter.Last()
+	if report == nil || req.URL.Query().Get("refresh") == "true" {
+		var err error
+		report, err = s.hygieneReporter.Generate()
+		if err != nil {
+			response(nil, http.StatusInternalServerError, err, resp, req)
+			return
+		}
+	}
+	response(&hygieneResponse{Enabled: true, Report: report}, http.StatusOK, nil, resp, req)
+}
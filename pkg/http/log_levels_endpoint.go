@@ -0,0 +1,59 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/keel-hq/keel/internal/logging"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// logLevelsHandler - default and per module log levels
+func (s *TriggerServer) logLevelsHandler(resp http.ResponseWriter, req *http.Request) {
+	response(logging.Current(), http.StatusOK, nil, resp, req)
+}
+
+// logLevelsSetHandler - replaces log levels without restarting keel, modules
+// that aren't in the request use the default level
+func (s *TriggerServer) logLevelsSetHandler(resp http.ResponseWriter, req *http.Request) {
+	var levels logging.Levels
+	dec := json.NewDecoder(req.Body)
+	defer req.Body.Close()
+
+	err := dec.Decode(&levels)
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "%s", err)
+		return
+	}
+
+	err = logging.Configure(levels)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	current := logging.Current()
+	modules := make([]string, 0, len(current.Modules))
+	for module, level := range current.Modules {
+		modules = append(modules, module+"="+level)
+	}
+	sort.Strings(modules)
+
+	log.WithFields(log.Fields{
+		"default": current.Default,
+		"modules": strings.Join(modules, ","),
+	}).Info("http.logLevelsSetHandler: log levels updated")
+
+	s.auditRequest(req, types.AuditActionUpdated, types.AuditResourceKindLogLevels, "log-levels", map[string]string{
+		"default": current.Default,
+		"modules": strings.Join(modules, ","),
+	})
+
+	response(current, http.StatusOK, nil, resp, req)
+}
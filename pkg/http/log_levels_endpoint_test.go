@@ -0,0 +1,47 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/keel-hq/keel/internal/logging"
+)
+
+func TestLogLevels(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+	defer logging.Configure(logging.Levels{})
+
+	do := func(method, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, "/v1/log-levels", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		req.SetBasicAuth("user-1", "secret")
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("PUT", `{"default": "warning", "modules": {"trigger.poll": "debug"}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = do("GET", "")
+	var levels logging.Levels
+	if err := json.Unmarshal(rec.Body.Bytes(), &levels); err != nil {
+		t.Fatalf("failed to unmarshal levels: %s", err)
+	}
+	if levels.Default != "warning" || levels.Modules["trigger.poll"] != "debug" {
+		t.Errorf("unexpected levels: %+v", levels)
+	}
+
+	if rec := do("PUT", `{"default": "verbose"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected invalid level to be rejected, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/keel-hq/keel/pkg/auth"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type maintenanceStatus struct {
+	Enabled bool `json:"enabled"`
+	// User and Since - who enabled maintenance mode and when
+	User  string     `json:"user,omitempty"`
+	Since *time.Time `json:"since,omitempty"`
+}
+
+type maintenanceRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+func (s *TriggerServer) maintenanceStatus() *maintenanceStatus {
+	paused := store.Maintenance(s.store)
+	if paused == nil {
+		return &maintenanceStatus{}
+	}
+	return &maintenanceStatus{Enabled: true, User: paused.User, Since: &paused.CreatedAt}
+}
+
+// maintenanceHandler - whether automatic updates are suspended cluster-wide
+func (s *TriggerServer) maintenanceHandler(resp http.ResponseWriter, req *http.Request) {
+	response(s.maintenanceStatus(), http.StatusOK, nil, resp, req)
+}
+
+// maintenanceSetHandler - enables or disables maintenance mode, updates that
+// would have been applied are reported while it's enabled
+func (s *TriggerServer) maintenanceSetHandler(resp http.ResponseWriter, req *http.Request) {
+	var mr maintenanceRequest
+	dec := json.NewDecoder(req.Body)
+	defer req.Body.Close()
+
+	err := dec.Decode(&mr)
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "%s", err)
+		return
+	}
+
+	user := "api"
+	if u := auth.GetAccountFromCtx(req.Context()); u != nil {
+		user = u.Username
+	}
+
+	changed, err := store.SetMaintenance(s.store, mr.Enabled, user)
+	if err != nil {
+		response(nil, http.StatusInternalServerError, err, resp, req)
+		return
+	}
+
+	if changed {
+		action := "disabled"
+		if mr.Enabled {
+			action = "enabled"
+		}
+		log.WithFields(log.Fields{
+			"user":    user,
+			"enabled": mr.Enabled,
+		}).Info("http.maintenanceSetHandler: maintenance mode " + action)
+
+		s.auditRequest(req, types.AuditActionUpdated, types.AuditResourceKindMaintenance, types.PausedAllIdentifier, map[string]string{
+			"enabled": fmt.Sprintf("%t", mr.Enabled),
+		})
+	}
+
+	response(s.maintenanceStatus(), http.StatusOK, nil, resp, req)
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	do := func(method, body string) *maintenanceStatus {
+		req, err := http.NewRequest(method, "/v1/maintenance", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		req.SetBasicAuth("user-1", "secret")
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+		}
+
+		var status maintenanceStatus
+		if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+			t.Fatalf("failed to unmarshal status: %s", err)
+		}
+		return &status
+	}
+
+	if status := do("GET", ""); status.Enabled {
+		t.Errorf("expected maintenance mode to be disabled")
+	}
+
+	status := do("PUT", `{"enabled": true}`)
+	if !status.Enabled || status.User != "user-1" || status.Since == nil {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if status := do("GET", ""); !status.Enabled {
+		t.Errorf("expected maintenance mode to be enabled")
+	}
+
+	if status := do("PUT", `{"enabled": false}`); status.Enabled {
+		t.Errorf("expected maintenance mode to be disabled")
+	}
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"time"
 
@@ -24,10 +25,31 @@ func init() {
 	prometheus.MustRegister(newNativeWebhooksCounter)
 }
 
-// nativeHandler - used to trigger event directly
+// DefaultNativeWebhookSignatureHeader - header that contains native webhook
+// HMAC signature when header name is not configured
+const DefaultNativeWebhookSignatureHeader = "X-Keel-Signature"
+
+// nativeHandler - used to trigger event directly, when webhook secret is
+// configured requests have to be signed
 func (s *TriggerServer) nativeHandler(resp http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("failed to read request")
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if s.nativeWebhookVerifier != nil && !s.nativeWebhookVerifier.valid(body, req.Header.Get(s.nativeWebhookVerifier.header)) {
+		log.Warn("trigger.nativeHandler: missing or invalid webhook signature")
+		resp.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintf(resp, "invalid signature")
+		return
+	}
+
 	repo := types.Repository{}
-	if err := json.NewDecoder(req.Body).Decode(&repo); err != nil {
+	if err := json.Unmarshal(body, &repo); err != nil {
 		log.WithFields(log.Fields{
 			"error": err,
 		}).Error("failed to decode request")
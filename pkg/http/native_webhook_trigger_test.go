@@ -2,6 +2,9 @@ package http
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -68,6 +71,7 @@ func NewTestingServer(fp provider.Provider) (*TriggerServer, func()) {
 
 type fakeProvider struct {
 	submitted []types.Event
+	deleted   []types.Event
 	images    []*types.TrackedImage
 }
 
@@ -76,6 +80,11 @@ func (p *fakeProvider) Submit(event types.Event) error {
 	return nil
 }
 
+func (p *fakeProvider) TagDeleted(event types.Event) error {
+	p.deleted = append(p.deleted, event)
+	return nil
+}
+
 func (p *fakeProvider) TrackedImages() ([]*types.TrackedImage, error) {
 	return p.images, nil
 }
@@ -136,3 +145,106 @@ func TestNativeWebhookHandlerNoRepoName(t *testing.T) {
 	}
 
 }
+
+func TestNativeWebhookHandlerFollower(t *testing.T) {
+
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+	leader := false
+	srv.isLeader = func() bool { return leader }
+
+	send := func() int {
+		req, err := http.NewRequest("POST", "/v1/webhooks/native", bytes.NewBuffer([]byte(`{"name": "gcr.io/v2-namespace/hello-world", "tag": "1.1.1"}`)))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := send(); code != http.StatusServiceUnavailable {
+		t.Errorf("unexpected status code: %d", code)
+	}
+	if len(fp.submitted) != 0 {
+		t.Fatalf("follower shouldn't submit events, got: %d", len(fp.submitted))
+	}
+
+	leader = true
+	if code := send(); code != http.StatusOK {
+		t.Errorf("unexpected status code: %d", code)
+	}
+	if len(fp.submitted) != 1 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+}
+
+func TestNativeWebhookHandlerSignature(t *testing.T) {
+
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+	srv.nativeWebhookVerifier = newSignatureVerifier("very-secret", "X-Signature", "sha512")
+
+	body := []byte(`{"name": "gcr.io/v2-namespace/hello-world", "tag": "1.1.1"}`)
+	mac := hmac.New(sha512.New, []byte("very-secret"))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name      string
+		signature string
+		wantCode  int
+	}{
+		{name: "unsigned", signature: "", wantCode: 401},
+		{name: "invalid", signature: "sha512=" + hex.EncodeToString([]byte("invalid")), wantCode: 401},
+		{name: "not hex", signature: "zzz", wantCode: 401},
+		{name: "signed", signature: signature, wantCode: 200},
+		{name: "signed with prefix", signature: "sha512=" + signature, wantCode: 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/v1/webhooks/native", bytes.NewBuffer(body))
+			if err != nil {
+				t.Fatalf("failed to create req: %s", err)
+			}
+			if tt.signature != "" {
+				req.Header.Set("X-Signature", tt.signature)
+			}
+
+			rec := httptest.NewRecorder()
+
+			srv.router.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Errorf("unexpected status code: %d", rec.Code)
+			}
+		})
+	}
+
+	if len(fp.submitted) != 2 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+}
+
+func TestNativeWebhookHandlerUnknownAlgorithm(t *testing.T) {
+
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+	srv.nativeWebhookVerifier = newSignatureVerifier("very-secret", DefaultNativeWebhookSignatureHeader, "md5")
+
+	req, err := http.NewRequest("POST", "/v1/webhooks/native", bytes.NewBuffer([]byte(`{"name": "gcr.io/v2-namespace/hello-world", "tag": "1.1.1"}`)))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	req.Header.Set(DefaultNativeWebhookSignatureHeader, "abcd")
+
+	rec := httptest.NewRecorder()
+
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != 401 {
+		t.Errorf("unexpected status code: %d", rec.Code)
+	}
+}
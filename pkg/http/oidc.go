@@ -0,0 +1,87 @@
+package http
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const oidcStateCookie = "keel_oidc_state"
+
+// oidcLoginHandler - redirects UI users to the OIDC provider, state is kept
+// in a cookie and checked by the callback
+func (s *TriggerServer) oidcLoginHandler(resp http.ResponseWriter, req *http.Request) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	state := hex.EncodeToString(b)
+
+	authURL, err := s.oidc.AuthCodeURL(state)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("http.oidcLoginHandler: failed to get authorization URL")
+		http.Error(resp, "OIDC provider is not available", http.StatusBadGateway)
+		return
+	}
+
+	http.SetCookie(resp, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/v1/auth/oidc",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   req.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(resp, req, authURL, http.StatusFound)
+}
+
+// oidcCallbackHandler - exchanges authorization code, UI receives keel token
+// in the URL fragment of the redirect
+func (s *TriggerServer) oidcCallbackHandler(resp http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	if e := q.Get("error"); e != "" {
+		http.Error(resp, "OIDC sign in failed: "+e, http.StatusUnauthorized)
+		return
+	}
+
+	cookie, err := req.Cookie(oidcStateCookie)
+	if err != nil || q.Get("state") == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(q.Get("state"))) != 1 {
+		http.Error(resp, "invalid OIDC state", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(resp, &http.Cookie{Name: oidcStateCookie, Path: "/v1/auth/oidc", MaxAge: -1})
+
+	user, err := s.oidc.Exchange(q.Get("code"))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Warn("http.oidcCallbackHandler: OIDC sign in failed")
+		http.Error(resp, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	authResp, err := s.authenticator.GenerateToken(*user)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"user": user.Username,
+		"role": user.Role,
+	}).Info("http.oidcCallbackHandler: OIDC sign in successful")
+
+	redirect := s.oidcUIRedirect
+	if redirect == "" {
+		redirect = "/"
+	}
+	http.Redirect(resp, req, redirect+"#token="+url.QueryEscape(authResp.Token), http.StatusFound)
+}
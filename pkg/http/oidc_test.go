@@ -0,0 +1,189 @@
+package http
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/pkg/auth"
+	"github.com/keel-hq/keel/provider"
+	"github.com/keel-hq/keel/types"
+)
+
+type fakeIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+	issuer := &fakeIssuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.URL,
+			"authorization_endpoint": issuer.URL + "/authorize",
+			"token_endpoint":         issuer.URL + "/token",
+			"jwks_uri":               issuer.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "key-1",
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	issuer.Server = httptest.NewServer(mux)
+	return issuer
+}
+
+func (i *fakeIssuer) token(t *testing.T, claims jwt.MapClaims) string {
+	if _, ok := claims["iss"]; !ok {
+		claims["iss"] = i.URL
+	}
+	if _, ok := claims["aud"]; !ok {
+		claims["aud"] = []string{"keel"}
+	}
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "key-1"
+	signed, err := token.SignedString(i.key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %s", err)
+	}
+	return signed
+}
+
+func TestOIDCRoles(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	defer issuer.Close()
+
+	roles, err := auth.ParseRoleMapping("admin=keel-admins,approver=sre|release,viewer=*")
+	if err != nil {
+		t.Fatalf("failed to parse roles: %s", err)
+	}
+	oidc, err := auth.NewOIDC(auth.OIDCOpts{
+		IssuerURL: issuer.URL,
+		ClientID:  "keel",
+		Roles:     roles,
+	})
+	if err != nil {
+		t.Fatalf("failed to create OIDC: %s", err)
+	}
+
+	fp := &fakeProvider{}
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	am := approvals.New(&approvals.Opts{
+		Store: store,
+	})
+
+	providers := provider.New([]provider.Provider{fp}, am)
+	srv := NewTriggerServer(&Opts{
+		Providers:       providers,
+		ApprovalManager: am,
+		Authenticator:   auth.New(&auth.Opts{OIDC: oidc}),
+		OIDC:            oidc,
+		Store:           store,
+	})
+	srv.registerRoutes(srv.router)
+
+	err = am.Create(&types.Approval{
+		Provider:       types.ProviderTypeKubernetes,
+		Identifier:     "deployment/default/app:1.1.0",
+		VotesRequired:  1,
+		NewVersion:     "1.1.0",
+		CurrentVersion: "1.0.0",
+		Deadline:       time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to create approval: %s", err)
+	}
+	approval, err := am.Get("deployment/default/app:1.1.0")
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+
+	viewer := issuer.token(t, jwt.MapClaims{"sub": "1", "email": "viewer@example.com"})
+	approver := issuer.token(t, jwt.MapClaims{"sub": "2", "email": "sre@example.com", "groups": []string{"sre"}})
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, nil)
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do("GET", "/v1/approvals", viewer); rec.Code != http.StatusOK {
+		t.Errorf("expected viewer to list approvals, got %d", rec.Code)
+	}
+	if rec := do("POST", "/v1/approvals/"+approval.ID+"/approve", viewer); rec.Code != http.StatusForbidden {
+		t.Errorf("expected viewer to be forbidden from approving, got %d", rec.Code)
+	}
+	if rec := do("PUT", "/v1/approvals", approver); rec.Code != http.StatusForbidden {
+		t.Errorf("expected approver to be forbidden from changing approvals, got %d", rec.Code)
+	}
+
+	wrongAudience := issuer.token(t, jwt.MapClaims{"sub": "3", "email": "other@example.com", "aud": "other"})
+	if rec := do("GET", "/v1/approvals", wrongAudience); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected token of other audience to be rejected, got %d", rec.Code)
+	}
+
+	rec := do("POST", "/v1/approvals/"+approval.ID+"/approve", approver)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+	approved, err := am.Get("deployment/default/app:1.1.0")
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+	if voters := approved.GetVoters(); len(voters) != 1 || voters[0] != "sre@example.com" {
+		t.Errorf("unexpected voters: %v", voters)
+	}
+
+	logs, err := store.GetAuditLogs(&types.AuditLogQuery{ResourceKindFilter: []string{types.AuditResourceKindApproval}})
+	if err != nil {
+		t.Fatalf("failed to get audit logs: %s", err)
+	}
+	found := false
+	for _, l := range logs {
+		if l.Action == types.AuditActionApprovalApproved && l.Username == "sre@example.com" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected approval audit log of sre@example.com, got %+v", logs)
+	}
+
+	// excluded from basic auth when only OIDC is configured
+	req, _ := http.NewRequest("GET", "/v1/approvals", nil)
+	req.SetBasicAuth("", "")
+	rec = httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected empty basic auth to be rejected, got %d", rec.Code)
+	}
+}
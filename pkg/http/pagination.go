@@ -0,0 +1,110 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// totalCountHeader - number of the matching items when the list is paginated
+const totalCountHeader = "X-Total-Count"
+
+// listOptions - limit, offset and sort query parameters of the list endpoints,
+// ie: ?limit=50&offset=100&sort=namespace&order=desc
+type listOptions struct {
+	Limit  int
+	Offset int
+	Sort   string
+	Desc   bool
+}
+
+func parseListOptions(req *http.Request, sortFields map[string]func(i int) string) (*listOptions, error) {
+	q := req.URL.Query()
+	opts := &listOptions{}
+
+	var err error
+	if v := q.Get("limit"); v != "" {
+		opts.Limit, err = strconv.Atoi(v)
+		if err != nil || opts.Limit < 0 {
+			return nil, fmt.Errorf("invalid limit '%s'", v)
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		opts.Offset, err = strconv.Atoi(v)
+		if err != nil || opts.Offset < 0 {
+			return nil, fmt.Errorf("invalid offset '%s'", v)
+		}
+	}
+
+	opts.Sort = q.Get("sort")
+	if _, ok := sortFields[opts.Sort]; opts.Sort != "" && !ok {
+		fields := make([]string, 0, len(sortFields))
+		for f := range sortFields {
+			fields = append(fields, f)
+		}
+		sort.Strings(fields)
+		return nil, fmt.Errorf("unknown sort field '%s', supported: %s", opts.Sort, strings.Join(fields, ", "))
+	}
+
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		opts.Desc = true
+	default:
+		return nil, fmt.Errorf("unknown order '%s', supported: asc, desc", q.Get("order"))
+	}
+	return opts, nil
+}
+
+// apply - sorts the list with swap and returns page bounds, total count is
+// set in the response header
+func (o *listOptions) apply(resp http.ResponseWriter, n int, sortFields map[string]func(i int) string, swap func(i, j int)) (start, end int) {
+	if key, ok := sortFields[o.Sort]; ok {
+		// keys are computed upfront as they follow the items when swapped
+		keys := make([]string, n)
+		for i := range keys {
+			keys[i] = key(i)
+		}
+		sort.Stable(&keySorter{keys: keys, desc: o.Desc, swap: swap})
+	}
+
+	resp.Header().Set(totalCountHeader, strconv.Itoa(n))
+
+	start = o.Offset
+	if start > n {
+		start = n
+	}
+	end = n
+	if o.Limit > 0 && start+o.Limit < n {
+		end = start + o.Limit
+	}
+	return start, end
+}
+
+type keySorter struct {
+	keys []string
+	desc bool
+	swap func(i, j int)
+}
+
+func (s *keySorter) Len() int { return len(s.keys) }
+
+func (s *keySorter) Less(i, j int) bool {
+	if s.desc {
+		return s.keys[i] > s.keys[j]
+	}
+	return s.keys[i] < s.keys[j]
+}
+
+func (s *keySorter) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.swap(i, j)
+}
+
+// matchesFilter - whether value matches the query parameter filter, empty
+// filters match all values
+func matchesFilter(filter, value string) bool {
+	return filter == "" || filter == value
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/keel-hq/keel/pkg/auth"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type pauseRequest struct {
+	// Identifier - <namespace>/<name> of the workload
+	Identifier string `json:"identifier"`
+}
+
+// pausedHandler - lists workloads with paused automatic updates, maintenance
+// mode is reported by /v1/maintenance
+func (s *TriggerServer) pausedHandler(resp http.ResponseWriter, req *http.Request) {
+	paused, err := s.store.ListPausedResources()
+	if err != nil {
+		response(nil, http.StatusInternalServerError, err, resp, req)
+		return
+	}
+
+	resources := []*types.PausedResource{}
+	for _, p := range paused {
+		if p.Identifier != types.PausedAllIdentifier {
+			resources = append(resources, p)
+		}
+	}
+
+	response(&resources, http.StatusOK, nil, resp, req)
+}
+
+// pauseHandler - pauses automatic updates of a workload, pausing already
+// paused workload is not an error
+func (s *TriggerServer) pauseHandler(resp http.ResponseWriter, req *http.Request) {
+	var pr pauseRequest
+	dec := json.NewDecoder(req.Body)
+	defer req.Body.Close()
+
+	err := dec.Decode(&pr)
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "%s", err)
+		return
+	}
+
+	parts := strings.Split(strings.TrimSpace(pr.Identifier), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		http.Error(resp, fmt.Sprintf("invalid workload '%s', expected format: <namespace>/<name>", pr.Identifier), http.StatusBadRequest)
+		return
+	}
+	identifier := types.PausedIdentifier(parts[0], parts[1])
+
+	existing, err := s.store.GetPausedResource(identifier)
+	if err == nil {
+		response(existing, http.StatusOK, nil, resp, req)
+		return
+	}
+	if err != store.ErrRecordNotFound {
+		response(nil, http.StatusInternalServerError, err, resp, req)
+		return
+	}
+
+	user := "api"
+	if u := auth.GetAccountFromCtx(req.Context()); u != nil {
+		user = u.Username
+	}
+
+	paused, err := s.store.CreatePausedResource(&types.PausedResource{
+		Identifier: identifier,
+		User:       user,
+	})
+	if err != nil {
+		response(nil, http.StatusInternalServerError, err, resp, req)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"identifier": identifier,
+		"user":       user,
+	}).Info("http.pauseHandler: automatic updates paused")
+
+	s.auditRequest(req, types.AuditActionCreated, types.AuditResourceKindPaused, identifier, nil)
+
+	response(paused, http.StatusCreated, nil, resp, req)
+}
+
+// resumeHandler - resumes automatic updates of a paused workload
+func (s *TriggerServer) resumeHandler(resp http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	identifier := types.PausedIdentifier(vars["namespace"], vars["name"])
+
+	_, err := s.store.GetPausedResource(identifier)
+	if err == store.ErrRecordNotFound {
+		http.Error(resp, fmt.Sprintf("updates of '%s' are not paused", identifier), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		response(nil, http.StatusInternalServerError, err, resp, req)
+		return
+	}
+
+	err = s.store.DeletePausedResource(identifier)
+	if err != nil {
+		response(nil, http.StatusInternalServerError, err, resp, req)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"identifier": identifier,
+	}).Info("http.resumeHandler: automatic updates resumed")
+
+	s.auditRequest(req, types.AuditActionDeleted, types.AuditResourceKindPaused, identifier, nil)
+
+	response(&APIResponse{Status: "resumed"}, http.StatusOK, nil, resp, req)
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestPauseResume(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		req.SetBasicAuth("user-1", "secret")
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	list := func() []*types.PausedResource {
+		rec := do("GET", "/v1/paused", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+		}
+		var paused []*types.PausedResource
+		if err := json.Unmarshal(rec.Body.Bytes(), &paused); err != nil {
+			t.Fatalf("failed to unmarshal paused resources: %s", err)
+		}
+		return paused
+	}
+
+	if rec := do("POST", "/v1/paused", `{"identifier": "default"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected invalid identifier to be rejected, got: %d", rec.Code)
+	}
+
+	if rec := do("POST", "/v1/paused", `{"identifier": "default/wd"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+	// pausing twice is not an error
+	if rec := do("POST", "/v1/paused", `{"identifier": "default/wd"}`); rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+
+	// maintenance mode isn't listed
+	if rec := do("PUT", "/v1/maintenance", `{"enabled": true}`); rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+
+	paused := list()
+	if len(paused) != 1 || paused[0].Identifier != "default/wd" || paused[0].User != "user-1" {
+		t.Fatalf("unexpected paused resources: %+v", paused)
+	}
+
+	if rec := do("DELETE", "/v1/paused/default/wd", ""); rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do("DELETE", "/v1/paused/default/wd", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected resuming workload that isn't paused to fail, got: %d", rec.Code)
+	}
+
+	if paused := list(); len(paused) != 0 {
+		t.Errorf("expected no paused resources, got: %d", len(paused))
+	}
+
+	logs, err := srv.store.GetAuditLogs(&types.AuditLogQuery{ResourceKindFilter: []string{types.AuditResourceKindPaused}})
+	if err != nil {
+		t.Fatalf("failed to get audit logs: %s", err)
+	}
+	if len(logs) != 2 {
+		t.Errorf("expected pause and resume to be audited, got: %d entries", len(logs))
+	}
+}
@@ -0,0 +1,25 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/keel-hq/keel/internal/k8s"
+)
+
+type permissionsResponse struct {
+	Checked bool `json:"checked"`
+	k8s.AccessStatus
+}
+
+// permissionsHandler - permissions of keel per watched namespace, resources
+// keel isn't allowed to watch are skipped
+func (s *TriggerServer) permissionsHandler(resp http.ResponseWriter, req *http.Request) {
+	if s.accessChecker == nil {
+		response(&permissionsResponse{}, http.StatusOK, nil, resp, req)
+		return
+	}
+	response(&permissionsResponse{
+		Checked:      true,
+		AccessStatus: s.accessChecker.Status(),
+	}, http.StatusOK, nil, resp, req)
+}
@@ -33,6 +33,10 @@ func (s *TriggerServer) policyUpdateHandler(resp http.ResponseWriter, req *http.
 
 	for _, v := range s.grc.Values() {
 		if v.Identifier == policyRequest.Identifier {
+			if reason := s.outOfScope(v); reason != "" {
+				http.Error(resp, reason, http.StatusForbidden)
+				return
+			}
 
 			labels := v.GetLabels()
 			delete(labels, types.KeelPolicyLabel)
@@ -44,6 +48,11 @@ func (s *TriggerServer) policyUpdateHandler(resp http.ResponseWriter, req *http.
 			v.SetAnnotations(ann)
 
 			err := s.kubernetesClient.Update(v)
+			if err == nil {
+				s.auditRequest(req, types.AuditActionUpdated, v.Kind(), v.Identifier, map[string]string{
+					"policy": policyRequest.Policy,
+				})
+			}
 
 			response(&APIResponse{Status: "updated"}, 200, err, resp, req)
 			return
@@ -1,9 +1,12 @@
 package http
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/keel-hq/keel/types"
@@ -35,6 +38,10 @@ func init() {
 //     "latest"
 //   ]
 // }
+//
+// Build notifications (build_success etc.) use docker_tags instead of updated_tags.
+// Test notifications sent from Quay UI might not have any tags, such
+// notifications are acknowledged without triggering updates.
 
 type quayWebhook struct {
 	Name        string   `json:"name"`
@@ -43,36 +50,77 @@ type quayWebhook struct {
 	DockerURL   string   `json:"docker_url"`
 	Homepage    string   `json:"homepage"`
 	UpdatedTags []string `json:"updated_tags"`
+	DockerTags  []string `json:"docker_tags"`
+}
+
+// dockerURL - docker_url or, if it's missing, image name
+// constructed from the homepage and repository
+func (qw *quayWebhook) dockerURL() string {
+	if qw.DockerURL != "" {
+		return qw.DockerURL
+	}
+	if qw.Repository == "" {
+		return ""
+	}
+
+	host := "quay.io"
+	if u, err := url.Parse(qw.Homepage); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	return host + "/" + qw.Repository
+}
+
+func (qw *quayWebhook) tags() []string {
+	if len(qw.UpdatedTags) > 0 {
+		return qw.UpdatedTags
+	}
+	return qw.DockerTags
 }
 
 func (s *TriggerServer) quayHandler(resp http.ResponseWriter, req *http.Request) {
-	qw := quayWebhook{}
-	if err := json.NewDecoder(req.Body).Decode(&qw); err != nil {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err,
-		}).Error("trigger.quayHandler: failed to decode request")
+		}).Error("trigger.quayHandler: failed to read request")
 		resp.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	if qw.DockerURL == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "docker_url cannot be empty")
+	// empty ping
+	if len(bytes.TrimSpace(body)) == 0 {
+		resp.WriteHeader(http.StatusOK)
+		fmt.Fprintf(resp, "ok")
 		return
 	}
 
-	if len(qw.UpdatedTags) == 0 {
+	qw := quayWebhook{}
+	if err := json.Unmarshal(body, &qw); err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("trigger.quayHandler: failed to decode request")
 		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "updated_tags cannot be empty")
+		return
+	}
+
+	dockerURL := qw.dockerURL()
+	tags := qw.tags()
+	if dockerURL == "" || len(tags) == 0 {
+		log.WithFields(log.Fields{
+			"repository": qw.Repository,
+			"docker_url": qw.DockerURL,
+		}).Info("trigger.quayHandler: notification has no image or tags, ignoring (test notification?)")
+		resp.WriteHeader(http.StatusOK)
+		fmt.Fprintf(resp, "no updated tags, nothing to do")
 		return
 	}
 
 	// for every updated tag generating event
-	for _, tag := range qw.UpdatedTags {
+	for _, tag := range tags {
 		event := types.Event{}
 		event.CreatedAt = time.Now()
 		event.TriggerName = "quay"
-		event.Repository.Name = qw.DockerURL
+		event.Repository.Name = dockerURL
 		event.Repository.Tag = tag
 
 		s.trigger(event)
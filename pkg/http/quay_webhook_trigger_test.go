@@ -53,3 +53,88 @@ func TestQuayWebhookHandler(t *testing.T) {
 		t.Errorf("expected 1.2.3 but got %s", fp.submitted[0].Repository.Tag)
 	}
 }
+
+var fakeQuayBuildWebhook = `{
+  "repository": "mynamespace/repository",
+  "namespace": "mynamespace",
+  "name": "repository",
+  "docker_url": "quay.io/mynamespace/repository",
+  "homepage": "https://quay.io/repository/mynamespace/repository/build/296ec063-5f86-4706-a469-f0a400bf9df2",
+  "build_id": "296ec063-5f86-4706-a469-f0a400bf9df2",
+  "docker_tags": [
+    "2.0.0"
+  ]
+}
+`
+
+func TestQuayWebhookHandlerBuildEvent(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	req, err := http.NewRequest("POST", "/v1/webhooks/quay", bytes.NewBuffer([]byte(fakeQuayBuildWebhook)))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+
+	rec := httptest.NewRecorder()
+
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != 200 {
+		t.Errorf("unexpected status code: %d", rec.Code)
+		t.Log(rec.Body.String())
+	}
+
+	if len(fp.submitted) != 1 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+
+	if fp.submitted[0].Repository.Tag != "2.0.0" {
+		t.Errorf("expected 2.0.0 but got %s", fp.submitted[0].Repository.Tag)
+	}
+}
+
+func TestQuayWebhookHandlerTestNotification(t *testing.T) {
+	pings := []string{
+		``,
+		`{}`,
+		`{"repository": "mynamespace/repository", "namespace": "mynamespace", "name": "repository", "updated_tags": []}`,
+	}
+
+	for _, ping := range pings {
+		fp := &fakeProvider{}
+		srv, teardown := NewTestingServer(fp)
+
+		req, err := http.NewRequest("POST", "/v1/webhooks/quay", bytes.NewBuffer([]byte(ping)))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		srv.router.ServeHTTP(rec, req)
+		if rec.Code != 200 {
+			t.Errorf("unexpected status code for ping '%s': %d", ping, rec.Code)
+		}
+
+		if len(fp.submitted) != 0 {
+			t.Errorf("ping '%s' shouldn't submit events, got: %d", ping, len(fp.submitted))
+		}
+		teardown()
+	}
+}
+
+func TestQuayDockerURL(t *testing.T) {
+	qw := &quayWebhook{
+		Repository: "mynamespace/repository",
+		Homepage:   "https://quay.example.com/repository/mynamespace/repository",
+	}
+	if qw.dockerURL() != "quay.example.com/mynamespace/repository" {
+		t.Errorf("unexpected docker url: %s", qw.dockerURL())
+	}
+
+	qw.Homepage = ""
+	if qw.dockerURL() != "quay.io/mynamespace/repository" {
+		t.Errorf("unexpected docker url: %s", qw.dockerURL())
+	}
+}
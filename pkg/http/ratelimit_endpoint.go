@@ -0,0 +1,21 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/keel-hq/keel/internal/ratelimit"
+)
+
+type rateLimitResponse struct {
+	Enabled bool `json:"enabled"`
+	*ratelimit.Status
+}
+
+// rateLimitHandler - usage of update budgets and queued updates, the queue is
+// held by the leader when keel runs with leader election
+func (s *TriggerServer) rateLimitHandler(resp http.ResponseWriter, req *http.Request) {
+	response(&rateLimitResponse{
+		Enabled: s.rateLimiter != nil,
+		Status:  s.rateLimiter.Status(),
+	}, http.StatusOK, nil, resp, req)
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/internal/ratelimit"
+)
+
+func TestRateLimitHandler(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	get := func() *rateLimitResponse {
+		req, err := http.NewRequest("GET", "/v1/ratelimit", nil)
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		req.SetBasicAuth("user-1", "secret")
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+		}
+		var status rateLimitResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+			t.Fatalf("failed to unmarshal status: %s", err)
+		}
+		return &status
+	}
+
+	if status := get(); status.Enabled || len(status.Queued) != 0 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	srv.rateLimiter = ratelimit.New(ratelimit.Opts{Global: ratelimit.Budget{Max: 1, Period: time.Hour}})
+	srv.rateLimiter.Allow("default")
+	srv.rateLimiter.Enqueue(ratelimit.Update{Provider: "kubernetes", Namespace: "default", Identifier: "deployment/default/wd", NewVersion: "0.0.2"}, func() {})
+
+	status := get()
+	if !status.Enabled || status.Global.Used != 1 || status.Global.Max != 1 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if len(status.Queued) != 1 || status.Queued[0].Identifier != "deployment/default/wd" {
+		t.Errorf("unexpected queue: %+v", status.Queued)
+	}
+}
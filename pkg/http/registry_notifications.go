@@ -100,11 +100,12 @@ func (s *TriggerServer) registryNotificationHandler(resp http.ResponseWriter, re
 
 	log.WithFields(log.Fields{
 		"event": rn,
-	}).Debug("registryNotificationHandler: received event, looking for a push tag")
+	}).Debug("registryNotificationHandler: received event, looking for a pushed or deleted tag")
 
 	for _, e := range rn.Events {
 
-		if e.Action != "push" {
+		// deleted tags are reported, they are never updates
+		if e.Action != "push" && e.Action != "delete" {
 			continue
 		}
 
@@ -120,6 +121,7 @@ func (s *TriggerServer) registryNotificationHandler(resp http.ResponseWriter, re
 		event.TriggerName = "registry-notification"
 		event.Repository.Tag = e.Target.Tag
 		event.Repository.Digest = e.Target.Digest
+		event.Deleted = e.Action == "delete"
 
 		log.WithFields(log.Fields{
 			"action":     e.Action,
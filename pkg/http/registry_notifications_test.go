@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -74,3 +75,35 @@ func TestRegistryNotificationsHandler(t *testing.T) {
 		t.Errorf("expected 1.6.1 but got %s", fp.submitted[0].Repository.Tag)
 	}
 }
+
+func TestRegistryNotificationsHandlerDeletedTag(t *testing.T) {
+
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	body := strings.Replace(fakeRegistryNotificationWebhook, `"action": "push"`, `"action": "delete"`, 1)
+	req, err := http.NewRequest("POST", "/v1/webhooks/registry", bytes.NewBuffer([]byte(body)))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+
+	rec := httptest.NewRecorder()
+
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != 200 {
+		t.Errorf("unexpected status code: %d", rec.Code)
+	}
+
+	if len(fp.submitted) != 0 {
+		t.Errorf("deleted tags shouldn't be submitted as updates")
+	}
+
+	if len(fp.deleted) != 1 {
+		t.Fatalf("unexpected number of deletions submitted: %d", len(fp.deleted))
+	}
+
+	if fp.deleted[0].Repository.Digest != "sha256:4afff550708506c5b8b7384ad10d401a02b29ed587cb2730cb02753095b5178d" {
+		t.Errorf("expected digest of the deleted tag but got %s", fp.deleted[0].Repository.Digest)
+	}
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/keel-hq/keel/internal/k8s"
 	"github.com/keel-hq/keel/internal/policy"
@@ -20,17 +21,21 @@ type resource struct {
 	Status      k8s.Status        `json:"status"`
 }
 
+// resourcesHandler - lists resources, optionally filtered by namespace, kind,
+// provider, policy and image (substring) query parameters. See listOptions
+// for pagination and sorting.
 func (s *TriggerServer) resourcesHandler(resp http.ResponseWriter, req *http.Request) {
 
 	vals := s.grc.Values()
+	q := req.URL.Query()
 
-	var res []resource
+	res := []resource{}
 
 	for _, v := range vals {
 
 		p := policy.GetPolicyFromLabelsOrAnnotations(v.GetLabels(), v.GetAnnotations())
 
-		res = append(res, resource{
+		r := resource{
 			Provider:    "kubernetes",
 			Identifier:  v.Identifier,
 			Name:        v.Name,
@@ -41,8 +46,41 @@ func (s *TriggerServer) resourcesHandler(resp http.ResponseWriter, req *http.Req
 			Annotations: v.GetAnnotations(),
 			Images:      v.GetImages(),
 			Status:      v.GetStatus(),
-		})
+		}
+
+		switch {
+		case !matchesFilter(q.Get("namespace"), r.Namespace):
+		case !matchesFilter(q.Get("kind"), r.Kind):
+		case !matchesFilter(q.Get("provider"), r.Provider):
+		case !matchesFilter(q.Get("policy"), r.Policy):
+		case q.Get("image") != "" && !containsImage(r.Images, q.Get("image")):
+		default:
+			res = append(res, r)
+		}
+	}
+
+	sortFields := map[string]func(i int) string{
+		"identifier": func(i int) string { return res[i].Identifier },
+		"name":       func(i int) string { return res[i].Name },
+		"namespace":  func(i int) string { return res[i].Namespace },
+		"kind":       func(i int) string { return res[i].Kind },
+		"policy":     func(i int) string { return res[i].Policy },
+	}
+	opts, err := parseListOptions(req, sortFields)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
 	}
+	start, end := opts.apply(resp, len(res), sortFields, func(i, j int) { res[i], res[j] = res[j], res[i] })
 
-	response(res, 200, nil, resp, req)
+	response(res[start:end], 200, nil, resp, req)
+}
+
+func containsImage(images []string, image string) bool {
+	for _, img := range images {
+		if strings.Contains(img, image) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"strings"
+)
+
+const signaturePrefix = "sha256="
+
+// signatureAlgorithms - supported HMAC algorithms
+var signatureAlgorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// sign - HMAC-SHA256 signature of the body, ie: sha256=<hex>
+func sign(secret, body []byte) string {
+	return signaturePrefix + hex.EncodeToString(mac(sha256.New, secret, body))
+}
+
+// validSignature - checks HMAC-SHA256 signature in the sha256=<hex> format
+// (used by GitHub X-Hub-Signature-256 header)
+func validSignature(secret, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	return hmac.Equal([]byte(sign(secret, body)), []byte(signature))
+}
+
+func mac(h func() hash.Hash, secret, body []byte) []byte {
+	m := hmac.New(h, secret)
+	m.Write(body)
+	return m.Sum(nil)
+}
+
+// signatureVerifier - validates HMAC signatures sent in a configurable header,
+// signature can be either <hex> or <algorithm>=<hex>
+type signatureVerifier struct {
+	secret    []byte
+	header    string
+	algorithm string
+	hash      func() hash.Hash
+}
+
+// newSignatureVerifier - returns nil when secret is not set, unknown algorithm
+// results in a verifier that rejects all requests
+func newSignatureVerifier(secret, header, algorithm string) *signatureVerifier {
+	if secret == "" {
+		return nil
+	}
+	algorithm = strings.ToLower(algorithm)
+	return &signatureVerifier{
+		secret:    []byte(secret),
+		header:    header,
+		algorithm: algorithm,
+		hash:      signatureAlgorithms[algorithm],
+	}
+}
+
+// valid - constant time comparison of the expected and received signatures
+func (v *signatureVerifier) valid(body []byte, signature string) bool {
+	if v.hash == nil || signature == "" {
+		return false
+	}
+
+	signature = strings.TrimPrefix(signature, v.algorithm+"=")
+	received, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(mac(v.hash, v.secret, body), received)
+}
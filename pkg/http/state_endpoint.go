@@ -0,0 +1,85 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/keel-hq/keel/internal/backup"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/version"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// stateExportHandler - exports approvals, audit logs, paused resources,
+// update history, received webhooks, API tokens and poll digests, format=tar returns a
+// gzipped tar archive instead of a single JSON document
+func (s *TriggerServer) stateExportHandler(resp http.ResponseWriter, req *http.Request) {
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = backup.FormatJSON
+	}
+	if format != backup.FormatJSON && format != backup.FormatTar {
+		http.Error(resp, fmt.Sprintf("unknown format '%s', supported: json, tar", format), http.StatusBadRequest)
+		return
+	}
+
+	state, err := s.store.ExportState()
+	if err != nil {
+		response(nil, 500, err, resp, req)
+		return
+	}
+	state.KeelVersion = version.GetKeelVersion().Version
+
+	filename := "keel-state.json"
+	resp.Header().Set("Content-Type", "application/json")
+	if format == backup.FormatTar {
+		filename = "keel-state.tar.gz"
+		resp.Header().Set("Content-Type", "application/gzip")
+	}
+	resp.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	err = backup.Write(resp, state, format)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("http.stateExportHandler: failed to write state")
+		return
+	}
+
+	s.auditRequest(req, types.AuditActionCreated, types.AuditResourceKindState, "export", map[string]string{
+		"format": format,
+	})
+}
+
+// stateImportHandler - imports state exported by stateExportHandler (in any
+// format), records that already exist are skipped
+func (s *TriggerServer) stateImportHandler(resp http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	state, err := backup.Read(req.Body)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.store.ImportState(state)
+	if err != nil {
+		response(nil, 500, err, resp, req)
+		return
+	}
+
+	meta := map[string]string{"keel_version": state.KeelVersion}
+	for kind, count := range result.Imported {
+		meta[kind] = strconv.Itoa(count)
+	}
+	s.auditRequest(req, types.AuditActionCreated, types.AuditResourceKindState, "import", meta)
+
+	log.WithFields(log.Fields{
+		"imported": result.Imported,
+		"skipped":  result.Skipped,
+	}).Info("http.stateImportHandler: state imported")
+
+	response(result, 200, nil, resp, req)
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestStateExportImport(t *testing.T) {
+	fp := &fakeProvider{}
+	source, teardown := NewTestingServer(fp)
+	defer teardown()
+	target, targetTeardown := NewTestingServer(fp)
+	defer targetTeardown()
+
+	_, err := source.store.CreatePausedResource(&types.PausedResource{Identifier: "default/wd", User: "admin"})
+	if err != nil {
+		t.Fatalf("failed to pause resource: %s", err)
+	}
+	_, err = source.store.CreateApproval(&types.Approval{
+		Identifier:    "default/wd:1.1.0",
+		VotesRequired: 2,
+		VotesReceived: 1,
+		Event:         &types.Event{},
+	})
+	if err != nil {
+		t.Fatalf("failed to create approval: %s", err)
+	}
+
+	for _, format := range []string{"json", "tar"} {
+		req, _ := http.NewRequest("GET", "/v1/state/export?format="+format, nil)
+		req.SetBasicAuth("user-1", "secret")
+		rec := httptest.NewRecorder()
+		source.router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: unexpected export status code: %d, body: %s", format, rec.Code, rec.Body.String())
+		}
+
+		req, _ = http.NewRequest("POST", "/v1/state/import", bytes.NewReader(rec.Body.Bytes()))
+		req.SetBasicAuth("user-1", "secret")
+		rec = httptest.NewRecorder()
+		target.router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: unexpected import status code: %d, body: %s", format, rec.Code, rec.Body.String())
+		}
+
+		var result types.StateImportResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("%s: failed to unmarshal result: %s", format, err)
+		}
+
+		// second import only finds existing records
+		imported := result.Imported
+		if format == "tar" {
+			imported = result.Skipped
+		}
+		if imported[types.StateKindApprovals] != 1 || imported[types.StateKindPausedResources] != 1 {
+			t.Errorf("%s: unexpected import result: %+v", format, result)
+		}
+	}
+
+	paused, err := target.store.GetPausedResource("default/wd")
+	if err != nil || paused.User != "admin" {
+		t.Errorf("expected paused resource to be imported, got: %v, %v", paused, err)
+	}
+	approvals, err := target.store.ListApprovals(&types.GetApprovalQuery{})
+	if err != nil || len(approvals) != 1 || approvals[0].VotesReceived != 1 {
+		t.Errorf("expected approval to be imported, got: %v, %v", approvals, err)
+	}
+
+	req, _ := http.NewRequest("POST", "/v1/state/import", bytes.NewBufferString("not a state"))
+	req.SetBasicAuth("user-1", "secret")
+	rec := httptest.NewRecorder()
+	target.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected invalid state to be rejected, got: %d", rec.Code)
+	}
+}
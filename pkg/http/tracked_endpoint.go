@@ -4,9 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/internal/k8s"
 	"github.com/keel-hq/keel/types"
+
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 type trackedImage struct {
@@ -17,15 +22,29 @@ type trackedImage struct {
 	Namespace    string `json:"namespace"`
 	Policy       string `json:"policy"`
 	Registry     string `json:"registry"`
+	Paused       bool   `json:"paused"`
+	// FreezeReason - set when updates are paused by a change freeze
+	FreezeReason string `json:"freezeReason,omitempty"`
+
+	// AuthFailure - registry rejected credentials of the image
+	AuthFailure *credentialshelper.AuthStatus `json:"authFailure,omitempty"`
 }
 
+// trackedHandler - lists tracked images, optionally filtered by namespace,
+// provider, policy, trigger and image (substring) query parameters. See
+// listOptions for pagination and sorting.
 func (s *TriggerServer) trackedHandler(resp http.ResponseWriter, req *http.Request) {
 	trackedImages, err := s.providers.TrackedImages()
+	if err != nil {
+		response(nil, 500, err, resp, req)
+		return
+	}
 
-	var imgs []trackedImage
+	q := req.URL.Query()
+	imgs := []trackedImage{}
 
 	for _, img := range trackedImages {
-		imgs = append(imgs, trackedImage{
+		ti := trackedImage{
 			Image:        img.Image.Name(),
 			Trigger:      img.Trigger.String(),
 			PollSchedule: img.PollSchedule,
@@ -33,10 +52,41 @@ func (s *TriggerServer) trackedHandler(resp http.ResponseWriter, req *http.Reque
 			Namespace:    img.Namespace,
 			Policy:       img.Policy.Name(),
 			Registry:     img.Image.Registry(),
-		})
+			Paused:       img.Paused,
+			FreezeReason: img.FreezeReason,
+		}
+
+		switch {
+		case !matchesFilter(q.Get("namespace"), ti.Namespace):
+		case !matchesFilter(q.Get("provider"), ti.Provider):
+		case !matchesFilter(q.Get("policy"), ti.Policy):
+		case !matchesFilter(q.Get("trigger"), ti.Trigger):
+		case q.Get("image") != "" && !strings.Contains(ti.Image, q.Get("image")):
+		default:
+			if status, ok := credentialshelper.AuthFailure(img); ok {
+				ti.AuthFailure = &status
+			}
+			imgs = append(imgs, ti)
+		}
 	}
 
-	response(&imgs, 200, err, resp, req)
+	sortFields := map[string]func(i int) string{
+		"image":     func(i int) string { return imgs[i].Image },
+		"namespace": func(i int) string { return imgs[i].Namespace },
+		"provider":  func(i int) string { return imgs[i].Provider },
+		"policy":    func(i int) string { return imgs[i].Policy },
+		"trigger":   func(i int) string { return imgs[i].Trigger },
+		"registry":  func(i int) string { return imgs[i].Registry },
+	}
+	opts, err := parseListOptions(req, sortFields)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+	start, end := opts.apply(resp, len(imgs), sortFields, func(i, j int) { imgs[i], imgs[j] = imgs[j], imgs[i] })
+
+	page := imgs[start:end]
+	response(&page, 200, nil, resp, req)
 }
 
 type trackRequest struct {
@@ -83,11 +133,15 @@ func (s *TriggerServer) trackSetHandler(resp http.ResponseWriter, req *http.Requ
 			return
 		}
 	} else {
-		trackReq.Schedule = types.KeelPollDefaultSchedule
+		trackReq.Schedule = types.DefaultPollSchedule()
 	}
 
 	for _, v := range s.grc.Values() {
 		if v.Identifier == trackReq.Identifier {
+			if reason := s.outOfScope(v); reason != "" {
+				http.Error(resp, reason, http.StatusForbidden)
+				return
+			}
 
 			labels := v.GetLabels()
 			delete(labels, types.KeelTriggerLabel)
@@ -100,6 +154,12 @@ func (s *TriggerServer) trackSetHandler(resp http.ResponseWriter, req *http.Requ
 			v.SetAnnotations(ann)
 
 			err := s.kubernetesClient.Update(v)
+			if err == nil {
+				s.auditRequest(req, types.AuditActionUpdated, v.Kind(), v.Identifier, map[string]string{
+					"trigger":  trackReq.Trigger,
+					"schedule": trackReq.Schedule,
+				})
+			}
 
 			response(&APIResponse{Status: "updated"}, 200, err, resp, req)
 			return
@@ -109,3 +169,22 @@ func (s *TriggerServer) trackSetHandler(resp http.ResponseWriter, req *http.Requ
 	resp.WriteHeader(http.StatusNotFound)
 	fmt.Fprintf(resp, "resource with identifier '%s' not found", trackReq.Identifier)
 }
+
+// outOfScope - why the resource can't be modified through the API, empty when it can
+func (s *TriggerServer) outOfScope(resource *k8s.GenericResource) string {
+	if s.scope == nil {
+		return ""
+	}
+	return s.scope.Check(resource, func(namespace string) (labels.Set, bool) {
+		list, err := s.kubernetesClient.Namespaces()
+		if err != nil {
+			return nil, false
+		}
+		for _, ns := range list.Items {
+			if ns.Name == namespace {
+				return labels.Set(ns.Labels), true
+			}
+		}
+		return nil, false
+	})
+}
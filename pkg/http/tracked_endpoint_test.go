@@ -0,0 +1,129 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/provider/kubernetes"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestTrackedImagesPagination(t *testing.T) {
+	fp := &fakeProvider{}
+	for _, img := range []struct {
+		name      string
+		namespace string
+	}{
+		{"gcr.io/v2-namespace/hello-world:1.0.0", "default"},
+		{"karolisr/webhook-demo:0.0.1", "staging"},
+		{"docker.io/library/alpine:3.9", "default"},
+		{"quay.io/keel/keel:0.10.0", "default"},
+	} {
+		ref, err := image.Parse(img.name)
+		if err != nil {
+			t.Fatalf("failed to parse image: %s", err)
+		}
+		fp.images = append(fp.images, &types.TrackedImage{
+			Image:     ref,
+			Namespace: img.namespace,
+			Provider:  "kubernetes",
+			Trigger:   types.TriggerTypePoll,
+			Policy:    policy.NewSemverPolicy(policy.SemverPolicyTypeMajor),
+		})
+	}
+
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	list := func(query string) ([]trackedImage, *httptest.ResponseRecorder) {
+		req, err := http.NewRequest("GET", "/v1/tracked"+query, nil)
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		req.SetBasicAuth("user-1", "secret")
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			return nil, rec
+		}
+
+		var imgs []trackedImage
+		if err := json.Unmarshal(rec.Body.Bytes(), &imgs); err != nil {
+			t.Fatalf("failed to unmarshal tracked images: %s", err)
+		}
+		return imgs, rec
+	}
+
+	imgs, rec := list("?namespace=default&sort=image&limit=2&offset=1")
+	if rec.Header().Get(totalCountHeader) != "3" {
+		t.Errorf("expected 3 matching images, got %s", rec.Header().Get(totalCountHeader))
+	}
+	if len(imgs) != 2 {
+		t.Fatalf("expected 2 images, got %d", len(imgs))
+	}
+	if imgs[0].Image != "library/alpine:3.9" || imgs[1].Image != "v2-namespace/hello-world:1.0.0" {
+		t.Errorf("unexpected page: %s, %s", imgs[0].Image, imgs[1].Image)
+	}
+
+	imgs, _ = list("?sort=namespace&order=desc&limit=1")
+	if len(imgs) != 1 || imgs[0].Namespace != "staging" {
+		t.Errorf("unexpected first image: %+v", imgs)
+	}
+
+	imgs, _ = list("?image=webhook-demo")
+	if len(imgs) != 1 || imgs[0].Image != "karolisr/webhook-demo:0.0.1" {
+		t.Errorf("unexpected images: %+v", imgs)
+	}
+
+	imgs, _ = list("?offset=10")
+	if len(imgs) != 0 {
+		t.Errorf("expected empty page, got %d images", len(imgs))
+	}
+
+	if _, rec := list("?sort=unknown"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected bad request for unknown sort field, got %d", rec.Code)
+	}
+	if _, rec := list("?limit=-1"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected bad request for negative limit, got %d", rec.Code)
+	}
+}
+
+func TestOutOfScope(t *testing.T) {
+	srv, teardown := NewTestingServer(&fakeProvider{})
+	defer teardown()
+
+	selector, _ := labels.Parse("team=payments")
+	srv.scope = &kubernetes.Scope{
+		IgnoredNamespaces: []string{"kube-*"},
+		Selector:          selector,
+	}
+
+	for _, tc := range []struct {
+		namespace string
+		labels    map[string]string
+		allowed   bool
+	}{
+		{namespace: "default", labels: map[string]string{"team": "payments"}, allowed: true},
+		{namespace: "default", labels: map[string]string{"team": "search"}},
+		{namespace: "kube-system", labels: map[string]string{"team": "payments"}},
+	} {
+		resource, err := k8s.NewGenericResource(&apps_v1.Deployment{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "wd", Namespace: tc.namespace, Labels: tc.labels},
+		})
+		if err != nil {
+			t.Fatalf("failed to create resource: %s", err)
+		}
+		if reason := srv.outOfScope(resource); (reason == "") != tc.allowed {
+			t.Errorf("%s %v: unexpected scope check result: '%s'", tc.namespace, tc.labels, reason)
+		}
+	}
+}
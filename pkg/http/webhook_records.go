@@ -0,0 +1,250 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultWebhookHistorySize - how many received webhooks are kept by default
+const DefaultWebhookHistorySize = 50
+
+const (
+	// maxRecordedPayload - larger payloads are not stored and can't be replayed
+	maxRecordedPayload  = 256 * 1024
+	maxRecordedResponse = 4 * 1024
+)
+
+// recordedHeaders - headers that are stored with the webhook payload, they are
+// needed to replay it (event type, signatures). Credentials are never stored.
+var recordedHeaders = []string{
+	"Content-Type",
+	"User-Agent",
+	"X-Forwarded-For",
+	githubEventHeader,
+	githubSignatureHeader,
+	"X-GitHub-Delivery",
+}
+
+// recordingResponseWriter - captures webhook handler response
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *recordingResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingResponseWriter) Write(b []byte) (int, error) {
+	if remaining := maxRecordedResponse - w.body.Len(); remaining > 0 {
+		if len(b) > remaining {
+			w.body.Write(b[:remaining])
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (s *TriggerServer) recordHeader(header string) bool {
+	if strings.HasPrefix(http.CanonicalHeaderKey(header), cloudEventsHeaderPrefix) {
+		return true
+	}
+	if s.nativeWebhookVerifier != nil && http.CanonicalHeaderKey(header) == http.CanonicalHeaderKey(s.nativeWebhookVerifier.header) {
+		return true
+	}
+	for _, h := range recordedHeaders {
+		if http.CanonicalHeaderKey(header) == h {
+			return true
+		}
+	}
+	return false
+}
+
+// recordWebhook - wraps webhook handler, received payloads are stored so they
+// can be inspected and replayed through the admin API
+func (s *TriggerServer) recordWebhook(source string, handler http.HandlerFunc) http.HandlerFunc {
+	if s.webhookHandlers == nil {
+		s.webhookHandlers = make(map[string]http.HandlerFunc)
+	}
+	s.webhookHandlers[source] = handler
+
+	return func(resp http.ResponseWriter, req *http.Request) {
+		if s.webhookHistorySize <= 0 || req.Method == http.MethodOptions {
+			handler(resp, req)
+			return
+		}
+		s.dispatch(source, handler, resp, req, "")
+	}
+}
+
+// dispatch - runs webhook handler and stores the request together with the result
+func (s *TriggerServer) dispatch(source string, handler http.HandlerFunc, resp http.ResponseWriter, req *http.Request, replayOf string) *types.WebhookRecord {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"source": source,
+		}).Error("http.dispatch: failed to read request")
+		resp.WriteHeader(http.StatusBadRequest)
+		return nil
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	rw := &recordingResponseWriter{ResponseWriter: resp, status: http.StatusOK}
+	handler(rw, req)
+
+	record := &types.WebhookRecord{
+		Source:     source,
+		Method:     req.Method,
+		Path:       req.URL.RequestURI(),
+		Headers:    types.JSONB{},
+		StatusCode: rw.status,
+		Response:   rw.body.String(),
+		ReplayOf:   replayOf,
+	}
+	if len(body) > maxRecordedPayload {
+		record.Truncated = true
+	} else {
+		record.Payload = string(body)
+	}
+	for header, values := range req.Header {
+		if len(values) > 0 && s.recordHeader(header) {
+			record.Headers[http.CanonicalHeaderKey(header)] = values[0]
+		}
+	}
+
+	if s.webhookHistorySize <= 0 {
+		return record
+	}
+
+	_, err = s.store.CreateWebhookRecord(record)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"source": source,
+		}).Error("http.dispatch: failed to store webhook record")
+		return record
+	}
+
+	err = s.store.TrimWebhookRecords(s.webhookHistorySize)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("http.dispatch: failed to remove old webhook records")
+	}
+	return record
+}
+
+type webhookRecordsResponse struct {
+	Data   []*types.WebhookRecord `json:"data"`
+	Limit  int                    `json:"limit"`
+	Offset int                    `json:"offset"`
+}
+
+func (s *TriggerServer) webhookRecordsHandler(resp http.ResponseWriter, req *http.Request) {
+	query := &types.WebhookRecordQuery{
+		Source: req.URL.Query().Get("source"),
+	}
+	if l, err := strconv.Atoi(req.URL.Query().Get("limit")); err == nil {
+		query.Limit = l
+	}
+	if o, err := strconv.Atoi(req.URL.Query().Get("offset")); err == nil {
+		query.Offset = o
+	}
+
+	records, err := s.store.ListWebhookRecords(query)
+	if err != nil {
+		response(nil, 500, err, resp, req)
+		return
+	}
+
+	response(webhookRecordsResponse{
+		Data:   records,
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	}, http.StatusOK, nil, resp, req)
+}
+
+func (s *TriggerServer) webhookRecordHandler(resp http.ResponseWriter, req *http.Request) {
+	record, err := s.store.GetWebhookRecord(getID(req))
+	if err == store.ErrRecordNotFound {
+		resp.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(resp, "webhook record not found")
+		return
+	}
+	if err != nil {
+		response(nil, 500, err, resp, req)
+		return
+	}
+
+	response(record, http.StatusOK, nil, resp, req)
+}
+
+// webhookReplayHandler - sends stored webhook through the same handler again,
+// returns the record of the replay
+func (s *TriggerServer) webhookReplayHandler(resp http.ResponseWriter, req *http.Request) {
+	record, err := s.store.GetWebhookRecord(getID(req))
+	if err == store.ErrRecordNotFound {
+		resp.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(resp, "webhook record not found")
+		return
+	}
+	if err != nil {
+		response(nil, 500, err, resp, req)
+		return
+	}
+
+	if record.Truncated {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "webhook payload was not stored, it can't be replayed")
+		return
+	}
+
+	handler, ok := s.webhookHandlers[record.Source]
+	if !ok {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "unknown webhook source '%s'", record.Source)
+		return
+	}
+
+	replay, err := http.NewRequest(record.Method, record.Path, strings.NewReader(record.Payload))
+	if err != nil {
+		response(nil, 500, err, resp, req)
+		return
+	}
+	for header, value := range record.Headers {
+		if v, ok := value.(string); ok {
+			replay.Header.Set(header, v)
+		}
+	}
+	var match mux.RouteMatch
+	if s.router.Match(replay, &match) {
+		replay = mux.SetURLVars(replay, match.Vars)
+	}
+
+	rec := httptest.NewRecorder()
+	result := s.dispatch(record.Source, handler, rec, replay, record.ID)
+
+	log.WithFields(log.Fields{
+		"id":     record.ID,
+		"source": record.Source,
+		"status": rec.Code,
+	}).Info("http.webhookReplayHandler: webhook replayed")
+
+	response(result, http.StatusOK, nil, resp, req)
+}
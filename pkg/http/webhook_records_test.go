@@ -0,0 +1,135 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestWebhookRecordAndReplay(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+	srv.webhookHistorySize = 2
+
+	req, err := http.NewRequest("POST", "/v1/webhooks/native", bytes.NewBuffer([]byte(`{"name": "gcr.io/v2-namespace/hello-world", "tag": "1.1.1"}`)))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret-token")
+
+	rec := httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != 200 {
+		t.Fatalf("unexpected status code: %d", rec.Code)
+	}
+
+	// listing received webhooks
+	req, _ = http.NewRequest("GET", "/v1/webhooks/received?source=native", nil)
+	req.SetBasicAuth("user-1", "secret")
+	rec = httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != 200 {
+		t.Fatalf("unexpected status code: %d", rec.Code)
+	}
+
+	var records webhookRecordsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+		t.Fatalf("failed to decode response: %s", err)
+	}
+	if len(records.Data) != 1 {
+		t.Fatalf("expected 1 record, got: %d", len(records.Data))
+	}
+	record := records.Data[0]
+	if record.Source != "native" || record.StatusCode != 200 || record.Path != "/v1/webhooks/native" {
+		t.Errorf("unexpected record: %#v", record)
+	}
+	if record.Headers["Content-Type"] != "application/json" {
+		t.Errorf("expected content type to be recorded, headers: %v", record.Headers)
+	}
+	if _, ok := record.Headers["Authorization"]; ok {
+		t.Errorf("credentials shouldn't be recorded")
+	}
+
+	// replaying it
+	req, _ = http.NewRequest("POST", fmt.Sprintf("/v1/webhooks/received/%s/replay", record.ID), nil)
+	req.SetBasicAuth("user-1", "secret")
+	rec = httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != 200 {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+
+	var replay types.WebhookRecord
+	if err := json.Unmarshal(rec.Body.Bytes(), &replay); err != nil {
+		t.Fatalf("failed to decode response: %s", err)
+	}
+	if replay.ReplayOf != record.ID || replay.StatusCode != 200 {
+		t.Errorf("unexpected replay record: %#v", replay)
+	}
+
+	if len(fp.submitted) != 2 {
+		t.Fatalf("expected replayed event to be submitted, submitted: %d", len(fp.submitted))
+	}
+	if fp.submitted[1].Repository.Tag != "1.1.1" {
+		t.Errorf("unexpected replayed tag: %s", fp.submitted[1].Repository.Tag)
+	}
+
+	for i := 0; i < 3; i++ {
+		req, _ = http.NewRequest("POST", "/v1/webhooks/native", bytes.NewBuffer([]byte(`{"tag": "1.1.1"}`)))
+		rec = httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+	}
+
+	// only the newest records are kept
+	stored, err := srv.store.ListWebhookRecords(&types.WebhookRecordQuery{})
+	if err != nil {
+		t.Fatalf("failed to list records: %s", err)
+	}
+	if len(stored) != 2 {
+		t.Errorf("expected 2 records to be kept, got: %d", len(stored))
+	}
+	for _, r := range stored {
+		if r.StatusCode != 400 {
+			t.Errorf("unexpected record status code: %d", r.StatusCode)
+		}
+	}
+}
+
+func TestWebhookReplayNotFound(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	req, _ := http.NewRequest("POST", "/v1/webhooks/received/unknown/replay", nil)
+	req.SetBasicAuth("user-1", "secret")
+	rec := httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != 404 {
+		t.Errorf("unexpected status code: %d", rec.Code)
+	}
+}
+
+func TestWebhookRecordingDisabled(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	req, _ := http.NewRequest("POST", "/v1/webhooks/native", bytes.NewBuffer([]byte(`{"name": "gcr.io/v2-namespace/hello-world", "tag": "1.1.1"}`)))
+	rec := httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, req)
+
+	stored, err := srv.store.ListWebhookRecords(&types.WebhookRecordQuery{})
+	if err != nil {
+		t.Fatalf("failed to list records: %s", err)
+	}
+	if len(stored) != 0 {
+		t.Errorf("expected no records, got: %d", len(stored))
+	}
+}
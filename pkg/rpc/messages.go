@@ -0,0 +1,244 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+)
+
+// messages and services of api.proto, kept in sync by hand so the API
+// doesn't need protoc to build keel
+
+// Approval - approval request
+type Approval struct {
+	Id             string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Identifier     string   `protobuf:"bytes,2,opt,name=identifier,proto3" json:"identifier,omitempty"`
+	Provider       string   `protobuf:"bytes,3,opt,name=provider,proto3" json:"provider,omitempty"`
+	Status         string   `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	CurrentVersion string   `protobuf:"bytes,5,opt,name=current_version,json=currentVersion,proto3" json:"current_version,omitempty"`
+	NewVersion     string   `protobuf:"bytes,6,opt,name=new_version,json=newVersion,proto3" json:"new_version,omitempty"`
+	Message        string   `protobuf:"bytes,7,opt,name=message,proto3" json:"message,omitempty"`
+	VotesRequired  int32    `protobuf:"varint,8,opt,name=votes_required,json=votesRequired,proto3" json:"votes_required,omitempty"`
+	VotesReceived  int32    `protobuf:"varint,9,opt,name=votes_received,json=votesReceived,proto3" json:"votes_received,omitempty"`
+	Voters         []string `protobuf:"bytes,10,rep,name=voters,proto3" json:"voters,omitempty"`
+	Deadline       int64    `protobuf:"varint,11,opt,name=deadline,proto3" json:"deadline,omitempty"`
+	CreatedAt      int64    `protobuf:"varint,12,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt      int64    `protobuf:"varint,13,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	Digest         string   `protobuf:"bytes,14,opt,name=digest,proto3" json:"digest,omitempty"`
+	FreezeReason   string   `protobuf:"bytes,15,opt,name=freeze_reason,json=freezeReason,proto3" json:"freeze_reason,omitempty"`
+}
+
+func (m *Approval) Reset()         { *m = Approval{} }
+func (m *Approval) String() string { return proto.CompactTextString(m) }
+func (*Approval) ProtoMessage()    {}
+
+// ListApprovalsRequest - optional approval filters
+type ListApprovalsRequest struct {
+	Status     string `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Namespace  string `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Provider   string `protobuf:"bytes,3,opt,name=provider,proto3" json:"provider,omitempty"`
+	Identifier string `protobuf:"bytes,4,opt,name=identifier,proto3" json:"identifier,omitempty"`
+}
+
+func (m *ListApprovalsRequest) Reset()         { *m = ListApprovalsRequest{} }
+func (m *ListApprovalsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListApprovalsRequest) ProtoMessage()    {}
+
+// ListApprovalsResponse - approvals visible to the caller
+type ListApprovalsResponse struct {
+	Approvals []*Approval `protobuf:"bytes,1,rep,name=approvals,proto3" json:"approvals,omitempty"`
+}
+
+func (m *ListApprovalsResponse) Reset()         { *m = ListApprovalsResponse{} }
+func (m *ListApprovalsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListApprovalsResponse) ProtoMessage()    {}
+
+// VoteRequest - approves or rejects approval by ID
+type VoteRequest struct {
+	Id    string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Voter string `protobuf:"bytes,2,opt,name=voter,proto3" json:"voter,omitempty"`
+}
+
+func (m *VoteRequest) Reset()         { *m = VoteRequest{} }
+func (m *VoteRequest) String() string { return proto.CompactTextString(m) }
+func (*VoteRequest) ProtoMessage()    {}
+
+// TrackedImage - image tracked by keel
+type TrackedImage struct {
+	Image        string `protobuf:"bytes,1,opt,name=image,proto3" json:"image,omitempty"`
+	Trigger      string `protobuf:"bytes,2,opt,name=trigger,proto3" json:"trigger,omitempty"`
+	PollSchedule string `protobuf:"bytes,3,opt,name=poll_schedule,json=pollSchedule,proto3" json:"poll_schedule,omitempty"`
+	Provider     string `protobuf:"bytes,4,opt,name=provider,proto3" json:"provider,omitempty"`
+	Namespace    string `protobuf:"bytes,5,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Policy       string `protobuf:"bytes,6,opt,name=policy,proto3" json:"policy,omitempty"`
+	Registry     string `protobuf:"bytes,7,opt,name=registry,proto3" json:"registry,omitempty"`
+	Paused       bool   `protobuf:"varint,8,opt,name=paused,proto3" json:"paused,omitempty"`
+	FreezeReason string `protobuf:"bytes,9,opt,name=freeze_reason,json=freezeReason,proto3" json:"freeze_reason,omitempty"`
+}
+
+func (m *TrackedImage) Reset()         { *m = TrackedImage{} }
+func (m *TrackedImage) String() string { return proto.CompactTextString(m) }
+func (*TrackedImage) ProtoMessage()    {}
+
+// ListTrackedRequest - optional tracked image filters
+type ListTrackedRequest struct {
+	Namespace string `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Provider  string `protobuf:"bytes,2,opt,name=provider,proto3" json:"provider,omitempty"`
+	Policy    string `protobuf:"bytes,3,opt,name=policy,proto3" json:"policy,omitempty"`
+	Trigger   string `protobuf:"bytes,4,opt,name=trigger,proto3" json:"trigger,omitempty"`
+	Image     string `protobuf:"bytes,5,opt,name=image,proto3" json:"image,omitempty"`
+}
+
+func (m *ListTrackedRequest) Reset()         { *m = ListTrackedRequest{} }
+func (m *ListTrackedRequest) String() string { return proto.CompactTextString(m) }
+func (*ListTrackedRequest) ProtoMessage()    {}
+
+// ListTrackedResponse - tracked images
+type ListTrackedResponse struct {
+	Images []*TrackedImage `protobuf:"bytes,1,rep,name=images,proto3" json:"images,omitempty"`
+}
+
+func (m *ListTrackedResponse) Reset()         { *m = ListTrackedResponse{} }
+func (m *ListTrackedResponse) String() string { return proto.CompactTextString(m) }
+func (*ListTrackedResponse) ProtoMessage()    {}
+
+// Event - audit log entry
+type Event struct {
+	Id           string            `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	CreatedAt    int64             `protobuf:"varint,2,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Action       string            `protobuf:"bytes,3,opt,name=action,proto3" json:"action,omitempty"`
+	ResourceKind string            `protobuf:"bytes,4,opt,name=resource_kind,json=resourceKind,proto3" json:"resource_kind,omitempty"`
+	Identifier   string            `protobuf:"bytes,5,opt,name=identifier,proto3" json:"identifier,omitempty"`
+	Username     string            `protobuf:"bytes,6,opt,name=username,proto3" json:"username,omitempty"`
+	Message      string            `protobuf:"bytes,7,opt,name=message,proto3" json:"message,omitempty"`
+	Metadata     map[string]string `protobuf:"bytes,8,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *Event) Reset()         { *m = Event{} }
+func (m *Event) String() string { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()    {}
+
+// WatchEventsRequest - optional event filters
+type WatchEventsRequest struct {
+	ResourceKinds []string `protobuf:"bytes,1,rep,name=resource_kinds,json=resourceKinds,proto3" json:"resource_kinds,omitempty"`
+	Since         int64    `protobuf:"varint,2,opt,name=since,proto3" json:"since,omitempty"`
+}
+
+func (m *WatchEventsRequest) Reset()         { *m = WatchEventsRequest{} }
+func (m *WatchEventsRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchEventsRequest) ProtoMessage()    {}
+
+const (
+	approvalsListMethod    = "/keel.api.v1.Approvals/List"
+	approvalsApproveMethod = "/keel.api.v1.Approvals/Approve"
+	approvalsRejectMethod  = "/keel.api.v1.Approvals/Reject"
+	trackedListMethod      = "/keel.api.v1.Tracked/List"
+	eventsWatchMethod      = "/keel.api.v1.Events/Watch"
+)
+
+// ApprovalsServer - server side of the Approvals service
+type ApprovalsServer interface {
+	List(context.Context, *ListApprovalsRequest) (*ListApprovalsResponse, error)
+	Approve(context.Context, *VoteRequest) (*Approval, error)
+	Reject(context.Context, *VoteRequest) (*Approval, error)
+}
+
+// TrackedServer - server side of the Tracked service
+type TrackedServer interface {
+	List(context.Context, *ListTrackedRequest) (*ListTrackedResponse, error)
+}
+
+// EventsServer - server side of the Events service
+type EventsServer interface {
+	Watch(*WatchEventsRequest, EventsWatchServer) error
+}
+
+// EventsWatchServer - event stream of the Watch call
+type EventsWatchServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type eventsWatchServer struct {
+	grpc.ServerStream
+}
+
+func (s *eventsWatchServer) Send(event *Event) error {
+	return s.ServerStream.SendMsg(event)
+}
+
+// unaryMethod - method descriptor of a unary call, handle is called with the
+// decoded request
+func unaryMethod(name, fullMethod string, newRequest func() interface{}, handle func(srv interface{}, ctx context.Context, req interface{}) (interface{}, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := newRequest()
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return handle(srv, ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}
+			return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return handle(srv, ctx, req)
+			})
+		},
+	}
+}
+
+// RegisterApprovalsServer - registers Approvals service
+func RegisterApprovalsServer(s *grpc.Server, srv ApprovalsServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "keel.api.v1.Approvals",
+		HandlerType: (*ApprovalsServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unaryMethod("List", approvalsListMethod, func() interface{} { return new(ListApprovalsRequest) }, func(srv interface{}, ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.(ApprovalsServer).List(ctx, req.(*ListApprovalsRequest))
+			}),
+			unaryMethod("Approve", approvalsApproveMethod, func() interface{} { return new(VoteRequest) }, func(srv interface{}, ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.(ApprovalsServer).Approve(ctx, req.(*VoteRequest))
+			}),
+			unaryMethod("Reject", approvalsRejectMethod, func() interface{} { return new(VoteRequest) }, func(srv interface{}, ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.(ApprovalsServer).Reject(ctx, req.(*VoteRequest))
+			}),
+		},
+		Metadata: "api.proto",
+	}, srv)
+}
+
+// RegisterTrackedServer - registers Tracked service
+func RegisterTrackedServer(s *grpc.Server, srv TrackedServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "keel.api.v1.Tracked",
+		HandlerType: (*TrackedServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unaryMethod("List", trackedListMethod, func() interface{} { return new(ListTrackedRequest) }, func(srv interface{}, ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.(TrackedServer).List(ctx, req.(*ListTrackedRequest))
+			}),
+		},
+		Metadata: "api.proto",
+	}, srv)
+}
+
+// RegisterEventsServer - registers Events service
+func RegisterEventsServer(s *grpc.Server, srv EventsServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "keel.api.v1.Events",
+		HandlerType: (*EventsServer)(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Watch",
+			ServerStreams: true,
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				req := new(WatchEventsRequest)
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				return srv.(EventsServer).Watch(req, &eventsWatchServer{stream})
+			},
+		}},
+		Metadata: "api.proto",
+	}, srv)
+}
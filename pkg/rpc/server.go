@@ -0,0 +1,189 @@
+// Package rpc - gRPC API of approvals, tracked images and audit log events,
+// see api.proto for the protocol. It's served alongside the REST API and
+// accepts the same credentials.
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/pkg/auth"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/provider"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Authenticator - authenticates the authorization metadata of calls, ie: the
+// REST API server so that both APIs accept the same credentials
+type Authenticator interface {
+	Authenticate(authorization string) (*auth.User, error)
+}
+
+// Opts - server options
+type Opts struct {
+	Port int
+
+	Providers       provider.Providers
+	ApprovalManager approvals.Manager
+	Store           store.Store
+	Authenticator   Authenticator
+}
+
+// methodAuthorization - role required by the method, API tokens restricted to
+// namespaces can only call scoped methods which check namespaces themselves
+type methodAuthorization struct {
+	role   auth.Role
+	scoped bool
+}
+
+var methods = map[string]methodAuthorization{
+	approvalsListMethod:    {role: auth.RoleViewer, scoped: true},
+	approvalsApproveMethod: {role: auth.RoleApprover, scoped: true},
+	approvalsRejectMethod:  {role: auth.RoleApprover, scoped: true},
+	trackedListMethod:      {role: auth.RoleViewer},
+	eventsWatchMethod:      {role: auth.RoleViewer},
+}
+
+// Server - gRPC API server
+type Server struct {
+	port             int
+	providers        provider.Providers
+	approvalsManager approvals.Manager
+	store            store.Store
+	authenticator    Authenticator
+
+	server *grpc.Server
+}
+
+// New - creates gRPC API server
+func New(opts *Opts) *Server {
+	s := &Server{
+		port:             opts.Port,
+		providers:        opts.Providers,
+		approvalsManager: opts.ApprovalManager,
+		store:            opts.Store,
+		authenticator:    opts.Authenticator,
+	}
+
+	s.server = grpc.NewServer(
+		grpc.UnaryInterceptor(s.authorizeUnary),
+		grpc.StreamInterceptor(s.authorizeStream),
+	)
+	RegisterApprovalsServer(s.server, &approvalsServer{s})
+	RegisterTrackedServer(s.server, &trackedServer{s})
+	RegisterEventsServer(s.server, &eventsServer{s})
+	return s
+}
+
+// Start - starts serving the API, blocks until the server is stopped
+func (s *Server) Start() error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		return err
+	}
+	return s.Serve(lis)
+}
+
+// Serve - serves the API on the listener
+func (s *Server) Serve(lis net.Listener) error {
+	log.WithFields(log.Fields{
+		"port": s.port,
+	}).Info("gRPC API server starting...")
+	return s.server.Serve(lis)
+}
+
+// Stop - stops gRPC server, in-flight calls and event streams get 10 seconds
+// to finish
+func (s *Server) Stop() {
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		s.server.Stop()
+	}
+}
+
+func (s *Server) authorizeUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) authorizeStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authorize(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authorizedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authorize - authenticates the call and checks user role, ctx of the call is
+// returned with the user
+func (s *Server) authorize(ctx context.Context, method string) (context.Context, error) {
+	authorization, ok := methods[method]
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "unknown method %s", method)
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || s.authenticator == nil {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata not set")
+	}
+	user, err := s.authenticator.Authenticate(values[0])
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"method": method,
+		}).Warn("rpc: authentication failed")
+		return nil, status.Error(codes.Unauthenticated, "authentication failed")
+	}
+
+	if len(user.Namespaces) > 0 && !authorization.scoped {
+		return nil, status.Error(codes.PermissionDenied, "API token is restricted to namespaces")
+	}
+	if !user.HasRole(authorization.role) {
+		log.WithFields(log.Fields{
+			"user":     user.Username,
+			"role":     user.Role,
+			"required": authorization.role,
+			"method":   method,
+		}).Warn("rpc: user role doesn't allow the call")
+		return nil, status.Errorf(codes.PermissionDenied, "%s role required", authorization.role)
+	}
+	return auth.WithAccount(ctx, user), nil
+}
+
+// authorizedStream - stream with the authenticated user in its ctx
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+func matchesFilter(filter, value string) bool {
+	return filter == "" || filter == value
+}
+
+func contains(value, substr string) bool {
+	return substr == "" || strings.Contains(value, substr)
+}
@@ -0,0 +1,297 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/pkg/auth"
+	"github.com/keel-hq/keel/pkg/store/sql"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+)
+
+type fakeProviders struct {
+	images []*types.TrackedImage
+}
+
+func (p *fakeProviders) Submit(event types.Event) error { return nil }
+func (p *fakeProviders) TrackedImages() ([]*types.TrackedImage, error) {
+	return p.images, nil
+}
+func (p *fakeProviders) List() []string { return []string{"fake"} }
+func (p *fakeProviders) Stop()          {}
+
+type fakeAuthenticator map[string]*auth.User
+
+func (a fakeAuthenticator) Authenticate(authorization string) (*auth.User, error) {
+	user, ok := a[authorization]
+	if !ok {
+		return nil, fmt.Errorf("unknown token")
+	}
+	return user, nil
+}
+
+var users = fakeAuthenticator{
+	"Bearer admin":    {Username: "admin", Role: auth.RoleAdmin},
+	"Bearer viewer":   {Username: "viewer", Role: auth.RoleViewer},
+	"Bearer approver": {Username: "ci", Role: auth.RoleApprover, Namespaces: []string{"default"}},
+}
+
+type testServer struct {
+	store *sql.SQLStore
+	am    approvals.Manager
+	conn  *grpc.ClientConn
+}
+
+func newTestServer(t *testing.T, providers *fakeProviders) (*testServer, func()) {
+	dir, err := ioutil.TempDir("", "rpcstoretest")
+	if err != nil {
+		t.Fatalf("failed to create dir: %s", err)
+	}
+	store, err := sql.New(sql.Opts{DatabaseType: "sqlite3", URI: filepath.Join(dir, "gorm.db")})
+	if err != nil {
+		t.Fatalf("failed to create store: %s", err)
+	}
+	am := approvals.New(&approvals.Opts{
+		Store: store,
+	})
+
+	srv := New(&Opts{
+		Providers:       providers,
+		ApprovalManager: am,
+		Store:           store,
+		Authenticator:   users,
+	})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	go srv.Serve(lis)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("failed to dial: %s", err)
+	}
+
+	return &testServer{store: store, am: am, conn: conn}, func() {
+		conn.Close()
+		srv.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func createApprovals(t *testing.T, am approvals.Manager) {
+	for _, identifier := range []string{"deployment/default/wd:1.1.0", "deployment/staging/wd:1.1.0"} {
+		err := am.Create(&types.Approval{
+			Identifier:     identifier,
+			Provider:       types.ProviderTypeKubernetes,
+			VotesRequired:  2,
+			CurrentVersion: "1.0.0",
+			NewVersion:     "1.1.0",
+			Deadline:       time.Now().Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("failed to create approval: %s", err)
+		}
+	}
+}
+
+func TestUnauthenticated(t *testing.T) {
+	ts, teardown := newTestServer(t, &fakeProviders{})
+	defer teardown()
+
+	for _, ctx := range []context.Context{context.Background(), withToken("unknown")} {
+		err := ts.conn.Invoke(ctx, approvalsListMethod, &ListApprovalsRequest{}, &ListApprovalsResponse{})
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("expected unauthenticated, got: %v", err)
+		}
+	}
+}
+
+func TestListApprovals(t *testing.T) {
+	ts, teardown := newTestServer(t, &fakeProviders{})
+	defer teardown()
+	createApprovals(t, ts.am)
+
+	resp := &ListApprovalsResponse{}
+	err := ts.conn.Invoke(withToken("admin"), approvalsListMethod, &ListApprovalsRequest{Status: "pending"}, resp)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(resp.Approvals) != 2 {
+		t.Fatalf("expected 2 approvals, got %d", len(resp.Approvals))
+	}
+
+	resp = &ListApprovalsResponse{}
+	err = ts.conn.Invoke(withToken("approver"), approvalsListMethod, &ListApprovalsRequest{}, resp)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(resp.Approvals) != 1 {
+		t.Fatalf("expected 1 approval of allowed namespace, got %d", len(resp.Approvals))
+	}
+	if resp.Approvals[0].Identifier != "deployment/default/wd:1.1.0" {
+		t.Errorf("unexpected approval: %s", resp.Approvals[0].Identifier)
+	}
+}
+
+func TestApprove(t *testing.T) {
+	ts, teardown := newTestServer(t, &fakeProviders{})
+	defer teardown()
+	createApprovals(t, ts.am)
+
+	allowed, err := ts.am.Get("deployment/default/wd:1.1.0")
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+	denied, err := ts.am.Get("deployment/staging/wd:1.1.0")
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+
+	err = ts.conn.Invoke(withToken("viewer"), approvalsApproveMethod, &VoteRequest{Id: allowed.ID}, &Approval{})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected viewer to be denied, got: %v", err)
+	}
+
+	err = ts.conn.Invoke(withToken("approver"), approvalsApproveMethod, &VoteRequest{Id: denied.ID}, &Approval{})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected approval of other namespace to be denied, got: %v", err)
+	}
+
+	resp := &Approval{}
+	err = ts.conn.Invoke(withToken("approver"), approvalsApproveMethod, &VoteRequest{Id: allowed.ID, Voter: "bob"}, resp)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.VotesReceived != 1 {
+		t.Errorf("expected 1 vote, got %d", resp.VotesReceived)
+	}
+	if len(resp.Voters) != 1 || resp.Voters[0] != "ci" {
+		t.Errorf("unexpected voters: %v", resp.Voters)
+	}
+
+	// same token voting on behalf of someone else is not counted again
+	err = ts.conn.Invoke(withToken("approver"), approvalsApproveMethod, &VoteRequest{Id: allowed.ID, Voter: "alice"}, resp)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.VotesReceived != 1 || len(resp.Voters) != 1 {
+		t.Errorf("expected a single vote of the token, got votes: %d, voters: %v", resp.VotesReceived, resp.Voters)
+	}
+
+	err = ts.conn.Invoke(withToken("admin"), approvalsRejectMethod, &VoteRequest{Id: "missing"}, &Approval{})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected not found, got: %v", err)
+	}
+}
+
+func TestListTracked(t *testing.T) {
+	ref, _ := image.Parse("gcr.io/v2-namespace/hello-world:1.1.1")
+	helmRef, _ := image.Parse("karolisr/keel:0.2.0")
+	ts, teardown := newTestServer(t, &fakeProviders{
+		images: []*types.TrackedImage{
+			{
+				Image:     ref,
+				Trigger:   types.TriggerTypePoll,
+				Provider:  "kubernetes",
+				Namespace: "default",
+				Policy:    policy.NewSemverPolicy(policy.SemverPolicyTypeMajor),
+			},
+			{
+				Image:     helmRef,
+				Trigger:   types.TriggerTypeDefault,
+				Provider:  "helm",
+				Namespace: "staging",
+				Policy:    policy.NewSemverPolicy(policy.SemverPolicyTypeMinor),
+			},
+		},
+	})
+	defer teardown()
+
+	resp := &ListTrackedResponse{}
+	err := ts.conn.Invoke(withToken("viewer"), trackedListMethod, &ListTrackedRequest{Provider: "kubernetes"}, resp)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(resp.Images) != 1 {
+		t.Fatalf("expected 1 image, got %d", len(resp.Images))
+	}
+	if resp.Images[0].Image != "v2-namespace/hello-world:1.1.1" {
+		t.Errorf("unexpected image: %s", resp.Images[0].Image)
+	}
+	if resp.Images[0].Trigger != "poll" {
+		t.Errorf("unexpected trigger: %s", resp.Images[0].Trigger)
+	}
+
+	err = ts.conn.Invoke(withToken("approver"), trackedListMethod, &ListTrackedRequest{}, resp)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected namespace restricted token to be denied, got: %v", err)
+	}
+}
+
+func TestWatchEvents(t *testing.T) {
+	interval := eventsPollInterval
+	eventsPollInterval = 20 * time.Millisecond
+	defer func() { eventsPollInterval = interval }()
+
+	ts, teardown := newTestServer(t, &fakeProviders{})
+	defer teardown()
+
+	ctx, cancel := context.WithTimeout(withToken("viewer"), 5*time.Second)
+	defer cancel()
+
+	stream, err := ts.conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, eventsWatchMethod)
+	if err != nil {
+		t.Fatalf("failed to create stream: %s", err)
+	}
+	if err := stream.SendMsg(&WatchEventsRequest{ResourceKinds: []string{"deployment"}}); err != nil {
+		t.Fatalf("failed to send request: %s", err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("failed to close send: %s", err)
+	}
+
+	// give the server time to start polling
+	time.Sleep(100 * time.Millisecond)
+	for _, kind := range []string{"approval", "deployment"} {
+		_, err := ts.store.CreateAuditLog(&types.AuditLog{
+			Action:       types.NotificationDeploymentUpdate.String(),
+			ResourceKind: kind,
+			Identifier:   kind + "/default/wd",
+			Message:      "updated",
+			Metadata:     types.JSONB{"version": "1.1.0"},
+		})
+		if err != nil {
+			t.Fatalf("failed to create audit log: %s", err)
+		}
+	}
+
+	event := &Event{}
+	if err := stream.RecvMsg(event); err != nil {
+		t.Fatalf("failed to receive event: %s", err)
+	}
+	if event.ResourceKind != "deployment" {
+		t.Errorf("unexpected resource kind: %s", event.ResourceKind)
+	}
+	if event.Metadata["version"] != "1.1.0" {
+		t.Errorf("unexpected metadata: %v", event.Metadata)
+	}
+}
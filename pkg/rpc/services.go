@@ -0,0 +1,211 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/pkg/auth"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// audit log is polled for new entries like by the REST events stream
+var eventsPollInterval = time.Second
+
+type approvalsServer struct {
+	*Server
+}
+
+func approvalAllowed(ctx context.Context, a *types.Approval) bool {
+	return approvals.Allowed(auth.GetAccountFromCtx(ctx), a)
+}
+
+func toApproval(a *types.Approval) *Approval {
+	voters := a.GetVoters()
+	sort.Strings(voters)
+	return &Approval{
+		Id:             a.ID,
+		Identifier:     a.Identifier,
+		Provider:       a.Provider.String(),
+		Status:         approvals.Status(a),
+		CurrentVersion: a.CurrentVersion,
+		NewVersion:     a.NewVersion,
+		Message:        a.Message,
+		VotesRequired:  int32(a.VotesRequired),
+		VotesReceived:  int32(a.VotesReceived),
+		Voters:         voters,
+		Deadline:       a.Deadline.Unix(),
+		CreatedAt:      a.CreatedAt.Unix(),
+		UpdatedAt:      a.UpdatedAt.Unix(),
+		Digest:         a.Digest,
+		FreezeReason:   a.FreezeReason,
+	}
+}
+
+// List - approvals (both archived) of namespaces allowed to the caller
+func (s *approvalsServer) List(ctx context.Context, req *ListApprovalsRequest) (*ListApprovalsResponse, error) {
+	list, err := s.store.ListApprovals(&types.GetApprovalQuery{})
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &ListApprovalsResponse{}
+	for _, a := range list {
+		switch {
+		case !approvalAllowed(ctx, a):
+		case !matchesFilter(req.Status, approvals.Status(a)):
+		case !matchesFilter(req.Namespace, approvals.Namespace(a.Identifier)):
+		case !matchesFilter(req.Provider, a.Provider.String()):
+		case !contains(a.Identifier, req.Identifier):
+		default:
+			resp.Approvals = append(resp.Approvals, toApproval(a))
+		}
+	}
+	return resp, nil
+}
+
+func (s *approvalsServer) Approve(ctx context.Context, req *VoteRequest) (*Approval, error) {
+	return s.vote(ctx, req, false)
+}
+
+func (s *approvalsServer) Reject(ctx context.Context, req *VoteRequest) (*Approval, error) {
+	return s.vote(ctx, req, true)
+}
+
+// vote - approves or rejects approval by ID, vote is counted for the caller identity
+func (s *approvalsServer) vote(ctx context.Context, req *VoteRequest, reject bool) (*Approval, error) {
+	existing, err := s.store.GetApproval(&types.GetApprovalQuery{ID: req.Id})
+	if err != nil {
+		if err == store.ErrRecordNotFound {
+			return nil, status.Errorf(codes.NotFound, "approval '%s' not found", req.Id)
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if !approvalAllowed(ctx, existing) {
+		return nil, status.Errorf(codes.PermissionDenied, "approvals of namespace '%s' are not allowed", approvals.Namespace(existing.Identifier))
+	}
+	if existing.Archived {
+		return nil, status.Errorf(codes.FailedPrecondition, "approval '%s' is archived", req.Id)
+	}
+
+	vote := approvals.UserVote(auth.GetAccountFromCtx(ctx), req.Voter)
+
+	var approval *types.Approval
+	if reject {
+		approval, err = s.approvalsManager.RejectVote(existing.Identifier, vote)
+	} else {
+		approval, err = s.approvalsManager.ApproveVote(existing.Identifier, vote)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return toApproval(approval), nil
+}
+
+type trackedServer struct {
+	*Server
+}
+
+// List - tracked images of all providers
+func (s *trackedServer) List(ctx context.Context, req *ListTrackedRequest) (*ListTrackedResponse, error) {
+	trackedImages, err := s.providers.TrackedImages()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &ListTrackedResponse{}
+	for _, img := range trackedImages {
+		ti := &TrackedImage{
+			Image:        img.Image.Name(),
+			Trigger:      img.Trigger.String(),
+			PollSchedule: img.PollSchedule,
+			Provider:     img.Provider,
+			Namespace:    img.Namespace,
+			Policy:       img.Policy.Name(),
+			Registry:     img.Image.Registry(),
+			Paused:       img.Paused,
+			FreezeReason: img.FreezeReason,
+		}
+
+		switch {
+		case !matchesFilter(req.Namespace, ti.Namespace):
+		case !matchesFilter(req.Provider, ti.Provider):
+		case !matchesFilter(req.Policy, ti.Policy):
+		case !matchesFilter(req.Trigger, ti.Trigger):
+		case !contains(ti.Image, req.Image):
+		default:
+			resp.Images = append(resp.Images, ti)
+		}
+	}
+	return resp, nil
+}
+
+type eventsServer struct {
+	*Server
+}
+
+func toEvent(entry *types.AuditLog) *Event {
+	event := &Event{
+		Id:           entry.ID,
+		CreatedAt:    entry.CreatedAt.Unix(),
+		Action:       entry.Action,
+		ResourceKind: entry.ResourceKind,
+		Identifier:   entry.Identifier,
+		Username:     entry.Username,
+		Message:      entry.Message,
+	}
+	if len(entry.Metadata) > 0 {
+		event.Metadata = make(map[string]string, len(entry.Metadata))
+		for k, v := range entry.Metadata {
+			event.Metadata[k] = fmt.Sprint(v)
+		}
+	}
+	return event
+}
+
+// Watch - streams new audit log entries until the call is cancelled
+func (s *eventsServer) Watch(req *WatchEventsRequest, stream EventsWatchServer) error {
+	query := types.AuditLogQuery{
+		Order:              "created_at",
+		ResourceKindFilter: []string{"*"},
+	}
+	if len(req.ResourceKinds) > 0 {
+		query.ResourceKindFilter = req.ResourceKinds
+	}
+	start := time.Now()
+	if req.Since > 0 {
+		start = time.Unix(req.Since, 0)
+	}
+
+	poller := store.NewAuditLogPoller(s.store, query, start, eventsPollInterval)
+	poll := time.NewTicker(eventsPollInterval)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-poll.C:
+			entries, err := poller.Poll()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+				}).Error("rpc.Watch: failed to get audit logs")
+				continue
+			}
+			for _, entry := range entries {
+				if err := stream.Send(toEvent(entry)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
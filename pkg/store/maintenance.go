@@ -0,0 +1,39 @@
+package store
+
+import (
+	"github.com/keel-hq/keel/types"
+)
+
+// Maintenance - maintenance mode record, nil when automatic updates are not
+// suspended cluster-wide
+func Maintenance(s Store) *types.PausedResource {
+	if s == nil {
+		return nil
+	}
+	paused, err := s.GetPausedResource(types.PausedAllIdentifier)
+	if err != nil {
+		return nil
+	}
+	return paused
+}
+
+// SetMaintenance - enables or disables maintenance mode, returns whether the
+// mode was changed
+func SetMaintenance(s Store, enabled bool, user string) (changed bool, err error) {
+	paused := Maintenance(s)
+	switch {
+	case enabled && paused == nil:
+		_, err = s.CreatePausedResource(&types.PausedResource{
+			Identifier: types.PausedAllIdentifier,
+			User:       user,
+		})
+	case !enabled && paused != nil:
+		err = s.DeletePausedResource(types.PausedAllIdentifier)
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
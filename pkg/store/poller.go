@@ -0,0 +1,64 @@
+package store
+
+import (
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+// AuditLogPoller - returns audit log entries created since the previous poll,
+// replicas share the database so entries of the leader are returned to
+// followers too. Entries are queried with an overlap so that entries committed
+// after the previous poll with an older timestamp aren't missed, returned
+// entries are remembered until they fall out of the overlap.
+type AuditLogPoller struct {
+	store   Store
+	query   types.AuditLogQuery
+	start   time.Time
+	last    time.Time
+	overlap time.Duration
+	seen    map[string]time.Time
+}
+
+// NewAuditLogPoller - poller of entries matching the query created after start
+func NewAuditLogPoller(store Store, query types.AuditLogQuery, start time.Time, overlap time.Duration) *AuditLogPoller {
+	return &AuditLogPoller{
+		store:   store,
+		query:   query,
+		start:   start,
+		last:    start,
+		overlap: overlap,
+		seen:    make(map[string]time.Time),
+	}
+}
+
+// Poll - entries that weren't returned before
+func (p *AuditLogPoller) Poll() ([]*types.AuditLog, error) {
+	p.query.Since = p.last.Add(-p.overlap)
+	if p.query.Since.Before(p.start) {
+		p.query.Since = p.start
+	}
+	entries, err := p.store.GetAuditLogs(&p.query)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []*types.AuditLog
+	for _, entry := range entries {
+		if _, ok := p.seen[entry.ID]; ok {
+			continue
+		}
+		p.seen[entry.ID] = entry.CreatedAt
+		if entry.CreatedAt.After(p.last) {
+			p.last = entry.CreatedAt
+		}
+		created = append(created, entry)
+	}
+
+	for id, createdAt := range p.seen {
+		if createdAt.Before(p.query.Since) {
+			delete(p.seen, id)
+		}
+	}
+	return created, nil
+}
@@ -0,0 +1,57 @@
+package sql
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+)
+
+// CreateAPIToken - stores API token
+func (s *SQLStore) CreateAPIToken(token *types.StoredAPIToken) (*types.StoredAPIToken, error) {
+	if token.ID == "" {
+		token.ID = uuid.New().String()
+	}
+
+	err := s.db.Create(token).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// GetAPIToken - get API token by ID or hash
+func (s *SQLStore) GetAPIToken(q *types.GetAPITokenQuery) (*types.StoredAPIToken, error) {
+	if q.ID == "" && q.Hash == "" {
+		return nil, fmt.Errorf("API token ID or hash is required")
+	}
+
+	var result types.StoredAPIToken
+	err := s.db.Where(&types.StoredAPIToken{ID: q.ID, Hash: q.Hash}).First(&result).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, store.ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListAPITokens - list API tokens, newest tokens first
+func (s *SQLStore) ListAPITokens() ([]*types.StoredAPIToken, error) {
+	var tokens []*types.StoredAPIToken
+	err := s.db.Order("created_at desc").Find(&tokens).Error
+	return tokens, err
+}
+
+// DeleteAPIToken - revokes API token
+func (s *SQLStore) DeleteAPIToken(id string) error {
+	if id == "" {
+		return fmt.Errorf("API token ID is required")
+	}
+	return s.db.Delete(&types.StoredAPIToken{ID: id}).Error
+}
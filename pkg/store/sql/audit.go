@@ -41,12 +41,17 @@ func (s *SQLStore) GetAuditLogs(query *types.AuditLogQuery) (logs []*types.Audit
 		query.Order = "created_at desc"
 	}
 
+	q := s.db.Order(query.Order).Limit(query.Limit).Offset(query.Offset)
+	if !query.Since.IsZero() {
+		q = q.Where("created_at > ?", query.Since)
+	}
+
 	if len(query.ResourceKindFilter) == 1 && query.ResourceKindFilter[0] == "*" {
-		err = s.db.Order(query.Order).Limit(query.Limit).Offset(query.Offset).Find(&logs).Error
+		err = q.Find(&logs).Error
 	} else if query.Username != "" {
-		err = s.db.Order(query.Order).Where("resource_kind in (?)", query.ResourceKindFilter).Limit(query.Limit).Offset(query.Offset).Where("username = ?", query.Username).Find(&logs).Error
+		err = q.Where("resource_kind in (?)", query.ResourceKindFilter).Where("username = ?", query.Username).Find(&logs).Error
 	} else {
-		err = s.db.Order(query.Order).Where("resource_kind in (?)", query.ResourceKindFilter).Limit(query.Limit).Offset(query.Offset).Find(&logs).Error
+		err = q.Where("resource_kind in (?)", query.ResourceKindFilter).Find(&logs).Error
 	}
 
 	return logs, err
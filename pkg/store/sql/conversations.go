@@ -0,0 +1,42 @@
+package sql
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+)
+
+// SaveBotConversation - creates or updates approval conversation of a bot
+func (s *SQLStore) SaveBotConversation(conversation *types.BotConversation) error {
+	conversation.ID = types.BotConversationID(conversation.Bot, conversation.Identifier)
+	return s.db.Save(conversation).Error
+}
+
+// GetBotConversation - conversation of the approval, store.ErrRecordNotFound
+// is returned when bot doesn't have one
+func (s *SQLStore) GetBotConversation(bot, identifier string) (*types.BotConversation, error) {
+	var conversation types.BotConversation
+	err := s.db.Where("id = ?", types.BotConversationID(bot, identifier)).First(&conversation).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, store.ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// ListBotConversations - conversations of the bot
+func (s *SQLStore) ListBotConversations(bot string) ([]*types.BotConversation, error) {
+	var conversations []*types.BotConversation
+	err := s.db.Where("bot = ?", bot).Order("created_at").Find(&conversations).Error
+	return conversations, err
+}
+
+// DeleteBotConversations - deletes conversations that expired before t
+func (s *SQLStore) DeleteBotConversations(expiredBefore time.Time) error {
+	return s.db.Where("expires < ?", expiredBefore).Delete(&types.BotConversation{}).Error
+}
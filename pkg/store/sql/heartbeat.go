@@ -0,0 +1,15 @@
+package sql
+
+import (
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+const heartbeatID = "keel"
+
+// Heartbeat - writes heartbeat row, fails when database is read-only or
+// unreachable
+func (s *SQLStore) Heartbeat() error {
+	return s.db.Save(&types.Heartbeat{ID: heartbeatID, UpdatedAt: time.Now()}).Error
+}
@@ -0,0 +1,53 @@
+package sql
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/keel-hq/keel/types"
+)
+
+// CreateUpdateRecord - create new update history entry
+func (s *SQLStore) CreateUpdateRecord(record *types.UpdateRecord) (*types.UpdateRecord, error) {
+	if record.ID == "" {
+		record.ID = uuid.New().String()
+	}
+
+	err := s.db.Create(record).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// ListUpdateRecords - list update history, newest entries first
+func (s *SQLStore) ListUpdateRecords(query *types.UpdateRecordQuery) ([]*types.UpdateRecord, error) {
+	var records []*types.UpdateRecord
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = -1
+	}
+	offset := query.Offset
+	if offset == 0 {
+		offset = -1
+	}
+
+	db := s.db.Order("created_at desc").Where(&types.UpdateRecord{
+		Namespace: query.Namespace,
+		Name:      query.Name,
+		Image:     query.Image,
+		Provider:  query.Provider,
+		Status:    query.Status,
+	})
+	if !query.Since.IsZero() {
+		db = db.Where("created_at >= ?", query.Since)
+	}
+	if !query.Until.IsZero() {
+		db = db.Where("created_at < ?", query.Until)
+	}
+
+	err := db.Limit(limit).Offset(offset).Find(&records).Error
+
+	return records, err
+}
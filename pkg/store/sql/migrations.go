@@ -0,0 +1,225 @@
+package sql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// schemaMigration - applied migration, stored in schema_migrations table
+type schemaMigration struct {
+	Version   int    `gorm:"primary_key;auto_increment:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type migration struct {
+	version int
+	name    string
+	migrate func(tx *gorm.DB) error
+}
+
+// migrations - schema changes in the order they were introduced. Released
+// migrations must not be changed, model changes that need schema changes
+// get a new migration at the end of the list.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create approvals and audit logs",
+		migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&approvalV1{}, &auditLogV1{}).Error
+		},
+	},
+	{
+		version: 2,
+		name:    "add approval routing, reminders and idempotency key",
+		migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&types.Approval{}).Error
+		},
+	},
+	{
+		version: 3,
+		name:    "create paused resources",
+		migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&types.PausedResource{}).Error
+		},
+	},
+	{
+		version: 4,
+		name:    "create update records",
+		migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&types.UpdateRecord{}).Error
+		},
+	},
+	{
+		version: 5,
+		name:    "create webhook records",
+		migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&types.WebhookRecord{}).Error
+		},
+	},
+	{
+		version: 6,
+		name:    "create api tokens",
+		migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&types.StoredAPIToken{}).Error
+		},
+	},
+	{
+		version: 7,
+		name:    "create heartbeats",
+		migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&types.Heartbeat{}).Error
+		},
+	},
+	{
+		version: 8,
+		name:    "create poll digests",
+		migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&types.PollDigest{}).Error
+		},
+	},
+	{
+		version: 9,
+		name:    "create bot conversations",
+		migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&types.BotConversation{}).Error
+		},
+	},
+}
+
+// approvalV1 - approvals table before versioned migrations were introduced
+type approvalV1 struct {
+	ID             string `gorm:"primary_key;type:varchar(36)"`
+	Archived       bool
+	Provider       types.ProviderType
+	Identifier     string
+	Event          *types.Event `gorm:"type:json"`
+	Message        string
+	CurrentVersion string
+	NewVersion     string
+	Digest         string
+	VotesRequired  int
+	VotesReceived  int
+	Voters         types.JSONB `gorm:"type:json"`
+	Rejected       bool
+	Deadline       time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (approvalV1) TableName() string {
+	return "approvals"
+}
+
+// auditLogV1 - audit_logs table before versioned migrations were introduced
+type auditLogV1 struct {
+	ID           string `gorm:"primary_key;type:varchar(36)"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	AccountID    string
+	Username     string
+	Email        string
+	Action       string
+	ResourceKind string
+	Identifier   string
+	Message      string
+	Payload      string
+	PayloadType  string
+	Metadata     types.JSONB `gorm:"type:json"`
+}
+
+func (auditLogV1) TableName() string {
+	return "audit_logs"
+}
+
+// SchemaVersion - latest schema version keel can work with
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// migrate - applies migrations that weren't applied yet, each in its own
+// transaction. Databases created before versioned migrations already have
+// some of the tables, migrations only create what's missing there.
+func migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(&schemaMigration{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %s", err)
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	for version := range applied {
+		if version > SchemaVersion() {
+			return fmt.Errorf("database schema version %d is newer than %d supported by this keel version, upgrade keel", version, SchemaVersion())
+		}
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+
+		err = applyMigration(db, m)
+		if err != nil {
+			// another replica might have applied it at the same time
+			if done, lookupErr := appliedMigrations(db); lookupErr == nil && done[m.version] {
+				continue
+			}
+			return fmt.Errorf("migration %d (%s) failed: %s", m.version, m.name, err)
+		}
+
+		log.WithFields(log.Fields{
+			"version": m.version,
+			"name":    m.name,
+		}).Info("sql store: migration applied")
+	}
+
+	return nil
+}
+
+func applyMigration(db *gorm.DB, m migration) error {
+	tx := db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	err := m.migrate(tx)
+	if err == nil {
+		err = tx.Create(&schemaMigration{
+			Version:   m.version,
+			Name:      m.name,
+			AppliedAt: time.Now(),
+		}).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func appliedMigrations(db *gorm.DB) (map[int]bool, error) {
+	var list []schemaMigration
+	err := db.Find(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %s", err)
+	}
+
+	applied := make(map[int]bool, len(list))
+	for _, m := range list {
+		applied[m.Version] = true
+	}
+	return applied, nil
+}
@@ -0,0 +1,108 @@
+package sql
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/keel-hq/keel/types"
+)
+
+func tempDatabase(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "migrationstest")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	return filepath.Join(dir, "gorm.db"), func() { os.RemoveAll(dir) }
+}
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	uri, teardown := tempDatabase(t)
+	defer teardown()
+
+	store, err := New(Opts{DatabaseType: DatabaseTypeSQLite, URI: uri})
+	if err != nil {
+		t.Fatalf("failed to create store: %s", err)
+	}
+	store.Close()
+
+	// reopening doesn't apply migrations again
+	store, err = New(Opts{DatabaseType: DatabaseTypeSQLite, URI: uri})
+	if err != nil {
+		t.Fatalf("failed to reopen store: %s", err)
+	}
+	defer store.Close()
+
+	applied, err := appliedMigrations(store.db)
+	if err != nil {
+		t.Fatalf("failed to list migrations: %s", err)
+	}
+	if len(applied) != len(migrations) || !applied[SchemaVersion()] {
+		t.Errorf("expected all %d migrations to be applied, got: %v", len(migrations), applied)
+	}
+}
+
+func TestMigrateExistingDatabase(t *testing.T) {
+	uri, teardown := tempDatabase(t)
+	defer teardown()
+
+	// database created by older keel versions, before versioned migrations
+	db, err := gorm.Open(DatabaseTypeSQLite, uri)
+	if err != nil {
+		t.Fatalf("failed to open database: %s", err)
+	}
+	err = db.AutoMigrate(&approvalV1{}, &auditLogV1{}).Error
+	if err != nil {
+		t.Fatalf("failed to create tables: %s", err)
+	}
+	err = db.Create(&approvalV1{
+		ID:            "1",
+		Identifier:    "deployment/default/wd:1.1.0",
+		VotesRequired: 1,
+		Deadline:      time.Now().Add(time.Hour),
+	}).Error
+	if err != nil {
+		t.Fatalf("failed to create approval: %s", err)
+	}
+	db.Close()
+
+	store, err := New(Opts{DatabaseType: DatabaseTypeSQLite, URI: uri})
+	if err != nil {
+		t.Fatalf("failed to create store: %s", err)
+	}
+	defer store.Close()
+
+	approval, err := store.GetApproval(&types.GetApprovalQuery{ID: "1"})
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+	approval.Workspace = "ops"
+	err = store.UpdateApproval(approval)
+	if err != nil {
+		t.Fatalf("failed to update approval with new columns: %s", err)
+	}
+}
+
+func TestMigrateNewerDatabase(t *testing.T) {
+	uri, teardown := tempDatabase(t)
+	defer teardown()
+
+	store, err := New(Opts{DatabaseType: DatabaseTypeSQLite, URI: uri})
+	if err != nil {
+		t.Fatalf("failed to create store: %s", err)
+	}
+	err = store.db.Create(&schemaMigration{Version: SchemaVersion() + 1, Name: "from the future"}).Error
+	if err != nil {
+		t.Fatalf("failed to record migration: %s", err)
+	}
+	store.Close()
+
+	_, err = New(Opts{DatabaseType: DatabaseTypeSQLite, URI: uri})
+	if err == nil || !strings.Contains(err.Error(), "newer than") {
+		t.Errorf("expected newer schema to be rejected, got: %v", err)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build mysql
+// +build mysql
+
+package sql
+
+import (
+	// importing mysql driver, DSN has to set parseTime=true
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func init() {
+	drivers[DatabaseTypeMySQL] = true
+}
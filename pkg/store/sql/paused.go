@@ -0,0 +1,45 @@
+package sql
+
+import (
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+)
+
+// CreatePausedResource - pause automatic updates for a resource
+func (s *SQLStore) CreatePausedResource(paused *types.PausedResource) (*types.PausedResource, error) {
+	if paused.ID == "" {
+		paused.ID = uuid.New().String()
+	}
+
+	err := s.db.Create(paused).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return paused, nil
+}
+
+// GetPausedResource - get paused resource by identifier
+func (s *SQLStore) GetPausedResource(identifier string) (*types.PausedResource, error) {
+	var result types.PausedResource
+	err := s.db.Where("identifier = ?", identifier).First(&result).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, store.ErrRecordNotFound
+	}
+	return &result, err
+}
+
+// ListPausedResources - list all paused resources
+func (s *SQLStore) ListPausedResources() ([]*types.PausedResource, error) {
+	var paused []*types.PausedResource
+	err := s.db.Order("created_at desc").Find(&paused).Error
+	return paused, err
+}
+
+// DeletePausedResource - resume automatic updates for a resource
+func (s *SQLStore) DeletePausedResource(identifier string) error {
+	return s.db.Where("identifier = ?", identifier).Delete(&types.PausedResource{}).Error
+}
@@ -0,0 +1,24 @@
+package sql
+
+import (
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+// SavePollDigest - creates or updates digest of a poll watch job
+func (s *SQLStore) SavePollDigest(digest *types.PollDigest) error {
+	return s.db.Save(digest).Error
+}
+
+// ListPollDigests - all stored poll digests
+func (s *SQLStore) ListPollDigests() ([]*types.PollDigest, error) {
+	var digests []*types.PollDigest
+	err := s.db.Order("id").Find(&digests).Error
+	return digests, err
+}
+
+// DeletePollDigests - deletes digests that weren't updated since t
+func (s *SQLStore) DeletePollDigests(before time.Time) error {
+	return s.db.Where("updated_at < ?", before).Delete(&types.PollDigest{}).Error
+}
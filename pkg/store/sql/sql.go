@@ -3,27 +3,69 @@ package sql
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
-	"github.com/keel-hq/keel/types"
 
-	// importing sqlite driver
+	// importing sqlite and postgres drivers
 	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	_ "github.com/lib/pq"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// database types
+const (
+	DatabaseTypeSQLite   = "sqlite3"
+	DatabaseTypePostgres = "postgres"
+	DatabaseTypeMySQL    = "mysql"
+)
+
+// drivers - database types keel is built with, MySQL driver is only included
+// when built with mysql tag
+var drivers = map[string]bool{
+	DatabaseTypeSQLite:   true,
+	DatabaseTypePostgres: true,
+}
+
 type SQLStore struct {
 	db *gorm.DB
 }
 
 type Opts struct {
-	DatabaseType string // sqlite3 / postgres
-	URI          string // path or conn string
+	DatabaseType string // sqlite3 (default) / postgres / mysql
+	// URI - sqlite path or DSN, ie: postgres://keel:pass@db:5432/keel?sslmode=disable
+	// or keel:pass@tcp(db:3306)/keel?parseTime=true for mysql
+	URI string
+}
+
+// ParseDatabaseType - normalizes database type, empty type is sqlite
+func ParseDatabaseType(value string) (string, error) {
+	dbType := strings.ToLower(strings.TrimSpace(value))
+	switch dbType {
+	case "", "sqlite":
+		dbType = DatabaseTypeSQLite
+	case "postgresql", "pg":
+		dbType = DatabaseTypePostgres
+	}
+
+	if drivers[dbType] {
+		return dbType, nil
+	}
+	if dbType == DatabaseTypeMySQL {
+		return "", fmt.Errorf("keel is built without mysql support, rebuild it with '-tags mysql'")
+	}
+	return "", fmt.Errorf("unsupported database type '%s', supported: sqlite3, postgres, mysql", value)
 }
 
 func New(opts Opts) (*SQLStore, error) {
+	dbType, err := ParseDatabaseType(opts.DatabaseType)
+	if err != nil {
+		return nil, err
+	}
+	opts.DatabaseType = dbType
+
 	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
 	defer cancel()
 	db, err := connect(ctx, opts)
@@ -31,10 +73,7 @@ func New(opts Opts) (*SQLStore, error) {
 		return nil, err
 	}
 
-	err = db.AutoMigrate(
-		&types.Approval{},
-		&types.AuditLog{},
-	).Error
+	err = migrate(db)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err,
@@ -65,12 +104,24 @@ func connect(ctx context.Context, opts Opts) (*gorm.DB, error) {
 			return nil, fmt.Errorf("sql store startup deadline exceeded")
 		default:
 			db, err := gorm.Open(opts.DatabaseType, opts.URI)
+			if err == nil {
+				// opening postgres and mysql connections doesn't reach the server
+				err = db.DB().Ping()
+				if err != nil {
+					db.Close()
+				}
+			}
 			if err != nil {
 				time.Sleep(1 * time.Second)
-				log.WithFields(log.Fields{
+				fields := log.Fields{
 					"error": err,
-					"uri":   opts.URI,
-				}).Warn("sql store connector: can't reach DB, waiting")
+					"type":  opts.DatabaseType,
+				}
+				// DSNs of database servers include credentials
+				if opts.DatabaseType == DatabaseTypeSQLite {
+					fields["uri"] = opts.URI
+				}
+				log.WithFields(fields).Warn("sql store connector: can't reach DB, waiting")
 				continue
 			}
 
@@ -0,0 +1,151 @@
+package sql
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+
+	"github.com/keel-hq/keel/types"
+)
+
+// ExportState - all stored records, oldest first
+func (s *SQLStore) ExportState() (*types.State, error) {
+	state := &types.State{
+		Version:    types.StateVersion,
+		ExportedAt: time.Now(),
+	}
+
+	for _, records := range []interface{}{
+		&state.Approvals,
+		&state.AuditLogs,
+		&state.PausedResources,
+		&state.UpdateRecords,
+		&state.WebhookRecords,
+	} {
+		err := s.db.Order("created_at").Find(records).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// token hashes aren't serialized with stored tokens
+	state.APITokens = []*types.ExportedAPIToken{}
+	var tokens []*types.StoredAPIToken
+	err := s.db.Order("created_at").Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		state.APITokens = append(state.APITokens, &types.ExportedAPIToken{StoredAPIToken: *token, Hash: token.Hash})
+	}
+
+	err = s.db.Order("id").Find(&state.PollDigests).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// ImportState - creates exported records in a single transaction, records
+// that already exist are skipped so import can be safely repeated
+func (s *SQLStore) ImportState(state *types.State) (*types.StateImportResult, error) {
+	result := &types.StateImportResult{
+		Imported: make(map[string]int),
+		Skipped:  make(map[string]int),
+	}
+
+	tx := s.db.Begin()
+	err := importState(tx, state, result)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func importState(tx *gorm.DB, state *types.State, result *types.StateImportResult) error {
+	for _, approval := range state.Approvals {
+		ensureID(&approval.ID)
+		err := importRecord(tx, result, types.StateKindApprovals, &types.Approval{}, approval, "id = ?", approval.ID)
+		if err != nil {
+			return err
+		}
+	}
+	for _, entry := range state.AuditLogs {
+		ensureID(&entry.ID)
+		err := importRecord(tx, result, types.StateKindAuditLogs, &types.AuditLog{}, entry, "id = ?", entry.ID)
+		if err != nil {
+			return err
+		}
+	}
+	for _, paused := range state.PausedResources {
+		// paused resources are unique by identifier
+		ensureID(&paused.ID)
+		err := importRecord(tx, result, types.StateKindPausedResources, &types.PausedResource{}, paused, "identifier = ?", paused.Identifier)
+		if err != nil {
+			return err
+		}
+	}
+	for _, record := range state.UpdateRecords {
+		ensureID(&record.ID)
+		err := importRecord(tx, result, types.StateKindUpdateRecords, &types.UpdateRecord{}, record, "id = ?", record.ID)
+		if err != nil {
+			return err
+		}
+	}
+	for _, record := range state.WebhookRecords {
+		ensureID(&record.ID)
+		err := importRecord(tx, result, types.StateKindWebhookRecords, &types.WebhookRecord{}, record, "id = ?", record.ID)
+		if err != nil {
+			return err
+		}
+	}
+	for _, digest := range state.PollDigests {
+		err := importRecord(tx, result, types.StateKindPollDigests, &types.PollDigest{}, digest, "id = ?", digest.ID)
+		if err != nil {
+			return err
+		}
+	}
+	for _, exported := range state.APITokens {
+		token := exported.StoredAPIToken
+		token.Hash = exported.Hash
+		ensureID(&token.ID)
+		err := importRecord(tx, result, types.StateKindAPITokens, &types.StoredAPIToken{}, &token, "hash = ?", token.Hash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ensureID(id *string) {
+	if *id == "" {
+		*id = uuid.New().String()
+	}
+}
+
+// importRecord - creates record unless an existing record matches the query
+func importRecord(tx *gorm.DB, result *types.StateImportResult, kind string, existing, record interface{}, query, value string) error {
+	err := tx.Where(query, value).First(existing).Error
+	switch {
+	case err == nil:
+		result.Skipped[kind]++
+		return nil
+	case err != gorm.ErrRecordNotFound:
+		return err
+	}
+
+	err = tx.Create(record).Error
+	if err != nil {
+		return err
+	}
+	result.Imported[kind]++
+	return nil
+}
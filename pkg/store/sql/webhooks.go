@@ -0,0 +1,74 @@
+package sql
+
+import (
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+)
+
+// CreateWebhookRecord - stores received webhook
+func (s *SQLStore) CreateWebhookRecord(record *types.WebhookRecord) (*types.WebhookRecord, error) {
+	if record.ID == "" {
+		record.ID = uuid.New().String()
+	}
+
+	err := s.db.Create(record).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// GetWebhookRecord - get received webhook by ID
+func (s *SQLStore) GetWebhookRecord(id string) (*types.WebhookRecord, error) {
+	var record types.WebhookRecord
+	err := s.db.Where(&types.WebhookRecord{ID: id}).First(&record).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, store.ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// ListWebhookRecords - list received webhooks, newest entries first
+func (s *SQLStore) ListWebhookRecords(query *types.WebhookRecordQuery) ([]*types.WebhookRecord, error) {
+	var records []*types.WebhookRecord
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = -1
+	}
+	offset := query.Offset
+	if offset == 0 {
+		offset = -1
+	}
+
+	err := s.db.Order("created_at desc").Where(&types.WebhookRecord{
+		Source: query.Source,
+	}).Limit(limit).Offset(offset).Find(&records).Error
+
+	return records, err
+}
+
+// TrimWebhookRecords - deletes all but the newest keep records
+func (s *SQLStore) TrimWebhookRecords(keep int) error {
+	var records []*types.WebhookRecord
+	err := s.db.Select("id").Order("created_at desc").Limit(keep).Find(&records).Error
+	if err != nil {
+		return err
+	}
+	if len(records) < keep {
+		return nil
+	}
+
+	ids := make([]string, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.ID)
+	}
+	return s.db.Where("id NOT IN (?)", ids).Delete(&types.WebhookRecord{}).Error
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"errors"
+	"time"
 
 	"github.com/keel-hq/keel/types"
 )
@@ -18,7 +19,42 @@ type Store interface {
 	ListApprovals(q *types.GetApprovalQuery) ([]*types.Approval, error)
 	DeleteApproval(approval *types.Approval) error
 
+	CreatePausedResource(paused *types.PausedResource) (*types.PausedResource, error)
+	GetPausedResource(identifier string) (*types.PausedResource, error)
+	ListPausedResources() ([]*types.PausedResource, error)
+	DeletePausedResource(identifier string) error
+
+	CreateUpdateRecord(record *types.UpdateRecord) (*types.UpdateRecord, error)
+	ListUpdateRecords(query *types.UpdateRecordQuery) ([]*types.UpdateRecord, error)
+
+	CreateWebhookRecord(record *types.WebhookRecord) (*types.WebhookRecord, error)
+	GetWebhookRecord(id string) (*types.WebhookRecord, error)
+	ListWebhookRecords(query *types.WebhookRecordQuery) ([]*types.WebhookRecord, error)
+	TrimWebhookRecords(keep int) error
+
+	SavePollDigest(digest *types.PollDigest) error
+	ListPollDigests() ([]*types.PollDigest, error)
+	DeletePollDigests(before time.Time) error
+
+	// SaveBotConversation - creates or updates approval conversation of a bot
+	SaveBotConversation(conversation *types.BotConversation) error
+	GetBotConversation(bot, identifier string) (*types.BotConversation, error)
+	ListBotConversations(bot string) ([]*types.BotConversation, error)
+	DeleteBotConversations(expiredBefore time.Time) error
+
+	CreateAPIToken(token *types.StoredAPIToken) (*types.StoredAPIToken, error)
+	GetAPIToken(q *types.GetAPITokenQuery) (*types.StoredAPIToken, error)
+	ListAPITokens() ([]*types.StoredAPIToken, error)
+	DeleteAPIToken(id string) error
+
+	// ExportState - all stored records, see types.State
+	ExportState() (*types.State, error)
+	// ImportState - creates exported records, existing ones are skipped
+	ImportState(state *types.State) (*types.StateImportResult, error)
+
 	OK() bool
+	// Heartbeat - checks that the store accepts writes
+	Heartbeat() error
 	Close() error
 }
 
@@ -0,0 +1,158 @@
+package gitops
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/types"
+)
+
+// supported Git hosting services
+const (
+	SCMGitHub = "github"
+	SCMGitLab = "gitlab"
+)
+
+// approval gates
+const (
+	// GatePullRequest - pull request is opened once the update is approved
+	GatePullRequest = "pr"
+	// GateMerge - pull request is opened straight away, approval gates the merge
+	GateMerge = "merge"
+)
+
+// DefaultRefresh - how often repository files are read to find tracked images
+const DefaultRefresh = 5 * time.Minute
+
+// Config - GitOps provider configuration, usually mounted from a ConfigMap
+type Config struct {
+	Repositories []*RepositoryConfig `json:"repositories"`
+}
+
+// RepositoryConfig - Git repository with manifests that keel updates
+type RepositoryConfig struct {
+	// Name - used in notifications and approvals, defaults to repository
+	Name string `json:"name"`
+	// SCM - github or gitlab
+	SCM string `json:"scm"`
+	// Repository - GitHub owner/repo or GitLab project path
+	Repository string `json:"repository"`
+	// URL - API URL for GitHub Enterprise or self-hosted GitLab
+	URL string `json:"url"`
+	// Token - API token, TokenEnv is preferred so tokens can be kept in secrets
+	Token    string `json:"token"`
+	TokenEnv string `json:"tokenEnv"`
+	// Branch - base branch of pull requests, defaults to master
+	Branch string `json:"branch"`
+
+	Files []*FileConfig `json:"files"`
+
+	Policy   string `json:"policy"`
+	MatchTag bool   `json:"matchTag"`
+	// Trigger - poll (default) or default when images are only updated
+	// by webhooks
+	Trigger      string `json:"trigger"`
+	PollSchedule string `json:"pollSchedule"`
+
+	Approvals        int    `json:"approvals"`
+	ApprovalDeadline int    `json:"approvalDeadline"`
+	ApprovalsGate    string `json:"approvalsGate"`
+	// AutoMerge - merge pull requests once they are opened (and approved
+	// when approvals gate merging)
+	AutoMerge bool `json:"autoMerge"`
+
+	NotificationChannels []string `json:"notify"`
+
+	plc policy.Policy
+}
+
+// FileConfig - file in the repository that references images
+type FileConfig struct {
+	Path string `json:"path"`
+	// Type - manifest (image: fields), kustomization (images: transformer) or
+	// helm (repository/tag values)
+	Type string `json:"type"`
+}
+
+// ParseConfig - parses YAML (or JSON) configuration
+func ParseConfig(data []byte) (*Config, error) {
+	var cfg Config
+	err := yaml.Unmarshal(data, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode gitops config: %s", err)
+	}
+
+	for _, r := range cfg.Repositories {
+		if r.Repository == "" {
+			return nil, fmt.Errorf("gitops repository is required")
+		}
+		if r.Name == "" {
+			r.Name = r.Repository
+		}
+		switch r.SCM {
+		case SCMGitHub, SCMGitLab:
+		case "":
+			r.SCM = SCMGitHub
+		default:
+			return nil, fmt.Errorf("repository %s: unsupported scm %s", r.Name, r.SCM)
+		}
+		if r.Branch == "" {
+			r.Branch = "master"
+		}
+		switch r.ApprovalsGate {
+		case GatePullRequest, GateMerge:
+		case "":
+			r.ApprovalsGate = GatePullRequest
+		default:
+			return nil, fmt.Errorf("repository %s: unsupported approvals gate %s", r.Name, r.ApprovalsGate)
+		}
+		if r.ApprovalDeadline == 0 {
+			r.ApprovalDeadline = types.DefaultApprovalDeadline()
+		}
+		if r.Trigger == "" {
+			r.Trigger = types.TriggerTypePoll.String()
+		}
+		if r.PollSchedule == "" {
+			r.PollSchedule = types.DefaultPollSchedule()
+		}
+		if r.TokenEnv != "" {
+			r.Token = os.Getenv(r.TokenEnv)
+		}
+
+		r.plc = policy.GetPolicy(r.Policy, &policy.Options{MatchTag: r.MatchTag})
+		if r.plc == nil || r.plc.Type() == policy.PolicyTypeNone {
+			return nil, fmt.Errorf("repository %s: invalid policy %q", r.Name, r.Policy)
+		}
+
+		if len(r.Files) == 0 {
+			return nil, fmt.Errorf("repository %s: no files configured", r.Name)
+		}
+		for _, f := range r.Files {
+			if f.Path == "" {
+				return nil, fmt.Errorf("repository %s: file path is required", r.Name)
+			}
+			if f.Type == "" {
+				f.Type = FileTypeManifest
+			}
+			if _, ok := imageFinders[f.Type]; !ok {
+				return nil, fmt.Errorf("repository %s: unsupported file type %s", r.Name, f.Type)
+			}
+		}
+	}
+
+	return &cfg, nil
+}
+
+// LoadConfig - loads configuration from a file
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
@@ -0,0 +1,239 @@
+package gitops
+
+import (
+	"regexp"
+	"strings"
+)
+
+// supported file types
+const (
+	// FileTypeManifest - Kubernetes manifests, image: fields are updated
+	FileTypeManifest = "manifest"
+	// FileTypeKustomization - kustomization.yaml, newTag of images: entries is updated
+	FileTypeKustomization = "kustomization"
+	// FileTypeHelm - Helm values, tag next to repository is updated
+	FileTypeHelm = "helm"
+)
+
+// FileImage - image reference found in a file
+type FileImage struct {
+	// Name - image name as it's written in the file (without tag)
+	Name string
+	Tag  string
+	// line - line that holds the tag
+	line int
+	// nameWithTag - tag is written together with name (image: nginx:1.0.0)
+	nameWithTag bool
+}
+
+type imageFinder func(lines []string) []*FileImage
+
+var imageFinders = map[string]imageFinder{
+	FileTypeManifest:      manifestImages,
+	FileTypeKustomization: kustomizationImages,
+	FileTypeHelm:          helmImages,
+}
+
+// FindImages - returns images referenced in the file
+func FindImages(fileType string, content []byte) []*FileImage {
+	find, ok := imageFinders[fileType]
+	if !ok {
+		return nil
+	}
+	return find(strings.Split(string(content), "\n"))
+}
+
+// UpdateImage - sets new tag for all references of the image name, files are
+// edited line by line so formatting and comments are preserved
+func UpdateImage(fileType string, content []byte, name, tag string) ([]byte, bool) {
+	lines := strings.Split(string(content), "\n")
+	find, ok := imageFinders[fileType]
+	if !ok {
+		return content, false
+	}
+
+	updated := false
+	for _, img := range find(lines) {
+		if img.Name != name || img.Tag == tag {
+			continue
+		}
+		value := tag
+		if img.nameWithTag {
+			value = img.Name + ":" + tag
+		}
+		lines[img.line] = setLineValue(lines[img.line], value)
+		updated = true
+	}
+	if !updated {
+		return content, false
+	}
+	return []byte(strings.Join(lines, "\n")), true
+}
+
+// yamlLine - "key: value" line, list items ("- key: value") start a new mapping
+type yamlLine struct {
+	column int
+	item   bool
+	key    string
+	value  string
+}
+
+var (
+	keyLineRegexp   = regexp.MustCompile(`^(\s*)(-\s+)?([A-Za-z0-9_.-]+):(?:\s+(.*))?$`)
+	lineValueRegexp = regexp.MustCompile(`^(\s*(?:-\s+)?[A-Za-z0-9_.-]+:\s+)(["']?)([^"'#]*?)(["']?)(\s+#.*)?$`)
+)
+
+func parseLine(s string) (yamlLine, bool) {
+	m := keyLineRegexp.FindStringSubmatch(strings.TrimRight(s, " \t\r"))
+	if m == nil {
+		return yamlLine{}, false
+	}
+	value := m[4]
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	value = strings.Trim(strings.TrimSpace(value), `"'`)
+	return yamlLine{
+		column: len(m[1]) + len(m[2]),
+		item:   m[2] != "",
+		key:    m[3],
+		value:  value,
+	}, true
+}
+
+func setLineValue(line, value string) string {
+	m := lineValueRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return line
+	}
+	return m[1] + m[2] + value + m[4] + m[5]
+}
+
+// indentation - column of the first non-space character, -1 for blank lines and comments
+func indentation(s string) int {
+	trimmed := strings.TrimLeft(s, " ")
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.TrimSpace(trimmed) == "" {
+		return -1
+	}
+	return len(s) - len(trimmed)
+}
+
+// mappings - groups keys of YAML mappings, map[key]line
+func mappings(lines []string) []map[string]int {
+	var all []map[string]int
+	open := make(map[int]map[string]int)
+
+	for i, l := range lines {
+		indent := indentation(l)
+		if indent < 0 {
+			continue
+		}
+		if l[indent] == '-' && strings.HasPrefix(l[indent:], "---") {
+			// new document
+			open = make(map[int]map[string]int)
+			continue
+		}
+
+		parsed, ok := parseLine(l)
+		column := indent
+		if ok {
+			column = parsed.column
+		}
+		// mappings that are more indented have ended
+		for c := range open {
+			if c > column || (c == column && (!ok || parsed.item)) {
+				delete(open, c)
+			}
+		}
+		if !ok {
+			continue
+		}
+		m, exists := open[column]
+		if !exists {
+			m = make(map[string]int)
+			open[column] = m
+			all = append(all, m)
+		}
+		m[parsed.key] = i
+	}
+	return all
+}
+
+func lineValue(lines []string, i int) string {
+	parsed, _ := parseLine(lines[i])
+	return parsed.value
+}
+
+// splitImage - splits image into name and tag, digests are not supported
+func splitImage(s string) (name, tag string, ok bool) {
+	if s == "" || strings.Contains(s, "@") || strings.Contains(s, "{{") {
+		return "", "", false
+	}
+	i := strings.LastIndex(s, ":")
+	if i > strings.LastIndex(s, "/") {
+		return s[:i], s[i+1:], true
+	}
+	return s, "latest", true
+}
+
+func manifestImages(lines []string) []*FileImage {
+	var images []*FileImage
+	for i, l := range lines {
+		parsed, ok := parseLine(l)
+		if !ok || parsed.key != "image" {
+			continue
+		}
+		name, tag, ok := splitImage(parsed.value)
+		if !ok {
+			continue
+		}
+		images = append(images, &FileImage{Name: name, Tag: tag, line: i, nameWithTag: true})
+	}
+	return images
+}
+
+func kustomizationImages(lines []string) []*FileImage {
+	var images []*FileImage
+	for _, m := range mappings(lines) {
+		nameLine, ok := m["name"]
+		tagLine, hasTag := m["newTag"]
+		if !ok || !hasTag {
+			continue
+		}
+		if _, ok := m["digest"]; ok {
+			continue
+		}
+		if parsed, _ := parseLine(lines[nameLine]); !parsed.item {
+			continue
+		}
+		name := lineValue(lines, nameLine)
+		if newName, ok := m["newName"]; ok {
+			name = lineValue(lines, newName)
+		}
+		images = append(images, &FileImage{Name: name, Tag: lineValue(lines, tagLine), line: tagLine})
+	}
+	return images
+}
+
+func helmImages(lines []string) []*FileImage {
+	var images []*FileImage
+	for _, m := range mappings(lines) {
+		repoLine, ok := m["repository"]
+		tagLine, hasTag := m["tag"]
+		if !ok || !hasTag {
+			continue
+		}
+		name := lineValue(lines, repoLine)
+		tag := lineValue(lines, tagLine)
+		if name == "" || tag == "" {
+			continue
+		}
+		if registryLine, ok := m["registry"]; ok {
+			if registry := lineValue(lines, registryLine); registry != "" {
+				name = registry + "/" + name
+			}
+		}
+		images = append(images, &FileImage{Name: name, Tag: tag, line: tagLine})
+	}
+	return images
+}
@@ -0,0 +1,185 @@
+package gitops
+
+import (
+	"testing"
+)
+
+const testManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: karolisr/webhook-demo:0.0.14 # keel updates this
+      - name: sidecar
+        image: "envoyproxy/envoy:v1.10.0"
+      initContainers:
+      - image: busybox
+---
+apiVersion: batch/v1
+kind: Job
+spec:
+  template:
+    spec:
+      containers:
+        - image: karolisr/webhook-demo:0.0.14
+`
+
+func TestManifestImages(t *testing.T) {
+	images := FindImages(FileTypeManifest, []byte(testManifest))
+	if len(images) != 4 {
+		t.Fatalf("expected 4 images, got: %d", len(images))
+	}
+	if images[0].Name != "karolisr/webhook-demo" || images[0].Tag != "0.0.14" {
+		t.Errorf("unexpected image: %+v", images[0])
+	}
+	if images[1].Name != "envoyproxy/envoy" || images[1].Tag != "v1.10.0" {
+		t.Errorf("unexpected image: %+v", images[1])
+	}
+	if images[2].Name != "busybox" || images[2].Tag != "latest" {
+		t.Errorf("unexpected image: %+v", images[2])
+	}
+}
+
+func TestUpdateManifestImage(t *testing.T) {
+	updated, ok := UpdateImage(FileTypeManifest, []byte(testManifest), "karolisr/webhook-demo", "0.0.15")
+	if !ok {
+		t.Fatalf("expected manifest to be updated")
+	}
+
+	expected := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: karolisr/webhook-demo:0.0.15 # keel updates this
+      - name: sidecar
+        image: "envoyproxy/envoy:v1.10.0"
+      initContainers:
+      - image: busybox
+---
+apiVersion: batch/v1
+kind: Job
+spec:
+  template:
+    spec:
+      containers:
+        - image: karolisr/webhook-demo:0.0.15
+`
+	if string(updated) != expected {
+		t.Errorf("unexpected manifest:\n%s", updated)
+	}
+
+	updated, ok = UpdateImage(FileTypeManifest, []byte(testManifest), "envoyproxy/envoy", "v1.11.0")
+	if !ok {
+		t.Fatalf("expected manifest to be updated")
+	}
+	if images := FindImages(FileTypeManifest, updated); images[1].Tag != "v1.11.0" {
+		t.Errorf("unexpected tag: %s", images[1].Tag)
+	}
+
+	if _, ok := UpdateImage(FileTypeManifest, []byte(testManifest), "nginx", "1.0.0"); ok {
+		t.Errorf("didn't expect manifest without the image to be updated")
+	}
+}
+
+const testKustomization = `resources:
+- deployment.yaml
+images:
+- name: karolisr/webhook-demo
+  newTag: 0.0.14
+- name: nginx
+  newName: registry.example.com/mirror/nginx
+  newTag: "1.15.0"
+- name: redis
+  digest: sha256:24a0c4b4a4c0eb97a1aabb8e29f18e917d05abfe1b7a7c07857230879ce7d3d3
+`
+
+func TestKustomizationImages(t *testing.T) {
+	images := FindImages(FileTypeKustomization, []byte(testKustomization))
+	if len(images) != 2 {
+		t.Fatalf("expected 2 images, got: %d", len(images))
+	}
+	if images[0].Name != "karolisr/webhook-demo" || images[0].Tag != "0.0.14" {
+		t.Errorf("unexpected image: %+v", images[0])
+	}
+	if images[1].Name != "registry.example.com/mirror/nginx" || images[1].Tag != "1.15.0" {
+		t.Errorf("unexpected image: %+v", images[1])
+	}
+
+	updated, ok := UpdateImage(FileTypeKustomization, []byte(testKustomization), "registry.example.com/mirror/nginx", "1.16.0")
+	if !ok {
+		t.Fatalf("expected kustomization to be updated")
+	}
+	expected := `resources:
+- deployment.yaml
+images:
+- name: karolisr/webhook-demo
+  newTag: 0.0.14
+- name: nginx
+  newName: registry.example.com/mirror/nginx
+  newTag: "1.16.0"
+- name: redis
+  digest: sha256:24a0c4b4a4c0eb97a1aabb8e29f18e917d05abfe1b7a7c07857230879ce7d3d3
+`
+	if string(updated) != expected {
+		t.Errorf("unexpected kustomization:\n%s", updated)
+	}
+}
+
+const testValues = `replicaCount: 1
+image:
+  registry: quay.io
+  repository: karolisr/webhook-demo
+  # current version
+  tag: 0.0.14
+  pullPolicy: IfNotPresent
+sidecar:
+  image:
+    repository: envoyproxy/envoy
+    pullPolicy: IfNotPresent
+    tag: 'v1.10.0'
+  resources: {}
+`
+
+func TestHelmImages(t *testing.T) {
+	images := FindImages(FileTypeHelm, []byte(testValues))
+	if len(images) != 2 {
+		t.Fatalf("expected 2 images, got: %d", len(images))
+	}
+	if images[0].Name != "quay.io/karolisr/webhook-demo" || images[0].Tag != "0.0.14" {
+		t.Errorf("unexpected image: %+v", images[0])
+	}
+	if images[1].Name != "envoyproxy/envoy" || images[1].Tag != "v1.10.0" {
+		t.Errorf("unexpected image: %+v", images[1])
+	}
+
+	updated, ok := UpdateImage(FileTypeHelm, []byte(testValues), "envoyproxy/envoy", "v1.11.0")
+	if !ok {
+		t.Fatalf("expected values to be updated")
+	}
+	expected := `replicaCount: 1
+image:
+  registry: quay.io
+  repository: karolisr/webhook-demo
+  # current version
+  tag: 0.0.14
+  pullPolicy: IfNotPresent
+sidecar:
+  image:
+    repository: envoyproxy/envoy
+    pullPolicy: IfNotPresent
+    tag: 'v1.11.0'
+  resources: {}
+`
+	if string(updated) != expected {
+		t.Errorf("unexpected values:\n%s", updated)
+	}
+}
@@ -0,0 +1,192 @@
+package gitops
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultGitHubURL - GitHub API URL, GitHub Enterprise uses https://<host>/api/v3
+const DefaultGitHubURL = "https://api.github.com"
+
+// GitHub - GitHub client, uses contents and git data APIs so no local clone is needed
+type GitHub struct {
+	api        *apiClient
+	repository string
+}
+
+// NewGitHub - creates GitHub client for owner/repo repository
+func NewGitHub(baseURL, repository, token string) *GitHub {
+	if baseURL == "" {
+		baseURL = DefaultGitHubURL
+	}
+	return &GitHub{
+		repository: repository,
+		api: newAPIClient(strings.TrimSuffix(baseURL, "/"), func(req *http.Request) {
+			req.Header.Set("Accept", "application/vnd.github.v3+json")
+			if token != "" {
+				req.Header.Set("Authorization", "token "+token)
+			}
+		}),
+	}
+}
+
+type githubContent struct {
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+type githubObject struct {
+	SHA    string `json:"sha"`
+	Object struct {
+		SHA string `json:"sha"`
+	} `json:"object"`
+	Tree struct {
+		SHA string `json:"sha"`
+	} `json:"tree"`
+}
+
+type githubPull struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+}
+
+func (g *GitHub) repoPath(format string, args ...interface{}) string {
+	return "/repos/" + g.repository + fmt.Sprintf(format, args...)
+}
+
+// GetFile - returns file contents at ref
+func (g *GitHub) GetFile(path, ref string) ([]byte, error) {
+	var content githubContent
+	err := g.api.do("GET", g.repoPath("/contents/%s?ref=%s", strings.TrimPrefix(path, "/"), url.QueryEscape(ref)), nil, &content)
+	if err != nil {
+		if statusCode(err) == http.StatusNotFound {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+	if content.Encoding != "base64" {
+		return nil, fmt.Errorf("unsupported content encoding %q of %s", content.Encoding, path)
+	}
+	return base64.StdEncoding.DecodeString(strings.Replace(content.Content, "\n", "", -1))
+}
+
+// commit - creates commit with files on top of the branch, commit SHA is returned
+func (g *GitHub) commit(branch, message string, files map[string][]byte) (string, error) {
+	var base githubObject
+	err := g.api.do("GET", g.repoPath("/git/ref/heads/%s", branch), nil, &base)
+	if err != nil {
+		return "", fmt.Errorf("failed to get branch %s: %s", branch, err)
+	}
+
+	var baseCommit githubObject
+	err = g.api.do("GET", g.repoPath("/git/commits/%s", base.Object.SHA), nil, &baseCommit)
+	if err != nil {
+		return "", fmt.Errorf("failed to get base commit: %s", err)
+	}
+
+	entries := make([]map[string]string, 0, len(files))
+	for path, content := range files {
+		entries = append(entries, map[string]string{
+			"path":    strings.TrimPrefix(path, "/"),
+			"mode":    "100644",
+			"type":    "blob",
+			"content": string(content),
+		})
+	}
+
+	var tree githubObject
+	err = g.api.do("POST", g.repoPath("/git/trees"), map[string]interface{}{
+		"base_tree": baseCommit.Tree.SHA,
+		"tree":      entries,
+	}, &tree)
+	if err != nil {
+		return "", fmt.Errorf("failed to create tree: %s", err)
+	}
+
+	var commit githubObject
+	err = g.api.do("POST", g.repoPath("/git/commits"), map[string]interface{}{
+		"message": message,
+		"tree":    tree.SHA,
+		"parents": []string{base.Object.SHA},
+	}, &commit)
+	if err != nil {
+		return "", fmt.Errorf("failed to create commit: %s", err)
+	}
+	return commit.SHA, nil
+}
+
+// Commit - commits files to the branch, fails if the branch was updated meanwhile
+func (g *GitHub) Commit(branch, message string, files map[string][]byte) error {
+	sha, err := g.commit(branch, message, files)
+	if err != nil {
+		return err
+	}
+	err = g.api.do("PATCH", g.repoPath("/git/refs/heads/%s", branch), map[string]interface{}{
+		"sha":   sha,
+		"force": false,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update branch %s: %s", branch, err)
+	}
+	return nil
+}
+
+// CreateChange - commits all files in a single commit on top of base and opens pull request
+func (g *GitHub) CreateChange(change *Change) (*ChangeRequest, error) {
+	sha, err := g.commit(change.Base, change.Message, change.Files)
+	if err != nil {
+		return nil, err
+	}
+
+	err = g.api.do("POST", g.repoPath("/git/refs"), map[string]string{
+		"ref": "refs/heads/" + change.Branch,
+		"sha": sha,
+	}, nil)
+	if statusCode(err) == http.StatusUnprocessableEntity {
+		// branch exists, previous change is replaced
+		err = g.api.do("PATCH", g.repoPath("/git/refs/heads/%s", change.Branch), map[string]interface{}{
+			"sha":   sha,
+			"force": true,
+		}, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update branch %s: %s", change.Branch, err)
+	}
+
+	var pull githubPull
+	err = g.api.do("POST", g.repoPath("/pulls"), map[string]string{
+		"title": change.Title,
+		"head":  change.Branch,
+		"base":  change.Base,
+		"body":  change.Body,
+	}, &pull)
+	if statusCode(err) == http.StatusUnprocessableEntity {
+		return g.openPull(change.Branch)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pull request: %s", err)
+	}
+
+	return &ChangeRequest{ID: pull.Number, URL: pull.HTMLURL}, nil
+}
+
+func (g *GitHub) openPull(branch string) (*ChangeRequest, error) {
+	owner := strings.SplitN(g.repository, "/", 2)[0]
+	var pulls []githubPull
+	err := g.api.do("GET", g.repoPath("/pulls?state=open&head=%s", url.QueryEscape(owner+":"+branch)), nil, &pulls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull requests: %s", err)
+	}
+	if len(pulls) == 0 {
+		return nil, fmt.Errorf("failed to open pull request for branch %s", branch)
+	}
+	return &ChangeRequest{ID: pulls[0].Number, URL: pulls[0].HTMLURL}, nil
+}
+
+// Merge - merges pull request
+func (g *GitHub) Merge(cr *ChangeRequest) error {
+	return g.api.do("PUT", g.repoPath("/pulls/%d/merge", cr.ID), map[string]string{}, nil)
+}
@@ -0,0 +1,110 @@
+package gitops
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitHubGetFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			t.Errorf("unexpected authorization header: %s", r.Header.Get("Authorization"))
+		}
+		switch r.URL.Path {
+		case "/repos/org/manifests/contents/apps/web/deployment.yaml":
+			if r.URL.Query().Get("ref") != "main" {
+				t.Errorf("unexpected ref: %s", r.URL.Query().Get("ref"))
+			}
+			json.NewEncoder(w).Encode(githubContent{
+				Content:  base64.StdEncoding.EncodeToString([]byte("image: nginx:1.15.0\n")),
+				Encoding: "base64",
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	gh := NewGitHub(srv.URL, "org/manifests", "secret")
+
+	content, err := gh.GetFile("apps/web/deployment.yaml", "main")
+	if err != nil {
+		t.Fatalf("failed to get file: %s", err)
+	}
+	if string(content) != "image: nginx:1.15.0\n" {
+		t.Errorf("unexpected content: %s", content)
+	}
+
+	if _, err := gh.GetFile("missing.yaml", "main"); err != ErrFileNotFound {
+		t.Errorf("expected ErrFileNotFound, got: %v", err)
+	}
+}
+
+func TestGitHubCreateChange(t *testing.T) {
+	var requests []string
+	var branchUpdate map[string]interface{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/org/manifests/git/ref/heads/main":
+			w.Write([]byte(`{"object": {"sha": "base-sha"}}`))
+		case "GET /repos/org/manifests/git/commits/base-sha":
+			w.Write([]byte(`{"sha": "base-sha", "tree": {"sha": "base-tree"}}`))
+		case "POST /repos/org/manifests/git/trees":
+			var req map[string]interface{}
+			json.Unmarshal(body, &req)
+			if req["base_tree"] != "base-tree" {
+				t.Errorf("unexpected base tree: %v", req["base_tree"])
+			}
+			w.Write([]byte(`{"sha": "new-tree"}`))
+		case "POST /repos/org/manifests/git/commits":
+			w.Write([]byte(`{"sha": "new-commit"}`))
+		case "POST /repos/org/manifests/git/refs":
+			// branch exists from previous update
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		case "PATCH /repos/org/manifests/git/refs/heads/keel/nginx-1.16.0":
+			json.Unmarshal(body, &branchUpdate)
+			w.Write([]byte(`{}`))
+		case "POST /repos/org/manifests/pulls":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		case "GET /repos/org/manifests/pulls":
+			if r.URL.Query().Get("head") != "org:keel/nginx-1.16.0" {
+				t.Errorf("unexpected head: %s", r.URL.Query().Get("head"))
+			}
+			w.Write([]byte(`[{"number": 12, "html_url": "https://github.com/org/manifests/pull/12"}]`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	gh := NewGitHub(srv.URL, "org/manifests", "secret")
+	cr, err := gh.CreateChange(&Change{
+		Base:    "main",
+		Branch:  "keel/nginx-1.16.0",
+		Title:   "Update nginx to 1.16.0",
+		Message: "Update nginx to 1.16.0",
+		Files:   map[string][]byte{"deployment.yaml": []byte("image: nginx:1.16.0\n")},
+	})
+	if err != nil {
+		t.Fatalf("failed to create change: %s", err)
+	}
+
+	if cr.ID != 12 || cr.URL != "https://github.com/org/manifests/pull/12" {
+		t.Errorf("unexpected change request: %+v", cr)
+	}
+	if branchUpdate["sha"] != "new-commit" || branchUpdate["force"] != true {
+		t.Errorf("unexpected branch update: %v", branchUpdate)
+	}
+	if len(requests) != 8 {
+		t.Errorf("unexpected requests: %v", requests)
+	}
+}
@@ -0,0 +1,137 @@
+package gitops
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultGitLabURL - GitLab API URL, self-hosted instances use https://<host>/api/v4
+const DefaultGitLabURL = "https://gitlab.com/api/v4"
+
+// GitLab - GitLab client, uses repository files and commits APIs
+type GitLab struct {
+	api     *apiClient
+	project string
+}
+
+// NewGitLab - creates GitLab client for group/project
+func NewGitLab(baseURL, project, token string) *GitLab {
+	if baseURL == "" {
+		baseURL = DefaultGitLabURL
+	}
+	return &GitLab{
+		project: url.PathEscape(project),
+		api: newAPIClient(strings.TrimSuffix(baseURL, "/"), func(req *http.Request) {
+			if token != "" {
+				req.Header.Set("PRIVATE-TOKEN", token)
+			}
+		}),
+	}
+}
+
+type gitlabFile struct {
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+type gitlabMergeRequest struct {
+	IID    int    `json:"iid"`
+	WebURL string `json:"web_url"`
+}
+
+func (g *GitLab) projectPath(format string, args ...interface{}) string {
+	return "/projects/" + g.project + fmt.Sprintf(format, args...)
+}
+
+// GetFile - returns file contents at ref
+func (g *GitLab) GetFile(path, ref string) ([]byte, error) {
+	var file gitlabFile
+	err := g.api.do("GET", g.projectPath("/repository/files/%s?ref=%s", url.PathEscape(strings.TrimPrefix(path, "/")), url.QueryEscape(ref)), nil, &file)
+	if err != nil {
+		if statusCode(err) == http.StatusNotFound {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+	if file.Encoding != "base64" {
+		return nil, fmt.Errorf("unsupported content encoding %q of %s", file.Encoding, path)
+	}
+	return base64.StdEncoding.DecodeString(file.Content)
+}
+
+func updateActions(files map[string][]byte) []map[string]string {
+	actions := make([]map[string]string, 0, len(files))
+	for path, content := range files {
+		actions = append(actions, map[string]string{
+			"action":    "update",
+			"file_path": strings.TrimPrefix(path, "/"),
+			"content":   string(content),
+		})
+	}
+	return actions
+}
+
+// Commit - commits files to the branch
+func (g *GitLab) Commit(branch, message string, files map[string][]byte) error {
+	err := g.api.do("POST", g.projectPath("/repository/commits"), map[string]interface{}{
+		"branch":         branch,
+		"commit_message": message,
+		"actions":        updateActions(files),
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to commit to branch %s: %s", branch, err)
+	}
+	return nil
+}
+
+// CreateChange - commits all files in a single commit on top of base and opens merge request
+func (g *GitLab) CreateChange(change *Change) (*ChangeRequest, error) {
+	// force - branch is recreated from base when it already exists
+	err := g.api.do("POST", g.projectPath("/repository/commits"), map[string]interface{}{
+		"branch":         change.Branch,
+		"start_branch":   change.Base,
+		"commit_message": change.Message,
+		"actions":        updateActions(change.Files),
+		"force":          true,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit to branch %s: %s", change.Branch, err)
+	}
+
+	var mr gitlabMergeRequest
+	err = g.api.do("POST", g.projectPath("/merge_requests"), map[string]interface{}{
+		"source_branch":        change.Branch,
+		"target_branch":        change.Base,
+		"title":                change.Title,
+		"description":          change.Body,
+		"remove_source_branch": true,
+	}, &mr)
+	if statusCode(err) == http.StatusConflict {
+		return g.openMergeRequest(change.Branch)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open merge request: %s", err)
+	}
+
+	return &ChangeRequest{ID: mr.IID, URL: mr.WebURL}, nil
+}
+
+func (g *GitLab) openMergeRequest(branch string) (*ChangeRequest, error) {
+	var mrs []gitlabMergeRequest
+	err := g.api.do("GET", g.projectPath("/merge_requests?state=opened&source_branch=%s", url.QueryEscape(branch)), nil, &mrs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list merge requests: %s", err)
+	}
+	if len(mrs) == 0 {
+		return nil, fmt.Errorf("failed to open merge request for branch %s", branch)
+	}
+	return &ChangeRequest{ID: mrs[0].IID, URL: mrs[0].WebURL}, nil
+}
+
+// Merge - merges merge request
+func (g *GitLab) Merge(cr *ChangeRequest) error {
+	return g.api.do("PUT", g.projectPath("/merge_requests/%d/merge", cr.ID), nil, nil)
+}
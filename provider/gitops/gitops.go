@@ -0,0 +1,373 @@
+// Package gitops - provider that updates image tags in Git repositories and opens
+// pull requests instead of patching live workloads, changes are applied to the
+// cluster by GitOps tooling (Flux, Argo CD) once merged
+package gitops
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ProviderName - provider name
+const ProviderName = "gitops"
+
+var gitopsChangesCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gitops_pull_requests_total",
+		Help: "How many pull requests were opened, partitioned by repository.",
+	},
+	[]string{"repository"},
+)
+
+func init() {
+	prometheus.MustRegister(gitopsChangesCounter)
+}
+
+type repository struct {
+	cfg *RepositoryConfig
+	scm SCM
+}
+
+type cachedFile struct {
+	content []byte
+	fetched time.Time
+}
+
+// UpdatePlan - files of the repository that are updated to the new image version
+type UpdatePlan struct {
+	Repository *RepositoryConfig
+	// Image - image name as it's written in the files
+	Image          string
+	CurrentVersion string
+	NewVersion     string
+	// Files - updated files, map[path]content
+	Files map[string][]byte
+}
+
+// Provider - GitOps provider
+type Provider struct {
+	repositories    []*repository
+	sender          notification.Sender
+	approvalManager approvals.Manager
+	refresh         time.Duration
+
+	mu sync.Mutex
+	// files - cached repository files used to list tracked images,
+	// map[repository name/path]file
+	files map[string]*cachedFile
+
+	events chan *types.Event
+	stop   chan struct{}
+}
+
+// NewProvider - creates GitOps provider for configured repositories
+func NewProvider(cfg *Config, sender notification.Sender, approvalManager approvals.Manager) (*Provider, error) {
+	p := &Provider{
+		sender:          sender,
+		approvalManager: approvalManager,
+		refresh:         DefaultRefresh,
+		files:           make(map[string]*cachedFile),
+		events:          make(chan *types.Event, 100),
+		stop:            make(chan struct{}),
+	}
+	for _, r := range cfg.Repositories {
+		scm, err := newSCM(r)
+		if err != nil {
+			return nil, err
+		}
+		p.repositories = append(p.repositories, &repository{cfg: r, scm: scm})
+	}
+	return p, nil
+}
+
+// GetName - get provider name
+func (p *Provider) GetName() string {
+	return ProviderName
+}
+
+// Submit - submit event to provider
+func (p *Provider) Submit(event types.Event) error {
+	p.events <- &event
+	return nil
+}
+
+// Start - starts GitOps provider, waits for events
+func (p *Provider) Start() error {
+	for {
+		select {
+		case event := <-p.events:
+			err := p.processEvent(event)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"image": event.Repository.Name,
+					"tag":   event.Repository.Tag,
+				}).Error("provider.gitops: failed to process event")
+			}
+		case <-p.stop:
+			log.Info("provider.gitops: got shutdown signal, stopping...")
+			return nil
+		}
+	}
+}
+
+// Stop - stops GitOps provider
+func (p *Provider) Stop() {
+	close(p.stop)
+}
+
+// file - returns file from the base branch, cached files are used unless fresh
+// content is required
+func (p *Provider) file(r *repository, path string, fresh bool) ([]byte, error) {
+	key := r.cfg.Name + "/" + path
+
+	p.mu.Lock()
+	cached, ok := p.files[key]
+	p.mu.Unlock()
+	if ok && !fresh && time.Since(cached.fetched) < p.refresh {
+		return cached.content, nil
+	}
+
+	content, err := r.scm.GetFile(path, r.cfg.Branch)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.files[key] = &cachedFile{content: content, fetched: time.Now()}
+	p.mu.Unlock()
+
+	return content, nil
+}
+
+// TrackedImages - returns images referenced in configured repository files
+func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
+	var trackedImages []*types.TrackedImage
+
+	for _, r := range p.repositories {
+		for _, f := range r.cfg.Files {
+			content, err := p.file(r, f.Path, false)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":      err,
+					"repository": r.cfg.Name,
+					"path":       f.Path,
+				}).Error("provider.gitops: failed to get file")
+				continue
+			}
+
+			for _, img := range FindImages(f.Type, content) {
+				ref, err := image.Parse(img.Name + ":" + img.Tag)
+				if err != nil {
+					log.WithFields(log.Fields{
+						"error":      err,
+						"repository": r.cfg.Name,
+						"path":       f.Path,
+						"image":      img.Name,
+					}).Debug("provider.gitops: failed to parse image")
+					continue
+				}
+				trackedImages = append(trackedImages, &types.TrackedImage{
+					Image:        ref,
+					Trigger:      types.ParseTrigger(r.cfg.Trigger),
+					PollSchedule: r.cfg.PollSchedule,
+					Provider:     ProviderName,
+					Policy:       r.cfg.plc,
+					Meta: map[string]string{
+						"repository": r.cfg.Name,
+						"path":       f.Path,
+					},
+				})
+			}
+		}
+	}
+
+	return trackedImages, nil
+}
+
+func (p *Provider) processEvent(event *types.Event) error {
+	// targeted and chart events are only supported by cluster providers
+	if event.Target != "" || event.Chart {
+		return nil
+	}
+
+	for _, r := range p.repositories {
+		plan, err := p.createUpdatePlan(r, event)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":      err,
+				"repository": r.cfg.Name,
+				"image":      event.Repository.Name,
+			}).Error("provider.gitops: failed to create update plan")
+			continue
+		}
+		if plan == nil {
+			continue
+		}
+
+		err = p.apply(r, event, plan)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":      err,
+				"repository": r.cfg.Name,
+				"image":      plan.Image,
+				"update":     fmt.Sprintf("%s->%s", plan.CurrentVersion, plan.NewVersion),
+			}).Error("provider.gitops: failed to apply update")
+		}
+	}
+	return nil
+}
+
+// createUpdatePlan - updates files that reference the event image, nil plan is
+// returned when nothing has to be changed
+func (p *Provider) createUpdatePlan(r *repository, event *types.Event) (*UpdatePlan, error) {
+	var plan *UpdatePlan
+
+	for _, f := range r.cfg.Files {
+		// files are read from the base branch to not overwrite recent changes
+		content, err := p.file(r, f.Path, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s: %s", f.Path, err)
+		}
+
+		for _, img := range FindImages(f.Type, content) {
+			ref, err := image.Parse(img.Name + ":" + img.Tag)
+			if err != nil || ref.Repository() != event.Repository.Name {
+				continue
+			}
+
+			update, err := r.cfg.plc.ShouldUpdate(img.Tag, event.Repository.Tag)
+			if err != nil || !update {
+				continue
+			}
+
+			updated, ok := UpdateImage(f.Type, content, img.Name, event.Repository.Tag)
+			if !ok {
+				continue
+			}
+			content = updated
+
+			if plan == nil {
+				plan = &UpdatePlan{
+					Repository:     r.cfg,
+					Image:          img.Name,
+					CurrentVersion: img.Tag,
+					NewVersion:     event.Repository.Tag,
+					Files:          make(map[string][]byte),
+				}
+			}
+			plan.Files[f.Path] = content
+		}
+	}
+
+	return plan, nil
+}
+
+var branchInvalidChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func changeBranch(plan *UpdatePlan) string {
+	return "keel/" + branchInvalidChars.ReplaceAllString(plan.Image, "-") + "-" + branchInvalidChars.ReplaceAllString(plan.NewVersion, "-")
+}
+
+func newChange(plan *UpdatePlan) *Change {
+	paths := make([]string, 0, len(plan.Files))
+	for path := range plan.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	title := fmt.Sprintf("Update %s to %s", plan.Image, plan.NewVersion)
+	body := fmt.Sprintf("New version of %s is available: %s -> %s (policy %s).\n\nUpdated files:\n- %s\n",
+		plan.Image, plan.CurrentVersion, plan.NewVersion, plan.Repository.plc.Name(), strings.Join(paths, "\n- "))
+
+	return &Change{
+		Base:    plan.Repository.Branch,
+		Branch:  changeBranch(plan),
+		Title:   title,
+		Body:    body,
+		Message: title,
+		Files:   plan.Files,
+	}
+}
+
+// apply - opens pull request (and merges it) once the plan is approved, when
+// approvals gate merging pull request is opened straight away
+func (p *Provider) apply(r *repository, event *types.Event, plan *UpdatePlan) error {
+	approved, err := p.isApproved(event, plan)
+	if err != nil {
+		return err
+	}
+
+	gateMerge := r.cfg.Approvals > 0 && r.cfg.ApprovalsGate == GateMerge
+	if !approved && !gateMerge {
+		return nil
+	}
+
+	cr, err := r.scm.CreateChange(newChange(plan))
+	if err != nil {
+		p.notify(plan, fmt.Sprintf("Failed to open pull request in %s updating %s %s->%s, error: %s", r.cfg.Name, plan.Image, plan.CurrentVersion, plan.NewVersion, err), types.LevelError)
+		return err
+	}
+	gitopsChangesCounter.With(prometheus.Labels{"repository": r.cfg.Name}).Inc()
+
+	if !approved {
+		p.notify(plan, fmt.Sprintf("Opened pull request %s in %s updating %s %s->%s, waiting for approval to merge", cr.URL, r.cfg.Name, plan.Image, plan.CurrentVersion, plan.NewVersion), types.LevelInfo)
+		return nil
+	}
+
+	if r.cfg.Approvals > 0 {
+		err = p.approvalManager.Archive(getApprovalIdentifier(plan))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":      err,
+				"repository": r.cfg.Name,
+				"image":      plan.Image,
+			}).Warn("provider.gitops: got error while archiving approval")
+		}
+	}
+
+	if !r.cfg.AutoMerge && !gateMerge {
+		p.notify(plan, fmt.Sprintf("Opened pull request %s in %s updating %s %s->%s", cr.URL, r.cfg.Name, plan.Image, plan.CurrentVersion, plan.NewVersion), types.LevelSuccess)
+		return nil
+	}
+
+	err = r.scm.Merge(cr)
+	if err != nil {
+		p.notify(plan, fmt.Sprintf("Failed to merge pull request %s in %s updating %s %s->%s, error: %s", cr.URL, r.cfg.Name, plan.Image, plan.CurrentVersion, plan.NewVersion, err), types.LevelError)
+		return err
+	}
+
+	p.notify(plan, fmt.Sprintf("Merged pull request %s in %s updating %s %s->%s", cr.URL, r.cfg.Name, plan.Image, plan.CurrentVersion, plan.NewVersion), types.LevelSuccess)
+	return nil
+}
+
+func (p *Provider) notify(plan *UpdatePlan, message string, level types.Level) {
+	p.sender.Send(types.EventNotification{
+		ResourceKind: "repository",
+		Identifier:   "repository/" + plan.Repository.Name,
+		Name:         "update repository",
+		Message:      message,
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        level,
+		Channels:     plan.Repository.NotificationChannels,
+		Metadata: map[string]string{
+			"provider":   p.GetName(),
+			"repository": plan.Repository.Name,
+			"image":      plan.Image,
+		},
+	})
+}
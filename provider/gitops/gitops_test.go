@@ -0,0 +1,175 @@
+package gitops
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+)
+
+type fakeSender struct {
+	sent []types.EventNotification
+}
+
+func (s *fakeSender) Configure(*notification.Config) (bool, error) { return true, nil }
+
+func (s *fakeSender) Send(event types.EventNotification) error {
+	s.sent = append(s.sent, event)
+	return nil
+}
+
+type fakeSCM struct {
+	files   map[string]string
+	changes []*Change
+	merged  []*ChangeRequest
+}
+
+func (s *fakeSCM) GetFile(path, ref string) ([]byte, error) {
+	content, ok := s.files[path]
+	if !ok {
+		return nil, ErrFileNotFound
+	}
+	return []byte(content), nil
+}
+
+func (s *fakeSCM) CreateChange(change *Change) (*ChangeRequest, error) {
+	s.changes = append(s.changes, change)
+	return &ChangeRequest{ID: len(s.changes), URL: "https://github.com/org/manifests/pull/1"}, nil
+}
+
+func (s *fakeSCM) Merge(cr *ChangeRequest) error {
+	s.merged = append(s.merged, cr)
+	return nil
+}
+
+func (s *fakeSCM) Commit(branch, message string, files map[string][]byte) error {
+	for path, content := range files {
+		s.files[path] = string(content)
+	}
+	return nil
+}
+
+const testConfig = `
+repositories:
+- repository: org/manifests
+  branch: main
+  policy: minor
+  autoMerge: true
+  files:
+  - path: apps/web/deployment.yaml
+  - path: apps/web/kustomization.yaml
+    type: kustomization
+`
+
+func newTestProvider(t *testing.T, config string) (*Provider, *fakeSCM, *fakeSender) {
+	cfg, err := ParseConfig([]byte(config))
+	if err != nil {
+		t.Fatalf("failed to parse config: %s", err)
+	}
+	sender := &fakeSender{}
+	p, err := NewProvider(cfg, sender, nil)
+	if err != nil {
+		t.Fatalf("failed to create provider: %s", err)
+	}
+	scm := &fakeSCM{files: map[string]string{
+		"apps/web/deployment.yaml":    testManifest,
+		"apps/web/kustomization.yaml": testKustomization,
+	}}
+	p.repositories[0].scm = scm
+	return p, scm, sender
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("failed to parse config: %s", err)
+	}
+	r := cfg.Repositories[0]
+	if r.Name != "org/manifests" || r.SCM != SCMGitHub || r.ApprovalsGate != GatePullRequest {
+		t.Errorf("unexpected defaults: %+v", r)
+	}
+	if r.Files[0].Type != FileTypeManifest {
+		t.Errorf("unexpected file type: %s", r.Files[0].Type)
+	}
+
+	for _, invalid := range []string{
+		"repositories:\n- repository: org/manifests\n  files:\n  - path: a.yaml\n",
+		"repositories:\n- repository: org/manifests\n  policy: major\n",
+		"repositories:\n- repository: org/manifests\n  policy: major\n  scm: svn\n  files:\n  - path: a.yaml\n",
+		"repositories:\n- repository: org/manifests\n  policy: major\n  files:\n  - path: a.yaml\n    type: jsonnet\n",
+	} {
+		if _, err := ParseConfig([]byte(invalid)); err == nil {
+			t.Errorf("expected error for config: %s", invalid)
+		}
+	}
+}
+
+func TestTrackedImages(t *testing.T) {
+	p, _, _ := newTestProvider(t, testConfig)
+
+	images, err := p.TrackedImages()
+	if err != nil {
+		t.Fatalf("failed to get tracked images: %s", err)
+	}
+	// 4 images in the manifest, 2 in kustomization
+	if len(images) != 6 {
+		t.Fatalf("expected 6 tracked images, got: %d", len(images))
+	}
+	if images[0].Image.Repository() != "index.docker.io/karolisr/webhook-demo" {
+		t.Errorf("unexpected image: %s", images[0].Image.Repository())
+	}
+	if images[0].Trigger != types.TriggerTypePoll || images[0].Provider != ProviderName {
+		t.Errorf("unexpected tracked image: %s", images[0])
+	}
+}
+
+func TestProcessEventOpensPullRequest(t *testing.T) {
+	p, scm, sender := newTestProvider(t, testConfig)
+
+	err := p.processEvent(&types.Event{
+		Repository: types.Repository{Name: "index.docker.io/karolisr/webhook-demo", Tag: "0.1.0"},
+	})
+	if err != nil {
+		t.Fatalf("failed to process event: %s", err)
+	}
+
+	if len(scm.changes) != 1 {
+		t.Fatalf("expected single change, got: %d", len(scm.changes))
+	}
+	change := scm.changes[0]
+	if change.Base != "main" || change.Branch != "keel/karolisr-webhook-demo-0.1.0" {
+		t.Errorf("unexpected branches: %s <- %s", change.Base, change.Branch)
+	}
+	if len(change.Files) != 2 {
+		t.Fatalf("expected both files to be updated, got: %d", len(change.Files))
+	}
+	if !strings.Contains(string(change.Files["apps/web/deployment.yaml"]), "image: karolisr/webhook-demo:0.1.0 # keel updates this") {
+		t.Errorf("unexpected manifest:\n%s", change.Files["apps/web/deployment.yaml"])
+	}
+	if !strings.Contains(string(change.Files["apps/web/kustomization.yaml"]), "newTag: 0.1.0") {
+		t.Errorf("unexpected kustomization:\n%s", change.Files["apps/web/kustomization.yaml"])
+	}
+
+	if len(scm.merged) != 1 {
+		t.Errorf("expected pull request to be merged")
+	}
+	if len(sender.sent) != 1 || sender.sent[0].Level != types.LevelSuccess {
+		t.Errorf("unexpected notifications: %+v", sender.sent)
+	}
+}
+
+func TestProcessEventPolicy(t *testing.T) {
+	p, scm, _ := newTestProvider(t, testConfig)
+
+	// minor policy doesn't allow major updates
+	err := p.processEvent(&types.Event{
+		Repository: types.Repository{Name: "index.docker.io/karolisr/webhook-demo", Tag: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("failed to process event: %s", err)
+	}
+	if len(scm.changes) != 0 {
+		t.Errorf("didn't expect changes, got: %d", len(scm.changes))
+	}
+}
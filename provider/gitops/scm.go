@@ -0,0 +1,124 @@
+package gitops
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// ErrFileNotFound - file doesn't exist in the repository
+var ErrFileNotFound = fmt.Errorf("file not found")
+
+// SCM - Git hosting service client
+type SCM interface {
+	// GetFile - returns file contents at ref
+	GetFile(path, ref string) ([]byte, error)
+	// CreateChange - commits files to the change branch (created from base or
+	// reset when it exists) and opens pull request, existing open pull request
+	// for the branch is returned instead of opening a new one
+	CreateChange(change *Change) (*ChangeRequest, error)
+	// Merge - merges pull request
+	Merge(cr *ChangeRequest) error
+	// Commit - commits files directly to the branch
+	Commit(branch, message string, files map[string][]byte) error
+}
+
+// Change - files that should be committed and proposed in a pull request
+type Change struct {
+	Base    string
+	Branch  string
+	Title   string
+	Body    string
+	Message string
+	// Files - map[path]content
+	Files map[string][]byte
+}
+
+// ChangeRequest - GitHub pull request or GitLab merge request
+type ChangeRequest struct {
+	ID  int
+	URL string
+}
+
+// apiClient - JSON API client shared by SCM implementations
+type apiClient struct {
+	baseURL string
+	client  *http.Client
+	auth    func(req *http.Request)
+}
+
+func newAPIClient(baseURL string, auth func(req *http.Request)) *apiClient {
+	return &apiClient{
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		auth:    auth,
+	}
+}
+
+// apiError - unexpected API response
+type apiError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Body)
+}
+
+func statusCode(err error) int {
+	if e, ok := err.(*apiError); ok {
+		return e.StatusCode
+	}
+	return 0
+}
+
+func (c *apiClient) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.auth(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &apiError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// newSCM - creates client for the configured hosting service
+func newSCM(cfg *RepositoryConfig) (SCM, error) {
+	switch cfg.SCM {
+	case SCMGitHub:
+		return NewGitHub(cfg.URL, cfg.Repository, cfg.Token), nil
+	case SCMGitLab:
+		return NewGitLab(cfg.URL, cfg.Repository, cfg.Token), nil
+	}
+	return nil, fmt.Errorf("unsupported scm %s", cfg.SCM)
+}
@@ -1,11 +1,12 @@
 package helm
 
 import (
-	"fmt"
+	"strings"
 	"time"
 
 	"github.com/keel-hq/keel/pkg/store"
 	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/i18n"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -15,6 +16,15 @@ func getIdentifier(namespace, name, version string) string {
 	return namespace + "/" + name + ":" + version
 }
 
+// planIdentifier - chart version updates are prefixed so they don't clash with
+// image updates to the same version
+func planIdentifier(plan *UpdatePlan) string {
+	if plan.ChartUpdate {
+		return getIdentifier(plan.Namespace, plan.Name, "chart-"+plan.NewVersion)
+	}
+	return getIdentifier(plan.Namespace, plan.Name, plan.NewVersion)
+}
+
 func (p *Provider) checkForApprovals(event *types.Event, plans []*UpdatePlan) (approvedPlans []*UpdatePlan) {
 	approvedPlans = []*UpdatePlan{}
 	for _, plan := range plans {
@@ -36,7 +46,7 @@ func (p *Provider) checkForApprovals(event *types.Event, plans []*UpdatePlan) (a
 
 // updateComplete is called after we successfully update resource
 func (p *Provider) updateComplete(plan *UpdatePlan) error {
-	return p.approvalManager.Archive(getIdentifier(plan.Namespace, plan.Name, plan.NewVersion))
+	return p.approvalManager.Archive(planIdentifier(plan))
 }
 
 func (p *Provider) isApproved(event *types.Event, plan *UpdatePlan) (bool, error) {
@@ -44,7 +54,7 @@ func (p *Provider) isApproved(event *types.Event, plan *UpdatePlan) (bool, error
 		return true, nil
 	}
 
-	identifier := getIdentifier(plan.Namespace, plan.Name, plan.NewVersion)
+	identifier := planIdentifier(plan)
 
 	// checking for existing approval
 	existing, err := p.approvalManager.Get(identifier)
@@ -59,7 +69,7 @@ func (p *Provider) isApproved(event *types.Event, plan *UpdatePlan) (bool, error
 			}
 
 			if plan.Config.ApprovalDeadline == 0 {
-				plan.Config.ApprovalDeadline = types.KeelApprovalDeadlineDefault
+				plan.Config.ApprovalDeadline = types.DefaultApprovalDeadline()
 			}
 
 			// creating new one
@@ -73,13 +83,26 @@ func (p *Provider) isApproved(event *types.Event, plan *UpdatePlan) (bool, error
 				VotesReceived:  0,
 				Rejected:       false,
 				Deadline:       time.Now().Add(time.Duration(plan.Config.ApprovalDeadline) * time.Hour),
+				Workspace:      plan.Config.ApprovalsWorkspace,
+				Reminders:      plan.Config.ApprovalReminders,
+				Approvers:      strings.Join(plan.Config.Approvers, ","),
+				Private:        plan.Config.ApprovalsPrivate,
 			}
 
-			approval.Message = fmt.Sprintf("New image is available for release %s/%s (%s).",
+			approval.Message = i18n.T("New image is available for release %s/%s (%s).",
 				plan.Namespace,
 				plan.Name,
 				approval.Delta(),
 			)
+			if plan.ChartUpdate {
+				approval.Message = i18n.T("New chart version is available for release %s/%s (%s).",
+					plan.Namespace,
+					plan.Name,
+					approval.Delta(),
+				)
+			}
+
+			approval.Diff = p.planDiff(plan)
 
 			return false, p.approvalManager.Create(approval)
 		}
@@ -0,0 +1,215 @@
+package helm
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/chartrepo"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/helm/pkg/chartutil"
+	hapi_chart "k8s.io/helm/pkg/proto/hapi/chart"
+	hapi_release "k8s.io/helm/pkg/proto/hapi/release"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ChartFetcher - downloads chart archives, used to upgrade releases to new chart versions
+type ChartFetcher interface {
+	// Pull - downloads chart, when digest is set chart has to match it
+	Pull(repository, name, version, digest string) (*chartrepo.Chart, error)
+	// Verify - checks chart provenance
+	Verify(chart *chartrepo.Chart, name, version string) error
+}
+
+// SetChartFetcher - enables chart version updates
+func (p *Provider) SetChartFetcher(charts ChartFetcher) {
+	p.charts = charts
+}
+
+// chartPolicy - chart updates are only done for semver policies, chart versions
+// are always semver
+func chartPolicy(cfg *KeelChartConfig) policy.Policy {
+	plc := cfg.Plc
+	if cfg.Chart.Policy != "" {
+		plc = policy.GetPolicy(cfg.Chart.Policy, &policy.Options{})
+	}
+	if plc == nil || plc.Type() != policy.PolicyTypeSemver {
+		return nil
+	}
+	return plc
+}
+
+// releaseChart - returns chart watch config of the release, nil if chart
+// repository isn't configured
+func releaseChart(release *hapi_release.Release) (*types.TrackedChart, *KeelChartConfig, error) {
+	vals, err := values(release.Chart, release.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg, err := getKeelConfig(vals)
+	if err != nil {
+		if err == ErrPolicyNotSpecified {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	if cfg.Chart == nil || cfg.Chart.Repository == "" {
+		return nil, nil, nil
+	}
+
+	plc := chartPolicy(cfg)
+	if plc == nil {
+		return nil, nil, fmt.Errorf("chart updates require semver policy")
+	}
+
+	name := cfg.Chart.Name
+	if name == "" {
+		name = release.Chart.Metadata.Name
+	}
+
+	return &types.TrackedChart{
+		Repository: strings.TrimSuffix(cfg.Chart.Repository, "/"),
+		Name:       name,
+		Version:    release.Chart.Metadata.Version,
+		Namespace:  release.Namespace,
+		Release:    release.Name,
+		Policy:     plc,
+	}, cfg, nil
+}
+
+// TrackedCharts - returns charts of releases that have chart repository configured
+func (p *Provider) TrackedCharts() ([]*types.TrackedChart, error) {
+	var trackedCharts []*types.TrackedChart
+
+	releaseList, err := p.implementer.ListReleases()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, release := range releaseList.GetReleases() {
+		tracked, _, err := releaseChart(release)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"release":   release.Name,
+				"namespace": release.Namespace,
+			}).Error("provider.helm: failed to get chart configuration for release")
+			continue
+		}
+		if tracked == nil || p.isPaused(release.Namespace, release.Name) {
+			continue
+		}
+		trackedCharts = append(trackedCharts, tracked)
+	}
+
+	return trackedCharts, nil
+}
+
+// createChartUpdatePlans - plans upgrades of releases that use the chart from the event
+func (p *Provider) createChartUpdatePlans(event *types.Event) ([]*UpdatePlan, error) {
+	var plans []*UpdatePlan
+
+	releaseList, err := p.implementer.ListReleases()
+	if err != nil {
+		return nil, err
+	}
+
+	// chart is downloaded once, several releases can use it
+	var newChart *hapi_chart.Chart
+
+	for _, release := range releaseList.GetReleases() {
+		tracked, cfg, err := releaseChart(release)
+		if err != nil || tracked == nil || tracked.Reference() != event.Repository.Name {
+			continue
+		}
+
+		if p.isPaused(release.Namespace, release.Name) {
+			log.WithFields(log.Fields{
+				"name":      release.Name,
+				"namespace": release.Namespace,
+			}).Debug("provider.helm: release is paused, skipping")
+			continue
+		}
+
+		update, err := tracked.Policy.ShouldUpdate(tracked.Version, event.Repository.Tag)
+		if err != nil || !update {
+			log.WithFields(log.Fields{
+				"name":        release.Name,
+				"namespace":   release.Namespace,
+				"current":     tracked.Version,
+				"new_version": event.Repository.Tag,
+				"policy":      tracked.Policy.Name(),
+			}).Debug("provider.helm: chart version doesn't match policy, ignoring")
+			continue
+		}
+
+		if newChart == nil {
+			newChart, err = p.downloadChart(tracked, event.Repository.Tag, event.Repository.Digest, cfg.Chart.Verify)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		helmVersionedUpdatesCounter.With(prometheus.Labels{"chart": fmt.Sprintf("%s/%s", release.Namespace, release.Name)}).Inc()
+
+		plans = append(plans, &UpdatePlan{
+			Namespace:      release.Namespace,
+			Name:           release.Name,
+			Config:         cfg,
+			Chart:          newChart,
+			Values:         make(map[string]string),
+			CurrentVersion: tracked.Version,
+			NewVersion:     event.Repository.Tag,
+			ChartUpdate:    true,
+			manifest:       release.Manifest,
+		})
+	}
+
+	return plans, nil
+}
+
+// downloadChart - chart is pulled by digest resolved by the trigger (if any) so
+// the release is upgraded to exactly the chart that was found
+func (p *Provider) downloadChart(tracked *types.TrackedChart, version, digest string, verify bool) (*hapi_chart.Chart, error) {
+	if p.charts == nil {
+		return nil, fmt.Errorf("chart updates are not enabled")
+	}
+
+	pulled, err := p.charts.Pull(tracked.Repository, tracked.Name, version, digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download chart %s-%s: %s", tracked.Reference(), version, err)
+	}
+
+	if verify {
+		err = p.charts.Verify(pulled, tracked.Name, version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify chart %s-%s: %s", tracked.Reference(), version, err)
+		}
+	}
+
+	chart, err := chartutil.LoadArchive(bytes.NewReader(pulled.Archive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart %s-%s: %s", tracked.Reference(), version, err)
+	}
+
+	if chart.Metadata == nil || chart.Metadata.Version != version {
+		return nil, fmt.Errorf("downloaded chart %s doesn't match version %s", tracked.Reference(), version)
+	}
+
+	return chart, nil
+}
+
+// planChanges - description of the update used in notifications and history
+func planChanges(plan *UpdatePlan) string {
+	if plan.ChartUpdate {
+		return "chart " + plan.Chart.Metadata.Name + "-" + plan.NewVersion
+	}
+	return strings.Join(mapToSlice(plan.Values), ", ")
+}
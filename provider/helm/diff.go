@@ -0,0 +1,221 @@
+package helm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/helm/pkg/helm"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// diffContext - unchanged lines shown around changed manifest lines
+	diffContext = 2
+	// maxDiffLines - manifest documents longer than this aren't compared line by
+	// line, whole documents are shown as removed and added
+	maxDiffLines = 2000
+
+	manifestSourcePrefix = "# Source: "
+)
+
+// planDiff - values and manifest changes the upgrade would apply, rendered with a
+// dry-run upgrade. Shown to reviewers in approval requests.
+func (p *Provider) planDiff(plan *UpdatePlan) string {
+	var b strings.Builder
+
+	if len(plan.Values) > 0 {
+		b.WriteString("Values:\n")
+		paths := make([]string, 0, len(plan.Values))
+		for path := range plan.Values {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			fmt.Fprintf(&b, "  %s: %s -> %s\n", path, plan.previous[path], plan.Values[path])
+		}
+	}
+
+	overrideBts, err := planOverrides(plan)
+	if err != nil {
+		return b.String()
+	}
+
+	resp, err := p.implementer.UpdateReleaseFromChart(plan.Name, plan.Chart,
+		helm.UpdateValueOverrides(overrideBts),
+		helm.UpgradeDryRun(true),
+		helm.ReuseValues(true))
+	if err != nil || resp.Release == nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      plan.Name,
+			"namespace": plan.Namespace,
+		}).Warn("provider.helm: failed to render release for the approval diff")
+		fmt.Fprintf(&b, "Manifest diff not available: %v\n", err)
+		return b.String()
+	}
+
+	manifest := manifestDiff(plan.manifest, resp.Release.Manifest)
+	if manifest != "" {
+		b.WriteString("Manifest:\n")
+		b.WriteString(manifest)
+	}
+	return b.String()
+}
+
+// manifestDocuments - rendered manifest split into documents keyed by template
+// source, documents without a source comment are numbered
+func manifestDocuments(manifest string) (map[string]string, []string) {
+	docs := make(map[string]string)
+	var order []string
+	for i, doc := range strings.Split(manifest, "\n---") {
+		doc = strings.Trim(doc, "\n")
+		if doc == "" || doc == "---" {
+			continue
+		}
+		doc = strings.TrimPrefix(doc, "---\n")
+		source := fmt.Sprintf("document %d", i)
+		if first := strings.SplitN(doc, "\n", 2)[0]; strings.HasPrefix(first, manifestSourcePrefix) {
+			source = strings.TrimPrefix(first, manifestSourcePrefix)
+		}
+		if _, ok := docs[source]; ok {
+			source = fmt.Sprintf("%s (%d)", source, i)
+		}
+		docs[source] = doc
+		order = append(order, source)
+	}
+	return docs, order
+}
+
+// manifestDiff - line diff of the changed manifest documents
+func manifestDiff(current, updated string) string {
+	currentDocs, currentOrder := manifestDocuments(current)
+	updatedDocs, updatedOrder := manifestDocuments(updated)
+
+	var b strings.Builder
+	for _, source := range updatedOrder {
+		if currentDocs[source] == updatedDocs[source] {
+			continue
+		}
+		fmt.Fprintf(&b, "@@ %s @@\n", source)
+		b.WriteString(lineDiff(currentDocs[source], updatedDocs[source]))
+	}
+	for _, source := range currentOrder {
+		if _, ok := updatedDocs[source]; ok {
+			continue
+		}
+		fmt.Fprintf(&b, "@@ %s (removed) @@\n", source)
+		b.WriteString(lineDiff(currentDocs[source], ""))
+	}
+	return b.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// lineDiff - changed lines prefixed with -/+ and diffContext unchanged lines
+// around them
+func lineDiff(a, b string) string {
+	from, to := splitLines(a), splitLines(b)
+
+	// common prefix and suffix are trimmed so that only the changed middle is compared
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix && from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+
+	type line struct {
+		op   byte
+		text string
+	}
+	var lines []line
+	for _, l := range from[:prefix] {
+		lines = append(lines, line{' ', l})
+	}
+
+	fromMid, toMid := from[prefix:len(from)-suffix], to[prefix:len(to)-suffix]
+	if len(fromMid) > maxDiffLines || len(toMid) > maxDiffLines {
+		for _, l := range fromMid {
+			lines = append(lines, line{'-', l})
+		}
+		for _, l := range toMid {
+			lines = append(lines, line{'+', l})
+		}
+	} else {
+		// longest common subsequence of the changed lines
+		lcs := make([][]int, len(fromMid)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(toMid)+1)
+		}
+		for i := len(fromMid) - 1; i >= 0; i-- {
+			for j := len(toMid) - 1; j >= 0; j-- {
+				if fromMid[i] == toMid[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else if lcs[i+1][j] >= lcs[i][j+1] {
+					lcs[i][j] = lcs[i+1][j]
+				} else {
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(fromMid) || j < len(toMid) {
+			switch {
+			case i < len(fromMid) && j < len(toMid) && fromMid[i] == toMid[j]:
+				lines = append(lines, line{' ', fromMid[i]})
+				i++
+				j++
+			case i < len(fromMid) && (j == len(toMid) || lcs[i+1][j] >= lcs[i][j+1]):
+				lines = append(lines, line{'-', fromMid[i]})
+				i++
+			default:
+				lines = append(lines, line{'+', toMid[j]})
+				j++
+			}
+		}
+	}
+
+	for _, l := range from[len(from)-suffix:] {
+		lines = append(lines, line{' ', l})
+	}
+
+	// only changed lines and their context are shown
+	show := make([]bool, len(lines))
+	for idx, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for k := idx - diffContext; k <= idx+diffContext; k++ {
+			if k >= 0 && k < len(lines) {
+				show[k] = true
+			}
+		}
+	}
+
+	var out strings.Builder
+	skipped := false
+	for idx, l := range lines {
+		if !show[idx] {
+			skipped = true
+			continue
+		}
+		if skipped && out.Len() > 0 {
+			out.WriteString("  ...\n")
+		}
+		skipped = false
+		out.WriteByte(l.op)
+		out.WriteByte(' ')
+		out.WriteString(l.text)
+		out.WriteByte('\n')
+	}
+	return out.String()
+}
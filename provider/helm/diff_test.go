@@ -0,0 +1,50 @@
+package helm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestManifestDiff(t *testing.T) {
+	current := `
+---
+# Source: app/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          image: "karolisr/app:1.0.0"
+          ports:
+            - containerPort: 8080
+`
+	updated := strings.Replace(current, "karolisr/app:1.0.0", "karolisr/app:1.1.0", 1)
+
+	diff := manifestDiff(current, updated)
+	for _, line := range []string{
+		"@@ app/templates/deployment.yaml @@\n        containers:\n",
+		"-           image: \"karolisr/app:1.0.0\"\n+           image: \"karolisr/app:1.1.0\"\n",
+		"              - containerPort: 8080\n",
+	} {
+		if !strings.Contains(diff, line) {
+			t.Errorf("expected diff to contain %q, got:\n%s", line, diff)
+		}
+	}
+	if strings.Contains(diff, "service.yaml") || strings.Contains(diff, "kind: Deployment") {
+		t.Errorf("unchanged lines shouldn't be shown, got:\n%s", diff)
+	}
+
+	if manifestDiff(current, current) != "" {
+		t.Errorf("expected no diff for unchanged manifest")
+	}
+}
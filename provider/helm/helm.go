@@ -3,11 +3,16 @@ package helm
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/keel-hq/keel/approvals"
 	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/internal/ratelimit"
+	"github.com/keel-hq/keel/internal/tracing"
+	"github.com/keel-hq/keel/pkg/store"
 	"github.com/keel-hq/keel/types"
 	"github.com/keel-hq/keel/util/image"
 
@@ -82,6 +87,34 @@ type UpdatePlan struct {
 
 	// ReleaseNotes is a slice of combined release notes.
 	ReleaseNotes []string
+
+	// ChartUpdate - release is upgraded to a new chart version (Chart), existing
+	// values are reused
+	ChartUpdate bool
+
+	// Lists - current values of the top level keys that have lists updated through
+	// index paths (app.containers[0].image), otherwise the rest of the list items
+	// would be dropped by the override
+	Lists map[string]interface{}
+
+	// Trigger - name of the trigger which found the new version, ie: poll or pubsub
+	Trigger string
+	// digest - digest of the new version reported by the trigger
+	digest string
+	// startedAt - time of the new version event, used to find out update duration
+	startedAt time.Time
+	// traceParent - trace context of the event processing
+	traceParent string
+	// revision - release revision created by the upgrade
+	revision int32
+	// attempts and retryBackoff - failed attempts of the upgrade and backoff before
+	// the next one, upgrades are retried on transient errors
+	attempts     int
+	retryBackoff time.Duration
+	// manifest - rendered manifest of the current release, used for approval diffs
+	manifest string
+	// previous - current values of the updated paths
+	previous map[string]string
 }
 
 // keel:
@@ -94,6 +127,31 @@ type UpdatePlan struct {
 //   images:
 //     - repository: image.repository
 //       tag: image.tag
+//       # optional, overrides pollSchedule for this image
+//       pollSchedule: "@every 1h"
+//   # optional, images of charts that don't follow repository/tag convention,
+//   # "<path>:tag" when value holds image with tag or "<repository path>:<tag path>",
+//   # releases are rendered with a dry-run before the upgrade
+//   imagePaths:
+//     - app.containers[0].image:tag
+//   # optional, chart repository (or oci:// registry) that is watched for new chart versions
+//   chart:
+//     repository: https://charts.example.com
+//     # optional, defaults to the release chart name
+//     name: mychart
+//     # optional semver policy, defaults to keel policy
+//     policy: minor
+//     # optional, upgrade only to charts with valid provenance file
+//     verify: true
+//   # optional, runs helm test after the upgrade, release is rolled back when tests fail
+//   test:
+//     enabled: true
+//     # optional, test timeout in seconds, defaults to 300
+//     timeout: 600
+//     # optional, deletes test pods once tests finish
+//     cleanup: true
+//     # optional, release isn't rolled back when tests fail
+//     keepOnFailure: false
 
 // Root - root element of the values yaml
 type Root struct {
@@ -106,10 +164,17 @@ type KeelChartConfig struct {
 	MatchTag             bool              `json:"matchTag"`
 	Trigger              types.TriggerType `json:"trigger"`
 	PollSchedule         string            `json:"pollSchedule"`
-	Approvals            int               `json:"approvals"`        // Minimum required approvals
-	ApprovalDeadline     int               `json:"approvalDeadline"` // Deadline in hours
+	Approvals            int               `json:"approvals"`          // Minimum required approvals
+	ApprovalDeadline     int               `json:"approvalDeadline"`   // Deadline in hours
+	ApprovalsWorkspace   string            `json:"approvalsWorkspace"` // optional chat workspace for approvals
+	ApprovalReminders    string            `json:"approvalReminders"`  // optional reminder intervals of pending approvals
+	Approvers            []string          `json:"approvers"`          // optional chat users that get approval requests as direct messages
+	ApprovalsPrivate     bool              `json:"approvalsPrivate"`   // only send approval requests to the approvers
 	Images               []ImageDetails    `json:"images"`
+	ImagePaths           []string          `json:"imagePaths"`
 	NotificationChannels []string          `json:"notificationChannels"` // optional notification channels
+	Chart                *ChartDetails     `json:"chart"`                // optional chart repository to watch
+	Test                 *TestDetails      `json:"test"`                 // optional helm test run after upgrades
 
 	Plc policy.Policy `json:"-"`
 }
@@ -121,6 +186,17 @@ type ImageDetails struct {
 	DigestPath      string `json:"digest"`
 	ReleaseNotes    string `json:"releaseNotes"`
 	ImagePullSecret string `json:"imagePullSecret"`
+	// PollSchedule - overrides chart poll schedule for this image
+	PollSchedule string `json:"pollSchedule"`
+}
+
+// ChartDetails - chart repository that is watched for new chart versions
+type ChartDetails struct {
+	Repository string `json:"repository"`
+	Name       string `json:"name"`
+	Policy     string `json:"policy"`
+	// Verify - chart has to have valid provenance file
+	Verify bool `json:"verify"`
 }
 
 // Provider - helm provider, responsible for managing release updates
@@ -131,15 +207,31 @@ type Provider struct {
 
 	approvalManager approvals.Manager
 
+	// store is optional, used to check paused releases
+	store store.Store
+
+	// charts is optional, used to download new chart versions
+	charts ChartFetcher
+
+	// maintenanceReported - versions reported in maintenance mode, map[identifier]<maintenance ID>/<version>
+	maintenanceReported sync.Map
+
+	// rateLimiter is optional, updates over the update budgets are queued
+	rateLimiter *ratelimit.Limiter
+
+	// updateAttempts - attempts of upgrades failing with transient errors, see SetUpdateAttempts
+	updateAttempts int
+
 	events chan *types.Event
 	stop   chan struct{}
 }
 
 // NewProvider - create new Helm provider
-func NewProvider(implementer Implementer, sender notification.Sender, approvalManager approvals.Manager) *Provider {
+func NewProvider(implementer Implementer, sender notification.Sender, approvalManager approvals.Manager, store store.Store) *Provider {
 	return &Provider{
 		implementer:     implementer,
 		approvalManager: approvalManager,
+		store:           store,
 		sender:          sender,
 		events:          make(chan *types.Event, 100),
 		stop:            make(chan struct{}),
@@ -201,7 +293,7 @@ func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 		}
 
 		if cfg.PollSchedule == "" {
-			cfg.PollSchedule = types.KeelPollDefaultSchedule
+			cfg.PollSchedule = types.DefaultPollSchedule()
 		}
 		// used to check pod secrets
 		selector := fmt.Sprintf("app=%s,release=%s", release.Chart.Metadata.Name, release.Name)
@@ -221,8 +313,10 @@ func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 				"selector":      selector,
 				"helm.sh/chart": fmt.Sprintf("%s-%s", release.Chart.Metadata.Name, release.Chart.Metadata.Version),
 			}
+			img.Meta["name"] = release.Name
 			img.Namespace = release.Namespace
 			img.Provider = ProviderName
+			img.Paused = p.isPaused(release.Namespace, release.Name)
 			trackedImages = append(trackedImages, img)
 		}
 
@@ -251,14 +345,54 @@ func (p *Provider) startInternal() error {
 }
 
 func (p *Provider) processEvent(event *types.Event) (err error) {
-	plans, err := p.createUpdatePlans(event)
+	// targeted events are only supported by kubernetes provider
+	if event.Target != "" {
+		return nil
+	}
+
+	span := tracing.Start(event.TraceParent, "provider.helm.processEvent")
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	policySpan := tracing.Start(span.TraceParent(), "policy.evaluate")
+	var plans []*UpdatePlan
+	if event.Chart {
+		plans, err = p.createChartUpdatePlans(event)
+	} else {
+		plans, err = p.createUpdatePlans(event)
+	}
+	policySpan.SetAttribute("plans", strconv.Itoa(len(plans)))
+	policySpan.SetError(err)
+	policySpan.End()
 	if err != nil {
 		return err
 	}
 
+	startedAt := event.CreatedAt
+	if startedAt.IsZero() {
+		startedAt = time.Now()
+	}
+	for _, plan := range plans {
+		plan.Trigger = event.TriggerName
+		plan.digest = event.Repository.Digest
+		plan.startedAt = startedAt
+		plan.traceParent = span.TraceParent()
+	}
+
+	if paused := store.Maintenance(p.store); paused != nil {
+		p.maintenanceUpdates(plans, paused)
+		return nil
+	}
+
+	approvalsSpan := tracing.Start(span.TraceParent(), "approvals.check")
 	approved := p.checkForApprovals(event, plans)
+	approvalsSpan.SetAttribute("plans", strconv.Itoa(len(plans)))
+	approvalsSpan.SetAttribute("approved", strconv.Itoa(len(approved)))
+	approvalsSpan.End()
 
-	return p.applyPlans(approved)
+	return p.applyPlans(p.checkRateLimit(approved))
 }
 
 func (p *Provider) createUpdatePlans(event *types.Event) ([]*UpdatePlan, error) {
@@ -273,6 +407,14 @@ func (p *Provider) createUpdatePlans(event *types.Event) ([]*UpdatePlan, error)
 
 	for _, release := range releases {
 
+		if p.isPaused(release.Namespace, release.Name) {
+			log.WithFields(log.Fields{
+				"name":      release.Name,
+				"namespace": release.Namespace,
+			}).Debug("provider.helm: release is paused, skipping")
+			continue
+		}
+
 		// plan, update, err := checkRelease(newVersion, &event.Repository, release.Namespace, release.Name, release.Chart, release.Config)
 		plan, update, err := checkRelease(&event.Repository, release.Namespace, release.Name, release.Chart, release.Config)
 		if err != nil {
@@ -285,6 +427,7 @@ func (p *Provider) createUpdatePlans(event *types.Event) ([]*UpdatePlan, error)
 		}
 		if update {
 			helmVersionedUpdatesCounter.With(prometheus.Labels{"chart": fmt.Sprintf("%s/%s", release.Namespace, release.Name)}).Inc()
+			plan.manifest = release.Manifest
 			plans = append(plans, plan)
 		}
 	}
@@ -292,6 +435,15 @@ func (p *Provider) createUpdatePlans(event *types.Event) ([]*UpdatePlan, error)
 	return plans, nil
 }
 
+// isPaused - checks whether automatic updates were paused for the release
+func (p *Provider) isPaused(namespace, name string) bool {
+	if p.store == nil {
+		return false
+	}
+	_, err := p.store.GetPausedResource(types.PausedIdentifier(namespace, name))
+	return err == nil
+}
+
 func (p *Provider) applyPlans(plans []*UpdatePlan) error {
 	for _, plan := range plans {
 
@@ -299,7 +451,7 @@ func (p *Provider) applyPlans(plans []*UpdatePlan) error {
 			ResourceKind: "chart",
 			Identifier:   fmt.Sprintf("%s/%s/%s", "chart", plan.Namespace, plan.Name),
 			Name:         "update release",
-			Message:      fmt.Sprintf("Preparing to update release %s/%s %s->%s (%s)", plan.Namespace, plan.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(mapToSlice(plan.Values), ", ")),
+			Message:      fmt.Sprintf("Preparing to update release %s/%s %s->%s (%s)", plan.Namespace, plan.Name, plan.CurrentVersion, plan.NewVersion, planChanges(plan)),
 			CreatedAt:    time.Now(),
 			Type:         types.NotificationPreReleaseUpdate,
 			Level:        types.LevelDebug,
@@ -311,7 +463,13 @@ func (p *Provider) applyPlans(plans []*UpdatePlan) error {
 			},
 		})
 
-		err := updateHelmRelease(p.implementer, plan.Name, plan.Chart, plan.Values)
+		upgradeSpan := tracing.Start(plan.traceParent, "helm.upgrade")
+		upgradeSpan.SetAttribute("namespace", plan.Namespace)
+		upgradeSpan.SetAttribute("name", plan.Name)
+		upgradeSpan.SetAttribute("version", plan.NewVersion)
+		err := p.upgrade(plan)
+		upgradeSpan.SetError(err)
+		upgradeSpan.End()
 		if err != nil {
 			log.WithFields(log.Fields{
 				"error":     err,
@@ -319,11 +477,51 @@ func (p *Provider) applyPlans(plans []*UpdatePlan) error {
 				"namespace": plan.Namespace,
 			}).Error("provider.helm: failed to apply plan")
 
+			// failure is reported once transient errors run out of attempts
+			if p.retryUpgrade(plan, err) {
+				continue
+			}
+
+			p.recordUpdate(plan, err)
+
+			msg := fmt.Sprintf("Release update failed %s/%s %s->%s (%s), error: %s", plan.Namespace, plan.Name, plan.CurrentVersion, plan.NewVersion, planChanges(plan), err)
+			if plan.attempts > 1 {
+				msg += fmt.Sprintf(" (%d attempts)", plan.attempts)
+			}
+
+			p.sender.Send(types.EventNotification{
+				ResourceKind: "chart",
+				Identifier:   fmt.Sprintf("%s/%s/%s", "chart", plan.Namespace, plan.Name),
+				Name:         "update release",
+				Message:      msg,
+				CreatedAt:    time.Now(),
+				Type:         types.NotificationReleaseUpdate,
+				Level:        types.LevelError,
+				Channels:     plan.Config.NotificationChannels,
+				Metadata: map[string]string{
+					"provider":  p.GetName(),
+					"namespace": plan.Namespace,
+					"name":      plan.Name,
+				},
+			})
+			continue
+		}
+
+		testResult, err := p.testRelease(plan)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"name":      plan.Name,
+				"namespace": plan.Namespace,
+			}).Error("provider.helm: release tests failed")
+
+			p.recordUpdate(plan, err)
+
 			p.sender.Send(types.EventNotification{
 				ResourceKind: "chart",
 				Identifier:   fmt.Sprintf("%s/%s/%s", "chart", plan.Namespace, plan.Name),
 				Name:         "update release",
-				Message:      fmt.Sprintf("Release update failed %s/%s %s->%s (%s), error: %s", plan.Namespace, plan.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(mapToSlice(plan.Values), ", "), err),
+				Message:      fmt.Sprintf("Release tests failed %s/%s %s->%s (%s), error: %s", plan.Namespace, plan.Name, plan.CurrentVersion, plan.NewVersion, planChanges(plan), err),
 				CreatedAt:    time.Now(),
 				Type:         types.NotificationReleaseUpdate,
 				Level:        types.LevelError,
@@ -337,6 +535,8 @@ func (p *Provider) applyPlans(plans []*UpdatePlan) error {
 			continue
 		}
 
+		p.recordUpdate(plan, nil)
+
 		err = p.updateComplete(plan)
 		if err != nil {
 			log.WithFields(log.Fields{
@@ -348,9 +548,12 @@ func (p *Provider) applyPlans(plans []*UpdatePlan) error {
 
 		var msg string
 		if len(plan.ReleaseNotes) == 0 {
-			msg = fmt.Sprintf("Successfully updated release %s/%s %s->%s (%s)", plan.Namespace, plan.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(mapToSlice(plan.Values), ", "))
+			msg = fmt.Sprintf("Successfully updated release %s/%s %s->%s (%s)", plan.Namespace, plan.Name, plan.CurrentVersion, plan.NewVersion, planChanges(plan))
 		} else {
-			msg = fmt.Sprintf("Successfully updated release %s/%s %s->%s (%s). Release notes: %s", plan.Namespace, plan.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(mapToSlice(plan.Values), ", "), strings.Join(plan.ReleaseNotes, ", "))
+			msg = fmt.Sprintf("Successfully updated release %s/%s %s->%s (%s). Release notes: %s", plan.Namespace, plan.Name, plan.CurrentVersion, plan.NewVersion, planChanges(plan), strings.Join(plan.ReleaseNotes, ", "))
+		}
+		if testResult != "" {
+			msg += ". " + testResult
 		}
 
 		p.sender.Send(types.EventNotification{
@@ -374,13 +577,53 @@ func (p *Provider) applyPlans(plans []*UpdatePlan) error {
 	return nil
 }
 
-func updateHelmRelease(implementer Implementer, releaseName string, chart *hapi_chart.Chart, overrideValues map[string]string) error {
+// upgrade - releases updated through image paths are rendered with a dry-run
+// first, upgrade is aborted if the new values are not used by the templates
+func (p *Provider) upgrade(plan *UpdatePlan) error {
+	overrideBts, err := planOverrides(plan)
+	if err != nil {
+		return err
+	}
 
-	overrideBts, err := convertToYaml(mapToSlice(overrideValues))
+	if plan.Config != nil && len(plan.Config.ImagePaths) > 0 && !plan.ChartUpdate {
+		resp, err := p.implementer.UpdateReleaseFromChart(plan.Name, plan.Chart,
+			helm.UpdateValueOverrides(overrideBts),
+			helm.UpgradeDryRun(true),
+			helm.ReuseValues(true))
+		if err != nil {
+			return fmt.Errorf("dry-run failed: %s", err)
+		}
+		for path, value := range plan.Values {
+			if resp.Release != nil && !strings.Contains(resp.Release.Manifest, value) {
+				return fmt.Errorf("dry-run failed: %s=%s is not used by the chart templates", path, value)
+			}
+		}
+	}
+
+	revision, err := updateHelmRelease(p.implementer, plan.Name, plan.Chart, overrideBts)
 	if err != nil {
 		return err
 	}
+	plan.revision = revision
+	return nil
+}
+
+// planOverrides - values override of the plan, lists are copied from current
+// values so that only indexed items change
+func planOverrides(plan *UpdatePlan) ([]byte, error) {
+	base := map[string]interface{}{}
+	for key, value := range plan.Lists {
+		copied, err := copyValue(value)
+		if err != nil {
+			return nil, err
+		}
+		base[key] = copied
+	}
+	return convertToYamlInto(base, mapToSlice(plan.Values))
+}
 
+// updateHelmRelease - upgrades the release, returns revision of the upgraded release
+func updateHelmRelease(implementer Implementer, releaseName string, chart *hapi_chart.Chart, overrideBts []byte) (int32, error) {
 	resp, err := implementer.UpdateReleaseFromChart(releaseName, chart,
 		helm.UpdateValueOverrides(overrideBts),
 		helm.UpgradeDryRun(false),
@@ -393,14 +636,14 @@ func updateHelmRelease(implementer Implementer, releaseName string, chart *hapi_
 		helm.UpgradeWait(true))
 
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	log.WithFields(log.Fields{
 		"version": resp.Release.Version,
 		"release": releaseName,
 	}).Info("provider.helm: release updated")
-	return nil
+	return resp.Release.Version, nil
 }
 
 func mapToSlice(values map[string]string) []string {
@@ -414,7 +657,10 @@ func mapToSlice(values map[string]string) []string {
 
 // parse
 func convertToYaml(values []string) ([]byte, error) {
-	base := map[string]interface{}{}
+	return convertToYamlInto(map[string]interface{}{}, values)
+}
+
+func convertToYamlInto(base map[string]interface{}, values []string) ([]byte, error) {
 	for _, value := range values {
 		if err := strvals.ParseInto(value, base); err != nil {
 			return []byte{}, fmt.Errorf("failed parsing --set data: %s", err)
@@ -425,7 +671,13 @@ func convertToYaml(values []string) ([]byte, error) {
 }
 
 func getValueAsString(vals chartutil.Values, path string) (string, error) {
-	valinterface, err := vals.PathValue(path)
+	var valinterface interface{}
+	var err error
+	if isIndexPath(path) {
+		valinterface, err = pathValue(vals, path)
+	} else {
+		valinterface, err = vals.PathValue(path)
+	}
 	if err != nil {
 		return "", err
 	}
@@ -459,6 +711,14 @@ func getKeelConfig(vals chartutil.Values) (*KeelChartConfig, error) {
 
 	cfg := r.Keel
 
+	for _, imagePath := range cfg.ImagePaths {
+		details, err := parseImagePath(imagePath)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Images = append(cfg.Images, details)
+	}
+
 	cfg.Plc = policy.GetPolicy(cfg.Policy, &policy.Options{MatchTag: cfg.MatchTag})
 
 	return &cfg, nil
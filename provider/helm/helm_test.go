@@ -1,14 +1,16 @@
 package helm
 
 import (
+	"fmt"
 	"reflect"
+	"sync/atomic"
 	"testing"
 
 	"github.com/ghodss/yaml"
 	"github.com/keel-hq/keel/approvals"
-	"github.com/keel-hq/keel/cache/memory"
 	"github.com/keel-hq/keel/extension/notification"
 	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/pkg/store/sql"
 	"github.com/keel-hq/keel/types"
 	"k8s.io/helm/pkg/chartutil"
 	"k8s.io/helm/pkg/helm"
@@ -17,10 +19,16 @@ import (
 	rls "k8s.io/helm/pkg/proto/hapi/services"
 )
 
-func approver() *approvals.DefaultManager {
-	cache := memory.NewMemoryCache()
+var approverDatabases int32
 
-	return approvals.New(cache)
+// approver - approvals manager with its own in-memory sqlite store
+func approver() *approvals.DefaultManager {
+	uri := fmt.Sprintf("file:approver%d?mode=memory&cache=shared", atomic.AddInt32(&approverDatabases, 1))
+	store, err := sql.New(sql.Opts{DatabaseType: sql.DatabaseTypeSQLite, URI: uri})
+	if err != nil {
+		panic(err)
+	}
+	return approvals.New(&approvals.Opts{Store: store})
 }
 
 type fakeSender struct {
@@ -43,6 +51,11 @@ type fakeImplementer struct {
 	updatedRlsName string
 	updatedChart   *chart.Chart
 	updatedOptions []helm.UpdateOption
+
+	// release tests and rollbacks
+	testResponses   []*rls.TestReleaseResponse
+	rolledBack      bool
+	rollbackOptions []helm.RollbackOption
 }
 
 func (i *fakeImplementer) ListReleases(opts ...helm.ReleaseListOption) (*rls.ListReleasesResponse, error) {
@@ -61,6 +74,23 @@ func (i *fakeImplementer) UpdateReleaseFromChart(rlsName string, chart *chart.Ch
 	}, nil
 }
 
+func (i *fakeImplementer) RunReleaseTest(rlsName string, opts ...helm.ReleaseTestOption) (<-chan *rls.TestReleaseResponse, <-chan error) {
+	results := make(chan *rls.TestReleaseResponse, len(i.testResponses))
+	errs := make(chan error, 1)
+	for _, res := range i.testResponses {
+		results <- res
+	}
+	close(results)
+	close(errs)
+	return results, errs
+}
+
+func (i *fakeImplementer) RollbackRelease(rlsName string, opts ...helm.RollbackOption) (*rls.RollbackReleaseResponse, error) {
+	i.rolledBack = true
+	i.rollbackOptions = opts
+	return &rls.RollbackReleaseResponse{}, nil
+}
+
 // helper function to generate keel configuration
 func testingConfigYaml(cfg *KeelChartConfig) (vals chartutil.Values, err error) {
 	root := &Root{Keel: *cfg}
@@ -221,7 +251,7 @@ keel:
 		},
 	}
 
-	prov := NewProvider(fakeImpl, &fakeSender{}, approver())
+	prov := NewProvider(fakeImpl, &fakeSender{}, approver(), nil)
 
 	tracked, _ := prov.TrackedImages()
 
@@ -262,7 +292,7 @@ image2:
 		},
 	}
 
-	prov := NewProvider(fakeImpl, &fakeSender{}, approver())
+	prov := NewProvider(fakeImpl, &fakeSender{}, approver(), nil)
 
 	tracked, _ := prov.TrackedImages()
 
@@ -311,7 +341,7 @@ keel:
 		},
 	}
 
-	prov := NewProvider(fakeImpl, &fakeSender{}, approver())
+	prov := NewProvider(fakeImpl, &fakeSender{}, approver(), nil)
 
 	tracked, _ := prov.TrackedImages()
 
@@ -414,7 +444,7 @@ keel:
 		},
 	}
 
-	provider := NewProvider(fakeImpl, &fakeSender{}, approver())
+	provider := NewProvider(fakeImpl, &fakeSender{}, approver(), nil)
 
 	err := provider.processEvent(&types.Event{
 		Repository: types.Repository{
@@ -658,3 +688,45 @@ keel:
 		t.Errorf("policy not found")
 	}
 }
+
+func TestTestReleaseRollback(t *testing.T) {
+	fakeImpl := &fakeImplementer{
+		testResponses: []*rls.TestReleaseResponse{
+			{Msg: "RUNNING: release-test", Status: hapi_release5.TestRun_RUNNING},
+			{Msg: "FAILED: release-test", Status: hapi_release5.TestRun_FAILURE},
+		},
+	}
+	provider := NewProvider(fakeImpl, &fakeSender{}, approver(), nil)
+
+	plan := &UpdatePlan{
+		Name:     "release",
+		Config:   &KeelChartConfig{Test: &TestDetails{Enabled: true}},
+		revision: 3,
+	}
+
+	_, err := provider.testRelease(plan)
+	if err == nil {
+		t.Fatalf("expected tests to fail")
+	}
+	if !fakeImpl.rolledBack {
+		t.Errorf("expected release to be rolled back")
+	}
+
+	fakeImpl.rolledBack = false
+	plan.Config.Test.KeepOnFailure = true
+	if _, err := provider.testRelease(plan); err == nil {
+		t.Errorf("expected tests to fail")
+	}
+	if fakeImpl.rolledBack {
+		t.Errorf("release shouldn't be rolled back with keepOnFailure")
+	}
+
+	fakeImpl.testResponses = fakeImpl.testResponses[:1]
+	result, err := provider.testRelease(plan)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result != "tests passed" {
+		t.Errorf("unexpected result: %s", result)
+	}
+}
@@ -0,0 +1,53 @@
+package helm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// recordUpdate - saves update history entry, has to be called before approval is archived
+func (p *Provider) recordUpdate(plan *UpdatePlan, updateErr error) {
+	if p.store == nil {
+		return
+	}
+
+	record := &types.UpdateRecord{
+		Provider:     p.GetName(),
+		ResourceKind: "chart",
+		Identifier:   fmt.Sprintf("%s/%s/%s", "chart", plan.Namespace, plan.Name),
+		Namespace:    plan.Namespace,
+		Name:         plan.Name,
+		PreviousTag:  plan.CurrentVersion,
+		NewTag:       plan.NewVersion,
+		NewDigest:    plan.digest,
+		Trigger:      plan.Trigger,
+		Status:       types.UpdateStatusSuccess,
+		Message:      planChanges(plan),
+	}
+	if !plan.startedAt.IsZero() {
+		record.DurationMs = int64(time.Since(plan.startedAt) / time.Millisecond)
+	}
+
+	if updateErr != nil {
+		record.Status = types.UpdateStatusFailed
+		record.Message = updateErr.Error()
+	}
+
+	approval, err := p.approvalManager.Get(planIdentifier(plan))
+	if err == nil {
+		record.Approvers = approval.Voters
+	}
+
+	_, err = p.store.CreateUpdateRecord(record)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      plan.Name,
+			"namespace": plan.Namespace,
+		}).Error("provider.helm: failed to save update history")
+	}
+}
@@ -17,6 +17,8 @@ var (
 type Implementer interface {
 	ListReleases(opts ...helm.ReleaseListOption) (*rls.ListReleasesResponse, error)
 	UpdateReleaseFromChart(rlsName string, chart *chart.Chart, opts ...helm.UpdateOption) (*rls.UpdateReleaseResponse, error)
+	RunReleaseTest(rlsName string, opts ...helm.ReleaseTestOption) (<-chan *rls.TestReleaseResponse, <-chan error)
+	RollbackRelease(rlsName string, opts ...helm.RollbackOption) (*rls.RollbackReleaseResponse, error)
 }
 
 // HelmImplementer - actual helm implementer
@@ -46,3 +48,13 @@ func (i *HelmImplementer) ListReleases(opts ...helm.ReleaseListOption) (*rls.Lis
 func (i *HelmImplementer) UpdateReleaseFromChart(rlsName string, chart *chart.Chart, opts ...helm.UpdateOption) (*rls.UpdateReleaseResponse, error) {
 	return i.client.UpdateReleaseFromChart(rlsName, chart, opts...)
 }
+
+// RunReleaseTest - runs release tests, results are streamed until tests finish
+func (i *HelmImplementer) RunReleaseTest(rlsName string, opts ...helm.ReleaseTestOption) (<-chan *rls.TestReleaseResponse, <-chan error) {
+	return i.client.RunReleaseTest(rlsName, opts...)
+}
+
+// RollbackRelease - rolls back release to a previous revision
+func (i *HelmImplementer) RollbackRelease(rlsName string, opts ...helm.RollbackOption) (*rls.RollbackReleaseResponse, error) {
+	return i.client.RollbackRelease(rlsName, opts...)
+}
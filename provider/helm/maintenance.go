@@ -0,0 +1,48 @@
+package helm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maintenanceUpdates - reports release updates that would have been applied
+// if maintenance mode wasn't enabled, each version is reported once per
+// maintenance window
+func (p *Provider) maintenanceUpdates(plans []*UpdatePlan, paused *types.PausedResource) {
+	for _, plan := range plans {
+		identifier := fmt.Sprintf("%s/%s/%s", "chart", plan.Namespace, plan.Name)
+
+		if reported, ok := p.maintenanceReported.Load(identifier); ok && reported == paused.ID+"/"+plan.NewVersion {
+			continue
+		}
+		p.maintenanceReported.Store(identifier, paused.ID+"/"+plan.NewVersion)
+
+		log.WithFields(log.Fields{
+			"name":      plan.Name,
+			"namespace": plan.Namespace,
+			"previous":  plan.CurrentVersion,
+			"new":       plan.NewVersion,
+		}).Info("provider.helm: maintenance mode, release not updated")
+
+		p.sender.Send(types.EventNotification{
+			ResourceKind: "chart",
+			Identifier:   identifier,
+			Name:         "maintenance mode",
+			Message:      fmt.Sprintf("Maintenance mode (enabled by %s): would have updated release %s/%s %s->%s (%s)", paused.User, plan.Namespace, plan.Name, plan.CurrentVersion, plan.NewVersion, planChanges(plan)),
+			CreatedAt:    time.Now(),
+			Type:         types.NotificationDryRunUpdate,
+			Level:        types.LevelInfo,
+			Channels:     plan.Config.NotificationChannels,
+			Metadata: map[string]string{
+				"provider":    p.GetName(),
+				"namespace":   plan.Namespace,
+				"name":        plan.Name,
+				"maintenance": "true",
+			},
+		})
+	}
+}
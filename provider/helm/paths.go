@@ -0,0 +1,131 @@
+package helm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/helm/pkg/chartutil"
+)
+
+// imagePathTag - image path suffix that marks value holding full image
+// reference (repository:tag)
+const imagePathTag = "tag"
+
+// parseImagePath - parses image path declared in keel.imagePaths, either
+// "app.containers[0].image:tag" when value holds image with tag or
+// "app.image.repository:app.image.tag" for separate repository and tag values
+func parseImagePath(imagePath string) (ImageDetails, error) {
+	parts := strings.SplitN(strings.TrimSpace(imagePath), ":", 2)
+
+	details := ImageDetails{RepositoryPath: parts[0]}
+	if len(parts) == 2 && parts[1] != imagePathTag {
+		details.TagPath = parts[1]
+	}
+
+	for _, path := range []string{details.RepositoryPath, details.TagPath} {
+		if path == "" {
+			continue
+		}
+		if _, err := splitPath(path); err != nil {
+			return details, fmt.Errorf("invalid image path %q: %s", imagePath, err)
+		}
+	}
+	if details.RepositoryPath == "" {
+		return details, fmt.Errorf("invalid image path %q: repository path cannot be empty", imagePath)
+	}
+	return details, nil
+}
+
+// pathElement - key of the values path, optionally followed by list index
+type pathElement struct {
+	key   string
+	index int
+}
+
+// splitPath - splits values path (a.b[0].c), index is -1 for keys without it
+func splitPath(path string) ([]pathElement, error) {
+	var elements []pathElement
+	for _, part := range strings.Split(path, ".") {
+		el := pathElement{key: part, index: -1}
+		if i := strings.Index(part, "["); i >= 0 {
+			if !strings.HasSuffix(part, "]") {
+				return nil, fmt.Errorf("unterminated index in %q", part)
+			}
+			index, err := strconv.Atoi(part[i+1 : len(part)-1])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid index in %q", part)
+			}
+			el.key, el.index = part[:i], index
+		}
+		if el.key == "" {
+			return nil, fmt.Errorf("empty key in path %q", path)
+		}
+		elements = append(elements, el)
+	}
+	return elements, nil
+}
+
+// isIndexPath - whether path goes through list items
+func isIndexPath(path string) bool {
+	return strings.Contains(path, "[")
+}
+
+func asMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case chartutil.Values:
+		return m, true
+	}
+	return nil, false
+}
+
+// pathValue - returns value at path, unlike Values.PathValue lists are supported
+func pathValue(vals chartutil.Values, path string) (interface{}, error) {
+	elements, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var current interface{} = map[string]interface{}(vals)
+	for _, el := range elements {
+		m, ok := asMap(current)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a table", el.key)
+		}
+		current, ok = m[el.key]
+		if !ok {
+			return nil, fmt.Errorf("key not found: %s", el.key)
+		}
+		if el.index < 0 {
+			continue
+		}
+		list, ok := current.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s is not a list", el.key)
+		}
+		if el.index >= len(list) {
+			return nil, fmt.Errorf("index %d of %s is out of range", el.index, el.key)
+		}
+		current = list[el.index]
+	}
+	return current, nil
+}
+
+// copyValue - deep copy of the value made of plain maps and slices
+func copyValue(v interface{}) (interface{}, error) {
+	bts, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var copied interface{}
+	err = json.Unmarshal(bts, &copied)
+	return copied, err
+}
+
+// rootKey - top level key of the values path
+func rootKey(path string) string {
+	return strings.SplitN(strings.SplitN(path, ".", 2)[0], "[", 2)[0]
+}
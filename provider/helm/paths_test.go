@@ -0,0 +1,83 @@
+package helm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+
+	"k8s.io/helm/pkg/chartutil"
+	hapi_chart "k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+var imagePathsValues = `
+keel:
+  policy: minor
+  imagePaths:
+    - app.containers[1].image:tag
+app:
+  containers:
+    - name: proxy
+      image: envoyproxy/envoy:v1.10.0
+    - name: app
+      image: karolisr/webhook-demo:0.0.10
+`
+
+func TestParseImagePath(t *testing.T) {
+	details, err := parseImagePath("app.containers[0].image:tag")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if details.RepositoryPath != "app.containers[0].image" || details.TagPath != "" {
+		t.Errorf("unexpected details: %+v", details)
+	}
+
+	details, err = parseImagePath("app.image.repository:app.image.tag")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if details.RepositoryPath != "app.image.repository" || details.TagPath != "app.image.tag" {
+		t.Errorf("unexpected details: %+v", details)
+	}
+
+	for _, invalid := range []string{"", "app.containers[x].image", "app.containers[0.image", "app..image"} {
+		if _, err := parseImagePath(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestImagePathsPlan(t *testing.T) {
+	chart := &hapi_chart.Chart{
+		Values:   &hapi_chart.Config{Raw: imagePathsValues},
+		Metadata: &hapi_chart.Metadata{Name: "app"},
+	}
+
+	plan, update, err := checkRelease(&types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.11"}, "default", "app", chart, &hapi_chart.Config{Raw: ""})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !update {
+		t.Fatalf("expected release to be updated")
+	}
+	if plan.Values["app.containers[1].image"] != "karolisr/webhook-demo:0.0.11" {
+		t.Errorf("unexpected values: %v", plan.Values)
+	}
+
+	overrides, err := planOverrides(plan)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	vals, err := chartutil.ReadValues(overrides)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// other containers are kept as is
+	proxy, err := getValueAsString(vals, "app.containers[0].image")
+	if err != nil || proxy != "envoyproxy/envoy:v1.10.0" {
+		t.Errorf("unexpected proxy image %q: %v\n%s", proxy, err, overrides)
+	}
+	if !strings.Contains(string(overrides), "karolisr/webhook-demo:0.0.11") {
+		t.Errorf("unexpected overrides:\n%s", overrides)
+	}
+}
@@ -0,0 +1,92 @@
+package helm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/keel-hq/keel/internal/ratelimit"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SetRateLimiter - enables update budgets, the limiter is shared with the
+// kubernetes providers
+func (p *Provider) SetRateLimiter(l *ratelimit.Limiter) {
+	p.rateLimiter = l
+}
+
+// checkRateLimit - queues release updates that exceed update budgets
+func (p *Provider) checkRateLimit(plans []*UpdatePlan) []*UpdatePlan {
+	if p.rateLimiter == nil {
+		return plans
+	}
+
+	var ready []*UpdatePlan
+	for _, plan := range plans {
+		if p.rateLimiter.Allow(plan.Namespace) {
+			ready = append(ready, plan)
+			continue
+		}
+
+		plan := plan
+		identifier := fmt.Sprintf("%s/%s/%s", "chart", plan.Namespace, plan.Name)
+		p.rateLimiter.Enqueue(ratelimit.Update{
+			Provider:       p.GetName(),
+			Namespace:      plan.Namespace,
+			Kind:           "chart",
+			Identifier:     identifier,
+			CurrentVersion: plan.CurrentVersion,
+			NewVersion:     plan.NewVersion,
+		}, func() { p.applyQueued(plan) })
+
+		log.WithFields(log.Fields{
+			"name":      plan.Name,
+			"namespace": plan.Namespace,
+			"previous":  plan.CurrentVersion,
+			"new":       plan.NewVersion,
+		}).Info("provider.helm: update budget exceeded, release update queued")
+
+		p.sender.Send(types.EventNotification{
+			ResourceKind: "chart",
+			Identifier:   identifier,
+			Name:         "update queued",
+			Message:      fmt.Sprintf("Update budget exceeded, queued update of release %s/%s %s->%s (%s)", plan.Namespace, plan.Name, plan.CurrentVersion, plan.NewVersion, planChanges(plan)),
+			CreatedAt:    time.Now(),
+			Type:         types.NotificationUpdateQueued,
+			Level:        types.LevelInfo,
+			Channels:     plan.Config.NotificationChannels,
+			Metadata: map[string]string{
+				"provider":  p.GetName(),
+				"namespace": plan.Namespace,
+				"name":      plan.Name,
+			},
+		})
+	}
+	return ready
+}
+
+// applyQueued - applies release update released by the rate limiter
+func (p *Provider) applyQueued(plan *UpdatePlan) {
+	if paused := store.Maintenance(p.store); paused != nil {
+		p.maintenanceUpdates([]*UpdatePlan{plan}, paused)
+		return
+	}
+	if p.isPaused(plan.Namespace, plan.Name) {
+		log.WithFields(log.Fields{
+			"name":      plan.Name,
+			"namespace": plan.Namespace,
+		}).Info("provider.helm: release was paused while update was queued, skipping")
+		return
+	}
+
+	err := p.applyPlans([]*UpdatePlan{plan})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      plan.Name,
+			"namespace": plan.Namespace,
+		}).Error("provider.helm: failed to apply queued update")
+	}
+}
@@ -0,0 +1,106 @@
+package helm
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/helm/pkg/helm"
+	hapi_release "k8s.io/helm/pkg/proto/hapi/release"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultTestTimeout - release test timeout in seconds
+const DefaultTestTimeout = 300
+
+// TestDetails - helm test run after keel upgrades the release
+type TestDetails struct {
+	Enabled bool `json:"enabled"`
+	// Timeout - test timeout in seconds, defaults to DefaultTestTimeout
+	Timeout int64 `json:"timeout"`
+	// Cleanup - deletes test pods once tests finish
+	Cleanup bool `json:"cleanup"`
+	// KeepOnFailure - release isn't rolled back when tests fail
+	KeepOnFailure bool `json:"keepOnFailure"`
+}
+
+// testRelease - runs release tests when they're enabled, result is added to the
+// completion notification. Release is rolled back to the previous revision when
+// tests fail, returned error includes the rollback outcome.
+func (p *Provider) testRelease(plan *UpdatePlan) (string, error) {
+	if plan.Config == nil || plan.Config.Test == nil || !plan.Config.Test.Enabled {
+		return "", nil
+	}
+
+	err := p.runTests(plan.Name, plan.Config.Test)
+	if err == nil {
+		return "tests passed", nil
+	}
+
+	if plan.Config.Test.KeepOnFailure {
+		return "", err
+	}
+	if plan.revision <= 1 {
+		return "", fmt.Errorf("%s, release has no previous revision to roll back to", err)
+	}
+
+	previous := plan.revision - 1
+	_, rollbackErr := p.implementer.RollbackRelease(plan.Name,
+		helm.RollbackVersion(previous),
+		helm.RollbackTimeout(DefaultUpdateTimeout),
+		helm.RollbackWait(true),
+		helm.RollbackDescription("keel: release tests failed"))
+	if rollbackErr != nil {
+		return "", fmt.Errorf("%s, rollback to revision %d failed: %s", err, previous, rollbackErr)
+	}
+
+	log.WithFields(log.Fields{
+		"name":     plan.Name,
+		"revision": previous,
+	}).Info("provider.helm: release rolled back after failed tests")
+
+	return "", fmt.Errorf("%s, rolled back to revision %d", err, previous)
+}
+
+// runTests - runs helm test for the release, error lists failed tests
+func (p *Provider) runTests(release string, details *TestDetails) error {
+	timeout := details.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTestTimeout
+	}
+
+	results, errs := p.implementer.RunReleaseTest(release,
+		helm.ReleaseTestTimeout(timeout),
+		helm.ReleaseTestCleanup(details.Cleanup))
+
+	var failed []string
+	for results != nil || errs != nil {
+		select {
+		case res, ok := <-results:
+			if !ok {
+				results = nil
+				continue
+			}
+			log.WithFields(log.Fields{
+				"name":   release,
+				"status": res.Status.String(),
+			}).Debug("provider.helm: " + res.Msg)
+			if res.Status == hapi_release.TestRun_FAILURE {
+				failed = append(failed, res.Msg)
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to run tests: %s", err)
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d test(s) failed: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
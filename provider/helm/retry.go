@@ -0,0 +1,86 @@
+package helm
+
+import (
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/util/timeutil"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultUpdateAttempts - attempts of release upgrades failing with transient
+// errors before the failure is reported
+const DefaultUpdateAttempts = 5
+
+// maxRetryBackoff - backoff between upgrade attempts doubles up to maxRetryBackoff
+const maxRetryBackoff = time.Minute
+
+// SetUpdateAttempts - sets how many times upgrades failing with transient errors
+// are attempted, 1 disables retries
+func (p *Provider) SetUpdateAttempts(attempts int) {
+	p.updateAttempts = attempts
+}
+
+func (p *Provider) maxUpdateAttempts() int {
+	if p.updateAttempts <= 0 {
+		return DefaultUpdateAttempts
+	}
+	return p.updateAttempts
+}
+
+// isTransient - whether the upgrade could succeed when it's attempted again:
+// tiller is unavailable, throttled or timed out, or another operation on the
+// release is in progress
+func isTransient(err error) bool {
+	if strings.Contains(err.Error(), "another operation (install/upgrade/rollback) is in progress") {
+		return true
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	switch s.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// retryUpgrade - applies the plan again with exponential backoff, returns false when
+// the error isn't transient or the plan ran out of attempts and the failure should
+// be reported. Values are reused on upgrades so the latest release is upgraded.
+func (p *Provider) retryUpgrade(plan *UpdatePlan, upgradeErr error) bool {
+	if !isTransient(upgradeErr) {
+		return false
+	}
+	plan.attempts++
+	if plan.attempts >= p.maxUpdateAttempts() {
+		return false
+	}
+	plan.retryBackoff = timeutil.ExpBackoff(plan.retryBackoff, maxRetryBackoff)
+
+	log.WithFields(log.Fields{
+		"error":     upgradeErr,
+		"name":      plan.Name,
+		"namespace": plan.Namespace,
+		"attempt":   plan.attempts,
+		"backoff":   plan.retryBackoff,
+	}).Warn("provider.helm: transient error while upgrading release, retrying")
+
+	// the plan belongs to the retry from now on, it isn't touched until the
+	// retry applies it again
+	backoff := plan.retryBackoff
+	go func() {
+		select {
+		case <-time.After(backoff):
+		case <-p.stop:
+			return
+		}
+		p.applyPlans([]*UpdatePlan{plan})
+	}()
+	return true
+}
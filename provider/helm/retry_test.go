@@ -0,0 +1,45 @@
+package helm
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{status.Error(codes.Unavailable, "transport is closing"), true},
+		{status.Error(codes.DeadlineExceeded, "context deadline exceeded"), true},
+		{status.Error(codes.Unknown, `UPGRADE FAILED: "wd" has no deployed releases`), false},
+		{errors.New("rpc error: code = Unknown desc = another operation (install/upgrade/rollback) is in progress"), true},
+		{errors.New("dry-run failed: image.tag=1.1.0 is not used by the chart templates"), false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("%s: got %t, want %t", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryUpgrade(t *testing.T) {
+	p := &Provider{stop: make(chan struct{})}
+	close(p.stop)
+	p.SetUpdateAttempts(2)
+
+	plan := &UpdatePlan{Namespace: "default", Name: "wd"}
+	unavailable := status.Error(codes.Unavailable, "transport is closing")
+	if !p.retryUpgrade(plan, unavailable) {
+		t.Fatalf("expected upgrade to be retried")
+	}
+	if p.retryUpgrade(plan, unavailable) {
+		t.Errorf("expected failure to be reported once attempts run out")
+	}
+	if plan.attempts != 2 {
+		t.Errorf("unexpected attempts: %d", plan.attempts)
+	}
+}
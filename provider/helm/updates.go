@@ -5,6 +5,7 @@ import (
 	"github.com/keel-hq/keel/types"
 	"github.com/keel-hq/keel/util/image"
 
+	"k8s.io/helm/pkg/chartutil"
 	hapi_chart "k8s.io/helm/pkg/proto/hapi/chart"
 
 	log "github.com/sirupsen/logrus"
@@ -105,7 +106,9 @@ func checkRelease(repo *types.Repository, namespace, name string, chart *hapi_ch
 		// }
 
 		if imageDetails.DigestPath != "" {
+			setPlanPrevious(plan, vals, imageDetails.DigestPath)
 			plan.Values[imageDetails.DigestPath] = repo.Digest
+			setPlanList(plan, vals, imageDetails.DigestPath)
 			log.WithFields(log.Fields{
 				"image_details_digestPath": imageDetails.DigestPath,
 				"target_image_digest":      repo.Digest,
@@ -113,7 +116,9 @@ func checkRelease(repo *types.Repository, namespace, name string, chart *hapi_ch
 		}
 
 		path, value := getUnversionedPlanValues(repo.Tag, imageRef, &imageDetails)
+		setPlanPrevious(plan, vals, path)
 		plan.Values[path] = value
+		setPlanList(plan, vals, path)
 		plan.NewVersion = repo.Tag
 		plan.CurrentVersion = imageRef.Tag()
 		plan.Config = keelCfg
@@ -125,3 +130,25 @@ func checkRelease(repo *types.Repository, namespace, name string, chart *hapi_ch
 
 	return plan, shouldUpdateRelease, nil
 }
+
+// setPlanPrevious - keeps current value of the updated path, shown in approval diffs
+func setPlanPrevious(plan *UpdatePlan, vals chartutil.Values, path string) {
+	if plan.previous == nil {
+		plan.previous = make(map[string]string)
+	}
+	plan.previous[path], _ = getValueAsString(vals, path)
+}
+
+// setPlanList - keeps current list values for index paths
+func setPlanList(plan *UpdatePlan, vals chartutil.Values, path string) {
+	if !isIndexPath(path) {
+		return
+	}
+	key := rootKey(path)
+	if plan.Lists == nil {
+		plan.Lists = make(map[string]interface{})
+	}
+	if _, ok := plan.Lists[key]; !ok {
+		plan.Lists[key] = vals[key]
+	}
+}
@@ -104,6 +104,7 @@ keel:
 				Name:           "release-1",
 				Chart:          helloWorldChart,
 				Values:         map[string]string{"image.tag": "latest"},
+				previous:       map[string]string{"image.tag": "1.1.0"},
 				CurrentVersion: "1.1.0",
 				NewVersion:     "latest",
 				Config: &KeelChartConfig{
@@ -135,6 +136,7 @@ keel:
 				Name:           "release-1",
 				Chart:          helloWorldChartPolicyMajorReleaseNotes,
 				Values:         map[string]string{"image.tag": "1.2.0"},
+				previous:       map[string]string{"image.tag": "1.1.0"},
 				CurrentVersion: "1.1.0",
 				NewVersion:     "1.2.0",
 				ReleaseNotes:   []string{"https://github.com/keel-hq/keel/releases"},
@@ -315,6 +317,7 @@ image:
 				Name:           "release-1",
 				Chart:          helloWorldChart,
 				Values:         map[string]string{"image.tag": "1.1.2"},
+				previous:       map[string]string{"image.tag": "1.1.0"},
 				NewVersion:     "1.1.2",
 				CurrentVersion: "1.1.0",
 				Config: &KeelChartConfig{
@@ -371,6 +374,7 @@ image:
 				Name:           "release-1",
 				Chart:          helloWorldNonSemverChart,
 				Values:         map[string]string{"image.tag": "1.1.0"},
+				previous:       map[string]string{"image.tag": "alpha"},
 				NewVersion:     "1.1.0",
 				CurrentVersion: "alpha",
 				Config: &KeelChartConfig{
@@ -414,6 +418,7 @@ image:
 				Name:           "release-1-no-tag",
 				Chart:          helloWorldNoTagChart,
 				Values:         map[string]string{"image.repository": "gcr.io/v2-namespace/hello-world:1.1.0"},
+				previous:       map[string]string{"image.repository": "gcr.io/v2-namespace/hello-world:1.0.0"},
 				NewVersion:     "1.1.0",
 				CurrentVersion: "1.0.0",
 				Config: &KeelChartConfig{
@@ -2,11 +2,14 @@ package kubernetes
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"time"
 
+	"github.com/keel-hq/keel/approvals"
 	"github.com/keel-hq/keel/pkg/store"
 	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/i18n"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -37,7 +40,7 @@ func (p *Provider) checkForApprovals(event *types.Event, plans []*UpdatePlan) (a
 
 // updateComplete is called after we successfully update resource
 func (p *Provider) updateComplete(plan *UpdatePlan) error {
-	return p.approvalManager.Archive(getApprovalIdentifier(plan.Resource.Identifier, plan.NewVersion))
+	return p.approvalManager.Archive(getApprovalIdentifier(p.resourceIdentifier(plan.Resource), plan.NewVersion))
 }
 
 func getInt(key string, labels map[string]string, annotations map[string]string) (int, error) {
@@ -75,12 +78,17 @@ func (p *Provider) isApproved(event *types.Event, plan *UpdatePlan) (bool, error
 		return false, err
 	}
 
+	// vulnerable images and images without verified provenance are only updated once approved
+	if minApprovals == 0 && (plan.vulnerabilities != "" || plan.provenanceFailure != "") {
+		minApprovals = 1
+	}
+
 	if minApprovals == 0 {
 		return true, nil
 	}
 
 	// deadline
-	deadline := types.KeelApprovalDeadlineDefault
+	deadline := types.DefaultApprovalDeadline()
 	d, err := getInt(types.KeelApprovalDeadlineLabel, plan.Resource.GetLabels(), plan.Resource.GetAnnotations())
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -91,7 +99,7 @@ func (p *Provider) isApproved(event *types.Event, plan *UpdatePlan) (bool, error
 		deadline = d
 	}
 
-	identifier := getApprovalIdentifier(plan.Resource.Identifier, plan.NewVersion)
+	identifier := getApprovalIdentifier(p.resourceIdentifier(plan.Resource), plan.NewVersion)
 
 	// checking for existing approval
 	existing, err := p.approvalManager.Get(identifier)
@@ -116,15 +124,56 @@ func (p *Provider) isApproved(event *types.Event, plan *UpdatePlan) (bool, error
 				VotesReceived:  0,
 				Rejected:       false,
 				Deadline:       time.Now().Add(time.Duration(deadline) * time.Hour),
+				Workspace:      plan.Resource.GetAnnotations()[types.KeelApprovalsWorkspaceAnnotation],
+				Reminders:      plan.Resource.GetAnnotations()[types.KeelApprovalRemindersAnnotation],
+				Approvers:      plan.Resource.GetAnnotations()[types.KeelApproversAnnotation],
+				Private:        plan.Resource.GetAnnotations()[types.KeelApprovalsPrivateAnnotation] == "true",
+				Oncall:         p.oncall(plan.Resource),
+				IdempotencyKey: idempotencyKey(plan),
 			}
 
-			approval.Message = fmt.Sprintf("New image is available for resource %s/%s (%s).",
-				plan.Resource.Namespace,
-				plan.Resource.Name,
-				approval.Delta(),
-			)
+			if p.cluster != "" {
+				approval.Message = i18n.T("New image is available for resource %s/%s in cluster %s (%s).",
+					plan.Resource.Namespace,
+					plan.Resource.Name,
+					p.cluster,
+					approval.Delta(),
+				)
+			} else {
+				approval.Message = i18n.T("New image is available for resource %s/%s (%s).",
+					plan.Resource.Namespace,
+					plan.Resource.Name,
+					approval.Delta(),
+				)
+			}
+			if plan.Digest != "" {
+				approval.Message += " " + i18n.T("Image digest: %s.", plan.Digest)
+			}
+			if plan.Signer != "" {
+				approval.Message += " " + i18n.T("Signature verified, signed by %s.", plan.Signer)
+			}
+			if plan.vulnerabilities != "" {
+				approval.Message += " " + i18n.T("Vulnerabilities found: %s.", plan.vulnerabilities)
+			}
+			if plan.provenance != "" {
+				approval.Message += " " + i18n.T("Provenance: %s.", plan.provenance)
+			}
+			if plan.provenanceFailure != "" {
+				approval.Message += " " + i18n.T("Provenance verification failed: %s.", plan.provenanceFailure)
+			}
+			if plan.soaked != "" {
+				approval.Message += " " + i18n.T("Soaked %s in %s.", plan.soaked, plan.soakSource)
+			}
+			if plan.releaseNotes != "" {
+				approval.Message += "\n\n" + i18n.T("Release notes:") + "\n" + plan.releaseNotes
+			}
 
-			return false, p.approvalManager.Create(approval)
+			err = p.approvalManager.Create(approval)
+			if err == approvals.ErrApprovalAlreadyExists {
+				// another event of the same change requested it first
+				return false, nil
+			}
+			return false, err
 		}
 
 		return false, err
@@ -142,5 +191,24 @@ func (p *Provider) isApproved(event *types.Event, plan *UpdatePlan) (bool, error
 	// 	"new":      event.Repository.Digest,
 	// }).Info("digests match")
 
-	return existing.Status() == types.ApprovalStatusApproved, nil
+	if existing.Status() != types.ApprovalStatusApproved {
+		return false, nil
+	}
+	plan.approval = fmt.Sprintf("approved (%d/%d)", existing.VotesReceived, existing.VotesRequired)
+	plan.approvalIdentifier = existing.Identifier
+	plan.approvers = existing.GetVoters()
+	sort.Strings(plan.approvers)
+
+	p.sender.Send(types.EventNotification{
+		Name:         "update approved",
+		ResourceKind: plan.Resource.Kind(),
+		Identifier:   plan.Resource.Identifier,
+		Message:      fmt.Sprintf("%s %s/%s update %s->%s %s", plan.Resource.Kind(), plan.Resource.Namespace, plan.Resource.Name, plan.CurrentVersion, plan.NewVersion, plan.approval),
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationUpdateApproved,
+		Level:        types.LevelInfo,
+		Channels:     types.ParseEventNotificationChannels(plan.Resource.GetAnnotations()),
+		Metadata:     p.planMetadata(plan),
+	})
+	return true, nil
 }
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/pkg/store"
 	"github.com/keel-hq/keel/types"
 
 	apps_v1 "k8s.io/api/apps/v1"
@@ -53,7 +54,7 @@ func TestCheckRequestedApproval(t *testing.T) {
 	grc.Add(grs...)
 
 	approver := approver()
-	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -128,7 +129,7 @@ func TestCheckRequestedApprovalAnnotation(t *testing.T) {
 	grc.Add(grs...)
 
 	approver := approver()
-	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -205,7 +206,7 @@ func TestApprovedCheck(t *testing.T) {
 	grc.Add(grs...)
 
 	approver := approver()
-	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -285,7 +286,7 @@ func TestApprovalsCleanup(t *testing.T) {
 	grc.Add(grs...)
 
 	approver := approver()
-	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -324,14 +325,9 @@ func TestApprovalsCleanup(t *testing.T) {
 		t.Errorf("expected to find 1 updated deployment but found %d", len(deps))
 	}
 
-	// no approvals expected
-
-	approvals, err := provider.approvalManager.List()
-	if err != nil {
-		t.Fatalf("failed to get a list of approvals: %s", err)
-	}
-
-	if len(approvals) != 0 {
-		t.Errorf("expected to find 0 but found %d", len(approvals))
+	// approval is archived once the update is applied
+	_, err = provider.approvalManager.Get("deployment/xxxx/dep-1:1.1.2")
+	if err != store.ErrRecordNotFound {
+		t.Errorf("expected approval to be archived, got: %v", err)
 	}
 }
@@ -0,0 +1,82 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// BlueGreenController - deploys updated resource next to the current version and
+// switches traffic to it before the resource itself is updated
+type BlueGreenController interface {
+	// Enabled - whether blue/green updates are configured for the resource
+	Enabled(resource *k8s.GenericResource) bool
+	// Run - blocks until traffic is switched to the updated version, error means
+	// that traffic stays on the current version
+	Run(current, updated *k8s.GenericResource, report func(phase, message string)) error
+}
+
+// SetBlueGreenController - enables blue/green updates of resources with keel.sh/blueGreenService annotation
+func (p *Provider) SetBlueGreenController(c BlueGreenController) {
+	p.blueGreen = c
+}
+
+// runBlueGreen - switches traffic to the new version and updates the resource once
+// it's done, so it reflects the version that serves traffic. When it fails traffic
+// stays on the current version and the resource isn't updated.
+func (p *Provider) runBlueGreen(plan *UpdatePlan, channels []string) {
+	resource := plan.Resource
+
+	// current version is deployed as blue on the first switch
+	current := p.cachedResource(resource.Identifier)
+	if current == nil {
+		current = resource
+	}
+
+	err := p.blueGreen.Run(current, resource, func(phase, message string) {
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"phase":     phase,
+		}).Info("provider.kubernetes: " + message)
+
+		p.sendBlueGreenNotification(plan, fmt.Sprintf("blue/green %s: %s", phase, message), types.LevelInfo, channels)
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"update":    fmt.Sprintf("%s->%s", plan.CurrentVersion, plan.NewVersion),
+		}).Error("provider.kubernetes: blue/green update failed, traffic stays on the current version")
+
+		p.recordUpdate(plan, fmt.Errorf("blue/green update failed: %s", err))
+		p.sendBlueGreenNotification(plan, fmt.Sprintf("blue/green update failed, traffic stays on the current version: %s", err), types.LevelError, channels)
+		return
+	}
+
+	plan.blueGreenSwitched = true
+	plan.Resource = p.promotedResource(resource)
+	p.updateDeployments([]*UpdatePlan{plan})
+}
+
+func (p *Provider) sendBlueGreenNotification(plan *UpdatePlan, message string, level types.Level, channels []string) {
+	resource := plan.Resource
+	p.sender.Send(types.EventNotification{
+		Name:         "blue/green",
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Message:      fmt.Sprintf("%s %s/%s update %s->%s %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, message),
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        level,
+		Channels:     channels,
+		Metadata:     p.planMetadata(plan),
+	})
+}
@@ -0,0 +1,102 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CanaryController - verifies updated resource on a subset of replicas before
+// the resource itself is updated
+type CanaryController interface {
+	// Enabled - whether canary is configured for the resource
+	Enabled(resource *k8s.GenericResource) bool
+	// Run - blocks until canary analysis finishes, error means that the update
+	// should be rolled back
+	Run(resource *k8s.GenericResource, report func(phase, message string)) error
+}
+
+// SetCanaryController - enables canary analysis of resources with canary annotations
+func (p *Provider) SetCanaryController(c CanaryController) {
+	p.canary = c
+}
+
+// runCanary - runs canary analysis and updates the resource once it passes.
+// When analysis fails canary is removed and resource stays on the current version.
+func (p *Provider) runCanary(plan *UpdatePlan, channels []string) {
+	resource := plan.Resource
+
+	err := p.canary.Run(resource, func(phase, message string) {
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"phase":     phase,
+		}).Info("provider.kubernetes: " + message)
+
+		p.sendCanaryNotification(plan, fmt.Sprintf("canary %s: %s", phase, message), types.LevelInfo, channels)
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"update":    fmt.Sprintf("%s->%s", plan.CurrentVersion, plan.NewVersion),
+		}).Error("provider.kubernetes: canary failed, update rolled back")
+
+		p.recordUpdate(plan, fmt.Errorf("canary failed: %s", err))
+		p.sendCanaryNotification(plan, fmt.Sprintf("canary failed, update rolled back: %s", err), types.LevelError, channels)
+		return
+	}
+
+	plan.canaryPassed = true
+	plan.Resource = p.promotedResource(resource)
+	p.updateDeployments([]*UpdatePlan{plan})
+}
+
+// promotedResource - resource could have changed during canary analysis, updated
+// images are applied to the latest cached version
+func (p *Provider) promotedResource(resource *k8s.GenericResource) *k8s.GenericResource {
+	current := p.cachedResource(resource.Identifier)
+	if current == nil {
+		return resource
+	}
+	containers := resource.Containers()
+	initContainers := resource.InitContainers()
+	if len(current.Containers()) != len(containers) || len(current.InitContainers()) != len(initContainers) {
+		return resource
+	}
+
+	promoted := current.DeepCopy()
+	for i, c := range containers {
+		promoted.UpdateContainer(i, c.Image)
+	}
+	for i, c := range initContainers {
+		promoted.UpdateInitContainer(i, c.Image)
+	}
+	return promoted
+}
+
+func (p *Provider) sendCanaryNotification(plan *UpdatePlan, message string, level types.Level, channels []string) {
+	resource := plan.Resource
+	p.sender.Send(types.EventNotification{
+		Name:         "canary",
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Message:      fmt.Sprintf("%s %s/%s update %s->%s %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, message),
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        level,
+		Channels:     channels,
+		Metadata: map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+		},
+	})
+}
@@ -0,0 +1,92 @@
+package kubernetes
+
+import (
+	"strings"
+
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/types"
+)
+
+// SetCluster - sets name of the cluster the provider manages, used when keel
+// manages several clusters. Resources can be limited to clusters with
+// keel.sh/clusters annotation, approvals and notifications include cluster name.
+func (p *Provider) SetCluster(name string) {
+	p.cluster = name
+	if name != "" {
+		p.sender = &clusterSender{Sender: p.sender, cluster: name}
+	}
+}
+
+// Cluster - returns cluster name, empty when keel manages a single cluster
+func (p *Provider) Cluster() string {
+	return p.cluster
+}
+
+// clusterSender - adds cluster name to notifications
+type clusterSender struct {
+	notification.Sender
+	cluster string
+}
+
+func (s *clusterSender) Send(event types.EventNotification) error {
+	metadata := make(map[string]string, len(event.Metadata)+1)
+	for k, v := range event.Metadata {
+		metadata[k] = v
+	}
+	metadata["cluster"] = s.cluster
+	event.Metadata = metadata
+	event.Message = "[" + s.cluster + "] " + event.Message
+	return s.Sender.Send(event)
+}
+
+// targetsCluster - resources without keel.sh/clusters annotation are updated in all clusters
+func targetsCluster(annotations map[string]string, cluster string) bool {
+	clusters, ok := annotations[types.KeelClustersAnnotation]
+	if !ok || cluster == "" {
+		return true
+	}
+	for _, c := range strings.Split(clusters, ",") {
+		if strings.TrimSpace(c) == cluster {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterMeta - keel.sh/policy.<cluster> overrides keel.sh/policy
+func clusterMeta(meta map[string]string, cluster string) map[string]string {
+	clusterPolicy, ok := meta[types.KeelPolicyLabel+"."+cluster]
+	if !ok {
+		return meta
+	}
+	overridden := make(map[string]string, len(meta))
+	for k, v := range meta {
+		overridden[k] = v
+	}
+	overridden[types.KeelPolicyLabel] = clusterPolicy
+	return overridden
+}
+
+// resourcePolicy - update policy of the resource in the provider cluster
+func (p *Provider) resourcePolicy(resource *k8s.GenericResource) policy.Policy {
+	labels := resource.GetLabels()
+	annotations := resource.GetAnnotations()
+	if p.cluster == "" {
+		return policy.GetPolicyFromLabelsOrAnnotations(labels, annotations)
+	}
+
+	if !targetsCluster(annotations, p.cluster) {
+		return &policy.NilPolicy{}
+	}
+	return policy.GetPolicyFromLabelsOrAnnotations(clusterMeta(labels, p.cluster), clusterMeta(annotations, p.cluster))
+}
+
+// resourceIdentifier - resource identifiers are only unique within a cluster
+func (p *Provider) resourceIdentifier(resource *k8s.GenericResource) string {
+	if p.cluster == "" {
+		return resource.Identifier
+	}
+	return p.cluster + "/" + resource.Identifier
+}
@@ -0,0 +1,99 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type recordingSender struct {
+	sent []types.EventNotification
+}
+
+func (s *recordingSender) Configure(*notification.Config) (bool, error) { return true, nil }
+
+func (s *recordingSender) Send(event types.EventNotification) error {
+	s.sent = append(s.sent, event)
+	return nil
+}
+
+func clusterDeployment(t *testing.T, annotations map[string]string) *k8s.GenericResource {
+	gr, err := k8s.NewGenericResource(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "dep-1",
+			Namespace:   "xxxx",
+			Annotations: annotations,
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create resource: %s", err)
+	}
+	return gr
+}
+
+func TestResourcePolicyClusters(t *testing.T) {
+	staging := &Provider{}
+	staging.SetCluster("staging")
+	prod := &Provider{}
+	prod.SetCluster("prod")
+
+	gr := clusterDeployment(t, map[string]string{
+		types.KeelPolicyLabel:              "patch",
+		types.KeelPolicyLabel + ".staging": "all",
+		types.KeelClustersAnnotation:       "staging, prod",
+	})
+	if plc := staging.resourcePolicy(gr); plc.Name() != policy.NewSemverPolicy(policy.SemverPolicyTypeAll).Name() {
+		t.Errorf("expected cluster policy, got: %s", plc.Name())
+	}
+	if plc := prod.resourcePolicy(gr); plc.Name() != policy.NewSemverPolicy(policy.SemverPolicyTypePatch).Name() {
+		t.Errorf("expected default policy, got: %s", plc.Name())
+	}
+
+	gr = clusterDeployment(t, map[string]string{
+		types.KeelPolicyLabel:        "patch",
+		types.KeelClustersAnnotation: "staging",
+	})
+	if plc := prod.resourcePolicy(gr); plc.Type() != policy.PolicyTypeNone {
+		t.Errorf("expected resource to be ignored in prod, got: %s", plc.Name())
+	}
+	if id := staging.resourceIdentifier(gr); id != "staging/"+gr.Identifier {
+		t.Errorf("unexpected identifier: %s", id)
+	}
+
+	// single cluster setup ignores cluster annotations
+	single := &Provider{}
+	if plc := single.resourcePolicy(gr); plc.Type() == policy.PolicyTypeNone {
+		t.Errorf("expected policy to be set")
+	}
+	if single.GetName() != ProviderName || prod.GetName() != "kubernetes/prod" {
+		t.Errorf("unexpected provider names: %s, %s", single.GetName(), prod.GetName())
+	}
+}
+
+func TestClusterSender(t *testing.T) {
+	sender := &recordingSender{}
+	p := &Provider{sender: sender}
+	p.SetCluster("prod")
+
+	p.sender.Send(types.EventNotification{
+		Message:  "Successfully updated deployment",
+		Metadata: map[string]string{"name": "dep-1"},
+	})
+
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected notification to be sent")
+	}
+	sent := sender.sent[0]
+	if sent.Message != "[prod] Successfully updated deployment" {
+		t.Errorf("unexpected message: %s", sent.Message)
+	}
+	if sent.Metadata["cluster"] != "prod" || sent.Metadata["name"] != "dep-1" {
+		t.Errorf("unexpected metadata: %v", sent.Metadata)
+	}
+}
@@ -0,0 +1,134 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SetPinDeletedTags - pins images of resources that run a tag deleted from the
+// registry to the digest reported by the registry, new pods keep pulling the
+// same image instead of failing
+func (p *Provider) SetPinDeletedTags(enabled bool) {
+	p.pinDeletedTags = enabled
+}
+
+// TagDeleted - deletion events are queued like other events
+func (p *Provider) TagDeleted(event types.Event) error {
+	return p.Submit(event)
+}
+
+// tagDeleted - reports tracked resources that run the deleted tag, they are
+// never updated by the event
+func (p *Provider) tagDeleted(event *types.Event) {
+	eventRef, err := image.Parse(event.Repository.String())
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"image": event.Repository.String(),
+		}).Error("provider.kubernetes: failed to parse deleted image")
+		return
+	}
+
+	for _, resource := range p.cache.ValuesForImage(eventRef.Repository()) {
+		plc := p.resourcePolicy(resource)
+		if plc.Type() == policy.PolicyTypeNone || !p.inScope(resource) {
+			continue
+		}
+
+		running, pinned := runsTag(resource, eventRef)
+		if !running {
+			continue
+		}
+
+		level := types.LevelFatal
+		message := fmt.Sprintf("%s %s/%s runs %s which was deleted from the registry, new pods can't pull the image", resource.Kind(), resource.Namespace, resource.Name, eventRef.Remote())
+		switch {
+		case pinned:
+			level = types.LevelWarn
+			message = fmt.Sprintf("%s %s/%s runs %s pinned to a digest, the tag was deleted from the registry", resource.Kind(), resource.Namespace, resource.Name, eventRef.Remote())
+		case p.pinDeletedTags && event.Repository.Digest != "":
+			if err := p.pinDeletedTag(resource, &event.Repository); err != nil {
+				log.WithFields(log.Fields{
+					"error":     err,
+					"name":      resource.Name,
+					"kind":      resource.Kind(),
+					"namespace": resource.Namespace,
+					"image":     eventRef.Remote(),
+				}).Error("provider.kubernetes: failed to pin image of deleted tag")
+				message += fmt.Sprintf(", pinning to %s failed: %s", event.Repository.Digest, err)
+			} else {
+				level = types.LevelError
+				message += fmt.Sprintf(", image was pinned to %s", event.Repository.Digest)
+			}
+		}
+
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"image":     eventRef.Remote(),
+			"pinned":    pinned,
+		}).Warn("provider.kubernetes: deployed tag was deleted from the registry")
+
+		p.sendTagDeletedNotification(resource, message, level)
+	}
+}
+
+// runsTag - whether containers of the resource run the tag, pinned is set when
+// all of them are pinned to a digest
+func runsTag(resource *k8s.GenericResource, tag *image.Reference) (running, pinned bool) {
+	pinned = true
+	for _, c := range resourceContainers(resource) {
+		ref, err := image.Parse(c.Image)
+		if err != nil || ref.Repository() != tag.Repository() || ref.Tag() != tag.Tag() {
+			continue
+		}
+		running = true
+		if ref.Digest() == "" {
+			pinned = false
+		}
+	}
+	return running, running && pinned
+}
+
+// pinDeletedTag - pins images of the deleted tag to its digest, paused resources
+// aren't modified
+func (p *Provider) pinDeletedTag(resource *k8s.GenericResource, repo *types.Repository) error {
+	if paused, reason := p.isPausedOrFrozen(resource); paused {
+		if reason == "" {
+			reason = "resource is paused"
+		}
+		return fmt.Errorf("%s", reason)
+	}
+
+	plan := &UpdatePlan{Resource: resource, NewVersion: repo.Tag}
+	if err := pinImages(plan, repo); err != nil {
+		return err
+	}
+	return p.update(resource)
+}
+
+func (p *Provider) sendTagDeletedNotification(resource *k8s.GenericResource, message string, level types.Level) {
+	p.sender.Send(types.EventNotification{
+		Name:         "tag deleted",
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Message:      message,
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationTagDeleted,
+		Level:        level,
+		Channels:     types.ParseEventNotificationChannels(resource.GetAnnotations()),
+		Metadata: map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+		},
+	})
+}
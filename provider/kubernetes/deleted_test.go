@@ -0,0 +1,102 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func deletedTagProvider(t *testing.T, image string) (*Provider, *fakeImplementer, *fakeSender) {
+	dep := &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "dep-1",
+			Namespace: "xxxx",
+			Labels:    map[string]string{types.KeelPolicyLabel: "all"},
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: core_v1.PodTemplateSpec{
+				Spec: core_v1.PodSpec{
+					Containers: []core_v1.Container{{Image: image}},
+				},
+			},
+		},
+	}
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGRS([]*apps_v1.Deployment{dep})...)
+
+	fi := &fakeImplementer{}
+	sender := &fakeSender{}
+	p, err := NewProvider(fi, sender, nil, grc, nil)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	return p, fi, sender
+}
+
+func deletedTagEvent(digest string) *types.Event {
+	return &types.Event{
+		Repository: types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.15", Digest: digest},
+		Deleted:    true,
+	}
+}
+
+func TestTagDeleted(t *testing.T) {
+	p, fi, sender := deletedTagProvider(t, "karolisr/webhook-demo:0.0.15")
+
+	updated, err := p.processEvent(deletedTagEvent(testDigest))
+	if err != nil || len(updated) != 0 {
+		t.Fatalf("expected no updates, got: %v, %v", updated, err)
+	}
+	if fi.updated != nil {
+		t.Errorf("resource shouldn't be modified without digest pinning")
+	}
+	if sender.sentEvent.Type != types.NotificationTagDeleted || sender.sentEvent.Level != types.LevelFatal {
+		t.Errorf("unexpected notification: %+v", sender.sentEvent)
+	}
+	if sender.sentEvent.Identifier != "deployment/xxxx/dep-1" {
+		t.Errorf("unexpected identifier: %s", sender.sentEvent.Identifier)
+	}
+}
+
+func TestTagDeletedPinsDigest(t *testing.T) {
+	p, fi, sender := deletedTagProvider(t, "karolisr/webhook-demo:0.0.15")
+	p.SetPinDeletedTags(true)
+
+	p.processEvent(deletedTagEvent(testDigest))
+	if fi.updated == nil {
+		t.Fatalf("expected resource to be pinned")
+	}
+	if images := fi.updated.GetImages(); images[0] != "karolisr/webhook-demo:0.0.15@"+testDigest {
+		t.Errorf("unexpected image: %s", images[0])
+	}
+	if sender.sentEvent.Level != types.LevelError {
+		t.Errorf("unexpected notification level: %s", sender.sentEvent.Level)
+	}
+}
+
+func TestTagDeletedPinnedImage(t *testing.T) {
+	p, fi, sender := deletedTagProvider(t, "karolisr/webhook-demo:0.0.15@"+testDigest)
+	p.SetPinDeletedTags(true)
+
+	p.processEvent(deletedTagEvent(testDigest))
+	if fi.updated != nil {
+		t.Errorf("pinned resource shouldn't be modified")
+	}
+	if sender.sentEvent.Level != types.LevelWarn {
+		t.Errorf("unexpected notification level: %s", sender.sentEvent.Level)
+	}
+}
+
+func TestTagDeletedOtherTag(t *testing.T) {
+	p, fi, sender := deletedTagProvider(t, "karolisr/webhook-demo:0.0.14")
+
+	p.processEvent(deletedTagEvent(testDigest))
+	if fi.updated != nil || sender.sentEvent.Type == types.NotificationTagDeleted {
+		t.Errorf("resources running other tags shouldn't be affected")
+	}
+}
@@ -0,0 +1,278 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SetRegistryClient - sets registry client used to resolve digests of images
+// pinned with keel.sh/digestPinning annotation
+func (p *Provider) SetRegistryClient(client registry.Client) {
+	p.registryClient = client
+}
+
+func digestPinningEnabled(annotations map[string]string) bool {
+	value := annotations[types.KeelDigestPinningAnnotation]
+	return value == "1" || value == "true"
+}
+
+// pinDigests - pins updated images of resources with digest pinning enabled, plans
+// of resources which image digest can't be resolved are dropped. Resolved digest is
+// kept in the event so approved updates are pinned to the approved digest. Resources
+// running on a specific platform are pinned to the platform manifest of multi-arch
+// images, the event keeps the manifest list digest.
+func (p *Provider) pinDigests(event *types.Event, plans []*UpdatePlan) []*UpdatePlan {
+	var pinned []*UpdatePlan
+	for _, plan := range plans {
+		resource := plan.Resource
+		if !digestPinningEnabled(resource.GetAnnotations()) {
+			pinned = append(pinned, plan)
+			continue
+		}
+
+		repo := &event.Repository
+		if platform := resourcePlatform(resource); platform != "" {
+			platformRepo, err := p.platformRepository(resource, &event.Repository, platform)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":     err,
+					"name":      resource.Name,
+					"kind":      resource.Kind(),
+					"namespace": resource.Namespace,
+					"image":     event.Repository.String(),
+					"platform":  platform,
+				}).Error("provider.kubernetes: failed to resolve platform digest, resource won't be updated")
+				continue
+			}
+			repo = platformRepo
+		} else if event.Repository.Digest == "" {
+			digest, err := p.resolveDigest(resource, &event.Repository)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":     err,
+					"name":      resource.Name,
+					"kind":      resource.Kind(),
+					"namespace": resource.Namespace,
+					"image":     event.Repository.String(),
+				}).Error("provider.kubernetes: failed to resolve image digest, resource won't be updated")
+				continue
+			}
+			event.Repository.Digest = digest
+		}
+
+		err := pinImages(plan, repo)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"name":      resource.Name,
+				"kind":      resource.Kind(),
+				"namespace": resource.Namespace,
+			}).Error("provider.kubernetes: failed to pin image digest, resource won't be updated")
+			continue
+		}
+		pinned = append(pinned, plan)
+	}
+	return pinned
+}
+
+// resolveDigest - gets digest of the event tag, resource image pull secrets are used
+func (p *Provider) resolveDigest(resource *k8s.GenericResource, repo *types.Repository) (string, error) {
+	if p.registryClient == nil {
+		return "", fmt.Errorf("registry client is not set")
+	}
+
+	opts, err := registryOpts(resource, repo)
+	if err != nil {
+		return "", err
+	}
+	return p.registryClient.Digest(opts)
+}
+
+// well-known node labels of the node OS and architecture
+const (
+	nodeOSLabel       = "kubernetes.io/os"
+	nodeOSLabelBeta   = "beta.kubernetes.io/os"
+	nodeArchLabel     = "kubernetes.io/arch"
+	nodeArchLabelBeta = "beta.kubernetes.io/arch"
+)
+
+// resourcePlatform - platform (os/arch[/variant]) pods of the resource run on, from
+// keel.sh/platform annotation or kubernetes.io/os and kubernetes.io/arch node
+// selectors. Empty when pods can run on any platform.
+func resourcePlatform(resource *k8s.GenericResource) string {
+	if platform := strings.TrimSpace(resource.GetAnnotations()[types.KeelPlatformAnnotation]); platform != "" {
+		return platform
+	}
+
+	selector := resource.GetNodeSelector()
+	nodeOS := selector[nodeOSLabel]
+	if nodeOS == "" {
+		nodeOS = selector[nodeOSLabelBeta]
+	}
+	arch := selector[nodeArchLabel]
+	if arch == "" {
+		arch = selector[nodeArchLabelBeta]
+	}
+	switch {
+	case nodeOS == "" && arch == "":
+		return ""
+	case nodeOS == "":
+		nodeOS = "linux"
+	case arch == "":
+		return nodeOS
+	}
+	return nodeOS + "/" + arch
+}
+
+// platformRepository - event repository with the digest of the platform manifest,
+// manifest list digest is resolved into the event when it isn't set so all plans
+// of the event are pinned to the same list
+func (p *Provider) platformRepository(resource *k8s.GenericResource, repo *types.Repository, platform string) (*types.Repository, error) {
+	if p.registryClient == nil {
+		return nil, fmt.Errorf("registry client is not set")
+	}
+
+	opts, err := registryOpts(resource, repo)
+	if err != nil {
+		return nil, err
+	}
+	if repo.Digest != "" {
+		opts.Tag = repo.Digest
+	}
+	manifest, err := p.registryClient.Manifest(opts)
+	if err != nil {
+		return nil, err
+	}
+	if repo.Digest == "" {
+		repo.Digest = manifest.Digest
+	}
+
+	digest, err := platformDigest(manifest, platform)
+	if err != nil {
+		return nil, err
+	}
+	platformRepo := *repo
+	platformRepo.Digest = digest
+	return &platformRepo, nil
+}
+
+// platformDigest - digest of the platform manifest, platforms without an
+// architecture or a variant have to match a single manifest of the list.
+// Single-arch images have only their own digest.
+func platformDigest(manifest *registry.Manifest, platform string) (string, error) {
+	if !manifest.IsList() {
+		return manifest.Digest, nil
+	}
+	if d, ok := manifest.Platforms[platform]; ok {
+		return d, nil
+	}
+
+	var matches []string
+	for p := range manifest.Platforms {
+		if strings.HasPrefix(p, platform+"/") {
+			matches = append(matches, p)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("manifest list has no %s image", platform)
+	case 1:
+		return manifest.Platforms[matches[0]], nil
+	}
+	sort.Strings(matches)
+	return "", fmt.Errorf("platform %s matches several images (%s), set %s", platform, strings.Join(matches, ", "), types.KeelPlatformAnnotation)
+}
+
+// planDigest - digest of the new image, resolved unless images are already pinned
+func (p *Provider) planDigest(plan *UpdatePlan, repo *types.Repository) (string, error) {
+	if plan.Digest != "" {
+		return plan.Digest, nil
+	}
+	if repo.Digest != "" {
+		return repo.Digest, nil
+	}
+	digest, err := p.resolveDigest(plan.Resource, repo)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve image digest: %s", err)
+	}
+	return digest, nil
+}
+
+// registryOpts - registry options of the event tag with credentials from resource
+// image pull secrets
+func registryOpts(resource *k8s.GenericResource, repo *types.Repository) (registry.Opts, error) {
+	ref, err := image.Parse(repo.String())
+	if err != nil {
+		return registry.Opts{}, err
+	}
+
+	var secrets []string
+	if secret := getImagePullSecretFromMeta(resource.GetLabels(), resource.GetAnnotations()); secret != "" {
+		secrets = append(secrets, secret)
+	}
+	secrets = append(secrets, resource.GetImagePullSecrets()...)
+
+	creds := credentialshelper.GetCredentials(&types.TrackedImage{
+		Image:     ref,
+		Namespace: resource.Namespace,
+		Secrets:   secrets,
+		Provider:  ProviderName,
+	})
+
+	return registry.Opts{
+		Registry: ref.Scheme() + "://" + ref.Registry(),
+		Name:     ref.ShortName(),
+		Tag:      ref.Tag(),
+		Username: creds.Username,
+		Password: creds.Password,
+	}, nil
+}
+
+// pinImages - appends digest to updated images (name:tag@digest)
+func pinImages(plan *UpdatePlan, repo *types.Repository) error {
+	eventRef, err := image.Parse(repo.String())
+	if err != nil {
+		return err
+	}
+
+	resource := plan.Resource
+	pin := func(img string) (string, bool) {
+		ref, err := image.Parse(img)
+		if err != nil || ref.Repository() != eventRef.Repository() || ref.Tag() != plan.NewVersion {
+			return "", false
+		}
+		if ref.Registry() == image.DefaultRegistryHostname {
+			return fmt.Sprintf("%s:%s@%s", ref.ShortName(), plan.NewVersion, repo.Digest), true
+		}
+		return fmt.Sprintf("%s:%s@%s", ref.Repository(), plan.NewVersion, repo.Digest), true
+	}
+
+	found := false
+	for idx, c := range resource.Containers() {
+		if img, ok := pin(c.Image); ok {
+			resource.UpdateContainer(idx, img)
+			found = true
+		}
+	}
+	for idx, c := range resource.InitContainers() {
+		if img, ok := pin(c.Image); ok {
+			resource.UpdateInitContainer(idx, img)
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("no images with version %s found", plan.NewVersion)
+	}
+
+	plan.Digest = repo.Digest
+	return nil
+}
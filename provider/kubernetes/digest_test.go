@@ -0,0 +1,198 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testDigest = "sha256:0a9c1e8c1d3e5a6b2c4d9f0b1e2a3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b"
+
+type digestRegistry struct {
+	registry.Client
+	requested []registry.Opts
+}
+
+func (r *digestRegistry) Digest(opts registry.Opts) (string, error) {
+	r.requested = append(r.requested, opts)
+	return testDigest, nil
+}
+
+func pinnedDeployment(t *testing.T, annotations map[string]string) *k8s.GenericResource {
+	gr, err := k8s.NewGenericResource(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "dep-1",
+			Namespace:   "xxxx",
+			Annotations: annotations,
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: core_v1.PodTemplateSpec{
+				Spec: core_v1.PodSpec{
+					Containers: []core_v1.Container{
+						{Image: "karolisr/webhook-demo:0.0.15"},
+						{Image: "karolisr/sidecar:0.1.0"},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create resource: %s", err)
+	}
+	return gr
+}
+
+func TestPinDigests(t *testing.T) {
+	reg := &digestRegistry{}
+	p := &Provider{registryClient: reg}
+
+	pinned := &UpdatePlan{
+		Resource:       pinnedDeployment(t, map[string]string{types.KeelDigestPinningAnnotation: "true"}),
+		CurrentVersion: "0.0.14",
+		NewVersion:     "0.0.15",
+	}
+	unpinned := &UpdatePlan{
+		Resource:       pinnedDeployment(t, map[string]string{}),
+		CurrentVersion: "0.0.14",
+		NewVersion:     "0.0.15",
+	}
+	event := &types.Event{Repository: types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.15"}}
+
+	plans := p.pinDigests(event, []*UpdatePlan{pinned, unpinned})
+	if len(plans) != 2 {
+		t.Fatalf("expected both plans to be kept, got: %d", len(plans))
+	}
+
+	if len(reg.requested) != 1 || reg.requested[0].Name != "karolisr/webhook-demo" || reg.requested[0].Tag != "0.0.15" {
+		t.Errorf("unexpected digest requests: %v", reg.requested)
+	}
+	if event.Repository.Digest != testDigest {
+		t.Errorf("expected resolved digest to be set in the event, got: %s", event.Repository.Digest)
+	}
+
+	images := pinned.Resource.GetImages()
+	if images[0] != "karolisr/webhook-demo:0.0.15@"+testDigest {
+		t.Errorf("unexpected pinned image: %s", images[0])
+	}
+	if images[1] != "karolisr/sidecar:0.1.0" {
+		t.Errorf("image of not updated container changed: %s", images[1])
+	}
+	if pinned.Digest != testDigest {
+		t.Errorf("unexpected plan digest: %s", pinned.Digest)
+	}
+
+	if img := unpinned.Resource.GetImages()[0]; img != "karolisr/webhook-demo:0.0.15" {
+		t.Errorf("image shouldn't be pinned without annotation: %s", img)
+	}
+}
+
+func TestPinDigestsWithoutRegistry(t *testing.T) {
+	p := &Provider{}
+	plan := &UpdatePlan{
+		Resource:       pinnedDeployment(t, map[string]string{types.KeelDigestPinningAnnotation: "true"}),
+		CurrentVersion: "0.0.14",
+		NewVersion:     "0.0.15",
+	}
+
+	plans := p.pinDigests(&types.Event{Repository: types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.15"}}, []*UpdatePlan{plan})
+	if len(plans) != 0 {
+		t.Errorf("expected plan to be dropped when digest can't be resolved")
+	}
+
+	// digest from the event doesn't have to be resolved
+	plans = p.pinDigests(&types.Event{Repository: types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.15", Digest: testDigest}}, []*UpdatePlan{plan})
+	if len(plans) != 1 || plan.Resource.GetImages()[0] != "karolisr/webhook-demo:0.0.15@"+testDigest {
+		t.Errorf("expected image to be pinned to event digest")
+	}
+}
+
+type platformRegistry struct {
+	registry.Client
+	requested []registry.Opts
+}
+
+func (r *platformRegistry) Manifest(opts registry.Opts) (*registry.Manifest, error) {
+	r.requested = append(r.requested, opts)
+	return &registry.Manifest{
+		Digest:    "sha256:list",
+		MediaType: registry.MediaTypeDockerManifestList,
+		Platforms: map[string]string{
+			"linux/amd64":   "sha256:linux-amd64",
+			"linux/arm64":   "sha256:linux-arm64",
+			"windows/amd64": "sha256:windows-amd64",
+		},
+	}, nil
+}
+
+func nodeSelectorDeployment(t *testing.T, annotations, selector map[string]string) *k8s.GenericResource {
+	gr := pinnedDeployment(t, annotations)
+	gr.GetResource().(*apps_v1.Deployment).Spec.Template.Spec.NodeSelector = selector
+	return gr
+}
+
+func TestResourcePlatform(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		selector    map[string]string
+		want        string
+	}{
+		{map[string]string{}, nil, ""},
+		{map[string]string{}, map[string]string{"kubernetes.io/os": "windows"}, "windows"},
+		{map[string]string{}, map[string]string{"kubernetes.io/os": "windows", "kubernetes.io/arch": "amd64"}, "windows/amd64"},
+		{map[string]string{}, map[string]string{"beta.kubernetes.io/arch": "arm64"}, "linux/arm64"},
+		{map[string]string{types.KeelPlatformAnnotation: "linux/arm/v7"}, map[string]string{"kubernetes.io/os": "linux"}, "linux/arm/v7"},
+	}
+	for _, tt := range tests {
+		if got := resourcePlatform(nodeSelectorDeployment(t, tt.annotations, tt.selector)); got != tt.want {
+			t.Errorf("resourcePlatform(%v, %v) = %s, want %s", tt.annotations, tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestPinDigestsPlatform(t *testing.T) {
+	reg := &platformRegistry{}
+	p := &Provider{registryClient: reg}
+
+	windows := &UpdatePlan{
+		Resource:       nodeSelectorDeployment(t, map[string]string{types.KeelDigestPinningAnnotation: "true"}, map[string]string{"kubernetes.io/os": "windows"}),
+		CurrentVersion: "0.0.14",
+		NewVersion:     "0.0.15",
+	}
+	linux := &UpdatePlan{
+		Resource:       nodeSelectorDeployment(t, map[string]string{types.KeelDigestPinningAnnotation: "true", types.KeelPlatformAnnotation: "linux/arm64"}, nil),
+		CurrentVersion: "0.0.14",
+		NewVersion:     "0.0.15",
+	}
+	ambiguous := &UpdatePlan{
+		Resource:       nodeSelectorDeployment(t, map[string]string{types.KeelDigestPinningAnnotation: "true"}, map[string]string{"kubernetes.io/os": "linux"}),
+		CurrentVersion: "0.0.14",
+		NewVersion:     "0.0.15",
+	}
+	event := &types.Event{Repository: types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.15"}}
+
+	plans := p.pinDigests(event, []*UpdatePlan{windows, linux, ambiguous})
+	if len(plans) != 2 || plans[0] != windows || plans[1] != linux {
+		t.Fatalf("expected plan with ambiguous platform to be dropped, got: %v", plans)
+	}
+
+	if img := windows.Resource.GetImages()[0]; img != "karolisr/webhook-demo:0.0.15@sha256:windows-amd64" {
+		t.Errorf("unexpected windows image: %s", img)
+	}
+	if img := linux.Resource.GetImages()[0]; img != "karolisr/webhook-demo:0.0.15@sha256:linux-arm64" {
+		t.Errorf("unexpected linux image: %s", img)
+	}
+	if event.Repository.Digest != "sha256:list" {
+		t.Errorf("expected manifest list digest in the event, got: %s", event.Repository.Digest)
+	}
+
+	// manifest list of the event is used once it's resolved
+	if len(reg.requested) != 3 || reg.requested[0].Tag != "0.0.15" || reg.requested[1].Tag != "sha256:list" {
+		t.Errorf("unexpected manifest requests: %v", reg.requested)
+	}
+}
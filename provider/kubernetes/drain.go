@@ -0,0 +1,115 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrDraining - provider is shutting down and doesn't accept new events
+var ErrDraining = errors.New("provider is shutting down")
+
+// background - runs fn in the background, Drain waits for it to finish
+func (p *Provider) background(fn func()) {
+	p.inFlight.Add(1)
+	go func() {
+		defer p.inFlight.Done()
+		fn()
+	}()
+}
+
+func (p *Provider) isDraining() bool {
+	select {
+	case <-p.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// processQueued - processes events that were submitted before draining started
+func (p *Provider) processQueued() {
+	for {
+		select {
+		case event := <-p.events:
+			p.handleEvent(event)
+		default:
+			return
+		}
+	}
+}
+
+// Drain - stops accepting events, processes queued ones and waits for updates
+// in the background (canary analysis, ordered updates, rollouts) until ctx is
+// done. Rollouts that are still in progress are reported, approvals of updates
+// that weren't applied stay approved and are resumed by the next leader.
+func (p *Provider) Drain(ctx context.Context) error {
+	p.drainOnce.Do(func() { close(p.draining) })
+
+	select {
+	case <-p.drained:
+	case <-ctx.Done():
+		p.reportInterrupted()
+		return ctx.Err()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Info("provider.kubernetes: drained")
+		return nil
+	case <-ctx.Done():
+		p.reportInterrupted()
+		return ctx.Err()
+	}
+}
+
+// reportInterrupted - rollouts keel stopped tracking, their resources are
+// already updated so only success (or failure) notifications are missing
+func (p *Provider) reportInterrupted() {
+	for _, resource := range p.rollouts.list() {
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"images":    resource.GetImages(),
+		}).Warn("provider.kubernetes: shutting down, rollout is still in progress")
+
+		p.sender.Send(types.EventNotification{
+			Name:         "update resource",
+			ResourceKind: resource.Kind(),
+			Identifier:   resource.Identifier,
+			Message:      fmt.Sprintf("%s %s/%s rollout is still in progress, keel is shutting down and won't report its result", resource.Kind(), resource.Namespace, resource.Name),
+			CreatedAt:    time.Now(),
+			Type:         types.NotificationDeploymentUpdate,
+			Level:        types.LevelWarn,
+			Channels:     types.ParseEventNotificationChannels(resource.GetAnnotations()),
+		})
+	}
+}
+
+// list - resources with rollouts in progress
+func (t *rolloutTracker) list() []*k8s.GenericResource {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var resources []*k8s.GenericResource
+	for _, r := range t.inProgress {
+		resources = append(resources, r.resource)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Identifier < resources[j].Identifier
+	})
+	return resources
+}
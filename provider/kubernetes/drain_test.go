@@ -0,0 +1,40 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestDrain(t *testing.T) {
+	p, _ := NewProvider(nil, nil, nil, nil, nil)
+
+	// chart events are ignored by the provider, they only have to be received
+	p.Submit(types.Event{Chart: true})
+	p.Submit(types.Event{Chart: true})
+	go p.Start()
+
+	release := make(chan struct{})
+	p.background(func() { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := p.Drain(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected drain to time out while update is in progress, got: %v", err)
+	}
+	if len(p.events) != 0 {
+		t.Errorf("expected queued events to be processed, %d left", len(p.events))
+	}
+	if err := p.Submit(types.Event{Chart: true}); err != ErrDraining {
+		t.Errorf("expected events to be rejected, got: %v", err)
+	}
+
+	close(release)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.Drain(ctx); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
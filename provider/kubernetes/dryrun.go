@@ -0,0 +1,55 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func dryRunEnabled(annotations map[string]string) bool {
+	return annotations[types.KeelModeAnnotation] == types.KeelModeDryRun
+}
+
+// dryRunUpdate - reports update that would have been applied, approvals are
+// archived as if the resource was updated so they aren't requested again
+func (p *Provider) dryRunUpdate(plan *UpdatePlan, channels []string) {
+	resource := plan.Resource
+
+	log.WithFields(log.Fields{
+		"name":      resource.Name,
+		"kind":      resource.Kind(),
+		"previous":  plan.CurrentVersion,
+		"new":       plan.NewVersion,
+		"namespace": resource.Namespace,
+	}).Info("provider.kubernetes: dry-run mode, resource not updated")
+
+	p.sender.Send(types.EventNotification{
+		Name:         "dry-run update",
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Message:      fmt.Sprintf("Dry-run: would have updated %s %s/%s %s->%s (%s)", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(resource.GetImages(), ", ")),
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationDryRunUpdate,
+		Level:        types.LevelInfo,
+		Channels:     channels,
+		Metadata: map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+		},
+	})
+
+	err := p.updateComplete(plan)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+		}).Warn("provider.kubernetes: got error while resetting approvals counter after dry-run update")
+	}
+}
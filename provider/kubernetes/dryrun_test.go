@@ -0,0 +1,56 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/types"
+)
+
+// archivingManager - approvals manager that only records archived approvals
+type archivingManager struct {
+	approvals.Manager
+	archived []string
+}
+
+func (m *archivingManager) Archive(identifier string) error {
+	m.archived = append(m.archived, identifier)
+	return nil
+}
+
+func TestDryRunUpdate(t *testing.T) {
+	implementer := &updateRecorder{}
+	sender := &recordingSender{}
+	manager := &archivingManager{}
+	p := &Provider{
+		implementer:     implementer,
+		sender:          sender,
+		approvalManager: manager,
+	}
+
+	gr := pinnedDeployment(t, map[string]string{types.KeelModeAnnotation: types.KeelModeDryRun})
+	plan := &UpdatePlan{Resource: gr, CurrentVersion: "0.0.14", NewVersion: "0.0.15"}
+
+	updated, err := p.updateDeployments([]*UpdatePlan{plan})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(updated) != 0 || len(implementer.updated) != 0 {
+		t.Errorf("resource in dry-run mode shouldn't be updated")
+	}
+
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected dry-run notification, got: %d", len(sender.sent))
+	}
+	if sender.sent[0].Type != types.NotificationDryRunUpdate {
+		t.Errorf("unexpected notification type: %s", sender.sent[0].Type)
+	}
+	if !strings.Contains(sender.sent[0].Message, "would have updated deployment xxxx/dep-1 0.0.14->0.0.15") {
+		t.Errorf("unexpected message: %s", sender.sent[0].Message)
+	}
+
+	if len(manager.archived) != 1 || manager.archived[0] != getApprovalIdentifier(gr.Identifier, "0.0.15") {
+		t.Errorf("expected approval to be archived, got: %v", manager.archived)
+	}
+}
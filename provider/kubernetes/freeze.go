@@ -0,0 +1,58 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// freeze - change freeze of workloads (or namespaces) matching the selector,
+// frozen workloads are treated as paused
+type freeze struct {
+	selector labels.Selector
+}
+
+// SetFreezeSelector - workloads matching the selector, or in namespaces matching it,
+// aren't updated, ie: change-freeze=true
+func (p *Provider) SetFreezeSelector(selector labels.Selector) {
+	p.freeze = &freeze{selector: selector}
+}
+
+// frozenReason - why the resource is frozen, empty when it isn't. Reason can be
+// set with keel.sh/freezeReason annotation of the workload or the namespace.
+func (p *Provider) frozenReason(resource *k8s.GenericResource) string {
+	if p.freeze == nil {
+		return ""
+	}
+
+	if p.freeze.selector.Matches(labels.Set(resource.GetLabels())) {
+		if reason := resource.GetAnnotations()[types.KeelFreezeReasonAnnotation]; reason != "" {
+			return reason
+		}
+		return fmt.Sprintf("workload matches freeze selector %s", p.freeze.selector)
+	}
+
+	ns := p.cachedNamespace(resource.Namespace)
+	if ns != nil && p.freeze.selector.Matches(ns.labels) {
+		if reason := ns.annotations[types.KeelFreezeReasonAnnotation]; reason != "" {
+			return reason
+		}
+		return fmt.Sprintf("namespace %s matches freeze selector %s", resource.Namespace, p.freeze.selector)
+	}
+	return ""
+}
+
+// isPausedOrFrozen - whether resource is paused or frozen, reason is empty for
+// paused resources
+func (p *Provider) isPausedOrFrozen(resource *k8s.GenericResource) (bool, string) {
+	if p.isPaused(resource.Namespace, resource.Name) {
+		return true, ""
+	}
+	if reason := p.frozenReason(resource); reason != "" {
+		return true, reason
+	}
+	return false, ""
+}
@@ -0,0 +1,115 @@
+package kubernetes
+
+import (
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// recordUpdate - saves update history entry, has to be called before approval is archived
+func (p *Provider) recordUpdate(plan *UpdatePlan, updateErr error) {
+	if updateErr != nil {
+		p.saveUpdateRecord(plan, types.UpdateStatusFailed, updateErr.Error())
+		return
+	}
+	p.saveUpdateRecord(plan, types.UpdateStatusSuccess, "")
+}
+
+// recordRollback - marks the new version as bad so it isn't updated to again
+func (p *Provider) recordRollback(plan *UpdatePlan, reason string) {
+	p.saveUpdateRecord(plan, types.UpdateStatusRolledBack, reason)
+}
+
+func (p *Provider) saveUpdateRecord(plan *UpdatePlan, status, message string) {
+	if p.store == nil {
+		return
+	}
+
+	resource := plan.Resource
+
+	record := &types.UpdateRecord{
+		Provider:       p.GetName(),
+		ResourceKind:   resource.Kind(),
+		Identifier:     resource.Identifier,
+		Namespace:      resource.Namespace,
+		Name:           resource.Name,
+		Containers:     strings.Join(plan.containers, ","),
+		Image:          updatedImage(plan),
+		PreviousTag:    plan.CurrentVersion,
+		NewTag:         plan.NewVersion,
+		PreviousDigest: plan.previousDigest,
+		NewDigest:      plan.eventDigest,
+		Trigger:        plan.Trigger,
+		Status:         status,
+		Message:        message,
+	}
+	if plan.Digest != "" {
+		record.NewDigest = plan.Digest
+	}
+	if !plan.startedAt.IsZero() {
+		record.DurationMs = int64(time.Since(plan.startedAt) / time.Millisecond)
+	}
+
+	approval, err := p.approvalManager.Get(getApprovalIdentifier(resource.Identifier, plan.NewVersion))
+	if err == nil {
+		record.Approvers = approval.Voters
+	}
+
+	_, err = p.store.CreateUpdateRecord(record)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+		}).Error("provider.kubernetes: failed to save update history")
+	}
+}
+
+// updatedImage - repository of the container that got updated to the new version
+func updatedImage(plan *UpdatePlan) string {
+	for _, img := range plan.Resource.GetImages() {
+		ref, err := image.Parse(img)
+		if err != nil {
+			continue
+		}
+		if ref.Tag() == plan.NewVersion {
+			return ref.Repository()
+		}
+	}
+	return strings.Join(plan.Resource.GetImages(), ", ")
+}
+
+// isRolledBack - whether the plan version was already rolled back for the resource
+func (p *Provider) isRolledBack(plan *UpdatePlan) bool {
+	if p.store == nil {
+		return false
+	}
+
+	resource := plan.Resource
+	records, err := p.store.ListUpdateRecords(&types.UpdateRecordQuery{
+		Namespace: resource.Namespace,
+		Name:      resource.Name,
+		Image:     updatedImage(plan),
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+		}).Error("provider.kubernetes: failed to get update history")
+		return false
+	}
+
+	for _, r := range records {
+		if r.Status == types.UpdateStatusRolledBack && r.Provider == p.GetName() && r.Identifier == resource.Identifier && r.NewTag == plan.NewVersion {
+			return true
+		}
+	}
+	return false
+}
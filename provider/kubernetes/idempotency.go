@@ -0,0 +1,127 @@
+package kubernetes
+
+import (
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// appliedChangeTTL - how long applied changes are remembered, cache reflects
+// the update long before that so later events don't plan it again
+const appliedChangeTTL = time.Hour
+
+type appliedChange struct {
+	digest    string
+	appliedAt time.Time
+	// inProgress - set until the update is done, failed updates are forgotten
+	inProgress bool
+}
+
+// changeLedger - changes that were applied (or are being applied). The same
+// change is often reported by several triggers (ie: webhook and poll) or
+// resubmitted once approved, events that arrive before the cache reflects the
+// update would apply it again.
+type changeLedger struct {
+	mu      sync.Mutex
+	changes map[string]*appliedChange
+}
+
+// changeKey - workload and new image of the plan, digest is compared separately
+// as webhooks often don't report it
+func changeKey(plan *UpdatePlan) string {
+	return types.IdempotencyKey(plan.Resource.Identifier, updatedImage(plan)+":"+plan.NewVersion, "")
+}
+
+// changeDigest - digest of the new image, empty when neither pinned nor reported
+func changeDigest(plan *UpdatePlan) string {
+	if plan.Digest != "" {
+		return plan.Digest
+	}
+	return plan.eventDigest
+}
+
+// idempotencyKey - key of the plan change, see types.IdempotencyKey
+func idempotencyKey(plan *UpdatePlan) string {
+	return types.IdempotencyKey(plan.Resource.Identifier, updatedImage(plan)+":"+plan.NewVersion, changeDigest(plan))
+}
+
+func (l *changeLedger) get(plan *UpdatePlan) *appliedChange {
+	change, ok := l.changes[changeKey(plan)]
+	if !ok || time.Since(change.appliedAt) > appliedChangeTTL {
+		return nil
+	}
+	digest := changeDigest(plan)
+	// unknown digests match, ie: webhook without digest and poll with one
+	if change.digest != "" && digest != "" && change.digest != digest {
+		return nil
+	}
+	return change
+}
+
+// applied - whether the change of the plan was applied or is being applied
+func (l *changeLedger) applied(plan *UpdatePlan) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.get(plan) != nil
+}
+
+// claim - marks the change as being applied, returns false when it already was
+func (l *changeLedger) claim(plan *UpdatePlan) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.get(plan) != nil {
+		return false
+	}
+	if l.changes == nil {
+		l.changes = make(map[string]*appliedChange)
+	}
+	for key, change := range l.changes {
+		if !change.inProgress && time.Since(change.appliedAt) > appliedChangeTTL {
+			delete(l.changes, key)
+		}
+	}
+	l.changes[changeKey(plan)] = &appliedChange{
+		digest:     changeDigest(plan),
+		appliedAt:  time.Now(),
+		inProgress: true,
+	}
+	return true
+}
+
+// done - update of the claimed change finished, failed changes can be applied again
+func (l *changeLedger) done(plan *UpdatePlan, success bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := changeKey(plan)
+	if !success {
+		delete(l.changes, key)
+		return
+	}
+	if change, ok := l.changes[key]; ok {
+		change.inProgress = false
+		change.appliedAt = time.Now()
+	}
+}
+
+// dropApplied - removes plans of changes that were already applied, so they
+// don't request approvals again after the approval was archived
+func (p *Provider) dropApplied(plans []*UpdatePlan) []*UpdatePlan {
+	var pending []*UpdatePlan
+	for _, plan := range plans {
+		if p.applied.applied(plan) {
+			log.WithFields(log.Fields{
+				"name":            plan.Resource.Name,
+				"kind":            plan.Resource.Kind(),
+				"namespace":       plan.Resource.Namespace,
+				"idempotency_key": idempotencyKey(plan),
+				"trigger":         plan.Trigger,
+			}).Info("provider.kubernetes: change was already applied, ignoring duplicate event")
+			continue
+		}
+		pending = append(pending, plan)
+	}
+	return pending
+}
@@ -0,0 +1,68 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChangeLedger(t *testing.T) {
+	l := &changeLedger{}
+
+	gr := pinnedDeployment(t, nil)
+	webhook := &UpdatePlan{Resource: gr, CurrentVersion: "0.0.14", NewVersion: "0.0.15"}
+	poll := &UpdatePlan{Resource: gr, CurrentVersion: "0.0.14", NewVersion: "0.0.15", eventDigest: "sha256:aaa"}
+
+	if idempotencyKey(poll) != "deployment/xxxx/dep-1/index.docker.io/karolisr/webhook-demo:0.0.15@sha256:aaa" {
+		t.Errorf("unexpected key: %s", idempotencyKey(poll))
+	}
+
+	if !l.claim(poll) {
+		t.Fatalf("expected change to be claimed")
+	}
+	// changes being applied are claimed too
+	if l.claim(poll) {
+		t.Errorf("expected change in progress to be skipped")
+	}
+	l.done(poll, true)
+
+	// digest isn't always reported
+	if !l.applied(webhook) {
+		t.Errorf("expected change without digest to match")
+	}
+	repushed := &UpdatePlan{Resource: gr, CurrentVersion: "0.0.14", NewVersion: "0.0.15", eventDigest: "sha256:bbb"}
+	if l.applied(repushed) {
+		t.Errorf("change with another digest shouldn't match")
+	}
+
+	other := &UpdatePlan{Resource: gr, CurrentVersion: "0.0.14", NewVersion: "0.0.16"}
+	if !l.claim(other) {
+		t.Fatalf("expected change to be claimed")
+	}
+	// failed updates can be applied again
+	l.done(other, false)
+	if l.applied(other) {
+		t.Errorf("failed change shouldn't be remembered")
+	}
+
+	l.changes[changeKey(poll)].appliedAt = time.Now().Add(-2 * appliedChangeTTL)
+	if l.applied(poll) {
+		t.Errorf("expired change shouldn't match")
+	}
+}
+
+func TestDropApplied(t *testing.T) {
+	p := &Provider{}
+
+	gr := pinnedDeployment(t, nil)
+	applied := &UpdatePlan{Resource: gr, CurrentVersion: "0.0.14", NewVersion: "0.0.15"}
+	p.applied.claim(applied)
+	p.applied.done(applied, true)
+
+	plans := p.dropApplied([]*UpdatePlan{
+		{Resource: gr, CurrentVersion: "0.0.14", NewVersion: "0.0.15", eventDigest: "sha256:aaa"},
+		{Resource: gr, CurrentVersion: "0.0.14", NewVersion: "0.0.16"},
+	})
+	if len(plans) != 1 || plans[0].NewVersion != "0.0.16" {
+		t.Errorf("expected only new change to be kept: %v", plans)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	v1beta1 "k8s.io/api/batch/v1beta1"
 	v1 "k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	core_v1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
@@ -35,6 +37,8 @@ type Implementer interface {
 type KubernetesImplementer struct {
 	cfg    *rest.Config
 	client *kubernetes.Clientset
+	// dynamic - client for custom resources such as Argo Rollouts
+	dynamic dynamic.Interface
 }
 
 // Opts - implementer options, usually for k8s deployments
@@ -44,6 +48,8 @@ type Opts struct {
 	InCluster  bool
 	ConfigPath string
 	Master     string
+	// Context - kubeconfig context, defaults to the current context
+	Context string
 }
 
 // NewKubernetesImplementer - create new k8s implementer
@@ -60,6 +66,19 @@ func NewKubernetesImplementer(opts *Opts) (*KubernetesImplementer, error) {
 			return nil, err
 		}
 		log.Info("provider.kubernetes: using in-cluster configuration")
+	} else if opts.ConfigPath != "" && opts.Context != "" {
+		var err error
+		cfg, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: opts.ConfigPath},
+			&clientcmd.ConfigOverrides{CurrentContext: opts.Context},
+		).ClientConfig()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"context": opts.Context,
+			}).Error("provider.kubernetes: failed to get kubernetes config for context")
+			return nil, err
+		}
 	} else if opts.ConfigPath != "" {
 		var err error
 		cfg, err = clientcmd.BuildConfigFromFlags("", opts.ConfigPath)
@@ -81,13 +100,26 @@ func NewKubernetesImplementer(opts *Opts) (*KubernetesImplementer, error) {
 		return nil, err
 	}
 
-	return &KubernetesImplementer{client: client, cfg: cfg}, nil
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("provider.kubernetes: failed to create kubernetes dynamic client")
+		return nil, err
+	}
+
+	return &KubernetesImplementer{client: client, cfg: cfg, dynamic: dynamicClient}, nil
 }
 
 func (i *KubernetesImplementer) Client() *kubernetes.Clientset {
 	return i.client
 }
 
+// Dynamic - client for custom resources
+func (i *KubernetesImplementer) Dynamic() dynamic.Interface {
+	return i.dynamic
+}
+
 func (i *KubernetesImplementer) Config() *rest.Config {
 	return i.cfg
 }
@@ -142,6 +174,15 @@ func (i *KubernetesImplementer) Update(obj *k8s.GenericResource) error {
 		if err != nil {
 			return err
 		}
+	case *unstructured.Unstructured:
+		gvr, ok := k8s.UnstructuredResource(resource)
+		if !ok {
+			return fmt.Errorf("unsupported object kind: %s", resource.GetKind())
+		}
+		_, err := i.dynamic.Resource(gvr).Namespace(resource.GetNamespace()).Update(resource, meta_v1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("unsupported object type")
 	}
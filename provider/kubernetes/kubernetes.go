@@ -3,7 +3,9 @@ package kubernetes
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Masterminds/semver"
@@ -16,7 +18,13 @@ import (
 	"github.com/keel-hq/keel/approvals"
 	"github.com/keel-hq/keel/extension/notification"
 	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/internal/oncall"
 	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/internal/ratelimit"
+	"github.com/keel-hq/keel/internal/tracing"
+	"github.com/keel-hq/keel/internal/vulnscan"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/registry"
 	"github.com/keel-hq/keel/types"
 	"github.com/keel-hq/keel/util/image"
 	"github.com/keel-hq/keel/util/policies"
@@ -56,6 +64,13 @@ type GenericResourceCache interface {
 	// The slice and its contents should be treated as read-only.
 	Values() []*k8s.GenericResource
 
+	// ValuesForImage returns copies of resources that may use the image
+	// repository, events don't have to go through every cached resource.
+	ValuesForImage(repository string) []*k8s.GenericResource
+
+	// Get returns a copy of the resource, nil when it's not cached.
+	Get(identifier string) *k8s.GenericResource
+
 	// Register registers ch to receive a value when Notify is called.
 	Register(chan int, int)
 }
@@ -70,6 +85,53 @@ type UpdatePlan struct {
 	CurrentVersion string
 	// New version that's already in the deployment
 	NewVersion string
+	// Digest - digest updated images are pinned to, only set when digest pinning is enabled
+	Digest string
+	// Signer - signer of the new image, only set when signature verification is enabled
+	Signer string
+	// Trigger - name of the trigger which found the new version, ie: poll or pubsub
+	Trigger string
+
+	// canaryPassed - set once canary analysis of the update passed
+	canaryPassed bool
+	// blueGreenSwitched - set once traffic is switched to the blue/green clone of the update
+	blueGreenSwitched bool
+	// vulnerabilities - findings summary, update requires an approval when it's set
+	vulnerabilities string
+	// provenance - provenance attestation summary, only set when provenance is verified
+	provenance string
+	// provenanceFailure - why provenance verification failed, update requires an approval when it's set
+	provenanceFailure string
+	// soaked and soakSource - time the new version ran in the promotion source, only
+	// set for resources with keel.sh/promoteFrom annotation
+	soaked     string
+	soakSource string
+	// releaseNotes - truncated release notes of the new image, only set when they are fetched
+	releaseNotes string
+	// approval - approval status, only set when the update required approvals
+	approval string
+	// approvalIdentifier - identifier of the approval request, rollout progress is reported back to it
+	approvalIdentifier string
+	// approvers - voters of the approval
+	approvers []string
+	// containers - names of the updated containers
+	containers []string
+	// envVars - updated env var images, <container>/<env var>
+	envVars []string
+	// configMaps - ConfigMap keys that are updated with the new images
+	configMaps []configMapImage
+	// attempts and retryBackoff - failed attempts of the update and backoff before
+	// the next one, updates are retried on transient API errors
+	attempts     int
+	retryBackoff time.Duration
+	// previousDigest - digest the updated containers were pinned to before the update
+	previousDigest string
+	// eventDigest - digest of the new version reported by the trigger
+	eventDigest string
+	// startedAt - time of the new version event, used to find out update duration
+	startedAt time.Time
+	// traceParent - trace context of the event processing
+	traceParent string
 }
 
 func (p *UpdatePlan) String() string {
@@ -79,6 +141,45 @@ func (p *UpdatePlan) String() string {
 	return "empty plan"
 }
 
+// planMetadata - notification metadata of the update, senders can render
+// versions, images, trigger and approval status
+func (p *Provider) planMetadata(plan *UpdatePlan) map[string]string {
+	metadata := map[string]string{
+		"provider":        p.GetName(),
+		"namespace":       plan.Resource.GetNamespace(),
+		"name":            plan.Resource.GetName(),
+		"previousVersion": plan.CurrentVersion,
+		"newVersion":      plan.NewVersion,
+		"images":          strings.Join(plan.Resource.GetImages(), ","),
+	}
+	if plan.Trigger != "" {
+		metadata["trigger"] = plan.Trigger
+	}
+	if plan.approval != "" {
+		metadata["approval"] = plan.approval
+		metadata["approvalIdentifier"] = plan.approvalIdentifier
+	}
+	if len(plan.approvers) > 0 {
+		metadata["approvers"] = strings.Join(plan.approvers, ",")
+	}
+	annotations := plan.Resource.GetAnnotations()
+	if repo := annotations[types.KeelGithubRepositoryAnnotation]; repo != "" {
+		metadata["githubRepository"] = repo
+		metadata["githubEnvironment"] = annotations[types.KeelGithubEnvironmentAnnotation]
+	}
+	if issue := annotations[types.KeelJiraIssueAnnotation]; issue != "" {
+		metadata["jiraIssue"] = issue
+	}
+	if plan.soaked != "" {
+		metadata["soaked"] = plan.soaked
+		metadata["soakSource"] = plan.soakSource
+	}
+	if plan.releaseNotes != "" {
+		metadata["releaseNotes"] = plan.releaseNotes
+	}
+	return metadata
+}
+
 // Provider - kubernetes provider for auto update
 type Provider struct {
 	implementer Implementer
@@ -89,30 +190,116 @@ type Provider struct {
 
 	cache GenericResourceCache
 
+	// store is optional, used to check paused resources
+	store store.Store
+
+	// cluster - cluster name, only set when keel manages several clusters
+	cluster string
+
+	// canary is optional, used to verify updates of resources with canary annotations
+	canary CanaryController
+
+	// blueGreen is optional, used to switch traffic of resources with keel.sh/blueGreenService annotation
+	blueGreen BlueGreenController
+
+	// approvalsChannels - chat channels of approval requests, rollbacks are reported there too
+	approvalsChannels []string
+
+	// registryClient is optional, used to resolve digests when images are pinned
+	registryClient registry.Client
+
+	// pullPreflight - whether updated images are checked before resources are updated
+	pullPreflight bool
+
+	// digestRestart - whether pods are restarted when only digest of the tag changes
+	digestRestart bool
+
+	// pinDeletedTags - whether images are pinned to the digest of their tag once it's deleted from the registry
+	pinDeletedTags bool
+
+	// signatureVerifier is optional, used to verify images of resources with keel.sh/verifySignature
+	signatureVerifier SignatureVerifier
+
+	// scanner is optional, used to check new images of resources with keel.sh/vulnerabilitySeverity
+	scanner vulnscan.Scanner
+
+	// provenanceVerifier is optional, used to check new images of resources with keel.sh/verifyProvenance
+	provenanceVerifier ProvenanceVerifier
+
+	// releaseNotesFetcher is optional, release notes of new images are added to approval requests and notifications
+	releaseNotesFetcher ReleaseNotesFetcher
+
+	// rollouts - rollouts in progress, used by ordered updates
+	rollouts rolloutTracker
+
+	// postponed - images of events that are submitted again once they reach minimum age
+	postponed sync.Map
+
+	// maintenanceReported - versions reported in maintenance mode, map[identifier]<maintenance ID>/<version>
+	maintenanceReported sync.Map
+
+	// oncallResolver is optional, used to mention on-call users of keel.sh/oncallSchedule in approval requests
+	oncallResolver oncall.Resolver
+
+	// freeze is optional, resources matching the freeze selector are treated as paused
+	freeze *freeze
+
+	// scope is optional, resources out of scope are never tracked or updated
+	scope *Scope
+
+	// namespaceCache - namespace labels used by freeze and scope selectors
+	namespaceCache namespaceCache
+
+	// rateLimiter is optional, plans over the update budgets are queued
+	rateLimiter *ratelimit.Limiter
+
+	// applied - recently applied changes, duplicate events of the same change are ignored
+	applied changeLedger
+
+	// updateAttempts - attempts of updates failing with transient API errors, see SetUpdateAttempts
+	updateAttempts int
+
 	events chan *types.Event
 	stop   chan struct{}
+
+	// draining - closed once Drain is called, drained - closed once queued events are processed
+	draining  chan struct{}
+	drained   chan struct{}
+	drainOnce sync.Once
+	// inFlight - updates in the background, see background
+	inFlight sync.WaitGroup
 }
 
 // NewProvider - create new kubernetes based provider
-func NewProvider(implementer Implementer, sender notification.Sender, approvalManager approvals.Manager, cache GenericResourceCache) (*Provider, error) {
+func NewProvider(implementer Implementer, sender notification.Sender, approvalManager approvals.Manager, cache GenericResourceCache, store store.Store) (*Provider, error) {
 	return &Provider{
 		implementer:     implementer,
 		cache:           cache,
+		store:           store,
 		approvalManager: approvalManager,
 		events:          make(chan *types.Event, 100),
 		stop:            make(chan struct{}),
+		draining:        make(chan struct{}),
+		drained:         make(chan struct{}),
 		sender:          sender,
 	}, nil
 }
 
 // Submit - submit event to provider
 func (p *Provider) Submit(event types.Event) error {
+	if p.isDraining() {
+		return ErrDraining
+	}
 	p.events <- &event
 	return nil
 }
 
-// GetName - get provider name
+// GetName - get provider name, cluster name is included when keel manages several
+// clusters so that providers of each cluster are registered
 func (p *Provider) GetName() string {
+	if p.cluster != "" {
+		return ProviderName + "/" + p.cluster
+	}
 	return ProviderName
 }
 
@@ -145,6 +332,25 @@ func getImagePullSecretFromMeta(labels map[string]string, annotations map[string
 	return ""
 }
 
+// containerPollSchedule - returns container specific poll schedule if it's set
+// through keel.sh/pollSchedule.<container name> annotation
+func containerPollSchedule(annotations map[string]string, container, defaultSchedule string) string {
+	schedule, ok := annotations[types.KeelPollScheduleContainerAnnotationPrefix+container]
+	if !ok {
+		return defaultSchedule
+	}
+	_, err := cron.Parse(schedule)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"schedule":  schedule,
+			"container": container,
+		}).Error("provider.kubernetes: failed to parse container poll schedule, using resource schedule")
+		return defaultSchedule
+	}
+	return schedule
+}
+
 // TrackedImages returns a list of tracked images.
 func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 	var trackedImages []*types.TrackedImage
@@ -154,8 +360,8 @@ func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 		annotations := gr.GetAnnotations()
 
 		// ignoring unlabelled deployments
-		plc := policy.GetPolicyFromLabelsOrAnnotations(labels, annotations)
-		if plc.Type() == policy.PolicyTypeNone {
+		plc := p.resourcePolicy(gr)
+		if plc.Type() == policy.PolicyTypeNone || !p.inScope(gr) {
 			continue
 		}
 
@@ -169,10 +375,10 @@ func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 					"name":      gr.Name,
 					"namespace": gr.Namespace,
 				}).Error("provider.kubernetes: failed to parse poll schedule, setting default schedule")
-				schedule = types.KeelPollDefaultSchedule
+				schedule = types.DefaultPollSchedule()
 			}
 		} else {
-			schedule = types.KeelPollDefaultSchedule
+			schedule = types.DefaultPollSchedule()
 		}
 
 		// trigger type, we only care for "poll" type triggers
@@ -186,8 +392,11 @@ func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 		}
 		secrets = append(secrets, gr.GetImagePullSecrets()...)
 
-		images := gr.GetImages()
-		for _, img := range images {
+		paused, freezeReason := p.isPausedOrFrozen(gr)
+
+		policies := resourceContainerPolicies(gr)
+
+		track := func(img, pollSchedule string, plc policy.Policy) {
 			ref, err := image.Parse(img)
 			if err != nil {
 				log.WithFields(log.Fields{
@@ -196,7 +405,7 @@ func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 					"namespace": gr.Namespace,
 					"name":      gr.Name,
 				}).Error("provider.kubernetes: failed to parse image")
-				continue
+				return
 			}
 			svp := make(map[string]string)
 
@@ -207,16 +416,34 @@ func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 				}
 			}
 
-			trackedImages = append(trackedImages, &types.TrackedImage{
+			trackedImage := &types.TrackedImage{
 				Image:        ref,
-				PollSchedule: schedule,
+				PollSchedule: pollSchedule,
 				Trigger:      trigger,
 				Provider:     ProviderName,
 				Namespace:    gr.Namespace,
 				Secrets:      secrets,
-				Meta:         make(map[string]string),
+				Meta: map[string]string{
+					"name": gr.Name,
+					"kind": gr.Kind(),
+				},
 				Policy:       plc,
-			})
+				Paused:       paused,
+				FreezeReason: freezeReason,
+			}
+			if p.cluster != "" {
+				trackedImage.Meta["cluster"] = p.cluster
+			}
+			trackedImages = append(trackedImages, trackedImage)
+		}
+
+		for _, container := range resourceContainers(gr) {
+			track(container.Image, containerPollSchedule(annotations, container.Name, schedule), policies.get(plc, container.Name))
+		}
+
+		// images referenced by env vars and ConfigMaps (keel.sh/envImages, keel.sh/configMapImages)
+		for _, img := range p.referencedImages(gr) {
+			track(img, schedule, plc)
 		}
 	}
 
@@ -227,14 +454,11 @@ func (p *Provider) startInternal() error {
 	for {
 		select {
 		case event := <-p.events:
-			_, err := p.processEvent(event)
-			if err != nil {
-				log.WithFields(log.Fields{
-					"error": err,
-					"image": event.Repository.Name,
-					"tag":   event.Repository.Tag,
-				}).Error("provider.kubernetes: failed to process event")
-			}
+			p.handleEvent(event)
+		case <-p.draining:
+			p.processQueued()
+			close(p.drained)
+			return nil
 		case <-p.stop:
 			log.Info("provider.kubernetes: got shutdown signal, stopping...")
 			return nil
@@ -242,8 +466,44 @@ func (p *Provider) startInternal() error {
 	}
 }
 
+func (p *Provider) handleEvent(event *types.Event) {
+	_, err := p.processEvent(event)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"image": event.Repository.Name,
+			"tag":   event.Repository.Tag,
+		}).Error("provider.kubernetes: failed to process event")
+	}
+}
+
 func (p *Provider) processEvent(event *types.Event) (updated []*k8s.GenericResource, err error) {
-	plans, err := p.createUpdatePlans(&event.Repository)
+	// chart events are only supported by helm provider
+	if event.Chart {
+		return nil, nil
+	}
+
+	if event.Deleted {
+		p.tagDeleted(event)
+		return nil, nil
+	}
+
+	span := tracing.Start(event.TraceParent, "provider.kubernetes.processEvent")
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	policySpan := tracing.Start(span.TraceParent(), "policy.evaluate")
+	var plans []*UpdatePlan
+	if event.Target != "" {
+		plans, err = p.createTargetedUpdatePlans(event)
+	} else {
+		plans, err = p.createUpdatePlans(&event.Repository)
+	}
+	policySpan.SetAttribute("plans", strconv.Itoa(len(plans)))
+	policySpan.SetError(err)
+	policySpan.End()
 	if err != nil {
 		return nil, err
 	}
@@ -256,7 +516,54 @@ func (p *Provider) processEvent(event *types.Event) (updated []*k8s.GenericResou
 		return
 	}
 
+	startedAt := event.CreatedAt
+	if startedAt.IsZero() {
+		startedAt = time.Now()
+	}
+	for _, plan := range plans {
+		plan.Trigger = event.TriggerName
+		plan.eventDigest = event.Repository.Digest
+		plan.startedAt = startedAt
+		plan.traceParent = span.TraceParent()
+	}
+
+	// explicitly requested updates of single workloads are still applied
+	if paused := store.Maintenance(p.store); paused != nil && event.Target == "" {
+		p.maintenanceUpdates(plans, paused)
+		return nil, nil
+	}
+
+	plans = p.pinDigests(event, plans)
+
+	plans = p.dropApplied(plans)
+
+	plans = p.checkMinimumAge(event, plans)
+
+	plans = p.checkSoak(event, plans)
+
+	plans = p.verifySignatures(event, plans)
+
+	plans = p.scanVulnerabilities(event, plans)
+
+	plans = p.verifyProvenance(event, plans)
+
+	plans = p.fetchReleaseNotes(event, plans)
+
+	approvalsSpan := tracing.Start(span.TraceParent(), "approvals.check")
 	approvedPlans := p.checkForApprovals(event, plans)
+	approvalsSpan.SetAttribute("plans", strconv.Itoa(len(plans)))
+	approvalsSpan.SetAttribute("approved", strconv.Itoa(len(approvedPlans)))
+	approvalsSpan.End()
+
+	approvedPlans = p.checkUpdateWindows(event, approvedPlans)
+
+	approvedPlans = p.checkRateLimit(event, approvedPlans)
+
+	// ordered updates wait for rollouts, they are applied in the background
+	if p.isOrdered(approvedPlans) {
+		p.background(func() { p.updateInOrder(approvedPlans) })
+		return nil, nil
+	}
 
 	return p.updateDeployments(approvedPlans)
 }
@@ -269,6 +576,64 @@ func (p *Provider) updateDeployments(plans []*UpdatePlan) (updated []*k8s.Generi
 
 		notificationChannels := types.ParseEventNotificationChannels(annotations)
 
+		if dryRunEnabled(annotations) {
+			p.dryRunUpdate(plan, notificationChannels)
+			continue
+		}
+
+		// pods of the resource would be stuck in ImagePullBackOff
+		if p.pullPreflightEnabled(annotations) {
+			if err := p.checkPull(plan); err != nil {
+				log.WithFields(log.Fields{
+					"error":     err,
+					"name":      resource.Name,
+					"kind":      resource.Kind(),
+					"namespace": resource.Namespace,
+				}).Error("provider.kubernetes: pull pre-flight check failed, resource won't be updated")
+
+				p.recordUpdate(plan, err)
+
+				p.sender.Send(types.EventNotification{
+					Name:         "update resource",
+					ResourceKind: resource.Kind(),
+					Identifier:   resource.Identifier,
+					Message:      fmt.Sprintf("%s %s/%s update %s->%s aborted, pull pre-flight check failed: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, err),
+					CreatedAt:    time.Now(),
+					Type:         types.NotificationDeploymentUpdate,
+					Level:        types.LevelError,
+					Channels:     notificationChannels,
+					Metadata:     p.planMetadata(plan),
+				})
+				continue
+			}
+		}
+
+		// resource is updated once canary analysis passes
+		if p.canary != nil && !plan.canaryPassed && p.canary.Enabled(resource) {
+			plan := plan
+			p.background(func() { p.runCanary(plan, notificationChannels) })
+			continue
+		}
+
+		// resource is updated once traffic is switched to the new version
+		if p.blueGreen != nil && !plan.blueGreenSwitched && p.blueGreen.Enabled(resource) {
+			plan := plan
+			p.background(func() { p.runBlueGreen(plan, notificationChannels) })
+			continue
+		}
+
+		// the same change can be planned by several events before the cache
+		// reflects the update, ie: webhook and poll reporting the same tag
+		if !p.applied.claim(plan) {
+			log.WithFields(log.Fields{
+				"name":            resource.Name,
+				"kind":            resource.Kind(),
+				"namespace":       resource.Namespace,
+				"idempotency_key": idempotencyKey(plan),
+			}).Info("provider.kubernetes: change is already applied, skipping duplicate update")
+			continue
+		}
+
 		p.sender.Send(types.EventNotification{
 			ResourceKind: resource.Kind(),
 			Identifier:   resource.Identifier,
@@ -278,13 +643,40 @@ func (p *Provider) updateDeployments(plans []*UpdatePlan) (updated []*k8s.Generi
 			Type:         types.NotificationPreDeploymentUpdate,
 			Level:        types.LevelDebug,
 			Channels:     notificationChannels,
-			Metadata: map[string]string{
-				"provider":  p.GetName(),
-				"namespace": resource.GetNamespace(),
-				"name":      resource.GetName(),
-			},
+			Metadata:     p.planMetadata(plan),
 		})
 
+		// ConfigMaps are updated first so restarted pods read the new images
+		if err := p.updateConfigMaps(plan); err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"name":      resource.Name,
+				"kind":      resource.Kind(),
+				"namespace": resource.Namespace,
+			}).Error("provider.kubernetes: got error while updating ConfigMap images")
+
+			p.applied.done(plan, false)
+			p.recordUpdate(plan, err)
+
+			p.sender.Send(types.EventNotification{
+				Name:         "update resource",
+				ResourceKind: resource.Kind(),
+				Identifier:   resource.Identifier,
+				Message:      fmt.Sprintf("%s %s/%s update %s->%s failed, error: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, err),
+				CreatedAt:    time.Now(),
+				Type:         types.NotificationDeploymentUpdate,
+				Level:        types.LevelError,
+				Channels:     notificationChannels,
+				Metadata:     p.planMetadata(plan),
+			})
+			continue
+		}
+		if plan.configMapsOnly() {
+			p.applied.done(plan, true)
+			p.configMapsUpdated(plan, notificationChannels)
+			continue
+		}
+
 		var err error
 
 		timestamp := time.Now().Format(time.RFC3339)
@@ -292,7 +684,34 @@ func (p *Provider) updateDeployments(plans []*UpdatePlan) (updated []*k8s.Generi
 
 		resource.SetAnnotations(annotations)
 
-		err = p.implementer.Update(resource)
+		// spec of mutable tags doesn't change when only the digest does
+		if plan.digestOnly() && p.digestRestartEnabled(annotations) {
+			restartForDigest(plan)
+		}
+
+		if percent, ok := trafficPercent(annotations); ok {
+			err = resource.ShiftTraffic(percent)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":     err,
+					"name":      resource.Name,
+					"kind":      resource.Kind(),
+					"namespace": resource.Namespace,
+				}).Warn("provider.kubernetes: failed to shift traffic, using default routing")
+			}
+		}
+
+		// generation before the update, used to find out when the update is observed
+		generation := resource.GetGeneration()
+
+		updateSpan := tracing.Start(plan.traceParent, "kubernetes.update")
+		updateSpan.SetAttribute("kind", resource.Kind())
+		updateSpan.SetAttribute("namespace", resource.Namespace)
+		updateSpan.SetAttribute("name", resource.Name)
+		updateSpan.SetAttribute("version", plan.NewVersion)
+		err = p.update(resource)
+		updateSpan.SetError(err)
+		updateSpan.End()
 		kubernetesVersionedUpdatesCounter.With(prometheus.Labels{"kubernetes": fmt.Sprintf("%s/%s", resource.Namespace, resource.Name)}).Inc()
 		if err != nil {
 			log.WithFields(log.Fields{
@@ -303,25 +722,37 @@ func (p *Provider) updateDeployments(plans []*UpdatePlan) (updated []*k8s.Generi
 				"update":     fmt.Sprintf("%s->%s", plan.CurrentVersion, plan.NewVersion),
 			}).Error("provider.kubernetes: got error while updating resource")
 
+			// failure is reported once transient errors run out of attempts
+			if p.retryUpdate(plan, err) {
+				continue
+			}
+
+			p.applied.done(plan, false)
+			p.recordUpdate(plan, err)
+
+			msg := fmt.Sprintf("%s %s/%s update %s->%s failed, error: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, err)
+			if plan.attempts > 1 {
+				msg += fmt.Sprintf(" (%d attempts)", plan.attempts)
+			}
+
 			p.sender.Send(types.EventNotification{
 				Name:         "update resource",
 				ResourceKind: resource.Kind(),
 				Identifier:   resource.Identifier,
-				Message:      fmt.Sprintf("%s %s/%s update %s->%s failed, error: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, err),
+				Message:      msg,
 				CreatedAt:    time.Now(),
 				Type:         types.NotificationDeploymentUpdate,
 				Level:        types.LevelError,
 				Channels:     notificationChannels,
-				Metadata: map[string]string{
-					"provider":  p.GetName(),
-					"namespace": resource.GetNamespace(),
-					"name":      resource.GetName(),
-				},
+				Metadata:     p.planMetadata(plan),
 			})
 
 			continue
 		}
 
+		p.applied.done(plan, true)
+		p.recordUpdate(plan, nil)
+
 		err = p.updateComplete(plan)
 		if err != nil {
 			log.WithFields(log.Fields{
@@ -332,29 +763,10 @@ func (p *Provider) updateDeployments(plans []*UpdatePlan) (updated []*k8s.Generi
 			}).Warn("provider.kubernetes: got error while resetting approvals counter after successful update")
 		}
 
-		var msg string
-		releaseNotes := types.ParseReleaseNotesURL(resource.GetAnnotations())
-		if releaseNotes != "" {
-			msg = fmt.Sprintf("Successfully updated %s %s/%s %s->%s (%s). Release notes: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(resource.GetImages(), ", "), releaseNotes)
-		} else {
-			msg = fmt.Sprintf("Successfully updated %s %s/%s %s->%s (%s)", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(resource.GetImages(), ", "))
-		}
-
-		p.sender.Send(types.EventNotification{
-			ResourceKind: resource.Kind(),
-			Identifier:   resource.Identifier,
-			Name:         "update resource",
-			Message:      msg,
-			CreatedAt:    time.Now(),
-			Type:         types.NotificationDeploymentUpdate,
-			Level:        types.LevelSuccess,
-			Channels:     notificationChannels,
-			Metadata: map[string]string{
-				"provider":  p.GetName(),
-				"namespace": resource.GetNamespace(),
-				"name":      resource.GetName(),
-			},
-		})
+		// success (or failure) notification is sent once the rollout finishes
+		p.rollouts.start(resource)
+		plan := plan
+		p.background(func() { p.trackRollout(plan, generation, notificationChannels) })
 
 		log.WithFields(log.Fields{
 			"name":      resource.Name,
@@ -395,17 +807,29 @@ func getDesiredImage(delta map[string]string, currentImage string) (string, erro
 func (p *Provider) createUpdatePlans(repo *types.Repository) ([]*UpdatePlan, error) {
 	impacted := []*UpdatePlan{}
 
-	for _, resource := range p.cache.Values() {
+	eventRepoRef, err := image.Parse(repo.String())
+	if err != nil {
+		return nil, err
+	}
 
-		labels := resource.GetLabels()
-		annotations := resource.GetAnnotations()
+	for _, resource := range p.cache.ValuesForImage(eventRepoRef.Repository()) {
 
-		plc := policy.GetPolicyFromLabelsOrAnnotations(labels, annotations)
-		if plc.Type() == policy.PolicyTypeNone {
+		plc := p.resourcePolicy(resource)
+		if plc.Type() == policy.PolicyTypeNone || !p.inScope(resource) {
 			continue
 		}
 
-		updated, shouldUpdateDeployment, err := checkForUpdate(plc, repo, resource)
+		if paused, reason := p.isPausedOrFrozen(resource); paused {
+			log.WithFields(log.Fields{
+				"name":      resource.Name,
+				"kind":      resource.Kind(),
+				"namespace": resource.Namespace,
+				"freeze":    reason,
+			}).Debug("provider.kubernetes: resource is paused, skipping")
+			continue
+		}
+
+		updated, shouldUpdateDeployment, err := checkForContainerUpdates(plc, resourceContainerPolicies(resource), repo, resource)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"error":      err,
@@ -416,6 +840,67 @@ func (p *Provider) createUpdatePlans(repo *types.Repository) ([]*UpdatePlan, err
 			continue
 		}
 
+		if p.planConfigMapUpdates(plc, repo, resource, updated) {
+			shouldUpdateDeployment = true
+		}
+
+		if !shouldUpdateDeployment {
+			continue
+		}
+
+		if p.isRolledBack(updated) {
+			log.WithFields(log.Fields{
+				"name":      resource.Name,
+				"kind":      resource.Kind(),
+				"namespace": resource.Namespace,
+				"version":   updated.NewVersion,
+			}).Info("provider.kubernetes: version was rolled back before, skipping")
+			continue
+		}
+
+		impacted = append(impacted, updated)
+	}
+
+	return impacted, nil
+}
+
+// createTargetedUpdatePlans - update plans for events that target a single workload,
+// policy is not checked as the version was explicitly requested
+func (p *Provider) createTargetedUpdatePlans(event *types.Event) ([]*UpdatePlan, error) {
+	impacted := []*UpdatePlan{}
+
+	eventRepoRef, err := image.Parse(event.Repository.String())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, resource := range p.cache.ValuesForImage(eventRepoRef.Repository()) {
+		if fmt.Sprintf("%s/%s", resource.Namespace, resource.Name) != event.Target {
+			continue
+		}
+
+		// only tracked resources can be updated
+		plc := p.resourcePolicy(resource)
+		if plc.Type() == policy.PolicyTypeNone || !p.inScope(resource) {
+			continue
+		}
+
+		forcePolicy := policy.NewForcePolicy(false)
+		updated, shouldUpdateDeployment, err := checkForUpdate(forcePolicy, &event.Repository, resource)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"name":      resource.Name,
+				"kind":      resource.Kind(),
+				"namespace": resource.Namespace,
+			}).Error("provider.kubernetes: got error while checking targeted resource")
+			continue
+		}
+
+		if p.planConfigMapUpdates(forcePolicy, &event.Repository, resource, updated) {
+			shouldUpdateDeployment = true
+		}
+
 		if shouldUpdateDeployment {
 			impacted = append(impacted, updated)
 		}
@@ -424,6 +909,15 @@ func (p *Provider) createUpdatePlans(repo *types.Repository) ([]*UpdatePlan, err
 	return impacted, nil
 }
 
+// isPaused - checks whether automatic updates were paused for the resource
+func (p *Provider) isPaused(namespace, name string) bool {
+	if p.store == nil {
+		return false
+	}
+	_, err := p.store.GetPausedResource(types.PausedIdentifier(namespace, name))
+	return err == nil
+}
+
 func (p *Provider) namespaces() (*v1.NamespaceList, error) {
 	return p.implementer.Namespaces()
 }
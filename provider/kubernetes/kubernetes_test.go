@@ -1,12 +1,14 @@
 package kubernetes
 
 import (
+	"fmt"
+	"sync/atomic"
 	"testing"
 
 	"github.com/keel-hq/keel/approvals"
-	"github.com/keel-hq/keel/cache/memory"
 	"github.com/keel-hq/keel/extension/notification"
 	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/pkg/store/sql"
 	"github.com/keel-hq/keel/types"
 
 	apps_v1 "k8s.io/api/apps/v1"
@@ -94,6 +96,27 @@ func (i *fakeImplementer) ConfigMaps(namespace string) core_v1.ConfigMapInterfac
 	return nil
 }
 
+// rolloutImplementer - updated deployments are rolled out right away, the cache
+// is updated like watchers would update it
+type rolloutImplementer struct {
+	*fakeImplementer
+	cache *k8s.GenericResourceCache
+}
+
+func (i *rolloutImplementer) Update(obj *k8s.GenericResource) error {
+	rolledOut := obj.DeepCopy()
+	d := rolledOut.GetResource().(*apps_v1.Deployment)
+	d.Generation++
+	d.Status = apps_v1.DeploymentStatus{
+		ObservedGeneration: d.Generation,
+		Replicas:           1,
+		UpdatedReplicas:    1,
+		AvailableReplicas:  1,
+	}
+	i.cache.Add(rolledOut)
+	return i.fakeImplementer.Update(obj)
+}
+
 type fakeSender struct {
 	sentEvent types.EventNotification
 }
@@ -107,9 +130,16 @@ func (s *fakeSender) Send(event types.EventNotification) error {
 	return nil
 }
 
+var approverDatabases int32
+
+// approver - approvals manager with its own in-memory sqlite store
 func approver() *approvals.DefaultManager {
-	cache := memory.NewMemoryCache()
-	return approvals.New(cache)
+	uri := fmt.Sprintf("file:approver%d?mode=memory&cache=shared", atomic.AddInt32(&approverDatabases, 1))
+	store, err := sql.New(sql.Opts{DatabaseType: sql.DatabaseTypeSQLite, URI: uri})
+	if err != nil {
+		panic(err)
+	}
+	return approvals.New(&approvals.Opts{Store: store})
 }
 
 func TestGetNamespaces(t *testing.T) {
@@ -128,7 +158,7 @@ func TestGetNamespaces(t *testing.T) {
 
 	grc := &k8s.GenericResourceCache{}
 
-	provider, err := NewProvider(fi, &fakeSender{}, approver(), grc)
+	provider, err := NewProvider(fi, &fakeSender{}, approver(), grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -232,7 +262,7 @@ func TestGetImpacted(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -328,7 +358,7 @@ func TestGetImpactedPolicyAnnotations(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -428,7 +458,7 @@ func TestPrereleaseGetImpactedA(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -518,7 +548,7 @@ func TestPrereleaseGetImpactedB(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -645,7 +675,7 @@ func TestProcessEvent(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -710,7 +740,7 @@ func TestProcessEventBuildNumber(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -772,7 +802,7 @@ func TestEventSent(t *testing.T) {
 	grc.Add(grs...)
 
 	fs := &fakeSender{}
-	provider, err := NewProvider(fp, fs, approver(), grc)
+	provider, err := NewProvider(&rolloutImplementer{fakeImplementer: fp, cache: grc}, fs, approver(), grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -787,13 +817,15 @@ func TestEventSent(t *testing.T) {
 	if err != nil {
 		t.Errorf("got error while processing event: %s", err)
 	}
+	// success notification is sent once the rollout finishes
+	provider.inFlight.Wait()
 
 	if fp.updated.Containers()[0].Image != repo.Name+":"+repo.Tag {
 		t.Errorf("expected to find a deployment with updated image but found: %s", fp.updated.Containers()[0].Image)
 	}
 
-	if fs.sentEvent.Message != "Successfully updated deployment xxxx/deployment-1 10.0.0->11.0.0 (gcr.io/v2-namespace/hello-world:11.0.0)" {
-		t.Errorf("expected 'Successfully updated deployment xxxx/deployment-1 10.0.0->11.0.0 (gcr.io/v2-namespace/hello-world:11.0.0)' sent message, got: %s", fs.sentEvent.Message)
+	if fs.sentEvent.Message != "Successfully updated deployment xxxx/deployment-1 10.0.0->11.0.0 (gcr.io/v2-namespace/hello-world:11.0.0), successfully rolled out" {
+		t.Errorf("expected 'Successfully updated deployment xxxx/deployment-1 10.0.0->11.0.0 (gcr.io/v2-namespace/hello-world:11.0.0), successfully rolled out' sent message, got: %s", fs.sentEvent.Message)
 	}
 }
 
@@ -838,7 +870,7 @@ func TestEventSentWithReleaseNotes(t *testing.T) {
 	grc.Add(grs...)
 
 	fs := &fakeSender{}
-	provider, err := NewProvider(fp, fs, approver(), grc)
+	provider, err := NewProvider(&rolloutImplementer{fakeImplementer: fp, cache: grc}, fs, approver(), grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -853,13 +885,15 @@ func TestEventSentWithReleaseNotes(t *testing.T) {
 	if err != nil {
 		t.Errorf("got error while processing event: %s", err)
 	}
+	// success notification is sent once the rollout finishes
+	provider.inFlight.Wait()
 
 	if fp.updated.Containers()[0].Image != repo.Name+":"+repo.Tag {
 		t.Errorf("expected to find a deployment with updated image but found: %s", fp.updated.Containers()[0].Image)
 	}
 
-	if fs.sentEvent.Message != "Successfully updated deployment xxxx/deployment-1 10.0.0->11.0.0 (gcr.io/v2-namespace/hello-world:11.0.0). Release notes: https://github.com/keel-hq/keel/releases" {
-		t.Errorf("expected 'Successfully updated deployment xxxx/deployment-1 10.0.0->11.0.0 (gcr.io/v2-namespace/hello-world:11.0.0). Release notes: https://github.com/keel-hq/keel/releases' sent message, got: %s", fs.sentEvent.Message)
+	if fs.sentEvent.Message != "Successfully updated deployment xxxx/deployment-1 10.0.0->11.0.0 (gcr.io/v2-namespace/hello-world:11.0.0), successfully rolled out. Release notes: https://github.com/keel-hq/keel/releases" {
+		t.Errorf("expected 'Successfully updated deployment xxxx/deployment-1 10.0.0->11.0.0 (gcr.io/v2-namespace/hello-world:11.0.0), successfully rolled out. Release notes: https://github.com/keel-hq/keel/releases' sent message, got: %s", fs.sentEvent.Message)
 	}
 }
 
@@ -926,7 +960,7 @@ func TestGetImpactedTwoContainersInSameDeployment(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -1027,7 +1061,7 @@ func TestGetImpactedTwoSameContainersInSameDeployment(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -1123,7 +1157,7 @@ func TestGetImpactedUntaggedImage(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -1220,7 +1254,7 @@ func TestGetImpactedUntaggedOneImage(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -1302,7 +1336,7 @@ func TestTrackedImages(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -1367,7 +1401,7 @@ func TestTrackedImagesWithSecrets(t *testing.T) {
 	grc := &k8s.GenericResourceCache{}
 	grc.Add(grs...)
 
-	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc)
+	provider, err := NewProvider(fp, &fakeSender{}, approver(), grc, nil)
 	if err != nil {
 		t.Fatalf("failed to get provider: %s", err)
 	}
@@ -0,0 +1,51 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maintenanceUpdates - reports updates that would have been applied if
+// maintenance mode wasn't enabled, each version is reported once per
+// maintenance window. Approvals are not requested, plans are created again
+// once maintenance mode ends.
+func (p *Provider) maintenanceUpdates(plans []*UpdatePlan, paused *types.PausedResource) {
+	for _, plan := range plans {
+		resource := plan.Resource
+
+		if reported, ok := p.maintenanceReported.Load(resource.Identifier); ok && reported == paused.ID+"/"+plan.NewVersion {
+			continue
+		}
+		p.maintenanceReported.Store(resource.Identifier, paused.ID+"/"+plan.NewVersion)
+
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"previous":  plan.CurrentVersion,
+			"new":       plan.NewVersion,
+			"namespace": resource.Namespace,
+		}).Info("provider.kubernetes: maintenance mode, resource not updated")
+
+		p.sender.Send(types.EventNotification{
+			Name:         "maintenance mode",
+			ResourceKind: resource.Kind(),
+			Identifier:   resource.Identifier,
+			Message:      fmt.Sprintf("Maintenance mode (enabled by %s): would have updated %s %s/%s %s->%s (%s)", paused.User, resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(resource.GetImages(), ", ")),
+			CreatedAt:    time.Now(),
+			Type:         types.NotificationDryRunUpdate,
+			Level:        types.LevelInfo,
+			Channels:     types.ParseEventNotificationChannels(resource.GetAnnotations()),
+			Metadata: map[string]string{
+				"provider":    p.GetName(),
+				"namespace":   resource.GetNamespace(),
+				"name":        resource.GetName(),
+				"maintenance": "true",
+			},
+		})
+	}
+}
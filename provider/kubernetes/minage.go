@@ -0,0 +1,134 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// minimumAge - minimum age of the new image from keel.sh/minimumAge annotation
+func minimumAge(annotations map[string]string) (time.Duration, bool) {
+	value, ok := annotations[types.KeelMinimumAgeAnnotation]
+	if !ok {
+		return 0, false
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"minimum": value,
+		}).Warn("provider.kubernetes: invalid minimum image age, ignoring")
+		return 0, false
+	}
+	return d, true
+}
+
+// resourceMinimumAge - minimum image age required by the resource policy
+// (ie: "semver:minor AND minimumAge:1h") or keel.sh/minimumAge annotation
+func (p *Provider) resourceMinimumAge(resource *k8s.GenericResource) (time.Duration, bool) {
+	if plc, ok := p.resourcePolicy(resource).(interface {
+		MinimumAge() (time.Duration, bool)
+	}); ok {
+		if d, ok := plc.MinimumAge(); ok {
+			return d, true
+		}
+	}
+	return minimumAge(resource.GetAnnotations())
+}
+
+// checkMinimumAge - holds back plans of resources with minimum image age until the
+// image is old enough, the event is submitted again once it is. Plans of images
+// which creation time can't be found are dropped.
+func (p *Provider) checkMinimumAge(event *types.Event, plans []*UpdatePlan) []*UpdatePlan {
+	var created time.Time
+	var retry time.Duration
+
+	var ready []*UpdatePlan
+	for _, plan := range plans {
+		resource := plan.Resource
+		age, ok := p.resourceMinimumAge(resource)
+		if !ok {
+			ready = append(ready, plan)
+			continue
+		}
+
+		if created.IsZero() {
+			var err error
+			created, err = p.imageCreated(resource, &event.Repository)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":     err,
+					"name":      resource.Name,
+					"kind":      resource.Kind(),
+					"namespace": resource.Namespace,
+					"image":     event.Repository.String(),
+				}).Error("provider.kubernetes: failed to get image creation time, resource won't be updated")
+				continue
+			}
+		}
+
+		remaining := age - timeutil.Now().Sub(created)
+		if remaining <= 0 {
+			ready = append(ready, plan)
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"image":     event.Repository.String(),
+			"created":   created,
+			"remaining": remaining,
+		}).Info("provider.kubernetes: image is newer than minimum age, update postponed")
+
+		if retry == 0 || remaining < retry {
+			retry = remaining
+		}
+	}
+
+	if retry > 0 {
+		p.submitAfter(*event, retry.Round(time.Second)+time.Second)
+	}
+
+	return ready
+}
+
+func (p *Provider) imageCreated(resource *k8s.GenericResource, repo *types.Repository) (time.Time, error) {
+	if p.registryClient == nil {
+		return time.Time{}, fmt.Errorf("registry client is not set")
+	}
+
+	opts, err := registryOpts(resource, repo)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return p.registryClient.Created(opts)
+}
+
+// submitAfter - submits event again after the delay, unless the provider stops.
+// Events of the image that are already postponed (ie: by poll trigger) are skipped.
+func (p *Provider) submitAfter(event types.Event, delay time.Duration) {
+	key := event.Repository.String()
+	if _, pending := p.postponed.LoadOrStore(key, true); pending {
+		return
+	}
+
+	go func() {
+		defer p.postponed.Delete(key)
+		select {
+		case <-time.After(delay):
+		case <-p.stop:
+			return
+		}
+		select {
+		case p.events <- &event:
+		case <-p.stop:
+		}
+	}()
+}
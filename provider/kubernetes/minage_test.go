@@ -0,0 +1,82 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+)
+
+type createdRegistry struct {
+	registry.Client
+	created time.Time
+}
+
+func (r *createdRegistry) Created(opts registry.Opts) (time.Time, error) {
+	return r.created, nil
+}
+
+func TestCheckMinimumAge(t *testing.T) {
+	now := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	timeutil.Now = func() time.Time { return now }
+	defer func() { timeutil.Now = time.Now }()
+
+	p := &Provider{
+		registryClient: &createdRegistry{created: now.Add(-90 * time.Minute)},
+		events:         make(chan *types.Event, 1),
+		stop:           make(chan struct{}),
+	}
+	defer close(p.stop)
+
+	old := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{types.KeelMinimumAgeAnnotation: "1h"})}
+	young := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{types.KeelMinimumAgeAnnotation: "2h"})}
+	unset := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{})}
+	invalid := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{types.KeelMinimumAgeAnnotation: "soon"})}
+
+	event := &types.Event{Repository: types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.15"}}
+	plans := p.checkMinimumAge(event, []*UpdatePlan{old, young, unset, invalid})
+	if len(plans) != 3 || plans[0] != old || plans[1] != unset || plans[2] != invalid {
+		t.Fatalf("expected only young image plan to be postponed, got: %v", plans)
+	}
+
+	if _, pending := p.postponed.Load(event.Repository.String()); !pending {
+		t.Errorf("expected event to be submitted again")
+	}
+
+	// same image is only postponed once
+	p.checkMinimumAge(event, []*UpdatePlan{young})
+	count := 0
+	p.postponed.Range(func(key, value interface{}) bool {
+		count++
+		return true
+	})
+	if count != 1 {
+		t.Errorf("expected single postponed event, got: %d", count)
+	}
+}
+
+func TestCheckMinimumAgeWithoutRegistry(t *testing.T) {
+	p := &Provider{}
+	plan := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{types.KeelMinimumAgeAnnotation: "1h"})}
+
+	plans := p.checkMinimumAge(&types.Event{Repository: types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.15"}}, []*UpdatePlan{plan})
+	if len(plans) != 0 {
+		t.Errorf("expected plan to be dropped when image creation time can't be found")
+	}
+}
+
+func TestResourceMinimumAgeFromPolicy(t *testing.T) {
+	p := &Provider{}
+
+	resource := pinnedDeployment(t, map[string]string{types.KeelPolicyLabel: "semver:minor AND minimumAge:30m"})
+	if age, ok := p.resourceMinimumAge(resource); !ok || age != 30*time.Minute {
+		t.Errorf("expected minimum age from policy, got: %s %t", age, ok)
+	}
+
+	resource = pinnedDeployment(t, map[string]string{types.KeelPolicyLabel: "minor", types.KeelMinimumAgeAnnotation: "2h"})
+	if age, ok := p.resourceMinimumAge(resource); !ok || age != 2*time.Hour {
+		t.Errorf("expected minimum age from annotation, got: %s %t", age, ok)
+	}
+}
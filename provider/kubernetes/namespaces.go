@@ -0,0 +1,49 @@
+package kubernetes
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// namespacesTTL - how long namespace labels are cached for freeze and scope checks
+var namespacesTTL = 30 * time.Second
+
+// namespaceCache - labels and annotations of namespaces, fetched at most once per TTL
+type namespaceCache struct {
+	mu         sync.Mutex
+	namespaces map[string]*namespaceLabels
+	fetched    time.Time
+}
+
+type namespaceLabels struct {
+	labels      labels.Set
+	annotations map[string]string
+}
+
+// cachedNamespace - cached namespace labels, stale labels are used when
+// namespaces can't be listed
+func (p *Provider) cachedNamespace(namespace string) *namespaceLabels {
+	c := &p.namespaceCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.fetched) > namespacesTTL {
+		list, err := p.namespaces()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Warn("provider.kubernetes: failed to list namespaces")
+		} else {
+			c.namespaces = make(map[string]*namespaceLabels, len(list.Items))
+			for _, ns := range list.Items {
+				c.namespaces[ns.Name] = &namespaceLabels{labels: labels.Set(ns.Labels), annotations: ns.Annotations}
+			}
+		}
+		c.fetched = time.Now()
+	}
+	return c.namespaces[namespace]
+}
@@ -0,0 +1,37 @@
+package kubernetes
+
+import (
+	"strings"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/internal/oncall"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SetOncallResolver - sets resolver of on-call schedules of resources with keel.sh/oncallSchedule annotation
+func (p *Provider) SetOncallResolver(resolver oncall.Resolver) {
+	p.oncallResolver = resolver
+}
+
+// oncall - emails of users currently on call for the resource schedule, approval
+// is still requested when schedule can't be resolved
+func (p *Provider) oncall(resource *k8s.GenericResource) string {
+	schedule := resource.GetAnnotations()[types.KeelOncallScheduleAnnotation]
+	if schedule == "" || p.oncallResolver == nil {
+		return ""
+	}
+
+	users, err := p.oncallResolver.OnCall(schedule)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"schedule":  schedule,
+			"name":      resource.Name,
+			"namespace": resource.Namespace,
+		}).Warn("provider.kubernetes: failed to resolve on-call schedule")
+		return ""
+	}
+	return strings.Join(users, ",")
+}
@@ -0,0 +1,255 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// updateAfter - resources that have to be updated (and rolled out) before the
+// resource, either namespace/name or kind/namespace/name
+func updateAfter(resource *k8s.GenericResource) []string {
+	var deps []string
+	for _, dep := range strings.Split(resource.GetAnnotations()[types.KeelUpdateAfterAnnotation], ",") {
+		if dep = strings.TrimSpace(dep); dep != "" {
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+func matchesDependency(resource *k8s.GenericResource, dep string) bool {
+	return dep == resource.Namespace+"/"+resource.Name || dep == resource.Identifier
+}
+
+// orderPlans - groups plans into steps, plans only depend on plans from previous
+// steps. Dependencies on resources that aren't updated together are ignored, plans
+// with circular dependencies are returned separately.
+func orderPlans(plans []*UpdatePlan) (steps [][]*UpdatePlan, deps map[*UpdatePlan][]*UpdatePlan, cyclic []*UpdatePlan) {
+	deps = make(map[*UpdatePlan][]*UpdatePlan)
+	for _, plan := range plans {
+		for _, dep := range updateAfter(plan.Resource) {
+			for _, upstream := range plans {
+				if upstream != plan && matchesDependency(upstream.Resource, dep) {
+					deps[plan] = append(deps[plan], upstream)
+				}
+			}
+		}
+	}
+
+	done := make(map[*UpdatePlan]bool)
+	remaining := plans
+	for len(remaining) > 0 {
+		var step, blocked []*UpdatePlan
+		for _, plan := range remaining {
+			ready := true
+			for _, upstream := range deps[plan] {
+				if !done[upstream] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				step = append(step, plan)
+			} else {
+				blocked = append(blocked, plan)
+			}
+		}
+		if len(step) == 0 {
+			return steps, deps, blocked
+		}
+		for _, plan := range step {
+			done[plan] = true
+		}
+		steps = append(steps, step)
+		remaining = blocked
+	}
+	return steps, deps, nil
+}
+
+// rollout - rollout of the resource updated by keel, done is closed when it finishes
+type rollout struct {
+	resource *k8s.GenericResource
+	done     chan struct{}
+	failed   bool
+}
+
+// rolloutTracker - rollouts in progress, ordered updates wait for upstream
+// resources that were updated by other events (ie: migrations job image)
+type rolloutTracker struct {
+	mu         sync.Mutex
+	inProgress map[string]*rollout
+}
+
+func (t *rolloutTracker) start(resource *k8s.GenericResource) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.inProgress == nil {
+		t.inProgress = make(map[string]*rollout)
+	}
+	if r, ok := t.inProgress[resource.Identifier]; ok {
+		close(r.done)
+	}
+	t.inProgress[resource.Identifier] = &rollout{resource: resource, done: make(chan struct{})}
+}
+
+func (t *rolloutTracker) finish(resource *k8s.GenericResource, failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, ok := t.inProgress[resource.Identifier]
+	if !ok || r.resource != resource {
+		return
+	}
+	r.failed = failed
+	close(r.done)
+	delete(t.inProgress, resource.Identifier)
+}
+
+// upstream - rollouts in progress the resource has to wait for
+func (t *rolloutTracker) upstream(resource *k8s.GenericResource) []*rollout {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var rollouts []*rollout
+	for _, dep := range updateAfter(resource) {
+		for _, r := range t.inProgress {
+			if r.resource.Identifier != resource.Identifier && matchesDependency(r.resource, dep) {
+				rollouts = append(rollouts, r)
+			}
+		}
+	}
+	return rollouts
+}
+
+// isOrdered - whether some of the plans have to wait for other plans or rollouts in progress
+func (p *Provider) isOrdered(plans []*UpdatePlan) bool {
+	_, deps, _ := orderPlans(plans)
+	if len(deps) > 0 {
+		return true
+	}
+	for _, plan := range plans {
+		if len(p.rollouts.upstream(plan.Resource)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForUpstream - waits for upstream rollouts started by other events, returns
+// resource of the failed rollout
+func (p *Provider) waitForUpstream(plan *UpdatePlan) *k8s.GenericResource {
+	for _, r := range p.rollouts.upstream(plan.Resource) {
+		select {
+		case <-r.done:
+		case <-p.stop:
+			return r.resource
+		}
+		if r.failed {
+			return r.resource
+		}
+	}
+	return nil
+}
+
+// updateInOrder - updates plans step by step, each step waits for the previous
+// step to roll out. Updates that depend on failed updates are aborted.
+func (p *Provider) updateInOrder(plans []*UpdatePlan) {
+	steps, deps, cyclic := orderPlans(plans)
+
+	failed := make(map[*UpdatePlan]bool)
+	for _, plan := range cyclic {
+		failed[plan] = true
+		p.abortUpdate(plan, "circular "+types.KeelUpdateAfterAnnotation+" dependency")
+	}
+
+	for _, step := range steps {
+		var ready []*UpdatePlan
+		generations := make(map[*UpdatePlan]int64)
+		for _, plan := range step {
+			if upstream := failedDependency(plan, deps, failed); upstream != nil {
+				failed[plan] = true
+				p.abortUpdate(plan, fmt.Sprintf("%s %s/%s wasn't updated", upstream.Resource.Kind(), upstream.Resource.Namespace, upstream.Resource.Name))
+				continue
+			}
+			if upstream := p.waitForUpstream(plan); upstream != nil {
+				failed[plan] = true
+				p.abortUpdate(plan, fmt.Sprintf("%s %s/%s rollout failed", upstream.Kind(), upstream.Namespace, upstream.Name))
+				continue
+			}
+			generations[plan] = plan.Resource.GetGeneration()
+			ready = append(ready, plan)
+		}
+
+		updated, _ := p.updateDeployments(ready)
+		for _, plan := range ready {
+			if dryRunEnabled(plan.Resource.GetAnnotations()) {
+				continue
+			}
+			if !containsResource(updated, plan.Resource) {
+				failed[plan] = true
+				continue
+			}
+			if plan.Resource.Kind() == "cronjob" {
+				continue
+			}
+			status := p.waitForRollout(plan.Resource.Identifier, generations[plan], rolloutTimeout(plan.Resource.GetAnnotations()), func(k8s.RolloutStatus) {})
+			if status.Failed {
+				failed[plan] = true
+			}
+		}
+	}
+}
+
+func failedDependency(plan *UpdatePlan, deps map[*UpdatePlan][]*UpdatePlan, failed map[*UpdatePlan]bool) *UpdatePlan {
+	for _, upstream := range deps[plan] {
+		if failed[upstream] {
+			return upstream
+		}
+	}
+	return nil
+}
+
+func containsResource(resources []*k8s.GenericResource, resource *k8s.GenericResource) bool {
+	for _, r := range resources {
+		if r.Identifier == resource.Identifier {
+			return true
+		}
+	}
+	return false
+}
+
+// abortUpdate - reports update that wasn't applied because of ordering
+func (p *Provider) abortUpdate(plan *UpdatePlan, reason string) {
+	resource := plan.Resource
+
+	log.WithFields(log.Fields{
+		"name":      resource.Name,
+		"kind":      resource.Kind(),
+		"namespace": resource.Namespace,
+		"update":    fmt.Sprintf("%s->%s", plan.CurrentVersion, plan.NewVersion),
+		"reason":    reason,
+	}).Error("provider.kubernetes: update aborted")
+
+	p.recordUpdate(plan, fmt.Errorf("update aborted: %s", reason))
+
+	p.sender.Send(types.EventNotification{
+		Name:         "update resource",
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Message:      fmt.Sprintf("%s %s/%s update %s->%s aborted: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, reason),
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        types.LevelError,
+		Channels:     types.ParseEventNotificationChannels(resource.GetAnnotations()),
+		Metadata: map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+		},
+	})
+}
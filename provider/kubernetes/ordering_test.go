@@ -0,0 +1,135 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func orderedPlan(t *testing.T, name, after string) *UpdatePlan {
+	gr, err := k8s.NewGenericResource(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        name,
+			Namespace:   "xxxx",
+			Annotations: map[string]string{types.KeelUpdateAfterAnnotation: after},
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: core_v1.PodTemplateSpec{
+				Spec: core_v1.PodSpec{
+					Containers: []core_v1.Container{
+						{Image: "karolisr/webhook-demo:0.0.15"},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create resource: %s", err)
+	}
+	return &UpdatePlan{Resource: gr, CurrentVersion: "0.0.14", NewVersion: "0.0.15"}
+}
+
+func TestOrderPlans(t *testing.T) {
+	a := orderedPlan(t, "a", "")
+	b := orderedPlan(t, "b", "xxxx/a")
+	c := orderedPlan(t, "c", "deployment/xxxx/b, xxxx/a")
+	// dependencies outside of the batch are ignored
+	d := orderedPlan(t, "d", "xxxx/missing")
+
+	steps, deps, cyclic := orderPlans([]*UpdatePlan{c, b, a, d})
+	if len(cyclic) != 0 {
+		t.Errorf("unexpected cyclic plans: %d", len(cyclic))
+	}
+	if len(deps[c]) != 2 || len(deps[d]) != 0 {
+		t.Errorf("unexpected dependencies: %v", deps)
+	}
+
+	var names []string
+	for _, step := range steps {
+		var stepNames []string
+		for _, plan := range step {
+			stepNames = append(stepNames, plan.Resource.Name)
+		}
+		names = append(names, strings.Join(stepNames, ","))
+	}
+	if strings.Join(names, " ") != "a,d b c" {
+		t.Errorf("unexpected steps: %v", names)
+	}
+}
+
+func TestOrderPlansCycle(t *testing.T) {
+	a := orderedPlan(t, "a", "xxxx/b")
+	b := orderedPlan(t, "b", "xxxx/a")
+	c := orderedPlan(t, "c", "")
+
+	steps, _, cyclic := orderPlans([]*UpdatePlan{a, b, c})
+	if len(steps) != 1 || len(steps[0]) != 1 || steps[0][0] != c {
+		t.Errorf("expected only independent plan to be updated, got: %v", steps)
+	}
+	if len(cyclic) != 2 {
+		t.Errorf("expected circular plans to be returned, got: %d", len(cyclic))
+	}
+}
+
+func TestUpdateInOrderCycle(t *testing.T) {
+	implementer := &updateRecorder{}
+	sender := &recordingSender{}
+	p := &Provider{implementer: implementer, sender: sender}
+
+	plans := []*UpdatePlan{
+		orderedPlan(t, "a", "xxxx/b"),
+		orderedPlan(t, "b", "xxxx/a"),
+		orderedPlan(t, "c", "xxxx/a"),
+	}
+	if !p.isOrdered(plans) {
+		t.Fatalf("expected plans to be ordered")
+	}
+
+	p.updateInOrder(plans)
+	if len(implementer.updated) != 0 {
+		t.Errorf("resources with circular dependencies shouldn't be updated")
+	}
+	if len(sender.sent) != 3 {
+		t.Fatalf("expected abort notifications, got: %d", len(sender.sent))
+	}
+	for _, sent := range sender.sent {
+		if sent.Level != types.LevelError || !strings.Contains(sent.Message, "circular") {
+			t.Errorf("unexpected notification: %s", sent.Message)
+		}
+	}
+}
+
+func TestUpdateInOrderUpstreamRolloutFailed(t *testing.T) {
+	implementer := &updateRecorder{}
+	sender := &recordingSender{}
+	p := &Provider{implementer: implementer, sender: sender}
+
+	// upstream resource updated by a different event
+	upstream := orderedPlan(t, "migrations", "")
+	p.rollouts.start(upstream.Resource)
+
+	plan := orderedPlan(t, "service", "xxxx/migrations")
+	if !p.isOrdered([]*UpdatePlan{plan}) {
+		t.Fatalf("expected plan to wait for upstream rollout")
+	}
+
+	go p.rollouts.finish(upstream.Resource, true)
+	p.updateInOrder([]*UpdatePlan{plan})
+
+	if len(implementer.updated) != 0 {
+		t.Errorf("resource shouldn't be updated after upstream rollout failed")
+	}
+	if len(sender.sent) != 1 || !strings.Contains(sender.sent[0].Message, "deployment xxxx/migrations rollout failed") {
+		t.Errorf("unexpected notifications: %v", sender.sent)
+	}
+
+	if p.isOrdered([]*UpdatePlan{plan}) {
+		t.Errorf("finished rollouts shouldn't be waited for")
+	}
+}
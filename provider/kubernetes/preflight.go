@@ -0,0 +1,78 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+)
+
+// SetPullPreflight - enables pull check of updated images of all resources,
+// resources can opt out (or in) with keel.sh/pullPreflight annotation
+func (p *Provider) SetPullPreflight(enabled bool) {
+	p.pullPreflight = enabled
+}
+
+func (p *Provider) pullPreflightEnabled(annotations map[string]string) bool {
+	switch annotations[types.KeelPullPreflightAnnotation] {
+	case "1", "true":
+		return true
+	case "0", "false":
+		return false
+	}
+	return p.pullPreflight
+}
+
+// checkPull - verifies that updated images of the plan exist and can be pulled
+// with the resource image pull secrets, the manifest is only requested with HEAD
+// so the check doesn't count towards registry pull limits
+func (p *Provider) checkPull(plan *UpdatePlan) error {
+	if p.registryClient == nil {
+		return fmt.Errorf("registry client is not set")
+	}
+
+	for _, img := range updatedImages(plan.Resource, plan.NewVersion) {
+		ref, err := image.Parse(img)
+		if err != nil {
+			return err
+		}
+		opts, err := registryOpts(plan.Resource, &types.Repository{Name: ref.Repository(), Tag: ref.Tag()})
+		if err != nil {
+			return err
+		}
+		if ref.Digest() != "" {
+			opts.Tag = ref.Digest()
+		}
+
+		_, err = p.registryClient.Digest(opts)
+		switch {
+		case err == nil:
+			continue
+		case registry.IsNotFound(err):
+			return fmt.Errorf("image %s not found", img)
+		case registry.IsUnauthorized(err):
+			return fmt.Errorf("image %s can't be pulled, registry rejected credentials of image pull secrets (%s)", img, strings.Join(plan.Resource.GetImagePullSecrets(), ", "))
+		default:
+			return fmt.Errorf("image %s can't be pulled: %s", img, err)
+		}
+	}
+	return nil
+}
+
+// updatedImages - unique images of resource containers and init containers with the new version
+func updatedImages(resource *k8s.GenericResource, version string) []string {
+	seen := make(map[string]bool)
+	var images []string
+	for _, c := range append(resource.Containers(), resource.InitContainers()...) {
+		ref, err := image.Parse(c.Image)
+		if err != nil || ref.Tag() != version || seen[c.Image] {
+			continue
+		}
+		seen[c.Image] = true
+		images = append(images, c.Image)
+	}
+	return images
+}
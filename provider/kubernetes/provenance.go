@@ -0,0 +1,124 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/keel-hq/keel/internal/provenance"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ProvenanceVerifier - checks provenance attestations of images
+type ProvenanceVerifier interface {
+	Verify(opts registry.Opts, digest string) (*provenance.Attestation, error)
+}
+
+// SetProvenanceVerifier - sets verifier of images of resources with keel.sh/verifyProvenance annotation
+func (p *Provider) SetProvenanceVerifier(verifier ProvenanceVerifier) {
+	p.provenanceVerifier = verifier
+}
+
+// provenanceAction - what happens when new image doesn't have provenance from
+// an allowed builder, empty when provenance isn't checked
+func provenanceAction(annotations map[string]string) string {
+	switch annotations[types.KeelVerifyProvenanceAnnotation] {
+	case "":
+		return ""
+	case types.KeelProvenanceActionApproval:
+		return types.KeelProvenanceActionApproval
+	default:
+		return types.KeelProvenanceActionBlock
+	}
+}
+
+// verifyProvenance - checks provenance attestations of new images of resources with
+// keel.sh/verifyProvenance annotation, updates without provenance from an allowed
+// builder are dropped or require an approval. Attestation summary is added to
+// approval requests.
+func (p *Provider) verifyProvenance(event *types.Event, plans []*UpdatePlan) []*UpdatePlan {
+	var verified []*UpdatePlan
+	for _, plan := range plans {
+		resource := plan.Resource
+		action := provenanceAction(resource.GetAnnotations())
+		if action == "" {
+			verified = append(verified, plan)
+			continue
+		}
+
+		attestation, digest, err := p.planProvenance(plan, &event.Repository)
+		if err == nil {
+			plan.provenance = attestation.Summary()
+			p.sendProvenanceNotification(plan, types.LevelInfo,
+				fmt.Sprintf("%s %s/%s new image %s (%s) has %s", resource.Kind(), resource.Namespace, resource.Name, event.Repository.String(), digest, plan.provenance))
+			verified = append(verified, plan)
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"image":     event.Repository.String(),
+			"action":    action,
+		}).Warn("provider.kubernetes: provenance verification of new image failed")
+
+		if action == types.KeelProvenanceActionApproval {
+			plan.provenanceFailure = err.Error()
+			p.sendProvenanceNotification(plan, types.LevelWarn,
+				fmt.Sprintf("%s %s/%s update %s->%s requires approval, provenance verification of %s failed: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, digest, err))
+			verified = append(verified, plan)
+			continue
+		}
+
+		p.sendProvenanceNotification(plan, types.LevelError,
+			fmt.Sprintf("%s %s/%s update %s->%s blocked, provenance verification of %s failed: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, digest, err))
+	}
+	return verified
+}
+
+// planProvenance - returns attestation and verified digest, the digest is resolved
+// unless images are already pinned
+func (p *Provider) planProvenance(plan *UpdatePlan, repo *types.Repository) (*provenance.Attestation, string, error) {
+	if p.provenanceVerifier == nil {
+		return nil, "", fmt.Errorf("provenance verification is not configured")
+	}
+
+	digest, err := p.planDigest(plan, repo)
+	if err != nil {
+		return nil, "", err
+	}
+
+	opts, err := registryOpts(plan.Resource, repo)
+	if err != nil {
+		return nil, digest, err
+	}
+
+	attestation, err := p.provenanceVerifier.Verify(opts, digest)
+	if err != nil {
+		return nil, digest, err
+	}
+	return attestation, digest, nil
+}
+
+func (p *Provider) sendProvenanceNotification(plan *UpdatePlan, level types.Level, message string) {
+	resource := plan.Resource
+	p.sender.Send(types.EventNotification{
+		Name:         "verify image provenance",
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Message:      message,
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationProvenanceVerification,
+		Level:        level,
+		Channels:     types.ParseEventNotificationChannels(resource.GetAnnotations()),
+		Metadata: map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+		},
+	})
+}
@@ -0,0 +1,97 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/internal/ratelimit"
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SetRateLimiter - enables update budgets, the limiter can be shared by providers
+// of several clusters
+func (p *Provider) SetRateLimiter(l *ratelimit.Limiter) {
+	p.rateLimiter = l
+}
+
+// checkRateLimit - queues plans that exceed update budgets. Explicitly requested
+// updates of single workloads and dry-run updates aren't limited.
+func (p *Provider) checkRateLimit(event *types.Event, plans []*UpdatePlan) []*UpdatePlan {
+	if p.rateLimiter == nil || event.Target != "" {
+		return plans
+	}
+
+	var ready []*UpdatePlan
+	for _, plan := range plans {
+		resource := plan.Resource
+		if dryRunEnabled(resource.GetAnnotations()) || p.rateLimiter.Allow(resource.Namespace) {
+			ready = append(ready, plan)
+			continue
+		}
+
+		plan := plan
+		p.rateLimiter.Enqueue(ratelimit.Update{
+			Provider:       p.GetName(),
+			Namespace:      resource.Namespace,
+			Kind:           resource.Kind(),
+			Identifier:     resource.Identifier,
+			CurrentVersion: plan.CurrentVersion,
+			NewVersion:     plan.NewVersion,
+		}, func() { p.applyQueued(plan) })
+
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"previous":  plan.CurrentVersion,
+			"new":       plan.NewVersion,
+		}).Info("provider.kubernetes: update budget exceeded, update queued")
+
+		p.sender.Send(types.EventNotification{
+			Name:         "update queued",
+			ResourceKind: resource.Kind(),
+			Identifier:   resource.Identifier,
+			Message:      fmt.Sprintf("Update budget exceeded, queued update of %s %s/%s %s->%s (%s)", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(resource.GetImages(), ", ")),
+			CreatedAt:    time.Now(),
+			Type:         types.NotificationUpdateQueued,
+			Level:        types.LevelInfo,
+			Channels:     types.ParseEventNotificationChannels(resource.GetAnnotations()),
+			Metadata:     p.planMetadata(plan),
+		})
+	}
+	return ready
+}
+
+// applyQueued - applies plan released by the rate limiter, resources paused
+// while the update was queued are skipped
+func (p *Provider) applyQueued(plan *UpdatePlan) {
+	resource := plan.Resource
+
+	if paused := store.Maintenance(p.store); paused != nil {
+		p.maintenanceUpdates([]*UpdatePlan{plan}, paused)
+		return
+	}
+	if paused, reason := p.isPausedOrFrozen(resource); paused {
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"freeze":    reason,
+		}).Info("provider.kubernetes: resource was paused while update was queued, skipping")
+		return
+	}
+
+	_, err := p.updateDeployments([]*UpdatePlan{plan})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+		}).Error("provider.kubernetes: failed to apply queued update")
+	}
+}
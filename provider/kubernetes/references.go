@@ -0,0 +1,313 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// images can also be referenced outside of container specs, ie: an env var with an
+// image of the jobs created by the workload or an operator ConfigMap. References are
+// declared with keel.sh/envImages and keel.sh/configMapImages annotations, they're
+// tracked and updated with the same policy as container images.
+
+// envImageNames - env vars listed in keel.sh/envImages annotation
+func envImageNames(annotations map[string]string) []string {
+	var names []string
+	for _, name := range strings.Split(annotations[types.KeelEnvImagesAnnotation], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// containerEnvImages - container env vars listed in names, env vars that are set from
+// secrets or ConfigMaps (valueFrom) are skipped
+func containerEnvImages(c core_v1.Container, names []string) []core_v1.EnvVar {
+	var env []core_v1.EnvVar
+	for _, e := range c.Env {
+		if e.ValueFrom != nil || e.Value == "" {
+			continue
+		}
+		for _, name := range names {
+			if e.Name == name {
+				env = append(env, e)
+			}
+		}
+	}
+	return env
+}
+
+// updateEnvImages - updates env var images of the containers with their policies,
+// update sets the env var of the container with the index
+func updateEnvImages(plc policy.Policy, policies containerPolicies, repo *types.Repository, eventRepoRef *image.Reference, resource *k8s.GenericResource, plan *UpdatePlan, containers []core_v1.Container, update func(index int, name, value string)) bool {
+	names := envImageNames(resource.GetAnnotations())
+	if len(names) == 0 {
+		return false
+	}
+
+	updated := false
+	for idx, c := range containers {
+		for _, env := range containerEnvImages(c, names) {
+			currentTag, updatedImage, ok := imageUpdate(policies.get(plc, c.Name), repo, eventRepoRef, resource, env.Value)
+			if !ok {
+				continue
+			}
+
+			setUpdateTime(resource)
+			update(idx, env.Name, updatedImage)
+
+			updated = true
+			plan.envVars = append(plan.envVars, c.Name+"/"+env.Name)
+
+			if plan.CurrentVersion == "" {
+				plan.CurrentVersion = currentTag
+			}
+			plan.NewVersion = repo.Tag
+			plan.Resource = resource
+		}
+	}
+	return updated
+}
+
+// configMapRef - ConfigMap key listed in keel.sh/configMapImages annotation
+type configMapRef struct {
+	name string
+	key  string
+}
+
+func (r configMapRef) String() string {
+	return r.name + "/" + r.key
+}
+
+// configMapImage - ConfigMap key and the image it references
+type configMapImage struct {
+	configMapRef
+	image string
+}
+
+// configMapRefs - ConfigMap keys listed in keel.sh/configMapImages annotation,
+// invalid entries are skipped
+func configMapRefs(annotations map[string]string) []configMapRef {
+	var refs []configMapRef
+	for _, s := range strings.Split(annotations[types.KeelConfigMapImagesAnnotation], ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		parts := strings.SplitN(s, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			log.WithFields(log.Fields{
+				"reference":  s,
+				"annotation": types.KeelConfigMapImagesAnnotation,
+			}).Warn("provider.kubernetes: invalid ConfigMap image reference, expected <configmap>/<key>")
+			continue
+		}
+		refs = append(refs, configMapRef{name: parts[0], key: parts[1]})
+	}
+	return refs
+}
+
+func configMapRestartEnabled(annotations map[string]string) bool {
+	switch annotations[types.KeelConfigMapRestartAnnotation] {
+	case "1", "true":
+		return true
+	}
+	return false
+}
+
+// configMapImages - images referenced by ConfigMaps of the resource, ConfigMaps
+// that can't be fetched are skipped
+func (p *Provider) configMapImages(resource *k8s.GenericResource) []configMapImage {
+	refs := configMapRefs(resource.GetAnnotations())
+	if len(refs) == 0 {
+		return nil
+	}
+
+	configMaps := make(map[string]*core_v1.ConfigMap)
+	var images []configMapImage
+	for _, ref := range refs {
+		cm, ok := configMaps[ref.name]
+		if !ok {
+			var err error
+			cm, err = p.implementer.ConfigMaps(resource.Namespace).Get(ref.name, meta_v1.GetOptions{})
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":     err,
+					"configmap": ref.name,
+					"namespace": resource.Namespace,
+					"name":      resource.Name,
+				}).Warn("provider.kubernetes: failed to get ConfigMap with image references")
+				cm = nil
+			}
+			configMaps[ref.name] = cm
+		}
+		if cm == nil {
+			continue
+		}
+		value := strings.TrimSpace(cm.Data[ref.key])
+		if value == "" {
+			continue
+		}
+		images = append(images, configMapImage{configMapRef: ref, image: value})
+	}
+	return images
+}
+
+// referencedImages - images referenced by env vars and ConfigMaps of the resource
+func (p *Provider) referencedImages(resource *k8s.GenericResource) []string {
+	seen := make(map[string]bool)
+	var images []string
+	add := func(img string) {
+		if !seen[img] {
+			seen[img] = true
+			images = append(images, img)
+		}
+	}
+
+	names := envImageNames(resource.GetAnnotations())
+	for _, c := range resourceContainers(resource) {
+		for _, env := range containerEnvImages(c, names) {
+			add(env.Value)
+		}
+	}
+	for _, ref := range p.configMapImages(resource) {
+		add(ref.image)
+	}
+	return images
+}
+
+// planConfigMapUpdates - adds ConfigMap images that should be updated to the plan,
+// workload pods are restarted when keel.sh/configMapRestart is set
+func (p *Provider) planConfigMapUpdates(plc policy.Policy, repo *types.Repository, resource *k8s.GenericResource, plan *UpdatePlan) bool {
+	images := p.configMapImages(resource)
+	if len(images) == 0 {
+		return false
+	}
+
+	eventRepoRef, err := image.Parse(repo.String())
+	if err != nil {
+		return false
+	}
+
+	for _, ref := range images {
+		currentTag, updatedImage, ok := imageUpdate(plc, repo, eventRepoRef, resource, ref.image)
+		if !ok {
+			continue
+		}
+		plan.configMaps = append(plan.configMaps, configMapImage{configMapRef: ref.configMapRef, image: updatedImage})
+
+		if plan.CurrentVersion == "" {
+			plan.CurrentVersion = currentTag
+		}
+		plan.NewVersion = repo.Tag
+		plan.Resource = resource
+	}
+
+	if len(plan.configMaps) == 0 {
+		return false
+	}
+	if configMapRestartEnabled(resource.GetAnnotations()) {
+		setUpdateTime(resource)
+	}
+	return true
+}
+
+// configMapsOnly - whether plan only updates ConfigMaps, workload itself isn't changed
+func (p *UpdatePlan) configMapsOnly() bool {
+	return len(p.configMaps) > 0 && len(p.containers) == 0 && len(p.envVars) == 0 &&
+		!configMapRestartEnabled(p.Resource.GetAnnotations())
+}
+
+// updateConfigMaps - writes updated images of the plan to the ConfigMaps
+func (p *Provider) updateConfigMaps(plan *UpdatePlan) error {
+	if len(plan.configMaps) == 0 {
+		return nil
+	}
+	if reason := p.outOfScope(plan.Resource); reason != "" {
+		return fmt.Errorf("resource %s is out of keel scope: %s", plan.Resource.Identifier, reason)
+	}
+	namespace := plan.Resource.Namespace
+
+	byName := make(map[string][]configMapImage)
+	var names []string
+	for _, ref := range plan.configMaps {
+		if _, ok := byName[ref.name]; !ok {
+			names = append(names, ref.name)
+		}
+		byName[ref.name] = append(byName[ref.name], ref)
+	}
+
+	for _, name := range names {
+		cm, err := p.implementer.ConfigMaps(namespace).Get(name, meta_v1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get ConfigMap %s: %s", name, err)
+		}
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		for _, ref := range byName[name] {
+			cm.Data[ref.key] = ref.image
+		}
+
+		// resource version from Get makes update fail on concurrent changes
+		_, err = p.implementer.ConfigMaps(namespace).Update(cm)
+		if err != nil {
+			return fmt.Errorf("failed to update ConfigMap %s: %s", name, err)
+		}
+
+		log.WithFields(log.Fields{
+			"configmap": name,
+			"namespace": namespace,
+			"name":      plan.Resource.Name,
+			"version":   plan.NewVersion,
+		}).Info("provider.kubernetes: ConfigMap images updated")
+	}
+	return nil
+}
+
+// configMapsUpdated - plans that only update ConfigMaps don't roll out the workload,
+// update is complete once ConfigMaps are written
+func (p *Provider) configMapsUpdated(plan *UpdatePlan, channels []string) {
+	resource := plan.Resource
+
+	p.recordUpdate(plan, nil)
+
+	err := p.updateComplete(plan)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+		}).Warn("provider.kubernetes: got error while resetting approvals counter after successful update")
+	}
+
+	var refs []string
+	for _, ref := range plan.configMaps {
+		refs = append(refs, ref.String())
+	}
+
+	p.sender.Send(types.EventNotification{
+		Name:         "update resource",
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Message:      fmt.Sprintf("Successfully updated ConfigMap images of %s %s/%s %s->%s (%s)", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(refs, ", ")),
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        types.LevelSuccess,
+		Channels:     channels,
+		Metadata:     p.planMetadata(plan),
+	})
+}
@@ -0,0 +1,45 @@
+package kubernetes
+
+import (
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ReleaseNotesFetcher - fetches release notes of new images, repository (owner/repo)
+// is empty unless the resource has keel.sh/githubRepository annotation
+type ReleaseNotesFetcher interface {
+	Fetch(repository string, opts registry.Opts) (string, error)
+}
+
+// SetReleaseNotesFetcher - sets fetcher of release notes that are added to approval
+// requests and update notifications
+func (p *Provider) SetReleaseNotesFetcher(fetcher ReleaseNotesFetcher) {
+	p.releaseNotesFetcher = fetcher
+}
+
+// fetchReleaseNotes - adds release notes of new images to plans, updates aren't
+// held back when they can't be fetched
+func (p *Provider) fetchReleaseNotes(event *types.Event, plans []*UpdatePlan) []*UpdatePlan {
+	if p.releaseNotesFetcher == nil {
+		return plans
+	}
+	for _, plan := range plans {
+		resource := plan.Resource
+		opts, err := registryOpts(resource, &event.Repository)
+		if err == nil {
+			plan.releaseNotes, err = p.releaseNotesFetcher.Fetch(resource.GetAnnotations()[types.KeelGithubRepositoryAnnotation], opts)
+		}
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"name":      resource.Name,
+				"kind":      resource.Kind(),
+				"namespace": resource.Namespace,
+				"image":     event.Repository.String(),
+			}).Warn("provider.kubernetes: failed to fetch release notes of new image")
+		}
+	}
+	return plans
+}
@@ -0,0 +1,52 @@
+package kubernetes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+)
+
+type fakeReleaseNotes struct {
+	repositories []string
+	err          error
+}
+
+func (f *fakeReleaseNotes) Fetch(repository string, opts registry.Opts) (string, error) {
+	f.repositories = append(f.repositories, repository)
+	if f.err != nil {
+		return "", f.err
+	}
+	return "notes of " + opts.Tag, nil
+}
+
+func TestFetchReleaseNotes(t *testing.T) {
+	fetcher := &fakeReleaseNotes{}
+	p := &Provider{releaseNotesFetcher: fetcher}
+
+	annotated := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{types.KeelGithubRepositoryAnnotation: "keel-hq/keel"})}
+	unset := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{})}
+
+	event := &types.Event{Repository: types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.15"}}
+	plans := p.fetchReleaseNotes(event, []*UpdatePlan{annotated, unset})
+	if len(plans) != 2 {
+		t.Fatalf("expected both plans, got: %v", plans)
+	}
+	if annotated.releaseNotes != "notes of 0.0.15" || unset.releaseNotes != "notes of 0.0.15" {
+		t.Errorf("unexpected release notes: %q, %q", annotated.releaseNotes, unset.releaseNotes)
+	}
+	if fetcher.repositories[0] != "keel-hq/keel" || fetcher.repositories[1] != "" {
+		t.Errorf("unexpected repositories: %v", fetcher.repositories)
+	}
+	if p.planMetadata(annotated)["releaseNotes"] != "notes of 0.0.15" {
+		t.Errorf("expected release notes in notification metadata")
+	}
+
+	// updates aren't held back when release notes can't be fetched
+	fetcher.err = errors.New("rate limited")
+	failed := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{})}
+	if plans := p.fetchReleaseNotes(event, []*UpdatePlan{failed}); len(plans) != 1 || failed.releaseNotes != "" {
+		t.Errorf("expected plan without release notes, got: %v", plans)
+	}
+}
@@ -0,0 +1,76 @@
+package kubernetes
+
+import (
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	core_v1 "k8s.io/api/core/v1"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SetDigestRestart - enables pod restarts of digest-only updates of all resources,
+// resources can opt out (or in) with keel.sh/digestRestart annotation
+func (p *Provider) SetDigestRestart(enabled bool) {
+	p.digestRestart = enabled
+}
+
+func (p *Provider) digestRestartEnabled(annotations map[string]string) bool {
+	switch annotations[types.KeelDigestRestartAnnotation] {
+	case "1", "true":
+		return true
+	case "0", "false":
+		return false
+	}
+	return p.digestRestart
+}
+
+// digestOnly - whether the plan keeps the tag of updated containers, only
+// the digest of the tag changed (ie: force policy and mutable tags). Pinned
+// images change with the digest, they don't need a restart.
+func (p *UpdatePlan) digestOnly() bool {
+	return len(p.containers) > 0 && p.CurrentVersion == p.NewVersion && p.Digest == ""
+}
+
+// restartForDigest - sets the same pod template annotation as kubectl rollout restart
+// so pods are recreated and pull the new digest of the tag. Cron jobs create new
+// pods on every run, they aren't restarted.
+func restartForDigest(plan *UpdatePlan) {
+	resource := plan.Resource
+	if resource.Kind() == "cronjob" {
+		return
+	}
+
+	specAnnotations := resource.GetSpecAnnotations()
+	specAnnotations[types.KubectlRestartedAtAnnotation] = time.Now().Format(time.RFC3339)
+	resource.SetSpecAnnotations(specAnnotations)
+
+	for _, c := range restartedContainers(resource, plan.containers) {
+		if c.ImagePullPolicy == core_v1.PullAlways {
+			continue
+		}
+		log.WithFields(log.Fields{
+			"name":              resource.Name,
+			"kind":              resource.Kind(),
+			"namespace":         resource.Namespace,
+			"container":         c.Name,
+			"image_pull_policy": c.ImagePullPolicy,
+		}).Warn("provider.kubernetes: restarted pods can keep running the cached image of the tag, set imagePullPolicy to Always or enable digest pinning")
+	}
+}
+
+// restartedContainers - containers (and init containers) of the resource with the names
+func restartedContainers(resource *k8s.GenericResource, names []string) []core_v1.Container {
+	var containers []core_v1.Container
+	for _, c := range resourceContainers(resource) {
+		for _, name := range names {
+			if c.Name == name {
+				containers = append(containers, c)
+				break
+			}
+		}
+	}
+	return containers
+}
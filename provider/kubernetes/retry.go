@@ -0,0 +1,160 @@
+package kubernetes
+
+import (
+	"net"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultUpdateAttempts - attempts of resource updates failing with transient
+// API errors before the failure is reported
+const DefaultUpdateAttempts = 5
+
+// maxRetryBackoff - backoff between update attempts doubles up to maxRetryBackoff
+const maxRetryBackoff = time.Minute
+
+// SetUpdateAttempts - sets how many times updates failing with transient API
+// errors are attempted, 1 disables retries
+func (p *Provider) SetUpdateAttempts(attempts int) {
+	p.updateAttempts = attempts
+}
+
+func (p *Provider) maxUpdateAttempts() int {
+	if p.updateAttempts <= 0 {
+		return DefaultUpdateAttempts
+	}
+	return p.updateAttempts
+}
+
+// isTransient - whether the update could succeed when it's attempted again:
+// conflicts with newer versions of the resource, timeouts, throttling and
+// unavailable API servers
+func isTransient(err error) bool {
+	switch {
+	case apierrors.IsConflict(err),
+		apierrors.IsServerTimeout(err),
+		apierrors.IsTimeout(err),
+		apierrors.IsTooManyRequests(err),
+		apierrors.IsServiceUnavailable(err),
+		apierrors.IsInternalError(err):
+		return true
+	}
+	if netErr, ok := err.(net.Error); ok {
+		return netErr.Timeout() || netErr.Temporary()
+	}
+	return false
+}
+
+// retryUpdate - attempts the failed update again in the background with exponential
+// backoff, returns false when the error isn't transient or the plan ran out of
+// attempts and the failure should be reported. The change stays claimed while the
+// retry is pending so duplicate events don't update the resource in the meantime.
+func (p *Provider) retryUpdate(plan *UpdatePlan, updateErr error) bool {
+	if !isTransient(updateErr) {
+		return false
+	}
+	plan.attempts++
+	if plan.attempts >= p.maxUpdateAttempts() {
+		return false
+	}
+	plan.retryBackoff = timeutil.ExpBackoff(plan.retryBackoff, maxRetryBackoff)
+
+	resource := plan.Resource
+	log.WithFields(log.Fields{
+		"error":     updateErr,
+		"name":      resource.Name,
+		"kind":      resource.Kind(),
+		"namespace": resource.Namespace,
+		"attempt":   plan.attempts,
+		"backoff":   plan.retryBackoff,
+	}).Warn("provider.kubernetes: transient error while updating resource, retrying")
+
+	// the plan belongs to the retry from now on, it isn't touched until the
+	// retry applies it again
+	conflict := apierrors.IsConflict(updateErr)
+	backoff := plan.retryBackoff
+	p.background(func() {
+		select {
+		case <-time.After(backoff):
+		case <-p.stop:
+			p.applied.done(plan, false)
+			return
+		}
+
+		// the update was based on a stale version, changes of the plan are
+		// applied to the latest one
+		if conflict {
+			if latest := p.cache.Get(resource.Identifier); latest != nil {
+				plan.Resource = rebase(plan, latest)
+			}
+		}
+
+		p.applied.done(plan, false)
+		p.updateDeployments([]*UpdatePlan{plan})
+	})
+	return true
+}
+
+// rebase - applies images and env var images of the updated containers and pod
+// template annotations of the plan to the latest version of the resource,
+// everything else is taken from the latest version
+func rebase(plan *UpdatePlan, latest *k8s.GenericResource) *k8s.GenericResource {
+	updated := latest.DeepCopy()
+
+	envVars := make(map[string]bool, len(plan.envVars))
+	for _, e := range plan.envVars {
+		envVars[e] = true
+	}
+	updatedContainers := make(map[string]bool, len(plan.containers))
+	for _, c := range plan.containers {
+		updatedContainers[c] = true
+	}
+
+	latestContainers := updated.Containers()
+	for _, c := range plan.Resource.Containers() {
+		for idx, lc := range latestContainers {
+			if lc.Name != c.Name {
+				continue
+			}
+			if updatedContainers[c.Name] {
+				updated.UpdateContainer(idx, c.Image)
+			}
+			for _, env := range c.Env {
+				if envVars[c.Name+"/"+env.Name] {
+					updated.UpdateContainerEnv(idx, env.Name, env.Value)
+				}
+			}
+		}
+	}
+
+	latestInitContainers := updated.InitContainers()
+	for _, c := range plan.Resource.InitContainers() {
+		for idx, lc := range latestInitContainers {
+			if lc.Name != c.Name {
+				continue
+			}
+			if updatedContainers[c.Name] {
+				updated.UpdateInitContainer(idx, c.Image)
+			}
+			for _, env := range c.Env {
+				if envVars[c.Name+"/"+env.Name] {
+					updated.UpdateInitContainerEnv(idx, env.Name, env.Value)
+				}
+			}
+		}
+	}
+
+	specAnnotations := updated.GetSpecAnnotations()
+	for k, v := range plan.Resource.GetSpecAnnotations() {
+		specAnnotations[k] = v
+	}
+	updated.SetSpecAnnotations(specAnnotations)
+
+	return updated
+}
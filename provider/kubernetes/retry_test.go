@@ -0,0 +1,133 @@
+package kubernetes
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestIsTransient(t *testing.T) {
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{apierrors.NewConflict(deployments, "dep-1", errors.New("object has been modified")), true},
+		{apierrors.NewTooManyRequests("throttled", 1), true},
+		{apierrors.NewServerTimeout(deployments, "update", 1), true},
+		{apierrors.NewServiceUnavailable("unavailable"), true},
+		{apierrors.NewNotFound(deployments, "dep-1"), false},
+		{apierrors.NewForbidden(deployments, "dep-1", errors.New("denied")), false},
+		{errors.New("invalid image"), false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("%s: got %t, want %t", tt.err, got, tt.want)
+		}
+	}
+}
+
+func retryDeployment(t *testing.T, resourceVersion, image, sidecar string) *k8s.GenericResource {
+	gr, err := k8s.NewGenericResource(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:            "dep-1",
+			Namespace:       "xxxx",
+			ResourceVersion: resourceVersion,
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: core_v1.PodTemplateSpec{
+				Spec: core_v1.PodSpec{
+					Containers: []core_v1.Container{
+						{
+							Name:  "wd",
+							Image: image,
+							Env: []core_v1.EnvVar{
+								{Name: "WORKER_IMAGE", Value: image},
+								{Name: "LOG_LEVEL", Value: resourceVersion},
+							},
+						},
+						{Name: "sidecar", Image: sidecar},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create resource: %s", err)
+	}
+	return gr
+}
+
+func TestRebase(t *testing.T) {
+	planned := retryDeployment(t, "1", "karolisr/webhook-demo:0.0.15", "karolisr/sidecar:0.1.0")
+	planned.SetSpecAnnotations(map[string]string{"kubectl.kubernetes.io/restartedAt": "2018-10-02T12:00:00Z"})
+	plan := &UpdatePlan{
+		Resource:   planned,
+		NewVersion: "0.0.15",
+		containers: []string{"wd"},
+		envVars:    []string{"wd/WORKER_IMAGE"},
+	}
+
+	// sidecar and env var were changed in the meantime
+	latest := retryDeployment(t, "2", "karolisr/webhook-demo:0.0.14", "karolisr/sidecar:0.2.0")
+
+	rebased := rebase(plan, latest)
+	if v := rebased.GetResource().(*apps_v1.Deployment).ResourceVersion; v != "2" {
+		t.Errorf("expected latest resource version, got: %s", v)
+	}
+	containers := rebased.Containers()
+	if containers[0].Image != "karolisr/webhook-demo:0.0.15" || containers[1].Image != "karolisr/sidecar:0.2.0" {
+		t.Errorf("unexpected images: %s, %s", containers[0].Image, containers[1].Image)
+	}
+	if containers[0].Env[0].Value != "karolisr/webhook-demo:0.0.15" || containers[0].Env[1].Value != "2" {
+		t.Errorf("unexpected env: %+v", containers[0].Env)
+	}
+	if rebased.GetSpecAnnotations()["kubectl.kubernetes.io/restartedAt"] != "2018-10-02T12:00:00Z" {
+		t.Errorf("expected pod template annotations of the plan")
+	}
+	if latest.Containers()[0].Image != "karolisr/webhook-demo:0.0.14" {
+		t.Errorf("latest resource shouldn't be modified")
+	}
+}
+
+func TestRetryUpdate(t *testing.T) {
+	p := &Provider{stop: make(chan struct{})}
+	p.SetUpdateAttempts(3)
+	conflict := apierrors.NewConflict(schema.GroupResource{Group: "apps", Resource: "deployments"}, "dep-1", errors.New("object has been modified"))
+
+	plan := &UpdatePlan{Resource: retryDeployment(t, "1", "karolisr/webhook-demo:0.0.15", "karolisr/sidecar:0.1.0")}
+	if p.retryUpdate(plan, errors.New("invalid image")) {
+		t.Errorf("non transient errors shouldn't be retried")
+	}
+
+	// retries are cancelled right away, waiting for them keeps the plan owned
+	// by the test between attempts
+	close(p.stop)
+	for attempt := 1; attempt <= 2; attempt++ {
+		if !p.retryUpdate(plan, conflict) {
+			t.Fatalf("expected conflict to be retried, attempt: %d", attempt)
+		}
+		p.inFlight.Wait()
+	}
+	if plan.attempts != 2 || plan.retryBackoff != 2*time.Second {
+		t.Errorf("unexpected attempts: %d, backoff: %s", plan.attempts, plan.retryBackoff)
+	}
+	if p.retryUpdate(plan, conflict) {
+		t.Errorf("expected failure to be reported once attempts run out")
+	}
+	p.inFlight.Wait()
+
+	fresh := &UpdatePlan{Resource: retryDeployment(t, "1", "karolisr/webhook-demo:0.0.15", "karolisr/sidecar:0.1.0")}
+	if !p.retryUpdate(fresh, conflict) {
+		t.Errorf("attempts of other plans shouldn't be affected")
+	}
+	p.inFlight.Wait()
+}
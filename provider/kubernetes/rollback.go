@@ -0,0 +1,148 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SetApprovalsChannels - sets chat channels that receive approval requests,
+// automatic rollbacks are reported to them as well
+func (p *Provider) SetApprovalsChannels(channels []string) {
+	p.approvalsChannels = channels
+}
+
+func rollbackEnabled(annotations map[string]string) bool {
+	value := annotations[types.KeelRollbackAnnotation]
+	return value == "1" || value == "true"
+}
+
+// rollbackResource - resource with updated containers set back to the previous
+// version, latest cached version of the resource is used when it's available
+func (p *Provider) rollbackResource(plan *UpdatePlan) (*k8s.GenericResource, error) {
+	resource := plan.Resource
+	if current := p.cachedResource(resource.Identifier); current != nil {
+		resource = current
+	}
+	resource = resource.DeepCopy()
+
+	rolledBack := false
+	for idx, c := range resource.Containers() {
+		if img, ok := previousImage(c.Image, plan); ok {
+			resource.UpdateContainer(idx, img)
+			rolledBack = true
+		}
+	}
+	for idx, c := range resource.InitContainers() {
+		if img, ok := previousImage(c.Image, plan); ok {
+			resource.UpdateInitContainer(idx, img)
+			rolledBack = true
+		}
+	}
+	if !rolledBack {
+		return nil, fmt.Errorf("no containers with version %s found", plan.NewVersion)
+	}
+
+	setUpdateTime(resource)
+	annotations := resource.GetAnnotations()
+	annotations["kubernetes.io/change-cause"] = fmt.Sprintf("keel automated rollback, version %s -> %s [%s]", plan.NewVersion, plan.CurrentVersion, time.Now().Format(time.RFC3339))
+	resource.SetAnnotations(annotations)
+
+	return resource, nil
+}
+
+// previousImage - image with the version before the update, only images with
+// the new version were updated
+func previousImage(img string, plan *UpdatePlan) (string, bool) {
+	ref, err := image.Parse(img)
+	if err != nil || ref.Tag() != plan.NewVersion {
+		return "", false
+	}
+	if ref.Registry() == image.DefaultRegistryHostname {
+		return fmt.Sprintf("%s:%s", ref.ShortName(), plan.CurrentVersion), true
+	}
+	return fmt.Sprintf("%s:%s", ref.Repository(), plan.CurrentVersion), true
+}
+
+// rollback - rolls back resource that failed to roll out to the previous version,
+// the new version is recorded as rolled back so it isn't updated to again
+func (p *Provider) rollback(plan *UpdatePlan, status k8s.RolloutStatus, channels []string) {
+	resource := plan.Resource
+
+	rolledBack, err := p.rollbackResource(plan)
+	if err == nil {
+		err = p.update(rolledBack)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"update":    fmt.Sprintf("%s->%s", plan.CurrentVersion, plan.NewVersion),
+		}).Error("provider.kubernetes: failed to roll back resource")
+
+		p.sendRollbackNotification(plan, fmt.Sprintf("%s %s/%s rollback %s->%s failed, error: %s", resource.Kind(), resource.Namespace, resource.Name, plan.NewVersion, plan.CurrentVersion, err), types.LevelError, channels)
+		return
+	}
+
+	p.recordRollback(plan, status.Message)
+
+	log.WithFields(log.Fields{
+		"name":      resource.Name,
+		"kind":      resource.Kind(),
+		"namespace": resource.Namespace,
+		"previous":  plan.NewVersion,
+		"new":       plan.CurrentVersion,
+	}).Info("provider.kubernetes: resource rolled back")
+
+	p.sendRollbackNotification(plan, fmt.Sprintf("%s %s/%s rolled back %s->%s, version %s won't be updated to again: %s", resource.Kind(), resource.Namespace, resource.Name, plan.NewVersion, plan.CurrentVersion, plan.NewVersion, status.Message), types.LevelWarn, channels)
+}
+
+// sendRollbackNotification - rollbacks are reported to resource notification
+// channels and approvals channels
+func (p *Provider) sendRollbackNotification(plan *UpdatePlan, message string, level types.Level, channels []string) {
+	resource := plan.Resource
+
+	notification := types.EventNotification{
+		Name:         "rollback resource",
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Message:      message,
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        level,
+		Channels:     channels,
+		Metadata: map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+		},
+	}
+	p.sender.Send(notification)
+
+	var approvalsChannels []string
+	for _, c := range p.approvalsChannels {
+		if !containsString(channels, c) {
+			approvalsChannels = append(approvalsChannels, c)
+		}
+	}
+	if len(approvalsChannels) > 0 {
+		notification.Channels = approvalsChannels
+		p.sender.Send(notification)
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
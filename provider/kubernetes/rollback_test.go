@@ -0,0 +1,90 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// updateRecorder - implementer that only records updates
+type updateRecorder struct {
+	Implementer
+	updated []*k8s.GenericResource
+}
+
+func (i *updateRecorder) Update(obj *k8s.GenericResource) error {
+	i.updated = append(i.updated, obj)
+	return nil
+}
+
+func TestRollback(t *testing.T) {
+	gr, err := k8s.NewGenericResource(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "dep-1",
+			Namespace:   "xxxx",
+			Annotations: map[string]string{types.KeelRollbackAnnotation: "true"},
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: core_v1.PodTemplateSpec{
+				Spec: core_v1.PodSpec{
+					Containers: []core_v1.Container{
+						{Image: "gcr.io/v2-namespace/hello-world:1.1.2"},
+						{Image: "karolisr/sidecar:0.1.0"},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create resource: %s", err)
+	}
+
+	cache := &k8s.GenericResourceCache{}
+	cache.Add(gr)
+	implementer := &updateRecorder{}
+	sender := &recordingSender{}
+	p := &Provider{
+		implementer: implementer,
+		cache:       cache,
+		sender:      sender,
+	}
+	p.SetApprovalsChannels([]string{"approvals", "deployments"})
+
+	plan := &UpdatePlan{Resource: gr, CurrentVersion: "1.1.1", NewVersion: "1.1.2"}
+	p.rollback(plan, k8s.RolloutStatus{Failed: true, Message: "deployment exceeded its progress deadline"}, []string{"deployments"})
+
+	if len(implementer.updated) != 1 {
+		t.Fatalf("expected resource to be rolled back, got %d updates", len(implementer.updated))
+	}
+	images := implementer.updated[0].GetImages()
+	if images[0] != "gcr.io/v2-namespace/hello-world:1.1.1" {
+		t.Errorf("unexpected rolled back image: %s", images[0])
+	}
+	if images[1] != "karolisr/sidecar:0.1.0" {
+		t.Errorf("image of not updated container changed: %s", images[1])
+	}
+	if gr.GetImages()[0] != "gcr.io/v2-namespace/hello-world:1.1.2" {
+		t.Errorf("cached resource shouldn't be modified")
+	}
+
+	if len(sender.sent) != 2 {
+		t.Fatalf("expected notifications to resource and approvals channels, got: %d", len(sender.sent))
+	}
+	if sender.sent[1].Channels[0] != "approvals" || len(sender.sent[1].Channels) != 1 {
+		t.Errorf("unexpected approvals channels: %v", sender.sent[1].Channels)
+	}
+}
+
+func TestRollbackEnabled(t *testing.T) {
+	if rollbackEnabled(map[string]string{}) {
+		t.Errorf("rollback should be disabled by default")
+	}
+	if !rollbackEnabled(map[string]string{types.KeelRollbackAnnotation: "1"}) {
+		t.Errorf("expected rollback to be enabled")
+	}
+}
@@ -0,0 +1,193 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultRolloutTimeout - how long to wait for updated resource to roll out
+const DefaultRolloutTimeout = 10 * time.Minute
+
+// rolloutCheckInterval - how often cached resource status is checked
+var rolloutCheckInterval = 5 * time.Second
+
+func rolloutTimeout(annotations map[string]string) time.Duration {
+	if timeout, ok := annotations[types.KeelRolloutTimeoutAnnotation]; ok {
+		d, err := time.ParseDuration(timeout)
+		if err == nil && d > 0 {
+			return d
+		}
+		log.WithFields(log.Fields{
+			"error":   err,
+			"timeout": timeout,
+		}).Warn("provider.kubernetes: invalid rollout timeout, using default")
+	}
+	return DefaultRolloutTimeout
+}
+
+// trafficPercent - percent of traffic routed to the updated revision, only
+// used by resources that support traffic splitting
+func trafficPercent(annotations map[string]string) (int64, bool) {
+	value, ok := annotations[types.KeelTrafficPercentAnnotation]
+	if !ok {
+		return 0, false
+	}
+	percent, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || percent <= 0 || percent > 100 {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"percent": value,
+		}).Warn("provider.kubernetes: invalid traffic percent, traffic won't be shifted")
+		return 0, false
+	}
+	return percent, true
+}
+
+func (p *Provider) cachedResource(identifier string) *k8s.GenericResource {
+	return p.cache.Get(identifier)
+}
+
+// waitForRollout - waits until updated resource is rolled out. Cache is updated by
+// watchers, resources with generation not newer than the one before the update
+// are ignored as they don't reflect the update yet. Progress is called with
+// every observed status of the rollout in progress.
+func (p *Provider) waitForRollout(identifier string, generation int64, timeout time.Duration, progress func(k8s.RolloutStatus)) k8s.RolloutStatus {
+	deadline := time.Now().Add(timeout)
+	last := k8s.RolloutStatus{Message: "update wasn't observed"}
+
+	for {
+		if r := p.cachedResource(identifier); r != nil && r.GetGeneration() > generation {
+			status := r.RolloutStatus()
+			if !status.Done && !status.Failed {
+				progress(status)
+			}
+			last = status
+			if last.Done || last.Failed {
+				return last
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return k8s.RolloutStatus{Failed: true, Message: fmt.Sprintf("timed out after %s: %s", timeout, last.Message)}
+		}
+
+		select {
+		case <-time.After(rolloutCheckInterval):
+		case <-p.stop:
+			return k8s.RolloutStatus{Failed: true, Stopped: true, Message: "provider stopped"}
+		}
+	}
+}
+
+// trackRollout - waits for the rollout and sends success or failure notification
+func (p *Provider) trackRollout(plan *UpdatePlan, generation int64, channels []string) {
+	resource := plan.Resource
+
+	var status k8s.RolloutStatus
+	defer func() {
+		p.rollouts.finish(resource, status.Failed)
+	}()
+
+	if resource.Kind() == "cronjob" {
+		status = resource.RolloutStatus()
+	} else {
+		var progress string
+		reporter := p.newRolloutReporter(plan, channels)
+		status = p.waitForRollout(resource.Identifier, generation, rolloutTimeout(resource.GetAnnotations()), func(s k8s.RolloutStatus) {
+			if s.Progress != progress {
+				progress = s.Progress
+				p.sendRolloutProgress(plan, s, channels)
+			}
+			reporter.report(s)
+		})
+	}
+
+	if status.Failed {
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"status":    status.Message,
+		}).Error("provider.kubernetes: resource rollout failed")
+
+		p.sender.Send(types.EventNotification{
+			Name:         "update resource",
+			ResourceKind: resource.Kind(),
+			Identifier:   resource.Identifier,
+			Message:      fmt.Sprintf("%s %s/%s update %s->%s rollout failed%s: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, progressSuffix(status), status.Message),
+			CreatedAt:    time.Now(),
+			Type:         types.NotificationDeploymentUpdate,
+			Level:        types.LevelError,
+			Channels:     channels,
+			Metadata:     p.planMetadata(plan),
+		})
+
+		if rollbackEnabled(resource.GetAnnotations()) && !status.Stopped {
+			p.rollback(plan, status, channels)
+		}
+		return
+	}
+
+	var msg string
+	releaseNotes := types.ParseReleaseNotesURL(resource.GetAnnotations())
+	if releaseNotes != "" {
+		msg = fmt.Sprintf("Successfully updated %s %s/%s %s->%s (%s), %s. Release notes: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(resource.GetImages(), ", "), status.Message, releaseNotes)
+	} else {
+		msg = fmt.Sprintf("Successfully updated %s %s/%s %s->%s (%s), %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(resource.GetImages(), ", "), status.Message)
+	}
+	if plan.releaseNotes != "" {
+		msg += "\n\n" + plan.releaseNotes
+	}
+
+	p.sender.Send(types.EventNotification{
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Name:         "update resource",
+		Message:      msg,
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        types.LevelSuccess,
+		Channels:     channels,
+		Metadata:     p.planMetadata(plan),
+	})
+}
+
+func progressSuffix(status k8s.RolloutStatus) string {
+	if status.Progress == "" {
+		return ""
+	}
+	return " (" + status.Progress + ")"
+}
+
+// sendRolloutProgress - reports progressive delivery steps, ie: canary
+// rollout moving to the next step or pausing for analysis
+func (p *Provider) sendRolloutProgress(plan *UpdatePlan, status k8s.RolloutStatus, channels []string) {
+	resource := plan.Resource
+
+	log.WithFields(log.Fields{
+		"name":      resource.Name,
+		"kind":      resource.Kind(),
+		"namespace": resource.Namespace,
+		"progress":  status.Progress,
+		"status":    status.Message,
+	}).Info("provider.kubernetes: resource rollout progressing")
+
+	p.sender.Send(types.EventNotification{
+		Name:         "update resource",
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Message:      fmt.Sprintf("%s %s/%s update %s->%s progress: %s, %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, status.Progress, status.Message),
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        types.LevelInfo,
+		Channels:     channels,
+		Metadata:     p.planMetadata(plan),
+	})
+}
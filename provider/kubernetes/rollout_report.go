@@ -0,0 +1,101 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// rolloutReportInterval - how often rollout status of approved updates is
+// reported back to the approval request
+var rolloutReportInterval = 30 * time.Second
+
+// rolloutReporter - reports rollout status of an approved update so approvers
+// can follow the update from the approval request. Reports are debug level
+// notifications, bots pick them up and reply to the approval request.
+type rolloutReporter struct {
+	p        *Provider
+	plan     *UpdatePlan
+	channels []string
+
+	sent time.Time
+	last string
+}
+
+// newRolloutReporter - nil reporter is returned for updates that didn't require
+// approvals, it doesn't report anything
+func (p *Provider) newRolloutReporter(plan *UpdatePlan, channels []string) *rolloutReporter {
+	if plan.approvalIdentifier == "" {
+		return nil
+	}
+	return &rolloutReporter{p: p, plan: plan, channels: channels}
+}
+
+// report - sends rollout status unless it was reported recently or didn't change
+func (r *rolloutReporter) report(status k8s.RolloutStatus) {
+	if r == nil || time.Since(r.sent) < rolloutReportInterval {
+		return
+	}
+
+	resource := r.plan.Resource
+	msg := r.p.rolloutReport(resource, status)
+	if msg == r.last {
+		return
+	}
+	r.sent = time.Now()
+	r.last = msg
+
+	r.p.sender.Send(types.EventNotification{
+		Name:         "rollout progress",
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Message:      fmt.Sprintf("%s %s/%s update %s->%s rolling out: %s", resource.Kind(), resource.Namespace, resource.Name, r.plan.CurrentVersion, r.plan.NewVersion, msg),
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationRolloutProgress,
+		Level:        types.LevelDebug,
+		Channels:     r.channels,
+		Metadata:     r.p.planMetadata(r.plan),
+	})
+}
+
+// rolloutReport - rollout status with ready replicas and problems of the
+// resource pods such as image pull errors
+func (p *Provider) rolloutReport(resource *k8s.GenericResource, status k8s.RolloutStatus) string {
+	parts := []string{status.Message}
+	if status.Progress != "" {
+		parts = append(parts, status.Progress)
+	}
+
+	cached := p.cachedResource(resource.Identifier)
+	if cached == nil {
+		return strings.Join(parts, ", ")
+	}
+	if ready := cached.ReadyReplicas(); ready != "" {
+		parts = append(parts, ready)
+	}
+
+	selector := cached.PodSelector()
+	if selector == "" {
+		return strings.Join(parts, ", ")
+	}
+	pods, err := p.implementer.Pods(cached.Namespace, selector)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      cached.Name,
+			"namespace": cached.Namespace,
+		}).Warn("provider.kubernetes: failed to get pods of the rolling out resource")
+		return strings.Join(parts, ", ")
+	}
+
+	msg := strings.Join(parts, ", ")
+	if problems := k8s.PodProblems(pods.Items); len(problems) > 0 {
+		msg += ". Problems: " + strings.Join(problems, "; ")
+	}
+	return msg
+}
@@ -0,0 +1,88 @@
+package kubernetes
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/keel-hq/keel/internal/k8s"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Scope - namespaces and workloads keel is allowed to track and update, used to
+// keep keel away from namespaces of other tenants (or kube-system). Namespaces can
+// be patterns, ie: team-*. Empty fields allow everything.
+type Scope struct {
+	// Namespaces - only workloads in these namespaces are tracked
+	Namespaces []string
+	// IgnoredNamespaces - workloads in these namespaces are never tracked,
+	// takes precedence over Namespaces
+	IgnoredNamespaces []string
+	// NamespaceSelector - only namespaces with matching labels, ie: keel.sh/enabled=true
+	NamespaceSelector labels.Selector
+	// Selector - only workloads with matching labels
+	Selector labels.Selector
+}
+
+// SetScope - resources out of the scope are neither tracked nor updated
+func (p *Provider) SetScope(scope *Scope) {
+	p.scope = scope
+}
+
+func matchesNamespace(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, namespace); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Check - why the resource is out of scope, empty when it's in scope. Namespace
+// selector fails closed, namespaces without labels (ok is false) aren't allowed.
+func (s *Scope) Check(resource *k8s.GenericResource, namespaceLabels func(namespace string) (labels.Set, bool)) string {
+	if matchesNamespace(s.IgnoredNamespaces, resource.Namespace) {
+		return fmt.Sprintf("namespace %s is ignored", resource.Namespace)
+	}
+	if len(s.Namespaces) > 0 && !matchesNamespace(s.Namespaces, resource.Namespace) {
+		return fmt.Sprintf("namespace %s is not in tracked namespaces", resource.Namespace)
+	}
+	if s.Selector != nil && !s.Selector.Matches(labels.Set(resource.GetLabels())) {
+		return fmt.Sprintf("workload doesn't match selector %s", s.Selector)
+	}
+	if s.NamespaceSelector != nil {
+		set, ok := namespaceLabels(resource.Namespace)
+		if !ok || !s.NamespaceSelector.Matches(set) {
+			return fmt.Sprintf("namespace %s doesn't match selector %s", resource.Namespace, s.NamespaceSelector)
+		}
+	}
+	return ""
+}
+
+// outOfScope - why the resource is out of scope, namespace labels are cached
+func (p *Provider) outOfScope(resource *k8s.GenericResource) string {
+	if p.scope == nil {
+		return ""
+	}
+	return p.scope.Check(resource, func(namespace string) (labels.Set, bool) {
+		ns := p.cachedNamespace(namespace)
+		if ns == nil {
+			return nil, false
+		}
+		return ns.labels, true
+	})
+}
+
+// inScope - whether resource can be tracked and updated
+func (p *Provider) inScope(resource *k8s.GenericResource) bool {
+	return p.outOfScope(resource) == ""
+}
+
+// update - all resource writes (updates, rollbacks) go through the scope check,
+// so no code path can modify resources out of scope
+func (p *Provider) update(resource *k8s.GenericResource) error {
+	if reason := p.outOfScope(resource); reason != "" {
+		return fmt.Errorf("resource %s is out of keel scope: %s", resource.Identifier, reason)
+	}
+	return p.implementer.Update(resource)
+}
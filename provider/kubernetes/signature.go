@@ -0,0 +1,115 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/keel-hq/keel/internal/cosign"
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SignatureVerifier - verifies image signatures, ie: cosign
+type SignatureVerifier interface {
+	Verify(opts registry.Opts, digest string) (*cosign.Result, error)
+}
+
+// SetSignatureVerifier - sets verifier of images of resources with keel.sh/verifySignature annotation
+func (p *Provider) SetSignatureVerifier(verifier SignatureVerifier) {
+	p.signatureVerifier = verifier
+}
+
+func signatureVerificationEnabled(annotations map[string]string) bool {
+	value := annotations[types.KeelVerifySignatureAnnotation]
+	return value == "1" || value == "true"
+}
+
+// signatureRequired - whether the resource policy (ie: "semver:minor AND signed")
+// or keel.sh/verifySignature annotation requires signed images
+func (p *Provider) signatureRequired(resource *k8s.GenericResource) bool {
+	if plc, ok := p.resourcePolicy(resource).(interface{ RequiresSignature() bool }); ok && plc.RequiresSignature() {
+		return true
+	}
+	return signatureVerificationEnabled(resource.GetAnnotations())
+}
+
+// verifySignatures - drops plans of resources with signature verification enabled
+// when the new image digest isn't signed, verification results are sent as
+// notifications so they are kept in the audit log
+func (p *Provider) verifySignatures(event *types.Event, plans []*UpdatePlan) []*UpdatePlan {
+	var verified []*UpdatePlan
+	for _, plan := range plans {
+		resource := plan.Resource
+		if !p.signatureRequired(resource) {
+			verified = append(verified, plan)
+			continue
+		}
+
+		signer, digest, err := p.verifySignature(plan, &event.Repository)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"name":      resource.Name,
+				"kind":      resource.Kind(),
+				"namespace": resource.Namespace,
+				"image":     event.Repository.String(),
+			}).Error("provider.kubernetes: image signature verification failed, resource won't be updated")
+
+			p.sendSignatureNotification(plan, types.LevelError,
+				fmt.Sprintf("%s %s/%s update %s->%s blocked, signature verification of %s failed: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, digest, err))
+			continue
+		}
+
+		plan.Signer = signer
+		p.sendSignatureNotification(plan, types.LevelInfo,
+			fmt.Sprintf("%s %s/%s new image %s (%s) is signed by %s", resource.Kind(), resource.Namespace, resource.Name, event.Repository.String(), digest, signer))
+		verified = append(verified, plan)
+	}
+	return verified
+}
+
+// verifySignature - returns signer and verified digest, the digest is resolved
+// unless images are already pinned
+func (p *Provider) verifySignature(plan *UpdatePlan, repo *types.Repository) (signer, digest string, err error) {
+	if p.signatureVerifier == nil {
+		return "", "", fmt.Errorf("signature verification is not configured")
+	}
+
+	digest, err = p.planDigest(plan, repo)
+	if err != nil {
+		return "", "", err
+	}
+
+	opts, err := registryOpts(plan.Resource, repo)
+	if err != nil {
+		return "", digest, err
+	}
+
+	result, err := p.signatureVerifier.Verify(opts, digest)
+	if err != nil {
+		return "", digest, err
+	}
+	return result.Signer, digest, nil
+}
+
+func (p *Provider) sendSignatureNotification(plan *UpdatePlan, level types.Level, message string) {
+	resource := plan.Resource
+	p.sender.Send(types.EventNotification{
+		Name:         "verify image signature",
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Message:      message,
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationSignatureVerification,
+		Level:        level,
+		Channels:     types.ParseEventNotificationChannels(resource.GetAnnotations()),
+		Metadata: map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+		},
+	})
+}
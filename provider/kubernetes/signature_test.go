@@ -0,0 +1,85 @@
+package kubernetes
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/keel-hq/keel/internal/cosign"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+)
+
+type fakeVerifier struct {
+	signed map[string]string
+}
+
+func (v *fakeVerifier) Verify(opts registry.Opts, digest string) (*cosign.Result, error) {
+	signer, ok := v.signed[digest]
+	if !ok {
+		return nil, fmt.Errorf("no valid signatures")
+	}
+	return &cosign.Result{Signer: signer}, nil
+}
+
+func TestVerifySignatures(t *testing.T) {
+	sender := &recordingSender{}
+	p := &Provider{
+		sender:            sender,
+		registryClient:    &digestRegistry{},
+		signatureVerifier: &fakeVerifier{signed: map[string]string{testDigest: "release@example.com"}},
+	}
+
+	verified := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{types.KeelVerifySignatureAnnotation: "true"})}
+	unverified := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{})}
+
+	event := &types.Event{Repository: types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.15"}}
+	plans := p.verifySignatures(event, []*UpdatePlan{verified, unverified})
+	if len(plans) != 2 {
+		t.Fatalf("expected both plans to be kept, got: %d", len(plans))
+	}
+	if verified.Signer != "release@example.com" {
+		t.Errorf("unexpected signer: %s", verified.Signer)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].Type != types.NotificationSignatureVerification || sender.sent[0].Level != types.LevelInfo {
+		t.Errorf("expected verification notification, got: %v", sender.sent)
+	}
+}
+
+func TestVerifySignaturesUnsigned(t *testing.T) {
+	sender := &recordingSender{}
+	p := &Provider{
+		sender:            sender,
+		signatureVerifier: &fakeVerifier{},
+	}
+
+	plan := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{types.KeelVerifySignatureAnnotation: "true"})}
+	event := &types.Event{Repository: types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.15", Digest: testDigest}}
+
+	plans := p.verifySignatures(event, []*UpdatePlan{plan})
+	if len(plans) != 0 {
+		t.Errorf("expected update of unsigned image to be blocked")
+	}
+	if len(sender.sent) != 1 || sender.sent[0].Level != types.LevelError {
+		t.Errorf("expected verification failure notification, got: %v", sender.sent)
+	}
+
+	// verification isn't configured
+	p.signatureVerifier = nil
+	if plans := p.verifySignatures(event, []*UpdatePlan{plan}); len(plans) != 0 {
+		t.Errorf("expected update to be blocked without verifier")
+	}
+}
+
+func TestVerifySignaturesRequiredByPolicy(t *testing.T) {
+	p := &Provider{
+		sender:            &recordingSender{},
+		signatureVerifier: &fakeVerifier{},
+	}
+
+	plan := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{types.KeelPolicyLabel: "semver:minor AND signed"})}
+	event := &types.Event{Repository: types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.15", Digest: testDigest}}
+
+	if plans := p.verifySignatures(event, []*UpdatePlan{plan}); len(plans) != 0 {
+		t.Errorf("expected policy to require signed image")
+	}
+}
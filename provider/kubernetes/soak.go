@@ -0,0 +1,201 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultSoakTime - soak time of promotions without keel.sh/soakTime annotation
+const defaultSoakTime = 24 * time.Hour
+
+// promotion - source environment of the resource updates from keel.sh/promoteFrom
+// and keel.sh/soakTime annotations
+type promotion struct {
+	// source - annotation value shown in approvals, ie: staging or eu/staging
+	source    string
+	provider  string
+	namespace string
+	soakTime  time.Duration
+}
+
+// resourcePromotion - promotion source of the resource, cluster is optional and
+// defaults to the cluster of the provider
+func (p *Provider) resourcePromotion(resource *k8s.GenericResource) (*promotion, bool) {
+	annotations := resource.GetAnnotations()
+	source := strings.TrimSpace(annotations[types.KeelPromoteFromAnnotation])
+	if source == "" {
+		return nil, false
+	}
+
+	promo := &promotion{
+		source:    source,
+		provider:  p.GetName(),
+		namespace: source,
+		soakTime:  defaultSoakTime,
+	}
+	if parts := strings.SplitN(source, "/", 2); len(parts) == 2 {
+		promo.provider = ProviderName + "/" + parts[0]
+		promo.namespace = parts[1]
+	}
+
+	if value, ok := annotations[types.KeelSoakTimeAnnotation]; ok {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"soakTime":  value,
+				"name":      resource.Name,
+				"namespace": resource.Namespace,
+			}).Warn("provider.kubernetes: invalid soak time, using default")
+		} else {
+			promo.soakTime = d
+		}
+	}
+	return promo, true
+}
+
+// soak - how the tag ran in the promotion source
+type soak struct {
+	// soaked - longest time the tag ran in a workload of the source
+	soaked time.Duration
+	// running - tag is still running in the source
+	running bool
+	// rolledBack - tag was rolled back in the source, it won't be promoted
+	rolledBack bool
+}
+
+// soakOf - soak of the tag in the update history of the source namespace, tag runs
+// from its successful update until the workload is updated again. Failed updates
+// don't replace the running tag.
+func soakOf(records []*types.UpdateRecord, tag string, now time.Time) soak {
+	workloads := make(map[string][]*types.UpdateRecord)
+	for _, r := range records {
+		if r.Status == types.UpdateStatusFailed {
+			continue
+		}
+		workloads[r.Identifier] = append(workloads[r.Identifier], r)
+	}
+
+	var result soak
+	for _, history := range workloads {
+		sort.SliceStable(history, func(i, j int) bool {
+			return history[i].CreatedAt.Before(history[j].CreatedAt)
+		})
+		for i, r := range history {
+			if r.NewTag != tag {
+				continue
+			}
+			if r.Status == types.UpdateStatusRolledBack {
+				result.rolledBack = true
+				continue
+			}
+			if r.Status != types.UpdateStatusSuccess {
+				continue
+			}
+			end := now
+			if i+1 < len(history) {
+				end = history[i+1].CreatedAt
+			} else {
+				result.running = true
+			}
+			if d := end.Sub(r.CreatedAt); d > result.soaked {
+				result.soaked = d
+			}
+		}
+	}
+	return result
+}
+
+// soakDuration - soak time shown in approvals, ie: 26h or 45m
+func soakDuration(d time.Duration) string {
+	if d >= time.Hour {
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	}
+	return fmt.Sprintf("%dm", int(d/time.Minute))
+}
+
+// checkSoak - holds back plans of resources promoted from another environment until
+// the new tag has run there successfully for the soak time, the event is submitted
+// again once it could have. Plans of tags rolled back or replaced in the source
+// before they soaked are dropped.
+func (p *Provider) checkSoak(event *types.Event, plans []*UpdatePlan) []*UpdatePlan {
+	var retry time.Duration
+
+	var ready []*UpdatePlan
+	for _, plan := range plans {
+		resource := plan.Resource
+		promo, ok := p.resourcePromotion(resource)
+		if !ok {
+			ready = append(ready, plan)
+			continue
+		}
+
+		fields := log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"image":     event.Repository.String(),
+			"source":    promo.source,
+		}
+
+		if p.store == nil {
+			log.WithFields(fields).Error("provider.kubernetes: update history is not available, promoted resource won't be updated")
+			continue
+		}
+
+		records, err := p.store.ListUpdateRecords(&types.UpdateRecordQuery{
+			Namespace: promo.namespace,
+			Image:     updatedImage(plan),
+			Provider:  promo.provider,
+		})
+		if err != nil {
+			fields["error"] = err
+			log.WithFields(fields).Error("provider.kubernetes: failed to get update history of promotion source, resource won't be updated")
+			continue
+		}
+
+		s := soakOf(records, plan.NewVersion, timeutil.Now())
+		switch {
+		case s.rolledBack:
+			log.WithFields(fields).Warn("provider.kubernetes: new version was rolled back in promotion source, resource won't be updated")
+			continue
+		case s.soaked >= promo.soakTime && (s.soaked > 0 || s.running):
+			plan.soaked = soakDuration(s.soaked)
+			plan.soakSource = promo.source
+			ready = append(ready, plan)
+			continue
+		case s.soaked > 0 && !s.running:
+			log.WithFields(fields).Info("provider.kubernetes: new version was replaced in promotion source before it soaked, resource won't be updated")
+			continue
+		}
+
+		// still soaking or not deployed to the source yet, tags that aren't there yet
+		// can't be ready sooner than the soak time
+		remaining := promo.soakTime - s.soaked
+		if remaining <= 0 {
+			remaining = time.Minute
+		}
+
+		fields["soaked"] = s.soaked
+		fields["remaining"] = remaining
+		log.WithFields(fields).Info("provider.kubernetes: new version hasn't soaked in promotion source, update postponed")
+
+		if retry == 0 || remaining < retry {
+			retry = remaining
+		}
+	}
+
+	if retry > 0 {
+		p.submitAfter(*event, retry.Round(time.Second)+time.Second)
+	}
+
+	return ready
+}
@@ -0,0 +1,140 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+)
+
+type historyStore struct {
+	store.Store
+	records []*types.UpdateRecord
+	queries []*types.UpdateRecordQuery
+}
+
+func (s *historyStore) ListUpdateRecords(query *types.UpdateRecordQuery) ([]*types.UpdateRecord, error) {
+	s.queries = append(s.queries, query)
+	var records []*types.UpdateRecord
+	for _, r := range s.records {
+		if r.Namespace == query.Namespace && r.Provider == query.Provider {
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
+func TestSoakOf(t *testing.T) {
+	now := time.Date(2018, 10, 2, 12, 0, 0, 0, time.UTC)
+	record := func(identifier, tag, status string, ago time.Duration) *types.UpdateRecord {
+		return &types.UpdateRecord{Identifier: identifier, NewTag: tag, Status: status, CreatedAt: now.Add(-ago)}
+	}
+
+	tests := []struct {
+		name    string
+		records []*types.UpdateRecord
+		want    soak
+	}{
+		{
+			name: "running",
+			records: []*types.UpdateRecord{
+				record("deployment/staging/wd", "0.0.15", types.UpdateStatusSuccess, 26*time.Hour),
+				record("deployment/staging/wd", "0.0.14", types.UpdateStatusSuccess, 48*time.Hour),
+			},
+			want: soak{soaked: 26 * time.Hour, running: true},
+		},
+		{
+			name: "replaced",
+			records: []*types.UpdateRecord{
+				record("deployment/staging/wd", "0.0.16", types.UpdateStatusSuccess, 20*time.Hour),
+				record("deployment/staging/wd", "0.0.15", types.UpdateStatusSuccess, 26*time.Hour),
+			},
+			want: soak{soaked: 6 * time.Hour},
+		},
+		{
+			name: "failed update doesn't replace tag",
+			records: []*types.UpdateRecord{
+				record("deployment/staging/wd", "0.0.16", types.UpdateStatusFailed, 20*time.Hour),
+				record("deployment/staging/wd", "0.0.15", types.UpdateStatusSuccess, 26*time.Hour),
+			},
+			want: soak{soaked: 26 * time.Hour, running: true},
+		},
+		{
+			name: "longest of workloads",
+			records: []*types.UpdateRecord{
+				record("deployment/staging/worker", "0.0.15", types.UpdateStatusSuccess, 2*time.Hour),
+				record("deployment/staging/wd", "0.0.15", types.UpdateStatusSuccess, 10*time.Hour),
+			},
+			want: soak{soaked: 10 * time.Hour, running: true},
+		},
+		{
+			name: "rolled back",
+			records: []*types.UpdateRecord{
+				record("deployment/staging/wd", "0.0.15", types.UpdateStatusRolledBack, 25*time.Hour),
+				record("deployment/staging/wd", "0.0.15", types.UpdateStatusSuccess, 26*time.Hour),
+			},
+			want: soak{soaked: time.Hour, rolledBack: true},
+		},
+		{
+			name:    "not deployed",
+			records: []*types.UpdateRecord{record("deployment/staging/wd", "0.0.14", types.UpdateStatusSuccess, time.Hour)},
+			want:    soak{},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := soakOf(tt.records, "0.0.15", now); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckSoak(t *testing.T) {
+	now := time.Date(2018, 10, 2, 12, 0, 0, 0, time.UTC)
+	timeutil.Now = func() time.Time { return now }
+	defer func() { timeutil.Now = time.Now }()
+
+	history := &historyStore{records: []*types.UpdateRecord{
+		{Provider: "kubernetes", Namespace: "staging", Identifier: "deployment/staging/wd", NewTag: "0.0.15", Status: types.UpdateStatusSuccess, CreatedAt: now.Add(-26 * time.Hour)},
+		{Provider: "kubernetes/eu", Namespace: "staging", Identifier: "deployment/staging/wd", NewTag: "0.0.15", Status: types.UpdateStatusSuccess, CreatedAt: now.Add(-2 * time.Hour)},
+	}}
+	p := &Provider{
+		store:  history,
+		events: make(chan *types.Event, 1),
+		stop:   make(chan struct{}),
+	}
+	defer close(p.stop)
+
+	soaked := &UpdatePlan{NewVersion: "0.0.15", Resource: pinnedDeployment(t, map[string]string{types.KeelPromoteFromAnnotation: "staging"})}
+	soaking := &UpdatePlan{NewVersion: "0.0.15", Resource: pinnedDeployment(t, map[string]string{types.KeelPromoteFromAnnotation: "eu/staging", types.KeelSoakTimeAnnotation: "4h"})}
+	unset := &UpdatePlan{NewVersion: "0.0.15", Resource: pinnedDeployment(t, map[string]string{})}
+
+	event := &types.Event{Repository: types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.15"}}
+	plans := p.checkSoak(event, []*UpdatePlan{soaked, soaking, unset})
+	if len(plans) != 2 || plans[0] != soaked || plans[1] != unset {
+		t.Fatalf("expected only soaking plan to be postponed, got: %v", plans)
+	}
+	if soaked.soaked != "26h" || soaked.soakSource != "staging" {
+		t.Errorf("unexpected soak: %s in %s", soaked.soaked, soaked.soakSource)
+	}
+
+	if len(history.queries) != 2 || history.queries[1].Provider != "kubernetes/eu" || history.queries[1].Image != "index.docker.io/karolisr/webhook-demo" {
+		t.Errorf("unexpected history queries: %+v", history.queries)
+	}
+
+	if _, pending := p.postponed.Load(event.Repository.String()); !pending {
+		t.Errorf("expected event to be submitted again")
+	}
+}
+
+func TestCheckSoakWithoutStore(t *testing.T) {
+	p := &Provider{}
+	plan := &UpdatePlan{NewVersion: "0.0.15", Resource: pinnedDeployment(t, map[string]string{types.KeelPromoteFromAnnotation: "staging"})}
+
+	plans := p.checkSoak(&types.Event{Repository: types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.15"}}, []*UpdatePlan{plan})
+	if len(plans) != 0 {
+		t.Errorf("expected plan to be dropped when update history isn't available")
+	}
+}
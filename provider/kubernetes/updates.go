@@ -9,10 +9,46 @@ import (
 	"github.com/keel-hq/keel/types"
 	"github.com/keel-hq/keel/util/image"
 
+	core_v1 "k8s.io/api/core/v1"
+
 	log "github.com/sirupsen/logrus"
 )
 
 func checkForUpdate(plc policy.Policy, repo *types.Repository, resource *k8s.GenericResource) (updatePlan *UpdatePlan, shouldUpdateDeployment bool, err error) {
+	return checkForContainerUpdates(plc, nil, repo, resource)
+}
+
+// containerPolicies - container policy overrides by container name
+type containerPolicies map[string]policy.Policy
+
+// get - container policy, resource policy when the container doesn't override it
+func (c containerPolicies) get(plc policy.Policy, container string) policy.Policy {
+	if override, ok := c[container]; ok {
+		return override
+	}
+	return plc
+}
+
+// resourceContainerPolicies - policies of containers with keel.sh/policy.<container name>
+// annotation, ie: sidecar only takes patches while the app follows minor updates
+func resourceContainerPolicies(resource *k8s.GenericResource) containerPolicies {
+	var policies containerPolicies
+	for _, c := range resourceContainers(resource) {
+		plc, ok := policy.GetContainerPolicy(c.Name, resource.GetLabels(), resource.GetAnnotations())
+		if !ok {
+			continue
+		}
+		if policies == nil {
+			policies = make(containerPolicies)
+		}
+		policies[c.Name] = plc
+	}
+	return policies
+}
+
+// checkForContainerUpdates - checks containers with their own policies, containers
+// without overrides use resource policy
+func checkForContainerUpdates(plc policy.Policy, policies containerPolicies, repo *types.Repository, resource *k8s.GenericResource) (updatePlan *UpdatePlan, shouldUpdateDeployment bool, err error) {
 	updatePlan = &UpdatePlan{}
 
 	eventRepoRef, err := image.Parse(repo.String())
@@ -28,69 +64,136 @@ func checkForUpdate(plc policy.Policy, repo *types.Repository, resource *k8s.Gen
 	}).Debug("provider.kubernetes.checkVersionedDeployment: keel policy found, checking resource...")
 	shouldUpdateDeployment = false
 	for idx, c := range resource.Containers() {
-		containerImageRef, err := image.Parse(c.Image)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"error":      err,
-				"image_name": c.Image,
-			}).Error("provider.kubernetes: failed to parse image name")
+		currentTag, updatedImage, ok := containerUpdate(policies.get(plc, c.Name), repo, eventRepoRef, resource, c)
+		if !ok {
 			continue
 		}
 
-		log.WithFields(log.Fields{
-			"name":              resource.Name,
-			"namespace":         resource.Namespace,
-			"kind":              resource.Kind(),
-			"parsed_image_name": containerImageRef.Remote(),
-			"target_image_name": repo.Name,
-			"target_tag":        repo.Tag,
-			"policy":            plc.Name(),
-			"image":             c.Image,
-		}).Debug("provider.kubernetes: checking image")
-
-		if containerImageRef.Repository() != eventRepoRef.Repository() {
-			log.WithFields(log.Fields{
-				"parsed_image_name": containerImageRef.Remote(),
-				"target_image_name": repo.Name,
-			}).Debug("provider.kubernetes: images do not match, ignoring")
-			continue
-		}
+		// updating spec template annotations
+		setUpdateTime(resource)
 
-		shouldUpdateContainer, err := plc.ShouldUpdate(containerImageRef.Tag(), eventRepoRef.Tag())
-		if err != nil {
-			log.WithFields(log.Fields{
-				"error":             err,
-				"parsed_image_name": containerImageRef.Remote(),
-				"target_image_name": repo.Name,
-				"policy":            plc.Name(),
-			}).Error("provider.kubernetes: failed to check whether container should be updated")
-			continue
-		}
+		// updating image
+		resource.UpdateContainer(idx, updatedImage)
+
+		shouldUpdateDeployment = true
+		updatePlan.trackContainer(c)
+
+		updatePlan.CurrentVersion = currentTag
+		updatePlan.NewVersion = repo.Tag
+		updatePlan.Resource = resource
+	}
 
-		if !shouldUpdateContainer {
+	// init containers are updated with the same policy as containers
+	for idx, c := range resource.InitContainers() {
+		currentTag, updatedImage, ok := containerUpdate(policies.get(plc, c.Name), repo, eventRepoRef, resource, c)
+		if !ok {
 			continue
 		}
 
-		// updating spec template annotations
 		setUpdateTime(resource)
-
-		// updating image
-		if containerImageRef.Registry() == image.DefaultRegistryHostname {
-			resource.UpdateContainer(idx, fmt.Sprintf("%s:%s", containerImageRef.ShortName(), repo.Tag))
-		} else {
-			resource.UpdateContainer(idx, fmt.Sprintf("%s:%s", containerImageRef.Repository(), repo.Tag))
-		}
+		resource.UpdateInitContainer(idx, updatedImage)
 
 		shouldUpdateDeployment = true
+		updatePlan.trackContainer(c)
 
-		updatePlan.CurrentVersion = containerImageRef.Tag()
+		if updatePlan.CurrentVersion == "" {
+			updatePlan.CurrentVersion = currentTag
+		}
 		updatePlan.NewVersion = repo.Tag
 		updatePlan.Resource = resource
 	}
 
+	// images referenced by env vars listed in keel.sh/envImages
+	if updateEnvImages(plc, policies, repo, eventRepoRef, resource, updatePlan, resource.Containers(), resource.UpdateContainerEnv) {
+		shouldUpdateDeployment = true
+	}
+	if updateEnvImages(plc, policies, repo, eventRepoRef, resource, updatePlan, resource.InitContainers(), resource.UpdateInitContainerEnv) {
+		shouldUpdateDeployment = true
+	}
+
 	return updatePlan, shouldUpdateDeployment, nil
 }
 
+// trackContainer - remembers the updated container for the update history
+func (p *UpdatePlan) trackContainer(c core_v1.Container) {
+	p.containers = append(p.containers, c.Name)
+	if p.previousDigest != "" {
+		return
+	}
+	if ref, err := image.Parse(c.Image); err == nil {
+		p.previousDigest = ref.Digest()
+	}
+}
+
+// containerUpdate - checks whether container image should be updated to the event
+// tag, returns current container tag and updated image
+func containerUpdate(plc policy.Policy, repo *types.Repository, eventRepoRef *image.Reference, resource *k8s.GenericResource, c core_v1.Container) (currentTag, updatedImage string, ok bool) {
+	return imageUpdate(plc, repo, eventRepoRef, resource, c.Image)
+}
+
+// imageUpdate - checks whether image (of a container, env var or ConfigMap) should
+// be updated to the event tag, returns current tag and updated image
+func imageUpdate(plc policy.Policy, repo *types.Repository, eventRepoRef *image.Reference, resource *k8s.GenericResource, img string) (currentTag, updatedImage string, ok bool) {
+	containerImageRef, err := image.Parse(img)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"image_name": img,
+		}).Error("provider.kubernetes: failed to parse image name")
+		return "", "", false
+	}
+
+	log.WithFields(log.Fields{
+		"name":              resource.Name,
+		"namespace":         resource.Namespace,
+		"kind":              resource.Kind(),
+		"parsed_image_name": containerImageRef.Remote(),
+		"target_image_name": repo.Name,
+		"target_tag":        repo.Tag,
+		"policy":            plc.Name(),
+		"image":             img,
+	}).Debug("provider.kubernetes: checking image")
+
+	if containerImageRef.Repository() != eventRepoRef.Repository() {
+		log.WithFields(log.Fields{
+			"parsed_image_name": containerImageRef.Remote(),
+			"target_image_name": repo.Name,
+		}).Debug("provider.kubernetes: images do not match, ignoring")
+		return "", "", false
+	}
+
+	shouldUpdateContainer, err := policy.ShouldUpdate(plc, containerImageRef.Tag(), eventRepoRef.Tag(), policy.Metadata{
+		Registry:   eventRepoRef.Registry(),
+		Repository: eventRepoRef.Repository(),
+		Digest:     repo.Digest,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":             err,
+			"parsed_image_name": containerImageRef.Remote(),
+			"target_image_name": repo.Name,
+			"policy":            plc.Name(),
+		}).Error("provider.kubernetes: failed to check whether container should be updated")
+		return "", "", false
+	}
+
+	if !shouldUpdateContainer {
+		return "", "", false
+	}
+
+	if containerImageRef.Registry() == image.DefaultRegistryHostname {
+		return containerImageRef.Tag(), fmt.Sprintf("%s:%s", containerImageRef.ShortName(), repo.Tag), true
+	}
+	return containerImageRef.Tag(), fmt.Sprintf("%s:%s", containerImageRef.Repository(), repo.Tag), true
+}
+
+// resourceContainers - containers and init containers of the resource
+func resourceContainers(resource *k8s.GenericResource) []core_v1.Container {
+	var containers []core_v1.Container
+	containers = append(containers, resource.Containers()...)
+	return append(containers, resource.InitContainers()...)
+}
+
 func setUpdateTime(resource *k8s.GenericResource) {
 	specAnnotations := resource.GetSpecAnnotations()
 	specAnnotations[types.KeelUpdateTimeAnnotation] = time.Now().String()
@@ -66,6 +66,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "hello-world",
 										Image: "gcr.io/v2-namespace/hello-world",
 									},
 								},
@@ -94,6 +95,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "hello-world",
 										Image: "gcr.io/v2-namespace/hello-world:latest",
 									},
 								},
@@ -104,6 +106,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 				}),
 				NewVersion:     "latest",
 				CurrentVersion: "latest",
+				containers:     []string{"hello-world"},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -202,6 +205,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "keel",
 										Image: "karolisr/keel:latest",
 									},
 								},
@@ -230,6 +234,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "keel",
 										Image: "karolisr/keel:0.2.0",
 									},
 								},
@@ -240,6 +245,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 				}),
 				NewVersion:     "0.2.0",
 				CurrentVersion: "latest",
+				containers:     []string{"keel"},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -267,6 +273,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "keel",
 										Image: "karolisr/keel:master",
 									},
 								},
@@ -297,6 +304,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "keel",
 										Image: "karolisr/keel:master",
 									},
 								},
@@ -307,6 +315,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 				}),
 				NewVersion:     "master",
 				CurrentVersion: "master",
+				containers:     []string{"keel"},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -337,6 +346,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "keel",
 										Image: "karolisr/keel:latest-staging",
 									},
 								},
@@ -367,6 +377,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "keel",
 										Image: "karolisr/keel:latest-staging",
 									},
 								},
@@ -377,6 +388,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 				}),
 				NewVersion:     "latest-staging",
 				CurrentVersion: "latest-staging",
+				containers:     []string{"keel"},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -407,6 +419,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "keel",
 										Image: "eu.gcr.io/karolisr/keel:latest-staging",
 									},
 								},
@@ -438,6 +451,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "keel",
 										Image: "eu.gcr.io/karolisr/keel:latest-staging",
 									},
 								},
@@ -448,6 +462,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 				}),
 				NewVersion:     "latest-staging",
 				CurrentVersion: "latest-staging",
+				containers:     []string{"keel"},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -512,6 +527,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "keel",
 										Image: "eu.gcr.io/karolisr/keel:latest-staging",
 									},
 								},
@@ -543,6 +559,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "keel",
 										Image: "eu.gcr.io/karolisr/keel:latest-staging",
 									},
 								},
@@ -553,6 +570,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 				}),
 				NewVersion:     "latest-staging",
 				CurrentVersion: "latest-staging",
+				containers:     []string{"keel"},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -582,6 +600,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "keel",
 										Image: "eu.gcr.io/karolisr/keel:release-1",
 									},
 								},
@@ -613,6 +632,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "keel",
 										Image: "eu.gcr.io/karolisr/keel:release-2",
 									},
 								},
@@ -623,6 +643,7 @@ func TestProvider_checkForUpdate(t *testing.T) {
 				}),
 				NewVersion:     "release-2",
 				CurrentVersion: "release-1",
+				containers:     []string{"keel"},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -694,6 +715,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "hello-world",
 										Image: "gcr.io/v2-namespace/hello-world:1.1.1",
 									},
 								},
@@ -722,6 +744,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "hello-world",
 										Image: "gcr.io/v2-namespace/hello-world:1.1.2",
 									},
 								},
@@ -732,6 +755,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 				}),
 				NewVersion:     "1.1.2",
 				CurrentVersion: "1.1.1",
+				containers:     []string{"hello-world"},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -869,6 +893,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "hello-world",
 										Image: "gcr.io/v2-namespace/hello-world:1.1.1",
 									},
 									v1.Container{
@@ -900,6 +925,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "hello-world",
 										Image: "gcr.io/v2-namespace/hello-world:1.1.2",
 									},
 									v1.Container{
@@ -913,6 +939,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 				}),
 				NewVersion:     "1.1.2",
 				CurrentVersion: "1.1.1",
+				containers:     []string{"hello-world"},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -940,6 +967,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "hello-world",
 										Image: "gcr.io/v2-namespace/hello-world:latest",
 									},
 									v1.Container{
@@ -971,6 +999,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "hello-world",
 										Image: "gcr.io/v2-namespace/hello-world:1.1.2",
 									},
 									v1.Container{
@@ -984,6 +1013,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 				}),
 				NewVersion:     "1.1.2",
 				CurrentVersion: "latest",
+				containers:     []string{"hello-world"},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -1014,6 +1044,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "hello-world",
 										Image: "gcr.io/v2-namespace/hello-world:1.1.2",
 									},
 									v1.Container{
@@ -1048,6 +1079,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 							Spec: v1.PodSpec{
 								Containers: []v1.Container{
 									v1.Container{
+										Name:  "hello-world",
 										Image: "gcr.io/v2-namespace/hello-world:1.1.2",
 									},
 									v1.Container{
@@ -1061,6 +1093,7 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 				}),
 				NewVersion:     "1.1.2",
 				CurrentVersion: "1.1.2",
+				containers:     []string{"hello-world"},
 			},
 			wantShouldUpdateDeployment: true,
 			wantErr:                    false,
@@ -1141,3 +1174,96 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 		})
 	}
 }
+
+func TestProvider_checkForUpdateInitContainers(t *testing.T) {
+	gr, err := k8s.NewGenericResource(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "dep-1",
+			Namespace:   "xxxx",
+			Annotations: map[string]string{},
+			Labels:      map[string]string{types.KeelPolicyLabel: "all"},
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					InitContainers: []v1.Container{
+						{Image: "gcr.io/v2-namespace/migrations:1.1.1"},
+					},
+					Containers: []v1.Container{
+						{Image: "gcr.io/v2-namespace/hello-world:1.1.1"},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create resource: %s", err)
+	}
+
+	plan, shouldUpdate, err := checkForUpdate(policy.NewSemverPolicy(policy.SemverPolicyTypeAll), &types.Repository{Name: "gcr.io/v2-namespace/migrations", Tag: "1.1.2"}, gr)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !shouldUpdate {
+		t.Fatalf("expected init container to be updated")
+	}
+	if plan.CurrentVersion != "1.1.1" || plan.NewVersion != "1.1.2" {
+		t.Errorf("unexpected plan versions: %s->%s", plan.CurrentVersion, plan.NewVersion)
+	}
+	if img := gr.InitContainers()[0].Image; img != "gcr.io/v2-namespace/migrations:1.1.2" {
+		t.Errorf("unexpected init container image: %s", img)
+	}
+	if img := gr.Containers()[0].Image; img != "gcr.io/v2-namespace/hello-world:1.1.1" {
+		t.Errorf("container image shouldn't be updated: %s", img)
+	}
+}
+
+func TestProvider_checkForContainerUpdates(t *testing.T) {
+	deployment := func() *k8s.GenericResource {
+		return MustParseGR(&apps_v1.Deployment{
+			meta_v1.TypeMeta{},
+			meta_v1.ObjectMeta{
+				Name:      "dep-1",
+				Namespace: "xxxx",
+				Annotations: map[string]string{
+					types.KeelPolicyLabel:                               "minor",
+					types.KeelPolicyContainerAnnotationPrefix + "proxy": "patch",
+				},
+			},
+			apps_v1.DeploymentSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{
+							{Name: "app", Image: "gcr.io/v2-namespace/app:1.0.0"},
+							{Name: "proxy", Image: "gcr.io/v2-namespace/proxy:1.0.0"},
+						},
+					},
+				},
+			},
+			apps_v1.DeploymentStatus{},
+		})
+	}
+
+	tests := []struct {
+		name       string
+		repo       *types.Repository
+		wantUpdate bool
+	}{
+		{name: "app takes minor", repo: &types.Repository{Name: "gcr.io/v2-namespace/app", Tag: "1.1.0"}, wantUpdate: true},
+		{name: "proxy ignores minor", repo: &types.Repository{Name: "gcr.io/v2-namespace/proxy", Tag: "1.1.0"}, wantUpdate: false},
+		{name: "proxy takes patch", repo: &types.Repository{Name: "gcr.io/v2-namespace/proxy", Tag: "1.0.1"}, wantUpdate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := deployment()
+			plc := policy.GetPolicyFromLabelsOrAnnotations(resource.GetLabels(), resource.GetAnnotations())
+			_, update, err := checkForContainerUpdates(plc, resourceContainerPolicies(resource), tt.repo, resource)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if update != tt.wantUpdate {
+				t.Errorf("expected update %t, got %t", tt.wantUpdate, update)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/keel-hq/keel/internal/vulnscan"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SetScanner - sets vulnerability scanner used for resources with keel.sh/vulnerabilitySeverity annotation
+func (p *Provider) SetScanner(scanner vulnscan.Scanner) {
+	p.scanner = scanner
+}
+
+// vulnerabilityThreshold - severity of vulnerabilities that stop the update
+func vulnerabilityThreshold(annotations map[string]string) (vulnscan.Severity, bool) {
+	value, ok := annotations[types.KeelVulnerabilitySeverityAnnotation]
+	if !ok {
+		return vulnscan.SeverityUnknown, false
+	}
+	severity, err := vulnscan.ParseSeverity(value)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"severity": value,
+		}).Warn("provider.kubernetes: invalid vulnerability severity, using critical")
+		return vulnscan.SeverityCritical, true
+	}
+	return severity, true
+}
+
+func vulnerabilityAction(annotations map[string]string) string {
+	if annotations[types.KeelVulnerabilityActionAnnotation] == types.KeelVulnerabilityActionApproval {
+		return types.KeelVulnerabilityActionApproval
+	}
+	return types.KeelVulnerabilityActionBlock
+}
+
+// scanVulnerabilities - checks new images of resources with vulnerability threshold,
+// updates to vulnerable images are dropped or require an approval. Images that
+// can't be scanned are treated as vulnerable.
+func (p *Provider) scanVulnerabilities(event *types.Event, plans []*UpdatePlan) []*UpdatePlan {
+	reports := make(map[string]*vulnscan.Report)
+
+	var passed []*UpdatePlan
+	for _, plan := range plans {
+		resource := plan.Resource
+		annotations := resource.GetAnnotations()
+		threshold, ok := vulnerabilityThreshold(annotations)
+		if !ok {
+			passed = append(passed, plan)
+			continue
+		}
+
+		var summary string
+		report, err := p.scanPlan(event, plan, reports)
+		if err != nil {
+			summary = fmt.Sprintf("scan failed: %s", err)
+		} else if findings := report.Findings(threshold); len(findings) > 0 {
+			summary = vulnscan.Summary(findings)
+		}
+
+		if summary == "" {
+			passed = append(passed, plan)
+			continue
+		}
+
+		action := vulnerabilityAction(annotations)
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"image":     event.Repository.String(),
+			"findings":  summary,
+			"action":    action,
+		}).Warn("provider.kubernetes: vulnerabilities found in new image")
+
+		if action == types.KeelVulnerabilityActionApproval {
+			plan.vulnerabilities = summary
+			p.sendVulnerabilityNotification(plan, types.LevelWarn,
+				fmt.Sprintf("%s %s/%s update %s->%s requires approval, vulnerabilities of %s severity or higher found: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, threshold, summary))
+			passed = append(passed, plan)
+			continue
+		}
+
+		p.sendVulnerabilityNotification(plan, types.LevelError,
+			fmt.Sprintf("%s %s/%s update %s->%s blocked, vulnerabilities of %s severity or higher found: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, threshold, summary))
+	}
+	return passed
+}
+
+// scanPlan - scans new image of the plan, reports are reused for plans with the same digest
+func (p *Provider) scanPlan(event *types.Event, plan *UpdatePlan, reports map[string]*vulnscan.Report) (*vulnscan.Report, error) {
+	if p.scanner == nil {
+		return nil, fmt.Errorf("vulnerability scanner is not configured")
+	}
+	digest, err := p.planDigest(plan, &event.Repository)
+	if err != nil {
+		return nil, err
+	}
+	if report, ok := reports[digest]; ok {
+		return report, nil
+	}
+	report, err := p.scanner.Scan(event.Repository.String(), digest)
+	if err != nil {
+		return nil, err
+	}
+	reports[digest] = report
+	return report, nil
+}
+
+func (p *Provider) sendVulnerabilityNotification(plan *UpdatePlan, level types.Level, message string) {
+	resource := plan.Resource
+	p.sender.Send(types.EventNotification{
+		Name:         "scan image vulnerabilities",
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Message:      message,
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationVulnerabilityScan,
+		Level:        level,
+		Channels:     types.ParseEventNotificationChannels(resource.GetAnnotations()),
+		Metadata: map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+		},
+	})
+}
@@ -0,0 +1,69 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/internal/vulnscan"
+	"github.com/keel-hq/keel/types"
+)
+
+type fakeScanner struct {
+	report *vulnscan.Report
+	scans  int
+}
+
+func (s *fakeScanner) Scan(image, digest string) (*vulnscan.Report, error) {
+	s.scans++
+	return s.report, nil
+}
+
+func TestScanVulnerabilities(t *testing.T) {
+	sender := &recordingSender{}
+	scanner := &fakeScanner{report: &vulnscan.Report{Vulnerabilities: []vulnscan.Vulnerability{
+		{ID: "CVE-2019-1", Severity: vulnscan.SeverityHigh},
+		{ID: "CVE-2019-2", Severity: vulnscan.SeverityLow},
+	}}}
+	p := &Provider{sender: sender, scanner: scanner}
+
+	blocked := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{types.KeelVulnerabilitySeverityAnnotation: "high"})}
+	approval := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{
+		types.KeelVulnerabilitySeverityAnnotation: "high",
+		types.KeelVulnerabilityActionAnnotation:   types.KeelVulnerabilityActionApproval,
+	})}
+	critical := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{types.KeelVulnerabilitySeverityAnnotation: "critical"})}
+	unset := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{})}
+
+	event := &types.Event{Repository: types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.15", Digest: testDigest}}
+	plans := p.scanVulnerabilities(event, []*UpdatePlan{blocked, approval, critical, unset})
+	if len(plans) != 3 || plans[0] != approval || plans[1] != critical || plans[2] != unset {
+		t.Fatalf("expected vulnerable update to be blocked, got: %v", plans)
+	}
+	if scanner.scans != 1 {
+		t.Errorf("expected image to be scanned once, got: %d", scanner.scans)
+	}
+	if approval.vulnerabilities != "1 high (CVE-2019-1)" {
+		t.Errorf("unexpected findings: %s", approval.vulnerabilities)
+	}
+	if critical.vulnerabilities != "" {
+		t.Errorf("findings below threshold shouldn't require approval: %s", critical.vulnerabilities)
+	}
+
+	if len(sender.sent) != 2 || sender.sent[0].Level != types.LevelError || !strings.Contains(sender.sent[0].Message, "blocked") {
+		t.Errorf("unexpected notifications: %v", sender.sent)
+	}
+}
+
+func TestScanVulnerabilitiesWithoutScanner(t *testing.T) {
+	p := &Provider{sender: &recordingSender{}}
+	plan := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{
+		types.KeelVulnerabilitySeverityAnnotation: "high",
+		types.KeelVulnerabilityActionAnnotation:   types.KeelVulnerabilityActionApproval,
+	})}
+
+	event := &types.Event{Repository: types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.15", Digest: testDigest}}
+	plans := p.scanVulnerabilities(event, []*UpdatePlan{plan})
+	if len(plans) != 1 || !strings.Contains(plan.vulnerabilities, "scan failed") {
+		t.Errorf("expected update to require approval when image can't be scanned, got: %s", plan.vulnerabilities)
+	}
+}
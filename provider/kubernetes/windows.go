@@ -0,0 +1,69 @@
+package kubernetes
+
+import (
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// updateWindows - windows from keel.sh/updateWindow annotation, resources with
+// invalid windows are updated anytime
+func updateWindows(annotations map[string]string) ([]timeutil.Window, bool) {
+	value, ok := annotations[types.KeelUpdateWindowAnnotation]
+	if !ok {
+		return nil, false
+	}
+	windows, err := timeutil.ParseWindows(value)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"window": value,
+		}).Warn("provider.kubernetes: invalid update window, ignoring")
+		return nil, false
+	}
+	return windows, true
+}
+
+// checkUpdateWindows - holds back plans of resources outside of their update
+// windows, the event is submitted again once the next window opens
+func (p *Provider) checkUpdateWindows(event *types.Event, plans []*UpdatePlan) []*UpdatePlan {
+	now := timeutil.Now()
+	var retry time.Duration
+
+	var ready []*UpdatePlan
+	for _, plan := range plans {
+		resource := plan.Resource
+		windows, ok := updateWindows(resource.GetAnnotations())
+		if !ok {
+			ready = append(ready, plan)
+			continue
+		}
+
+		wait := timeutil.UntilWindows(windows, now)
+		if wait == 0 {
+			ready = append(ready, plan)
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"image":     event.Repository.String(),
+			"opens_in":  wait,
+		}).Info("provider.kubernetes: outside of update window, update postponed")
+
+		if retry == 0 || wait < retry {
+			retry = wait
+		}
+	}
+
+	if retry > 0 {
+		p.submitAfter(*event, retry.Round(time.Second)+time.Second)
+	}
+
+	return ready
+}
@@ -0,0 +1,36 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+)
+
+func TestCheckUpdateWindows(t *testing.T) {
+	// Monday noon
+	now := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	timeutil.Now = func() time.Time { return now }
+	defer func() { timeutil.Now = time.Now }()
+
+	p := &Provider{
+		events: make(chan *types.Event, 1),
+		stop:   make(chan struct{}),
+	}
+	defer close(p.stop)
+
+	open := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{types.KeelUpdateWindowAnnotation: "Mon-Fri 09:00-17:00"})}
+	closed := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{types.KeelUpdateWindowAnnotation: "Sat 00:00-06:00"})}
+	unset := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{})}
+	invalid := &UpdatePlan{Resource: pinnedDeployment(t, map[string]string{types.KeelUpdateWindowAnnotation: "weekends"})}
+
+	event := &types.Event{Repository: types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.15"}}
+	plans := p.checkUpdateWindows(event, []*UpdatePlan{open, closed, unset, invalid})
+	if len(plans) != 3 || plans[0] != open || plans[1] != unset || plans[2] != invalid {
+		t.Fatalf("expected only plan outside of window to be postponed, got: %v", plans)
+	}
+	if _, pending := p.postponed.Load(event.Repository.String()); !pending {
+		t.Errorf("expected event to be submitted again")
+	}
+}
@@ -0,0 +1,59 @@
+package kustomize
+
+import (
+	"time"
+
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/i18n"
+)
+
+// kustomization name/image:version
+func getApprovalIdentifier(plan *UpdatePlan) string {
+	return ProviderName + "/" + plan.Kustomization.Name + "/" + plan.Image + ":" + plan.NewVersion
+}
+
+func (p *Provider) isApproved(event *types.Event, plan *UpdatePlan) (bool, error) {
+	if plan.Kustomization.Approvals == 0 {
+		return true, nil
+	}
+
+	identifier := getApprovalIdentifier(plan)
+
+	// checking for existing approval
+	existing, err := p.approvalManager.Get(identifier)
+	if err != nil {
+		if err == store.ErrRecordNotFound {
+
+			// if approval doesn't exist and trigger wasn't existing approval fulfillment -
+			// create a new one, otherwise if several kustomizations rely on the same image, it would just be
+			// requesting approvals in a loop
+			if event.TriggerName == types.TriggerTypeApproval.String() {
+				return false, nil
+			}
+
+			approval := &types.Approval{
+				Provider:       types.ProviderTypeKustomize,
+				Identifier:     identifier,
+				Event:          event,
+				CurrentVersion: plan.CurrentVersion,
+				NewVersion:     plan.NewVersion,
+				VotesRequired:  plan.Kustomization.Approvals,
+				VotesReceived:  0,
+				Rejected:       false,
+				Deadline:       time.Now().Add(time.Duration(plan.Kustomization.ApprovalDeadline) * time.Hour),
+			}
+
+			approval.Message = i18n.T("New image is available for kustomization %s (%s).",
+				plan.Kustomization.Name,
+				approval.Delta(),
+			)
+
+			return false, p.approvalManager.Create(approval)
+		}
+
+		return false, err
+	}
+
+	return existing.Status() == types.ApprovalStatusApproved, nil
+}
@@ -0,0 +1,132 @@
+package kustomize
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/provider/gitops"
+	"github.com/keel-hq/keel/types"
+)
+
+// DefaultKey - ConfigMap key that holds kustomization
+const DefaultKey = "kustomization.yaml"
+
+// Config - kustomize provider configuration
+type Config struct {
+	Kustomizations []*KustomizationConfig `json:"kustomizations"`
+}
+
+// KustomizationConfig - kustomization.yaml location and update settings,
+// either Git or ConfigMap source has to be set
+type KustomizationConfig struct {
+	// Name - used in notifications and approvals
+	Name string `json:"name"`
+
+	Git       *GitSource       `json:"git"`
+	ConfigMap *ConfigMapSource `json:"configMap"`
+
+	Policy       string `json:"policy"`
+	MatchTag     bool   `json:"matchTag"`
+	Trigger      string `json:"trigger"`
+	PollSchedule string `json:"pollSchedule"`
+
+	Approvals        int `json:"approvals"`
+	ApprovalDeadline int `json:"approvalDeadline"`
+
+	NotificationChannels []string `json:"notify"`
+
+	plc policy.Policy
+}
+
+// GitSource - kustomization.yaml in a Git repository, changes are committed
+// directly to the branch
+type GitSource struct {
+	SCM        string `json:"scm"`
+	Repository string `json:"repository"`
+	URL        string `json:"url"`
+	Token      string `json:"token"`
+	TokenEnv   string `json:"tokenEnv"`
+	Branch     string `json:"branch"`
+	Path       string `json:"path"`
+}
+
+// ConfigMapSource - kustomization.yaml stored in a ConfigMap
+type ConfigMapSource struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+}
+
+// ParseConfig - parses YAML (or JSON) configuration
+func ParseConfig(data []byte) (*Config, error) {
+	var cfg Config
+	err := yaml.Unmarshal(data, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode kustomize config: %s", err)
+	}
+
+	for _, k := range cfg.Kustomizations {
+		switch {
+		case k.Git != nil && k.ConfigMap != nil:
+			return nil, fmt.Errorf("kustomization %s: only one of git or configMap can be set", k.Name)
+		case k.Git != nil:
+			if k.Git.Repository == "" || k.Git.Path == "" {
+				return nil, fmt.Errorf("kustomization %s: git repository and path are required", k.Name)
+			}
+			if k.Git.SCM == "" {
+				k.Git.SCM = gitops.SCMGitHub
+			}
+			if k.Git.Branch == "" {
+				k.Git.Branch = "master"
+			}
+			if k.Git.TokenEnv != "" {
+				k.Git.Token = os.Getenv(k.Git.TokenEnv)
+			}
+			if k.Name == "" {
+				k.Name = k.Git.Repository + "/" + k.Git.Path
+			}
+		case k.ConfigMap != nil:
+			if k.ConfigMap.Namespace == "" || k.ConfigMap.Name == "" {
+				return nil, fmt.Errorf("kustomization %s: configMap namespace and name are required", k.Name)
+			}
+			if k.ConfigMap.Key == "" {
+				k.ConfigMap.Key = DefaultKey
+			}
+			if k.Name == "" {
+				k.Name = k.ConfigMap.Namespace + "/" + k.ConfigMap.Name
+			}
+		default:
+			return nil, fmt.Errorf("kustomization %s: git or configMap source is required", k.Name)
+		}
+
+		if k.ApprovalDeadline == 0 {
+			k.ApprovalDeadline = types.DefaultApprovalDeadline()
+		}
+		if k.Trigger == "" {
+			k.Trigger = types.TriggerTypePoll.String()
+		}
+		if k.PollSchedule == "" {
+			k.PollSchedule = types.DefaultPollSchedule()
+		}
+
+		k.plc = policy.GetPolicy(k.Policy, &policy.Options{MatchTag: k.MatchTag})
+		if k.plc == nil || k.plc.Type() == policy.PolicyTypeNone {
+			return nil, fmt.Errorf("kustomization %s: invalid policy %q", k.Name, k.Policy)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// LoadConfig - loads configuration from a file
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
@@ -0,0 +1,311 @@
+// Package kustomize - provider that updates images: overrides of kustomization.yaml
+// stored in Git or a ConfigMap, workloads themselves are not touched so clusters
+// managed by Flux or Argo CD pick up new tags on their next sync
+package kustomize
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/provider/gitops"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	core_v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ProviderName - provider name
+const ProviderName = "kustomize"
+
+var kustomizeUpdatesCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "kustomization_updates_total",
+		Help: "How many kustomization image overrides were updated, partitioned by kustomization.",
+	},
+	[]string{"kustomization"},
+)
+
+func init() {
+	prometheus.MustRegister(kustomizeUpdatesCounter)
+}
+
+type kustomization struct {
+	cfg   *KustomizationConfig
+	store Store
+
+	// cached content used to list tracked images
+	content []byte
+	fetched time.Time
+}
+
+// UpdatePlan - kustomization update to the new image version
+type UpdatePlan struct {
+	Kustomization *KustomizationConfig
+	// Image - image name as it's written in the images: entry
+	Image          string
+	CurrentVersion string
+	NewVersion     string
+	Content        []byte
+}
+
+// Provider - kustomize provider
+type Provider struct {
+	kustomizations  []*kustomization
+	sender          notification.Sender
+	approvalManager approvals.Manager
+	refresh         time.Duration
+
+	mu sync.Mutex
+
+	events chan *types.Event
+	stop   chan struct{}
+}
+
+// NewProvider - creates kustomize provider, configMaps client is only required
+// when kustomizations are stored in ConfigMaps
+func NewProvider(cfg *Config, configMaps core_v1.ConfigMapsGetter, sender notification.Sender, approvalManager approvals.Manager) (*Provider, error) {
+	p := &Provider{
+		sender:          sender,
+		approvalManager: approvalManager,
+		refresh:         gitops.DefaultRefresh,
+		events:          make(chan *types.Event, 100),
+		stop:            make(chan struct{}),
+	}
+	for _, k := range cfg.Kustomizations {
+		store, err := newStore(k, configMaps)
+		if err != nil {
+			return nil, err
+		}
+		p.kustomizations = append(p.kustomizations, &kustomization{cfg: k, store: store})
+	}
+	return p, nil
+}
+
+// GetName - get provider name
+func (p *Provider) GetName() string {
+	return ProviderName
+}
+
+// Submit - submit event to provider
+func (p *Provider) Submit(event types.Event) error {
+	p.events <- &event
+	return nil
+}
+
+// Start - starts kustomize provider, waits for events
+func (p *Provider) Start() error {
+	for {
+		select {
+		case event := <-p.events:
+			err := p.processEvent(event)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"image": event.Repository.Name,
+					"tag":   event.Repository.Tag,
+				}).Error("provider.kustomize: failed to process event")
+			}
+		case <-p.stop:
+			log.Info("provider.kustomize: got shutdown signal, stopping...")
+			return nil
+		}
+	}
+}
+
+// Stop - stops kustomize provider
+func (p *Provider) Stop() {
+	close(p.stop)
+}
+
+// get - returns kustomization content, cached content is used unless fresh
+// content is required
+func (p *Provider) get(k *kustomization, fresh bool) ([]byte, error) {
+	p.mu.Lock()
+	content, fetched := k.content, k.fetched
+	p.mu.Unlock()
+	if content != nil && !fresh && time.Since(fetched) < p.refresh {
+		return content, nil
+	}
+
+	content, err := k.store.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	k.content, k.fetched = content, time.Now()
+	p.mu.Unlock()
+
+	return content, nil
+}
+
+// TrackedImages - returns images overridden in configured kustomizations
+func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
+	var trackedImages []*types.TrackedImage
+
+	for _, k := range p.kustomizations {
+		content, err := p.get(k, false)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":         err,
+				"kustomization": k.cfg.Name,
+				"store":         k.store.String(),
+			}).Error("provider.kustomize: failed to get kustomization")
+			continue
+		}
+
+		for _, img := range gitops.FindImages(gitops.FileTypeKustomization, content) {
+			ref, err := image.Parse(img.Name + ":" + img.Tag)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":         err,
+					"kustomization": k.cfg.Name,
+					"image":         img.Name,
+				}).Debug("provider.kustomize: failed to parse image")
+				continue
+			}
+			trackedImages = append(trackedImages, &types.TrackedImage{
+				Image:        ref,
+				Trigger:      types.ParseTrigger(k.cfg.Trigger),
+				PollSchedule: k.cfg.PollSchedule,
+				Provider:     ProviderName,
+				Policy:       k.cfg.plc,
+				Meta: map[string]string{
+					"kustomization": k.cfg.Name,
+				},
+			})
+		}
+	}
+
+	return trackedImages, nil
+}
+
+func (p *Provider) processEvent(event *types.Event) error {
+	// targeted and chart events are only supported by cluster providers
+	if event.Target != "" || event.Chart {
+		return nil
+	}
+
+	for _, k := range p.kustomizations {
+		plan, err := p.createUpdatePlan(k, event)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":         err,
+				"kustomization": k.cfg.Name,
+				"image":         event.Repository.Name,
+			}).Error("provider.kustomize: failed to create update plan")
+			continue
+		}
+		if plan == nil {
+			continue
+		}
+
+		err = p.apply(k, event, plan)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":         err,
+				"kustomization": k.cfg.Name,
+				"image":         plan.Image,
+				"update":        fmt.Sprintf("%s->%s", plan.CurrentVersion, plan.NewVersion),
+			}).Error("provider.kustomize: failed to apply update")
+		}
+	}
+	return nil
+}
+
+// createUpdatePlan - updates images: entries that reference the event image, nil
+// plan is returned when nothing has to be changed
+func (p *Provider) createUpdatePlan(k *kustomization, event *types.Event) (*UpdatePlan, error) {
+	// content is read fresh to not overwrite recent changes
+	content, err := p.get(k, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kustomization from %s: %s", k.store, err)
+	}
+
+	for _, img := range gitops.FindImages(gitops.FileTypeKustomization, content) {
+		ref, err := image.Parse(img.Name + ":" + img.Tag)
+		if err != nil || ref.Repository() != event.Repository.Name {
+			continue
+		}
+
+		update, err := k.cfg.plc.ShouldUpdate(img.Tag, event.Repository.Tag)
+		if err != nil || !update {
+			continue
+		}
+
+		updated, ok := gitops.UpdateImage(gitops.FileTypeKustomization, content, img.Name, event.Repository.Tag)
+		if !ok {
+			continue
+		}
+
+		return &UpdatePlan{
+			Kustomization:  k.cfg,
+			Image:          img.Name,
+			CurrentVersion: img.Tag,
+			NewVersion:     event.Repository.Tag,
+			Content:        updated,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func (p *Provider) apply(k *kustomization, event *types.Event, plan *UpdatePlan) error {
+	approved, err := p.isApproved(event, plan)
+	if err != nil {
+		return err
+	}
+	if !approved {
+		return nil
+	}
+
+	err = k.store.Put(plan.Content, fmt.Sprintf("Update %s to %s", plan.Image, plan.NewVersion))
+	if err != nil {
+		p.notify(plan, fmt.Sprintf("Failed to update kustomization %s (%s) %s->%s, error: %s", k.cfg.Name, plan.Image, plan.CurrentVersion, plan.NewVersion, err), types.LevelError)
+		return err
+	}
+	kustomizeUpdatesCounter.With(prometheus.Labels{"kustomization": k.cfg.Name}).Inc()
+
+	p.mu.Lock()
+	k.content, k.fetched = plan.Content, time.Now()
+	p.mu.Unlock()
+
+	if k.cfg.Approvals > 0 {
+		err = p.approvalManager.Archive(getApprovalIdentifier(plan))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":         err,
+				"kustomization": k.cfg.Name,
+				"image":         plan.Image,
+			}).Warn("provider.kustomize: got error while archiving approval")
+		}
+	}
+
+	p.notify(plan, fmt.Sprintf("Successfully updated kustomization %s (%s) %s->%s", k.cfg.Name, plan.Image, plan.CurrentVersion, plan.NewVersion), types.LevelSuccess)
+	return nil
+}
+
+func (p *Provider) notify(plan *UpdatePlan, message string, level types.Level) {
+	p.sender.Send(types.EventNotification{
+		ResourceKind: "kustomization",
+		Identifier:   "kustomization/" + plan.Kustomization.Name,
+		Name:         "update kustomization",
+		Message:      message,
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        level,
+		Channels:     plan.Kustomization.NotificationChannels,
+		Metadata: map[string]string{
+			"provider":      p.GetName(),
+			"kustomization": plan.Kustomization.Name,
+			"image":         plan.Image,
+		},
+	})
+}
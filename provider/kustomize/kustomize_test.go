@@ -0,0 +1,200 @@
+package kustomize
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+
+	"k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	core_v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+type fakeSender struct {
+	sent []types.EventNotification
+}
+
+func (s *fakeSender) Configure(*notification.Config) (bool, error) { return true, nil }
+
+func (s *fakeSender) Send(event types.EventNotification) error {
+	s.sent = append(s.sent, event)
+	return nil
+}
+
+type fakeStore struct {
+	content  string
+	messages []string
+}
+
+func (s *fakeStore) Get() ([]byte, error) { return []byte(s.content), nil }
+
+func (s *fakeStore) Put(content []byte, message string) error {
+	s.content = string(content)
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+func (s *fakeStore) String() string { return "fake" }
+
+// fakeConfigMaps - only Get and Update are implemented
+type fakeConfigMaps struct {
+	core_v1.ConfigMapInterface
+	cm      *v1.ConfigMap
+	updated int
+}
+
+func (c *fakeConfigMaps) ConfigMaps(namespace string) core_v1.ConfigMapInterface {
+	return c
+}
+
+func (c *fakeConfigMaps) Get(name string, options meta_v1.GetOptions) (*v1.ConfigMap, error) {
+	return c.cm.DeepCopy(), nil
+}
+
+func (c *fakeConfigMaps) Update(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+	c.cm = cm
+	c.updated++
+	return cm, nil
+}
+
+const testKustomization = `resources:
+- deployment.yaml
+images:
+- name: karolisr/webhook-demo
+  newTag: 0.0.14 # keel updates this
+- name: nginx
+  newName: registry.example.com/mirror/nginx
+  newTag: "1.15.0"
+`
+
+const testConfig = `
+kustomizations:
+- name: web
+  git:
+    repository: org/manifests
+    path: apps/web/kustomization.yaml
+  policy: minor
+- configMap:
+    namespace: flux-system
+    name: web-kustomization
+  policy: minor
+`
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("failed to parse config: %s", err)
+	}
+	if len(cfg.Kustomizations) != 2 {
+		t.Fatalf("expected 2 kustomizations, got: %d", len(cfg.Kustomizations))
+	}
+
+	g := cfg.Kustomizations[0]
+	if g.Git.SCM != "github" || g.Git.Branch != "master" || g.Trigger != types.TriggerTypePoll.String() {
+		t.Errorf("unexpected defaults: %+v", g.Git)
+	}
+	c := cfg.Kustomizations[1]
+	if c.Name != "flux-system/web-kustomization" || c.ConfigMap.Key != DefaultKey {
+		t.Errorf("unexpected defaults: %s, %+v", c.Name, c.ConfigMap)
+	}
+
+	_, err = ParseConfig([]byte("kustomizations:\n- name: missing\n  policy: minor\n"))
+	if err == nil {
+		t.Errorf("expected error for kustomization without source")
+	}
+}
+
+func newTestProvider(t *testing.T, config string) (*Provider, *fakeStore, *fakeSender) {
+	cfg, err := ParseConfig([]byte(config))
+	if err != nil {
+		t.Fatalf("failed to parse config: %s", err)
+	}
+	sender := &fakeSender{}
+	p, err := NewProvider(cfg, &fakeConfigMaps{}, sender, nil)
+	if err != nil {
+		t.Fatalf("failed to create provider: %s", err)
+	}
+	store := &fakeStore{content: testKustomization}
+	p.kustomizations = p.kustomizations[:1]
+	p.kustomizations[0].store = store
+	return p, store, sender
+}
+
+func TestTrackedImages(t *testing.T) {
+	p, _, _ := newTestProvider(t, testConfig)
+
+	images, err := p.TrackedImages()
+	if err != nil {
+		t.Fatalf("failed to get tracked images: %s", err)
+	}
+	if len(images) != 2 {
+		t.Fatalf("expected 2 tracked images, got: %d", len(images))
+	}
+	if images[1].Image.Repository() != "registry.example.com/mirror/nginx" {
+		t.Errorf("unexpected image: %s", images[1].Image.Repository())
+	}
+	if images[0].Provider != ProviderName || images[0].Meta["kustomization"] != "web" {
+		t.Errorf("unexpected tracked image: %s", images[0])
+	}
+}
+
+func TestProcessEvent(t *testing.T) {
+	p, store, sender := newTestProvider(t, testConfig)
+
+	err := p.processEvent(&types.Event{
+		Repository: types.Repository{Name: "registry.example.com/mirror/nginx", Tag: "1.16.0"},
+	})
+	if err != nil {
+		t.Fatalf("failed to process event: %s", err)
+	}
+
+	if len(store.messages) != 1 || store.messages[0] != "Update registry.example.com/mirror/nginx to 1.16.0" {
+		t.Fatalf("unexpected commits: %v", store.messages)
+	}
+	if !strings.Contains(store.content, `newTag: "1.16.0"`) || !strings.Contains(store.content, "newTag: 0.0.14 # keel updates this") {
+		t.Errorf("unexpected kustomization:\n%s", store.content)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].Level != types.LevelSuccess || sender.sent[0].Identifier != "kustomization/web" {
+		t.Errorf("unexpected notifications: %+v", sender.sent)
+	}
+
+	// minor policy doesn't allow major updates
+	err = p.processEvent(&types.Event{
+		Repository: types.Repository{Name: "index.docker.io/karolisr/webhook-demo", Tag: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("failed to process event: %s", err)
+	}
+	if len(store.messages) != 1 {
+		t.Errorf("didn't expect another update, got: %v", store.messages)
+	}
+}
+
+func TestConfigMapStore(t *testing.T) {
+	configMaps := &fakeConfigMaps{cm: &v1.ConfigMap{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: "flux-system", Name: "web-kustomization"},
+		Data:       map[string]string{DefaultKey: testKustomization},
+	}}
+	s := &configMapStore{
+		source:     &ConfigMapSource{Namespace: "flux-system", Name: "web-kustomization", Key: DefaultKey},
+		configMaps: configMaps,
+	}
+
+	content, err := s.Get()
+	if err != nil {
+		t.Fatalf("failed to get kustomization: %s", err)
+	}
+	if string(content) != testKustomization {
+		t.Errorf("unexpected content: %s", content)
+	}
+
+	err = s.Put([]byte("images: []\n"), "update")
+	if err != nil {
+		t.Fatalf("failed to put kustomization: %s", err)
+	}
+	if configMaps.updated != 1 || configMaps.cm.Data[DefaultKey] != "images: []\n" {
+		t.Errorf("unexpected configmap: %+v", configMaps.cm.Data)
+	}
+}
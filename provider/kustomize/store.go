@@ -0,0 +1,94 @@
+package kustomize
+
+import (
+	"fmt"
+
+	"github.com/keel-hq/keel/provider/gitops"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	core_v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// Store - kustomization.yaml storage
+type Store interface {
+	// Get - returns current kustomization.yaml content
+	Get() ([]byte, error)
+	// Put - stores updated content, message is used as a commit message
+	Put(content []byte, message string) error
+	String() string
+}
+
+func newStore(cfg *KustomizationConfig, configMaps core_v1.ConfigMapsGetter) (Store, error) {
+	if cfg.ConfigMap != nil {
+		if configMaps == nil {
+			return nil, fmt.Errorf("kustomization %s: kubernetes client is required for configMap source", cfg.Name)
+		}
+		return &configMapStore{source: cfg.ConfigMap, configMaps: configMaps}, nil
+	}
+
+	var scm gitops.SCM
+	switch cfg.Git.SCM {
+	case gitops.SCMGitHub:
+		scm = gitops.NewGitHub(cfg.Git.URL, cfg.Git.Repository, cfg.Git.Token)
+	case gitops.SCMGitLab:
+		scm = gitops.NewGitLab(cfg.Git.URL, cfg.Git.Repository, cfg.Git.Token)
+	default:
+		return nil, fmt.Errorf("kustomization %s: unsupported scm %s", cfg.Name, cfg.Git.SCM)
+	}
+	return &gitStore{source: cfg.Git, scm: scm}, nil
+}
+
+// gitStore - commits changes directly to the configured branch
+type gitStore struct {
+	source *GitSource
+	scm    gitops.SCM
+}
+
+func (s *gitStore) Get() ([]byte, error) {
+	return s.scm.GetFile(s.source.Path, s.source.Branch)
+}
+
+func (s *gitStore) Put(content []byte, message string) error {
+	return s.scm.Commit(s.source.Branch, message, map[string][]byte{s.source.Path: content})
+}
+
+func (s *gitStore) String() string {
+	return s.source.Repository + "@" + s.source.Branch + ":" + s.source.Path
+}
+
+// configMapStore - updates ConfigMap key
+type configMapStore struct {
+	source     *ConfigMapSource
+	configMaps core_v1.ConfigMapsGetter
+}
+
+func (s *configMapStore) Get() ([]byte, error) {
+	cm, err := s.configMaps.ConfigMaps(s.source.Namespace).Get(s.source.Name, meta_v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	content, ok := cm.Data[s.source.Key]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found in configmap %s/%s", s.source.Key, s.source.Namespace, s.source.Name)
+	}
+	return []byte(content), nil
+}
+
+func (s *configMapStore) Put(content []byte, message string) error {
+	cm, err := s.configMaps.ConfigMaps(s.source.Namespace).Get(s.source.Name, meta_v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[s.source.Key] = string(content)
+
+	// resource version from Get makes update fail on concurrent changes
+	_, err = s.configMaps.ConfigMaps(s.source.Namespace).Update(cm)
+	return err
+}
+
+func (s *configMapStore) String() string {
+	return "configmap " + s.source.Namespace + "/" + s.source.Name + ":" + s.source.Key
+}
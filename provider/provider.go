@@ -2,8 +2,15 @@ package provider
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/internal/bus"
+	"github.com/keel-hq/keel/internal/tracing"
+	"github.com/keel-hq/keel/registry"
 	"github.com/keel-hq/keel/types"
 
 	log "github.com/sirupsen/logrus"
@@ -25,6 +32,55 @@ type Providers interface {
 	Stop()          // stop all providers
 }
 
+// Gate - checks events before they are submitted to providers, ie: external plugins
+// with proprietary checks. Gates are consulted in order, first denial drops the event.
+type Gate interface {
+	Name() string
+	// Check - returns decision about the event, errors deny the event
+	Check(event types.Event) (*GateDecision, error)
+}
+
+// GateDecision - gate decision, allowed events can be modified by the gate
+type GateDecision struct {
+	Allowed bool
+	Reason  string
+	// Event - optional modified event (ie: with pinned digest) that is
+	// submitted instead of the original one
+	Event *types.Event
+}
+
+// Drainer - provider that finishes in-flight updates before shutdown
+type Drainer interface {
+	Drain(ctx context.Context) error
+}
+
+// DeletionHandler - provider that handles deletions of tags from registries,
+// deletion events are only submitted to providers implementing it so that
+// providers unaware of them never treat deleted tags as new versions
+type DeletionHandler interface {
+	TagDeleted(event types.Event) error
+}
+
+// TopicEvent - events that passed the gates are published to it when the event
+// bus is set, providers and other modules (ie: audit) subscribe to it
+var TopicEvent = bus.NewTopic("provider.event", (*types.Event)(nil))
+
+// ErrShuttingDown - events aren't accepted once draining started
+var ErrShuttingDown = errors.New("shutting down")
+
+// ErrNotLeader - events are only processed by the leader when keel runs with
+// leader election
+var ErrNotLeader = errors.New("not the leader")
+
+// resume approved approvals that weren't applied, ie: collected by another
+// replica or before a failover
+const (
+	resumeApprovedInterval = 30 * time.Second
+	// resumeApprovedDelay - approvals collected by the leader itself are
+	// submitted right away, only older ones are resumed
+	resumeApprovedDelay = time.Minute
+)
+
 // New - new providers registry
 func New(providers []Provider, approvalsManager approvals.Manager) *DefaultProviders {
 	pvs := make(map[string]Provider)
@@ -52,6 +108,170 @@ type DefaultProviders struct {
 	providers        map[string]Provider
 	approvalsManager approvals.Manager
 	stopCh           chan struct{}
+	stopOnce         sync.Once
+
+	// isLeader - set when keel runs with leader election
+	isLeader func() bool
+	leaderMu sync.RWMutex
+	// resumed - approvals (and their update time) that were already resumed
+	resumed map[string]time.Time
+
+	// gates - optional pre-update checks of the events
+	gates []Gate
+
+	// bus is optional, events are delivered to providers through it, see SetEventBus
+	bus *bus.Bus
+	// unsubscribe - ends subscriptions of the providers, delivering - events
+	// received by providers' subscriptions that weren't submitted yet
+	unsubscribe context.CancelFunc
+	delivering  sync.WaitGroup
+}
+
+// SetEventBus - triggers publish events to the bus and every provider consumes
+// them through its own subscription, events are submitted to providers directly
+// when it's not set
+func (p *DefaultProviders) SetEventBus(b *bus.Bus) {
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, provider := range p.providers {
+		sub := b.Subscribe(ctx, TopicEvent, bus.SubscribeOpts{Name: "provider." + provider.GetName(), Policy: bus.Block})
+		go p.consume(provider, sub)
+	}
+	p.bus = b
+	p.unsubscribe = cancel
+}
+
+// consume - submits events of the subscription to the provider
+func (p *DefaultProviders) consume(provider Provider, sub *bus.Subscription) {
+	for msg := range sub.C {
+		p.submitTo(provider, *msg.(*types.Event))
+		p.delivering.Done()
+	}
+}
+
+func (p *DefaultProviders) submitTo(provider Provider, event types.Event) {
+	err := provider.Submit(event)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"provider": provider.GetName(),
+			"event":    event.Repository,
+			"trigger":  event.TriggerName,
+		}).Error("provider.Submit: submit event failed")
+	}
+}
+
+// SetGates - sets gates that check events before they're submitted to providers
+func (p *DefaultProviders) SetGates(gates []Gate) {
+	p.gates = gates
+}
+
+// checkGates - runs the event through the gates, ok is false when a gate denied it
+func (p *DefaultProviders) checkGates(event types.Event) (types.Event, bool) {
+	for _, gate := range p.gates {
+		decision, err := gate.Check(event)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"gate":    gate.Name(),
+				"event":   event.Repository,
+				"trigger": event.TriggerName,
+			}).Error("provider.Submit: gate check failed, event denied")
+			return event, false
+		}
+		if !decision.Allowed {
+			log.WithFields(log.Fields{
+				"gate":    gate.Name(),
+				"reason":  decision.Reason,
+				"event":   event.Repository,
+				"trigger": event.TriggerName,
+			}).Info("provider.Submit: event denied by gate")
+			return event, false
+		}
+		if decision.Event != nil {
+			log.WithFields(log.Fields{
+				"gate":     gate.Name(),
+				"reason":   decision.Reason,
+				"event":    event.Repository,
+				"modified": decision.Event.Repository,
+			}).Info("provider.Submit: event modified by gate")
+			traceParent := event.TraceParent
+			event = *decision.Event
+			if event.TraceParent == "" {
+				event.TraceParent = traceParent
+			}
+		}
+	}
+	return event, true
+}
+
+// SetLeaderElection - only the leader submits events to providers, approved
+// approvals that no replica applied yet are periodically resumed by the leader
+func (p *DefaultProviders) SetLeaderElection(isLeader func() bool) {
+	p.leaderMu.Lock()
+	p.isLeader = isLeader
+	p.resumed = make(map[string]time.Time)
+	p.leaderMu.Unlock()
+
+	go p.resumeApprovedLoop()
+}
+
+func (p *DefaultProviders) leading() bool {
+	p.leaderMu.RLock()
+	defer p.leaderMu.RUnlock()
+	return p.isLeader == nil || p.isLeader()
+}
+
+func (p *DefaultProviders) resumeApprovedLoop() {
+	ticker := time.NewTicker(resumeApprovedInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if p.leading() {
+				p.resumeApproved()
+			}
+		case <-p.stopCh:
+			return
+		}
+	}
+}
+
+// resumeApproved - submits approved approvals that haven't been archived yet,
+// each approval is resumed once per its update
+func (p *DefaultProviders) resumeApproved() {
+	approvals, err := p.approvalsManager.List()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("provider.resumeApproved: failed to list approvals")
+		return
+	}
+
+	for _, approval := range approvals {
+		if approval.Archived || approval.Status() != types.ApprovalStatusApproved || approval.Event == nil {
+			continue
+		}
+		if time.Since(approval.UpdatedAt) < resumeApprovedDelay {
+			continue
+		}
+
+		p.leaderMu.Lock()
+		updatedAt, ok := p.resumed[approval.Identifier]
+		p.resumed[approval.Identifier] = approval.UpdatedAt
+		p.leaderMu.Unlock()
+		if ok && updatedAt.Equal(approval.UpdatedAt) {
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"approval": approval.Identifier,
+			"provider": approval.Provider.String(),
+		}).Info("provider.resumeApproved: resuming approved update")
+
+		approval.Event.TriggerName = types.TriggerTypeApproval.String()
+		p.Submit(*approval.Event)
+	}
 }
 
 func (p *DefaultProviders) subscribeToApproved() {
@@ -68,6 +288,16 @@ func (p *DefaultProviders) subscribeToApproved() {
 		select {
 		case approval := <-approvedCh:
 			approval.Event.TriggerName = types.TriggerTypeApproval.String()
+
+			// approval wait is only known once it's over
+			span := tracing.StartAt(approval.Event.TraceParent, "approval.wait", approval.CreatedAt)
+			span.SetAttribute("approval.identifier", approval.Identifier)
+			span.SetAttribute("approval.voters", strings.Join(approval.GetVoters(), ","))
+			span.End()
+			if tp := span.TraceParent(); tp != "" {
+				approval.Event.TraceParent = tp
+			}
+
 			p.Submit(*approval.Event)
 		case <-p.stopCh:
 			cancel()
@@ -79,19 +309,84 @@ func (p *DefaultProviders) subscribeToApproved() {
 
 // Submit - submit event to all providers
 func (p *DefaultProviders) Submit(event types.Event) error {
+	select {
+	case <-p.stopCh:
+		log.WithFields(log.Fields{
+			"event":   event.Repository,
+			"trigger": event.TriggerName,
+		}).Warn("provider.Submit: shutting down, ignoring event")
+		return ErrShuttingDown
+	default:
+	}
+
+	if !p.leading() {
+		log.WithFields(log.Fields{
+			"event":   event.Repository,
+			"trigger": event.TriggerName,
+		}).Debug("provider.Submit: not the leader, ignoring event")
+		return ErrNotLeader
+	}
+
+	// signatures, attestations and SBOMs share the tag namespace with images,
+	// pushes of their tags are never updates
+	if registry.IsArtifactTag(event.Repository.Tag) {
+		log.WithFields(log.Fields{
+			"event":   event.Repository,
+			"trigger": event.TriggerName,
+		}).Debug("provider.Submit: ignoring artifact tag")
+		return nil
+	}
+
+	span := tracing.Start(event.TraceParent, "event")
+	span.SetAttribute("image", event.Repository.Name)
+	span.SetAttribute("tag", event.Repository.Tag)
+	span.SetAttribute("digest", event.Repository.Digest)
+	span.SetAttribute("trigger", event.TriggerName)
+	defer span.End()
+	if tp := span.TraceParent(); tp != "" {
+		event.TraceParent = tp
+	}
+
+	// deletions aren't updates, gates aren't consulted
+	if event.Deleted {
+		span.SetAttribute("deleted", "true")
+		p.submitDeleted(event)
+		return nil
+	}
+
+	event, ok := p.checkGates(event)
+	if !ok {
+		span.SetAttribute("denied", "true")
+		return nil
+	}
+
+	if p.bus != nil {
+		p.delivering.Add(len(p.providers))
+		return p.bus.Publish(TopicEvent, &event)
+	}
+
 	for _, provider := range p.providers {
-		err := provider.Submit(event)
-		if err != nil {
+		p.submitTo(provider, event)
+	}
+
+	return nil
+}
+
+func (p *DefaultProviders) submitDeleted(event types.Event) {
+	for _, provider := range p.providers {
+		handler, ok := provider.(DeletionHandler)
+		if !ok {
+			continue
+		}
+		if err := handler.TagDeleted(event); err != nil {
 			log.WithFields(log.Fields{
 				"error":    err,
 				"provider": provider.GetName(),
 				"event":    event.Repository,
 				"trigger":  event.TriggerName,
-			}).Error("provider.Submit: submit event failed")
+			}).Error("provider.Submit: submit tag deletion failed")
 		}
 	}
-
-	return nil
 }
 
 // TrackedImages - get tracked images for provider
@@ -121,8 +416,47 @@ func (p *DefaultProviders) List() []string {
 	return list
 }
 
+// Drain - stops accepting events and approved approvals, waits until providers
+// finish in-flight updates or ctx is done
+func (p *DefaultProviders) Drain(ctx context.Context) {
+	p.stopOnce.Do(func() { close(p.stopCh) })
+
+	// events published before draining started are submitted first
+	delivered := make(chan struct{})
+	go func() {
+		p.delivering.Wait()
+		close(delivered)
+	}()
+	select {
+	case <-delivered:
+	case <-ctx.Done():
+	}
+
+	var wg sync.WaitGroup
+	for name, provider := range p.providers {
+		drainer, ok := provider.(Drainer)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(name string, drainer Drainer) {
+			defer wg.Done()
+			if err := drainer.Drain(ctx); err != nil {
+				log.WithFields(log.Fields{
+					"error":    err,
+					"provider": name,
+				}).Warn("provider.Drain: provider didn't finish in-flight updates")
+			}
+		}(name, drainer)
+	}
+	wg.Wait()
+}
+
 // Stop - stop all providers
 func (p *DefaultProviders) Stop() {
+	if p.unsubscribe != nil {
+		p.unsubscribe()
+	}
 	for _, provider := range p.providers {
 		provider.Stop()
 	}
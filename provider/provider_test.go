@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/internal/bus"
+	"github.com/keel-hq/keel/types"
+)
+
+type fakeProvider struct {
+	name string
+
+	mu        sync.Mutex
+	submitted []types.Event
+}
+
+func (p *fakeProvider) Submit(event types.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.submitted = append(p.submitted, event)
+	return nil
+}
+
+func (p *fakeProvider) TrackedImages() ([]*types.TrackedImage, error) {
+	return nil, nil
+}
+
+func (p *fakeProvider) GetName() string {
+	return p.name
+}
+
+func (p *fakeProvider) Stop() {}
+
+func TestSubmitThroughEventBus(t *testing.T) {
+	k8s := &fakeProvider{name: "kubernetes"}
+	helm := &fakeProvider{name: "helm"}
+	providers := New([]Provider{k8s, helm}, approvals.New(&approvals.Opts{}))
+	defer providers.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := bus.New()
+	audit := b.Subscribe(ctx, TopicEvent, bus.SubscribeOpts{Name: "audit", Buffer: 1})
+	providers.SetEventBus(b)
+
+	err := providers.Submit(types.Event{Repository: types.Repository{Name: "karolisr/webhook-demo", Tag: "0.0.15"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// events published before draining are submitted to providers
+	providers.Drain(context.Background())
+	for _, p := range []*fakeProvider{k8s, helm} {
+		if len(p.submitted) != 1 || p.submitted[0].Repository.Tag != "0.0.15" {
+			t.Errorf("unexpected events submitted to %s: %v", p.name, p.submitted)
+		}
+	}
+
+	msg := <-audit.C
+	if msg.(*types.Event).Repository.Name != "karolisr/webhook-demo" {
+		t.Errorf("unexpected audited event: %v", msg)
+	}
+
+	if err := providers.Submit(types.Event{}); err != ErrShuttingDown {
+		t.Errorf("expected events to be refused once draining started, got: %v", err)
+	}
+}
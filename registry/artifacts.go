@@ -0,0 +1,28 @@
+package registry
+
+import (
+	"regexp"
+	"strings"
+)
+
+// artifactTagRegexp - tags of artifacts attached to images, ie: cosign
+// signatures (sha256-<digest>.sig), attestations (.att), SBOMs (.sbom) and
+// OCI referrers tag schema fallback (sha256-<digest>)
+var artifactTagRegexp = regexp.MustCompile(`^sha(256|384|512)-[0-9a-f]{32,128}(\.[a-z0-9]+)?$`)
+
+// IsArtifactTag - whether tag belongs to an artifact that shares the tag
+// namespace with images, such tags are never update candidates
+func IsArtifactTag(tag string) bool {
+	return artifactTagRegexp.MatchString(strings.ToLower(tag))
+}
+
+// imageTags - tags without artifact tags
+func imageTags(tags []string) []string {
+	filtered := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !IsArtifactTag(tag) {
+			filtered = append(filtered, tag)
+		}
+	}
+	return filtered
+}
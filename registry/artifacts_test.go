@@ -0,0 +1,43 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsArtifactTag(t *testing.T) {
+	hex := strings.Repeat("0a", 32)
+	for tag, expected := range map[string]bool{
+		"sha256-" + hex + ".sig":  true,
+		"sha256-" + hex + ".att":  true,
+		"sha256-" + hex + ".sbom": true,
+		"sha256-" + hex:           true,
+		"1.2.3":                   false,
+		"latest":                  false,
+		"sha256-abc.sig":          false,
+		"sha-1234567":             false,
+	} {
+		if IsArtifactTag(tag) != expected {
+			t.Errorf("%s: expected %v", tag, expected)
+		}
+	}
+}
+
+func TestGetFiltersArtifactTags(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"name":"keelhq/keel","tags":["0.15.0","sha256-%s.sig","0.16.0","sha256-%s.att"]}`, strings.Repeat("a", 64), strings.Repeat("b", 64))
+	}))
+	defer ts.Close()
+
+	repo, err := New().Get(Opts{Registry: ts.URL, Name: "keelhq/keel"})
+	if err != nil {
+		t.Fatalf("failed to get tags: %s", err)
+	}
+	if strings.Join(repo.Tags, ",") != "0.15.0,0.16.0" {
+		t.Errorf("unexpected tags: %v", repo.Tags)
+	}
+}
@@ -0,0 +1,218 @@
+package registry
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+// EnvConfig - path of the per-registry config, see ParseConfig
+const EnvConfig = "REGISTRY_CONFIG"
+
+// HostConfig - settings of a registry host. CA bundle is added to the system
+// roots, client certificate is used for mTLS, InsecureSkipVerify should be
+// the last resort. Proxy is an HTTP(S) proxy URL used instead of the
+// HTTPS_PROXY environment variables. Mirror (ie: harbor.internal/dockerhub)
+// is queried instead of the registry, images keep referencing the registry.
+type HostConfig struct {
+	CA                 string `json:"ca"`
+	Cert               string `json:"cert"`
+	Key                string `json:"key"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"`
+
+	Proxy string `json:"proxy"`
+
+	Mirror string `json:"mirror"`
+	// mirror credentials, registry credentials aren't sent to mirrors.
+	// Environment variables are expanded, ie: $MIRROR_PASSWORD
+	MirrorUsername string `json:"mirrorUsername"`
+	MirrorPassword string `json:"mirrorPassword"`
+}
+
+// hostConfig - parsed HostConfig
+type hostConfig struct {
+	tls   *tls.Config
+	proxy *url.URL
+
+	// mirror address and repository name prefix
+	mirror         string
+	mirrorPrefix   string
+	mirrorUsername string
+	mirrorPassword string
+}
+
+// ParseConfig - parses YAML (or JSON) configs keyed by registry host (with an
+// optional port), ie:
+//
+//	registry.corp.example.com:
+//	  ca: /etc/keel/tls/corp-ca.pem
+//	  cert: /etc/keel/tls/client.pem
+//	  key: /etc/keel/tls/client-key.pem
+//	docker.io:
+//	  mirror: harbor.internal/dockerhub
+//	harbor.internal:
+//	  proxy: http://proxy.internal:3128
+func ParseConfig(data []byte) (map[string]HostConfig, error) {
+	var raw map[string]HostConfig
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode registry config: %s", err)
+	}
+	return raw, nil
+}
+
+func (c HostConfig) parse() (*hostConfig, error) {
+	cfg := &hostConfig{}
+
+	if c.CA != "" || c.Cert != "" || c.Key != "" || c.InsecureSkipVerify {
+		var err error
+		if cfg.tls, err = c.tlsConfig(); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Proxy != "" {
+		proxy, err := url.Parse(c.Proxy)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", c.Proxy)
+		}
+		cfg.proxy = proxy
+	}
+
+	if c.Mirror != "" {
+		mirror := c.Mirror
+		scheme := "https://"
+		if strings.HasPrefix(mirror, "http://") {
+			scheme = "http://"
+		}
+		mirror = strings.TrimPrefix(strings.TrimPrefix(mirror, "https://"), "http://")
+		parts := strings.SplitN(strings.Trim(mirror, "/"), "/", 2)
+		cfg.mirror = scheme + parts[0]
+		if len(parts) == 2 {
+			cfg.mirrorPrefix = parts[1] + "/"
+		}
+		cfg.mirrorUsername = os.ExpandEnv(c.MirrorUsername)
+		cfg.mirrorPassword = os.ExpandEnv(c.MirrorPassword)
+	}
+	return cfg, nil
+}
+
+var (
+	configsM sync.RWMutex
+	configs  map[string]*hostConfig
+)
+
+// LoadConfig - loads per-registry configs used by new clients
+func LoadConfig(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	c, err := ParseConfig(data)
+	if err != nil {
+		return err
+	}
+	return SetConfig(c)
+}
+
+// SetConfig - sets per-registry configs used by new clients, certificates
+// are loaded right away
+func SetConfig(c map[string]HostConfig) error {
+	parsed := make(map[string]*hostConfig, len(c))
+	for host, hc := range c {
+		cfg, err := hc.parse()
+		if err != nil {
+			return fmt.Errorf("registry %s: %s", host, err)
+		}
+		parsed[registryHost(host)] = cfg
+	}
+
+	configsM.Lock()
+	configs = parsed
+	configsM.Unlock()
+	return nil
+}
+
+// registryConfig - config of the registry address, configs with a port
+// take precedence over the ones of the whole host
+func registryConfig(registryAddress string) *hostConfig {
+	configsM.RLock()
+	defer configsM.RUnlock()
+
+	host := registryHost(registryAddress)
+	if cfg, ok := configs[host]; ok {
+		return cfg
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		return configs[hostname]
+	}
+	return nil
+}
+
+// mirrored - points options to the registry mirror, if there's one
+func mirrored(opts Opts) Opts {
+	cfg := registryConfig(opts.Registry)
+	if cfg == nil || cfg.mirror == "" {
+		return opts
+	}
+	name := opts.Name
+	// Docker Hub official images are stored under library/
+	if isDockerHub(registryHost(opts.Registry)) && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	opts.Registry = cfg.mirror
+	opts.Name = cfg.mirrorPrefix + name
+	opts.Username = cfg.mirrorUsername
+	opts.Password = cfg.mirrorPassword
+	return opts
+}
+
+// Docker Hub has several names, configs use any of them
+var dockerHubHosts = map[string]bool{
+	"docker.io":            true,
+	"index.docker.io":      true,
+	"registry-1.docker.io": true,
+}
+
+func isDockerHub(host string) bool {
+	return dockerHubHosts[host]
+}
+
+func registryHost(address string) string {
+	address = strings.TrimPrefix(address, "https://")
+	address = strings.TrimPrefix(address, "http://")
+	host := strings.SplitN(address, "/", 2)[0]
+	if isDockerHub(host) {
+		return "docker.io"
+	}
+	return host
+}
+
+// newTransport - same transport as the registry client uses, with the TLS
+// config and proxy of the registry
+func newTransport(cfg *tls.Config, proxy *url.URL) *http.Transport {
+	proxyFunc := http.ProxyFromEnvironment
+	if proxy != nil {
+		proxyFunc = http.ProxyURL(proxy)
+	}
+	return &http.Transport{
+		Proxy: proxyFunc,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:       cfg,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
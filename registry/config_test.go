@@ -0,0 +1,96 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestRegistryConfigHost(t *testing.T) {
+	err := SetConfig(map[string]HostConfig{
+		"registry.corp.example.com:5000": {Proxy: "http://port.internal:3128"},
+		"registry.corp.example.com":      {Proxy: "http://host.internal:3128"},
+	})
+	if err != nil {
+		t.Fatalf("failed to set config: %s", err)
+	}
+	defer SetConfig(nil)
+
+	if cfg := registryConfig("https://registry.corp.example.com:5000/"); cfg == nil || cfg.proxy.Host != "port.internal:3128" {
+		t.Errorf("expected config of the port, got: %+v", cfg)
+	}
+	if cfg := registryConfig("https://registry.corp.example.com:443"); cfg == nil || cfg.proxy.Host != "host.internal:3128" {
+		t.Errorf("expected config of the host, got: %+v", cfg)
+	}
+	if registryConfig("https://index.docker.io") != nil {
+		t.Errorf("expected no config")
+	}
+}
+
+func TestMirror(t *testing.T) {
+	var requested string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		if user, _, _ := r.BasicAuth(); user != "" && user != "mirror" {
+			t.Errorf("registry credentials were sent to the mirror: %s", user)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name":"dockerhub/library/nginx","tags":["1.19"]}`)
+	}))
+	defer ts.Close()
+
+	os.Setenv("TEST_MIRROR_PASSWORD", "secret")
+	defer os.Unsetenv("TEST_MIRROR_PASSWORD")
+	configs, err := ParseConfig([]byte(fmt.Sprintf(`
+docker.io:
+  mirror: %s/dockerhub
+  mirrorUsername: mirror
+  mirrorPassword: $TEST_MIRROR_PASSWORD
+`, ts.URL)))
+	if err != nil {
+		t.Fatalf("failed to parse config: %s", err)
+	}
+	if err := SetConfig(configs); err != nil {
+		t.Fatalf("failed to set config: %s", err)
+	}
+	defer SetConfig(nil)
+
+	opts := mirrored(Opts{Registry: "https://index.docker.io", Name: "nginx", Username: "hub", Password: "hub"})
+	if opts.Registry != ts.URL || opts.Name != "dockerhub/library/nginx" || opts.Username != "mirror" || opts.Password != "secret" {
+		t.Errorf("unexpected mirrored options: %+v", opts)
+	}
+
+	repo, err := New().Get(Opts{Registry: "https://index.docker.io", Name: "nginx", Username: "hub", Password: "hub"})
+	if err != nil {
+		t.Fatalf("failed to get tags: %s", err)
+	}
+	if requested != "/v2/dockerhub/library/nginx/tags/list" {
+		t.Errorf("unexpected mirror request: %s", requested)
+	}
+	if len(repo.Tags) != 1 {
+		t.Errorf("unexpected tags: %v", repo.Tags)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		fmt.Fprint(w, `{"name":"corp/app","tags":["1.0.0"]}`)
+	}))
+	defer proxy.Close()
+
+	if err := SetConfig(map[string]HostConfig{"registry.corp.example.com": {Proxy: proxy.URL}}); err != nil {
+		t.Fatalf("failed to set config: %s", err)
+	}
+	defer SetConfig(nil)
+
+	if _, err := New().Get(Opts{Registry: "http://registry.corp.example.com", Name: "corp/app"}); err != nil {
+		t.Fatalf("failed to get tags: %s", err)
+	}
+	if proxied != "http://registry.corp.example.com/v2/corp/app/tags/list" {
+		t.Errorf("expected request through the proxy, got: %q", proxied)
+	}
+}
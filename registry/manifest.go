@@ -0,0 +1,362 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strings"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/rusenask/docker-registry-client/registry"
+)
+
+// manifest media types
+const (
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+)
+
+// unknownPlatform - platform of attestation manifests stored in image indexes
+const unknownPlatform = "unknown/unknown"
+
+// dockerReferenceTypeAnnotation - set on attestation manifests of BuildKit
+// image indexes
+const dockerReferenceTypeAnnotation = "vnd.docker.reference.type"
+
+// manifestAccept - all manifest types, registries that only have an OCI index
+// of the tag reject requests that don't accept it
+var manifestAccept = strings.Join([]string{
+	MediaTypeDockerManifestList,
+	MediaTypeOCIIndex,
+	MediaTypeDockerManifest,
+	MediaTypeOCIManifest,
+}, ", ")
+
+// Manifest - tag manifest digests. For multi-arch tags Digest is the manifest list
+// (image index) digest and Platforms holds digests of the per-platform manifests,
+// for single-arch tags Platforms is empty.
+type Manifest struct {
+	Digest    string
+	MediaType string
+	// Platforms - map[os/arch[/variant]]digest
+	Platforms map[string]string
+	// Layers - layers of single-arch manifests (OCI artifacts such as Helm charts)
+	Layers []Descriptor
+	// Config - image config of single-arch manifests
+	Config Descriptor
+}
+
+// Descriptor - manifest layer or referrer descriptor
+type Descriptor struct {
+	MediaType string `json:"mediaType"`
+	// ArtifactType - type of artifacts listed by the referrers API, ie: in-toto attestations
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+// IsList - whether manifest is a manifest list or an image index
+func (m *Manifest) IsList() bool {
+	return m.MediaType == MediaTypeDockerManifestList || m.MediaType == MediaTypeOCIIndex
+}
+
+// PlatformDigest - digest of the manifest for the platform (ie: linux/amd64),
+// platform without a variant matches any variant. For single-arch tags
+// manifest digest is returned, empty string if list doesn't have the platform.
+func (m *Manifest) PlatformDigest(platform string) string {
+	if !m.IsList() {
+		return m.Digest
+	}
+	if d, ok := m.Platforms[platform]; ok {
+		return d
+	}
+	for p, d := range m.Platforms {
+		if strings.HasPrefix(p, platform+"/") {
+			return d
+		}
+	}
+	return ""
+}
+
+type manifestList struct {
+	MediaType string `json:"mediaType"`
+	Manifests []struct {
+		Digest       string            `json:"digest"`
+		ArtifactType string            `json:"artifactType"`
+		Annotations  map[string]string `json:"annotations"`
+		Platform     struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+			Variant      string `json:"variant"`
+		} `json:"platform"`
+	} `json:"manifests"`
+	Layers []Descriptor `json:"layers"`
+	Config Descriptor   `json:"config"`
+}
+
+// Manifest - get tag manifest, unlike Digest it also resolves per-platform
+// digests of multi-arch images
+func (c *DefaultClient) Manifest(opts Opts) (*Manifest, error) {
+	if opts.Tag == "" {
+		return nil, ErrTagNotSupplied
+	}
+
+	opts = mirrored(opts)
+
+	// fallback to HTTP if the registry doesn't speak HTTPS https://github.com/keel-hq/keel/issues/331
+INIT_CLIENT:
+	hub, err := c.getRegistryClient(opts.Registry, opts.Username, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	url := hub.URL + fmt.Sprintf("/v2/%s/manifests/%s", opts.Name, opts.Tag)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", manifestAccept)
+
+	resp, err := hub.Client.Do(req)
+	if err != nil {
+		if strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") && strings.HasPrefix(opts.Registry, "https://") && c.insecure {
+			opts.Registry = strings.Replace(opts.Registry, "https://", "http://", 1)
+			goto INIT_CLIENT
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseManifest(resp.Header.Get("Content-Type"), resp.Header.Get("Docker-Content-Digest"), body)
+}
+
+// manifestDigest - digest of the tag manifest, for multi-arch tags it's the
+// manifest list (image index) digest. HEAD requests don't count towards
+// Docker Hub pull limits, registries that don't support them are asked with GET.
+func manifestDigest(hub *registry.Registry, name, tag string) (string, error) {
+	url := hub.URL + fmt.Sprintf("/v2/%s/manifests/%s", name, tag)
+	req, err := http.NewRequest(http.MethodHead, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", manifestAccept)
+
+	resp, err := hub.Client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		if d := resp.Header.Get("Docker-Content-Digest"); d != "" {
+			parsed, err := digest.Parse(d)
+			if err != nil {
+				return "", err
+			}
+			return parsed.String(), nil
+		}
+	} else if statusErr, ok := unwrapStatusError(err); !ok || statusErr.Response.StatusCode != http.StatusMethodNotAllowed {
+		return "", err
+	}
+
+	req.Method = http.MethodGet
+	resp, err = hub.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if d := resp.Header.Get("Docker-Content-Digest"); d != "" {
+		parsed, err := digest.Parse(d)
+		if err != nil {
+			return "", err
+		}
+		return parsed.String(), nil
+	}
+	// digest of the body is equal to what would be presented in Docker-Content-Digest
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return digest.FromBytes(body).String(), nil
+}
+
+func unwrapStatusError(err error) (*registry.HttpStatusError, bool) {
+	if urlErr, ok := err.(*neturl.Error); ok {
+		err = urlErr.Err
+	}
+	statusErr, ok := err.(*registry.HttpStatusError)
+	return statusErr, ok
+}
+
+// IsUnauthorized - whether registry rejected the credentials
+func IsUnauthorized(err error) bool {
+	statusErr, ok := unwrapStatusError(err)
+	if !ok {
+		return false
+	}
+	code := statusErr.Response.StatusCode
+	return code == http.StatusUnauthorized || code == http.StatusForbidden
+}
+
+// IsNotFound - whether registry doesn't have the manifest or the repository
+func IsNotFound(err error) bool {
+	statusErr, ok := unwrapStatusError(err)
+	return ok && statusErr.Response.StatusCode == http.StatusNotFound
+}
+
+// Blob - downloads blob, ie: layer of an OCI artifact
+func (c *DefaultClient) Blob(opts Opts, blobDigest string) ([]byte, error) {
+	opts = mirrored(opts)
+
+	// fallback to HTTP if the registry doesn't speak HTTPS https://github.com/keel-hq/keel/issues/331
+INIT_CLIENT:
+	hub, err := c.getRegistryClient(opts.Registry, opts.Username, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := hub.Client.Get(hub.URL + fmt.Sprintf("/v2/%s/blobs/%s", opts.Name, blobDigest))
+	if err != nil {
+		if strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") && strings.HasPrefix(opts.Registry, "https://") && c.insecure {
+			opts.Registry = strings.Replace(opts.Registry, "https://", "http://", 1)
+			goto INIT_CLIENT
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := digest.Parse(blobDigest)
+	if err == nil && expected.Validate() == nil && expected.Algorithm().FromBytes(body) != expected {
+		return nil, fmt.Errorf("blob %s digest mismatch", blobDigest)
+	}
+
+	return body, nil
+}
+
+// ImageConfig - image config fields keel uses
+type ImageConfig struct {
+	Created time.Time
+	// Labels - image labels, ie: org.opencontainers.image.revision
+	Labels map[string]string
+}
+
+// Config - image config of the tag. Multi-arch images use linux/amd64 (or the
+// first platform) config.
+func (c *DefaultClient) Config(opts Opts) (*ImageConfig, error) {
+	manifest, err := c.Manifest(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.IsList() {
+		platformDigest := manifest.PlatformDigest("linux/amd64")
+		if platformDigest == "" {
+			var platforms []string
+			for platform := range manifest.Platforms {
+				platforms = append(platforms, platform)
+			}
+			if len(platforms) == 0 {
+				return nil, fmt.Errorf("manifest list has no platforms")
+			}
+			sort.Strings(platforms)
+			platformDigest = manifest.Platforms[platforms[0]]
+		}
+
+		platformOpts := opts
+		platformOpts.Tag = platformDigest
+		manifest, err = c.Manifest(platformOpts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if manifest.Config.Digest == "" {
+		return nil, fmt.Errorf("manifest has no image config")
+	}
+
+	body, err := c.Blob(opts, manifest.Config.Digest)
+	if err != nil {
+		return nil, err
+	}
+	return parseImageConfig(body)
+}
+
+func parseImageConfig(body []byte) (*ImageConfig, error) {
+	var config struct {
+		Created time.Time `json:"created"`
+		Config  struct {
+			Labels map[string]string `json:"Labels"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal(body, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode image config: %s", err)
+	}
+	return &ImageConfig{Created: config.Created, Labels: config.Config.Labels}, nil
+}
+
+// Created - when the image was built, read from the image config
+func (c *DefaultClient) Created(opts Opts) (time.Time, error) {
+	config, err := c.Config(opts)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if config.Created.IsZero() {
+		return time.Time{}, fmt.Errorf("image config has no created timestamp")
+	}
+	return config.Created, nil
+}
+
+func parseManifest(contentType, contentDigest string, body []byte) (*Manifest, error) {
+	var list manifestList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %s", err)
+	}
+
+	m := &Manifest{
+		Digest:    contentDigest,
+		MediaType: strings.TrimSpace(strings.Split(contentType, ";")[0]),
+		Platforms: map[string]string{},
+	}
+	if m.Digest == "" {
+		// digest of the body is equal to what would be presented in Docker-Content-Digest
+		m.Digest = digest.FromBytes(body).String()
+	}
+	if m.MediaType == "" || m.MediaType == "application/json" {
+		m.MediaType = list.MediaType
+	}
+	if !m.IsList() {
+		m.Layers = list.Layers
+		m.Config = list.Config
+		return m, nil
+	}
+
+	for _, pm := range list.Manifests {
+		// artifacts (signatures, SBOMs, attestations) stored in the index
+		// aren't images of a platform
+		if pm.ArtifactType != "" || pm.Annotations[dockerReferenceTypeAnnotation] != "" || pm.Platform.OS == "" {
+			continue
+		}
+		platform := pm.Platform.OS + "/" + pm.Platform.Architecture
+		if platform == unknownPlatform {
+			continue
+		}
+		if pm.Platform.Variant != "" {
+			platform += "/" + pm.Platform.Variant
+		}
+		m.Platforms[platform] = pm.Digest
+	}
+	return m, nil
+}
@@ -0,0 +1,220 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+var manifestListResp = `{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.docker.distribution.manifest.list.v2+json",
+  "manifests": [
+    {
+      "mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+      "digest": "sha256:amd64",
+      "platform": {"architecture": "amd64", "os": "linux"}
+    },
+    {
+      "mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+      "digest": "sha256:arm64",
+      "platform": {"architecture": "arm64", "os": "linux", "variant": "v8"}
+    },
+    {
+      "mediaType": "application/vnd.oci.image.manifest.v1+json",
+      "digest": "sha256:attestation",
+      "platform": {"architecture": "unknown", "os": "unknown"}
+    }
+  ]
+}`
+
+func TestManifestList(t *testing.T) {
+	var accept string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", MediaTypeDockerManifestList)
+		w.Header().Set("Docker-Content-Digest", "sha256:list")
+		fmt.Fprintln(w, manifestListResp)
+	}))
+	defer ts.Close()
+
+	client := New()
+	manifest, err := client.Manifest(Opts{
+		Registry: ts.URL,
+		Name:     "keelhq/keel",
+		Tag:      "latest",
+	})
+	if err != nil {
+		t.Fatalf("error while getting manifest: %s", err)
+	}
+
+	if accept == "" {
+		t.Errorf("expected Accept header to be set")
+	}
+	if !manifest.IsList() {
+		t.Errorf("expected manifest list, got: %s", manifest.MediaType)
+	}
+	if manifest.Digest != "sha256:list" {
+		t.Errorf("unexpected digest: %s", manifest.Digest)
+	}
+	if len(manifest.Platforms) != 2 {
+		t.Errorf("expected 2 platforms, got: %v", manifest.Platforms)
+	}
+
+	for platform, expected := range map[string]string{
+		"linux/amd64":    "sha256:amd64",
+		"linux/arm64":    "sha256:arm64",
+		"linux/arm64/v8": "sha256:arm64",
+		"linux/s390x":    "",
+	} {
+		if d := manifest.PlatformDigest(platform); d != expected {
+			t.Errorf("%s: unexpected digest: %s, expected: %s", platform, d, expected)
+		}
+	}
+}
+
+func TestManifestSingleArch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", MediaTypeDockerManifest)
+		fmt.Fprintln(w, registryResp)
+	}))
+	defer ts.Close()
+
+	client := New()
+	manifest, err := client.Manifest(Opts{
+		Registry: ts.URL,
+		Name:     "keelhq/keel",
+		Tag:      "0.8.0",
+	})
+	if err != nil {
+		t.Fatalf("error while getting manifest: %s", err)
+	}
+
+	if manifest.IsList() {
+		t.Errorf("unexpected manifest list")
+	}
+	if manifest.Digest == "" {
+		t.Errorf("expected digest to be calculated from the body")
+	}
+	if manifest.PlatformDigest("linux/arm64") != manifest.Digest {
+		t.Errorf("expected manifest digest for single-arch image")
+	}
+}
+
+func TestCreated(t *testing.T) {
+	config := `{"architecture": "amd64", "created": "2018-10-01T10:20:30Z"}`
+	configDigest := digest.FromString(config).String()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/keelhq/keel/manifests/latest":
+			w.Header().Set("Content-Type", MediaTypeDockerManifestList)
+			fmt.Fprintln(w, manifestListResp)
+		case "/v2/keelhq/keel/manifests/sha256:amd64":
+			w.Header().Set("Content-Type", MediaTypeDockerManifest)
+			fmt.Fprintf(w, `{"mediaType": %q, "config": {"mediaType": "application/vnd.docker.container.image.v1+json", "digest": %q}}`, MediaTypeDockerManifest, configDigest)
+		case "/v2/keelhq/keel/blobs/" + configDigest:
+			fmt.Fprint(w, config)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	client := New()
+	created, err := client.Created(Opts{
+		Registry: ts.URL,
+		Name:     "keelhq/keel",
+		Tag:      "latest",
+	})
+	if err != nil {
+		t.Fatalf("error while getting image creation time: %s", err)
+	}
+
+	if !created.Equal(time.Date(2018, 10, 1, 10, 20, 30, 0, time.UTC)) {
+		t.Errorf("unexpected creation time: %s", created)
+	}
+}
+
+func TestParseImageConfig(t *testing.T) {
+	config, err := parseImageConfig([]byte(`{"created": "2018-10-01T10:20:30Z", "config": {"Labels": {"org.opencontainers.image.revision": "abc123"}}}`))
+	if err != nil {
+		t.Fatalf("failed to parse image config: %s", err)
+	}
+	if config.Labels["org.opencontainers.image.revision"] != "abc123" {
+		t.Errorf("unexpected labels: %v", config.Labels)
+	}
+	if !config.Created.Equal(time.Date(2018, 10, 1, 10, 20, 30, 0, time.UTC)) {
+		t.Errorf("unexpected creation time: %s", config.Created)
+	}
+}
+
+func TestDigestOCIIndex(t *testing.T) {
+	var methods []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		// registries with only an OCI index reject clients that don't accept it
+		if !strings.Contains(r.Header.Get("Accept"), MediaTypeOCIIndex) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", MediaTypeOCIIndex)
+		w.Header().Set("Docker-Content-Digest", "sha256:"+strings.Repeat("a", 64))
+	}))
+	defer ts.Close()
+
+	d, err := New().Digest(Opts{Registry: ts.URL, Name: "keelhq/keel", Tag: "0.16.0"})
+	if err != nil {
+		t.Fatalf("failed to get digest: %s", err)
+	}
+	if d != "sha256:"+strings.Repeat("a", 64) {
+		t.Errorf("unexpected digest: %s", d)
+	}
+	if len(methods) != 1 || methods[0] != http.MethodHead {
+		t.Errorf("expected a single HEAD request, got: %v", methods)
+	}
+}
+
+func TestDigestWithoutHead(t *testing.T) {
+	body := `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json"}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", MediaTypeOCIManifest)
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+
+	d, err := New().Digest(Opts{Registry: ts.URL, Name: "keelhq/keel", Tag: "0.16.0"})
+	if err != nil {
+		t.Fatalf("failed to get digest: %s", err)
+	}
+	if d != digest.FromString(body).String() {
+		t.Errorf("unexpected digest: %s", d)
+	}
+}
+
+func TestManifestIndexArtifacts(t *testing.T) {
+	m, err := parseManifest(MediaTypeOCIIndex, "sha256:index", []byte(`{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.oci.image.index.v1+json",
+  "manifests": [
+    {"digest": "sha256:amd64", "platform": {"architecture": "amd64", "os": "linux"}},
+    {"digest": "sha256:sbom", "artifactType": "application/spdx+json"},
+    {"digest": "sha256:att", "annotations": {"vnd.docker.reference.type": "attestation-manifest", "vnd.docker.reference.digest": "sha256:amd64"}, "platform": {"architecture": "amd64", "os": "linux"}}
+  ]
+}`))
+	if err != nil {
+		t.Fatalf("failed to parse manifest: %s", err)
+	}
+	if len(m.Platforms) != 1 || m.Platforms["linux/amd64"] != "sha256:amd64" {
+		t.Errorf("unexpected platforms: %v", m.Platforms)
+	}
+}
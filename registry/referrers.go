@@ -0,0 +1,96 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"strings"
+
+	"github.com/rusenask/docker-registry-client/registry"
+)
+
+type referrersIndex struct {
+	Manifests []Descriptor `json:"manifests"`
+}
+
+// Referrers - manifests that refer to the digest (signatures, SBOMs, attestations),
+// artifactType is optional. Registries without the OCI referrers API are asked for
+// the referrers tag schema index (sha256-<digest>), digest without referrers
+// returns an empty list.
+func (c *DefaultClient) Referrers(opts Opts, digest, artifactType string) ([]Descriptor, error) {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid digest %q", digest)
+	}
+
+	opts = mirrored(opts)
+
+	// fallback to HTTP if the registry doesn't speak HTTPS https://github.com/keel-hq/keel/issues/331
+INIT_CLIENT:
+	hub, err := c.getRegistryClient(opts.Registry, opts.Username, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	url := hub.URL + fmt.Sprintf("/v2/%s/referrers/%s", opts.Name, digest)
+	if artifactType != "" {
+		url += "?artifactType=" + neturl.QueryEscape(artifactType)
+	}
+	referrers, err := getReferrers(hub, url)
+	if err != nil {
+		if strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") && strings.HasPrefix(opts.Registry, "https://") && c.insecure {
+			opts.Registry = strings.Replace(opts.Registry, "https://", "http://", 1)
+			goto INIT_CLIENT
+		}
+		if !IsNotFound(err) {
+			return nil, err
+		}
+
+		referrers, err = getReferrers(hub, hub.URL+fmt.Sprintf("/v2/%s/manifests/%s-%s", opts.Name, parts[0], parts[1]))
+		if IsNotFound(err) {
+			return []Descriptor{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// registries can ignore the filter, the tag schema doesn't support it
+	if artifactType == "" {
+		return referrers, nil
+	}
+	filtered := make([]Descriptor, 0, len(referrers))
+	for _, r := range referrers {
+		if r.ArtifactType == artifactType {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered, nil
+}
+
+func getReferrers(hub *registry.Registry, url string) ([]Descriptor, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", MediaTypeOCIIndex)
+
+	resp, err := hub.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var index referrersIndex
+	if err := json.Unmarshal(body, &index); err != nil {
+		return nil, fmt.Errorf("failed to decode referrers: %s", err)
+	}
+	return index.Manifests, nil
+}
@@ -1,11 +1,15 @@
 package registry
 
 import (
+	"crypto/tls"
 	"errors"
 	"hash/fnv"
+	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/rusenask/docker-registry-client/registry"
 
@@ -30,6 +34,8 @@ type Repository struct {
 type Client interface {
 	Get(opts Opts) (*Repository, error)
 	Digest(opts Opts) (string, error)
+	Manifest(opts Opts) (*Manifest, error)
+	Created(opts Opts) (time.Time, error)
 }
 
 // New - new registry client
@@ -83,14 +89,26 @@ func (c *DefaultClient) getRegistryClient(registryAddress, username, password st
 		return r, nil
 	}
 
-	url := strings.TrimSuffix(registryAddress, "/")
-	if os.Getenv(EnvInsecure) == "true" {
-		r = registry.NewInsecure(url, username, password)
-	} else {
-		r = registry.New(url, username, password)
+	registryURL := strings.TrimSuffix(registryAddress, "/")
+	var (
+		tlsConfig *tls.Config
+		proxy     *url.URL
+	)
+	if cfg := registryConfig(registryURL); cfg != nil {
+		tlsConfig, proxy = cfg.tls, cfg.proxy
+	}
+	if tlsConfig == nil && os.Getenv(EnvInsecure) == "true" {
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
 	}
 
-	r.Logf = LogFormatter
+	// tokens are cached across clients, see tokencache.go
+	r = &registry.Registry{
+		URL: registryURL,
+		Client: &http.Client{
+			Transport: newRegistryTransport(newTransport(tlsConfig, proxy), registryURL, username, password, tokens),
+		},
+		Logf: LogFormatter,
+	}
 
 	c.registries[h] = r
 
@@ -99,6 +117,7 @@ func (c *DefaultClient) getRegistryClient(registryAddress, username, password st
 
 // Get - get repository
 func (c *DefaultClient) Get(opts Opts) (*Repository, error) {
+	opts = mirrored(opts)
 
 	// fallback to HTTP if the registry doesn't speak HTTPS https://github.com/keel-hq/keel/issues/331
 INIT_CLIENT:
@@ -116,18 +135,43 @@ INIT_CLIENT:
 		return nil, err
 	}
 	repo := &Repository{
-		Tags: tags,
+		Tags: imageTags(tags),
 	}
 
 	return repo, nil
 }
 
+// Catalog - lists registry repositories (/v2/_catalog), not all registries
+// support it (ie: Docker Hub doesn't)
+func (c *DefaultClient) Catalog(opts Opts) ([]string, error) {
+
+	// fallback to HTTP if the registry doesn't speak HTTPS https://github.com/keel-hq/keel/issues/331
+INIT_CLIENT:
+	hub, err := c.getRegistryClient(opts.Registry, opts.Username, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	repositories, err := hub.Repositories()
+	if err != nil {
+		if strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") && strings.HasPrefix(opts.Registry, "https://") && c.insecure {
+			opts.Registry = strings.Replace(opts.Registry, "https://", "http://", 1)
+			goto INIT_CLIENT
+		}
+		return nil, err
+	}
+
+	return repositories, nil
+}
+
 // Digest - get digest for repo
 func (c *DefaultClient) Digest(opts Opts) (string, error) {
 	if opts.Tag == "" {
 		return "", ErrTagNotSupplied
 	}
 
+	opts = mirrored(opts)
+
 	// fallback to HTTP if the registry doesn't speak HTTPS https://github.com/keel-hq/keel/issues/331
 INIT_CLIENT:
 	hub, err := c.getRegistryClient(opts.Registry, opts.Username, opts.Password)
@@ -135,7 +179,7 @@ INIT_CLIENT:
 		return "", err
 	}
 
-	manifestDigest, err := hub.ManifestDigest(opts.Name, opts.Tag)
+	manifestDigest, err := manifestDigest(hub, opts.Name, opts.Tag)
 	if err != nil {
 		if strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") && strings.HasPrefix(opts.Registry, "https://") && c.insecure {
 			opts.Registry = strings.Replace(opts.Registry, "https://", "http://", 1)
@@ -144,5 +188,5 @@ INIT_CLIENT:
 		return "", err
 	}
 
-	return manifestDigest.String(), nil
+	return manifestDigest, nil
 }
@@ -0,0 +1,55 @@
+package registry
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// EnvTLSConfig - deprecated, path of the TLS only per-registry config, use
+// EnvConfig. TLS configs are valid registry configs so the file is loaded the
+// same way
+const EnvTLSConfig = "REGISTRY_TLS_CONFIG"
+
+// ConfigPath - path of the per-registry config, falls back to the deprecated
+// EnvTLSConfig when EnvConfig isn't set
+func ConfigPath() string {
+	if path := os.Getenv(EnvConfig); path != "" {
+		return path
+	}
+	path := os.Getenv(EnvTLSConfig)
+	if path != "" {
+		log.Warnf("registry: %s is deprecated, use %s instead", EnvTLSConfig, EnvConfig)
+	}
+	return path
+}
+
+func (c HostConfig) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+	if c.CA != "" {
+		ca, err := ioutil.ReadFile(c.CA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %s", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", c.CA)
+		}
+		cfg.RootCAs = pool
+	}
+	if c.Cert != "" || c.Key != "" {
+		pair, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %s", err)
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,111 @@
+package registry
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeClientCert - self-signed client certificate and key files
+func writeClientCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "keel"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	certPath, keyPath := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600)
+	return cert, certPath, keyPath
+}
+
+func TestTLSConfigMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "registrytls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	clientCert, certPath, keyPath := writeClientCert(t, dir)
+	clients := x509.NewCertPool()
+	clients.AddCert(clientCert)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name":"corp/app","tags":["1.0.0","1.1.0"]}`)
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clients}
+	ts.StartTLS()
+	defer ts.Close()
+
+	caPath := filepath.Join(dir, "ca.pem")
+	ioutil.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0600)
+
+	// without the config certificate is unknown
+	if _, err := New().Get(Opts{Registry: ts.URL, Name: "corp/app"}); err == nil {
+		t.Fatalf("expected error without TLS config")
+	}
+
+	configs, err := ParseConfig([]byte(fmt.Sprintf("%s:\n  ca: %s\n  cert: %s\n  key: %s\n", ts.URL, caPath, certPath, keyPath)))
+	if err != nil {
+		t.Fatalf("failed to parse config: %s", err)
+	}
+	if err := SetConfig(configs); err != nil {
+		t.Fatalf("failed to set config: %s", err)
+	}
+	defer SetConfig(nil)
+
+	repo, err := New().Get(Opts{Registry: ts.URL, Name: "corp/app"})
+	if err != nil {
+		t.Fatalf("failed to get tags: %s", err)
+	}
+	if len(repo.Tags) != 2 {
+		t.Errorf("unexpected tags: %v", repo.Tags)
+	}
+}
+
+func TestSetConfigMissingCA(t *testing.T) {
+	err := SetConfig(map[string]HostConfig{"registry.corp.example.com": {CA: "/does/not/exist.pem"}})
+	if err == nil {
+		t.Errorf("expected error for missing CA bundle")
+	}
+}
+
+func TestConfigPathDeprecatedTLSConfig(t *testing.T) {
+	defer os.Unsetenv(EnvConfig)
+	defer os.Unsetenv(EnvTLSConfig)
+
+	os.Setenv(EnvTLSConfig, "/etc/keel/registry-tls.yaml")
+	if path := ConfigPath(); path != "/etc/keel/registry-tls.yaml" {
+		t.Errorf("expected path of the TLS config, got: %s", path)
+	}
+
+	os.Setenv(EnvConfig, "/etc/keel/registry.yaml")
+	if path := ConfigPath(); path != "/etc/keel/registry.yaml" {
+		t.Errorf("expected path of the registry config, got: %s", path)
+	}
+}
@@ -0,0 +1,286 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rusenask/docker-registry-client/registry"
+)
+
+var tokenCacheCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "registry_token_cache_requests_total",
+		Help: "How many registry requests used a cached token (hit) or needed a token exchange (miss), partitioned by registry.",
+	},
+	[]string{"registry", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(tokenCacheCounter)
+}
+
+// defaultTokenExpiry - token lifetime when the token server doesn't say,
+// https://docs.docker.com/registry/spec/auth/token/
+const defaultTokenExpiry = 60 * time.Second
+
+// tokenExpiryMargin - tokens are renewed a bit earlier so they don't expire
+// in flight
+const tokenExpiryMargin = 10 * time.Second
+
+type cachedToken struct {
+	token   string
+	expires time.Time
+}
+
+// TokenCache - bearer tokens keyed by registry, credentials and scope, shared
+// by all clients so polls of the same repository reuse tokens
+type TokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]cachedToken
+	now    func() time.Time
+}
+
+// NewTokenCache - new empty cache
+func NewTokenCache() *TokenCache {
+	return &TokenCache{
+		tokens: make(map[string]cachedToken),
+		now:    time.Now,
+	}
+}
+
+// tokens - cache used by registry clients
+var tokens = NewTokenCache()
+
+func (c *TokenCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.tokens[key]
+	if !ok {
+		return "", false
+	}
+	if !c.now().Before(t.expires) {
+		delete(c.tokens, key)
+		return "", false
+	}
+	return t.token, true
+}
+
+func (c *TokenCache) set(key, token string, expiresIn time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens[key] = cachedToken{token: token, expires: c.now().Add(expiresIn - tokenExpiryMargin)}
+}
+
+func (c *TokenCache) delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tokens, key)
+}
+
+// InvalidateTokens - drops cached tokens of the registry, ie: after its
+// credentials were rotated
+func InvalidateTokens(registry string) {
+	tokens.invalidate(registryHost(registry))
+}
+
+func (c *TokenCache) invalidate(host string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.tokens {
+		if strings.HasPrefix(key, host+"|") {
+			delete(c.tokens, key)
+		}
+	}
+}
+
+// requestScope - token scope of the registry API request, ie:
+// repository:keelhq/keel:pull for /v2/keelhq/keel/manifests/0.8.0
+func requestScope(path string) string {
+	path = strings.TrimPrefix(path, "/v2/")
+	if path == "_catalog" {
+		return "registry:catalog:*"
+	}
+	for _, endpoint := range []string{"/tags/", "/manifests/", "/blobs/"} {
+		if i := strings.LastIndex(path, endpoint); i > 0 {
+			return "repository:" + path[:i] + ":pull"
+		}
+	}
+	return ""
+}
+
+// tokenTransport - replaces the registry client token transport, tokens of
+// the bearer challenges are cached until they expire
+type tokenTransport struct {
+	transport http.RoundTripper
+	registry  string
+	username  string
+	password  string
+	cache     *TokenCache
+}
+
+func newRegistryTransport(base http.RoundTripper, url, username, password string, cache *TokenCache) http.RoundTripper {
+	return &registry.ErrorTransport{
+		Transport: &registry.BasicTransport{
+			Transport: &tokenTransport{
+				transport: base,
+				registry:  registryHost(url),
+				username:  username,
+				password:  password,
+				cache:     cache,
+			},
+			URL:      url,
+			Username: username,
+			Password: password,
+		},
+	}
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := ""
+	if scope := requestScope(req.URL.Path); scope != "" {
+		key = t.registry + "|" + t.username + "|" + scope
+		if token, ok := t.cache.get(key); ok {
+			resp, err := t.transport.RoundTrip(withToken(req, token))
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				tokenCacheCounter.With(prometheus.Labels{"registry": t.registry, "result": "hit"}).Inc()
+				return resp, err
+			}
+			// revoked or narrower than needed, exchanging again
+			resp.Body.Close()
+			t.cache.delete(key)
+		}
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	challenge := bearerChallenge(resp)
+	if challenge == nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+	tokenCacheCounter.With(prometheus.Labels{"registry": t.registry, "result": "miss"}).Inc()
+
+	token, expiresIn, authResp, err := t.exchange(challenge)
+	if err != nil || authResp != nil {
+		return authResp, err
+	}
+	if key != "" {
+		t.cache.set(key, token, expiresIn)
+	}
+	return t.transport.RoundTrip(withToken(req, token))
+}
+
+func withToken(req *http.Request, token string) *http.Request {
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+type challenge struct {
+	realm, service, scope string
+}
+
+func bearerChallenge(resp *http.Response) *challenge {
+	if resp.StatusCode != http.StatusUnauthorized {
+		return nil
+	}
+	header := resp.Header.Get("Www-Authenticate")
+	if !strings.HasPrefix(strings.ToLower(header), "bearer ") {
+		return nil
+	}
+	params := parseChallengeParams(header[len("bearer "):])
+	return &challenge{realm: params["realm"], service: params["service"], scope: params["scope"]}
+}
+
+// parseChallengeParams - key="value" pairs separated by commas, quoted values
+// can contain commas (ie: scope="repository:a:pull,push")
+func parseChallengeParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; {
+		eq := strings.Index(s, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimSpace(s[eq+1:])
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.Index(s[1:], `"`)
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else if comma := strings.Index(s, ","); comma >= 0 {
+			value, s = s[:comma], s[comma:]
+		} else {
+			value, s = s, ""
+		}
+		params[key] = value
+		s = strings.TrimPrefix(strings.TrimSpace(s), ",")
+		s = strings.TrimSpace(s)
+	}
+	return params
+}
+
+type tokenResponse struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// exchange - requests token from the token server, failed response is
+// returned so the caller gets the token server error
+func (t *tokenTransport) exchange(c *challenge) (string, time.Duration, *http.Response, error) {
+	u, err := url.Parse(c.realm)
+	if err != nil {
+		return "", 0, nil, fmt.Errorf("invalid token realm %q: %s", c.realm, err)
+	}
+	q := u.Query()
+	if c.service != "" {
+		q.Set("service", c.service)
+	}
+	if c.scope != "" {
+		q.Set("scope", c.scope)
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if t.username != "" || t.password != "" {
+		req.SetBasicAuth(t.username, t.password)
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, resp, nil
+	}
+	defer resp.Body.Close()
+
+	var tr tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return "", 0, nil, fmt.Errorf("failed to decode token response: %s", err)
+	}
+	token := tr.Token
+	if token == "" {
+		token = tr.AccessToken
+	}
+	expiresIn := defaultTokenExpiry
+	if tr.ExpiresIn > 0 {
+		expiresIn = time.Duration(tr.ExpiresIn) * time.Second
+	}
+	return token, expiresIn, nil, nil
+}
@@ -0,0 +1,142 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTokenRegistry - registry with a token server, tokens are valid until the
+// token server issues a new one
+func newTokenRegistry(t *testing.T, expiresIn int) (*httptest.Server, *int32) {
+	var exchanges int32
+	var current atomic.Value
+	current.Store("")
+
+	mux := http.NewServeMux()
+	var ts *httptest.Server
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("scope") != "repository:corp/app:pull" {
+			t.Errorf("unexpected scope: %s", r.URL.Query().Get("scope"))
+		}
+		n := atomic.AddInt32(&exchanges, 1)
+		token := fmt.Sprintf("token-%d", n)
+		current.Store(token)
+		fmt.Fprintf(w, `{"token":%q,"expires_in":%d}`, token, expiresIn)
+	})
+	mux.HandleFunc("/v2/corp/app/tags/list", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+current.Load().(string) {
+			w.Header().Set("Www-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:corp/app:pull"`, ts.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"name":"corp/app","tags":["1.0.0"]}`)
+	})
+	ts = httptest.NewServer(mux)
+	return ts, &exchanges
+}
+
+func TestTokenCacheReuse(t *testing.T) {
+	ts, exchanges := newTokenRegistry(t, 300)
+	defer ts.Close()
+
+	cache := NewTokenCache()
+	client := &http.Client{Transport: newRegistryTransport(http.DefaultTransport, ts.URL, "user", "pass", cache)}
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(ts.URL + "/v2/corp/app/tags/list")
+		if err != nil {
+			t.Fatalf("request %d failed: %s", i, err)
+		}
+		resp.Body.Close()
+	}
+	if n := atomic.LoadInt32(exchanges); n != 1 {
+		t.Errorf("expected 1 token exchange, got %d", n)
+	}
+
+	// expired tokens are exchanged again
+	cache.now = func() time.Time { return time.Now().Add(time.Hour) }
+	resp, err := client.Get(ts.URL + "/v2/corp/app/tags/list")
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	resp.Body.Close()
+	if n := atomic.LoadInt32(exchanges); n != 2 {
+		t.Errorf("expected 2 token exchanges, got %d", n)
+	}
+}
+
+func TestTokenCacheSeparatesCredentials(t *testing.T) {
+	ts, _ := newTokenRegistry(t, 300)
+	defer ts.Close()
+
+	cache := NewTokenCache()
+	client := &http.Client{Transport: newRegistryTransport(http.DefaultTransport, ts.URL, "user", "pass", cache)}
+	resp, err := client.Get(ts.URL + "/v2/corp/app/tags/list")
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	resp.Body.Close()
+
+	// other credentials don't get the cached token
+	other := &http.Client{Transport: newRegistryTransport(http.DefaultTransport, ts.URL, "other", "wrong", cache)}
+	if _, err := other.Get(ts.URL + "/v2/corp/app/tags/list"); err == nil {
+		t.Errorf("expected token exchange with wrong credentials to fail")
+	}
+}
+
+func TestTokenCacheInvalidate(t *testing.T) {
+	cache := NewTokenCache()
+	cache.set("registry.corp.example.com|user|repository:corp/app:pull", "token", time.Minute)
+	cache.set("registry.corp.example.com:5000|user|repository:corp/app:pull", "token", time.Minute)
+
+	cache.invalidate("registry.corp.example.com")
+	if _, ok := cache.get("registry.corp.example.com|user|repository:corp/app:pull"); ok {
+		t.Errorf("expected token to be invalidated")
+	}
+	if _, ok := cache.get("registry.corp.example.com:5000|user|repository:corp/app:pull"); !ok {
+		t.Errorf("expected token of the other registry to be kept")
+	}
+}
+
+func TestIsUnauthorized(t *testing.T) {
+	ts, _ := newTokenRegistry(t, 300)
+	defer ts.Close()
+
+	client := &http.Client{Transport: newRegistryTransport(http.DefaultTransport, ts.URL, "other", "wrong", NewTokenCache())}
+	_, err := client.Get(ts.URL + "/v2/corp/app/tags/list")
+	if !IsUnauthorized(err) {
+		t.Errorf("expected unauthorized error, got %v", err)
+	}
+	if IsUnauthorized(fmt.Errorf("connection refused")) {
+		t.Errorf("unexpected unauthorized error")
+	}
+}
+
+func TestRequestScope(t *testing.T) {
+	for path, expected := range map[string]string{
+		"/v2/keelhq/keel/tags/list":        "repository:keelhq/keel:pull",
+		"/v2/library/nginx/manifests/1.19": "repository:library/nginx:pull",
+		"/v2/a/b/c/blobs/sha256:0123":      "repository:a/b/c:pull",
+		"/v2/_catalog":                     "registry:catalog:*",
+		"/v2/":                             "",
+	} {
+		if scope := requestScope(path); scope != expected {
+			t.Errorf("%s: expected %q, got %q", path, expected, scope)
+		}
+	}
+}
+
+func TestParseChallengeParams(t *testing.T) {
+	params := parseChallengeParams(`realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:a:pull,push"`)
+	if params["realm"] != "https://auth.docker.io/token" || params["service"] != "registry.docker.io" || params["scope"] != "repository:a:pull,push" {
+		t.Errorf("unexpected params: %v", params)
+	}
+}
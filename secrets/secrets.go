@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/keel-hq/keel/provider/helm"
 	"github.com/keel-hq/keel/provider/kubernetes"
@@ -37,6 +38,13 @@ type Getter interface {
 type DefaultGetter struct {
 	kubernetesImplementer kubernetes.Implementer
 	defaultDockerConfig   DockerCfg // default configuration supplied by optional environment variable
+
+	// clusters - implementers of additional clusters, map[cluster]implementer
+	clusters map[string]kubernetes.Implementer
+
+	// cached - watched secrets, map[cluster/namespace/name]secret
+	secretsM sync.RWMutex
+	cached   map[string]*v1.Secret
 }
 
 // NewGetter - create new default getter
@@ -50,7 +58,21 @@ func NewGetter(implementer kubernetes.Implementer, defaultDockerConfig DockerCfg
 	return &DefaultGetter{
 		kubernetesImplementer: implementer,
 		defaultDockerConfig:   defaultDockerConfig,
+		clusters:              make(map[string]kubernetes.Implementer),
+		cached:                make(map[string]*v1.Secret),
+	}
+}
+
+// AddCluster - secrets of images tracked in the cluster are looked up with the implementer
+func (g *DefaultGetter) AddCluster(name string, implementer kubernetes.Implementer) {
+	g.clusters[name] = implementer
+}
+
+func (g *DefaultGetter) implementer(image *types.TrackedImage) kubernetes.Implementer {
+	if implementer, ok := g.clusters[image.Meta["cluster"]]; ok {
+		return implementer
 	}
+	return g.kubernetesImplementer
 }
 
 // Get - get secret for tracked image
@@ -98,7 +120,7 @@ func (g *DefaultGetter) lookupSecrets(image *types.TrackedImage) ([]string, erro
 		return secrets, nil
 	}
 
-	podList, err := g.kubernetesImplementer.Pods(image.Namespace, selector)
+	podList, err := g.implementer(image).Pods(image.Namespace, selector)
 	if err != nil {
 		return secrets, err
 	}
@@ -144,7 +166,7 @@ func (g *DefaultGetter) getCredentialsFromSecret(image *types.TrackedImage) (*ty
 	secretFound := false
 
 	for _, secretRef := range image.Secrets {
-		secret, err := g.kubernetesImplementer.Secret(image.Namespace, secretRef)
+		secret, err := g.secret(image, secretRef)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"image":      image.Image.Repository(),
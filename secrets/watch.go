@@ -0,0 +1,122 @@
+package secrets
+
+import (
+	"bytes"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// EventHandler - keeps docker config secrets of the cluster (name of the
+// tracked images cluster metadata) cached, secrets are then read from the cache instead of the API.
+// Rotated secrets drop cached registry tokens and end error backoffs of the
+// images using them.
+func (g *DefaultGetter) EventHandler(cluster string) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if secret, ok := dockerSecret(obj); ok {
+				g.store(cluster, secret)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			secret, ok := dockerSecret(newObj)
+			if !ok {
+				g.remove(cluster, secret)
+				return
+			}
+			g.store(cluster, secret)
+			if old, ok := dockerSecret(oldObj); ok && !secretDataEqual(old, secret) {
+				g.rotated(cluster, old)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if secret, ok := dockerSecret(obj); ok {
+				g.remove(cluster, secret)
+				g.rotated(cluster, secret)
+			}
+		},
+	}
+}
+
+func dockerSecret(obj interface{}) (*v1.Secret, bool) {
+	secret, ok := obj.(*v1.Secret)
+	if !ok {
+		return nil, false
+	}
+	return secret, secret.Type == v1.SecretTypeDockercfg || secret.Type == v1.SecretTypeDockerConfigJson
+}
+
+func secretDataEqual(a, b *v1.Secret) bool {
+	if len(a.Data) != len(b.Data) {
+		return false
+	}
+	for k, v := range a.Data {
+		if !bytes.Equal(v, b.Data[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+func secretKey(cluster, namespace, name string) string {
+	return cluster + "/" + namespace + "/" + name
+}
+
+func (g *DefaultGetter) store(cluster string, secret *v1.Secret) {
+	g.secretsM.Lock()
+	g.cached[secretKey(cluster, secret.Namespace, secret.Name)] = secret
+	g.secretsM.Unlock()
+}
+
+func (g *DefaultGetter) remove(cluster string, secret *v1.Secret) {
+	if secret == nil {
+		return
+	}
+	g.secretsM.Lock()
+	delete(g.cached, secretKey(cluster, secret.Namespace, secret.Name))
+	g.secretsM.Unlock()
+}
+
+// rotated - drops registry tokens issued for the old secret credentials
+func (g *DefaultGetter) rotated(cluster string, old *v1.Secret) {
+	log.WithFields(log.Fields{
+		"cluster":   cluster,
+		"namespace": old.Namespace,
+		"secret":    old.Name,
+	}).Info("secrets.defaultGetter: image pull secret changed, reloading credentials")
+
+	if cfg, err := decodeDockerSecret(old); err == nil {
+		for reg := range cfg {
+			registry.InvalidateTokens(reg)
+		}
+	}
+	credentialshelper.SecretChanged(cluster, old.Namespace, old.Name)
+}
+
+// secret - cached secret when the cluster secrets are watched, secrets
+// missing in the cache are fetched from the API
+func (g *DefaultGetter) secret(image *types.TrackedImage, name string) (*v1.Secret, error) {
+	g.secretsM.RLock()
+	secret, ok := g.cached[secretKey(image.Meta["cluster"], image.Namespace, name)]
+	g.secretsM.RUnlock()
+	if ok {
+		return secret, nil
+	}
+	return g.implementer(image).Secret(image.Namespace, name)
+}
+
+func decodeDockerSecret(secret *v1.Secret) (DockerCfg, error) {
+	if secret.Type == v1.SecretTypeDockercfg {
+		return decodeSecret(secret.Data[dockerConfigKey])
+	}
+	return DecodeDockerCfgJson(secret.Data[dockerConfigJSONKey])
+}
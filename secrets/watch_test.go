@@ -0,0 +1,74 @@
+package secrets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+	testutil "github.com/keel-hq/keel/util/testing"
+
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func dockercfgSecret(payload string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "myregistrysecret", Namespace: "default"},
+		Data: map[string][]byte{
+			dockerConfigKey: []byte(payload),
+		},
+		Type: v1.SecretTypeDockercfg,
+	}
+}
+
+func TestWatchedSecretRotation(t *testing.T) {
+	imgRef, _ := image.Parse("karolisr/webhook-demo:0.0.11")
+
+	old := dockercfgSecret(secretDataPayload)
+	impl := &testutil.FakeK8sImplementer{
+		AvailableSecret: map[string]*v1.Secret{"myregistrysecret": old},
+	}
+	getter := NewGetter(impl, nil)
+	handler := getter.EventHandler("")
+	handler.OnAdd(old)
+
+	trackedImage := &types.TrackedImage{
+		Image:     imgRef,
+		Namespace: "default",
+		Secrets:   []string{"myregistrysecret"},
+	}
+
+	creds, err := getter.Get(trackedImage)
+	if err != nil {
+		t.Fatalf("failed to get creds: %s", err)
+	}
+	if creds.Username != "user-x" {
+		t.Errorf("unexpected username: %s", creds.Username)
+	}
+
+	before := time.Now().Add(-time.Second)
+	handler.OnUpdate(old, dockercfgSecret(secretDataPayload2))
+
+	creds, err = getter.Get(trackedImage)
+	if err != nil {
+		t.Fatalf("failed to get creds: %s", err)
+	}
+	if creds.Username != "foo-user-x-2" || creds.Password != "bar-pass-x-2" {
+		t.Errorf("expected rotated credentials, got %s:%s", creds.Username, creds.Password)
+	}
+	if !credentialshelper.SecretsChangedSince(trackedImage, before) {
+		t.Errorf("expected secret change to be recorded")
+	}
+
+	// deleted secrets are looked up in the API
+	handler.OnDelete(dockercfgSecret(secretDataPayload2))
+	creds, err = getter.Get(trackedImage)
+	if err != nil {
+		t.Fatalf("failed to get creds: %s", err)
+	}
+	if creds.Username != "user-x" {
+		t.Errorf("expected API secret, got %s", creds.Username)
+	}
+}
@@ -0,0 +1,206 @@
+// Package chart - watches Helm chart repositories for new versions of the charts
+// that are used by releases, new versions are submitted to providers as chart events
+package chart
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/provider"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/version"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TriggerName - chart trigger name
+const TriggerName = "chart"
+
+// DefaultInterval - how often chart repositories are checked
+const DefaultInterval = 5 * time.Minute
+
+var chartRepositoriesScannedCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "chart_repositories_scanned_total",
+		Help: "How many chart repositories were checked for new chart versions, partitioned by chart.",
+	},
+	[]string{"chart"},
+)
+
+func init() {
+	prometheus.MustRegister(chartRepositoriesScannedCounter)
+}
+
+// Lister - lists charts of releases that should be watched
+type Lister interface {
+	TrackedCharts() ([]*types.TrackedChart, error)
+}
+
+// Repositories - chart repository client
+type Repositories interface {
+	Versions(repository, name string) ([]string, error)
+	// Digest - digest of the chart version, providers pull chart by it
+	Digest(repository, name, version string) (string, error)
+}
+
+// Trigger - periodically checks chart repositories
+type Trigger struct {
+	providers    provider.Providers
+	lister       Lister
+	repositories Repositories
+	interval     time.Duration
+
+	mu sync.Mutex
+	// submitted - last submitted version per release, map[namespace/release]version
+	submitted map[string]string
+}
+
+// New - creates chart trigger
+func New(providers provider.Providers, lister Lister, repositories Repositories, interval time.Duration) *Trigger {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Trigger{
+		providers:    providers,
+		lister:       lister,
+		repositories: repositories,
+		interval:     interval,
+		submitted:    make(map[string]string),
+	}
+}
+
+// Start - starts checking chart repositories, blocks until ctx is cancelled
+func (t *Trigger) Start(ctx context.Context) {
+	log.WithFields(log.Fields{
+		"interval": t.interval,
+	}).Info("trigger.chart: chart trigger configured")
+
+	t.scan()
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.scan()
+		}
+	}
+}
+
+func (t *Trigger) scan() {
+	charts, err := t.lister.TrackedCharts()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("trigger.chart: failed to get tracked charts")
+		return
+	}
+
+	// several releases can use the same chart, versions are fetched once per scan
+	versions := make(map[string][]string)
+
+	for _, c := range charts {
+		ref := c.Reference()
+		available, ok := versions[ref]
+		if !ok {
+			available, err = t.repositories.Versions(c.Repository, c.Name)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"chart": ref,
+				}).Error("trigger.chart: failed to get chart versions")
+				continue
+			}
+			versions[ref] = available
+			chartRepositoriesScannedCounter.With(prometheus.Labels{"chart": ref}).Inc()
+		}
+
+		latest := latestVersion(c, available)
+		if latest == "" {
+			continue
+		}
+
+		t.submit(c, latest)
+	}
+}
+
+// latestVersion - highest version the release can be updated to according to its policy
+func latestVersion(c *types.TrackedChart, available []string) string {
+	latest := c.Version
+	for _, v := range available {
+		if _, err := version.GetVersion(v); err != nil {
+			continue
+		}
+		update, err := c.Policy.ShouldUpdate(latest, v)
+		if err != nil || !update {
+			continue
+		}
+		latest = v
+	}
+	if latest == c.Version {
+		return ""
+	}
+	return latest
+}
+
+func (t *Trigger) submit(c *types.TrackedChart, latest string) {
+	key := c.Namespace + "/" + c.Release
+
+	t.mu.Lock()
+	if t.submitted[key] == latest {
+		t.mu.Unlock()
+		return
+	}
+	t.mu.Unlock()
+
+	// version is resolved to digest so that providers upgrade to this exact chart
+	// even if the version gets overwritten in the repository
+	digest, err := t.repositories.Digest(c.Repository, c.Name, latest)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"chart":   c.Reference(),
+			"version": latest,
+		}).Error("trigger.chart: failed to get chart digest")
+		return
+	}
+
+	t.mu.Lock()
+	t.submitted[key] = latest
+	t.mu.Unlock()
+
+	log.WithFields(log.Fields{
+		"chart":       c.Reference(),
+		"release":     key,
+		"current":     c.Version,
+		"new_version": latest,
+		"digest":      digest,
+	}).Info("trigger.chart: new chart version found, submitting event to providers")
+
+	err = t.providers.Submit(types.Event{
+		Repository: types.Repository{
+			Name:   c.Reference(),
+			Tag:    latest,
+			Digest: digest,
+		},
+		CreatedAt:   time.Now(),
+		TriggerName: TriggerName,
+		Chart:       true,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"chart": c.Reference(),
+		}).Error("trigger.chart: failed to submit event")
+
+		t.mu.Lock()
+		delete(t.submitted, key)
+		t.mu.Unlock()
+	}
+}
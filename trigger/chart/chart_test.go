@@ -0,0 +1,130 @@
+package chart
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/types"
+)
+
+type fakeProviders struct {
+	submitted []types.Event
+}
+
+func (p *fakeProviders) Submit(event types.Event) error {
+	p.submitted = append(p.submitted, event)
+	return nil
+}
+
+func (p *fakeProviders) TrackedImages() ([]*types.TrackedImage, error) {
+	return nil, nil
+}
+
+func (p *fakeProviders) List() []string {
+	return []string{"fake"}
+}
+
+func (p *fakeProviders) Stop() {}
+
+type fakeLister struct {
+	charts []*types.TrackedChart
+}
+
+func (l *fakeLister) TrackedCharts() ([]*types.TrackedChart, error) {
+	return l.charts, nil
+}
+
+type fakeRepositories struct {
+	calls    int
+	versions []string
+}
+
+func (r *fakeRepositories) Versions(repository, name string) ([]string, error) {
+	r.calls++
+	return r.versions, nil
+}
+
+func (r *fakeRepositories) Digest(repository, name, version string) (string, error) {
+	return "sha256:" + version, nil
+}
+
+func TestScan(t *testing.T) {
+	providers := &fakeProviders{}
+	lister := &fakeLister{
+		charts: []*types.TrackedChart{
+			{
+				Repository: "https://charts.example.com",
+				Name:       "mychart",
+				Version:    "1.0.0",
+				Namespace:  "default",
+				Release:    "minor",
+				Policy:     policy.NewSemverPolicy(policy.SemverPolicyTypeMinor),
+			},
+			{
+				Repository: "https://charts.example.com",
+				Name:       "mychart",
+				Version:    "1.0.0",
+				Namespace:  "default",
+				Release:    "major",
+				Policy:     policy.NewSemverPolicy(policy.SemverPolicyTypeMajor),
+			},
+		},
+	}
+	repositories := &fakeRepositories{versions: []string{"0.9.0", "1.0.0", "1.2.0", "1.1.0", "2.0.0", "latest"}}
+
+	trigger := New(providers, lister, repositories, 0)
+	trigger.scan()
+
+	if repositories.calls != 1 {
+		t.Errorf("expected versions to be fetched once, got: %d", repositories.calls)
+	}
+
+	if len(providers.submitted) != 2 {
+		t.Fatalf("expected 2 events, got: %d", len(providers.submitted))
+	}
+
+	for i, expected := range []string{"1.2.0", "2.0.0"} {
+		event := providers.submitted[i]
+		if !event.Chart {
+			t.Errorf("expected chart event")
+		}
+		if event.Repository.Name != "https://charts.example.com/mychart" {
+			t.Errorf("unexpected repository name: %s", event.Repository.Name)
+		}
+		if event.Repository.Tag != expected {
+			t.Errorf("unexpected version: %s, expected: %s", event.Repository.Tag, expected)
+		}
+		if event.Repository.Digest != "sha256:"+expected {
+			t.Errorf("unexpected digest: %s", event.Repository.Digest)
+		}
+	}
+
+	// same versions are not submitted again
+	trigger.scan()
+	if len(providers.submitted) != 2 {
+		t.Errorf("expected 2 events, got: %d", len(providers.submitted))
+	}
+}
+
+func TestScanUpToDate(t *testing.T) {
+	providers := &fakeProviders{}
+	lister := &fakeLister{
+		charts: []*types.TrackedChart{
+			{
+				Repository: "oci://registry.example.com/charts",
+				Name:       "mychart",
+				Version:    "1.2.0",
+				Namespace:  "default",
+				Release:    "app",
+				Policy:     policy.NewSemverPolicy(policy.SemverPolicyTypeMinor),
+			},
+		},
+	}
+
+	trigger := New(providers, lister, &fakeRepositories{versions: []string{"1.1.0", "1.2.0", "2.0.0"}}, 0)
+	trigger.scan()
+
+	if len(providers.submitted) != 0 {
+		t.Errorf("expected no events, got: %d", len(providers.submitted))
+	}
+}
@@ -0,0 +1,100 @@
+// Package dedup - suppresses duplicate trigger events. Registries often deliver
+// the same push webhook several times, without deduplication every delivery
+// would result in a separate update (or approval request).
+package dedup
+
+import (
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/provider"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var duplicateEventsCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "trigger_duplicate_events_suppressed_total",
+		Help: "How many duplicate trigger events were suppressed, partitioned by trigger and image.",
+	},
+	[]string{"trigger", "image"},
+)
+
+func init() {
+	prometheus.MustRegister(duplicateEventsCounter)
+}
+
+// Providers - wraps providers, events with the same image, tag and digest
+// that are received within the window are submitted only once
+type Providers struct {
+	provider.Providers
+
+	window time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// New - creates deduplicating providers wrapper
+func New(providers provider.Providers, window time.Duration) *Providers {
+	return &Providers{
+		Providers: providers,
+		window:    window,
+		seen:      make(map[string]time.Time),
+	}
+}
+
+func key(event *types.Event) string {
+	return event.Repository.Name + ":" + event.Repository.Tag + "@" + event.Repository.Digest
+}
+
+// Submit - submits event unless the same event was already submitted within the window
+func (p *Providers) Submit(event types.Event) error {
+	if p.duplicate(&event) {
+		log.WithFields(log.Fields{
+			"image":   event.Repository.Name,
+			"tag":     event.Repository.Tag,
+			"digest":  event.Repository.Digest,
+			"trigger": event.TriggerName,
+		}).Info("trigger.dedup: duplicate event suppressed")
+		duplicateEventsCounter.With(prometheus.Labels{"trigger": event.TriggerName, "image": event.Repository.Name}).Inc()
+		return nil
+	}
+
+	err := p.Providers.Submit(event)
+	if err != nil {
+		// failed events can be retried straight away
+		p.forget(&event)
+	}
+	return err
+}
+
+func (p *Providers) duplicate(event *types.Event) bool {
+	now := timeutil.Now()
+	k := key(event)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for sk, ts := range p.seen {
+		if now.Sub(ts) >= p.window {
+			delete(p.seen, sk)
+		}
+	}
+
+	if _, ok := p.seen[k]; ok {
+		return true
+	}
+	p.seen[k] = now
+	return false
+}
+
+func (p *Providers) forget(event *types.Event) {
+	p.mu.Lock()
+	delete(p.seen, key(event))
+	p.mu.Unlock()
+}
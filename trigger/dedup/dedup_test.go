@@ -0,0 +1,81 @@
+package dedup
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+)
+
+type fakeProviders struct {
+	submitted []types.Event
+	err       error
+}
+
+func (p *fakeProviders) Submit(event types.Event) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.submitted = append(p.submitted, event)
+	return nil
+}
+
+func (p *fakeProviders) TrackedImages() ([]*types.TrackedImage, error) { return nil, nil }
+func (p *fakeProviders) List() []string                                { return nil }
+func (p *fakeProviders) Stop()                                         {}
+
+func TestSubmitDuplicates(t *testing.T) {
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	timeutil.Now = func() time.Time { return now }
+	defer func() { timeutil.Now = time.Now }()
+
+	fp := &fakeProviders{}
+	p := New(fp, time.Minute)
+
+	event := types.Event{
+		Repository:  types.Repository{Name: "gcr.io/v2-namespace/hello-world", Tag: "1.1.1", Digest: "sha256:abc"},
+		TriggerName: "dockerhub",
+	}
+
+	p.Submit(event)
+	p.Submit(event)
+	if len(fp.submitted) != 1 {
+		t.Fatalf("expected duplicate to be suppressed, submitted: %d", len(fp.submitted))
+	}
+
+	// different digest is a different push
+	other := event
+	other.Repository.Digest = "sha256:def"
+	p.Submit(other)
+	if len(fp.submitted) != 2 {
+		t.Fatalf("expected event with different digest to be submitted, submitted: %d", len(fp.submitted))
+	}
+
+	// window expired
+	now = now.Add(time.Minute)
+	p.Submit(event)
+	if len(fp.submitted) != 3 {
+		t.Fatalf("expected event to be submitted after window, submitted: %d", len(fp.submitted))
+	}
+}
+
+func TestSubmitFailedIsNotRemembered(t *testing.T) {
+	fp := &fakeProviders{err: errors.New("boom")}
+	p := New(fp, time.Minute)
+
+	event := types.Event{
+		Repository: types.Repository{Name: "gcr.io/v2-namespace/hello-world", Tag: "1.1.1"},
+	}
+
+	if err := p.Submit(event); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	fp.err = nil
+	p.Submit(event)
+	if len(fp.submitted) != 1 {
+		t.Fatalf("expected retried event to be submitted, submitted: %d", len(fp.submitted))
+	}
+}
@@ -0,0 +1,247 @@
+// Package ecr - trigger that consumes ECR image push events from an SQS queue. EventBridge
+// rule (source "aws.ecr", detail-type "ECR Image Action") should target the queue, events
+// delivered through SNS subscriptions are supported as well.
+package ecr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+
+	"github.com/keel-hq/keel/provider"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/awsauth"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TriggerName - name of the events that are submitted by this trigger
+const TriggerName = "ecr"
+
+const (
+	maxMessages     = 10
+	longPollSeconds = 20
+	maxBackoff      = 2 * time.Minute
+)
+
+var ecrEventsCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "ecr_sqs_events_total",
+		Help: "How many ECR push events were received from SQS, partitioned by image.",
+	},
+	[]string{"image"},
+)
+
+func init() {
+	prometheus.MustRegister(ecrEventsCounter)
+}
+
+// errIgnored - message is valid but doesn't contain image push
+var errIgnored = errors.New("event ignored")
+
+// Opts - trigger options
+type Opts struct {
+	QueueURL  string
+	Region    string
+	Providers provider.Providers
+}
+
+// Trigger - long polls SQS queue for ECR events
+type Trigger struct {
+	queue     Queue
+	queueURL  string
+	providers provider.Providers
+}
+
+// New - creates new trigger, region is taken from the queue URL if it's not set
+func New(opts *Opts) (*Trigger, error) {
+	region := opts.Region
+	if region == "" {
+		region = queueRegion(opts.QueueURL)
+	}
+
+	sess, err := awsauth.NewSession(region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %s", err)
+	}
+
+	return &Trigger{
+		queue:     newSQSClient(sess, aws.NewConfig().WithRegion(region)),
+		queueURL:  opts.QueueURL,
+		providers: opts.Providers,
+	}, nil
+}
+
+// queueRegion - https://sqs.us-west-2.amazonaws.com/123456789012/keel -> us-west-2
+func queueRegion(queueURL string) string {
+	u, err := url.Parse(queueURL)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(u.Host, ".")
+	if len(parts) > 2 && parts[0] == "sqs" {
+		return parts[1]
+	}
+	return ""
+}
+
+// Start - consumes messages until context is cancelled
+func (t *Trigger) Start(ctx context.Context) {
+	log.WithFields(log.Fields{
+		"queue": t.queueURL,
+	}).Info("trigger.ecr: starting SQS consumer")
+
+	var backoff time.Duration
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		messages, err := t.queue.ReceiveMessages(ctx, t.queueURL, maxMessages, longPollSeconds)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			backoff = timeutil.ExpBackoff(backoff, maxBackoff)
+			log.WithFields(log.Fields{
+				"error": err,
+				"queue": t.queueURL,
+				"retry": backoff,
+			}).Error("trigger.ecr: failed to receive messages")
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			continue
+		}
+		backoff = 0
+
+		for _, msg := range messages {
+			t.processMessage(ctx, msg)
+		}
+	}
+}
+
+// processMessage - submits event and deletes the message. If the event can't
+// be submitted message is kept so it's received again after visibility timeout.
+func (t *Trigger) processMessage(ctx context.Context, msg *Message) {
+	event, err := ParseMessage([]byte(aws.StringValue(msg.Body)))
+	switch {
+	case err == errIgnored:
+		log.WithFields(log.Fields{
+			"message_id": aws.StringValue(msg.MessageId),
+		}).Debug("trigger.ecr: message ignored")
+	case err != nil:
+		// invalid messages are removed, they would fail again anyway
+		log.WithFields(log.Fields{
+			"error":      err,
+			"message_id": aws.StringValue(msg.MessageId),
+		}).Error("trigger.ecr: failed to parse message")
+	default:
+		err = t.providers.Submit(*event)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"image": event.Repository.Name,
+				"tag":   event.Repository.Tag,
+			}).Error("trigger.ecr: failed to submit event")
+			return
+		}
+		ecrEventsCounter.With(prometheus.Labels{"image": event.Repository.Name}).Inc()
+	}
+
+	err = t.queue.DeleteMessage(ctx, t.queueURL, aws.StringValue(msg.ReceiptHandle))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"message_id": aws.StringValue(msg.MessageId),
+		}).Error("trigger.ecr: failed to delete message")
+	}
+}
+
+// Event - EventBridge event
+//
+//	{
+//	  "version": "0",
+//	  "detail-type": "ECR Image Action",
+//	  "source": "aws.ecr",
+//	  "account": "123456789012",
+//	  "region": "us-west-2",
+//	  "detail": {
+//	    "result": "SUCCESS",
+//	    "repository-name": "my-repo",
+//	    "image-digest": "sha256:7f5b2640fe6fb4f46592dfd3410c4a79dac4f89e4782432e0378abcd1234",
+//	    "action-type": "PUSH",
+//	    "image-tag": "latest"
+//	  }
+//	}
+type Event struct {
+	DetailType string      `json:"detail-type"`
+	Source     string      `json:"source"`
+	Account    string      `json:"account"`
+	Region     string      `json:"region"`
+	Detail     EventDetail `json:"detail"`
+}
+
+// EventDetail - ECR image action details
+type EventDetail struct {
+	Result         string `json:"result"`
+	RepositoryName string `json:"repository-name"`
+	ImageDigest    string `json:"image-digest"`
+	ActionType     string `json:"action-type"`
+	ImageTag       string `json:"image-tag"`
+}
+
+// snsEnvelope - message delivered through SNS subscription
+type snsEnvelope struct {
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+}
+
+// ParseMessage - converts SQS message body to keel event
+func ParseMessage(body []byte) (*types.Event, error) {
+	var envelope snsEnvelope
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope.Type == "Notification" && envelope.Message != "" {
+		body = []byte(envelope.Message)
+	}
+
+	var ev Event
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %s", err)
+	}
+
+	if ev.Source != "aws.ecr" || ev.DetailType != "ECR Image Action" {
+		return nil, errIgnored
+	}
+
+	if ev.Detail.ActionType != "PUSH" || ev.Detail.Result != "SUCCESS" || ev.Detail.ImageTag == "" {
+		return nil, errIgnored
+	}
+
+	if ev.Account == "" || ev.Region == "" || ev.Detail.RepositoryName == "" {
+		return nil, fmt.Errorf("event is missing account, region or repository name")
+	}
+
+	return &types.Event{
+		Repository: types.Repository{
+			Name:   fmt.Sprintf("%s.dkr.ecr.%s.amazonaws.com/%s", ev.Account, ev.Region, ev.Detail.RepositoryName),
+			Tag:    ev.Detail.ImageTag,
+			Digest: ev.Detail.ImageDigest,
+		},
+		CreatedAt:   time.Now(),
+		TriggerName: TriggerName,
+	}, nil
+}
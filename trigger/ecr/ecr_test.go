@@ -0,0 +1,148 @@
+package ecr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+
+	"github.com/keel-hq/keel/types"
+)
+
+var fakePushEvent = `{
+  "version": "0",
+  "id": "13cde686-328b-6117-af20-0e5566167482",
+  "detail-type": "ECR Image Action",
+  "source": "aws.ecr",
+  "account": "123456789012",
+  "time": "2019-11-16T01:54:34Z",
+  "region": "us-west-2",
+  "resources": [],
+  "detail": {
+    "result": "SUCCESS",
+    "repository-name": "my-repo",
+    "image-digest": "sha256:7f5b2640fe6fb4f46592dfd3410c4a79dac4f89e4782432e0378abcd1234",
+    "action-type": "PUSH",
+    "image-tag": "1.2.3"
+  }
+}`
+
+type fakeProviders struct {
+	submitted []types.Event
+	err       error
+}
+
+func (p *fakeProviders) Submit(event types.Event) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.submitted = append(p.submitted, event)
+	return nil
+}
+
+func (p *fakeProviders) TrackedImages() ([]*types.TrackedImage, error) { return nil, nil }
+func (p *fakeProviders) List() []string                                { return nil }
+func (p *fakeProviders) Stop()                                         {}
+
+type fakeQueue struct {
+	deleted []string
+}
+
+func (q *fakeQueue) ReceiveMessages(ctx context.Context, queueURL string, max, wait int64) ([]*Message, error) {
+	return nil, nil
+}
+
+func (q *fakeQueue) DeleteMessage(ctx context.Context, queueURL, receiptHandle string) error {
+	q.deleted = append(q.deleted, receiptHandle)
+	return nil
+}
+
+func TestParseMessage(t *testing.T) {
+	event, err := ParseMessage([]byte(fakePushEvent))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if event.Repository.Name != "123456789012.dkr.ecr.us-west-2.amazonaws.com/my-repo" {
+		t.Errorf("unexpected image: %s", event.Repository.Name)
+	}
+	if event.Repository.Tag != "1.2.3" {
+		t.Errorf("unexpected tag: %s", event.Repository.Tag)
+	}
+	if event.Repository.Digest != "sha256:7f5b2640fe6fb4f46592dfd3410c4a79dac4f89e4782432e0378abcd1234" {
+		t.Errorf("unexpected digest: %s", event.Repository.Digest)
+	}
+	if event.TriggerName != TriggerName {
+		t.Errorf("unexpected trigger name: %s", event.TriggerName)
+	}
+}
+
+func TestParseMessageSNS(t *testing.T) {
+	body, _ := json.Marshal(snsEnvelope{
+		Type:    "Notification",
+		Message: fakePushEvent,
+	})
+
+	event, err := ParseMessage(body)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if event.Repository.Tag != "1.2.3" {
+		t.Errorf("unexpected tag: %s", event.Repository.Tag)
+	}
+}
+
+func TestParseMessageIgnored(t *testing.T) {
+	ignored := []string{
+		`{"source": "aws.ec2", "detail-type": "EC2 Instance State-change Notification"}`,
+		`{"source": "aws.ecr", "detail-type": "ECR Image Action", "account": "1", "region": "us-west-2", "detail": {"result": "SUCCESS", "repository-name": "r", "action-type": "DELETE", "image-tag": "1.0.0"}}`,
+		`{"source": "aws.ecr", "detail-type": "ECR Image Action", "account": "1", "region": "us-west-2", "detail": {"result": "FAILURE", "repository-name": "r", "action-type": "PUSH", "image-tag": "1.0.0"}}`,
+		`{"source": "aws.ecr", "detail-type": "ECR Image Action", "account": "1", "region": "us-west-2", "detail": {"result": "SUCCESS", "repository-name": "r", "action-type": "PUSH"}}`,
+	}
+
+	for _, body := range ignored {
+		_, err := ParseMessage([]byte(body))
+		if err != errIgnored {
+			t.Errorf("expected message to be ignored, got: %v, message: %s", err, body)
+		}
+	}
+}
+
+func TestProcessMessage(t *testing.T) {
+	fp := &fakeProviders{}
+	fq := &fakeQueue{}
+	tr := &Trigger{queue: fq, queueURL: "https://sqs.us-west-2.amazonaws.com/123456789012/keel", providers: fp}
+
+	tr.processMessage(context.Background(), &Message{
+		Body:          aws.String(fakePushEvent),
+		ReceiptHandle: aws.String("receipt-1"),
+	})
+
+	if len(fp.submitted) != 1 {
+		t.Fatalf("expected 1 event, got: %d", len(fp.submitted))
+	}
+	if len(fq.deleted) != 1 || fq.deleted[0] != "receipt-1" {
+		t.Errorf("expected message to be deleted, got: %v", fq.deleted)
+	}
+
+	// failed submit, message is kept for retry
+	fp.err = errors.New("boom")
+	tr.processMessage(context.Background(), &Message{
+		Body:          aws.String(fakePushEvent),
+		ReceiptHandle: aws.String("receipt-2"),
+	})
+	if len(fq.deleted) != 1 {
+		t.Errorf("message shouldn't be deleted when submit fails, deleted: %v", fq.deleted)
+	}
+}
+
+func TestQueueRegion(t *testing.T) {
+	if r := queueRegion("https://sqs.eu-west-1.amazonaws.com/123456789012/keel"); r != "eu-west-1" {
+		t.Errorf("unexpected region: %s", r)
+	}
+	if r := queueRegion("http://localhost:9324/queue/keel"); r != "" {
+		t.Errorf("unexpected region: %s", r)
+	}
+}
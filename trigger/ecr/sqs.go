@@ -0,0 +1,120 @@
+package ecr
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/query"
+)
+
+// Queue - SQS operations used by the trigger
+type Queue interface {
+	ReceiveMessages(ctx context.Context, queueURL string, max, wait int64) ([]*Message, error)
+	DeleteMessage(ctx context.Context, queueURL, receiptHandle string) error
+}
+
+// Message - SQS message
+type Message struct {
+	_ struct{} `type:"structure"`
+
+	Body          *string `type:"string"`
+	MessageId     *string `type:"string"`
+	ReceiptHandle *string `type:"string"`
+}
+
+type receiveMessageInput struct {
+	_ struct{} `type:"structure"`
+
+	MaxNumberOfMessages *int64  `type:"integer"`
+	QueueUrl            *string `type:"string" required:"true"`
+	WaitTimeSeconds     *int64  `type:"integer"`
+}
+
+type receiveMessageOutput struct {
+	_ struct{} `type:"structure"`
+
+	Messages []*Message `locationNameList:"Message" type:"list" flattened:"true"`
+}
+
+type deleteMessageInput struct {
+	_ struct{} `type:"structure"`
+
+	QueueUrl      *string `type:"string" required:"true"`
+	ReceiptHandle *string `type:"string" required:"true"`
+}
+
+type deleteMessageOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+// sqsClient - minimal SQS query API client, only long polling
+// and deleting messages is required
+type sqsClient struct {
+	*client.Client
+}
+
+// newSQSClient - creates SQS client from AWS session
+func newSQSClient(p client.ConfigProvider, cfgs ...*aws.Config) *sqsClient {
+	c := p.ClientConfig("sqs", cfgs...)
+	svc := &sqsClient{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   "sqs",
+				ServiceID:     "SQS",
+				SigningName:   c.SigningName,
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    "2012-11-05",
+			},
+			c.Handlers,
+		),
+	}
+
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(query.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(query.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(query.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(query.UnmarshalErrorHandler)
+
+	return svc
+}
+
+func (c *sqsClient) ReceiveMessages(ctx context.Context, queueURL string, max, wait int64) ([]*Message, error) {
+	op := &request.Operation{
+		Name:       "ReceiveMessage",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+	output := &receiveMessageOutput{}
+	req := c.NewRequest(op, &receiveMessageInput{
+		MaxNumberOfMessages: aws.Int64(max),
+		QueueUrl:            aws.String(queueURL),
+		WaitTimeSeconds:     aws.Int64(wait),
+	}, output)
+	req.SetContext(ctx)
+
+	if err := req.Send(); err != nil {
+		return nil, err
+	}
+	return output.Messages, nil
+}
+
+func (c *sqsClient) DeleteMessage(ctx context.Context, queueURL, receiptHandle string) error {
+	op := &request.Operation{
+		Name:       "DeleteMessage",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+	req := c.NewRequest(op, &deleteMessageInput{
+		QueueUrl:      aws.String(queueURL),
+		ReceiptHandle: aws.String(receiptHandle),
+	}, &deleteMessageOutput{})
+	req.SetContext(ctx)
+
+	return req.Send()
+}
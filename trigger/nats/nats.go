@@ -0,0 +1,363 @@
+// Package nats - trigger that subscribes to a NATS subject for image push events. When
+// stream is configured keel creates (or reuses) a durable JetStream push consumer so events
+// published while keel is unavailable are not lost. Expected message payload:
+//
+//	{
+//	  "name": "registry.example.com/team/app",
+//	  "tag": "1.2.3",
+//	  "digest": "sha256:7f5b2640fe6fb4f46592dfd3410c4a79dac4f89e4782432e0378abcd1234"
+//	}
+//
+// name can also contain the tag ("registry.example.com/team/app:1.2.3"), digest is optional.
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/provider"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TriggerName - name of the events that are submitted by this trigger
+const TriggerName = "nats"
+
+// DefaultDurable - JetStream consumer name used when durable is not set
+const DefaultDurable = "keel"
+
+const (
+	maxBackoff     = 2 * time.Minute
+	requestTimeout = 10 * time.Second
+	pendingEvents  = 64
+)
+
+var natsEventsCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "nats_events_total",
+		Help: "How many image push events were received from NATS, partitioned by image.",
+	},
+	[]string{"image"},
+)
+
+func init() {
+	prometheus.MustRegister(natsEventsCounter)
+}
+
+// JetStream acknowledgements, published to the message reply subject
+var (
+	ackAck  = []byte("+ACK")
+	ackNak  = []byte("-NAK")
+	ackTerm = []byte("+TERM")
+)
+
+// Opts - trigger options
+type Opts struct {
+	// URL - nats://[user:pass@]host:4222, multiple servers can be separated with commas
+	URL     string
+	Subject string
+	// Queue - queue group, load balances events between keel replicas
+	Queue string
+	// Stream - JetStream stream that captures the subject, enables JetStream consumer
+	Stream string
+	// Durable - JetStream consumer name, defaults to "keel"
+	Durable string
+
+	Providers provider.Providers
+}
+
+// Trigger - NATS subscriber
+type Trigger struct {
+	servers   []string
+	subject   string
+	queue     string
+	stream    string
+	durable   string
+	providers provider.Providers
+}
+
+// New - creates new trigger
+func New(opts *Opts) (*Trigger, error) {
+	if opts.Subject == "" {
+		return nil, fmt.Errorf("subject cannot be empty")
+	}
+
+	var servers []string
+	for _, s := range strings.Split(opts.URL, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			servers = append(servers, s)
+		}
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("NATS URL cannot be empty")
+	}
+
+	durable := opts.Durable
+	if durable == "" {
+		durable = DefaultDurable
+	}
+
+	return &Trigger{
+		servers:   servers,
+		subject:   opts.Subject,
+		queue:     opts.Queue,
+		stream:    opts.Stream,
+		durable:   durable,
+		providers: opts.Providers,
+	}, nil
+}
+
+// Start - subscribes to the subject and reconnects when connection is lost,
+// blocks until context is cancelled
+func (t *Trigger) Start(ctx context.Context) {
+	log.WithFields(log.Fields{
+		"subject": t.subject,
+		"queue":   t.queue,
+		"stream":  t.stream,
+	}).Info("trigger.nats: starting subscriber")
+
+	var backoff time.Duration
+	for {
+		err := t.consume(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		backoff = timeutil.ExpBackoff(backoff, maxBackoff)
+		log.WithFields(log.Fields{
+			"error": err,
+			"retry": backoff,
+		}).Error("trigger.nats: connection failed")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// connect - connects to one of the servers, client reconnects to the others
+// and restores subscriptions when connection is lost. Returned channel is
+// closed once the client gives up.
+func (t *Trigger) connect() (*nats.Conn, <-chan struct{}, error) {
+	closed := make(chan struct{})
+	nc, err := nats.Connect(strings.Join(t.servers, ","),
+		nats.Name("keel"),
+		nats.Timeout(requestTimeout),
+		nats.DisconnectErrHandler(func(c *nats.Conn, err error) {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Warn("trigger.nats: disconnected")
+		}),
+		nats.ReconnectHandler(func(c *nats.Conn) {
+			log.WithFields(log.Fields{
+				"server": redact(c.ConnectedUrl()),
+			}).Info("trigger.nats: reconnected")
+		}),
+		nats.ClosedHandler(func(c *nats.Conn) {
+			close(closed)
+		}),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nc, closed, nil
+}
+
+// consume - connects and processes messages until the connection is closed
+// or context is cancelled
+func (t *Trigger) consume(ctx context.Context) error {
+	nc, closed, err := t.connect()
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	subject := t.subject
+	if t.stream != "" {
+		subject, err = t.ensureConsumer(nc)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = nc.QueueSubscribe(subject, t.queue, func(m *nats.Msg) {
+		t.processMessage(nc, m)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"server":  redact(nc.ConnectedUrl()),
+		"subject": subject,
+	}).Info("trigger.nats: subscribed")
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-closed:
+		if err := nc.LastError(); err != nil {
+			return err
+		}
+		return nats.ErrConnectionClosed
+	}
+}
+
+type consumerConfig struct {
+	DurableName    string `json:"durable_name"`
+	DeliverSubject string `json:"deliver_subject"`
+	DeliverGroup   string `json:"deliver_group,omitempty"`
+	DeliverPolicy  string `json:"deliver_policy"`
+	AckPolicy      string `json:"ack_policy"`
+	AckWait        int64  `json:"ack_wait,omitempty"`
+	FilterSubject  string `json:"filter_subject,omitempty"`
+}
+
+type createConsumerRequest struct {
+	Stream string         `json:"stream_name"`
+	Config consumerConfig `json:"config"`
+}
+
+type apiResponse struct {
+	Error *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error,omitempty"`
+}
+
+// deliverSubject - push consumer delivery subject, deterministic so the
+// consumer config stays the same between restarts
+func (t *Trigger) deliverSubject() string {
+	return "keel.deliver." + t.stream + "." + t.durable
+}
+
+// ensureConsumer - creates durable push consumer, returns subject that the
+// consumer delivers messages to
+func (t *Trigger) ensureConsumer(nc *nats.Conn) (string, error) {
+	req, _ := json.Marshal(&createConsumerRequest{
+		Stream: t.stream,
+		Config: consumerConfig{
+			DurableName:    t.durable,
+			DeliverSubject: t.deliverSubject(),
+			DeliverGroup:   t.queue,
+			DeliverPolicy:  "new",
+			AckPolicy:      "explicit",
+			AckWait:        int64(time.Minute),
+			FilterSubject:  t.subject,
+		},
+	})
+
+	resp, err := nc.Request(fmt.Sprintf("$JS.API.CONSUMER.DURABLE.CREATE.%s.%s", t.stream, t.durable), req, requestTimeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to create JetStream consumer: %s", err)
+	}
+
+	var r apiResponse
+	if err := json.Unmarshal(resp.Data, &r); err != nil {
+		return "", fmt.Errorf("failed to decode JetStream response: %s", err)
+	}
+	if r.Error != nil {
+		return "", fmt.Errorf("failed to create JetStream consumer: %s (%d)", r.Error.Description, r.Error.Code)
+	}
+
+	return t.deliverSubject(), nil
+}
+
+// processMessage - submits event, JetStream messages are acknowledged after
+// successful submit, failed ones are redelivered
+func (t *Trigger) processMessage(c publisher, m *nats.Msg) {
+	event, err := ParseMessage(m.Data)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"subject": m.Subject,
+		}).Error("trigger.nats: failed to parse message")
+		// invalid messages would fail again anyway
+		t.ack(c, m, ackTerm)
+		return
+	}
+
+	err = t.providers.Submit(*event)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"image": event.Repository.Name,
+			"tag":   event.Repository.Tag,
+		}).Error("trigger.nats: failed to submit event")
+		t.ack(c, m, ackNak)
+		return
+	}
+
+	natsEventsCounter.With(prometheus.Labels{"image": event.Repository.Name}).Inc()
+	t.ack(c, m, ackAck)
+}
+
+type publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+func (t *Trigger) ack(c publisher, m *nats.Msg, ack []byte) {
+	if t.stream == "" || m.Reply == "" {
+		return
+	}
+	err := c.Publish(m.Reply, ack)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"subject": m.Subject,
+		}).Error("trigger.nats: failed to acknowledge message")
+	}
+}
+
+// ParseMessage - converts message payload to keel event
+func ParseMessage(data []byte) (*types.Event, error) {
+	var repo types.Repository
+	if err := json.Unmarshal(data, &repo); err != nil {
+		return nil, fmt.Errorf("failed to decode message: %s", err)
+	}
+
+	if repo.Name == "" {
+		return nil, errors.New("repository name cannot be empty")
+	}
+
+	if repo.Tag == "" {
+		ref, err := image.Parse(repo.Name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image name: %s", err)
+		}
+		if !strings.HasSuffix(repo.Name, ":"+ref.Tag()) {
+			return nil, errors.New("repository tag cannot be empty")
+		}
+		repo.Name = strings.TrimSuffix(repo.Name, ":"+ref.Tag())
+		repo.Tag = ref.Tag()
+	}
+
+	return &types.Event{
+		Repository:  repo,
+		CreatedAt:   time.Now(),
+		TriggerName: TriggerName,
+	}, nil
+}
+
+// redact - removes credentials from the server URL before logging it
+func redact(server string) string {
+	if idx := strings.LastIndex(server, "@"); idx >= 0 {
+		if scheme := strings.Index(server, "://"); scheme >= 0 && scheme < idx {
+			return server[:scheme+3] + "***" + server[idx:]
+		}
+		return "***" + server[idx:]
+	}
+	return server
+}
@@ -0,0 +1,94 @@
+package poll
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/util/timeutil"
+
+	"github.com/rusenask/cron"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// minErrorBackoff - backoff after the first failed registry check
+	minErrorBackoff = 30 * time.Second
+	// maxErrorBackoff - upper bound of the backoff, schedules with longer
+	// intervals are not affected
+	maxErrorBackoff = 30 * time.Minute
+)
+
+// errorBackoff - tracks consecutive registry errors of a watch job, while
+// backing off scheduled checks are skipped so a failing registry isn't hammered
+type errorBackoff struct {
+	mu       sync.Mutex
+	failures int
+	until    time.Time
+	failedAt time.Time
+}
+
+// skip - whether check should be skipped
+func (b *errorBackoff) skip() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return timeutil.Now().Before(b.until)
+}
+
+// failed - records failed check, returns current backoff
+func (b *errorBackoff) failed() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	backoff := minErrorBackoff
+	for i := 0; i < b.failures && backoff < maxErrorBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxErrorBackoff {
+		backoff = maxErrorBackoff
+	}
+
+	b.failures++
+	b.failedAt = timeutil.Now()
+	b.until = b.failedAt.Add(backoff)
+	return backoff
+}
+
+// lastFailure - time of the last failed check
+func (b *errorBackoff) lastFailure() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failedAt
+}
+
+// succeeded - resets backoff
+func (b *errorBackoff) succeeded() {
+	b.mu.Lock()
+	b.failures = 0
+	b.until = time.Time{}
+	b.mu.Unlock()
+}
+
+// jitteredJob - delays scheduled runs by a random duration so jobs with the
+// same schedule don't query registries at the same time
+type jitteredJob struct {
+	job    cron.Job
+	jitter time.Duration
+}
+
+func withJitter(job cron.Job, jitter time.Duration) cron.Job {
+	if jitter <= 0 {
+		return job
+	}
+	return &jitteredJob{job: job, jitter: jitter}
+}
+
+func (j *jitteredJob) Run() {
+	delay := time.Duration(rand.Int63n(int64(j.jitter)))
+	log.WithFields(log.Fields{
+		"delay": delay,
+	}).Debug("trigger.poll.jitteredJob: delaying check")
+	time.Sleep(delay)
+	j.job.Run()
+}
@@ -0,0 +1,69 @@
+package poll
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/util/timeutil"
+)
+
+func TestErrorBackoff(t *testing.T) {
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	timeutil.Now = func() time.Time { return now }
+	defer func() { timeutil.Now = time.Now }()
+
+	b := &errorBackoff{}
+	if b.skip() {
+		t.Fatalf("checks shouldn't be skipped before errors")
+	}
+
+	expected := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute}
+	for _, want := range expected {
+		if got := b.failed(); got != want {
+			t.Errorf("unexpected backoff: %s, want: %s", got, want)
+		}
+	}
+
+	if !b.skip() {
+		t.Errorf("checks should be skipped while backing off")
+	}
+
+	now = now.Add(4 * time.Minute)
+	if b.skip() {
+		t.Errorf("checks shouldn't be skipped after backoff")
+	}
+
+	for i := 0; i < 20; i++ {
+		b.failed()
+	}
+	if got := b.failed(); got != maxErrorBackoff {
+		t.Errorf("expected max backoff, got: %s", got)
+	}
+
+	b.succeeded()
+	if b.skip() {
+		t.Errorf("checks shouldn't be skipped after success")
+	}
+	if got := b.failed(); got != minErrorBackoff {
+		t.Errorf("expected backoff to be reset, got: %s", got)
+	}
+}
+
+type countingJob struct {
+	runs int
+}
+
+func (j *countingJob) Run() { j.runs++ }
+
+func TestWithJitter(t *testing.T) {
+	job := &countingJob{}
+	if withJitter(job, 0) != job {
+		t.Errorf("job shouldn't be wrapped without jitter")
+	}
+
+	jittered := withJitter(job, time.Millisecond)
+	jittered.Run()
+	if job.runs != 1 {
+		t.Errorf("expected job to run once, runs: %d", job.runs)
+	}
+}
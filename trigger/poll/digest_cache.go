@@ -0,0 +1,104 @@
+package poll
+
+import (
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// digestCache - watched digests persisted in the keel database. Jobs added
+// after a restart start from the stored digests instead of querying the
+// registry, digests of changes made while keel was down are still compared
+// against them on the first scheduled check.
+type digestCache struct {
+	store store.Store
+	ttl   time.Duration
+
+	mu sync.Mutex
+	// warm - digests loaded on startup, each is used by the first job that
+	// watches the image
+	warm map[string]*types.PollDigest
+}
+
+func newDigestCache(s store.Store, ttl time.Duration) (*digestCache, error) {
+	err := s.DeletePollDigests(time.Now().Add(-ttl))
+	if err != nil {
+		return nil, err
+	}
+
+	digests, err := s.ListPollDigests()
+	if err != nil {
+		return nil, err
+	}
+
+	warm := make(map[string]*types.PollDigest, len(digests))
+	for _, d := range digests {
+		warm[d.ID] = d
+	}
+
+	log.WithFields(log.Fields{
+		"digests": len(warm),
+		"ttl":     ttl,
+	}).Info("trigger.poll.digestCache: loaded stored digests")
+
+	return &digestCache{
+		store: s,
+		ttl:   ttl,
+		warm:  warm,
+	}, nil
+}
+
+// get - stored digest of the watch job, digests of other platforms or older
+// than the TTL aren't used
+func (c *digestCache) get(key, platform string) (*types.PollDigest, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	d, ok := c.warm[key]
+	if !ok {
+		return nil, false
+	}
+	delete(c.warm, key)
+
+	if d.Platform != platform || time.Since(d.UpdatedAt) > c.ttl {
+		return nil, false
+	}
+	return d, true
+}
+
+// save - stores digests of the watch job, unchanged digests are only
+// refreshed once half of the TTL passed
+func (c *digestCache) save(details *watchDetails) {
+	if c == nil {
+		return
+	}
+
+	saved := details.digest + details.platformDigest
+	if details.savedDigest == saved && time.Since(details.savedAt) < c.ttl/2 {
+		return
+	}
+
+	err := c.store.SavePollDigest(&types.PollDigest{
+		ID:             details.key,
+		Digest:         details.digest,
+		Platform:       details.platform,
+		PlatformDigest: details.platformDigest,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"image": details.trackedImage.Image.String(),
+		}).Warn("trigger.poll.digestCache: failed to store digest")
+		return
+	}
+	details.savedDigest = saved
+	details.savedAt = time.Now()
+}
@@ -0,0 +1,87 @@
+package poll
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/pkg/store/sql"
+	"github.com/keel-hq/keel/registry"
+)
+
+func newTestingStore(t *testing.T) (*sql.SQLStore, func()) {
+	dir, err := ioutil.TempDir("", "polldigeststest")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	store, err := sql.New(sql.Opts{DatabaseType: "sqlite3", URI: filepath.Join(dir, "gorm.db")})
+	if err != nil {
+		t.Fatalf("failed to create store: %s", err)
+	}
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestDigestCacheWarmStart(t *testing.T) {
+	store, teardown := newTestingStore(t)
+	defer teardown()
+
+	frc := &fakeRegistryClient{digestToReturn: "sha256:0x1"}
+	watcher := NewRepositoryWatcher(nil, frc)
+	if err := watcher.SetDigestCache(store, time.Hour); err != nil {
+		t.Fatalf("failed to set digest cache: %s", err)
+	}
+	if err := watcher.Watch(mustParse("gcr.io/v2-namespace/hello-world:alpha", "@every 10m")); err != nil {
+		t.Fatalf("failed to watch: %s", err)
+	}
+
+	digests, err := store.ListPollDigests()
+	if err != nil || len(digests) != 1 {
+		t.Fatalf("expected digest to be stored, got: %v, %v", digests, err)
+	}
+	if digests[0].ID != "gcr.io/v2-namespace/hello-world:alpha" || digests[0].Digest != "sha256:0x1" {
+		t.Errorf("unexpected stored digest: %+v", digests[0])
+	}
+
+	// restarted keel takes the digest from the store
+	restarted := &fakeRegistryClient{digestToReturn: "sha256:0x2"}
+	watcher = NewRepositoryWatcher(nil, restarted)
+	if err := watcher.SetDigestCache(store, time.Hour); err != nil {
+		t.Fatalf("failed to set digest cache: %s", err)
+	}
+	if err := watcher.Watch(mustParse("gcr.io/v2-namespace/hello-world:alpha", "@every 10m")); err != nil {
+		t.Fatalf("failed to watch: %s", err)
+	}
+
+	if restarted.opts != (registry.Opts{}) {
+		t.Errorf("expected registry not to be queried, got: %+v", restarted.opts)
+	}
+	details := watcher.watched["gcr.io/v2-namespace/hello-world:alpha"]
+	if details == nil || details.digest != "sha256:0x1" {
+		t.Errorf("expected stored digest to be used, got: %+v", details)
+	}
+}
+
+func TestDigestCacheExpired(t *testing.T) {
+	store, teardown := newTestingStore(t)
+	defer teardown()
+
+	frc := &fakeRegistryClient{digestToReturn: "sha256:0x1"}
+	watcher := NewRepositoryWatcher(nil, frc)
+	watcher.SetDigestCache(store, time.Hour)
+	watcher.Watch(mustParse("gcr.io/v2-namespace/hello-world:alpha", "@every 10m"))
+
+	// digests older than the TTL are removed on startup
+	watcher = NewRepositoryWatcher(nil, frc)
+	if err := watcher.SetDigestCache(store, time.Nanosecond); err != nil {
+		t.Fatalf("failed to set digest cache: %s", err)
+	}
+	digests, err := store.ListPollDigests()
+	if err != nil || len(digests) != 0 {
+		t.Errorf("expected expired digests to be removed, got: %v, %v", digests, err)
+	}
+}
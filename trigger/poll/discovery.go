@@ -0,0 +1,235 @@
+package poll
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultDiscoveryInterval - how often registry catalogs are checked for new repositories
+const DefaultDiscoveryInterval = 5 * time.Minute
+
+var discoveredRepositories = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "poll_discovered_repositories",
+		Help: "How many repositories are tracked by registry discovery, partitioned by pattern.",
+	},
+	[]string{"pattern"},
+)
+
+func init() {
+	prometheus.MustRegister(discoveredRepositories)
+}
+
+// CatalogClient - registry client that can list repositories
+type CatalogClient interface {
+	Catalog(opts registry.Opts) ([]string, error)
+}
+
+// discoveryTarget - registry namespace that is watched, ie: registry.example.com/team/*
+type discoveryTarget struct {
+	pattern  string
+	registry string
+	glob     string
+	known    map[string]bool
+	seeded   bool
+}
+
+// RegistryDiscovery - watches registry namespaces (via the catalog API) and
+// tracks repositories that match glob patterns, notification is sent every time
+// a new matching repository shows up
+type RegistryDiscovery struct {
+	client   CatalogClient
+	sender   notification.Sender
+	targets  []*discoveryTarget
+	interval time.Duration
+
+	mu sync.RWMutex
+}
+
+// NewRegistryDiscovery - creates registry discovery, patterns are registry
+// hosts followed by a repository glob, ie: registry.example.com/team/*
+func NewRegistryDiscovery(client CatalogClient, sender notification.Sender, patterns []string, interval time.Duration) (*RegistryDiscovery, error) {
+	if interval <= 0 {
+		interval = DefaultDiscoveryInterval
+	}
+
+	d := &RegistryDiscovery{
+		client:   client,
+		sender:   sender,
+		interval: interval,
+	}
+
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		target, err := parseDiscoveryPattern(p)
+		if err != nil {
+			return nil, err
+		}
+		d.targets = append(d.targets, target)
+	}
+
+	if len(d.targets) == 0 {
+		return nil, fmt.Errorf("no discovery patterns supplied")
+	}
+
+	return d, nil
+}
+
+func parseDiscoveryPattern(pattern string) (*discoveryTarget, error) {
+	parts := strings.SplitN(pattern, "/", 2)
+	if len(parts) != 2 || parts[1] == "" || (!strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost") {
+		return nil, fmt.Errorf("invalid discovery pattern '%s', expected <registry>/<repository glob>", pattern)
+	}
+	if _, err := path.Match(parts[1], ""); err != nil {
+		return nil, fmt.Errorf("invalid discovery pattern '%s': %s", pattern, err)
+	}
+
+	return &discoveryTarget{
+		pattern:  pattern,
+		registry: parts[0],
+		glob:     parts[1],
+		known:    make(map[string]bool),
+	}, nil
+}
+
+// Start - starts checking registry catalogs, blocks until ctx is cancelled
+func (d *RegistryDiscovery) Start(ctx context.Context) {
+	log.WithFields(log.Fields{
+		"targets":  len(d.targets),
+		"interval": d.interval,
+	}).Info("trigger.poll.RegistryDiscovery: registry discovery configured")
+
+	d.discover()
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.discover()
+		}
+	}
+}
+
+// Repositories - currently tracked repositories, ie: registry.example.com/team/app
+func (d *RegistryDiscovery) Repositories() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var repositories []string
+	for _, t := range d.targets {
+		for repo := range t.known {
+			repositories = append(repositories, t.registry+"/"+repo)
+		}
+	}
+	return repositories
+}
+
+func (d *RegistryDiscovery) discover() {
+	for _, t := range d.targets {
+		err := d.discoverTarget(t)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"pattern": t.pattern,
+			}).Error("trigger.poll.RegistryDiscovery: failed to list registry repositories")
+		}
+	}
+}
+
+func (d *RegistryDiscovery) discoverTarget(t *discoveryTarget) error {
+	opts := registry.Opts{
+		Registry: "https://" + t.registry,
+	}
+	// credentials are looked up by registry only, there's no workload to take secrets from
+	if ref, err := image.Parse(t.registry + "/catalog"); err == nil {
+		creds := credentialshelper.GetCredentials(&types.TrackedImage{Image: ref})
+		opts.Username = creds.Username
+		opts.Password = creds.Password
+	}
+
+	repositories, err := d.client.Catalog(opts)
+	if err != nil {
+		return err
+	}
+
+	var discovered []string
+
+	d.mu.Lock()
+	current := make(map[string]bool)
+	for _, repo := range repositories {
+		if ok, _ := path.Match(t.glob, repo); !ok {
+			continue
+		}
+		current[repo] = true
+		if !t.known[repo] && t.seeded {
+			discovered = append(discovered, repo)
+		}
+	}
+	// repositories that were deleted are dropped so they are reported again if recreated
+	t.known = current
+	t.seeded = true
+	d.mu.Unlock()
+
+	discoveredRepositories.With(prometheus.Labels{"pattern": t.pattern}).Set(float64(len(current)))
+
+	for _, repo := range discovered {
+		d.notify(t, repo)
+	}
+
+	return nil
+}
+
+func (d *RegistryDiscovery) notify(t *discoveryTarget, repo string) {
+	name := t.registry + "/" + repo
+
+	log.WithFields(log.Fields{
+		"repository": name,
+		"pattern":    t.pattern,
+	}).Info("trigger.poll.RegistryDiscovery: new repository discovered")
+
+	if d.sender == nil {
+		return
+	}
+
+	err := d.sender.Send(types.EventNotification{
+		Name:         "repository discovered",
+		Message:      fmt.Sprintf("New repository %s discovered (pattern: %s)", name, t.pattern),
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationRepositoryDiscovered,
+		Level:        types.LevelInfo,
+		ResourceKind: "repository",
+		Identifier:   name,
+		Metadata: map[string]string{
+			"registry":   t.registry,
+			"repository": repo,
+			"pattern":    t.pattern,
+		},
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"repository": name,
+		}).Error("trigger.poll.RegistryDiscovery: failed to send notification")
+	}
+}
@@ -0,0 +1,99 @@
+package poll
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+)
+
+type fakeCatalogClient struct {
+	opts         registry.Opts
+	repositories []string
+}
+
+func (c *fakeCatalogClient) Catalog(opts registry.Opts) ([]string, error) {
+	c.opts = opts
+	return c.repositories, nil
+}
+
+type fakeSender struct {
+	sent []types.EventNotification
+}
+
+func (s *fakeSender) Configure(*notification.Config) (bool, error) {
+	return true, nil
+}
+
+func (s *fakeSender) Send(event types.EventNotification) error {
+	s.sent = append(s.sent, event)
+	return nil
+}
+
+func TestRegistryDiscovery(t *testing.T) {
+	client := &fakeCatalogClient{
+		repositories: []string{"team/api", "team/web", "other/api", "team/nested/worker"},
+	}
+	sender := &fakeSender{}
+
+	d, err := NewRegistryDiscovery(client, sender, []string{"registry.example.com/team/*"}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// existing repositories are tracked without notifications
+	d.discover()
+
+	if client.opts.Registry != "https://registry.example.com" {
+		t.Errorf("unexpected registry: %s", client.opts.Registry)
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("expected no notifications after first scan, got: %d", len(sender.sent))
+	}
+
+	repositories := d.Repositories()
+	sort.Strings(repositories)
+	if len(repositories) != 2 || repositories[0] != "registry.example.com/team/api" || repositories[1] != "registry.example.com/team/web" {
+		t.Errorf("unexpected repositories: %v", repositories)
+	}
+
+	client.repositories = append(client.repositories, "team/billing", "other/billing")
+	d.discover()
+
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected 1 notification, got: %d", len(sender.sent))
+	}
+	if sender.sent[0].Type != types.NotificationRepositoryDiscovered {
+		t.Errorf("unexpected notification type: %s", sender.sent[0].Type)
+	}
+	if sender.sent[0].Identifier != "registry.example.com/team/billing" {
+		t.Errorf("unexpected identifier: %s", sender.sent[0].Identifier)
+	}
+
+	// nothing new
+	d.discover()
+	if len(sender.sent) != 1 {
+		t.Errorf("expected 1 notification, got: %d", len(sender.sent))
+	}
+}
+
+func TestParseDiscoveryPattern(t *testing.T) {
+	for pattern, valid := range map[string]bool{
+		"registry.example.com/team/*":   true,
+		"localhost:5000/*":              true,
+		"localhost/team/*":              true,
+		"team/*":                        false,
+		"registry.example.com/":         false,
+		"registry.example.com/team/[a-": false,
+	} {
+		_, err := parseDiscoveryPattern(pattern)
+		if valid && err != nil {
+			t.Errorf("%s: unexpected error: %s", pattern, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("%s: expected error", pattern)
+		}
+	}
+}
@@ -4,8 +4,6 @@ import (
 	"context"
 	"os"
 
-	"github.com/keel-hq/keel/approvals"
-	"github.com/keel-hq/keel/cache/memory"
 	"github.com/keel-hq/keel/provider"
 	"github.com/keel-hq/keel/registry"
 	"github.com/keel-hq/keel/types"
@@ -46,8 +44,7 @@ func TestCheckDeployment(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	// returning some sha
@@ -123,8 +120,7 @@ func TestCheckECRDeployment(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 	rc := registry.New()
 
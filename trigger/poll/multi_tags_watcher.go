@@ -36,6 +36,14 @@ func NewWatchRepositoryTagsJob(providers provider.Providers, registryClient regi
 
 // Run - main function to check schedule
 func (j *WatchRepositoryTagsJob) Run() {
+	// rotated image pull secrets end the backoff
+	if j.details.backoff.skip() && !credentialshelper.SecretsChangedSince(j.details.trackedImage, j.details.backoff.lastFailure()) {
+		log.WithFields(log.Fields{
+			"image": j.details.trackedImage.Image.String(),
+		}).Debug("trigger.poll.WatchRepositoryTagsJob: backing off after registry errors, skipping check")
+		return
+	}
+
 	j.details.mu.RLock()
 	defer j.details.mu.RUnlock()
 
@@ -55,13 +63,20 @@ func (j *WatchRepositoryTagsJob) Run() {
 	})
 
 	if err != nil {
+		if registry.IsUnauthorized(err) {
+			credentialshelper.RecordAuthFailure(j.details.trackedImage, err)
+		}
+		backoff := j.details.backoff.failed()
 		log.WithFields(log.Fields{
 			"error":        err,
 			"registry_url": reg,
 			"image":        j.details.trackedImage.Image.String(),
+			"backoff":      backoff,
 		}).Error("trigger.poll.WatchRepositoryTagsJob: failed to get repository")
 		return
 	}
+	j.details.backoff.succeeded()
+	credentialshelper.ClearAuthFailure(j.details.trackedImage)
 
 	registriesScannedCounter.With(prometheus.Labels{"registry": j.details.trackedImage.Image.Registry(), "image": j.details.trackedImage.Image.Repository()}).Inc()
 
@@ -80,6 +95,9 @@ func (j *WatchRepositoryTagsJob) Run() {
 		}).Error("trigger.poll.WatchRepositoryTagsJob: failed to process tags")
 		return
 	}
+
+	// keeping stored digest fresh
+	j.details.cache.save(j.details)
 }
 
 func (j *WatchRepositoryTagsJob) computeEvents(tags []string) ([]types.Event, error) {
@@ -93,11 +111,26 @@ func (j *WatchRepositoryTagsJob) computeEvents(tags []string) ([]types.Event, er
 	// collapse removes all non-semver tags and only takes
 	// the highest versions of each prerelease + the main version that doesn't have
 	// any prereleases
-	tags = collapse(tags)
+	collapsed := collapse(tags)
 
 	for _, trackedImage := range getRelatedTrackedImages(j.details.trackedImage, trackedImages) {
+		// policies that order tags themselves only get the highest tag
+		if op, ok := trackedImage.Policy.(orderedPolicy); ok && op.Ordered() {
+			tag, ok := highestTag(trackedImage, tags)
+			if ok && !exists(tag, events) {
+				events = append(events, types.Event{
+					Repository: types.Repository{
+						Name: j.details.trackedImage.Image.Repository(),
+						Tag:  tag,
+					},
+					TriggerName: types.TriggerTypePoll.String(),
+				})
+			}
+			continue
+		}
+
 		// matches, going through tags
-		for _, tag := range tags {
+		for _, tag := range collapsed {
 			update, err := trackedImage.Policy.ShouldUpdate(trackedImage.Image.Tag(), tag)
 			if err != nil {
 				continue
@@ -120,6 +153,31 @@ func (j *WatchRepositoryTagsJob) computeEvents(tags []string) ([]types.Event, er
 	return events, nil
 }
 
+// orderedPolicy - policies that can order tags that aren't semver, ie: regexp
+// policy with capture groups
+type orderedPolicy interface {
+	Ordered() bool
+}
+
+// highestTag - highest tag the tracked image should be updated to
+func highestTag(trackedImage *types.TrackedImage, tags []string) (string, bool) {
+	highest, found := "", false
+	for _, tag := range tags {
+		update, err := trackedImage.Policy.ShouldUpdate(trackedImage.Image.Tag(), tag)
+		if err != nil || !update {
+			continue
+		}
+		if found {
+			higher, err := trackedImage.Policy.ShouldUpdate(highest, tag)
+			if err != nil || !higher {
+				continue
+			}
+		}
+		highest, found = tag, true
+	}
+	return highest, found
+}
+
 func exists(tag string, events []types.Event) bool {
 	for _, e := range events {
 		if tag == e.Repository.Tag {
@@ -5,8 +5,6 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/keel-hq/keel/approvals"
-	"github.com/keel-hq/keel/cache/memory"
 	"github.com/keel-hq/keel/internal/policy"
 	"github.com/keel-hq/keel/provider"
 	"github.com/keel-hq/keel/types"
@@ -27,8 +25,7 @@ func TestWatchMultipleTagsWithSemver(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	// returning some sha
@@ -64,8 +61,7 @@ func TestWatchAllTagsJobWithSemver(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -114,8 +110,7 @@ func TestWatchAllTagsPrerelease(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -168,8 +163,7 @@ func TestWatchAllTagsMixed(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -227,8 +221,7 @@ func TestWatchAllTagsMixedPolicyAll(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -312,3 +305,40 @@ func Test_collapse(t *testing.T) {
 		})
 	}
 }
+
+func TestWatchAllTagsRegexpPolicy(t *testing.T) {
+
+	reference, _ := image.Parse("foo/bar:release-2018.09.30")
+	plc, _ := policy.NewRegexpPolicy(`regexp:^release-(\d{4})\.(\d{2})\.(\d{2})$`)
+
+	fp := &fakeProvider{
+		images: []*types.TrackedImage{
+			&types.TrackedImage{
+				Image:  reference,
+				Policy: plc,
+			},
+		},
+	}
+	am := approver()
+	providers := provider.New([]provider.Provider{fp}, am)
+
+	frc := &fakeRegistryClient{
+		tagsToReturn: []string{"release-2018.10.02", "release-2018.11.01", "release-2018.09.01", "latest", "1.5.0"},
+	}
+
+	details := &watchDetails{
+		trackedImage: fp.images[0],
+	}
+
+	job := NewWatchRepositoryTagsJob(providers, frc, details)
+
+	job.Run()
+
+	if len(fp.submitted) != 1 {
+		t.Fatalf("expected 1 event, got: %d", len(fp.submitted))
+	}
+
+	if fp.submitted[0].Repository.Tag != "release-2018.11.01" {
+		t.Errorf("expected event repository tag release-2018.11.01, but got: %s", fp.submitted[0].Repository.Tag)
+	}
+}
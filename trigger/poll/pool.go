@@ -0,0 +1,213 @@
+package poll
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rusenask/cron"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var pollQueueDepth = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "poll_trigger_queue_depth",
+		Help: "How many registry checks are waiting for a poll worker, partitioned by registry.",
+	},
+	[]string{"registry"},
+)
+
+var pollCycleDuration = prometheus.NewHistogram(
+	prometheus.HistogramOpts{
+		Name:    "poll_trigger_cycle_duration_seconds",
+		Help:    "How long poll workers were busy until all queued checks were done.",
+		Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800},
+	},
+)
+
+func init() {
+	prometheus.MustRegister(pollQueueDepth)
+	prometheus.MustRegister(pollCycleDuration)
+}
+
+// DefaultPollWorkers - default number of concurrent registry checks
+const DefaultPollWorkers = 10
+
+type poolTask struct {
+	registry string
+	key      string
+	run      func()
+}
+
+// WorkerPool - runs registry checks with a limited number of workers. Registries
+// take turns so that a registry with many images doesn't delay checks of the
+// others, perRegistry limits concurrent checks of a single registry.
+type WorkerPool struct {
+	perRegistry int
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// queues - queued checks by registry, order - registries with queued
+	// checks in the order they take turns
+	queues map[string][]*poolTask
+	order  []string
+	next   int
+	// active - running checks by registry
+	active map[string]int
+	// pending - keys of queued and running checks
+	pending map[string]bool
+
+	busySince time.Time
+	stopped   bool
+}
+
+// NewWorkerPool - starts workers, perRegistry 0 doesn't limit registries
+func NewWorkerPool(workers, perRegistry int) *WorkerPool {
+	if workers <= 0 {
+		workers = DefaultPollWorkers
+	}
+	p := &WorkerPool{
+		perRegistry: perRegistry,
+		queues:      make(map[string][]*poolTask),
+		active:      make(map[string]int),
+		pending:     make(map[string]bool),
+	}
+	p.cond = sync.NewCond(&p.mu)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Submit - queues the check, returns false when a check with the same key is
+// already queued or running (or the pool is stopped)
+func (p *WorkerPool) Submit(registry, key string, run func()) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopped || p.pending[key] {
+		return false
+	}
+	if p.idle() {
+		p.busySince = time.Now()
+	}
+	p.pending[key] = true
+	if len(p.queues[registry]) == 0 {
+		p.order = append(p.order, registry)
+	}
+	p.queues[registry] = append(p.queues[registry], &poolTask{registry: registry, key: key, run: run})
+	pollQueueDepth.WithLabelValues(registry).Set(float64(len(p.queues[registry])))
+	p.cond.Signal()
+	return true
+}
+
+// Stop - stops workers once they finish running checks, queued checks are dropped
+func (p *WorkerPool) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+	for registry := range p.queues {
+		pollQueueDepth.WithLabelValues(registry).Set(0)
+	}
+	p.cond.Broadcast()
+}
+
+func (p *WorkerPool) idle() bool {
+	return len(p.order) == 0 && len(p.pending) == 0
+}
+
+// take - next check of the registry whose turn it is, nil when all registries
+// with queued checks are at their limit
+func (p *WorkerPool) take() *poolTask {
+	for i := 0; i < len(p.order); i++ {
+		idx := (p.next + i) % len(p.order)
+		registry := p.order[idx]
+		if p.perRegistry > 0 && p.active[registry] >= p.perRegistry {
+			continue
+		}
+
+		queue := p.queues[registry]
+		task := queue[0]
+		p.queues[registry] = queue[1:]
+		p.active[registry]++
+		pollQueueDepth.WithLabelValues(registry).Set(float64(len(queue) - 1))
+
+		if len(queue) == 1 {
+			delete(p.queues, registry)
+			p.order = append(p.order[:idx], p.order[idx+1:]...)
+			p.next = idx
+		} else {
+			p.next = idx + 1
+		}
+		if len(p.order) > 0 {
+			p.next = p.next % len(p.order)
+		} else {
+			p.next = 0
+		}
+		return task
+	}
+	return nil
+}
+
+func (p *WorkerPool) work() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		var task *poolTask
+		for !p.stopped {
+			if task = p.take(); task != nil {
+				break
+			}
+			p.cond.Wait()
+		}
+		if task == nil {
+			return
+		}
+
+		p.mu.Unlock()
+		p.run(task)
+		p.mu.Lock()
+
+		p.active[task.registry]--
+		if p.active[task.registry] == 0 {
+			delete(p.active, task.registry)
+		}
+		delete(p.pending, task.key)
+		if p.idle() {
+			pollCycleDuration.Observe(time.Since(p.busySince).Seconds())
+		}
+		// registry limit was freed, other workers can take its checks
+		p.cond.Broadcast()
+	}
+}
+
+func (p *WorkerPool) run(task *poolTask) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.WithFields(log.Fields{
+				"registry": task.registry,
+				"job_name": task.key,
+				"panic":    r,
+			}).Error("trigger.poll.WorkerPool: check panicked")
+		}
+	}()
+	task.run()
+}
+
+// pooledJob - scheduled job that is queued in the pool instead of running right
+// away, runs are skipped while the previous one is still queued or running
+type pooledJob struct {
+	pool     *WorkerPool
+	registry string
+	key      string
+	job      cron.Job
+}
+
+func (j *pooledJob) Run() {
+	if !j.pool.Submit(j.registry, j.key, j.job.Run) {
+		log.WithFields(log.Fields{
+			"job_name": j.key,
+		}).Debug("trigger.poll.pooledJob: previous check is still queued or running, skipping")
+	}
+}
@@ -0,0 +1,143 @@
+package poll
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type concurrency struct {
+	mu      sync.Mutex
+	current map[string]int
+	max     map[string]int
+	total   int
+	maxAll  int
+}
+
+func (c *concurrency) run(registry string, release <-chan struct{}) {
+	c.mu.Lock()
+	c.current[registry]++
+	c.total++
+	if c.current[registry] > c.max[registry] {
+		c.max[registry] = c.current[registry]
+	}
+	if c.total > c.maxAll {
+		c.maxAll = c.total
+	}
+	c.mu.Unlock()
+
+	<-release
+
+	c.mu.Lock()
+	c.current[registry]--
+	c.total--
+	c.mu.Unlock()
+}
+
+func TestWorkerPoolLimits(t *testing.T) {
+	pool := NewWorkerPool(4, 2)
+	defer pool.Stop()
+
+	c := &concurrency{current: map[string]int{}, max: map[string]int{}}
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for _, registry := range []string{"index.docker.io", "quay.io", "gcr.io"} {
+		for i := 0; i < 5; i++ {
+			registry := registry
+			wg.Add(1)
+			submitted := pool.Submit(registry, fmt.Sprintf("%s/%d", registry, i), func() {
+				defer wg.Done()
+				c.run(registry, release)
+			})
+			if !submitted {
+				t.Fatalf("expected check to be submitted")
+			}
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if c.maxAll != 4 {
+		t.Errorf("expected 4 concurrent checks, got %d", c.maxAll)
+	}
+	for registry, max := range c.max {
+		if max > 2 {
+			t.Errorf("expected at most 2 concurrent checks of %s, got %d", registry, max)
+		}
+	}
+}
+
+func TestWorkerPoolFairness(t *testing.T) {
+	pool := NewWorkerPool(1, 0)
+	defer pool.Stop()
+
+	block := make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	pool.Submit("blocker", "blocker", func() {
+		defer wg.Done()
+		<-block
+	})
+
+	// busy registry is queued first but the others shouldn't wait for all its checks
+	submit := func(registry string, count int) {
+		for i := 0; i < count; i++ {
+			wg.Add(1)
+			pool.Submit(registry, fmt.Sprintf("%s/%d", registry, i), func() {
+				defer wg.Done()
+				mu.Lock()
+				order = append(order, registry)
+				mu.Unlock()
+			})
+		}
+	}
+	submit("busy", 4)
+	submit("quay.io", 1)
+	submit("gcr.io", 1)
+
+	close(block)
+	wg.Wait()
+
+	expected := []string{"busy", "quay.io", "gcr.io", "busy", "busy", "busy"}
+	if fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("unexpected order: %v, expected %v", order, expected)
+	}
+}
+
+func TestWorkerPoolDuplicates(t *testing.T) {
+	pool := NewWorkerPool(1, 0)
+
+	block := make(chan struct{})
+	done := make(chan struct{})
+	if !pool.Submit("quay.io", "quay.io/foo/bar", func() {
+		<-block
+		close(done)
+	}) {
+		t.Fatalf("expected check to be submitted")
+	}
+	if pool.Submit("quay.io", "quay.io/foo/bar", func() {}) {
+		t.Errorf("expected running check to be skipped")
+	}
+	close(block)
+	<-done
+
+	// key is released once the check is done
+	time.Sleep(50 * time.Millisecond)
+	ran := make(chan struct{})
+	if !pool.Submit("quay.io", "quay.io/foo/bar", func() { close(ran) }) {
+		t.Errorf("expected check to be submitted again")
+	}
+	<-ran
+
+	pool.Stop()
+	if pool.Submit("quay.io", "quay.io/foo/baz", func() {}) {
+		t.Errorf("stopped pool shouldn't accept checks")
+	}
+}
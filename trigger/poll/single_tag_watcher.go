@@ -28,9 +28,17 @@ func NewWatchTagJob(providers provider.Providers, registryClient registry.Client
 
 // Run - main function to check schedule
 func (j *WatchTagJob) Run() {
+	// rotated image pull secrets end the backoff
+	if j.details.backoff.skip() && !credentialshelper.SecretsChangedSince(j.details.trackedImage, j.details.backoff.lastFailure()) {
+		log.WithFields(log.Fields{
+			"image": j.details.trackedImage.Image.String(),
+		}).Debug("trigger.poll.WatchTagJob: backing off after registry errors, skipping check")
+		return
+	}
+
 	creds := credentialshelper.GetCredentials(j.details.trackedImage)
 	reg := j.details.trackedImage.Image.Scheme() + "://" + j.details.trackedImage.Image.Registry()
-	currentDigest, err := j.registryClient.Digest(registry.Opts{
+	manifest, err := j.registryClient.Manifest(registry.Opts{
 		Registry: reg,
 		Name:     j.details.trackedImage.Image.ShortName(),
 		Tag:      j.details.trackedImage.Image.Tag(),
@@ -41,24 +49,49 @@ func (j *WatchTagJob) Run() {
 	registriesScannedCounter.With(prometheus.Labels{"registry": j.details.trackedImage.Image.Registry(), "image": j.details.trackedImage.Image.Repository()}).Inc()
 
 	if err != nil {
+		if registry.IsUnauthorized(err) {
+			credentialshelper.RecordAuthFailure(j.details.trackedImage, err)
+		}
+		backoff := j.details.backoff.failed()
 		log.WithFields(log.Fields{
-			"error": err,
-			"image": j.details.trackedImage.Image.String(),
+			"error":   err,
+			"image":   j.details.trackedImage.Image.String(),
+			"backoff": backoff,
 		}).Error("trigger.poll.WatchTagJob: failed to check digest")
 		return
 	}
+	j.details.backoff.succeeded()
+	credentialshelper.ClearAuthFailure(j.details.trackedImage)
+
+	currentDigest := manifest.Digest
+	platformDigest := trackedDigest(manifest, j.details.platform)
 
 	log.WithFields(log.Fields{
-		"current_digest": j.details.digest,
-		"new_digest":     currentDigest,
-		"registry_url":   reg,
-		"image":          j.details.trackedImage.Image.String(),
+		"current_digest":  j.details.digest,
+		"new_digest":      currentDigest,
+		"platform":        j.details.platform,
+		"platform_digest": platformDigest,
+		"registry_url":    reg,
+		"image":           j.details.trackedImage.Image.String(),
 	}).Debug("trigger.poll.WatchTagJob: checking digest")
 
+	if j.details.platformDigest == platformDigest && j.details.digest != currentDigest {
+		log.WithFields(log.Fields{
+			"image":      j.details.trackedImage.Image.String(),
+			"new_digest": currentDigest,
+			"platform":   j.details.platform,
+		}).Debug("trigger.poll.WatchTagJob: manifest list changed, platform digest is the same, ignoring")
+		j.details.digest = currentDigest
+		j.details.cache.save(j.details)
+		return
+	}
+
 	// checking whether image digest has changed
-	if j.details.digest != currentDigest {
+	if j.details.platformDigest != platformDigest {
 		// updating digest
 		j.details.digest = currentDigest
+		j.details.platformDigest = platformDigest
+		j.details.cache.save(j.details)
 
 		event := types.Event{
 			Repository: types.Repository{
@@ -82,6 +115,9 @@ func (j *WatchTagJob) Run() {
 				"error":      err,
 			}).Error("trigger.poll.WatchRepositoryTagsJob: error while submitting an event")
 		}
-
+		return
 	}
+
+	// keeping stored digest fresh
+	j.details.cache.save(j.details)
 }
@@ -3,10 +3,13 @@ package poll
 import (
 	"context"
 	"fmt"
+	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/pkg/store"
 	"github.com/keel-hq/keel/provider"
 	"github.com/keel-hq/keel/registry"
 	"github.com/keel-hq/keel/types"
@@ -47,10 +50,25 @@ type Watcher interface {
 
 type watchDetails struct {
 	trackedImage *types.TrackedImage
-	digest       string // image digest
+	digest       string // image digest, manifest list digest for multi-arch tags
 	latest       string // latest tag
 	schedule     string
 
+	// platform - platform of multi-arch tags that is watched, ie: linux/amd64
+	platform string
+	// platformDigest - digest of the platform manifest, equal to digest for single-arch tags
+	platformDigest string
+
+	backoff errorBackoff
+
+	// key - watch job name
+	key string
+	// cache - persists digests, nil when disabled
+	cache *digestCache
+	// savedDigest and savedAt - digests that were last persisted
+	savedDigest string
+	savedAt     time.Time
+
 	mu sync.RWMutex
 }
 
@@ -65,6 +83,18 @@ type RepositoryWatcher struct {
 	// map[registry/name]=image.Reference
 	watched map[string]*watchDetails
 
+	// jitter - maximum random delay of scheduled checks
+	jitter time.Duration
+
+	// platform - multi-arch tags are updated only when this platform changes
+	platform string
+
+	// cache - persisted digests, see SetDigestCache
+	cache *digestCache
+
+	// pool - runs registry checks concurrently, see SetWorkerPool
+	pool *WorkerPool
+
 	cron *cron.Cron
 }
 
@@ -76,10 +106,52 @@ func NewRepositoryWatcher(providers provider.Providers, registryClient registry.
 		providers:      providers,
 		registryClient: registryClient,
 		watched:        make(map[string]*watchDetails),
+		platform:       runtime.GOOS + "/" + runtime.GOARCH,
 		cron:           c,
 	}
 }
 
+// SetPlatform - sets platform (os/arch[/variant]) that is watched for multi-arch
+// tags, defaults to the platform keel is running on
+func (w *RepositoryWatcher) SetPlatform(platform string) {
+	w.platform = platform
+}
+
+// trackedDigest - digest that is compared to detect updates, for multi-arch tags
+// it's the digest of the watched platform so rebuilt (or re-pushed) manifest
+// lists without changes for the platform don't trigger updates. Falls back to
+// the list digest when the platform isn't in the list.
+func trackedDigest(m *registry.Manifest, platform string) string {
+	if d := m.PlatformDigest(platform); d != "" {
+		return d
+	}
+	return m.Digest
+}
+
+// SetDigestCache - persists watched digests in the store so restarts don't
+// query registries for every watched image, digests that weren't updated
+// within ttl are ignored and removed
+func (w *RepositoryWatcher) SetDigestCache(s store.Store, ttl time.Duration) error {
+	cache, err := newDigestCache(s, ttl)
+	if err != nil {
+		return err
+	}
+	w.cache = cache
+	return nil
+}
+
+// SetJitter - sets maximum random delay that is added to scheduled checks
+func (w *RepositoryWatcher) SetJitter(jitter time.Duration) {
+	w.jitter = jitter
+}
+
+// SetWorkerPool - registry checks are run by a pool of workers instead of
+// running sequentially (initial checks) or all at once (scheduled checks),
+// perRegistry limits concurrent checks of a single registry (0 - no limit)
+func (w *RepositoryWatcher) SetWorkerPool(workers, perRegistry int) {
+	w.pool = NewWorkerPool(workers, perRegistry)
+}
+
 // Start - starts repository watcher
 func (w *RepositoryWatcher) Start(ctx context.Context) {
 	// starting cron job
@@ -87,6 +159,9 @@ func (w *RepositoryWatcher) Start(ctx context.Context) {
 	go func() {
 		<-ctx.Done()
 		w.cron.Stop()
+		if w.pool != nil {
+			w.pool.Stop()
+		}
 	}()
 }
 
@@ -127,11 +202,13 @@ func (w *RepositoryWatcher) Watch(images ...*types.TrackedImage) error {
 	var errs []string
 	tracked := map[string]bool{}
 
+	prepared := w.prepare(images)
+
 	for _, image := range images {
 		if image.Trigger != types.TriggerTypePoll {
 			continue
 		}
-		identifier, err := w.watch(image)
+		identifier, err := w.watch(image, prepared)
 		if err != nil {
 			errs = append(errs, err.Error())
 			continue
@@ -167,7 +244,66 @@ func (w *RepositoryWatcher) unwatch(tracked map[string]bool) {
 	}
 }
 
-func (w *RepositoryWatcher) watch(image *types.TrackedImage) (string, error) {
+// preparedJob - new watch job with its initial check done
+type preparedJob struct {
+	details *watchDetails
+	job     cron.Job
+	err     error
+}
+
+// prepare - runs initial checks of new images in the worker pool, nil without
+// the pool
+func (w *RepositoryWatcher) prepare(images []*types.TrackedImage) map[string]*preparedJob {
+	if w.pool == nil {
+		return nil
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	prepared := make(map[string]*preparedJob)
+
+	for _, ti := range images {
+		if ti.Trigger != types.TriggerTypePoll || ti.PollSchedule == "" {
+			continue
+		}
+		// invalid schedules are reported by watch
+		if _, err := cron.Parse(ti.PollSchedule); err != nil {
+			continue
+		}
+		key := getImageIdentifier(ti.Image)
+		if _, ok := w.watched[key]; ok {
+			continue
+		}
+		mu.Lock()
+		_, ok := prepared[key]
+		if !ok {
+			prepared[key] = nil
+		}
+		mu.Unlock()
+		if ok {
+			continue
+		}
+
+		ti := ti
+		wg.Add(1)
+		run := func() {
+			defer wg.Done()
+			details, job, err := w.newJob(ti, ti.PollSchedule)
+			mu.Lock()
+			prepared[key] = &preparedJob{details: details, job: job, err: err}
+			mu.Unlock()
+		}
+		if !w.pool.Submit(ti.Image.Registry(), key, run) {
+			// pool is stopped
+			run()
+		}
+	}
+	wg.Wait()
+
+	return prepared
+}
+
+func (w *RepositoryWatcher) watch(image *types.TrackedImage, prepared map[string]*preparedJob) (string, error) {
 
 	if image.PollSchedule == "" {
 		return "", fmt.Errorf("cron schedule cannot be empty")
@@ -188,8 +324,14 @@ func (w *RepositoryWatcher) watch(image *types.TrackedImage) (string, error) {
 	// checking whether it's already being watched
 	details, ok := w.watched[key]
 	if !ok {
-		// err = w.addJob(imageRef, registryUsername, registryPassword, schedule)
-		err = w.addJob(image, image.PollSchedule)
+		if p := prepared[key]; p != nil {
+			err = p.err
+			if err == nil {
+				err = w.addPreparedJob(p.details, p.job)
+			}
+		} else {
+			err = w.addJob(image, image.PollSchedule)
+		}
 		if err != nil {
 			log.WithFields(log.Fields{
 				"error": err,
@@ -222,71 +364,113 @@ func (w *RepositoryWatcher) watch(image *types.TrackedImage) (string, error) {
 }
 
 func (w *RepositoryWatcher) addJob(ti *types.TrackedImage, schedule string) error {
-	// getting initial digest
-	reg := ti.Image.Scheme() + "://" + ti.Image.Registry()
-
-	creds := credentialshelper.GetCredentials(ti)
-
-	digest, err := w.registryClient.Digest(registry.Opts{
-		Registry: reg,
-		Name:     ti.Image.ShortName(),
-		Tag:      ti.Image.Tag(),
-		Username: creds.Username,
-		Password: creds.Password,
-	})
+	details, job, err := w.newJob(ti, schedule)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"error":    err,
-			"image":    ti.Image.String(),
-			"username": creds.Username,
-			"password": strings.Repeat("*", len(creds.Password)),
-		}).Error("trigger.poll.RepositoryWatcher.addJob: failed to get image digest")
 		return err
 	}
+	return w.addPreparedJob(details, job)
+}
+
+// addPreparedJob - adds job to internal map and schedules it
+func (w *RepositoryWatcher) addPreparedJob(details *watchDetails, job cron.Job) error {
+	w.watched[details.key] = details
+
+	if w.pool != nil {
+		job = &pooledJob{
+			pool:     w.pool,
+			registry: details.trackedImage.Image.Registry(),
+			key:      details.key,
+			job:      job,
+		}
+	}
+	return w.cron.AddJob(details.key, details.schedule, withJitter(job, w.jitter))
+}
 
+// newJob - creates watch job and runs the initial check, safe to call
+// concurrently as it doesn't modify the watcher
+func (w *RepositoryWatcher) newJob(ti *types.TrackedImage, schedule string) (*watchDetails, cron.Job, error) {
 	key := getImageIdentifier(ti.Image)
 	details := &watchDetails{
 		trackedImage: ti,
-		digest:       digest, // current image digest
+		platform:     w.platform,
 		latest:       ti.Image.Tag(),
 		schedule:     schedule,
+		key:          key,
+		cache:        w.cache,
 	}
 
-	// adding job to internal map
-	w.watched[key] = details
+	// stored digest replaces the initial check, the image is checked on
+	// schedule
+	cached, warm := w.cache.get(key, w.platform)
+	if warm {
+		details.digest = cached.Digest
+		details.platformDigest = cached.PlatformDigest
+		details.savedDigest = cached.Digest + cached.PlatformDigest
+		details.savedAt = cached.UpdatedAt
+	} else {
+		// getting initial digest
+		reg := ti.Image.Scheme() + "://" + ti.Image.Registry()
+
+		creds := credentialshelper.GetCredentials(ti)
+
+		manifest, err := w.registryClient.Manifest(registry.Opts{
+			Registry: reg,
+			Name:     ti.Image.ShortName(),
+			Tag:      ti.Image.Tag(),
+			Username: creds.Username,
+			Password: creds.Password,
+		})
+		if err != nil {
+			if registry.IsUnauthorized(err) {
+				credentialshelper.RecordAuthFailure(ti, err)
+			}
+			log.WithFields(log.Fields{
+				"error":    err,
+				"image":    ti.Image.String(),
+				"username": creds.Username,
+				"password": strings.Repeat("*", len(creds.Password)),
+			}).Error("trigger.poll.RepositoryWatcher.addJob: failed to get image digest")
+			return nil, nil, err
+		}
+
+		credentialshelper.ClearAuthFailure(ti)
+		details.digest = manifest.Digest // current image digest
+		details.platformDigest = trackedDigest(manifest, w.platform)
+		w.cache.save(details)
+	}
+	digest := details.digest
 
 	// checking tag type, for versioned (semver) tags we setup a watch all tags job
 	// and for non-semver types we create a single tag watcher which
 	// checks digest
-	_, err = version.GetVersion(ti.Image.Tag())
+	var job cron.Job
+	_, err := version.GetVersion(ti.Image.Tag())
 	if err != nil {
 		// adding new job
-		job := NewWatchTagJob(w.providers, w.registryClient, details)
+		job = NewWatchTagJob(w.providers, w.registryClient, details)
 		log.WithFields(log.Fields{
 			"job_name": key,
 			"image":    ti.Image.String(),
 			"digest":   digest,
 			"schedule": schedule,
+			"cached":   warm,
 		}).Info("trigger.poll.RepositoryWatcher: new watch tag digest job added")
-
-		// running it now
-		job.Run()
-
-		return w.cron.AddJob(key, schedule, job)
+	} else {
+		// adding new job
+		job = NewWatchRepositoryTagsJob(w.providers, w.registryClient, details)
+		log.WithFields(log.Fields{
+			"job_name": key,
+			"image":    ti.Image.String(),
+			"digest":   digest,
+			"schedule": schedule,
+			"cached":   warm,
+		}).Info("trigger.poll.RepositoryWatcher: new watch repository tags job added")
 	}
 
-	// adding new job
-	job := NewWatchRepositoryTagsJob(w.providers, w.registryClient, details)
-	log.WithFields(log.Fields{
-		"job_name": key,
-		"image":    ti.Image.String(),
-		"digest":   digest,
-		"schedule": schedule,
-	}).Info("trigger.poll.RepositoryWatcher: new watch repository tags job added")
-
 	// running it now
-	job.Run()
-
-	return w.cron.AddJob(key, schedule, job)
+	if !warm {
+		job.Run()
+	}
 
+	return details, job, nil
 }
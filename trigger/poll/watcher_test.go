@@ -2,13 +2,16 @@ package poll
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/keel-hq/keel/approvals"
-	"github.com/keel-hq/keel/cache/memory"
 	"github.com/keel-hq/keel/extension/credentialshelper"
 	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/pkg/store/sql"
 	"github.com/keel-hq/keel/provider"
 	"github.com/keel-hq/keel/registry"
 	"github.com/keel-hq/keel/types"
@@ -31,7 +34,8 @@ func mustParse(img string, schedule string) *types.TrackedImage {
 type fakeRegistryClient struct {
 	opts registry.Opts // opts set if anything called Digest(opts Opts)
 
-	digestToReturn string
+	digestToReturn   string
+	manifestToReturn *registry.Manifest
 
 	tagsToReturn []string
 }
@@ -49,6 +53,22 @@ func (c *fakeRegistryClient) Digest(opts registry.Opts) (digest string, err erro
 	return c.digestToReturn, nil
 }
 
+func (c *fakeRegistryClient) Manifest(opts registry.Opts) (*registry.Manifest, error) {
+	c.opts = opts
+	if c.manifestToReturn != nil {
+		return c.manifestToReturn, nil
+	}
+	return &registry.Manifest{
+		Digest:    c.digestToReturn,
+		MediaType: registry.MediaTypeDockerManifest,
+	}, nil
+}
+
+func (c *fakeRegistryClient) Created(opts registry.Opts) (time.Time, error) {
+	c.opts = opts
+	return time.Now(), nil
+}
+
 // ======== fake provider for testing =======
 type fakeProvider struct {
 	submitted []types.Event
@@ -73,8 +93,7 @@ func (p *fakeProvider) TrackedImages() ([]*types.TrackedImage, error) {
 func TestWatchTagJob(t *testing.T) {
 
 	fp := &fakeProvider{}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -117,11 +136,66 @@ func TestWatchTagJob(t *testing.T) {
 	}
 }
 
+func TestWatchTagJobMultiArch(t *testing.T) {
+
+	fp := &fakeProvider{}
+	am := approver()
+	providers := provider.New([]provider.Provider{fp}, am)
+
+	frc := &fakeRegistryClient{
+		manifestToReturn: &registry.Manifest{
+			Digest:    "sha256:list-2",
+			MediaType: registry.MediaTypeDockerManifestList,
+			Platforms: map[string]string{
+				"linux/amd64": "sha256:amd64-1",
+				"linux/arm64": "sha256:arm64-2",
+			},
+		},
+	}
+
+	reference, _ := image.Parse("foo/bar:latest")
+
+	details := &watchDetails{
+		trackedImage: &types.TrackedImage{
+			Image: reference,
+		},
+		digest:         "sha256:list-1",
+		platform:       "linux/amd64",
+		platformDigest: "sha256:amd64-1",
+	}
+
+	job := NewWatchTagJob(providers, frc, details)
+
+	// only arm64 image was rebuilt
+	job.Run()
+
+	if len(fp.submitted) != 0 {
+		t.Fatalf("expected no events, got: %d", len(fp.submitted))
+	}
+	if job.details.digest != "sha256:list-2" {
+		t.Errorf("job details digest wasn't updated")
+	}
+
+	frc.manifestToReturn.Digest = "sha256:list-3"
+	frc.manifestToReturn.Platforms["linux/amd64"] = "sha256:amd64-3"
+
+	job.Run()
+
+	if len(fp.submitted) != 1 {
+		t.Fatalf("expected 1 event, got: %d", len(fp.submitted))
+	}
+	if fp.submitted[0].Repository.Digest != "sha256:list-3" {
+		t.Errorf("unexpected event repository digest: %s", fp.submitted[0].Repository.Digest)
+	}
+	if job.details.platformDigest != "sha256:amd64-3" {
+		t.Errorf("job details platform digest wasn't updated")
+	}
+}
+
 func TestWatchTagJobLatest(t *testing.T) {
 
 	fp := &fakeProvider{}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -175,8 +249,7 @@ func TestWatchAllTagsJob(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -215,8 +288,7 @@ func TestWatchAllTagsJobCurrentLatest(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -282,8 +354,7 @@ func TestWatchMultipleTags(t *testing.T) {
 			},
 		},
 	}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	// returning some sha
@@ -358,8 +429,7 @@ func TestWatchTagJobCheckCredentials(t *testing.T) {
 	defer credentialshelper.UnregisterCredentialsHelper("fake")
 
 	fp := &fakeProvider{}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	frc := &fakeRegistryClient{
@@ -407,8 +477,7 @@ func TestWatchTagJobLatestECR(t *testing.T) {
 		},
 	}
 
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 	rc := registry.New()
 
@@ -450,8 +519,7 @@ func TestWatchTagJobLatestECR(t *testing.T) {
 
 func TestUnwatchAfterNotTrackedAnymore(t *testing.T) {
 	fp := &fakeProvider{}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	// returning some sha
@@ -511,3 +579,15 @@ func TestUnwatchAfterNotTrackedAnymore(t *testing.T) {
 		t.Errorf("expected to find watching 3 entries, found: %d", len(watcher.watched))
 	}
 }
+
+var approverDatabases int32
+
+// approver - approvals manager with its own in-memory sqlite store
+func approver() *approvals.DefaultManager {
+	uri := fmt.Sprintf("file:approver%d?mode=memory&cache=shared", atomic.AddInt32(&approverDatabases, 1))
+	store, err := sql.New(sql.Opts{DatabaseType: sql.DatabaseTypeSQLite, URI: uri})
+	if err != nil {
+		panic(err)
+	}
+	return approvals.New(&approvals.Opts{Store: store})
+}
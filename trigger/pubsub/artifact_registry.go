@@ -0,0 +1,36 @@
+package pubsub
+
+import (
+	"strings"
+)
+
+// Artifact Registry publishes push notifications to the same "gcr" topic
+// as Container Registry, image references use regional hostnames:
+// {
+//   "action": "INSERT",
+//   "digest": "us-east1-docker.pkg.dev/my-project/my-repo/hello-world@sha256:6ec128e26cd5...",
+//   "tag": "us-east1-docker.pkg.dev/my-project/my-repo/hello-world:1.1"
+// }
+
+// artifactRegistryHostSuffix - LOCATION-docker.pkg.dev
+const artifactRegistryHostSuffix = "-docker.pkg.dev"
+
+// isArtifactRegistry - checks whether registry is a Google Artifact Registry
+// docker repository host, ie: europe-west1-docker.pkg.dev
+func isArtifactRegistry(registry string) bool {
+	return strings.HasSuffix(registry, artifactRegistryHostSuffix)
+}
+
+// isGoogleRegistry - registries that publish notifications to pubsub
+func isGoogleRegistry(registry string) bool {
+	return isGoogleContainerRegistry(registry) || isArtifactRegistry(registry)
+}
+
+// parseDigest - digest field contains full image reference (image@sha256:...),
+// returning just the digest
+func parseDigest(digest string) string {
+	if idx := strings.LastIndex(digest, "@"); idx >= 0 {
+		return digest[idx+1:]
+	}
+	return digest
+}
@@ -0,0 +1,66 @@
+package pubsub
+
+import (
+	"testing"
+)
+
+func Test_isGoogleRegistry(t *testing.T) {
+	tests := []struct {
+		name     string
+		registry string
+		want     bool
+	}{
+		{name: "gcr", registry: unsafeImageRef("gcr.io/v2-namespace/hello-world:1.1").Registry(), want: true},
+		{name: "regional gcr", registry: unsafeImageRef("eu.gcr.io/v2-namespace/hello-world:1.1").Registry(), want: true},
+		{name: "artifact registry", registry: unsafeImageRef("us-east1-docker.pkg.dev/my-project/my-repo/hello-world:1.1").Registry(), want: true},
+		{name: "multi-region artifact registry", registry: unsafeImageRef("europe-docker.pkg.dev/my-project/my-repo/hello-world:1.1").Registry(), want: true},
+		{name: "docker registry", registry: unsafeImageRef("docker.io/v2-namespace/hello-world:1.1").Registry(), want: false},
+		{name: "npm artifact registry", registry: "us-east1-npm.pkg.dev", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isGoogleRegistry(tt.registry); got != tt.want {
+				t.Errorf("isGoogleRegistry(%s) = %v, want %v", tt.registry, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArtifactRegistryReference(t *testing.T) {
+	ref := unsafeImageRef("us-east1-docker.pkg.dev/my-project/my-repo/hello-world:1.1")
+	if ref.Repository() != "us-east1-docker.pkg.dev/my-project/my-repo/hello-world" {
+		t.Errorf("unexpected repository: %s", ref.Repository())
+	}
+	if ref.Tag() != "1.1" {
+		t.Errorf("unexpected tag: %s", ref.Tag())
+	}
+}
+
+func Test_parseDigest(t *testing.T) {
+	tests := []struct {
+		digest string
+		want   string
+	}{
+		{
+			digest: "us-east1-docker.pkg.dev/my-project/my-repo/hello-world@sha256:6ec128e26cd5",
+			want:   "sha256:6ec128e26cd5",
+		},
+		{
+			digest: "gcr.io/my-project/hello-world@sha256:6ec128e26cd5",
+			want:   "sha256:6ec128e26cd5",
+		},
+		{
+			digest: "sha256:6ec128e26cd5",
+			want:   "sha256:6ec128e26cd5",
+		},
+		{
+			digest: "",
+			want:   "",
+		},
+	}
+	for _, tt := range tests {
+		if got := parseDigest(tt.digest); got != tt.want {
+			t.Errorf("parseDigest(%s) = %s, want %s", tt.digest, got, tt.want)
+		}
+	}
+}
@@ -95,13 +95,14 @@ func (s *DefaultManager) scan(ctx context.Context) error {
 	}
 
 	for _, trackedImage := range trackedImages {
-		if !isGoogleContainerRegistry(trackedImage.Image.Registry()) {
-			log.Debugf("registry %s is not a GCR, skipping", trackedImage.Image.Registry())
+		if !isGoogleRegistry(trackedImage.Image.Registry()) {
+			log.Debugf("registry %s is not a GCR or Artifact Registry, skipping", trackedImage.Image.Registry())
 			continue
 		}
 
 		// uri
 		// https://cloud.google.com/container-registry/docs/configuring-notifications
+		// https://cloud.google.com/artifact-registry/docs/configure-notifications
 		s.ensureSubscription("gcr")
 	}
 	return nil
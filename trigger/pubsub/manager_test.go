@@ -6,8 +6,6 @@ import (
 
 	"golang.org/x/net/context"
 
-	"github.com/keel-hq/keel/approvals"
-	"github.com/keel-hq/keel/cache/memory"
 	"github.com/keel-hq/keel/provider"
 	"github.com/keel-hq/keel/types"
 	"github.com/keel-hq/keel/util/image"
@@ -66,8 +64,7 @@ func TestCheckDeployment(t *testing.T) {
 		},
 	}
 
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 
 	fs := &fakeSubscriber{}
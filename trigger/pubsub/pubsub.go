@@ -188,7 +188,7 @@ func (s *PubsubSubscriber) callback(ctx context.Context, msg *pubsub.Message) {
 		Repository: types.Repository{
 			Name:   ref.Repository(),
 			Tag:    ref.Tag(),
-			Digest: decoded.Digest,
+			Digest: parseDigest(decoded.Digest),
 		},
 		CreatedAt: time.Now(),
 	}
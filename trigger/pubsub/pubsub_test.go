@@ -2,12 +2,14 @@ package pubsub
 
 import (
 	"encoding/json"
+	"fmt"
+	"sync/atomic"
 
 	"cloud.google.com/go/pubsub"
 	"golang.org/x/net/context"
 
 	"github.com/keel-hq/keel/approvals"
-	"github.com/keel-hq/keel/cache/memory"
+	"github.com/keel-hq/keel/pkg/store/sql"
 	"github.com/keel-hq/keel/provider"
 
 	"testing"
@@ -23,8 +25,7 @@ func fakeDoneFunc(id string, done bool) {
 func TestCallback(t *testing.T) {
 
 	fp := &fakeProvider{}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 	sub := &PubsubSubscriber{disableAck: true, providers: providers}
 
@@ -50,8 +51,7 @@ func TestCallback(t *testing.T) {
 func TestCallbackTagNotSemver(t *testing.T) {
 
 	fp := &fakeProvider{}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 	sub := &PubsubSubscriber{disableAck: true, providers: providers}
 
@@ -78,8 +78,7 @@ func TestCallbackTagNotSemver(t *testing.T) {
 func TestCallbackNoTag(t *testing.T) {
 
 	fp := &fakeProvider{}
-	mem := memory.NewMemoryCache()
-	am := approvals.New(mem)
+	am := approver()
 	providers := provider.New([]provider.Provider{fp}, am)
 	sub := &PubsubSubscriber{disableAck: true, providers: providers}
 
@@ -101,3 +100,15 @@ func TestCallbackNoTag(t *testing.T) {
 		t.Errorf("expected repo tag %s but got %s", "latest", fp.submitted[0].Repository.Tag)
 	}
 }
+
+var approverDatabases int32
+
+// approver - approvals manager with its own in-memory sqlite store
+func approver() *approvals.DefaultManager {
+	uri := fmt.Sprintf("file:approver%d?mode=memory&cache=shared", atomic.AddInt32(&approverDatabases, 1))
+	store, err := sql.New(sql.Opts{DatabaseType: sql.DatabaseTypeSQLite, URI: uri})
+	if err != nil {
+		panic(err)
+	}
+	return approvals.New(&approvals.Opts{Store: store})
+}
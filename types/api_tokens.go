@@ -0,0 +1,60 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+// API token scopes
+const (
+	APITokenScopeReadOnly = "read-only"
+	APITokenScopeApprove  = "approve"
+	APITokenScopeAdmin    = "admin"
+)
+
+// StoredAPIToken - API token created through the admin API, only the hash
+// of the token is stored
+type StoredAPIToken struct {
+	ID        string    `json:"id" gorm:"primary_key;type:varchar(36)"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	Name string `json:"name"`
+	// Hash - SHA-256 of the token
+	Hash string `json:"-" gorm:"unique_index"`
+	// Prefix - first characters of the token, helps users to recognize it
+	Prefix string `json:"prefix"`
+
+	// Scope - read-only, approve or admin
+	Scope string `json:"scope"`
+	// Namespaces - comma separated namespaces the token is restricted to,
+	// all when empty
+	Namespaces string `json:"namespaces"`
+
+	// ExpiresAt - optional, tokens don't expire when it's not set
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+
+	// CreatedBy - user that created the token
+	CreatedBy string `json:"createdBy"`
+}
+
+// GetNamespaces - namespaces the token is restricted to
+func (t *StoredAPIToken) GetNamespaces() []string {
+	var namespaces []string
+	for _, ns := range strings.Split(t.Namespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// Expired - whether token expired at t
+func (t *StoredAPIToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// GetAPITokenQuery - either ID or hash of the token
+type GetAPITokenQuery struct {
+	ID   string
+	Hash string
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -36,6 +37,24 @@ type Approval struct {
 
 	Message string `json:"message"`
 
+	// Workspace - optional chat workspace that should handle this approval
+	Workspace string `json:"workspace,omitempty"`
+
+	// Reminders - optional reminder intervals of the pending approval (ie: 1h,4h,12h),
+	// default schedule is used when empty
+	Reminders string `json:"reminders,omitempty"`
+	// RemindersSent - number of reminders sent so far
+	RemindersSent int `json:"remindersSent"`
+
+	// Approvers - optional comma separated chat users that get the request as a direct message
+	Approvers string `json:"approvers,omitempty"`
+	// Private - request is only sent to the approvers, not to the approvals channel
+	Private bool `json:"private,omitempty"`
+
+	// Oncall - optional comma separated emails of users on call when approval was
+	// requested, bots mention them in the request
+	Oncall string `json:"oncall,omitempty"`
+
 	CurrentVersion string `json:"currentVersion"`
 	NewVersion     string `json:"newVersion"`
 
@@ -43,6 +62,17 @@ type Approval struct {
 	// If digest doesn't match for the image, votes are reset.
 	Digest string `json:"digest"`
 
+	// IdempotencyKey - change the approval was requested for, see IdempotencyKey
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+
+	// FreezeReason - set when the resource is frozen, the approved update
+	// is only applied once the freeze ends. Not stored.
+	FreezeReason string `json:"freezeReason,omitempty" gorm:"-"`
+
+	// Diff - optional preview of the changes the update would apply, ie: values
+	// and manifest diff of a Helm release. Not stored.
+	Diff string `json:"diff,omitempty" gorm:"-"`
+
 	// Requirements for the update such as number of votes
 	// and deadline
 	VotesRequired int `json:"votesRequired"`
@@ -67,6 +97,38 @@ type Approval struct {
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
+// IdempotencyKey - identifies a change of a workload (new image and its digest
+// when known), the same change reported by several triggers has the same key
+// ie: deployment/default/wd/karolisr/webhook-demo:0.0.16@sha256:...
+func IdempotencyKey(identifier, image, digest string) string {
+	if digest == "" {
+		return identifier + "/" + image
+	}
+	return identifier + "/" + image + "@" + digest
+}
+
+// GetApprovers - returns a list of approvers that get direct messages
+func (a *Approval) GetApprovers() []string {
+	var approvers []string
+	for _, approver := range strings.Split(a.Approvers, ",") {
+		if approver = strings.TrimPrefix(strings.TrimSpace(approver), "@"); approver != "" {
+			approvers = append(approvers, approver)
+		}
+	}
+	return approvers
+}
+
+// GetOncall - returns emails of users that were on call when approval was requested
+func (a *Approval) GetOncall() []string {
+	var users []string
+	for _, user := range strings.Split(a.Oncall, ",") {
+		if user = strings.TrimSpace(user); user != "" {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
 func (a *Approval) GetVoters() []string {
 	// meta := make(map[string]string)
 	var voters []string
@@ -84,6 +146,23 @@ func (a *Approval) AddVoter(voter string) {
 	a.Voters[voter] = time.Now()
 }
 
+// Copy - returns a copy of the approval that can be changed without
+// affecting the original, ie: by subscribers of approval events
+func (a *Approval) Copy() *Approval {
+	cp := *a
+	if a.Event != nil {
+		event := *a.Event
+		cp.Event = &event
+	}
+	if a.Voters != nil {
+		cp.Voters = make(JSONB, len(a.Voters))
+		for k, v := range a.Voters {
+			cp.Voters[k] = v
+		}
+	}
+	return &cp
+}
+
 // ApprovalStatus - approval status type used in approvals
 // to determine whether it was rejected/approved or still pending
 type ApprovalStatus int
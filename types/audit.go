@@ -17,8 +17,15 @@ const (
 
 	// audit specific resource kinds (others are set by
 	// providers, ie: deployment, daemonset, helm chart)
-	AuditResourceKindApproval = "approval"
-	AuditResourceKindWebhook  = "webhook"
+	AuditResourceKindApproval    = "approval"
+	AuditResourceKindWebhook     = "webhook"
+	AuditResourceKindAPIToken    = "api_token"
+	AuditResourceKindMaintenance = "maintenance"
+	AuditResourceKindLogLevels   = "log_levels"
+	AuditResourceKindState       = "state"
+	AuditResourceKindPaused      = "paused_resource"
+	AuditResourceKindDeploy      = "deploy"
+	AuditResourceKindConfig      = "config"
 )
 
 // AuditLog - audit logs lets users basic things happening in keel such as
@@ -62,6 +69,9 @@ type AuditLogQuery struct {
 	Limit    int    `json:"limit"`
 	Offset   int    `json:"offset"`
 
+	// Since - only entries created after the given time
+	Since time.Time `json:"since"`
+
 	ResourceKindFilter []string `json:"resourceKindFilter"`
 }
 
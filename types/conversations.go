@@ -0,0 +1,99 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// BotConversation - approval conversation of a chat bot: the request message,
+// direct message channels of the approvers and the approval state shown in
+// the message. Conversations are stored so that any replica can update or
+// conclude them.
+type BotConversation struct {
+	// ID - <bot>/<approval identifier>
+	ID        string    `json:"id" gorm:"primary_key;type:varchar(255)"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// Bot - name of the bot, ie: slack or slack-<workspace>
+	Bot        string `json:"bot" gorm:"index"`
+	Identifier string `json:"identifier"`
+
+	// Channel and TS - approval request message, empty when the request was
+	// only sent to the approvers
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+
+	// DirectChannels - comma separated direct message channels of the approvers
+	DirectChannels string `json:"directChannels"`
+	// Private - votes are only accepted in the direct message channels
+	Private bool `json:"private"`
+
+	// Approval - latest state of the approval shown in the request message
+	Approval *ConversationApproval `json:"approval" gorm:"type:json"`
+	// Final - approval was approved, rejected or expired, request message
+	// won't change anymore
+	Final bool `json:"final"`
+
+	Expires time.Time `json:"expires" gorm:"index"`
+}
+
+// BotConversationID - ID of the bot conversation of the approval
+func BotConversationID(bot, identifier string) string {
+	return bot + "/" + identifier
+}
+
+// GetDirectChannels - direct message channels of the approvers
+func (c *BotConversation) GetDirectChannels() []string {
+	if c.DirectChannels == "" {
+		return []string{}
+	}
+	return strings.Split(c.DirectChannels, ",")
+}
+
+// HasDirectChannel - checks whether the request was sent to the direct message channel
+func (c *BotConversation) HasDirectChannel(channel string) bool {
+	for _, ch := range c.GetDirectChannels() {
+		if ch == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// GetApproval - approval shown in the request message, nil when it wasn't posted
+func (c *BotConversation) GetApproval() *Approval {
+	return (*Approval)(c.Approval)
+}
+
+// SetApproval - sets approval shown in the request message
+func (c *BotConversation) SetApproval(approval *Approval) {
+	c.Approval = (*ConversationApproval)(approval)
+}
+
+// ConversationApproval - approval stored with the conversation as JSON
+type ConversationApproval Approval
+
+func (a *ConversationApproval) Value() (driver.Value, error) {
+	j, err := json.Marshal(a)
+	return j, err
+}
+
+func (a *ConversationApproval) Scan(src interface{}) error {
+	source, ok := src.([]byte)
+	if !ok {
+		return errors.New("type assertion .([]byte) failed.")
+	}
+
+	var approval ConversationApproval
+	if err := json.Unmarshal(source, &approval); err != nil {
+		return err
+	}
+
+	*a = approval
+
+	return nil
+}
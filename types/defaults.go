@@ -0,0 +1,47 @@
+package types
+
+import "sync"
+
+// Defaults - settings of workloads that don't set them with annotations,
+// they can be changed in keel config file
+type Defaults struct {
+	// PollSchedule - poll schedule, defaults to KeelPollDefaultSchedule
+	PollSchedule string
+	// ApprovalDeadline - approval deadline in hours, defaults to KeelApprovalDeadlineDefault
+	ApprovalDeadline int
+}
+
+var (
+	defaultsM sync.RWMutex
+	defaults  = Defaults{
+		PollSchedule:     KeelPollDefaultSchedule,
+		ApprovalDeadline: KeelApprovalDeadlineDefault,
+	}
+)
+
+// SetDefaults - sets workload defaults, empty fields reset them to keel defaults
+func SetDefaults(d Defaults) {
+	if d.PollSchedule == "" {
+		d.PollSchedule = KeelPollDefaultSchedule
+	}
+	if d.ApprovalDeadline <= 0 {
+		d.ApprovalDeadline = KeelApprovalDeadlineDefault
+	}
+	defaultsM.Lock()
+	defaults = d
+	defaultsM.Unlock()
+}
+
+// DefaultPollSchedule - poll schedule of workloads without keel.sh/pollSchedule
+func DefaultPollSchedule() string {
+	defaultsM.RLock()
+	defer defaultsM.RUnlock()
+	return defaults.PollSchedule
+}
+
+// DefaultApprovalDeadline - approval deadline (in hours) of workloads without keel.sh/approvalDeadline
+func DefaultApprovalDeadline() int {
+	defaultsM.RLock()
+	defer defaultsM.RUnlock()
+	return defaults.ApprovalDeadline
+}
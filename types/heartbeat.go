@@ -0,0 +1,10 @@
+package types
+
+import "time"
+
+// Heartbeat - single row that is written by the readiness probe to check
+// that the database accepts writes
+type Heartbeat struct {
+	ID        string    `json:"id" gorm:"primary_key;type:varchar(36)"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,78 @@
+package types
+
+import (
+	"time"
+)
+
+// update record statuses
+const (
+	UpdateStatusSuccess = "success"
+	UpdateStatusFailed  = "failed"
+	// UpdateStatusRolledBack - update didn't roll out and was rolled back, the
+	// version isn't updated to again
+	UpdateStatusRolledBack = "rolled_back"
+	// UpdateStatusManual - image was changed outside of keel (ie: kubectl set image),
+	// recorded by the admission webhook
+	UpdateStatusManual = "manual"
+)
+
+// UpdateRecord - update history entry, created by providers
+// after each attempted update
+type UpdateRecord struct {
+	ID        string    `json:"id" gorm:"primary_key;type:varchar(36)"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	Provider     string `json:"provider"`
+	ResourceKind string `json:"resourceKind"`
+	// Identifier - resource identifier, ie: deployment/default/wd
+	Identifier string `json:"identifier"`
+	Namespace  string `json:"namespace" gorm:"index"`
+	Name       string `json:"name" gorm:"index"`
+
+	// Containers - names of the updated containers, comma separated
+	Containers string `json:"containers"`
+
+	// Image - updated image repository, ie: index.docker.io/karolisr/webhook-demo
+	Image          string `json:"image" gorm:"index"`
+	PreviousTag    string `json:"previousTag"`
+	NewTag         string `json:"newTag"`
+	PreviousDigest string `json:"previousDigest"`
+	NewDigest      string `json:"newDigest"`
+
+	// Trigger - trigger that found the new version, ie: poll, pubsub, approval
+	Trigger string `json:"trigger"`
+
+	// Approvers - voters of the approval that allowed this update
+	Approvers JSONB `json:"approvers" gorm:"type:json"`
+
+	Status  string `json:"status"`
+	Message string `json:"message"`
+
+	// DurationMs - milliseconds from the new version event to the end of the update
+	DurationMs int64 `json:"durationMs"`
+}
+
+// GetApprovers - returns a list of approvers
+func (r *UpdateRecord) GetApprovers() []string {
+	var approvers []string
+	for key := range r.Approvers {
+		approvers = append(approvers, key)
+	}
+	return approvers
+}
+
+// UpdateRecordQuery - update history query, all set fields have to match
+type UpdateRecordQuery struct {
+	Namespace string
+	Name      string
+	Image     string
+	Provider  string
+	Status    string
+
+	// Since, Until - optional creation time range
+	Since time.Time
+	Until time.Time
+
+	Limit  int
+	Offset int
+}
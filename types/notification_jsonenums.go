@@ -9,27 +9,47 @@ import (
 
 var (
 	_NotificationNameToValue = map[string]Notification{
-		"PreProviderSubmitNotification":   PreProviderSubmitNotification,
-		"PostProviderSubmitNotification":  PostProviderSubmitNotification,
-		"NotificationPreDeploymentUpdate": NotificationPreDeploymentUpdate,
-		"NotificationDeploymentUpdate":    NotificationDeploymentUpdate,
-		"NotificationPreReleaseUpdate":    NotificationPreReleaseUpdate,
-		"NotificationReleaseUpdate":       NotificationReleaseUpdate,
-		"NotificationSystemEvent":         NotificationSystemEvent,
-		"NotificationUpdateApproved":      NotificationUpdateApproved,
-		"NotificationUpdateRejected":      NotificationUpdateRejected,
+		"PreProviderSubmitNotification":      PreProviderSubmitNotification,
+		"PostProviderSubmitNotification":     PostProviderSubmitNotification,
+		"NotificationPreDeploymentUpdate":    NotificationPreDeploymentUpdate,
+		"NotificationDeploymentUpdate":       NotificationDeploymentUpdate,
+		"NotificationPreReleaseUpdate":       NotificationPreReleaseUpdate,
+		"NotificationReleaseUpdate":          NotificationReleaseUpdate,
+		"NotificationSystemEvent":            NotificationSystemEvent,
+		"NotificationUpdateApproved":         NotificationUpdateApproved,
+		"NotificationUpdateRejected":         NotificationUpdateRejected,
+		"NotificationRepositoryDiscovered":   NotificationRepositoryDiscovered,
+		"NotificationDryRunUpdate":           NotificationDryRunUpdate,
+		"NotificationSignatureVerification":  NotificationSignatureVerification,
+		"NotificationVulnerabilityScan":      NotificationVulnerabilityScan,
+		"NotificationDigest":                 NotificationDigest,
+		"NotificationUpdateQueued":           NotificationUpdateQueued,
+		"NotificationRolloutProgress":        NotificationRolloutProgress,
+		"NotificationProvenanceVerification": NotificationProvenanceVerification,
+		"NotificationTagDeleted":             NotificationTagDeleted,
+		"NotificationHygieneReport":          NotificationHygieneReport,
 	}
 
 	_NotificationValueToName = map[Notification]string{
-		PreProviderSubmitNotification:   "PreProviderSubmitNotification",
-		PostProviderSubmitNotification:  "PostProviderSubmitNotification",
-		NotificationPreDeploymentUpdate: "NotificationPreDeploymentUpdate",
-		NotificationDeploymentUpdate:    "NotificationDeploymentUpdate",
-		NotificationPreReleaseUpdate:    "NotificationPreReleaseUpdate",
-		NotificationReleaseUpdate:       "NotificationReleaseUpdate",
-		NotificationSystemEvent:         "NotificationSystemEvent",
-		NotificationUpdateApproved:      "NotificationUpdateApproved",
-		NotificationUpdateRejected:      "NotificationUpdateRejected",
+		PreProviderSubmitNotification:      "PreProviderSubmitNotification",
+		PostProviderSubmitNotification:     "PostProviderSubmitNotification",
+		NotificationPreDeploymentUpdate:    "NotificationPreDeploymentUpdate",
+		NotificationDeploymentUpdate:       "NotificationDeploymentUpdate",
+		NotificationPreReleaseUpdate:       "NotificationPreReleaseUpdate",
+		NotificationReleaseUpdate:          "NotificationReleaseUpdate",
+		NotificationSystemEvent:            "NotificationSystemEvent",
+		NotificationUpdateApproved:         "NotificationUpdateApproved",
+		NotificationUpdateRejected:         "NotificationUpdateRejected",
+		NotificationRepositoryDiscovered:   "NotificationRepositoryDiscovered",
+		NotificationDryRunUpdate:           "NotificationDryRunUpdate",
+		NotificationSignatureVerification:  "NotificationSignatureVerification",
+		NotificationVulnerabilityScan:      "NotificationVulnerabilityScan",
+		NotificationDigest:                 "NotificationDigest",
+		NotificationUpdateQueued:           "NotificationUpdateQueued",
+		NotificationRolloutProgress:        "NotificationRolloutProgress",
+		NotificationProvenanceVerification: "NotificationProvenanceVerification",
+		NotificationTagDeleted:             "NotificationTagDeleted",
+		NotificationHygieneReport:          "NotificationHygieneReport",
 	}
 )
 
@@ -37,15 +57,25 @@ func init() {
 	var v Notification
 	if _, ok := interface{}(v).(fmt.Stringer); ok {
 		_NotificationNameToValue = map[string]Notification{
-			interface{}(PreProviderSubmitNotification).(fmt.Stringer).String():   PreProviderSubmitNotification,
-			interface{}(PostProviderSubmitNotification).(fmt.Stringer).String():  PostProviderSubmitNotification,
-			interface{}(NotificationPreDeploymentUpdate).(fmt.Stringer).String(): NotificationPreDeploymentUpdate,
-			interface{}(NotificationDeploymentUpdate).(fmt.Stringer).String():    NotificationDeploymentUpdate,
-			interface{}(NotificationPreReleaseUpdate).(fmt.Stringer).String():    NotificationPreReleaseUpdate,
-			interface{}(NotificationReleaseUpdate).(fmt.Stringer).String():       NotificationReleaseUpdate,
-			interface{}(NotificationSystemEvent).(fmt.Stringer).String():         NotificationSystemEvent,
-			interface{}(NotificationUpdateApproved).(fmt.Stringer).String():      NotificationUpdateApproved,
-			interface{}(NotificationUpdateRejected).(fmt.Stringer).String():      NotificationUpdateRejected,
+			interface{}(PreProviderSubmitNotification).(fmt.Stringer).String():      PreProviderSubmitNotification,
+			interface{}(PostProviderSubmitNotification).(fmt.Stringer).String():     PostProviderSubmitNotification,
+			interface{}(NotificationPreDeploymentUpdate).(fmt.Stringer).String():    NotificationPreDeploymentUpdate,
+			interface{}(NotificationDeploymentUpdate).(fmt.Stringer).String():       NotificationDeploymentUpdate,
+			interface{}(NotificationPreReleaseUpdate).(fmt.Stringer).String():       NotificationPreReleaseUpdate,
+			interface{}(NotificationReleaseUpdate).(fmt.Stringer).String():          NotificationReleaseUpdate,
+			interface{}(NotificationSystemEvent).(fmt.Stringer).String():            NotificationSystemEvent,
+			interface{}(NotificationUpdateApproved).(fmt.Stringer).String():         NotificationUpdateApproved,
+			interface{}(NotificationUpdateRejected).(fmt.Stringer).String():         NotificationUpdateRejected,
+			interface{}(NotificationRepositoryDiscovered).(fmt.Stringer).String():   NotificationRepositoryDiscovered,
+			interface{}(NotificationDryRunUpdate).(fmt.Stringer).String():           NotificationDryRunUpdate,
+			interface{}(NotificationSignatureVerification).(fmt.Stringer).String():  NotificationSignatureVerification,
+			interface{}(NotificationVulnerabilityScan).(fmt.Stringer).String():      NotificationVulnerabilityScan,
+			interface{}(NotificationDigest).(fmt.Stringer).String():                 NotificationDigest,
+			interface{}(NotificationUpdateQueued).(fmt.Stringer).String():           NotificationUpdateQueued,
+			interface{}(NotificationRolloutProgress).(fmt.Stringer).String():        NotificationRolloutProgress,
+			interface{}(NotificationProvenanceVerification).(fmt.Stringer).String(): NotificationProvenanceVerification,
+			interface{}(NotificationTagDeleted).(fmt.Stringer).String():             NotificationTagDeleted,
+			interface{}(NotificationHygieneReport).(fmt.Stringer).String():          NotificationHygieneReport,
 		}
 	}
 }
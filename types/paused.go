@@ -0,0 +1,29 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// PausedResource - automatic updates are disabled for paused resources
+// until they are resumed
+type PausedResource struct {
+	ID        string    `json:"id" gorm:"primary_key;type:varchar(36)"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Identifier - <namespace>/<name> of the paused workload,
+	// applies to all resource kinds and helm releases
+	Identifier string `json:"identifier" gorm:"unique_index"`
+
+	// User - who paused the resource
+	User string `json:"user"`
+}
+
+// PausedAllIdentifier - identifier of the maintenance mode record, automatic
+// updates of all resources are suspended while it exists
+const PausedAllIdentifier = "*"
+
+// PausedIdentifier - returns identifier that is used to track paused resources
+func PausedIdentifier(namespace, name string) string {
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
@@ -0,0 +1,19 @@
+package types
+
+import (
+	"time"
+)
+
+// PollDigest - last seen digest of an image watched by the poll trigger, keel
+// continues watching from it after restarts instead of querying registries
+type PollDigest struct {
+	// ID - watch job key, <registry>/<name> for semver tags and
+	// <registry>/<name>:<tag> for the rest
+	ID        string    `json:"id" gorm:"primary_key;type:varchar(255)"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"index"`
+
+	Digest string `json:"digest"`
+	// Platform and PlatformDigest - watched platform of multi-arch tags
+	Platform       string `json:"platform"`
+	PlatformDigest string `json:"platformDigest"`
+}
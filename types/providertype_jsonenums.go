@@ -12,12 +12,16 @@ var (
 		"ProviderTypeUnknown":    ProviderTypeUnknown,
 		"ProviderTypeKubernetes": ProviderTypeKubernetes,
 		"ProviderTypeHelm":       ProviderTypeHelm,
+		"ProviderTypeGitOps":     ProviderTypeGitOps,
+		"ProviderTypeKustomize":  ProviderTypeKustomize,
 	}
 
 	_ProviderTypeValueToName = map[ProviderType]string{
 		ProviderTypeUnknown:    "ProviderTypeUnknown",
 		ProviderTypeKubernetes: "ProviderTypeKubernetes",
 		ProviderTypeHelm:       "ProviderTypeHelm",
+		ProviderTypeGitOps:     "ProviderTypeGitOps",
+		ProviderTypeKustomize:  "ProviderTypeKustomize",
 	}
 )
 
@@ -28,6 +32,8 @@ func init() {
 			interface{}(ProviderTypeUnknown).(fmt.Stringer).String():    ProviderTypeUnknown,
 			interface{}(ProviderTypeKubernetes).(fmt.Stringer).String(): ProviderTypeKubernetes,
 			interface{}(ProviderTypeHelm).(fmt.Stringer).String():       ProviderTypeHelm,
+			interface{}(ProviderTypeGitOps).(fmt.Stringer).String():     ProviderTypeGitOps,
+			interface{}(ProviderTypeKustomize).(fmt.Stringer).String():  ProviderTypeKustomize,
 		}
 	}
 }
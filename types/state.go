@@ -0,0 +1,49 @@
+package types
+
+import (
+	"time"
+)
+
+// StateVersion - version of the state export format
+const StateVersion = 1
+
+// state record kinds, used in import results and archive file names
+const (
+	StateKindApprovals       = "approvals"
+	StateKindAuditLogs       = "audit_logs"
+	StateKindPausedResources = "paused_resources"
+	StateKindUpdateRecords   = "update_records"
+	StateKindWebhookRecords  = "webhook_records"
+	StateKindAPITokens       = "api_tokens"
+	StateKindPollDigests     = "poll_digests"
+)
+
+// State - keel state export, used to migrate keel to a fresh instance and
+// for disaster recovery
+type State struct {
+	Version     int       `json:"version"`
+	KeelVersion string    `json:"keelVersion"`
+	ExportedAt  time.Time `json:"exportedAt"`
+
+	Approvals       []*Approval         `json:"approvals"`
+	AuditLogs       []*AuditLog         `json:"auditLogs"`
+	PausedResources []*PausedResource   `json:"pausedResources"`
+	UpdateRecords   []*UpdateRecord     `json:"updateRecords"`
+	WebhookRecords  []*WebhookRecord    `json:"webhookRecords"`
+	APITokens       []*ExportedAPIToken `json:"apiTokens"`
+	PollDigests     []*PollDigest       `json:"pollDigests"`
+}
+
+// ExportedAPIToken - stored API token together with its hash, tokens keep
+// working after they are imported
+type ExportedAPIToken struct {
+	StoredAPIToken
+	Hash string `json:"hash"`
+}
+
+// StateImportResult - number of imported and skipped (already existing)
+// records of each kind
+type StateImportResult struct {
+	Imported map[string]int `json:"imported"`
+	Skipped  map[string]int `json:"skipped"`
+}
@@ -0,0 +1,33 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TrackedChart - Helm chart of a release that is watched for new versions
+type TrackedChart struct {
+	// Repository - chart repository URL, ie: https://charts.example.com or
+	// oci://registry.example.com/charts
+	Repository string `json:"repository"`
+	Name       string `json:"name"`
+	// Version - currently released chart version
+	Version   string `json:"version"`
+	Namespace string `json:"namespace"`
+	Release   string `json:"release"`
+	Policy    Policy `json:"policy"`
+}
+
+// Reference - chart reference that is used as event repository name
+func (c TrackedChart) Reference() string {
+	return ChartReference(c.Repository, c.Name)
+}
+
+func (c TrackedChart) String() string {
+	return fmt.Sprintf("namespace:%s,release:%s,chart:%s:%s", c.Namespace, c.Release, c.Reference(), c.Version)
+}
+
+// ChartReference - <chart repository>/<chart name>
+func ChartReference(repository, name string) string {
+	return strings.TrimSuffix(repository, "/") + "/" + name
+}
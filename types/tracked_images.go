@@ -25,6 +25,10 @@ type TrackedImage struct {
 	// combined semver tags
 	Tags   []string `json:"tags"`
 	Policy Policy   `json:"policy"`
+	// Paused - automatic updates are paused for the resource
+	Paused bool `json:"paused"`
+	// FreezeReason - set when the resource is paused by a change freeze
+	FreezeReason string `json:"freezeReason,omitempty"`
 }
 
 type Policy interface {
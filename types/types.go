@@ -21,6 +21,11 @@ const KeelDefaultPort = 9300
 // KeelPolicyLabel - keel update policies (version checking)
 const KeelPolicyLabel = "keel.sh/policy"
 
+// KeelPolicyContainerAnnotationPrefix - per container policy override, ie:
+// keel.sh/policy.sidecar: patch. Container names shouldn't clash with cluster
+// names of keel.sh/policy.<cluster> overrides.
+const KeelPolicyContainerAnnotationPrefix = KeelPolicyLabel + "."
+
 const KeelImagePullSecretAnnotation = "keel.sh/imagePullSecret"
 
 // KeelTriggerLabel - trigger label is used to specify custom trigger types
@@ -35,22 +40,223 @@ const KeelForceTagMatchLabel = "keel.sh/matchTag"
 // KeelPollScheduleAnnotation - optional variable to setup custom schedule for polling, defaults to @every 10m
 const KeelPollScheduleAnnotation = "keel.sh/pollSchedule"
 
+// KeelPollScheduleContainerAnnotationPrefix - per container poll schedule override,
+// ie: keel.sh/pollSchedule.redis: "@every 1h"
+const KeelPollScheduleContainerAnnotationPrefix = KeelPollScheduleAnnotation + "."
+
 // KeelPollDefaultSchedule - defaul polling schedule
 const KeelPollDefaultSchedule = "@every 1m"
 
 // KeelDigestAnnotation - digest annotation
 const KeelDigestAnnotation = "keel.sh/digest"
 
+// KeelDigestPinningAnnotation - set to true to pin updated images to the digest of the
+// new tag (name:tag@sha256:...) so re-pushed tags don't change running pods
+const KeelDigestPinningAnnotation = "keel.sh/digestPinning"
+
+// KeelPlatformAnnotation - optional platform (os/arch[/variant], ie: windows/amd64)
+// of multi-arch images that is pinned, by default it's taken from kubernetes.io/os
+// and kubernetes.io/arch node selectors
+const KeelPlatformAnnotation = "keel.sh/platform"
+
+// KeelVerifySignatureAnnotation - set to true to update only to images that have
+// a valid cosign signature from configured keys or identities
+const KeelVerifySignatureAnnotation = "keel.sh/verifySignature"
+
+// KeelVulnerabilitySeverityAnnotation - minimum severity (low, medium, high, critical)
+// of vulnerabilities that stop updates, enables vulnerability scan of new images
+const KeelVulnerabilitySeverityAnnotation = "keel.sh/vulnerabilitySeverity"
+
+// KeelVulnerabilityActionAnnotation - what happens when vulnerabilities are found,
+// block (default) or approval to require an approval of the update
+const KeelVulnerabilityActionAnnotation = "keel.sh/vulnerabilityAction"
+
+// vulnerability actions
+const (
+	KeelVulnerabilityActionBlock    = "block"
+	KeelVulnerabilityActionApproval = "approval"
+)
+
+// KeelVerifyProvenanceAnnotation - requires new images to have SLSA provenance
+// attestation from an allowed builder, block (or true) drops updates without it,
+// approval requires an approval of the update
+const KeelVerifyProvenanceAnnotation = "keel.sh/verifyProvenance"
+
+// provenance actions
+const (
+	KeelProvenanceActionBlock    = "block"
+	KeelProvenanceActionApproval = "approval"
+)
+
+// KeelPullPreflightAnnotation - set to true (or false) to check (or skip checking) that
+// updated images can be pulled with the resource image pull secrets before the
+// resource is updated, overrides PULL_PREFLIGHT
+const KeelPullPreflightAnnotation = "keel.sh/pullPreflight"
+
+// KeelDigestRestartAnnotation - set to true (or false) to restart (or not) pods when
+// digest of the tag changes but the tag stays the same, overrides DIGEST_RESTART
+const KeelDigestRestartAnnotation = "keel.sh/digestRestart"
+
+// KubectlRestartedAtAnnotation - pod template annotation kubectl rollout restart sets
+const KubectlRestartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// KeelManualChangeAnnotation - set by the admission webhook on workloads whose images
+// were changed manually (<user> <time>) while keel updates are paused for them
+const KeelManualChangeAnnotation = "keel.sh/manualChange"
+
+// KeelFreezeReasonAnnotation - optional reason of the change freeze shown in tracked
+// images and approvals, set on workloads or namespaces matching FREEZE_SELECTOR
+const KeelFreezeReasonAnnotation = "keel.sh/freezeReason"
+
+// KeelMinimumAgeAnnotation - optional minimum image age (ie: 2h), updates wait until
+// the new image has existed in the registry for the duration
+const KeelMinimumAgeAnnotation = "keel.sh/minimumAge"
+
+// KeelPromoteFromAnnotation - optional source environment of promotions, namespace
+// (ie: staging) or <cluster>/<namespace>. New tags are only offered once they have
+// run successfully there for the keel.sh/soakTime.
+const KeelPromoteFromAnnotation = "keel.sh/promoteFrom"
+
+// KeelSoakTimeAnnotation - how long new tags have to run in the promotion source
+// (ie: 24h), defaults to 24h
+const KeelSoakTimeAnnotation = "keel.sh/soakTime"
+
+// KeelUpdateWindowAnnotation - optional windows when updates can be applied, separated
+// by semicolons, ie: "Mon-Fri 09:00-17:00 Europe/London; Sat 00:00-06:00", updates
+// outside of them wait until the next window opens
+const KeelUpdateWindowAnnotation = "keel.sh/updateWindow"
+
+// KeelModeAnnotation - optional update mode, set to dry-run to evaluate policies and
+// approvals without updating the resource
+const KeelModeAnnotation = "keel.sh/mode"
+
+// KeelModeDryRun - resources in dry-run mode are not updated, notifications and
+// audit entries report updates that would have been applied
+const KeelModeDryRun = "dry-run"
+
 // KeelNotificationChanAnnotation - optional notification to override
-// default notification channel(-s) per deployment/chart
+// default notification channel(-s) per deployment/chart. Entries can be routed
+// to a sender with <sender>:<target>, ie: "slack:#team-payments,webhook:https://example.com/hook"
 const KeelNotificationChanAnnotation = "keel.sh/notify"
 
+// KeelNotificationModeAnnotation - optional, "replace" sends notifications of the
+// resource only to senders routed in keel.sh/notify, by default routed targets get
+// notifications in addition to the global channels
+const KeelNotificationModeAnnotation = "keel.sh/notifyMode"
+
+// NotificationModeReplace - routed targets replace global channels
+const NotificationModeReplace = "replace"
+
+// NotificationReplaceRoute - channel added by ParseEventNotificationChannels in
+// replace mode, notification sender removes it before routing
+const NotificationReplaceRoute = "keel:replace"
+
+// KeelApprovalsWorkspaceAnnotation - optional chat workspace that should receive
+// approval requests for the resource, when not set approvals are routed by namespace
+const KeelApprovalsWorkspaceAnnotation = "keel.sh/approvalsWorkspace"
+
+// KeelApprovalRemindersAnnotation - optional reminder intervals of pending approvals
+// measured from the request, ie: 1h,4h,12h. The first reminder doesn't mention anyone,
+// the second one mentions the approver group and later ones the on-call handle.
+// Set to "off" to disable reminders.
+const KeelApprovalRemindersAnnotation = "keel.sh/approvalReminders"
+
+// KeelApproversAnnotation - optional comma separated chat users (ie: Slack user names
+// or IDs) that receive approval requests of the resource as direct messages and can
+// vote in them
+const KeelApproversAnnotation = "keel.sh/approvers"
+
+// KeelApprovalsPrivateAnnotation - set to true to only send approval requests to
+// the approvers (keel.sh/approvers), votes are then only accepted in direct messages
+const KeelApprovalsPrivateAnnotation = "keel.sh/approvalsPrivate"
+
+// KeelOncallScheduleAnnotation - optional PagerDuty or Opsgenie schedule of the owning
+// team, users on call when approval is requested are mentioned in the request
+const KeelOncallScheduleAnnotation = "keel.sh/oncallSchedule"
+
+// KeelGithubRepositoryAnnotation - GitHub repository (owner/repo) the image is
+// built from, updates are reported as GitHub deployments of the new version ref
+const KeelGithubRepositoryAnnotation = "keel.sh/githubRepository"
+
+// KeelGithubEnvironmentAnnotation - GitHub deployment environment, defaults to
+// the namespace
+const KeelGithubEnvironmentAnnotation = "keel.sh/githubEnvironment"
+
+// KeelJiraIssueAnnotation - Jira issue (ie: OPS-123) updates of the resource are
+// commented on, when not set an issue is created for every update
+const KeelJiraIssueAnnotation = "keel.sh/jiraIssue"
+
 // KeelMinimumApprovalsLabel - min approvals
 const KeelMinimumApprovalsLabel = "keel.sh/approvals"
 
 // KeelUpdateTimeAnnotation - update time
 const KeelUpdateTimeAnnotation = "keel.sh/update-time"
 
+// KeelRolloutTimeoutAnnotation - optional duration (ie: 15m) keel waits for the
+// rollout of the updated resource before reporting it as failed
+const KeelRolloutTimeoutAnnotation = "keel.sh/rolloutTimeout"
+
+// KeelTrafficPercentAnnotation - optional percent of traffic (1-100) that is routed
+// to the updated Knative service revision, the rest stays with the previous revision
+const KeelTrafficPercentAnnotation = "keel.sh/trafficPercent"
+
+// KeelRollbackAnnotation - set to true to roll back to the previous version when the
+// updated resource doesn't roll out in time, rolled back version isn't updated to again
+const KeelRollbackAnnotation = "keel.sh/rollback"
+
+// KeelClustersAnnotation - optional comma separated list of clusters the resource is
+// updated in when keel manages several clusters, policy can also be set per cluster
+// with keel.sh/policy.<cluster>
+const KeelClustersAnnotation = "keel.sh/clusters"
+
+// KeelImagePathsAnnotation - comma separated fields (ie: .spec.image) that hold images
+// of custom resources, custom resource kinds have to be watched (CUSTOM_RESOURCES)
+const KeelImagePathsAnnotation = "keel.sh/image-paths"
+
+// KeelEnvImagesAnnotation - comma separated env vars (ie: JOB_IMAGE) of workload
+// containers that hold image references, they're tracked and updated together with
+// container images
+const KeelEnvImagesAnnotation = "keel.sh/envImages"
+
+// KeelConfigMapImagesAnnotation - comma separated <configmap>/<key> of ConfigMaps in
+// workload namespace whose values are image references, ie: operator-config/jobImage
+const KeelConfigMapImagesAnnotation = "keel.sh/configMapImages"
+
+// KeelConfigMapRestartAnnotation - restart workload pods when images of its
+// ConfigMaps are updated, pods that read ConfigMap only on start need it
+const KeelConfigMapRestartAnnotation = "keel.sh/configMapRestart"
+
+// KeelUpdateAfterAnnotation - optional comma separated resources (namespace/name) that are
+// updated and rolled out first when they are updated to the same image, ie: migrations
+const KeelUpdateAfterAnnotation = "keel.sh/update-after"
+
+// KeelCanaryReplicasAnnotation - optional number of canary replicas, when set keel
+// verifies new deployment version on canary replicas before updating the deployment
+const KeelCanaryReplicasAnnotation = "keel.sh/canaryReplicas"
+
+// KeelCanaryQueriesAnnotation - Prometheus queries (one per line) that have to return
+// a non empty result during canary analysis, ie: error ratio below the SLO
+const KeelCanaryQueriesAnnotation = "keel.sh/canaryQueries"
+
+// KeelCanaryAnalysisAnnotation - optional duration (ie: 10m) of canary analysis, defaults to 5m
+const KeelCanaryAnalysisAnnotation = "keel.sh/canaryAnalysis"
+
+// KeelCanaryIntervalAnnotation - optional interval (ie: 30s) of canary checks, defaults to 1m
+const KeelCanaryIntervalAnnotation = "keel.sh/canaryInterval"
+
+// KeelBlueGreenServiceAnnotation - optional Service of the deployment, when set keel
+// deploys new versions as a clone (blue or green) and switches the Service selector
+// to it once it's ready, the previous color is kept for fast rollback
+const KeelBlueGreenServiceAnnotation = "keel.sh/blueGreenService"
+
+// KeelBlueGreenSmokeTestAnnotation - optional URL that is called (POST) once the new
+// color is ready, traffic is only switched when it responds with 2xx
+const KeelBlueGreenSmokeTestAnnotation = "keel.sh/blueGreenSmokeTest"
+
+// KeelBlueGreenVerifyAnnotation - optional duration (ie: 2m) the new color has to stay
+// ready after the switch, traffic is switched back otherwise. Defaults to 1m.
+const KeelBlueGreenVerifyAnnotation = "keel.sh/blueGreenVerify"
+
 // KeelApprovalDeadlineLabel - approval deadline
 const KeelApprovalDeadlineLabel = "keel.sh/approvalDeadline"
 
@@ -89,6 +295,18 @@ type Event struct {
 	CreatedAt  time.Time  `json:"createdAt,omitempty"`
 	// optional field to identify trigger
 	TriggerName string `json:"triggerName,omitempty"`
+	// optional field to limit event to a single workload (<namespace>/<name>),
+	// targeted events are created manually (ie: deploy bot command) and
+	// skip update policy checks
+	Target string `json:"target,omitempty"`
+	// Chart - event is a new Helm chart version, Repository name is then
+	// chart reference (<chart repository>/<chart name>) and tag chart version
+	Chart bool `json:"chart,omitempty"`
+	// TraceParent - W3C trace context of the event, set when tracing is enabled
+	TraceParent string `json:"traceParent,omitempty"`
+	// Deleted - tag was deleted from the registry, the event is never an update.
+	// Digest is set when the registry reports digest of the deleted tag.
+	Deleted bool `json:"deleted,omitempty"`
 }
 
 func (e *Event) Value() (driver.Value, error) {
@@ -203,6 +421,9 @@ func ParseEventNotificationChannels(annotations map[string]string) []string {
 			channels = append(channels, strings.TrimSpace(c))
 		}
 	}
+	if ok && annotations[KeelNotificationModeAnnotation] == NotificationModeReplace {
+		channels = append(channels, NotificationReplaceRoute)
+	}
 
 	return channels
 }
@@ -235,6 +456,40 @@ const (
 
 	NotificationUpdateApproved
 	NotificationUpdateRejected
+
+	NotificationRepositoryDiscovered
+
+	// NotificationDryRunUpdate - update that would have been applied, sent for
+	// resources in dry-run mode
+	NotificationDryRunUpdate
+
+	// NotificationSignatureVerification - result of image signature verification
+	NotificationSignatureVerification
+
+	// NotificationVulnerabilityScan - vulnerabilities found in the new image
+	NotificationVulnerabilityScan
+
+	// NotificationDigest - summary of buffered low priority notifications
+	NotificationDigest
+
+	// NotificationUpdateQueued - update exceeded rate limit budget, it's
+	// applied once the budget allows
+	NotificationUpdateQueued
+
+	// NotificationRolloutProgress - periodic rollout status of an approved
+	// update, reported back to the approval request
+	NotificationRolloutProgress
+
+	// NotificationProvenanceVerification - provenance attestation of the new image
+	// was verified or is missing
+	NotificationProvenanceVerification
+
+	// NotificationTagDeleted - tag of a deployed image was deleted from the registry
+	NotificationTagDeleted
+
+	// NotificationHygieneReport - scheduled report of deployed images drifting
+	// from registry contents
+	NotificationHygieneReport
 )
 
 func (n Notification) String() string {
@@ -257,6 +512,26 @@ func (n Notification) String() string {
 		return "update approved"
 	case NotificationUpdateRejected:
 		return "update rejected "
+	case NotificationRepositoryDiscovered:
+		return "repository discovered"
+	case NotificationDryRunUpdate:
+		return "dry-run update"
+	case NotificationSignatureVerification:
+		return "signature verification"
+	case NotificationVulnerabilityScan:
+		return "vulnerability scan"
+	case NotificationDigest:
+		return "notification digest"
+	case NotificationUpdateQueued:
+		return "update queued"
+	case NotificationRolloutProgress:
+		return "rollout progress"
+	case NotificationProvenanceVerification:
+		return "provenance verification"
+	case NotificationTagDeleted:
+		return "tag deleted"
+	case NotificationHygieneReport:
+		return "hygiene report"
 	default:
 		return "unknown"
 	}
@@ -342,6 +617,8 @@ const (
 	ProviderTypeUnknown ProviderType = iota
 	ProviderTypeKubernetes
 	ProviderTypeHelm
+	ProviderTypeGitOps
+	ProviderTypeKustomize
 )
 
 func (t ProviderType) String() string {
@@ -352,6 +629,10 @@ func (t ProviderType) String() string {
 		return "kubernetes"
 	case ProviderTypeHelm:
 		return "helm"
+	case ProviderTypeGitOps:
+		return "gitops"
+	case ProviderTypeKustomize:
+		return "kustomize"
 	default:
 		return ""
 	}
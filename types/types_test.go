@@ -79,6 +79,34 @@ func TestNotExpired(t *testing.T) {
 	}
 }
 
+func TestGetApprovers(t *testing.T) {
+	a := &Approval{Approvers: "@karolis, U024BE7LH,,"}
+	if got := a.GetApprovers(); !reflect.DeepEqual(got, []string{"karolis", "U024BE7LH"}) {
+		t.Errorf("unexpected approvers: %v", got)
+	}
+}
+
+func TestApprovalCopy(t *testing.T) {
+	a := &Approval{
+		Identifier: "default/wd",
+		Event:      &Event{TriggerName: "poll"},
+		Voters:     JSONB{"karolis": true},
+	}
+	cp := a.Copy()
+	if !reflect.DeepEqual(cp, a) {
+		t.Fatalf("unexpected copy: %+v", cp)
+	}
+
+	cp.Event.TriggerName = "approval"
+	cp.AddVoter("other")
+	if a.Event.TriggerName != "poll" {
+		t.Errorf("event of the original approval changed: %s", a.Event.TriggerName)
+	}
+	if len(a.Voters) != 1 {
+		t.Errorf("voters of the original approval changed: %v", a.Voters)
+	}
+}
+
 func TestParseEventNotificationChannels(t *testing.T) {
 	type args struct {
 		annotations map[string]string
@@ -0,0 +1,36 @@
+package types
+
+import (
+	"time"
+)
+
+// WebhookRecord - received trigger webhook, kept for debugging and replays
+type WebhookRecord struct {
+	ID        string    `json:"id" gorm:"primary_key;type:varchar(36)"`
+	CreatedAt time.Time `json:"createdAt" gorm:"index"`
+
+	// Source - webhook type, ie: dockerhub, native, custom/gitea
+	Source string `json:"source" gorm:"index"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Headers - subset of request headers, credentials are never stored
+	Headers JSONB  `json:"headers" gorm:"type:json"`
+	Payload string `json:"payload" gorm:"type:text"`
+	// Truncated - payload was too large to be stored, record can't be replayed
+	Truncated bool `json:"truncated"`
+
+	// StatusCode and Response - result of the webhook processing
+	StatusCode int    `json:"statusCode"`
+	Response   string `json:"response"`
+
+	// ReplayOf - ID of the record that was replayed
+	ReplayOf string `json:"replayOf,omitempty"`
+}
+
+// WebhookRecordQuery - received webhooks query, newest records first
+type WebhookRecordQuery struct {
+	Source string
+
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,104 @@
+// Package awsauth creates AWS sessions, in addition to the default credential
+// chain it supports IAM roles for service accounts (IRSA) through web identity tokens.
+package awsauth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// IRSA environment variables, set by EKS pod identity webhook
+const (
+	EnvRoleARN              = "AWS_ROLE_ARN"
+	EnvWebIdentityTokenFile = "AWS_WEB_IDENTITY_TOKEN_FILE"
+	EnvRoleSessionName      = "AWS_ROLE_SESSION_NAME"
+)
+
+// WebIdentityProviderName - provider name of the web identity credentials
+const WebIdentityProviderName = "WebIdentityCredentials"
+
+// NewSession - creates AWS session for the region, when IRSA environment variables
+// are set credentials are retrieved by assuming the role with web identity token
+func NewSession(region string) (*session.Session, error) {
+	cfg := aws.NewConfig()
+	if region != "" {
+		cfg = cfg.WithRegion(region)
+	}
+
+	roleARN := os.Getenv(EnvRoleARN)
+	tokenFile := os.Getenv(EnvWebIdentityTokenFile)
+	if roleARN == "" || tokenFile == "" {
+		return session.NewSession(cfg)
+	}
+
+	// assuming role with web identity doesn't require credentials
+	stsSess, err := session.NewSession(cfg.Copy().WithCredentials(credentials.AnonymousCredentials))
+	if err != nil {
+		return nil, err
+	}
+
+	sessionName := os.Getenv(EnvRoleSessionName)
+	if sessionName == "" {
+		sessionName = "keel-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+
+	provider := &WebIdentityProvider{
+		client:      sts.New(stsSess),
+		RoleARN:     roleARN,
+		TokenFile:   tokenFile,
+		SessionName: sessionName,
+	}
+
+	return session.NewSession(cfg.Copy().WithCredentials(credentials.NewCredentials(provider)))
+}
+
+type assumeRoleWithWebIdentity interface {
+	AssumeRoleWithWebIdentity(input *sts.AssumeRoleWithWebIdentityInput) (*sts.AssumeRoleWithWebIdentityOutput, error)
+}
+
+// WebIdentityProvider - retrieves credentials by assuming role with a web
+// identity token (service account token projected by EKS)
+type WebIdentityProvider struct {
+	credentials.Expiry
+
+	client assumeRoleWithWebIdentity
+
+	RoleARN     string
+	TokenFile   string
+	SessionName string
+}
+
+// Retrieve - assumes role, token file is read on every call since it's rotated by kubelet
+func (p *WebIdentityProvider) Retrieve() (credentials.Value, error) {
+	token, err := ioutil.ReadFile(p.TokenFile)
+	if err != nil {
+		return credentials.Value{ProviderName: WebIdentityProviderName}, fmt.Errorf("failed to read web identity token: %s", err)
+	}
+
+	out, err := p.client.AssumeRoleWithWebIdentity(&sts.AssumeRoleWithWebIdentityInput{
+		RoleArn:          aws.String(p.RoleARN),
+		RoleSessionName:  aws.String(p.SessionName),
+		WebIdentityToken: aws.String(string(token)),
+	})
+	if err != nil {
+		return credentials.Value{ProviderName: WebIdentityProviderName}, err
+	}
+
+	// refreshing credentials a bit earlier than they expire
+	p.SetExpiration(aws.TimeValue(out.Credentials.Expiration), time.Minute)
+
+	return credentials.Value{
+		AccessKeyID:     aws.StringValue(out.Credentials.AccessKeyId),
+		SecretAccessKey: aws.StringValue(out.Credentials.SecretAccessKey),
+		SessionToken:    aws.StringValue(out.Credentials.SessionToken),
+		ProviderName:    WebIdentityProviderName,
+	}, nil
+}
@@ -0,0 +1,214 @@
+package chartrepo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/registry"
+)
+
+const testIndex = `apiVersion: v1
+entries:
+  mychart:
+  - name: mychart
+    version: 1.1.0
+    urls:
+    - charts/mychart-1.1.0.tgz
+  - name: mychart
+    version: 1.0.0
+    urls:
+    - https://downloads.example.com/mychart-1.0.0.tgz
+`
+
+func TestVersionsAndDownload(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stable/index.yaml":
+			fmt.Fprint(w, testIndex)
+		case "/stable/charts/mychart-1.1.0.tgz":
+			fmt.Fprint(w, "archive")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client := New(nil)
+
+	versions, err := client.Versions(ts.URL+"/stable/", "mychart")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	sort.Strings(versions)
+	if len(versions) != 2 || versions[0] != "1.0.0" || versions[1] != "1.1.0" {
+		t.Errorf("unexpected versions: %v", versions)
+	}
+
+	_, err = client.Versions(ts.URL+"/stable", "other")
+	if err == nil {
+		t.Errorf("expected error for missing chart")
+	}
+
+	archive, err := client.Download(ts.URL+"/stable", "mychart", "1.1.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(archive) != "archive" {
+		t.Errorf("unexpected archive: %s", archive)
+	}
+
+	_, err = client.Download(ts.URL+"/stable", "mychart", "2.0.0")
+	if err == nil {
+		t.Errorf("expected error for missing version")
+	}
+}
+
+type fakeOCIClient struct {
+	opts registry.Opts
+	blob string
+}
+
+func (c *fakeOCIClient) Get(opts registry.Opts) (*registry.Repository, error) {
+	c.opts = opts
+	return &registry.Repository{Tags: []string{"1.0.0", "1.1.0_build.1"}}, nil
+}
+
+func (c *fakeOCIClient) Manifest(opts registry.Opts) (*registry.Manifest, error) {
+	c.opts = opts
+	return &registry.Manifest{
+		Digest:    "sha256:manifest",
+		MediaType: registry.MediaTypeOCIManifest,
+		Layers: []registry.Descriptor{
+			{MediaType: "application/vnd.cncf.helm.config.v1+json", Digest: "sha256:config"},
+			{MediaType: MediaTypeChartContent, Digest: "sha256:chart"},
+		},
+	}, nil
+}
+
+func (c *fakeOCIClient) Blob(opts registry.Opts, digest string) ([]byte, error) {
+	c.blob = digest
+	return []byte("archive"), nil
+}
+
+func TestOCI(t *testing.T) {
+	oci := &fakeOCIClient{}
+	client := New(oci)
+
+	versions, err := client.Versions("oci://registry.example.com/charts", "mychart")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if oci.opts.Registry != "https://registry.example.com" || oci.opts.Name != "charts/mychart" {
+		t.Errorf("unexpected opts: %+v", oci.opts)
+	}
+	if len(versions) != 2 || versions[1] != "1.1.0+build.1" {
+		t.Errorf("unexpected versions: %v", versions)
+	}
+
+	archive, err := client.Download("oci://registry.example.com/charts", "mychart", "1.1.0+build.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if oci.opts.Tag != "1.1.0_build.1" {
+		t.Errorf("unexpected tag: %s", oci.opts.Tag)
+	}
+	if oci.blob != "sha256:chart" || string(archive) != "archive" {
+		t.Errorf("unexpected blob: %s", oci.blob)
+	}
+}
+
+func TestOCIPullByDigest(t *testing.T) {
+	oci := &fakeOCIClient{}
+	client := New(oci)
+	client.Login("oci://registry.example.com", "user", "pass")
+
+	digest, err := client.Digest("oci://registry.example.com/charts", "mychart", "1.1.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if digest != "sha256:manifest" {
+		t.Errorf("unexpected digest: %s", digest)
+	}
+	if oci.opts.Username != "user" || oci.opts.Password != "pass" {
+		t.Errorf("expected login credentials to be used, got: %+v", oci.opts)
+	}
+
+	chart, err := client.Pull("oci://registry.example.com/charts", "mychart", "1.1.0", digest)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if oci.opts.Tag != "sha256:manifest" {
+		t.Errorf("expected manifest to be pulled by digest, got: %s", oci.opts.Tag)
+	}
+	if chart.Digest != digest || string(chart.Archive) != "archive" {
+		t.Errorf("unexpected chart: %+v", chart)
+	}
+
+	_, err = client.Pull("oci://registry.example.com/charts", "mychart", "1.1.0", "sha256:other")
+	if err == nil {
+		t.Errorf("expected error for digest mismatch")
+	}
+}
+
+func TestPullByDigest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.yaml":
+			fmt.Fprintf(w, "apiVersion: v1\nentries:\n  mychart:\n  - name: mychart\n    version: 1.1.0\n    digest: %s\n    urls:\n    - mychart-1.1.0.tgz\n", strings.TrimPrefix(sha256Digest([]byte("archive")), "sha256:"))
+		case "/mychart-1.1.0.tgz":
+			fmt.Fprint(w, "archive")
+		case "/mychart-1.1.0.tgz.prov":
+			fmt.Fprint(w, "provenance")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client := New(nil)
+
+	digest, err := client.Digest(ts.URL, "mychart", "1.1.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	chart, err := client.Pull(ts.URL, "mychart", "1.1.0", digest)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(chart.Archive) != "archive" || string(chart.Provenance) != "provenance" {
+		t.Errorf("unexpected chart: %+v", chart)
+	}
+
+	_, err = client.Pull(ts.URL, "mychart", "1.1.0", "sha256:other")
+	if err == nil {
+		t.Errorf("expected error for digest mismatch")
+	}
+}
+
+func TestLoadRegistryConfig(t *testing.T) {
+	f, err := ioutil.TempFile("", "registry-config")
+	if err != nil {
+		t.Fatalf("failed to create file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	fmt.Fprint(f, `{"auths":{"registry.example.com":{"auth":"dXNlcjpwYXNz"},"https://other.example.com":{"username":"u","password":"p"}}}`)
+	f.Close()
+
+	client := New(nil)
+	err = client.LoadRegistryConfig(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if c := client.credentials["registry.example.com"]; c == nil || c.Username != "user" || c.Password != "pass" {
+		t.Errorf("unexpected credentials: %+v", c)
+	}
+	if c := client.credentials["other.example.com"]; c == nil || c.Username != "u" {
+		t.Errorf("unexpected credentials: %+v", c)
+	}
+}
@@ -0,0 +1,68 @@
+package chartrepo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/keel-hq/keel/types"
+)
+
+// registryConfig - registry config.json written by 'helm registry login' (same
+// format as docker config.json)
+type registryConfig struct {
+	Auths map[string]struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"auths"`
+}
+
+// Login - sets credentials used for OCI registry host (ie: registry.example.com),
+// they take precedence over credentials helpers
+func (c *Client) Login(host, username, password string) {
+	c.credentials[registryHost(host)] = &types.Credentials{
+		Username: username,
+		Password: password,
+	}
+}
+
+// LoadRegistryConfig - logs in to all registries from the registry config
+func (c *Client) LoadRegistryConfig(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var cfg registryConfig
+	err = json.Unmarshal(data, &cfg)
+	if err != nil {
+		return fmt.Errorf("failed to decode registry config: %s", err)
+	}
+
+	for host, auth := range cfg.Auths {
+		username, password := auth.Username, auth.Password
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return fmt.Errorf("failed to decode auth of %s: %s", host, err)
+			}
+			parts := strings.SplitN(string(decoded), ":", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("unexpected auth format of %s", host)
+			}
+			username, password = parts[0], parts[1]
+		}
+		c.Login(host, username, password)
+	}
+	return nil
+}
+
+func registryHost(host string) string {
+	host = strings.TrimPrefix(host, OCIScheme)
+	host = strings.TrimPrefix(host, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	return strings.SplitN(host, "/", 2)[0]
+}
@@ -0,0 +1,118 @@
+package chartrepo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// clearsigned provenance file markers
+const (
+	pgpMessageHeader   = "-----BEGIN PGP SIGNED MESSAGE-----"
+	pgpSignatureHeader = "-----BEGIN PGP SIGNATURE-----"
+)
+
+// provenance - signed part of the provenance file: chart metadata and archive
+// digests separated by a YAML document end marker
+type provenance struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Files - map[archive name]digest
+	Files map[string]string `json:"files"`
+}
+
+// SetKeyring - enables provenance signature verification, keyring is a public
+// keyring (ie: exported with 'gpg --export') and gpgv has to be installed
+func (c *Client) SetKeyring(path string) {
+	c.keyring = path
+}
+
+// Verify - checks that the chart has provenance file that matches chart name,
+// version and archive digest. Signature is verified when keyring is set.
+func (c *Client) Verify(chart *Chart, name, version string) error {
+	if len(chart.Provenance) == 0 {
+		return fmt.Errorf("chart %s-%s doesn't have provenance file", name, version)
+	}
+
+	prov, err := parseProvenance(chart.Provenance)
+	if err != nil {
+		return err
+	}
+	if prov.Name != name || prov.Version != version {
+		return fmt.Errorf("provenance file is for chart %s-%s, expected %s-%s", prov.Name, prov.Version, name, version)
+	}
+
+	digest := sha256Digest(chart.Archive)
+	found := false
+	for _, d := range prov.Files {
+		if d == digest {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("chart %s-%s archive digest %s doesn't match provenance file", name, version, digest)
+	}
+
+	if c.keyring == "" {
+		return nil
+	}
+	return verifySignature(c.keyring, chart.Provenance)
+}
+
+func parseProvenance(data []byte) (*provenance, error) {
+	content := strings.Replace(string(data), "\r\n", "\n", -1)
+
+	start := strings.Index(content, pgpMessageHeader)
+	end := strings.Index(content, pgpSignatureHeader)
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("provenance file isn't clearsigned")
+	}
+	// armor headers (Hash: SHA512) are followed by an empty line
+	message := content[start+len(pgpMessageHeader) : end]
+	idx := strings.Index(message, "\n\n")
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid provenance file")
+	}
+	message = message[idx+2:]
+
+	var prov provenance
+	for _, doc := range strings.Split(message, "\n...\n") {
+		var part provenance
+		err := yaml.Unmarshal([]byte(doc), &part)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode provenance file: %s", err)
+		}
+		if part.Name != "" {
+			prov.Name, prov.Version = part.Name, part.Version
+		}
+		if part.Files != nil {
+			prov.Files = part.Files
+		}
+	}
+	return &prov, nil
+}
+
+func verifySignature(keyring string, data []byte) error {
+	f, err := ioutil.TempFile("", "keel-chart-prov")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	out, err := exec.Command("gpgv", "--keyring", keyring, f.Name()).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("provenance signature verification failed: %s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package chartrepo
+
+import (
+	"fmt"
+	"testing"
+)
+
+const testProvenance = `-----BEGIN PGP SIGNED MESSAGE-----
+Hash: SHA512
+
+apiVersion: v1
+description: A Helm chart for Kubernetes
+name: mychart
+version: 1.1.0
+
+...
+files:
+  mychart-1.1.0.tgz: %s
+-----BEGIN PGP SIGNATURE-----
+
+wsBcBAEBCgAQBQJcRTapCRCEO7+YH8GHYgAAfhUIADx3pHHLLINv0MFkiEYpX/Kd
+=rIuD
+-----END PGP SIGNATURE-----
+`
+
+func TestVerify(t *testing.T) {
+	archive := []byte("archive")
+	client := New(nil)
+
+	chart := &Chart{Archive: archive, Provenance: []byte(fmt.Sprintf(testProvenance, sha256Digest(archive)))}
+	err := client.Verify(chart, "mychart", "1.1.0")
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	err = client.Verify(chart, "mychart", "1.2.0")
+	if err == nil {
+		t.Errorf("expected error for version mismatch")
+	}
+
+	tampered := &Chart{Archive: []byte("other"), Provenance: chart.Provenance}
+	err = client.Verify(tampered, "mychart", "1.1.0")
+	if err == nil {
+		t.Errorf("expected error for archive digest mismatch")
+	}
+
+	err = client.Verify(&Chart{Archive: archive}, "mychart", "1.1.0")
+	if err == nil {
+		t.Errorf("expected error for unsigned chart")
+	}
+}
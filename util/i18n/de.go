@@ -0,0 +1,154 @@
+package i18n
+
+func init() {
+	Register("de", de)
+}
+
+// de - German translations
+var de = map[string]string{
+	// help and command descriptions
+	"Here's a list of supported commands": "Folgende Befehle werden unterstützt",
+	"approve update request":              "Update-Anfrage genehmigen",
+	"reject update request":               "Update-Anfrage ablehnen",
+	"remove approval":                     "Genehmigung entfernen",
+	"deploy specific tag, bypasses update policy but still requires approvals": "bestimmten Tag ausrollen, umgeht die Update-Richtlinie, erfordert aber weiterhin Genehmigungen",
+	"get a list of all deployments":                                            "Liste aller Deployments anzeigen",
+	"get a list of approvals":                                                  "Liste der Genehmigungen anzeigen",
+	"get a list of supported commands":                                         "Liste der unterstützten Befehle anzeigen",
+	"get a list of tracked images":                                             "Liste der überwachten Images anzeigen",
+	"get recent updates of an image or a workload":                             "letzte Updates eines Images oder Workloads anzeigen",
+	"pause automatic updates for a workload":                                   "automatische Updates für einen Workload pausieren",
+	"enable maintenance mode, suspends automatic updates of all workloads":     "Wartungsmodus aktivieren, setzt automatische Updates aller Workloads aus",
+	"disable maintenance mode":                                                 "Wartungsmodus deaktivieren",
+	"resume automatic updates for a workload":                                  "automatische Updates für einen Workload fortsetzen",
+
+	// command parsing
+	"unknown command '%s'":                 "unbekannter Befehl '%s'",
+	"missing argument '%s', usage: %s":     "fehlendes Argument '%s', Verwendung: %s",
+	"unexpected arguments '%s', usage: %s": "unerwartete Argumente '%s', Verwendung: %s",
+
+	// approvals
+	"Approval required":  "Genehmigung erforderlich",
+	"Approval required!": "Genehmigung erforderlich!",
+	"To vote for change type '%s approve %s' to reject it: '%s reject %s'.": "Um der Änderung zuzustimmen, schreibe '%s approve %s', um sie abzulehnen: '%s reject %s'.",
+	"Vote received":                              "Stimme erhalten",
+	"vote received!":                             "Stimme erhalten!",
+	"Waiting for remaining votes.":               "Warte auf die restlichen Stimmen.",
+	"Change rejected":                            "Änderung abgelehnt",
+	"change rejected":                            "Änderung abgelehnt",
+	"Change was rejected":                        "Die Änderung wurde abgelehnt",
+	"Change was rejected.":                       "Die Änderung wurde abgelehnt.",
+	"approval received":                          "Genehmigung erhalten",
+	"update approved!":                           "Update genehmigt!",
+	"All approvals received, thanks for voting!": "Alle Genehmigungen erhalten, danke für die Abstimmung!",
+	"Votes":      "Stimmen",
+	"Delta":      "Delta",
+	"Identifier": "Kennung",
+	"Provider":   "Provider",
+	"Status":     "Status",
+	"Deadline":   "Frist",
+	"Error":      "Fehler",
+	"New image is available for resource %s/%s (%s).":               "Ein neues Image ist für die Ressource %s/%s verfügbar (%s).",
+	"New image is available for resource %s/%s in cluster %s (%s).": "Ein neues Image ist für die Ressource %s/%s im Cluster %s verfügbar (%s).",
+	"Image digest: %s.":                                      "Image-Digest: %s.",
+	"Signature verified, signed by %s.":                      "Signatur verifiziert, signiert von %s.",
+	"Vulnerabilities found: %s.":                             "Gefundene Schwachstellen: %s.",
+	"Provenance: %s.":                                        "Herkunft: %s.",
+	"Provenance verification failed: %s.":                    "Herkunftsprüfung fehlgeschlagen: %s.",
+	"Soaked %s in %s.":                                       "%s in %s bewährt.",
+	"Release notes:":                                         "Versionshinweise:",
+	"New image is available for release %s/%s (%s).":         "Ein neues Image ist für das Release %s/%s verfügbar (%s).",
+	"New image is available for repository %s (%s).":         "Ein neues Image ist für das Repository %s verfügbar (%s).",
+	"New image is available for kustomization %s (%s).":      "Ein neues Image ist für die Kustomization %s verfügbar (%s).",
+	"Approvals waiting for votes: %d":                        "Genehmigungen, die auf Stimmen warten: %d",
+	"page %d/%d":                                             "Seite %d/%d",
+	", use 'get approvals page=%d' to see more":              ", mit 'get approvals page=%d' werden weitere angezeigt",
+	"invalid page '%s', expected a positive number":          "ungültige Seite '%s', erwartet wird eine positive Zahl",
+	"there are currently no request waiting to be approved.": "derzeit warten keine Anfragen auf Genehmigung.",
+	"got error while fetching approvals: %s":                 "Fehler beim Abrufen der Genehmigungen: %s",
+	"got error while formatting approvals: %s":               "Fehler beim Formatieren der Genehmigungen: %s",
+	"approval with identifier '%s' was not found":            "Genehmigung mit der Kennung '%s' wurde nicht gefunden",
+	"failed to remove '%s' approval: %s.":                    "Genehmigung '%s' konnte nicht entfernt werden: %s.",
+	"approval '%s' removed.":                                 "Genehmigung '%s' entfernt.",
+	"please use approvals channel '%s'":                      "bitte verwende den Genehmigungskanal '%s'",
+
+	// deployments and tracked images
+	"got error while fetching deployments: %s":      "Fehler beim Abrufen der Deployments: %s",
+	" got error while formatting deployments: %s":   " Fehler beim Formatieren der Deployments: %s",
+	"tracked images are not available":              "überwachte Images sind nicht verfügbar",
+	"there are currently no tracked images.":        "derzeit werden keine Images überwacht.",
+	"got error while fetching tracked images: %s":   "Fehler beim Abrufen der überwachten Images: %s",
+	"got error while formatting tracked images: %s": "Fehler beim Formatieren der überwachten Images: %s",
+
+	// pause/resume
+	"pausing updates is not available":                                         "das Pausieren von Updates ist nicht verfügbar",
+	"invalid workload '%s', expected format: <namespace>/<name>":               "ungültiger Workload '%s', erwartetes Format: <namespace>/<name>",
+	"updates for '%s' are already paused.":                                     "Updates für '%s' sind bereits pausiert.",
+	"updates for '%s' are not paused.":                                         "Updates für '%s' sind nicht pausiert.",
+	"failed to pause '%s': %s":                                                 "'%s' konnte nicht pausiert werden: %s",
+	"failed to resume '%s': %s":                                                "'%s' konnte nicht fortgesetzt werden: %s",
+	"automatic updates for '%s' paused, use 'resume %s' to enable them again.": "automatische Updates für '%s' pausiert, mit 'resume %s' werden sie wieder aktiviert.",
+	"automatic updates for '%s' resumed.":                                      "automatische Updates für '%s' fortgesetzt.",
+
+	// maintenance mode
+	"failed to change maintenance mode: %s": "Wartungsmodus konnte nicht geändert werden: %s",
+	"maintenance mode is already enabled.":  "der Wartungsmodus ist bereits aktiviert.",
+	"maintenance mode is not enabled.":      "der Wartungsmodus ist nicht aktiviert.",
+	"maintenance mode enabled, automatic updates of all workloads are suspended. Use 'resume all' to enable them again.": "Wartungsmodus aktiviert, automatische Updates aller Workloads sind ausgesetzt. Mit 'resume all' werden sie wieder aktiviert.",
+	"maintenance mode disabled, automatic updates resumed.":                                                              "Wartungsmodus deaktiviert, automatische Updates fortgesetzt.",
+
+	// deploy
+	"deploy is not available":                                                        "deploy ist nicht verfügbar",
+	"failed to deploy '%s': %s":                                                      "'%s' konnte nicht ausgerollt werden: %s",
+	"deploying %s: %s %s -> %s (approvals still apply)":                              "rolle %s aus: %s %s -> %s (Genehmigungen gelten weiterhin)",
+	"workload '%s/%s' not found or is not tracked by keel":                           "Workload '%s/%s' wurde nicht gefunden oder wird von keel nicht überwacht",
+	"workload '%s/%s' has multiple images, specify one of: %s":                       "Workload '%s/%s' hat mehrere Images, gib eines davon an: %s",
+	"invalid image '%s': %s":                                                         "ungültiges Image '%s': %s",
+	"image '%s' not found in workload '%s/%s'":                                       "Image '%s' wurde im Workload '%s/%s' nicht gefunden",
+	"failed to check rollout status of %s/%s: %s":                                    "Rollout-Status von %s/%s konnte nicht geprüft werden: %s",
+	"%s/%s rolled out %s:%s, %s":                                                     "%s/%s hat %s:%s ausgerollt, %s",
+	"%s/%s rollout of %s:%s is not complete yet (it might be waiting for approvals)": "%s/%s: Rollout von %s:%s ist noch nicht abgeschlossen (wartet möglicherweise auf Genehmigungen)",
+
+	// history
+	"update history is not available":                                             "der Update-Verlauf ist nicht verfügbar",
+	"invalid limit '%s', expected a positive number":                              "ungültiges Limit '%s', erwartet wird eine positive Zahl",
+	"got error while fetching update history: %s":                                 "Fehler beim Abrufen des Update-Verlaufs: %s",
+	"got error while formatting update history: %s":                               "Fehler beim Formatieren des Update-Verlaufs: %s",
+	"no updates found for '%s'.":                                                  "keine Updates für '%s' gefunden.",
+	"Reminder: approval %s (%s) is still waiting for votes (%d/%d), deadline %s.": "Erinnerung: Genehmigung %s (%s) wartet noch auf Stimmen (%d/%d), Frist %s.",
+	"Approval reminder":                                                           "Erinnerung an Genehmigung",
+	"Vote received, waiting for remaining votes (%d/%d).":                         "Stimme erhalten, warte auf weitere Stimmen (%d/%d).",
+	"Update approved (%d/%d), thanks for voting!":                                 "Update genehmigt (%d/%d), danke fürs Abstimmen!",
+	"Approval expired without enough votes (%d/%d).":                              "Genehmigung ist ohne genügend Stimmen abgelaufen (%d/%d).",
+	"Aliases: %s": "Aliase: %s",
+	"votes for private approvals are only accepted in direct messages from the approvers": "Stimmen für private Genehmigungen werden nur in Direktnachrichten der Genehmiger angenommen",
+	"On call: %s": "Bereitschaft: %s",
+	"show what updating workload to the tag would change": "zeigt, was sich durch die Aktualisierung des Workloads auf den Tag ändern würde",
+	"diff is not available":                               "diff ist nicht verfügbar",
+	"invalid tag '%s': %s":                                "ungültiger Tag '%s': %s",
+	"Current: %s":                                         "Aktuell: %s",
+	"Candidate: %s":                                       "Kandidat: %s",
+	"Tag":                                                 "Tag",
+	"Diff of %s: %s %s -> %s":                             "Diff von %s: %s %s -> %s",
+	"Digest":                                              "Digest",
+	"not available: %s":                                   "nicht verfügbar: %s",
+	"Labels":                                              "Labels",
+	"Created":                                             "Erstellt",
+	"Git SHA":                                             "Git-SHA",
+	"Source":                                              "Quelle",
+	"Build URL":                                           "Build-URL",
+	"Workload has no update policy, keel wouldn't update it automatically.":                         "Der Workload hat keine Update-Richtlinie, keel würde ihn nicht automatisch aktualisieren.",
+	"Policy %s can't compare tags: %s":                                                              "Richtlinie %s kann die Tags nicht vergleichen: %s",
+	"Policy %s allows the update.":                                                                  "Richtlinie %s erlaubt die Aktualisierung.",
+	"Policy %s wouldn't update to the candidate, it can still be deployed with the deploy command.": "Richtlinie %s würde nicht auf den Kandidaten aktualisieren, er kann trotzdem mit dem deploy-Befehl ausgerollt werden.",
+	"Updates are frozen: %s.":                                                                       "Aktualisierungen sind eingefroren: %s.",
+	"Automatic updates are paused.":                                                                 "Automatische Aktualisierungen sind pausiert.",
+	"Changes":                                                                                       "Änderungen",
+	"Changes of %s":                                                                                 "Änderungen von %s",
+	"... %d more lines":                                                                             "... %d weitere Zeilen",
+	"This is a test message from keel, bots and notifications are set up.":                          "Dies ist eine Testnachricht von keel, Bots und Benachrichtigungen sind eingerichtet.",
+	"no bots or notification senders are configured.":                                               "es sind keine Bots oder Benachrichtigungen konfiguriert.",
+	"Test messages":                                                                                 "Testnachrichten",
+	"delivered":                                                                                     "zugestellt",
+	"failed":                                                                                        "fehlgeschlagen",
+}
@@ -0,0 +1,105 @@
+// Package i18n provides message catalogs for bot replies and approval messages.
+// Messages are identified by their English format string so untranslated
+// messages fall back to English.
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale - messages are written in English
+const DefaultLocale = "en"
+
+// ErrUnknownLocale - returned when there is no catalog for the locale
+var ErrUnknownLocale = errors.New("unknown locale")
+
+var (
+	catalogsM sync.RWMutex
+	catalogs  = map[string]map[string]string{
+		DefaultLocale: {},
+	}
+	locale = DefaultLocale
+)
+
+// Register - registers message catalog for the locale, catalog maps English
+// format strings to translated ones
+func Register(loc string, catalog map[string]string) {
+	catalogsM.Lock()
+	defer catalogsM.Unlock()
+
+	loc = normalize(loc)
+	if _, dup := catalogs[loc]; dup {
+		panic("i18n: Register called twice for " + loc)
+	}
+	catalogs[loc] = catalog
+}
+
+// SetLocale - sets locale that is used by T, ie: "de" or "de_DE.UTF-8"
+func SetLocale(loc string) error {
+	loc = normalize(loc)
+
+	catalogsM.Lock()
+	defer catalogsM.Unlock()
+
+	if _, ok := catalogs[loc]; !ok {
+		return ErrUnknownLocale
+	}
+	locale = loc
+	return nil
+}
+
+// Locale - returns current locale
+func Locale() string {
+	catalogsM.RLock()
+	defer catalogsM.RUnlock()
+	return locale
+}
+
+// Locales - returns all available locales
+func Locales() []string {
+	catalogsM.RLock()
+	defer catalogsM.RUnlock()
+
+	var locales []string
+	for loc := range catalogs {
+		locales = append(locales, loc)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// T - translates message to the current locale and formats it
+// with the arguments
+func T(message string, args ...interface{}) string {
+	return Translate(Locale(), message, args...)
+}
+
+// Translate - translates message to the given locale, English message
+// is used when translation is missing
+func Translate(loc, message string, args ...interface{}) string {
+	catalogsM.RLock()
+	translated, ok := catalogs[normalize(loc)][message]
+	catalogsM.RUnlock()
+
+	if !ok || translated == "" {
+		translated = message
+	}
+
+	if len(args) == 0 {
+		return translated
+	}
+	return fmt.Sprintf(translated, args...)
+}
+
+// normalize - "de_DE.UTF-8" -> "de"
+func normalize(loc string) string {
+	loc = strings.ToLower(strings.TrimSpace(loc))
+	if idx := strings.IndexAny(loc, "_-."); idx > 0 {
+		loc = loc[:idx]
+	}
+	return loc
+}
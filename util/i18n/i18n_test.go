@@ -0,0 +1,69 @@
+package i18n
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"en", "updates for 'default/wd' are not paused."},
+		{"de", "Updates für 'default/wd' sind nicht pausiert."},
+		{"de_DE.UTF-8", "Updates für 'default/wd' sind nicht pausiert."},
+		{"fr", "updates for 'default/wd' are not paused."},
+	}
+
+	for _, tt := range tests {
+		if got := Translate(tt.locale, "updates for '%s' are not paused.", "default/wd"); got != tt.want {
+			t.Errorf("Translate(%s) = %s, want %s", tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestTranslateMissing(t *testing.T) {
+	if got := Translate("de", "not translated 100%"); got != "not translated 100%" {
+		t.Errorf("unexpected message: %s", got)
+	}
+}
+
+func TestSetLocale(t *testing.T) {
+	defer SetLocale(DefaultLocale)
+
+	if err := SetLocale("xx"); err != ErrUnknownLocale {
+		t.Errorf("expected unknown locale error, got: %v", err)
+	}
+	if Locale() != DefaultLocale {
+		t.Errorf("locale shouldn't change, got: %s", Locale())
+	}
+
+	if err := SetLocale("DE"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := T("Votes"); got != "Stimmen" {
+		t.Errorf("unexpected translation: %s", got)
+	}
+}
+
+var verbsRe = regexp.MustCompile(`%[a-z]`)
+
+// translations must keep the same format verbs in the same order
+func TestCatalogVerbs(t *testing.T) {
+	for _, loc := range Locales() {
+		for message, translated := range catalogs[loc] {
+			want := verbsRe.FindAllString(message, -1)
+			got := verbsRe.FindAllString(translated, -1)
+			if len(want) != len(got) {
+				t.Errorf("%s: %q has %d verbs, translation has %d", loc, message, len(want), len(got))
+				continue
+			}
+			for idx := range want {
+				if want[idx] != got[idx] {
+					t.Errorf("%s: %q verbs don't match translation", loc, message)
+				}
+			}
+		}
+	}
+}
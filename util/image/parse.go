@@ -2,6 +2,8 @@ package image
 
 import (
 	"strings"
+
+	"github.com/opencontainers/go-digest"
 )
 
 // Reference is an opaque object that include identifier such as a name, tag, repository, registry, etc...
@@ -9,6 +11,8 @@ type Reference struct {
 	named  Named  `json:"named"`
 	tag    string `json:"tag"`
 	scheme string `json:"scheme"` // registry scheme, i.e. http, https
+	// digest - set for digest references and images pinned to a digest (name:tag@digest)
+	digest string
 }
 
 func (r Reference) String() string {
@@ -33,6 +37,12 @@ func (r Reference) Tag() string {
 	return ""
 }
 
+// Digest returns the image's digest, for images pinned to a digest (ie: debian:8.2@sha256:...)
+// tag is kept and digest is only available here
+func (r Reference) Digest() string {
+	return r.digest
+}
+
 // Registry returns the image's registry. (ie: host[:port])
 func (r Reference) Registry() string {
 	return r.named.Hostname()
@@ -76,6 +86,21 @@ func clean(url string) (cleaned string, scheme string) {
 func Parse(remote string) (*Reference, error) {
 
 	cleaned, scheme := clean(remote)
+
+	// pinned images (name:tag@digest) keep the tag, digest is stored separately
+	var pinned string
+	if i := strings.Index(cleaned, "@"); i > 0 {
+		name := cleaned[:i]
+		if strings.LastIndex(name, ":") > strings.LastIndex(name, "/") {
+			d, err := digest.Parse(cleaned[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			pinned = d.String()
+			cleaned = name
+		}
+	}
+
 	n, err := ParseNamed(cleaned)
 
 	if err != nil {
@@ -88,11 +113,12 @@ func Parse(remote string) (*Reference, error) {
 	switch x := n.(type) {
 	case Canonical:
 		t = "@" + x.Digest().String()
+		pinned = x.Digest().String()
 	case NamedTagged:
 		t = ":" + x.Tag()
 	}
 
-	return &Reference{named: n, tag: t, scheme: scheme}, nil
+	return &Reference{named: n, tag: t, scheme: scheme, digest: pinned}, nil
 }
 
 // ParseRepo - parses remote
@@ -142,3 +142,36 @@ func TestParseRepo(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePinned(t *testing.T) {
+	d := "sha256:0a9c1e8c1d3e5a6b2c4d9f0b1e2a3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b"
+
+	reference, err := Parse("gcr.io/v2-namespace/hello-world:1.1.1@" + d)
+	if err != nil {
+		t.Fatalf("error while parsing pinned image: %s", err)
+	}
+	if reference.Tag() != "1.1.1" {
+		t.Errorf("unexpected tag: %s", reference.Tag())
+	}
+	if reference.Digest() != d {
+		t.Errorf("unexpected digest: %s", reference.Digest())
+	}
+	if reference.Repository() != "gcr.io/v2-namespace/hello-world" {
+		t.Errorf("unexpected repository: %s", reference.Repository())
+	}
+	if reference.Remote() != "gcr.io/v2-namespace/hello-world:1.1.1" {
+		t.Errorf("unexpected remote: %s", reference.Remote())
+	}
+
+	reference, err = Parse("localhost:5000/foo@" + d)
+	if err != nil {
+		t.Fatalf("error while parsing digest reference: %s", err)
+	}
+	if reference.Tag() != d || reference.Digest() != d {
+		t.Errorf("unexpected tag %s and digest %s", reference.Tag(), reference.Digest())
+	}
+
+	if _, err := Parse("foo/bar:1.1@sha256:invalid"); err == nil {
+		t.Errorf("expected error for invalid digest")
+	}
+}
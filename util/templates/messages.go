@@ -0,0 +1,223 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MessageApprovalRequest - template name of the approval request message,
+// template data is *types.Approval
+const MessageApprovalRequest = "approvalRequest"
+
+// MessageTemplate - customizable message, title, text and field values are Go templates
+type MessageTemplate struct {
+	Title  string                 `json:"title"`
+	Text   string                 `json:"text"`
+	Fields []MessageFieldTemplate `json:"fields"`
+}
+
+// MessageFieldTemplate - message field (Slack attachment field) template
+type MessageFieldTemplate struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// Message - rendered message
+type Message struct {
+	Title  string
+	Text   string
+	Fields []MessageField
+}
+
+// String - plain text version of the message, used by senders that
+// can't render fields
+func (m *Message) String() string {
+	var lines []string
+	if m.Title != "" {
+		lines = append(lines, m.Title)
+	}
+	if m.Text != "" {
+		lines = append(lines, m.Text)
+	}
+	for _, f := range m.Fields {
+		lines = append(lines, fmt.Sprintf("%s: %s", f.Title, f.Value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// MessageField - rendered message field
+type MessageField struct {
+	Title string
+	Value string
+	Short bool
+}
+
+// Messages - message templates keyed by message name, ie:
+//
+//	approvalRequest:
+//	  title: "Approval required :rocket:"
+//	  text: "{{ .Message }} <https://dashboard.example.com/{{ .Identifier }}|details>"
+//	deploymentUpdate:
+//	  text: "{{ .Message }}"
+//
+// notification templates are named after the notification type, see NotificationMessageName
+type Messages struct {
+	templates map[string]*parsedMessage
+}
+
+type parsedMessage struct {
+	title  *template.Template
+	text   *template.Template
+	fields []parsedField
+}
+
+type parsedField struct {
+	title *template.Template
+	value *template.Template
+	short bool
+}
+
+// ParseMessages - parses YAML (or JSON) message templates
+func ParseMessages(data []byte) (*Messages, error) {
+	var raw map[string]*MessageTemplate
+	err := yaml.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode message templates: %s", err)
+	}
+
+	msgs := &Messages{templates: make(map[string]*parsedMessage)}
+	for name, tmpl := range raw {
+		if tmpl == nil {
+			continue
+		}
+		parsed := &parsedMessage{}
+		if parsed.title, err = NewParse(name+".title", tmpl.Title); err != nil {
+			return nil, fmt.Errorf("invalid %s title template: %s", name, err)
+		}
+		if parsed.text, err = NewParse(name+".text", tmpl.Text); err != nil {
+			return nil, fmt.Errorf("invalid %s text template: %s", name, err)
+		}
+		for idx, f := range tmpl.Fields {
+			field := parsedField{short: f.Short}
+			if field.title, err = NewParse(fmt.Sprintf("%s.fields[%d].title", name, idx), f.Title); err != nil {
+				return nil, fmt.Errorf("invalid %s field template: %s", name, err)
+			}
+			if field.value, err = NewParse(fmt.Sprintf("%s.fields[%d].value", name, idx), f.Value); err != nil {
+				return nil, fmt.Errorf("invalid %s field template: %s", name, err)
+			}
+			parsed.fields = append(parsed.fields, field)
+		}
+		msgs.templates[name] = parsed
+	}
+
+	return msgs, nil
+}
+
+// Render - renders message, returns false if template is not defined
+func (m *Messages) Render(name string, data interface{}) (*Message, bool, error) {
+	if m == nil {
+		return nil, false, nil
+	}
+	tmpl, ok := m.templates[name]
+	if !ok {
+		return nil, false, nil
+	}
+
+	var err error
+	msg := &Message{}
+	if msg.Title, err = execute(tmpl.title, data); err != nil {
+		return nil, true, err
+	}
+	if msg.Text, err = execute(tmpl.text, data); err != nil {
+		return nil, true, err
+	}
+	for _, f := range tmpl.fields {
+		field := MessageField{Short: f.short}
+		if field.Title, err = execute(f.title, data); err != nil {
+			return nil, true, err
+		}
+		if field.Value, err = execute(f.value, data); err != nil {
+			return nil, true, err
+		}
+		msg.Fields = append(msg.Fields, field)
+	}
+	return msg, true, nil
+}
+
+func execute(tmpl *template.Template, data interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	err := tmpl.Execute(buf, data)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// NotificationMessageName - template name for the notification type,
+// ie: "deployment update" -> "deploymentUpdate"
+func NotificationMessageName(n types.Notification) string {
+	words := strings.Fields(n.String())
+	for idx := 1; idx < len(words); idx++ {
+		words[idx] = strings.Title(words[idx])
+	}
+	return strings.Join(words, "")
+}
+
+var (
+	messagesM sync.RWMutex
+	messages  *Messages
+)
+
+// LoadMessages - loads message templates from a file (usually mounted ConfigMap)
+// and makes them available through RenderMessage
+func LoadMessages(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	msgs, err := ParseMessages(data)
+	if err != nil {
+		return err
+	}
+
+	SetMessages(msgs)
+	return nil
+}
+
+// SetMessages - sets message templates that are used by RenderMessage
+func SetMessages(msgs *Messages) {
+	messagesM.Lock()
+	messages = msgs
+	messagesM.Unlock()
+}
+
+// RenderMessage - renders configured message template, returns false when
+// template is not configured or fails to render so callers can fall back to
+// the default message
+func RenderMessage(name string, data interface{}) (*Message, bool) {
+	messagesM.RLock()
+	msgs := messages
+	messagesM.RUnlock()
+
+	msg, ok, err := msgs.Render(name, data)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"template": name,
+		}).Error("templates.RenderMessage: failed to render message template, using default message")
+		return nil, false
+	}
+	return msg, ok
+}
@@ -0,0 +1,102 @@
+package templates
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+)
+
+var testMessages = `
+approvalRequest:
+  title: "Approval required :rocket:"
+  text: "{{ .Message }} <https://dashboard.example.com/{{ .Identifier }}|details>"
+  fields:
+    - title: Votes
+      value: "{{ .VotesReceived }}/{{ .VotesRequired }}"
+      short: true
+deploymentUpdate:
+  text: "{{ .Message }} ({{ index .Metadata \"namespace\" }})"
+`
+
+func TestRenderApprovalRequest(t *testing.T) {
+	msgs, err := ParseMessages([]byte(testMessages))
+	if err != nil {
+		t.Fatalf("failed to parse messages: %s", err)
+	}
+
+	msg, ok, err := msgs.Render(MessageApprovalRequest, &types.Approval{
+		Identifier:    "deployment/default/wd:1.2.3",
+		Message:       "New image is available",
+		VotesRequired: 2,
+		VotesReceived: 1,
+	})
+	if err != nil {
+		t.Fatalf("failed to render: %s", err)
+	}
+	if !ok {
+		t.Fatalf("expected template to be found")
+	}
+
+	if msg.Title != "Approval required :rocket:" {
+		t.Errorf("unexpected title: %s", msg.Title)
+	}
+	if msg.Text != "New image is available <https://dashboard.example.com/deployment/default/wd:1.2.3|details>" {
+		t.Errorf("unexpected text: %s", msg.Text)
+	}
+	if len(msg.Fields) != 1 || msg.Fields[0].Value != "1/2" || !msg.Fields[0].Short {
+		t.Errorf("unexpected fields: %#v", msg.Fields)
+	}
+}
+
+func TestRenderNotification(t *testing.T) {
+	msgs, err := ParseMessages([]byte(testMessages))
+	if err != nil {
+		t.Fatalf("failed to parse messages: %s", err)
+	}
+
+	event := types.EventNotification{
+		Message:  "Successfully updated",
+		Type:     types.NotificationDeploymentUpdate,
+		Metadata: map[string]string{"namespace": "staging"},
+	}
+
+	msg, ok, err := msgs.Render(NotificationMessageName(event.Type), event)
+	if err != nil {
+		t.Fatalf("failed to render: %s", err)
+	}
+	if !ok {
+		t.Fatalf("expected template to be found")
+	}
+	if msg.String() != "Successfully updated (staging)" {
+		t.Errorf("unexpected message: %s", msg.String())
+	}
+
+	_, ok, _ = msgs.Render(NotificationMessageName(types.NotificationReleaseUpdate), event)
+	if ok {
+		t.Errorf("release update template shouldn't be defined")
+	}
+}
+
+func TestNotificationMessageName(t *testing.T) {
+	tests := []struct {
+		notification types.Notification
+		want         string
+	}{
+		{types.NotificationDeploymentUpdate, "deploymentUpdate"},
+		{types.NotificationPreReleaseUpdate, "preparingReleaseUpdate"},
+		{types.NotificationUpdateRejected, "updateRejected"},
+	}
+
+	for _, tt := range tests {
+		if got := NotificationMessageName(tt.notification); got != tt.want {
+			t.Errorf("NotificationMessageName(%s) = %s, want %s", tt.notification, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalidTemplate(t *testing.T) {
+	_, err := ParseMessages([]byte(`approvalRequest: {text: "{{ .Message "}`))
+	if err == nil {
+		t.Errorf("expected error")
+	}
+}
@@ -0,0 +1,163 @@
+package timeutil
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Window - recurring time window, ie: "Mon-Fri 09:00-17:00 Europe/London".
+// Windows ending before they start end on the next day.
+type Window struct {
+	days     [7]bool
+	start    time.Duration
+	end      time.Duration
+	location *time.Location
+}
+
+// ParseWindows - parses windows separated by semicolons, days and time zone
+// are optional (every day, UTC)
+func ParseWindows(value string) ([]Window, error) {
+	var windows []Window
+	for _, w := range strings.Split(value, ";") {
+		if strings.TrimSpace(w) == "" {
+			continue
+		}
+		window, err := ParseWindow(w)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no windows in %q", value)
+	}
+	return windows, nil
+}
+
+// ParseWindow - parses [days] HH:MM-HH:MM [time zone] window, days are a
+// range (Mon-Fri) or a single day (Sat)
+func ParseWindow(value string) (Window, error) {
+	fields := strings.Fields(value)
+	w := Window{location: time.UTC}
+	if len(fields) == 0 || len(fields) > 3 {
+		return w, fmt.Errorf("invalid window %q, expected [days] HH:MM-HH:MM [time zone]", value)
+	}
+
+	// days are optional
+	if !strings.Contains(fields[0], ":") {
+		if err := w.parseDays(fields[0]); err != nil {
+			return w, err
+		}
+		fields = fields[1:]
+	} else {
+		for i := range w.days {
+			w.days[i] = true
+		}
+	}
+	if len(fields) == 0 {
+		return w, fmt.Errorf("invalid window %q, missing hours", value)
+	}
+
+	hours := strings.SplitN(fields[0], "-", 2)
+	if len(hours) != 2 {
+		return w, fmt.Errorf("invalid window hours %q, expected HH:MM-HH:MM", fields[0])
+	}
+	var err error
+	if w.start, err = parseClock(hours[0]); err != nil {
+		return w, err
+	}
+	if w.end, err = parseClock(hours[1]); err != nil {
+		return w, err
+	}
+	if w.start == w.end {
+		return w, fmt.Errorf("invalid window hours %q, window is empty", fields[0])
+	}
+
+	if len(fields) == 2 {
+		if w.location, err = time.LoadLocation(fields[1]); err != nil {
+			return w, fmt.Errorf("invalid window time zone %q: %s", fields[1], err)
+		}
+	}
+	return w, nil
+}
+
+func (w *Window) parseDays(value string) error {
+	bounds := strings.SplitN(strings.ToLower(value), "-", 2)
+	from, ok := weekdays[bounds[0]]
+	if !ok {
+		return fmt.Errorf("invalid window day %q", bounds[0])
+	}
+	to := from
+	if len(bounds) == 2 {
+		if to, ok = weekdays[bounds[1]]; !ok {
+			return fmt.Errorf("invalid window day %q", bounds[1])
+		}
+	}
+	for d := from; ; d = (d + 1) % 7 {
+		w.days[d] = true
+		if d == to {
+			return nil
+		}
+	}
+}
+
+func parseClock(value string) (time.Duration, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(value, "%d:%d", &h, &m); err != nil || h < 0 || h > 24 || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid window time %q", value)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// Until - duration until the window opens, 0 when it's open
+func (w Window) Until(now time.Time) time.Duration {
+	local := now.In(w.location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, w.location)
+
+	length := w.end - w.start
+	if length < 0 {
+		length += 24 * time.Hour
+	}
+
+	// window started yesterday can still be open
+	for offset := -1; offset <= 7; offset++ {
+		day := midnight.AddDate(0, 0, offset)
+		if !w.days[day.Weekday()] {
+			continue
+		}
+		start := time.Date(day.Year(), day.Month(), day.Day(), int(w.start/time.Hour), int(w.start%time.Hour/time.Minute), 0, 0, w.location)
+		if now.Before(start) {
+			return start.Sub(now)
+		}
+		if now.Before(start.Add(length)) {
+			return 0
+		}
+	}
+	return 0
+}
+
+// UntilWindows - duration until any of the windows opens, 0 when one is open
+func UntilWindows(windows []Window, now time.Time) time.Duration {
+	var wait time.Duration
+	for i, w := range windows {
+		until := w.Until(now)
+		if until == 0 {
+			return 0
+		}
+		if i == 0 || until < wait {
+			wait = until
+		}
+	}
+	return wait
+}
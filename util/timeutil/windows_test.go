@@ -0,0 +1,66 @@
+package timeutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowUntil(t *testing.T) {
+	// Monday
+	monday := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		window string
+		now    time.Time
+		want   time.Duration
+	}{
+		{"Mon-Fri 09:00-17:00", monday, 0},
+		{"Mon-Fri 13:00-17:00", monday, time.Hour},
+		{"Mon-Fri 09:00-11:00", monday, 21 * time.Hour},
+		{"Sat 00:00-06:00", monday, 4*24*time.Hour + 12*time.Hour},
+		{"Fri-Mon 09:00-17:00", monday, 0},
+		{"22:00-06:00", monday.Add(-8 * time.Hour), 0},
+		{"22:00-06:00", monday, 10 * time.Hour},
+		// window from Sunday night still open on Monday morning
+		{"Sun 22:00-06:00", monday.Add(-7 * time.Hour), 0},
+		{"Mon 09:00-17:00 Europe/Vilnius", monday, 0},
+		{"Mon 09:00-14:00 Europe/Vilnius", monday, 6*24*time.Hour + 18*time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.window, func(t *testing.T) {
+			w, err := ParseWindow(tt.window)
+			if err != nil {
+				t.Fatalf("failed to parse window: %s", err)
+			}
+			if got := w.Until(tt.now); got != tt.want {
+				t.Errorf("Until() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUntilWindows(t *testing.T) {
+	monday := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	windows, err := ParseWindows("Sat 00:00-06:00; Mon-Fri 18:00-20:00")
+	if err != nil {
+		t.Fatalf("failed to parse windows: %s", err)
+	}
+	if got := UntilWindows(windows, monday); got != 6*time.Hour {
+		t.Errorf("UntilWindows() = %s, want 6h", got)
+	}
+}
+
+func TestParseWindowErrors(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"Mon-Fri",
+		"Mon-Fri 09:00",
+		"Someday 09:00-17:00",
+		"Mon-Fri 09:00-25:00",
+		"Mon-Fri 09:00-09:00",
+		"Mon-Fri 09:00-17:00 Mars/Olympus",
+	} {
+		if _, err := ParseWindows(value); err == nil {
+			t.Errorf("expected error for window %q", value)
+		}
+	}
+}